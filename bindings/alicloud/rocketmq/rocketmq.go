@@ -49,6 +49,10 @@ func NewAliCloudRocketMQ(l logger.Logger) *AliCloudRocketMQ {
 
 // Init performs metadata parsing.
 func (a *AliCloudRocketMQ) Init(metadata bindings.Metadata) error {
+	if err := bindings.ValidateConfirmedDelivery(metadata.Properties); err != nil {
+		return err
+	}
+
 	var err error
 	if err = a.settings.Decode(metadata.Properties); err != nil {
 		return err
@@ -0,0 +1,406 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package athena
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+
+	"github.com/dapr/components-contrib/bindings"
+	awsAuth "github.com/dapr/components-contrib/internal/authentication/aws"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// startQueryOperation starts the execution of the query of the "sql" metadata, returning its ID without waiting
+	// for its completion.
+	startQueryOperation bindings.OperationKind = "startQuery"
+	// getQueryStatusOperation returns the state of the execution of a query.
+	getQueryStatusOperation bindings.OperationKind = "getQueryStatus"
+	// getQueryResultsOperation returns a page of the results of a query.
+	getQueryResultsOperation bindings.OperationKind = "getQueryResults"
+	// cancelQueryOperation stops the execution of a query.
+	cancelQueryOperation bindings.OperationKind = "cancelQuery"
+
+	metadataSQL            = "sql"
+	metadataDatabase       = "database"
+	metadataWorkGroup      = "workGroup"
+	metadataOutputLocation = "outputLocation"
+	metadataQueryID        = "queryId"
+	metadataNextToken      = "nextToken"
+	metadataMaxResults     = "maxResults"
+)
+
+// AWSAthena is an AWS Athena binding running SQL queries asynchronously.
+type AWSAthena struct {
+	client   athenaiface.AthenaAPI
+	metadata *athenaMetadata
+
+	logger logger.Logger
+}
+
+type athenaMetadata struct {
+	Region                string `json:"region"`
+	Endpoint              string `json:"endpoint"`
+	AccessKey             string `json:"accessKey"`
+	SecretKey             string `json:"secretKey" mdsensitive:"true"`
+	SessionToken          string `json:"sessionToken" mdsensitive:"true"`
+	AssumeRoleArn         string `json:"assumeRoleArn"`
+	ExternalID            string `json:"externalId"`
+	AssumeRoleSessionName string `json:"assumeRoleSessionName"`
+	Catalog               string `json:"catalog"`
+	Database              string `json:"database"`
+	WorkGroup             string `json:"workGroup"`
+	// S3 location of the results of the queries, e.g. s3://bucket/path/; required unless set by the work group.
+	OutputLocation string `json:"outputLocation"`
+}
+
+type startQueryResponse struct {
+	QueryID string `json:"queryId"`
+}
+
+type queryStatusResponse struct {
+	QueryID            string     `json:"queryId"`
+	State              string     `json:"state"`
+	StateChangeReason  string     `json:"stateChangeReason,omitempty"`
+	SubmittedAt        *time.Time `json:"submittedAt,omitempty"`
+	CompletedAt        *time.Time `json:"completedAt,omitempty"`
+	DataScannedInBytes *int64     `json:"dataScannedInBytes,omitempty"`
+	OutputLocation     string     `json:"outputLocation,omitempty"`
+}
+
+type queryResultsResponse struct {
+	Columns []column         `json:"columns"`
+	Rows    []map[string]any `json:"rows"`
+}
+
+type column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// NewAWSAthena creates a new AWSAthena binding instance.
+func NewAWSAthena(logger logger.Logger) bindings.OutputBinding {
+	return &AWSAthena{logger: logger}
+}
+
+// Init does metadata parsing and client creation.
+func (a *AWSAthena) Init(metadata bindings.Metadata) error {
+	m, err := a.parseMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	client, err := a.getClient(m)
+	if err != nil {
+		return err
+	}
+	a.client = client
+	a.metadata = m
+
+	return nil
+}
+
+func (a *AWSAthena) parseMetadata(metadata bindings.Metadata) (*athenaMetadata, error) {
+	b, err := json.Marshal(metadata.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	var m athenaMetadata
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+func (a *AWSAthena) getClient(metadata *athenaMetadata) (*athena.Athena, error) {
+	sess, err := awsAuth.NewSession(awsAuth.Options{
+		AccessKey:    metadata.AccessKey,
+		SecretKey:    metadata.SecretKey,
+		SessionToken: metadata.SessionToken,
+		Region:       metadata.Region,
+		Endpoint:     metadata.Endpoint,
+		AssumeRole: awsAuth.AssumeRole{
+			RoleARN:     metadata.AssumeRoleArn,
+			ExternalID:  metadata.ExternalID,
+			SessionName: metadata.AssumeRoleSessionName,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	c := athena.New(sess)
+
+	return c, nil
+}
+
+func (a *AWSAthena) Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{
+		startQueryOperation,
+		getQueryStatusOperation,
+		getQueryResultsOperation,
+		cancelQueryOperation,
+	}
+}
+
+func (a *AWSAthena) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	switch req.Operation {
+	case startQueryOperation:
+		return a.startQuery(ctx, req)
+	case getQueryStatusOperation:
+		return a.getQueryStatus(ctx, req)
+	case getQueryResultsOperation:
+		return a.getQueryResults(ctx, req)
+	case cancelQueryOperation:
+		return nil, a.cancelQuery(ctx, req)
+	default:
+		return nil, fmt.Errorf("athena binding error: unsupported operation %s", req.Operation)
+	}
+}
+
+// startQuery starts the execution of the query of the "sql" request metadata, in the database, work group and output
+// location of the component or of the request metadata.
+// The request data is an optional JSON array of the values of the "?" parameters of the query.
+func (a *AWSAthena) startQuery(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	sql := req.Metadata[metadataSQL]
+	if sql == "" {
+		return nil, fmt.Errorf("athena binding error: required metadata not set: %s", metadataSQL)
+	}
+
+	input := &athena.StartQueryExecutionInput{
+		QueryString:           aws.String(sql),
+		QueryExecutionContext: &athena.QueryExecutionContext{},
+	}
+	if a.metadata.Catalog != "" {
+		input.QueryExecutionContext.Catalog = aws.String(a.metadata.Catalog)
+	}
+	if database := req.GetMetadataOrDefault(metadataDatabase, a.metadata.Database); database != "" {
+		input.QueryExecutionContext.Database = aws.String(database)
+	}
+	if workGroup := req.GetMetadataOrDefault(metadataWorkGroup, a.metadata.WorkGroup); workGroup != "" {
+		input.WorkGroup = aws.String(workGroup)
+	}
+	if outputLocation := req.GetMetadataOrDefault(metadataOutputLocation, a.metadata.OutputLocation); outputLocation != "" {
+		input.ResultConfiguration = &athena.ResultConfiguration{OutputLocation: aws.String(outputLocation)}
+	}
+	if len(req.Data) > 0 {
+		// The numbers are passed with the text of the request, without losing precision
+		decoder := json.NewDecoder(bytes.NewReader(req.Data))
+		decoder.UseNumber()
+		var params []any
+		err := decoder.Decode(&params)
+		if err != nil {
+			return nil, fmt.Errorf("athena binding error: query parameters must be a JSON array: %w", err)
+		}
+		for _, param := range params {
+			switch v := param.(type) {
+			case string:
+				// Parameters are SQL literals: strings are quoted
+				input.ExecutionParameters = append(input.ExecutionParameters, aws.String(quoteString(v)))
+			case json.Number:
+				input.ExecutionParameters = append(input.ExecutionParameters, aws.String(v.String()))
+			case bool:
+				input.ExecutionParameters = append(input.ExecutionParameters, aws.String(strconv.FormatBool(v)))
+			default:
+				return nil, fmt.Errorf("athena binding error: unsupported query parameter %v", param)
+			}
+		}
+	}
+
+	out, err := a.client.StartQueryExecutionWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("athena binding error: error starting query: %w", err)
+	}
+
+	data, err := json.Marshal(startQueryResponse{
+		QueryID: aws.StringValue(out.QueryExecutionId),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &bindings.InvokeResponse{
+		Data: data,
+		Metadata: map[string]string{
+			metadataQueryID: aws.StringValue(out.QueryExecutionId),
+		},
+	}, nil
+}
+
+// getQueryStatus returns the state of the execution of the query of the "queryId" request metadata.
+func (a *AWSAthena) getQueryStatus(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	queryID := req.Metadata[metadataQueryID]
+	if queryID == "" {
+		return nil, errors.New("athena binding error: queryId property not supplied in request-metadata")
+	}
+
+	out, err := a.client.GetQueryExecutionWithContext(ctx, &athena.GetQueryExecutionInput{
+		QueryExecutionId: aws.String(queryID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("athena binding error: error getting query %s: %w", queryID, err)
+	}
+
+	res := queryStatusResponse{QueryID: queryID}
+	if execution := out.QueryExecution; execution != nil {
+		if execution.Status != nil {
+			res.State = aws.StringValue(execution.Status.State)
+			res.StateChangeReason = aws.StringValue(execution.Status.StateChangeReason)
+			res.SubmittedAt = execution.Status.SubmissionDateTime
+			res.CompletedAt = execution.Status.CompletionDateTime
+		}
+		if execution.Statistics != nil {
+			res.DataScannedInBytes = execution.Statistics.DataScannedInBytes
+		}
+		if execution.ResultConfiguration != nil {
+			res.OutputLocation = aws.StringValue(execution.ResultConfiguration.OutputLocation)
+		}
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bindings.InvokeResponse{Data: data}, nil
+}
+
+// getQueryResults returns the page of the "nextToken" request metadata, or the first page, of the results of the
+// query of the "queryId" request metadata, with at most "maxResults" rows. The token of the next page, if any, is
+// returned in the "nextToken" response metadata.
+func (a *AWSAthena) getQueryResults(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	queryID := req.Metadata[metadataQueryID]
+	if queryID == "" {
+		return nil, errors.New("athena binding error: queryId property not supplied in request-metadata")
+	}
+
+	input := &athena.GetQueryResultsInput{
+		QueryExecutionId: aws.String(queryID),
+	}
+	nextToken := req.Metadata[metadataNextToken]
+	if nextToken != "" {
+		input.NextToken = aws.String(nextToken)
+	}
+	if val, ok := req.Metadata[metadataMaxResults]; ok && val != "" {
+		maxResults, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || maxResults < 1 {
+			return nil, fmt.Errorf("athena binding error: invalid %s: %s", metadataMaxResults, val)
+		}
+		input.MaxResults = aws.Int64(maxResults)
+	}
+
+	out, err := a.client.GetQueryResultsWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("athena binding error: error getting results of query %s: %w", queryID, err)
+	}
+
+	res := queryResultsResponse{
+		Columns: []column{},
+		Rows:    []map[string]any{},
+	}
+	if out.ResultSet != nil {
+		if out.ResultSet.ResultSetMetadata != nil {
+			for _, info := range out.ResultSet.ResultSetMetadata.ColumnInfo {
+				res.Columns = append(res.Columns, column{
+					Name: aws.StringValue(info.Name),
+					Type: aws.StringValue(info.Type),
+				})
+			}
+		}
+		rows := out.ResultSet.Rows
+		// The first row of the results of a SELECT query holds the names of the columns
+		if nextToken == "" && len(rows) > 0 && isHeader(rows[0], res.Columns) {
+			rows = rows[1:]
+		}
+		for _, row := range rows {
+			record := make(map[string]any, len(res.Columns))
+			for i, col := range res.Columns {
+				if i < len(row.Data) && row.Data[i].VarCharValue != nil {
+					record[col.Name] = *row.Data[i].VarCharValue
+				} else {
+					record[col.Name] = nil
+				}
+			}
+			res.Rows = append(res.Rows, record)
+		}
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &bindings.InvokeResponse{
+		Data:     data,
+		Metadata: map[string]string{},
+	}
+	if out.NextToken != nil {
+		resp.Metadata[metadataNextToken] = *out.NextToken
+	}
+
+	return resp, nil
+}
+
+// cancelQuery stops the execution of the query of the "queryId" request metadata.
+func (a *AWSAthena) cancelQuery(ctx context.Context, req *bindings.InvokeRequest) error {
+	queryID := req.Metadata[metadataQueryID]
+	if queryID == "" {
+		return errors.New("athena binding error: queryId property not supplied in request-metadata")
+	}
+
+	_, err := a.client.StopQueryExecutionWithContext(ctx, &athena.StopQueryExecutionInput{
+		QueryExecutionId: aws.String(queryID),
+	})
+	if err != nil {
+		return fmt.Errorf("athena binding error: error stopping query %s: %w", queryID, err)
+	}
+
+	return nil
+}
+
+// isHeader returns true if the values of the row are the names of the columns.
+func isHeader(row *athena.Row, columns []column) bool {
+	if len(row.Data) != len(columns) {
+		return false
+	}
+	for i, col := range columns {
+		if aws.StringValue(row.Data[i].VarCharValue) != col.Name {
+			return false
+		}
+	}
+
+	return true
+}
+
+// quoteString returns a SQL string literal.
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// GetComponentMetadataSchema returns the schema of the metadata of the AWS Athena binding.
+func (a *AWSAthena) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(athenaMetadata{})
+	return fields
+}
@@ -0,0 +1,300 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package athena
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/kit/logger"
+)
+
+type mockedAthena struct {
+	StartQueryExecutionFn func(*athena.StartQueryExecutionInput) (*athena.StartQueryExecutionOutput, error)
+	GetQueryExecutionFn   func(*athena.GetQueryExecutionInput) (*athena.GetQueryExecutionOutput, error)
+	GetQueryResultsFn     func(*athena.GetQueryResultsInput) (*athena.GetQueryResultsOutput, error)
+	StopQueryExecutionFn  func(*athena.StopQueryExecutionInput) (*athena.StopQueryExecutionOutput, error)
+	athenaiface.AthenaAPI
+}
+
+func (m *mockedAthena) StartQueryExecutionWithContext(_ context.Context, input *athena.StartQueryExecutionInput, _ ...request.Option) (*athena.StartQueryExecutionOutput, error) {
+	return m.StartQueryExecutionFn(input)
+}
+
+func (m *mockedAthena) GetQueryExecutionWithContext(_ context.Context, input *athena.GetQueryExecutionInput, _ ...request.Option) (*athena.GetQueryExecutionOutput, error) {
+	return m.GetQueryExecutionFn(input)
+}
+
+func (m *mockedAthena) GetQueryResultsWithContext(_ context.Context, input *athena.GetQueryResultsInput, _ ...request.Option) (*athena.GetQueryResultsOutput, error) {
+	return m.GetQueryResultsFn(input)
+}
+
+func (m *mockedAthena) StopQueryExecutionWithContext(_ context.Context, input *athena.StopQueryExecutionInput, _ ...request.Option) (*athena.StopQueryExecutionOutput, error) {
+	return m.StopQueryExecutionFn(input)
+}
+
+func newAthena(client athenaiface.AthenaAPI) *AWSAthena {
+	return &AWSAthena{
+		client: client,
+		metadata: &athenaMetadata{
+			Database:       "mydb",
+			WorkGroup:      "primary",
+			OutputLocation: "s3://results/",
+		},
+		logger: logger.NewLogger("test"),
+	}
+}
+
+func TestParseMetadata(t *testing.T) {
+	m := bindings.Metadata{}
+	m.Properties = map[string]string{
+		"region": "region", "accessKey": "key", "secretKey": "secret",
+		"catalog": "AwsDataCatalog", "database": "mydb", "workGroup": "primary", "outputLocation": "s3://results/",
+	}
+	a := AWSAthena{}
+	meta, err := a.parseMetadata(m)
+	assert.Nil(t, err)
+	assert.Equal(t, "region", meta.Region)
+	assert.Equal(t, "key", meta.AccessKey)
+	assert.Equal(t, "secret", meta.SecretKey)
+	assert.Equal(t, "AwsDataCatalog", meta.Catalog)
+	assert.Equal(t, "mydb", meta.Database)
+	assert.Equal(t, "primary", meta.WorkGroup)
+	assert.Equal(t, "s3://results/", meta.OutputLocation)
+}
+
+func TestStartQuery(t *testing.T) {
+	t.Run("passes the numbers as written", func(t *testing.T) {
+		a := newAthena(&mockedAthena{
+			StartQueryExecutionFn: func(input *athena.StartQueryExecutionInput) (*athena.StartQueryExecutionOutput, error) {
+				assert.Equal(t, []string{"12345678901234567890", "0.10", "true"}, aws.StringValueSlice(input.ExecutionParameters))
+
+				return &athena.StartQueryExecutionOutput{QueryExecutionId: aws.String("query1")}, nil
+			},
+		})
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: startQueryOperation,
+			Data:      []byte(`[12345678901234567890, 0.10, true]`),
+			Metadata:  map[string]string{"sql": "SELECT * FROM events WHERE id = ? AND ratio = ? AND active = ?"},
+		})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("starts the query with its parameters", func(t *testing.T) {
+		a := newAthena(&mockedAthena{
+			StartQueryExecutionFn: func(input *athena.StartQueryExecutionInput) (*athena.StartQueryExecutionOutput, error) {
+				assert.Equal(t, "SELECT * FROM events WHERE name = ? AND count > ?", *input.QueryString)
+				assert.Equal(t, "otherdb", *input.QueryExecutionContext.Database)
+				assert.Equal(t, "primary", *input.WorkGroup)
+				assert.Equal(t, "s3://results/", *input.ResultConfiguration.OutputLocation)
+				assert.Equal(t, []string{"'o''brien'", "10"}, aws.StringValueSlice(input.ExecutionParameters))
+
+				return &athena.StartQueryExecutionOutput{QueryExecutionId: aws.String("query1")}, nil
+			},
+		})
+
+		res, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: startQueryOperation,
+			Data:      []byte(`["o'brien", 10]`),
+			Metadata: map[string]string{
+				"sql":      "SELECT * FROM events WHERE name = ? AND count > ?",
+				"database": "otherdb",
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"queryId":"query1"}`, string(res.Data))
+		assert.Equal(t, "query1", res.Metadata["queryId"])
+	})
+
+	t.Run("returns error for missing sql", func(t *testing.T) {
+		a := newAthena(&mockedAthena{})
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{Operation: startQueryOperation})
+
+		assert.ErrorContains(t, err, "required metadata not set: sql")
+	})
+
+	t.Run("returns error for invalid parameters", func(t *testing.T) {
+		a := newAthena(&mockedAthena{})
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: startQueryOperation,
+			Data:      []byte(`{"name": "a"}`),
+			Metadata:  map[string]string{"sql": "SELECT 1"},
+		})
+
+		assert.ErrorContains(t, err, "query parameters must be a JSON array")
+	})
+
+	t.Run("returns the error of the client", func(t *testing.T) {
+		a := newAthena(&mockedAthena{
+			StartQueryExecutionFn: func(input *athena.StartQueryExecutionInput) (*athena.StartQueryExecutionOutput, error) {
+				return nil, errors.New("throttled")
+			},
+		})
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: startQueryOperation,
+			Metadata:  map[string]string{"sql": "SELECT 1"},
+		})
+
+		assert.ErrorContains(t, err, "throttled")
+	})
+}
+
+func TestGetQueryStatus(t *testing.T) {
+	submitted := time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)
+	a := newAthena(&mockedAthena{
+		GetQueryExecutionFn: func(input *athena.GetQueryExecutionInput) (*athena.GetQueryExecutionOutput, error) {
+			assert.Equal(t, "query1", *input.QueryExecutionId)
+
+			return &athena.GetQueryExecutionOutput{
+				QueryExecution: &athena.QueryExecution{
+					QueryExecutionId: aws.String("query1"),
+					Status: &athena.QueryExecutionStatus{
+						State:              aws.String(athena.QueryExecutionStateFailed),
+						StateChangeReason:  aws.String("syntax error"),
+						SubmissionDateTime: &submitted,
+					},
+				},
+			}, nil
+		},
+	})
+
+	res, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+		Operation: getQueryStatusOperation,
+		Metadata:  map[string]string{"queryId": "query1"},
+	})
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"queryId":"query1","state":"FAILED","stateChangeReason":"syntax error","submittedAt":"2022-11-01T00:00:00Z"}`, string(res.Data))
+
+	t.Run("returns error for missing queryId", func(t *testing.T) {
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{Operation: getQueryStatusOperation})
+
+		assert.ErrorContains(t, err, "queryId property not supplied")
+	})
+}
+
+func TestGetQueryResults(t *testing.T) {
+	resultSet := func(rows ...[]*string) *athena.ResultSet {
+		rs := &athena.ResultSet{
+			ResultSetMetadata: &athena.ResultSetMetadata{
+				ColumnInfo: []*athena.ColumnInfo{
+					{Name: aws.String("name"), Type: aws.String("varchar")},
+					{Name: aws.String("count"), Type: aws.String("bigint")},
+				},
+			},
+		}
+		for _, row := range rows {
+			r := &athena.Row{}
+			for _, v := range row {
+				r.Data = append(r.Data, &athena.Datum{VarCharValue: v})
+			}
+			rs.Rows = append(rs.Rows, r)
+		}
+		return rs
+	}
+
+	t.Run("returns the first page without the header row", func(t *testing.T) {
+		a := newAthena(&mockedAthena{
+			GetQueryResultsFn: func(input *athena.GetQueryResultsInput) (*athena.GetQueryResultsOutput, error) {
+				assert.Equal(t, "query1", *input.QueryExecutionId)
+				assert.Nil(t, input.NextToken)
+				assert.Equal(t, int64(2), *input.MaxResults)
+
+				return &athena.GetQueryResultsOutput{
+					ResultSet: resultSet(
+						[]*string{aws.String("name"), aws.String("count")},
+						[]*string{aws.String("a"), nil},
+					),
+					NextToken: aws.String("page2"),
+				}, nil
+			},
+		})
+
+		res, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: getQueryResultsOperation,
+			Metadata:  map[string]string{"queryId": "query1", "maxResults": "2"},
+		})
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"columns":[{"name":"name","type":"varchar"},{"name":"count","type":"bigint"}],"rows":[{"name":"a","count":null}]}`, string(res.Data))
+		assert.Equal(t, "page2", res.Metadata["nextToken"])
+	})
+
+	t.Run("returns the next page", func(t *testing.T) {
+		a := newAthena(&mockedAthena{
+			GetQueryResultsFn: func(input *athena.GetQueryResultsInput) (*athena.GetQueryResultsOutput, error) {
+				assert.Equal(t, "page2", *input.NextToken)
+
+				return &athena.GetQueryResultsOutput{
+					ResultSet: resultSet([]*string{aws.String("name"), aws.String("count")}),
+				}, nil
+			},
+		})
+
+		res, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: getQueryResultsOperation,
+			Metadata:  map[string]string{"queryId": "query1", "nextToken": "page2"},
+		})
+
+		assert.NoError(t, err)
+		// Only the first row of the first page is the header
+		assert.JSONEq(t, `{"columns":[{"name":"name","type":"varchar"},{"name":"count","type":"bigint"}],"rows":[{"name":"name","count":"count"}]}`, string(res.Data))
+		assert.Empty(t, res.Metadata["nextToken"])
+	})
+
+	t.Run("returns error for invalid maxResults", func(t *testing.T) {
+		a := newAthena(&mockedAthena{})
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: getQueryResultsOperation,
+			Metadata:  map[string]string{"queryId": "query1", "maxResults": "0"},
+		})
+
+		assert.ErrorContains(t, err, "invalid maxResults")
+	})
+}
+
+func TestCancelQuery(t *testing.T) {
+	var stopped string
+	a := newAthena(&mockedAthena{
+		StopQueryExecutionFn: func(input *athena.StopQueryExecutionInput) (*athena.StopQueryExecutionOutput, error) {
+			stopped = *input.QueryExecutionId
+
+			return &athena.StopQueryExecutionOutput{}, nil
+		},
+	})
+
+	_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+		Operation: cancelQueryOperation,
+		Metadata:  map[string]string{"queryId": "query1"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "query1", stopped)
+}
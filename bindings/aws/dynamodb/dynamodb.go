@@ -16,6 +16,7 @@ package dynamodb
 import (
 	"context"
 	"encoding/json"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
@@ -35,12 +36,18 @@ type DynamoDB struct {
 }
 
 type dynamoDBMetadata struct {
-	Region       string `json:"region"`
-	Endpoint     string `json:"endpoint"`
-	AccessKey    string `json:"accessKey"`
-	SecretKey    string `json:"secretKey"`
-	SessionToken string `json:"sessionToken"`
-	Table        string `json:"table"`
+	Region                string `json:"region"`
+	Endpoint              string `json:"endpoint"`
+	AccessKey             string `json:"accessKey"`
+	SecretKey             string `json:"secretKey" mdsensitive:"true"`
+	SessionToken          string `json:"sessionToken" mdsensitive:"true"`
+	AssumeRoleArn         string `json:"assumeRoleArn"`
+	ExternalID            string `json:"externalId"`
+	AssumeRoleSessionName string `json:"assumeRoleSessionName"`
+	UseFIPSEndpoint       bool   `json:"useFipsEndpoint"`
+	UseDualStackEndpoint  bool   `json:"useDualStackEndpoint"`
+	FailoverRegions       string `json:"failoverRegions"`
+	Table                 string `json:"table"`
 }
 
 // NewDynamoDB returns a new DynamoDB instance.
@@ -104,7 +111,21 @@ func (d *DynamoDB) getDynamoDBMetadata(spec bindings.Metadata) (*dynamoDBMetadat
 }
 
 func (d *DynamoDB) getClient(metadata *dynamoDBMetadata) (*dynamodb.DynamoDB, error) {
-	sess, err := awsAuth.GetClient(metadata.AccessKey, metadata.SecretKey, metadata.SessionToken, metadata.Region, metadata.Endpoint)
+	sess, err := awsAuth.NewSession(awsAuth.Options{
+		AccessKey:    metadata.AccessKey,
+		SecretKey:    metadata.SecretKey,
+		SessionToken: metadata.SessionToken,
+		Region:       metadata.Region,
+		Endpoint:     metadata.Endpoint,
+		AssumeRole: awsAuth.AssumeRole{
+			RoleARN:     metadata.AssumeRoleArn,
+			ExternalID:  metadata.ExternalID,
+			SessionName: metadata.AssumeRoleSessionName,
+		},
+		UseFIPSEndpoint:      metadata.UseFIPSEndpoint,
+		UseDualStackEndpoint: metadata.UseDualStackEndpoint,
+		FailoverRegions:      strings.Split(metadata.FailoverRegions, ","),
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -112,3 +133,9 @@ func (d *DynamoDB) getClient(metadata *dynamoDBMetadata) (*dynamodb.DynamoDB, er
 
 	return c, nil
 }
+
+// GetComponentMetadataSchema returns the schema of the metadata of the AWS DynamoDB binding.
+func (d *DynamoDB) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(dynamoDBMetadata{})
+	return fields
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventbridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
+
+	"github.com/dapr/components-contrib/bindings"
+	awsAuth "github.com/dapr/components-contrib/internal/authentication/aws"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	metadataEventBusName = "eventBusName"
+	metadataSource       = "source"
+	metadataDetailType   = "detailType"
+	metadataResources    = "resources"
+
+	metadataEventID = "eventId"
+)
+
+// AWSEventBridge is an AWS EventBridge binding.
+type AWSEventBridge struct {
+	client   eventbridgeiface.EventBridgeAPI
+	metadata *eventBridgeMetadata
+
+	logger logger.Logger
+}
+
+type eventBridgeMetadata struct {
+	EventBusName          string `json:"eventBusName"`
+	Source                string `json:"source"`
+	DetailType            string `json:"detailType"`
+	Resources             string `json:"resources"`
+	Region                string `json:"region"`
+	Endpoint              string `json:"endpoint"`
+	AccessKey             string `json:"accessKey"`
+	SecretKey             string `json:"secretKey" mdsensitive:"true"`
+	SessionToken          string `json:"sessionToken" mdsensitive:"true"`
+	AssumeRoleArn         string `json:"assumeRoleArn"`
+	ExternalID            string `json:"externalId"`
+	AssumeRoleSessionName string `json:"assumeRoleSessionName"`
+}
+
+// NewAWSEventBridge creates a new AWSEventBridge binding instance.
+func NewAWSEventBridge(logger logger.Logger) bindings.OutputBinding {
+	return &AWSEventBridge{logger: logger}
+}
+
+// Init does metadata parsing and client creation.
+func (a *AWSEventBridge) Init(metadata bindings.Metadata) error {
+	m, err := a.parseMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	client, err := a.getClient(m)
+	if err != nil {
+		return err
+	}
+	a.client = client
+	a.metadata = m
+
+	return nil
+}
+
+func (a *AWSEventBridge) parseMetadata(metadata bindings.Metadata) (*eventBridgeMetadata, error) {
+	b, err := json.Marshal(metadata.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	var m eventBridgeMetadata
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+func (a *AWSEventBridge) getClient(metadata *eventBridgeMetadata) (*eventbridge.EventBridge, error) {
+	sess, err := awsAuth.NewSession(awsAuth.Options{
+		AccessKey:    metadata.AccessKey,
+		SecretKey:    metadata.SecretKey,
+		SessionToken: metadata.SessionToken,
+		Region:       metadata.Region,
+		Endpoint:     metadata.Endpoint,
+		AssumeRole: awsAuth.AssumeRole{
+			RoleARN:     metadata.AssumeRoleArn,
+			ExternalID:  metadata.ExternalID,
+			SessionName: metadata.AssumeRoleSessionName,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	c := eventbridge.New(sess)
+
+	return c, nil
+}
+
+func (a *AWSEventBridge) Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{bindings.CreateOperation}
+}
+
+// Invoke puts an event with the request data as detail, which must be a JSON object.
+// The event bus, source, detail type and resources can be set in the request metadata.
+func (a *AWSEventBridge) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	metadata := a.metadata.mergeWithRequestMetadata(req)
+	if metadata.Source == "" {
+		return nil, errors.New("eventbridge binding error: source property not supplied in configuration- or request-metadata")
+	}
+	if metadata.DetailType == "" {
+		return nil, errors.New("eventbridge binding error: detailType property not supplied in configuration- or request-metadata")
+	}
+
+	entry := &eventbridge.PutEventsRequestEntry{
+		Source:     aws.String(metadata.Source),
+		DetailType: aws.String(metadata.DetailType),
+		Detail:     aws.String(string(req.Data)),
+	}
+	if metadata.EventBusName != "" {
+		entry.EventBusName = aws.String(metadata.EventBusName)
+	}
+	if metadata.Resources != "" {
+		for _, r := range strings.Split(metadata.Resources, ",") {
+			if r = strings.TrimSpace(r); r != "" {
+				entry.Resources = append(entry.Resources, aws.String(r))
+			}
+		}
+	}
+
+	out, err := a.client.PutEventsWithContext(ctx, &eventbridge.PutEventsInput{
+		Entries: []*eventbridge.PutEventsRequestEntry{entry},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("eventbridge binding error: error putting event: %w", err)
+	}
+	if aws.Int64Value(out.FailedEntryCount) > 0 || len(out.Entries) == 0 {
+		var code, msg string
+		if len(out.Entries) > 0 {
+			code, msg = aws.StringValue(out.Entries[0].ErrorCode), aws.StringValue(out.Entries[0].ErrorMessage)
+		}
+
+		return nil, fmt.Errorf("eventbridge binding error: event rejected: %s: %s", code, msg)
+	}
+
+	return &bindings.InvokeResponse{
+		Metadata: map[string]string{
+			metadataEventID: aws.StringValue(out.Entries[0].EventId),
+		},
+	}, nil
+}
+
+// Helper to merge config and request metadata.
+func (metadata eventBridgeMetadata) mergeWithRequestMetadata(req *bindings.InvokeRequest) eventBridgeMetadata {
+	merged := metadata
+
+	if val, ok := req.Metadata[metadataEventBusName]; ok && val != "" {
+		merged.EventBusName = val
+	}
+
+	if val, ok := req.Metadata[metadataSource]; ok && val != "" {
+		merged.Source = val
+	}
+
+	if val, ok := req.Metadata[metadataDetailType]; ok && val != "" {
+		merged.DetailType = val
+	}
+
+	if val, ok := req.Metadata[metadataResources]; ok && val != "" {
+		merged.Resources = val
+	}
+
+	return merged
+}
+
+// GetComponentMetadataSchema returns the schema of the metadata of the AWS EventBridge binding.
+func (a *AWSEventBridge) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(eventBridgeMetadata{})
+	return fields
+}
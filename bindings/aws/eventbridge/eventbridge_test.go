@@ -0,0 +1,119 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventbridge
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/kit/logger"
+)
+
+type mockedEventBridge struct {
+	PutEventsFn func(context.Context, *eventbridge.PutEventsInput, ...request.Option) (*eventbridge.PutEventsOutput, error)
+	eventbridgeiface.EventBridgeAPI
+}
+
+func (m *mockedEventBridge) PutEventsWithContext(ctx context.Context, input *eventbridge.PutEventsInput, option ...request.Option) (*eventbridge.PutEventsOutput, error) {
+	return m.PutEventsFn(ctx, input, option...)
+}
+
+func TestParseMetadata(t *testing.T) {
+	m := bindings.Metadata{}
+	m.Properties = map[string]string{
+		"eventBusName": "bus", "source": "dapr.app", "detailType": "order", "resources": "arn:a", "region": "region", "accessKey": "key", "secretKey": "secret",
+	}
+	a := AWSEventBridge{}
+	meta, err := a.parseMetadata(m)
+	assert.Nil(t, err)
+	assert.Equal(t, "bus", meta.EventBusName)
+	assert.Equal(t, "dapr.app", meta.Source)
+	assert.Equal(t, "order", meta.DetailType)
+	assert.Equal(t, "arn:a", meta.Resources)
+	assert.Equal(t, "region", meta.Region)
+	assert.Equal(t, "key", meta.AccessKey)
+	assert.Equal(t, "secret", meta.SecretKey)
+}
+
+func TestInvoke(t *testing.T) {
+	newEventBridge := func(fn func(context.Context, *eventbridge.PutEventsInput, ...request.Option) (*eventbridge.PutEventsOutput, error)) *AWSEventBridge {
+		return &AWSEventBridge{
+			client: &mockedEventBridge{PutEventsFn: fn},
+			metadata: &eventBridgeMetadata{
+				EventBusName: "bus",
+				Source:       "dapr.app",
+				DetailType:   "order",
+			},
+			logger: logger.NewLogger("test"),
+		}
+	}
+
+	t.Run("puts event", func(t *testing.T) {
+		a := newEventBridge(func(ctx context.Context, input *eventbridge.PutEventsInput, option ...request.Option) (*eventbridge.PutEventsOutput, error) {
+			assert.Len(t, input.Entries, 1)
+			entry := input.Entries[0]
+			assert.Equal(t, "bus", *entry.EventBusName)
+			assert.Equal(t, "dapr.app", *entry.Source)
+			assert.Equal(t, "shipment", *entry.DetailType)
+			assert.Equal(t, `{"id":1}`, *entry.Detail)
+			assert.Equal(t, []*string{aws.String("arn:a"), aws.String("arn:b")}, entry.Resources)
+
+			return &eventbridge.PutEventsOutput{
+				FailedEntryCount: aws.Int64(0),
+				Entries:          []*eventbridge.PutEventsResultEntry{{EventId: aws.String("event-1")}},
+			}, nil
+		})
+
+		res, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      []byte(`{"id":1}`),
+			Metadata:  map[string]string{"detailType": "shipment", "resources": "arn:a, arn:b"},
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, "event-1", res.Metadata["eventId"])
+	})
+
+	t.Run("event rejected", func(t *testing.T) {
+		a := newEventBridge(func(ctx context.Context, input *eventbridge.PutEventsInput, option ...request.Option) (*eventbridge.PutEventsOutput, error) {
+			return &eventbridge.PutEventsOutput{
+				FailedEntryCount: aws.Int64(1),
+				Entries: []*eventbridge.PutEventsResultEntry{{
+					ErrorCode:    aws.String("MalformedDetail"),
+					ErrorMessage: aws.String("Detail is malformed."),
+				}},
+			}, nil
+		})
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{Data: []byte("not json")})
+
+		assert.ErrorContains(t, err, "MalformedDetail")
+	})
+
+	t.Run("source missing", func(t *testing.T) {
+		a := newEventBridge(nil)
+		a.metadata.Source = ""
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{Data: []byte(`{}`)})
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kinesis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/vmware/vmware-go-kcl/clientlibrary/checkpoint"
+)
+
+// checkpointStore persists in a DynamoDB table the sequence number of the last record processed in each shard, and the
+// leases of the shards, so each shard is read by a single replica.
+// The items have the same layout as the lease table of the shared throughput mode.
+type checkpointStore struct {
+	client dynamodbiface.DynamoDBAPI
+	table  string
+}
+
+func newCheckpointStore(client dynamodbiface.DynamoDBAPI, table string) *checkpointStore {
+	return &checkpointStore{
+		client: client,
+		table:  table,
+	}
+}
+
+// ensureTable creates the table if it does not exist, and waits for it to be active.
+func (s *checkpointStore) ensureTable(ctx context.Context) error {
+	_, err := s.client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(s.table),
+	})
+	if err == nil {
+		return nil
+	}
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) || awsErr.Code() != dynamodb.ErrCodeResourceNotFoundException {
+		return fmt.Errorf("error describing checkpoint table %s: %w", s.table, err)
+	}
+
+	_, err = s.client.CreateTableWithContext(ctx, &dynamodb.CreateTableInput{
+		TableName:   aws.String(s.table),
+		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{{
+			AttributeName: aws.String(checkpoint.LeaseKeyKey),
+			AttributeType: aws.String(dynamodb.ScalarAttributeTypeS),
+		}},
+		KeySchema: []*dynamodb.KeySchemaElement{{
+			AttributeName: aws.String(checkpoint.LeaseKeyKey),
+			KeyType:       aws.String(dynamodb.KeyTypeHash),
+		}},
+	})
+	if err != nil && (!errors.As(err, &awsErr) || awsErr.Code() != dynamodb.ErrCodeResourceInUseException) {
+		return fmt.Errorf("error creating checkpoint table %s: %w", s.table, err)
+	}
+
+	return s.client.WaitUntilTableExistsWithContext(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(s.table),
+	})
+}
+
+// get returns the checkpoint of a shard, or an empty string if there is none.
+func (s *checkpointStore) get(ctx context.Context, shardID string) (string, error) {
+	out, err := s.client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.table),
+		ConsistentRead: aws.Bool(true),
+		Key:            shardKey(shardID),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error reading checkpoint of shard %s: %w", shardID, err)
+	}
+	if v, ok := out.Item[checkpoint.SequenceNumberKey]; ok {
+		return aws.StringValue(v.S), nil
+	}
+
+	return "", nil
+}
+
+// acquire takes the lease of a shard for owner until now+duration, if the shard isn't leased, its lease expired, or it
+// is already leased by owner. It returns false if the shard is leased by another owner, and else the checkpoint of the
+// shard.
+func (s *checkpointStore) acquire(ctx context.Context, shardID string, owner string, duration time.Duration) (bool, string, error) {
+	// The lease timeouts are compared as strings, so they are all formatted in UTC
+	now := time.Now().UTC()
+	out, err := s.client.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(s.table),
+		Key:                 shardKey(shardID),
+		UpdateExpression:    aws.String("SET #owner = :owner, #timeout = :timeout"),
+		ConditionExpression: aws.String("attribute_not_exists(#owner) OR #owner = :owner OR #timeout < :now"),
+		ExpressionAttributeNames: map[string]*string{
+			"#owner":   aws.String(checkpoint.LeaseOwnerKey),
+			"#timeout": aws.String(checkpoint.LeaseTimeoutKey),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":owner":   {S: aws.String(owner)},
+			":timeout": {S: aws.String(now.Add(duration).Format(time.RFC3339))},
+			":now":     {S: aws.String(now.Format(time.RFC3339))},
+		},
+		ReturnValues: aws.String(dynamodb.ReturnValueAllNew),
+	})
+	if isConditionFailed(err) {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", fmt.Errorf("error acquiring the lease of shard %s: %w", shardID, err)
+	}
+
+	var sequenceNumber string
+	if v, ok := out.Attributes[checkpoint.SequenceNumberKey]; ok {
+		sequenceNumber = aws.StringValue(v.S)
+	}
+
+	return true, sequenceNumber, nil
+}
+
+// release gives up the lease of a shard by owner, so another replica can read it right away.
+func (s *checkpointStore) release(ctx context.Context, shardID string, owner string) error {
+	_, err := s.client.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(s.table),
+		Key:                 shardKey(shardID),
+		UpdateExpression:    aws.String("REMOVE #owner, #timeout"),
+		ConditionExpression: aws.String("#owner = :owner"),
+		ExpressionAttributeNames: map[string]*string{
+			"#owner":   aws.String(checkpoint.LeaseOwnerKey),
+			"#timeout": aws.String(checkpoint.LeaseTimeoutKey),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":owner": {S: aws.String(owner)},
+		},
+	})
+	if err != nil && !isConditionFailed(err) {
+		return fmt.Errorf("error releasing the lease of shard %s: %w", shardID, err)
+	}
+
+	return nil
+}
+
+// set stores the checkpoint of a shard, keeping the other attributes of its item. If owner is set, the checkpoint is
+// only stored if the shard is still leased by owner.
+func (s *checkpointStore) set(ctx context.Context, shardID string, owner string, sequenceNumber string) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName:        aws.String(s.table),
+		Key:              shardKey(shardID),
+		UpdateExpression: aws.String("SET #checkpoint = :checkpoint"),
+		ExpressionAttributeNames: map[string]*string{
+			"#checkpoint": aws.String(checkpoint.SequenceNumberKey),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":checkpoint": {S: aws.String(sequenceNumber)},
+		},
+	}
+	if owner != "" {
+		input.ConditionExpression = aws.String("#owner = :owner")
+		input.ExpressionAttributeNames["#owner"] = aws.String(checkpoint.LeaseOwnerKey)
+		input.ExpressionAttributeValues[":owner"] = &dynamodb.AttributeValue{S: aws.String(owner)}
+	}
+
+	_, err := s.client.UpdateItemWithContext(ctx, input)
+	if isConditionFailed(err) {
+		return fmt.Errorf("error storing checkpoint of shard %s: the lease of the shard was lost", shardID)
+	}
+	if err != nil {
+		return fmt.Errorf("error storing checkpoint of shard %s: %w", shardID, err)
+	}
+
+	return nil
+}
+
+func shardKey(shardID string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		checkpoint.LeaseKeyKey: {S: aws.String(shardID)},
+	}
+}
+
+func isConditionFailed(err error) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
@@ -16,28 +16,41 @@ package kinesis
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
 	"github.com/cenkalti/backoff/v4"
 	"github.com/google/uuid"
+	"github.com/vmware/vmware-go-kcl/clientlibrary/checkpoint"
 	"github.com/vmware/vmware-go-kcl/clientlibrary/config"
 	"github.com/vmware/vmware-go-kcl/clientlibrary/interfaces"
 	"github.com/vmware/vmware-go-kcl/clientlibrary/worker"
 
 	"github.com/dapr/components-contrib/bindings"
 	awsAuth "github.com/dapr/components-contrib/internal/authentication/aws"
+	"github.com/dapr/components-contrib/internal/logging"
+	"github.com/dapr/components-contrib/internal/poller"
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 )
 
 // AWSKinesis allows receiving and sending data to/from AWS Kinesis stream.
 type AWSKinesis struct {
-	client   *kinesis.Kinesis
-	metadata *kinesisMetadata
+	client      kinesisiface.KinesisAPI
+	metadata    *kinesisMetadata
+	checkpoints *checkpointStore
+
+	// State store of the checkpoints in extended fan-out mode without checkpoint table, and prefix of their keys
+	stateStores bindings.StateStoreResolver
+	cursors     poller.CursorStore
+	cursorKey   string
 
 	worker       *worker.Worker
 	workerConfig *config.KinesisClientLibConfiguration
@@ -45,17 +58,42 @@ type AWSKinesis struct {
 	streamARN   *string
 	consumerARN *string
 	logger      logger.Logger
+	// Owner of the leases of the shards read by this replica
+	workerID string
+
+	// Status of the shards in extended fan-out mode, and poller looking for the shards to read, which is triggered after
+	// one is read to its end
+	shards     map[string]shardStatus
+	shardsLock sync.Mutex
+	discovery  *poller.Poller
 }
 
+type shardStatus int
+
+const (
+	shardReading shardStatus = iota + 1
+	// Read to its end
+	shardFinished
+	// Closed before being read, when only the latest records are read
+	shardSkipped
+)
+
 type kinesisMetadata struct {
-	StreamName          string `json:"streamName"`
-	ConsumerName        string `json:"consumerName"`
-	Region              string `json:"region"`
-	Endpoint            string `json:"endpoint"`
-	AccessKey           string `json:"accessKey"`
-	SecretKey           string `json:"secretKey"`
-	SessionToken        string `json:"sessionToken"`
-	KinesisConsumerMode string `json:"mode" mapstructure:"mode"`
+	StreamName            string `json:"streamName"`
+	ConsumerName          string `json:"consumerName"`
+	Region                string `json:"region"`
+	Endpoint              string `json:"endpoint"`
+	AccessKey             string `json:"accessKey"`
+	SecretKey             string `json:"secretKey" mdsensitive:"true"`
+	SessionToken          string `json:"sessionToken" mdsensitive:"true"`
+	AssumeRoleArn         string `json:"assumeRoleArn"`
+	ExternalID            string `json:"externalId"`
+	AssumeRoleSessionName string `json:"assumeRoleSessionName"`
+	KinesisConsumerMode   string `json:"mode" mapstructure:"mode"`
+	// Position of the first record read in the shards without checkpoint: LATEST or TRIM_HORIZON
+	InitialPosition string `json:"initialPosition"`
+	// DynamoDB table storing the checkpoints in extended fan-out mode
+	CheckpointTable string `json:"checkpointTable"`
 }
 
 const (
@@ -66,6 +104,14 @@ const (
 	SharedThroughput = "shared"
 
 	partitionKeyName = "partitionKey"
+
+	// Interval between the lookups of new shards in extended fan-out mode.
+	shardDiscoveryInterval = time.Minute
+	// Maximum random delay added to the discovery interval, so that the replicas don't list the shards together.
+	shardDiscoveryJitter = 10 * time.Second
+	// Duration of the leases of the shards in extended fan-out mode, and interval of their renewal.
+	leaseDuration        = 30 * time.Second
+	leaseRenewalInterval = 10 * time.Second
 )
 
 // recordProcessorFactory.
@@ -83,11 +129,20 @@ type recordProcessor struct {
 
 // NewAWSKinesis returns a new AWS Kinesis instance.
 func NewAWSKinesis(logger logger.Logger) bindings.InputOutputBinding {
-	return &AWSKinesis{logger: logger}
+	return &AWSKinesis{
+		logger:   logger,
+		workerID: uuid.New().String(),
+		shards:   make(map[string]shardStatus),
+	}
 }
 
 // Init does metadata parsing and connection creation.
 func (a *AWSKinesis) Init(metadata bindings.Metadata) error {
+	a.logger = logging.ForComponent(a.logger, "bindings.aws.kinesis", metadata.Name)
+	if err := bindings.ValidateConfirmedDelivery(metadata.Properties); err != nil {
+		return err
+	}
+
 	m, err := a.parseMetadata(metadata)
 	if err != nil {
 		return err
@@ -101,10 +156,27 @@ func (a *AWSKinesis) Init(metadata bindings.Metadata) error {
 		return fmt.Errorf("%s invalid \"mode\" field %s", "aws.kinesis", m.KinesisConsumerMode)
 	}
 
-	client, err := a.getClient(m)
+	if m.InitialPosition == "" {
+		m.InitialPosition = kinesis.ShardIteratorTypeLatest
+	}
+
+	if m.InitialPosition != kinesis.ShardIteratorTypeLatest && m.InitialPosition != kinesis.ShardIteratorTypeTrimHorizon {
+		return fmt.Errorf("%s invalid \"initialPosition\" field %s", "aws.kinesis", m.InitialPosition)
+	}
+
+	a.cursors, a.cursorKey, err = poller.CursorStoreFromMetadata(metadata.Properties, metadata.Name, a.stateStores)
+	if err != nil {
+		return err
+	}
+	if a.cursors != nil && (m.KinesisConsumerMode != ExtendedFanout || m.CheckpointTable != "") {
+		return fmt.Errorf("aws.kinesis %s is only supported in %s mode without checkpointTable", poller.CursorStateStoreKey, ExtendedFanout)
+	}
+
+	sess, err := a.getSession(m)
 	if err != nil {
 		return err
 	}
+	client := kinesis.New(sess)
 
 	streamName := aws.String(m.StreamName)
 	stream, err := client.DescribeStream(&kinesis.DescribeStreamInput{
@@ -118,7 +190,12 @@ func (a *AWSKinesis) Init(metadata bindings.Metadata) error {
 		kclConfig := config.NewKinesisClientLibConfigWithCredential(m.ConsumerName,
 			m.StreamName, m.Region, m.ConsumerName,
 			credentials.NewStaticCredentials(m.AccessKey, m.SecretKey, ""))
+		if m.InitialPosition == kinesis.ShardIteratorTypeTrimHorizon {
+			kclConfig.WithInitialPositionInStream(config.TRIM_HORIZON)
+		}
 		a.workerConfig = kclConfig
+	} else if m.CheckpointTable != "" {
+		a.checkpoints = newCheckpointStore(dynamodb.New(sess), m.CheckpointTable)
 	}
 
 	a.streamARN = stream.StreamDescription.StreamARN
@@ -128,6 +205,11 @@ func (a *AWSKinesis) Init(metadata bindings.Metadata) error {
 	return nil
 }
 
+// SetStateStoreResolver sets the resolver of the state store of the checkpoints.
+func (a *AWSKinesis) SetStateStoreResolver(resolver bindings.StateStoreResolver) {
+	a.stateStores = resolver
+}
+
 func (a *AWSKinesis) Operations() []bindings.OperationKind {
 	return []bindings.OperationKind{bindings.CreateOperation}
 }
@@ -178,56 +260,331 @@ func (a *AWSKinesis) Read(ctx context.Context, handler bindings.Handler) (err er
 	return nil
 }
 
-// Subscribe to all shards.
+// Subscribe to all shards, and to the shards created by resharding once their parents are read to their end.
+// With a checkpoint table, the replicas lease the shards, so each shard is read by a single replica at a time.
 func (a *AWSKinesis) Subscribe(ctx context.Context, streamDesc kinesis.StreamDescription, handler bindings.Handler) error {
+	if a.checkpoints != nil {
+		if err := a.checkpoints.ensureTable(ctx); err != nil {
+			a.logger.Errorf("error creating the checkpoint table: %v", err)
+			return err
+		}
+	}
+
 	consumerARN, err := a.ensureConsumer(ctx, streamDesc.StreamARN)
 	if err != nil {
-		a.logger.Error(err)
+		a.logger.Errorf("error registering the stream consumer: %v", err)
 		return err
 	}
 
 	a.consumerARN = consumerARN
 
-	for i, shard := range streamDesc.Shards {
-		go func(idx int, s *kinesis.Shard) error {
-			// Reconnection backoff
-			bo := backoff.NewExponentialBackOff()
-			bo.InitialInterval = 2 * time.Second
-
-			// Repeat until context is canceled
-			for ctx.Err() == nil {
-				sub, err := a.client.SubscribeToShardWithContext(ctx, &kinesis.SubscribeToShardInput{
-					ConsumerARN:      consumerARN,
-					ShardId:          s.ShardId,
-					StartingPosition: &kinesis.StartingPosition{Type: aws.String(kinesis.ShardIteratorTypeLatest)},
-				})
-				if err != nil {
-					wait := bo.NextBackOff()
-					a.logger.Errorf("Error while reading from shard %v: %v. Attempting to reconnect in %s...", s.ShardId, err, wait)
-					time.Sleep(wait)
-					continue
-				}
+	// Look for new shards periodically, and as soon as a shard is read to its end
+	a.discovery = poller.New(func(ctx context.Context, _ string) (string, bool, error) {
+		positions, err := a.discoverShards(ctx)
+		if err != nil {
+			return "", false, fmt.Errorf("error while discovering shards: %w", err)
+		}
+		for shardID, position := range positions {
+			go a.readShard(ctx, consumerARN, shardID, position, handler)
+		}
+
+		return "", false, nil
+	}, poller.Options{Interval: shardDiscoveryInterval, Jitter: shardDiscoveryJitter}, a.logger)
+	go func() {
+		_ = a.discovery.Run(ctx)
+	}()
+
+	return nil
+}
+
+// discoverShards returns the starting position of the shards to start reading: those not read yet, whose parents, if they
+// are still in the stream, were read to their end, and whose lease is acquired if leases are used. The shards created by
+// resharding are read from their first record, and the shards with a checkpoint after it.
+func (a *AWSKinesis) discoverShards(ctx context.Context) (map[string]*kinesis.StartingPosition, error) {
+	shards, err := a.listShards(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	a.shardsLock.Lock()
+	defer a.shardsLock.Unlock()
+
+	listed := make(map[string]bool, len(shards))
+	checkpoints := make(map[string]string)
+	for _, s := range shards {
+		shardID := aws.StringValue(s.ShardId)
+		listed[shardID] = true
+		if _, ok := a.shards[shardID]; ok {
+			continue
+		}
+
+		sequenceNumber, err := a.getCheckpoint(ctx, shardID)
+		if err != nil {
+			return nil, err
+		}
+		closed := s.SequenceNumberRange != nil && s.SequenceNumberRange.EndingSequenceNumber != nil
+		switch {
+		case sequenceNumber == checkpoint.ShardEnd:
+			a.shards[shardID] = shardFinished
+		case sequenceNumber == "" && closed && a.metadata.InitialPosition == kinesis.ShardIteratorTypeLatest:
+			// No record will be added to the shard
+			a.shards[shardID] = shardSkipped
+		default:
+			checkpoints[shardID] = sequenceNumber
+		}
+	}
+
+	positions := make(map[string]*kinesis.StartingPosition)
+	for _, s := range shards {
+		shardID := aws.StringValue(s.ShardId)
+		sequenceNumber, ok := checkpoints[shardID]
+		if !ok {
+			continue
+		}
+
+		position := &kinesis.StartingPosition{Type: aws.String(a.metadata.InitialPosition)}
+		ready := true
+		for _, parent := range []*string{s.ParentShardId, s.AdjacentParentShardId} {
+			if parent == nil || !listed[*parent] {
+				continue
+			}
+			switch a.shards[*parent] {
+			case shardFinished:
+				position.Type = aws.String(kinesis.ShardIteratorTypeTrimHorizon)
+			case shardSkipped:
+			default:
+				ready = false
+			}
+		}
+		if !ready {
+			continue
+		}
+
+		if a.checkpoints != nil {
+			// The shard is read by the replica owning its lease, from the checkpoint of the previous owner
+			acquired, leaseCheckpoint, err := a.checkpoints.acquire(ctx, shardID, a.workerID, leaseDuration)
+			if err != nil {
+				return nil, err
+			}
+			if !acquired {
+				continue
+			}
+			sequenceNumber = leaseCheckpoint
+			if sequenceNumber == checkpoint.ShardEnd {
+				a.shards[shardID] = shardFinished
+				a.releaseLease(shardID)
+				continue
+			}
+		}
+
+		if sequenceNumber != "" {
+			position = &kinesis.StartingPosition{
+				Type:           aws.String(kinesis.ShardIteratorTypeAfterSequenceNumber),
+				SequenceNumber: aws.String(sequenceNumber),
+			}
+		}
+		a.shards[shardID] = shardReading
+		positions[shardID] = position
+	}
+
+	return positions, nil
+}
+
+func (a *AWSKinesis) listShards(ctx context.Context) ([]*kinesis.Shard, error) {
+	var shards []*kinesis.Shard
+	input := &kinesis.ListShardsInput{StreamName: &a.metadata.StreamName}
+	for {
+		out, err := a.client.ListShardsWithContext(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("error listing the shards of stream %s: %w", a.metadata.StreamName, err)
+		}
+		shards = append(shards, out.Shards...)
+		if out.NextToken == nil {
+			return shards, nil
+		}
+		input = &kinesis.ListShardsInput{NextToken: out.NextToken}
+	}
+}
 
-				// Reset the backoff on connection success
-				bo.Reset()
-
-				// Process events
-				for event := range sub.EventStream.Events() {
-					switch e := event.(type) {
-					case *kinesis.SubscribeToShardEvent:
-						for _, rec := range e.Records {
-							handler(ctx, &bindings.ReadResponse{
-								Data: rec.Data,
-							})
-						}
+// readShard reads a shard from position until ctx is canceled, the shard is read to its end or its lease is lost.
+func (a *AWSKinesis) readShard(ctx context.Context, consumerARN *string, shardID string, position *kinesis.StartingPosition, handler bindings.Handler) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if a.checkpoints != nil {
+		go a.renewLease(ctx, cancel, shardID)
+	}
+
+	// Reconnection backoff
+	bo := backoff.NewExponentialBackOff()
+	bo.InitialInterval = 2 * time.Second
+
+	// Repeat until context is canceled, as subscriptions expire after 5 minutes
+	for ctx.Err() == nil {
+		sub, err := a.client.SubscribeToShardWithContext(ctx, &kinesis.SubscribeToShardInput{
+			ConsumerARN:      consumerARN,
+			ShardId:          &shardID,
+			StartingPosition: position,
+		})
+		if err != nil {
+			wait := bo.NextBackOff()
+			a.logger.Errorf("Error while reading from shard %v: %v. Attempting to reconnect in %s...", shardID, err, wait)
+			time.Sleep(wait)
+			continue
+		}
+
+		// Reset the backoff on connection success
+		bo.Reset()
+
+		// Process events
+		for event := range sub.EventStream.Events() {
+			e, ok := event.(*kinesis.SubscribeToShardEvent)
+			if !ok {
+				continue
+			}
+			// The records are only checkpointed once handled, and the reading stops if a record is not handled, so it
+			// is read again from the checkpoint
+			handled := handleRecords(ctx, a.logger, e.Records, handler)
+			if handled != nil {
+				a.storeCheckpoint(ctx, shardID, aws.StringValue(handled))
+			}
+			if len(e.Records) > 0 && handled != e.Records[len(e.Records)-1].SequenceNumber {
+				if handled != nil {
+					position = &kinesis.StartingPosition{
+						Type:           aws.String(kinesis.ShardIteratorTypeAfterSequenceNumber),
+						SequenceNumber: handled,
 					}
 				}
+				break
+			}
+
+			if e.ContinuationSequenceNumber == nil {
+				// The shard was closed by a resharding, and all its records were read
+				sub.EventStream.Close()
+				a.finishShard(ctx, shardID)
+
+				return
+			}
+			position = &kinesis.StartingPosition{
+				Type:           aws.String(kinesis.ShardIteratorTypeAfterSequenceNumber),
+				SequenceNumber: e.ContinuationSequenceNumber,
 			}
-			return nil
-		}(i, shard)
+		}
+		sub.EventStream.Close()
 	}
 
-	return nil
+	// The shard can be read again once discovered, by this replica or another one
+	a.shardsLock.Lock()
+	delete(a.shards, shardID)
+	a.shardsLock.Unlock()
+	a.releaseLease(shardID)
+}
+
+// handleRecords delivers the records to the handler in order, retrying each record until it is handled or ctx is
+// canceled. It returns the sequence number of the last record handled, or nil if none was.
+func handleRecords(ctx context.Context, logger logger.Logger, records []*kinesis.Record, handler bindings.Handler) *string {
+	var handled *string
+	for _, rec := range records {
+		bo := backoff.NewExponentialBackOff()
+		bo.InitialInterval = time.Second
+		bo.MaxInterval = 30 * time.Second
+		// Retry until ctx is canceled
+		bo.MaxElapsedTime = 0
+		err := backoff.RetryNotify(func() error {
+			_, err := handler(ctx, &bindings.ReadResponse{
+				Data: rec.Data,
+			})
+			return err
+		}, backoff.WithContext(bo, ctx), func(err error, wait time.Duration) {
+			logger.Errorf("Error handling record %s: %v. Retrying in %s...", aws.StringValue(rec.SequenceNumber), err, wait)
+		})
+		if err != nil {
+			return handled
+		}
+		handled = rec.SequenceNumber
+	}
+
+	return handled
+}
+
+// renewLease renews the lease of the shard until ctx is canceled, calling cancel if the lease is lost.
+func (a *AWSKinesis) renewLease(ctx context.Context, cancel context.CancelFunc, shardID string) {
+	t := time.NewTicker(leaseRenewalInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			acquired, _, err := a.checkpoints.acquire(ctx, shardID, a.workerID, leaseDuration)
+			if err != nil && ctx.Err() == nil {
+				// The lease is still valid until it times out
+				a.logger.Warn(err)
+				continue
+			}
+			if !acquired {
+				a.logger.Infof("Lease of shard %s taken by another consumer, stopping reading it", shardID)
+				cancel()
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// releaseLease releases the lease of the shard, if leases are used.
+func (a *AWSKinesis) releaseLease(shardID string) {
+	if a.checkpoints == nil {
+		return
+	}
+
+	// Use a background context because the running context may have been canceled already
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := a.checkpoints.release(ctx, shardID, a.workerID); err != nil {
+		a.logger.Warn(err)
+	}
+}
+
+// finishShard records that a shard was read to its end, so that its children can be read.
+func (a *AWSKinesis) finishShard(ctx context.Context, shardID string) {
+	a.storeCheckpoint(ctx, shardID, checkpoint.ShardEnd)
+	a.releaseLease(shardID)
+
+	a.shardsLock.Lock()
+	a.shards[shardID] = shardFinished
+	a.shardsLock.Unlock()
+
+	if a.discovery != nil {
+		a.discovery.Trigger()
+	}
+}
+
+func (a *AWSKinesis) getCheckpoint(ctx context.Context, shardID string) (string, error) {
+	switch {
+	case a.checkpoints != nil:
+		return a.checkpoints.get(ctx, shardID)
+	case a.cursors != nil:
+		return a.cursors.GetCursor(ctx, a.shardCursorKey(shardID))
+	default:
+		return "", nil
+	}
+}
+
+func (a *AWSKinesis) storeCheckpoint(ctx context.Context, shardID string, sequenceNumber string) {
+	var err error
+	switch {
+	case a.checkpoints != nil:
+		err = a.checkpoints.set(ctx, shardID, a.workerID, sequenceNumber)
+	case a.cursors != nil:
+		err = a.cursors.SetCursor(ctx, a.shardCursorKey(shardID), sequenceNumber)
+	}
+	if err != nil {
+		a.logger.Warn(err)
+	}
+}
+
+// shardCursorKey returns the key of the checkpoint of the shard in the state store.
+func (a *AWSKinesis) shardCursorKey(shardID string) string {
+	return a.cursorKey + "||" + shardID
 }
 
 func (a *AWSKinesis) ensureConsumer(parentCtx context.Context, streamARN *string) (*string, error) {
@@ -312,14 +669,19 @@ func (a *AWSKinesis) waitUntilConsumerExists(ctx aws.Context, input *kinesis.Des
 	return w.WaitWithContext(ctx)
 }
 
-func (a *AWSKinesis) getClient(metadata *kinesisMetadata) (*kinesis.Kinesis, error) {
-	sess, err := awsAuth.GetClient(metadata.AccessKey, metadata.SecretKey, metadata.SessionToken, metadata.Region, metadata.Endpoint)
-	if err != nil {
-		return nil, err
-	}
-	k := kinesis.New(sess)
-
-	return k, nil
+func (a *AWSKinesis) getSession(metadata *kinesisMetadata) (*session.Session, error) {
+	return awsAuth.NewSession(awsAuth.Options{
+		AccessKey:    metadata.AccessKey,
+		SecretKey:    metadata.SecretKey,
+		SessionToken: metadata.SessionToken,
+		Region:       metadata.Region,
+		Endpoint:     metadata.Endpoint,
+		AssumeRole: awsAuth.AssumeRole{
+			RoleARN:     metadata.AssumeRoleArn,
+			ExternalID:  metadata.ExternalID,
+			SessionName: metadata.AssumeRoleSessionName,
+		},
+	})
 }
 
 func (a *AWSKinesis) parseMetadata(meta bindings.Metadata) (*kinesisMetadata, error) {
@@ -357,15 +719,12 @@ func (p *recordProcessor) ProcessRecords(input *interfaces.ProcessRecordsInput)
 		return
 	}
 
-	for _, v := range input.Records {
-		p.handler(p.ctx, &bindings.ReadResponse{
-			Data: v.Data,
-		})
+	// Only the records handled are checkpointed: if the context is canceled before a record is handled, the records
+	// are read again from the checkpoint by the next owner of the lease of the shard
+	handled := handleRecords(p.ctx, p.logger, input.Records, p.handler)
+	if handled != nil {
+		input.Checkpointer.Checkpoint(handled)
 	}
-
-	// checkpoint it after processing this batch
-	lastRecordSequenceNumber := input.Records[len(input.Records)-1].SequenceNumber
-	input.Checkpointer.Checkpoint(lastRecordSequenceNumber)
 }
 
 func (p *recordProcessor) Shutdown(input *interfaces.ShutdownInput) {
@@ -373,3 +732,12 @@ func (p *recordProcessor) Shutdown(input *interfaces.ShutdownInput) {
 		input.Checkpointer.Checkpoint(nil)
 	}
 }
+
+// GetComponentMetadataSchema returns the schema of the metadata of the AWS Kinesis binding.
+func (a *AWSKinesis) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(kinesisMetadata{
+		KinesisConsumerMode: SharedThroughput,
+		InitialPosition:     kinesis.ShardIteratorTypeLatest,
+	})
+	return fields
+}
@@ -14,24 +14,131 @@ limitations under the License.
 package kinesis
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/internal/poller"
+	"github.com/dapr/components-contrib/state"
+	stateInMemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/kit/logger"
 )
 
+type mockedKinesis struct {
+	ListShardsFn func(context.Context, *kinesis.ListShardsInput, ...request.Option) (*kinesis.ListShardsOutput, error)
+	kinesisiface.KinesisAPI
+}
+
+func (m *mockedKinesis) ListShardsWithContext(ctx context.Context, input *kinesis.ListShardsInput, option ...request.Option) (*kinesis.ListShardsOutput, error) {
+	return m.ListShardsFn(ctx, input, option...)
+}
+
+// mockedDynamoDB stores the items of the checkpoint table in memory, by shard ID.
+type mockedDynamoDB struct {
+	items map[string]map[string]string
+	dynamodbiface.DynamoDBAPI
+}
+
+func newMockedDynamoDB(checkpoints map[string]string) *mockedDynamoDB {
+	m := &mockedDynamoDB{items: map[string]map[string]string{}}
+	for shardID, sequenceNumber := range checkpoints {
+		m.items[shardID] = map[string]string{"Checkpoint": sequenceNumber}
+	}
+
+	return m
+}
+
+func (m *mockedDynamoDB) checkpoints() map[string]string {
+	checkpoints := map[string]string{}
+	for shardID, item := range m.items {
+		if v, ok := item["Checkpoint"]; ok {
+			checkpoints[shardID] = v
+		}
+	}
+
+	return checkpoints
+}
+
+func (m *mockedDynamoDB) GetItemWithContext(ctx context.Context, input *dynamodb.GetItemInput, option ...request.Option) (*dynamodb.GetItemOutput, error) {
+	out := &dynamodb.GetItemOutput{}
+	if item, ok := m.items[aws.StringValue(input.Key["ShardID"].S)]; ok {
+		out.Item = map[string]*dynamodb.AttributeValue{}
+		for k, v := range item {
+			out.Item[k] = &dynamodb.AttributeValue{S: aws.String(v)}
+		}
+	}
+
+	return out, nil
+}
+
+// UpdateItemWithContext supports the updates of the checkpoint store, checking the owner of the lease of the shard.
+func (m *mockedDynamoDB) UpdateItemWithContext(ctx context.Context, input *dynamodb.UpdateItemInput, option ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	shardID := aws.StringValue(input.Key["ShardID"].S)
+	item, ok := m.items[shardID]
+	if !ok {
+		item = map[string]string{}
+	}
+	value := func(name string) string {
+		return aws.StringValue(input.ExpressionAttributeValues[name].S)
+	}
+	conditionFailed := awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "condition failed", nil)
+
+	switch {
+	case strings.HasPrefix(*input.UpdateExpression, "SET #owner"):
+		owner, leased := item["AssignedTo"]
+		if leased && owner != value(":owner") && item["LeaseTimeout"] >= value(":now") {
+			return nil, conditionFailed
+		}
+		item["AssignedTo"] = value(":owner")
+		item["LeaseTimeout"] = value(":timeout")
+	case strings.HasPrefix(*input.UpdateExpression, "REMOVE"):
+		if item["AssignedTo"] != value(":owner") {
+			return nil, conditionFailed
+		}
+		delete(item, "AssignedTo")
+		delete(item, "LeaseTimeout")
+	default:
+		if input.ConditionExpression != nil && item["AssignedTo"] != value(":owner") {
+			return nil, conditionFailed
+		}
+		item["Checkpoint"] = value(":checkpoint")
+	}
+	m.items[shardID] = item
+
+	out := &dynamodb.UpdateItemOutput{Attributes: map[string]*dynamodb.AttributeValue{}}
+	for k, v := range item {
+		out.Attributes[k] = &dynamodb.AttributeValue{S: aws.String(v)}
+	}
+
+	return out, nil
+}
+
 func TestParseMetadata(t *testing.T) {
 	m := bindings.Metadata{}
 	m.Properties = map[string]string{
-		"accessKey":    "key",
-		"region":       "region",
-		"secretKey":    "secret",
-		"consumerName": "test",
-		"streamName":   "stream",
-		"mode":         "extended",
-		"endpoint":     "endpoint",
-		"sessionToken": "token",
+		"accessKey":       "key",
+		"region":          "region",
+		"secretKey":       "secret",
+		"consumerName":    "test",
+		"streamName":      "stream",
+		"mode":            "extended",
+		"endpoint":        "endpoint",
+		"sessionToken":    "token",
+		"initialPosition": "TRIM_HORIZON",
+		"checkpointTable": "checkpoints",
 	}
 	kinesis := AWSKinesis{}
 	meta, err := kinesis.parseMetadata(m)
@@ -44,4 +151,290 @@ func TestParseMetadata(t *testing.T) {
 	assert.Equal(t, "endpoint", meta.Endpoint)
 	assert.Equal(t, "token", meta.SessionToken)
 	assert.Equal(t, "extended", meta.KinesisConsumerMode)
+	assert.Equal(t, "TRIM_HORIZON", meta.InitialPosition)
+	assert.Equal(t, "checkpoints", meta.CheckpointTable)
+}
+
+func TestCheckpointStore(t *testing.T) {
+	ctx := context.Background()
+	store := newCheckpointStore(newMockedDynamoDB(nil), "checkpoints")
+
+	sequenceNumber, err := store.get(ctx, "shard-1")
+	require.NoError(t, err)
+	assert.Empty(t, sequenceNumber)
+
+	require.NoError(t, store.set(ctx, "shard-1", "", "42"))
+	sequenceNumber, err = store.get(ctx, "shard-1")
+	require.NoError(t, err)
+	assert.Equal(t, "42", sequenceNumber)
+
+	t.Run("leases", func(t *testing.T) {
+		acquired, sequenceNumber, err := store.acquire(ctx, "shard-1", "worker-1", time.Minute)
+		require.NoError(t, err)
+		assert.True(t, acquired)
+		assert.Equal(t, "42", sequenceNumber)
+
+		// Leased by another worker
+		acquired, _, err = store.acquire(ctx, "shard-1", "worker-2", time.Minute)
+		require.NoError(t, err)
+		assert.False(t, acquired)
+		assert.Error(t, store.set(ctx, "shard-1", "worker-2", "43"))
+
+		// Renewed by its owner
+		acquired, _, err = store.acquire(ctx, "shard-1", "worker-1", time.Minute)
+		require.NoError(t, err)
+		assert.True(t, acquired)
+		require.NoError(t, store.set(ctx, "shard-1", "worker-1", "43"))
+
+		// Taken over once released
+		require.NoError(t, store.release(ctx, "shard-1", "worker-1"))
+		acquired, sequenceNumber, err = store.acquire(ctx, "shard-1", "worker-2", time.Minute)
+		require.NoError(t, err)
+		assert.True(t, acquired)
+		assert.Equal(t, "43", sequenceNumber)
+	})
+
+	t.Run("expired leases are taken over", func(t *testing.T) {
+		acquired, _, err := store.acquire(ctx, "shard-2", "worker-1", -time.Minute)
+		require.NoError(t, err)
+		require.True(t, acquired)
+
+		acquired, _, err = store.acquire(ctx, "shard-2", "worker-2", time.Minute)
+		require.NoError(t, err)
+		assert.True(t, acquired)
+	})
+}
+
+func TestDiscoverShards(t *testing.T) {
+	shard := func(id string, closed bool, parents ...string) *kinesis.Shard {
+		s := &kinesis.Shard{
+			ShardId:             aws.String(id),
+			SequenceNumberRange: &kinesis.SequenceNumberRange{StartingSequenceNumber: aws.String("1")},
+		}
+		if closed {
+			s.SequenceNumberRange.EndingSequenceNumber = aws.String("100")
+		}
+		if len(parents) > 0 {
+			s.ParentShardId = aws.String(parents[0])
+		}
+		if len(parents) > 1 {
+			s.AdjacentParentShardId = aws.String(parents[1])
+		}
+
+		return s
+	}
+	newKinesis := func(initialPosition string, checkpoints map[string]string, shards ...*kinesis.Shard) *AWSKinesis {
+		a := NewAWSKinesis(logger.NewLogger("test")).(*AWSKinesis)
+		a.metadata = &kinesisMetadata{StreamName: "stream", InitialPosition: initialPosition}
+		a.client = &mockedKinesis{
+			ListShardsFn: func(ctx context.Context, input *kinesis.ListShardsInput, option ...request.Option) (*kinesis.ListShardsOutput, error) {
+				// Return a shard per page
+				i := 0
+				if input.NextToken != nil {
+					i = int((*input.NextToken)[0] - '0')
+				} else {
+					assert.Equal(t, "stream", *input.StreamName)
+				}
+				out := &kinesis.ListShardsOutput{Shards: shards[i : i+1]}
+				if i+1 < len(shards) {
+					out.NextToken = aws.String(string(rune('0' + i + 1)))
+				}
+
+				return out, nil
+			},
+		}
+		if checkpoints != nil {
+			a.checkpoints = newCheckpointStore(newMockedDynamoDB(checkpoints), "checkpoints")
+		}
+
+		return a
+	}
+
+	t.Run("children are read after their parents", func(t *testing.T) {
+		a := newKinesis(kinesis.ShardIteratorTypeLatest, nil,
+			shard("parent", false), shard("child-1", false, "parent"), shard("child-2", false, "parent"))
+
+		positions, err := a.discoverShards(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, map[string]*kinesis.StartingPosition{
+			"parent": {Type: aws.String(kinesis.ShardIteratorTypeLatest)},
+		}, positions)
+
+		// The parent is read to its end
+		a.finishShard(context.Background(), "parent")
+		positions, err = a.discoverShards(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, map[string]*kinesis.StartingPosition{
+			"child-1": {Type: aws.String(kinesis.ShardIteratorTypeTrimHorizon)},
+			"child-2": {Type: aws.String(kinesis.ShardIteratorTypeTrimHorizon)},
+		}, positions)
+
+		// Nothing left to read
+		positions, err = a.discoverShards(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, positions)
+	})
+
+	t.Run("closed shards are skipped when reading the latest records", func(t *testing.T) {
+		a := newKinesis(kinesis.ShardIteratorTypeLatest, nil,
+			shard("parent-1", true), shard("parent-2", true), shard("child", false, "parent-1", "parent-2"))
+
+		positions, err := a.discoverShards(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, map[string]*kinesis.StartingPosition{
+			"child": {Type: aws.String(kinesis.ShardIteratorTypeLatest)},
+		}, positions)
+	})
+
+	t.Run("closed shards are read from the start", func(t *testing.T) {
+		a := newKinesis(kinesis.ShardIteratorTypeTrimHorizon, nil,
+			shard("parent", true), shard("child", false, "parent"))
+
+		positions, err := a.discoverShards(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, map[string]*kinesis.StartingPosition{
+			"parent": {Type: aws.String(kinesis.ShardIteratorTypeTrimHorizon)},
+		}, positions)
+	})
+
+	t.Run("reading resumes at the checkpoints", func(t *testing.T) {
+		checkpoints := map[string]string{"parent": "SHARD_END", "child-1": "42"}
+		a := newKinesis(kinesis.ShardIteratorTypeLatest, checkpoints,
+			shard("parent", true), shard("child-1", false, "parent"), shard("child-2", false, "parent"))
+
+		positions, err := a.discoverShards(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, map[string]*kinesis.StartingPosition{
+			"child-1": {Type: aws.String(kinesis.ShardIteratorTypeAfterSequenceNumber), SequenceNumber: aws.String("42")},
+			"child-2": {Type: aws.String(kinesis.ShardIteratorTypeTrimHorizon)},
+		}, positions)
+	})
+
+	t.Run("reading the end of a shard is checkpointed", func(t *testing.T) {
+		a := newKinesis(kinesis.ShardIteratorTypeLatest, map[string]string{}, shard("parent", false))
+		_, err := a.discoverShards(context.Background())
+		require.NoError(t, err)
+
+		a.finishShard(context.Background(), "parent")
+
+		assert.Equal(t, map[string]string{"parent": "SHARD_END"}, a.checkpoints.client.(*mockedDynamoDB).checkpoints())
+	})
+
+	t.Run("shards leased by another replica are not read", func(t *testing.T) {
+		a := newKinesis(kinesis.ShardIteratorTypeLatest, map[string]string{}, shard("shard-1", false), shard("shard-2", false))
+		other := newKinesis(kinesis.ShardIteratorTypeLatest, nil, shard("shard-1", false), shard("shard-2", false))
+		other.checkpoints = a.checkpoints
+		_, _, err := a.checkpoints.acquire(context.Background(), "shard-1", other.workerID, time.Minute)
+		require.NoError(t, err)
+
+		positions, err := a.discoverShards(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, map[string]*kinesis.StartingPosition{
+			"shard-2": {Type: aws.String(kinesis.ShardIteratorTypeLatest)},
+		}, positions)
+
+		// Read from the checkpoint of the other replica once released
+		require.NoError(t, a.checkpoints.set(context.Background(), "shard-1", other.workerID, "42"))
+		other.releaseLease("shard-1")
+		positions, err = a.discoverShards(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, map[string]*kinesis.StartingPosition{
+			"shard-1": {Type: aws.String(kinesis.ShardIteratorTypeAfterSequenceNumber), SequenceNumber: aws.String("42")},
+		}, positions)
+	})
+}
+
+func TestStateStoreCheckpoints(t *testing.T) {
+	store := stateInMemory.NewInMemoryStateStore(logger.NewLogger("test"))
+	require.NoError(t, store.Init(state.Metadata{}))
+	resolver := func(name string) (state.Store, error) {
+		return store, nil
+	}
+
+	t.Run("reading resumes at the checkpoints of the state store", func(t *testing.T) {
+		require.NoError(t, store.Set(&state.SetRequest{Key: "orders||shard-1", Value: []byte("42")}))
+		a := NewAWSKinesis(logger.NewLogger("test")).(*AWSKinesis)
+		a.metadata = &kinesisMetadata{StreamName: "stream", InitialPosition: kinesis.ShardIteratorTypeLatest}
+		a.client = &mockedKinesis{
+			ListShardsFn: func(ctx context.Context, input *kinesis.ListShardsInput, option ...request.Option) (*kinesis.ListShardsOutput, error) {
+				return &kinesis.ListShardsOutput{Shards: []*kinesis.Shard{
+					{ShardId: aws.String("shard-1"), SequenceNumberRange: &kinesis.SequenceNumberRange{StartingSequenceNumber: aws.String("1")}},
+					{ShardId: aws.String("shard-2"), SequenceNumberRange: &kinesis.SequenceNumberRange{StartingSequenceNumber: aws.String("1")}},
+				}}, nil
+			},
+		}
+		a.SetStateStoreResolver(resolver)
+		var err error
+		a.cursors, a.cursorKey, err = poller.CursorStoreFromMetadata(map[string]string{"cursorStateStore": "statestore", "cursorKey": "orders"}, "kinesis", a.stateStores)
+		require.NoError(t, err)
+
+		positions, err := a.discoverShards(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, map[string]*kinesis.StartingPosition{
+			"shard-1": {Type: aws.String(kinesis.ShardIteratorTypeAfterSequenceNumber), SequenceNumber: aws.String("42")},
+			"shard-2": {Type: aws.String(kinesis.ShardIteratorTypeLatest)},
+		}, positions)
+
+		a.storeCheckpoint(context.Background(), "shard-2", "7")
+		res, err := store.Get(&state.GetRequest{Key: "orders||shard-2"})
+		require.NoError(t, err)
+		assert.Equal(t, "7", string(res.Data))
+	})
+
+	t.Run("not supported with a checkpoint table", func(t *testing.T) {
+		a := NewAWSKinesis(logger.NewLogger("test")).(*AWSKinesis)
+		a.SetStateStoreResolver(resolver)
+		m := bindings.Metadata{}
+		m.Name = "kinesis"
+		m.Properties = map[string]string{
+			"streamName":       "stream",
+			"mode":             "extended",
+			"checkpointTable":  "checkpoints",
+			"cursorStateStore": "statestore",
+		}
+
+		err := a.Init(m)
+
+		assert.EqualError(t, err, "aws.kinesis cursorStateStore is only supported in extended mode without checkpointTable")
+	})
+}
+
+func TestHandleRecords(t *testing.T) {
+	records := []*kinesis.Record{
+		{Data: []byte("a"), SequenceNumber: aws.String("1")},
+		{Data: []byte("b"), SequenceNumber: aws.String("2")},
+		{Data: []byte("c"), SequenceNumber: aws.String("3")},
+	}
+
+	t.Run("failed records are retried", func(t *testing.T) {
+		var received []string
+		failed := false
+		handled := handleRecords(context.Background(), logger.NewLogger("test"), records, func(ctx context.Context, msg *bindings.ReadResponse) ([]byte, error) {
+			if string(msg.Data) == "b" && !failed {
+				failed = true
+				return nil, errors.New("failed")
+			}
+			received = append(received, string(msg.Data))
+			return nil, nil
+		})
+
+		assert.Equal(t, "3", aws.StringValue(handled))
+		assert.Equal(t, []string{"a", "b", "c"}, received)
+	})
+
+	t.Run("records not handled are not checkpointed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		handled := handleRecords(ctx, logger.NewLogger("test"), records, func(ctx context.Context, msg *bindings.ReadResponse) ([]byte, error) {
+			if string(msg.Data) == "b" {
+				cancel()
+				return nil, errors.New("failed")
+			}
+			return nil, nil
+		})
+
+		assert.Equal(t, "1", aws.StringValue(handled))
+	})
 }
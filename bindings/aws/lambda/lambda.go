@@ -0,0 +1,213 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+
+	"github.com/dapr/components-contrib/bindings"
+	awsAuth "github.com/dapr/components-contrib/internal/authentication/aws"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	metadataFunctionName   = "functionName"
+	metadataQualifier      = "qualifier"
+	metadataInvocationType = "invocationType"
+
+	metadataStatusCode      = "statusCode"
+	metadataExecutedVersion = "executedVersion"
+)
+
+// AWSLambda is an AWS Lambda binding.
+type AWSLambda struct {
+	client   lambdaiface.LambdaAPI
+	metadata *lambdaMetadata
+
+	logger logger.Logger
+}
+
+type lambdaMetadata struct {
+	FunctionName          string `json:"functionName"`
+	Qualifier             string `json:"qualifier"`
+	InvocationType        string `json:"invocationType"`
+	Region                string `json:"region"`
+	Endpoint              string `json:"endpoint"`
+	AccessKey             string `json:"accessKey"`
+	SecretKey             string `json:"secretKey" mdsensitive:"true"`
+	SessionToken          string `json:"sessionToken" mdsensitive:"true"`
+	AssumeRoleArn         string `json:"assumeRoleArn"`
+	ExternalID            string `json:"externalId"`
+	AssumeRoleSessionName string `json:"assumeRoleSessionName"`
+}
+
+// NewAWSLambda creates a new AWSLambda binding instance.
+func NewAWSLambda(logger logger.Logger) bindings.OutputBinding {
+	return &AWSLambda{logger: logger}
+}
+
+// Init does metadata parsing and client creation.
+func (a *AWSLambda) Init(metadata bindings.Metadata) error {
+	m, err := a.parseMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	client, err := a.getClient(m)
+	if err != nil {
+		return err
+	}
+	a.client = client
+	a.metadata = m
+
+	return nil
+}
+
+func (a *AWSLambda) parseMetadata(metadata bindings.Metadata) (*lambdaMetadata, error) {
+	b, err := json.Marshal(metadata.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	var m lambdaMetadata
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.InvocationType == "" {
+		m.InvocationType = lambda.InvocationTypeRequestResponse
+	}
+	if err = validateInvocationType(m.InvocationType); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+func validateInvocationType(invocationType string) error {
+	for _, v := range lambda.InvocationType_Values() {
+		if v == invocationType {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("lambda binding error: invalid invocationType %q", invocationType)
+}
+
+func (a *AWSLambda) getClient(metadata *lambdaMetadata) (*lambda.Lambda, error) {
+	sess, err := awsAuth.NewSession(awsAuth.Options{
+		AccessKey:    metadata.AccessKey,
+		SecretKey:    metadata.SecretKey,
+		SessionToken: metadata.SessionToken,
+		Region:       metadata.Region,
+		Endpoint:     metadata.Endpoint,
+		AssumeRole: awsAuth.AssumeRole{
+			RoleARN:     metadata.AssumeRoleArn,
+			ExternalID:  metadata.ExternalID,
+			SessionName: metadata.AssumeRoleSessionName,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	c := lambda.New(sess)
+
+	return c, nil
+}
+
+func (a *AWSLambda) Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{bindings.CreateOperation}
+}
+
+// Invoke invokes the function with the request data as payload.
+// The function, its qualifier and the invocation type can be set in the request metadata.
+func (a *AWSLambda) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	metadata, err := a.metadata.mergeWithRequestMetadata(req)
+	if err != nil {
+		return nil, err
+	}
+	if metadata.FunctionName == "" {
+		return nil, errors.New("lambda binding error: functionName property not supplied in configuration- or request-metadata")
+	}
+
+	input := &lambda.InvokeInput{
+		FunctionName:   aws.String(metadata.FunctionName),
+		InvocationType: aws.String(metadata.InvocationType),
+		Payload:        req.Data,
+	}
+	if metadata.Qualifier != "" {
+		input.Qualifier = aws.String(metadata.Qualifier)
+	}
+
+	out, err := a.client.InvokeWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("lambda binding error: error invoking function %s: %w", metadata.FunctionName, err)
+	}
+	if out.FunctionError != nil {
+		return nil, fmt.Errorf("lambda binding error: function %s returned an error (%s): %s", metadata.FunctionName, *out.FunctionError, string(out.Payload))
+	}
+
+	resMetadata := map[string]string{
+		metadataStatusCode: strconv.FormatInt(aws.Int64Value(out.StatusCode), 10),
+	}
+	if out.ExecutedVersion != nil {
+		resMetadata[metadataExecutedVersion] = *out.ExecutedVersion
+	}
+
+	return &bindings.InvokeResponse{
+		Data:     out.Payload,
+		Metadata: resMetadata,
+	}, nil
+}
+
+// Helper to merge config and request metadata.
+func (metadata lambdaMetadata) mergeWithRequestMetadata(req *bindings.InvokeRequest) (lambdaMetadata, error) {
+	merged := metadata
+
+	if val, ok := req.Metadata[metadataFunctionName]; ok && val != "" {
+		merged.FunctionName = val
+		// The qualifier of the component refers to its function
+		merged.Qualifier = ""
+	}
+
+	if val, ok := req.Metadata[metadataQualifier]; ok && val != "" {
+		merged.Qualifier = val
+	}
+
+	if val, ok := req.Metadata[metadataInvocationType]; ok && val != "" {
+		if err := validateInvocationType(val); err != nil {
+			return merged, err
+		}
+		merged.InvocationType = val
+	}
+
+	return merged, nil
+}
+
+// GetComponentMetadataSchema returns the schema of the metadata of the AWS Lambda binding.
+func (a *AWSLambda) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(lambdaMetadata{
+		InvocationType: lambda.InvocationTypeRequestResponse,
+	})
+	return fields
+}
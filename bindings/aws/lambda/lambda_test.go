@@ -0,0 +1,164 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lambda
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/kit/logger"
+)
+
+type mockedLambda struct {
+	InvokeFn func(context.Context, *lambda.InvokeInput, ...request.Option) (*lambda.InvokeOutput, error)
+	lambdaiface.LambdaAPI
+}
+
+func (m *mockedLambda) InvokeWithContext(ctx context.Context, input *lambda.InvokeInput, option ...request.Option) (*lambda.InvokeOutput, error) {
+	return m.InvokeFn(ctx, input, option...)
+}
+
+func TestParseMetadata(t *testing.T) {
+	t.Run("Has correct metadata", func(t *testing.T) {
+		m := bindings.Metadata{}
+		m.Properties = map[string]string{
+			"functionName": "hello", "qualifier": "prod", "invocationType": "Event", "region": "region", "accessKey": "key", "secretKey": "secret",
+		}
+		a := AWSLambda{}
+		meta, err := a.parseMetadata(m)
+		assert.Nil(t, err)
+		assert.Equal(t, "hello", meta.FunctionName)
+		assert.Equal(t, "prod", meta.Qualifier)
+		assert.Equal(t, "Event", meta.InvocationType)
+		assert.Equal(t, "region", meta.Region)
+		assert.Equal(t, "key", meta.AccessKey)
+		assert.Equal(t, "secret", meta.SecretKey)
+	})
+
+	t.Run("Defaults to synchronous invocation", func(t *testing.T) {
+		m := bindings.Metadata{}
+		m.Properties = map[string]string{"functionName": "hello"}
+		a := AWSLambda{}
+		meta, err := a.parseMetadata(m)
+		assert.Nil(t, err)
+		assert.Equal(t, lambda.InvocationTypeRequestResponse, meta.InvocationType)
+	})
+
+	t.Run("Has invalid invocation type", func(t *testing.T) {
+		m := bindings.Metadata{}
+		m.Properties = map[string]string{"functionName": "hello", "invocationType": "Later"}
+		a := AWSLambda{}
+		_, err := a.parseMetadata(m)
+		assert.Error(t, err)
+	})
+}
+
+func TestInvoke(t *testing.T) {
+	newLambda := func(fn func(context.Context, *lambda.InvokeInput, ...request.Option) (*lambda.InvokeOutput, error)) *AWSLambda {
+		return &AWSLambda{
+			client: &mockedLambda{InvokeFn: fn},
+			metadata: &lambdaMetadata{
+				FunctionName:   "hello",
+				Qualifier:      "prod",
+				InvocationType: lambda.InvocationTypeRequestResponse,
+			},
+			logger: logger.NewLogger("test"),
+		}
+	}
+
+	t.Run("synchronous invocation", func(t *testing.T) {
+		a := newLambda(func(ctx context.Context, input *lambda.InvokeInput, option ...request.Option) (*lambda.InvokeOutput, error) {
+			assert.Equal(t, "hello", *input.FunctionName)
+			assert.Equal(t, "prod", *input.Qualifier)
+			assert.Equal(t, lambda.InvocationTypeRequestResponse, *input.InvocationType)
+			assert.Equal(t, `{"name":"dapr"}`, string(input.Payload))
+
+			return &lambda.InvokeOutput{
+				StatusCode:      aws.Int64(200),
+				ExecutedVersion: aws.String("3"),
+				Payload:         []byte(`"hello dapr"`),
+			}, nil
+		})
+
+		res, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      []byte(`{"name":"dapr"}`),
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, `"hello dapr"`, string(res.Data))
+		assert.Equal(t, map[string]string{"statusCode": "200", "executedVersion": "3"}, res.Metadata)
+	})
+
+	t.Run("asynchronous invocation of another function", func(t *testing.T) {
+		a := newLambda(func(ctx context.Context, input *lambda.InvokeInput, option ...request.Option) (*lambda.InvokeOutput, error) {
+			assert.Equal(t, "other", *input.FunctionName)
+			assert.Nil(t, input.Qualifier)
+			assert.Equal(t, lambda.InvocationTypeEvent, *input.InvocationType)
+
+			return &lambda.InvokeOutput{StatusCode: aws.Int64(202)}, nil
+		})
+
+		res, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Metadata:  map[string]string{"functionName": "other", "invocationType": "Event"},
+		})
+
+		assert.Nil(t, err)
+		assert.Empty(t, res.Data)
+		assert.Equal(t, "202", res.Metadata["statusCode"])
+	})
+
+	t.Run("invalid invocation type", func(t *testing.T) {
+		a := newLambda(nil)
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Metadata: map[string]string{"invocationType": "Later"},
+		})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("function error", func(t *testing.T) {
+		a := newLambda(func(ctx context.Context, input *lambda.InvokeInput, option ...request.Option) (*lambda.InvokeOutput, error) {
+			return &lambda.InvokeOutput{
+				StatusCode:    aws.Int64(200),
+				FunctionError: aws.String("Unhandled"),
+				Payload:       []byte(`{"errorMessage":"boom"}`),
+			}, nil
+		})
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{})
+
+		assert.ErrorContains(t, err, "boom")
+	})
+
+	t.Run("invocation error", func(t *testing.T) {
+		a := newLambda(func(ctx context.Context, input *lambda.InvokeInput, option ...request.Option) (*lambda.InvokeOutput, error) {
+			return nil, errors.New("throttled")
+		})
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{})
+
+		assert.ErrorContains(t, err, "throttled")
+	})
+}
@@ -0,0 +1,417 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redshiftdata
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/redshiftdataapiservice"
+	"github.com/aws/aws-sdk-go/service/redshiftdataapiservice/redshiftdataapiserviceiface"
+
+	"github.com/dapr/components-contrib/bindings"
+	awsAuth "github.com/dapr/components-contrib/internal/authentication/aws"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// startQueryOperation starts the execution of the statement of the "sql" metadata, returning its ID without
+	// waiting for its completion.
+	startQueryOperation bindings.OperationKind = "startQuery"
+	// getQueryStatusOperation returns the status of the execution of a statement.
+	getQueryStatusOperation bindings.OperationKind = "getQueryStatus"
+	// getQueryResultsOperation returns a page of the results of a statement.
+	getQueryResultsOperation bindings.OperationKind = "getQueryResults"
+	// cancelQueryOperation cancels the execution of a statement.
+	cancelQueryOperation bindings.OperationKind = "cancelQuery"
+
+	metadataSQL       = "sql"
+	metadataDatabase  = "database"
+	metadataQueryID   = "queryId"
+	metadataNextToken = "nextToken"
+	metadataTotalRows = "totalRows"
+)
+
+// AWSRedshiftData is an AWS Redshift Data API binding running SQL statements asynchronously on a provisioned cluster
+// or a serverless work group.
+type AWSRedshiftData struct {
+	client   redshiftdataapiserviceiface.RedshiftDataAPIServiceAPI
+	metadata *redshiftDataMetadata
+
+	logger logger.Logger
+}
+
+type redshiftDataMetadata struct {
+	Region                string `json:"region"`
+	Endpoint              string `json:"endpoint"`
+	AccessKey             string `json:"accessKey"`
+	SecretKey             string `json:"secretKey" mdsensitive:"true"`
+	SessionToken          string `json:"sessionToken" mdsensitive:"true"`
+	AssumeRoleArn         string `json:"assumeRoleArn"`
+	ExternalID            string `json:"externalId"`
+	AssumeRoleSessionName string `json:"assumeRoleSessionName"`
+	Database              string `json:"database" mdrequired:"true"`
+	// Identifier of the provisioned cluster; exclusive with workgroupName.
+	ClusterIdentifier string `json:"clusterIdentifier"`
+	// Name of the serverless work group; exclusive with clusterIdentifier.
+	WorkgroupName string `json:"workgroupName"`
+	// Database user authenticated with temporary credentials, for provisioned clusters.
+	DBUser string `json:"dbUser"`
+	// ARN of the Secrets Manager secret of the database credentials.
+	SecretArn string `json:"secretArn"`
+}
+
+type startQueryResponse struct {
+	QueryID string `json:"queryId"`
+}
+
+type queryStatusResponse struct {
+	QueryID      string     `json:"queryId"`
+	State        string     `json:"state"`
+	Error        string     `json:"error,omitempty"`
+	HasResultSet bool       `json:"hasResultSet"`
+	ResultRows   *int64     `json:"resultRows,omitempty"`
+	CreatedAt    *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt    *time.Time `json:"updatedAt,omitempty"`
+}
+
+type queryResultsResponse struct {
+	Columns []column         `json:"columns"`
+	Rows    []map[string]any `json:"rows"`
+}
+
+type column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// NewAWSRedshiftData creates a new AWSRedshiftData binding instance.
+func NewAWSRedshiftData(logger logger.Logger) bindings.OutputBinding {
+	return &AWSRedshiftData{logger: logger}
+}
+
+// Init does metadata parsing and client creation.
+func (a *AWSRedshiftData) Init(metadata bindings.Metadata) error {
+	m, err := a.parseMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	client, err := a.getClient(m)
+	if err != nil {
+		return err
+	}
+	a.client = client
+	a.metadata = m
+
+	return nil
+}
+
+func (a *AWSRedshiftData) parseMetadata(metadata bindings.Metadata) (*redshiftDataMetadata, error) {
+	b, err := json.Marshal(metadata.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	var m redshiftDataMetadata
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Database == "" {
+		return nil, errors.New("redshift data binding error: database is required")
+	}
+	if (m.ClusterIdentifier == "") == (m.WorkgroupName == "") {
+		return nil, errors.New("redshift data binding error: exactly one of clusterIdentifier and workgroupName is required")
+	}
+
+	return &m, nil
+}
+
+func (a *AWSRedshiftData) getClient(metadata *redshiftDataMetadata) (*redshiftdataapiservice.RedshiftDataAPIService, error) {
+	sess, err := awsAuth.NewSession(awsAuth.Options{
+		AccessKey:    metadata.AccessKey,
+		SecretKey:    metadata.SecretKey,
+		SessionToken: metadata.SessionToken,
+		Region:       metadata.Region,
+		Endpoint:     metadata.Endpoint,
+		AssumeRole: awsAuth.AssumeRole{
+			RoleARN:     metadata.AssumeRoleArn,
+			ExternalID:  metadata.ExternalID,
+			SessionName: metadata.AssumeRoleSessionName,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	c := redshiftdataapiservice.New(sess)
+
+	return c, nil
+}
+
+func (a *AWSRedshiftData) Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{
+		startQueryOperation,
+		getQueryStatusOperation,
+		getQueryResultsOperation,
+		cancelQueryOperation,
+	}
+}
+
+func (a *AWSRedshiftData) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	switch req.Operation {
+	case startQueryOperation:
+		return a.startQuery(ctx, req)
+	case getQueryStatusOperation:
+		return a.getQueryStatus(ctx, req)
+	case getQueryResultsOperation:
+		return a.getQueryResults(ctx, req)
+	case cancelQueryOperation:
+		return nil, a.cancelQuery(ctx, req)
+	default:
+		return nil, fmt.Errorf("redshift data binding error: unsupported operation %s", req.Operation)
+	}
+}
+
+// startQuery starts the execution of the statement of the "sql" request metadata, in the database of the component
+// or of the request metadata.
+// The request data is an optional JSON object of the values of the ":name" parameters of the statement.
+func (a *AWSRedshiftData) startQuery(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	sql := req.Metadata[metadataSQL]
+	if sql == "" {
+		return nil, fmt.Errorf("redshift data binding error: required metadata not set: %s", metadataSQL)
+	}
+
+	database := a.metadata.Database
+	if val, ok := req.Metadata[metadataDatabase]; ok && val != "" {
+		database = val
+	}
+	input := &redshiftdataapiservice.ExecuteStatementInput{
+		Sql:      aws.String(sql),
+		Database: aws.String(database),
+	}
+	if a.metadata.ClusterIdentifier != "" {
+		input.ClusterIdentifier = aws.String(a.metadata.ClusterIdentifier)
+	}
+	if a.metadata.WorkgroupName != "" {
+		input.WorkgroupName = aws.String(a.metadata.WorkgroupName)
+	}
+	if a.metadata.DBUser != "" {
+		input.DbUser = aws.String(a.metadata.DBUser)
+	}
+	if a.metadata.SecretArn != "" {
+		input.SecretArn = aws.String(a.metadata.SecretArn)
+	}
+	params, err := parseParameters(req.Data)
+	if err != nil {
+		return nil, err
+	}
+	input.Parameters = params
+
+	out, err := a.client.ExecuteStatementWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("redshift data binding error: error executing statement: %w", err)
+	}
+
+	data, err := json.Marshal(startQueryResponse{
+		QueryID: aws.StringValue(out.Id),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &bindings.InvokeResponse{
+		Data: data,
+		Metadata: map[string]string{
+			metadataQueryID: aws.StringValue(out.Id),
+		},
+	}, nil
+}
+
+// getQueryStatus returns the status of the execution of the statement of the "queryId" request metadata.
+func (a *AWSRedshiftData) getQueryStatus(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	queryID := req.Metadata[metadataQueryID]
+	if queryID == "" {
+		return nil, errors.New("redshift data binding error: queryId property not supplied in request-metadata")
+	}
+
+	out, err := a.client.DescribeStatementWithContext(ctx, &redshiftdataapiservice.DescribeStatementInput{
+		Id: aws.String(queryID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("redshift data binding error: error describing statement %s: %w", queryID, err)
+	}
+
+	data, err := json.Marshal(queryStatusResponse{
+		QueryID:      queryID,
+		State:        aws.StringValue(out.Status),
+		Error:        aws.StringValue(out.Error),
+		HasResultSet: aws.BoolValue(out.HasResultSet),
+		ResultRows:   out.ResultRows,
+		CreatedAt:    out.CreatedAt,
+		UpdatedAt:    out.UpdatedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &bindings.InvokeResponse{Data: data}, nil
+}
+
+// getQueryResults returns the page of the "nextToken" request metadata, or the first page, of the results of the
+// statement of the "queryId" request metadata. The token of the next page, if any, is returned in the "nextToken"
+// response metadata.
+func (a *AWSRedshiftData) getQueryResults(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	queryID := req.Metadata[metadataQueryID]
+	if queryID == "" {
+		return nil, errors.New("redshift data binding error: queryId property not supplied in request-metadata")
+	}
+
+	input := &redshiftdataapiservice.GetStatementResultInput{
+		Id: aws.String(queryID),
+	}
+	if val, ok := req.Metadata[metadataNextToken]; ok && val != "" {
+		input.NextToken = aws.String(val)
+	}
+
+	out, err := a.client.GetStatementResultWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("redshift data binding error: error getting results of statement %s: %w", queryID, err)
+	}
+
+	res := queryResultsResponse{
+		Columns: make([]column, len(out.ColumnMetadata)),
+		Rows:    make([]map[string]any, len(out.Records)),
+	}
+	for i, col := range out.ColumnMetadata {
+		res.Columns[i] = column{
+			Name: aws.StringValue(col.Name),
+			Type: aws.StringValue(col.TypeName),
+		}
+	}
+	for i, record := range out.Records {
+		row := make(map[string]any, len(res.Columns))
+		for j, col := range res.Columns {
+			if j < len(record) {
+				row[col.Name] = fieldValue(record[j])
+			} else {
+				row[col.Name] = nil
+			}
+		}
+		res.Rows[i] = row
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &bindings.InvokeResponse{
+		Data:     data,
+		Metadata: map[string]string{},
+	}
+	if out.NextToken != nil {
+		resp.Metadata[metadataNextToken] = *out.NextToken
+	}
+	if out.TotalNumRows != nil {
+		resp.Metadata[metadataTotalRows] = strconv.FormatInt(*out.TotalNumRows, 10)
+	}
+
+	return resp, nil
+}
+
+// cancelQuery cancels the execution of the statement of the "queryId" request metadata.
+func (a *AWSRedshiftData) cancelQuery(ctx context.Context, req *bindings.InvokeRequest) error {
+	queryID := req.Metadata[metadataQueryID]
+	if queryID == "" {
+		return errors.New("redshift data binding error: queryId property not supplied in request-metadata")
+	}
+
+	_, err := a.client.CancelStatementWithContext(ctx, &redshiftdataapiservice.CancelStatementInput{
+		Id: aws.String(queryID),
+	})
+	if err != nil {
+		return fmt.Errorf("redshift data binding error: error cancelling statement %s: %w", queryID, err)
+	}
+
+	return nil
+}
+
+// parseParameters parses the named parameters of a statement from a JSON object of strings, numbers and booleans.
+func parseParameters(data []byte) ([]*redshiftdataapiservice.SqlParameter, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	var values map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&values)
+	if err != nil {
+		return nil, fmt.Errorf("redshift data binding error: statement parameters must be a JSON object: %w", err)
+	}
+
+	params := make([]*redshiftdataapiservice.SqlParameter, 0, len(values))
+	for name, val := range values {
+		var s string
+		switch v := val.(type) {
+		case string:
+			s = v
+		case json.Number:
+			s = v.String()
+		case bool:
+			s = strconv.FormatBool(v)
+		default:
+			return nil, fmt.Errorf("redshift data binding error: unsupported value of statement parameter %s", name)
+		}
+		params = append(params, &redshiftdataapiservice.SqlParameter{
+			Name:  aws.String(name),
+			Value: aws.String(s),
+		})
+	}
+
+	return params, nil
+}
+
+// fieldValue returns the value of a field of a record.
+func fieldValue(field *redshiftdataapiservice.Field) any {
+	switch {
+	case field == nil || aws.BoolValue(field.IsNull):
+		return nil
+	case field.StringValue != nil:
+		return *field.StringValue
+	case field.LongValue != nil:
+		return *field.LongValue
+	case field.DoubleValue != nil:
+		return *field.DoubleValue
+	case field.BooleanValue != nil:
+		return *field.BooleanValue
+	case field.BlobValue != nil:
+		return field.BlobValue
+	default:
+		return nil
+	}
+}
+
+// GetComponentMetadataSchema returns the schema of the metadata of the AWS Redshift Data binding.
+func (a *AWSRedshiftData) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(redshiftDataMetadata{})
+	return fields
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redshiftdata
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/redshiftdataapiservice"
+	"github.com/aws/aws-sdk-go/service/redshiftdataapiservice/redshiftdataapiserviceiface"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/kit/logger"
+)
+
+type mockedRedshiftData struct {
+	ExecuteStatementFn   func(*redshiftdataapiservice.ExecuteStatementInput) (*redshiftdataapiservice.ExecuteStatementOutput, error)
+	DescribeStatementFn  func(*redshiftdataapiservice.DescribeStatementInput) (*redshiftdataapiservice.DescribeStatementOutput, error)
+	GetStatementResultFn func(*redshiftdataapiservice.GetStatementResultInput) (*redshiftdataapiservice.GetStatementResultOutput, error)
+	CancelStatementFn    func(*redshiftdataapiservice.CancelStatementInput) (*redshiftdataapiservice.CancelStatementOutput, error)
+	redshiftdataapiserviceiface.RedshiftDataAPIServiceAPI
+}
+
+func (m *mockedRedshiftData) ExecuteStatementWithContext(_ context.Context, input *redshiftdataapiservice.ExecuteStatementInput, _ ...request.Option) (*redshiftdataapiservice.ExecuteStatementOutput, error) {
+	return m.ExecuteStatementFn(input)
+}
+
+func (m *mockedRedshiftData) DescribeStatementWithContext(_ context.Context, input *redshiftdataapiservice.DescribeStatementInput, _ ...request.Option) (*redshiftdataapiservice.DescribeStatementOutput, error) {
+	return m.DescribeStatementFn(input)
+}
+
+func (m *mockedRedshiftData) GetStatementResultWithContext(_ context.Context, input *redshiftdataapiservice.GetStatementResultInput, _ ...request.Option) (*redshiftdataapiservice.GetStatementResultOutput, error) {
+	return m.GetStatementResultFn(input)
+}
+
+func (m *mockedRedshiftData) CancelStatementWithContext(_ context.Context, input *redshiftdataapiservice.CancelStatementInput, _ ...request.Option) (*redshiftdataapiservice.CancelStatementOutput, error) {
+	return m.CancelStatementFn(input)
+}
+
+func newRedshiftData(client redshiftdataapiserviceiface.RedshiftDataAPIServiceAPI) *AWSRedshiftData {
+	return &AWSRedshiftData{
+		client: client,
+		metadata: &redshiftDataMetadata{
+			Database:          "dev",
+			ClusterIdentifier: "cluster1",
+			SecretArn:         "arn:secret",
+		},
+		logger: logger.NewLogger("test"),
+	}
+}
+
+func TestParseMetadata(t *testing.T) {
+	a := AWSRedshiftData{}
+
+	t.Run("parses the metadata", func(t *testing.T) {
+		m := bindings.Metadata{}
+		m.Properties = map[string]string{
+			"region": "region", "accessKey": "key", "secretKey": "secret",
+			"database": "dev", "workgroupName": "default", "secretArn": "arn:secret",
+		}
+		meta, err := a.parseMetadata(m)
+		assert.Nil(t, err)
+		assert.Equal(t, "region", meta.Region)
+		assert.Equal(t, "dev", meta.Database)
+		assert.Equal(t, "default", meta.WorkgroupName)
+		assert.Equal(t, "arn:secret", meta.SecretArn)
+	})
+
+	t.Run("missing database", func(t *testing.T) {
+		m := bindings.Metadata{}
+		m.Properties = map[string]string{"clusterIdentifier": "cluster1"}
+		_, err := a.parseMetadata(m)
+		assert.ErrorContains(t, err, "database is required")
+	})
+
+	t.Run("cluster and work group", func(t *testing.T) {
+		m := bindings.Metadata{}
+		m.Properties = map[string]string{"database": "dev", "clusterIdentifier": "cluster1", "workgroupName": "default"}
+		_, err := a.parseMetadata(m)
+		assert.ErrorContains(t, err, "exactly one of clusterIdentifier and workgroupName")
+	})
+}
+
+func TestStartQuery(t *testing.T) {
+	t.Run("executes the statement with its parameters", func(t *testing.T) {
+		a := newRedshiftData(&mockedRedshiftData{
+			ExecuteStatementFn: func(input *redshiftdataapiservice.ExecuteStatementInput) (*redshiftdataapiservice.ExecuteStatementOutput, error) {
+				assert.Equal(t, "SELECT * FROM events WHERE name = :name", *input.Sql)
+				assert.Equal(t, "dev", *input.Database)
+				assert.Equal(t, "cluster1", *input.ClusterIdentifier)
+				assert.Nil(t, input.WorkgroupName)
+				assert.Equal(t, "arn:secret", *input.SecretArn)
+				params := map[string]string{}
+				for _, p := range input.Parameters {
+					params[*p.Name] = *p.Value
+				}
+				assert.Equal(t, map[string]string{"name": "a", "count": "10", "active": "true"}, params)
+
+				return &redshiftdataapiservice.ExecuteStatementOutput{Id: aws.String("statement1")}, nil
+			},
+		})
+
+		res, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: startQueryOperation,
+			Data:      []byte(`{"name": "a", "count": 10, "active": true}`),
+			Metadata:  map[string]string{"sql": "SELECT * FROM events WHERE name = :name"},
+		})
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"queryId":"statement1"}`, string(res.Data))
+		assert.Equal(t, "statement1", res.Metadata["queryId"])
+	})
+
+	t.Run("returns error for missing sql", func(t *testing.T) {
+		a := newRedshiftData(&mockedRedshiftData{})
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{Operation: startQueryOperation})
+
+		assert.ErrorContains(t, err, "required metadata not set: sql")
+	})
+
+	t.Run("returns error for unsupported parameters", func(t *testing.T) {
+		a := newRedshiftData(&mockedRedshiftData{})
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: startQueryOperation,
+			Data:      []byte(`{"name": null}`),
+			Metadata:  map[string]string{"sql": "SELECT :name"},
+		})
+
+		assert.ErrorContains(t, err, "unsupported value of statement parameter name")
+	})
+}
+
+func TestGetQueryStatus(t *testing.T) {
+	created := time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)
+	a := newRedshiftData(&mockedRedshiftData{
+		DescribeStatementFn: func(input *redshiftdataapiservice.DescribeStatementInput) (*redshiftdataapiservice.DescribeStatementOutput, error) {
+			assert.Equal(t, "statement1", *input.Id)
+
+			return &redshiftdataapiservice.DescribeStatementOutput{
+				Id:           aws.String("statement1"),
+				Status:       aws.String(redshiftdataapiservice.StatusStringFinished),
+				HasResultSet: aws.Bool(true),
+				ResultRows:   aws.Int64(2),
+				CreatedAt:    &created,
+			}, nil
+		},
+	})
+
+	res, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+		Operation: getQueryStatusOperation,
+		Metadata:  map[string]string{"queryId": "statement1"},
+	})
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"queryId":"statement1","state":"FINISHED","hasResultSet":true,"resultRows":2,"createdAt":"2022-11-01T00:00:00Z"}`, string(res.Data))
+
+	t.Run("returns error for missing queryId", func(t *testing.T) {
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{Operation: getQueryStatusOperation})
+
+		assert.ErrorContains(t, err, "queryId property not supplied")
+	})
+}
+
+func TestGetQueryResults(t *testing.T) {
+	a := newRedshiftData(&mockedRedshiftData{
+		GetStatementResultFn: func(input *redshiftdataapiservice.GetStatementResultInput) (*redshiftdataapiservice.GetStatementResultOutput, error) {
+			assert.Equal(t, "statement1", *input.Id)
+			assert.Equal(t, "page2", *input.NextToken)
+
+			return &redshiftdataapiservice.GetStatementResultOutput{
+				ColumnMetadata: []*redshiftdataapiservice.ColumnMetadata{
+					{Name: aws.String("name"), TypeName: aws.String("varchar")},
+					{Name: aws.String("count"), TypeName: aws.String("int8")},
+					{Name: aws.String("ratio"), TypeName: aws.String("float8")},
+					{Name: aws.String("active"), TypeName: aws.String("bool")},
+				},
+				Records: [][]*redshiftdataapiservice.Field{
+					{
+						{StringValue: aws.String("a")},
+						{LongValue: aws.Int64(1)},
+						{DoubleValue: aws.Float64(0.5)},
+						{BooleanValue: aws.Bool(true)},
+					},
+					{
+						{StringValue: aws.String("b")},
+						{IsNull: aws.Bool(true)},
+						{IsNull: aws.Bool(true)},
+						{BooleanValue: aws.Bool(false)},
+					},
+				},
+				NextToken:    aws.String("page3"),
+				TotalNumRows: aws.Int64(10),
+			}, nil
+		},
+	})
+
+	res, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+		Operation: getQueryResultsOperation,
+		Metadata:  map[string]string{"queryId": "statement1", "nextToken": "page2"},
+	})
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"columns":[{"name":"name","type":"varchar"},{"name":"count","type":"int8"},{"name":"ratio","type":"float8"},{"name":"active","type":"bool"}],`+
+		`"rows":[{"name":"a","count":1,"ratio":0.5,"active":true},{"name":"b","count":null,"ratio":null,"active":false}]}`, string(res.Data))
+	assert.Equal(t, "page3", res.Metadata["nextToken"])
+	assert.Equal(t, "10", res.Metadata["totalRows"])
+}
+
+func TestCancelQuery(t *testing.T) {
+	var cancelled string
+	a := newRedshiftData(&mockedRedshiftData{
+		CancelStatementFn: func(input *redshiftdataapiservice.CancelStatementInput) (*redshiftdataapiservice.CancelStatementOutput, error) {
+			cancelled = *input.Id
+
+			return &redshiftdataapiservice.CancelStatementOutput{Status: aws.Bool(true)}, nil
+		},
+	})
+
+	_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+		Operation: cancelQueryOperation,
+		Metadata:  map[string]string{"queryId": "statement1"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "statement1", cancelled)
+}
@@ -22,8 +22,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -35,6 +37,7 @@ import (
 	"github.com/dapr/components-contrib/bindings"
 	awsAuth "github.com/dapr/components-contrib/internal/authentication/aws"
 	"github.com/dapr/components-contrib/internal/utils"
+	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 )
 
@@ -44,35 +47,57 @@ const (
 	metadataFilePath     = "filePath"
 	metadataPresignTTL   = "presignTTL"
 
+	metadataServerSideEncryption = "serverSideEncryption"
+	metadataSSEKMSKeyID          = "sseKmsKeyId"
+	metadataStorageClass         = "storageClass"
+	metadataACL                  = "acl"
+	metadataTags                 = "tags"
+
 	metadataKey = "key"
 
+	metadataContentType   = "contentType"
+	metadataContentLength = "contentLength"
+	metadataETag          = "etag"
+	metadataLastModified  = "lastModified"
+	metadataVersionID     = "versionID"
+
 	maxResults       = 1000
 	presignOperation = "presign"
 )
 
 // AWSS3 is a binding for an AWS S3 storage bucket.
 type AWSS3 struct {
-	metadata   *s3Metadata
-	s3Client   *s3.S3
-	uploader   *s3manager.Uploader
-	downloader *s3manager.Downloader
-	logger     logger.Logger
+	metadata *s3Metadata
+	s3Client *s3.S3
+	uploader *s3manager.Uploader
+	logger   logger.Logger
 }
 
 type s3Metadata struct {
-	Region         string `json:"region"`
-	Endpoint       string `json:"endpoint"`
-	AccessKey      string `json:"accessKey"`
-	SecretKey      string `json:"secretKey"`
-	SessionToken   string `json:"sessionToken"`
-	Bucket         string `json:"bucket"`
-	DecodeBase64   bool   `json:"decodeBase64,string"`
-	EncodeBase64   bool   `json:"encodeBase64,string"`
-	ForcePathStyle bool   `json:"forcePathStyle,string"`
-	DisableSSL     bool   `json:"disableSSL,string"`
-	InsecureSSL    bool   `json:"insecureSSL,string"`
-	FilePath       string
-	PresignTTL     string
+	Region                string `json:"region"`
+	Endpoint              string `json:"endpoint"`
+	AccessKey             string `json:"accessKey"`
+	SecretKey             string `json:"secretKey" mdsensitive:"true"`
+	SessionToken          string `json:"sessionToken" mdsensitive:"true"`
+	AssumeRoleArn         string `json:"assumeRoleArn"`
+	ExternalID            string `json:"externalId"`
+	AssumeRoleSessionName string `json:"assumeRoleSessionName"`
+	UseFIPSEndpoint       bool   `json:"useFipsEndpoint,string"`
+	UseDualStackEndpoint  bool   `json:"useDualStackEndpoint,string"`
+	FailoverRegions       string `json:"failoverRegions"`
+	Bucket                string `json:"bucket"`
+	DecodeBase64          bool   `json:"decodeBase64,string"`
+	EncodeBase64          bool   `json:"encodeBase64,string"`
+	ForcePathStyle        bool   `json:"forcePathStyle,string"`
+	DisableSSL            bool   `json:"disableSSL,string"`
+	InsecureSSL           bool   `json:"insecureSSL,string"`
+	ServerSideEncryption  string `json:"serverSideEncryption"`
+	SSEKMSKeyID           string `json:"sseKmsKeyId"`
+	StorageClass          string `json:"storageClass"`
+	ACL                   string `json:"acl"`
+	Tags                  string `json:"tags"`
+	FilePath              string
+	PresignTTL            string
 }
 
 type createResponse struct {
@@ -127,7 +152,6 @@ func (s *AWSS3) Init(metadata bindings.Metadata) error {
 
 	s.metadata = m
 	s.s3Client = s3.New(session, cfg)
-	s.downloader = s3manager.NewDownloaderWithClient(s.s3Client)
 	s.uploader = s3manager.NewUploaderWithClient(s.s3Client)
 
 	return nil
@@ -160,30 +184,48 @@ func (s *AWSS3) create(ctx context.Context, req *bindings.InvokeRequest) (*bindi
 		s.logger.Debugf("s3 binding: key not found. generating key %s", key)
 	}
 
-	d, err := strconv.Unquote(string(req.Data))
-	if err == nil {
-		req.Data = []byte(d)
-	}
-
 	var r io.Reader
 	if metadata.FilePath != "" {
 		r, err = os.Open(metadata.FilePath)
 		if err != nil {
 			return nil, fmt.Errorf("s3 file read error: %s", err)
 		}
+		if metadata.DecodeBase64 {
+			r = b64.NewDecoder(b64.StdEncoding, r)
+		}
 	} else {
-		r = bytes.NewReader(req.Data)
-	}
-
-	if metadata.DecodeBase64 {
-		r = b64.NewDecoder(b64.StdEncoding, r)
+		data, err := req.DecodedData(metadata.DecodeBase64)
+		if err != nil {
+			return nil, fmt.Errorf("s3 binding error: %w", err)
+		}
+		r = bytes.NewReader(data)
 	}
 
-	resultUpload, err := s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+	input := &s3manager.UploadInput{
 		Bucket: aws.String(metadata.Bucket),
 		Key:    aws.String(key),
 		Body:   r,
-	})
+	}
+	if contentType := req.Metadata[metadataContentType]; contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	if metadata.ServerSideEncryption != "" {
+		input.ServerSideEncryption = aws.String(metadata.ServerSideEncryption)
+	}
+	if metadata.SSEKMSKeyID != "" {
+		input.SSEKMSKeyId = aws.String(metadata.SSEKMSKeyID)
+	}
+	if metadata.StorageClass != "" {
+		input.StorageClass = aws.String(metadata.StorageClass)
+	}
+	if metadata.ACL != "" {
+		input.ACL = aws.String(metadata.ACL)
+	}
+	if metadata.Tags != "" {
+		input.Tagging = aws.String(metadata.Tags)
+	}
+
+	resultUpload, err := s.uploader.UploadWithContext(ctx, input)
 	if err != nil {
 		return nil, fmt.Errorf("s3 binding error: Uploading: %w", err)
 	}
@@ -276,10 +318,7 @@ func (s *AWSS3) get(ctx context.Context, req *bindings.InvokeRequest) (*bindings
 		return nil, fmt.Errorf("s3 binding error: can't read key value")
 	}
 
-	buff := &aws.WriteAtBuffer{}
-
-	_, err = s.downloader.DownloadWithContext(ctx,
-		buff,
+	out, err := s.s3Client.GetObjectWithContext(ctx,
 		&s3.GetObjectInput{
 			Bucket: aws.String(s.metadata.Bucket),
 			Key:    aws.String(key),
@@ -288,21 +327,54 @@ func (s *AWSS3) get(ctx context.Context, req *bindings.InvokeRequest) (*bindings
 	if err != nil {
 		return nil, fmt.Errorf("s3 binding error: error downloading S3 object: %w", err)
 	}
+	defer out.Body.Close()
+
+	buff, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("s3 binding error: error reading S3 object: %w", err)
+	}
 
 	var data []byte
 	if metadata.EncodeBase64 {
-		encoded := b64.StdEncoding.EncodeToString(buff.Bytes())
+		encoded := b64.StdEncoding.EncodeToString(buff)
 		data = []byte(encoded)
 	} else {
-		data = buff.Bytes()
+		data = buff
 	}
 
 	return &bindings.InvokeResponse{
 		Data:     data,
-		Metadata: nil,
+		Metadata: objectMetadata(out),
 	}, nil
 }
 
+// objectMetadata returns the properties and the user-defined metadata of an object as response metadata.
+func objectMetadata(out *s3.GetObjectOutput) map[string]string {
+	md := make(map[string]string, len(out.Metadata)+5)
+	for k, v := range out.Metadata {
+		if v != nil {
+			md[k] = *v
+		}
+	}
+	if out.ContentType != nil {
+		md[metadataContentType] = *out.ContentType
+	}
+	if out.ContentLength != nil {
+		md[metadataContentLength] = strconv.FormatInt(*out.ContentLength, 10)
+	}
+	if out.ETag != nil {
+		md[metadataETag] = *out.ETag
+	}
+	if out.LastModified != nil {
+		md[metadataLastModified] = out.LastModified.UTC().Format(time.RFC3339)
+	}
+	if out.VersionId != nil {
+		md[metadataVersionID] = *out.VersionId
+	}
+
+	return md
+}
+
 func (s *AWSS3) delete(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
 	var key string
 	if val, ok := req.Metadata[metadataKey]; ok && val != "" {
@@ -344,6 +416,11 @@ func (s *AWSS3) list(ctx context.Context, req *bindings.InvokeRequest) (*binding
 		return nil, fmt.Errorf("s3 binding error. list operation. cannot marshal blobs to json: %w", err)
 	}
 
+	// S3 only returns the next marker when a delimiter is set; otherwise the next page starts after the last key
+	if aws.BoolValue(result.IsTruncated) && result.NextMarker == nil && len(result.Contents) > 0 {
+		result.NextMarker = result.Contents[len(result.Contents)-1].Key
+	}
+
 	jsonResponse, err := json.Marshal(result)
 	if err != nil {
 		return nil, fmt.Errorf("s3 binding error. list operation. cannot marshal blobs to json: %w", err)
@@ -383,11 +460,63 @@ func (s *AWSS3) parseMetadata(metadata bindings.Metadata) (*s3Metadata, error) {
 		return nil, err
 	}
 
+	if err = m.validateUploadOptions(); err != nil {
+		return nil, err
+	}
+
 	return &m, nil
 }
 
+// validateUploadOptions checks the encryption, storage class, ACL and tags of the objects to create.
+func (metadata s3Metadata) validateUploadOptions() error {
+	if metadata.ServerSideEncryption != "" && !contains(s3.ServerSideEncryption_Values(), metadata.ServerSideEncryption) {
+		return fmt.Errorf("invalid %s %q", metadataServerSideEncryption, metadata.ServerSideEncryption)
+	}
+	if metadata.SSEKMSKeyID != "" && metadata.ServerSideEncryption != s3.ServerSideEncryptionAwsKms {
+		return fmt.Errorf("%s requires %s to be %q", metadataSSEKMSKeyID, metadataServerSideEncryption, s3.ServerSideEncryptionAwsKms)
+	}
+	if metadata.StorageClass != "" && !contains(s3.StorageClass_Values(), metadata.StorageClass) {
+		return fmt.Errorf("invalid %s %q", metadataStorageClass, metadata.StorageClass)
+	}
+	if metadata.ACL != "" && !contains(s3.ObjectCannedACL_Values(), metadata.ACL) {
+		return fmt.Errorf("invalid %s %q", metadataACL, metadata.ACL)
+	}
+	// Tags are URL query parameters, e.g. "project=dapr&env=dev"
+	if metadata.Tags != "" {
+		if _, err := url.ParseQuery(metadata.Tags); err != nil {
+			return fmt.Errorf("invalid %s: %w", metadataTags, err)
+		}
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (s *AWSS3) getSession(metadata *s3Metadata) (*session.Session, error) {
-	sess, err := awsAuth.GetClient(metadata.AccessKey, metadata.SecretKey, metadata.SessionToken, metadata.Region, metadata.Endpoint)
+	sess, err := awsAuth.NewSession(awsAuth.Options{
+		AccessKey:    metadata.AccessKey,
+		SecretKey:    metadata.SecretKey,
+		SessionToken: metadata.SessionToken,
+		Region:       metadata.Region,
+		Endpoint:     metadata.Endpoint,
+		AssumeRole: awsAuth.AssumeRole{
+			RoleARN:     metadata.AssumeRoleArn,
+			ExternalID:  metadata.ExternalID,
+			SessionName: metadata.AssumeRoleSessionName,
+		},
+		UseFIPSEndpoint:      metadata.UseFIPSEndpoint,
+		UseDualStackEndpoint: metadata.UseDualStackEndpoint,
+		FailoverRegions:      strings.Split(metadata.FailoverRegions, ","),
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -415,5 +544,38 @@ func (metadata s3Metadata) mergeWithRequestMetadata(req *bindings.InvokeRequest)
 		merged.PresignTTL = val
 	}
 
+	if val, ok := req.Metadata[metadataServerSideEncryption]; ok && val != "" {
+		merged.ServerSideEncryption = val
+		if val != s3.ServerSideEncryptionAwsKms {
+			merged.SSEKMSKeyID = ""
+		}
+	}
+
+	if val, ok := req.Metadata[metadataSSEKMSKeyID]; ok && val != "" {
+		merged.SSEKMSKeyID = val
+	}
+
+	if val, ok := req.Metadata[metadataStorageClass]; ok && val != "" {
+		merged.StorageClass = val
+	}
+
+	if val, ok := req.Metadata[metadataACL]; ok && val != "" {
+		merged.ACL = val
+	}
+
+	if val, ok := req.Metadata[metadataTags]; ok && val != "" {
+		merged.Tags = val
+	}
+
+	if err := merged.validateUploadOptions(); err != nil {
+		return merged, err
+	}
+
 	return merged, nil
 }
+
+// GetComponentMetadataSchema returns the schema of the metadata of the AWS S3 binding.
+func (s *AWSS3) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(s3Metadata{})
+	return fields
+}
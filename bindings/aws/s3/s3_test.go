@@ -16,7 +16,10 @@ package s3
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/dapr/components-contrib/bindings"
@@ -165,3 +168,118 @@ func TestDeleteOption(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestPresignOption(t *testing.T) {
+	s3 := NewAWSS3(logger.NewLogger("s3")).(*AWSS3)
+	s3.metadata = &s3Metadata{}
+
+	t.Run("return error if key is missing", func(t *testing.T) {
+		r := bindings.InvokeRequest{
+			Metadata: map[string]string{metadataPresignTTL: "15m"},
+		}
+		_, err := s3.presign(context.Background(), &r)
+		assert.Error(t, err)
+	})
+
+	t.Run("return error if TTL is missing", func(t *testing.T) {
+		r := bindings.InvokeRequest{
+			Metadata: map[string]string{metadataKey: "foo"},
+		}
+		_, err := s3.presign(context.Background(), &r)
+		assert.Error(t, err)
+	})
+}
+
+func TestObjectMetadata(t *testing.T) {
+	lastModified := time.Date(2022, 10, 1, 12, 30, 0, 0, time.UTC)
+	out := &s3.GetObjectOutput{
+		ContentType:   aws.String("text/plain"),
+		ContentLength: aws.Int64(42),
+		ETag:          aws.String(`"etag"`),
+		LastModified:  &lastModified,
+		VersionId:     aws.String("v1"),
+		Metadata:      map[string]*string{"Owner": aws.String("dapr")},
+	}
+
+	// act
+	md := objectMetadata(out)
+
+	// assert
+	assert.Equal(t, map[string]string{
+		"contentType":   "text/plain",
+		"contentLength": "42",
+		"etag":          `"etag"`,
+		"lastModified":  "2022-10-01T12:30:00Z",
+		"versionID":     "v1",
+		"Owner":         "dapr",
+	}, md)
+}
+
+func TestUploadOptions(t *testing.T) {
+	t.Run("Has upload options", func(t *testing.T) {
+		m := bindings.Metadata{}
+		m.Properties = map[string]string{
+			"bucket": "test", "serverSideEncryption": "aws:kms", "sseKmsKeyId": "key-id", "storageClass": "STANDARD_IA", "acl": "private", "tags": "project=dapr&env=dev",
+		}
+		s3 := AWSS3{}
+		meta, err := s3.parseMetadata(m)
+		assert.Nil(t, err)
+		assert.Equal(t, "aws:kms", meta.ServerSideEncryption)
+		assert.Equal(t, "key-id", meta.SSEKMSKeyID)
+		assert.Equal(t, "STANDARD_IA", meta.StorageClass)
+		assert.Equal(t, "private", meta.ACL)
+		assert.Equal(t, "project=dapr&env=dev", meta.Tags)
+	})
+
+	t.Run("Has merged upload options", func(t *testing.T) {
+		meta := s3Metadata{ServerSideEncryption: "aws:kms", SSEKMSKeyID: "key-id", StorageClass: "STANDARD_IA"}
+
+		request := bindings.InvokeRequest{}
+		request.Metadata = map[string]string{
+			"serverSideEncryption": "AES256",
+			"storageClass":         "GLACIER",
+			"acl":                  "public-read",
+			"tags":                 "env=prod",
+		}
+
+		mergedMeta, err := meta.mergeWithRequestMetadata(&request)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "AES256", mergedMeta.ServerSideEncryption)
+		assert.Empty(t, mergedMeta.SSEKMSKeyID)
+		assert.Equal(t, "GLACIER", mergedMeta.StorageClass)
+		assert.Equal(t, "public-read", mergedMeta.ACL)
+		assert.Equal(t, "env=prod", mergedMeta.Tags)
+	})
+
+	t.Run("Has invalid upload options", func(t *testing.T) {
+		for name, props := range map[string]map[string]string{
+			"serverSideEncryption": {"serverSideEncryption": "rot13"},
+			"sseKmsKeyId":          {"serverSideEncryption": "AES256", "sseKmsKeyId": "key-id"},
+			"storageClass":         {"storageClass": "COLD"},
+			"acl":                  {"acl": "everyone"},
+			"tags":                 {"tags": "env=%zz"},
+		} {
+			t.Run(name, func(t *testing.T) {
+				m := bindings.Metadata{}
+				m.Properties = props
+				s3 := AWSS3{}
+				_, err := s3.parseMetadata(m)
+				assert.Error(t, err)
+			})
+		}
+	})
+
+	t.Run("Has invalid merged upload options", func(t *testing.T) {
+		meta := s3Metadata{}
+
+		request := bindings.InvokeRequest{}
+		request.Metadata = map[string]string{
+			"storageClass": "COLD",
+		}
+
+		_, err := meta.mergeWithRequestMetadata(&request)
+
+		assert.Error(t, err)
+	})
+}
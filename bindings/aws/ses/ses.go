@@ -22,37 +22,45 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
-
-	awsAuth "github.com/dapr/components-contrib/internal/authentication/aws"
-
-	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/aws/aws-sdk-go/service/sesv2"
+	"github.com/aws/aws-sdk-go/service/sesv2/sesv2iface"
 
 	"github.com/dapr/components-contrib/bindings"
+	awsAuth "github.com/dapr/components-contrib/internal/authentication/aws"
+	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 )
 
 const (
 	// The character encoding for the email.
 	CharSet = "UTF-8"
+
+	// Types of the body of the email, set by the bodyType metadata.
+	bodyTypeHTML = "html"
+	bodyTypeText = "text"
 )
 
-// AWSSES is an AWS SNS binding.
+// AWSSES is an AWS SES binding.
 type AWSSES struct {
 	metadata *sesMetadata
 	logger   logger.Logger
-	svc      *ses.SES
+	svc      sesv2iface.SESV2API
 }
 
 type sesMetadata struct {
-	Region       string `json:"region"`
-	AccessKey    string `json:"accessKey"`
-	SecretKey    string `json:"secretKey"`
-	SessionToken string `json:"sessionToken"`
-	EmailFrom    string `json:"emailFrom"`
-	EmailTo      string `json:"emailTo"`
-	Subject      string `json:"subject"`
-	EmailCc      string `json:"emailCc"`
-	EmailBcc     string `json:"emailBcc"`
+	Region                string `json:"region" mdrequired:"true"`
+	AccessKey             string `json:"accessKey"`
+	SecretKey             string `json:"secretKey" mdsensitive:"true"`
+	SessionToken          string `json:"sessionToken" mdsensitive:"true"`
+	AssumeRoleArn         string `json:"assumeRoleArn"`
+	ExternalID            string `json:"externalId"`
+	AssumeRoleSessionName string `json:"assumeRoleSessionName"`
+	EmailFrom             string `json:"emailFrom"`
+	EmailTo               string `json:"emailTo"`
+	Subject               string `json:"subject"`
+	EmailCc               string `json:"emailCc"`
+	EmailBcc              string `json:"emailBcc"`
+	BodyType              string `json:"bodyType"`
 }
 
 // NewAWSSES creates a new AWSSES binding instance.
@@ -94,9 +102,13 @@ func (a *AWSSES) parseMetadata(meta bindings.Metadata) (*sesMetadata, error) {
 		return nil, err
 	}
 
-	if meta.Properties["region"] == "" || meta.Properties["accessKey"] == "" ||
-		meta.Properties["secretKey"] == "" {
-		return &m, errors.New("SES binding error: region, accessKey or secretKey fields are required in metadata")
+	if meta.Properties["region"] == "" {
+		return &m, errors.New("SES binding error: region field is required in metadata")
+	}
+
+	// Static keys are optional, the credentials are otherwise resolved from the environment
+	if (meta.Properties["accessKey"] == "") != (meta.Properties["secretKey"] == "") {
+		return &m, errors.New("SES binding error: accessKey and secretKey fields must be set together in metadata")
 	}
 
 	return &m, nil
@@ -115,53 +127,62 @@ func (a *AWSSES) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bind
 		return nil, fmt.Errorf("SES binding error: subject property not supplied in configuration- or request-metadata")
 	}
 
-	body, err := strconv.Unquote(string(req.Data))
-	if err != nil {
-		return nil, fmt.Errorf("SES binding error. Can't unquote data field: %w", err)
+	body := string(req.Data)
+	if unquoted, err := strconv.Unquote(body); err == nil {
+		body = unquoted
 	}
 
-	// Assemble the email.
-	input := &ses.SendEmailInput{
-		Destination: &ses.Destination{
-			ToAddresses: aws.StringSlice(strings.Split(metadata.EmailTo, ";")),
-		},
-		Message: &ses.Message{
-			Body: &ses.Body{
-				Html: &ses.Content{
-					Charset: aws.String(CharSet),
-					Data:    aws.String(body),
-				},
-			},
-			Subject: &ses.Content{
-				Charset: aws.String(CharSet),
-				Data:    aws.String(metadata.Subject),
-			},
-		},
-		Source: aws.String(metadata.EmailFrom),
-		// TODO: Add  configuration set: https://docs.aws.amazon.com/ses/latest/DeveloperGuide/using-configuration-sets.html
-		// ConfigurationSetName: aws.String(ConfigurationSet),
+	content := &sesv2.Content{
+		Charset: aws.String(CharSet),
+		Data:    aws.String(body),
+	}
+	var emailBody *sesv2.Body
+	switch metadata.BodyType {
+	case "", bodyTypeHTML:
+		emailBody = &sesv2.Body{Html: content}
+	case bodyTypeText:
+		emailBody = &sesv2.Body{Text: content}
+	default:
+		return nil, fmt.Errorf("SES binding error: invalid bodyType %q, expected %q or %q", metadata.BodyType, bodyTypeHTML, bodyTypeText)
 	}
 
+	// Assemble the email.
+	destination := &sesv2.Destination{
+		ToAddresses: aws.StringSlice(strings.Split(metadata.EmailTo, ";")),
+	}
 	if metadata.EmailCc != "" {
-		input.SetDestination(&ses.Destination{
-			CcAddresses: aws.StringSlice(strings.Split(metadata.EmailCc, ";")),
-		})
+		destination.CcAddresses = aws.StringSlice(strings.Split(metadata.EmailCc, ";"))
 	}
 	if metadata.EmailBcc != "" {
-		input.SetDestination(&ses.Destination{
-			BccAddresses: aws.StringSlice(strings.Split(metadata.EmailBcc, ";")),
-		})
+		destination.BccAddresses = aws.StringSlice(strings.Split(metadata.EmailBcc, ";"))
+	}
+	input := &sesv2.SendEmailInput{
+		Destination: destination,
+		Content: &sesv2.EmailContent{
+			Simple: &sesv2.Message{
+				Body: emailBody,
+				Subject: &sesv2.Content{
+					Charset: aws.String(CharSet),
+					Data:    aws.String(metadata.Subject),
+				},
+			},
+		},
+		FromEmailAddress: aws.String(metadata.EmailFrom),
 	}
 
 	// Attempt to send the email.
-	result, err := a.svc.SendEmail(input)
+	result, err := a.svc.SendEmailWithContext(ctx, input)
 	if err != nil {
 		return nil, fmt.Errorf("SES binding error. Sending email failed: %w", err)
 	}
 
-	a.logger.Debug("SES binding: sent email successfully ", result.MessageId)
+	a.logger.Debug("SES binding: sent email successfully ", aws.StringValue(result.MessageId))
 
-	return nil, nil
+	return &bindings.InvokeResponse{
+		Metadata: map[string]string{
+			"messageId": aws.StringValue(result.MessageId),
+		},
+	}, nil
 }
 
 // Helper to merge config and request metadata.
@@ -188,17 +209,37 @@ func (metadata sesMetadata) mergeWithRequestMetadata(req *bindings.InvokeRequest
 		merged.Subject = subject
 	}
 
+	if bodyType := req.Metadata["bodyType"]; bodyType != "" {
+		merged.BodyType = bodyType
+	}
+
 	return merged
 }
 
-func (a *AWSSES) getClient(metadata *sesMetadata) (*ses.SES, error) {
-	sess, err := awsAuth.GetClient(metadata.AccessKey, metadata.SecretKey, metadata.SessionToken, metadata.Region, "")
+func (a *AWSSES) getClient(metadata *sesMetadata) (*sesv2.SESV2, error) {
+	sess, err := awsAuth.NewSession(awsAuth.Options{
+		AccessKey:    metadata.AccessKey,
+		SecretKey:    metadata.SecretKey,
+		SessionToken: metadata.SessionToken,
+		Region:       metadata.Region,
+		AssumeRole: awsAuth.AssumeRole{
+			RoleARN:     metadata.AssumeRoleArn,
+			ExternalID:  metadata.ExternalID,
+			SessionName: metadata.AssumeRoleSessionName,
+		},
+	})
 	if err != nil {
 		return nil, fmt.Errorf("SES binding error: error creating AWS session %w", err)
 	}
 
 	// Create an SES instance
-	svc := ses.New(sess)
+	svc := sesv2.New(sess)
 
 	return svc, nil
 }
+
+// GetComponentMetadataSchema returns the schema of the metadata of the AWS SES binding.
+func (a *AWSSES) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(sesMetadata{})
+	return fields
+}
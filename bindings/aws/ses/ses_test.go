@@ -14,14 +14,28 @@ limitations under the License.
 package ses
 
 import (
+	"context"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sesv2"
+	"github.com/aws/aws-sdk-go/service/sesv2/sesv2iface"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/dapr/components-contrib/bindings"
 	"github.com/dapr/kit/logger"
 )
 
+type mockedSESV2 struct {
+	SendEmailFn func(context.Context, *sesv2.SendEmailInput, ...request.Option) (*sesv2.SendEmailOutput, error)
+	sesv2iface.SESV2API
+}
+
+func (m *mockedSESV2) SendEmailWithContext(ctx context.Context, input *sesv2.SendEmailInput, option ...request.Option) (*sesv2.SendEmailOutput, error) {
+	return m.SendEmailFn(ctx, input, option...)
+}
+
 func TestParseMetadata(t *testing.T) {
 	logger := logger.NewLogger("test")
 
@@ -84,6 +98,21 @@ func TestParseMetadata(t *testing.T) {
 		assert.Error(t, err)
 	})
 
+	t.Run("static keys are optional", func(t *testing.T) {
+		m := bindings.Metadata{}
+		m.Properties = map[string]string{
+			"region":        "myRegionForSES",
+			"assumeRoleArn": "arn:aws:iam::123456789012:role/ses",
+			"externalId":    "myExternalID",
+			"emailFrom":     "from@dapr.io",
+		}
+		r := AWSSES{logger: logger}
+		smtpMeta, err := r.parseMetadata(m)
+		assert.NoError(t, err)
+		assert.Equal(t, "arn:aws:iam::123456789012:role/ses", smtpMeta.AssumeRoleArn)
+		assert.Equal(t, "myExternalID", smtpMeta.ExternalID)
+	})
+
 	t.Run("secretKey is required", func(t *testing.T) {
 		m := bindings.Metadata{}
 		m.Properties = map[string]string{
@@ -162,3 +191,65 @@ func TestMergeWithRequestMetadata(t *testing.T) {
 		assert.Equal(t, "Test email", mergedMeta.Subject)
 	})
 }
+
+func TestInvoke(t *testing.T) {
+	newSES := func(fn func(context.Context, *sesv2.SendEmailInput, ...request.Option) (*sesv2.SendEmailOutput, error)) *AWSSES {
+		return &AWSSES{
+			svc: &mockedSESV2{SendEmailFn: fn},
+			metadata: &sesMetadata{
+				EmailFrom: "from@dapr.io",
+				EmailTo:   "to@dapr.io;to2@dapr.io",
+				EmailCc:   "cc@dapr.io",
+				EmailBcc:  "bcc@dapr.io",
+				Subject:   "Test email",
+			},
+			logger: logger.NewLogger("test"),
+		}
+	}
+
+	t.Run("sends html email", func(t *testing.T) {
+		a := newSES(func(ctx context.Context, input *sesv2.SendEmailInput, option ...request.Option) (*sesv2.SendEmailOutput, error) {
+			assert.Equal(t, "from@dapr.io", *input.FromEmailAddress)
+			assert.Equal(t, aws.StringSlice([]string{"to@dapr.io", "to2@dapr.io"}), input.Destination.ToAddresses)
+			assert.Equal(t, aws.StringSlice([]string{"cc@dapr.io"}), input.Destination.CcAddresses)
+			assert.Equal(t, aws.StringSlice([]string{"bcc@dapr.io"}), input.Destination.BccAddresses)
+			assert.Equal(t, "Test email", *input.Content.Simple.Subject.Data)
+			assert.Equal(t, "<b>Hello</b>", *input.Content.Simple.Body.Html.Data)
+			assert.Nil(t, input.Content.Simple.Body.Text)
+
+			return &sesv2.SendEmailOutput{MessageId: aws.String("message-1")}, nil
+		})
+
+		res, err := a.Invoke(context.Background(), &bindings.InvokeRequest{Data: []byte(`"<b>Hello</b>"`)})
+
+		assert.Nil(t, err)
+		assert.Equal(t, "message-1", res.Metadata["messageId"])
+	})
+
+	t.Run("sends text email", func(t *testing.T) {
+		a := newSES(func(ctx context.Context, input *sesv2.SendEmailInput, option ...request.Option) (*sesv2.SendEmailOutput, error) {
+			assert.Equal(t, "Hello", *input.Content.Simple.Body.Text.Data)
+			assert.Nil(t, input.Content.Simple.Body.Html)
+
+			return &sesv2.SendEmailOutput{MessageId: aws.String("message-2")}, nil
+		})
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Data:     []byte("Hello"),
+			Metadata: map[string]string{"bodyType": "text"},
+		})
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("invalid body type", func(t *testing.T) {
+		a := newSES(nil)
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Data:     []byte("Hello"),
+			Metadata: map[string]string{"bodyType": "markdown"},
+		})
+
+		assert.Error(t, err)
+	})
+}
@@ -17,11 +17,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/service/sns"
 
 	"github.com/dapr/components-contrib/bindings"
 	awsAuth "github.com/dapr/components-contrib/internal/authentication/aws"
+	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 )
 
@@ -34,12 +36,18 @@ type AWSSNS struct {
 }
 
 type snsMetadata struct {
-	TopicArn     string `json:"topicArn"`
-	Region       string `json:"region"`
-	Endpoint     string `json:"endpoint"`
-	AccessKey    string `json:"accessKey"`
-	SecretKey    string `json:"secretKey"`
-	SessionToken string `json:"sessionToken"`
+	TopicArn              string `json:"topicArn"`
+	Region                string `json:"region"`
+	Endpoint              string `json:"endpoint"`
+	AccessKey             string `json:"accessKey"`
+	SecretKey             string `json:"secretKey" mdsensitive:"true"`
+	SessionToken          string `json:"sessionToken" mdsensitive:"true"`
+	AssumeRoleArn         string `json:"assumeRoleArn"`
+	ExternalID            string `json:"externalId"`
+	AssumeRoleSessionName string `json:"assumeRoleSessionName"`
+	UseFIPSEndpoint       bool   `json:"useFipsEndpoint,string"`
+	UseDualStackEndpoint  bool   `json:"useDualStackEndpoint,string"`
+	FailoverRegions       string `json:"failoverRegions"`
 }
 
 type dataPayload struct {
@@ -54,6 +62,10 @@ func NewAWSSNS(logger logger.Logger) bindings.OutputBinding {
 
 // Init does metadata parsing.
 func (a *AWSSNS) Init(metadata bindings.Metadata) error {
+	if err := bindings.ValidateConfirmedDelivery(metadata.Properties); err != nil {
+		return err
+	}
+
 	m, err := a.parseMetadata(metadata)
 	if err != nil {
 		return err
@@ -84,7 +96,21 @@ func (a *AWSSNS) parseMetadata(metadata bindings.Metadata) (*snsMetadata, error)
 }
 
 func (a *AWSSNS) getClient(metadata *snsMetadata) (*sns.SNS, error) {
-	sess, err := awsAuth.GetClient(metadata.AccessKey, metadata.SecretKey, metadata.SessionToken, metadata.Region, metadata.Endpoint)
+	sess, err := awsAuth.NewSession(awsAuth.Options{
+		AccessKey:    metadata.AccessKey,
+		SecretKey:    metadata.SecretKey,
+		SessionToken: metadata.SessionToken,
+		Region:       metadata.Region,
+		Endpoint:     metadata.Endpoint,
+		AssumeRole: awsAuth.AssumeRole{
+			RoleARN:     metadata.AssumeRoleArn,
+			ExternalID:  metadata.ExternalID,
+			SessionName: metadata.AssumeRoleSessionName,
+		},
+		UseFIPSEndpoint:      metadata.UseFIPSEndpoint,
+		UseDualStackEndpoint: metadata.UseDualStackEndpoint,
+		FailoverRegions:      strings.Split(metadata.FailoverRegions, ","),
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -118,3 +144,9 @@ func (a *AWSSNS) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bind
 
 	return nil, nil
 }
+
+// GetComponentMetadataSchema returns the schema of the metadata of the AWS SNS binding.
+func (a *AWSSNS) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(snsMetadata{})
+	return fields
+}
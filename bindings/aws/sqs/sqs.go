@@ -16,6 +16,11 @@ package sqs
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -23,33 +28,77 @@ import (
 
 	"github.com/dapr/components-contrib/bindings"
 	awsAuth "github.com/dapr/components-contrib/internal/authentication/aws"
+	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 )
 
+const (
+	fifoQueueSuffix = ".fifo"
+
+	// Write metadata
+	messageGroupIDKey         = "messageGroupId"
+	messageDeduplicationIDKey = "messageDeduplicationId"
+
+	defaultWaitTimeSeconds = 20
+	maxWaitTimeSeconds     = 20
+	maxMessages            = 10
+	// Messages of FIFO queues are received in batches by default, as the messages of each group are handled in order
+	defaultFifoMaxNumberOfMessages = maxMessages
+	// Handled messages are deleted at least this often while the other messages of their batch are still handled, so
+	// that a slow message group doesn't hold them back until their visibility timeout expires.
+	defaultDeleteInterval = time.Second
+)
+
 // AWSSQS allows receiving and sending data to/from AWS SQS.
 type AWSSQS struct {
 	Client   *sqs.SQS
 	QueueURL *string
 
-	logger logger.Logger
+	fifo                bool
+	visibilityTimeout   int64
+	waitTimeSeconds     int64
+	maxNumberOfMessages int64
+	deleteInterval      time.Duration
+	logger              logger.Logger
 }
 
 type sqsMetadata struct {
-	QueueName    string `json:"queueName"`
-	Region       string `json:"region"`
-	Endpoint     string `json:"endpoint"`
-	AccessKey    string `json:"accessKey"`
-	SecretKey    string `json:"secretKey"`
-	SessionToken string `json:"sessionToken"`
+	QueueName             string `json:"queueName"`
+	Region                string `json:"region"`
+	Endpoint              string `json:"endpoint"`
+	AccessKey             string `json:"accessKey"`
+	SecretKey             string `json:"secretKey" mdsensitive:"true"`
+	SessionToken          string `json:"sessionToken" mdsensitive:"true"`
+	AssumeRoleArn         string `json:"assumeRoleArn"`
+	ExternalID            string `json:"externalId"`
+	AssumeRoleSessionName string `json:"assumeRoleSessionName"`
+	UseFIPSEndpoint       bool   `json:"useFipsEndpoint,string"`
+	UseDualStackEndpoint  bool   `json:"useDualStackEndpoint,string"`
+	FailoverRegions       string `json:"failoverRegions"`
+	// If set, enables or disables content-based deduplication on the FIFO queue.
+	ContentBasedDeduplication string `json:"contentBasedDeduplication"`
+	// Seconds a received message is hidden from other consumers; if 0, the visibility timeout of the queue applies.
+	VisibilityTimeout int64 `json:"visibilityTimeout,string"`
+	// Seconds to wait for messages when receiving (long polling), between 0 and 20.
+	WaitTimeSeconds *int64 `json:"waitTimeSeconds,string"`
+	// Maximum number of messages received at once, between 1 and 10.
+	MaxNumberOfMessages int64 `json:"maxNumberOfMessages,string"`
 }
 
 // NewAWSSQS returns a new AWS SQS instance.
 func NewAWSSQS(logger logger.Logger) bindings.InputOutputBinding {
-	return &AWSSQS{logger: logger}
+	return &AWSSQS{
+		deleteInterval: defaultDeleteInterval,
+		logger:         logger,
+	}
 }
 
 // Init does metadata parsing and connection creation.
 func (a *AWSSQS) Init(metadata bindings.Metadata) error {
+	if err := bindings.ValidateConfirmedDelivery(metadata.Properties); err != nil {
+		return err
+	}
+
 	m, err := a.parseSQSMetadata(metadata)
 	if err != nil {
 		return err
@@ -70,6 +119,59 @@ func (a *AWSSQS) Init(metadata bindings.Metadata) error {
 
 	a.QueueURL = resultURL.QueueUrl
 	a.Client = client
+	// FIFO queues are identified by their name
+	a.fifo = strings.HasSuffix(queueName, fifoQueueSuffix)
+
+	if err = a.setReceiveOptions(m); err != nil {
+		return err
+	}
+
+	if m.ContentBasedDeduplication != "" {
+		if !a.fifo {
+			return errors.New("aws sqs error: contentBasedDeduplication is only supported by FIFO queues")
+		}
+		dedup, err := strconv.ParseBool(m.ContentBasedDeduplication)
+		if err != nil {
+			return fmt.Errorf("aws sqs error: invalid contentBasedDeduplication %s: %w", m.ContentBasedDeduplication, err)
+		}
+		_, err = client.SetQueueAttributes(&sqs.SetQueueAttributesInput{
+			QueueUrl: a.QueueURL,
+			Attributes: map[string]*string{
+				sqs.QueueAttributeNameContentBasedDeduplication: aws.String(strconv.FormatBool(dedup)),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("aws sqs error: failed to set contentBasedDeduplication on queue %s: %w", queueName, err)
+		}
+	}
+
+	return nil
+}
+
+func (a *AWSSQS) setReceiveOptions(m *sqsMetadata) error {
+	if m.VisibilityTimeout < 0 {
+		return fmt.Errorf("aws sqs error: invalid visibilityTimeout %d, must be greater than or equal to 0", m.VisibilityTimeout)
+	}
+	a.visibilityTimeout = m.VisibilityTimeout
+
+	a.waitTimeSeconds = defaultWaitTimeSeconds
+	if m.WaitTimeSeconds != nil {
+		if *m.WaitTimeSeconds < 0 || *m.WaitTimeSeconds > maxWaitTimeSeconds {
+			return fmt.Errorf("aws sqs error: invalid waitTimeSeconds %d, must be between 0 and %d", *m.WaitTimeSeconds, maxWaitTimeSeconds)
+		}
+		a.waitTimeSeconds = *m.WaitTimeSeconds
+	}
+
+	switch {
+	case m.MaxNumberOfMessages < 0 || m.MaxNumberOfMessages > maxMessages:
+		return fmt.Errorf("aws sqs error: invalid maxNumberOfMessages %d, must be between 1 and %d", m.MaxNumberOfMessages, maxMessages)
+	case m.MaxNumberOfMessages > 0:
+		a.maxNumberOfMessages = m.MaxNumberOfMessages
+	case a.fifo:
+		a.maxNumberOfMessages = defaultFifoMaxNumberOfMessages
+	default:
+		a.maxNumberOfMessages = 1
+	}
 
 	return nil
 }
@@ -80,50 +182,51 @@ func (a *AWSSQS) Operations() []bindings.OperationKind {
 
 func (a *AWSSQS) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
 	msgBody := string(req.Data)
-	_, err := a.Client.SendMessageWithContext(ctx, &sqs.SendMessageInput{
+	input := &sqs.SendMessageInput{
 		MessageBody: &msgBody,
 		QueueUrl:    a.QueueURL,
-	})
+	}
+	if val := req.Metadata[messageGroupIDKey]; val != "" {
+		input.MessageGroupId = aws.String(val)
+	} else if a.fifo {
+		return nil, fmt.Errorf("aws sqs error: %s is required to send messages to a FIFO queue", messageGroupIDKey)
+	}
+	// Without a deduplication ID, FIFO queues must have content-based deduplication enabled
+	if val := req.Metadata[messageDeduplicationIDKey]; val != "" {
+		input.MessageDeduplicationId = aws.String(val)
+	}
+	_, err := a.Client.SendMessageWithContext(ctx, input)
 
 	return nil, err
 }
 
 func (a *AWSSQS) Read(ctx context.Context, handler bindings.Handler) error {
+	input := &sqs.ReceiveMessageInput{
+		QueueUrl: a.QueueURL,
+		AttributeNames: aws.StringSlice([]string{
+			"SentTimestamp",
+			sqs.MessageSystemAttributeNameMessageGroupId,
+		}),
+		MaxNumberOfMessages: aws.Int64(a.maxNumberOfMessages),
+		MessageAttributeNames: aws.StringSlice([]string{
+			"All",
+		}),
+		WaitTimeSeconds: aws.Int64(a.waitTimeSeconds),
+	}
+	if a.visibilityTimeout > 0 {
+		input.VisibilityTimeout = aws.Int64(a.visibilityTimeout)
+	}
+
 	go func() {
 		// Repeat until the context is canceled
 		for ctx.Err() == nil {
-			result, err := a.Client.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
-				QueueUrl: a.QueueURL,
-				AttributeNames: aws.StringSlice([]string{
-					"SentTimestamp",
-				}),
-				MaxNumberOfMessages: aws.Int64(1),
-				MessageAttributeNames: aws.StringSlice([]string{
-					"All",
-				}),
-				WaitTimeSeconds: aws.Int64(20),
-			})
+			result, err := a.Client.ReceiveMessageWithContext(ctx, input)
 			if err != nil {
 				a.logger.Errorf("Unable to receive message from queue %q, %v.", *a.QueueURL, err)
 			}
 
-			if len(result.Messages) > 0 {
-				for _, m := range result.Messages {
-					body := m.Body
-					res := bindings.ReadResponse{
-						Data: []byte(*body),
-					}
-					_, err := handler(ctx, &res)
-					if err == nil {
-						msgHandle := m.ReceiptHandle
-
-						// Use a background context here because ctx may be canceled already
-						a.Client.DeleteMessageWithContext(context.Background(), &sqs.DeleteMessageInput{
-							QueueUrl:      a.QueueURL,
-							ReceiptHandle: msgHandle,
-						})
-					}
-				}
+			if result != nil && len(result.Messages) > 0 {
+				a.handleMessages(ctx, result.Messages, handler)
 			}
 
 			time.Sleep(time.Millisecond * 50)
@@ -133,6 +236,120 @@ func (a *AWSSQS) Read(ctx context.Context, handler bindings.Handler) error {
 	return nil
 }
 
+// handleMessages invokes the handler for each message, in order within each message group, and deletes the handled
+// messages in batches, flushed every deleteInterval and once all the messages are handled.
+func (a *AWSSQS) handleMessages(ctx context.Context, messages []*sqs.Message, handler bindings.Handler) {
+	var (
+		wg      sync.WaitGroup
+		lock    sync.Mutex
+		handled = make([]*sqs.Message, 0, len(messages))
+	)
+	for _, group := range groupMessages(messages) {
+		wg.Add(1)
+		go func(group []*sqs.Message) {
+			defer wg.Done()
+			for _, m := range group {
+				if !a.handleMessage(ctx, m, handler) {
+					// The following messages of the group are received again after the failed one, to preserve their order
+					return
+				}
+				lock.Lock()
+				handled = append(handled, m)
+				lock.Unlock()
+			}
+		}(group)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	flush := func() {
+		lock.Lock()
+		batch := handled
+		handled = nil
+		lock.Unlock()
+		if len(batch) > 0 {
+			a.deleteMessages(batch)
+		}
+	}
+
+	ticker := time.NewTicker(a.deleteInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			flush()
+		case <-done:
+			flush()
+			return
+		}
+	}
+}
+
+// handleMessage invokes the handler and returns whether it succeeded.
+func (a *AWSSQS) handleMessage(ctx context.Context, m *sqs.Message, handler bindings.Handler) bool {
+	body := m.Body
+	res := bindings.ReadResponse{
+		Data: []byte(*body),
+	}
+	if groupID, ok := m.Attributes[sqs.MessageSystemAttributeNameMessageGroupId]; ok && groupID != nil {
+		res.Metadata = map[string]string{messageGroupIDKey: *groupID}
+	}
+	_, err := handler(ctx, &res)
+
+	return err == nil
+}
+
+// deleteMessages deletes the messages from the queue in a single request.
+// Messages that fail to be deleted are received again once their visibility timeout expires.
+func (a *AWSSQS) deleteMessages(messages []*sqs.Message) {
+	entries := make([]*sqs.DeleteMessageBatchRequestEntry, len(messages))
+	for i, m := range messages {
+		entries[i] = &sqs.DeleteMessageBatchRequestEntry{
+			Id:            aws.String(strconv.Itoa(i)),
+			ReceiptHandle: m.ReceiptHandle,
+		}
+	}
+
+	// Use a background context here because ctx may be canceled already
+	res, err := a.Client.DeleteMessageBatchWithContext(context.Background(), &sqs.DeleteMessageBatchInput{
+		QueueUrl: a.QueueURL,
+		Entries:  entries,
+	})
+	if err != nil {
+		a.logger.Errorf("Unable to delete %d messages from queue %q, %v.", len(messages), *a.QueueURL, err)
+		return
+	}
+	for _, f := range res.Failed {
+		a.logger.Errorf("Unable to delete message from queue %q, %s: %s.", *a.QueueURL, aws.StringValue(f.Code), aws.StringValue(f.Message))
+	}
+}
+
+// groupMessages splits the messages by message group, preserving their order.
+// Messages without a group, from standard queues, are all in the same group.
+func groupMessages(messages []*sqs.Message) [][]*sqs.Message {
+	groups := make([][]*sqs.Message, 0, 1)
+	index := make(map[string]int)
+	for _, m := range messages {
+		groupID := ""
+		if val, ok := m.Attributes[sqs.MessageSystemAttributeNameMessageGroupId]; ok && val != nil {
+			groupID = *val
+		}
+		i, ok := index[groupID]
+		if !ok {
+			i = len(groups)
+			index[groupID] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], m)
+	}
+
+	return groups
+}
+
 func (a *AWSSQS) parseSQSMetadata(metadata bindings.Metadata) (*sqsMetadata, error) {
 	b, err := json.Marshal(metadata.Properties)
 	if err != nil {
@@ -149,7 +366,21 @@ func (a *AWSSQS) parseSQSMetadata(metadata bindings.Metadata) (*sqsMetadata, err
 }
 
 func (a *AWSSQS) getClient(metadata *sqsMetadata) (*sqs.SQS, error) {
-	sess, err := awsAuth.GetClient(metadata.AccessKey, metadata.SecretKey, metadata.SessionToken, metadata.Region, metadata.Endpoint)
+	sess, err := awsAuth.NewSession(awsAuth.Options{
+		AccessKey:    metadata.AccessKey,
+		SecretKey:    metadata.SecretKey,
+		SessionToken: metadata.SessionToken,
+		Region:       metadata.Region,
+		Endpoint:     metadata.Endpoint,
+		AssumeRole: awsAuth.AssumeRole{
+			RoleARN:     metadata.AssumeRoleArn,
+			ExternalID:  metadata.ExternalID,
+			SessionName: metadata.AssumeRoleSessionName,
+		},
+		UseFIPSEndpoint:      metadata.UseFIPSEndpoint,
+		UseDualStackEndpoint: metadata.UseDualStackEndpoint,
+		FailoverRegions:      strings.Split(metadata.FailoverRegions, ","),
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -157,3 +388,12 @@ func (a *AWSSQS) getClient(metadata *sqsMetadata) (*sqs.SQS, error) {
 
 	return c, nil
 }
+
+// GetComponentMetadataSchema returns the schema of the metadata of the AWS SQS binding.
+func (a *AWSSQS) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(sqsMetadata{
+		WaitTimeSeconds:     aws.Int64(defaultWaitTimeSeconds),
+		MaxNumberOfMessages: 1,
+	})
+	return fields
+}
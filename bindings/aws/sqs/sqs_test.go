@@ -14,17 +14,31 @@ limitations under the License.
 package sqs
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/kit/logger"
 )
 
 func TestParseMetadata(t *testing.T) {
 	m := bindings.Metadata{}
 	m.Properties = map[string]string{
 		"QueueName": "a", "Region": "a", "AccessKey": "a", "SecretKey": "a", "Endpoint": "a", "SessionToken": "t",
+		"ContentBasedDeduplication": "true",
+		"visibilityTimeout":         "30", "waitTimeSeconds": "0", "maxNumberOfMessages": "5",
 	}
 	s := AWSSQS{}
 	sqsM, err := s.parseSQSMetadata(m)
@@ -35,4 +49,137 @@ func TestParseMetadata(t *testing.T) {
 	assert.Equal(t, "a", sqsM.SecretKey)
 	assert.Equal(t, "a", sqsM.Endpoint)
 	assert.Equal(t, "t", sqsM.SessionToken)
+	assert.Equal(t, "true", sqsM.ContentBasedDeduplication)
+	assert.Equal(t, int64(30), sqsM.VisibilityTimeout)
+	assert.Equal(t, int64(0), *sqsM.WaitTimeSeconds)
+	assert.Equal(t, int64(5), sqsM.MaxNumberOfMessages)
+}
+
+func TestSetReceiveOptions(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		s := AWSSQS{}
+		err := s.setReceiveOptions(&sqsMetadata{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), s.visibilityTimeout)
+		assert.Equal(t, int64(20), s.waitTimeSeconds)
+		assert.Equal(t, int64(1), s.maxNumberOfMessages)
+	})
+
+	t.Run("FIFO defaults", func(t *testing.T) {
+		s := AWSSQS{fifo: true}
+		err := s.setReceiveOptions(&sqsMetadata{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(10), s.maxNumberOfMessages)
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		s := AWSSQS{fifo: true}
+		err := s.setReceiveOptions(&sqsMetadata{VisibilityTimeout: 60, WaitTimeSeconds: aws.Int64(0), MaxNumberOfMessages: 2})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(60), s.visibilityTimeout)
+		assert.Equal(t, int64(0), s.waitTimeSeconds)
+		assert.Equal(t, int64(2), s.maxNumberOfMessages)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		s := AWSSQS{}
+
+		assert.Error(t, s.setReceiveOptions(&sqsMetadata{VisibilityTimeout: -1}))
+		assert.Error(t, s.setReceiveOptions(&sqsMetadata{WaitTimeSeconds: aws.Int64(21)}))
+		assert.Error(t, s.setReceiveOptions(&sqsMetadata{MaxNumberOfMessages: 11}))
+	})
+}
+
+func TestInvokeFifoRequiresMessageGroupID(t *testing.T) {
+	s := AWSSQS{fifo: true}
+
+	_, err := s.Invoke(context.Background(), &bindings.InvokeRequest{Data: []byte("a")})
+
+	assert.ErrorContains(t, err, messageGroupIDKey)
+}
+
+func TestGroupMessages(t *testing.T) {
+	message := func(body, groupID string) *sqs.Message {
+		m := &sqs.Message{Body: aws.String(body)}
+		if groupID != "" {
+			m.Attributes = map[string]*string{sqs.MessageSystemAttributeNameMessageGroupId: aws.String(groupID)}
+		}
+
+		return m
+	}
+
+	t.Run("standard queue", func(t *testing.T) {
+		messages := []*sqs.Message{message("1", ""), message("2", "")}
+
+		groups := groupMessages(messages)
+
+		assert.Equal(t, [][]*sqs.Message{messages}, groups)
+	})
+
+	t.Run("FIFO queue", func(t *testing.T) {
+		a1, b1, a2, c1, b2 := message("a1", "a"), message("b1", "b"), message("a2", "a"), message("c1", "c"), message("b2", "b")
+
+		groups := groupMessages([]*sqs.Message{a1, b1, a2, c1, b2})
+
+		assert.Equal(t, [][]*sqs.Message{{a1, a2}, {b1, b2}, {c1}}, groups)
+	})
+}
+
+func TestHandleMessagesDeletesOnInterval(t *testing.T) {
+	var (
+		lock    sync.Mutex
+		deleted []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		lock.Lock()
+		for i := 1; r.Form.Get("DeleteMessageBatchRequestEntry."+strconv.Itoa(i)+".ReceiptHandle") != ""; i++ {
+			deleted = append(deleted, r.Form.Get("DeleteMessageBatchRequestEntry."+strconv.Itoa(i)+".ReceiptHandle"))
+		}
+		lock.Unlock()
+		w.Write([]byte(`<DeleteMessageBatchResponse><DeleteMessageBatchResult></DeleteMessageBatchResult></DeleteMessageBatchResponse>`))
+	}))
+	defer server.Close()
+
+	sess, err := session.NewSession(&aws.Config{
+		Endpoint:    aws.String(server.URL),
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("a", "b", ""),
+	})
+	require.NoError(t, err)
+	a := NewAWSSQS(logger.NewLogger("test")).(*AWSSQS)
+	a.Client = sqs.New(sess)
+	a.QueueURL = aws.String(server.URL + "/queue.fifo")
+	a.deleteInterval = 10 * time.Millisecond
+
+	isDeleted := func(handle string) func() bool {
+		return func() bool {
+			lock.Lock()
+			defer lock.Unlock()
+			for _, d := range deleted {
+				if d == handle {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	// The message of the slow group is handled once the message of the other group is deleted
+	messages := []*sqs.Message{
+		{ReceiptHandle: aws.String("slow"), Body: aws.String("1"), Attributes: map[string]*string{sqs.MessageSystemAttributeNameMessageGroupId: aws.String("a")}},
+		{ReceiptHandle: aws.String("fast"), Body: aws.String("2"), Attributes: map[string]*string{sqs.MessageSystemAttributeNameMessageGroupId: aws.String("b")}},
+	}
+	a.handleMessages(context.Background(), messages, func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		if res.Metadata[messageGroupIDKey] == "a" {
+			assert.Eventually(t, isDeleted("fast"), time.Second, 5*time.Millisecond)
+		}
+		return nil, nil
+	})
+
+	assert.True(t, isDeleted("fast")())
+	assert.True(t, isDeleted("slow")())
 }
@@ -0,0 +1,300 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stepfunctions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sfn"
+	"github.com/aws/aws-sdk-go/service/sfn/sfniface"
+
+	"github.com/dapr/components-contrib/bindings"
+	awsAuth "github.com/dapr/components-contrib/internal/authentication/aws"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// startExecutionOperation starts an execution of the state machine, with the request data as input.
+	startExecutionOperation bindings.OperationKind = "startExecution"
+	// describeExecutionOperation returns the status, and the output once completed, of an execution.
+	describeExecutionOperation bindings.OperationKind = "describeExecution"
+	// sendTaskSuccessOperation completes a task waiting for a callback, with the request data as output.
+	sendTaskSuccessOperation bindings.OperationKind = "sendTaskSuccess"
+	// sendTaskFailureOperation fails a task waiting for a callback.
+	sendTaskFailureOperation bindings.OperationKind = "sendTaskFailure"
+
+	metadataStateMachineArn = "stateMachineArn"
+	metadataName            = "name"
+	metadataExecutionArn    = "executionArn"
+	metadataTaskToken       = "taskToken"
+	metadataError           = "error"
+	metadataCause           = "cause"
+)
+
+// AWSStepFunctions is an AWS Step Functions binding.
+type AWSStepFunctions struct {
+	client   sfniface.SFNAPI
+	metadata *stepFunctionsMetadata
+
+	logger logger.Logger
+}
+
+type stepFunctionsMetadata struct {
+	StateMachineArn       string `json:"stateMachineArn"`
+	Region                string `json:"region"`
+	Endpoint              string `json:"endpoint"`
+	AccessKey             string `json:"accessKey"`
+	SecretKey             string `json:"secretKey" mdsensitive:"true"`
+	SessionToken          string `json:"sessionToken" mdsensitive:"true"`
+	AssumeRoleArn         string `json:"assumeRoleArn"`
+	ExternalID            string `json:"externalId"`
+	AssumeRoleSessionName string `json:"assumeRoleSessionName"`
+}
+
+type startExecutionResponse struct {
+	ExecutionArn string    `json:"executionArn"`
+	StartDate    time.Time `json:"startDate"`
+}
+
+type describeExecutionResponse struct {
+	ExecutionArn    string          `json:"executionArn"`
+	StateMachineArn string          `json:"stateMachineArn"`
+	Name            string          `json:"name,omitempty"`
+	Status          string          `json:"status"`
+	StartDate       *time.Time      `json:"startDate,omitempty"`
+	StopDate        *time.Time      `json:"stopDate,omitempty"`
+	Input           json.RawMessage `json:"input,omitempty"`
+	Output          json.RawMessage `json:"output,omitempty"`
+}
+
+// NewAWSStepFunctions creates a new AWSStepFunctions binding instance.
+func NewAWSStepFunctions(logger logger.Logger) bindings.OutputBinding {
+	return &AWSStepFunctions{logger: logger}
+}
+
+// Init does metadata parsing and client creation.
+func (a *AWSStepFunctions) Init(metadata bindings.Metadata) error {
+	m, err := a.parseMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	client, err := a.getClient(m)
+	if err != nil {
+		return err
+	}
+	a.client = client
+	a.metadata = m
+
+	return nil
+}
+
+func (a *AWSStepFunctions) parseMetadata(metadata bindings.Metadata) (*stepFunctionsMetadata, error) {
+	b, err := json.Marshal(metadata.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	var m stepFunctionsMetadata
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+func (a *AWSStepFunctions) getClient(metadata *stepFunctionsMetadata) (*sfn.SFN, error) {
+	sess, err := awsAuth.NewSession(awsAuth.Options{
+		AccessKey:    metadata.AccessKey,
+		SecretKey:    metadata.SecretKey,
+		SessionToken: metadata.SessionToken,
+		Region:       metadata.Region,
+		Endpoint:     metadata.Endpoint,
+		AssumeRole: awsAuth.AssumeRole{
+			RoleARN:     metadata.AssumeRoleArn,
+			ExternalID:  metadata.ExternalID,
+			SessionName: metadata.AssumeRoleSessionName,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	c := sfn.New(sess)
+
+	return c, nil
+}
+
+func (a *AWSStepFunctions) Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{
+		startExecutionOperation,
+		describeExecutionOperation,
+		sendTaskSuccessOperation,
+		sendTaskFailureOperation,
+	}
+}
+
+func (a *AWSStepFunctions) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	switch req.Operation {
+	case startExecutionOperation:
+		return a.startExecution(ctx, req)
+	case describeExecutionOperation:
+		return a.describeExecution(ctx, req)
+	case sendTaskSuccessOperation:
+		return nil, a.sendTaskSuccess(ctx, req)
+	case sendTaskFailureOperation:
+		return nil, a.sendTaskFailure(ctx, req)
+	default:
+		return nil, fmt.Errorf("step functions binding error: unsupported operation %s", req.Operation)
+	}
+}
+
+// startExecution starts an execution of the state machine of the component, or of the "stateMachineArn" request
+// metadata, optionally named with the "name" request metadata.
+func (a *AWSStepFunctions) startExecution(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	stateMachineArn := a.metadata.StateMachineArn
+	if val, ok := req.Metadata[metadataStateMachineArn]; ok && val != "" {
+		stateMachineArn = val
+	}
+	if stateMachineArn == "" {
+		return nil, errors.New("step functions binding error: stateMachineArn property not supplied in configuration- or request-metadata")
+	}
+
+	input := &sfn.StartExecutionInput{
+		StateMachineArn: aws.String(stateMachineArn),
+	}
+	if len(req.Data) > 0 {
+		input.Input = aws.String(string(req.Data))
+	}
+	if val, ok := req.Metadata[metadataName]; ok && val != "" {
+		input.Name = aws.String(val)
+	}
+
+	out, err := a.client.StartExecutionWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("step functions binding error: error starting execution of %s: %w", stateMachineArn, err)
+	}
+
+	data, err := json.Marshal(startExecutionResponse{
+		ExecutionArn: aws.StringValue(out.ExecutionArn),
+		StartDate:    aws.TimeValue(out.StartDate),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &bindings.InvokeResponse{
+		Data: data,
+		Metadata: map[string]string{
+			metadataExecutionArn: aws.StringValue(out.ExecutionArn),
+		},
+	}, nil
+}
+
+// describeExecution returns the execution of the "executionArn" request metadata.
+func (a *AWSStepFunctions) describeExecution(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	executionArn := req.Metadata[metadataExecutionArn]
+	if executionArn == "" {
+		return nil, errors.New("step functions binding error: executionArn property not supplied in request-metadata")
+	}
+
+	out, err := a.client.DescribeExecutionWithContext(ctx, &sfn.DescribeExecutionInput{
+		ExecutionArn: aws.String(executionArn),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("step functions binding error: error describing execution %s: %w", executionArn, err)
+	}
+
+	res := describeExecutionResponse{
+		ExecutionArn:    aws.StringValue(out.ExecutionArn),
+		StateMachineArn: aws.StringValue(out.StateMachineArn),
+		Name:            aws.StringValue(out.Name),
+		Status:          aws.StringValue(out.Status),
+		StartDate:       out.StartDate,
+		StopDate:        out.StopDate,
+	}
+	if out.Input != nil {
+		res.Input = json.RawMessage(*out.Input)
+	}
+	if out.Output != nil {
+		res.Output = json.RawMessage(*out.Output)
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bindings.InvokeResponse{Data: data}, nil
+}
+
+// sendTaskSuccess completes the task of the "taskToken" request metadata, with the request data as output.
+func (a *AWSStepFunctions) sendTaskSuccess(ctx context.Context, req *bindings.InvokeRequest) error {
+	taskToken := req.Metadata[metadataTaskToken]
+	if taskToken == "" {
+		return errors.New("step functions binding error: taskToken property not supplied in request-metadata")
+	}
+
+	// The output is required, and must be JSON
+	output := "{}"
+	if len(req.Data) > 0 {
+		output = string(req.Data)
+	}
+
+	_, err := a.client.SendTaskSuccessWithContext(ctx, &sfn.SendTaskSuccessInput{
+		TaskToken: aws.String(taskToken),
+		Output:    aws.String(output),
+	})
+	if err != nil {
+		return fmt.Errorf("step functions binding error: error sending task success: %w", err)
+	}
+
+	return nil
+}
+
+// sendTaskFailure fails the task of the "taskToken" request metadata, with the optional "error" and "cause" request
+// metadata.
+func (a *AWSStepFunctions) sendTaskFailure(ctx context.Context, req *bindings.InvokeRequest) error {
+	taskToken := req.Metadata[metadataTaskToken]
+	if taskToken == "" {
+		return errors.New("step functions binding error: taskToken property not supplied in request-metadata")
+	}
+
+	input := &sfn.SendTaskFailureInput{
+		TaskToken: aws.String(taskToken),
+	}
+	if val, ok := req.Metadata[metadataError]; ok && val != "" {
+		input.Error = aws.String(val)
+	}
+	if val, ok := req.Metadata[metadataCause]; ok && val != "" {
+		input.Cause = aws.String(val)
+	}
+
+	_, err := a.client.SendTaskFailureWithContext(ctx, input)
+	if err != nil {
+		return fmt.Errorf("step functions binding error: error sending task failure: %w", err)
+	}
+
+	return nil
+}
+
+// GetComponentMetadataSchema returns the schema of the metadata of the AWS Step Functions binding.
+func (a *AWSStepFunctions) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(stepFunctionsMetadata{})
+	return fields
+}
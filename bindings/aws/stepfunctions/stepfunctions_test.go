@@ -0,0 +1,251 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stepfunctions
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sfn"
+	"github.com/aws/aws-sdk-go/service/sfn/sfniface"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/kit/logger"
+)
+
+type mockedSFN struct {
+	StartExecutionFn    func(*sfn.StartExecutionInput) (*sfn.StartExecutionOutput, error)
+	DescribeExecutionFn func(*sfn.DescribeExecutionInput) (*sfn.DescribeExecutionOutput, error)
+	SendTaskSuccessFn   func(*sfn.SendTaskSuccessInput) (*sfn.SendTaskSuccessOutput, error)
+	SendTaskFailureFn   func(*sfn.SendTaskFailureInput) (*sfn.SendTaskFailureOutput, error)
+	sfniface.SFNAPI
+}
+
+func (m *mockedSFN) StartExecutionWithContext(_ context.Context, input *sfn.StartExecutionInput, _ ...request.Option) (*sfn.StartExecutionOutput, error) {
+	return m.StartExecutionFn(input)
+}
+
+func (m *mockedSFN) DescribeExecutionWithContext(_ context.Context, input *sfn.DescribeExecutionInput, _ ...request.Option) (*sfn.DescribeExecutionOutput, error) {
+	return m.DescribeExecutionFn(input)
+}
+
+func (m *mockedSFN) SendTaskSuccessWithContext(_ context.Context, input *sfn.SendTaskSuccessInput, _ ...request.Option) (*sfn.SendTaskSuccessOutput, error) {
+	return m.SendTaskSuccessFn(input)
+}
+
+func (m *mockedSFN) SendTaskFailureWithContext(_ context.Context, input *sfn.SendTaskFailureInput, _ ...request.Option) (*sfn.SendTaskFailureOutput, error) {
+	return m.SendTaskFailureFn(input)
+}
+
+func newStepFunctions(client sfniface.SFNAPI) *AWSStepFunctions {
+	return &AWSStepFunctions{
+		client:   client,
+		metadata: &stepFunctionsMetadata{StateMachineArn: "arn:machine"},
+		logger:   logger.NewLogger("test"),
+	}
+}
+
+func TestParseMetadata(t *testing.T) {
+	m := bindings.Metadata{}
+	m.Properties = map[string]string{
+		"stateMachineArn": "arn:machine", "region": "region", "accessKey": "key", "secretKey": "secret",
+	}
+	a := AWSStepFunctions{}
+	meta, err := a.parseMetadata(m)
+	assert.Nil(t, err)
+	assert.Equal(t, "arn:machine", meta.StateMachineArn)
+	assert.Equal(t, "region", meta.Region)
+	assert.Equal(t, "key", meta.AccessKey)
+	assert.Equal(t, "secret", meta.SecretKey)
+}
+
+func TestStartExecution(t *testing.T) {
+	startDate := time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("starts an execution of the state machine", func(t *testing.T) {
+		a := newStepFunctions(&mockedSFN{
+			StartExecutionFn: func(input *sfn.StartExecutionInput) (*sfn.StartExecutionOutput, error) {
+				assert.Equal(t, "arn:machine", *input.StateMachineArn)
+				assert.Equal(t, `{"order":1}`, *input.Input)
+				assert.Equal(t, "order-1", *input.Name)
+
+				return &sfn.StartExecutionOutput{
+					ExecutionArn: aws.String("arn:execution"),
+					StartDate:    &startDate,
+				}, nil
+			},
+		})
+
+		res, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: startExecutionOperation,
+			Data:      []byte(`{"order":1}`),
+			Metadata:  map[string]string{"name": "order-1"},
+		})
+
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"executionArn": "arn:execution", "startDate": "2022-11-01T00:00:00Z"}`, string(res.Data))
+		assert.Equal(t, "arn:execution", res.Metadata["executionArn"])
+	})
+
+	t.Run("state machine from the request metadata", func(t *testing.T) {
+		a := newStepFunctions(&mockedSFN{
+			StartExecutionFn: func(input *sfn.StartExecutionInput) (*sfn.StartExecutionOutput, error) {
+				assert.Equal(t, "arn:other", *input.StateMachineArn)
+				assert.Nil(t, input.Input)
+				assert.Nil(t, input.Name)
+
+				return &sfn.StartExecutionOutput{ExecutionArn: aws.String("arn:execution"), StartDate: &startDate}, nil
+			},
+		})
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: startExecutionOperation,
+			Metadata:  map[string]string{"stateMachineArn": "arn:other"},
+		})
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("missing state machine", func(t *testing.T) {
+		a := newStepFunctions(nil)
+		a.metadata.StateMachineArn = ""
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{Operation: startExecutionOperation})
+
+		assert.ErrorContains(t, err, "stateMachineArn")
+	})
+}
+
+func TestDescribeExecution(t *testing.T) {
+	t.Run("returns the execution", func(t *testing.T) {
+		startDate := time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)
+		a := newStepFunctions(&mockedSFN{
+			DescribeExecutionFn: func(input *sfn.DescribeExecutionInput) (*sfn.DescribeExecutionOutput, error) {
+				assert.Equal(t, "arn:execution", *input.ExecutionArn)
+
+				return &sfn.DescribeExecutionOutput{
+					ExecutionArn:    aws.String("arn:execution"),
+					StateMachineArn: aws.String("arn:machine"),
+					Name:            aws.String("order-1"),
+					Status:          aws.String(sfn.ExecutionStatusSucceeded),
+					StartDate:       &startDate,
+					Input:           aws.String(`{"order":1}`),
+					Output:          aws.String(`{"shipped":true}`),
+				}, nil
+			},
+		})
+
+		res, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: describeExecutionOperation,
+			Metadata:  map[string]string{"executionArn": "arn:execution"},
+		})
+
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{
+			"executionArn": "arn:execution",
+			"stateMachineArn": "arn:machine",
+			"name": "order-1",
+			"status": "SUCCEEDED",
+			"startDate": "2022-11-01T00:00:00Z",
+			"input": {"order": 1},
+			"output": {"shipped": true}
+		}`, string(res.Data))
+	})
+
+	t.Run("missing execution", func(t *testing.T) {
+		a := newStepFunctions(nil)
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{Operation: describeExecutionOperation})
+
+		assert.ErrorContains(t, err, "executionArn")
+	})
+}
+
+func TestSendTaskResult(t *testing.T) {
+	t.Run("task success", func(t *testing.T) {
+		a := newStepFunctions(&mockedSFN{
+			SendTaskSuccessFn: func(input *sfn.SendTaskSuccessInput) (*sfn.SendTaskSuccessOutput, error) {
+				assert.Equal(t, "token", *input.TaskToken)
+				assert.Equal(t, `{"approved":true}`, *input.Output)
+
+				return &sfn.SendTaskSuccessOutput{}, nil
+			},
+		})
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: sendTaskSuccessOperation,
+			Data:      []byte(`{"approved":true}`),
+			Metadata:  map[string]string{"taskToken": "token"},
+		})
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("task success without output", func(t *testing.T) {
+		a := newStepFunctions(&mockedSFN{
+			SendTaskSuccessFn: func(input *sfn.SendTaskSuccessInput) (*sfn.SendTaskSuccessOutput, error) {
+				assert.Equal(t, "{}", *input.Output)
+
+				return &sfn.SendTaskSuccessOutput{}, nil
+			},
+		})
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: sendTaskSuccessOperation,
+			Metadata:  map[string]string{"taskToken": "token"},
+		})
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("task failure", func(t *testing.T) {
+		a := newStepFunctions(&mockedSFN{
+			SendTaskFailureFn: func(input *sfn.SendTaskFailureInput) (*sfn.SendTaskFailureOutput, error) {
+				assert.Equal(t, "token", *input.TaskToken)
+				assert.Equal(t, "Rejected", *input.Error)
+				assert.Equal(t, "out of stock", *input.Cause)
+
+				return nil, errors.New("task timed out")
+			},
+		})
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: sendTaskFailureOperation,
+			Metadata:  map[string]string{"taskToken": "token", "error": "Rejected", "cause": "out of stock"},
+		})
+
+		assert.ErrorContains(t, err, "task timed out")
+	})
+
+	t.Run("missing task token", func(t *testing.T) {
+		a := newStepFunctions(nil)
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{Operation: sendTaskFailureOperation})
+
+		assert.ErrorContains(t, err, "taskToken")
+	})
+}
+
+func TestUnsupportedOperation(t *testing.T) {
+	a := newStepFunctions(nil)
+
+	_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{Operation: bindings.CreateOperation})
+
+	assert.Error(t, err)
+}
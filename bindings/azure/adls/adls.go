@@ -0,0 +1,433 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adls
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+	azauth "github.com/dapr/components-contrib/internal/authentication/azure"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// Path of the file or directory, relative to the file system.
+	metadataKeyPath = "path"
+	// Position at which the data is appended by the append operation, or up to which it's committed by the flush
+	// operation.
+	// By default the append operation appends the data at the end of the committed content of the file.
+	metadataKeyPosition = "position"
+	// Defines if the data appended by the append operation is committed right away.
+	metadataKeyFlush = "flush"
+	// Defines if the delete operation deletes non-empty directories.
+	metadataKeyRecursive = "recursive"
+	// Destination path of the rename operation.
+	metadataKeyDestinationPath = "destinationPath"
+
+	appendOperation           bindings.OperationKind = "append"
+	flushOperation            bindings.OperationKind = "flush"
+	createDirectoryOperation  bindings.OperationKind = "createDirectory"
+	renameOperation           bindings.OperationKind = "rename"
+	setAccessControlOperation bindings.OperationKind = "setAccessControl"
+	getAccessControlOperation bindings.OperationKind = "getAccessControl"
+
+	resourceFile      = "file"
+	resourceDirectory = "directory"
+
+	defaultTimeout = 30 * time.Second
+)
+
+var ErrMissingPath = errors.New("path is a required attribute")
+
+// AzureDataLakeStorage is an output binding managing the files and directories of a file system of an Azure Data Lake
+// Storage Gen2 account, i.e. a storage account with the hierarchical namespace enabled.
+type AzureDataLakeStorage struct {
+	metadata *adlsMetadata
+	client   *client
+
+	logger logger.Logger
+}
+
+type adlsMetadata struct {
+	AccountName string `mapstructure:"accountName" mdrequired:"true"`
+	// Shared key of the account; if not set, requests are authorized with Azure AD.
+	AccountKey string `mapstructure:"accountKey" mdsensitive:"true"`
+	FileSystem string `mapstructure:"fileSystem" mdrequired:"true"`
+	// Custom endpoint, e.g. of an emulator; the account name and the file system are appended to its path.
+	Endpoint     string `mapstructure:"endpoint"`
+	TimeoutInSec int    `mapstructure:"timeoutInSec"`
+}
+
+type createResponse struct {
+	FileURL string `json:"fileURL"`
+	Path    string `json:"path"`
+}
+
+type listPayload struct {
+	Directory    string `json:"directory"`
+	Recursive    bool   `json:"recursive"`
+	Continuation string `json:"continuation"`
+	MaxResults   int    `json:"maxResults"`
+}
+
+// NewAzureDataLakeStorage returns a new Azure Data Lake Storage Gen2 output binding.
+func NewAzureDataLakeStorage(logger logger.Logger) bindings.OutputBinding {
+	return &AzureDataLakeStorage{logger: logger}
+}
+
+// Init parses the metadata and creates the client of the file system.
+func (a *AzureDataLakeStorage) Init(md bindings.Metadata) error {
+	m, err := parseMetadata(md.Properties)
+	if err != nil {
+		return err
+	}
+
+	settings, err := azauth.NewEnvironmentSettings("storage", md.Properties)
+	if err != nil {
+		return err
+	}
+
+	c := &client{
+		fileSystem:  m.FileSystem,
+		accountName: m.AccountName,
+		userAgent:   "dapr-" + logger.DaprVersion,
+		httpClient: &http.Client{
+			Timeout: time.Duration(m.TimeoutInSec) * time.Second,
+		},
+	}
+	if m.Endpoint != "" {
+		c.fileSystemURL = fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(m.Endpoint, "/"), m.AccountName, m.FileSystem)
+	} else {
+		c.fileSystemURL = fmt.Sprintf("https://%s.dfs.%s/%s", m.AccountName, settings.AzureEnvironment.StorageEndpointSuffix, m.FileSystem)
+	}
+
+	// Use the shared key if set, falling back to Azure AD
+	if m.AccountKey != "" {
+		c.accountKey, err = base64.StdEncoding.DecodeString(m.AccountKey)
+		if err != nil {
+			return fmt.Errorf("azure data lake storage binding error: invalid accountKey: %w", err)
+		}
+	} else {
+		c.credential, err = settings.GetTokenCredential()
+		if err != nil {
+			return err
+		}
+		c.scope = strings.TrimSuffix(settings.Resource, "/") + "/.default"
+	}
+
+	a.metadata = m
+	a.client = c
+
+	return nil
+}
+
+func parseMetadata(md map[string]string) (*adlsMetadata, error) {
+	m := adlsMetadata{
+		TimeoutInSec: int(defaultTimeout / time.Second),
+	}
+	err := metadata.DecodeMetadata(md, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.AccountName == "" {
+		return nil, errors.New("azure data lake storage binding error: accountName is required")
+	}
+	if m.FileSystem == "" {
+		return nil, errors.New("azure data lake storage binding error: fileSystem is required")
+	}
+	if m.TimeoutInSec < 1 {
+		return nil, fmt.Errorf("azure data lake storage binding error: invalid timeoutInSec %d", m.TimeoutInSec)
+	}
+
+	return &m, nil
+}
+
+func (a *AzureDataLakeStorage) Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{
+		bindings.CreateOperation,
+		appendOperation,
+		flushOperation,
+		bindings.GetOperation,
+		bindings.DeleteOperation,
+		bindings.ListOperation,
+		createDirectoryOperation,
+		renameOperation,
+		setAccessControlOperation,
+		getAccessControlOperation,
+	}
+}
+
+func (a *AzureDataLakeStorage) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	if req.Operation == bindings.ListOperation {
+		return a.list(ctx, req)
+	}
+
+	path := req.Metadata[metadataKeyPath]
+	if path == "" {
+		return nil, ErrMissingPath
+	}
+
+	switch req.Operation {
+	case bindings.CreateOperation:
+		return a.create(ctx, path, req)
+	case appendOperation:
+		return a.append(ctx, path, req)
+	case flushOperation:
+		return nil, a.flush(ctx, path, req)
+	case bindings.GetOperation:
+		return a.get(ctx, path)
+	case bindings.DeleteOperation:
+		return nil, a.delete(ctx, path, req)
+	case createDirectoryOperation:
+		return nil, a.client.createPath(ctx, path, resourceDirectory)
+	case renameOperation:
+		return nil, a.rename(ctx, path, req)
+	case setAccessControlOperation:
+		return nil, a.setAccessControl(ctx, path, req)
+	case getAccessControlOperation:
+		return a.getAccessControl(ctx, path)
+	default:
+		return nil, fmt.Errorf("azure data lake storage binding error: unsupported operation %s", req.Operation)
+	}
+}
+
+// create creates the file with the request data, replacing it if it exists.
+func (a *AzureDataLakeStorage) create(ctx context.Context, path string, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	err := a.client.createPath(ctx, path, resourceFile)
+	if err != nil {
+		return nil, fmt.Errorf("azure data lake storage binding error: error creating file %s: %w", path, err)
+	}
+
+	if len(req.Data) > 0 {
+		err = a.client.appendData(ctx, path, 0, req.Data)
+		if err == nil {
+			err = a.client.flush(ctx, path, int64(len(req.Data)))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("azure data lake storage binding error: error uploading file %s: %w", path, err)
+		}
+	}
+
+	b, err := json.Marshal(createResponse{
+		FileURL: a.client.fileSystemURL + "/" + escapePath(path),
+		Path:    path,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("azure data lake storage binding error: error marshalling create response: %w", err)
+	}
+
+	return &bindings.InvokeResponse{
+		Data: b,
+	}, nil
+}
+
+// append appends the request data to the file, returning the position following it in the response metadata.
+func (a *AzureDataLakeStorage) append(ctx context.Context, path string, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	if len(req.Data) == 0 {
+		return nil, errors.New("azure data lake storage binding error: no data to append")
+	}
+
+	var (
+		position int64
+		err      error
+	)
+	if val := req.Metadata[metadataKeyPosition]; val != "" {
+		position, err = strconv.ParseInt(val, 10, 64)
+		if err != nil || position < 0 {
+			return nil, fmt.Errorf("azure data lake storage binding error: invalid %s %s", metadataKeyPosition, val)
+		}
+	} else {
+		position, err = a.client.length(ctx, path)
+		if err != nil {
+			return nil, fmt.Errorf("azure data lake storage binding error: error getting the length of file %s: %w", path, err)
+		}
+	}
+
+	err = a.client.appendData(ctx, path, position, req.Data)
+	if err != nil {
+		return nil, fmt.Errorf("azure data lake storage binding error: error appending to file %s: %w", path, err)
+	}
+	position += int64(len(req.Data))
+
+	if val := req.Metadata[metadataKeyFlush]; val != "" {
+		flush, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("azure data lake storage binding error: invalid %s: %w", metadataKeyFlush, err)
+		}
+		if flush {
+			err = a.client.flush(ctx, path, position)
+			if err != nil {
+				return nil, fmt.Errorf("azure data lake storage binding error: error flushing file %s: %w", path, err)
+			}
+		}
+	}
+
+	return &bindings.InvokeResponse{
+		Metadata: map[string]string{
+			metadataKeyPosition: strconv.FormatInt(position, 10),
+		},
+	}, nil
+}
+
+// flush commits the data appended to the file up to the position of the request metadata.
+func (a *AzureDataLakeStorage) flush(ctx context.Context, path string, req *bindings.InvokeRequest) error {
+	val := req.Metadata[metadataKeyPosition]
+	if val == "" {
+		return fmt.Errorf("azure data lake storage binding error: %s is required", metadataKeyPosition)
+	}
+	position, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || position < 0 {
+		return fmt.Errorf("azure data lake storage binding error: invalid %s %s", metadataKeyPosition, val)
+	}
+
+	err = a.client.flush(ctx, path, position)
+	if err != nil {
+		return fmt.Errorf("azure data lake storage binding error: error flushing file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func (a *AzureDataLakeStorage) get(ctx context.Context, path string) (*bindings.InvokeResponse, error) {
+	data, err := a.client.read(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("azure data lake storage binding error: error reading file %s: %w", path, err)
+	}
+
+	return &bindings.InvokeResponse{
+		Data: data,
+	}, nil
+}
+
+// delete deletes the file or the directory; non-empty directories are deleted only with the recursive metadata.
+func (a *AzureDataLakeStorage) delete(ctx context.Context, path string, req *bindings.InvokeRequest) error {
+	recursive := false
+	if val := req.Metadata[metadataKeyRecursive]; val != "" {
+		var err error
+		recursive, err = strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("azure data lake storage binding error: invalid %s: %w", metadataKeyRecursive, err)
+		}
+	}
+
+	err := a.client.deletePath(ctx, path, recursive)
+	if err != nil {
+		return fmt.Errorf("azure data lake storage binding error: error deleting %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// list lists the paths of the directory of the request data, or of the root of the file system, returning the
+// continuation token of the next page in the response metadata.
+func (a *AzureDataLakeStorage) list(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	var payload listPayload
+	if len(req.Data) > 0 {
+		err := json.Unmarshal(req.Data, &payload)
+		if err != nil {
+			return nil, fmt.Errorf("azure data lake storage binding error: invalid list payload: %w", err)
+		}
+	}
+
+	paths, continuation, err := a.client.listPaths(ctx, payload.Directory, payload.Recursive, payload.Continuation, payload.MaxResults)
+	if err != nil {
+		return nil, fmt.Errorf("azure data lake storage binding error: error listing paths: %w", err)
+	}
+
+	b, err := json.Marshal(paths)
+	if err != nil {
+		return nil, fmt.Errorf("azure data lake storage binding error: error marshalling list response: %w", err)
+	}
+
+	res := &bindings.InvokeResponse{
+		Data:     b,
+		Metadata: map[string]string{},
+	}
+	if continuation != "" {
+		res.Metadata["continuation"] = continuation
+	}
+
+	return res, nil
+}
+
+func (a *AzureDataLakeStorage) rename(ctx context.Context, path string, req *bindings.InvokeRequest) error {
+	destination := req.Metadata[metadataKeyDestinationPath]
+	if destination == "" {
+		return fmt.Errorf("azure data lake storage binding error: %s is required", metadataKeyDestinationPath)
+	}
+
+	err := a.client.rename(ctx, path, destination)
+	if err != nil {
+		return fmt.Errorf("azure data lake storage binding error: error renaming %s to %s: %w", path, destination, err)
+	}
+
+	return nil
+}
+
+// setAccessControl sets the owner, group, permissions and POSIX ACL of the request data on the path.
+func (a *AzureDataLakeStorage) setAccessControl(ctx context.Context, path string, req *bindings.InvokeRequest) error {
+	var ac accessControl
+	err := json.Unmarshal(req.Data, &ac)
+	if err != nil {
+		return fmt.Errorf("azure data lake storage binding error: invalid access control payload: %w", err)
+	}
+	if ac == (accessControl{}) {
+		return errors.New("azure data lake storage binding error: one of owner, group, permissions or acl is required")
+	}
+	// The permissions and the ACL are mutually exclusive
+	if ac.Permissions != "" && ac.ACL != "" {
+		return errors.New("azure data lake storage binding error: permissions and acl can't be both set")
+	}
+
+	err = a.client.setAccessControl(ctx, path, ac)
+	if err != nil {
+		return fmt.Errorf("azure data lake storage binding error: error setting the access control of %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func (a *AzureDataLakeStorage) getAccessControl(ctx context.Context, path string) (*bindings.InvokeResponse, error) {
+	ac, err := a.client.getAccessControl(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("azure data lake storage binding error: error getting the access control of %s: %w", path, err)
+	}
+
+	b, err := json.Marshal(ac)
+	if err != nil {
+		return nil, fmt.Errorf("azure data lake storage binding error: error marshalling access control: %w", err)
+	}
+
+	return &bindings.InvokeResponse{
+		Data: b,
+	}, nil
+}
+
+// GetComponentMetadataSchema returns the schema of the metadata of the Azure Data Lake Storage Gen2 binding.
+func (a *AzureDataLakeStorage) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(adlsMetadata{
+		TimeoutInSec: int(defaultTimeout / time.Second),
+	})
+	return fields
+}
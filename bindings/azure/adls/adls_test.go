@@ -0,0 +1,423 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adls
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+var testAccountKey = base64.StdEncoding.EncodeToString([]byte("secret"))
+
+type recordedRequest struct {
+	method string
+	path   string
+	query  string
+	header http.Header
+	body   string
+}
+
+// newTestADLS returns a binding whose account is served by handler, recording the requests it receives.
+func newTestADLS(t *testing.T, handler http.HandlerFunc) (*AzureDataLakeStorage, *[]recordedRequest) {
+	t.Helper()
+
+	var requests []recordedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		requests = append(requests, recordedRequest{
+			method: r.Method,
+			path:   r.URL.EscapedPath(),
+			query:  r.URL.RawQuery,
+			header: r.Header,
+			body:   string(b),
+		})
+		if handler != nil {
+			handler(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	a := NewAzureDataLakeStorage(logger.NewLogger("test")).(*AzureDataLakeStorage)
+	err := a.Init(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"accountName": "myaccount",
+		"accountKey":  testAccountKey,
+		"fileSystem":  "myfs",
+		"endpoint":    server.URL,
+	}}})
+	require.NoError(t, err)
+
+	return a, &requests
+}
+
+func TestParseMetadata(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		m, err := parseMetadata(map[string]string{
+			"accountName": "myaccount",
+			"fileSystem":  "myfs",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "myaccount", m.AccountName)
+		assert.Equal(t, "myfs", m.FileSystem)
+		assert.Equal(t, 30, m.TimeoutInSec)
+	})
+
+	t.Run("missing account name", func(t *testing.T) {
+		_, err := parseMetadata(map[string]string{"fileSystem": "myfs"})
+
+		assert.ErrorContains(t, err, "accountName is required")
+	})
+
+	t.Run("missing file system", func(t *testing.T) {
+		_, err := parseMetadata(map[string]string{"accountName": "myaccount"})
+
+		assert.ErrorContains(t, err, "fileSystem is required")
+	})
+
+	t.Run("invalid timeout", func(t *testing.T) {
+		_, err := parseMetadata(map[string]string{
+			"accountName":  "myaccount",
+			"fileSystem":   "myfs",
+			"timeoutInSec": "0",
+		})
+
+		assert.ErrorContains(t, err, "invalid timeoutInSec")
+	})
+}
+
+func TestInit(t *testing.T) {
+	t.Run("default endpoint", func(t *testing.T) {
+		a := NewAzureDataLakeStorage(logger.NewLogger("test")).(*AzureDataLakeStorage)
+		err := a.Init(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"accountName": "myaccount",
+			"accountKey":  testAccountKey,
+			"fileSystem":  "myfs",
+		}}})
+
+		require.NoError(t, err)
+		assert.Equal(t, "https://myaccount.dfs.core.windows.net/myfs", a.client.fileSystemURL)
+		assert.Equal(t, []byte("secret"), a.client.accountKey)
+	})
+
+	t.Run("invalid account key", func(t *testing.T) {
+		a := NewAzureDataLakeStorage(logger.NewLogger("test")).(*AzureDataLakeStorage)
+		err := a.Init(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"accountName": "myaccount",
+			"accountKey":  "not base64!",
+			"fileSystem":  "myfs",
+		}}})
+
+		assert.ErrorContains(t, err, "invalid accountKey")
+	})
+}
+
+func TestCreate(t *testing.T) {
+	a, requests := newTestADLS(t, nil)
+
+	res, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+		Operation: bindings.CreateOperation,
+		Data:      []byte("hello"),
+		Metadata:  map[string]string{metadataKeyPath: "dir/my file.txt"},
+	})
+
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"fileURL":"`+a.client.fileSystemURL+`/dir/my%20file.txt","path":"dir/my file.txt"}`, string(res.Data))
+	require.Len(t, *requests, 3)
+	for _, r := range *requests {
+		assert.Equal(t, "/myaccount/myfs/dir/my%20file.txt", r.path)
+		assert.True(t, strings.HasPrefix(r.header.Get("Authorization"), "SharedKey myaccount:"))
+		assert.Equal(t, storageAPIVersion, r.header.Get("x-ms-version"))
+	}
+	assert.Equal(t, http.MethodPut, (*requests)[0].method)
+	assert.Equal(t, "resource=file", (*requests)[0].query)
+	assert.Equal(t, http.MethodPatch, (*requests)[1].method)
+	assert.Equal(t, "action=append&position=0", (*requests)[1].query)
+	assert.Equal(t, "hello", (*requests)[1].body)
+	assert.Equal(t, http.MethodPatch, (*requests)[2].method)
+	assert.Equal(t, "action=flush&position=5", (*requests)[2].query)
+}
+
+func TestAppend(t *testing.T) {
+	t.Run("appends at the end of the file", func(t *testing.T) {
+		a, requests := newTestADLS(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead {
+				w.Header().Set("Content-Length", "10")
+			}
+		})
+
+		res, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: appendOperation,
+			Data:      []byte("world"),
+			Metadata:  map[string]string{metadataKeyPath: "log.txt", metadataKeyFlush: "true"},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "15", res.Metadata[metadataKeyPosition])
+		require.Len(t, *requests, 3)
+		assert.Equal(t, http.MethodHead, (*requests)[0].method)
+		assert.Equal(t, "action=append&position=10", (*requests)[1].query)
+		assert.Equal(t, "world", (*requests)[1].body)
+		assert.Equal(t, "action=flush&position=15", (*requests)[2].query)
+	})
+
+	t.Run("appends at the position without flushing", func(t *testing.T) {
+		a, requests := newTestADLS(t, nil)
+
+		res, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: appendOperation,
+			Data:      []byte("world"),
+			Metadata:  map[string]string{metadataKeyPath: "log.txt", metadataKeyPosition: "3"},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "8", res.Metadata[metadataKeyPosition])
+		require.Len(t, *requests, 1)
+		assert.Equal(t, "action=append&position=3", (*requests)[0].query)
+	})
+
+	t.Run("invalid position", func(t *testing.T) {
+		a, _ := newTestADLS(t, nil)
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: appendOperation,
+			Data:      []byte("world"),
+			Metadata:  map[string]string{metadataKeyPath: "log.txt", metadataKeyPosition: "-1"},
+		})
+
+		assert.ErrorContains(t, err, "invalid position")
+	})
+}
+
+func TestFlush(t *testing.T) {
+	t.Run("flushes up to the position", func(t *testing.T) {
+		a, requests := newTestADLS(t, nil)
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: flushOperation,
+			Metadata:  map[string]string{metadataKeyPath: "log.txt", metadataKeyPosition: "15"},
+		})
+
+		require.NoError(t, err)
+		require.Len(t, *requests, 1)
+		assert.Equal(t, "action=flush&position=15", (*requests)[0].query)
+	})
+
+	t.Run("missing position", func(t *testing.T) {
+		a, _ := newTestADLS(t, nil)
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: flushOperation,
+			Metadata:  map[string]string{metadataKeyPath: "log.txt"},
+		})
+
+		assert.ErrorContains(t, err, "position is required")
+	})
+}
+
+func TestGet(t *testing.T) {
+	t.Run("returns the content of the file", func(t *testing.T) {
+		a, _ := newTestADLS(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("hello"))
+		})
+
+		res, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.GetOperation,
+			Metadata:  map[string]string{metadataKeyPath: "dir/file.txt"},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(res.Data))
+	})
+
+	t.Run("returns the errors of the service", func(t *testing.T) {
+		a, _ := newTestADLS(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":"PathNotFound"}}`))
+		})
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.GetOperation,
+			Metadata:  map[string]string{metadataKeyPath: "dir/file.txt"},
+		})
+
+		assert.ErrorContains(t, err, "PathNotFound")
+	})
+
+	t.Run("missing path", func(t *testing.T) {
+		a, _ := newTestADLS(t, nil)
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.GetOperation,
+		})
+
+		assert.ErrorIs(t, err, ErrMissingPath)
+	})
+}
+
+func TestDirectoryOperations(t *testing.T) {
+	t.Run("create directory", func(t *testing.T) {
+		a, requests := newTestADLS(t, nil)
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: createDirectoryOperation,
+			Metadata:  map[string]string{metadataKeyPath: "dir/sub"},
+		})
+
+		require.NoError(t, err)
+		require.Len(t, *requests, 1)
+		assert.Equal(t, http.MethodPut, (*requests)[0].method)
+		assert.Equal(t, "/myaccount/myfs/dir/sub", (*requests)[0].path)
+		assert.Equal(t, "resource=directory", (*requests)[0].query)
+	})
+
+	t.Run("delete recursively", func(t *testing.T) {
+		a, requests := newTestADLS(t, nil)
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.DeleteOperation,
+			Metadata:  map[string]string{metadataKeyPath: "dir", metadataKeyRecursive: "true"},
+		})
+
+		require.NoError(t, err)
+		require.Len(t, *requests, 1)
+		assert.Equal(t, http.MethodDelete, (*requests)[0].method)
+		assert.Equal(t, "recursive=true", (*requests)[0].query)
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		a, requests := newTestADLS(t, nil)
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: renameOperation,
+			Metadata:  map[string]string{metadataKeyPath: "dir/old", metadataKeyDestinationPath: "dir/new"},
+		})
+
+		require.NoError(t, err)
+		require.Len(t, *requests, 1)
+		assert.Equal(t, http.MethodPut, (*requests)[0].method)
+		assert.Equal(t, "/myaccount/myfs/dir/new", (*requests)[0].path)
+		assert.Equal(t, "/myfs/dir/old", (*requests)[0].header.Get("x-ms-rename-source"))
+	})
+
+	t.Run("list", func(t *testing.T) {
+		a, requests := newTestADLS(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("x-ms-continuation", "next")
+			w.Write([]byte(`{"paths":[{"name":"dir/file.txt","contentLength":"5","owner":"$superuser"},{"name":"dir/sub","isDirectory":"true"}]}`))
+		})
+
+		res, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.ListOperation,
+			Data:      []byte(`{"directory":"dir","maxResults":2}`),
+		})
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"name":"dir/file.txt","contentLength":"5","owner":"$superuser"},{"name":"dir/sub","isDirectory":"true"}]`, string(res.Data))
+		assert.Equal(t, "next", res.Metadata["continuation"])
+		require.Len(t, *requests, 1)
+		assert.Equal(t, "/myaccount/myfs", (*requests)[0].path)
+		assert.Equal(t, "directory=dir&maxResults=2&recursive=false&resource=filesystem", (*requests)[0].query)
+	})
+}
+
+func TestAccessControl(t *testing.T) {
+	t.Run("set", func(t *testing.T) {
+		a, requests := newTestADLS(t, nil)
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: setAccessControlOperation,
+			Data:      []byte(`{"owner":"alice","acl":"user::rwx,group::r-x,other::---"}`),
+			Metadata:  map[string]string{metadataKeyPath: "dir"},
+		})
+
+		require.NoError(t, err)
+		require.Len(t, *requests, 1)
+		r := (*requests)[0]
+		assert.Equal(t, http.MethodPatch, r.method)
+		assert.Equal(t, "action=setAccessControl", r.query)
+		assert.Equal(t, "alice", r.header.Get("x-ms-owner"))
+		assert.Equal(t, "user::rwx,group::r-x,other::---", r.header.Get("x-ms-acl"))
+		assert.Empty(t, r.header.Get("x-ms-group"))
+	})
+
+	t.Run("permissions and acl are exclusive", func(t *testing.T) {
+		a, _ := newTestADLS(t, nil)
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: setAccessControlOperation,
+			Data:      []byte(`{"permissions":"0750","acl":"user::rwx"}`),
+			Metadata:  map[string]string{metadataKeyPath: "dir"},
+		})
+
+		assert.ErrorContains(t, err, "can't be both set")
+	})
+
+	t.Run("nothing to set", func(t *testing.T) {
+		a, _ := newTestADLS(t, nil)
+
+		_, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: setAccessControlOperation,
+			Data:      []byte(`{}`),
+			Metadata:  map[string]string{metadataKeyPath: "dir"},
+		})
+
+		assert.ErrorContains(t, err, "one of owner, group, permissions or acl is required")
+	})
+
+	t.Run("get", func(t *testing.T) {
+		a, requests := newTestADLS(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("x-ms-owner", "alice")
+			w.Header().Set("x-ms-group", "staff")
+			w.Header().Set("x-ms-permissions", "rwxr-x---")
+			w.Header().Set("x-ms-acl", "user::rwx,group::r-x,other::---")
+		})
+
+		res, err := a.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: getAccessControlOperation,
+			Metadata:  map[string]string{metadataKeyPath: "dir"},
+		})
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"owner":"alice","group":"staff","permissions":"rwxr-x---","acl":"user::rwx,group::r-x,other::---"}`, string(res.Data))
+		require.Len(t, *requests, 1)
+		assert.Equal(t, http.MethodHead, (*requests)[0].method)
+		assert.Equal(t, "action=getAccessControl", (*requests)[0].query)
+	})
+}
+
+func TestStringToSign(t *testing.T) {
+	c := &client{accountName: "myaccount"}
+	req, err := http.NewRequest(http.MethodPatch, "https://myaccount.dfs.core.windows.net/myfs/dir/file.txt?position=0&action=append", strings.NewReader("hello"))
+	require.NoError(t, err)
+	req.Header.Set("x-ms-version", storageAPIVersion)
+	req.Header.Set("x-ms-date", "Mon, 02 Jan 2006 15:04:05 GMT")
+
+	s, err := c.stringToSign(req)
+
+	require.NoError(t, err)
+	assert.Equal(t, "PATCH\n\n\n5\n\n\n\n\n\n\n\n\n"+
+		"x-ms-date:Mon, 02 Jan 2006 15:04:05 GMT\nx-ms-version:"+storageAPIVersion+"\n"+
+		"/myaccount/myfs/dir/file.txt\naction:append\nposition:0", s)
+}
@@ -0,0 +1,395 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adls
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/google/uuid"
+)
+
+// Version of the Azure Data Lake Storage Gen2 REST API.
+const storageAPIVersion = "2020-06-12"
+
+// client is a client of the REST API of a file system of an Azure Data Lake Storage Gen2 account, authorized with
+// the shared key of the account or with an Azure AD token.
+// See: https://learn.microsoft.com/rest/api/storageservices/data-lake-storage-gen2
+type client struct {
+	// URL of the file system
+	fileSystemURL string
+	fileSystem    string
+	accountName   string
+	accountKey    []byte
+	credential    azcore.TokenCredential
+	scope         string
+	userAgent     string
+	httpClient    *http.Client
+}
+
+// accessControl is the owner, group, permissions and ACL of a path.
+type accessControl struct {
+	Owner       string `json:"owner,omitempty"`
+	Group       string `json:"group,omitempty"`
+	Permissions string `json:"permissions,omitempty"`
+	ACL         string `json:"acl,omitempty"`
+}
+
+// pathItem is a path returned by the list operation.
+type pathItem struct {
+	Name          string `json:"name"`
+	IsDirectory   string `json:"isDirectory,omitempty"`
+	ContentLength string `json:"contentLength,omitempty"`
+	LastModified  string `json:"lastModified,omitempty"`
+	ETag          string `json:"etag,omitempty"`
+	Owner         string `json:"owner,omitempty"`
+	Group         string `json:"group,omitempty"`
+	Permissions   string `json:"permissions,omitempty"`
+}
+
+type listResponse struct {
+	Paths []pathItem `json:"paths"`
+}
+
+// createPath creates a file or a directory, depending on resource, replacing it if it exists.
+func (c *client) createPath(ctx context.Context, path string, resource string) error {
+	q := url.Values{}
+	q.Set("resource", resource)
+
+	req, err := c.newRequest(ctx, http.MethodPut, path, q, nil)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = c.do(req)
+	return err
+}
+
+// appendData uploads the data at the position of the file; it's not committed until flushed.
+func (c *client) appendData(ctx context.Context, path string, position int64, data []byte) error {
+	q := url.Values{}
+	q.Set("action", "append")
+	q.Set("position", strconv.FormatInt(position, 10))
+
+	req, err := c.newRequest(ctx, http.MethodPatch, path, q, data)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = c.do(req)
+	return err
+}
+
+// flush commits the data appended to the file, up to position, which is the length of the file after the flush.
+func (c *client) flush(ctx context.Context, path string, position int64) error {
+	q := url.Values{}
+	q.Set("action", "flush")
+	q.Set("position", strconv.FormatInt(position, 10))
+
+	req, err := c.newRequest(ctx, http.MethodPatch, path, q, nil)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = c.do(req)
+	return err
+}
+
+// read returns the content of the file.
+func (c *client) read(ctx context.Context, path string) ([]byte, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	_, body, err := c.do(req)
+	return body, err
+}
+
+// length returns the length of the committed content of the file.
+func (c *client) length(ctx context.Context, path string) (int64, error) {
+	req, err := c.newRequest(ctx, http.MethodHead, path, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	header, _, err := c.do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+}
+
+// deletePath deletes a file or a directory; non-empty directories are deleted only if recursive is true.
+func (c *client) deletePath(ctx context.Context, path string, recursive bool) error {
+	q := url.Values{}
+	q.Set("recursive", strconv.FormatBool(recursive))
+
+	req, err := c.newRequest(ctx, http.MethodDelete, path, q, nil)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = c.do(req)
+	return err
+}
+
+// rename moves a file or a directory to the destination path, in the same file system.
+func (c *client) rename(ctx context.Context, source string, destination string) error {
+	req, err := c.newRequest(ctx, http.MethodPut, destination, nil, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-ms-rename-source", "/"+url.PathEscape(c.fileSystem)+"/"+escapePath(source))
+
+	_, _, err = c.do(req)
+	return err
+}
+
+// setAccessControl sets the owner, group, permissions and ACL of a path; empty values are left unchanged.
+func (c *client) setAccessControl(ctx context.Context, path string, ac accessControl) error {
+	q := url.Values{}
+	q.Set("action", "setAccessControl")
+
+	req, err := c.newRequest(ctx, http.MethodPatch, path, q, nil)
+	if err != nil {
+		return err
+	}
+	for header, val := range map[string]string{
+		"x-ms-owner":       ac.Owner,
+		"x-ms-group":       ac.Group,
+		"x-ms-permissions": ac.Permissions,
+		"x-ms-acl":         ac.ACL,
+	} {
+		if val != "" {
+			req.Header.Set(header, val)
+		}
+	}
+
+	_, _, err = c.do(req)
+	return err
+}
+
+// getAccessControl returns the owner, group, permissions and ACL of a path.
+func (c *client) getAccessControl(ctx context.Context, path string) (*accessControl, error) {
+	q := url.Values{}
+	q.Set("action", "getAccessControl")
+
+	req, err := c.newRequest(ctx, http.MethodHead, path, q, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	header, _, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &accessControl{
+		Owner:       header.Get("x-ms-owner"),
+		Group:       header.Get("x-ms-group"),
+		Permissions: header.Get("x-ms-permissions"),
+		ACL:         header.Get("x-ms-acl"),
+	}, nil
+}
+
+// listPaths lists the paths of the directory, or of the root of the file system if empty, returning the
+// continuation token of the next page, if any.
+func (c *client) listPaths(ctx context.Context, directory string, recursive bool, continuation string, maxResults int) ([]pathItem, string, error) {
+	q := url.Values{}
+	q.Set("resource", "filesystem")
+	q.Set("recursive", strconv.FormatBool(recursive))
+	if directory != "" {
+		q.Set("directory", directory)
+	}
+	if continuation != "" {
+		q.Set("continuation", continuation)
+	}
+	if maxResults > 0 {
+		q.Set("maxResults", strconv.Itoa(maxResults))
+	}
+
+	req, err := c.newRequest(ctx, http.MethodGet, "", q, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	header, body, err := c.do(req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var res listResponse
+	err = json.Unmarshal(body, &res)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid list response: %w", err)
+	}
+
+	return res.Paths, header.Get("x-ms-continuation"), nil
+}
+
+// newRequest returns a request for the path of the file system.
+func (c *client) newRequest(ctx context.Context, method string, path string, query url.Values, data []byte) (*http.Request, error) {
+	u := c.fileSystemURL
+	if path != "" {
+		u += "/" + escapePath(path)
+	}
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+
+	return http.NewRequestWithContext(ctx, method, u, body)
+}
+
+// do sends a request, authorized with the shared key of the account if set, or with an Azure AD token otherwise, and
+// returns the header and the body of the response.
+func (c *client) do(req *http.Request) (http.Header, []byte, error) {
+	req.Header.Set("x-ms-version", storageAPIVersion)
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-client-request-id", uuid.NewString())
+	req.Header.Set("User-Agent", c.userAgent)
+
+	if len(c.accountKey) > 0 {
+		signature, err := c.sign(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		req.Header.Set("Authorization", "SharedKey "+c.accountName+":"+signature)
+	} else if c.credential != nil {
+		token, err := c.credential.GetToken(req.Context(), policy.TokenRequestOptions{
+			Scopes: []string{c.scope},
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get an Azure AD token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token.Token)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	// Read the body regardless to drain it and ensure the connection can be reused
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, nil, fmt.Errorf("request to %s %s failed with code %d, content is '%s'", req.Method, req.URL.Path, res.StatusCode, string(body))
+	}
+
+	return res.Header, body, nil
+}
+
+// sign returns the shared key signature of the request.
+// See: https://learn.microsoft.com/rest/api/storageservices/authorize-with-shared-key
+func (c *client) sign(req *http.Request) (string, error) {
+	stringToSign, err := c.stringToSign(req)
+	if err != nil {
+		return "", err
+	}
+
+	h := hmac.New(sha256.New, c.accountKey)
+	_, err = h.Write([]byte(stringToSign))
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *client) stringToSign(req *http.Request) (string, error) {
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+
+	// Headers prefixed with x-ms-, sorted by name
+	var msHeaders []string
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-ms-") {
+			msHeaders = append(msHeaders, name+":"+strings.Join(values, ","))
+		}
+	}
+	sort.Strings(msHeaders)
+
+	// Path of the request, followed by the query parameters sorted by name
+	resource := "/" + c.accountName + req.URL.EscapedPath()
+	params, err := url.ParseQuery(req.URL.RawQuery)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse query params: %w", err)
+	}
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values := params[name]
+		sort.Strings(values)
+		resource += "\n" + strings.ToLower(name) + ":" + strings.Join(values, ",")
+	}
+
+	return strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		// Empty date, as x-ms-date is set
+		"",
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+		strings.Join(msHeaders, "\n"),
+		resource,
+	}, "\n"), nil
+}
+
+// escapePath escapes each segment of the path.
+func escapePath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
@@ -15,21 +15,25 @@ package blobstorage
 
 import (
 	"context"
-	b64 "encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
-	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
 	"github.com/google/uuid"
 
 	"github.com/dapr/components-contrib/bindings"
 	storageinternal "github.com/dapr/components-contrib/internal/component/azure/blobstorage"
+	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 	"github.com/dapr/kit/ptr"
 )
@@ -54,6 +58,40 @@ const (
 	// See: https://docs.microsoft.com/en-us/rest/api/storageservices/list-blobs#uri-parameters
 	maxResults  int32 = 5000
 	endpointKey       = "endpoint"
+
+	// Access tier of the blob for the setTier and rehydrate operations: Hot, Cool or Archive.
+	metadataKeyAccessTier = "accessTier"
+	// Priority of the rehydration of an archived blob: Standard or High.
+	// See: https://learn.microsoft.com/azure/storage/blobs/archive-rehydrate-overview#rehydration-priority
+	metadataKeyRehydratePriority = "rehydratePriority"
+	// Legal hold of the blob for the setLegalHold operation: true or false.
+	metadataKeyLegalHold = "legalHold"
+	// Time until which the blob can't be modified nor deleted, in RFC3339 format.
+	metadataKeyImmutabilityPolicyUntil = "immutabilityPolicyUntil"
+	// Mode of the immutability policy: Unlocked (default) or Locked.
+	metadataKeyImmutabilityPolicyMode = "immutabilityPolicyMode"
+	// Snapshot of the blob for the get and delete operations, returned by the snapshot operation.
+	metadataKeySnapshot = "snapshot"
+	// Offset of the first byte, and number of bytes, of the range of the blob returned by the get operation.
+	metadataKeyRangeOffset = "rangeOffset"
+	metadataKeyRangeCount  = "rangeCount"
+	// Time for which the URL returned by the presign operation is valid, as a duration, e.g. 15m, up to
+	// maxPresignTTL.
+	metadataKeyPresignTTL = "presignTTL"
+	// Permissions granted by the URL returned by the presign operation, among r(ead), a(dd), c(reate), w(rite),
+	// d(elete) and t(ag); read only by default.
+	metadataKeyPresignPermissions = "presignPermissions"
+
+	setTierOperation                  bindings.OperationKind = "setTier"
+	rehydrateOperation                bindings.OperationKind = "rehydrate"
+	setLegalHoldOperation             bindings.OperationKind = "setLegalHold"
+	setImmutabilityPolicyOperation    bindings.OperationKind = "setImmutabilityPolicy"
+	deleteImmutabilityPolicyOperation bindings.OperationKind = "deleteImmutabilityPolicy"
+	snapshotOperation                 bindings.OperationKind = "snapshot"
+	presignOperation                  bindings.OperationKind = "presign"
+
+	// Longest validity of the presigned URLs, which is also the longest validity of the user delegation keys.
+	maxPresignTTL = 7 * 24 * time.Hour
 )
 
 var ErrMissingBlobName = errors.New("blobName is a required attribute")
@@ -62,6 +100,8 @@ var ErrMissingBlobName = errors.New("blobName is a required attribute")
 type AzureBlobStorage struct {
 	metadata        *storageinternal.BlobStorageMetadata
 	containerClient *container.Client
+	// Client of the Blob service getting the user delegation keys, when authenticated with Azure AD.
+	serviceClient *service.Client
 
 	logger logger.Logger
 }
@@ -71,6 +111,15 @@ type createResponse struct {
 	BlobName string `json:"blobName"`
 }
 
+type snapshotResponse struct {
+	SnapshotURL string `json:"snapshotURL"`
+	Snapshot    string `json:"snapshot"`
+}
+
+type presignResponse struct {
+	PresignURL string `json:"presignURL"`
+}
+
 type listInclude struct {
 	Copy             bool `json:"copy"`
 	Metadata         bool `json:"metadata"`
@@ -98,6 +147,10 @@ func (a *AzureBlobStorage) Init(metadata bindings.Metadata) error {
 	if err != nil {
 		return err
 	}
+	a.serviceClient, err = storageinternal.CreateServiceStorageClient(metadata.Properties)
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -107,6 +160,13 @@ func (a *AzureBlobStorage) Operations() []bindings.OperationKind {
 		bindings.GetOperation,
 		bindings.DeleteOperation,
 		bindings.ListOperation,
+		setTierOperation,
+		rehydrateOperation,
+		setLegalHoldOperation,
+		setImmutabilityPolicyOperation,
+		deleteImmutabilityPolicyOperation,
+		snapshotOperation,
+		presignOperation,
 	}
 }
 
@@ -123,22 +183,15 @@ func (a *AzureBlobStorage) create(ctx context.Context, req *bindings.InvokeReque
 		blobName = id.String()
 	}
 
-	blobHTTPHeaders, err := storageinternal.CreateBlobHTTPHeadersFromRequest(req.Metadata, nil, a.logger)
+	// The data is decoded first, as the content type is removed from the metadata with the HTTP headers
+	data, err := req.DecodedData(a.metadata.DecodeBase64)
 	if err != nil {
 		return nil, err
 	}
 
-	d, err := strconv.Unquote(string(req.Data))
-	if err == nil {
-		req.Data = []byte(d)
-	}
-
-	if a.metadata.DecodeBase64 {
-		decoded, decodeError := b64.StdEncoding.DecodeString(string(req.Data))
-		if decodeError != nil {
-			return nil, decodeError
-		}
-		req.Data = decoded
+	blobHTTPHeaders, err := storageinternal.CreateBlobHTTPHeadersFromRequest(req.Metadata, nil, a.logger)
+	if err != nil {
+		return nil, err
 	}
 
 	uploadOptions := azblob.UploadBufferOptions{
@@ -148,7 +201,7 @@ func (a *AzureBlobStorage) create(ctx context.Context, req *bindings.InvokeReque
 	}
 
 	blockBlobClient := a.containerClient.NewBlockBlobClient(blobName)
-	_, err = blockBlobClient.UploadBuffer(ctx, req.Data, &uploadOptions)
+	_, err = blockBlobClient.UploadBuffer(ctx, data, &uploadOptions)
 
 	if err != nil {
 		return nil, fmt.Errorf("error uploading az blob: %w", err)
@@ -172,19 +225,33 @@ func (a *AzureBlobStorage) create(ctx context.Context, req *bindings.InvokeReque
 	}, nil
 }
 
+// get returns the content of a blob, or of the snapshot of the "snapshot" metadata, and its properties.
+// The content is limited to the range of the "rangeOffset" and "rangeCount" metadata, if any.
 func (a *AzureBlobStorage) get(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
-	var blockBlobClient *blockblob.Client
-	if val, ok := req.Metadata[metadataKeyBlobName]; ok && val != "" {
-		blockBlobClient = a.containerClient.NewBlockBlobClient(val)
-	} else {
+	if val, ok := req.Metadata[metadataKeyBlobName]; !ok || val == "" {
 		return nil, ErrMissingBlobName
 	}
 
+	httpRange, err := parseRange(req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	fetchMetadata, err := req.GetMetadataAsBool(metadataKeyIncludeMetadata)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing metadata: %w", err)
+	}
+
+	blobClient, err := a.snapshotBlobClient(req)
+	if err != nil {
+		return nil, err
+	}
+
 	downloadOptions := azblob.DownloadStreamOptions{
+		Range:            httpRange,
 		AccessConditions: &blob.AccessConditions{},
 	}
 
-	blobDownloadResponse, err := blockBlobClient.DownloadStream(ctx, &downloadOptions)
+	blobDownloadResponse, err := blobClient.DownloadStream(ctx, &downloadOptions)
 	if err != nil {
 		return nil, fmt.Errorf("error downloading az blob: %w", err)
 	}
@@ -195,23 +262,27 @@ func (a *AzureBlobStorage) get(ctx context.Context, req *bindings.InvokeRequest)
 		return nil, fmt.Errorf("error reading az blob: %w", err)
 	}
 
-	var metadata map[string]string
-	fetchMetadata, err := req.GetMetadataAsBool(metadataKeyIncludeMetadata)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing metadata: %w", err)
-	}
-
-	getPropertiesOptions := blob.GetPropertiesOptions{
-		AccessConditions: &blob.AccessConditions{},
-	}
-
+	metadata := make(map[string]string)
+	// The user defined metadata are returned only if requested, and can't override the properties of the blob
 	if fetchMetadata {
-		props, err := blockBlobClient.GetProperties(ctx, &getPropertiesOptions)
-		if err != nil {
-			return nil, fmt.Errorf("error reading blob metadata: %w", err)
+		for k, v := range blobDownloadResponse.Metadata {
+			metadata[k] = v
 		}
-
-		metadata = props.Metadata
+	}
+	if blobDownloadResponse.ContentType != nil {
+		metadata["contentType"] = *blobDownloadResponse.ContentType
+	}
+	if blobDownloadResponse.ContentLength != nil {
+		metadata["contentLength"] = strconv.FormatInt(*blobDownloadResponse.ContentLength, 10)
+	}
+	if blobDownloadResponse.ContentRange != nil {
+		metadata["contentRange"] = *blobDownloadResponse.ContentRange
+	}
+	if blobDownloadResponse.ETag != nil {
+		metadata["eTag"] = string(*blobDownloadResponse.ETag)
+	}
+	if blobDownloadResponse.LastModified != nil {
+		metadata["lastModified"] = blobDownloadResponse.LastModified.Format(time.RFC3339)
 	}
 
 	return &bindings.InvokeResponse{
@@ -220,29 +291,34 @@ func (a *AzureBlobStorage) get(ctx context.Context, req *bindings.InvokeRequest)
 	}, nil
 }
 
+// delete deletes a blob, or only the snapshot of the "snapshot" metadata.
+// The snapshots of the blob are deleted too with the "include" value of the "deleteSnapshots" metadata, or only them
+// with "only".
 func (a *AzureBlobStorage) delete(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
-	var blockBlobClient *blockblob.Client
-	val, ok := req.Metadata[metadataKeyBlobName]
-	if !ok || val == "" {
+	if val, ok := req.Metadata[metadataKeyBlobName]; !ok || val == "" {
 		return nil, ErrMissingBlobName
 	}
 
-	var deleteSnapshotsOptions blob.DeleteSnapshotsOptionType
-	if deleteSnapShotOption, ok := req.Metadata[metadataKeyDeleteSnapshots]; ok && val != "" {
-		deleteSnapshotsOptions = azblob.DeleteSnapshotsOptionType(deleteSnapShotOption)
-		if !a.isValidDeleteSnapshotsOptionType(deleteSnapshotsOptions) {
+	deleteOptions := blob.DeleteOptions{
+		AccessConditions: &blob.AccessConditions{},
+	}
+	if val, ok := req.Metadata[metadataKeyDeleteSnapshots]; ok && val != "" {
+		deleteSnapshotsOption := azblob.DeleteSnapshotsOptionType(val)
+		if !a.isValidDeleteSnapshotsOptionType(deleteSnapshotsOption) {
 			return nil, fmt.Errorf("invalid delete snapshot option type: %s; allowed: %s",
-				deleteSnapshotsOptions, azblob.PossibleDeleteSnapshotsOptionTypeValues())
+				deleteSnapshotsOption, azblob.PossibleDeleteSnapshotsOptionTypeValues())
+		}
+		if req.Metadata[metadataKeySnapshot] != "" {
+			return nil, fmt.Errorf("%s and %s cannot both be specified", metadataKeyDeleteSnapshots, metadataKeySnapshot)
 		}
+		deleteOptions.DeleteSnapshots = &deleteSnapshotsOption
 	}
 
-	deleteOptions := blob.DeleteOptions{
-		DeleteSnapshots:  &deleteSnapshotsOptions,
-		AccessConditions: &blob.AccessConditions{},
+	blobClient, err := a.snapshotBlobClient(req)
+	if err != nil {
+		return nil, err
 	}
-
-	blockBlobClient = a.containerClient.NewBlockBlobClient(val)
-	_, err := blockBlobClient.Delete(ctx, &deleteOptions)
+	_, err = blobClient.Delete(ctx, &deleteOptions)
 
 	return nil, err
 }
@@ -331,11 +407,358 @@ func (a *AzureBlobStorage) Invoke(ctx context.Context, req *bindings.InvokeReque
 		return a.delete(ctx, req)
 	case bindings.ListOperation:
 		return a.list(ctx, req)
+	case setTierOperation:
+		return a.setTier(ctx, req, "")
+	case rehydrateOperation:
+		// Archived blobs are rehydrated by moving them to an online tier, the hot one by default
+		return a.setTier(ctx, req, blob.AccessTierHot)
+	case setLegalHoldOperation:
+		return a.setLegalHold(ctx, req)
+	case setImmutabilityPolicyOperation:
+		return a.setImmutabilityPolicy(ctx, req)
+	case deleteImmutabilityPolicyOperation:
+		return a.deleteImmutabilityPolicy(ctx, req)
+	case snapshotOperation:
+		return a.createSnapshot(ctx, req)
+	case presignOperation:
+		return a.presign(ctx, req)
 	default:
 		return nil, fmt.Errorf("unsupported operation %s", req.Operation)
 	}
 }
 
+func (a *AzureBlobStorage) blobClient(req *bindings.InvokeRequest) (*blob.Client, error) {
+	val, ok := req.Metadata[metadataKeyBlobName]
+	if !ok || val == "" {
+		return nil, ErrMissingBlobName
+	}
+
+	return a.containerClient.NewBlobClient(val), nil
+}
+
+// snapshotBlobClient returns the client of the blob, or of its snapshot if the "snapshot" metadata is set.
+func (a *AzureBlobStorage) snapshotBlobClient(req *bindings.InvokeRequest) (*blob.Client, error) {
+	blobClient, err := a.blobClient(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if val := req.Metadata[metadataKeySnapshot]; val != "" {
+		blobClient, err = blobClient.WithSnapshot(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", metadataKeySnapshot, err)
+		}
+	}
+
+	return blobClient, nil
+}
+
+// createSnapshot creates a read-only snapshot of a blob, whose timestamp is returned in the "snapshot" metadata.
+func (a *AzureBlobStorage) createSnapshot(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	blobClient, err := a.blobClient(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := blobClient.CreateSnapshot(ctx, &blob.CreateSnapshotOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error creating snapshot of az blob: %w", err)
+	}
+	if resp.Snapshot == nil {
+		return nil, errors.New("error creating snapshot of az blob: no snapshot returned")
+	}
+
+	snapshotClient, err := blobClient.WithSnapshot(*resp.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(snapshotResponse{
+		SnapshotURL: snapshotClient.URL(),
+		Snapshot:    *resp.Snapshot,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling snapshot response for azure blob: %w", err)
+	}
+
+	return &bindings.InvokeResponse{
+		Data: b,
+		Metadata: map[string]string{
+			metadataKeySnapshot: *resp.Snapshot,
+		},
+	}, nil
+}
+
+// setTier sets the access tier of a blob, rehydrating it if it is archived.
+func (a *AzureBlobStorage) setTier(ctx context.Context, req *bindings.InvokeRequest, defaultTier blob.AccessTier) (*bindings.InvokeResponse, error) {
+	blobClient, err := a.blobClient(req)
+	if err != nil {
+		return nil, err
+	}
+
+	tier := defaultTier
+	if val := req.Metadata[metadataKeyAccessTier]; val != "" {
+		tier, err = parseAccessTier(val)
+		if err != nil {
+			return nil, err
+		}
+	} else if tier == "" {
+		return nil, fmt.Errorf("%s is a required attribute", metadataKeyAccessTier)
+	}
+
+	setTierOptions := blob.SetTierOptions{}
+	if val := req.Metadata[metadataKeyRehydratePriority]; val != "" {
+		priority, err := parseRehydratePriority(val)
+		if err != nil {
+			return nil, err
+		}
+		setTierOptions.RehydratePriority = &priority
+	}
+
+	_, err = blobClient.SetTier(ctx, tier, &setTierOptions)
+	if err != nil {
+		return nil, fmt.Errorf("error setting the access tier of az blob: %w", err)
+	}
+
+	return nil, nil
+}
+
+func (a *AzureBlobStorage) setLegalHold(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	blobClient, err := a.blobClient(req)
+	if err != nil {
+		return nil, err
+	}
+
+	val, ok := req.Metadata[metadataKeyLegalHold]
+	if !ok || val == "" {
+		return nil, fmt.Errorf("%s is a required attribute", metadataKeyLegalHold)
+	}
+	legalHold, err := strconv.ParseBool(val)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", metadataKeyLegalHold, err)
+	}
+
+	err = a.metadata.SetLegalHold(ctx, blobClient, legalHold)
+	if err != nil {
+		return nil, fmt.Errorf("error setting the legal hold of az blob: %w", err)
+	}
+
+	return nil, nil
+}
+
+func (a *AzureBlobStorage) setImmutabilityPolicy(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	blobClient, err := a.blobClient(req)
+	if err != nil {
+		return nil, err
+	}
+
+	val, ok := req.Metadata[metadataKeyImmutabilityPolicyUntil]
+	if !ok || val == "" {
+		return nil, fmt.Errorf("%s is a required attribute", metadataKeyImmutabilityPolicyUntil)
+	}
+	until, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", metadataKeyImmutabilityPolicyUntil, err)
+	}
+
+	mode := blob.ImmutabilityPolicySettingUnlocked
+	if val := req.Metadata[metadataKeyImmutabilityPolicyMode]; val != "" {
+		mode, err = parseImmutabilityPolicyMode(val)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = a.metadata.SetImmutabilityPolicy(ctx, blobClient, until, mode)
+	if err != nil {
+		return nil, fmt.Errorf("error setting the immutability policy of az blob: %w", err)
+	}
+
+	return nil, nil
+}
+
+func (a *AzureBlobStorage) deleteImmutabilityPolicy(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	blobClient, err := a.blobClient(req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.metadata.DeleteImmutabilityPolicy(ctx, blobClient)
+	if err != nil {
+		return nil, fmt.Errorf("error deleting the immutability policy of az blob: %w", err)
+	}
+
+	return nil, nil
+}
+
+// The values of the options are case-insensitive.
+
+func parseAccessTier(val string) (blob.AccessTier, error) {
+	for _, item := range blob.PossibleAccessTierValues() {
+		if strings.EqualFold(string(item), val) {
+			return item, nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid %s: %s; allowed: %s", metadataKeyAccessTier, val, blob.PossibleAccessTierValues())
+}
+
+func parseRehydratePriority(val string) (blob.RehydratePriority, error) {
+	for _, item := range blob.PossibleRehydratePriorityValues() {
+		if strings.EqualFold(string(item), val) {
+			return item, nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid %s: %s; allowed: %s", metadataKeyRehydratePriority, val, blob.PossibleRehydratePriorityValues())
+}
+
+func parseImmutabilityPolicyMode(val string) (blob.ImmutabilityPolicySetting, error) {
+	for _, item := range blob.PossibleImmutabilityPolicySettingValues() {
+		if strings.EqualFold(string(item), val) {
+			return item, nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid %s: %s; allowed: %s", metadataKeyImmutabilityPolicyMode, val, blob.PossibleImmutabilityPolicySettingValues())
+}
+
+// presign returns a SAS URL of a blob, or of the snapshot of the "snapshot" metadata, valid for the duration of the
+// "presignTTL" metadata and granting the permissions of the "presignPermissions" metadata.
+// The URL is signed with the account key, or with a user delegation key when the component is authenticated with
+// Azure AD.
+func (a *AzureBlobStorage) presign(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	val, ok := req.Metadata[metadataKeyPresignTTL]
+	if !ok || val == "" {
+		return nil, fmt.Errorf("%s is a required attribute", metadataKeyPresignTTL)
+	}
+	ttl, err := time.ParseDuration(val)
+	if err != nil || ttl <= 0 {
+		return nil, fmt.Errorf("invalid %s: %s", metadataKeyPresignTTL, val)
+	}
+	if ttl > maxPresignTTL {
+		return nil, fmt.Errorf("invalid %s: %s; the maximum is %s", metadataKeyPresignTTL, val, maxPresignTTL)
+	}
+
+	permissions := sas.BlobPermissions{Read: true}
+	if val := req.Metadata[metadataKeyPresignPermissions]; val != "" {
+		permissions, err = parseBlobPermissions(val)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	blobClient, err := a.snapshotBlobClient(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var presignURL string
+	expiry := time.Now().Add(ttl)
+	switch {
+	case a.metadata.AccountKey != "":
+		presignURL, err = blobClient.GetSASURL(permissions, time.Time{}, expiry)
+	case a.serviceClient != nil:
+		presignURL, err = a.userDelegationSASURL(ctx, blobClient, permissions, expiry)
+	default:
+		return nil, errors.New("the presign operation requires the component to be authenticated with the account key or Azure AD")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error presigning az blob: %w", err)
+	}
+
+	b, err := json.Marshal(presignResponse{
+		PresignURL: presignURL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling presign response for azure blob: %w", err)
+	}
+
+	return &bindings.InvokeResponse{Data: b}, nil
+}
+
+// userDelegationSASURL returns a SAS URL of the blob signed with a user delegation key, valid until expiry.
+// The identity of the component needs the Storage Blob Delegator role, and roles granting the permissions.
+func (a *AzureBlobStorage) userDelegationSASURL(ctx context.Context, blobClient *blob.Client, permissions sas.BlobPermissions, expiry time.Time) (string, error) {
+	// Tolerates the clock skew with the storage account
+	start := time.Now().Add(-5 * time.Minute).UTC()
+	expiry = expiry.UTC()
+	credential, err := a.serviceClient.GetUserDelegationCredential(ctx, service.KeyInfo{
+		Start:  to.Ptr(start.Format(sas.TimeFormat)),
+		Expiry: to.Ptr(expiry.Format(sas.TimeFormat)),
+	}, nil)
+	if err != nil {
+		return "", fmt.Errorf("error getting the user delegation key: %w", err)
+	}
+
+	urlParts, err := blob.ParseURL(blobClient.URL())
+	if err != nil {
+		return "", err
+	}
+	snapshotTime, _ := time.Parse(blob.SnapshotTimeFormat, urlParts.Snapshot)
+	urlParts.SAS, err = sas.BlobSignatureValues{
+		Version:       sas.Version,
+		Protocol:      sas.ProtocolHTTPS,
+		StartTime:     start,
+		ExpiryTime:    expiry,
+		Permissions:   permissions.String(),
+		ContainerName: urlParts.ContainerName,
+		BlobName:      urlParts.BlobName,
+		SnapshotTime:  snapshotTime,
+	}.SignWithUserDelegation(credential)
+	if err != nil {
+		return "", err
+	}
+
+	return urlParts.String(), nil
+}
+
+// parseBlobPermissions parses the permissions of a SAS URL of a blob.
+func parseBlobPermissions(val string) (sas.BlobPermissions, error) {
+	var permissions sas.BlobPermissions
+	for _, c := range val {
+		switch c {
+		case 'r':
+			permissions.Read = true
+		case 'a':
+			permissions.Add = true
+		case 'c':
+			permissions.Create = true
+		case 'w':
+			permissions.Write = true
+		case 'd':
+			permissions.Delete = true
+		case 't':
+			permissions.Tag = true
+		default:
+			return sas.BlobPermissions{}, fmt.Errorf("invalid %s: %s; allowed: r, a, c, w, d, t", metadataKeyPresignPermissions, val)
+		}
+	}
+
+	return permissions, nil
+}
+
+// parseRange returns the range of the "rangeOffset" and "rangeCount" metadata; the whole blob if not set.
+func parseRange(md map[string]string) (blob.HTTPRange, error) {
+	var (
+		httpRange blob.HTTPRange
+		err       error
+	)
+	if val := md[metadataKeyRangeOffset]; val != "" {
+		httpRange.Offset, err = strconv.ParseInt(val, 10, 64)
+		if err != nil || httpRange.Offset < 0 {
+			return blob.HTTPRange{}, fmt.Errorf("invalid %s: %s", metadataKeyRangeOffset, val)
+		}
+	}
+	if val := md[metadataKeyRangeCount]; val != "" {
+		httpRange.Count, err = strconv.ParseInt(val, 10, 64)
+		if err != nil || httpRange.Count < 1 {
+			return blob.HTTPRange{}, fmt.Errorf("invalid %s: %s", metadataKeyRangeCount, val)
+		}
+	}
+
+	return httpRange, nil
+}
+
 func (a *AzureBlobStorage) isValidDeleteSnapshotsOptionType(accessType azblob.DeleteSnapshotsOptionType) bool {
 	validTypes := azblob.PossibleDeleteSnapshotsOptionTypeValues()
 	for _, item := range validTypes {
@@ -346,3 +769,8 @@ func (a *AzureBlobStorage) isValidDeleteSnapshotsOptionType(accessType azblob.De
 
 	return false
 }
+
+// GetComponentMetadataSchema returns the schema of the metadata of the Blob Storage binding.
+func (a *AzureBlobStorage) GetComponentMetadataSchema() []metadata.MetadataField {
+	return storageinternal.MetadataSchema()
+}
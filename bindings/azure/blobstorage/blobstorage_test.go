@@ -15,11 +15,25 @@ package blobstorage
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/components-contrib/bindings"
+	storageinternal "github.com/dapr/components-contrib/internal/component/azure/blobstorage"
 	"github.com/dapr/kit/logger"
 )
 
@@ -33,6 +47,38 @@ func TestGetOption(t *testing.T) {
 			assert.Equal(t, ErrMissingBlobName, err)
 		}
 	})
+
+	t.Run("return error for invalid range", func(t *testing.T) {
+		r := bindings.InvokeRequest{}
+		r.Metadata = map[string]string{
+			"blobName":   "foo",
+			"rangeCount": "0",
+		}
+		_, err := blobStorage.get(context.Background(), &r)
+		assert.ErrorContains(t, err, "invalid rangeCount")
+	})
+}
+
+func TestParseRange(t *testing.T) {
+	t.Run("whole blob", func(t *testing.T) {
+		httpRange, err := parseRange(map[string]string{})
+
+		require.NoError(t, err)
+		assert.Equal(t, blob.HTTPRange{}, httpRange)
+	})
+
+	t.Run("offset and count", func(t *testing.T) {
+		httpRange, err := parseRange(map[string]string{"rangeOffset": "1024", "rangeCount": "512"})
+
+		require.NoError(t, err)
+		assert.Equal(t, blob.HTTPRange{Offset: 1024, Count: 512}, httpRange)
+	})
+
+	t.Run("invalid offset", func(t *testing.T) {
+		_, err := parseRange(map[string]string{"rangeOffset": "-1"})
+
+		assert.ErrorContains(t, err, "invalid rangeOffset")
+	})
 }
 
 func TestDeleteOption(t *testing.T) {
@@ -55,4 +101,227 @@ func TestDeleteOption(t *testing.T) {
 		_, err := blobStorage.delete(context.Background(), &r)
 		assert.Error(t, err)
 	})
+
+	t.Run("return error for deleteSnapshots of a snapshot", func(t *testing.T) {
+		r := bindings.InvokeRequest{}
+		r.Metadata = map[string]string{
+			"blobName":        "foo",
+			"deleteSnapshots": "include",
+			"snapshot":        "2022-11-01T10:00:00.0000000Z",
+		}
+		_, err := blobStorage.delete(context.Background(), &r)
+		assert.ErrorContains(t, err, "cannot both be specified")
+	})
+}
+
+func TestTierAndImmutabilityOptions(t *testing.T) {
+	containerClient, err := container.NewClientWithNoCredential("https://account.blob.core.windows.net/container", nil)
+	require.NoError(t, err)
+	blobStorage := NewAzureBlobStorage(logger.NewLogger("test")).(*AzureBlobStorage)
+	blobStorage.containerClient = containerClient
+
+	t.Run("return error if blobName is missing", func(t *testing.T) {
+		for _, operation := range []bindings.OperationKind{setTierOperation, rehydrateOperation, setLegalHoldOperation, setImmutabilityPolicyOperation, deleteImmutabilityPolicyOperation, snapshotOperation} {
+			r := bindings.InvokeRequest{Operation: operation}
+			_, err := blobStorage.Invoke(context.Background(), &r)
+			assert.Equal(t, ErrMissingBlobName, err, operation)
+		}
+	})
+
+	t.Run("return error if accessTier is missing", func(t *testing.T) {
+		r := bindings.InvokeRequest{Operation: setTierOperation}
+		r.Metadata = map[string]string{"blobName": "foo"}
+		_, err := blobStorage.Invoke(context.Background(), &r)
+		assert.ErrorContains(t, err, "accessTier is a required attribute")
+	})
+
+	t.Run("return error for invalid accessTier", func(t *testing.T) {
+		r := bindings.InvokeRequest{Operation: setTierOperation}
+		r.Metadata = map[string]string{"blobName": "foo", "accessTier": "Frozen"}
+		_, err := blobStorage.Invoke(context.Background(), &r)
+		assert.ErrorContains(t, err, "invalid accessTier")
+	})
+
+	t.Run("return error for invalid rehydratePriority", func(t *testing.T) {
+		r := bindings.InvokeRequest{Operation: rehydrateOperation}
+		r.Metadata = map[string]string{"blobName": "foo", "rehydratePriority": "Urgent"}
+		_, err := blobStorage.Invoke(context.Background(), &r)
+		assert.ErrorContains(t, err, "invalid rehydratePriority")
+	})
+
+	t.Run("return error for invalid legalHold", func(t *testing.T) {
+		r := bindings.InvokeRequest{Operation: setLegalHoldOperation}
+		r.Metadata = map[string]string{"blobName": "foo", "legalHold": "maybe"}
+		_, err := blobStorage.Invoke(context.Background(), &r)
+		assert.ErrorContains(t, err, "invalid legalHold")
+	})
+
+	t.Run("return error for invalid immutabilityPolicyUntil", func(t *testing.T) {
+		r := bindings.InvokeRequest{Operation: setImmutabilityPolicyOperation}
+		r.Metadata = map[string]string{"blobName": "foo", "immutabilityPolicyUntil": "tomorrow"}
+		_, err := blobStorage.Invoke(context.Background(), &r)
+		assert.ErrorContains(t, err, "invalid immutabilityPolicyUntil")
+	})
+
+	t.Run("return error for invalid immutabilityPolicyMode", func(t *testing.T) {
+		r := bindings.InvokeRequest{Operation: setImmutabilityPolicyOperation}
+		r.Metadata = map[string]string{"blobName": "foo", "immutabilityPolicyUntil": "2030-01-01T00:00:00Z", "immutabilityPolicyMode": "Mutable"}
+		_, err := blobStorage.Invoke(context.Background(), &r)
+		assert.ErrorContains(t, err, "invalid immutabilityPolicyMode")
+	})
+}
+
+func TestParseAccessTier(t *testing.T) {
+	tier, err := parseAccessTier("archive")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Archive", string(tier))
+}
+
+func TestPresignOption(t *testing.T) {
+	accountKey := base64.StdEncoding.EncodeToString([]byte("accountkey"))
+	credential, err := container.NewSharedKeyCredential("account", accountKey)
+	require.NoError(t, err)
+	containerClient, err := container.NewClientWithSharedKeyCredential("https://account.blob.core.windows.net/container", credential, nil)
+	require.NoError(t, err)
+	blobStorage := NewAzureBlobStorage(logger.NewLogger("test")).(*AzureBlobStorage)
+	blobStorage.containerClient = containerClient
+	blobStorage.metadata = &storageinternal.BlobStorageMetadata{}
+	blobStorage.metadata.AccountKey = accountKey
+
+	t.Run("return the SAS URL of the blob", func(t *testing.T) {
+		r := bindings.InvokeRequest{}
+		r.Metadata = map[string]string{
+			"blobName":           "foo",
+			"presignTTL":         "15m",
+			"presignPermissions": "rw",
+		}
+		res, err := blobStorage.presign(context.Background(), &r)
+		require.NoError(t, err)
+
+		var presign presignResponse
+		require.NoError(t, json.Unmarshal(res.Data, &presign))
+		u, err := url.Parse(presign.PresignURL)
+		require.NoError(t, err)
+		assert.Equal(t, "/container/foo", u.Path)
+		assert.Equal(t, "rw", u.Query().Get("sp"))
+		assert.NotEmpty(t, u.Query().Get("se"))
+		assert.NotEmpty(t, u.Query().Get("sig"))
+	})
+
+	t.Run("return read only SAS URL by default", func(t *testing.T) {
+		r := bindings.InvokeRequest{}
+		r.Metadata = map[string]string{
+			"blobName":   "foo",
+			"presignTTL": "1h",
+		}
+		res, err := blobStorage.presign(context.Background(), &r)
+		require.NoError(t, err)
+
+		var presign presignResponse
+		require.NoError(t, json.Unmarshal(res.Data, &presign))
+		u, err := url.Parse(presign.PresignURL)
+		require.NoError(t, err)
+		assert.Equal(t, "r", u.Query().Get("sp"))
+	})
+
+	t.Run("return error if blobName is missing", func(t *testing.T) {
+		r := bindings.InvokeRequest{}
+		r.Metadata = map[string]string{
+			"presignTTL": "15m",
+		}
+		_, err := blobStorage.presign(context.Background(), &r)
+		assert.Equal(t, ErrMissingBlobName, err)
+	})
+
+	t.Run("return error for missing or invalid presignTTL", func(t *testing.T) {
+		r := bindings.InvokeRequest{}
+		r.Metadata = map[string]string{
+			"blobName": "foo",
+		}
+		_, err := blobStorage.presign(context.Background(), &r)
+		assert.ErrorContains(t, err, "presignTTL is a required attribute")
+
+		r.Metadata["presignTTL"] = "-1m"
+		_, err = blobStorage.presign(context.Background(), &r)
+		assert.ErrorContains(t, err, "invalid presignTTL")
+
+		r.Metadata["presignTTL"] = "169h"
+		_, err = blobStorage.presign(context.Background(), &r)
+		assert.EqualError(t, err, "invalid presignTTL: 169h; the maximum is 168h0m0s")
+	})
+
+	t.Run("return error for invalid presignPermissions", func(t *testing.T) {
+		r := bindings.InvokeRequest{}
+		r.Metadata = map[string]string{
+			"blobName":           "foo",
+			"presignTTL":         "15m",
+			"presignPermissions": "rx",
+		}
+		_, err := blobStorage.presign(context.Background(), &r)
+		assert.ErrorContains(t, err, "invalid presignPermissions")
+	})
+
+	t.Run("return error without account key", func(t *testing.T) {
+		noKeyStorage := NewAzureBlobStorage(logger.NewLogger("test")).(*AzureBlobStorage)
+		noKeyStorage.containerClient = containerClient
+		noKeyStorage.metadata = &storageinternal.BlobStorageMetadata{}
+		r := bindings.InvokeRequest{}
+		r.Metadata = map[string]string{
+			"blobName":   "foo",
+			"presignTTL": "15m",
+		}
+		_, err := noKeyStorage.presign(context.Background(), &r)
+		assert.ErrorContains(t, err, "account key or Azure AD")
+	})
+}
+
+type fakeTokenCredential struct{}
+
+func (fakeTokenCredential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func TestPresignWithUserDelegation(t *testing.T) {
+	var keyInfo service.KeyInfo
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "userdelegationkey", r.URL.Query().Get("comp"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		require.NoError(t, xml.NewDecoder(r.Body).Decode(&keyInfo))
+		w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?><UserDelegationKey>` +
+			`<SignedOid>oid</SignedOid><SignedTid>tid</SignedTid>` +
+			`<SignedStart>` + *keyInfo.Start + `</SignedStart><SignedExpiry>` + *keyInfo.Expiry + `</SignedExpiry>` +
+			`<SignedService>b</SignedService><SignedVersion>2020-02-10</SignedVersion>` +
+			`<Value>` + base64.StdEncoding.EncodeToString([]byte("delegationkey")) + `</Value></UserDelegationKey>`))
+	}))
+	defer server.Close()
+
+	serviceClient, err := service.NewClient(server.URL, fakeTokenCredential{}, &service.ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: server.Client()},
+	})
+	require.NoError(t, err)
+	containerClient, err := container.NewClient("https://account.blob.core.windows.net/container", fakeTokenCredential{}, nil)
+	require.NoError(t, err)
+	blobStorage := NewAzureBlobStorage(logger.NewLogger("test")).(*AzureBlobStorage)
+	blobStorage.containerClient = containerClient
+	blobStorage.serviceClient = serviceClient
+	blobStorage.metadata = &storageinternal.BlobStorageMetadata{}
+
+	res, err := blobStorage.presign(context.Background(), &bindings.InvokeRequest{Metadata: map[string]string{
+		"blobName":   "foo",
+		"presignTTL": "15m",
+	}})
+	require.NoError(t, err)
+
+	var presign presignResponse
+	require.NoError(t, json.Unmarshal(res.Data, &presign))
+	u, err := url.Parse(presign.PresignURL)
+	require.NoError(t, err)
+	assert.Equal(t, "/container/foo", u.Path)
+	assert.Equal(t, "r", u.Query().Get("sp"))
+	assert.Equal(t, "https", u.Query().Get("spr"))
+	assert.Equal(t, "oid", u.Query().Get("skoid"))
+	assert.NotEmpty(t, u.Query().Get("sig"))
+	// The key is valid as long as the URL
+	assert.Equal(t, *keyInfo.Expiry, u.Query().Get("se"))
 }
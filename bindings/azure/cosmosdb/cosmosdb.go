@@ -17,6 +17,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -25,6 +27,7 @@ import (
 
 	"github.com/dapr/components-contrib/bindings"
 	"github.com/dapr/components-contrib/internal/authentication/azure"
+	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 )
 
@@ -37,16 +40,41 @@ type CosmosDB struct {
 }
 
 type cosmosDBCredentials struct {
-	URL          string `json:"url"`
-	MasterKey    string `json:"masterKey"`
-	Database     string `json:"database"`
-	Collection   string `json:"collection"`
-	PartitionKey string `json:"partitionKey"`
+	URL          string `json:"url" mdrequired:"true"`
+	MasterKey    string `json:"masterKey" mdsensitive:"true"`
+	Database     string `json:"database" mdrequired:"true"`
+	Collection   string `json:"collection" mdrequired:"true"`
+	PartitionKey string `json:"partitionKey" mdrequired:"true"`
 }
 
 // Value used for timeout durations
 const timeoutValue = 30
 
+const (
+	// QueryOperation runs the SQL query of the query metadata, with the parameters in the data of the request.
+	QueryOperation bindings.OperationKind = "query"
+
+	queryKey             = "query"
+	partitionKeyKey      = "partitionKey"
+	continuationTokenKey = "continuationToken"
+	pageSizeKey          = "pageSize"
+)
+
+// crossPartitionQueryKey is the key of the context of the queries which are sent to all the partitions.
+type crossPartitionQueryKey struct{}
+
+// crossPartitionQueryPolicy enables the cross-partition queries, which the Go sdk does not support.
+type crossPartitionQueryPolicy struct{}
+
+func (p *crossPartitionQueryPolicy) Do(req *policy.Request) (*http.Response, error) {
+	raw := req.Raw()
+	if raw.Context().Value(crossPartitionQueryKey{}) != nil && strings.ToLower(raw.Header.Get("x-ms-documentdb-query")) == "true" {
+		raw.Header.Set("x-ms-documentdb-query-enablecrosspartition", "true")
+		raw.Header.Del("x-ms-documentdb-partitionkey")
+	}
+	return req.Next()
+}
+
 // NewCosmosDB returns a new CosmosDB instance.
 func NewCosmosDB(logger logger.Logger) bindings.OutputBinding {
 	return &CosmosDB{logger: logger}
@@ -61,13 +89,7 @@ func (c *CosmosDB) Init(metadata bindings.Metadata) error {
 
 	c.partitionKey = m.PartitionKey
 
-	opts := azcosmos.ClientOptions{
-		ClientOptions: policy.ClientOptions{
-			Telemetry: policy.TelemetryOptions{
-				ApplicationID: "dapr-" + logger.DaprVersion,
-			},
-		},
-	}
+	opts := clientOptions()
 
 	// Create the client; first, try authenticating with a master key, if present
 	var client *azcosmos.Client
@@ -76,7 +98,7 @@ func (c *CosmosDB) Init(metadata bindings.Metadata) error {
 		if keyErr != nil {
 			return keyErr
 		}
-		client, err = azcosmos.NewClientWithKey(m.URL, cred, &opts)
+		client, err = azcosmos.NewClientWithKey(m.URL, cred, opts)
 		if err != nil {
 			return err
 		}
@@ -90,7 +112,7 @@ func (c *CosmosDB) Init(metadata bindings.Metadata) error {
 		if errToken != nil {
 			return errToken
 		}
-		client, err = azcosmos.NewClient(m.URL, token, &opts)
+		client, err = azcosmos.NewClient(m.URL, token, opts)
 		if err != nil {
 			return err
 		}
@@ -109,6 +131,17 @@ func (c *CosmosDB) Init(metadata bindings.Metadata) error {
 	return err
 }
 
+func clientOptions() *azcosmos.ClientOptions {
+	return &azcosmos.ClientOptions{
+		ClientOptions: policy.ClientOptions{
+			PerCallPolicies: []policy.Policy{&crossPartitionQueryPolicy{}},
+			Telemetry: policy.TelemetryOptions{
+				ApplicationID: "dapr-" + logger.DaprVersion,
+			},
+		},
+	}
+}
+
 func (c *CosmosDB) parseMetadata(metadata bindings.Metadata) (*cosmosDBCredentials, error) {
 	connInfo := metadata.Properties
 	b, err := json.Marshal(connInfo)
@@ -125,8 +158,14 @@ func (c *CosmosDB) parseMetadata(metadata bindings.Metadata) (*cosmosDBCredentia
 	return &creds, nil
 }
 
+// GetComponentMetadataSchema returns the schema of the metadata of the Cosmos DB binding.
+func (c *CosmosDB) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(cosmosDBCredentials{})
+	return fields
+}
+
 func (c *CosmosDB) Operations() []bindings.OperationKind {
-	return []bindings.OperationKind{bindings.CreateOperation}
+	return []bindings.OperationKind{bindings.CreateOperation, QueryOperation}
 }
 
 func (c *CosmosDB) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
@@ -149,11 +188,80 @@ func (c *CosmosDB) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bi
 			return nil, err
 		}
 		return nil, nil
+	case QueryOperation:
+		return c.query(ctx, req)
 	default:
 		return nil, fmt.Errorf("operation kind %s not supported", req.Operation)
 	}
 }
 
+// query returns a page of the results of the query as a JSON array, with the continuationToken of the next page in
+// the metadata of the response. Without a partitionKey in the metadata, the query is sent to all the partitions.
+func (c *CosmosDB) query(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	query := req.Metadata[queryKey]
+	if query == "" {
+		return nil, fmt.Errorf("required metadata not set: %s", queryKey)
+	}
+
+	opts := &azcosmos.QueryOptions{
+		ContinuationToken: req.Metadata[continuationTokenKey],
+	}
+	if val := req.Metadata[pageSizeKey]; val != "" {
+		pageSize, err := strconv.ParseInt(val, 10, 32)
+		if err != nil || pageSize <= 0 {
+			return nil, fmt.Errorf("invalid %s %s", pageSizeKey, val)
+		}
+		opts.PageSizeHint = int32(pageSize)
+	}
+	if len(req.Data) > 0 {
+		var params map[string]interface{}
+		err := json.Unmarshal(req.Data, &params)
+		if err != nil {
+			return nil, fmt.Errorf("query parameters must be a JSON object: %w", err)
+		}
+		for name, value := range params {
+			if !strings.HasPrefix(name, "@") {
+				name = "@" + name
+			}
+			opts.QueryParameters = append(opts.QueryParameters, azcosmos.QueryParameter{Name: name, Value: value})
+		}
+	}
+
+	var pk azcosmos.PartitionKey
+	if val, ok := req.Metadata[partitionKeyKey]; ok && val != "" {
+		pk = azcosmos.NewPartitionKeyString(val)
+	} else {
+		pk = azcosmos.NewPartitionKeyBool(true)
+		ctx = context.WithValue(ctx, crossPartitionQueryKey{}, true)
+	}
+
+	page, err := c.client.NewQueryItemsPager(query, pk, opts).NextPage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]json.RawMessage, len(page.Items))
+	for i, item := range page.Items {
+		items[i] = item
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]string{
+		"count": strconv.Itoa(len(items)),
+	}
+	if page.ContinuationToken != "" {
+		metadata[continuationTokenKey] = page.ContinuationToken
+	}
+
+	return &bindings.InvokeResponse{
+		Data:     data,
+		Metadata: metadata,
+	}, nil
+}
+
 func (c *CosmosDB) getPartitionKeyValue(key string, obj interface{}) (string, error) {
 	valI, err := c.lookup(obj.(map[string]interface{}), strings.Split(key, "."))
 	if err != nil {
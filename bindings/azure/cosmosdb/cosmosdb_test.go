@@ -14,10 +14,17 @@ limitations under the License.
 package cosmosdb
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/components-contrib/bindings"
 	"github.com/dapr/kit/logger"
@@ -75,3 +82,84 @@ func TestPartitionKeyValue(t *testing.T) {
 	_, err = cosmosDB.getPartitionKeyValue("", obj)
 	assert.NotNil(t, err)
 }
+
+func TestQuery(t *testing.T) {
+	var headers http.Header
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		b, _ := io.ReadAll(r.Body)
+		body = nil
+		json.Unmarshal(b, &body)
+
+		if r.Header.Get("x-ms-continuation") == "" {
+			w.Header().Set("x-ms-continuation", "page2")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"Documents": [{"id": "1", "name": "a"}, {"id": "2", "name": "b"}], "_count": 2}`))
+	}))
+	defer server.Close()
+
+	cred, err := azcosmos.NewKeyCredential(base64.StdEncoding.EncodeToString([]byte("key")))
+	require.NoError(t, err)
+	client, err := azcosmos.NewClientWithKey(server.URL, cred, clientOptions())
+	require.NoError(t, err)
+	container, err := client.NewContainer("db", "items")
+	require.NoError(t, err)
+	cosmosDB := CosmosDB{client: container, logger: logger.NewLogger("test")}
+
+	t.Run("queries all the partitions", func(t *testing.T) {
+		res, err := cosmosDB.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: QueryOperation,
+			Data:      []byte(`{"@name": "a", "count": 1}`),
+			Metadata:  map[string]string{"query": "SELECT * FROM c WHERE c.name = @name AND c.count > @count", "pageSize": "2"},
+		})
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"id": "1", "name": "a"}, {"id": "2", "name": "b"}]`, string(res.Data))
+		assert.Equal(t, "page2", res.Metadata["continuationToken"])
+		assert.Equal(t, "2", res.Metadata["count"])
+
+		assert.Equal(t, "true", headers.Get("x-ms-documentdb-query-enablecrosspartition"))
+		assert.Empty(t, headers.Get("x-ms-documentdb-partitionkey"))
+		assert.Equal(t, "2", headers.Get("x-ms-max-item-count"))
+		assert.Equal(t, "SELECT * FROM c WHERE c.name = @name AND c.count > @count", body["query"])
+		assert.ElementsMatch(t, []interface{}{
+			map[string]interface{}{"name": "@name", "value": "a"},
+			map[string]interface{}{"name": "@count", "value": float64(1)},
+		}, body["parameters"])
+	})
+
+	t.Run("queries a partition from a continuation token", func(t *testing.T) {
+		res, err := cosmosDB.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: QueryOperation,
+			Metadata: map[string]string{
+				"query":             "SELECT * FROM c",
+				"partitionKey":      "a",
+				"continuationToken": "page2",
+			},
+		})
+
+		require.NoError(t, err)
+		assert.NotContains(t, res.Metadata, "continuationToken")
+		assert.Empty(t, headers.Get("x-ms-documentdb-query-enablecrosspartition"))
+		assert.Equal(t, `["a"]`, headers.Get("x-ms-documentdb-partitionkey"))
+		assert.Equal(t, "page2", headers.Get("x-ms-continuation"))
+	})
+
+	t.Run("returns error for missing query", func(t *testing.T) {
+		_, err := cosmosDB.Invoke(context.Background(), &bindings.InvokeRequest{Operation: QueryOperation})
+
+		assert.ErrorContains(t, err, "required metadata not set: query")
+	})
+
+	t.Run("returns error for invalid parameters", func(t *testing.T) {
+		_, err := cosmosDB.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: QueryOperation,
+			Data:      []byte(`["a"]`),
+			Metadata:  map[string]string{"query": "SELECT * FROM c"},
+		})
+
+		assert.ErrorContains(t, err, "query parameters must be a JSON object")
+	})
+}
@@ -0,0 +1,395 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cosmosdbchangefeed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/google/uuid"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/internal/authentication/azure"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	startFromNow       = "now"
+	startFromBeginning = "beginning"
+
+	// Metadata of the events, with the partition key range of the changed document.
+	partitionKeyRangeIDKey = "partitionKeyRangeId"
+
+	defaultLeaseCollection = "leases"
+	defaultLeaseExpiration = time.Minute
+	defaultPollInterval    = 5 * time.Second
+	defaultMaxItemCount    = 100
+
+	// Value used for timeout durations
+	timeoutValue = 30
+)
+
+// CosmosDBChangeFeed is an input binding delivering the changes of the documents of a Cosmos DB container.
+// The partition key ranges of the container are shared between the instances of the binding with leases, stored with
+// the continuation of their change feed in a lease container.
+type CosmosDBChangeFeed struct {
+	metadata      *changeFeedMetadata
+	feed          *feedClient
+	leases        *leaseStore
+	startTime     time.Time
+	fromBeginning bool
+
+	// Partition key ranges processed by this instance
+	owned      map[string]struct{}
+	ownedLock  sync.Mutex
+	rediscover chan struct{}
+
+	logger logger.Logger
+}
+
+type changeFeedMetadata struct {
+	URL             string `mapstructure:"url" mdrequired:"true"`
+	MasterKey       string `mapstructure:"masterKey" mdsensitive:"true"`
+	Database        string `mapstructure:"database" mdrequired:"true"`
+	Collection      string `mapstructure:"collection" mdrequired:"true"`
+	LeaseCollection string `mapstructure:"leaseCollection"`
+	LeasePrefix     string `mapstructure:"leasePrefix"`
+	// "now" (default), "beginning", or a time in RFC3339 format.
+	StartFrom               string        `mapstructure:"startFrom"`
+	MaxConcurrentPartitions int           `mapstructure:"maxConcurrentPartitions"`
+	MaxItemCount            int           `mapstructure:"maxItemCount"`
+	PollInterval            time.Duration `mapstructure:"pollInterval"`
+	LeaseExpiration         time.Duration `mapstructure:"leaseExpiration"`
+}
+
+// NewCosmosDBChangeFeed returns a new Cosmos DB change feed input binding.
+func NewCosmosDBChangeFeed(logger logger.Logger) bindings.InputBinding {
+	return &CosmosDBChangeFeed{
+		owned:      make(map[string]struct{}),
+		rediscover: make(chan struct{}, 1),
+		logger:     logger,
+	}
+}
+
+// Init creates the lease container if it does not exist.
+func (c *CosmosDBChangeFeed) Init(metadata bindings.Metadata) error {
+	m, err := c.parseMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	c.metadata = m
+
+	opts := azcosmos.ClientOptions{
+		ClientOptions: policy.ClientOptions{
+			Telemetry: policy.TelemetryOptions{
+				ApplicationID: "dapr-" + logger.DaprVersion,
+			},
+		},
+	}
+
+	// Create the clients; first, try authenticating with a master key, if present
+	var (
+		client    *azcosmos.Client
+		authorize authorizer
+	)
+	if m.MasterKey != "" {
+		cred, keyErr := azcosmos.NewKeyCredential(m.MasterKey)
+		if keyErr != nil {
+			return keyErr
+		}
+		client, err = azcosmos.NewClientWithKey(m.URL, cred, &opts)
+		if err != nil {
+			return err
+		}
+		authorize, err = masterKeyAuthorizer(m.MasterKey)
+		if err != nil {
+			return err
+		}
+	} else {
+		// Fallback to using Azure AD
+		env, errEnv := azure.NewEnvironmentSettings("cosmosdb", metadata.Properties)
+		if errEnv != nil {
+			return errEnv
+		}
+		token, errToken := env.GetTokenCredential()
+		if errToken != nil {
+			return errToken
+		}
+		client, err = azcosmos.NewClient(m.URL, token, &opts)
+		if err != nil {
+			return err
+		}
+		authorize, err = tokenAuthorizer(token, m.URL)
+		if err != nil {
+			return err
+		}
+	}
+	c.feed = newFeedClient(m.URL, m.Database, m.Collection, authorize)
+
+	db, err := client.NewDatabase(m.Database)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeoutValue*time.Second)
+	defer cancel()
+	_, err = db.CreateContainer(ctx, azcosmos.ContainerProperties{
+		ID: m.LeaseCollection,
+		PartitionKeyDefinition: azcosmos.PartitionKeyDefinition{
+			Paths: []string{"/id"},
+		},
+	}, nil)
+	if err != nil && !hasStatusCode(err, http.StatusConflict) {
+		return fmt.Errorf("error creating lease collection %s: %w", m.LeaseCollection, err)
+	}
+	leaseContainer, err := db.NewContainer(m.LeaseCollection)
+	if err != nil {
+		return err
+	}
+	c.leases = newLeaseStore(leaseContainer, m.LeasePrefix, uuid.New().String(), m.LeaseExpiration)
+
+	return nil
+}
+
+func (c *CosmosDBChangeFeed) parseMetadata(meta bindings.Metadata) (*changeFeedMetadata, error) {
+	m := changeFeedMetadata{
+		LeaseCollection: defaultLeaseCollection,
+		StartFrom:       startFromNow,
+		MaxItemCount:    defaultMaxItemCount,
+		PollInterval:    defaultPollInterval,
+		LeaseExpiration: defaultLeaseExpiration,
+	}
+	err := metadata.DecodeMetadata(meta.Properties, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.URL == "" || m.Database == "" || m.Collection == "" {
+		return nil, errors.New("url, database and collection are required")
+	}
+	if m.LeasePrefix == "" {
+		m.LeasePrefix = m.Collection + "."
+	}
+	if m.MaxConcurrentPartitions < 0 {
+		return nil, fmt.Errorf("invalid maxConcurrentPartitions %d", m.MaxConcurrentPartitions)
+	}
+	if m.PollInterval <= 0 {
+		return nil, fmt.Errorf("invalid pollInterval %s", m.PollInterval)
+	}
+	if m.LeaseExpiration <= 0 {
+		return nil, fmt.Errorf("invalid leaseExpiration %s", m.LeaseExpiration)
+	}
+
+	switch strings.ToLower(m.StartFrom) {
+	case startFromNow:
+	case startFromBeginning:
+		c.fromBeginning = true
+	default:
+		c.startTime, err = time.Parse(time.RFC3339, m.StartFrom)
+		if err != nil {
+			return nil, fmt.Errorf("invalid startFrom %s, expected %s, %s or a time in RFC3339 format", m.StartFrom, startFromNow, startFromBeginning)
+		}
+	}
+
+	return &m, nil
+}
+
+// Read processes the change feed of the partition key ranges whose lease this instance acquires.
+func (c *CosmosDBChangeFeed) Read(ctx context.Context, handler bindings.Handler) error {
+	go func() {
+		// The leases are acquired again regularly, to process the ranges of the instances which stopped
+		ticker := time.NewTicker(c.metadata.LeaseExpiration / 2)
+		defer ticker.Stop()
+
+		for {
+			err := c.discover(ctx, handler)
+			if err != nil {
+				c.logger.Errorf("Error acquiring the leases of the partition key ranges: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-c.rediscover:
+			}
+		}
+	}()
+
+	return nil
+}
+
+// discover creates the leases of the current partition key ranges, and starts processing the ranges whose lease it
+// acquires.
+func (c *CosmosDBChangeFeed) discover(ctx context.Context, handler bindings.Handler) error {
+	ranges, err := c.feed.partitionKeyRanges(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range ranges {
+		err = c.leases.ensure(ctx, r.ID, r.Parents)
+		if err != nil {
+			return err
+		}
+	}
+	// The changes of the ranges which were replaced are read from their children from now on
+	for _, r := range ranges {
+		for _, parent := range r.Parents {
+			err = c.leases.remove(ctx, parent)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, r := range ranges {
+		c.ownedLock.Lock()
+		_, owned := c.owned[r.ID]
+		full := c.metadata.MaxConcurrentPartitions > 0 && len(c.owned) >= c.metadata.MaxConcurrentPartitions
+		c.ownedLock.Unlock()
+		if owned {
+			continue
+		}
+		if full {
+			break
+		}
+
+		l, err := c.leases.acquire(ctx, r.ID)
+		if err != nil {
+			return err
+		}
+		if l == nil {
+			continue
+		}
+
+		c.ownedLock.Lock()
+		c.owned[r.ID] = struct{}{}
+		c.ownedLock.Unlock()
+		go c.processRange(ctx, l, handler)
+	}
+
+	return nil
+}
+
+// processRange delivers the changes of a partition key range until its lease is lost or the context is canceled.
+func (c *CosmosDBChangeFeed) processRange(ctx context.Context, l *lease, handler bindings.Handler) {
+	rangeID := l.PartitionKeyRangeID
+	c.logger.Debugf("Processing the changes of partition key range %s", rangeID)
+	defer func() {
+		c.ownedLock.Lock()
+		delete(c.owned, rangeID)
+		c.ownedLock.Unlock()
+	}()
+
+	renewedAt := time.Now()
+	for ctx.Err() == nil {
+		docs, continuation, err := c.feed.readChanges(ctx, rangeID, l.ContinuationToken, c.startTime, c.fromBeginning, c.metadata.MaxItemCount)
+		if errors.Is(err, errPartitionGone) {
+			c.logger.Infof("Partition key range %s was split or merged", rangeID)
+			c.releaseLease(l)
+			select {
+			case c.rediscover <- struct{}{}:
+			default:
+			}
+			return
+		}
+		readErr := err
+		if readErr != nil {
+			if ctx.Err() == nil {
+				c.logger.Errorf("Error reading the changes of partition key range %s: %v", rangeID, readErr)
+			}
+		} else if c.handleChanges(ctx, rangeID, docs, handler) {
+			if continuation != "" && continuation != l.ContinuationToken {
+				l.ContinuationToken = continuation
+				renewedAt = time.Time{}
+			}
+		}
+
+		// The lease is renewed with the new continuation, or before it expires
+		if time.Since(renewedAt) > c.metadata.LeaseExpiration/3 {
+			err = c.leases.update(ctx, l)
+			if errors.Is(err, errLeaseLost) {
+				c.logger.Infof("Lease of partition key range %s was acquired by another instance", rangeID)
+				return
+			}
+			if err != nil {
+				c.logger.Errorf("Error updating the lease of partition key range %s: %v", rangeID, err)
+			} else {
+				renewedAt = time.Now()
+			}
+		}
+
+		// The next changes are read right away if the page was full
+		if readErr == nil && len(docs) > 0 && len(docs) >= c.metadata.MaxItemCount {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(c.metadata.PollInterval):
+		}
+	}
+
+	c.releaseLease(l)
+}
+
+// handleChanges invokes the handler for each changed document, and returns whether they were all handled.
+// The changes are read again from the same continuation otherwise.
+func (c *CosmosDBChangeFeed) handleChanges(ctx context.Context, rangeID string, docs []json.RawMessage, handler bindings.Handler) bool {
+	for _, doc := range docs {
+		_, err := handler(ctx, &bindings.ReadResponse{
+			Data: doc,
+			Metadata: map[string]string{
+				partitionKeyRangeIDKey: rangeID,
+			},
+		})
+		if err != nil {
+			c.logger.Errorf("Error handling the changes of partition key range %s: %v", rangeID, err)
+			return false
+		}
+	}
+
+	return true
+}
+
+// releaseLease releases a lease, so that another instance can acquire it without waiting for its expiration.
+func (c *CosmosDBChangeFeed) releaseLease(l *lease) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeoutValue*time.Second)
+	defer cancel()
+
+	err := c.leases.release(ctx, l)
+	if err != nil && !errors.Is(err, errLeaseLost) {
+		c.logger.Warnf("Error releasing the lease of partition key range %s: %v", l.PartitionKeyRangeID, err)
+	}
+}
+
+// GetComponentMetadataSchema returns the schema of the metadata of the Cosmos DB change feed binding.
+func (c *CosmosDBChangeFeed) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(changeFeedMetadata{
+		LeaseCollection: defaultLeaseCollection,
+		StartFrom:       startFromNow,
+		MaxItemCount:    defaultMaxItemCount,
+		PollInterval:    defaultPollInterval,
+		LeaseExpiration: defaultLeaseExpiration,
+	})
+	return fields
+}
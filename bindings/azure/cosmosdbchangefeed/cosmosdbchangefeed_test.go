@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cosmosdbchangefeed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/kit/logger"
+)
+
+func TestParseMetadata(t *testing.T) {
+	properties := func(extra map[string]string) map[string]string {
+		p := map[string]string{"url": "https://account.documents.azure.com:443/", "database": "db", "collection": "coll"}
+		for k, v := range extra {
+			p[k] = v
+		}
+		return p
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		c := NewCosmosDBChangeFeed(logger.NewLogger("test")).(*CosmosDBChangeFeed)
+		m := bindings.Metadata{}
+		m.Properties = properties(nil)
+
+		meta, err := c.parseMetadata(m)
+
+		require.NoError(t, err)
+		assert.Equal(t, "leases", meta.LeaseCollection)
+		assert.Equal(t, "coll.", meta.LeasePrefix)
+		assert.Equal(t, 100, meta.MaxItemCount)
+		assert.Equal(t, 0, meta.MaxConcurrentPartitions)
+		assert.Equal(t, 5*time.Second, meta.PollInterval)
+		assert.Equal(t, time.Minute, meta.LeaseExpiration)
+		assert.False(t, c.fromBeginning)
+		assert.True(t, c.startTime.IsZero())
+	})
+
+	t.Run("options", func(t *testing.T) {
+		c := NewCosmosDBChangeFeed(logger.NewLogger("test")).(*CosmosDBChangeFeed)
+		m := bindings.Metadata{}
+		m.Properties = properties(map[string]string{
+			"leaseCollection":         "myleases",
+			"leasePrefix":             "app.",
+			"startFrom":               "2022-11-01T00:00:00Z",
+			"maxConcurrentPartitions": "4",
+			"maxItemCount":            "10",
+			"pollInterval":            "1s",
+			"leaseExpiration":         "30s",
+		})
+
+		meta, err := c.parseMetadata(m)
+
+		require.NoError(t, err)
+		assert.Equal(t, "myleases", meta.LeaseCollection)
+		assert.Equal(t, "app.", meta.LeasePrefix)
+		assert.Equal(t, 4, meta.MaxConcurrentPartitions)
+		assert.Equal(t, 10, meta.MaxItemCount)
+		assert.Equal(t, time.Second, meta.PollInterval)
+		assert.Equal(t, 30*time.Second, meta.LeaseExpiration)
+		assert.Equal(t, time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC), c.startTime)
+	})
+
+	t.Run("start from the beginning", func(t *testing.T) {
+		c := NewCosmosDBChangeFeed(logger.NewLogger("test")).(*CosmosDBChangeFeed)
+		m := bindings.Metadata{}
+		m.Properties = properties(map[string]string{"startFrom": "Beginning"})
+
+		_, err := c.parseMetadata(m)
+
+		require.NoError(t, err)
+		assert.True(t, c.fromBeginning)
+	})
+
+	t.Run("invalid startFrom", func(t *testing.T) {
+		c := NewCosmosDBChangeFeed(logger.NewLogger("test")).(*CosmosDBChangeFeed)
+		m := bindings.Metadata{}
+		m.Properties = properties(map[string]string{"startFrom": "yesterday"})
+
+		_, err := c.parseMetadata(m)
+
+		assert.ErrorContains(t, err, "invalid startFrom")
+	})
+
+	t.Run("missing collection", func(t *testing.T) {
+		c := NewCosmosDBChangeFeed(logger.NewLogger("test")).(*CosmosDBChangeFeed)
+		m := bindings.Metadata{}
+		m.Properties = map[string]string{"url": "https://account.documents.azure.com:443/", "database": "db"}
+
+		_, err := c.parseMetadata(m)
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cosmosdbchangefeed
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// The change feed is not exposed by the Cosmos DB SDK yet: it is read with the REST API.
+// See: https://learn.microsoft.com/rest/api/cosmos-db/list-documents
+
+const apiVersion = "2018-12-31"
+
+// errPartitionGone is returned when a partition key range was split or merged, and its changes must be read from the
+// ranges which replaced it.
+var errPartitionGone = errors.New("partition key range is gone")
+
+// authorizer returns the value of the authorization header of a request.
+type authorizer func(ctx context.Context, method, resourceType, resourceLink, date string) (string, error)
+
+// masterKeyAuthorizer signs the requests with the master key of the account.
+// See: https://learn.microsoft.com/rest/api/cosmos-db/access-control-on-cosmosdb-resources
+func masterKeyAuthorizer(masterKey string) (authorizer, error) {
+	key, err := base64.StdEncoding.DecodeString(masterKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid master key: %w", err)
+	}
+
+	return func(_ context.Context, method, resourceType, resourceLink, date string) (string, error) {
+		stringToSign := strings.ToLower(method) + "\n" + strings.ToLower(resourceType) + "\n" + resourceLink + "\n" + strings.ToLower(date) + "\n\n"
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(stringToSign))
+		sig := base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+		return url.QueryEscape("type=master&ver=1.0&sig=" + sig), nil
+	}, nil
+}
+
+// tokenAuthorizer authorizes the requests with an Azure AD token.
+func tokenAuthorizer(cred azcore.TokenCredential, endpoint string) (authorizer, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %s: %w", endpoint, err)
+	}
+	scope := u.Scheme + "://" + u.Host + "/.default"
+
+	return func(ctx context.Context, _, _, _, _ string) (string, error) {
+		token, err := cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{scope}})
+		if err != nil {
+			return "", err
+		}
+
+		return "type=aad&ver=1.0&sig=" + token.Token, nil
+	}, nil
+}
+
+// partitionKeyRange is a range of the partition keys of a container, with its own change feed.
+type partitionKeyRange struct {
+	ID      string   `json:"id"`
+	Parents []string `json:"parents"`
+}
+
+// feedPage is a page of the changes of a partition key range.
+type feedPage struct {
+	Documents []json.RawMessage `json:"Documents"`
+}
+
+// feedClient reads the change feed of a container.
+type feedClient struct {
+	endpoint     string
+	resourceLink string
+	authorize    authorizer
+	httpClient   *http.Client
+}
+
+func newFeedClient(endpoint, database, container string, authorize authorizer) *feedClient {
+	return &feedClient{
+		endpoint:     strings.TrimSuffix(endpoint, "/"),
+		resourceLink: "dbs/" + database + "/colls/" + container,
+		authorize:    authorize,
+		httpClient:   &http.Client{},
+	}
+}
+
+// partitionKeyRanges returns the current partition key ranges of the container.
+func (c *feedClient) partitionKeyRanges(ctx context.Context) ([]partitionKeyRange, error) {
+	var ranges []partitionKeyRange
+	continuation := ""
+	for {
+		headers := map[string]string{}
+		if continuation != "" {
+			headers["x-ms-continuation"] = continuation
+		}
+		res, err := c.send(ctx, "pkranges", headers)
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			PartitionKeyRanges []partitionKeyRange `json:"PartitionKeyRanges"`
+		}
+		err = decodeResponse(res, &page)
+		if err != nil {
+			return nil, fmt.Errorf("error listing partition key ranges: %w", err)
+		}
+		ranges = append(ranges, page.PartitionKeyRanges...)
+
+		continuation = res.Header.Get("x-ms-continuation")
+		if continuation == "" {
+			return ranges, nil
+		}
+	}
+}
+
+// readChanges reads the changes of a partition key range after the continuation, and returns them with the
+// continuation of the next read.
+// Without continuation, the changes are read from the start time; or from the current time if it is zero, or from the
+// beginning if fromBeginning is true.
+func (c *feedClient) readChanges(ctx context.Context, rangeID string, continuation string, startTime time.Time, fromBeginning bool, maxItemCount int) ([]json.RawMessage, string, error) {
+	headers := map[string]string{
+		"A-IM":                                "Incremental feed",
+		"x-ms-documentdb-partitionkeyrangeid": rangeID,
+	}
+	if maxItemCount > 0 {
+		headers["x-ms-max-item-count"] = strconv.Itoa(maxItemCount)
+	}
+	switch {
+	case continuation != "":
+		headers["If-None-Match"] = continuation
+	case !startTime.IsZero():
+		headers["If-Modified-Since"] = startTime.UTC().Format(http.TimeFormat)
+	case !fromBeginning:
+		headers["If-None-Match"] = "*"
+	}
+
+	res, err := c.send(ctx, "docs", headers)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch res.StatusCode {
+	case http.StatusNotModified:
+		res.Body.Close()
+		return nil, res.Header.Get("etag"), nil
+	case http.StatusGone:
+		res.Body.Close()
+		return nil, "", errPartitionGone
+	}
+
+	var page feedPage
+	err = decodeResponse(res, &page)
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading the changes of partition key range %s: %w", rangeID, err)
+	}
+
+	return page.Documents, res.Header.Get("etag"), nil
+}
+
+// send sends a GET request on a resource of the container.
+func (c *feedClient) send(ctx context.Context, resourceType string, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+"/"+c.resourceLink+"/"+resourceType, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	date := time.Now().UTC().Format(http.TimeFormat)
+	auth, err := c.authorize(ctx, http.MethodGet, resourceType, c.resourceLink, date)
+	if err != nil {
+		return nil, fmt.Errorf("error authorizing request: %w", err)
+	}
+	req.Header.Set("Authorization", auth)
+	req.Header.Set("x-ms-date", date)
+	req.Header.Set("x-ms-version", apiVersion)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	return c.httpClient.Do(req)
+}
+
+// decodeResponse decodes the body of a successful response, and closes it.
+func decodeResponse(res *http.Response, v interface{}) error {
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("unexpected status code %d: %s", res.StatusCode, string(body))
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cosmosdbchangefeed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMasterKeyAuthorizer(t *testing.T) {
+	t.Run("signs the request", func(t *testing.T) {
+		authorize, err := masterKeyAuthorizer("dGVzdGtleQ==")
+		require.NoError(t, err)
+
+		auth, err := authorize(context.Background(), http.MethodGet, "docs", "dbs/db/colls/coll", "Tue, 01 Nov 2022 00:00:00 GMT")
+		require.NoError(t, err)
+
+		decoded, err := url.QueryUnescape(auth)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(decoded, "type=master&ver=1.0&sig="))
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		_, err := masterKeyAuthorizer("not base64!")
+		assert.Error(t, err)
+	})
+}
+
+func TestReadChanges(t *testing.T) {
+	var received *http.Request
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		w.Header().Set("etag", `"42"`)
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			w.Write([]byte(`{"_rid": "rid", "Documents": [{"id": "1"}, {"id": "2"}], "_count": 2}`))
+		}
+	}))
+	defer server.Close()
+
+	authorize := func(_ context.Context, method, resourceType, resourceLink, _ string) (string, error) {
+		return method + " " + resourceType + " " + resourceLink, nil
+	}
+	client := newFeedClient(server.URL+"/", "db", "coll", authorize)
+
+	t.Run("reads the changes after the continuation", func(t *testing.T) {
+		docs, continuation, err := client.readChanges(context.Background(), "0", `"41"`, time.Time{}, false, 10)
+
+		require.NoError(t, err)
+		assert.Equal(t, "/dbs/db/colls/coll/docs", received.URL.Path)
+		assert.Equal(t, "GET docs dbs/db/colls/coll", received.Header.Get("Authorization"))
+		assert.Equal(t, "Incremental feed", received.Header.Get("A-IM"))
+		assert.Equal(t, "0", received.Header.Get("x-ms-documentdb-partitionkeyrangeid"))
+		assert.Equal(t, "10", received.Header.Get("x-ms-max-item-count"))
+		assert.Equal(t, `"41"`, received.Header.Get("If-None-Match"))
+		assert.Len(t, docs, 2)
+		assert.JSONEq(t, `{"id": "1"}`, string(docs[0]))
+		assert.Equal(t, `"42"`, continuation)
+	})
+
+	t.Run("reads the changes from the start time", func(t *testing.T) {
+		_, _, err := client.readChanges(context.Background(), "0", "", time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC), false, 0)
+
+		require.NoError(t, err)
+		assert.Equal(t, "Tue, 01 Nov 2022 00:00:00 GMT", received.Header.Get("If-Modified-Since"))
+		assert.Empty(t, received.Header.Get("If-None-Match"))
+	})
+
+	t.Run("reads the changes from now", func(t *testing.T) {
+		_, _, err := client.readChanges(context.Background(), "0", "", time.Time{}, false, 0)
+
+		require.NoError(t, err)
+		assert.Equal(t, "*", received.Header.Get("If-None-Match"))
+	})
+
+	t.Run("reads the changes from the beginning", func(t *testing.T) {
+		_, _, err := client.readChanges(context.Background(), "0", "", time.Time{}, true, 0)
+
+		require.NoError(t, err)
+		assert.Empty(t, received.Header.Get("If-None-Match"))
+		assert.Empty(t, received.Header.Get("If-Modified-Since"))
+	})
+
+	t.Run("no changes", func(t *testing.T) {
+		status = http.StatusNotModified
+		docs, continuation, err := client.readChanges(context.Background(), "0", `"42"`, time.Time{}, false, 0)
+
+		require.NoError(t, err)
+		assert.Empty(t, docs)
+		assert.Equal(t, `"42"`, continuation)
+	})
+
+	t.Run("partition key range gone", func(t *testing.T) {
+		status = http.StatusGone
+		_, _, err := client.readChanges(context.Background(), "0", `"42"`, time.Time{}, false, 0)
+
+		assert.ErrorIs(t, err, errPartitionGone)
+	})
+
+	t.Run("unexpected status code", func(t *testing.T) {
+		status = http.StatusForbidden
+		_, _, err := client.readChanges(context.Background(), "0", `"42"`, time.Time{}, false, 0)
+
+		assert.ErrorContains(t, err, "unexpected status code 403")
+	})
+}
+
+func TestPartitionKeyRanges(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/dbs/db/colls/coll/pkranges", r.URL.Path)
+		if r.Header.Get("x-ms-continuation") == "" {
+			w.Header().Set("x-ms-continuation", "next")
+			w.Write([]byte(`{"PartitionKeyRanges": [{"id": "1", "parents": ["0"]}]}`))
+			return
+		}
+		w.Write([]byte(`{"PartitionKeyRanges": [{"id": "2", "parents": ["0"]}]}`))
+	}))
+	defer server.Close()
+
+	authorize := func(_ context.Context, _, _, _, _ string) (string, error) {
+		return "auth", nil
+	}
+	client := newFeedClient(server.URL, "db", "coll", authorize)
+
+	ranges, err := client.partitionKeyRanges(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []partitionKeyRange{
+		{ID: "1", Parents: []string{"0"}},
+		{ID: "2", Parents: []string{"0"}},
+	}, ranges)
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cosmosdbchangefeed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+)
+
+// errLeaseLost is returned when a lease was acquired by another instance.
+var errLeaseLost = errors.New("lease acquired by another instance")
+
+// leaseContainer is the subset of the operations of azcosmos.ContainerClient used by the lease store.
+type leaseContainer interface {
+	CreateItem(ctx context.Context, partitionKey azcosmos.PartitionKey, item []byte, o *azcosmos.ItemOptions) (azcosmos.ItemResponse, error)
+	ReadItem(ctx context.Context, partitionKey azcosmos.PartitionKey, itemID string, o *azcosmos.ItemOptions) (azcosmos.ItemResponse, error)
+	ReplaceItem(ctx context.Context, partitionKey azcosmos.PartitionKey, itemID string, item []byte, o *azcosmos.ItemOptions) (azcosmos.ItemResponse, error)
+	DeleteItem(ctx context.Context, partitionKey azcosmos.PartitionKey, itemID string, o *azcosmos.ItemOptions) (azcosmos.ItemResponse, error)
+}
+
+// lease is the ownership of a partition key range by an instance, with the continuation of its change feed.
+type lease struct {
+	ID                  string `json:"id"`
+	PartitionKeyRangeID string `json:"partitionKeyRangeId"`
+	ContinuationToken   string `json:"continuationToken"`
+	Owner               string `json:"owner"`
+	// Unix time of the last renewal of the lease by its owner.
+	Timestamp int64 `json:"timestamp"`
+
+	etag azcore.ETag
+}
+
+// leaseStore stores the leases of the partition key ranges in a container partitioned by id.
+type leaseStore struct {
+	container  leaseContainer
+	prefix     string
+	owner      string
+	expiration time.Duration
+}
+
+func newLeaseStore(container leaseContainer, prefix string, owner string, expiration time.Duration) *leaseStore {
+	return &leaseStore{
+		container:  container,
+		prefix:     prefix,
+		owner:      owner,
+		expiration: expiration,
+	}
+}
+
+// ensure creates the unowned lease of a partition key range if it does not exist.
+// The changes of a range which replaced others are read after the continuation of its parents.
+func (s *leaseStore) ensure(ctx context.Context, rangeID string, parents []string) error {
+	l, err := s.read(ctx, rangeID)
+	if err != nil || l != nil {
+		return err
+	}
+
+	l = &lease{
+		ID:                  s.prefix + rangeID,
+		PartitionKeyRangeID: rangeID,
+	}
+	for _, parent := range parents {
+		p, err := s.read(ctx, parent)
+		if err != nil {
+			return err
+		}
+		if p != nil && p.ContinuationToken != "" {
+			l.ContinuationToken = p.ContinuationToken
+			break
+		}
+	}
+
+	b, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	_, err = s.container.CreateItem(ctx, azcosmos.NewPartitionKeyString(l.ID), b, nil)
+	if err != nil && !hasStatusCode(err, http.StatusConflict) {
+		return fmt.Errorf("error creating lease %s: %w", l.ID, err)
+	}
+
+	return nil
+}
+
+// acquire acquires the lease of a partition key range, unless it is owned by another instance which renewed it
+// recently. It returns nil if the lease could not be acquired.
+func (s *leaseStore) acquire(ctx context.Context, rangeID string) (*lease, error) {
+	l, err := s.read(ctx, rangeID)
+	if err != nil || l == nil {
+		return nil, err
+	}
+	if l.Owner != "" && l.Owner != s.owner && time.Since(time.Unix(l.Timestamp, 0)) < s.expiration {
+		return nil, nil
+	}
+
+	l.Owner = s.owner
+	err = s.update(ctx, l)
+	if errors.Is(err, errLeaseLost) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// update renews a lease owned by this instance, storing its continuation.
+func (s *leaseStore) update(ctx context.Context, l *lease) error {
+	l.Timestamp = time.Now().Unix()
+
+	return s.replace(ctx, l)
+}
+
+// release gives up the ownership of a lease.
+func (s *leaseStore) release(ctx context.Context, l *lease) error {
+	l.Owner = ""
+
+	return s.replace(ctx, l)
+}
+
+// remove deletes the lease of a partition key range, if it exists.
+func (s *leaseStore) remove(ctx context.Context, rangeID string) error {
+	id := s.prefix + rangeID
+	_, err := s.container.DeleteItem(ctx, azcosmos.NewPartitionKeyString(id), id, nil)
+	if err != nil && !hasStatusCode(err, http.StatusNotFound) {
+		return fmt.Errorf("error deleting lease %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// read returns the lease of a partition key range, or nil if it does not exist.
+func (s *leaseStore) read(ctx context.Context, rangeID string) (*lease, error) {
+	id := s.prefix + rangeID
+	res, err := s.container.ReadItem(ctx, azcosmos.NewPartitionKeyString(id), id, nil)
+	if hasStatusCode(err, http.StatusNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading lease %s: %w", id, err)
+	}
+
+	var l lease
+	err = json.Unmarshal(res.Value, &l)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding lease %s: %w", id, err)
+	}
+	l.etag = res.ETag
+
+	return &l, nil
+}
+
+// replace replaces a lease if it was not modified since it was read.
+func (s *leaseStore) replace(ctx context.Context, l *lease) error {
+	b, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.container.ReplaceItem(ctx, azcosmos.NewPartitionKeyString(l.ID), l.ID, b, &azcosmos.ItemOptions{
+		IfMatchEtag: &l.etag,
+	})
+	if hasStatusCode(err, http.StatusPreconditionFailed) {
+		return errLeaseLost
+	}
+	if err != nil {
+		return fmt.Errorf("error updating lease %s: %w", l.ID, err)
+	}
+	l.etag = res.ETag
+
+	return nil
+}
+
+func hasStatusCode(err error, statusCode int) bool {
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == statusCode
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cosmosdbchangefeed
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeContainer stores the items in memory, with an etag incremented on each write.
+type fakeContainer struct {
+	items map[string][]byte
+	etags map[string]azcore.ETag
+	next  int
+}
+
+func newFakeContainer() *fakeContainer {
+	return &fakeContainer{
+		items: make(map[string][]byte),
+		etags: make(map[string]azcore.ETag),
+	}
+}
+
+func (c *fakeContainer) write(id string, item []byte) azcosmos.ItemResponse {
+	c.next++
+	c.items[id] = item
+	c.etags[id] = azcore.ETag(strconv.Itoa(c.next))
+
+	return azcosmos.ItemResponse{Response: azcosmos.Response{ETag: c.etags[id]}}
+}
+
+func (c *fakeContainer) CreateItem(_ context.Context, _ azcosmos.PartitionKey, item []byte, _ *azcosmos.ItemOptions) (azcosmos.ItemResponse, error) {
+	var l lease
+	json.Unmarshal(item, &l)
+	if _, ok := c.items[l.ID]; ok {
+		return azcosmos.ItemResponse{}, &azcore.ResponseError{StatusCode: http.StatusConflict}
+	}
+
+	return c.write(l.ID, item), nil
+}
+
+func (c *fakeContainer) ReadItem(_ context.Context, _ azcosmos.PartitionKey, id string, _ *azcosmos.ItemOptions) (azcosmos.ItemResponse, error) {
+	item, ok := c.items[id]
+	if !ok {
+		return azcosmos.ItemResponse{}, &azcore.ResponseError{StatusCode: http.StatusNotFound}
+	}
+
+	return azcosmos.ItemResponse{Value: item, Response: azcosmos.Response{ETag: c.etags[id]}}, nil
+}
+
+func (c *fakeContainer) ReplaceItem(_ context.Context, _ azcosmos.PartitionKey, id string, item []byte, o *azcosmos.ItemOptions) (azcosmos.ItemResponse, error) {
+	if _, ok := c.items[id]; !ok {
+		return azcosmos.ItemResponse{}, &azcore.ResponseError{StatusCode: http.StatusNotFound}
+	}
+	if o != nil && o.IfMatchEtag != nil && *o.IfMatchEtag != c.etags[id] {
+		return azcosmos.ItemResponse{}, &azcore.ResponseError{StatusCode: http.StatusPreconditionFailed}
+	}
+
+	return c.write(id, item), nil
+}
+
+func (c *fakeContainer) DeleteItem(_ context.Context, _ azcosmos.PartitionKey, id string, _ *azcosmos.ItemOptions) (azcosmos.ItemResponse, error) {
+	if _, ok := c.items[id]; !ok {
+		return azcosmos.ItemResponse{}, &azcore.ResponseError{StatusCode: http.StatusNotFound}
+	}
+	delete(c.items, id)
+
+	return azcosmos.ItemResponse{}, nil
+}
+
+func TestLeaseStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("acquires an unowned lease", func(t *testing.T) {
+		s := newLeaseStore(newFakeContainer(), "coll.", "owner1", time.Minute)
+		require.NoError(t, s.ensure(ctx, "0", nil))
+
+		l, err := s.acquire(ctx, "0")
+
+		require.NoError(t, err)
+		require.NotNil(t, l)
+		assert.Equal(t, "coll.0", l.ID)
+		assert.Equal(t, "owner1", l.Owner)
+	})
+
+	t.Run("does not acquire a lease owned by another instance", func(t *testing.T) {
+		container := newFakeContainer()
+		s1 := newLeaseStore(container, "coll.", "owner1", time.Minute)
+		s2 := newLeaseStore(container, "coll.", "owner2", time.Minute)
+		require.NoError(t, s1.ensure(ctx, "0", nil))
+		l1, err := s1.acquire(ctx, "0")
+		require.NoError(t, err)
+
+		l2, err := s2.acquire(ctx, "0")
+		require.NoError(t, err)
+		assert.Nil(t, l2)
+
+		// Once released, the lease can be acquired by the other instance
+		require.NoError(t, s1.release(ctx, l1))
+		l2, err = s2.acquire(ctx, "0")
+		require.NoError(t, err)
+		require.NotNil(t, l2)
+
+		// The first instance lost the lease
+		assert.ErrorIs(t, s1.update(ctx, l1), errLeaseLost)
+	})
+
+	t.Run("acquires an expired lease", func(t *testing.T) {
+		container := newFakeContainer()
+		s1 := newLeaseStore(container, "coll.", "owner1", time.Minute)
+		s2 := newLeaseStore(container, "coll.", "owner2", time.Nanosecond)
+		require.NoError(t, s1.ensure(ctx, "0", nil))
+		_, err := s1.acquire(ctx, "0")
+		require.NoError(t, err)
+
+		l2, err := s2.acquire(ctx, "0")
+
+		require.NoError(t, err)
+		require.NotNil(t, l2)
+		assert.Equal(t, "owner2", l2.Owner)
+	})
+
+	t.Run("stores the continuation", func(t *testing.T) {
+		s := newLeaseStore(newFakeContainer(), "coll.", "owner1", time.Minute)
+		require.NoError(t, s.ensure(ctx, "0", nil))
+		l, err := s.acquire(ctx, "0")
+		require.NoError(t, err)
+
+		l.ContinuationToken = `"42"`
+		require.NoError(t, s.update(ctx, l))
+
+		stored, err := s.read(ctx, "0")
+		require.NoError(t, err)
+		assert.Equal(t, `"42"`, stored.ContinuationToken)
+	})
+
+	t.Run("children inherit the continuation of their parent", func(t *testing.T) {
+		s := newLeaseStore(newFakeContainer(), "coll.", "owner1", time.Minute)
+		require.NoError(t, s.ensure(ctx, "0", nil))
+		l, err := s.acquire(ctx, "0")
+		require.NoError(t, err)
+		l.ContinuationToken = `"42"`
+		require.NoError(t, s.update(ctx, l))
+
+		require.NoError(t, s.ensure(ctx, "1", []string{"0"}))
+		require.NoError(t, s.ensure(ctx, "2", []string{"0"}))
+		require.NoError(t, s.remove(ctx, "0"))
+
+		for _, id := range []string{"1", "2"} {
+			child, err := s.read(ctx, id)
+			require.NoError(t, err)
+			assert.Equal(t, `"42"`, child.ContinuationToken)
+			assert.Empty(t, child.Owner)
+		}
+		parent, err := s.read(ctx, "0")
+		require.NoError(t, err)
+		assert.Nil(t, parent)
+	})
+}
@@ -23,6 +23,7 @@ import (
 	gremcos "github.com/supplyon/gremcos"
 
 	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 )
 
@@ -48,9 +49,9 @@ type CosmosDBGremlinAPI struct {
 }
 
 type cosmosDBGremlinAPICredentials struct {
-	URL       string `json:"url"`
-	MasterKey string `json:"masterKey"`
-	Username  string `json:"username"`
+	URL       string `json:"url" mdrequired:"true"`
+	MasterKey string `json:"masterKey" mdrequired:"true" mdsensitive:"true"`
+	Username  string `json:"username" mdrequired:"true"`
 }
 
 // NewCosmosDBGremlinAPI returns a new CosmosDBGremlinAPI instance.
@@ -94,6 +95,12 @@ func (c *CosmosDBGremlinAPI) parseMetadata(metadata bindings.Metadata) (*cosmosD
 	return &creds, nil
 }
 
+// GetComponentMetadataSchema returns the schema of the metadata of the Cosmos DB Gremlin API binding.
+func (c *CosmosDBGremlinAPI) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(cosmosDBGremlinAPICredentials{})
+	return fields
+}
+
 func (c *CosmosDBGremlinAPI) Operations() []bindings.OperationKind {
 	return []bindings.OperationKind{queryOperation}
 }
@@ -0,0 +1,373 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dataexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/google/uuid"
+)
+
+const (
+	// Time the ingestion resources of the cluster are cached for.
+	ingestionResourcesTTL = time.Hour
+
+	// Version of the Azure Storage REST API used to upload the data of the queued ingestions.
+	storageAPIVersion = "2019-12-12"
+)
+
+// client is a client of the REST API of an Azure Data Explorer cluster.
+// See: https://learn.microsoft.com/azure/data-explorer/kusto/api/rest/
+type client struct {
+	endpoint       string
+	ingestEndpoint string
+	credential     azcore.TokenCredential
+	userAgent      string
+	httpClient     *http.Client
+
+	// Resources for the queued ingestions, retrieved from the ingestion endpoint
+	resources     *ingestionResources
+	resourcesLock sync.Mutex
+	// Number of queued ingestions, to spread them over the queues and the containers of the resources
+	ingestions atomic.Uint64
+}
+
+// ingestionResources are the queues and the temporary storage of the queued ingestions, and the token authorizing them.
+type ingestionResources struct {
+	queues               []string
+	containers           []string
+	authorizationContext string
+	expiresAt            time.Time
+}
+
+type queryRequest struct {
+	DB  string `json:"db"`
+	CSL string `json:"csl"`
+}
+
+// v1Response is the response of the management commands.
+type v1Response struct {
+	Tables []struct {
+		TableName string `json:"TableName"`
+		Columns   []struct {
+			ColumnName string `json:"ColumnName"`
+		} `json:"Columns"`
+		Rows [][]any `json:"Rows"`
+	} `json:"Tables"`
+}
+
+// v2Frame is a frame of the response of the queries; only the fields reporting errors are decoded.
+type v2Frame struct {
+	FrameType    string            `json:"FrameType"`
+	HasErrors    bool              `json:"HasErrors"`
+	OneAPIErrors []json.RawMessage `json:"OneApiErrors"`
+}
+
+// ingestionMessage is the message posted to the ingestion queues for each blob to ingest.
+// See: https://learn.microsoft.com/azure/data-explorer/kusto/api/netfx/kusto-ingest-client-rest#ingestion-message-internal-structure
+type ingestionMessage struct {
+	ID                   string            `json:"Id"`
+	BlobPath             string            `json:"BlobPath"`
+	RawDataSize          int               `json:"RawDataSize"`
+	DatabaseName         string            `json:"DatabaseName"`
+	TableName            string            `json:"TableName"`
+	RetainBlobOnSuccess  bool              `json:"RetainBlobOnSuccess"`
+	FlushImmediately     bool              `json:"FlushImmediately"`
+	ReportLevel          int               `json:"ReportLevel"`
+	ReportMethod         int               `json:"ReportMethod"`
+	AdditionalProperties map[string]string `json:"AdditionalProperties"`
+}
+
+type queueMessage struct {
+	XMLName     xml.Name `xml:"QueueMessage"`
+	MessageText string   `xml:"MessageText"`
+}
+
+// query runs a query on the database, returning the frames of the response.
+func (c *client) query(ctx context.Context, database string, query string) ([]byte, error) {
+	res, err := c.post(ctx, c.endpoint+"/v2/rest/query", queryRequest{DB: database, CSL: query})
+	if err != nil {
+		return nil, err
+	}
+
+	// Errors occurring while the results are streamed are reported in the last frame
+	var frames []v2Frame
+	err = json.Unmarshal(res, &frames)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query response: %w", err)
+	}
+	for _, frame := range frames {
+		if frame.HasErrors {
+			errs := make([]string, len(frame.OneAPIErrors))
+			for i, e := range frame.OneAPIErrors {
+				errs[i] = string(e)
+			}
+			return nil, fmt.Errorf("query failed: %s", strings.Join(errs, ", "))
+		}
+	}
+
+	return res, nil
+}
+
+// streamingIngest ingests the data in the table synchronously.
+// Streaming ingestion must be enabled on the cluster and the table.
+func (c *client) streamingIngest(ctx context.Context, database string, table string, format string, mapping string, data []byte) error {
+	q := url.Values{}
+	q.Set("streamFormat", format)
+	if mapping != "" {
+		q.Set("mappingName", mapping)
+	}
+	u := fmt.Sprintf("%s/v1/rest/ingest/%s/%s?%s", c.endpoint, url.PathEscape(database), url.PathEscape(table), q.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	_, err = c.do(req, true)
+	return err
+}
+
+// queuedIngest uploads the data to the temporary storage of the cluster, and queues its ingestion in the table.
+// The ingestion is asynchronous, and its failures are reported in the cluster only.
+func (c *client) queuedIngest(ctx context.Context, database string, table string, format string, mapping string, data []byte) error {
+	resources, err := c.ingestionResources(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The ingestions are spread over the queues and the containers, as the SDKs do
+	n := c.ingestions.Add(1)
+	container := resources.containers[n%uint64(len(resources.containers))]
+	queue := resources.queues[n%uint64(len(resources.queues))]
+
+	id := uuid.NewString()
+	blobPath, err := c.uploadBlob(ctx, container, fmt.Sprintf("%s__%s__%s.%s", database, table, id, format), data)
+	if err != nil {
+		return err
+	}
+
+	msg := ingestionMessage{
+		ID:                  id,
+		BlobPath:            blobPath,
+		RawDataSize:         len(data),
+		DatabaseName:        database,
+		TableName:           table,
+		RetainBlobOnSuccess: false,
+		AdditionalProperties: map[string]string{
+			"authorizationContext": resources.authorizationContext,
+			"format":               format,
+		},
+	}
+	if mapping != "" {
+		msg.AdditionalProperties["ingestionMappingReference"] = mapping
+	}
+
+	return c.enqueue(ctx, queue, msg)
+}
+
+// ingestionResources returns the resources for the queued ingestions, which are retrieved from the ingestion
+// endpoint when expired.
+func (c *client) ingestionResources(ctx context.Context) (*ingestionResources, error) {
+	c.resourcesLock.Lock()
+	defer c.resourcesLock.Unlock()
+
+	if c.resources != nil && time.Now().Before(c.resources.expiresAt) {
+		return c.resources, nil
+	}
+
+	res, err := c.mgmt(ctx, ".get ingestion resources")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the ingestion resources: %w", err)
+	}
+	resources := &ingestionResources{
+		expiresAt: time.Now().Add(ingestionResourcesTTL),
+	}
+	for _, row := range res {
+		switch row["ResourceTypeName"] {
+		case "SecuredReadyForAggregationQueue":
+			resources.queues = append(resources.queues, row["StorageRoot"])
+		case "TempStorage":
+			resources.containers = append(resources.containers, row["StorageRoot"])
+		}
+	}
+	if len(resources.queues) == 0 || len(resources.containers) == 0 {
+		return nil, errors.New("failed to get the ingestion resources: no ingestion queue or temporary storage returned")
+	}
+
+	res, err = c.mgmt(ctx, ".get kusto identity token")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the ingestion identity token: %w", err)
+	}
+	if len(res) == 0 || res[0]["AuthorizationContext"] == "" {
+		return nil, errors.New("failed to get the ingestion identity token: no token returned")
+	}
+	resources.authorizationContext = res[0]["AuthorizationContext"]
+
+	c.resources = resources
+	return resources, nil
+}
+
+// mgmt runs a management command on the ingestion endpoint, returning the rows of the first table of the response
+// with the values by column name.
+func (c *client) mgmt(ctx context.Context, command string) ([]map[string]string, error) {
+	res, err := c.post(ctx, c.ingestEndpoint+"/v1/rest/mgmt", queryRequest{DB: "NetDefaultDB", CSL: command})
+	if err != nil {
+		return nil, err
+	}
+
+	var v1 v1Response
+	err = json.Unmarshal(res, &v1)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response of command %s: %w", command, err)
+	}
+	if len(v1.Tables) == 0 {
+		return nil, nil
+	}
+
+	table := v1.Tables[0]
+	rows := make([]map[string]string, len(table.Rows))
+	for i, row := range table.Rows {
+		rows[i] = make(map[string]string, len(row))
+		for j, val := range row {
+			if j < len(table.Columns) {
+				rows[i][table.Columns[j].ColumnName] = fmt.Sprint(val)
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+// uploadBlob uploads the data in a blob of the container, returning the URL of the blob including the SAS token of
+// the container.
+func (c *client) uploadBlob(ctx context.Context, containerURL string, name string, data []byte) (string, error) {
+	u, err := url.Parse(containerURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid temporary storage URL: %w", err)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + name
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	req.Header.Set("x-ms-version", storageAPIVersion)
+
+	_, err = c.do(req, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload the data to ingest: %w", err)
+	}
+
+	return u.String(), nil
+}
+
+// enqueue posts the ingestion message to the queue.
+func (c *client) enqueue(ctx context.Context, queueURL string, msg ingestionMessage) error {
+	u, err := url.Parse(queueURL)
+	if err != nil {
+		return fmt.Errorf("invalid ingestion queue URL: %w", err)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/messages"
+
+	msgJSON, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	body, err := xml.Marshal(queueMessage{MessageText: base64.StdEncoding.EncodeToString(msgJSON)})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-ms-version", storageAPIVersion)
+
+	_, err = c.do(req, false)
+	if err != nil {
+		return fmt.Errorf("failed to queue the ingestion: %w", err)
+	}
+
+	return nil
+}
+
+// post sends a JSON request to the cluster.
+func (c *client) post(ctx context.Context, u string, body any) ([]byte, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Accept", "application/json")
+
+	return c.do(req, true)
+}
+
+// do sends a request, authorized with an Azure AD token for the host of the request if authorize is true, and returns
+// the body of the response.
+func (c *client) do(req *http.Request, authorize bool) ([]byte, error) {
+	if authorize && c.credential != nil {
+		token, err := c.credential.GetToken(req.Context(), policy.TokenRequestOptions{
+			Scopes: []string{req.URL.Scheme + "://" + req.URL.Host + "/.default"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get an Azure AD token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token.Token)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("x-ms-client-request-id", "dapr;"+uuid.NewString())
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	// Read the body regardless to drain it and ensure the connection can be reused
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("request to %s failed with code %d, content is '%s'", req.URL.Host, res.StatusCode, string(body))
+	}
+
+	return body, nil
+}
@@ -0,0 +1,241 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dataexplorer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+	azauth "github.com/dapr/components-contrib/internal/authentication/azure"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// queryOperation runs the query of the request data, returning the frames of the response as JSON.
+	queryOperation bindings.OperationKind = "query"
+
+	// Request metadata keys, overriding the values of the component metadata.
+	metadataKeyDatabase                  = "database"
+	metadataKeyTable                     = "table"
+	metadataKeyDataFormat                = "dataFormat"
+	metadataKeyIngestionMappingReference = "ingestionMappingReference"
+
+	ingestionTypeQueued    = "queued"
+	ingestionTypeStreaming = "streaming"
+
+	defaultDataFormat = "json"
+	defaultTimeout    = 30 * time.Second
+)
+
+// Data formats supported by the ingestion in Azure Data Explorer.
+var supportedDataFormats = map[string]struct{}{
+	"csv": {}, "tsv": {}, "tsve": {}, "psv": {}, "scsv": {}, "sohsv": {}, "txt": {}, "raw": {},
+	"json": {}, "multijson": {}, "singlejson": {},
+	"avro": {}, "apacheavro": {}, "parquet": {}, "orc": {}, "w3clogfile": {},
+}
+
+// DataExplorer is an output binding ingesting data in, and querying, Azure Data Explorer (Kusto) tables.
+type DataExplorer struct {
+	metadata *dataExplorerMetadata
+	client   *client
+
+	logger logger.Logger
+}
+
+type dataExplorerMetadata struct {
+	// URL of the cluster, e.g. https://mycluster.westeurope.kusto.windows.net
+	Endpoint string `mapstructure:"endpoint" mdrequired:"true"`
+	// URL of the ingestion endpoint of the cluster, for queued ingestions; by default the URL of the cluster with the
+	// "ingest-" prefix.
+	IngestEndpoint            string `mapstructure:"ingestEndpoint"`
+	Database                  string `mapstructure:"database" mdrequired:"true"`
+	Table                     string `mapstructure:"table"`
+	IngestionType             string `mapstructure:"ingestionType"`
+	DataFormat                string `mapstructure:"dataFormat"`
+	IngestionMappingReference string `mapstructure:"ingestionMappingReference"`
+	TimeoutInSec              int    `mapstructure:"timeoutInSec"`
+}
+
+// NewDataExplorer returns a new Azure Data Explorer output binding.
+func NewDataExplorer(logger logger.Logger) bindings.OutputBinding {
+	return &DataExplorer{logger: logger}
+}
+
+// Init parses the metadata and creates the client, authenticated with Azure AD.
+func (d *DataExplorer) Init(md bindings.Metadata) error {
+	m, err := parseMetadata(md.Properties)
+	if err != nil {
+		return err
+	}
+
+	settings, err := azauth.NewEnvironmentSettings("dataexplorer", md.Properties)
+	if err != nil {
+		return err
+	}
+	credential, err := settings.GetTokenCredential()
+	if err != nil {
+		return err
+	}
+
+	d.metadata = m
+	d.client = &client{
+		endpoint:       m.Endpoint,
+		ingestEndpoint: m.IngestEndpoint,
+		credential:     credential,
+		userAgent:      "dapr-" + logger.DaprVersion,
+		httpClient: &http.Client{
+			Timeout: time.Duration(m.TimeoutInSec) * time.Second,
+		},
+	}
+
+	return nil
+}
+
+func parseMetadata(md map[string]string) (*dataExplorerMetadata, error) {
+	m := dataExplorerMetadata{
+		IngestionType: ingestionTypeQueued,
+		DataFormat:    defaultDataFormat,
+		TimeoutInSec:  int(defaultTimeout / time.Second),
+	}
+	err := metadata.DecodeMetadata(md, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Endpoint == "" {
+		return nil, errors.New("azure data explorer binding error: endpoint is required")
+	}
+	m.Endpoint = strings.TrimSuffix(m.Endpoint, "/")
+	if m.Database == "" {
+		return nil, errors.New("azure data explorer binding error: database is required")
+	}
+
+	switch m.IngestionType {
+	case ingestionTypeQueued:
+		if m.IngestEndpoint == "" {
+			u, err := url.Parse(m.Endpoint)
+			if err != nil {
+				return nil, fmt.Errorf("azure data explorer binding error: invalid endpoint: %w", err)
+			}
+			u.Host = "ingest-" + u.Host
+			m.IngestEndpoint = u.String()
+		}
+		m.IngestEndpoint = strings.TrimSuffix(m.IngestEndpoint, "/")
+	case ingestionTypeStreaming:
+	default:
+		return nil, fmt.Errorf("azure data explorer binding error: invalid ingestionType %s, supported values are %s and %s", m.IngestionType, ingestionTypeQueued, ingestionTypeStreaming)
+	}
+
+	m.DataFormat = strings.ToLower(m.DataFormat)
+	if err = validateDataFormat(m.DataFormat); err != nil {
+		return nil, err
+	}
+
+	if m.TimeoutInSec < 1 {
+		return nil, fmt.Errorf("azure data explorer binding error: invalid timeoutInSec %d", m.TimeoutInSec)
+	}
+
+	return &m, nil
+}
+
+func (d *DataExplorer) Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{bindings.CreateOperation, queryOperation}
+}
+
+func (d *DataExplorer) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	switch req.Operation {
+	case bindings.CreateOperation:
+		return nil, d.ingest(ctx, req)
+	case queryOperation:
+		return d.query(ctx, req)
+	default:
+		return nil, fmt.Errorf("azure data explorer binding error: unsupported operation %s", req.Operation)
+	}
+}
+
+// ingest ingests the request data in the table of the "table" metadata, in the format of the "dataFormat" metadata
+// and with the mapping of the "ingestionMappingReference" metadata.
+func (d *DataExplorer) ingest(ctx context.Context, req *bindings.InvokeRequest) error {
+	if len(req.Data) == 0 {
+		return errors.New("azure data explorer binding error: no data to ingest")
+	}
+
+	database := req.GetMetadataOrDefault(metadataKeyDatabase, d.metadata.Database)
+	table := req.GetMetadataOrDefault(metadataKeyTable, d.metadata.Table)
+	if table == "" {
+		return errors.New("azure data explorer binding error: table property not supplied in configuration- or request-metadata")
+	}
+	format := strings.ToLower(req.GetMetadataOrDefault(metadataKeyDataFormat, d.metadata.DataFormat))
+	if err := validateDataFormat(format); err != nil {
+		return err
+	}
+	mapping := req.GetMetadataOrDefault(metadataKeyIngestionMappingReference, d.metadata.IngestionMappingReference)
+
+	var err error
+	if d.metadata.IngestionType == ingestionTypeStreaming {
+		err = d.client.streamingIngest(ctx, database, table, format, mapping, req.Data)
+	} else {
+		err = d.client.queuedIngest(ctx, database, table, format, mapping, req.Data)
+	}
+	if err != nil {
+		return fmt.Errorf("azure data explorer binding error: error ingesting in table %s: %w", table, err)
+	}
+
+	return nil
+}
+
+// query runs the query of the request data on the database, returning the frames of the response.
+func (d *DataExplorer) query(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	if len(req.Data) == 0 {
+		return nil, errors.New("azure data explorer binding error: query is required")
+	}
+
+	database := req.GetMetadataOrDefault(metadataKeyDatabase, d.metadata.Database)
+	res, err := d.client.query(ctx, database, string(req.Data))
+	if err != nil {
+		return nil, fmt.Errorf("azure data explorer binding error: error running query: %w", err)
+	}
+
+	return &bindings.InvokeResponse{
+		Data: res,
+		Metadata: map[string]string{
+			metadataKeyDatabase: database,
+		},
+	}, nil
+}
+
+func validateDataFormat(format string) error {
+	if _, ok := supportedDataFormats[format]; !ok {
+		return fmt.Errorf("azure data explorer binding error: unsupported dataFormat %s", format)
+	}
+
+	return nil
+}
+
+// GetComponentMetadataSchema returns the schema of the metadata of the Azure Data Explorer binding.
+func (d *DataExplorer) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(dataExplorerMetadata{
+		IngestionType: ingestionTypeQueued,
+		DataFormat:    defaultDataFormat,
+		TimeoutInSec:  int(defaultTimeout / time.Second),
+	})
+	return fields
+}
@@ -0,0 +1,305 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dataexplorer
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/kit/logger"
+)
+
+func TestParseMetadata(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		m, err := parseMetadata(map[string]string{
+			"endpoint": "https://mycluster.westeurope.kusto.windows.net/",
+			"database": "mydb",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "https://mycluster.westeurope.kusto.windows.net", m.Endpoint)
+		assert.Equal(t, "https://ingest-mycluster.westeurope.kusto.windows.net", m.IngestEndpoint)
+		assert.Equal(t, ingestionTypeQueued, m.IngestionType)
+		assert.Equal(t, defaultDataFormat, m.DataFormat)
+		assert.Equal(t, 30, m.TimeoutInSec)
+	})
+
+	t.Run("streaming ingestion", func(t *testing.T) {
+		m, err := parseMetadata(map[string]string{
+			"endpoint":                  "https://mycluster.westeurope.kusto.windows.net",
+			"database":                  "mydb",
+			"table":                     "events",
+			"ingestionType":             "streaming",
+			"dataFormat":                "csv",
+			"ingestionMappingReference": "events_mapping",
+			"timeoutInSec":              "10",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "events", m.Table)
+		assert.Equal(t, ingestionTypeStreaming, m.IngestionType)
+		assert.Equal(t, "csv", m.DataFormat)
+		assert.Equal(t, "events_mapping", m.IngestionMappingReference)
+		assert.Equal(t, 10, m.TimeoutInSec)
+	})
+
+	t.Run("missing endpoint", func(t *testing.T) {
+		_, err := parseMetadata(map[string]string{"database": "mydb"})
+
+		assert.ErrorContains(t, err, "endpoint is required")
+	})
+
+	t.Run("missing database", func(t *testing.T) {
+		_, err := parseMetadata(map[string]string{"endpoint": "https://mycluster.kusto.windows.net"})
+
+		assert.ErrorContains(t, err, "database is required")
+	})
+
+	t.Run("invalid ingestion type", func(t *testing.T) {
+		_, err := parseMetadata(map[string]string{
+			"endpoint":      "https://mycluster.kusto.windows.net",
+			"database":      "mydb",
+			"ingestionType": "batch",
+		})
+
+		assert.ErrorContains(t, err, "invalid ingestionType")
+	})
+
+	t.Run("invalid data format", func(t *testing.T) {
+		_, err := parseMetadata(map[string]string{
+			"endpoint":   "https://mycluster.kusto.windows.net",
+			"database":   "mydb",
+			"dataFormat": "xml",
+		})
+
+		assert.ErrorContains(t, err, "unsupported dataFormat")
+	})
+}
+
+// newTestDataExplorer returns a binding whose cluster, ingestion endpoint and storage are served by handler.
+func newTestDataExplorer(t *testing.T, ingestionType string, handler http.HandlerFunc) *DataExplorer {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	m, err := parseMetadata(map[string]string{
+		"endpoint":       server.URL,
+		"ingestEndpoint": server.URL,
+		"database":       "mydb",
+		"table":          "events",
+		"ingestionType":  ingestionType,
+	})
+	require.NoError(t, err)
+
+	return &DataExplorer{
+		metadata: m,
+		client: &client{
+			endpoint:       m.Endpoint,
+			ingestEndpoint: m.IngestEndpoint,
+			httpClient:     server.Client(),
+		},
+		logger: logger.NewLogger("test"),
+	}
+}
+
+func TestQuery(t *testing.T) {
+	t.Run("returns the frames", func(t *testing.T) {
+		frames := `[{"FrameType":"DataSetHeader","Version":"v2.0"},{"FrameType":"DataTable","TableKind":"PrimaryResult","Rows":[[1]]},{"FrameType":"DataSetCompletion","HasErrors":false}]`
+		var received queryRequest
+		d := newTestDataExplorer(t, ingestionTypeQueued, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v2/rest/query", r.URL.Path)
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+			w.Write([]byte(frames))
+		})
+
+		res, err := d.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: queryOperation,
+			Data:      []byte("events | count"),
+			Metadata:  map[string]string{"database": "otherdb"},
+		})
+
+		require.NoError(t, err)
+		assert.JSONEq(t, frames, string(res.Data))
+		assert.Equal(t, queryRequest{DB: "otherdb", CSL: "events | count"}, received)
+	})
+
+	t.Run("returns the errors of the frames", func(t *testing.T) {
+		d := newTestDataExplorer(t, ingestionTypeQueued, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`[{"FrameType":"DataSetHeader"},{"FrameType":"DataSetCompletion","HasErrors":true,"OneApiErrors":[{"error":{"code":"LimitsExceeded"}}]}]`))
+		})
+
+		_, err := d.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: queryOperation,
+			Data:      []byte("events"),
+		})
+
+		assert.ErrorContains(t, err, "LimitsExceeded")
+	})
+
+	t.Run("returns the errors of the cluster", func(t *testing.T) {
+		d := newTestDataExplorer(t, ingestionTypeQueued, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"code":"BadRequest_SyntaxError"}}`))
+		})
+
+		_, err := d.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: queryOperation,
+			Data:      []byte("events |"),
+		})
+
+		assert.ErrorContains(t, err, "BadRequest_SyntaxError")
+	})
+}
+
+func TestStreamingIngest(t *testing.T) {
+	var path, format, mapping, body string
+	d := newTestDataExplorer(t, ingestionTypeStreaming, func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		format = r.URL.Query().Get("streamFormat")
+		mapping = r.URL.Query().Get("mappingName")
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	})
+
+	_, err := d.Invoke(context.Background(), &bindings.InvokeRequest{
+		Operation: bindings.CreateOperation,
+		Data:      []byte(`{"id": 1}`),
+		Metadata:  map[string]string{"ingestionMappingReference": "events_mapping"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "/v1/rest/ingest/mydb/events", path)
+	assert.Equal(t, "json", format)
+	assert.Equal(t, "events_mapping", mapping)
+	assert.Equal(t, `{"id": 1}`, body)
+}
+
+func TestQueuedIngest(t *testing.T) {
+	var (
+		serverURL  string
+		blobPaths  []string
+		blobData   string
+		messages   []ingestionMessage
+		queues     []string
+		containers []string
+		mgmtCalls  int
+	)
+	d := newTestDataExplorer(t, ingestionTypeQueued, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/rest/mgmt":
+			mgmtCalls++
+			var req queryRequest
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			if req.CSL == ".get ingestion resources" {
+				w.Write([]byte(`{"Tables":[{"TableName":"Table_0","Columns":[{"ColumnName":"ResourceTypeName"},{"ColumnName":"StorageRoot"}],"Rows":[` +
+					`["SecuredReadyForAggregationQueue","` + serverURL + `/readyforaggregation0?sas=queue"],` +
+					`["SecuredReadyForAggregationQueue","` + serverURL + `/readyforaggregation1?sas=queue"],` +
+					`["TempStorage","` + serverURL + `/tempstorage0?sas=blob"],` +
+					`["TempStorage","` + serverURL + `/tempstorage1?sas=blob"]]}]}`))
+			} else {
+				w.Write([]byte(`{"Tables":[{"TableName":"Table_0","Columns":[{"ColumnName":"AuthorizationContext"}],"Rows":[["identitytoken"]]}]}`))
+			}
+		case strings.HasPrefix(r.URL.Path, "/tempstorage"):
+			assert.Equal(t, http.MethodPut, r.Method)
+			assert.Equal(t, "BlockBlob", r.Header.Get("x-ms-blob-type"))
+			assert.Equal(t, "blob", r.URL.Query().Get("sas"))
+			container, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+			containers = append(containers, container)
+			blobPaths = append(blobPaths, serverURL+r.URL.String())
+			b, _ := io.ReadAll(r.Body)
+			blobData = string(b)
+			w.WriteHeader(http.StatusCreated)
+		case strings.HasPrefix(r.URL.Path, "/readyforaggregation") && strings.HasSuffix(r.URL.Path, "/messages"):
+			assert.Equal(t, "queue", r.URL.Query().Get("sas"))
+			queues = append(queues, strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), "/messages"))
+			var msg queueMessage
+			assert.NoError(t, xml.NewDecoder(r.Body).Decode(&msg))
+			b, err := base64.StdEncoding.DecodeString(msg.MessageText)
+			assert.NoError(t, err)
+			var message ingestionMessage
+			assert.NoError(t, json.Unmarshal(b, &message))
+			messages = append(messages, message)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	serverURL = d.metadata.Endpoint
+
+	for i := 0; i < 2; i++ {
+		_, err := d.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      []byte("1,event"),
+			Metadata:  map[string]string{"table": "others", "dataFormat": "CSV"},
+		})
+		require.NoError(t, err)
+	}
+
+	// The ingestion resources are cached
+	assert.Equal(t, 2, mgmtCalls)
+	// The ingestions are spread over the queues and the containers
+	assert.ElementsMatch(t, []string{"readyforaggregation0", "readyforaggregation1"}, queues)
+	assert.ElementsMatch(t, []string{"tempstorage0", "tempstorage1"}, containers)
+
+	require.Len(t, messages, 2)
+	message := messages[1]
+	assert.Equal(t, "1,event", blobData)
+	assert.Equal(t, blobPaths[1], message.BlobPath)
+	assert.Contains(t, message.BlobPath, "mydb__others__"+message.ID+".csv")
+	assert.Equal(t, 7, message.RawDataSize)
+	assert.Equal(t, "mydb", message.DatabaseName)
+	assert.Equal(t, "others", message.TableName)
+	assert.Equal(t, map[string]string{"authorizationContext": "identitytoken", "format": "csv"}, message.AdditionalProperties)
+}
+
+func TestIngestErrors(t *testing.T) {
+	d := newTestDataExplorer(t, ingestionTypeStreaming, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL)
+	})
+
+	t.Run("missing data", func(t *testing.T) {
+		_, err := d.Invoke(context.Background(), &bindings.InvokeRequest{Operation: bindings.CreateOperation})
+
+		assert.ErrorContains(t, err, "no data to ingest")
+	})
+
+	t.Run("unsupported data format", func(t *testing.T) {
+		_, err := d.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      []byte("<event/>"),
+			Metadata:  map[string]string{metadataKeyDataFormat: "xml"},
+		})
+
+		assert.ErrorContains(t, err, "unsupported dataFormat")
+	})
+
+	t.Run("unsupported operation", func(t *testing.T) {
+		_, err := d.Invoke(context.Background(), &bindings.InvokeRequest{Operation: bindings.DeleteOperation})
+
+		assert.ErrorContains(t, err, "unsupported operation")
+	})
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventgrid
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// Annotation of an Ingress or a Service holding the public URL of the binding.
+	publicURLAnnotation = "dapr.io/eventgrid-public-url"
+	// Path on which the binding receives the events.
+	eventsPath = "/api/events"
+)
+
+// resolveSubscriberEndpoint returns the public URL of the events endpoint, from the subscriberEndpointTemplate
+// expanded with environment variables, or from the Ingress or Service exposing the binding.
+func resolveSubscriberEndpoint(ctx context.Context, m *azureEventGridMetadata, getClient func() (kubernetes.Interface, error)) (string, error) {
+	switch {
+	case m.SubscriberEndpointTemplate != "":
+		return expandEndpointTemplate(m.SubscriberEndpointTemplate)
+	case m.SubscriberEndpointIngress != "":
+		client, err := getClient()
+		if err != nil {
+			return "", err
+		}
+
+		return ingressEndpoint(ctx, client, m.SubscriberEndpointIngress)
+	case m.SubscriberEndpointService != "":
+		client, err := getClient()
+		if err != nil {
+			return "", err
+		}
+
+		return serviceEndpoint(ctx, client, m.SubscriberEndpointService)
+	default:
+		return "", nil
+	}
+}
+
+// expandEndpointTemplate replaces ${VAR} or $VAR in the template by the value of the environment variables, which must be set.
+func expandEndpointTemplate(template string) (string, error) {
+	var missing []string
+	endpoint := os.Expand(template, func(name string) string {
+		val := os.Getenv(name)
+		if val == "" {
+			missing = append(missing, name)
+		}
+
+		return val
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variables of subscriberEndpointTemplate are not set: %s", strings.Join(missing, ", "))
+	}
+
+	return eventsEndpoint(endpoint)
+}
+
+func ingressEndpoint(ctx context.Context, client kubernetes.Interface, ref string) (string, error) {
+	namespace, name := splitResourceRef(ref)
+	ingress, err := client.NetworkingV1().Ingresses(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error getting ingress %s/%s: %w", namespace, name, err)
+	}
+	if val := ingress.Annotations[publicURLAnnotation]; val != "" {
+		return eventsEndpoint(val)
+	}
+
+	for _, rule := range ingress.Spec.Rules {
+		if rule.Host == "" {
+			continue
+		}
+		// Event Grid only delivers events to HTTPS endpoints, which a host without TLS would not serve
+		for _, tls := range ingress.Spec.TLS {
+			for _, host := range tls.Hosts {
+				if host == rule.Host {
+					return eventsEndpoint("https://" + rule.Host)
+				}
+			}
+		}
+	}
+
+	return "", fmt.Errorf("ingress %s/%s has neither the %s annotation nor a host with TLS", namespace, name, publicURLAnnotation)
+}
+
+func serviceEndpoint(ctx context.Context, client kubernetes.Interface, ref string) (string, error) {
+	namespace, name := splitResourceRef(ref)
+	service, err := client.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error getting service %s/%s: %w", namespace, name, err)
+	}
+	if val := service.Annotations[publicURLAnnotation]; val != "" {
+		return eventsEndpoint(val)
+	}
+
+	return "", fmt.Errorf("service %s/%s does not have the %s annotation", namespace, name, publicURLAnnotation)
+}
+
+// splitResourceRef splits a "namespace/name" reference. The namespace defaults to the one of the pod.
+func splitResourceRef(ref string) (string, string) {
+	if i := strings.IndexByte(ref, '/'); i >= 0 {
+		return ref[:i], ref[i+1:]
+	}
+	namespace := os.Getenv("NAMESPACE")
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+
+	return namespace, ref
+}
+
+// eventsEndpoint validates a public URL, and appends the path of the events endpoint if it has none.
+func eventsEndpoint(publicURL string) (string, error) {
+	u, err := url.Parse(publicURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid subscriber endpoint %q: %w", publicURL, err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("invalid subscriber endpoint %q: an https URL is required", publicURL)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = eventsPath
+	}
+
+	return u.String(), nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventgrid
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/kit/logger"
+)
+
+func TestResolveSubscriberEndpoint(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "annotated", Namespace: "apps", Annotations: map[string]string{publicURLAnnotation: "https://events.example.com/hooks/grid"}},
+		},
+		&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "tls", Namespace: "default"},
+			Spec: networkingv1.IngressSpec{
+				TLS:   []networkingv1.IngressTLS{{Hosts: []string{"app.example.com"}}},
+				Rules: []networkingv1.IngressRule{{Host: "other.example.com"}, {Host: "app.example.com"}},
+			},
+		},
+		&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "plain", Namespace: "default"},
+			Spec:       networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{Host: "app.example.com"}}},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "default", Annotations: map[string]string{publicURLAnnotation: "https://svc.example.com"}},
+		},
+	)
+	getClient := func() (kubernetes.Interface, error) {
+		return client, nil
+	}
+
+	t.Run("template", func(t *testing.T) {
+		t.Setenv("PUBLIC_HOST", "grid.example.com")
+
+		endpoint, err := resolveSubscriberEndpoint(context.Background(), &azureEventGridMetadata{SubscriberEndpointTemplate: "https://${PUBLIC_HOST}"}, getClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "https://grid.example.com/api/events", endpoint)
+	})
+
+	t.Run("template with variable not set", func(t *testing.T) {
+		_, err := resolveSubscriberEndpoint(context.Background(), &azureEventGridMetadata{SubscriberEndpointTemplate: "https://${NOT_SET_HOST}/api/events"}, getClient)
+
+		assert.ErrorContains(t, err, "NOT_SET_HOST")
+	})
+
+	t.Run("ingress annotation", func(t *testing.T) {
+		endpoint, err := resolveSubscriberEndpoint(context.Background(), &azureEventGridMetadata{SubscriberEndpointIngress: "apps/annotated"}, getClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "https://events.example.com/hooks/grid", endpoint)
+	})
+
+	t.Run("ingress host with TLS", func(t *testing.T) {
+		t.Setenv("NAMESPACE", "")
+
+		endpoint, err := resolveSubscriberEndpoint(context.Background(), &azureEventGridMetadata{SubscriberEndpointIngress: "tls"}, getClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "https://app.example.com/api/events", endpoint)
+	})
+
+	t.Run("ingress host without TLS", func(t *testing.T) {
+		_, err := resolveSubscriberEndpoint(context.Background(), &azureEventGridMetadata{SubscriberEndpointIngress: "default/plain"}, getClient)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("service annotation", func(t *testing.T) {
+		t.Setenv("NAMESPACE", "default")
+
+		endpoint, err := resolveSubscriberEndpoint(context.Background(), &azureEventGridMetadata{SubscriberEndpointService: "svc"}, getClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "https://svc.example.com/api/events", endpoint)
+	})
+
+	t.Run("service not found", func(t *testing.T) {
+		_, err := resolveSubscriberEndpoint(context.Background(), &azureEventGridMetadata{SubscriberEndpointService: "default/missing"}, getClient)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("http endpoint", func(t *testing.T) {
+		_, err := eventsEndpoint("http://app.example.com")
+
+		assert.Error(t, err)
+	})
+}
+
+func TestInitResolvesSubscriberEndpoint(t *testing.T) {
+	t.Setenv("PUBLIC_HOST", "grid.example.com")
+	m := bindings.Metadata{}
+	m.Properties = map[string]string{
+		"subscriberEndpointTemplate": "https://${PUBLIC_HOST}/api/events",
+	}
+
+	eh := NewAzureEventGrid(logger.NewLogger("test")).(*AzureEventGrid)
+	err := eh.Init(m)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://grid.example.com/api/events", eh.metadata.SubscriberEndpoint)
+}
@@ -24,27 +24,32 @@ import (
 	"github.com/Azure/azure-sdk-for-go/services/eventgrid/mgmt/2021-12-01/eventgrid"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
 	"github.com/valyala/fasthttp"
+	"k8s.io/client-go/kubernetes"
 
 	"github.com/dapr/components-contrib/bindings"
+	kubeclient "github.com/dapr/components-contrib/internal/authentication/kubernetes"
+	"github.com/dapr/components-contrib/internal/logging"
+	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 )
 
 // AzureEventGrid allows sending/receiving Azure Event Grid events.
 type AzureEventGrid struct {
-	metadata  *azureEventGridMetadata
-	logger    logger.Logger
-	userAgent string
+	metadata   *azureEventGridMetadata
+	logger     logger.Logger
+	userAgent  string
+	kubeClient kubernetes.Interface
 }
 
 type azureEventGridMetadata struct {
 	// Component Name
-	Name string
+	Name string `json:"-"`
 
 	// Required Input Binding Metadata
 	TenantID           string `json:"tenantId"`
 	SubscriptionID     string `json:"subscriptionId"`
 	ClientID           string `json:"clientId"`
-	ClientSecret       string `json:"clientSecret"`
+	ClientSecret       string `json:"clientSecret" mdsensitive:"true"`
 	SubscriberEndpoint string `json:"subscriberEndpoint"`
 	HandshakePort      string `json:"handshakePort"`
 	Scope              string `json:"scope"`
@@ -52,8 +57,13 @@ type azureEventGridMetadata struct {
 	// Optional Input Binding Metadata
 	EventSubscriptionName string `json:"eventSubscriptionName"`
 
+	// Discovery of the subscriber endpoint, when it is not set
+	SubscriberEndpointTemplate string `json:"subscriberEndpointTemplate"`
+	SubscriberEndpointIngress  string `json:"subscriberEndpointIngress"`
+	SubscriberEndpointService  string `json:"subscriberEndpointService"`
+
 	// Required Output Binding Metadata
-	AccessKey     string `json:"accessKey"`
+	AccessKey     string `json:"accessKey" mdsensitive:"true"`
 	TopicEndpoint string `json:"topicEndpoint"`
 }
 
@@ -64,16 +74,39 @@ func NewAzureEventGrid(logger logger.Logger) bindings.InputOutputBinding {
 
 // Init performs metadata init.
 func (a *AzureEventGrid) Init(metadata bindings.Metadata) error {
+	a.logger = logging.ForComponent(a.logger, "bindings.azure.eventgrid", metadata.Name)
 	a.userAgent = "dapr-" + logger.DaprVersion
 	m, err := a.parseMetadata(metadata)
 	if err != nil {
 		return err
 	}
+
+	if m.SubscriberEndpoint == "" {
+		m.SubscriberEndpoint, err = resolveSubscriberEndpoint(context.Background(), m, a.getKubeClient)
+		if err != nil {
+			return fmt.Errorf("error resolving subscriber endpoint: %w", err)
+		}
+		if m.SubscriberEndpoint != "" {
+			a.logger.Infof("Resolved Event Grid subscriber endpoint %s", m.SubscriberEndpoint)
+		}
+	}
 	a.metadata = m
 
 	return nil
 }
 
+func (a *AzureEventGrid) getKubeClient() (kubernetes.Interface, error) {
+	if a.kubeClient == nil {
+		client, err := kubeclient.GetKubeClient()
+		if err != nil {
+			return nil, err
+		}
+		a.kubeClient = client
+	}
+
+	return a.kubeClient, nil
+}
+
 func (a *AzureEventGrid) Read(ctx context.Context, handler bindings.Handler) error {
 	err := a.ensureInputBindingMetadata()
 	if err != nil {
@@ -94,7 +127,7 @@ func (a *AzureEventGrid) Read(ctx context.Context, handler bindings.Handler) err
 				ctx.Response.Header.SetStatusCode(fasthttp.StatusOK)
 				_, err = ctx.Response.BodyWriter().Write([]byte(""))
 				if err != nil {
-					a.logger.Error(err.Error())
+					a.logger.Errorf("error writing handshake response: %v", err)
 				}
 			case "POST":
 				bodyBytes := ctx.PostBody()
@@ -103,7 +136,7 @@ func (a *AzureEventGrid) Read(ctx context.Context, handler bindings.Handler) err
 					Data: bodyBytes,
 				})
 				if err != nil {
-					a.logger.Error(err.Error())
+					a.logger.Errorf("error handling event: %v", err)
 					ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
 				}
 			}
@@ -140,9 +173,10 @@ func (a *AzureEventGrid) Operations() []bindings.OperationKind {
 }
 
 func (a *AzureEventGrid) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	log := logging.ForRequest(ctx, a.logger, req.Metadata)
 	err := a.ensureOutputBindingMetadata()
 	if err != nil {
-		a.logger.Error(err.Error())
+		log.Errorf("invalid output binding metadata: %v", err)
 
 		return nil, err
 	}
@@ -162,19 +196,19 @@ func (a *AzureEventGrid) Invoke(ctx context.Context, req *bindings.InvokeRequest
 	client := &fasthttp.Client{WriteTimeout: time.Second * 10}
 	err = client.Do(request, response)
 	if err != nil {
-		a.logger.Error(err.Error())
+		log.Errorf("error publishing event: %v", err)
 
 		return nil, err
 	}
 
 	if response.StatusCode() != fasthttp.StatusOK {
 		body := response.Body()
-		a.logger.Error(string(body))
+		log.Errorf("error publishing event: status code %d: %s", response.StatusCode(), string(body))
 
 		return nil, errors.New(string(body))
 	}
 
-	a.logger.Debugf("Successfully posted event to %s", a.metadata.TopicEndpoint)
+	log.Debugf("Successfully posted event to %s", a.metadata.TopicEndpoint)
 
 	return nil, nil
 }
@@ -296,3 +330,9 @@ func (a *AzureEventGrid) createSubscription(ctx context.Context) error {
 
 	return nil
 }
+
+// GetComponentMetadataSchema returns the schema of the metadata of the Event Grid binding.
+func (a *AzureEventGrid) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(azureEventGridMetadata{})
+	return fields
+}
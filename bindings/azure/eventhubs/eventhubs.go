@@ -15,22 +15,28 @@ package eventhubs
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Azure/azure-amqp-common-go/v3/aad"
 	"github.com/Azure/azure-amqp-common-go/v3/conn"
 	eventhub "github.com/Azure/azure-event-hubs-go/v3"
 	"github.com/Azure/azure-event-hubs-go/v3/eph"
-	"github.com/Azure/azure-event-hubs-go/v3/storage"
+	"github.com/Azure/azure-event-hubs-go/v3/persist"
 	"github.com/Azure/azure-storage-blob-go/azblob"
 	"github.com/Azure/go-autorest/autorest/azure"
 
 	"github.com/dapr/components-contrib/bindings"
 	azauth "github.com/dapr/components-contrib/internal/authentication/azure"
+	ehcheckpoint "github.com/dapr/components-contrib/internal/component/azure/eventhubs"
+	"github.com/dapr/components-contrib/internal/component/azure/schemaregistry"
+	"github.com/dapr/components-contrib/internal/utils"
+	contribMetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 )
 
@@ -50,6 +56,12 @@ const (
 	hubName          = "eventHub"
 	hubNamespaceName = "eventHubNamespace"
 
+	// optional, to serialize the events with the schemas of an Azure Schema Registry.
+	schemaRegistryNamespace = "schemaRegistryNamespace"
+	schemaGroup             = "schemaGroup"
+	schemaName              = "schemaName"
+	schemaCacheTTLInSec     = "schemaCacheTTLInSec"
+
 	// errors.
 	hubConnectionInitErrorMsg           = "error: creating eventHub hub client"
 	invalidConnectionStringErrorMsg     = "error: connectionString is invalid"
@@ -59,6 +71,7 @@ const (
 	missingStorageAccountKeyErrorMsg    = "error: storageAccountKey is a required attribute when connectionString is provided"
 	missingStorageContainerNameErrorMsg = "error: storageContainerName is a required attribute"
 	missingConsumerGroupErrorMsg        = "error: consumerGroup is a required attribute"
+	missingSchemaGroupErrorMsg          = "error: schemaGroup is a required attribute when schemaRegistryNamespace is provided"
 
 	// Event Hubs SystemProperties names for metadata passthrough.
 	sysPropSequenceNumber             = "x-opt-sequence-number"
@@ -72,8 +85,32 @@ const (
 	sysPropIotHubConnectionModuleID   = "iothub-connection-module-id"
 	sysPropIotHubEnqueuedTime         = "iothub-enqueuedtime"
 	sysPropMessageID                  = "message-id"
+
+	// batchOperation sends the events of a JSON array of batchEvent in batches.
+	batchOperation bindings.OperationKind = "batch"
+	// exportCheckpointsOperation returns the checkpoints of the partitions for the consumer group, as a JSON array.
+	exportCheckpointsOperation bindings.OperationKind = "exportCheckpoints"
+	// importCheckpointsOperation replaces the checkpoints of the partitions with the request data, as returned by
+	// exportCheckpointsOperation. The consumers of the consumer group must be stopped.
+	importCheckpointsOperation bindings.OperationKind = "importCheckpoints"
 )
 
+// Keys of the request metadata which aren't sent as application properties of the events.
+var reservedMetadataKeys = map[string]struct{}{
+	partitionKeyName:                   {},
+	partitionIDName:                    {},
+	schemaName:                         {},
+	contribMetadata.MaxBulkPubBytesKey: {},
+}
+
+// batchEvent is an event of the data of a batch request.
+type batchEvent struct {
+	// Data of the event. A JSON string is sent as its value, the other JSON values as they are.
+	Data         json.RawMessage   `json:"data"`
+	PartitionKey string            `json:"partitionKey,omitempty"`
+	Properties   map[string]string `json:"properties,omitempty"`
+}
+
 func readHandler(ctx context.Context, e *eventhub.Event, handler bindings.Handler) error {
 	res := bindings.ReadResponse{Data: e.Data, Metadata: map[string]string{}}
 	if e.SystemProperties.SequenceNumber != nil {
@@ -125,20 +162,31 @@ type AzureEventHubs struct {
 	tokenProvider     *aad.TokenProvider
 	storageCredential azblob.Credential
 	azureEnvironment  *azure.Environment
+	schemaSerializer  *schemaregistry.Serializer
 	logger            logger.Logger
 	userAgent         string
+
+	// Senders to the partitions selected with the partitionID of the requests
+	partitionHubs     map[string]*eventhub.Hub
+	partitionHubsLock sync.Mutex
 }
 
 type azureEventHubsMetadata struct {
-	connectionString      string
-	consumerGroup         string
-	storageAccountName    string
-	storageAccountKey     string
-	storageContainerName  string
-	partitionID           string
-	partitionKey          string
-	eventHubName          string
-	eventHubNamespaceName string
+	connectionString        string
+	consumerGroup           string
+	storageAccountName      string
+	storageAccountKey       string
+	storageContainerName    string
+	partitionID             string
+	partitionKey            string
+	eventHubName            string
+	eventHubNamespaceName   string
+	schemaRegistryNamespace string
+	schemaGroup             string
+	schemaName              string
+	schemaCacheTTL          time.Duration
+	// Position where the consumption of the partitions without a checkpoint starts, if configured.
+	startingPosition *ehcheckpoint.StartingPosition
 }
 
 func (m azureEventHubsMetadata) partitioned() bool {
@@ -179,6 +227,10 @@ func (a *AzureEventHubs) validateAndGetHubName() (string, error) {
 
 // Init performs metadata init.
 func (a *AzureEventHubs) Init(metadata bindings.Metadata) error {
+	if err := bindings.ValidateConfirmedDelivery(metadata.Properties); err != nil {
+		return err
+	}
+
 	m, err := parseMetadata(metadata)
 	if err != nil {
 		return err
@@ -193,24 +245,6 @@ func (a *AzureEventHubs) Init(metadata bindings.Metadata) error {
 		if validateErr != nil {
 			return errors.New(invalidConnectionStringErrorMsg)
 		}
-
-		var hub *eventhub.Hub
-		// Create partitioned sender if the partitionID is configured.
-		if a.metadata.partitioned() {
-			hub, err = eventhub.NewHubFromConnectionString(a.metadata.connectionString,
-				eventhub.HubWithPartitionedSender(a.metadata.partitionID),
-				eventhub.HubWithUserAgent(a.userAgent),
-			)
-		} else {
-			hub, err = eventhub.NewHubFromConnectionString(a.metadata.connectionString,
-				eventhub.HubWithUserAgent(a.userAgent),
-			)
-		}
-
-		if err != nil {
-			return fmt.Errorf("unable to connect to azure event hubs: %w", err)
-		}
-		a.hub = hub
 	} else {
 		// Connect via AAD.
 		settings, sErr := azauth.NewEnvironmentSettings(azauth.AzureEventHubsResourceName, metadata.Properties)
@@ -223,11 +257,27 @@ func (a *AzureEventHubs) Init(metadata bindings.Metadata) error {
 			return fmt.Errorf("%s %w", hubConnectionInitErrorMsg, err)
 		}
 		a.tokenProvider = tokenProvider
+	}
 
-		a.hub, err = eventhub.NewHub(a.metadata.eventHubNamespaceName, a.metadata.eventHubName, a.tokenProvider, eventhub.HubWithUserAgent(a.userAgent))
-		if err != nil {
-			return fmt.Errorf("unable to connect to azure event hubs: %w", err)
+	// Create partitioned sender if the partitionID is configured.
+	a.hub, err = a.newHub(a.metadata.partitionID)
+	if err != nil {
+		return fmt.Errorf("unable to connect to azure event hubs: %w", err)
+	}
+	a.partitionHubs = map[string]*eventhub.Hub{}
+
+	// The schema registry is accessed via AAD, even with a connectionString.
+	if m.schemaRegistryNamespace != "" {
+		settings, sErr := azauth.NewEnvironmentSettings(azauth.AzureEventHubsResourceName, metadata.Properties)
+		if sErr != nil {
+			return sErr
 		}
+		cred, credErr := settings.GetTokenCredential()
+		if credErr != nil {
+			return credErr
+		}
+		client := schemaregistry.NewClient(m.schemaRegistryNamespace, cred, m.schemaCacheTTL)
+		a.schemaSerializer = schemaregistry.NewSerializer(client, m.schemaGroup)
 	}
 
 	// connect to the storage account.
@@ -242,6 +292,39 @@ func (a *AzureEventHubs) Init(metadata bindings.Metadata) error {
 	return nil
 }
 
+// newHub returns a client of the event hub, sending the events to the partition if partitionID is set.
+func (a *AzureEventHubs) newHub(partitionID string) (*eventhub.Hub, error) {
+	opts := []eventhub.HubOption{eventhub.HubWithUserAgent(a.userAgent)}
+	if partitionID != "" {
+		opts = append(opts, eventhub.HubWithPartitionedSender(partitionID))
+	}
+
+	if a.metadata.connectionString != "" {
+		return eventhub.NewHubFromConnectionString(a.metadata.connectionString, opts...)
+	}
+	return eventhub.NewHub(a.metadata.eventHubNamespaceName, a.metadata.eventHubName, a.tokenProvider, opts...)
+}
+
+// sender returns the client sending the events to the partition, or the client of the binding if partitionID is
+// empty or the partition of the binding.
+func (a *AzureEventHubs) sender(partitionID string) (*eventhub.Hub, error) {
+	if partitionID == "" || partitionID == a.metadata.partitionID {
+		return a.hub, nil
+	}
+
+	a.partitionHubsLock.Lock()
+	defer a.partitionHubsLock.Unlock()
+	if hub, ok := a.partitionHubs[partitionID]; ok {
+		return hub, nil
+	}
+	hub, err := a.newHub(partitionID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to partition %s of azure event hubs: %w", partitionID, err)
+	}
+	a.partitionHubs[partitionID] = hub
+	return hub, nil
+}
+
 func parseMetadata(meta bindings.Metadata) (*azureEventHubsMetadata, error) {
 	m := &azureEventHubsMetadata{}
 
@@ -293,35 +376,260 @@ func parseMetadata(meta bindings.Metadata) (*azureEventHubsMetadata, error) {
 		return m, errors.New(missingHubNamespaceErrorMsg)
 	}
 
+	if val, ok := meta.Properties[schemaRegistryNamespace]; ok && val != "" {
+		m.schemaRegistryNamespace = val
+		if m.schemaGroup = meta.Properties[schemaGroup]; m.schemaGroup == "" {
+			return m, errors.New(missingSchemaGroupErrorMsg)
+		}
+		m.schemaName = meta.Properties[schemaName]
+	}
+
+	if val, ok := meta.Properties[schemaCacheTTLInSec]; ok && val != "" {
+		ttl, err := strconv.Atoi(val)
+		if err != nil {
+			return m, fmt.Errorf("error: invalid %s: %w", schemaCacheTTLInSec, err)
+		}
+		m.schemaCacheTTL = time.Duration(ttl) * time.Second
+	}
+
+	position, ok, err := ehcheckpoint.ParseStartingPosition(meta.Properties)
+	if err != nil {
+		return m, err
+	}
+	if ok {
+		m.startingPosition = &position
+	}
+
 	return m, nil
 }
 
+// GetComponentMetadataSchema returns the schema of the metadata of the Event Hubs binding.
+// The starting position has no default, since the partitions are read from the earliest events, but a single partition
+// from the latest one.
+func (a *AzureEventHubs) GetComponentMetadataSchema() []contribMetadata.MetadataField {
+	fields := []contribMetadata.MetadataField{
+		{Name: connectionString, Type: contribMetadata.FieldTypeString, Sensitive: true},
+		{Name: hubNamespaceName, Type: contribMetadata.FieldTypeString},
+		{Name: hubName, Type: contribMetadata.FieldTypeString},
+		{Name: consumerGroup, Type: contribMetadata.FieldTypeString, Required: true},
+		{Name: storageAccountName, Type: contribMetadata.FieldTypeString, Required: true},
+		{Name: storageAccountKey, Type: contribMetadata.FieldTypeString, Sensitive: true},
+		{Name: storageContainerName, Type: contribMetadata.FieldTypeString, Required: true},
+		{Name: partitionIDName, Type: contribMetadata.FieldTypeString},
+		{Name: partitionKeyName, Type: contribMetadata.FieldTypeString},
+		{Name: schemaRegistryNamespace, Type: contribMetadata.FieldTypeString},
+		{Name: schemaGroup, Type: contribMetadata.FieldTypeString},
+		{Name: schemaName, Type: contribMetadata.FieldTypeString},
+		{Name: schemaCacheTTLInSec, Type: contribMetadata.FieldTypeNumber},
+	}
+	return append(fields, ehcheckpoint.MetadataSchema("")...)
+}
+
 func (a *AzureEventHubs) Operations() []bindings.OperationKind {
-	return []bindings.OperationKind{bindings.CreateOperation}
+	return []bindings.OperationKind{bindings.CreateOperation, batchOperation, exportCheckpointsOperation, importCheckpointsOperation}
 }
 
 // Write posts an event hubs message.
+// The batch operation posts the events of a JSON array of batchEvent in batches of up to maxBulkPubBytes.
 func (a *AzureEventHubs) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	switch req.Operation {
+	case exportCheckpointsOperation:
+		return a.exportCheckpoints(ctx)
+	case importCheckpointsOperation:
+		return nil, a.importCheckpoints(ctx, req)
+	}
+
+	partitionID := req.Metadata[partitionIDName]
+	hub, err := a.sender(partitionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Operation == batchOperation {
+		events, err := a.newBatchEvents(ctx, req.Data, req.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		opts := []eventhub.BatchOption{
+			eventhub.BatchWithMaxSizeInBytes(utils.GetElemOrDefaultFromMap(
+				req.Metadata, contribMetadata.MaxBulkPubBytesKey, int(eventhub.DefaultMaxMessageSizeInBytes))),
+		}
+		return nil, hub.SendBatch(ctx, eventhub.NewEventBatchIterator(events...), opts...)
+	}
+
+	event, err := a.newEvent(ctx, req.Data, req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	return nil, hub.Send(ctx, event)
+}
+
+// checkpointStore returns the store of the checkpoints of the consumer group, which the event processors use.
+func (a *AzureEventHubs) checkpointStore() (*ehcheckpoint.CheckpointStore, error) {
+	storagePrefix, err := a.getStoragePrefixString()
+	if err != nil {
+		return nil, err
+	}
+
+	return ehcheckpoint.NewCheckpointStore(a.storageCredential, a.metadata.storageAccountName, a.metadata.storageContainerName, *a.azureEnvironment, storagePrefix)
+}
+
+func (a *AzureEventHubs) exportCheckpoints(ctx context.Context) (*bindings.InvokeResponse, error) {
+	store, err := a.checkpointStore()
+	if err != nil {
+		return nil, err
+	}
+	checkpoints, err := store.ExportCheckpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(checkpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := "application/json"
+	return &bindings.InvokeResponse{
+		Data:        data,
+		ContentType: &contentType,
+	}, nil
+}
+
+func (a *AzureEventHubs) importCheckpoints(ctx context.Context, req *bindings.InvokeRequest) error {
+	var checkpoints []ehcheckpoint.PartitionCheckpoint
+	if err := json.Unmarshal(req.Data, &checkpoints); err != nil {
+		return fmt.Errorf("error: the data of the %s operation must be a JSON array of checkpoints: %w", importCheckpointsOperation, err)
+	}
+
+	store, err := a.checkpointStore()
+	if err != nil {
+		return err
+	}
+
+	return store.ImportCheckpoints(ctx, checkpoints)
+}
+
+// newEvent returns an event with the data, the partition key and the application properties of the metadata.
+func (a *AzureEventHubs) newEvent(ctx context.Context, data []byte, metadata map[string]string) (*eventhub.Event, error) {
 	event := &eventhub.Event{
-		Data: req.Data,
+		Data: data,
 	}
 
 	// Send partitionKey in event.
 	if a.metadata.partitionKey != "" {
 		event.PartitionKey = &a.metadata.partitionKey
 	} else {
-		partitionKey, ok := req.Metadata[partitionKeyName]
+		partitionKey, ok := metadata[partitionKeyName]
 		if partitionKey != "" && ok {
 			event.PartitionKey = &partitionKey
 		}
 	}
+	// The partition is selected either by the partitionID or by the partitionKey.
+	if event.PartitionKey != nil && metadata[partitionIDName] != "" {
+		return nil, fmt.Errorf("error: %s and %s cannot both be set", partitionKeyName, partitionIDName)
+	}
 
-	err := a.hub.Send(ctx, event)
+	for k, v := range metadata {
+		if _, ok := reservedMetadataKeys[k]; ok {
+			continue
+		}
+		if event.Properties == nil {
+			event.Properties = make(map[string]interface{}, len(metadata))
+		}
+		event.Properties[k] = v
+	}
+
+	err := a.serialize(ctx, event, metadata)
 	if err != nil {
 		return nil, err
 	}
 
-	return nil, nil
+	return event, nil
+}
+
+// newBatchEvents returns the events of the JSON array of batchEvent. The partition key and the application properties
+// of each event take precedence over those of the request metadata.
+func (a *AzureEventHubs) newBatchEvents(ctx context.Context, data []byte, metadata map[string]string) ([]*eventhub.Event, error) {
+	var batch []batchEvent
+	err := json.Unmarshal(data, &batch)
+	if err != nil {
+		return nil, fmt.Errorf("error: the data of the %s operation must be a JSON array of events: %w", batchOperation, err)
+	}
+	if len(batch) == 0 {
+		return nil, fmt.Errorf("error: no events in the data of the %s operation", batchOperation)
+	}
+
+	events := make([]*eventhub.Event, len(batch))
+	for i, e := range batch {
+		eventData := []byte(e.Data)
+		var text string
+		if json.Unmarshal(e.Data, &text) == nil {
+			eventData = []byte(text)
+		}
+
+		eventMetadata := make(map[string]string, len(metadata)+len(e.Properties)+1)
+		for k, v := range metadata {
+			eventMetadata[k] = v
+		}
+		for k, v := range e.Properties {
+			eventMetadata[k] = v
+		}
+		if e.PartitionKey != "" {
+			eventMetadata[partitionKeyName] = e.PartitionKey
+		}
+
+		events[i], err = a.newEvent(ctx, eventData, eventMetadata)
+		if err != nil {
+			return nil, fmt.Errorf("error: invalid event %d: %w", i, err)
+		}
+	}
+
+	return events, nil
+}
+
+// serialize encodes the data of an event with its schema, if the schema registry is configured.
+func (a *AzureEventHubs) serialize(ctx context.Context, event *eventhub.Event, metadata map[string]string) error {
+	if a.schemaSerializer == nil {
+		return nil
+	}
+	name := a.metadata.schemaName
+	if val, ok := metadata[schemaName]; ok && val != "" {
+		name = val
+	}
+	if name == "" {
+		return nil
+	}
+
+	data, contentType, err := a.schemaSerializer.Serialize(ctx, event.Data, name)
+	if err != nil {
+		return err
+	}
+	event.Data = data
+	if event.Properties == nil {
+		event.Properties = map[string]interface{}{}
+	}
+	event.Properties[schemaregistry.ContentTypeProperty] = contentType
+
+	return nil
+}
+
+// deserialize decodes the data of an event encoded with a schema of the registry to JSON.
+func (a *AzureEventHubs) deserialize(ctx context.Context, event *eventhub.Event) error {
+	if a.schemaSerializer == nil {
+		return nil
+	}
+	contentType := event.RawAMQPMessage.Properties.ContentType
+	if val, ok := event.Properties[schemaregistry.ContentTypeProperty].(string); ok {
+		contentType = val
+	}
+
+	data, err := a.schemaSerializer.Deserialize(ctx, event.Data, contentType)
+	if err != nil {
+		return err
+	}
+	event.Data = data
+
+	return nil
 }
 
 // Read gets messages from eventhubs in a non-blocking way.
@@ -356,12 +664,18 @@ func (a *AzureEventHubs) RegisterPartitionedEventProcessor(ctx context.Context,
 		if event == nil {
 			return nil
 		}
+		if err := a.deserialize(c, event); err != nil {
+			return err
+		}
 		return readHandler(c, event, handler)
 	}
 
 	ops := []eventhub.ReceiveOption{
 		eventhub.ReceiveWithLatestOffset(),
 	}
+	if a.metadata.startingPosition != nil {
+		ops[0] = a.metadata.startingPosition.ReceiveOption()
+	}
 
 	if a.metadata.consumerGroup != "" {
 		a.logger.Infof("eventhubs: using consumer group %s", a.metadata.consumerGroup)
@@ -388,8 +702,12 @@ func (a *AzureEventHubs) RegisterEventProcessor(ctx context.Context, handler bin
 		return err
 	}
 
-	leaserPrefixOpt := storage.WithPrefixInBlobPath(storagePrefix)
-	leaserCheckpointer, err := storage.NewStorageLeaserCheckpointer(a.storageCredential, a.metadata.storageAccountName, a.metadata.storageContainerName, *a.azureEnvironment, leaserPrefixOpt)
+	// The event processors start from the earliest event by default
+	position := ehcheckpoint.StartingPosition{Offset: persist.StartOfStream}
+	if a.metadata.startingPosition != nil {
+		position = *a.metadata.startingPosition
+	}
+	leaserCheckpointer, err := ehcheckpoint.NewLeaserCheckpointer(a.storageCredential, a.metadata.storageAccountName, a.metadata.storageContainerName, *a.azureEnvironment, storagePrefix, position)
 	if err != nil {
 		return err
 	}
@@ -412,6 +730,9 @@ func (a *AzureEventHubs) RegisterEventProcessor(ctx context.Context, handler bin
 	_, err = processor.RegisterHandler(
 		ctx,
 		func(c context.Context, event *eventhub.Event) error {
+			if err := a.deserialize(c, event); err != nil {
+				return err
+			}
 			return readHandler(c, event, handler)
 		},
 	)
@@ -440,7 +761,17 @@ func contains(arr []string, str string) bool {
 func (a *AzureEventHubs) Close() (err error) {
 	// Use a background context because the connection context may be canceled already
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 	err = a.hub.Close(ctx)
-	cancel()
+
+	a.partitionHubsLock.Lock()
+	defer a.partitionHubsLock.Unlock()
+	for partitionID, hub := range a.partitionHubs {
+		if closeErr := hub.Close(ctx); closeErr != nil {
+			a.logger.Warnf("error closing the sender to partition %s: %v", partitionID, closeErr)
+		}
+	}
+	a.partitionHubs = map[string]*eventhub.Hub{}
+
 	return err
 }
@@ -14,7 +14,9 @@ limitations under the License.
 package eventhubs
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -73,6 +75,35 @@ func TestParseMetadata(t *testing.T) {
 		assert.Equal(t, m.consumerGroup, "mygroup")
 	})
 
+	t.Run("test schema registry configuration", func(t *testing.T) {
+		props := map[string]string{connectionString: "fake", consumerGroup: "mygroup", storageAccountName: "account", storageAccountKey: "key", storageContainerName: "container", schemaRegistryNamespace: "fake.servicebus.windows.net", schemaGroup: "group", schemaName: "order", schemaCacheTTLInSec: "60"}
+
+		bindingsMetadata := bindings.Metadata{Base: metadata.Base{Properties: props}}
+
+		m, err := parseMetadata(bindingsMetadata)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "fake.servicebus.windows.net", m.schemaRegistryNamespace)
+		assert.Equal(t, "group", m.schemaGroup)
+		assert.Equal(t, "order", m.schemaName)
+		assert.Equal(t, time.Minute, m.schemaCacheTTL)
+	})
+
+	t.Run("test starting position", func(t *testing.T) {
+		props := map[string]string{connectionString: "fake", consumerGroup: "mygroup", storageAccountName: "account", storageAccountKey: "key", storageContainerName: "container"}
+
+		m, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: props}})
+		require.NoError(t, err)
+		assert.Nil(t, m.startingPosition)
+
+		props["startingPosition"] = "timestamp"
+		props["startingTimestamp"] = "2022-11-01T10:00:00Z"
+		m, err = parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: props}})
+		require.NoError(t, err)
+		require.NotNil(t, m.startingPosition)
+		assert.Equal(t, time.Date(2022, 11, 1, 10, 0, 0, 0, time.UTC), m.startingPosition.Timestamp)
+	})
+
 	type invalidConfigTestCase struct {
 		name   string
 		config map[string]string
@@ -109,6 +140,11 @@ func TestParseMetadata(t *testing.T) {
 			map[string]string{consumerGroup: "fake", connectionString: "fake", storageAccountName: "name", storageAccountKey: "key"},
 			missingStorageContainerNameErrorMsg,
 		},
+		{
+			"missing schemaGroup",
+			map[string]string{consumerGroup: "fake", connectionString: "fake", storageAccountName: "name", storageAccountKey: "key", storageContainerName: "container", schemaRegistryNamespace: "fake.servicebus.windows.net"},
+			missingSchemaGroupErrorMsg,
+		},
 	}
 
 	for _, c := range invalidConfigTestCases {
@@ -120,3 +156,95 @@ func TestParseMetadata(t *testing.T) {
 		})
 	}
 }
+
+func TestNewEvent(t *testing.T) {
+	aeh := &AzureEventHubs{logger: testLogger, metadata: &azureEventHubsMetadata{}}
+
+	t.Run("partition key and application properties", func(t *testing.T) {
+		event, err := aeh.newEvent(context.Background(), []byte("hello"), map[string]string{
+			partitionKeyName: "key",
+			"tenant":         "contoso",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []byte("hello"), event.Data)
+		require.NotNil(t, event.PartitionKey)
+		assert.Equal(t, "key", *event.PartitionKey)
+		assert.Equal(t, map[string]interface{}{"tenant": "contoso"}, event.Properties)
+	})
+
+	t.Run("partition key of the binding", func(t *testing.T) {
+		aeh := &AzureEventHubs{logger: testLogger, metadata: &azureEventHubsMetadata{partitionKey: "binding"}}
+
+		event, err := aeh.newEvent(context.Background(), []byte("hello"), map[string]string{partitionKeyName: "key"})
+
+		require.NoError(t, err)
+		assert.Equal(t, "binding", *event.PartitionKey)
+		assert.Nil(t, event.Properties)
+	})
+
+	t.Run("partition key and partition ID", func(t *testing.T) {
+		_, err := aeh.newEvent(context.Background(), []byte("hello"), map[string]string{partitionKeyName: "key", partitionIDName: "1"})
+
+		assert.ErrorContains(t, err, "partitionKey and partitionID cannot both be set")
+	})
+}
+
+func TestNewBatchEvents(t *testing.T) {
+	aeh := &AzureEventHubs{logger: testLogger, metadata: &azureEventHubsMetadata{}}
+
+	t.Run("events", func(t *testing.T) {
+		data := []byte(`[
+			{"data": {"id": 1}, "partitionKey": "a", "properties": {"type": "created"}},
+			{"data": "text", "properties": {"tenant": "fabrikam"}}
+		]`)
+
+		events, err := aeh.newBatchEvents(context.Background(), data, map[string]string{
+			partitionKeyName:            "default",
+			metadata.MaxBulkPubBytesKey: "1024",
+			"tenant":                    "contoso",
+		})
+
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.Equal(t, []byte(`{"id": 1}`), events[0].Data)
+		assert.Equal(t, "a", *events[0].PartitionKey)
+		assert.Equal(t, map[string]interface{}{"tenant": "contoso", "type": "created"}, events[0].Properties)
+		assert.Equal(t, []byte("text"), events[1].Data)
+		assert.Equal(t, "default", *events[1].PartitionKey)
+		assert.Equal(t, map[string]interface{}{"tenant": "fabrikam"}, events[1].Properties)
+	})
+
+	t.Run("partition ID", func(t *testing.T) {
+		events, err := aeh.newBatchEvents(context.Background(), []byte(`[{"data": 1}, {"data": 2}]`), map[string]string{partitionIDName: "1"})
+
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.Nil(t, events[0].PartitionKey)
+		assert.Nil(t, events[1].PartitionKey)
+
+		_, err = aeh.newBatchEvents(context.Background(), []byte(`[{"data": 1, "partitionKey": "a"}]`), map[string]string{partitionIDName: "1"})
+		assert.ErrorContains(t, err, "invalid event 0: error: partitionKey and partitionID cannot both be set")
+	})
+
+	t.Run("invalid data", func(t *testing.T) {
+		_, err := aeh.newBatchEvents(context.Background(), []byte(`{"data": 1}`), nil)
+		assert.ErrorContains(t, err, "must be a JSON array of events")
+
+		_, err = aeh.newBatchEvents(context.Background(), []byte(`[]`), nil)
+		assert.ErrorContains(t, err, "no events")
+	})
+}
+
+func TestGetComponentMetadataSchema(t *testing.T) {
+	fields := map[string]metadata.MetadataField{}
+	for _, f := range (&AzureEventHubs{}).GetComponentMetadataSchema() {
+		fields[f.Name] = f
+	}
+
+	assert.True(t, fields[consumerGroup].Required)
+	assert.True(t, fields[storageAccountKey].Sensitive)
+	assert.Equal(t, metadata.FieldTypeNumber, fields[schemaCacheTTLInSec].Type)
+	assert.Contains(t, fields, "startingPosition")
+	assert.Equal(t, "", fields["startingPosition"].Default)
+}
@@ -24,6 +24,8 @@ import (
 
 	"github.com/dapr/components-contrib/bindings"
 	impl "github.com/dapr/components-contrib/internal/component/azure/servicebus"
+	"github.com/dapr/components-contrib/internal/logging"
+	contribMetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 )
 
@@ -50,6 +52,11 @@ func NewAzureServiceBusQueues(logger logger.Logger) bindings.InputOutputBinding
 
 // Init parses connection properties and creates a new Service Bus Queue client.
 func (a *AzureServiceBusQueues) Init(metadata bindings.Metadata) (err error) {
+	a.logger = logging.ForComponent(a.logger, "bindings.azure.servicebusqueues", metadata.Name)
+	if err := bindings.ValidateConfirmedDelivery(metadata.Properties); err != nil {
+		return err
+	}
+
 	a.metadata, err = impl.ParseMetadata(metadata.Properties, a.logger, (impl.MetadataModeBinding | impl.MetadataModeQueues))
 	if err != nil {
 		return err
@@ -112,18 +119,17 @@ func (a *AzureServiceBusQueues) Read(subscribeCtx context.Context, handler bindi
 		for {
 			sub := impl.NewSubscription(
 				subscribeCtx,
-				a.metadata.MaxActiveMessages,
-				a.metadata.TimeoutInSec,
-				nil,
-				a.metadata.MaxRetriableErrorsPerSec,
-				a.metadata.MaxConcurrentHandlers,
-				"queue "+a.metadata.QueueName,
+				a.metadata.SubscriptionOptions("queue "+a.metadata.QueueName, nil),
 				a.logger,
 			)
 
 			// Blocks until a successful connection (or until context is canceled)
-			err := sub.Connect(func() (*servicebus.Receiver, error) {
-				return a.client.GetClient().NewReceiverForQueue(a.metadata.QueueName, nil)
+			err := sub.Connect(func() (impl.Receiver, error) {
+				receiver, err := a.client.GetClient().NewReceiverForQueue(a.metadata.QueueName, nil)
+				if err != nil {
+					return nil, err
+				}
+				return impl.NewMessageReceiver(receiver), nil
 			})
 			if err != nil {
 				// Realistically, the only time we should get to this point is if the context was canceled, but let's log any other error we may get.
@@ -145,7 +151,7 @@ func (a *AzureServiceBusQueues) Read(subscribeCtx context.Context, handler bindi
 				},
 			)
 			if err != nil && !errors.Is(err, context.Canceled) {
-				a.logger.Error(err)
+				a.logger.Errorf("Error receiving messages: %v", err)
 			}
 
 			// Gracefully close the connection (in case it's not closed already)
@@ -205,3 +211,8 @@ func (a *AzureServiceBusQueues) Close() (err error) {
 	a.client.CloseSender(a.metadata.QueueName)
 	return nil
 }
+
+// GetComponentMetadataSchema returns the schema of the metadata of the Service Bus queues binding.
+func (a *AzureServiceBusQueues) GetComponentMetadataSchema() []contribMetadata.MetadataField {
+	return impl.MetadataSchema(impl.MetadataModeBinding | impl.MetadataModeQueues)
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
 	"strings"
 	"time"
 
@@ -29,6 +30,7 @@ import (
 
 	"github.com/dapr/components-contrib/bindings"
 	azauth "github.com/dapr/components-contrib/internal/authentication/azure"
+	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 )
 
@@ -44,8 +46,15 @@ const (
 	hubKey              = "hub"
 
 	// Invoke metadata keys.
-	groupKey = "group"
-	userKey  = "user"
+	groupKey        = "group"
+	userKey         = "user"
+	connectionIDKey = "connectionId"
+	reasonKey       = "reason"
+
+	// Operations managing the groups and the connections.
+	addUserToGroupOperation      bindings.OperationKind = "addUserToGroup"
+	removeUserFromGroupOperation bindings.OperationKind = "removeUserFromGroup"
+	closeConnectionOperation     bindings.OperationKind = "closeConnection"
 )
 
 // Global HTTP client
@@ -172,7 +181,17 @@ func (s *SignalR) parseMetadata(md map[string]string) (err error) {
 	return nil
 }
 
-func (s *SignalR) resolveAPIURL(req *bindings.InvokeRequest) (string, error) {
+// GetComponentMetadataSchema returns the schema of the metadata of the SignalR binding.
+func (s *SignalR) GetComponentMetadataSchema() []metadata.MetadataField {
+	return []metadata.MetadataField{
+		{Name: connectionStringKey, Type: metadata.FieldTypeString, Sensitive: true},
+		{Name: endpointKey, Type: metadata.FieldTypeString},
+		{Name: accessKeyKey, Type: metadata.FieldTypeString, Sensitive: true},
+		{Name: hubKey, Type: metadata.FieldTypeString},
+	}
+}
+
+func (s *SignalR) resolveHubURL(req *bindings.InvokeRequest) (string, error) {
 	hub, ok := req.Metadata[hubKey]
 	if !ok || hub == "" {
 		hub = s.hub
@@ -184,26 +203,70 @@ func (s *SignalR) resolveAPIURL(req *bindings.InvokeRequest) (string, error) {
 	// Hub name is lower-cased in the official SDKs (e.g. .NET)
 	hub = strings.ToLower(hub)
 
-	var url string
+	return fmt.Sprintf("%s/api/v1/hubs/%s", s.endpoint, neturl.PathEscape(hub)), nil
+}
+
+func (s *SignalR) resolveAPIURL(req *bindings.InvokeRequest) (string, error) {
+	hubURL, err := s.resolveHubURL(req)
+	if err != nil {
+		return "", err
+	}
+
 	if group, ok := req.Metadata[groupKey]; ok && group != "" {
-		url = fmt.Sprintf("%s/api/v1/hubs/%s/groups/%s", s.endpoint, hub, group)
+		return hubURL + "/groups/" + neturl.PathEscape(group), nil
 	} else if user, ok := req.Metadata[userKey]; ok && user != "" {
-		url = fmt.Sprintf("%s/api/v1/hubs/%s/users/%s", s.endpoint, hub, user)
-	} else {
-		url = fmt.Sprintf("%s/api/v1/hubs/%s", s.endpoint, hub)
+		return hubURL + "/users/" + neturl.PathEscape(user), nil
+	} else if connectionID, ok := req.Metadata[connectionIDKey]; ok && connectionID != "" {
+		return hubURL + "/connections/" + neturl.PathEscape(connectionID), nil
 	}
 
-	return url, nil
+	return hubURL, nil
 }
 
-func (s *SignalR) sendMessageToSignalR(ctx context.Context, url string, token string, data []byte) error {
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(data))
+// resolveGroupUserURL returns the URL of the membership of the user in the group.
+func (s *SignalR) resolveGroupUserURL(req *bindings.InvokeRequest) (string, error) {
+	hubURL, err := s.resolveHubURL(req)
+	if err != nil {
+		return "", err
+	}
+
+	group := req.Metadata[groupKey]
+	user := req.Metadata[userKey]
+	if group == "" || user == "" {
+		return "", fmt.Errorf("%s the %s and %s metadata are required for the %s operation", errorPrefix, groupKey, userKey, req.Operation)
+	}
+
+	return fmt.Sprintf("%s/groups/%s/users/%s", hubURL, neturl.PathEscape(group), neturl.PathEscape(user)), nil
+}
+
+func (s *SignalR) resolveConnectionURL(req *bindings.InvokeRequest) (string, error) {
+	hubURL, err := s.resolveHubURL(req)
+	if err != nil {
+		return "", err
+	}
+
+	connectionID := req.Metadata[connectionIDKey]
+	if connectionID == "" {
+		return "", fmt.Errorf("%s the %s metadata is required for the %s operation", errorPrefix, connectionIDKey, req.Operation)
+	}
+
+	return hubURL + "/connections/" + neturl.PathEscape(connectionID), nil
+}
+
+func (s *SignalR) sendRequestToSignalR(ctx context.Context, method string, url string, token string, data []byte) error {
+	var reqBody io.Reader
+	if data != nil {
+		reqBody = bytes.NewBuffer(data)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 	if err != nil {
 		return err
 	}
 
 	httpReq.Header.Set("Authorization", "Bearer "+token)
-	httpReq.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if data != nil {
+		httpReq.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
 	httpReq.Header.Set("User-Agent", s.userAgent)
 
 	resp, err := s.httpClient.Do(httpReq)
@@ -222,27 +285,56 @@ func (s *SignalR) sendMessageToSignalR(ctx context.Context, url string, token st
 		return fmt.Errorf("%s azure signalr failed with code %d, content is '%s'", errorPrefix, resp.StatusCode, string(body))
 	}
 
-	s.logger.Debugf("%s azure signalr call to '%s' completed with code %d", logPrefix, url, resp.StatusCode)
+	s.logger.Debugf("%s azure signalr %s call to '%s' completed with code %d", logPrefix, method, url, resp.StatusCode)
 
 	return nil
 }
 
 func (s *SignalR) Operations() []bindings.OperationKind {
-	return []bindings.OperationKind{bindings.CreateOperation}
+	return []bindings.OperationKind{
+		bindings.CreateOperation,
+		addUserToGroupOperation,
+		removeUserFromGroupOperation,
+		closeConnectionOperation,
+	}
 }
 
 func (s *SignalR) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
-	url, err := s.resolveAPIURL(req)
+	var (
+		method, url string
+		data        []byte
+		err         error
+	)
+	switch req.Operation {
+	case addUserToGroupOperation:
+		method = http.MethodPut
+		url, err = s.resolveGroupUserURL(req)
+	case removeUserFromGroupOperation:
+		method = http.MethodDelete
+		url, err = s.resolveGroupUserURL(req)
+	case closeConnectionOperation:
+		method = http.MethodDelete
+		url, err = s.resolveConnectionURL(req)
+	default:
+		method = http.MethodPost
+		url, err = s.resolveAPIURL(req)
+		data = req.Data
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	// The token audience is the URL without the query string
 	token, err := s.getToken(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 
-	err = s.sendMessageToSignalR(ctx, url, token, req.Data)
+	if reason := req.Metadata[reasonKey]; req.Operation == closeConnectionOperation && reason != "" {
+		url += "?reason=" + neturl.QueryEscape(reason)
+	}
+
+	err = s.sendRequestToSignalR(ctx, method, url, token, data)
 	if err != nil {
 		return nil, err
 	}
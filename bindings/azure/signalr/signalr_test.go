@@ -24,6 +24,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/components-contrib/bindings"
 	"github.com/dapr/kit/logger"
@@ -398,3 +399,64 @@ func TestWriteShouldSucceed(t *testing.T) {
 		})
 	}
 }
+
+func TestManagementOperations(t *testing.T) {
+	httpTransport := &mockTransport{
+		response: &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))},
+	}
+
+	s := NewSignalR(logger.NewLogger("test")).(*SignalR)
+	s.endpoint = "https://fake.service.signalr.net"
+	s.accessKey = "fakekey"
+	s.hub = "testHub"
+	s.httpClient = &http.Client{
+		Transport: httpTransport,
+	}
+
+	tests := []struct {
+		name           string
+		operation      bindings.OperationKind
+		metadata       map[string]string
+		expectedMethod string
+		expectedURL    string
+	}{
+		{"Add user to group", addUserToGroupOperation, map[string]string{groupKey: "mygroup", userKey: "my user"}, http.MethodPut, "https://fake.service.signalr.net/api/v1/hubs/testhub/groups/mygroup/users/my%20user"},
+		{"Remove user from group", removeUserFromGroupOperation, map[string]string{groupKey: "mygroup", userKey: "myuser"}, http.MethodDelete, "https://fake.service.signalr.net/api/v1/hubs/testhub/groups/mygroup/users/myuser"},
+		{"Close connection", closeConnectionOperation, map[string]string{connectionIDKey: "abc"}, http.MethodDelete, "https://fake.service.signalr.net/api/v1/hubs/testhub/connections/abc"},
+		{"Close connection with reason", closeConnectionOperation, map[string]string{connectionIDKey: "abc", reasonKey: "logged out"}, http.MethodDelete, "https://fake.service.signalr.net/api/v1/hubs/testhub/connections/abc?reason=logged+out"},
+		{"Send to connection", bindings.CreateOperation, map[string]string{connectionIDKey: "abc"}, http.MethodPost, "https://fake.service.signalr.net/api/v1/hubs/testhub/connections/abc"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			httpTransport.reset()
+			_, err := s.Invoke(context.Background(), &bindings.InvokeRequest{
+				Operation: tt.operation,
+				Metadata:  tt.metadata,
+			})
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedMethod, httpTransport.request.Method)
+			assert.Equal(t, tt.expectedURL, httpTransport.request.URL.String())
+		})
+	}
+
+	t.Run("Missing user", func(t *testing.T) {
+		_, err := s.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: addUserToGroupOperation,
+			Metadata:  map[string]string{groupKey: "mygroup"},
+		})
+
+		assert.ErrorContains(t, err, "the group and user metadata are required for the addUserToGroup operation")
+	})
+
+	t.Run("Missing connection ID", func(t *testing.T) {
+		_, err := s.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: closeConnectionOperation,
+			Metadata:  map[string]string{},
+		})
+
+		assert.ErrorContains(t, err, "the connectionId metadata is required for the closeConnection operation")
+	})
+}
@@ -16,6 +16,7 @@ package storagequeues
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -25,6 +26,7 @@ import (
 
 	"github.com/dapr/components-contrib/bindings"
 	azauth "github.com/dapr/components-contrib/internal/authentication/azure"
+	"github.com/dapr/components-contrib/internal/poller"
 	contribMetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 	"github.com/dapr/kit/ptr"
@@ -32,6 +34,12 @@ import (
 
 const (
 	defaultTTL = time.Minute * 10
+	// The messages of the poison queue never expire.
+	poisonMessageTTL = -time.Second
+	// Suffix of the name of the poison queue of a queue, by default.
+	poisonQueueSuffix = "-poison"
+	// Interval between the reads of an empty queue, by default.
+	defaultPollingInterval = 10 * time.Second
 )
 
 type consumer struct {
@@ -42,7 +50,9 @@ type consumer struct {
 type QueueHelper interface {
 	Init(metadata bindings.Metadata) (*storageQueuesMetadata, error)
 	Write(ctx context.Context, data []byte, ttl *time.Duration) error
-	Read(ctx context.Context, consumer *consumer) error
+	// Read reads a message, if any, and returns whether one was read. The cursor is the ID of the last message handled,
+	// which is deleted without being handled again if its deletion failed; the ID of the message handled is returned.
+	Read(ctx context.Context, consumer *consumer, cursor string) (next string, more bool, err error)
 }
 
 // AzureQueueHelper concrete impl of queue helper.
@@ -52,6 +62,9 @@ type AzureQueueHelper struct {
 	decodeBase64      bool
 	encodeBase64      bool
 	visibilityTimeout time.Duration
+	// Messages failing maxDequeueCount times are moved to the poison queue, if maxDequeueCount is set.
+	maxDequeueCount int64
+	poisonQueueURL  azqueue.QueueURL
 }
 
 // Init sets up this helper.
@@ -61,7 +74,7 @@ func (d *AzureQueueHelper) Init(metadata bindings.Metadata) (*storageQueuesMetad
 		return nil, err
 	}
 
-	credential, env, err := azauth.GetAzureStorageQueueCredentials(d.logger, m.AccountName, metadata.Properties)
+	credential, env, err := azauth.GetAzureStorageQueueCredentials(d.logger, m.auth, metadata.Properties)
 	if err != nil {
 		return nil, fmt.Errorf("invalid credentials with error: %s", err.Error())
 	}
@@ -77,25 +90,46 @@ func (d *AzureQueueHelper) Init(metadata bindings.Metadata) (*storageQueuesMetad
 	d.decodeBase64 = m.DecodeBase64
 	d.encodeBase64 = m.EncodeBase64
 	d.visibilityTimeout = *m.VisibilityTimeout
+	d.maxDequeueCount = m.MaxDequeueCount
 
-	if m.QueueEndpoint != "" {
-		URL, parseErr := url.Parse(fmt.Sprintf("%s/%s/%s", m.QueueEndpoint, m.AccountName, m.QueueName))
+	newQueueURL := func(queueName string) (azqueue.QueueURL, error) {
+		if m.QueueEndpoint != "" {
+			URL, parseErr := url.Parse(m.auth.AddSASToken(fmt.Sprintf("%s/%s/%s", m.QueueEndpoint, m.AccountName, queueName)))
+			if parseErr != nil {
+				return azqueue.QueueURL{}, parseErr
+			}
+			return azqueue.NewQueueURL(*URL, p), nil
+		}
+		URL, parseErr := url.Parse(m.auth.AddSASToken(m.auth.ServiceURL(azauth.StorageServiceQueue, env) + "/" + queueName))
 		if parseErr != nil {
-			return nil, parseErr
+			return azqueue.QueueURL{}, parseErr
 		}
-		d.queueURL = azqueue.NewQueueURL(*URL, p)
-	} else {
-		URL, _ := url.Parse(fmt.Sprintf("https://%s.queue.%s/%s", m.AccountName, env.StorageEndpointSuffix, m.QueueName))
-		d.queueURL = azqueue.NewQueueURL(*URL, p)
+		return azqueue.NewQueueURL(*URL, p), nil
+	}
+
+	d.queueURL, err = newQueueURL(m.QueueName)
+	if err != nil {
+		return nil, err
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
 	_, err = d.queueURL.Create(ctx, azqueue.Metadata{})
-	cancel()
 	if err != nil {
 		return nil, err
 	}
 
+	if d.maxDequeueCount > 0 {
+		d.poisonQueueURL, err = newQueueURL(m.PoisonQueueName)
+		if err != nil {
+			return nil, err
+		}
+		_, err = d.poisonQueueURL.Create(ctx, azqueue.Metadata{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the poison queue %s: %w", m.PoisonQueueName, err)
+		}
+	}
+
 	return m, nil
 }
 
@@ -120,44 +154,71 @@ func (d *AzureQueueHelper) Write(ctx context.Context, data []byte, ttl *time.Dur
 	return err
 }
 
-func (d *AzureQueueHelper) Read(ctx context.Context, consumer *consumer) error {
+func (d *AzureQueueHelper) Read(ctx context.Context, consumer *consumer, cursor string) (string, bool, error) {
 	messagesURL := d.queueURL.NewMessagesURL()
 	res, err := messagesURL.Dequeue(ctx, 1, d.visibilityTimeout)
 	if err != nil {
-		return err
+		return cursor, false, err
 	}
 	if res.NumMessages() == 0 {
-		// Queue was empty so back off by 10 seconds before trying again
-		time.Sleep(10 * time.Second)
-		return nil
+		return cursor, false, nil
+	}
+	msg := res.Message(0)
+	messageIDURL := messagesURL.NewMessageIDURL(msg.ID)
+
+	// The message was handled, but not deleted
+	if string(msg.ID) == cursor {
+		_, err = messageIDURL.Delete(ctx, msg.PopReceipt)
+		return cursor, true, err
+	}
+
+	err = d.handleMessage(ctx, consumer, msg)
+	if err != nil {
+		if d.maxDequeueCount > 0 && msg.DequeueCount >= d.maxDequeueCount {
+			return cursor, true, d.moveToPoisonQueue(ctx, msg, err)
+		}
+		return cursor, true, err
+	}
+	_, err = messageIDURL.Delete(ctx, msg.PopReceipt)
+	if err != nil {
+		d.logger.Warnf("failed to delete message %s, it is deleted when received again: %v", msg.ID, err)
 	}
-	mt := res.Message(0).Text
 
+	return string(msg.ID), true, nil
+}
+
+func (d *AzureQueueHelper) handleMessage(ctx context.Context, consumer *consumer, msg *azqueue.DequeuedMessage) error {
 	var data []byte
 
 	if d.decodeBase64 {
-		decoded, decodeError := base64.StdEncoding.DecodeString(mt)
+		decoded, decodeError := base64.StdEncoding.DecodeString(msg.Text)
 		if decodeError != nil {
 			return decodeError
 		}
 		data = decoded
 	} else {
-		data = []byte(mt)
+		data = []byte(msg.Text)
 	}
 
-	_, err = consumer.callback(ctx, &bindings.ReadResponse{
+	_, err := consumer.callback(ctx, &bindings.ReadResponse{
 		Data:     data,
 		Metadata: map[string]string{},
 	})
+	return err
+}
+
+// moveToPoisonQueue moves a message which failed maxDequeueCount times to the poison queue, as it was received.
+func (d *AzureQueueHelper) moveToPoisonQueue(ctx context.Context, msg *azqueue.DequeuedMessage, cause error) error {
+	_, err := d.poisonQueueURL.NewMessagesURL().Enqueue(ctx, msg.Text, 0, poisonMessageTTL)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to move message %s to the poison queue after error %v: %w", msg.ID, cause, err)
 	}
-	messageIDURL := messagesURL.NewMessageIDURL(res.Message(0).ID)
-	pr := res.Message(0).PopReceipt
-	_, err = messageIDURL.Delete(ctx, pr)
+
+	_, err = d.queueURL.NewMessagesURL().NewMessageIDURL(msg.ID).Delete(ctx, msg.PopReceipt)
 	if err != nil {
 		return err
 	}
+	d.logger.Warnf("moved message %s to the poison queue after %d failed attempts: %v", msg.ID, msg.DequeueCount, cause)
 
 	return nil
 }
@@ -174,17 +235,32 @@ type AzureStorageQueues struct {
 	metadata *storageQueuesMetadata
 	helper   QueueHelper
 
+	// State store of the cursor, if any, and key of the cursor
+	stateStores bindings.StateStoreResolver
+	cursors     poller.CursorStore
+	cursorKey   string
+
 	logger logger.Logger
 }
 
 type storageQueuesMetadata struct {
-	QueueName         string
+	QueueName         string `mdrequired:"true"`
 	QueueEndpoint     string
-	AccountName       string
+	AccountName       string `mdrequired:"true"`
 	DecodeBase64      bool
 	EncodeBase64      bool
 	ttl               *time.Duration
 	VisibilityTimeout *time.Duration
+	// Number of failed attempts after which the messages are moved to the poison queue, 0 to retry them until they
+	// expire.
+	MaxDequeueCount int64
+	// Name of the poison queue, the name of the queue with the -poison suffix by default.
+	PoisonQueueName string
+	// Interval between the reads of the queue when it's empty.
+	PollingInterval time.Duration
+
+	// Credentials selected by the metadata provided
+	auth *azauth.StorageAuth
 }
 
 // NewAzureStorageQueues returns a new AzureStorageQueues instance.
@@ -194,33 +270,63 @@ func NewAzureStorageQueues(logger logger.Logger) bindings.InputOutputBinding {
 
 // Init parses connection properties and creates a new Storage Queue client.
 func (a *AzureStorageQueues) Init(metadata bindings.Metadata) (err error) {
+	if err := bindings.ValidateConfirmedDelivery(metadata.Properties); err != nil {
+		return err
+	}
+
 	a.metadata, err = a.helper.Init(metadata)
 	if err != nil {
 		return err
 	}
 
+	a.cursors, a.cursorKey, err = poller.CursorStoreFromMetadata(metadata.Properties, metadata.Name, a.stateStores)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// SetStateStoreResolver sets the resolver of the state store of the cursor.
+func (a *AzureStorageQueues) SetStateStoreResolver(resolver bindings.StateStoreResolver) {
+	a.stateStores = resolver
+}
+
 func parseMetadata(meta bindings.Metadata) (*storageQueuesMetadata, error) {
 	m := storageQueuesMetadata{
 		VisibilityTimeout: ptr.Of(time.Second * 30),
+		PollingInterval:   defaultPollingInterval,
 	}
-	// AccountKey is parsed in azauth
+	// AccountKey, SAS token and connection string are parsed in azauth
 
-	contribMetadata.DecodeMetadata(meta.Properties, &m)
+	err := contribMetadata.DecodeMetadata(meta.Properties, &m)
+	if err != nil {
+		return nil, err
+	}
+	if *m.VisibilityTimeout <= 0 {
+		return nil, errors.New("visibilityTimeout must be positive")
+	}
+	if m.MaxDequeueCount < 0 {
+		return nil, errors.New("maxDequeueCount must not be negative")
+	}
+	if m.PollingInterval <= 0 {
+		return nil, errors.New("pollingInterval must be positive")
+	}
 
-	if val, ok := contribMetadata.GetMetadataProperty(meta.Properties, azauth.StorageAccountNameKeys...); ok && val != "" {
-		m.AccountName = val
-	} else {
-		return nil, fmt.Errorf("missing or empty %s field from metadata", azauth.StorageAccountNameKeys[0])
+	m.auth, err = azauth.GetStorageAuth(meta.Properties)
+	if err != nil {
+		return nil, err
 	}
+	m.AccountName = m.auth.AccountName
 
 	if val, ok := contribMetadata.GetMetadataProperty(meta.Properties, azauth.StorageQueueNameKeys...); ok && val != "" {
 		m.QueueName = val
 	} else {
 		return nil, fmt.Errorf("missing or empty %s field from metadata", azauth.StorageQueueNameKeys[0])
 	}
+	if m.PoisonQueueName == "" {
+		m.PoisonQueueName = m.QueueName + poisonQueueSuffix
+	}
 
 	if val, ok := contribMetadata.GetMetadataProperty(meta.Properties, azauth.StorageEndpointKeys...); ok && val != "" {
 		m.QueueEndpoint = val
@@ -264,16 +370,28 @@ func (a *AzureStorageQueues) Read(ctx context.Context, handler bindings.Handler)
 	c := consumer{
 		callback: handler,
 	}
+	// The queue is read again right away after a message, and after pollingInterval once it's empty, plus up to a
+	// tenth of it so that the replicas don't poll the queue together
+	p := poller.New(func(ctx context.Context, cursor string) (string, bool, error) {
+		return a.helper.Read(ctx, &c, cursor)
+	}, poller.Options{
+		Interval:    a.metadata.PollingInterval,
+		Jitter:      a.metadata.PollingInterval / 10,
+		CursorStore: a.cursors,
+		CursorKey:   a.cursorKey,
+	}, a.logger)
 	go func() {
 		// Read until context is canceled
-		var err error
-		for ctx.Err() == nil {
-			err = a.helper.Read(ctx, &c)
-			if err != nil {
-				a.logger.Errorf("error from c: %s", err)
-			}
-		}
+		_ = p.Run(ctx)
 	}()
 
 	return nil
 }
+
+// GetComponentMetadataSchema returns the schema of the metadata of the Storage Queues binding.
+func (a *AzureStorageQueues) GetComponentMetadataSchema() []contribMetadata.MetadataField {
+	fields, _ := contribMetadata.GetMetadataSchemaFromStruct(storageQueuesMetadata{
+		PollingInterval: defaultPollingInterval,
+	})
+	return fields
+}
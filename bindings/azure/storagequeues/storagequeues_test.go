@@ -16,14 +16,24 @@ package storagequeues
 import (
 	"context"
 	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
+	"github.com/Azure/azure-storage-queue-go/azqueue"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/components-contrib/bindings"
 	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+	stateInMemory "github.com/dapr/components-contrib/state/in-memory"
 	"github.com/dapr/kit/logger"
 	"github.com/dapr/kit/ptr"
 )
@@ -32,10 +42,13 @@ type MockHelper struct {
 	mock.Mock
 	messages chan []byte
 	metadata *storageQueuesMetadata
+	// Cursors the queue is read from
+	cursors chan string
 }
 
 func (m *MockHelper) Init(metadata bindings.Metadata) (*storageQueuesMetadata, error) {
 	m.messages = make(chan []byte, 10)
+	m.cursors = make(chan string, 10)
 	var err error
 	m.metadata, err = parseMetadata(metadata)
 	return m.metadata, err
@@ -47,8 +60,12 @@ func (m *MockHelper) Write(ctx context.Context, data []byte, ttl *time.Duration)
 	return retvals.Error(0)
 }
 
-func (m *MockHelper) Read(ctx context.Context, consumer *consumer) error {
+func (m *MockHelper) Read(ctx context.Context, consumer *consumer, cursor string) (string, bool, error) {
 	retvals := m.Called(ctx, consumer)
+	select {
+	case m.cursors <- cursor:
+	default:
+	}
 
 	go func() {
 		for msg := range m.messages {
@@ -61,7 +78,7 @@ func (m *MockHelper) Read(ctx context.Context, consumer *consumer) error {
 		}
 	}()
 
-	return retvals.Error(0)
+	return cursor, false, retvals.Error(0)
 }
 
 func TestWriteQueue(t *testing.T) {
@@ -387,3 +404,189 @@ func TestParseMetadataWithInvalidTTL(t *testing.T) {
 		})
 	}
 }
+
+func TestParseMetadataPoisonQueue(t *testing.T) {
+	t.Run("default poison queue", func(t *testing.T) {
+		m := bindings.Metadata{}
+		m.Properties = map[string]string{"storageAccessKey": "myKey", "queue": "queue1", "storageAccount": "devstoreaccount1", "maxDequeueCount": "5"}
+
+		meta, err := parseMetadata(m)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), meta.MaxDequeueCount)
+		assert.Equal(t, "queue1-poison", meta.PoisonQueueName)
+	})
+
+	t.Run("poison queue name", func(t *testing.T) {
+		m := bindings.Metadata{}
+		m.Properties = map[string]string{"storageAccessKey": "myKey", "queue": "queue1", "storageAccount": "devstoreaccount1", "maxDequeueCount": "5", "poisonQueueName": "failed"}
+
+		meta, err := parseMetadata(m)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "failed", meta.PoisonQueueName)
+	})
+
+	t.Run("polling interval", func(t *testing.T) {
+		m := bindings.Metadata{}
+		m.Properties = map[string]string{"storageAccessKey": "myKey", "queue": "queue1", "storageAccount": "devstoreaccount1"}
+
+		meta, err := parseMetadata(m)
+		assert.NoError(t, err)
+		assert.Equal(t, 10*time.Second, meta.PollingInterval)
+
+		m.Properties["pollingInterval"] = "1m"
+		meta, err = parseMetadata(m)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Minute, meta.PollingInterval)
+	})
+
+	t.Run("invalid metadata", func(t *testing.T) {
+		for _, properties := range []map[string]string{
+			{"storageAccessKey": "myKey", "queue": "queue1", "storageAccount": "devstoreaccount1", "maxDequeueCount": "-1"},
+			{"storageAccessKey": "myKey", "queue": "queue1", "storageAccount": "devstoreaccount1", "maxDequeueCount": "abc"},
+			{"storageAccessKey": "myKey", "queue": "queue1", "storageAccount": "devstoreaccount1", "visibilityTimeout": "0s"},
+			{"storageAccessKey": "myKey", "queue": "queue1", "storageAccount": "devstoreaccount1", "pollingInterval": "0s"},
+		} {
+			m := bindings.Metadata{}
+			m.Properties = properties
+
+			_, err := parseMetadata(m)
+
+			assert.Error(t, err)
+		}
+	})
+}
+
+// fakeQueueService is a storage queue service where queue1 has a single message, and queue1-poison receives messages.
+type fakeQueueService struct {
+	dequeueCount int64
+	deleted      []string
+	poisoned     []string
+}
+
+func (f *fakeQueueService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().UTC().Format(http.TimeFormat)
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/account/queue1/messages":
+		f.dequeueCount++
+		fmt.Fprintf(w, `<QueueMessagesList><QueueMessage><MessageId>id1</MessageId><InsertionTime>%[1]s</InsertionTime>`+
+			`<ExpirationTime>%[1]s</ExpirationTime><PopReceipt>receipt</PopReceipt><TimeNextVisible>%[1]s</TimeNextVisible>`+
+			`<DequeueCount>%[2]d</DequeueCount><MessageText>bWVzc2FnZQ==</MessageText></QueueMessage></QueueMessagesList>`, now, f.dequeueCount)
+	case r.Method == http.MethodDelete && r.URL.Path == "/account/queue1/messages/id1":
+		f.deleted = append(f.deleted, r.URL.Query().Get("popreceipt"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && r.URL.Path == "/account/queue1-poison/messages":
+		body, _ := io.ReadAll(r.Body)
+		f.poisoned = append(f.poisoned, string(body))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `<QueueMessagesList><QueueMessage><MessageId>id2</MessageId><InsertionTime>%[1]s</InsertionTime>`+
+			`<ExpirationTime>%[1]s</ExpirationTime><PopReceipt>receipt</PopReceipt><TimeNextVisible>%[1]s</TimeNextVisible>`+
+			`</QueueMessage></QueueMessagesList>`, now)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestReadPoisonMessage(t *testing.T) {
+	service := &fakeQueueService{}
+	server := httptest.NewServer(service)
+	defer server.Close()
+
+	newQueueURL := func(name string) azqueue.QueueURL {
+		u, _ := url.Parse(server.URL + "/account/" + name)
+		return azqueue.NewQueueURL(*u, azqueue.NewPipeline(azqueue.NewAnonymousCredential(), azqueue.PipelineOptions{}))
+	}
+	helper := &AzureQueueHelper{
+		queueURL:          newQueueURL("queue1"),
+		poisonQueueURL:    newQueueURL("queue1-poison"),
+		logger:            logger.NewLogger("test"),
+		decodeBase64:      true,
+		visibilityTimeout: time.Second,
+		maxDequeueCount:   2,
+	}
+	var received []string
+	c := &consumer{callback: func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		received = append(received, string(res.Data))
+		return nil, errors.New("failed")
+	}}
+
+	// The message is kept in the queue until it fails maxDequeueCount times
+	_, read, err := helper.Read(context.Background(), c, "")
+	assert.True(t, read)
+	assert.EqualError(t, err, "failed")
+	assert.Empty(t, service.deleted)
+
+	_, read, err = helper.Read(context.Background(), c, "")
+	assert.True(t, read)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"message", "message"}, received)
+	assert.Equal(t, []string{"receipt"}, service.deleted)
+	require.Len(t, service.poisoned, 1)
+	assert.Contains(t, service.poisoned[0], "<MessageText>bWVzc2FnZQ==</MessageText>")
+}
+
+func TestReadHandledMessage(t *testing.T) {
+	service := &fakeQueueService{}
+	server := httptest.NewServer(service)
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL + "/account/queue1")
+	helper := &AzureQueueHelper{
+		queueURL:          azqueue.NewQueueURL(*u, azqueue.NewPipeline(azqueue.NewAnonymousCredential(), azqueue.PipelineOptions{})),
+		logger:            logger.NewLogger("test"),
+		visibilityTimeout: time.Second,
+	}
+	received := 0
+	c := &consumer{callback: func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		received++
+		return nil, nil
+	}}
+
+	// The message of the cursor was handled before, it is only deleted
+	next, read, err := helper.Read(context.Background(), c, "id1")
+	require.NoError(t, err)
+	assert.True(t, read)
+	assert.Equal(t, "id1", next)
+	assert.Equal(t, 0, received)
+	assert.Equal(t, []string{"receipt"}, service.deleted)
+
+	next, _, err = helper.Read(context.Background(), c, "id0")
+	require.NoError(t, err)
+	assert.Equal(t, "id1", next)
+	assert.Equal(t, 1, received)
+}
+
+func TestReadQueueResumesFromCursor(t *testing.T) {
+	store := stateInMemory.NewInMemoryStateStore(logger.NewLogger("test"))
+	require.NoError(t, store.Init(state.Metadata{}))
+	require.NoError(t, store.Set(&state.SetRequest{Key: "orders", Value: []byte("id7")}))
+
+	mm := new(MockHelper)
+	mm.On("Read", mock.Anything, mock.AnythingOfType("*storagequeues.consumer")).Return(nil)
+	a := AzureStorageQueues{helper: mm, logger: logger.NewLogger("test")}
+	a.SetStateStoreResolver(func(name string) (state.Store, error) {
+		assert.Equal(t, "statestore", name)
+		return store, nil
+	})
+
+	m := bindings.Metadata{}
+	m.Properties = map[string]string{
+		"storageAccessKey": "myKey", "queue": "queue1", "storageAccount": "devstoreaccount1",
+		"cursorStateStore": "statestore", "cursorKey": "orders",
+	}
+	require.NoError(t, a.Init(m))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, a.Read(ctx, func(ctx context.Context, res *bindings.ReadResponse) ([]byte, error) {
+		return nil, nil
+	}))
+
+	select {
+	case cursor := <-mm.cursors:
+		assert.Equal(t, "id7", cursor)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the queue wasn't read")
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+	mdata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+// ErrInjected is returned by the invocations failed on purpose.
+var ErrInjected = errors.New("chaos binding: injected error")
+
+// Binding is an output binding which wraps another one, and injects faults in its invocations
+// to test the resilience of applications.
+type Binding struct {
+	binding  bindings.OutputBinding
+	metadata chaosMetadata
+	logger   logger.Logger
+
+	rand     *rand.Rand
+	randLock sync.Mutex
+}
+
+// chaosMetadata configures the faults. The properties are prefixed to not conflict with those of the wrapped binding.
+type chaosMetadata struct {
+	// Latency added to each invocation
+	Latency time.Duration `mapstructure:"chaosLatency"`
+	// Probability for an invocation to fail, between 0 and 1
+	ErrorRate float64 `mapstructure:"chaosErrorRate"`
+	// Probability for the payload of an invocation to be corrupted, between 0 and 1
+	CorruptionRate float64 `mapstructure:"chaosCorruptionRate"`
+}
+
+// Wrap returns a factory of the bindings created by factory, wrapped to inject faults.
+func Wrap(factory func(logger.Logger) bindings.OutputBinding) func(logger.Logger) bindings.OutputBinding {
+	return func(logger logger.Logger) bindings.OutputBinding {
+		return NewChaosBinding(factory(logger), logger)
+	}
+}
+
+// NewChaosBinding returns a new chaos output binding wrapping binding.
+func NewChaosBinding(binding bindings.OutputBinding, logger logger.Logger) *Binding {
+	return &Binding{
+		binding: binding,
+		logger:  logger,
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())), //nolint:gosec
+	}
+}
+
+// Init parses the faults to inject, and initializes the wrapped binding.
+func (b *Binding) Init(metadata bindings.Metadata) error {
+	m, err := parseMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	b.metadata = m
+
+	return b.binding.Init(metadata)
+}
+
+func parseMetadata(metadata bindings.Metadata) (chaosMetadata, error) {
+	var m chaosMetadata
+	if err := mdata.DecodeMetadata(metadata.Properties, &m); err != nil {
+		return m, fmt.Errorf("chaos binding error: %w", err)
+	}
+	if m.Latency < 0 {
+		return m, errors.New("chaos binding error: chaosLatency must not be negative")
+	}
+	if m.ErrorRate < 0 || m.ErrorRate > 1 {
+		return m, errors.New("chaos binding error: chaosErrorRate must be between 0 and 1")
+	}
+	if m.CorruptionRate < 0 || m.CorruptionRate > 1 {
+		return m, errors.New("chaos binding error: chaosCorruptionRate must be between 0 and 1")
+	}
+
+	return m, nil
+}
+
+// Operations returns the operations of the wrapped binding.
+func (b *Binding) Operations() []bindings.OperationKind {
+	return b.binding.Operations()
+}
+
+// Invoke invokes the wrapped binding after the configured latency, unless an error is injected.
+// The payload passed on may be corrupted.
+func (b *Binding) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	if b.metadata.Latency > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(b.metadata.Latency):
+		}
+	}
+
+	if b.inject(b.metadata.ErrorRate) {
+		b.logger.Debugf("chaos binding: injecting error in %s operation", req.Operation)
+
+		return nil, ErrInjected
+	}
+
+	if len(req.Data) > 0 && b.inject(b.metadata.CorruptionRate) {
+		b.logger.Debugf("chaos binding: corrupting payload of %s operation", req.Operation)
+		corrupted := *req
+		corrupted.Data = b.corrupt(req.Data)
+		req = &corrupted
+	}
+
+	return b.binding.Invoke(ctx, req)
+}
+
+// inject returns true if a fault with the given probability happens.
+func (b *Binding) inject(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+
+	b.randLock.Lock()
+	defer b.randLock.Unlock()
+
+	return b.rand.Float64() < rate
+}
+
+// corrupt returns a copy of data with a random byte altered.
+func (b *Binding) corrupt(data []byte) []byte {
+	b.randLock.Lock()
+	defer b.randLock.Unlock()
+
+	corrupted := make([]byte, len(data))
+	copy(corrupted, data)
+	corrupted[b.rand.Intn(len(corrupted))] ^= byte(1 + b.rand.Intn(255))
+
+	return corrupted
+}
+
+// Ping pings the wrapped binding, if supported.
+func (b *Binding) Ping() error {
+	return bindings.PingOutBinding(b.binding)
+}
+
+// Close closes the wrapped binding, if supported.
+func (b *Binding) Close() error {
+	if closer, ok := b.binding.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
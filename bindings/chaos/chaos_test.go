@@ -0,0 +1,167 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/kit/logger"
+)
+
+type fakeBinding struct {
+	metadata bindings.Metadata
+	requests []*bindings.InvokeRequest
+	closed   bool
+}
+
+func (f *fakeBinding) Init(metadata bindings.Metadata) error {
+	f.metadata = metadata
+
+	return nil
+}
+
+func (f *fakeBinding) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	f.requests = append(f.requests, req)
+
+	return &bindings.InvokeResponse{Data: req.Data}, nil
+}
+
+func (f *fakeBinding) Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{bindings.CreateOperation}
+}
+
+func (f *fakeBinding) Close() error {
+	f.closed = true
+
+	return nil
+}
+
+func newTestBinding(t *testing.T, properties map[string]string) (*Binding, *fakeBinding) {
+	t.Helper()
+
+	fake := &fakeBinding{}
+	b := NewChaosBinding(fake, logger.NewLogger("test"))
+	m := bindings.Metadata{}
+	m.Properties = properties
+	require.NoError(t, b.Init(m))
+
+	return b, fake
+}
+
+func TestParseMetadata(t *testing.T) {
+	t.Run("faults", func(t *testing.T) {
+		m := bindings.Metadata{}
+		m.Properties = map[string]string{
+			"chaosLatency":        "100ms",
+			"chaosErrorRate":      "0.5",
+			"chaosCorruptionRate": "0.1",
+		}
+
+		meta, err := parseMetadata(m)
+
+		require.NoError(t, err)
+		assert.Equal(t, chaosMetadata{Latency: 100 * time.Millisecond, ErrorRate: 0.5, CorruptionRate: 0.1}, meta)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, props := range []map[string]string{
+			{"chaosLatency": "-1s"},
+			{"chaosErrorRate": "2"},
+			{"chaosCorruptionRate": "-0.5"},
+		} {
+			m := bindings.Metadata{}
+			m.Properties = props
+
+			_, err := parseMetadata(m)
+
+			assert.Error(t, err)
+		}
+	})
+}
+
+func TestInvoke(t *testing.T) {
+	req := &bindings.InvokeRequest{Operation: bindings.CreateOperation, Data: []byte("payload")}
+
+	t.Run("passes through", func(t *testing.T) {
+		b, fake := newTestBinding(t, map[string]string{"url": "http://localhost"})
+
+		res, err := b.Invoke(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Equal(t, "payload", string(res.Data))
+		assert.Equal(t, "http://localhost", fake.metadata.Properties["url"])
+		assert.Equal(t, []bindings.OperationKind{bindings.CreateOperation}, b.Operations())
+		assert.NoError(t, b.Close())
+		assert.True(t, fake.closed)
+	})
+
+	t.Run("injects latency", func(t *testing.T) {
+		b, _ := newTestBinding(t, map[string]string{"chaosLatency": "50ms"})
+
+		start := time.Now()
+		_, err := b.Invoke(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	})
+
+	t.Run("latency canceled", func(t *testing.T) {
+		b, fake := newTestBinding(t, map[string]string{"chaosLatency": "1m"})
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := b.Invoke(ctx, req)
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Empty(t, fake.requests)
+	})
+
+	t.Run("injects errors", func(t *testing.T) {
+		b, fake := newTestBinding(t, map[string]string{"chaosErrorRate": "1"})
+
+		_, err := b.Invoke(context.Background(), req)
+
+		assert.ErrorIs(t, err, ErrInjected)
+		assert.Empty(t, fake.requests)
+	})
+
+	t.Run("corrupts payload", func(t *testing.T) {
+		b, fake := newTestBinding(t, map[string]string{"chaosCorruptionRate": "1"})
+
+		res, err := b.Invoke(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Len(t, res.Data, len(req.Data))
+		assert.NotEqual(t, req.Data, res.Data)
+		assert.Equal(t, "payload", string(req.Data))
+		assert.Len(t, fake.requests, 1)
+	})
+}
+
+func TestWrap(t *testing.T) {
+	fake := &fakeBinding{}
+	factory := Wrap(func(logger.Logger) bindings.OutputBinding {
+		return fake
+	})
+
+	b := factory(logger.NewLogger("test"))
+
+	assert.Equal(t, fake, b.(*Binding).binding)
+}
@@ -0,0 +1,863 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bigquery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/google/uuid"
+	bigquery "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/googleapi"
+
+	"github.com/dapr/components-contrib/bindings"
+	gcpauth "github.com/dapr/components-contrib/internal/authentication/gcp"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// loadOperation loads the request data in the table with a load job.
+	loadOperation bindings.OperationKind = "load"
+	// queryOperation runs the query of the "sql" metadata with the named parameters of the request data.
+	queryOperation bindings.OperationKind = "query"
+
+	// Request metadata keys; dataset, table and insertIdField override the values of the component metadata.
+	metadataKeyDataset          = "dataset"
+	metadataKeyTable            = "table"
+	metadataKeyInsertIDField    = "insertIdField"
+	metadataKeySQL              = "sql"
+	metadataKeySourceFormat     = "sourceFormat"
+	metadataKeyWriteDisposition = "writeDisposition"
+	metadataKeyAutodetect       = "autodetect"
+
+	// Response metadata keys.
+	metadataKeyJobID      = "jobId"
+	metadataKeyTotalRows  = "totalRows"
+	metadataKeyOutputRows = "outputRows"
+
+	defaultSourceFormat     = "NEWLINE_DELIMITED_JSON"
+	defaultWriteDisposition = "WRITE_APPEND"
+
+	jobStateDone        = "DONE"
+	defaultPollInterval = time.Second
+	queryTimeout        = 10 * time.Second
+	// The streaming inserts in a new table fail until the creation of the table is propagated, which can take minutes.
+	tableCreationTimeout = 5 * time.Minute
+)
+
+// BigQuery is an output binding inserting rows in, loading data in, and querying, GCP BigQuery tables.
+type BigQuery struct {
+	metadata *bigQueryMetadata
+	service  *bigquery.Service
+	// Interval between the requests of the state of the jobs, and initial interval between the inserts in new tables.
+	pollInterval time.Duration
+
+	logger logger.Logger
+}
+
+type bigQueryMetadata struct {
+	Type                string `json:"type"`
+	ProjectID           string `json:"project_id"`
+	PrivateKeyID        string `json:"private_key_id"`
+	PrivateKey          string `json:"private_key"`
+	ClientEmail         string `json:"client_email"`
+	ClientID            string `json:"client_id"`
+	AuthURI             string `json:"auth_uri"`
+	TokenURI            string `json:"token_uri"`
+	AuthProviderCertURL string `json:"auth_provider_x509_cert_url"`
+	ClientCertURL       string `json:"client_x509_cert_url"`
+	Dataset             string `json:"dataset"`
+	Table               string `json:"table"`
+	// Location of the jobs, e.g. EU; by default the location of the dataset.
+	Location string `json:"location"`
+	// Field of the rows inserted holding the insert ID deduplicating the rows; random insert IDs are used if empty.
+	InsertIDField string `json:"insertIdField"`
+	// Create the missing tables of the streaming inserts, with a schema detected from the JSON rows.
+	AutodetectSchema bool `json:"autodetectSchema,string"`
+}
+
+// NewBigQuery returns a new GCP BigQuery output binding.
+func NewBigQuery(logger logger.Logger) bindings.OutputBinding {
+	return &BigQuery{
+		pollInterval: defaultPollInterval,
+		logger:       logger,
+	}
+}
+
+// Init parses the metadata and creates the BigQuery service.
+func (b *BigQuery) Init(metadata bindings.Metadata) error {
+	m, credentials, err := b.parseMetadata(metadata)
+	if err != nil {
+		return err
+	}
+
+	key, err := gcpauth.KeyFromJSON(credentials)
+	if err != nil {
+		return err
+	}
+	clientOptions, err := gcpauth.ClientOptions(key)
+	if err != nil {
+		return fmt.Errorf("bigquery binding error: %w", err)
+	}
+
+	service, err := bigquery.NewService(context.Background(), clientOptions...)
+	if err != nil {
+		return fmt.Errorf("bigquery binding error: error creating the service: %w", err)
+	}
+
+	b.metadata = m
+	b.service = service
+
+	return nil
+}
+
+func (b *BigQuery) parseMetadata(metadata bindings.Metadata) (*bigQueryMetadata, []byte, error) {
+	credentials, err := json.Marshal(metadata.Properties)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var m bigQueryMetadata
+	err = json.Unmarshal(credentials, &m)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if m.ProjectID == "" {
+		return nil, nil, errors.New("bigquery binding error: project_id is required")
+	}
+
+	return &m, credentials, nil
+}
+
+func (b *BigQuery) Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{
+		bindings.CreateOperation,
+		loadOperation,
+		queryOperation,
+	}
+}
+
+func (b *BigQuery) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	switch req.Operation {
+	case bindings.CreateOperation:
+		return nil, b.insert(ctx, req)
+	case loadOperation:
+		return b.load(ctx, req)
+	case queryOperation:
+		return b.query(ctx, req)
+	default:
+		return nil, fmt.Errorf("bigquery binding error: unsupported operation %s", req.Operation)
+	}
+}
+
+// insert streams the rows of the request data, a JSON object or array of objects, in the table.
+// The insert ID of each row is the value of its field of the "insertIdField" metadata, or a random ID.
+func (b *BigQuery) insert(ctx context.Context, req *bindings.InvokeRequest) error {
+	dataset, table, err := b.tableReference(req)
+	if err != nil {
+		return err
+	}
+
+	rows, err := parseRows(req.Data)
+	if err != nil {
+		return err
+	}
+
+	insertIDField := req.GetMetadataOrDefault(metadataKeyInsertIDField, b.metadata.InsertIDField)
+	insertRequest := &bigquery.TableDataInsertAllRequest{
+		Rows: make([]*bigquery.TableDataInsertAllRequestRows, len(rows)),
+	}
+	for i, row := range rows {
+		insertID := uuid.New().String()
+		if insertIDField != "" {
+			val, ok := row[insertIDField]
+			if !ok || val == nil {
+				return fmt.Errorf("bigquery binding error: field %s of row %d is required as insert ID", insertIDField, i)
+			}
+			insertID, err = insertIDValue(val)
+			if err != nil {
+				return fmt.Errorf("bigquery binding error: field %s of row %d: %w", insertIDField, i, err)
+			}
+		}
+		insertRequest.Rows[i] = &bigquery.TableDataInsertAllRequestRows{
+			InsertId: insertID,
+			Json:     row,
+		}
+	}
+
+	autodetect := b.metadata.AutodetectSchema
+	if val, ok := req.Metadata[metadataKeyAutodetect]; ok && val != "" {
+		autodetect, err = strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("bigquery binding error: invalid %s: %s", metadataKeyAutodetect, val)
+		}
+	}
+
+	res, err := b.service.Tabledata.InsertAll(b.metadata.ProjectID, dataset, table, insertRequest).Context(ctx).Do()
+	if err != nil && autodetect && isHTTPError(err, http.StatusNotFound) {
+		err = b.createTable(ctx, dataset, table, rows)
+		if err != nil {
+			return err
+		}
+		res, err = b.insertInNewTable(ctx, dataset, table, insertRequest)
+	}
+	if err != nil {
+		return fmt.Errorf("bigquery binding error: error inserting rows in table %s: %w", table, err)
+	}
+	if len(res.InsertErrors) > 0 {
+		errs := make([]string, 0, len(res.InsertErrors))
+		for _, insertErr := range res.InsertErrors {
+			errs = append(errs, fmt.Sprintf("row %d: %s", insertErr.Index, errorMessages(insertErr.Errors)))
+		}
+
+		return fmt.Errorf("bigquery binding error: error inserting rows in table %s: %s", table, strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// insertInNewTable inserts the rows in a table which has just been created, retrying while the table is not found.
+func (b *BigQuery) insertInNewTable(ctx context.Context, dataset string, table string, insertRequest *bigquery.TableDataInsertAllRequest) (*bigquery.TableDataInsertAllResponse, error) {
+	bo := backoff.NewExponentialBackOff()
+	bo.InitialInterval = b.pollInterval
+	bo.MaxElapsedTime = tableCreationTimeout
+
+	return backoff.RetryNotifyWithData(func() (*bigquery.TableDataInsertAllResponse, error) {
+		res, err := b.service.Tabledata.InsertAll(b.metadata.ProjectID, dataset, table, insertRequest).Context(ctx).Do()
+		if err != nil && !isHTTPError(err, http.StatusNotFound) {
+			return nil, backoff.Permanent(err)
+		}
+
+		return res, err
+	}, backoff.WithContext(bo, ctx), func(err error, d time.Duration) {
+		b.logger.Debugf("table %s is not available yet, retrying the insert in %s", table, d)
+	})
+}
+
+// createTable creates the table with the schema detected from the rows. The table may have been created concurrently.
+func (b *BigQuery) createTable(ctx context.Context, dataset string, table string, rows []map[string]bigquery.JsonValue) error {
+	schema, err := detectSchema(rows)
+	if err != nil {
+		return fmt.Errorf("bigquery binding error: error detecting the schema of table %s: %w", table, err)
+	}
+
+	b.logger.Infof("creating table %s of dataset %s with the schema detected from the rows", table, dataset)
+	_, err = b.service.Tables.Insert(b.metadata.ProjectID, dataset, &bigquery.Table{
+		TableReference: &bigquery.TableReference{
+			ProjectId: b.metadata.ProjectID,
+			DatasetId: dataset,
+			TableId:   table,
+		},
+		Schema: schema,
+	}).Context(ctx).Do()
+	if err != nil && !isHTTPError(err, http.StatusConflict) {
+		return fmt.Errorf("bigquery binding error: error creating table %s: %w", table, err)
+	}
+
+	return nil
+}
+
+// load loads the request data in the table with a load job, in the format of the "sourceFormat" metadata and with the
+// write disposition of the "writeDisposition" metadata, and waits for its completion.
+func (b *BigQuery) load(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	if len(req.Data) == 0 {
+		return nil, errors.New("bigquery binding error: no data to load")
+	}
+
+	dataset, table, err := b.tableReference(req)
+	if err != nil {
+		return nil, err
+	}
+
+	autodetect := true
+	if val, ok := req.Metadata[metadataKeyAutodetect]; ok && val != "" {
+		autodetect, err = strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("bigquery binding error: invalid %s: %s", metadataKeyAutodetect, val)
+		}
+	}
+
+	job := &bigquery.Job{
+		JobReference: &bigquery.JobReference{
+			ProjectId: b.metadata.ProjectID,
+			Location:  b.metadata.Location,
+		},
+		Configuration: &bigquery.JobConfiguration{
+			Load: &bigquery.JobConfigurationLoad{
+				DestinationTable: &bigquery.TableReference{
+					ProjectId: b.metadata.ProjectID,
+					DatasetId: dataset,
+					TableId:   table,
+				},
+				SourceFormat:     strings.ToUpper(req.GetMetadataOrDefault(metadataKeySourceFormat, defaultSourceFormat)),
+				WriteDisposition: strings.ToUpper(req.GetMetadataOrDefault(metadataKeyWriteDisposition, defaultWriteDisposition)),
+				Autodetect:       autodetect,
+			},
+		},
+	}
+	job, err = b.service.Jobs.Insert(b.metadata.ProjectID, job).Media(bytes.NewReader(req.Data)).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("bigquery binding error: error creating load job: %w", err)
+	}
+
+	job, err = b.waitForJob(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &bindings.InvokeResponse{
+		Metadata: map[string]string{
+			metadataKeyJobID: job.JobReference.JobId,
+		},
+	}
+	if job.Statistics != nil && job.Statistics.Load != nil {
+		res.Metadata[metadataKeyOutputRows] = strconv.FormatInt(job.Statistics.Load.OutputRows, 10)
+	}
+
+	return res, nil
+}
+
+// waitForJob polls the state of the job until done, returning the error of the job if any.
+func (b *BigQuery) waitForJob(ctx context.Context, job *bigquery.Job) (*bigquery.Job, error) {
+	var err error
+	for job.Status == nil || job.Status.State != jobStateDone {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("bigquery binding error: error waiting for job %s: %w", job.JobReference.JobId, ctx.Err())
+		case <-time.After(b.pollInterval):
+		}
+
+		job, err = b.service.Jobs.Get(job.JobReference.ProjectId, job.JobReference.JobId).
+			Location(job.JobReference.Location).
+			Context(ctx).
+			Do()
+		if err != nil {
+			return nil, fmt.Errorf("bigquery binding error: error getting job: %w", err)
+		}
+	}
+
+	if job.Status.ErrorResult != nil {
+		return nil, fmt.Errorf("bigquery binding error: job %s failed: %s", job.JobReference.JobId, errorMessages(append([]*bigquery.ErrorProto{job.Status.ErrorResult}, job.Status.Errors...)))
+	}
+
+	return job, nil
+}
+
+// query runs the standard SQL query of the "sql" metadata, with the named parameters of the request data if any, and
+// returns the rows of the result as a JSON array of objects.
+func (b *BigQuery) query(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	sql := req.Metadata[metadataKeySQL]
+	if sql == "" {
+		return nil, fmt.Errorf("bigquery binding error: required metadata not set: %s", metadataKeySQL)
+	}
+
+	params, err := parseQueryParameters(req.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	useLegacySQL := false
+	queryRequest := &bigquery.QueryRequest{
+		Query:           sql,
+		UseLegacySql:    &useLegacySQL,
+		ParameterMode:   "NAMED",
+		QueryParameters: params,
+		Location:        b.metadata.Location,
+		TimeoutMs:       queryTimeout.Milliseconds(),
+	}
+	if dataset := req.GetMetadataOrDefault(metadataKeyDataset, b.metadata.Dataset); dataset != "" {
+		queryRequest.DefaultDataset = &bigquery.DatasetReference{
+			ProjectId: b.metadata.ProjectID,
+			DatasetId: dataset,
+		}
+	}
+
+	res, err := b.service.Jobs.Query(b.metadata.ProjectID, queryRequest).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("bigquery binding error: error running query: %w", err)
+	}
+	if len(res.Errors) > 0 {
+		return nil, fmt.Errorf("bigquery binding error: error running query: %s", errorMessages(res.Errors))
+	}
+
+	result := &bigquery.GetQueryResultsResponse{
+		JobComplete: res.JobComplete,
+		PageToken:   res.PageToken,
+		Rows:        res.Rows,
+		Schema:      res.Schema,
+		TotalRows:   res.TotalRows,
+	}
+	rows := make([]map[string]any, 0, len(res.Rows))
+	// Waits for the completion of the query and reads all the pages of the result
+	for {
+		if result.JobComplete {
+			converted, err := convertRows(result.Schema, result.Rows)
+			if err != nil {
+				return nil, err
+			}
+			rows = append(rows, converted...)
+			if result.PageToken == "" {
+				break
+			}
+		}
+
+		call := b.service.Jobs.GetQueryResults(b.metadata.ProjectID, res.JobReference.JobId).
+			Location(res.JobReference.Location).
+			TimeoutMs(queryTimeout.Milliseconds()).
+			Context(ctx)
+		if result.JobComplete {
+			call = call.PageToken(result.PageToken)
+		}
+		result, err = call.Do()
+		if err != nil {
+			return nil, fmt.Errorf("bigquery binding error: error getting query results: %w", err)
+		}
+		if len(result.Errors) > 0 {
+			return nil, fmt.Errorf("bigquery binding error: error running query: %s", errorMessages(result.Errors))
+		}
+	}
+
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return nil, fmt.Errorf("bigquery binding error: error marshalling query results: %w", err)
+	}
+
+	return &bindings.InvokeResponse{
+		Data: data,
+		Metadata: map[string]string{
+			metadataKeyJobID:     res.JobReference.JobId,
+			metadataKeyTotalRows: strconv.FormatUint(result.TotalRows, 10),
+		},
+	}, nil
+}
+
+// tableReference returns the dataset and the table of the request.
+func (b *BigQuery) tableReference(req *bindings.InvokeRequest) (string, string, error) {
+	dataset := req.GetMetadataOrDefault(metadataKeyDataset, b.metadata.Dataset)
+	if dataset == "" {
+		return "", "", errors.New("bigquery binding error: dataset property not supplied in configuration- or request-metadata")
+	}
+	table := req.GetMetadataOrDefault(metadataKeyTable, b.metadata.Table)
+	if table == "" {
+		return "", "", errors.New("bigquery binding error: table property not supplied in configuration- or request-metadata")
+	}
+
+	return dataset, table, nil
+}
+
+// insertIDValue returns the text of the string or number used as insert ID.
+func insertIDValue(val any) (string, error) {
+	switch v := val.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	default:
+		return "", fmt.Errorf("insert ID must be a string or a number, not %T", val)
+	}
+}
+
+// parseRows parses a JSON object or array of objects. The numbers are kept as json.Number, so that they are sent with
+// their original precision.
+func parseRows(data []byte) ([]map[string]bigquery.JsonValue, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, errors.New("bigquery binding error: no rows to insert")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var rows []map[string]bigquery.JsonValue
+	var err error
+	if data[0] == '[' {
+		err = decoder.Decode(&rows)
+	} else {
+		var row map[string]bigquery.JsonValue
+		err = decoder.Decode(&row)
+		rows = append(rows, row)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("bigquery binding error: rows must be a JSON object or array of objects: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("bigquery binding error: no rows to insert")
+	}
+
+	return rows, nil
+}
+
+// detectSchema returns the schema of the fields of the rows, all nullable or repeated. The types are inferred from the
+// JSON values: strings, integers, floats, booleans, objects as records, and arrays of those. Fields with both integers
+// and floats are floats.
+func detectSchema(rows []map[string]bigquery.JsonValue) (*bigquery.TableSchema, error) {
+	var fields []*bigquery.TableFieldSchema
+	for _, row := range rows {
+		var err error
+		fields, err = mergeFields(fields, row)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("the rows have no field")
+	}
+
+	return &bigquery.TableSchema{Fields: fields}, nil
+}
+
+// mergeFields adds the fields of the object to the fields detected so far, in the order of their names.
+func mergeFields(fields []*bigquery.TableFieldSchema, obj map[string]bigquery.JsonValue) ([]*bigquery.TableFieldSchema, error) {
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		field, err := detectField(name, obj[name])
+		if err != nil {
+			return nil, err
+		}
+		if field == nil {
+			// Nulls and empty arrays don't tell the type
+			continue
+		}
+		fields, err = addField(fields, field)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return fields, nil
+}
+
+// addField adds the field to the fields, merging it with the field of the same name if any.
+func addField(fields []*bigquery.TableFieldSchema, field *bigquery.TableFieldSchema) ([]*bigquery.TableFieldSchema, error) {
+	for _, f := range fields {
+		if f.Name == field.Name {
+			return fields, mergeField(f, field)
+		}
+	}
+
+	return append(fields, field), nil
+}
+
+// mergeField merges the type of the same field found in another row in the existing field.
+func mergeField(existing *bigquery.TableFieldSchema, field *bigquery.TableFieldSchema) error {
+	if existing.Mode != field.Mode {
+		return fmt.Errorf("field %s is both an array and a single value", field.Name)
+	}
+
+	switch {
+	case existing.Type == field.Type:
+	case existing.Type == "INTEGER" && (field.Type == "FLOAT" || field.Type == "BIGNUMERIC"):
+		existing.Type = field.Type
+	case existing.Type == "BIGNUMERIC" && field.Type == "FLOAT":
+		existing.Type = "FLOAT"
+	case (existing.Type == "FLOAT" || existing.Type == "BIGNUMERIC") && field.Type == "INTEGER":
+	case existing.Type == "FLOAT" && field.Type == "BIGNUMERIC":
+	default:
+		return fmt.Errorf("field %s has values of types %s and %s", field.Name, existing.Type, field.Type)
+	}
+
+	if existing.Type == "RECORD" {
+		var err error
+		for _, f := range field.Fields {
+			existing.Fields, err = addField(existing.Fields, f)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// detectField returns the schema of the field with the value, or nil if the type of the value is unknown.
+func detectField(name string, val any) (*bigquery.TableFieldSchema, error) {
+	field := &bigquery.TableFieldSchema{Name: name, Mode: "NULLABLE"}
+	if arr, ok := val.([]any); ok {
+		field.Mode = "REPEATED"
+		for _, elem := range arr {
+			if _, nested := elem.([]any); nested {
+				return nil, fmt.Errorf("field %s is an array of arrays", name)
+			}
+			elemField, err := detectField(name, elem)
+			if err != nil {
+				return nil, err
+			}
+			if elemField == nil {
+				continue
+			}
+			if field.Type == "" {
+				field.Type, field.Fields = elemField.Type, elemField.Fields
+				continue
+			}
+			elemField.Mode = field.Mode
+			if err = mergeField(field, elemField); err != nil {
+				return nil, err
+			}
+		}
+		if field.Type == "" {
+			return nil, nil
+		}
+
+		return field, nil
+	}
+
+	switch v := val.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		field.Type = "STRING"
+	case bool:
+		field.Type = "BOOLEAN"
+	case json.Number:
+		// The type is the one of the number as written: 1.0 is a float, and integers out of the range of INTEGER are
+		// BIGNUMERIC
+		switch {
+		case strings.ContainsAny(v.String(), ".eE"):
+			field.Type = "FLOAT"
+		default:
+			if _, err := v.Int64(); err != nil {
+				field.Type = "BIGNUMERIC"
+			} else {
+				field.Type = "INTEGER"
+			}
+		}
+	case map[string]any:
+		obj := make(map[string]bigquery.JsonValue, len(v))
+		for k, e := range v {
+			obj[k] = e
+		}
+		fields, err := mergeFields(nil, obj)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) == 0 {
+			return nil, nil
+		}
+		field.Type = "RECORD"
+		field.Fields = fields
+	default:
+		return nil, fmt.Errorf("field %s has a value of unsupported type %T", name, val)
+	}
+
+	return field, nil
+}
+
+// isHTTPError returns true if the error is a response of the API with the status code.
+func isHTTPError(err error, code int) bool {
+	var apiErr *googleapi.Error
+
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// parseQueryParameters parses the named parameters of a query from a JSON object; the types of the parameters are
+// inferred from the JSON values: strings, integers, floats, booleans and arrays of those.
+func parseQueryParameters(data []byte) ([]*bigquery.QueryParameter, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	var values map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&values)
+	if err != nil {
+		return nil, fmt.Errorf("bigquery binding error: query parameters must be a JSON object: %w", err)
+	}
+
+	params := make([]*bigquery.QueryParameter, 0, len(values))
+	for name, val := range values {
+		paramType, paramValue, err := queryParameter(val)
+		if err != nil {
+			return nil, fmt.Errorf("bigquery binding error: invalid query parameter %s: %w", name, err)
+		}
+		params = append(params, &bigquery.QueryParameter{
+			Name:           name,
+			ParameterType:  paramType,
+			ParameterValue: paramValue,
+		})
+	}
+
+	return params, nil
+}
+
+func queryParameter(val any) (*bigquery.QueryParameterType, *bigquery.QueryParameterValue, error) {
+	switch v := val.(type) {
+	case string:
+		return &bigquery.QueryParameterType{Type: "STRING"}, &bigquery.QueryParameterValue{Value: v}, nil
+	case bool:
+		return &bigquery.QueryParameterType{Type: "BOOL"}, &bigquery.QueryParameterValue{Value: strconv.FormatBool(v)}, nil
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return &bigquery.QueryParameterType{Type: "INT64"}, &bigquery.QueryParameterValue{Value: v.String()}, nil
+		}
+		return &bigquery.QueryParameterType{Type: "FLOAT64"}, &bigquery.QueryParameterValue{Value: v.String()}, nil
+	case []any:
+		if len(v) == 0 {
+			return nil, nil, errors.New("the type of empty arrays cannot be inferred")
+		}
+		arrayType := &bigquery.QueryParameterType{Type: "ARRAY"}
+		arrayValue := &bigquery.QueryParameterValue{ArrayValues: make([]*bigquery.QueryParameterValue, len(v))}
+		for i, item := range v {
+			if _, ok := item.([]any); ok {
+				return nil, nil, errors.New("arrays of arrays are not supported")
+			}
+			itemType, itemValue, err := queryParameter(item)
+			if err != nil {
+				return nil, nil, err
+			}
+			if arrayType.ArrayType == nil {
+				arrayType.ArrayType = itemType
+			} else if arrayType.ArrayType.Type != itemType.Type {
+				return nil, nil, errors.New("arrays must have items of the same type")
+			}
+			arrayValue.ArrayValues[i] = itemValue
+		}
+		return arrayType, arrayValue, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported value %v", val)
+	}
+}
+
+// convertRows converts the rows of a query result to objects keyed by the names of the columns.
+// Integers, floats and booleans are converted to JSON numbers and booleans, records to objects and repeated fields
+// to arrays; other values, e.g. timestamps and numerics, are kept as returned by BigQuery, as strings.
+func convertRows(schema *bigquery.TableSchema, rows []*bigquery.TableRow) ([]map[string]any, error) {
+	if schema == nil {
+		return nil, nil
+	}
+
+	converted := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		cells := make([]any, len(row.F))
+		for j, cell := range row.F {
+			cells[j] = cell.V
+		}
+		record, err := convertRecord(schema.Fields, cells)
+		if err != nil {
+			return nil, err
+		}
+		converted[i] = record
+	}
+
+	return converted, nil
+}
+
+func convertRecord(fields []*bigquery.TableFieldSchema, cells []any) (map[string]any, error) {
+	if len(cells) != len(fields) {
+		return nil, fmt.Errorf("bigquery binding error: row has %d cells for %d fields", len(cells), len(fields))
+	}
+
+	record := make(map[string]any, len(fields))
+	for i, field := range fields {
+		val, err := convertValue(field, cells[i], field.Mode == "REPEATED")
+		if err != nil {
+			return nil, err
+		}
+		record[field.Name] = val
+	}
+
+	return record, nil
+}
+
+func convertValue(field *bigquery.TableFieldSchema, val any, repeated bool) (any, error) {
+	if val == nil {
+		return nil, nil
+	}
+
+	if repeated {
+		items, ok := val.([]any)
+		if !ok {
+			return nil, fmt.Errorf("bigquery binding error: invalid value of repeated field %s", field.Name)
+		}
+		converted := make([]any, len(items))
+		for i, item := range items {
+			var err error
+			converted[i], err = convertValue(field, cellValue(item), false)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return converted, nil
+	}
+
+	if field.Type == "RECORD" || field.Type == "STRUCT" {
+		record, ok := val.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("bigquery binding error: invalid value of record field %s", field.Name)
+		}
+		items, _ := record["f"].([]any)
+		cells := make([]any, len(items))
+		for i, item := range items {
+			cells[i] = cellValue(item)
+		}
+		return convertRecord(field.Fields, cells)
+	}
+
+	s, ok := val.(string)
+	if !ok {
+		return val, nil
+	}
+	var (
+		converted any
+		err       error
+	)
+	switch field.Type {
+	case "INTEGER", "INT64":
+		converted, err = strconv.ParseInt(s, 10, 64)
+	case "FLOAT", "FLOAT64":
+		converted, err = strconv.ParseFloat(s, 64)
+	case "BOOLEAN", "BOOL":
+		converted, err = strconv.ParseBool(s)
+	default:
+		converted = s
+	}
+	if err != nil {
+		return nil, fmt.Errorf("bigquery binding error: invalid value of field %s: %w", field.Name, err)
+	}
+
+	return converted, nil
+}
+
+// cellValue returns the value of a cell of a record or repeated field, encoded as {"v": value}.
+func cellValue(cell any) any {
+	if m, ok := cell.(map[string]any); ok {
+		return m["v"]
+	}
+
+	return cell
+}
+
+func errorMessages(errs []*bigquery.ErrorProto) string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, fmt.Sprintf("%s: %s", err.Reason, err.Message))
+	}
+
+	return strings.Join(messages, ", ")
+}
@@ -0,0 +1,529 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/option"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+func TestParseMetadata(t *testing.T) {
+	b := NewBigQuery(logger.NewLogger("test")).(*BigQuery)
+
+	t.Run("parses the metadata", func(t *testing.T) {
+		m, _, err := b.parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"project_id":       "myproject",
+			"dataset":          "mydataset",
+			"table":            "mytable",
+			"location":         "EU",
+			"insertIdField":    "id",
+			"autodetectSchema": "true",
+		}}})
+
+		require.NoError(t, err)
+		assert.Equal(t, "myproject", m.ProjectID)
+		assert.Equal(t, "mydataset", m.Dataset)
+		assert.Equal(t, "mytable", m.Table)
+		assert.Equal(t, "EU", m.Location)
+		assert.Equal(t, "id", m.InsertIDField)
+		assert.True(t, m.AutodetectSchema)
+	})
+
+	t.Run("missing project", func(t *testing.T) {
+		_, _, err := b.parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{"dataset": "mydataset"}}})
+
+		assert.ErrorContains(t, err, "project_id is required")
+	})
+}
+
+// newTestBigQuery returns a binding whose requests are served by handler.
+func newTestBigQuery(t *testing.T, handler http.HandlerFunc) *BigQuery {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	service, err := bigquery.NewService(context.Background(), option.WithEndpoint(server.URL+"/"), option.WithHTTPClient(server.Client()))
+	require.NoError(t, err)
+
+	return &BigQuery{
+		metadata: &bigQueryMetadata{
+			ProjectID: "myproject",
+			Dataset:   "mydataset",
+			Table:     "mytable",
+		},
+		service:      service,
+		pollInterval: time.Millisecond,
+		logger:       logger.NewLogger("test"),
+	}
+}
+
+func TestInsert(t *testing.T) {
+	t.Run("inserts the rows with the insert IDs of the field", func(t *testing.T) {
+		var (
+			path     string
+			received bigquery.TableDataInsertAllRequest
+		)
+		b := newTestBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+			w.Write([]byte(`{}`))
+		})
+
+		_, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      []byte(`[{"id": "a", "value": 1}, {"id": "b", "value": 2}]`),
+			Metadata:  map[string]string{"table": "othertable", "insertIdField": "id"},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "/projects/myproject/datasets/mydataset/tables/othertable/insertAll", path)
+		require.Len(t, received.Rows, 2)
+		assert.Equal(t, "a", received.Rows[0].InsertId)
+		assert.Equal(t, "b", received.Rows[1].InsertId)
+		assert.Equal(t, map[string]bigquery.JsonValue{"id": "b", "value": float64(2)}, received.Rows[1].Json)
+	})
+
+	t.Run("keeps the precision of the numbers", func(t *testing.T) {
+		var body string
+		b := newTestBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+			data, _ := io.ReadAll(r.Body)
+			body = string(data)
+			w.Write([]byte(`{}`))
+		})
+
+		_, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      []byte(`{"id": 12345678901234567890, "amount": 0.1000000000000000055}`),
+			Metadata:  map[string]string{"insertIdField": "id"},
+		})
+
+		require.NoError(t, err)
+		assert.Contains(t, body, `"insertId":"12345678901234567890"`)
+		assert.Contains(t, body, `"amount":0.1000000000000000055`)
+		assert.Contains(t, body, `"id":12345678901234567890`)
+	})
+
+	t.Run("inserts a row with a random insert ID", func(t *testing.T) {
+		var received bigquery.TableDataInsertAllRequest
+		b := newTestBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+			w.Write([]byte(`{}`))
+		})
+
+		_, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      []byte(`{"value": 1}`),
+		})
+
+		require.NoError(t, err)
+		require.Len(t, received.Rows, 1)
+		assert.NotEmpty(t, received.Rows[0].InsertId)
+	})
+
+	t.Run("returns the insert errors", func(t *testing.T) {
+		b := newTestBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"insertErrors":[{"index":1,"errors":[{"reason":"invalid","message":"no such field"}]}]}`))
+		})
+
+		_, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      []byte(`[{"value": 1}, {"other": 2}]`),
+		})
+
+		assert.ErrorContains(t, err, "row 1: invalid: no such field")
+	})
+
+	t.Run("returns error for missing insert ID", func(t *testing.T) {
+		b := newTestBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("unexpected request %s", r.URL)
+		})
+
+		_, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      []byte(`[{"value": 1}]`),
+			Metadata:  map[string]string{"insertIdField": "id"},
+		})
+
+		assert.ErrorContains(t, err, "field id of row 0 is required")
+	})
+
+	t.Run("returns error for invalid rows", func(t *testing.T) {
+		b := newTestBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("unexpected request %s", r.URL)
+		})
+
+		_, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      []byte(`"value"`),
+		})
+
+		assert.ErrorContains(t, err, "rows must be a JSON object or array of objects")
+	})
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("loads the data and waits for the job", func(t *testing.T) {
+		var (
+			uploaded string
+			gets     int
+		)
+		b := newTestBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Path == "/upload/bigquery/v2/projects/myproject/jobs":
+				b, _ := io.ReadAll(r.Body)
+				uploaded = string(b)
+				w.Write([]byte(`{"jobReference":{"projectId":"myproject","jobId":"job1"},"status":{"state":"RUNNING"}}`))
+			case r.URL.Path == "/projects/myproject/jobs/job1":
+				gets++
+				if gets < 2 {
+					w.Write([]byte(`{"jobReference":{"projectId":"myproject","jobId":"job1"},"status":{"state":"RUNNING"}}`))
+					return
+				}
+				w.Write([]byte(`{"jobReference":{"projectId":"myproject","jobId":"job1"},"status":{"state":"DONE"},"statistics":{"load":{"outputRows":"2"}}}`))
+			default:
+				t.Errorf("unexpected request %s", r.URL)
+				w.WriteHeader(http.StatusNotFound)
+			}
+		})
+
+		res, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: loadOperation,
+			Data:      []byte("a,1\nb,2\n"),
+			Metadata:  map[string]string{"sourceFormat": "csv", "writeDisposition": "write_truncate"},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, gets)
+		assert.Equal(t, "job1", res.Metadata["jobId"])
+		assert.Equal(t, "2", res.Metadata["outputRows"])
+		// The job configuration and the data are uploaded in a multipart request
+		assert.Contains(t, uploaded, `"sourceFormat":"CSV"`)
+		assert.Contains(t, uploaded, `"writeDisposition":"WRITE_TRUNCATE"`)
+		assert.Contains(t, uploaded, `"autodetect":true`)
+		assert.Contains(t, uploaded, "a,1\nb,2\n")
+	})
+
+	t.Run("returns the error of the job", func(t *testing.T) {
+		b := newTestBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"jobReference":{"projectId":"myproject","jobId":"job1"},"status":{"state":"DONE","errorResult":{"reason":"invalid","message":"bad CSV"}}}`))
+		})
+
+		_, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: loadOperation,
+			Data:      []byte("a,1"),
+		})
+
+		assert.ErrorContains(t, err, "job job1 failed: invalid: bad CSV")
+	})
+
+	t.Run("returns error for invalid autodetect", func(t *testing.T) {
+		b := newTestBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("unexpected request %s", r.URL)
+		})
+
+		_, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: loadOperation,
+			Data:      []byte("a,1"),
+			Metadata:  map[string]string{"autodetect": "maybe"},
+		})
+
+		assert.ErrorContains(t, err, "invalid autodetect")
+	})
+}
+
+func TestQuery(t *testing.T) {
+	schema := `"schema":{"fields":[{"name":"name","type":"STRING"},{"name":"count","type":"INTEGER"},{"name":"tags","type":"STRING","mode":"REPEATED"},` +
+		`{"name":"location","type":"RECORD","fields":[{"name":"lat","type":"FLOAT"},{"name":"active","type":"BOOLEAN"}]}]}`
+
+	t.Run("returns the rows of all the pages", func(t *testing.T) {
+		var received bigquery.QueryRequest
+		b := newTestBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/projects/myproject/queries":
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+				w.Write([]byte(`{"jobComplete":true,"jobReference":{"projectId":"myproject","jobId":"job1"},"pageToken":"page2","totalRows":"2",` + schema +
+					`,"rows":[{"f":[{"v":"a"},{"v":"1"},{"v":[{"v":"x"},{"v":"y"}]},{"v":{"f":[{"v":"1.5"},{"v":"true"}]}}]}]}`))
+			case "/projects/myproject/queries/job1":
+				assert.Equal(t, "page2", r.URL.Query().Get("pageToken"))
+				w.Write([]byte(`{"jobComplete":true,"jobReference":{"projectId":"myproject","jobId":"job1"},"totalRows":"2",` + schema +
+					`,"rows":[{"f":[{"v":"b"},{"v":null},{"v":[]},{"v":null}]}]}`))
+			default:
+				t.Errorf("unexpected request %s", r.URL)
+				w.WriteHeader(http.StatusNotFound)
+			}
+		})
+
+		res, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: queryOperation,
+			Data:      []byte(`{"name": "a", "min": 1, "tags": ["x", "y"]}`),
+			Metadata:  map[string]string{"sql": "SELECT * FROM events WHERE name = @name AND count >= @min"},
+		})
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"name":"a","count":1,"tags":["x","y"],"location":{"lat":1.5,"active":true}},{"name":"b","count":null,"tags":[],"location":null}]`, string(res.Data))
+		assert.Equal(t, "job1", res.Metadata["jobId"])
+		assert.Equal(t, "2", res.Metadata["totalRows"])
+
+		assert.Equal(t, "NAMED", received.ParameterMode)
+		assert.Equal(t, "mydataset", received.DefaultDataset.DatasetId)
+		require.NotNil(t, received.UseLegacySql)
+		assert.False(t, *received.UseLegacySql)
+		params := map[string]*bigquery.QueryParameter{}
+		for _, p := range received.QueryParameters {
+			params[p.Name] = p
+		}
+		assert.Equal(t, "STRING", params["name"].ParameterType.Type)
+		assert.Equal(t, "INT64", params["min"].ParameterType.Type)
+		assert.Equal(t, "1", params["min"].ParameterValue.Value)
+		assert.Equal(t, "ARRAY", params["tags"].ParameterType.Type)
+		assert.Equal(t, "STRING", params["tags"].ParameterType.ArrayType.Type)
+		assert.Equal(t, "y", params["tags"].ParameterValue.ArrayValues[1].Value)
+	})
+
+	t.Run("waits for the completion of the query", func(t *testing.T) {
+		b := newTestBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/projects/myproject/queries":
+				w.Write([]byte(`{"jobComplete":false,"jobReference":{"projectId":"myproject","jobId":"job1"}}`))
+			case "/projects/myproject/queries/job1":
+				assert.Empty(t, r.URL.Query().Get("pageToken"))
+				w.Write([]byte(`{"jobComplete":true,"jobReference":{"projectId":"myproject","jobId":"job1"},"totalRows":"1",` + schema +
+					`,"rows":[{"f":[{"v":"a"},{"v":"1"},{"v":[]},{"v":null}]}]}`))
+			}
+		})
+
+		res, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: queryOperation,
+			Metadata:  map[string]string{"sql": "SELECT * FROM events"},
+		})
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"name":"a","count":1,"tags":[],"location":null}]`, string(res.Data))
+	})
+
+	t.Run("returns error for missing sql", func(t *testing.T) {
+		b := newTestBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("unexpected request %s", r.URL)
+		})
+
+		_, err := b.Invoke(context.Background(), &bindings.InvokeRequest{Operation: queryOperation})
+
+		assert.ErrorContains(t, err, "required metadata not set: sql")
+	})
+}
+
+func TestParseQueryParameters(t *testing.T) {
+	t.Run("infers the types", func(t *testing.T) {
+		params, err := parseQueryParameters([]byte(`{"f": 1.5, "b": true}`))
+
+		require.NoError(t, err)
+		types := map[string]string{}
+		for _, p := range params {
+			types[p.Name] = p.ParameterType.Type
+		}
+		assert.Equal(t, map[string]string{"f": "FLOAT64", "b": "BOOL"}, types)
+	})
+
+	t.Run("returns error for unsupported values", func(t *testing.T) {
+		for _, data := range []string{`{"n": null}`, `{"o": {}}`, `{"a": []}`, `{"a": [1, "x"]}`} {
+			_, err := parseQueryParameters([]byte(data))
+
+			assert.Error(t, err, data)
+		}
+	})
+
+	t.Run("returns error for invalid JSON", func(t *testing.T) {
+		_, err := parseQueryParameters([]byte(`["x"]`))
+
+		assert.ErrorContains(t, err, "must be a JSON object")
+	})
+}
+
+func TestInsertAutodetectSchema(t *testing.T) {
+	newBigQuery := func(t *testing.T, autodetect bool) (*BigQuery, *[]string, *bigquery.Table) {
+		var (
+			requests []string
+			created  bigquery.Table
+		)
+		tableExists := false
+		b := newTestBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			switch r.URL.Path {
+			case "/projects/myproject/datasets/mydataset/tables":
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&created))
+				tableExists = true
+				w.Write([]byte(`{}`))
+			case "/projects/myproject/datasets/mydataset/tables/mytable/insertAll":
+				if !tableExists {
+					w.WriteHeader(http.StatusNotFound)
+					w.Write([]byte(`{"error": {"code": 404, "message": "Not found: Table myproject:mydataset.mytable"}}`))
+					return
+				}
+				w.Write([]byte(`{}`))
+			}
+		})
+		b.metadata.AutodetectSchema = autodetect
+
+		return b, &requests, &created
+	}
+
+	t.Run("creates the missing table", func(t *testing.T) {
+		b, requests, created := newBigQuery(t, true)
+
+		_, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      []byte(`[{"name": "a", "count": 1}, {"name": "b", "count": 1.5, "tags": ["x"]}]`),
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"POST /projects/myproject/datasets/mydataset/tables/mytable/insertAll",
+			"POST /projects/myproject/datasets/mydataset/tables",
+			"POST /projects/myproject/datasets/mydataset/tables/mytable/insertAll",
+		}, *requests)
+		assert.Equal(t, "mytable", created.TableReference.TableId)
+		assert.Equal(t, []*bigquery.TableFieldSchema{
+			{Name: "count", Type: "FLOAT", Mode: "NULLABLE"},
+			{Name: "name", Type: "STRING", Mode: "NULLABLE"},
+			{Name: "tags", Type: "STRING", Mode: "REPEATED"},
+		}, created.Schema.Fields)
+	})
+
+	t.Run("retries until the new table is available", func(t *testing.T) {
+		inserts := 0
+		b := newTestBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/projects/myproject/datasets/mydataset/tables" {
+				w.Write([]byte(`{}`))
+				return
+			}
+			// The table is only found by the third insert after its creation
+			inserts++
+			if inserts <= 3 {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error": {"code": 404, "message": "Not found: Table myproject:mydataset.mytable"}}`))
+				return
+			}
+			w.Write([]byte(`{}`))
+		})
+		b.metadata.AutodetectSchema = true
+
+		_, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      []byte(`{"name": "a"}`),
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 4, inserts)
+	})
+
+	t.Run("disabled by the request", func(t *testing.T) {
+		b, requests, _ := newBigQuery(t, true)
+
+		_, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      []byte(`{"name": "a"}`),
+			Metadata:  map[string]string{"autodetect": "false"},
+		})
+
+		assert.ErrorContains(t, err, "Not found")
+		assert.Len(t, *requests, 1)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		b, _, _ := newBigQuery(t, false)
+
+		_, err := b.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      []byte(`{"name": "a"}`),
+		})
+
+		assert.ErrorContains(t, err, "Not found")
+	})
+}
+
+func TestDetectSchema(t *testing.T) {
+	t.Run("nested records and arrays", func(t *testing.T) {
+		rows, err := parseRows([]byte(`[
+			{"id": 1, "address": {"city": "Paris"}, "scores": [], "note": null},
+			{"id": 2, "address": {"zip": "75001"}, "scores": [1, 2.5], "active": true}
+		]`))
+		require.NoError(t, err)
+
+		schema, err := detectSchema(rows)
+
+		require.NoError(t, err)
+		assert.Equal(t, []*bigquery.TableFieldSchema{
+			{Name: "address", Type: "RECORD", Mode: "NULLABLE", Fields: []*bigquery.TableFieldSchema{
+				{Name: "city", Type: "STRING", Mode: "NULLABLE"},
+				{Name: "zip", Type: "STRING", Mode: "NULLABLE"},
+			}},
+			{Name: "id", Type: "INTEGER", Mode: "NULLABLE"},
+			{Name: "active", Type: "BOOLEAN", Mode: "NULLABLE"},
+			{Name: "scores", Type: "FLOAT", Mode: "REPEATED"},
+		}, schema.Fields)
+	})
+
+	t.Run("numbers are typed as written", func(t *testing.T) {
+		rows, err := parseRows([]byte(`[
+			{"count": 1, "ratio": 1.0, "big": 12345678901234567890, "mixed": 1},
+			{"count": 2, "ratio": 2, "big": 1, "mixed": 1e3}
+		]`))
+		require.NoError(t, err)
+
+		schema, err := detectSchema(rows)
+
+		require.NoError(t, err)
+		assert.Equal(t, []*bigquery.TableFieldSchema{
+			{Name: "big", Type: "BIGNUMERIC", Mode: "NULLABLE"},
+			{Name: "count", Type: "INTEGER", Mode: "NULLABLE"},
+			{Name: "mixed", Type: "FLOAT", Mode: "NULLABLE"},
+			{Name: "ratio", Type: "FLOAT", Mode: "NULLABLE"},
+		}, schema.Fields)
+	})
+
+	t.Run("conflicting types", func(t *testing.T) {
+		tests := map[string]string{
+			"string and number":  `[{"a": "x"}, {"a": 1}]`,
+			"array and value":    `[{"a": [1]}, {"a": 1}]`,
+			"array of arrays":    `{"a": [[1]]}`,
+			"mixed array":        `{"a": [1, "x"]}`,
+			"only null values":   `{"a": null}`,
+			"record and boolean": `[{"a": {"b": 1}}, {"a": true}]`,
+		}
+		for name, data := range tests {
+			t.Run(name, func(t *testing.T) {
+				rows, err := parseRows([]byte(data))
+				require.NoError(t, err)
+
+				_, err = detectSchema(rows)
+				assert.Error(t, err)
+			})
+		}
+	})
+}
@@ -14,21 +14,23 @@ limitations under the License.
 package bucket
 
 import (
-	"bytes"
 	"context"
 	b64 "encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/google/uuid"
 	"google.golang.org/api/iterator"
-	"google.golang.org/api/option"
 
 	"github.com/dapr/components-contrib/bindings"
+	gcpauth "github.com/dapr/components-contrib/internal/authentication/gcp"
 	"github.com/dapr/components-contrib/internal/utils"
 	"github.com/dapr/kit/logger"
 )
@@ -37,11 +39,28 @@ const (
 	objectURLBase        = "https://storage.googleapis.com/%s/%s"
 	metadataDecodeBase64 = "decodeBase64"
 	metadataEncodeBase64 = "encodeBase64"
+	metadataKMSKeyName   = "kmsKeyName"
 
 	metadataKey = "key"
 	maxResults  = 1000
 
 	metadataKeyBC = "name"
+
+	// Generation of the object read or deleted, instead of the live one.
+	metadataGeneration = "generation"
+	// Generation the object must have for the operation to be performed, or 0 if it must not exist.
+	metadataIfGenerationMatch = "ifGenerationMatch"
+	// Time for which the URL returned by the presign operation is valid, as a duration, e.g. 15m.
+	metadataPresignTTL = "presignTTL"
+	// HTTP method allowed by the URL returned by the presign operation; GET by default.
+	metadataPresignMethod = "presignMethod"
+
+	copyOperation    bindings.OperationKind = "copy"
+	composeOperation bindings.OperationKind = "compose"
+	presignOperation bindings.OperationKind = "presign"
+
+	// Maximum number of objects composed by a request.
+	maxComposeSources = 32
 )
 
 // GCPStorage allows saving data to GCP bucket storage.
@@ -65,6 +84,8 @@ type gcpMetadata struct {
 	ClientCertURL       string `json:"client_x509_cert_url"`
 	DecodeBase64        bool   `json:"decodeBase64,string"`
 	EncodeBase64        bool   `json:"encodeBase64,string"`
+	// Cloud KMS key encrypting the objects created, instead of the default key of the bucket
+	KMSKeyName string `json:"kmsKeyName"`
 }
 
 type listPayload struct {
@@ -73,8 +94,27 @@ type listPayload struct {
 	Delimiter  string `json:"delimiter"`
 }
 
+// copyPayload is the data of the copy requests, which copy the source object to the object of the "key" metadata.
+type copyPayload struct {
+	SourceKey string `json:"sourceKey"`
+	// Bucket of the source object; the bucket of the binding by default.
+	SourceBucket     string `json:"sourceBucket"`
+	SourceGeneration int64  `json:"sourceGeneration"`
+}
+
+// composePayload is the data of the compose requests, which concatenate the source objects in the object of the "key"
+// metadata.
+type composePayload struct {
+	SourceKeys []string `json:"sourceKeys"`
+}
+
 type createResponse struct {
-	ObjectURL string `json:"objectURL"`
+	ObjectURL  string `json:"objectURL"`
+	Generation int64  `json:"generation,omitempty"`
+}
+
+type presignResponse struct {
+	PresignURL string `json:"presignURL"`
 }
 
 // NewGCPStorage returns a new GCP storage instance.
@@ -89,9 +129,16 @@ func (g *GCPStorage) Init(metadata bindings.Metadata) error {
 		return err
 	}
 
-	clientOptions := option.WithCredentialsJSON(b)
+	key, err := gcpauth.KeyFromJSON(b)
+	if err != nil {
+		return err
+	}
+	clientOptions, err := gcpauth.ClientOptions(key)
+	if err != nil {
+		return fmt.Errorf("gcp bucket binding error: %w", err)
+	}
 	ctx := context.Background()
-	client, err := storage.NewClient(ctx, clientOptions)
+	client, err := storage.NewClient(ctx, clientOptions...)
 	if err != nil {
 		return err
 	}
@@ -123,6 +170,9 @@ func (g *GCPStorage) Operations() []bindings.OperationKind {
 		bindings.GetOperation,
 		bindings.DeleteOperation,
 		bindings.ListOperation,
+		copyOperation,
+		composeOperation,
+		presignOperation,
 	}
 }
 
@@ -138,6 +188,12 @@ func (g *GCPStorage) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*
 		return g.delete(ctx, req)
 	case bindings.ListOperation:
 		return g.list(ctx, req)
+	case copyOperation:
+		return g.copy(ctx, req)
+	case composeOperation:
+		return g.compose(ctx, req)
+	case presignOperation:
+		return g.presign(ctx, req)
 	default:
 		return nil, fmt.Errorf("unsupported operation %s", req.Operation)
 	}
@@ -158,19 +214,16 @@ func (g *GCPStorage) create(ctx context.Context, req *bindings.InvokeRequest) (*
 		g.logger.Debugf("key not found. generating name %s", name)
 	}
 
-	d, err := strconv.Unquote(string(req.Data))
-	if err == nil {
-		req.Data = []byte(d)
-	}
-
-	var r io.Reader = bytes.NewReader(req.Data)
-	if metadata.DecodeBase64 {
-		r = b64.NewDecoder(b64.StdEncoding, r)
+	data, err := req.DecodedData(metadata.DecodeBase64)
+	if err != nil {
+		return nil, fmt.Errorf("gcp bucket binding error: %w", err)
 	}
 
 	h := g.client.Bucket(g.metadata.Bucket).Object(name).NewWriter(ctx)
+	h.KMSKeyName = metadata.KMSKeyName
+	h.ContentType = req.Metadata[bindings.ContentTypeMetadataKey]
 	defer h.Close()
-	if _, err = io.Copy(h, r); err != nil {
+	if _, err = h.Write(data); err != nil {
 		return nil, fmt.Errorf("gcp bucket binding error. Uploading: %w", err)
 	}
 
@@ -206,8 +259,12 @@ func (g *GCPStorage) get(ctx context.Context, req *bindings.InvokeRequest) (*bin
 		return nil, fmt.Errorf("gcp bucket binding error: can't read key value")
 	}
 
-	var rc io.ReadCloser
-	rc, err = g.client.Bucket(g.metadata.Bucket).Object(key).NewReader(ctx)
+	object, err := g.object(g.metadata.Bucket, key, req.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("gcp bucket binding error: %w", err)
+	}
+
+	rc, err := object.NewReader(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("gcp bucketgcp bucket binding error: error downloading bucket object: %w", err)
 	}
@@ -223,9 +280,16 @@ func (g *GCPStorage) get(ctx context.Context, req *bindings.InvokeRequest) (*bin
 		data = []byte(encoded)
 	}
 
+	respMetadata := map[string]string{
+		metadataGeneration: strconv.FormatInt(rc.Attrs.Generation, 10),
+	}
+	if rc.Attrs.ContentType != "" {
+		respMetadata[bindings.ContentTypeMetadataKey] = rc.Attrs.ContentType
+	}
+
 	return &bindings.InvokeResponse{
 		Data:     data,
-		Metadata: nil,
+		Metadata: respMetadata,
 	}, nil
 }
 
@@ -237,9 +301,12 @@ func (g *GCPStorage) delete(ctx context.Context, req *bindings.InvokeRequest) (*
 		return nil, fmt.Errorf("gcp bucketgcp bucket binding error: can't read key value")
 	}
 
-	object := g.client.Bucket(g.metadata.Bucket).Object(key)
+	object, err := g.object(g.metadata.Bucket, key, req.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("gcp bucket binding error: %w", err)
+	}
 
-	err := object.Delete(ctx)
+	err = object.Delete(ctx)
 
 	return nil, err
 }
@@ -262,11 +329,14 @@ func (g *GCPStorage) list(ctx context.Context, req *bindings.InvokeRequest) (*bi
 
 	var result []storage.ObjectAttrs
 	it := g.client.Bucket(g.metadata.Bucket).Objects(ctx, input)
-	for {
+	for len(result) < int(payload.MaxResults) {
 		attrs, errIt := it.Next()
-		if errIt == iterator.Done || len(result) == int(payload.MaxResults) {
+		if errIt == iterator.Done {
 			break
 		}
+		if errIt != nil {
+			return nil, fmt.Errorf("gcp bucket binding error. list operation: %w", errIt)
+		}
 		result = append(result, *attrs)
 	}
 
@@ -280,6 +350,182 @@ func (g *GCPStorage) list(ctx context.Context, req *bindings.InvokeRequest) (*bi
 	}, nil
 }
 
+func (g *GCPStorage) copy(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	var payload copyPayload
+	err := json.Unmarshal(req.Data, &payload)
+	if err != nil {
+		return nil, fmt.Errorf("gcp bucket binding error. copy operation: invalid payload: %w", err)
+	}
+	if payload.SourceKey == "" {
+		return nil, fmt.Errorf("gcp bucket binding error. copy operation: sourceKey is required")
+	}
+	if payload.SourceBucket == "" {
+		payload.SourceBucket = g.metadata.Bucket
+	}
+
+	dst, err := g.destination(req)
+	if err != nil {
+		return nil, err
+	}
+	src := g.client.Bucket(payload.SourceBucket).Object(payload.SourceKey)
+	if payload.SourceGeneration != 0 {
+		src = src.Generation(payload.SourceGeneration)
+	}
+
+	copier := dst.CopierFrom(src)
+	copier.DestinationKMSKeyName = g.metadata.KMSKeyName
+	attrs, err := copier.Run(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("gcp bucket binding error. copy operation: %w", err)
+	}
+
+	return g.objectResponse(attrs)
+}
+
+func (g *GCPStorage) compose(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	var payload composePayload
+	err := json.Unmarshal(req.Data, &payload)
+	if err != nil {
+		return nil, fmt.Errorf("gcp bucket binding error. compose operation: invalid payload: %w", err)
+	}
+	if len(payload.SourceKeys) == 0 || len(payload.SourceKeys) > maxComposeSources {
+		return nil, fmt.Errorf("gcp bucket binding error. compose operation: between 1 and %d sourceKeys are required", maxComposeSources)
+	}
+
+	dst, err := g.destination(req)
+	if err != nil {
+		return nil, err
+	}
+	srcs := make([]*storage.ObjectHandle, len(payload.SourceKeys))
+	for i, key := range payload.SourceKeys {
+		srcs[i] = g.client.Bucket(g.metadata.Bucket).Object(key)
+	}
+
+	composer := dst.ComposerFrom(srcs...)
+	composer.KMSKeyName = g.metadata.KMSKeyName
+	composer.ContentType = req.Metadata[bindings.ContentTypeMetadataKey]
+	attrs, err := composer.Run(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("gcp bucket binding error. compose operation: %w", err)
+	}
+
+	return g.objectResponse(attrs)
+}
+
+func (g *GCPStorage) presign(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	var key string
+	if val, ok := req.Metadata[metadataKey]; ok && val != "" {
+		key = val
+	} else {
+		return nil, fmt.Errorf("gcp bucket binding error: required metadata '%s' missing", metadataKey)
+	}
+	ttl, ok := req.Metadata[metadataPresignTTL]
+	if !ok || ttl == "" {
+		return nil, fmt.Errorf("gcp bucket binding error: required metadata '%s' missing", metadataPresignTTL)
+	}
+	d, err := time.ParseDuration(ttl)
+	if err != nil || d <= 0 {
+		return nil, fmt.Errorf("gcp bucket binding error: invalid %s %s", metadataPresignTTL, ttl)
+	}
+	method := http.MethodGet
+	if val, ok := req.Metadata[metadataPresignMethod]; ok && val != "" {
+		method = strings.ToUpper(val)
+	}
+
+	opts := &storage.SignedURLOptions{
+		Scheme:  storage.SigningSchemeV4,
+		Method:  method,
+		Expires: time.Now().Add(d),
+	}
+	// Without the key of a service account, the URL is signed with the credentials of the client
+	if g.metadata.ClientEmail != "" && g.metadata.PrivateKey != "" {
+		opts.GoogleAccessID = g.metadata.ClientEmail
+		opts.PrivateKey = []byte(g.metadata.PrivateKey)
+	}
+	signedURL, err := g.client.Bucket(g.metadata.Bucket).SignedURL(key, opts)
+	if err != nil {
+		return nil, fmt.Errorf("gcp bucket binding error: failed to presign URL: %w", err)
+	}
+
+	b, err := json.Marshal(presignResponse{
+		PresignURL: signedURL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gcp bucket binding error: error marshalling presign response: %w", err)
+	}
+
+	return &bindings.InvokeResponse{
+		Data: b,
+	}, nil
+}
+
+// object returns the handle of the object in the bucket, with the generation and precondition of the request metadata.
+func (g *GCPStorage) object(bucket string, key string, metadata map[string]string) (*storage.ObjectHandle, error) {
+	object := g.client.Bucket(bucket).Object(key)
+
+	if val, ok := metadata[metadataGeneration]; ok && val != "" {
+		generation, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %s", metadataGeneration, val)
+		}
+		object = object.Generation(generation)
+	}
+
+	if val, ok := metadata[metadataIfGenerationMatch]; ok && val != "" {
+		generation, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %s", metadataIfGenerationMatch, val)
+		}
+		if generation == 0 {
+			object = object.If(storage.Conditions{DoesNotExist: true})
+		} else {
+			object = object.If(storage.Conditions{GenerationMatch: generation})
+		}
+	}
+
+	return object, nil
+}
+
+// destination returns the handle of the object of the "key" metadata, written by the copy and compose operations.
+func (g *GCPStorage) destination(req *bindings.InvokeRequest) (*storage.ObjectHandle, error) {
+	key, ok := req.Metadata[metadataKey]
+	if !ok || key == "" {
+		return nil, fmt.Errorf("gcp bucket binding error: required metadata '%s' missing", metadataKey)
+	}
+	if _, ok := req.Metadata[metadataGeneration]; ok {
+		return nil, fmt.Errorf("gcp bucket binding error: metadata '%s' not supported by operation %s", metadataGeneration, req.Operation)
+	}
+
+	dst, err := g.object(g.metadata.Bucket, key, req.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("gcp bucket binding error: %w", err)
+	}
+
+	return dst, nil
+}
+
+func (g *GCPStorage) objectResponse(attrs *storage.ObjectAttrs) (*bindings.InvokeResponse, error) {
+	objectURL, err := url.Parse(fmt.Sprintf(objectURLBase, attrs.Bucket, attrs.Name))
+	if err != nil {
+		return nil, fmt.Errorf("gcp bucket binding error. error building url response: %w", err)
+	}
+
+	b, err := json.Marshal(createResponse{
+		ObjectURL:  objectURL.String(),
+		Generation: attrs.Generation,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gcp bucket binding error. error marshalling response: %w", err)
+	}
+
+	return &bindings.InvokeResponse{
+		Data: b,
+		Metadata: map[string]string{
+			metadataGeneration: strconv.FormatInt(attrs.Generation, 10),
+		},
+	}, nil
+}
+
 func (g *GCPStorage) Close() error {
 	return g.client.Close()
 }
@@ -296,6 +542,10 @@ func (metadata gcpMetadata) mergeWithRequestMetadata(req *bindings.InvokeRequest
 		merged.EncodeBase64 = utils.IsTruthy(val)
 	}
 
+	if val, ok := req.Metadata[metadataKMSKeyName]; ok && val != "" {
+		merged.KMSKeyName = val
+	}
+
 	return merged, nil
 }
 
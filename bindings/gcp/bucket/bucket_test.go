@@ -15,9 +15,20 @@ package bucket
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 
+	"cloud.google.com/go/storage"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
 
 	"github.com/dapr/components-contrib/bindings"
 	"github.com/dapr/kit/logger"
@@ -126,6 +137,28 @@ func TestMergeWithRequestMetadata(t *testing.T) {
 		assert.Equal(t, true, mergedMeta.DecodeBase64)
 	})
 
+	t.Run("Has merged kms key name", func(t *testing.T) {
+		m := bindings.Metadata{}
+		m.Properties = map[string]string{
+			"Bucket":     "my_bucket",
+			"kmsKeyName": "projects/p/locations/l/keyRings/r/cryptoKeys/component",
+		}
+		gs := GCPStorage{logger: logger.NewLogger("test")}
+		meta, _, err := gs.parseMetadata(m)
+		assert.Nil(t, err)
+		assert.Equal(t, "projects/p/locations/l/keyRings/r/cryptoKeys/component", meta.KMSKeyName)
+
+		request := bindings.InvokeRequest{}
+		request.Metadata = map[string]string{
+			"kmsKeyName": "projects/p/locations/l/keyRings/r/cryptoKeys/request",
+		}
+
+		mergedMeta, err := meta.mergeWithRequestMetadata(&request)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "projects/p/locations/l/keyRings/r/cryptoKeys/request", mergedMeta.KMSKeyName)
+	})
+
 	t.Run("Has invalid merged metadata decodeBase64", func(t *testing.T) {
 		m := bindings.Metadata{}
 		m.Properties = map[string]string{
@@ -238,3 +271,155 @@ func TestDeleteOption(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+// newTestStorage returns a binding whose client sends the requests of the JSON API to handler.
+func newTestStorage(t *testing.T, handler http.HandlerFunc) *GCPStorage {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client, err := storage.NewClient(context.Background(), option.WithEndpoint(server.URL+"/storage/v1/"), option.WithoutAuthentication())
+	require.NoError(t, err)
+
+	return &GCPStorage{
+		metadata: &gcpMetadata{Bucket: "my_bucket"},
+		client:   client,
+		logger:   logger.NewLogger("test"),
+	}
+}
+
+func TestDeleteGeneration(t *testing.T) {
+	var query url.Values
+	gs := newTestStorage(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		assert.Equal(t, "/storage/v1/b/my_bucket/o/file.txt", r.URL.Path)
+		query = r.URL.Query()
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	_, err := gs.delete(context.Background(), &bindings.InvokeRequest{Metadata: map[string]string{
+		"key":               "file.txt",
+		"generation":        "12",
+		"ifGenerationMatch": "12",
+	}})
+
+	require.NoError(t, err)
+	assert.Equal(t, "12", query.Get("generation"))
+	assert.Equal(t, "12", query.Get("ifGenerationMatch"))
+
+	_, err = gs.delete(context.Background(), &bindings.InvokeRequest{Metadata: map[string]string{
+		"key":        "file.txt",
+		"generation": "latest",
+	}})
+	assert.ErrorContains(t, err, "invalid generation latest")
+}
+
+func TestCopy(t *testing.T) {
+	var path string
+	var query url.Values
+	gs := newTestStorage(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		path = r.URL.Path
+		query = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"done": true, "resource": {"bucket": "my_bucket", "name": "copy.txt", "generation": "42"}}`))
+	})
+
+	t.Run("copy from another bucket", func(t *testing.T) {
+		res, err := gs.copy(context.Background(), &bindings.InvokeRequest{
+			Operation: copyOperation,
+			Data:      []byte(`{"sourceKey": "file.txt", "sourceBucket": "other_bucket", "sourceGeneration": 7}`),
+			Metadata:  map[string]string{"key": "copy.txt", "ifGenerationMatch": "0"},
+		})
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"objectURL": "https://storage.googleapis.com/my_bucket/copy.txt", "generation": 42}`, string(res.Data))
+		assert.Equal(t, "42", res.Metadata["generation"])
+		assert.Equal(t, "/storage/v1/b/other_bucket/o/file.txt/rewriteTo/b/my_bucket/o/copy.txt", path)
+		assert.Equal(t, "7", query.Get("sourceGeneration"))
+		assert.Equal(t, "0", query.Get("ifGenerationMatch"))
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		tests := map[string]*bindings.InvokeRequest{
+			"missing source":      {Data: []byte(`{}`), Metadata: map[string]string{"key": "copy.txt"}},
+			"missing destination": {Data: []byte(`{"sourceKey": "file.txt"}`), Metadata: map[string]string{}},
+			"invalid payload":     {Data: []byte(`not json`), Metadata: map[string]string{"key": "copy.txt"}},
+			"generation":          {Data: []byte(`{"sourceKey": "file.txt"}`), Metadata: map[string]string{"key": "copy.txt", "generation": "1"}},
+		}
+		for name, req := range tests {
+			t.Run(name, func(t *testing.T) {
+				req.Operation = copyOperation
+				_, err := gs.copy(context.Background(), req)
+				assert.Error(t, err)
+			})
+		}
+	})
+}
+
+func TestCompose(t *testing.T) {
+	var body map[string]interface{}
+	gs := newTestStorage(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/storage/v1/b/my_bucket/o/all.log/compose", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"bucket": "my_bucket", "name": "all.log", "generation": "3"}`))
+	})
+
+	res, err := gs.compose(context.Background(), &bindings.InvokeRequest{
+		Operation: composeOperation,
+		Data:      []byte(`{"sourceKeys": ["1.log", "2.log"]}`),
+		Metadata:  map[string]string{"key": "all.log", "contentType": "text/plain"},
+	})
+
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"objectURL": "https://storage.googleapis.com/my_bucket/all.log", "generation": 3}`, string(res.Data))
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "1.log"}, map[string]interface{}{"name": "2.log"}}, body["sourceObjects"])
+	assert.Equal(t, "text/plain", body["destination"].(map[string]interface{})["contentType"])
+
+	_, err = gs.compose(context.Background(), &bindings.InvokeRequest{
+		Operation: composeOperation,
+		Data:      []byte(`{"sourceKeys": []}`),
+		Metadata:  map[string]string{"key": "all.log"},
+	})
+	assert.Error(t, err)
+}
+
+func TestPresign(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	gs := newTestStorage(t, nil)
+	gs.metadata.ClientEmail = "dapr@project.iam.gserviceaccount.com"
+	gs.metadata.PrivateKey = string(privateKey)
+
+	t.Run("signed URL", func(t *testing.T) {
+		res, err := gs.presign(context.Background(), &bindings.InvokeRequest{Metadata: map[string]string{
+			"key":           "file.txt",
+			"presignTTL":    "15m",
+			"presignMethod": "put",
+		}})
+		require.NoError(t, err)
+
+		var presigned presignResponse
+		require.NoError(t, json.Unmarshal(res.Data, &presigned))
+		u, err := url.Parse(presigned.PresignURL)
+		require.NoError(t, err)
+		assert.Equal(t, "/my_bucket/file.txt", u.Path)
+		assert.Equal(t, "GOOG4-RSA-SHA256", u.Query().Get("X-Goog-Algorithm"))
+		assert.Contains(t, []string{"899", "900"}, u.Query().Get("X-Goog-Expires"))
+		assert.Contains(t, u.Query().Get("X-Goog-Credential"), "dapr@project.iam.gserviceaccount.com")
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		for _, md := range []map[string]string{
+			{"presignTTL": "15m"},
+			{"key": "file.txt"},
+			{"key": "file.txt", "presignTTL": "soon"},
+		} {
+			_, err := gs.presign(context.Background(), &bindings.InvokeRequest{Metadata: md})
+			assert.Error(t, err, "%v", md)
+		}
+	})
+}
@@ -0,0 +1,416 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudtasks
+
+import (
+	"context"
+	b64 "encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	cloudtasks "google.golang.org/api/cloudtasks/v2"
+
+	"github.com/dapr/components-contrib/bindings"
+	gcpauth "github.com/dapr/components-contrib/internal/authentication/gcp"
+	"github.com/dapr/components-contrib/internal/utils"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// Request metadata keys; url, httpMethod, dispatchDeadline and the OIDC token keys override the values of the
+	// component metadata.
+	metadataKeyURL                     = "url"
+	metadataKeyHTTPMethod              = "httpMethod"
+	metadataKeyContentType             = "contentType"
+	metadataKeyDispatchDeadline        = "dispatchDeadline"
+	metadataKeyOIDCServiceAccountEmail = "oidcServiceAccountEmail"
+	metadataKeyOIDCAudience            = "oidcAudience"
+	// Time at which the task is dispatched, in RFC 3339 format; exclusive with delay.
+	metadataKeyScheduleTime = "scheduleTime"
+	// Delay after which the task is dispatched, as a duration, e.g. 10m.
+	metadataKeyDelay = "delay"
+	// ID of the task, deduplicating the tasks created with the same ID.
+	metadataKeyTaskID = "taskId"
+	// Prefix of the request metadata keys of the headers of the HTTP requests of the tasks.
+	metadataKeyHeaderPrefix = "header."
+
+	// Response metadata keys.
+	metadataKeyTaskName = "taskName"
+
+	defaultHTTPMethod = http.MethodPost
+	// Limits of Cloud Tasks for the dispatch deadlines of HTTP tasks.
+	minDispatchDeadline = 15 * time.Second
+	maxDispatchDeadline = 30 * time.Minute
+)
+
+// CloudTasks is an output binding enqueueing HTTP target tasks in a queue of GCP Cloud Tasks.
+type CloudTasks struct {
+	metadata *cloudTasksMetadata
+	service  *cloudtasks.Service
+
+	logger logger.Logger
+}
+
+type cloudTasksMetadata struct {
+	Type                string `json:"type"`
+	ProjectID           string `json:"project_id"`
+	PrivateKeyID        string `json:"private_key_id"`
+	PrivateKey          string `json:"private_key"`
+	ClientEmail         string `json:"client_email"`
+	ClientID            string `json:"client_id"`
+	AuthURI             string `json:"auth_uri"`
+	TokenURI            string `json:"token_uri"`
+	AuthProviderCertURL string `json:"auth_provider_x509_cert_url"`
+	ClientCertURL       string `json:"client_x509_cert_url"`
+	// Location and ID of the queue.
+	Location string `json:"location"`
+	Queue    string `json:"queue"`
+	// Target of the tasks, overridden by the request metadata.
+	URL        string `json:"url"`
+	HTTPMethod string `json:"httpMethod"`
+	// Timeout of the requests of the tasks, as a duration, e.g. 1m; 10 minutes by default in Cloud Tasks.
+	DispatchDeadline string `json:"dispatchDeadline"`
+	// Service account whose OIDC token authenticates the requests of the tasks, e.g. to Cloud Run services.
+	OIDCServiceAccountEmail string `json:"oidcServiceAccountEmail"`
+	// Audience of the OIDC token; the URL of the task by default.
+	OIDCAudience string `json:"oidcAudience"`
+	// If true, the retry configuration is applied to the queue, which is shared by all its clients, when the binding is
+	// initialized.
+	UpdateQueueRetryConfig string `json:"updateQueueRetryConfig"`
+	// Retry configuration of the queue; the backoffs and durations are durations, e.g. 10s.
+	MaxAttempts      string `json:"maxAttempts"`
+	MinBackoff       string `json:"minBackoff"`
+	MaxBackoff       string `json:"maxBackoff"`
+	MaxDoublings     string `json:"maxDoublings"`
+	MaxRetryDuration string `json:"maxRetryDuration"`
+
+	retryConfig *cloudtasks.RetryConfig
+	// Fields of the retry configuration set in the metadata.
+	retryConfigFields []string
+}
+
+// NewCloudTasks returns a new GCP Cloud Tasks output binding.
+func NewCloudTasks(logger logger.Logger) bindings.OutputBinding {
+	return &CloudTasks{logger: logger}
+}
+
+// Init parses the metadata, creates the Cloud Tasks service, and updates the retry configuration of the queue if
+// updateQueueRetryConfig is true.
+func (c *CloudTasks) Init(metadata bindings.Metadata) error {
+	m, credentials, err := parseMetadata(metadata)
+	if err != nil {
+		return err
+	}
+
+	key, err := gcpauth.KeyFromJSON(credentials)
+	if err != nil {
+		return err
+	}
+	clientOptions, err := gcpauth.ClientOptions(key)
+	if err != nil {
+		return fmt.Errorf("cloudtasks binding error: %w", err)
+	}
+
+	service, err := cloudtasks.NewService(context.Background(), clientOptions...)
+	if err != nil {
+		return fmt.Errorf("cloudtasks binding error: error creating the service: %w", err)
+	}
+
+	c.metadata = m
+	c.service = service
+
+	return c.updateRetryConfig(context.Background())
+}
+
+func parseMetadata(metadata bindings.Metadata) (*cloudTasksMetadata, []byte, error) {
+	credentials, err := json.Marshal(metadata.Properties)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var m cloudTasksMetadata
+	err = json.Unmarshal(credentials, &m)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if m.ProjectID == "" {
+		return nil, nil, errors.New("cloudtasks binding error: project_id is required")
+	}
+	if m.Location == "" {
+		return nil, nil, errors.New("cloudtasks binding error: location is required")
+	}
+	if m.Queue == "" {
+		return nil, nil, errors.New("cloudtasks binding error: queue is required")
+	}
+	if m.DispatchDeadline != "" {
+		if _, err = parseDispatchDeadline(m.DispatchDeadline); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	m.retryConfig, m.retryConfigFields, err = parseRetryConfig(&m)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The queue is only updated when asked explicitly, rather than as a side effect of the retry properties
+	if m.retryConfig != nil && !utils.IsTruthy(m.UpdateQueueRetryConfig) {
+		return nil, nil, errors.New("cloudtasks binding error: the retry configuration is only applied to the queue with updateQueueRetryConfig set to true")
+	}
+	if m.retryConfig == nil && utils.IsTruthy(m.UpdateQueueRetryConfig) {
+		return nil, nil, errors.New("cloudtasks binding error: updateQueueRetryConfig is set without any retry configuration")
+	}
+
+	return &m, credentials, nil
+}
+
+// parseRetryConfig returns the retry configuration of the metadata and its fields set, or nil if none is set.
+func parseRetryConfig(m *cloudTasksMetadata) (*cloudtasks.RetryConfig, []string, error) {
+	config := &cloudtasks.RetryConfig{}
+	var fields []string
+
+	if m.MaxAttempts != "" {
+		// -1 is unlimited attempts
+		attempts, err := strconv.ParseInt(m.MaxAttempts, 10, 64)
+		if err != nil || attempts < -1 {
+			return nil, nil, fmt.Errorf("cloudtasks binding error: invalid maxAttempts: %s", m.MaxAttempts)
+		}
+		config.MaxAttempts = attempts
+		// 0 is the default of the queue, so it must be sent explicitly
+		config.ForceSendFields = append(config.ForceSendFields, "MaxAttempts")
+		fields = append(fields, "maxAttempts")
+	}
+	if m.MaxDoublings != "" {
+		doublings, err := strconv.ParseInt(m.MaxDoublings, 10, 64)
+		if err != nil || doublings < 0 {
+			return nil, nil, fmt.Errorf("cloudtasks binding error: invalid maxDoublings: %s", m.MaxDoublings)
+		}
+		config.MaxDoublings = doublings
+		config.ForceSendFields = append(config.ForceSendFields, "MaxDoublings")
+		fields = append(fields, "maxDoublings")
+	}
+
+	durations := []struct {
+		name  string
+		value string
+		field *string
+	}{
+		{"minBackoff", m.MinBackoff, &config.MinBackoff},
+		{"maxBackoff", m.MaxBackoff, &config.MaxBackoff},
+		{"maxRetryDuration", m.MaxRetryDuration, &config.MaxRetryDuration},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(d.value)
+		if err != nil || duration < 0 {
+			return nil, nil, fmt.Errorf("cloudtasks binding error: invalid %s: %s", d.name, d.value)
+		}
+		*d.field = formatDuration(duration)
+		fields = append(fields, d.name)
+	}
+
+	if len(fields) == 0 {
+		return nil, nil, nil
+	}
+
+	return config, fields, nil
+}
+
+// updateRetryConfig updates the fields of the retry configuration of the queue set in the metadata.
+func (c *CloudTasks) updateRetryConfig(ctx context.Context) error {
+	if c.metadata.retryConfig == nil {
+		return nil
+	}
+
+	mask := make([]string, len(c.metadata.retryConfigFields))
+	for i, field := range c.metadata.retryConfigFields {
+		mask[i] = "retryConfig." + field
+	}
+	_, err := c.service.Projects.Locations.Queues.Patch(c.queueName(), &cloudtasks.Queue{RetryConfig: c.metadata.retryConfig}).
+		UpdateMask(strings.Join(mask, ",")).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return fmt.Errorf("cloudtasks binding error: error updating the retry configuration of queue %s: %w", c.metadata.Queue, err)
+	}
+
+	return nil
+}
+
+func (c *CloudTasks) Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{
+		bindings.CreateOperation,
+		bindings.DeleteOperation,
+	}
+}
+
+func (c *CloudTasks) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	switch req.Operation {
+	case bindings.CreateOperation:
+		return c.create(ctx, req)
+	case bindings.DeleteOperation:
+		return nil, c.delete(ctx, req)
+	default:
+		return nil, fmt.Errorf("cloudtasks binding error: unsupported operation %s", req.Operation)
+	}
+}
+
+// create enqueues a task sending the request data to the URL, when scheduled.
+func (c *CloudTasks) create(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	targetURL := req.GetMetadataOrDefault(metadataKeyURL, c.metadata.URL)
+	if targetURL == "" {
+		return nil, fmt.Errorf("cloudtasks binding error: required metadata not set: %s", metadataKeyURL)
+	}
+
+	httpRequest := &cloudtasks.HttpRequest{
+		Url:        targetURL,
+		HttpMethod: strings.ToUpper(req.GetMetadataOrDefault(metadataKeyHTTPMethod, c.metadata.HTTPMethod)),
+		Headers:    map[string]string{},
+	}
+	if httpRequest.HttpMethod == "" {
+		httpRequest.HttpMethod = defaultHTTPMethod
+	}
+	if len(req.Data) > 0 {
+		httpRequest.Body = b64.StdEncoding.EncodeToString(req.Data)
+	}
+	for k, v := range req.Metadata {
+		if strings.HasPrefix(k, metadataKeyHeaderPrefix) {
+			httpRequest.Headers[strings.TrimPrefix(k, metadataKeyHeaderPrefix)] = v
+		}
+	}
+	if contentType := req.Metadata[metadataKeyContentType]; contentType != "" {
+		httpRequest.Headers["Content-Type"] = contentType
+	}
+	if email := req.GetMetadataOrDefault(metadataKeyOIDCServiceAccountEmail, c.metadata.OIDCServiceAccountEmail); email != "" {
+		httpRequest.OidcToken = &cloudtasks.OidcToken{
+			ServiceAccountEmail: email,
+			Audience:            req.GetMetadataOrDefault(metadataKeyOIDCAudience, c.metadata.OIDCAudience),
+		}
+	}
+
+	task := &cloudtasks.Task{HttpRequest: httpRequest}
+	if id := req.Metadata[metadataKeyTaskID]; id != "" {
+		task.Name = c.taskName(id)
+	}
+
+	scheduleTime, err := parseScheduleTime(req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	if !scheduleTime.IsZero() {
+		task.ScheduleTime = scheduleTime.UTC().Format(time.RFC3339Nano)
+	}
+
+	if deadline := req.GetMetadataOrDefault(metadataKeyDispatchDeadline, c.metadata.DispatchDeadline); deadline != "" {
+		d, err := parseDispatchDeadline(deadline)
+		if err != nil {
+			return nil, err
+		}
+		task.DispatchDeadline = formatDuration(d)
+	}
+
+	task, err = c.service.Projects.Locations.Queues.Tasks.Create(c.queueName(), &cloudtasks.CreateTaskRequest{Task: task}).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return nil, fmt.Errorf("cloudtasks binding error: error creating task: %w", err)
+	}
+
+	data, err := json.Marshal(map[string]string{
+		"name":         task.Name,
+		"scheduleTime": task.ScheduleTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &bindings.InvokeResponse{
+		Data: data,
+		Metadata: map[string]string{
+			metadataKeyTaskName: task.Name,
+		},
+	}, nil
+}
+
+// delete deletes the task of the "taskId" metadata, an ID or the full name of the task, if not dispatched yet.
+func (c *CloudTasks) delete(ctx context.Context, req *bindings.InvokeRequest) error {
+	id := req.Metadata[metadataKeyTaskID]
+	if id == "" {
+		return fmt.Errorf("cloudtasks binding error: required metadata not set: %s", metadataKeyTaskID)
+	}
+
+	name := id
+	if !strings.HasPrefix(id, "projects/") {
+		name = c.taskName(id)
+	}
+	_, err := c.service.Projects.Locations.Queues.Tasks.Delete(name).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("cloudtasks binding error: error deleting task %s: %w", id, err)
+	}
+
+	return nil
+}
+
+func (c *CloudTasks) queueName() string {
+	return fmt.Sprintf("projects/%s/locations/%s/queues/%s", c.metadata.ProjectID, c.metadata.Location, c.metadata.Queue)
+}
+
+func (c *CloudTasks) taskName(id string) string {
+	return c.queueName() + "/tasks/" + id
+}
+
+// parseScheduleTime returns the time of the "scheduleTime" or "delay" metadata, or the zero time to dispatch the task
+// immediately.
+func parseScheduleTime(md map[string]string) (time.Time, error) {
+	scheduleTime, delay := md[metadataKeyScheduleTime], md[metadataKeyDelay]
+	switch {
+	case scheduleTime != "" && delay != "":
+		return time.Time{}, fmt.Errorf("cloudtasks binding error: %s and %s are exclusive", metadataKeyScheduleTime, metadataKeyDelay)
+	case scheduleTime != "":
+		t, err := time.Parse(time.RFC3339, scheduleTime)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("cloudtasks binding error: invalid %s: %s", metadataKeyScheduleTime, scheduleTime)
+		}
+		return t, nil
+	case delay != "":
+		d, err := time.ParseDuration(delay)
+		if err != nil || d < 0 {
+			return time.Time{}, fmt.Errorf("cloudtasks binding error: invalid %s: %s", metadataKeyDelay, delay)
+		}
+		return time.Now().Add(d), nil
+	default:
+		return time.Time{}, nil
+	}
+}
+
+func parseDispatchDeadline(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil || d < minDispatchDeadline || d > maxDispatchDeadline {
+		return 0, fmt.Errorf("cloudtasks binding error: invalid %s: %s, must be between %s and %s", metadataKeyDispatchDeadline, s, minDispatchDeadline, maxDispatchDeadline)
+	}
+
+	return d, nil
+}
+
+// formatDuration formats the duration as the JSON representation of the protobuf durations, e.g. 3.5s.
+func formatDuration(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
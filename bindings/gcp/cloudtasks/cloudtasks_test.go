@@ -0,0 +1,268 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudtasks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cloudtasks "google.golang.org/api/cloudtasks/v2"
+	"google.golang.org/api/option"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const testQueue = "projects/myproject/locations/europe-west1/queues/myqueue"
+
+func TestParseMetadata(t *testing.T) {
+	t.Run("parses the metadata", func(t *testing.T) {
+		m, _, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"project_id":              "myproject",
+			"location":                "europe-west1",
+			"queue":                   "myqueue",
+			"url":                     "https://example.com/tasks",
+			"dispatchDeadline":        "1m",
+			"oidcServiceAccountEmail": "tasks@myproject.iam.gserviceaccount.com",
+			"updateQueueRetryConfig":  "true",
+			"maxAttempts":             "0",
+			"minBackoff":              "1.5s",
+			"maxRetryDuration":        "1h",
+		}}})
+
+		require.NoError(t, err)
+		assert.Equal(t, "myqueue", m.Queue)
+		assert.Equal(t, "https://example.com/tasks", m.URL)
+		assert.Equal(t, "tasks@myproject.iam.gserviceaccount.com", m.OIDCServiceAccountEmail)
+		assert.Equal(t, []string{"maxAttempts", "minBackoff", "maxRetryDuration"}, m.retryConfigFields)
+		assert.Equal(t, int64(0), m.retryConfig.MaxAttempts)
+		assert.Equal(t, "1.5s", m.retryConfig.MinBackoff)
+		assert.Equal(t, "3600s", m.retryConfig.MaxRetryDuration)
+	})
+
+	t.Run("no retry configuration", func(t *testing.T) {
+		m, _, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"project_id": "myproject",
+			"location":   "europe-west1",
+			"queue":      "myqueue",
+		}}})
+
+		require.NoError(t, err)
+		assert.Nil(t, m.retryConfig)
+	})
+
+	t.Run("retry configuration requires the opt-in", func(t *testing.T) {
+		props := map[string]string{"project_id": "myproject", "location": "europe-west1", "queue": "myqueue", "maxAttempts": "5"}
+
+		_, _, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: props}})
+		assert.EqualError(t, err, "cloudtasks binding error: the retry configuration is only applied to the queue with updateQueueRetryConfig set to true")
+
+		delete(props, "maxAttempts")
+		props["updateQueueRetryConfig"] = "true"
+		_, _, err = parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: props}})
+		assert.EqualError(t, err, "cloudtasks binding error: updateQueueRetryConfig is set without any retry configuration")
+	})
+
+	t.Run("dispatch deadline out of the bounds of Cloud Tasks", func(t *testing.T) {
+		_, _, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"project_id":       "myproject",
+			"location":         "europe-west1",
+			"queue":            "myqueue",
+			"dispatchDeadline": "1h",
+		}}})
+
+		assert.EqualError(t, err, "cloudtasks binding error: invalid dispatchDeadline: 1h, must be between 15s and 30m0s")
+	})
+}
+
+// newTestCloudTasks returns a binding whose requests are served by handler.
+func newTestCloudTasks(t *testing.T, m *cloudTasksMetadata, handler http.HandlerFunc) *CloudTasks {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	service, err := cloudtasks.NewService(context.Background(), option.WithEndpoint(server.URL+"/"), option.WithHTTPClient(server.Client()))
+	require.NoError(t, err)
+
+	m.ProjectID = "myproject"
+	m.Location = "europe-west1"
+	m.Queue = "myqueue"
+
+	return &CloudTasks{
+		metadata: m,
+		service:  service,
+		logger:   logger.NewLogger("test"),
+	}
+}
+
+func TestCreate(t *testing.T) {
+	var (
+		path     string
+		received cloudtasks.CreateTaskRequest
+	)
+	c := newTestCloudTasks(t, &cloudTasksMetadata{
+		URL:                     "https://example.com/tasks",
+		OIDCServiceAccountEmail: "tasks@myproject.iam.gserviceaccount.com",
+		DispatchDeadline:        "1m",
+	}, func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		received = cloudtasks.CreateTaskRequest{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		task := *received.Task
+		if task.Name == "" {
+			task.Name = testQueue + "/tasks/123"
+		}
+		if task.ScheduleTime == "" {
+			task.ScheduleTime = "2022-10-01T00:00:00Z"
+		}
+		json.NewEncoder(w).Encode(task)
+	})
+
+	t.Run("enqueues the task with the component metadata", func(t *testing.T) {
+		res, err := c.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Data:      []byte(`{"orderId": 1}`),
+			Metadata: map[string]string{
+				"contentType":   "application/json",
+				"header.X-Team": "orders",
+			},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "/v2/"+testQueue+"/tasks", path)
+		assert.JSONEq(t, `{"name": "`+testQueue+`/tasks/123", "scheduleTime": "2022-10-01T00:00:00Z"}`, string(res.Data))
+		assert.Equal(t, testQueue+"/tasks/123", res.Metadata["taskName"])
+
+		task := received.Task
+		assert.Equal(t, "https://example.com/tasks", task.HttpRequest.Url)
+		assert.Equal(t, "POST", task.HttpRequest.HttpMethod)
+		assert.Equal(t, "eyJvcmRlcklkIjogMX0=", task.HttpRequest.Body)
+		assert.Equal(t, map[string]string{"Content-Type": "application/json", "X-Team": "orders"}, task.HttpRequest.Headers)
+		assert.Equal(t, "tasks@myproject.iam.gserviceaccount.com", task.HttpRequest.OidcToken.ServiceAccountEmail)
+		assert.Equal(t, "60s", task.DispatchDeadline)
+		assert.Empty(t, task.ScheduleTime)
+		assert.Empty(t, task.Name)
+	})
+
+	t.Run("request metadata overrides the component metadata", func(t *testing.T) {
+		_, err := c.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Metadata: map[string]string{
+				"url":              "https://example.com/other",
+				"httpMethod":       "put",
+				"oidcAudience":     "https://example.com",
+				"dispatchDeadline": "20s",
+				"scheduleTime":     "2030-01-02T15:04:05+01:00",
+				"taskId":           "order-1",
+			},
+		})
+
+		require.NoError(t, err)
+		task := received.Task
+		assert.Equal(t, testQueue+"/tasks/order-1", task.Name)
+		assert.Equal(t, "https://example.com/other", task.HttpRequest.Url)
+		assert.Equal(t, "PUT", task.HttpRequest.HttpMethod)
+		assert.Equal(t, "https://example.com", task.HttpRequest.OidcToken.Audience)
+		assert.Equal(t, "20s", task.DispatchDeadline)
+		assert.Equal(t, "2030-01-02T14:04:05Z", task.ScheduleTime)
+	})
+
+	t.Run("delay", func(t *testing.T) {
+		_, err := c.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Metadata:  map[string]string{"delay": "1h"},
+		})
+
+		require.NoError(t, err)
+		scheduleTime, err := time.Parse(time.RFC3339Nano, received.Task.ScheduleTime)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), scheduleTime, time.Minute)
+	})
+
+	t.Run("schedule time and delay are exclusive", func(t *testing.T) {
+		_, err := c.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.CreateOperation,
+			Metadata:  map[string]string{"scheduleTime": "2030-01-02T15:04:05Z", "delay": "1h"},
+		})
+
+		assert.EqualError(t, err, "cloudtasks binding error: scheduleTime and delay are exclusive")
+	})
+}
+
+func TestCreateWithoutURL(t *testing.T) {
+	c := newTestCloudTasks(t, &cloudTasksMetadata{}, func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("unexpected request")
+	})
+
+	_, err := c.Invoke(context.Background(), &bindings.InvokeRequest{Operation: bindings.CreateOperation})
+
+	assert.ErrorContains(t, err, "required metadata not set: url")
+}
+
+func TestDelete(t *testing.T) {
+	var paths []string
+	c := newTestCloudTasks(t, &cloudTasksMetadata{}, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte(`{}`))
+	})
+
+	for _, id := range []string{"order-1", testQueue + "/tasks/order-2"} {
+		_, err := c.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.DeleteOperation,
+			Metadata:  map[string]string{"taskId": id},
+		})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"/v2/" + testQueue + "/tasks/order-1", "/v2/" + testQueue + "/tasks/order-2"}, paths)
+
+	_, err := c.Invoke(context.Background(), &bindings.InvokeRequest{Operation: bindings.DeleteOperation})
+	assert.ErrorContains(t, err, "required metadata not set: taskId")
+}
+
+func TestUpdateRetryConfig(t *testing.T) {
+	m, _, err := parseMetadata(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"project_id":             "myproject",
+		"location":               "europe-west1",
+		"queue":                  "myqueue",
+		"updateQueueRetryConfig": "true",
+		"maxAttempts":            "5",
+		"maxBackoff":             "1m",
+	}}})
+	require.NoError(t, err)
+
+	var (
+		query    string
+		received map[string]any
+	)
+	c := newTestCloudTasks(t, m, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPatch, r.Method)
+		assert.Equal(t, "/v2/"+testQueue, r.URL.Path)
+		query = r.URL.Query().Get("updateMask")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.Write([]byte(`{}`))
+	})
+
+	require.NoError(t, c.updateRetryConfig(context.Background()))
+	assert.Equal(t, "retryConfig.maxAttempts,retryConfig.maxBackoff", query)
+	assert.Equal(t, map[string]any{"retryConfig": map[string]any{"maxAttempts": float64(5), "maxBackoff": "60s"}}, received)
+}
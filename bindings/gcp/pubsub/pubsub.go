@@ -19,9 +19,9 @@ import (
 	"fmt"
 
 	"cloud.google.com/go/pubsub"
-	"google.golang.org/api/option"
 
 	"github.com/dapr/components-contrib/bindings"
+	gcpauth "github.com/dapr/components-contrib/internal/authentication/gcp"
 	"github.com/dapr/kit/logger"
 )
 
@@ -60,6 +60,10 @@ func NewGCPPubSub(logger logger.Logger) bindings.InputOutputBinding {
 
 // Init parses metadata and creates a new Pub Sub client.
 func (g *GCPPubSub) Init(metadata bindings.Metadata) error {
+	if err := bindings.ValidateConfirmedDelivery(metadata.Properties); err != nil {
+		return err
+	}
+
 	b, err := g.parseMetadata(metadata)
 	if err != nil {
 		return err
@@ -70,9 +74,16 @@ func (g *GCPPubSub) Init(metadata bindings.Metadata) error {
 	if err != nil {
 		return err
 	}
-	clientOptions := option.WithCredentialsJSON(b)
+	key, err := gcpauth.KeyFromJSON(b)
+	if err != nil {
+		return err
+	}
+	clientOptions, err := gcpauth.ClientOptions(key)
+	if err != nil {
+		return fmt.Errorf("error creating pubsub client: %w", err)
+	}
 	ctx := context.Background()
-	pubsubClient, err := pubsub.NewClient(ctx, pubsubMeta.ProjectID, clientOptions)
+	pubsubClient, err := pubsub.NewClient(ctx, pubsubMeta.ProjectID, clientOptions...)
 	if err != nil {
 		return fmt.Errorf("error creating pubsub client: %s", err)
 	}
@@ -0,0 +1,189 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package geocoding
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const azureMapsAPIVersion = "1.0"
+
+// azureMaps is the Azure Maps Search and Route services, authenticated with a subscription key.
+type azureMaps struct {
+	client   *httpClient
+	endpoint string
+	apiKey   string
+}
+
+type azureMapsPosition struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+type azureMapsAddress struct {
+	FreeformAddress string `json:"freeformAddress"`
+}
+
+type azureMapsSearchResponse struct {
+	Results []struct {
+		Address  azureMapsAddress  `json:"address"`
+		Position azureMapsPosition `json:"position"`
+	} `json:"results"`
+}
+
+type azureMapsReverseResponse struct {
+	Addresses []struct {
+		Address azureMapsAddress `json:"address"`
+		// Position as "lat,lon"
+		Position string `json:"position"`
+	} `json:"addresses"`
+}
+
+type azureMapsRouteResponse struct {
+	Routes []struct {
+		Summary struct {
+			LengthInMeters      float64 `json:"lengthInMeters"`
+			TravelTimeInSeconds float64 `json:"travelTimeInSeconds"`
+		} `json:"summary"`
+		Legs []struct {
+			Points []struct {
+				Latitude  float64 `json:"latitude"`
+				Longitude float64 `json:"longitude"`
+			} `json:"points"`
+		} `json:"legs"`
+	} `json:"routes"`
+}
+
+func (a *azureMaps) geocode(ctx context.Context, address string, opts queryOptions) ([]Location, error) {
+	q := a.query(opts)
+	q.Set("query", address)
+	q.Set("limit", strconv.Itoa(opts.maxResults))
+
+	var res azureMapsSearchResponse
+	if err := a.get(ctx, "/search/address/json", q, &res); err != nil {
+		return nil, err
+	}
+
+	locations := make([]Location, 0, len(res.Results))
+	for _, r := range res.Results {
+		locations = append(locations, Location{
+			Coordinates: Coordinates{Lat: r.Position.Lat, Lon: r.Position.Lon},
+			Address:     r.Address.FreeformAddress,
+		})
+	}
+
+	return locations, nil
+}
+
+func (a *azureMaps) reverseGeocode(ctx context.Context, point Coordinates, opts queryOptions) ([]Location, error) {
+	q := a.query(opts)
+	q.Set("query", formatPoint(point))
+
+	var res azureMapsReverseResponse
+	if err := a.get(ctx, "/search/address/reverse/json", q, &res); err != nil {
+		return nil, err
+	}
+
+	locations := make([]Location, 0, len(res.Addresses))
+	for _, r := range res.Addresses {
+		coordinates, err := parsePoint(r.Position)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, Location{
+			Coordinates: coordinates,
+			Address:     r.Address.FreeformAddress,
+		})
+		if len(locations) == opts.maxResults {
+			break
+		}
+	}
+
+	return locations, nil
+}
+
+func (a *azureMaps) route(ctx context.Context, points []Coordinates, opts queryOptions) (*Route, error) {
+	waypoints := make([]string, len(points))
+	for i, p := range points {
+		waypoints[i] = formatPoint(p)
+	}
+	q := a.query(opts)
+	q.Set("query", strings.Join(waypoints, ":"))
+
+	var res azureMapsRouteResponse
+	if err := a.get(ctx, "/route/directions/json", q, &res); err != nil {
+		return nil, err
+	}
+	if len(res.Routes) == 0 {
+		return nil, fmt.Errorf("no route found")
+	}
+
+	r := res.Routes[0]
+	route := &Route{
+		DistanceInMeters:  r.Summary.LengthInMeters,
+		DurationInSeconds: r.Summary.TravelTimeInSeconds,
+		Points:            []Coordinates{},
+	}
+	for _, leg := range r.Legs {
+		for _, p := range leg.Points {
+			route.Points = append(route.Points, Coordinates{Lat: p.Latitude, Lon: p.Longitude})
+		}
+	}
+
+	return route, nil
+}
+
+func (a *azureMaps) query(opts queryOptions) url.Values {
+	q := url.Values{}
+	q.Set("api-version", azureMapsAPIVersion)
+	if opts.language != "" {
+		q.Set("language", opts.language)
+	}
+
+	return q
+}
+
+func (a *azureMaps) get(ctx context.Context, path string, q url.Values, v interface{}) error {
+	header := http.Header{}
+	header.Set("subscription-key", a.apiKey)
+
+	return a.client.getJSON(ctx, a.endpoint+path+"?"+q.Encode(), header, v)
+}
+
+func formatPoint(p Coordinates) string {
+	return strconv.FormatFloat(p.Lat, 'f', -1, 64) + "," + strconv.FormatFloat(p.Lon, 'f', -1, 64)
+}
+
+// parsePoint parses a point formatted as "lat,lon".
+func parsePoint(s string) (Coordinates, error) {
+	latStr, lonStr, ok := strings.Cut(s, ",")
+	if !ok {
+		return Coordinates{}, fmt.Errorf("invalid position %s", s)
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+	if err != nil {
+		return Coordinates{}, fmt.Errorf("invalid position %s", s)
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(lonStr), 64)
+	if err != nil {
+		return Coordinates{}, fmt.Errorf("invalid position %s", s)
+	}
+
+	return Coordinates{Lat: lat, Lon: lon}, nil
+}
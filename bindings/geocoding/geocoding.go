@@ -0,0 +1,374 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package geocoding
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/ratelimit"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// geocodeOperation returns the locations matching the address of the request.
+	geocodeOperation bindings.OperationKind = "geocode"
+	// reverseGeocodeOperation returns the addresses of the coordinates of the request.
+	reverseGeocodeOperation bindings.OperationKind = "reverseGeocode"
+	// routeOperation returns the route between the points of the request.
+	routeOperation bindings.OperationKind = "route"
+
+	providerAzureMaps = "azuremaps"
+	providerNominatim = "nominatim"
+
+	// Request metadata keys, overriding the values of the component metadata.
+	metadataKeyLanguage   = "language"
+	metadataKeyMaxResults = "maxResults"
+
+	defaultAzureMapsEndpoint = "https://atlas.microsoft.com"
+	defaultNominatimEndpoint = "https://nominatim.openstreetmap.org"
+	// The usage policy of the public Nominatim instance allows at most one request per second.
+	defaultNominatimRequestsPerSecond = 1
+	defaultMaxResults                 = 5
+	defaultTimeout                    = 30 * time.Second
+)
+
+// Coordinates are the WGS 84 coordinates of a point.
+type Coordinates struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// Location is a location found by a geocoding.
+type Location struct {
+	Coordinates
+	Address string `json:"address"`
+}
+
+// Route is the route between points.
+type Route struct {
+	DistanceInMeters  float64       `json:"distanceInMeters"`
+	DurationInSeconds float64       `json:"durationInSeconds"`
+	Points            []Coordinates `json:"points"`
+}
+
+// geocodeRequest is the data of the geocode requests.
+type geocodeRequest struct {
+	Address string `json:"address"`
+}
+
+// routeRequest is the data of the route requests.
+type routeRequest struct {
+	Points []Coordinates `json:"points"`
+}
+
+// locationsResponse is the data of the responses of the geocode and reverseGeocode operations.
+type locationsResponse struct {
+	Locations []Location `json:"locations"`
+}
+
+// provider is a geocoding service.
+type provider interface {
+	geocode(ctx context.Context, address string, opts queryOptions) ([]Location, error)
+	reverseGeocode(ctx context.Context, point Coordinates, opts queryOptions) ([]Location, error)
+	// route returns the route between the points, or errUnsupported.
+	route(ctx context.Context, points []Coordinates, opts queryOptions) (*Route, error)
+}
+
+type queryOptions struct {
+	language   string
+	maxResults int
+}
+
+var errUnsupported = errors.New("operation not supported by the provider")
+
+// Geocoding is an output binding geocoding addresses and computing routes with Azure Maps or a Nominatim service.
+type Geocoding struct {
+	metadata *geocodingMetadata
+	provider provider
+	limiter  ratelimit.Limiter
+
+	logger logger.Logger
+}
+
+type geocodingMetadata struct {
+	// Geocoding service, azuremaps or nominatim.
+	Provider string `mapstructure:"provider"`
+	// URL of the service; by default the Azure Maps API or the public Nominatim instance.
+	Endpoint string `mapstructure:"endpoint"`
+	// Subscription key of Azure Maps, or key of the Nominatim service if it requires one.
+	APIKey string `mapstructure:"apiKey" mdsensitive:"true"`
+	// Maximum number of requests per second sent to the service; unlimited if 0, except with the public Nominatim
+	// instance.
+	RequestsPerSecond int `mapstructure:"requestsPerSecond"`
+	// Preferred language of the addresses, as an IETF language tag.
+	Language   string `mapstructure:"language"`
+	MaxResults int    `mapstructure:"maxResults"`
+	// User agent sent to the service, which Nominatim requires to identify the application.
+	UserAgent    string `mapstructure:"userAgent"`
+	TimeoutInSec int    `mapstructure:"timeoutInSec"`
+}
+
+// NewGeocoding returns a new geocoding output binding.
+func NewGeocoding(logger logger.Logger) bindings.OutputBinding {
+	return &Geocoding{logger: logger}
+}
+
+// Init parses the metadata and creates the client of the provider.
+func (g *Geocoding) Init(md bindings.Metadata) error {
+	m, err := parseMetadata(md.Properties)
+	if err != nil {
+		return err
+	}
+
+	c := &httpClient{
+		httpClient: &http.Client{
+			Timeout: time.Duration(m.TimeoutInSec) * time.Second,
+		},
+		userAgent: m.UserAgent,
+	}
+	switch m.Provider {
+	case providerAzureMaps:
+		g.provider = &azureMaps{client: c, endpoint: m.Endpoint, apiKey: m.APIKey}
+	case providerNominatim:
+		g.provider = &nominatim{client: c, endpoint: m.Endpoint, apiKey: m.APIKey}
+	}
+
+	if m.RequestsPerSecond > 0 {
+		g.limiter = ratelimit.New(m.RequestsPerSecond)
+	} else {
+		g.limiter = ratelimit.NewUnlimited()
+	}
+	g.metadata = m
+
+	return nil
+}
+
+func parseMetadata(md map[string]string) (*geocodingMetadata, error) {
+	m := geocodingMetadata{
+		Provider:     providerAzureMaps,
+		MaxResults:   defaultMaxResults,
+		UserAgent:    "dapr-" + logger.DaprVersion,
+		TimeoutInSec: int(defaultTimeout / time.Second),
+	}
+	err := metadata.DecodeMetadata(md, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Provider = strings.ToLower(m.Provider)
+	switch m.Provider {
+	case providerAzureMaps:
+		if m.APIKey == "" {
+			return nil, errors.New("geocoding binding error: apiKey is required with Azure Maps")
+		}
+		if m.Endpoint == "" {
+			m.Endpoint = defaultAzureMapsEndpoint
+		}
+	case providerNominatim:
+		if m.Endpoint == "" {
+			m.Endpoint = defaultNominatimEndpoint
+			if m.RequestsPerSecond == 0 {
+				m.RequestsPerSecond = defaultNominatimRequestsPerSecond
+			}
+		}
+	default:
+		return nil, fmt.Errorf("geocoding binding error: invalid provider %s, supported values are %s and %s", m.Provider, providerAzureMaps, providerNominatim)
+	}
+	m.Endpoint = strings.TrimSuffix(m.Endpoint, "/")
+
+	if m.RequestsPerSecond < 0 {
+		return nil, fmt.Errorf("geocoding binding error: invalid requestsPerSecond %d", m.RequestsPerSecond)
+	}
+	if m.MaxResults < 1 {
+		return nil, fmt.Errorf("geocoding binding error: invalid maxResults %d", m.MaxResults)
+	}
+	if m.TimeoutInSec < 1 {
+		return nil, fmt.Errorf("geocoding binding error: invalid timeoutInSec %d", m.TimeoutInSec)
+	}
+
+	return &m, nil
+}
+
+func (g *Geocoding) Operations() []bindings.OperationKind {
+	if g.metadata != nil && g.metadata.Provider == providerNominatim {
+		return []bindings.OperationKind{geocodeOperation, reverseGeocodeOperation}
+	}
+
+	return []bindings.OperationKind{geocodeOperation, reverseGeocodeOperation, routeOperation}
+}
+
+func (g *Geocoding) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	opts := queryOptions{
+		language:   g.metadata.Language,
+		maxResults: g.metadata.MaxResults,
+	}
+	if val := req.Metadata[metadataKeyLanguage]; val != "" {
+		opts.language = val
+	}
+	if val := req.Metadata[metadataKeyMaxResults]; val != "" {
+		maxResults, err := strconv.Atoi(val)
+		if err != nil || maxResults < 1 {
+			return nil, fmt.Errorf("geocoding binding error: invalid %s %s", metadataKeyMaxResults, val)
+		}
+		opts.maxResults = maxResults
+	}
+
+	var (
+		res interface{}
+		err error
+	)
+	switch req.Operation {
+	case geocodeOperation:
+		res, err = g.geocode(ctx, req.Data, opts)
+	case reverseGeocodeOperation:
+		res, err = g.reverseGeocode(ctx, req.Data, opts)
+	case routeOperation:
+		res, err = g.route(ctx, req.Data, opts)
+	default:
+		return nil, fmt.Errorf("geocoding binding error: unsupported operation %s", req.Operation)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("geocoding binding error: %s failed: %w", req.Operation, err)
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bindings.InvokeResponse{
+		Data: data,
+		Metadata: map[string]string{
+			"provider": g.metadata.Provider,
+		},
+	}, nil
+}
+
+func (g *Geocoding) geocode(ctx context.Context, data []byte, opts queryOptions) (*locationsResponse, error) {
+	var req geocodeRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if req.Address == "" {
+		return nil, errors.New("address is required")
+	}
+
+	g.limiter.Take()
+	locations, err := g.provider.geocode(ctx, req.Address, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &locationsResponse{Locations: locations}, nil
+}
+
+func (g *Geocoding) reverseGeocode(ctx context.Context, data []byte, opts queryOptions) (*locationsResponse, error) {
+	var point Coordinates
+	if err := json.Unmarshal(data, &point); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if err := validateCoordinates(point); err != nil {
+		return nil, err
+	}
+
+	g.limiter.Take()
+	locations, err := g.provider.reverseGeocode(ctx, point, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &locationsResponse{Locations: locations}, nil
+}
+
+func (g *Geocoding) route(ctx context.Context, data []byte, opts queryOptions) (*Route, error) {
+	var req routeRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if len(req.Points) < 2 {
+		return nil, errors.New("at least two points are required")
+	}
+	for _, p := range req.Points {
+		if err := validateCoordinates(p); err != nil {
+			return nil, err
+		}
+	}
+
+	g.limiter.Take()
+	return g.provider.route(ctx, req.Points, opts)
+}
+
+func validateCoordinates(p Coordinates) error {
+	if p.Lat < -90 || p.Lat > 90 || p.Lon < -180 || p.Lon > 180 {
+		return fmt.Errorf("invalid coordinates %v,%v", p.Lat, p.Lon)
+	}
+
+	return nil
+}
+
+// GetComponentMetadataSchema returns the schema of the metadata of the geocoding binding.
+func (g *Geocoding) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(geocodingMetadata{
+		Provider:     providerAzureMaps,
+		MaxResults:   defaultMaxResults,
+		TimeoutInSec: int(defaultTimeout / time.Second),
+	})
+	return fields
+}
+
+// httpClient sends the requests to the provider.
+type httpClient struct {
+	httpClient *http.Client
+	userAgent  string
+}
+
+// getJSON sends a GET request to url and decodes the JSON response in v.
+func (c *httpClient) getJSON(ctx context.Context, url string, header http.Header, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	for k, vals := range header {
+		req.Header[k] = vals
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return json.Unmarshal(body, v)
+}
@@ -0,0 +1,299 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package geocoding
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+func TestParseMetadata(t *testing.T) {
+	t.Run("Azure Maps defaults", func(t *testing.T) {
+		m, err := parseMetadata(map[string]string{"apiKey": "key"})
+
+		require.NoError(t, err)
+		assert.Equal(t, providerAzureMaps, m.Provider)
+		assert.Equal(t, defaultAzureMapsEndpoint, m.Endpoint)
+		assert.Equal(t, 0, m.RequestsPerSecond)
+		assert.Equal(t, 5, m.MaxResults)
+		assert.Equal(t, 30, m.TimeoutInSec)
+	})
+
+	t.Run("public Nominatim instance is rate limited", func(t *testing.T) {
+		m, err := parseMetadata(map[string]string{"provider": "Nominatim"})
+
+		require.NoError(t, err)
+		assert.Equal(t, providerNominatim, m.Provider)
+		assert.Equal(t, defaultNominatimEndpoint, m.Endpoint)
+		assert.Equal(t, 1, m.RequestsPerSecond)
+	})
+
+	t.Run("own Nominatim instance", func(t *testing.T) {
+		m, err := parseMetadata(map[string]string{
+			"provider":          "nominatim",
+			"endpoint":          "https://nominatim.example.com/",
+			"requestsPerSecond": "0",
+			"language":          "fr",
+			"maxResults":        "10",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "https://nominatim.example.com", m.Endpoint)
+		assert.Equal(t, 0, m.RequestsPerSecond)
+		assert.Equal(t, "fr", m.Language)
+		assert.Equal(t, 10, m.MaxResults)
+	})
+
+	t.Run("invalid metadata", func(t *testing.T) {
+		tests := map[string]struct {
+			md  map[string]string
+			err string
+		}{
+			"missing Azure Maps key": {
+				md:  map[string]string{},
+				err: "geocoding binding error: apiKey is required with Azure Maps",
+			},
+			"invalid provider": {
+				md:  map[string]string{"provider": "other"},
+				err: "geocoding binding error: invalid provider other, supported values are azuremaps and nominatim",
+			},
+			"negative requestsPerSecond": {
+				md:  map[string]string{"provider": "nominatim", "requestsPerSecond": "-1"},
+				err: "geocoding binding error: invalid requestsPerSecond -1",
+			},
+			"invalid maxResults": {
+				md:  map[string]string{"provider": "nominatim", "maxResults": "0"},
+				err: "geocoding binding error: invalid maxResults 0",
+			},
+			"invalid timeoutInSec": {
+				md:  map[string]string{"provider": "nominatim", "timeoutInSec": "0"},
+				err: "geocoding binding error: invalid timeoutInSec 0",
+			},
+		}
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				_, err := parseMetadata(tt.md)
+				assert.EqualError(t, err, tt.err)
+			})
+		}
+	})
+}
+
+// newBinding returns a binding sending its requests to a server serving the responses by path, and recording the
+// requests.
+func newBinding(t *testing.T, props map[string]string, responses map[string]string) (*Geocoding, *[]*http.Request) {
+	t.Helper()
+
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		res, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(res))
+	}))
+	t.Cleanup(server.Close)
+
+	props["endpoint"] = server.URL
+	g := NewGeocoding(logger.NewLogger("test")).(*Geocoding)
+	require.NoError(t, g.Init(bindings.Metadata{Base: metadata.Base{Properties: props}}))
+
+	return g, &requests
+}
+
+func invoke(t *testing.T, g *Geocoding, op bindings.OperationKind, data string, md map[string]string) (string, error) {
+	t.Helper()
+
+	res, err := g.Invoke(context.Background(), &bindings.InvokeRequest{
+		Operation: op,
+		Data:      []byte(data),
+		Metadata:  md,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(res.Data), nil
+}
+
+func TestAzureMaps(t *testing.T) {
+	g, requests := newBinding(t, map[string]string{"apiKey": "secret", "language": "en-US"}, map[string]string{
+		"/search/address/json": `{"results": [
+			{"address": {"freeformAddress": "1 Microsoft Way, Redmond, WA 98052"}, "position": {"lat": 47.63962, "lon": -122.12911}}
+		]}`,
+		"/search/address/reverse/json": `{"addresses": [
+			{"address": {"freeformAddress": "1 Microsoft Way, Redmond, WA 98052"}, "position": "47.639620,-122.129110"}
+		]}`,
+		"/route/directions/json": `{"routes": [{
+			"summary": {"lengthInMeters": 1147, "travelTimeInSeconds": 162},
+			"legs": [{"points": [{"latitude": 52.50931, "longitude": 13.42937}, {"latitude": 52.50904, "longitude": 13.42912}]}]
+		}]}`,
+	})
+
+	assert.Equal(t, []bindings.OperationKind{geocodeOperation, reverseGeocodeOperation, routeOperation}, g.Operations())
+
+	t.Run("geocode", func(t *testing.T) {
+		res, err := invoke(t, g, geocodeOperation, `{"address": "1 Microsoft Way, Redmond"}`, map[string]string{"maxResults": "1"})
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"locations": [{"lat": 47.63962, "lon": -122.12911, "address": "1 Microsoft Way, Redmond, WA 98052"}]}`, res)
+		req := (*requests)[len(*requests)-1]
+		assert.Equal(t, "secret", req.Header.Get("subscription-key"))
+		assert.Equal(t, url.Values{
+			"api-version": {"1.0"},
+			"query":       {"1 Microsoft Way, Redmond"},
+			"limit":       {"1"},
+			"language":    {"en-US"},
+		}, req.URL.Query())
+	})
+
+	t.Run("reverse geocode", func(t *testing.T) {
+		res, err := invoke(t, g, reverseGeocodeOperation, `{"lat": 47.63962, "lon": -122.12911}`, nil)
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"locations": [{"lat": 47.63962, "lon": -122.12911, "address": "1 Microsoft Way, Redmond, WA 98052"}]}`, res)
+		assert.Equal(t, "47.63962,-122.12911", (*requests)[len(*requests)-1].URL.Query().Get("query"))
+	})
+
+	t.Run("route", func(t *testing.T) {
+		res, err := invoke(t, g, routeOperation, `{"points": [{"lat": 52.50931, "lon": 13.42936}, {"lat": 52.50274, "lon": 13.43872}]}`, nil)
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"distanceInMeters": 1147, "durationInSeconds": 162, "points": [{"lat": 52.50931, "lon": 13.42937}, {"lat": 52.50904, "lon": 13.42912}]}`, res)
+		assert.Equal(t, "52.50931,13.42936:52.50274,13.43872", (*requests)[len(*requests)-1].URL.Query().Get("query"))
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		tests := map[string]struct {
+			op   bindings.OperationKind
+			data string
+			md   map[string]string
+			err  string
+		}{
+			"missing address": {
+				op:   geocodeOperation,
+				data: `{}`,
+				err:  "geocoding binding error: geocode failed: address is required",
+			},
+			"invalid JSON": {
+				op:   geocodeOperation,
+				data: `not json`,
+				err:  "geocoding binding error: geocode failed: invalid request: invalid character 'o' in literal null (expecting 'u')",
+			},
+			"invalid maxResults": {
+				op:   geocodeOperation,
+				data: `{"address": "a"}`,
+				md:   map[string]string{"maxResults": "none"},
+				err:  "geocoding binding error: invalid maxResults none",
+			},
+			"latitude out of range": {
+				op:   reverseGeocodeOperation,
+				data: `{"lat": 91, "lon": 0}`,
+				err:  "geocoding binding error: reverseGeocode failed: invalid coordinates 91,0",
+			},
+			"route with a single point": {
+				op:   routeOperation,
+				data: `{"points": [{"lat": 0, "lon": 0}]}`,
+				err:  "geocoding binding error: route failed: at least two points are required",
+			},
+			"unsupported operation": {
+				op:   "delete",
+				data: `{}`,
+				err:  "geocoding binding error: unsupported operation delete",
+			},
+		}
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				_, err := invoke(t, g, tt.op, tt.data, tt.md)
+				assert.EqualError(t, err, tt.err)
+			})
+		}
+	})
+}
+
+func TestNominatim(t *testing.T) {
+	g, requests := newBinding(t, map[string]string{"provider": "nominatim", "apiKey": "key", "userAgent": "myapp"}, map[string]string{
+		"/search": `[
+			{"lat": "48.8582599", "lon": "2.2945006", "display_name": "Tour Eiffel, Paris, France"},
+			{"lat": "36.1124", "lon": "-115.1728", "display_name": "Eiffel Tower, Las Vegas, United States"}
+		]`,
+		"/reverse": `{"lat": "48.8582599", "lon": "2.2945006", "display_name": "Tour Eiffel, Paris, France"}`,
+	})
+
+	assert.Equal(t, []bindings.OperationKind{geocodeOperation, reverseGeocodeOperation}, g.Operations())
+
+	t.Run("geocode", func(t *testing.T) {
+		res, err := invoke(t, g, geocodeOperation, `{"address": "Eiffel Tower"}`, map[string]string{"language": "fr"})
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"locations": [
+			{"lat": 48.8582599, "lon": 2.2945006, "address": "Tour Eiffel, Paris, France"},
+			{"lat": 36.1124, "lon": -115.1728, "address": "Eiffel Tower, Las Vegas, United States"}
+		]}`, res)
+		req := (*requests)[len(*requests)-1]
+		assert.Equal(t, "myapp", req.Header.Get("User-Agent"))
+		assert.Equal(t, url.Values{
+			"q":               {"Eiffel Tower"},
+			"format":          {"jsonv2"},
+			"limit":           {"5"},
+			"accept-language": {"fr"},
+			"key":             {"key"},
+		}, req.URL.Query())
+	})
+
+	t.Run("reverse geocode", func(t *testing.T) {
+		res, err := invoke(t, g, reverseGeocodeOperation, `{"lat": 48.85826, "lon": 2.2945}`, nil)
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"locations": [{"lat": 48.8582599, "lon": 2.2945006, "address": "Tour Eiffel, Paris, France"}]}`, res)
+	})
+
+	t.Run("route is not supported", func(t *testing.T) {
+		_, err := invoke(t, g, routeOperation, `{"points": [{"lat": 0, "lon": 0}, {"lat": 1, "lon": 1}]}`, nil)
+
+		assert.ErrorIs(t, err, errUnsupported)
+	})
+}
+
+func TestNominatimNoResult(t *testing.T) {
+	g, _ := newBinding(t, map[string]string{"provider": "nominatim"}, map[string]string{
+		"/reverse": `{"error": "Unable to geocode"}`,
+	})
+
+	res, err := invoke(t, g, reverseGeocodeOperation, `{"lat": 0, "lon": 0}`, nil)
+
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"locations": []}`, res)
+}
+
+func TestServiceError(t *testing.T) {
+	g, _ := newBinding(t, map[string]string{"apiKey": "key"}, map[string]string{})
+
+	_, err := invoke(t, g, geocodeOperation, `{"address": "somewhere"}`, nil)
+
+	assert.ErrorContains(t, err, "unexpected status 404")
+}
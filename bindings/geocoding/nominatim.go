@@ -0,0 +1,115 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package geocoding
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// nominatim is an OpenStreetMap Nominatim service, which doesn't compute routes.
+type nominatim struct {
+	client   *httpClient
+	endpoint string
+	// Key of the service, for the hosted instances which require one
+	apiKey string
+}
+
+type nominatimPlace struct {
+	// Coordinates, as strings
+	Lat         string `json:"lat"`
+	Lon         string `json:"lon"`
+	DisplayName string `json:"display_name"`
+	// Set instead of the place if there is no result
+	Error string `json:"error"`
+}
+
+func (n *nominatim) geocode(ctx context.Context, address string, opts queryOptions) ([]Location, error) {
+	q := n.query(opts)
+	q.Set("q", address)
+	q.Set("limit", strconv.Itoa(opts.maxResults))
+
+	var res []nominatimPlace
+	if err := n.client.getJSON(ctx, n.endpoint+"/search?"+q.Encode(), nil, &res); err != nil {
+		return nil, err
+	}
+
+	locations := make([]Location, 0, len(res))
+	for _, p := range res {
+		location, err := p.toLocation()
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, location)
+	}
+
+	return locations, nil
+}
+
+func (n *nominatim) reverseGeocode(ctx context.Context, point Coordinates, opts queryOptions) ([]Location, error) {
+	q := n.query(opts)
+	q.Set("lat", strconv.FormatFloat(point.Lat, 'f', -1, 64))
+	q.Set("lon", strconv.FormatFloat(point.Lon, 'f', -1, 64))
+
+	var res nominatimPlace
+	if err := n.client.getJSON(ctx, n.endpoint+"/reverse?"+q.Encode(), nil, &res); err != nil {
+		return nil, err
+	}
+	if res.Error != "" {
+		// Nothing at these coordinates, such as in the middle of an ocean
+		return []Location{}, nil
+	}
+
+	location, err := res.toLocation()
+	if err != nil {
+		return nil, err
+	}
+
+	return []Location{location}, nil
+}
+
+func (n *nominatim) route(ctx context.Context, points []Coordinates, opts queryOptions) (*Route, error) {
+	return nil, errUnsupported
+}
+
+func (n *nominatim) query(opts queryOptions) url.Values {
+	q := url.Values{}
+	q.Set("format", "jsonv2")
+	if opts.language != "" {
+		q.Set("accept-language", opts.language)
+	}
+	if n.apiKey != "" {
+		q.Set("key", n.apiKey)
+	}
+
+	return q
+}
+
+func (p nominatimPlace) toLocation() (Location, error) {
+	lat, err := strconv.ParseFloat(p.Lat, 64)
+	if err != nil {
+		return Location{}, fmt.Errorf("invalid latitude %s", p.Lat)
+	}
+	lon, err := strconv.ParseFloat(p.Lon, 64)
+	if err != nil {
+		return Location{}, fmt.Errorf("invalid longitude %s", p.Lon)
+	}
+
+	return Location{
+		Coordinates: Coordinates{Lat: lat, Lon: lon},
+		Address:     p.DisplayName,
+	}, nil
+}
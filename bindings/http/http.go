@@ -16,10 +16,12 @@ package http
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -28,6 +30,7 @@ import (
 	"github.com/mitchellh/mapstructure"
 
 	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/internal/authentication/clientcert"
 	"github.com/dapr/components-contrib/internal/utils"
 	"github.com/dapr/kit/logger"
 )
@@ -39,6 +42,7 @@ type HTTPSource struct {
 	metadata      httpMetadata
 	client        *http.Client
 	errorIfNot2XX bool
+	clientCert    *clientcert.Source
 	logger        logger.Logger
 }
 
@@ -53,7 +57,8 @@ func NewHTTP(logger logger.Logger) bindings.OutputBinding {
 
 // Init performs metadata parsing.
 func (h *HTTPSource) Init(metadata bindings.Metadata) error {
-	if err := mapstructure.Decode(metadata.Properties, &h.metadata); err != nil {
+	err := mapstructure.Decode(metadata.Properties, &h.metadata)
+	if err != nil {
 		return err
 	}
 
@@ -66,6 +71,22 @@ func (h *HTTPSource) Init(metadata bindings.Metadata) error {
 		Dial:                dialer.Dial,
 		TLSHandshakeTimeout: 5 * time.Second,
 	}
+	// The client certificates for mTLS are rotated by their source
+	h.clientCert, err = clientcert.New(metadata.Properties, h.logger)
+	if err != nil {
+		return err
+	}
+	if h.clientCert != nil {
+		// The server certificate is verified with the host of the URL, even if it's an IP address
+		u, err := url.Parse(h.metadata.URL)
+		if err != nil {
+			return fmt.Errorf("invalid url %s: %w", h.metadata.URL, err)
+		}
+		netTransport.TLSClientConfig = h.clientCert.TLSConfig(&tls.Config{
+			MinVersion: tls.VersionTLS12,
+			ServerName: u.Hostname(),
+		})
+	}
 	h.client = &http.Client{
 		Timeout:   time.Second * 30,
 		Transport: netTransport,
@@ -81,6 +102,14 @@ func (h *HTTPSource) Init(metadata bindings.Metadata) error {
 	return nil
 }
 
+// Close stops the rotation of the client certificates.
+func (h *HTTPSource) Close() error {
+	if h.clientCert != nil {
+		return h.clientCert.Close()
+	}
+	return nil
+}
+
 // Operations returns the supported operations for this binding.
 func (h *HTTPSource) Operations() []bindings.OperationKind {
 	return []bindings.OperationKind{
@@ -127,7 +156,11 @@ func (h *HTTPSource) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*
 	}
 	switch method {
 	case "PUT", "POST", "PATCH":
-		body = bytes.NewBuffer(req.Data)
+		data, err := req.BinaryData()
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewBuffer(data)
 	case "GET", "HEAD", "DELETE", "OPTIONS", "TRACE":
 	default:
 		return nil, fmt.Errorf("invalid operation: %s", req.Operation)
@@ -141,7 +174,11 @@ func (h *HTTPSource) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*
 	// Set default values for Content-Type and Accept headers.
 	if body != nil {
 		if _, ok := req.Metadata["Content-Type"]; !ok {
-			request.Header.Set("Content-Type", "application/json; charset=utf-8")
+			if contentType := req.Metadata[bindings.ContentTypeMetadataKey]; contentType != "" {
+				request.Header.Set("Content-Type", contentType)
+			} else {
+				request.Header.Set("Content-Type", "application/json; charset=utf-8")
+			}
 		}
 	}
 	if _, ok := req.Metadata["Accept"]; !ok {
@@ -171,7 +208,7 @@ func (h *HTTPSource) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*
 		return nil, err
 	}
 
-	metadata := make(map[string]string, len(resp.Header)+2)
+	metadata := make(map[string]string, len(resp.Header)+3)
 	// Include status code & desc
 	metadata["statusCode"] = strconv.Itoa(resp.StatusCode)
 	metadata["status"] = resp.Status
@@ -181,6 +218,9 @@ func (h *HTTPSource) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*
 	for key, values := range resp.Header {
 		metadata[key] = strings.Join(values, ", ")
 	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		metadata[bindings.ContentTypeMetadataKey] = contentType
+	}
 
 	// Create an error for non-200 status codes unless suppressed.
 	if errorIfNot2XX && resp.StatusCode/100 != 2 {
@@ -15,12 +15,23 @@ package http_test
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"io"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -364,3 +375,90 @@ func TestNon2XXErrorsSuppressed(t *testing.T) {
 		})
 	}
 }
+
+func TestClientCertSource(t *testing.T) {
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	s.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert, MinVersion: tls.VersionTLS12}
+	s.StartTLS()
+	defer s.Close()
+
+	// The server certificate is verified with the CA certificates of the source
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw}), 0o600))
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "dapr"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	hs, err := InitBinding(s, map[string]string{
+		"clientCertSource": "files",
+		"clientCertFile":   certFile,
+		"clientKeyFile":    keyFile,
+		"caCertFile":       caFile,
+	})
+	require.NoError(t, err)
+	defer hs.(io.Closer).Close()
+
+	resp, err := hs.Invoke(context.Background(), &bindings.InvokeRequest{Operation: "get"})
+	require.NoError(t, err)
+	assert.Equal(t, "dapr", string(resp.Data))
+}
+
+func TestBinaryData(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		w.Write(body)
+	}))
+	defer s.Close()
+
+	hs, err := InitBinding(s, nil)
+	require.NoError(t, err)
+
+	t.Run("binary data sent as base64 JSON string", func(t *testing.T) {
+		response, err := hs.Invoke(context.TODO(), &bindings.InvokeRequest{
+			Data:      []byte(`"iVBORw0KGgo="`),
+			Metadata:  map[string]string{"contentType": "image/png", "decodeBase64": "true"},
+			Operation: "post",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []byte("\x89PNG\r\n\x1a\n"), response.Data)
+		assert.Equal(t, "image/png", response.Metadata["contentType"])
+	})
+
+	t.Run("base64 data not decoded without asking", func(t *testing.T) {
+		response, err := hs.Invoke(context.TODO(), &bindings.InvokeRequest{
+			Data:      []byte(`"iVBORw0KGgo="`),
+			Metadata:  map[string]string{"contentType": "image/png"},
+			Operation: "post",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, `"iVBORw0KGgo="`, string(response.Data))
+	})
+
+	t.Run("JSON data sent as is", func(t *testing.T) {
+		response, err := hs.Invoke(context.TODO(), &bindings.InvokeRequest{
+			Data:      []byte(`"expected"`),
+			Operation: "post",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, `"expected"`, string(response.Data))
+		assert.Equal(t, "application/json; charset=utf-8", response.Metadata["contentType"])
+	})
+}
@@ -19,7 +19,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"strconv"
 
 	"github.com/google/uuid"
 	"github.com/huaweicloud/huaweicloud-sdk-go-obs/obs"
@@ -127,9 +126,9 @@ func (o *HuaweiOBS) Operations() []bindings.OperationKind {
 }
 
 func (o *HuaweiOBS) create(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
-	d, err := strconv.Unquote(string(req.Data))
-	if err == nil {
-		req.Data = []byte(d)
+	data, err := req.DecodedData(false)
+	if err != nil {
+		return nil, fmt.Errorf("obs binding error: %w", err)
 	}
 
 	var key string
@@ -140,12 +139,13 @@ func (o *HuaweiOBS) create(ctx context.Context, req *bindings.InvokeRequest) (*b
 		o.logger.Debugf("key not found. generating key %s", key)
 	}
 
-	r := bytes.NewReader(req.Data)
+	r := bytes.NewReader(data)
 
 	input := &obs.PutObjectInput{}
 	input.Key = key
 	input.Bucket = o.metadata.Bucket
 	input.Body = r
+	input.ContentType = req.Metadata[bindings.ContentTypeMetadataKey]
 
 	out, err := o.service.PutObject(ctx, input)
 	if err != nil {
@@ -233,9 +233,14 @@ func (o *HuaweiOBS) get(ctx context.Context, req *bindings.InvokeRequest) (*bind
 		return nil, fmt.Errorf("obs binding error. error reading obs object content: %w", err)
 	}
 
+	var respMetadata map[string]string
+	if out.ContentType != "" {
+		respMetadata = map[string]string{bindings.ContentTypeMetadataKey: out.ContentType}
+	}
+
 	return &bindings.InvokeResponse{
 		Data:     data,
-		Metadata: nil,
+		Metadata: respMetadata,
 	}, nil
 }
 
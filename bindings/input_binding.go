@@ -18,6 +18,7 @@ import (
 	"fmt"
 
 	"github.com/dapr/components-contrib/health"
+	"github.com/dapr/components-contrib/state"
 )
 
 // InputBinding is the interface to define a binding that triggers on incoming events.
@@ -31,6 +32,15 @@ type InputBinding interface {
 // Handler is the handler used to invoke the app handler.
 type Handler func(context.Context, *ReadResponse) ([]byte, error)
 
+// StateStoreResolver returns the initialized state store of the app with the given name.
+type StateStoreResolver func(name string) (state.Store, error)
+
+// StateStoreUser is implemented by the input bindings which persist their position in a state store of the app, named
+// in their metadata. The runtime sets the resolver of the state stores before calling Init.
+type StateStoreUser interface {
+	SetStateStoreResolver(resolver StateStoreResolver)
+}
+
 func PingInpBinding(inputBinding InputBinding) error {
 	// checks if this input binding has the ping option then executes
 	if inputBindingWithPing, ok := inputBinding.(health.Pinger); ok {
@@ -0,0 +1,346 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// updateOperation updates the fields of the request data of the issue of the "issueKey" metadata.
+	updateOperation bindings.OperationKind = "update"
+
+	// Request metadata keys; project and issueType override the values of the component metadata.
+	metadataKeyProject   = "project"
+	metadataKeyIssueType = "issueType"
+	metadataKeyIssueKey  = "issueKey"
+
+	// Response metadata keys.
+	metadataKeyIssueID = "issueId"
+
+	defaultIssueType = "Task"
+	defaultTimeout   = 30 * time.Second
+	defaultTokenURL  = "https://auth.atlassian.com/oauth/token"
+)
+
+// Jira is an output binding creating, updating and reading Jira issues with the REST API version 2, supported by both
+// Jira Cloud and Jira Data Center.
+type Jira struct {
+	metadata   *jiraMetadata
+	httpClient *http.Client
+
+	logger logger.Logger
+}
+
+type jiraMetadata struct {
+	// URL of the site, e.g. https://example.atlassian.net.
+	URL string `mapstructure:"url"`
+	// Email of the account and its API token, for the basic authentication of Jira Cloud.
+	Email    string `mapstructure:"email"`
+	APIToken string `mapstructure:"apiToken" mdsensitive:"true"`
+	// Personal access token of Jira Data Center, sent as bearer token.
+	AccessToken string `mapstructure:"accessToken" mdsensitive:"true"`
+	// OAuth 2.0 (3LO) app of Jira Cloud and the refresh token of its authorization, from which the access tokens are
+	// requested and refreshed. The url is then https://api.atlassian.com/ex/jira/{cloudId}.
+	ClientID     string `mapstructure:"clientId"`
+	ClientSecret string `mapstructure:"clientSecret" mdsensitive:"true"`
+	RefreshToken string `mapstructure:"refreshToken" mdsensitive:"true"`
+	TokenURL     string `mapstructure:"tokenURL"`
+	// Key of the project of the issues created.
+	Project      string `mapstructure:"project"`
+	IssueType    string `mapstructure:"issueType"`
+	TimeoutInSec int    `mapstructure:"timeoutInSec"`
+}
+
+// jiraError is the error returned by the REST API.
+type jiraError struct {
+	ErrorMessages []string          `json:"errorMessages"`
+	Errors        map[string]string `json:"errors"`
+}
+
+// createdIssue is the response of the creation of an issue.
+type createdIssue struct {
+	ID   string `json:"id"`
+	Key  string `json:"key"`
+	Self string `json:"self"`
+}
+
+// NewJira returns a new Jira output binding.
+func NewJira(logger logger.Logger) bindings.OutputBinding {
+	return &Jira{logger: logger}
+}
+
+// Init parses the metadata and creates the HTTP client.
+func (j *Jira) Init(md bindings.Metadata) error {
+	m, err := parseMetadata(md.Properties)
+	if err != nil {
+		return err
+	}
+
+	j.metadata = m
+	timeout := time.Duration(m.TimeoutInSec) * time.Second
+	if m.RefreshToken != "" {
+		config := &oauth2.Config{
+			ClientID:     m.ClientID,
+			ClientSecret: m.ClientSecret,
+			Endpoint:     oauth2.Endpoint{TokenURL: m.TokenURL, AuthStyle: oauth2.AuthStyleInParams},
+		}
+		// The tokens are requested with the same timeout as the requests of the API
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Timeout: timeout})
+		// The first access token is requested with the first request, and refreshed when it expires
+		j.httpClient = config.Client(ctx, &oauth2.Token{RefreshToken: m.RefreshToken})
+		j.httpClient.Timeout = timeout
+	} else {
+		j.httpClient = &http.Client{Timeout: timeout}
+	}
+
+	return nil
+}
+
+func parseMetadata(md map[string]string) (*jiraMetadata, error) {
+	m := jiraMetadata{
+		IssueType:    defaultIssueType,
+		TimeoutInSec: int(defaultTimeout / time.Second),
+		TokenURL:     defaultTokenURL,
+	}
+	err := metadata.DecodeMetadata(md, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.URL == "" {
+		return nil, errors.New("jira binding error: url is required")
+	}
+	m.URL = strings.TrimSuffix(m.URL, "/")
+	if m.RefreshToken != "" && (m.ClientID == "" || m.ClientSecret == "") {
+		return nil, errors.New("jira binding error: clientId and clientSecret are required with refreshToken")
+	}
+	if m.AccessToken == "" && m.RefreshToken == "" && (m.Email == "" || m.APIToken == "") {
+		return nil, errors.New("jira binding error: accessToken, refreshToken, or email and apiToken are required")
+	}
+	if m.TimeoutInSec < 1 {
+		return nil, fmt.Errorf("jira binding error: invalid timeoutInSec %d", m.TimeoutInSec)
+	}
+
+	return &m, nil
+}
+
+func (j *Jira) Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{
+		bindings.CreateOperation,
+		updateOperation,
+		bindings.GetOperation,
+	}
+}
+
+func (j *Jira) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	switch req.Operation {
+	case bindings.CreateOperation:
+		return j.create(ctx, req)
+	case updateOperation:
+		return j.update(ctx, req)
+	case bindings.GetOperation:
+		key, err := issueKey(req)
+		if err != nil {
+			return nil, err
+		}
+		res, err := j.do(ctx, http.MethodGet, "/rest/api/2/issue/"+url.PathEscape(key), nil)
+		if err != nil {
+			return nil, err
+		}
+		return &bindings.InvokeResponse{Data: res}, nil
+	default:
+		return nil, fmt.Errorf("jira binding error: unsupported operation %s", req.Operation)
+	}
+}
+
+// create creates an issue with the fields of the request data, in the project and with the issue type of the metadata
+// unless set in the fields. The response is the ID, key and URL of the issue.
+func (j *Jira) create(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	fields, err := parseFields(req.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := fields["project"]; !ok {
+		project := req.Metadata[metadataKeyProject]
+		if project == "" {
+			project = j.metadata.Project
+		}
+		if project == "" {
+			return nil, fmt.Errorf("jira binding error: required metadata not set: %s", metadataKeyProject)
+		}
+		fields["project"] = map[string]string{"key": project}
+	}
+	if _, ok := fields["issuetype"]; !ok {
+		issueType := req.Metadata[metadataKeyIssueType]
+		if issueType == "" {
+			issueType = j.metadata.IssueType
+		}
+		fields["issuetype"] = map[string]string{"name": issueType}
+	}
+
+	body, err := json.Marshal(map[string]any{"fields": fields})
+	if err != nil {
+		return nil, err
+	}
+	res, err := j.do(ctx, http.MethodPost, "/rest/api/2/issue", body)
+	if err != nil {
+		return nil, err
+	}
+
+	var issue createdIssue
+	if err = json.Unmarshal(res, &issue); err != nil {
+		return nil, fmt.Errorf("jira binding error: invalid response: %w", err)
+	}
+
+	return &bindings.InvokeResponse{
+		Data: res,
+		Metadata: map[string]string{
+			metadataKeyIssueID:  issue.ID,
+			metadataKeyIssueKey: issue.Key,
+		},
+	}, nil
+}
+
+// update sets the fields of the request data on the issue.
+func (j *Jira) update(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	key, err := issueKey(req)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := parseFields(req.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(map[string]any{"fields": fields})
+	if err != nil {
+		return nil, err
+	}
+	// The response is empty
+	_, err = j.do(ctx, http.MethodPut, "/rest/api/2/issue/"+url.PathEscape(key), body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bindings.InvokeResponse{
+		Metadata: map[string]string{
+			metadataKeyIssueKey: key,
+		},
+	}, nil
+}
+
+// do sends the request to the REST API and returns the body of the response.
+func (j *Jira) do(ctx context.Context, method string, path string, body []byte) ([]byte, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, method, j.metadata.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	// With OAuth, the client sets the access token
+	switch {
+	case j.metadata.RefreshToken != "":
+	case j.metadata.AccessToken != "":
+		httpReq.Header.Set("Authorization", "Bearer "+j.metadata.AccessToken)
+	default:
+		httpReq.SetBasicAuth(j.metadata.Email, j.metadata.APIToken)
+	}
+
+	resp, err := j.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("jira binding error: error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	res, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("jira binding error: error reading response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var jErr jiraError
+		if json.Unmarshal(res, &jErr) == nil && (len(jErr.ErrorMessages) > 0 || len(jErr.Errors) > 0) {
+			return nil, fmt.Errorf("jira binding error: %s (status %d)", jErr.message(), resp.StatusCode)
+		}
+		return nil, fmt.Errorf("jira binding error: unexpected status %s: %s", resp.Status, strings.TrimSpace(string(res)))
+	}
+
+	return res, nil
+}
+
+// message returns the messages of the error, followed by the errors of the fields sorted by field.
+func (e *jiraError) message() string {
+	messages := append([]string{}, e.ErrorMessages...)
+	fields := make([]string, 0, len(e.Errors))
+	for field := range e.Errors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		messages = append(messages, field+": "+e.Errors[field])
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+func issueKey(req *bindings.InvokeRequest) (string, error) {
+	key := req.Metadata[metadataKeyIssueKey]
+	if key == "" {
+		return "", fmt.Errorf("jira binding error: required metadata not set: %s", metadataKeyIssueKey)
+	}
+
+	return key, nil
+}
+
+// parseFields parses the fields of the issue, a JSON object. The numbers are kept as is, so that the large values of
+// the number custom fields aren't rounded.
+func parseFields(data []byte) (map[string]any, error) {
+	var fields map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&fields); err != nil || fields == nil {
+		return nil, errors.New("jira binding error: the fields of the issue must be a JSON object")
+	}
+
+	return fields, nil
+}
+
+// GetComponentMetadataSchema returns the schema of the metadata of the Jira binding.
+func (j *Jira) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(jiraMetadata{
+		IssueType:    defaultIssueType,
+		TimeoutInSec: int(defaultTimeout / time.Second),
+		TokenURL:     defaultTokenURL,
+	})
+	return fields
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+func TestParseMetadata(t *testing.T) {
+	m, err := parseMetadata(map[string]string{
+		"url":      "https://example.atlassian.net/",
+		"email":    "ops@example.com",
+		"apiToken": "token",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.atlassian.net", m.URL)
+	assert.Equal(t, "Task", m.IssueType)
+	assert.Equal(t, 30, m.TimeoutInSec)
+	assert.Equal(t, "https://auth.atlassian.com/oauth/token", m.TokenURL)
+
+	_, err = parseMetadata(map[string]string{"url": "https://jira.example.com", "email": "ops@example.com"})
+	assert.EqualError(t, err, "jira binding error: accessToken, refreshToken, or email and apiToken are required")
+
+	_, err = parseMetadata(map[string]string{"url": "https://api.atlassian.com/ex/jira/1", "refreshToken": "refresh", "clientId": "app"})
+	assert.EqualError(t, err, "jira binding error: clientId and clientSecret are required with refreshToken")
+
+	_, err = parseMetadata(map[string]string{"accessToken": "pat"})
+	assert.EqualError(t, err, "jira binding error: url is required")
+}
+
+// fakeJira serves the issue endpoints of the REST API and the token endpoint of Atlassian.
+type fakeJira struct {
+	*httptest.Server
+
+	// Authorization headers of the API requests, and grants of the token requests.
+	auths  []string
+	grants []string
+	// Fields of the issues created or updated, decoded with the numbers as is.
+	fields map[string]json.RawMessage
+}
+
+func newFakeJira(t *testing.T) *fakeJira {
+	t.Helper()
+
+	f := &fakeJira{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		f.grants = append(f.grants, r.Form.Get("grant_type")+":"+r.Form.Get("refresh_token"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "oauth-token", "token_type": "Bearer", "expires_in": 3600, "refresh_token": "rotated"}`))
+	})
+	mux.HandleFunc("/rest/api/2/issue", func(w http.ResponseWriter, r *http.Request) {
+		f.record(r)
+		if _, ok := f.fields["summary"]; !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"errorMessages": [], "errors": {"summary": "You must specify a summary of the issue."}}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": "10000", "key": "OPS-1", "self": "https://example.atlassian.net/rest/api/2/issue/10000"}`))
+	})
+	mux.HandleFunc("/rest/api/2/issue/OPS-1", func(w http.ResponseWriter, r *http.Request) {
+		f.record(r)
+		switch r.Method {
+		case http.MethodPut:
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodGet:
+			w.Write([]byte(`{"id": "10000", "key": "OPS-1", "fields": {"summary": "Disk full on db-1"}}`))
+		}
+	})
+	mux.HandleFunc("/rest/api/2/issue/OPS-404", func(w http.ResponseWriter, r *http.Request) {
+		f.record(r)
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errorMessages": ["Issue does not exist or you do not have permission to see it."], "errors": {}}`))
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func (f *fakeJira) record(r *http.Request) {
+	f.auths = append(f.auths, r.Header.Get("Authorization"))
+	f.fields = nil
+	body, _ := io.ReadAll(r.Body)
+	if len(body) > 0 {
+		var issue struct {
+			Fields map[string]json.RawMessage `json:"fields"`
+		}
+		json.Unmarshal(body, &issue)
+		f.fields = issue.Fields
+	}
+}
+
+func initJira(t *testing.T, props map[string]string) *Jira {
+	t.Helper()
+
+	j := NewJira(logger.NewLogger("test")).(*Jira)
+	require.NoError(t, j.Init(bindings.Metadata{Base: metadata.Base{Properties: props}}))
+
+	return j
+}
+
+func TestCreateIssue(t *testing.T) {
+	f := newFakeJira(t)
+	j := initJira(t, map[string]string{"url": f.URL, "email": "ops@example.com", "apiToken": "token", "project": "OPS"})
+
+	res, err := j.Invoke(context.Background(), &bindings.InvokeRequest{
+		Operation: bindings.CreateOperation,
+		Data:      []byte(`{"summary": "Disk full on db-1", "customfield_10010": 9007199254740993}`),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"issueId": "10000", "issueKey": "OPS-1"}, res.Metadata)
+	assert.Equal(t, []string{"Basic b3BzQGV4YW1wbGUuY29tOnRva2Vu"}, f.auths)
+	assert.JSONEq(t, `{"key": "OPS"}`, string(f.fields["project"]))
+	assert.JSONEq(t, `{"name": "Task"}`, string(f.fields["issuetype"]))
+	// The number isn't rounded to a float64
+	assert.Equal(t, "9007199254740993", string(f.fields["customfield_10010"]))
+
+	// The project and the issue type of the request, or of the fields, take priority
+	_, err = j.Invoke(context.Background(), &bindings.InvokeRequest{
+		Operation: bindings.CreateOperation,
+		Data:      []byte(`{"summary": "Outage", "issuetype": {"id": "3"}}`),
+		Metadata:  map[string]string{"project": "SRE", "issueType": "Incident"},
+	})
+
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"key": "SRE"}`, string(f.fields["project"]))
+	assert.JSONEq(t, `{"id": "3"}`, string(f.fields["issuetype"]))
+
+	_, err = j.Invoke(context.Background(), &bindings.InvokeRequest{Operation: bindings.CreateOperation, Data: []byte(`{}`)})
+
+	assert.EqualError(t, err, "jira binding error: summary: You must specify a summary of the issue. (status 400)")
+}
+
+func TestCreateIssueWithoutProject(t *testing.T) {
+	j := initJira(t, map[string]string{"url": "http://localhost:0", "accessToken": "pat"})
+
+	_, err := j.Invoke(context.Background(), &bindings.InvokeRequest{Operation: bindings.CreateOperation, Data: []byte(`{"summary": "Outage"}`)})
+
+	assert.EqualError(t, err, "jira binding error: required metadata not set: project")
+}
+
+func TestUpdateAndGetIssue(t *testing.T) {
+	f := newFakeJira(t)
+	j := initJira(t, map[string]string{"url": f.URL, "accessToken": "pat"})
+
+	res, err := j.Invoke(context.Background(), &bindings.InvokeRequest{
+		Operation: updateOperation,
+		Data:      []byte(`{"summary": "Disk full on db-1 (resolved)"}`),
+		Metadata:  map[string]string{"issueKey": "OPS-1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"issueKey": "OPS-1"}, res.Metadata)
+	assert.Equal(t, `"Disk full on db-1 (resolved)"`, string(f.fields["summary"]))
+
+	res, err = j.Invoke(context.Background(), &bindings.InvokeRequest{
+		Operation: bindings.GetOperation,
+		Metadata:  map[string]string{"issueKey": "OPS-1"},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id": "10000", "key": "OPS-1", "fields": {"summary": "Disk full on db-1"}}`, string(res.Data))
+	assert.Equal(t, []string{"Bearer pat", "Bearer pat"}, f.auths)
+
+	_, err = j.Invoke(context.Background(), &bindings.InvokeRequest{
+		Operation: bindings.GetOperation,
+		Metadata:  map[string]string{"issueKey": "OPS-404"},
+	})
+	assert.EqualError(t, err, "jira binding error: Issue does not exist or you do not have permission to see it. (status 404)")
+
+	_, err = j.Invoke(context.Background(), &bindings.InvokeRequest{Operation: updateOperation, Data: []byte(`{"summary": "no key"}`)})
+	assert.EqualError(t, err, "jira binding error: required metadata not set: issueKey")
+
+	_, err = j.Invoke(context.Background(), &bindings.InvokeRequest{
+		Operation: updateOperation,
+		Data:      []byte(`["summary"]`),
+		Metadata:  map[string]string{"issueKey": "OPS-1"},
+	})
+	assert.EqualError(t, err, "jira binding error: the fields of the issue must be a JSON object")
+}
+
+func TestOAuth(t *testing.T) {
+	f := newFakeJira(t)
+	j := initJira(t, map[string]string{
+		"url":          f.URL,
+		"clientId":     "app",
+		"clientSecret": "secret",
+		"refreshToken": "refresh",
+		"tokenURL":     f.URL + "/oauth/token",
+	})
+
+	for i := 0; i < 2; i++ {
+		_, err := j.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: bindings.GetOperation,
+			Metadata:  map[string]string{"issueKey": "OPS-1"},
+		})
+		require.NoError(t, err)
+	}
+
+	// The access token is requested once with the refresh token, then reused until it expires
+	assert.Equal(t, []string{"refresh_token:refresh"}, f.grants)
+	assert.Equal(t, []string{"Bearer oauth-token", "Bearer oauth-token"}, f.auths)
+}
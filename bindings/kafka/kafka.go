@@ -15,19 +15,29 @@ package kafka
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"strings"
 
 	"github.com/dapr/kit/logger"
 
 	"github.com/dapr/components-contrib/bindings"
 	"github.com/dapr/components-contrib/internal/component/kafka"
+	"github.com/dapr/components-contrib/metadata"
 )
 
 const (
 	publishTopic = "publishTopic"
 	topics       = "topics"
+
+	// exportCheckpointsOperation returns the offsets committed by the consumer group, as a JSON array.
+	exportCheckpointsOperation bindings.OperationKind = "exportCheckpoints"
+	// importCheckpointsOperation commits the offsets in the request data, as returned by exportCheckpointsOperation.
+	importCheckpointsOperation bindings.OperationKind = "importCheckpoints"
 )
 
+var jsonContentType = "application/json"
+
 type Binding struct {
 	kafka           *kafka.Kafka
 	publishTopic    string
@@ -70,7 +80,11 @@ func (b *Binding) Init(metadata bindings.Metadata) error {
 }
 
 func (b *Binding) Operations() []bindings.OperationKind {
-	return []bindings.OperationKind{bindings.CreateOperation}
+	return []bindings.OperationKind{
+		bindings.CreateOperation,
+		exportCheckpointsOperation,
+		importCheckpointsOperation,
+	}
 }
 
 func (b *Binding) Close() (err error) {
@@ -78,11 +92,62 @@ func (b *Binding) Close() (err error) {
 	return b.kafka.Close()
 }
 
+// GetComponentMetadataSchema returns the schema of the metadata of the Kafka binding.
+func (b *Binding) GetComponentMetadataSchema() []metadata.MetadataField {
+	return append(b.kafka.MetadataSchema(),
+		metadata.MetadataField{Name: publishTopic, Type: metadata.FieldTypeString},
+		metadata.MetadataField{Name: topics, Type: metadata.FieldTypeString},
+	)
+}
+
 func (b *Binding) Invoke(_ context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
-	err := b.kafka.Publish(b.publishTopic, req.Data, req.Metadata)
+	switch req.Operation {
+	case exportCheckpointsOperation:
+		return b.exportCheckpoints(req)
+	case importCheckpointsOperation:
+		return nil, b.importCheckpoints(req)
+	}
+
+	data, err := req.BinaryData()
+	if err != nil {
+		return nil, err
+	}
+	err = b.kafka.Publish(b.publishTopic, data, req.Metadata)
 	return nil, err
 }
 
+// exportCheckpoints returns the checkpoints of the topics in the "topics" request metadata,
+// or of the topics the binding reads from if not set.
+func (b *Binding) exportCheckpoints(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	exportTopics := b.topics
+	if val, ok := req.Metadata[topics]; ok && val != "" {
+		exportTopics = strings.Split(val, ",")
+	}
+
+	checkpoints, err := b.kafka.ExportCheckpoints(exportTopics)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(checkpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bindings.InvokeResponse{
+		Data:        data,
+		ContentType: &jsonContentType,
+	}, nil
+}
+
+func (b *Binding) importCheckpoints(req *bindings.InvokeRequest) error {
+	var checkpoints []kafka.ConsumerCheckpoint
+	if err := json.Unmarshal(req.Data, &checkpoints); err != nil {
+		return fmt.Errorf("kafka binding: error decoding checkpoints: %w", err)
+	}
+
+	return b.kafka.ImportCheckpoints(checkpoints)
+}
+
 func (b *Binding) Read(ctx context.Context, handler bindings.Handler) error {
 	if len(b.topics) == 0 {
 		b.logger.Warnf("kafka binding: no topic defined, input bindings will not be started")
@@ -9,6 +9,7 @@ import (
 	qs "github.com/kubemq-io/kubemq-go/queues_stream"
 
 	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/internal/logging"
 	"github.com/dapr/kit/logger"
 )
 
@@ -37,6 +38,11 @@ func NewKubeMQ(logger logger.Logger) Kubemq {
 }
 
 func (k *kubeMQ) Init(metadata bindings.Metadata) error {
+	if err := bindings.ValidateConfirmedDelivery(metadata.Properties); err != nil {
+		return err
+	}
+
+	k.logger = logging.ForComponent(k.logger, "bindings.kubemq", metadata.Name)
 	opts, err := createOptions(metadata)
 	if err != nil {
 		return err
@@ -63,7 +69,7 @@ func (k *kubeMQ) Read(ctx context.Context, handler bindings.Handler) error {
 		for {
 			err := k.processQueueMessage(k.ctx, handler)
 			if err != nil {
-				k.logger.Error(err.Error())
+				k.logger.Errorf("error processing queue message: %v", err)
 				time.Sleep(time.Second)
 			}
 			if k.ctx.Err() != nil {
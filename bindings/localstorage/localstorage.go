@@ -22,7 +22,6 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"strconv"
 
 	securejoin "github.com/cyphar/filepath-securejoin"
 	"github.com/google/uuid"
@@ -93,14 +92,13 @@ func (ls *LocalStorage) Operations() []bindings.OperationKind {
 }
 
 func (ls *LocalStorage) create(filename string, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
-	d, err := strconv.Unquote(string(req.Data))
-	if err == nil {
-		req.Data = []byte(d)
+	data, err := req.DecodedData(false)
+	if err != nil {
+		return nil, err
 	}
-
-	decoded, err := base64.StdEncoding.DecodeString(string(req.Data))
+	decoded, err := base64.StdEncoding.DecodeString(string(data))
 	if err == nil {
-		req.Data = decoded
+		data = decoded
 	}
 
 	absPath, relPath, err := getSecureAbsRelPath(ls.metadata.RootPath, filename)
@@ -119,7 +117,7 @@ func (ls *LocalStorage) create(filename string, req *bindings.InvokeRequest) (*b
 	}
 	defer f.Close()
 
-	numBytes, err := f.Write(req.Data)
+	numBytes, err := f.Write(data)
 	if err != nil {
 		return nil, err
 	}
@@ -13,9 +13,33 @@ limitations under the License.
 
 package bindings
 
-import "github.com/dapr/components-contrib/metadata"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dapr/components-contrib/metadata"
+)
 
 // Metadata represents a set of binding specific properties.
 type Metadata struct {
 	metadata.Base `json:",inline"`
 }
+
+const (
+	// DeliveryModeKey is the metadata property selecting how the output bindings of message brokers deliver messages.
+	// It is only implemented by Kafka, where "fireAndForget" returns as soon as a message is enqueued locally.
+	DeliveryModeKey = "deliveryMode"
+	// DeliveryModeConfirmed blocks the writing of a message until it has been acknowledged by the broker.
+	DeliveryModeConfirmed = "confirmed"
+)
+
+// ValidateConfirmedDelivery returns an error if the metadata selects a delivery mode other than "confirmed".
+// It is called in Init by the bindings which only implement the confirmed delivery, always waiting for the broker
+// to acknowledge the messages.
+func ValidateConfirmedDelivery(properties map[string]string) error {
+	if val, ok := properties[DeliveryModeKey]; ok && val != "" && !strings.EqualFold(val, DeliveryModeConfirmed) {
+		return fmt.Errorf("the '%s' delivery mode is not supported by this binding, only '%s' is", val, DeliveryModeConfirmed)
+	}
+
+	return nil
+}
@@ -22,6 +22,15 @@ type metadata struct {
 	cleanSession      bool
 	backOffMaxRetries int
 	topic             string
+	lastWill          *lastWill
+}
+
+// lastWill is the message published by the broker when the producer disconnects ungracefully.
+type lastWill struct {
+	topic   string
+	payload []byte
+	qos     byte
+	retain  bool
 }
 
 type tlsCfg struct {
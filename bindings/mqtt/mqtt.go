@@ -18,6 +18,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -38,12 +39,18 @@ const (
 	mqttTopic             = "topic"
 	mqttQOS               = "qos"
 	mqttRetain            = "retain"
+	mqttRetained          = "retained"
+	mqttDuplicate         = "duplicate"
 	mqttClientID          = "consumerID"
 	mqttCleanSession      = "cleanSession"
 	mqttCACert            = "caCert"
 	mqttClientCert        = "clientCert"
 	mqttClientKey         = "clientKey"
 	mqttBackOffMaxRetries = "backOffMaxRetries"
+	mqttWillTopic         = "willTopic"
+	mqttWillPayload       = "willPayload"
+	mqttWillQOS           = "willQos"
+	mqttWillRetain        = "willRetain"
 
 	// errors.
 	errorMsgPrefix = "mqtt binding error:"
@@ -99,11 +106,11 @@ func parseMQTTMetaData(md bindings.Metadata) (*metadata, error) {
 	// optional configuration settings
 	m.qos = defaultQOS
 	if val, ok := md.Properties[mqttQOS]; ok && val != "" {
-		qosInt, err := strconv.Atoi(val)
+		var err error
+		m.qos, err = parseQOS(val)
 		if err != nil {
 			return &m, fmt.Errorf("%s invalid qos %s, %s", errorMsgPrefix, val, err)
 		}
-		m.qos = byte(qosInt)
 	}
 
 	m.retain = defaultRetain
@@ -122,6 +129,22 @@ func parseMQTTMetaData(md bindings.Metadata) (*metadata, error) {
 		m.cleanSession = utils.IsTruthy(val)
 	}
 
+	if val, ok := md.Properties[mqttWillTopic]; ok && val != "" {
+		m.lastWill = &lastWill{
+			topic:   val,
+			payload: []byte(md.Properties[mqttWillPayload]),
+			qos:     m.qos,
+			retain:  utils.IsTruthy(md.Properties[mqttWillRetain]),
+		}
+		if val, ok = md.Properties[mqttWillQOS]; ok && val != "" {
+			var err error
+			m.lastWill.qos, err = parseQOS(val)
+			if err != nil {
+				return &m, fmt.Errorf("%s invalid willQos %s, %s", errorMsgPrefix, val, err)
+			}
+		}
+	}
+
 	if val, ok := md.Properties[mqttCACert]; ok && val != "" {
 		if !isValidPEM(val) {
 			return &m, fmt.Errorf("%s invalid ca certificate", errorMsgPrefix)
@@ -152,8 +175,25 @@ func parseMQTTMetaData(md bindings.Metadata) (*metadata, error) {
 	return &m, nil
 }
 
+// parseQOS parses a QoS level, which must be 0, 1 or 2.
+func parseQOS(val string) (byte, error) {
+	qos, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, err
+	}
+	if qos < 0 || qos > 2 {
+		return 0, errors.New("supported values are 0, 1 and 2")
+	}
+
+	return byte(qos), nil
+}
+
 // Init does MQTT connection parsing.
 func (m *MQTT) Init(metadata bindings.Metadata) error {
+	if err := bindings.ValidateConfirmedDelivery(metadata.Properties); err != nil {
+		return err
+	}
+
 	mqttMeta, err := parseMQTTMetaData(metadata)
 	if err != nil {
 		return err
@@ -162,7 +202,8 @@ func (m *MQTT) Init(metadata bindings.Metadata) error {
 
 	// mqtt broker allows only one connection at a given time from a clientID.
 	producerClientID := fmt.Sprintf("%s-producer", m.metadata.clientID)
-	p, err := m.connect(producerClientID)
+	// The last will is registered by the producer, whose connection lives as long as the component
+	p, err := m.connect(producerClientID, m.metadata.lastWill)
 	if err != nil {
 		return err
 	}
@@ -194,6 +235,20 @@ func (m *MQTT) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindin
 	bo := backoff.WithMaxRetries(cbo, 3)
 	bo = backoff.WithContext(bo, ctx)
 
+	// The QoS and retain flag of the component can be overridden for each message
+	qos := m.metadata.qos
+	if val, ok := req.Metadata[mqttQOS]; ok && val != "" {
+		var err error
+		qos, err = parseQOS(val)
+		if err != nil {
+			return nil, fmt.Errorf("%s invalid qos %s, %s", errorMsgPrefix, val, err)
+		}
+	}
+	retain := m.metadata.retain
+	if val, ok := req.Metadata[mqttRetain]; ok && val != "" {
+		retain = utils.IsTruthy(val)
+	}
+
 	return nil, retry.NotifyRecover(func() error {
 		topic, ok := req.Metadata[mqttTopic]
 		if !ok || topic == "" {
@@ -201,7 +256,7 @@ func (m *MQTT) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindin
 			topic = m.metadata.topic
 		}
 		m.logger.Debugf("mqtt publishing topic %s with data: %v", topic, req.Data)
-		token := m.producer.Publish(topic, m.metadata.qos, m.metadata.retain, req.Data)
+		token := m.producer.Publish(topic, qos, retain, req.Data)
 		if !token.WaitTimeout(defaultWait) || token.Error() != nil {
 			return fmt.Errorf("mqtt error from publish: %v", token.Error())
 		}
@@ -215,8 +270,13 @@ func (m *MQTT) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindin
 
 func (m *MQTT) handleMessage(ctx context.Context, handler bindings.Handler, mqttMsg mqtt.Message) error {
 	msg := bindings.ReadResponse{
-		Data:     mqttMsg.Payload(),
-		Metadata: map[string]string{mqttTopic: mqttMsg.Topic()},
+		Data: mqttMsg.Payload(),
+		Metadata: map[string]string{
+			mqttTopic:     mqttMsg.Topic(),
+			mqttQOS:       strconv.Itoa(int(mqttMsg.Qos())),
+			mqttRetained:  strconv.FormatBool(mqttMsg.Retained()),
+			mqttDuplicate: strconv.FormatBool(mqttMsg.Duplicate()),
+		},
 	}
 
 	// paho.mqtt.golang requires that handlers never block or it can deadlock on client.Disconnect.
@@ -252,7 +312,7 @@ func (m *MQTT) Read(ctx context.Context, handler bindings.Handler) error {
 
 	// mqtt broker allows only one connection at a given time from a clientID.
 	consumerClientID := fmt.Sprintf("%s-consumer", m.metadata.clientID)
-	c, err := m.connect(consumerClientID)
+	c, err := m.connect(consumerClientID, nil)
 	if err != nil {
 		return err
 	}
@@ -284,12 +344,15 @@ func (m *MQTT) Read(ctx context.Context, handler bindings.Handler) error {
 	return nil
 }
 
-func (m *MQTT) connect(clientID string) (mqtt.Client, error) {
+func (m *MQTT) connect(clientID string, will *lastWill) (mqtt.Client, error) {
 	uri, err := url.Parse(m.metadata.url)
 	if err != nil {
 		return nil, err
 	}
 	opts := m.createClientOptions(uri, clientID)
+	if will != nil {
+		opts.SetBinaryWill(will.topic, will.payload, will.qos, will.retain)
+	}
 	client := mqtt.NewClient(opts)
 	token := client.Connect()
 	for !token.WaitTimeout(defaultWait) {
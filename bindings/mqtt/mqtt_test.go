@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/components-contrib/bindings"
 	mdata "github.com/dapr/components-contrib/metadata"
@@ -66,6 +67,35 @@ func TestParseMetadata(t *testing.T) {
 		assert.Contains(t, err.Error(), "missing topic")
 	})
 
+	t.Run("invalid qos", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeMetaData := bindings.Metadata{Base: mdata.Base{Properties: fakeProperties}}
+		fakeMetaData.Properties[mqttQOS] = "3"
+		_, err := parseMQTTMetaData(fakeMetaData)
+
+		// assert
+		assert.Contains(t, err.Error(), "invalid qos")
+	})
+
+	t.Run("last will", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeMetaData := bindings.Metadata{Base: mdata.Base{Properties: fakeProperties}}
+		fakeMetaData.Properties[mqttWillTopic] = "devices/a/status"
+		fakeMetaData.Properties[mqttWillPayload] = "offline"
+		fakeMetaData.Properties[mqttWillQOS] = "2"
+		fakeMetaData.Properties[mqttWillRetain] = "true"
+		m, err := parseMQTTMetaData(fakeMetaData)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, &lastWill{
+			topic:   "devices/a/status",
+			payload: []byte("offline"),
+			qos:     2,
+			retain:  true,
+		}, m.lastWill)
+	})
+
 	t.Run("missing consumerID", func(t *testing.T) {
 		fakeProperties := getFakeProperties()
 		fakeMetaData := bindings.Metadata{Base: mdata.Base{Name: "binging-test", Properties: fakeProperties}}
@@ -214,6 +244,28 @@ func TestParseMetadata(t *testing.T) {
 			payload: payload,
 		})
 	})
+
+	t.Run("Response returns the QoS, retained and duplicate flags of the message.", func(t *testing.T) {
+		logger := logger.NewLogger("test")
+		m := NewMQTT(logger).(*MQTT)
+		m.ctx, m.cancel = context.WithCancel(context.Background())
+
+		var metadata map[string]string
+		err := m.handleMessage(context.Background(), func(ctx context.Context, r *bindings.ReadResponse) ([]byte, error) {
+			metadata = r.Metadata
+			return r.Data, nil
+		}, &mqttMockMessage{
+			topic:     "/topic",
+			qos:       2,
+			retained:  true,
+			duplicate: true,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "2", metadata[mqttQOS])
+		assert.Equal(t, "true", metadata[mqttRetained])
+		assert.Equal(t, "true", metadata[mqttDuplicate])
+	})
 }
 
 type mqttMockMessage struct {
@@ -24,7 +24,9 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-sql-driver/mysql"
@@ -57,24 +59,47 @@ const (
 	connMaxLifetimeKey = "connMaxLifetime"
 	connMaxIdleTimeKey = "connMaxIdleTime"
 
+	// Maximum number of rows returned by a query, or by a page of a query; 0 for no limit.
+	maxRowsKey = "maxRows"
+	// Maximum duration of an operation, as a Go duration; 0 for no limit.
+	queryTimeoutKey = "queryTimeout"
+
 	// keys from request's metadata.
 	commandSQLKey = "sql"
+	pageSizeKey   = "pageSize"
+	pageTokenKey  = "pageToken"
+	// Comma-separated columns uniquely identifying the rows, ordering the pages.
+	pageKeyKey = "pageKey"
 
 	// keys from response's metadata.
-	respOpKey           = "operation"
-	respSQLKey          = "sql"
-	respStartTimeKey    = "start-time"
-	respRowsAffectedKey = "rows-affected"
-	respEndTimeKey      = "end-time"
-	respDurationKey     = "duration"
+	respOpKey            = "operation"
+	respSQLKey           = "sql"
+	respStartTimeKey     = "start-time"
+	respRowsAffectedKey  = "rows-affected"
+	respEndTimeKey       = "end-time"
+	respDurationKey      = "duration"
+	respNextPageTokenKey = "next-page-token"
 )
 
 // Mysql represents MySQL output bindings.
 type Mysql struct {
-	db     *sql.DB
-	logger logger.Logger
+	db           *sql.DB
+	logger       logger.Logger
+	maxRows      int
+	queryTimeout time.Duration
+}
+
+// page is a page of the results of a query.
+type page struct {
+	size   int
+	offset int
+	// Columns ordering the results, so that the pages are stable.
+	key []string
 }
 
+// columnName is the syntax of the columns of the page keys.
+var columnName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 // NewMysql returns a new MySQL output binding.
 func NewMysql(logger logger.Logger) bindings.OutputBinding {
 	return &Mysql{logger: logger}
@@ -95,6 +120,19 @@ func (m *Mysql) Init(metadata bindings.Metadata) error {
 		return err
 	}
 
+	err = propertyToInt(p, maxRowsKey, func(i int) { m.maxRows = i })
+	if err != nil {
+		return err
+	}
+	if m.maxRows < 0 {
+		return fmt.Errorf("invalid %s: %d", maxRowsKey, m.maxRows)
+	}
+
+	err = propertyToDuration(p, queryTimeoutKey, func(d time.Duration) { m.queryTimeout = d })
+	if err != nil {
+		return err
+	}
+
 	err = propertyToInt(p, maxIdleConnsKey, db.SetMaxIdleConns)
 	if err != nil {
 		return err
@@ -155,6 +193,12 @@ func (m *Mysql) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindi
 		},
 	}
 
+	if m.queryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.queryTimeout)
+		defer cancel()
+	}
+
 	switch req.Operation { //nolint:exhaustive
 	case execOperation:
 		r, err := m.exec(ctx, s)
@@ -164,11 +208,18 @@ func (m *Mysql) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindi
 		resp.Metadata[respRowsAffectedKey] = strconv.FormatInt(r, 10)
 
 	case queryOperation:
-		d, err := m.query(ctx, s)
+		pg, err := m.parsePage(req.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		d, more, err := m.query(ctx, s, pg)
 		if err != nil {
 			return nil, err
 		}
 		resp.Data = d
+		if more {
+			resp.Metadata[respNextPageTokenKey] = strconv.Itoa(pg.offset + pg.size)
+		}
 
 	default:
 		return nil, fmt.Errorf("invalid operation type: %s. Expected %s, %s, or %s",
@@ -200,10 +251,51 @@ func (m *Mysql) Close() error {
 	return nil
 }
 
-func (m *Mysql) query(ctx context.Context, sql string) ([]byte, error) {
+// parsePage returns the page of results requested with the "pageSize", "pageToken" and "pageKey" request metadata.
+// Without page size, all the results are returned in a single page.
+func (m *Mysql) parsePage(metadata map[string]string) (pg page, err error) {
+	if err := propertyToInt(metadata, pageSizeKey, func(i int) { pg.size = i }); err != nil {
+		return pg, err
+	}
+	if pg.size < 0 || (pg.size == 0 && metadata[pageSizeKey] != "") {
+		return pg, fmt.Errorf("invalid %s: %d", pageSizeKey, pg.size)
+	}
+	if m.maxRows > 0 && pg.size > m.maxRows {
+		return pg, fmt.Errorf("%s %d exceeds %s %d", pageSizeKey, pg.size, maxRowsKey, m.maxRows)
+	}
+
+	if err := propertyToInt(metadata, pageTokenKey, func(i int) { pg.offset = i }); err != nil {
+		return pg, err
+	}
+	if pg.offset < 0 {
+		return pg, fmt.Errorf("invalid %s: %d", pageTokenKey, pg.offset)
+	}
+	if pg.offset > 0 && pg.size == 0 {
+		return pg, fmt.Errorf("%s requires %s", pageTokenKey, pageSizeKey)
+	}
+
+	if pg.size > 0 {
+		pg.key, err = parsePageKey(metadata[pageKeyKey])
+		if err != nil {
+			return pg, err
+		}
+	}
+
+	return pg, nil
+}
+
+// query returns the rows of a page of the results of the query, and whether there are more.
+func (m *Mysql) query(ctx context.Context, sql string, pg page) ([]byte, bool, error) {
+	limit := m.maxRows
+	if pg.size > 0 {
+		// One more row is read to know if there is a next page
+		sql = pagedSQL(sql, pg.key, pg.size+1, pg.offset)
+		limit = pg.size
+	}
+
 	rows, err := m.db.QueryContext(ctx, sql)
 	if err != nil {
-		return nil, fmt.Errorf("error executing query: %w", err)
+		return nil, false, fmt.Errorf("error executing query: %w", err)
 	}
 
 	defer func() {
@@ -211,12 +303,40 @@ func (m *Mysql) query(ctx context.Context, sql string) ([]byte, error) {
 		_ = rows.Err()
 	}()
 
-	result, err := m.jsonify(rows)
+	result, more, err := m.jsonify(rows, limit)
 	if err != nil {
-		return nil, fmt.Errorf("error marshalling query result for query: %w", err)
+		return nil, false, fmt.Errorf("error marshalling query result for query: %w", err)
+	}
+	if more && pg.size == 0 {
+		return nil, false, fmt.Errorf("query returned more than %d rows, set %s to read the results by pages", m.maxRows, pageSizeKey)
 	}
 
-	return result, nil
+	return result, more, nil
+}
+
+// parsePageKey returns the columns of the page key, required to read the results by pages, since the order of the rows
+// of a query is not stable without ORDER BY.
+func parsePageKey(val string) ([]string, error) {
+	if val == "" {
+		return nil, fmt.Errorf("%s requires %s, the columns uniquely identifying the rows", pageSizeKey, pageKeyKey)
+	}
+
+	key := strings.Split(val, ",")
+	for i := range key {
+		key[i] = strings.TrimSpace(key[i])
+		if !columnName.MatchString(key[i]) {
+			return nil, fmt.Errorf("invalid %s: %s", pageKeyKey, val)
+		}
+	}
+
+	return key, nil
+}
+
+// pagedSQL wraps a query to return a page of its results, ordered by the columns of the key.
+func pagedSQL(sql string, key []string, limit int, offset int) string {
+	sql = strings.TrimSuffix(strings.TrimSpace(sql), ";")
+
+	return fmt.Sprintf("SELECT * FROM (%s) AS dapr_page ORDER BY %s LIMIT %d OFFSET %d", sql, strings.Join(key, ", "), limit, offset)
 }
 
 func (m *Mysql) exec(ctx context.Context, sql string) (int64, error) {
@@ -285,25 +405,38 @@ func initDB(url, pemPath string) (*sql.DB, error) {
 	return db, nil
 }
 
-func (m *Mysql) jsonify(rows *sql.Rows) ([]byte, error) {
+// jsonify returns the rows in JSON, up to limit rows if not 0, and whether there are more rows.
+// The rows are read one at a time, so that the query is aborted as soon as it returns more than limit rows.
+func (m *Mysql) jsonify(rows *sql.Rows, limit int) ([]byte, bool, error) {
 	columnTypes, err := rows.ColumnTypes()
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	var ret []interface{}
+	more := false
 	for rows.Next() {
+		if limit > 0 && len(ret) == limit {
+			more = true
+			break
+		}
+
 		values := prepareValues(columnTypes)
 		err := rows.Scan(values...)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 
 		r := m.convert(columnTypes, values)
 		ret = append(ret, r)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	result, err := json.Marshal(ret)
 
-	return json.Marshal(ret)
+	return result, more, err
 }
 
 func prepareValues(columnTypes []*sql.ColumnType) []interface{} {
@@ -38,7 +38,7 @@ func TestQuery(t *testing.T) {
 			AddRow(3, "value-3", time.Now().Add(2000))
 
 		mock.ExpectQuery("SELECT \\* FROM foo WHERE id < 4").WillReturnRows(rows)
-		ret, err := m.query(context.Background(), `SELECT * FROM foo WHERE id < 4`)
+		ret, _, err := m.query(context.Background(), `SELECT * FROM foo WHERE id < 4`, page{})
 		assert.Nil(t, err)
 		t.Logf("query result: %s", ret)
 		assert.Contains(t, string(ret), "\"id\":1")
@@ -57,7 +57,7 @@ func TestQuery(t *testing.T) {
 			AddRow(2, 2.2, time.Now().Add(1000)).
 			AddRow(3, 3.3, time.Now().Add(2000))
 		mock.ExpectQuery("SELECT \\* FROM foo WHERE id < 4").WillReturnRows(rows)
-		ret, err := m.query(context.Background(), "SELECT * FROM foo WHERE id < 4")
+		ret, _, err := m.query(context.Background(), "SELECT * FROM foo WHERE id < 4", page{})
 		assert.Nil(t, err)
 		t.Logf("query result: %s", ret)
 
@@ -153,6 +153,76 @@ func TestInvoke(t *testing.T) {
 		assert.NotNil(t, err)
 	})
 
+	t.Run("query operation with pages", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id"}).AddRow(3).AddRow(4).AddRow(5)
+		mock.ExpectQuery("SELECT \\* FROM \\(SELECT id FROM foo\\) AS dapr_page ORDER BY id LIMIT 3 OFFSET 2").WillReturnRows(rows)
+
+		metadata := map[string]string{commandSQLKey: "SELECT id FROM foo;", pageSizeKey: "2", pageTokenKey: "2", pageKeyKey: "id"}
+		req := &bindings.InvokeRequest{
+			Metadata:  metadata,
+			Operation: queryOperation,
+		}
+		resp, err := m.Invoke(context.Background(), req)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `[{"id": 3}, {"id": 4}]`, string(resp.Data))
+		assert.Equal(t, "4", resp.Metadata[respNextPageTokenKey])
+	})
+
+	t.Run("query operation with last page", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id"}).AddRow(5)
+		mock.ExpectQuery("SELECT \\* FROM \\(SELECT id FROM foo\\) AS dapr_page ORDER BY id LIMIT 3 OFFSET 4").WillReturnRows(rows)
+
+		metadata := map[string]string{commandSQLKey: "SELECT id FROM foo", pageSizeKey: "2", pageTokenKey: "4", pageKeyKey: "id"}
+		req := &bindings.InvokeRequest{
+			Metadata:  metadata,
+			Operation: queryOperation,
+		}
+		resp, err := m.Invoke(context.Background(), req)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `[{"id": 5}]`, string(resp.Data))
+		assert.NotContains(t, resp.Metadata, respNextPageTokenKey)
+	})
+
+	t.Run("query operation exceeding maxRows", func(t *testing.T) {
+		m.maxRows = 2
+		defer func() { m.maxRows = 0 }()
+		rows := sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2).AddRow(3)
+		mock.ExpectQuery("SELECT id FROM foo").WillReturnRows(rows)
+
+		metadata := map[string]string{commandSQLKey: "SELECT id FROM foo"}
+		req := &bindings.InvokeRequest{
+			Metadata:  metadata,
+			Operation: queryOperation,
+		}
+		resp, err := m.Invoke(context.Background(), req)
+		assert.Nil(t, resp)
+		assert.ErrorContains(t, err, "more than 2 rows")
+	})
+
+	t.Run("query operation with invalid page", func(t *testing.T) {
+		m.maxRows = 2
+		defer func() { m.maxRows = 0 }()
+
+		for _, metadata := range []map[string]string{
+			{pageSizeKey: "0", pageKeyKey: "id"},
+			{pageSizeKey: "-1", pageKeyKey: "id"},
+			{pageSizeKey: "3", pageKeyKey: "id"},
+			{pageTokenKey: "2", pageKeyKey: "id"},
+			{pageSizeKey: "2", pageTokenKey: "next", pageKeyKey: "id"},
+			{pageSizeKey: "2"},
+			{pageSizeKey: "2", pageKeyKey: "1=1 --"},
+		} {
+			metadata[commandSQLKey] = "SELECT id FROM foo"
+			req := &bindings.InvokeRequest{
+				Metadata:  metadata,
+				Operation: queryOperation,
+			}
+			resp, err := m.Invoke(context.Background(), req)
+			assert.Nil(t, resp)
+			assert.Error(t, err, metadata)
+		}
+	})
+
 	t.Run("close operation", func(t *testing.T) {
 		mock.ExpectClose()
 		req := &bindings.InvokeRequest{
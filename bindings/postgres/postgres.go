@@ -16,13 +16,17 @@ package postgres
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pkg/errors"
 
 	"github.com/dapr/components-contrib/bindings"
+	contribMetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 )
 
@@ -34,14 +38,43 @@ const (
 
 	connectionURLKey = "url"
 	commandSQLKey    = "sql"
+
+	// Maximum number of rows returned by a query, or by a page of a query; 0 for no limit.
+	maxRowsKey = "maxRows"
+	// Maximum duration of an operation, as a Go duration; 0 for no limit.
+	queryTimeoutKey = "queryTimeout"
+
+	// Keys of the request metadata to read the results of a query by pages.
+	pageSizeKey  = "pageSize"
+	pageTokenKey = "pageToken"
+	// Comma-separated columns uniquely identifying the rows, ordering the pages.
+	pageKeyKey = "pageKey"
+
+	// Key of the response metadata with the token of the next page, if any.
+	nextPageTokenKey = "next-page-token"
+
+	defaultInitTimeout = 30 * time.Second
 )
 
 // Postgres represents PostgreSQL output binding.
 type Postgres struct {
-	logger logger.Logger
-	db     *pgxpool.Pool
+	logger       logger.Logger
+	db           *pgxpool.Pool
+	maxRows      int
+	queryTimeout time.Duration
 }
 
+// page is a page of the results of a query.
+type page struct {
+	size   int
+	offset int
+	// Columns ordering the results, so that the pages are stable.
+	key []string
+}
+
+// columnName is the syntax of the columns of the page keys.
+var columnName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 // NewPostgres returns a new PostgreSQL output binding.
 func NewPostgres(logger logger.Logger) bindings.OutputBinding {
 	return &Postgres{logger: logger}
@@ -54,6 +87,29 @@ func (p *Postgres) Init(metadata bindings.Metadata) error {
 		return errors.Errorf("required metadata not set: %s", connectionURLKey)
 	}
 
+	var err error
+	if val, ok := metadata.Properties[maxRowsKey]; ok && val != "" {
+		p.maxRows, err = strconv.Atoi(val)
+		if err != nil || p.maxRows < 0 {
+			return errors.Errorf("invalid %s: %s", maxRowsKey, val)
+		}
+	}
+	if val, ok := metadata.Properties[queryTimeoutKey]; ok && val != "" {
+		p.queryTimeout, err = time.ParseDuration(val)
+		if err != nil || p.queryTimeout < 0 {
+			return errors.Errorf("invalid %s: %s", queryTimeoutKey, val)
+		}
+	}
+
+	// By default, the pool connects on the first operation
+	initOptions, err := contribMetadata.GetInitOptions(metadata.Properties, contribMetadata.InitOptions{
+		FailFast: false,
+		Timeout:  defaultInitTimeout,
+	})
+	if err != nil {
+		return err
+	}
+
 	poolConfig, err := pgxpool.ParseConfig(url)
 	if err != nil {
 		return errors.Wrap(err, "error opening DB connection")
@@ -64,6 +120,17 @@ func (p *Postgres) Init(metadata bindings.Metadata) error {
 		return errors.Wrap(err, "unable to ping the DB")
 	}
 
+	if initOptions.FailFast {
+		ctx, cancel := context.WithTimeout(context.Background(), initOptions.Timeout)
+		defer cancel()
+		if err = p.db.Ping(ctx); err != nil {
+			p.db.Close()
+			p.db = nil
+
+			return errors.Wrap(err, "unable to ping the DB")
+		}
+	}
+
 	return nil
 }
 
@@ -107,6 +174,12 @@ func (p *Postgres) Invoke(ctx context.Context, req *bindings.InvokeRequest) (res
 		},
 	}
 
+	if p.queryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.queryTimeout)
+		defer cancel()
+	}
+
 	switch req.Operation { //nolint:exhaustive
 	case execOperation:
 		r, err := p.exec(ctx, sql)
@@ -116,11 +189,18 @@ func (p *Postgres) Invoke(ctx context.Context, req *bindings.InvokeRequest) (res
 		resp.Metadata["rows-affected"] = strconv.FormatInt(r, 10) // 0 if error
 
 	case queryOperation:
-		d, err := p.query(ctx, sql)
+		pg, err := p.parsePage(req.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		d, more, err := p.query(ctx, sql, pg)
 		if err != nil {
 			return nil, errors.Wrapf(err, "error executing %s with %v", sql, err)
 		}
 		resp.Data = d
+		if more {
+			resp.Metadata[nextPageTokenKey] = strconv.Itoa(pg.offset + pg.size)
+		}
 
 	default:
 		return nil, errors.Errorf(
@@ -146,28 +226,104 @@ func (p *Postgres) Close() error {
 	return nil
 }
 
-func (p *Postgres) query(ctx context.Context, sql string) (result []byte, err error) {
+// parsePage returns the page of results requested with the "pageSize", "pageToken" and "pageKey" request metadata.
+// Without page size, all the results are returned in a single page.
+func (p *Postgres) parsePage(metadata map[string]string) (pg page, err error) {
+	if val, ok := metadata[pageSizeKey]; ok && val != "" {
+		pg.size, err = strconv.Atoi(val)
+		if err != nil || pg.size <= 0 {
+			return pg, errors.Errorf("invalid %s: %s", pageSizeKey, val)
+		}
+		if p.maxRows > 0 && pg.size > p.maxRows {
+			return pg, errors.Errorf("%s %d exceeds %s %d", pageSizeKey, pg.size, maxRowsKey, p.maxRows)
+		}
+	}
+	if val, ok := metadata[pageTokenKey]; ok && val != "" {
+		if pg.size == 0 {
+			return pg, errors.Errorf("%s requires %s", pageTokenKey, pageSizeKey)
+		}
+		pg.offset, err = strconv.Atoi(val)
+		if err != nil || pg.offset < 0 {
+			return pg, errors.Errorf("invalid %s: %s", pageTokenKey, val)
+		}
+	}
+	if pg.size > 0 {
+		pg.key, err = parsePageKey(metadata[pageKeyKey])
+		if err != nil {
+			return pg, err
+		}
+	}
+
+	return pg, nil
+}
+
+// query returns the rows of a page of the results of the query, and whether there are more.
+// The rows are read one at a time, so that the query is aborted as soon as it returns more than the maximum rows.
+func (p *Postgres) query(ctx context.Context, sql string, pg page) (result []byte, more bool, err error) {
 	p.logger.Debugf("query: %s", sql)
 
+	limit := p.maxRows
+	if pg.size > 0 {
+		// One more row is read to know if there is a next page
+		sql = pagedSQL(sql, pg.key, pg.size+1, pg.offset)
+		limit = pg.size
+	}
+
 	rows, err := p.db.Query(ctx, sql)
 	if err != nil {
-		return nil, errors.Wrapf(err, "error executing %s", sql)
+		return nil, false, errors.Wrapf(err, "error executing %s", sql)
 	}
+	defer rows.Close()
 
 	rs := make([]any, 0)
 	for rows.Next() {
+		if limit > 0 && len(rs) == limit {
+			if pg.size > 0 {
+				more = true
+				break
+			}
+			return nil, false, errors.Errorf("query returned more than %d rows, set %s to read the results by pages", p.maxRows, pageSizeKey)
+		}
 		val, rowErr := rows.Values()
 		if rowErr != nil {
-			return nil, errors.Wrapf(rowErr, "error parsing result: %v", rows.Err())
+			return nil, false, errors.Wrapf(rowErr, "error parsing result: %v", rows.Err())
 		}
 		rs = append(rs, val) //nolint:asasalint
 	}
+	if err = rows.Err(); err != nil {
+		return nil, false, errors.Wrap(err, "error reading results")
+	}
 
 	if result, err = json.Marshal(rs); err != nil {
 		err = errors.Wrap(err, "error serializing results")
 	}
 
-	return
+	return result, more, err
+}
+
+// parsePageKey returns the columns of the page key, required to read the results by pages, since the order of the rows
+// of a query is not stable without ORDER BY.
+func parsePageKey(val string) ([]string, error) {
+	if val == "" {
+		return nil, errors.Errorf("%s requires %s, the columns uniquely identifying the rows", pageSizeKey, pageKeyKey)
+	}
+
+	key := strings.Split(val, ",")
+	for i := range key {
+		key[i] = strings.TrimSpace(key[i])
+		if !columnName.MatchString(key[i]) {
+			return nil, errors.Errorf("invalid %s: %s", pageKeyKey, val)
+		}
+	}
+
+	return key, nil
+}
+
+// pagedSQL wraps a query to return a page of its results, ordered by the columns of the key.
+func pagedSQL(sql string, key []string, limit int, offset int) string {
+	sql = strings.TrimSuffix(strings.TrimSpace(sql), ";")
+
+	return fmt.Sprintf("SELECT * FROM (%s) AS dapr_page ORDER BY %s LIMIT %d OFFSET %d", sql, strings.Join(key, ", "), limit, offset)
 }
 
 func (p *Postgres) exec(ctx context.Context, sql string) (result int64, err error) {
@@ -28,6 +28,7 @@ import (
 // Postmark allows sending of emails using the 3rd party Postmark service.
 type Postmark struct {
 	metadata postmarkMetadata
+	template bindings.MessageTemplate
 	logger   logger.Logger
 }
 
@@ -84,6 +85,11 @@ func (p *Postmark) Init(metadata bindings.Metadata) error {
 	// Um, yeah that's about it!
 	p.metadata = meta
 
+	p.template, err = bindings.ParseHTMLMessageTemplate(metadata.Properties)
+	if err != nil {
+		return fmt.Errorf("Postmark binding error: %w", err)
+	}
+
 	return nil
 }
 
@@ -148,13 +154,21 @@ func (p *Postmark) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bi
 		email.Bcc = req.Metadata["emailBcc"]
 	}
 
-	// Email body is held in req.Data, after we tidy it up a bit
-	emailBody, _ := strconv.Unquote(string(req.Data))
-	email.HTMLBody = emailBody
+	// Email body is held in req.Data, optionally rendered through the message template
+	data, rendered, err := bindings.RenderHTMLMessage(p.template, req)
+	if err != nil {
+		return nil, fmt.Errorf("Postmark binding error: %w", err)
+	}
+	if rendered {
+		email.HTMLBody = string(data)
+	} else {
+		// Tidy up the body a bit
+		email.HTMLBody, _ = strconv.Unquote(string(data))
+	}
 
 	// Send the email
 	client := postmark.NewClient(p.metadata.ServerToken, p.metadata.AccountToken)
-	_, err := client.SendEmail(ctx, email)
+	_, err = client.SendEmail(ctx, email)
 	if err != nil {
 		return nil, fmt.Errorf("error from Postmark, sending email failed: %+v", err)
 	}
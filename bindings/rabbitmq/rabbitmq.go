@@ -71,6 +71,10 @@ func NewRabbitMQ(logger logger.Logger) bindings.InputOutputBinding {
 
 // Init does metadata parsing and connection creation.
 func (r *RabbitMQ) Init(metadata bindings.Metadata) error {
+	if err := bindings.ValidateConfirmedDelivery(metadata.Properties); err != nil {
+		return err
+	}
+
 	err := r.parseMetadata(metadata)
 	if err != nil {
 		return err
@@ -16,15 +16,19 @@ package redis
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/pkg/errors"
 
 	"github.com/dapr/components-contrib/bindings"
 	rediscomponent "github.com/dapr/components-contrib/internal/component/redis"
+	contribMetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 )
 
+const defaultInitTimeout = 30 * time.Second
+
 // Redis is a redis output binding.
 type Redis struct {
 	client         redis.UniversalClient
@@ -42,6 +46,14 @@ func NewRedis(logger logger.Logger) bindings.OutputBinding {
 
 // Init performs metadata parsing and connection creation.
 func (r *Redis) Init(meta bindings.Metadata) (err error) {
+	// By default, Init fails if redis is not reachable
+	initOptions, err := contribMetadata.GetInitOptions(meta.Properties, contribMetadata.InitOptions{
+		FailFast: true,
+		Timeout:  defaultInitTimeout,
+	})
+	if err != nil {
+		return fmt.Errorf("redis binding: %v", err)
+	}
 	r.client, r.clientSettings, err = rediscomponent.ParseClientFromProperties(meta.Properties, nil)
 	if err != nil {
 		return err
@@ -49,16 +61,25 @@ func (r *Redis) Init(meta bindings.Metadata) (err error) {
 
 	r.ctx, r.cancel = context.WithCancel(context.Background())
 
-	_, err = r.client.Ping(r.ctx).Result()
-	if err != nil {
-		return fmt.Errorf("redis binding: error connecting to redis at %s: %s", r.clientSettings.Host, err)
+	pingCtx, pingCancel := context.WithTimeout(r.ctx, initOptions.Timeout)
+	defer pingCancel()
+	if err = r.ping(pingCtx); err != nil {
+		if initOptions.FailFast {
+			return err
+		}
+		// The client reconnects on the next command
+		r.logger.Warnf("%v, reconnecting on first use", err)
 	}
 
-	return err
+	return nil
 }
 
 func (r *Redis) Ping() error {
-	if _, err := r.client.Ping(r.ctx).Result(); err != nil {
+	return r.ping(r.ctx)
+}
+
+func (r *Redis) ping(ctx context.Context) error {
+	if _, err := r.client.Ping(ctx).Result(); err != nil {
 		return fmt.Errorf("redis binding: error connecting to redis at %s: %s", r.clientSettings.Host, err)
 	}
 
@@ -20,8 +20,10 @@ import (
 	miniredis "github.com/alicebob/miniredis/v2"
 	"github.com/go-redis/redis/v8"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 )
 
@@ -106,6 +108,36 @@ func TestInvokeDelete(t *testing.T) {
 	assert.Equal(t, nil, rgetRep)
 }
 
+func TestInitFailFast(t *testing.T) {
+	// Nothing listens on this port
+	props := func(failFast string) bindings.Metadata {
+		return bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"redisHost":             "127.0.0.1:1",
+			"redisMaxRetries":       "0",
+			metadata.FailFastKey:    failFast,
+			metadata.InitTimeoutKey: "1s",
+		}}}
+	}
+
+	t.Run("fail fast", func(t *testing.T) {
+		bind := NewRedis(logger.NewLogger("test")).(*Redis)
+
+		err := bind.Init(props("true"))
+
+		assert.ErrorContains(t, err, "error connecting to redis at 127.0.0.1:1")
+	})
+
+	t.Run("lazy connection", func(t *testing.T) {
+		bind := NewRedis(logger.NewLogger("test")).(*Redis)
+
+		err := bind.Init(props("false"))
+
+		require.NoError(t, err)
+		assert.Error(t, bind.Ping())
+		bind.Close()
+	})
+}
+
 func setupMiniredis() (*miniredis.Miniredis, *redis.Client) {
 	s, err := miniredis.Run()
 	if err != nil {
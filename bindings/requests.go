@@ -14,10 +14,20 @@ limitations under the License.
 package bindings
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"strconv"
 )
 
+const (
+	// ContentTypeMetadataKey is the metadata key of the content type of the data of the requests and of the responses.
+	ContentTypeMetadataKey = "contentType"
+	// DecodeBase64MetadataKey is the request metadata key asking the bindings sending the data as is to decode it from
+	// base64 first.
+	DecodeBase64MetadataKey = "decodeBase64"
+)
+
 // InvokeRequest is the object given to a dapr output binding.
 type InvokeRequest struct {
 	Data      []byte            `json:"data"`
@@ -63,3 +73,63 @@ func (r *InvokeRequest) GetMetadataAsInt64(key string, bitSize int) (int64, erro
 
 	return 0, nil
 }
+
+// GetMetadataOrDefault returns the value of the metadata key of the request, or defaultValue if it's missing or empty,
+// for the request metadata overriding a property of the component.
+func (r *InvokeRequest) GetMetadataOrDefault(key string, defaultValue string) string {
+	if val, ok := r.Metadata[key]; ok && val != "" {
+		return val
+	}
+
+	return defaultValue
+}
+
+// DecodedData returns the bytes of the data of the request.
+// Data sent as a JSON string, such as a string sent to the HTTP API of Dapr, is unquoted; other data, such as the bytes
+// sent to the gRPC API, is never unquoted. The data is decoded from base64 only when decodeBase64 is true, since JSON
+// can't carry binary data.
+func (r *InvokeRequest) DecodedData(decodeBase64 bool) ([]byte, error) {
+	data := r.Data
+	if s, ok := jsonString(r.Data); ok {
+		data = []byte(s)
+	}
+
+	if decodeBase64 {
+		decoded, err := base64.StdEncoding.DecodeString(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("error decoding the data from base64: %w", err)
+		}
+		data = decoded
+	}
+
+	return data, nil
+}
+
+// BinaryData returns the data of the request for the bindings sending it as is, such as JSON documents. The data is
+// only decoded, as in DecodedData, when the "decodeBase64" request metadata is true.
+func (r *InvokeRequest) BinaryData() ([]byte, error) {
+	decodeBase64, err := r.GetMetadataAsBool(DecodeBase64MetadataKey)
+	if err != nil {
+		return nil, err
+	}
+	if !decodeBase64 {
+		return r.Data, nil
+	}
+
+	return r.DecodedData(true)
+}
+
+// jsonString returns the value of the data if it's a JSON string.
+func jsonString(data []byte) (string, bool) {
+	if len(data) < 2 || data[0] != '"' || data[len(data)-1] != '"' {
+		return "", false
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return s, true
+	}
+	// Strings quoted by Go but not valid in JSON
+	s, err := strconv.Unquote(string(data))
+	return s, err == nil
+}
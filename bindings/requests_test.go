@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bindings
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodedData(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n")
+
+	tests := map[string]struct {
+		data         string
+		contentType  string
+		decodeBase64 bool
+		expected     []byte
+		err          string
+	}{
+		"JSON string":                     {data: `"hello \"world\" \/"`, expected: []byte(`hello "world" /`)},
+		"JSON object":                     {data: `{"key":"value"}`, expected: []byte(`{"key":"value"}`)},
+		"text JSON string":                {data: `"aGVsbG8="`, contentType: "text/plain; charset=utf-8", expected: []byte("aGVsbG8=")},
+		"base64 JSON string":              {data: `"aGVsbG8="`, decodeBase64: true, expected: []byte("hello")},
+		"base64 data":                     {data: "aGVsbG8=", decodeBase64: true, expected: []byte("hello")},
+		"binary data":                     {data: string(png), contentType: "image/png", expected: png},
+		"binary base64 JSON string as is": {data: `"iVBORw0KGgo="`, contentType: "image/png", expected: []byte("iVBORw0KGgo=")},
+		"binary JSON string decoded once": {data: `"iVBORw0KGgo="`, contentType: "image/png", decodeBase64: true, expected: png},
+		"invalid base64":                  {data: `"not base64"`, decodeBase64: true, err: "error decoding the data from base64"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := &InvokeRequest{Data: []byte(tt.data), Metadata: map[string]string{ContentTypeMetadataKey: tt.contentType}}
+
+			data, err := req.DecodedData(tt.decodeBase64)
+
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, data)
+		})
+	}
+}
+
+func TestBinaryData(t *testing.T) {
+	t.Run("JSON data as is", func(t *testing.T) {
+		req := &InvokeRequest{Data: []byte(`"hello"`)}
+
+		data, err := req.BinaryData()
+
+		require.NoError(t, err)
+		assert.Equal(t, []byte(`"hello"`), data)
+	})
+
+	t.Run("binary data not decoded without asking", func(t *testing.T) {
+		req := &InvokeRequest{Data: []byte(`"CgVoZWxsbw=="`), Metadata: map[string]string{ContentTypeMetadataKey: "application/x-protobuf"}}
+
+		data, err := req.BinaryData()
+
+		require.NoError(t, err)
+		assert.Equal(t, []byte(`"CgVoZWxsbw=="`), data)
+	})
+
+	t.Run("base64 JSON string decoded when asked", func(t *testing.T) {
+		req := &InvokeRequest{Data: []byte(`"CgVoZWxsbw=="`), Metadata: map[string]string{DecodeBase64MetadataKey: "true"}}
+
+		data, err := req.BinaryData()
+
+		require.NoError(t, err)
+		assert.Equal(t, []byte("\n\x05hello"), data)
+	})
+
+	t.Run("invalid decodeBase64", func(t *testing.T) {
+		req := &InvokeRequest{Data: []byte(`"CgVoZWxsbw=="`), Metadata: map[string]string{DecodeBase64MetadataKey: "maybe"}}
+
+		_, err := req.BinaryData()
+
+		assert.Error(t, err)
+	})
+}
+
+func TestGetMetadataOrDefault(t *testing.T) {
+	req := &InvokeRequest{Metadata: map[string]string{"set": "value", "empty": ""}}
+
+	assert.Equal(t, "value", req.GetMetadataOrDefault("set", "default"))
+	assert.Equal(t, "default", req.GetMetadataOrDefault("empty", "default"))
+	assert.Equal(t, "default", req.GetMetadataOrDefault("missing", "default"))
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicenow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// updateOperation updates the fields of the request data of the record of the "sysId" metadata.
+	updateOperation bindings.OperationKind = "update"
+
+	// Request metadata keys; table overrides the table of the component metadata.
+	metadataKeyTable = "table"
+	metadataKeySysID = "sysId"
+
+	// Response metadata keys.
+	metadataKeyNumber = "number"
+
+	defaultTable   = "incident"
+	defaultTimeout = 30 * time.Second
+)
+
+// ServiceNow is an output binding creating, updating and reading the records of a table of a ServiceNow instance,
+// incidents by default, with the Table API.
+type ServiceNow struct {
+	metadata   *serviceNowMetadata
+	httpClient *http.Client
+
+	logger logger.Logger
+}
+
+type serviceNowMetadata struct {
+	// URL of the instance, e.g. https://example.service-now.com.
+	InstanceURL string `mapstructure:"instanceURL"`
+	// Credentials of a user, for basic authentication, or for the password grant of OAuth with a client.
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password" mdsensitive:"true"`
+	// OAuth client of the instance; its credentials alone use the client credentials grant.
+	ClientID     string `mapstructure:"clientId"`
+	ClientSecret string `mapstructure:"clientSecret" mdsensitive:"true"`
+	// OAuth access token obtained by other means, used as is.
+	AccessToken  string `mapstructure:"accessToken" mdsensitive:"true"`
+	Table        string `mapstructure:"table"`
+	TimeoutInSec int    `mapstructure:"timeoutInSec"`
+}
+
+// serviceNowError is the error returned by the Table API.
+type serviceNowError struct {
+	Error struct {
+		Message string `json:"message"`
+		Detail  string `json:"detail"`
+	} `json:"error"`
+}
+
+// NewServiceNow returns a new ServiceNow output binding.
+func NewServiceNow(logger logger.Logger) bindings.OutputBinding {
+	return &ServiceNow{logger: logger}
+}
+
+// Init parses the metadata and creates the HTTP client, authenticated with OAuth if a client or a token is configured.
+func (s *ServiceNow) Init(md bindings.Metadata) error {
+	m, err := parseMetadata(md.Properties)
+	if err != nil {
+		return err
+	}
+
+	timeout := time.Duration(m.TimeoutInSec) * time.Second
+	// The tokens are requested with the same timeout as the requests of the API
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Timeout: timeout})
+	tokenURL := m.InstanceURL + "/oauth_token.do"
+	switch {
+	case m.AccessToken != "":
+		s.httpClient = oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: m.AccessToken}))
+	case m.ClientID != "" && m.Username != "":
+		config := &oauth2.Config{
+			ClientID:     m.ClientID,
+			ClientSecret: m.ClientSecret,
+			Endpoint:     oauth2.Endpoint{TokenURL: tokenURL, AuthStyle: oauth2.AuthStyleInParams},
+		}
+		token, err := config.PasswordCredentialsToken(ctx, m.Username, m.Password)
+		if err != nil {
+			return fmt.Errorf("servicenow binding error: error getting an OAuth token: %w", err)
+		}
+		// The token is refreshed with its refresh token
+		s.httpClient = config.Client(ctx, token)
+	case m.ClientID != "":
+		config := &clientcredentials.Config{
+			ClientID:     m.ClientID,
+			ClientSecret: m.ClientSecret,
+			TokenURL:     tokenURL,
+			AuthStyle:    oauth2.AuthStyleInParams,
+		}
+		s.httpClient = config.Client(ctx)
+	default:
+		s.httpClient = &http.Client{}
+	}
+	s.httpClient.Timeout = timeout
+	s.metadata = m
+
+	return nil
+}
+
+func parseMetadata(md map[string]string) (*serviceNowMetadata, error) {
+	m := serviceNowMetadata{
+		Table:        defaultTable,
+		TimeoutInSec: int(defaultTimeout / time.Second),
+	}
+	err := metadata.DecodeMetadata(md, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.InstanceURL == "" {
+		return nil, errors.New("servicenow binding error: instanceURL is required")
+	}
+	m.InstanceURL = strings.TrimSuffix(m.InstanceURL, "/")
+	if m.AccessToken == "" && m.ClientID == "" && (m.Username == "" || m.Password == "") {
+		return nil, errors.New("servicenow binding error: accessToken, clientId, or username and password are required")
+	}
+	if m.TimeoutInSec < 1 {
+		return nil, fmt.Errorf("servicenow binding error: invalid timeoutInSec %d", m.TimeoutInSec)
+	}
+
+	return &m, nil
+}
+
+func (s *ServiceNow) Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{
+		bindings.CreateOperation,
+		updateOperation,
+		bindings.GetOperation,
+	}
+}
+
+// Invoke creates a record with the fields of the request data, or updates or reads the record of the "sysId" metadata.
+// The response is the record.
+func (s *ServiceNow) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	table := req.Metadata[metadataKeyTable]
+	if table == "" {
+		table = s.metadata.Table
+	}
+	path := "/api/now/table/" + url.PathEscape(table)
+
+	var method string
+	switch req.Operation {
+	case bindings.CreateOperation:
+		method = http.MethodPost
+	case updateOperation, bindings.GetOperation:
+		sysID := req.Metadata[metadataKeySysID]
+		if sysID == "" {
+			return nil, fmt.Errorf("servicenow binding error: required metadata not set: %s", metadataKeySysID)
+		}
+		path += "/" + url.PathEscape(sysID)
+		method = http.MethodPatch
+		if req.Operation == bindings.GetOperation {
+			method = http.MethodGet
+		}
+	default:
+		return nil, fmt.Errorf("servicenow binding error: unsupported operation %s", req.Operation)
+	}
+
+	var body []byte
+	if method != http.MethodGet {
+		var fields map[string]any
+		if err := json.Unmarshal(req.Data, &fields); err != nil {
+			return nil, fmt.Errorf("servicenow binding error: the fields of the record must be a JSON object: %w", err)
+		}
+		body = req.Data
+	}
+
+	return s.do(ctx, method, path, body)
+}
+
+// do sends the request to the Table API and returns the record of the response.
+func (s *ServiceNow) do(ctx context.Context, method string, path string, body []byte) (*bindings.InvokeResponse, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, method, s.metadata.InstanceURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if s.metadata.AccessToken == "" && s.metadata.ClientID == "" {
+		httpReq.SetBasicAuth(s.metadata.Username, s.metadata.Password)
+	}
+
+	resp, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("servicenow binding error: error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	res, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("servicenow binding error: error reading response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var snErr serviceNowError
+		if json.Unmarshal(res, &snErr) == nil && snErr.Error.Message != "" {
+			return nil, fmt.Errorf("servicenow binding error: %s: %s (status %d)", snErr.Error.Message, snErr.Error.Detail, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("servicenow binding error: unexpected status %s: %s", resp.Status, strings.TrimSpace(string(res)))
+	}
+
+	var record struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err = json.Unmarshal(res, &record); err != nil || len(record.Result) == 0 {
+		return nil, fmt.Errorf("servicenow binding error: invalid response: %s", strings.TrimSpace(string(res)))
+	}
+	var ids struct {
+		SysID  string `json:"sys_id"`
+		Number string `json:"number"`
+	}
+	// The values of the fields are strings, unless requested otherwise
+	_ = json.Unmarshal(record.Result, &ids)
+
+	return &bindings.InvokeResponse{
+		Data: record.Result,
+		Metadata: map[string]string{
+			metadataKeySysID:  ids.SysID,
+			metadataKeyNumber: ids.Number,
+		},
+	}, nil
+}
+
+// GetComponentMetadataSchema returns the schema of the metadata of the ServiceNow binding.
+func (s *ServiceNow) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(serviceNowMetadata{
+		Table:        defaultTable,
+		TimeoutInSec: int(defaultTimeout / time.Second),
+	})
+	return fields
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicenow
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+func TestParseMetadata(t *testing.T) {
+	m, err := parseMetadata(map[string]string{
+		"instanceURL": "https://example.service-now.com/",
+		"username":    "admin",
+		"password":    "secret",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.service-now.com", m.InstanceURL)
+	assert.Equal(t, "incident", m.Table)
+	assert.Equal(t, 30, m.TimeoutInSec)
+
+	// A client alone is enough, for the client credentials grant, but a user alone needs its password
+	_, err = parseMetadata(map[string]string{"instanceURL": "https://example.service-now.com", "clientId": "client"})
+	assert.NoError(t, err)
+	_, err = parseMetadata(map[string]string{"instanceURL": "https://example.service-now.com", "username": "admin"})
+	assert.EqualError(t, err, "servicenow binding error: accessToken, clientId, or username and password are required")
+
+	_, err = parseMetadata(map[string]string{"accessToken": "token"})
+	assert.EqualError(t, err, "servicenow binding error: instanceURL is required")
+}
+
+type request struct {
+	method        string
+	path          string
+	authorization string
+	body          string
+}
+
+// newBinding returns a binding whose instance is a server recording the requests, and issuing OAuth tokens.
+func newBinding(t *testing.T, props map[string]string) (*ServiceNow, *[]request) {
+	t.Helper()
+
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/oauth_token.do" {
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "client", r.PostForm.Get("client_id"))
+			w.Write([]byte(`{"access_token": "oauth-` + r.PostForm.Get("grant_type") + `", "token_type": "Bearer", "expires_in": 1800}`))
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, request{r.Method, r.URL.Path, r.Header.Get("Authorization"), string(body)})
+		switch r.URL.Path {
+		case "/api/now/table/incident", "/api/now/table/incident/abc123", "/api/now/table/problem":
+			w.Write([]byte(`{"result": {"sys_id": "abc123", "number": "INC0010001", "short_description": "Disk full"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"message": "No Record found", "detail": "Record doesn't exist"}, "status": "failure"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	props["instanceURL"] = server.URL
+	s := NewServiceNow(logger.NewLogger("test")).(*ServiceNow)
+	require.NoError(t, s.Init(bindings.Metadata{Base: metadata.Base{Properties: props}}))
+
+	return s, &requests
+}
+
+func TestCreateRecord(t *testing.T) {
+	s, requests := newBinding(t, map[string]string{"username": "admin", "password": "secret"})
+
+	res, err := s.Invoke(context.Background(), &bindings.InvokeRequest{
+		Operation: bindings.CreateOperation,
+		Data:      []byte(`{"short_description": "Disk full", "urgency": "1"}`),
+	})
+
+	// The record is unwrapped from the result of the Table API
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"sys_id": "abc123", "number": "INC0010001", "short_description": "Disk full"}`, string(res.Data))
+	assert.Equal(t, map[string]string{"sysId": "abc123", "number": "INC0010001"}, res.Metadata)
+	assert.Equal(t, []request{{"POST", "/api/now/table/incident", "Basic YWRtaW46c2VjcmV0", `{"short_description": "Disk full", "urgency": "1"}`}}, *requests)
+
+	// Records of other tables than the incidents
+	_, err = s.Invoke(context.Background(), &bindings.InvokeRequest{
+		Operation: bindings.CreateOperation,
+		Data:      []byte(`{"short_description": "Recurring disk full"}`),
+		Metadata:  map[string]string{"table": "problem"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "/api/now/table/problem", (*requests)[1].path)
+
+	_, err = s.Invoke(context.Background(), &bindings.InvokeRequest{Operation: bindings.CreateOperation, Data: []byte(`"Disk full"`)})
+	assert.ErrorContains(t, err, "servicenow binding error: the fields of the record must be a JSON object")
+	assert.Len(t, *requests, 2)
+}
+
+func TestUpdateAndGetRecord(t *testing.T) {
+	s, requests := newBinding(t, map[string]string{"accessToken": "token"})
+
+	_, err := s.Invoke(context.Background(), &bindings.InvokeRequest{
+		Operation: updateOperation,
+		Data:      []byte(`{"state": "6", "close_notes": "Disk cleaned"}`),
+		Metadata:  map[string]string{"sysId": "abc123"},
+	})
+	require.NoError(t, err)
+
+	res, err := s.Invoke(context.Background(), &bindings.InvokeRequest{
+		Operation: bindings.GetOperation,
+		Metadata:  map[string]string{"sysId": "abc123"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "INC0010001", res.Metadata["number"])
+
+	// Only the fields of the request are updated
+	assert.Equal(t, []request{
+		{"PATCH", "/api/now/table/incident/abc123", "Bearer token", `{"state": "6", "close_notes": "Disk cleaned"}`},
+		{"GET", "/api/now/table/incident/abc123", "Bearer token", ""},
+	}, *requests)
+
+	_, err = s.Invoke(context.Background(), &bindings.InvokeRequest{
+		Operation: bindings.GetOperation,
+		Metadata:  map[string]string{"sysId": "missing"},
+	})
+	assert.EqualError(t, err, "servicenow binding error: No Record found: Record doesn't exist (status 404)")
+
+	_, err = s.Invoke(context.Background(), &bindings.InvokeRequest{Operation: updateOperation, Data: []byte(`{"state": "6"}`)})
+	assert.EqualError(t, err, "servicenow binding error: required metadata not set: sysId")
+}
+
+func TestOAuth(t *testing.T) {
+	tests := map[string]struct {
+		props         map[string]string
+		authorization string
+	}{
+		"password grant":     {map[string]string{"clientId": "client", "clientSecret": "s", "username": "admin", "password": "secret"}, "Bearer oauth-password"},
+		"client credentials": {map[string]string{"clientId": "client", "clientSecret": "s"}, "Bearer oauth-client_credentials"},
+		"access token":       {map[string]string{"accessToken": "token"}, "Bearer token"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, requests := newBinding(t, tt.props)
+
+			_, err := s.Invoke(context.Background(), &bindings.InvokeRequest{
+				Operation: bindings.GetOperation,
+				Metadata:  map[string]string{"sysId": "abc123"},
+			})
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.authorization, (*requests)[0].authorization)
+		})
+	}
+}
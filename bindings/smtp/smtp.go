@@ -37,6 +37,7 @@ const (
 // Mailer allows sending of emails using the Simple Mail Transfer Protocol.
 type Mailer struct {
 	metadata Metadata
+	template bindings.MessageTemplate
 	logger   logger.Logger
 }
 
@@ -69,6 +70,11 @@ func (s *Mailer) Init(metadata bindings.Metadata) error {
 	}
 	s.metadata = meta
 
+	s.template, err = bindings.ParseHTMLMessageTemplate(metadata.Properties)
+	if err != nil {
+		return fmt.Errorf("smtp binding error: %w", err)
+	}
+
 	return nil
 }
 
@@ -108,13 +114,18 @@ func (s *Mailer) Invoke(_ context.Context, req *bindings.InvokeRequest) (*bindin
 	msg.SetHeader("Subject", metadata.Subject)
 	msg.SetHeader("X-priority", strconv.Itoa(metadata.Priority))
 
-	body, err := strconv.Unquote(string(req.Data))
-
+	data, rendered, err := bindings.RenderHTMLMessage(s.template, req)
 	if err != nil {
+		return nil, fmt.Errorf("smtp binding error: %w", err)
+	}
+
+	body, err := strconv.Unquote(string(data))
+
+	if rendered || err != nil {
 		// When data arrives over gRPC it's not quoted. Unquoting the original data will result in an error.
 		// Instead of unquoting it we'll just use the raw string as that one's already in the right format.
 
-		msg.SetBody("text/html", string(req.Data))
+		msg.SetBody("text/html", string(data))
 	} else {
 		msg.SetBody("text/html", body)
 	}
@@ -0,0 +1,291 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stripe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// createPaymentIntentOperation creates a payment intent with the parameters of the request data.
+	createPaymentIntentOperation bindings.OperationKind = "createPaymentIntent"
+	// confirmPaymentIntentOperation confirms the payment intent of the "id" field of the request data.
+	confirmPaymentIntentOperation bindings.OperationKind = "confirmPaymentIntent"
+	// refundOperation refunds a payment intent or a charge with the parameters of the request data.
+	refundOperation bindings.OperationKind = "refund"
+	// verifyWebhookOperation verifies the signature of the webhook event of the request data and returns the event.
+	verifyWebhookOperation bindings.OperationKind = "verifyWebhook"
+
+	// Request metadata keys.
+	metadataKeyIdempotencyKey = "idempotencyKey"
+	metadataKeySignature      = "signature"
+
+	// Response metadata keys.
+	metadataKeyRequestID = "requestId"
+	metadataKeyObjectID  = "id"
+
+	defaultEndpoint         = "https://api.stripe.com"
+	defaultTimeout          = 80 * time.Second
+	defaultWebhookTolerance = 5 * time.Minute
+)
+
+// Stripe is an output binding creating, confirming and refunding Stripe payment intents, and verifying the signatures
+// of Stripe webhook events.
+// The responses are the Stripe objects, with the same shape as the objects of the webhook events.
+type Stripe struct {
+	metadata   *stripeMetadata
+	httpClient *http.Client
+
+	logger logger.Logger
+}
+
+type stripeMetadata struct {
+	// Secret or restricted key of the Stripe account.
+	APIKey string `mapstructure:"apiKey" mdsensitive:"true"`
+	// Signing secret of the webhook endpoint, required by the verifyWebhook operation.
+	WebhookSecret string `mapstructure:"webhookSecret" mdsensitive:"true"`
+	// Maximum age of the webhook events verified, preventing replays.
+	WebhookToleranceInSec int `mapstructure:"webhookToleranceInSec"`
+	// Version of the Stripe API; the default version of the account if empty.
+	APIVersion   string `mapstructure:"apiVersion"`
+	Endpoint     string `mapstructure:"endpoint"`
+	TimeoutInSec int    `mapstructure:"timeoutInSec"`
+}
+
+// stripeError is the error returned by the Stripe API.
+type stripeError struct {
+	Error struct {
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// NewStripe returns a new Stripe output binding.
+func NewStripe(logger logger.Logger) bindings.OutputBinding {
+	return &Stripe{logger: logger}
+}
+
+// Init parses the metadata and creates the HTTP client.
+func (s *Stripe) Init(md bindings.Metadata) error {
+	m, err := parseMetadata(md.Properties)
+	if err != nil {
+		return err
+	}
+
+	s.metadata = m
+	s.httpClient = &http.Client{
+		Timeout: time.Duration(m.TimeoutInSec) * time.Second,
+	}
+
+	return nil
+}
+
+func parseMetadata(md map[string]string) (*stripeMetadata, error) {
+	m := stripeMetadata{
+		WebhookToleranceInSec: int(defaultWebhookTolerance / time.Second),
+		Endpoint:              defaultEndpoint,
+		TimeoutInSec:          int(defaultTimeout / time.Second),
+	}
+	err := metadata.DecodeMetadata(md, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.APIKey == "" {
+		return nil, errors.New("stripe binding error: apiKey is required")
+	}
+	m.Endpoint = strings.TrimSuffix(m.Endpoint, "/")
+	if m.WebhookToleranceInSec < 1 {
+		return nil, fmt.Errorf("stripe binding error: invalid webhookToleranceInSec %d", m.WebhookToleranceInSec)
+	}
+	if m.TimeoutInSec < 1 {
+		return nil, fmt.Errorf("stripe binding error: invalid timeoutInSec %d", m.TimeoutInSec)
+	}
+
+	return &m, nil
+}
+
+func (s *Stripe) Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{
+		createPaymentIntentOperation,
+		confirmPaymentIntentOperation,
+		refundOperation,
+		verifyWebhookOperation,
+	}
+}
+
+// Invoke sends the request to the Stripe API, with the idempotency key of the request metadata if any.
+func (s *Stripe) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	if req.Operation == verifyWebhookOperation {
+		return s.verifyWebhook(req)
+	}
+
+	params, err := parseParams(req.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	var path string
+	switch req.Operation {
+	case createPaymentIntentOperation:
+		path = "/v1/payment_intents"
+	case confirmPaymentIntentOperation:
+		id, ok := params["id"].(string)
+		if !ok || id == "" {
+			return nil, errors.New("stripe binding error: the id of the payment intent is required")
+		}
+		delete(params, "id")
+		path = "/v1/payment_intents/" + url.PathEscape(id) + "/confirm"
+	case refundOperation:
+		if params["payment_intent"] == nil && params["charge"] == nil {
+			return nil, errors.New("stripe binding error: payment_intent or charge is required")
+		}
+		path = "/v1/refunds"
+	default:
+		return nil, fmt.Errorf("stripe binding error: unsupported operation %s", req.Operation)
+	}
+
+	form := url.Values{}
+	if err = encodeForm(form, "", params); err != nil {
+		return nil, fmt.Errorf("stripe binding error: %w", err)
+	}
+
+	return s.post(ctx, path, form, req.Metadata[metadataKeyIdempotencyKey])
+}
+
+// post sends the form to the API and returns the object of the response.
+func (s *Stripe) post(ctx context.Context, path string, form url.Values, idempotencyKey string) (*bindings.InvokeResponse, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.metadata.Endpoint+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+s.metadata.APIKey)
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if idempotencyKey != "" {
+		httpReq.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+	if s.metadata.APIVersion != "" {
+		httpReq.Header.Set("Stripe-Version", s.metadata.APIVersion)
+	}
+
+	resp, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("stripe binding error: error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("stripe binding error: error reading response: %w", err)
+	}
+	requestID := resp.Header.Get("Request-Id")
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var stripeErr stripeError
+		if json.Unmarshal(body, &stripeErr) == nil && stripeErr.Error.Message != "" {
+			return nil, fmt.Errorf("stripe binding error: %s (type %s, code %s, request %s)", stripeErr.Error.Message, stripeErr.Error.Type, stripeErr.Error.Code, requestID)
+		}
+		return nil, fmt.Errorf("stripe binding error: unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var object struct {
+		ID string `json:"id"`
+	}
+	if err = json.Unmarshal(body, &object); err != nil {
+		return nil, fmt.Errorf("stripe binding error: invalid response: %w", err)
+	}
+
+	return &bindings.InvokeResponse{
+		Data: body,
+		Metadata: map[string]string{
+			metadataKeyRequestID: requestID,
+			metadataKeyObjectID:  object.ID,
+		},
+	}, nil
+}
+
+// parseParams parses the parameters of the request, a JSON object.
+func parseParams(data []byte) (map[string]any, error) {
+	params := map[string]any{}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return params, nil
+	}
+
+	d := json.NewDecoder(strings.NewReader(string(data)))
+	// Keeps the amounts as written
+	d.UseNumber()
+	if err := d.Decode(&params); err != nil {
+		return nil, fmt.Errorf("stripe binding error: the parameters must be a JSON object: %w", err)
+	}
+
+	return params, nil
+}
+
+// encodeForm encodes the value in the form with the key, as the Stripe API expects: the fields of the objects as
+// key[field] and the elements of the arrays as key[index].
+func encodeForm(form url.Values, key string, val any) error {
+	switch v := val.(type) {
+	case map[string]any:
+		for field, fieldVal := range v {
+			k := field
+			if key != "" {
+				k = key + "[" + field + "]"
+			}
+			if err := encodeForm(form, k, fieldVal); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for i, elem := range v {
+			if err := encodeForm(form, fmt.Sprintf("%s[%d]", key, i), elem); err != nil {
+				return err
+			}
+		}
+	case nil:
+		// Empty values unset the fields
+		form.Set(key, "")
+	case string:
+		form.Set(key, v)
+	case json.Number:
+		form.Set(key, v.String())
+	case bool:
+		form.Set(key, fmt.Sprint(v))
+	default:
+		return fmt.Errorf("unsupported value of %s", key)
+	}
+
+	return nil
+}
+
+// GetComponentMetadataSchema returns the schema of the metadata of the Stripe binding.
+func (s *Stripe) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(stripeMetadata{
+		WebhookToleranceInSec: int(defaultWebhookTolerance / time.Second),
+		Endpoint:              defaultEndpoint,
+		TimeoutInSec:          int(defaultTimeout / time.Second),
+	})
+	return fields
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stripe
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+func TestParseMetadata(t *testing.T) {
+	m, err := parseMetadata(map[string]string{"apiKey": "sk_test"})
+	require.NoError(t, err)
+	assert.Equal(t, defaultEndpoint, m.Endpoint)
+	assert.Equal(t, 300, m.WebhookToleranceInSec)
+	// The timeout of the Stripe libraries
+	assert.Equal(t, 80, m.TimeoutInSec)
+
+	_, err = parseMetadata(map[string]string{"webhookSecret": "whsec_test"})
+	assert.EqualError(t, err, "stripe binding error: apiKey is required")
+	_, err = parseMetadata(map[string]string{"apiKey": "sk_test", "webhookToleranceInSec": "0"})
+	assert.EqualError(t, err, "stripe binding error: invalid webhookToleranceInSec 0")
+}
+
+func TestEncodeForm(t *testing.T) {
+	params, err := parseParams([]byte(`{
+		"amount": 1099.50,
+		"automatic_payment_methods": {"enabled": true},
+		"shipping": {"address": {"line1": "1 Main St"}, "name": "Jenny"},
+		"payment_method_types": ["card", "sepa_debit"],
+		"description": null
+	}`))
+	require.NoError(t, err)
+
+	form := url.Values{}
+	require.NoError(t, encodeForm(form, "", params))
+
+	// The nested objects and arrays use the bracket notation of the Stripe API, and the numbers are kept as written
+	assert.Equal(t, url.Values{
+		"amount":                             {"1099.50"},
+		"automatic_payment_methods[enabled]": {"true"},
+		"shipping[address][line1]":           {"1 Main St"},
+		"shipping[name]":                     {"Jenny"},
+		"payment_method_types[0]":            {"card"},
+		"payment_method_types[1]":            {"sepa_debit"},
+		"description":                        {""},
+	}, form)
+
+	params, err = parseParams(nil)
+	require.NoError(t, err)
+	assert.Empty(t, params)
+	_, err = parseParams([]byte(`[2000, "usd"]`))
+	assert.ErrorContains(t, err, "stripe binding error: the parameters must be a JSON object")
+}
+
+func newStripe(t *testing.T, props map[string]string, handler http.HandlerFunc) *Stripe {
+	t.Helper()
+
+	if handler != nil {
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+		props["endpoint"] = server.URL
+	}
+	s := NewStripe(logger.NewLogger("test")).(*Stripe)
+	require.NoError(t, s.Init(bindings.Metadata{Base: metadata.Base{Properties: props}}))
+
+	return s
+}
+
+func TestPaymentIntents(t *testing.T) {
+	var (
+		lastRequest *http.Request
+		lastForm    url.Values
+	)
+	s := newStripe(t, map[string]string{"apiKey": "sk_test", "apiVersion": "2022-11-15"}, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		lastRequest, lastForm = r, r.PostForm
+		w.Header().Set("Request-Id", "req_1")
+		switch r.URL.Path {
+		case "/v1/payment_intents":
+			w.Write([]byte(`{"id": "pi_1", "object": "payment_intent", "status": "requires_confirmation"}`))
+		case "/v1/payment_intents/pi_1/confirm":
+			w.Write([]byte(`{"id": "pi_1", "object": "payment_intent", "status": "succeeded"}`))
+		case "/v1/refunds":
+			w.Write([]byte(`{"id": "re_1", "object": "refund", "status": "succeeded"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"type": "invalid_request_error", "code": "resource_missing", "message": "No such payment_intent"}}`))
+		}
+	})
+
+	invoke := func(op bindings.OperationKind, data string, md map[string]string) (*bindings.InvokeResponse, error) {
+		return s.Invoke(context.Background(), &bindings.InvokeRequest{Operation: op, Data: []byte(data), Metadata: md})
+	}
+
+	t.Run("create with idempotency key", func(t *testing.T) {
+		res, err := invoke(createPaymentIntentOperation, `{
+			"amount": 2000,
+			"currency": "usd",
+			"payment_method_types": ["card"],
+			"metadata": {"order": "42"},
+			"capture_method": null
+		}`, map[string]string{"idempotencyKey": "order-42"})
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id": "pi_1", "object": "payment_intent", "status": "requires_confirmation"}`, string(res.Data))
+		assert.Equal(t, map[string]string{"requestId": "req_1", "id": "pi_1"}, res.Metadata)
+		assert.Equal(t, url.Values{
+			"amount":                  {"2000"},
+			"currency":                {"usd"},
+			"payment_method_types[0]": {"card"},
+			"metadata[order]":         {"42"},
+			"capture_method":          {""},
+		}, lastForm)
+		assert.Equal(t, "Bearer sk_test", lastRequest.Header.Get("Authorization"))
+		assert.Equal(t, "order-42", lastRequest.Header.Get("Idempotency-Key"))
+		assert.Equal(t, "2022-11-15", lastRequest.Header.Get("Stripe-Version"))
+	})
+
+	t.Run("confirm", func(t *testing.T) {
+		res, err := invoke(confirmPaymentIntentOperation, `{"id": "pi_1", "payment_method": "pm_card_visa"}`, nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, "pi_1", res.Metadata["id"])
+		assert.Equal(t, url.Values{"payment_method": {"pm_card_visa"}}, lastForm)
+		assert.Empty(t, lastRequest.Header.Get("Idempotency-Key"))
+	})
+
+	t.Run("refund", func(t *testing.T) {
+		res, err := invoke(refundOperation, `{"payment_intent": "pi_1", "amount": 500}`, nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, "re_1", res.Metadata["id"])
+		assert.Equal(t, url.Values{"payment_intent": {"pi_1"}, "amount": {"500"}}, lastForm)
+	})
+
+	t.Run("Stripe error", func(t *testing.T) {
+		_, err := invoke(confirmPaymentIntentOperation, `{"id": "pi_2"}`, nil)
+
+		assert.ErrorContains(t, err, "No such payment_intent (type invalid_request_error, code resource_missing, request req_1)")
+	})
+
+	t.Run("confirm requires the payment intent", func(t *testing.T) {
+		_, err := invoke(confirmPaymentIntentOperation, `{"payment_method": "pm_card_visa"}`, nil)
+
+		assert.EqualError(t, err, "stripe binding error: the id of the payment intent is required")
+	})
+
+	t.Run("refund requires the payment", func(t *testing.T) {
+		_, err := invoke(refundOperation, `{"amount": 500}`, nil)
+
+		assert.EqualError(t, err, "stripe binding error: payment_intent or charge is required")
+	})
+}
+
+func sign(secret string, ts time.Time, payload string) string {
+	timestamp := fmt.Sprint(ts.Unix())
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	const event = `{"id": "evt_1", "object": "event", "type": "payment_intent.succeeded", "data": {"object": {"id": "pi_1"}}}`
+	s := newStripe(t, map[string]string{"apiKey": "sk_test", "webhookSecret": "whsec_1"}, nil)
+
+	verify := func(payload string, signature string) (*bindings.InvokeResponse, error) {
+		return s.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: verifyWebhookOperation,
+			Data:      []byte(payload),
+			Metadata:  map[string]string{"signature": signature},
+		})
+	}
+
+	t.Run("valid signature", func(t *testing.T) {
+		res, err := verify(event, sign("whsec_1", time.Now(), event))
+
+		require.NoError(t, err)
+		assert.Equal(t, event, string(res.Data))
+		assert.Equal(t, map[string]string{"id": "evt_1", "type": "payment_intent.succeeded"}, res.Metadata)
+	})
+
+	t.Run("one of the signatures during a rotation", func(t *testing.T) {
+		other := sign("whsec_0", time.Now(), event)
+		valid := sign("whsec_1", time.Now(), event)
+
+		_, err := verify(event, other+","+valid[len("t=0000000000,"):])
+
+		require.NoError(t, err)
+	})
+
+	t.Run("invalid signatures", func(t *testing.T) {
+		tests := map[string]struct {
+			payload   string
+			signature string
+		}{
+			"tampered payload": {`{"id": "evt_2"}`, sign("whsec_1", time.Now(), event)},
+			"other secret":     {event, sign("whsec_0", time.Now(), event)},
+			"too old":          {event, sign("whsec_1", time.Now().Add(-10*time.Minute), event)},
+			"no timestamp":     {event, "v1=abcd"},
+			"no signature":     {event, ""},
+		}
+		for name, tc := range tests {
+			t.Run(name, func(t *testing.T) {
+				_, err := verify(tc.payload, tc.signature)
+				assert.Error(t, err)
+			})
+		}
+	})
+
+	t.Run("requires the webhook secret", func(t *testing.T) {
+		s := newStripe(t, map[string]string{"apiKey": "sk_test"}, nil)
+
+		_, err := s.Invoke(context.Background(), &bindings.InvokeRequest{
+			Operation: verifyWebhookOperation,
+			Data:      []byte(event),
+			Metadata:  map[string]string{"signature": sign("whsec_1", time.Now(), event)},
+		})
+
+		assert.ErrorContains(t, err, "webhookSecret is required")
+	})
+}
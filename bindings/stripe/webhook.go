@@ -0,0 +1,103 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stripe
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+)
+
+// Response metadata key of the type of the webhook events.
+const metadataKeyEventType = "type"
+
+var errInvalidSignature = errors.New("stripe binding error: invalid webhook signature")
+
+// verifyWebhook verifies the request data, the body of a webhook event, with the Stripe-Signature header of the
+// "signature" metadata, and returns the event.
+func (s *Stripe) verifyWebhook(req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	if s.metadata.WebhookSecret == "" {
+		return nil, errors.New("stripe binding error: webhookSecret is required to verify webhook events")
+	}
+	header := req.Metadata[metadataKeySignature]
+	if header == "" {
+		return nil, fmt.Errorf("stripe binding error: %s is required", metadataKeySignature)
+	}
+
+	// The header is "t=<timestamp>,v1=<signature>", with a v1 signature per signing secret during a rotation
+	var (
+		timestamp  string
+		signatures [][]byte
+	)
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			timestamp = v
+		case "v1":
+			if sig, err := hex.DecodeString(v); err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return nil, errInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.metadata.WebhookSecret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(req.Data)
+	expected := mac.Sum(nil)
+	valid := false
+	for _, sig := range signatures {
+		if hmac.Equal(sig, expected) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, errInvalidSignature
+	}
+
+	age := time.Since(time.Unix(ts, 0))
+	if age > time.Duration(s.metadata.WebhookToleranceInSec)*time.Second {
+		return nil, fmt.Errorf("stripe binding error: webhook event signed %s ago, exceeding the tolerance", age.Truncate(time.Second))
+	}
+
+	var event struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+	}
+	if err = json.Unmarshal(req.Data, &event); err != nil {
+		return nil, fmt.Errorf("stripe binding error: invalid webhook event: %w", err)
+	}
+
+	return &bindings.InvokeResponse{
+		Data: req.Data,
+		Metadata: map[string]string{
+			metadataKeyObjectID:  event.ID,
+			metadataKeyEventType: event.Type,
+		},
+	}, nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bindings
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"text/template"
+)
+
+// MessageTemplateKey is the metadata property holding a Go template used to render the message body
+// of notification bindings (SMS, email, chat). It can be set on the component or on each request.
+const MessageTemplateKey = "messageTemplate"
+
+// MessageTemplate is a parsed message template, either a text/template or an html/template.
+type MessageTemplate interface {
+	Execute(wr io.Writer, data interface{}) error
+}
+
+// ParseMessageTemplate parses the message template from the given metadata properties, for plain text messages.
+// It returns nil if no template is configured.
+func ParseMessageTemplate(properties map[string]string) (MessageTemplate, error) {
+	text, ok := properties[MessageTemplateKey]
+	if !ok || text == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.New(MessageTemplateKey).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing metadata `%s`: %w", MessageTemplateKey, err)
+	}
+
+	return tmpl, nil
+}
+
+// ParseHTMLMessageTemplate parses the message template from the given metadata properties, for HTML messages.
+// The values are escaped for the context of the HTML document they are rendered in.
+// It returns nil if no template is configured.
+func ParseHTMLMessageTemplate(properties map[string]string) (MessageTemplate, error) {
+	text, ok := properties[MessageTemplateKey]
+	if !ok || text == "" {
+		return nil, nil
+	}
+
+	tmpl, err := htmltemplate.New(MessageTemplateKey).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing metadata `%s`: %w", MessageTemplateKey, err)
+	}
+
+	return tmpl, nil
+}
+
+// RenderMessage renders the message body of a request.
+// A template set in the request metadata takes priority over the component template tmpl. The request
+// data must then be a JSON document, whose fields are available to the template.
+// If no template is set, the request data is returned as-is and the returned bool is false.
+func RenderMessage(tmpl MessageTemplate, req *InvokeRequest) ([]byte, bool, error) {
+	return renderMessage(tmpl, req, ParseMessageTemplate)
+}
+
+// RenderHTMLMessage renders the HTML message body of a request, as RenderMessage does, with a template set in the
+// request metadata parsed as an html/template.
+func RenderHTMLMessage(tmpl MessageTemplate, req *InvokeRequest) ([]byte, bool, error) {
+	return renderMessage(tmpl, req, ParseHTMLMessageTemplate)
+}
+
+func renderMessage(tmpl MessageTemplate, req *InvokeRequest, parse func(map[string]string) (MessageTemplate, error)) ([]byte, bool, error) {
+	reqTmpl, err := parse(req.Metadata)
+	if err != nil {
+		return nil, false, err
+	}
+	if reqTmpl != nil {
+		tmpl = reqTmpl
+	}
+	if tmpl == nil {
+		return req.Data, false, nil
+	}
+
+	var data interface{}
+	if len(req.Data) > 0 {
+		if err = json.Unmarshal(req.Data, &data); err != nil {
+			return nil, false, fmt.Errorf("error decoding data for `%s`, a JSON document is expected: %w", MessageTemplateKey, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, data); err != nil {
+		return nil, false, fmt.Errorf("error rendering `%s`: %w", MessageTemplateKey, err)
+	}
+
+	return buf.Bytes(), true, nil
+}
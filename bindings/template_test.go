@@ -0,0 +1,94 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bindings
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMessageTemplate(t *testing.T) {
+	t.Run("no template", func(t *testing.T) {
+		tmpl, err := ParseMessageTemplate(map[string]string{})
+		assert.NoError(t, err)
+		assert.Nil(t, tmpl)
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		_, err := ParseMessageTemplate(map[string]string{MessageTemplateKey: "Hello {{.name"})
+		assert.Error(t, err)
+	})
+}
+
+func TestRenderMessage(t *testing.T) {
+	componentTmpl, err := ParseMessageTemplate(map[string]string{MessageTemplateKey: "Hello {{.name}}, your order {{.order.id}} has shipped"})
+	require.NoError(t, err)
+
+	t.Run("no template returns data as-is", func(t *testing.T) {
+		data, rendered, err := RenderMessage(nil, &InvokeRequest{Data: []byte("hello world")})
+		assert.NoError(t, err)
+		assert.False(t, rendered)
+		assert.Equal(t, "hello world", string(data))
+	})
+
+	t.Run("component template", func(t *testing.T) {
+		data, rendered, err := RenderMessage(componentTmpl, &InvokeRequest{Data: []byte(`{"name":"Alice","order":{"id":42}}`)})
+		assert.NoError(t, err)
+		assert.True(t, rendered)
+		assert.Equal(t, "Hello Alice, your order 42 has shipped", string(data))
+	})
+
+	t.Run("request template takes priority", func(t *testing.T) {
+		data, rendered, err := RenderMessage(componentTmpl, &InvokeRequest{
+			Data:     []byte(`{"name":"Alice"}`),
+			Metadata: map[string]string{MessageTemplateKey: "Hi {{.name}}"},
+		})
+		assert.NoError(t, err)
+		assert.True(t, rendered)
+		assert.Equal(t, "Hi Alice", string(data))
+	})
+
+	t.Run("data is not JSON", func(t *testing.T) {
+		_, _, err := RenderMessage(componentTmpl, &InvokeRequest{Data: []byte("hello world")})
+		assert.Error(t, err)
+	})
+
+	t.Run("missing field", func(t *testing.T) {
+		_, _, err := RenderMessage(componentTmpl, &InvokeRequest{Data: []byte(`{"name":"Alice"}`)})
+		assert.Error(t, err)
+	})
+}
+
+func TestRenderHTMLMessage(t *testing.T) {
+	componentTmpl, err := ParseHTMLMessageTemplate(map[string]string{MessageTemplateKey: "<p>Hello {{.name}}</p>"})
+	require.NoError(t, err)
+
+	t.Run("values are escaped", func(t *testing.T) {
+		data, rendered, err := RenderHTMLMessage(componentTmpl, &InvokeRequest{Data: []byte(`{"name":"<script>alert(1)</script>"}`)})
+		assert.NoError(t, err)
+		assert.True(t, rendered)
+		assert.Equal(t, "<p>Hello &lt;script&gt;alert(1)&lt;/script&gt;</p>", string(data))
+	})
+
+	t.Run("request template is parsed as HTML", func(t *testing.T) {
+		data, _, err := RenderHTMLMessage(componentTmpl, &InvokeRequest{
+			Data:     []byte(`{"name":"Tom & Jerry"}`),
+			Metadata: map[string]string{MessageTemplateKey: "<b>{{.name}}</b>"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "<b>Tom &amp; Jerry</b>", string(data))
+	})
+}
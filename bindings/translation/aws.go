@@ -0,0 +1,100 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package translation
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/comprehend"
+	"github.com/aws/aws-sdk-go/service/comprehend/comprehendiface"
+	"github.com/aws/aws-sdk-go/service/translate"
+	"github.com/aws/aws-sdk-go/service/translate/translateiface"
+
+	awsAuth "github.com/dapr/components-contrib/internal/authentication/aws"
+)
+
+// Source language of AWS Translate detecting the language of the text.
+const awsAutoLanguage = "auto"
+
+// awsTranslate is the AWS Translate service, with AWS Comprehend detecting the languages.
+type awsTranslate struct {
+	translateClient  translateiface.TranslateAPI
+	comprehendClient comprehendiface.ComprehendAPI
+}
+
+func newAWSTranslate(m *translationMetadata) (*awsTranslate, error) {
+	sess, err := awsAuth.NewSession(awsAuth.Options{
+		AccessKey:    m.AccessKey,
+		SecretKey:    m.SecretKey,
+		SessionToken: m.SessionToken,
+		Region:       m.Region,
+		Endpoint:     m.Endpoint,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &awsTranslate{
+		translateClient:  translate.New(sess),
+		comprehendClient: comprehend.New(sess),
+	}, nil
+}
+
+func (a *awsTranslate) detect(ctx context.Context, text string) (*DetectResult, error) {
+	res, err := a.comprehendClient.DetectDominantLanguageWithContext(ctx, &comprehend.DetectDominantLanguageInput{
+		Text: aws.String(text),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The languages aren't sorted by score
+	var best *comprehend.DominantLanguage
+	for _, l := range res.Languages {
+		if best == nil || aws.Float64Value(l.Score) > aws.Float64Value(best.Score) {
+			best = l
+		}
+	}
+	if best == nil {
+		return nil, errors.New("no language detected")
+	}
+
+	return &DetectResult{
+		Language:   aws.StringValue(best.LanguageCode),
+		Confidence: aws.Float64Value(best.Score),
+	}, nil
+}
+
+func (a *awsTranslate) translate(ctx context.Context, text string, source string, target string) (*TranslateResult, error) {
+	if source == "" {
+		source = awsAutoLanguage
+	}
+
+	res, err := a.translateClient.TextWithContext(ctx, &translate.TextInput{
+		Text:               aws.String(text),
+		SourceLanguageCode: aws.String(source),
+		TargetLanguageCode: aws.String(target),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TranslateResult{
+		Text:           aws.StringValue(res.TranslatedText),
+		SourceLanguage: aws.StringValue(res.SourceLanguageCode),
+		TargetLanguage: aws.StringValue(res.TargetLanguageCode),
+	}, nil
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package translation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const azureTranslatorAPIVersion = "3.0"
+
+// azureTranslator is the Azure Translator service, authenticated with the key of the resource.
+type azureTranslator struct {
+	httpClient *http.Client
+	endpoint   string
+	apiKey     string
+	// Region of the resource, sent with the key unless the resource is global.
+	region string
+}
+
+type azureText struct {
+	Text string `json:"Text"`
+}
+
+type azureDetection struct {
+	Language string  `json:"language"`
+	Score    float64 `json:"score"`
+}
+
+type azureTranslateResponse []struct {
+	DetectedLanguage *azureDetection `json:"detectedLanguage"`
+	Translations     []struct {
+		Text string `json:"text"`
+		To   string `json:"to"`
+	} `json:"translations"`
+}
+
+func (a *azureTranslator) detect(ctx context.Context, text string) (*DetectResult, error) {
+	var res []azureDetection
+	if err := a.post(ctx, "/detect", url.Values{}, text, &res); err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, errors.New("no language detected")
+	}
+
+	return &DetectResult{Language: res[0].Language, Confidence: res[0].Score}, nil
+}
+
+func (a *azureTranslator) translate(ctx context.Context, text string, source string, target string) (*TranslateResult, error) {
+	q := url.Values{}
+	q.Set("to", target)
+	if source != "" {
+		q.Set("from", source)
+	}
+
+	var res azureTranslateResponse
+	if err := a.post(ctx, "/translate", q, text, &res); err != nil {
+		return nil, err
+	}
+	if len(res) == 0 || len(res[0].Translations) == 0 {
+		return nil, errors.New("no translation returned")
+	}
+
+	result := &TranslateResult{
+		Text:           res[0].Translations[0].Text,
+		SourceLanguage: source,
+		TargetLanguage: res[0].Translations[0].To,
+	}
+	if res[0].DetectedLanguage != nil {
+		result.SourceLanguage = res[0].DetectedLanguage.Language
+	}
+
+	return result, nil
+}
+
+// post sends the text to the operation at path and decodes the JSON response in v.
+func (a *azureTranslator) post(ctx context.Context, path string, q url.Values, text string, v interface{}) error {
+	q.Set("api-version", azureTranslatorAPIVersion)
+	body, err := json.Marshal([]azureText{{Text: text}})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint+path+"?"+q.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set("Ocp-Apim-Subscription-Key", a.apiKey)
+	if a.region != "" {
+		req.Header.Set("Ocp-Apim-Subscription-Region", a.region)
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	res, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(res)))
+	}
+
+	return json.Unmarshal(res, v)
+}
@@ -0,0 +1,223 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package translation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// detectOperation returns the language of the text of the request.
+	detectOperation bindings.OperationKind = "detect"
+	// translateOperation translates the text of the request in the target language.
+	translateOperation bindings.OperationKind = "translate"
+
+	providerAzure = "azure"
+	providerAWS   = "aws"
+
+	// Request metadata keys, overriding the values of the component metadata.
+	metadataKeySourceLanguage = "sourceLanguage"
+	metadataKeyTargetLanguage = "targetLanguage"
+
+	defaultAzureEndpoint = "https://api.cognitive.microsofttranslator.com"
+	defaultTimeout       = 30 * time.Second
+)
+
+// DetectResult is the response of the detect operation.
+type DetectResult struct {
+	// Language detected, as a BCP 47 language code.
+	Language string `json:"language"`
+	// Confidence of the detection, between 0 and 1.
+	Confidence float64 `json:"confidence"`
+}
+
+// TranslateResult is the response of the translate operation.
+type TranslateResult struct {
+	Text string `json:"text"`
+	// Language of the text of the request, as set or detected.
+	SourceLanguage string `json:"sourceLanguage"`
+	TargetLanguage string `json:"targetLanguage"`
+}
+
+// provider is a translation service.
+type provider interface {
+	detect(ctx context.Context, text string) (*DetectResult, error)
+	// translate translates the text, detecting its language if source is empty.
+	translate(ctx context.Context, text string, source string, target string) (*TranslateResult, error)
+}
+
+// Translation is an output binding detecting the language of texts and translating them with Azure Translator or AWS
+// Translate.
+type Translation struct {
+	metadata *translationMetadata
+	provider provider
+
+	logger logger.Logger
+}
+
+type translationMetadata struct {
+	// Translation service, azure or aws.
+	Provider string `mapstructure:"provider"`
+	// Endpoint of the service; by default the global Azure Translator endpoint, or the AWS endpoints of the region.
+	Endpoint string `mapstructure:"endpoint"`
+	// Key of the Azure Translator resource.
+	APIKey string `mapstructure:"apiKey" mdsensitive:"true"`
+	// Region of the Azure Translator resource, required if it isn't global, or AWS region.
+	Region string `mapstructure:"region"`
+	// AWS credentials; the default credential chain is used without keys.
+	AccessKey    string `mapstructure:"accessKey"`
+	SecretKey    string `mapstructure:"secretKey" mdsensitive:"true"`
+	SessionToken string `mapstructure:"sessionToken" mdsensitive:"true"`
+	// Default languages of the translations; the source language is detected if empty.
+	SourceLanguage string `mapstructure:"sourceLanguage"`
+	TargetLanguage string `mapstructure:"targetLanguage"`
+	TimeoutInSec   int    `mapstructure:"timeoutInSec"`
+}
+
+// NewTranslation returns a new translation output binding.
+func NewTranslation(logger logger.Logger) bindings.OutputBinding {
+	return &Translation{logger: logger}
+}
+
+// Init parses the metadata and creates the client of the provider.
+func (t *Translation) Init(md bindings.Metadata) error {
+	m, err := parseMetadata(md.Properties)
+	if err != nil {
+		return err
+	}
+
+	switch m.Provider {
+	case providerAzure:
+		t.provider = &azureTranslator{
+			httpClient: &http.Client{
+				Timeout: time.Duration(m.TimeoutInSec) * time.Second,
+			},
+			endpoint: m.Endpoint,
+			apiKey:   m.APIKey,
+			region:   m.Region,
+		}
+	case providerAWS:
+		t.provider, err = newAWSTranslate(m)
+		if err != nil {
+			return fmt.Errorf("translation binding error: error creating the AWS clients: %w", err)
+		}
+	}
+	t.metadata = m
+
+	return nil
+}
+
+func parseMetadata(md map[string]string) (*translationMetadata, error) {
+	m := translationMetadata{
+		Provider:     providerAzure,
+		TimeoutInSec: int(defaultTimeout / time.Second),
+	}
+	err := metadata.DecodeMetadata(md, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Provider = strings.ToLower(m.Provider)
+	switch m.Provider {
+	case providerAzure:
+		if m.APIKey == "" {
+			return nil, errors.New("translation binding error: apiKey is required with Azure Translator")
+		}
+		if m.Endpoint == "" {
+			m.Endpoint = defaultAzureEndpoint
+		}
+		m.Endpoint = strings.TrimSuffix(m.Endpoint, "/")
+	case providerAWS:
+		if m.Region == "" {
+			return nil, errors.New("translation binding error: region is required with AWS Translate")
+		}
+	default:
+		return nil, fmt.Errorf("translation binding error: invalid provider %s, supported values are %s and %s", m.Provider, providerAzure, providerAWS)
+	}
+
+	if m.TimeoutInSec < 1 {
+		return nil, fmt.Errorf("translation binding error: invalid timeoutInSec %d", m.TimeoutInSec)
+	}
+
+	return &m, nil
+}
+
+func (t *Translation) Operations() []bindings.OperationKind {
+	return []bindings.OperationKind{detectOperation, translateOperation}
+}
+
+// Invoke detects the language of the text of the request data, or translates it.
+func (t *Translation) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	text := string(req.Data)
+	if strings.TrimSpace(text) == "" {
+		return nil, errors.New("translation binding error: the text to process is required as data")
+	}
+
+	var (
+		res interface{}
+		err error
+	)
+	switch req.Operation {
+	case detectOperation:
+		res, err = t.provider.detect(ctx, text)
+	case translateOperation:
+		source, target := t.metadata.SourceLanguage, t.metadata.TargetLanguage
+		if val := req.Metadata[metadataKeySourceLanguage]; val != "" {
+			source = val
+		}
+		if val := req.Metadata[metadataKeyTargetLanguage]; val != "" {
+			target = val
+		}
+		if target == "" {
+			return nil, fmt.Errorf("translation binding error: %s is required", metadataKeyTargetLanguage)
+		}
+		res, err = t.provider.translate(ctx, text, source, target)
+	default:
+		return nil, fmt.Errorf("translation binding error: unsupported operation %s", req.Operation)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("translation binding error: %s failed: %w", req.Operation, err)
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bindings.InvokeResponse{
+		Data: data,
+		Metadata: map[string]string{
+			"provider": t.metadata.Provider,
+		},
+	}, nil
+}
+
+// GetComponentMetadataSchema returns the schema of the metadata of the translation binding.
+func (t *Translation) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(translationMetadata{
+		Provider:     providerAzure,
+		TimeoutInSec: int(defaultTimeout / time.Second),
+	})
+	return fields
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package translation
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/comprehend"
+	"github.com/aws/aws-sdk-go/service/comprehend/comprehendiface"
+	"github.com/aws/aws-sdk-go/service/translate"
+	"github.com/aws/aws-sdk-go/service/translate/translateiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+func TestParseMetadata(t *testing.T) {
+	// Azure Translator is the default provider, with its global endpoint
+	m, err := parseMetadata(map[string]string{"apiKey": "key"})
+	require.NoError(t, err)
+	assert.Equal(t, providerAzure, m.Provider)
+	assert.Equal(t, defaultAzureEndpoint, m.Endpoint)
+	assert.Equal(t, 30, m.TimeoutInSec)
+	_, err = parseMetadata(map[string]string{"region": "westeurope"})
+	assert.EqualError(t, err, "translation binding error: apiKey is required with Azure Translator")
+
+	m, err = parseMetadata(map[string]string{"provider": "AWS", "region": "us-east-1", "targetLanguage": "fr"})
+	require.NoError(t, err)
+	assert.Equal(t, providerAWS, m.Provider)
+	assert.Equal(t, "fr", m.TargetLanguage)
+	_, err = parseMetadata(map[string]string{"provider": "aws", "apiKey": "key"})
+	assert.EqualError(t, err, "translation binding error: region is required with AWS Translate")
+}
+
+func invoke(t *testing.T, tr *Translation, op bindings.OperationKind, text string, md map[string]string) (string, error) {
+	t.Helper()
+
+	res, err := tr.Invoke(context.Background(), &bindings.InvokeRequest{
+		Operation: op,
+		Data:      []byte(text),
+		Metadata:  md,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(res.Data), nil
+}
+
+func TestAzure(t *testing.T) {
+	var (
+		lastRequest *http.Request
+		lastBody    string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		lastRequest, lastBody = r, string(body)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/detect":
+			w.Write([]byte(`[{"language": "de", "score": 0.92, "isTranslationSupported": true}]`))
+		case r.URL.Path == "/translate" && r.URL.Query().Get("from") == "":
+			w.Write([]byte(`[{"detectedLanguage": {"language": "de", "score": 0.92}, "translations": [{"text": "Hello", "to": "en"}]}]`))
+		case r.URL.Path == "/translate":
+			w.Write([]byte(`[{"translations": [{"text": "Bonjour", "to": "fr"}]}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tr := NewTranslation(logger.NewLogger("test")).(*Translation)
+	require.NoError(t, tr.Init(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"endpoint":       server.URL,
+		"apiKey":         "secret",
+		"region":         "westeurope",
+		"targetLanguage": "en",
+	}}}))
+
+	assert.Equal(t, []bindings.OperationKind{detectOperation, translateOperation}, tr.Operations())
+
+	t.Run("detect", func(t *testing.T) {
+		res, err := invoke(t, tr, detectOperation, "Hallo", nil)
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"language": "de", "confidence": 0.92}`, res)
+		assert.Equal(t, "secret", lastRequest.Header.Get("Ocp-Apim-Subscription-Key"))
+		assert.Equal(t, "westeurope", lastRequest.Header.Get("Ocp-Apim-Subscription-Region"))
+		assert.Equal(t, "3.0", lastRequest.URL.Query().Get("api-version"))
+		assert.JSONEq(t, `[{"Text": "Hallo"}]`, lastBody)
+	})
+
+	t.Run("translate detecting the source language", func(t *testing.T) {
+		res, err := invoke(t, tr, translateOperation, "Hallo", nil)
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"text": "Hello", "sourceLanguage": "de", "targetLanguage": "en"}`, res)
+		assert.Equal(t, "en", lastRequest.URL.Query().Get("to"))
+	})
+
+	t.Run("translate with request languages", func(t *testing.T) {
+		res, err := invoke(t, tr, translateOperation, "Hello", map[string]string{"sourceLanguage": "en", "targetLanguage": "fr"})
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"text": "Bonjour", "sourceLanguage": "en", "targetLanguage": "fr"}`, res)
+		assert.Equal(t, "en", lastRequest.URL.Query().Get("from"))
+		assert.Equal(t, "fr", lastRequest.URL.Query().Get("to"))
+	})
+
+	t.Run("blank text is not sent", func(t *testing.T) {
+		lastRequest = nil
+
+		_, err := invoke(t, tr, translateOperation, " \n", nil)
+
+		assert.EqualError(t, err, "translation binding error: the text to process is required as data")
+		assert.Nil(t, lastRequest)
+	})
+}
+
+func TestTargetLanguageRequired(t *testing.T) {
+	tr := NewTranslation(logger.NewLogger("test")).(*Translation)
+	require.NoError(t, tr.Init(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{"apiKey": "key"}}}))
+
+	_, err := invoke(t, tr, translateOperation, "Hallo", nil)
+
+	assert.ErrorContains(t, err, "targetLanguage is required")
+}
+
+type fakeTranslate struct {
+	translateiface.TranslateAPI
+
+	input *translate.TextInput
+}
+
+func (f *fakeTranslate) TextWithContext(ctx aws.Context, input *translate.TextInput, opts ...request.Option) (*translate.TextOutput, error) {
+	f.input = input
+
+	return &translate.TextOutput{
+		TranslatedText:     aws.String("Hello"),
+		SourceLanguageCode: aws.String("de"),
+		TargetLanguageCode: input.TargetLanguageCode,
+	}, nil
+}
+
+type fakeComprehend struct {
+	comprehendiface.ComprehendAPI
+}
+
+func (f *fakeComprehend) DetectDominantLanguageWithContext(ctx aws.Context, input *comprehend.DetectDominantLanguageInput, opts ...request.Option) (*comprehend.DetectDominantLanguageOutput, error) {
+	return &comprehend.DetectDominantLanguageOutput{Languages: []*comprehend.DominantLanguage{
+		{LanguageCode: aws.String("nl"), Score: aws.Float64(0.2)},
+		{LanguageCode: aws.String("de"), Score: aws.Float64(0.8)},
+	}}, nil
+}
+
+func TestAWS(t *testing.T) {
+	tr := NewTranslation(logger.NewLogger("test")).(*Translation)
+	require.NoError(t, tr.Init(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"provider":       "aws",
+		"region":         "us-east-1",
+		"targetLanguage": "en",
+	}}}))
+	fake := &fakeTranslate{}
+	p := tr.provider.(*awsTranslate)
+	p.translateClient = fake
+	p.comprehendClient = &fakeComprehend{}
+
+	t.Run("detect", func(t *testing.T) {
+		res, err := invoke(t, tr, detectOperation, "Hallo", nil)
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"language": "de", "confidence": 0.8}`, res)
+	})
+
+	t.Run("translate detecting the source language", func(t *testing.T) {
+		res, err := invoke(t, tr, translateOperation, "Hallo", nil)
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"text": "Hello", "sourceLanguage": "de", "targetLanguage": "en"}`, res)
+		assert.Equal(t, "auto", aws.StringValue(fake.input.SourceLanguageCode))
+		assert.Equal(t, "Hallo", aws.StringValue(fake.input.Text))
+	})
+}
@@ -31,6 +31,7 @@ import (
 // SendGrid allows sending of emails using the 3rd party SendGrid service.
 type SendGrid struct {
 	metadata sendGridMetadata
+	template bindings.MessageTemplate
 	logger   logger.Logger
 }
 
@@ -94,6 +95,11 @@ func (sg *SendGrid) Init(metadata bindings.Metadata) error {
 	// Um, yeah that's about it!
 	sg.metadata = meta
 
+	sg.template, err = bindings.ParseHTMLMessageTemplate(metadata.Properties)
+	if err != nil {
+		return fmt.Errorf("SendGrid binding error: %w", err)
+	}
+
 	return nil
 }
 
@@ -184,11 +190,17 @@ func (sg *SendGrid) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*b
 		bccAddress = mail.NewEmail("", req.Metadata["emailBcc"])
 	}
 
-	// Email body is held in req.Data, after we tidy it up a bit
-	emailBody, err := strconv.Unquote(string(req.Data))
+	// Email body is held in req.Data, optionally rendered through the message template
+	data, rendered, err := bindings.RenderHTMLMessage(sg.template, req)
 	if err != nil {
+		return nil, fmt.Errorf("SendGrid binding error: %w", err)
+	}
+
+	// Tidy up the body a bit
+	emailBody, err := strconv.Unquote(string(data))
+	if rendered || err != nil {
 		// Unquote will error if the string is not quoted (not exactly graceful!), so fallback using the string as is
-		emailBody = string(req.Data)
+		emailBody = string(data)
 	}
 
 	// Construct email message
@@ -37,6 +37,7 @@ const (
 
 type SMS struct {
 	metadata   twilioMetadata
+	template   bindings.MessageTemplate
 	logger     logger.Logger
 	httpClient *http.Client
 }
@@ -85,7 +86,13 @@ func (t *SMS) Init(metadata bindings.Metadata) error {
 		twilioM.timeout = t
 	}
 
+	tmpl, err := bindings.ParseMessageTemplate(metadata.Properties)
+	if err != nil {
+		return err
+	}
+
 	t.metadata = twilioM
+	t.template = tmpl
 	t.httpClient.Timeout = twilioM.timeout
 
 	return nil
@@ -105,10 +112,15 @@ func (t *SMS) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*binding
 		toNumberValue = toNumberFromRequest
 	}
 
+	body, _, err := bindings.RenderMessage(t.template, req)
+	if err != nil {
+		return nil, err
+	}
+
 	v := url.Values{}
 	v.Set("To", toNumberValue)
 	v.Set("From", t.metadata.fromNumber)
-	v.Set("Body", string(req.Data))
+	v.Set("Body", string(body))
 	vDr := *strings.NewReader(v.Encode())
 
 	twilioURL := fmt.Sprintf("%s%s/Messages.json", twilioURLBase, t.metadata.accountSid)
@@ -103,6 +103,34 @@ func TestWriteShouldSucceed(t *testing.T) {
 	})
 }
 
+func TestWriteWithMessageTemplate(t *testing.T) {
+	httpTransport := &mockTransport{
+		response: &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))},
+	}
+	m := bindings.Metadata{}
+	m.Properties = map[string]string{
+		"toNumber": "toNumber", "fromNumber": "fromNumber",
+		"accountSid": "accountSid", "authToken": "authToken",
+		"messageTemplate": "Your code is {{.code}}",
+	}
+	tw := NewSMS(logger.NewLogger("test")).(*SMS)
+	tw.httpClient = &http.Client{
+		Transport: httpTransport,
+	}
+	err := tw.Init(m)
+	assert.Nil(t, err)
+
+	_, err = tw.Invoke(context.Background(), &bindings.InvokeRequest{
+		Data: []byte(`{"code":"1234"}`),
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), httpTransport.requestCount)
+	err = httpTransport.request.ParseForm()
+	assert.Nil(t, err)
+	assert.Equal(t, "Your code is 1234", httpTransport.request.PostForm.Get("Body"))
+}
+
 func TestWriteShouldFail(t *testing.T) {
 	httpTransport := &mockTransport{
 		response: &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))},
@@ -0,0 +1,219 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vision
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const azureVisionPath = "/vision/v3.2"
+
+// azureVision is the Azure Computer Vision service, authenticated with a subscription key.
+type azureVision struct {
+	httpClient *http.Client
+	endpoint   string
+	apiKey     string
+}
+
+type azureRectangle struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+type azureAnalyzeResponse struct {
+	Tags    []Tag `json:"tags"`
+	Objects []struct {
+		Rectangle  azureRectangle `json:"rectangle"`
+		Object     string         `json:"object"`
+		Confidence float64        `json:"confidence"`
+	} `json:"objects"`
+	Metadata struct {
+		Width  int `json:"width"`
+		Height int `json:"height"`
+	} `json:"metadata"`
+}
+
+type azureOCRResponse struct {
+	Language string `json:"language"`
+	Regions  []struct {
+		Lines []struct {
+			Words []struct {
+				Text string `json:"text"`
+			} `json:"words"`
+		} `json:"lines"`
+	} `json:"regions"`
+}
+
+type azureDescribeResponse struct {
+	Description struct {
+		Tags     []string  `json:"tags"`
+		Captions []Caption `json:"captions"`
+	} `json:"description"`
+}
+
+func (a *azureVision) analyze(ctx context.Context, img image, opts queryOptions) (*AnalyzeResult, error) {
+	q := a.query(opts)
+	q.Set("visualFeatures", "Tags,Objects")
+
+	var res azureAnalyzeResponse
+	if err := a.post(ctx, "/analyze", q, img, &res); err != nil {
+		return nil, err
+	}
+
+	result := &AnalyzeResult{
+		Tags:    res.Tags,
+		Objects: make([]Object, 0, len(res.Objects)),
+	}
+	if result.Tags == nil {
+		result.Tags = []Tag{}
+	}
+	if len(result.Tags) > opts.maxResults {
+		result.Tags = result.Tags[:opts.maxResults]
+	}
+	for _, o := range res.Objects {
+		if len(result.Objects) == opts.maxResults {
+			break
+		}
+		object := Object{Name: o.Object, Confidence: o.Confidence}
+		// The rectangles are in pixels
+		if res.Metadata.Width > 0 && res.Metadata.Height > 0 {
+			w, h := float64(res.Metadata.Width), float64(res.Metadata.Height)
+			object.Box = BoundingBox{
+				Left:   float64(o.Rectangle.X) / w,
+				Top:    float64(o.Rectangle.Y) / h,
+				Width:  float64(o.Rectangle.W) / w,
+				Height: float64(o.Rectangle.H) / h,
+			}
+		}
+		result.Objects = append(result.Objects, object)
+	}
+
+	return result, nil
+}
+
+func (a *azureVision) ocr(ctx context.Context, img image, opts queryOptions) (*OCRResult, error) {
+	q := url.Values{}
+	if opts.language != "" {
+		q.Set("language", opts.language)
+	} else {
+		q.Set("language", "unk")
+	}
+	q.Set("detectOrientation", "true")
+
+	var res azureOCRResponse
+	if err := a.post(ctx, "/ocr", q, img, &res); err != nil {
+		return nil, err
+	}
+
+	result := &OCRResult{
+		Language: res.Language,
+		Lines:    []TextLine{},
+	}
+	texts := []string{}
+	for _, r := range res.Regions {
+		for _, l := range r.Lines {
+			words := make([]string, len(l.Words))
+			for i, w := range l.Words {
+				words[i] = w.Text
+			}
+			text := strings.Join(words, " ")
+			result.Lines = append(result.Lines, TextLine{Text: text})
+			texts = append(texts, text)
+		}
+	}
+	result.Text = strings.Join(texts, "\n")
+
+	return result, nil
+}
+
+func (a *azureVision) describe(ctx context.Context, img image, opts queryOptions) (*DescribeResult, error) {
+	q := a.query(opts)
+	q.Set("maxCandidates", strconv.Itoa(opts.maxResults))
+
+	var res azureDescribeResponse
+	if err := a.post(ctx, "/describe", q, img, &res); err != nil {
+		return nil, err
+	}
+
+	result := &DescribeResult{
+		Captions: res.Description.Captions,
+		Tags:     res.Description.Tags,
+	}
+	if result.Captions == nil {
+		result.Captions = []Caption{}
+	}
+	if result.Tags == nil {
+		result.Tags = []string{}
+	}
+
+	return result, nil
+}
+
+func (a *azureVision) query(opts queryOptions) url.Values {
+	q := url.Values{}
+	if opts.language != "" {
+		q.Set("language", opts.language)
+	}
+
+	return q
+}
+
+// post sends the image to the operation at path, as JSON with its URL or as bytes, and decodes the JSON response in v.
+func (a *azureVision) post(ctx context.Context, path string, q url.Values, img image, v interface{}) error {
+	var (
+		body        []byte
+		contentType string
+	)
+	if img.url != "" {
+		body, _ = json.Marshal(map[string]string{"url": img.url})
+		contentType = "application/json"
+	} else {
+		body = img.data
+		contentType = "application/octet-stream"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint+azureVisionPath+path+"?"+q.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Ocp-Apim-Subscription-Key", a.apiKey)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	res, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(res)))
+	}
+
+	return json.Unmarshal(res, v)
+}
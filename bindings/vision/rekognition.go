@@ -0,0 +1,154 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vision
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rekognition"
+	"github.com/aws/aws-sdk-go/service/rekognition/rekognitioniface"
+
+	awsAuth "github.com/dapr/components-contrib/internal/authentication/aws"
+)
+
+// rekognitionVision is the AWS Rekognition service, which doesn't describe images. It reads the images from S3
+// instead of downloading them.
+type rekognitionVision struct {
+	client rekognitioniface.RekognitionAPI
+}
+
+func newRekognition(m *visionMetadata) (*rekognitionVision, error) {
+	sess, err := awsAuth.NewSession(awsAuth.Options{
+		AccessKey:    m.AccessKey,
+		SecretKey:    m.SecretKey,
+		SessionToken: m.SessionToken,
+		Region:       m.Region,
+		Endpoint:     m.Endpoint,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &rekognitionVision{
+		client: rekognition.New(sess),
+	}, nil
+}
+
+func (r *rekognitionVision) analyze(ctx context.Context, img image, opts queryOptions) (*AnalyzeResult, error) {
+	input, err := r.image(img)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := r.client.DetectLabelsWithContext(ctx, &rekognition.DetectLabelsInput{
+		Image:     input,
+		MaxLabels: aws.Int64(int64(opts.maxResults)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AnalyzeResult{
+		Tags:    make([]Tag, 0, len(res.Labels)),
+		Objects: []Object{},
+	}
+	for _, l := range res.Labels {
+		name := aws.StringValue(l.Name)
+		// The confidences are percentages
+		result.Tags = append(result.Tags, Tag{Name: name, Confidence: aws.Float64Value(l.Confidence) / 100})
+		for _, i := range l.Instances {
+			if len(result.Objects) == opts.maxResults {
+				break
+			}
+			result.Objects = append(result.Objects, Object{
+				Name:       name,
+				Confidence: aws.Float64Value(i.Confidence) / 100,
+				Box:        boundingBox(i.BoundingBox),
+			})
+		}
+	}
+
+	return result, nil
+}
+
+func (r *rekognitionVision) ocr(ctx context.Context, img image, opts queryOptions) (*OCRResult, error) {
+	input, err := r.image(img)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := r.client.DetectTextWithContext(ctx, &rekognition.DetectTextInput{Image: input})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &OCRResult{Lines: []TextLine{}}
+	texts := []string{}
+	for _, d := range res.TextDetections {
+		// The words are detected in addition to the lines they belong to
+		if aws.StringValue(d.Type) != rekognition.TextTypesLine {
+			continue
+		}
+		line := TextLine{
+			Text:       aws.StringValue(d.DetectedText),
+			Confidence: aws.Float64Value(d.Confidence) / 100,
+		}
+		if d.Geometry != nil && d.Geometry.BoundingBox != nil {
+			box := boundingBox(d.Geometry.BoundingBox)
+			line.Box = &box
+		}
+		result.Lines = append(result.Lines, line)
+		texts = append(texts, line.Text)
+	}
+	result.Text = strings.Join(texts, "\n")
+
+	return result, nil
+}
+
+func (r *rekognitionVision) describe(ctx context.Context, img image, opts queryOptions) (*DescribeResult, error) {
+	return nil, errUnsupported
+}
+
+// image returns the image to send to Rekognition, as bytes or as the S3 object of its s3://bucket/key URI.
+func (r *rekognitionVision) image(img image) (*rekognition.Image, error) {
+	if img.url == "" {
+		return &rekognition.Image{Bytes: img.data}, nil
+	}
+
+	bucket, key, ok := strings.Cut(strings.TrimPrefix(img.url, "s3://"), "/")
+	if !ok || bucket == "" || key == "" {
+		return nil, fmt.Errorf("invalid S3 URI %s, expected s3://bucket/key", img.url)
+	}
+
+	return &rekognition.Image{S3Object: &rekognition.S3Object{
+		Bucket: aws.String(bucket),
+		Name:   aws.String(key),
+	}}, nil
+}
+
+func boundingBox(b *rekognition.BoundingBox) BoundingBox {
+	if b == nil {
+		return BoundingBox{}
+	}
+
+	return BoundingBox{
+		Left:   aws.Float64Value(b.Left),
+		Top:    aws.Float64Value(b.Top),
+		Width:  aws.Float64Value(b.Width),
+		Height: aws.Float64Value(b.Height),
+	}
+}
@@ -0,0 +1,329 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vision
+
+import (
+	"context"
+	b64 "encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// analyzeOperation returns the tags and the objects detected in the image.
+	analyzeOperation bindings.OperationKind = "analyze"
+	// ocrOperation returns the text read in the image.
+	ocrOperation bindings.OperationKind = "ocr"
+	// describeOperation returns captions describing the image.
+	describeOperation bindings.OperationKind = "describe"
+
+	providerAzure = "azure"
+	providerAWS   = "aws"
+
+	// Request metadata keys.
+	metadataKeyImageURL     = "imageUrl"
+	metadataKeyDecodeBase64 = "decodeBase64"
+	metadataKeyLanguage     = "language"
+	metadataKeyMaxResults   = "maxResults"
+
+	defaultMaxResults = 10
+	defaultTimeout    = 30 * time.Second
+	// Largest image accepted as bytes by Rekognition, which is also enough for Computer Vision.
+	maxImageSize = 5 << 20
+)
+
+// BoundingBox is the box around something detected in an image, as ratios of the width and height of the image.
+type BoundingBox struct {
+	Left   float64 `json:"left"`
+	Top    float64 `json:"top"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// Tag is a label of the content of an image, with its confidence between 0 and 1.
+type Tag struct {
+	Name       string  `json:"name"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Object is an object detected in an image.
+type Object struct {
+	Name       string      `json:"name"`
+	Confidence float64     `json:"confidence"`
+	Box        BoundingBox `json:"box"`
+}
+
+// AnalyzeResult is the response of the analyze operation.
+type AnalyzeResult struct {
+	Tags    []Tag    `json:"tags"`
+	Objects []Object `json:"objects"`
+}
+
+// TextLine is a line of text read in an image. The providers don't all return the confidence and the box.
+type TextLine struct {
+	Text       string       `json:"text"`
+	Confidence float64      `json:"confidence,omitempty"`
+	Box        *BoundingBox `json:"box,omitempty"`
+}
+
+// OCRResult is the response of the ocr operation.
+type OCRResult struct {
+	// Language detected, if the provider detects it.
+	Language string     `json:"language,omitempty"`
+	Text     string     `json:"text"`
+	Lines    []TextLine `json:"lines"`
+}
+
+// Caption is a sentence describing an image.
+type Caption struct {
+	Text       string  `json:"text"`
+	Confidence float64 `json:"confidence"`
+}
+
+// DescribeResult is the response of the describe operation.
+type DescribeResult struct {
+	Captions []Caption `json:"captions"`
+	Tags     []string  `json:"tags"`
+}
+
+// image is the image of a request, sent as bytes or as the URL of the image for the provider.
+type image struct {
+	data []byte
+	url  string
+}
+
+type queryOptions struct {
+	language   string
+	maxResults int
+}
+
+// provider is an image analysis service.
+type provider interface {
+	analyze(ctx context.Context, img image, opts queryOptions) (*AnalyzeResult, error)
+	ocr(ctx context.Context, img image, opts queryOptions) (*OCRResult, error)
+	// describe returns the captions of the image, or errUnsupported.
+	describe(ctx context.Context, img image, opts queryOptions) (*DescribeResult, error)
+}
+
+var errUnsupported = errors.New("operation not supported by the provider")
+
+// Vision is an output binding analyzing images with Azure Computer Vision or AWS Rekognition.
+type Vision struct {
+	metadata   *visionMetadata
+	provider   provider
+	httpClient *http.Client
+
+	logger logger.Logger
+}
+
+type visionMetadata struct {
+	// Image analysis service, azure or aws.
+	Provider string `mapstructure:"provider"`
+	// Endpoint of the Computer Vision resource, or custom endpoint of Rekognition.
+	Endpoint string `mapstructure:"endpoint"`
+	// Subscription key of the Computer Vision resource.
+	APIKey string `mapstructure:"apiKey" mdsensitive:"true"`
+	// AWS region and credentials; the default credential chain is used without keys.
+	Region       string `mapstructure:"region"`
+	AccessKey    string `mapstructure:"accessKey"`
+	SecretKey    string `mapstructure:"secretKey" mdsensitive:"true"`
+	SessionToken string `mapstructure:"sessionToken" mdsensitive:"true"`
+	// Language of the tags and captions, and of the text read by Computer Vision.
+	Language     string `mapstructure:"language"`
+	MaxResults   int    `mapstructure:"maxResults"`
+	TimeoutInSec int    `mapstructure:"timeoutInSec"`
+}
+
+// NewVision returns a new vision output binding.
+func NewVision(logger logger.Logger) bindings.OutputBinding {
+	return &Vision{logger: logger}
+}
+
+// Init parses the metadata and creates the client of the provider.
+func (v *Vision) Init(md bindings.Metadata) error {
+	m, err := parseMetadata(md.Properties)
+	if err != nil {
+		return err
+	}
+
+	v.httpClient = &http.Client{
+		Timeout: time.Duration(m.TimeoutInSec) * time.Second,
+	}
+	switch m.Provider {
+	case providerAzure:
+		v.provider = &azureVision{httpClient: v.httpClient, endpoint: m.Endpoint, apiKey: m.APIKey}
+	case providerAWS:
+		v.provider, err = newRekognition(m)
+		if err != nil {
+			return fmt.Errorf("vision binding error: error creating the Rekognition client: %w", err)
+		}
+	}
+	v.metadata = m
+
+	return nil
+}
+
+func parseMetadata(md map[string]string) (*visionMetadata, error) {
+	m := visionMetadata{
+		Provider:     providerAzure,
+		MaxResults:   defaultMaxResults,
+		TimeoutInSec: int(defaultTimeout / time.Second),
+	}
+	err := metadata.DecodeMetadata(md, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Provider = strings.ToLower(m.Provider)
+	switch m.Provider {
+	case providerAzure:
+		if m.Endpoint == "" || m.APIKey == "" {
+			return nil, errors.New("vision binding error: endpoint and apiKey are required with Azure Computer Vision")
+		}
+		m.Endpoint = strings.TrimSuffix(m.Endpoint, "/")
+	case providerAWS:
+		if m.Region == "" {
+			return nil, errors.New("vision binding error: region is required with AWS Rekognition")
+		}
+	default:
+		return nil, fmt.Errorf("vision binding error: invalid provider %s, supported values are %s and %s", m.Provider, providerAzure, providerAWS)
+	}
+
+	if m.MaxResults < 1 {
+		return nil, fmt.Errorf("vision binding error: invalid maxResults %d", m.MaxResults)
+	}
+	if m.TimeoutInSec < 1 {
+		return nil, fmt.Errorf("vision binding error: invalid timeoutInSec %d", m.TimeoutInSec)
+	}
+
+	return &m, nil
+}
+
+func (v *Vision) Operations() []bindings.OperationKind {
+	if v.metadata != nil && v.metadata.Provider == providerAWS {
+		return []bindings.OperationKind{analyzeOperation, ocrOperation}
+	}
+
+	return []bindings.OperationKind{analyzeOperation, ocrOperation, describeOperation}
+}
+
+func (v *Vision) Invoke(ctx context.Context, req *bindings.InvokeRequest) (*bindings.InvokeResponse, error) {
+	opts := queryOptions{
+		language:   v.metadata.Language,
+		maxResults: v.metadata.MaxResults,
+	}
+	if val := req.Metadata[metadataKeyLanguage]; val != "" {
+		opts.language = val
+	}
+	if val := req.Metadata[metadataKeyMaxResults]; val != "" {
+		maxResults, err := strconv.Atoi(val)
+		if err != nil || maxResults < 1 {
+			return nil, fmt.Errorf("vision binding error: invalid %s %s", metadataKeyMaxResults, val)
+		}
+		opts.maxResults = maxResults
+	}
+
+	img, err := v.image(req)
+	if err != nil {
+		return nil, fmt.Errorf("vision binding error: %w", err)
+	}
+
+	var res interface{}
+	switch req.Operation {
+	case analyzeOperation:
+		res, err = v.provider.analyze(ctx, img, opts)
+	case ocrOperation:
+		res, err = v.provider.ocr(ctx, img, opts)
+	case describeOperation:
+		res, err = v.provider.describe(ctx, img, opts)
+	default:
+		return nil, fmt.Errorf("vision binding error: unsupported operation %s", req.Operation)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("vision binding error: %s failed: %w", req.Operation, err)
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bindings.InvokeResponse{
+		Data: data,
+		Metadata: map[string]string{
+			"provider": v.metadata.Provider,
+		},
+	}, nil
+}
+
+// image returns the image of the request: the URL of the imageUrl metadata, or else the data of the request,
+// optionally base64-encoded.
+// The images are never downloaded by the binding: Computer Vision downloads the http(s) URLs, and Rekognition reads
+// the s3:// URIs of objects.
+func (v *Vision) image(req *bindings.InvokeRequest) (image, error) {
+	if u := req.Metadata[metadataKeyImageURL]; u != "" {
+		scheme := "s3://"
+		if v.metadata.Provider == providerAzure {
+			scheme = "https://"
+			if strings.HasPrefix(u, "http://") {
+				scheme = "http://"
+			}
+		}
+		if !strings.HasPrefix(u, scheme) {
+			return image{}, fmt.Errorf("invalid %s %s, the %s provider only supports %s URLs", metadataKeyImageURL, u, v.metadata.Provider, strings.TrimSuffix(scheme, "://"))
+		}
+		return image{url: u}, nil
+	}
+
+	data := req.Data
+	if val := req.Metadata[metadataKeyDecodeBase64]; val != "" {
+		decode, err := strconv.ParseBool(val)
+		if err != nil {
+			return image{}, fmt.Errorf("invalid %s %s", metadataKeyDecodeBase64, val)
+		}
+		if decode {
+			data, err = b64.StdEncoding.DecodeString(string(req.Data))
+			if err != nil {
+				return image{}, fmt.Errorf("invalid base64 image: %w", err)
+			}
+		}
+	}
+	if len(data) == 0 {
+		return image{}, fmt.Errorf("the image is required, as data or with the %s metadata", metadataKeyImageURL)
+	}
+	if len(data) > maxImageSize {
+		return image{}, fmt.Errorf("the image exceeds %d bytes", maxImageSize)
+	}
+
+	return image{data: data}, nil
+}
+
+// GetComponentMetadataSchema returns the schema of the metadata of the vision binding.
+func (v *Vision) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(visionMetadata{
+		Provider:     providerAzure,
+		MaxResults:   defaultMaxResults,
+		TimeoutInSec: int(defaultTimeout / time.Second),
+	})
+	return fields
+}
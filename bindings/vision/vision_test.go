@@ -0,0 +1,264 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vision
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/rekognition"
+	"github.com/aws/aws-sdk-go/service/rekognition/rekognitioniface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/bindings"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+func TestParseMetadata(t *testing.T) {
+	m, err := parseMetadata(map[string]string{"endpoint": "https://vision.cognitiveservices.azure.com/", "apiKey": "key"})
+	require.NoError(t, err)
+	assert.Equal(t, providerAzure, m.Provider)
+	assert.Equal(t, "https://vision.cognitiveservices.azure.com", m.Endpoint)
+	assert.Equal(t, 10, m.MaxResults)
+	assert.Equal(t, 30, m.TimeoutInSec)
+
+	// Rekognition needs a region instead of an endpoint, and uses the AWS credentials
+	m, err = parseMetadata(map[string]string{"provider": "AWS", "region": "us-east-1", "maxResults": "3"})
+	require.NoError(t, err)
+	assert.Equal(t, providerAWS, m.Provider)
+	assert.Equal(t, 3, m.MaxResults)
+	_, err = parseMetadata(map[string]string{"provider": "aws", "endpoint": "https://vision.cognitiveservices.azure.com"})
+	assert.EqualError(t, err, "vision binding error: region is required with AWS Rekognition")
+
+	_, err = parseMetadata(map[string]string{"endpoint": "https://vision.cognitiveservices.azure.com"})
+	assert.EqualError(t, err, "vision binding error: endpoint and apiKey are required with Azure Computer Vision")
+	_, err = parseMetadata(map[string]string{"provider": "gcp"})
+	assert.EqualError(t, err, "vision binding error: invalid provider gcp, supported values are azure and aws")
+}
+
+func TestImage(t *testing.T) {
+	cv := &Vision{metadata: &visionMetadata{Provider: providerAzure}}
+	rk := &Vision{metadata: &visionMetadata{Provider: providerAWS}}
+
+	// Computer Vision downloads the http(s) URLs itself, Rekognition only reads S3 objects
+	img, err := cv.image(&bindings.InvokeRequest{Metadata: map[string]string{"imageUrl": "http://example.com/dog.jpg"}})
+	require.NoError(t, err)
+	assert.Equal(t, image{url: "http://example.com/dog.jpg"}, img)
+	_, err = cv.image(&bindings.InvokeRequest{Metadata: map[string]string{"imageUrl": "file:///etc/passwd"}})
+	assert.EqualError(t, err, "invalid imageUrl file:///etc/passwd, the azure provider only supports https URLs")
+	_, err = rk.image(&bindings.InvokeRequest{Metadata: map[string]string{"imageUrl": "https://example.com/dog.jpg"}})
+	assert.EqualError(t, err, "invalid imageUrl https://example.com/dog.jpg, the aws provider only supports s3 URLs")
+
+	// The URL takes priority over the data
+	img, err = rk.image(&bindings.InvokeRequest{Data: []byte("image"), Metadata: map[string]string{"imageUrl": "s3://photos/dog.jpg"}})
+	require.NoError(t, err)
+	assert.Equal(t, image{url: "s3://photos/dog.jpg"}, img)
+
+	img, err = rk.image(&bindings.InvokeRequest{Data: []byte("aW1hZ2U="), Metadata: map[string]string{"decodeBase64": "true"}})
+	require.NoError(t, err)
+	assert.Equal(t, image{data: []byte("image")}, img)
+	_, err = rk.image(&bindings.InvokeRequest{Data: []byte("not base64!"), Metadata: map[string]string{"decodeBase64": "true"}})
+	assert.ErrorContains(t, err, "invalid base64 image")
+
+	_, err = rk.image(&bindings.InvokeRequest{})
+	assert.EqualError(t, err, "the image is required, as data or with the imageUrl metadata")
+	_, err = rk.image(&bindings.InvokeRequest{Data: make([]byte, maxImageSize+1)})
+	assert.EqualError(t, err, "the image exceeds 5242880 bytes")
+}
+
+func invoke(t *testing.T, v *Vision, op bindings.OperationKind, data string, md map[string]string) (string, error) {
+	t.Helper()
+
+	res, err := v.Invoke(context.Background(), &bindings.InvokeRequest{
+		Operation: op,
+		Data:      []byte(data),
+		Metadata:  md,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(res.Data), nil
+}
+
+func TestAzure(t *testing.T) {
+	var (
+		lastRequest *http.Request
+		lastBody    string
+	)
+	responses := map[string]string{
+		"/vision/v3.2/analyze": `{
+			"tags": [{"name": "dog", "confidence": 0.99}, {"name": "grass", "confidence": 0.9}],
+			"objects": [{"rectangle": {"x": 10, "y": 20, "w": 50, "h": 40}, "object": "dog", "confidence": 0.8}],
+			"metadata": {"width": 100, "height": 200, "format": "Jpeg"}
+		}`,
+		"/vision/v3.2/ocr": `{"language": "en", "regions": [{"lines": [
+			{"words": [{"text": "Hello"}, {"text": "world"}]},
+			{"words": [{"text": "Dapr"}]}
+		]}]}`,
+		"/vision/v3.2/describe": `{"description": {"tags": ["dog", "outdoor"], "captions": [{"text": "a dog on the grass", "confidence": 0.7}]}}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		lastRequest, lastBody = r, string(body)
+		res, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"code": "NotFound"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(res))
+	}))
+	defer server.Close()
+
+	v := NewVision(logger.NewLogger("test")).(*Vision)
+	require.NoError(t, v.Init(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"endpoint": server.URL,
+		"apiKey":   "secret",
+		"language": "en",
+	}}}))
+
+	assert.Equal(t, []bindings.OperationKind{analyzeOperation, ocrOperation, describeOperation}, v.Operations())
+
+	t.Run("analyze image bytes", func(t *testing.T) {
+		res, err := invoke(t, v, analyzeOperation, "image", map[string]string{"maxResults": "1"})
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"tags": [{"name": "dog", "confidence": 0.99}],
+			"objects": [{"name": "dog", "confidence": 0.8, "box": {"left": 0.1, "top": 0.1, "width": 0.5, "height": 0.2}}]
+		}`, res)
+		assert.Equal(t, "secret", lastRequest.Header.Get("Ocp-Apim-Subscription-Key"))
+		assert.Equal(t, "application/octet-stream", lastRequest.Header.Get("Content-Type"))
+		assert.Equal(t, "Tags,Objects", lastRequest.URL.Query().Get("visualFeatures"))
+		assert.Equal(t, "en", lastRequest.URL.Query().Get("language"))
+		assert.Equal(t, "image", lastBody)
+	})
+
+	t.Run("analyze base64 image", func(t *testing.T) {
+		_, err := invoke(t, v, analyzeOperation, "aW1hZ2U=", map[string]string{"decodeBase64": "true"})
+
+		require.NoError(t, err)
+		assert.Equal(t, "image", lastBody)
+	})
+
+	t.Run("ocr image URL", func(t *testing.T) {
+		res, err := invoke(t, v, ocrOperation, "", map[string]string{"imageUrl": "https://example.com/sign.png"})
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"language": "en", "text": "Hello world\nDapr", "lines": [{"text": "Hello world"}, {"text": "Dapr"}]}`, res)
+		assert.Equal(t, "application/json", lastRequest.Header.Get("Content-Type"))
+		assert.JSONEq(t, `{"url": "https://example.com/sign.png"}`, lastBody)
+	})
+
+	t.Run("describe", func(t *testing.T) {
+		res, err := invoke(t, v, describeOperation, "image", nil)
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"captions": [{"text": "a dog on the grass", "confidence": 0.7}], "tags": ["dog", "outdoor"]}`, res)
+		assert.Equal(t, "10", lastRequest.URL.Query().Get("maxCandidates"))
+	})
+
+	t.Run("maxResults of the request", func(t *testing.T) {
+		_, err := invoke(t, v, analyzeOperation, "image", map[string]string{"maxResults": "none"})
+
+		assert.EqualError(t, err, "vision binding error: invalid maxResults none")
+	})
+}
+
+type fakeRekognition struct {
+	rekognitioniface.RekognitionAPI
+
+	images []*rekognition.Image
+}
+
+func (f *fakeRekognition) DetectLabelsWithContext(ctx aws.Context, input *rekognition.DetectLabelsInput, opts ...request.Option) (*rekognition.DetectLabelsOutput, error) {
+	f.images = append(f.images, input.Image)
+
+	return &rekognition.DetectLabelsOutput{Labels: []*rekognition.Label{
+		{
+			Name:       aws.String("Dog"),
+			Confidence: aws.Float64(99),
+			Instances: []*rekognition.Instance{{
+				Confidence:  aws.Float64(80),
+				BoundingBox: &rekognition.BoundingBox{Left: aws.Float64(0.1), Top: aws.Float64(0.2), Width: aws.Float64(0.3), Height: aws.Float64(0.4)},
+			}},
+		},
+		{Name: aws.String("Grass"), Confidence: aws.Float64(90)},
+	}}, nil
+}
+
+func (f *fakeRekognition) DetectTextWithContext(ctx aws.Context, input *rekognition.DetectTextInput, opts ...request.Option) (*rekognition.DetectTextOutput, error) {
+	f.images = append(f.images, input.Image)
+
+	return &rekognition.DetectTextOutput{TextDetections: []*rekognition.TextDetection{
+		{DetectedText: aws.String("Hello world"), Type: aws.String(rekognition.TextTypesLine), Confidence: aws.Float64(95)},
+		{DetectedText: aws.String("Hello"), Type: aws.String(rekognition.TextTypesWord), Confidence: aws.Float64(95)},
+	}}, nil
+}
+
+func TestRekognition(t *testing.T) {
+	v := NewVision(logger.NewLogger("test")).(*Vision)
+	require.NoError(t, v.Init(bindings.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"provider": "aws",
+		"region":   "us-east-1",
+	}}}))
+	fake := &fakeRekognition{}
+	v.provider.(*rekognitionVision).client = fake
+
+	assert.Equal(t, []bindings.OperationKind{analyzeOperation, ocrOperation}, v.Operations())
+
+	t.Run("analyze", func(t *testing.T) {
+		res, err := invoke(t, v, analyzeOperation, "image", nil)
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"tags": [{"name": "Dog", "confidence": 0.99}, {"name": "Grass", "confidence": 0.9}],
+			"objects": [{"name": "Dog", "confidence": 0.8, "box": {"left": 0.1, "top": 0.2, "width": 0.3, "height": 0.4}}]
+		}`, res)
+		assert.Equal(t, "image", string(fake.images[len(fake.images)-1].Bytes))
+	})
+
+	t.Run("ocr reads S3 objects", func(t *testing.T) {
+		res, err := invoke(t, v, ocrOperation, "", map[string]string{"imageUrl": "s3://photos/signs/stop.png"})
+
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"text": "Hello world", "lines": [{"text": "Hello world", "confidence": 0.95}]}`, res)
+		img := fake.images[len(fake.images)-1]
+		assert.Nil(t, img.Bytes)
+		assert.Equal(t, &rekognition.S3Object{Bucket: aws.String("photos"), Name: aws.String("signs/stop.png")}, img.S3Object)
+	})
+
+	t.Run("image URLs are not downloaded", func(t *testing.T) {
+		for _, u := range []string{"http://169.254.169.254/latest/meta-data", "https://example.com/dog.jpg", "s3://photos", "s3:///dog.jpg"} {
+			_, err := invoke(t, v, ocrOperation, "", map[string]string{"imageUrl": u})
+			assert.Error(t, err, u)
+		}
+		assert.Len(t, fake.images, 2)
+	})
+
+	t.Run("describe is not supported", func(t *testing.T) {
+		_, err := invoke(t, v, describeOperation, "image", nil)
+
+		assert.ErrorIs(t, err, errUnsupported)
+	})
+}
@@ -30,6 +30,7 @@ import (
 
 	"github.com/dapr/components-contrib/configuration"
 	azauth "github.com/dapr/components-contrib/internal/authentication/azure"
+	contribMetadata "github.com/dapr/components-contrib/metadata"
 
 	"github.com/dapr/kit/logger"
 )
@@ -189,6 +190,24 @@ func parseMetadata(meta configuration.Metadata) (metadata, error) {
 	return m, nil
 }
 
+// GetComponentMetadataSchema returns the schema of the metadata of the App Configuration store.
+// The delays and the intervals are numbers of nanoseconds.
+func (r *ConfigurationStore) GetComponentMetadataSchema() []contribMetadata.MetadataField {
+	nanoseconds := func(d time.Duration) string {
+		return strconv.FormatInt(int64(d), 10)
+	}
+
+	return []contribMetadata.MetadataField{
+		{Name: host, Type: contribMetadata.FieldTypeString},
+		{Name: connectionString, Type: contribMetadata.FieldTypeString, Sensitive: true},
+		{Name: maxRetries, Type: contribMetadata.FieldTypeNumber, Default: strconv.Itoa(defaultMaxRetries)},
+		{Name: retryDelay, Type: contribMetadata.FieldTypeNumber, Default: nanoseconds(defaultRetryDelay)},
+		{Name: maxRetryDelay, Type: contribMetadata.FieldTypeNumber, Default: nanoseconds(defaultMaxRetryDelay)},
+		{Name: subscribePollInterval, Type: contribMetadata.FieldTypeNumber, Default: nanoseconds(defaultSubscribePollInterval)},
+		{Name: requestTimeout, Type: contribMetadata.FieldTypeNumber, Default: nanoseconds(defaultRequestTimeout)},
+	}
+}
+
 func (r *ConfigurationStore) Get(ctx context.Context, req *configuration.GetRequest) (*configuration.GetResponse, error) {
 	keys := req.Keys
 	var items map[string]*configuration.Item
@@ -33,6 +33,7 @@ import (
 	"k8s.io/utils/strings/slices"
 
 	"github.com/dapr/components-contrib/configuration"
+	"github.com/dapr/components-contrib/internal/logging"
 	"github.com/dapr/kit/logger"
 )
 
@@ -88,12 +89,13 @@ func NewPostgresConfigurationStore(logger logger.Logger) configuration.Store {
 }
 
 func (p *ConfigurationStore) Init(metadata configuration.Metadata) error {
+	p.logger = logging.ForComponent(p.logger, "configuration.postgres", metadata.Name)
 	p.logger.Debug(InfoStartInit)
 	if p.client != nil {
 		return fmt.Errorf(ErrorAlreadyInitialized)
 	}
 	if m, err := parseMetadata(metadata); err != nil {
-		p.logger.Error(err)
+		p.logger.Errorf("invalid metadata: %v", err)
 		return err
 	} else {
 		p.metadata = m
@@ -124,12 +126,12 @@ func (p *ConfigurationStore) Init(metadata configuration.Metadata) error {
 
 func (p *ConfigurationStore) Get(ctx context.Context, req *configuration.GetRequest) (*configuration.GetResponse, error) {
 	if err := validateInput(req.Keys); err != nil {
-		p.logger.Error(err)
+		p.logger.Errorf("invalid keys: %v", err)
 		return nil, err
 	}
 	query, params, err := buildQuery(req, p.metadata.configTable)
 	if err != nil {
-		p.logger.Error(err)
+		p.logger.Errorf("error building the query: %v", err)
 		return nil, fmt.Errorf("error in configuration store query: '%w' ", err)
 	}
 	rows, err := p.client.Query(ctx, query, params...)
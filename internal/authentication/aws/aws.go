@@ -14,27 +14,71 @@ limitations under the License.
 package aws
 
 import (
+	"strings"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 
 	"github.com/dapr/kit/logger"
 )
 
-func GetClient(accessKey string, secretKey string, sessionToken string, region string, endpoint string) (*session.Session, error) {
+// AssumeRole is the IAM role to assume with the base credentials.
+type AssumeRole struct {
+	// ARN of the role; no role is assumed if empty.
+	RoleARN string
+	// External ID required by the trust policy of the role, if any.
+	ExternalID string
+	// Name of the role session; a name is generated if empty.
+	SessionName string
+}
+
+// Options are the options to create an AWS session.
+type Options struct {
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	Region       string
+	Endpoint     string
+	AssumeRole   AssumeRole
+
+	// Use the FIPS endpoints of the services.
+	UseFIPSEndpoint bool
+	// Use the dual-stack (IPv4 and IPv6) endpoints of the services.
+	UseDualStackEndpoint bool
+	// Regions to fail over to, in order of preference, when the region is unavailable. They are ignored with a custom
+	// endpoint. The resources must be available in these regions with the same names, as with DynamoDB global tables.
+	FailoverRegions []string
+}
+
+// NewSession creates an AWS session.
+// The static keys are optional: when not set, the credentials are resolved by the default chain, from the environment
+// variables, the shared configuration, a web identity token (such as IRSA on EKS), or the ECS task or EC2 instance role.
+// If a role is set, it is assumed with those base credentials.
+func NewSession(opts Options) (*session.Session, error) {
 	awsConfig := aws.NewConfig()
 
-	if region != "" {
-		awsConfig = awsConfig.WithRegion(region)
+	if opts.Region != "" {
+		awsConfig = awsConfig.WithRegion(opts.Region)
+	}
+
+	if opts.AccessKey != "" && opts.SecretKey != "" {
+		awsConfig = awsConfig.WithCredentials(credentials.NewStaticCredentials(opts.AccessKey, opts.SecretKey, opts.SessionToken))
+	}
+
+	if opts.Endpoint != "" {
+		awsConfig = awsConfig.WithEndpoint(opts.Endpoint)
 	}
 
-	if accessKey != "" && secretKey != "" {
-		awsConfig = awsConfig.WithCredentials(credentials.NewStaticCredentials(accessKey, secretKey, sessionToken))
+	if opts.UseFIPSEndpoint {
+		awsConfig.UseFIPSEndpoint = endpoints.FIPSEndpointStateEnabled
 	}
 
-	if endpoint != "" {
-		awsConfig = awsConfig.WithEndpoint(endpoint)
+	if opts.UseDualStackEndpoint {
+		awsConfig.UseDualStackEndpoint = endpoints.DualStackEndpointStateEnabled
 	}
 
 	awsSession, err := session.NewSessionWithOptions(session.Options{
@@ -45,11 +89,53 @@ func GetClient(accessKey string, secretKey string, sessionToken string, region s
 		return nil, err
 	}
 
+	if opts.AssumeRole.RoleARN != "" {
+		// The endpoint is only meant for the service, not for STS
+		baseSession := awsSession.Copy(&aws.Config{Endpoint: aws.String("")})
+		creds := stscreds.NewCredentials(baseSession, opts.AssumeRole.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+			if opts.AssumeRole.ExternalID != "" {
+				p.ExternalID = aws.String(opts.AssumeRole.ExternalID)
+			}
+			if opts.AssumeRole.SessionName != "" {
+				p.RoleSessionName = opts.AssumeRole.SessionName
+			}
+		})
+		awsSession = awsSession.Copy(&aws.Config{Credentials: creds})
+	}
+
 	userAgentHandler := request.NamedHandler{
 		Name: "UserAgentHandler",
 		Fn:   request.MakeAddToUserAgentHandler("dapr", logger.DaprVersion),
 	}
 	awsSession.Handlers.Build.PushBackNamed(userAgentHandler)
 
+	if regions := failoverRegions(aws.StringValue(awsSession.Config.Region), opts.FailoverRegions); opts.Endpoint == "" && len(regions) > 1 {
+		failover := newRegionFailover(regions, awsSession.Config.EndpointResolver, func(o *endpoints.Options) {
+			o.UseFIPSEndpoint = awsConfig.UseFIPSEndpoint
+			o.UseDualStackEndpoint = awsConfig.UseDualStackEndpoint
+		})
+		failover.addHandlers(&awsSession.Handlers)
+	}
+
 	return awsSession, nil
 }
+
+// failoverRegions returns the list of regions starting with region, followed by the failover regions not listed yet.
+func failoverRegions(region string, failover []string) []string {
+	regions := []string{region}
+	for _, r := range failover {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		listed := false
+		for _, l := range regions {
+			listed = listed || l == r
+		}
+		if !listed {
+			regions = append(regions, r)
+		}
+	}
+
+	return regions
+}
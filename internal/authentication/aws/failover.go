@@ -0,0 +1,171 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// Time after which the requests are sent to the preferred region again, after it was found unavailable.
+const failbackInterval = time.Minute
+
+// Prefixes of the names of the operations which only read, and can be retried in another region.
+var readOperationPrefixes = []string{"Get", "List", "Describe", "Query", "Scan", "BatchGet", "Head"}
+
+// regionFailover sends the requests to the first available region of an ordered list.
+// A region is considered unavailable when a request to it fails to connect: the following requests are sent to the next
+// region until the preferred region is tried again. The failed request is retried in the next region only if it is
+// idempotent, as it may have been applied in its region.
+type regionFailover struct {
+	regions         []string
+	resolver        endpoints.Resolver
+	resolverOptions func(*endpoints.Options)
+
+	current  int
+	failedAt time.Time
+	lock     sync.Mutex
+}
+
+func newRegionFailover(regions []string, resolver endpoints.Resolver, resolverOptions func(*endpoints.Options)) *regionFailover {
+	return &regionFailover{
+		regions:         regions,
+		resolver:        resolver,
+		resolverOptions: resolverOptions,
+	}
+}
+
+// addHandlers adds the handlers of the failover to the handlers of a session.
+func (f *regionFailover) addHandlers(handlers *request.Handlers) {
+	// The region is set before each attempt is signed
+	handlers.Sign.PushFrontNamed(request.NamedHandler{
+		Name: "RegionFailoverHandler",
+		Fn:   f.setRegion,
+	})
+	handlers.CompleteAttempt.PushBackNamed(request.NamedHandler{
+		Name: "RegionFailoverCompleteAttemptHandler",
+		Fn:   f.completeAttempt,
+	})
+}
+
+// region returns the region the requests are sent to.
+func (f *regionFailover) region() string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.current > 0 && time.Since(f.failedAt) > failbackInterval {
+		f.current = 0
+	}
+
+	return f.regions[f.current]
+}
+
+// failed moves on to the region after the given one, unless another request did it already.
+func (f *regionFailover) failed(region string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.regions[f.current] == region {
+		f.current = (f.current + 1) % len(f.regions)
+		f.failedAt = time.Now()
+	}
+}
+
+// setRegion sends the request to the endpoint of the current region, if it was built for another one.
+func (f *regionFailover) setRegion(r *request.Request) {
+	// The retries of the requests which aren't idempotent stay in the region of their first attempt
+	if r.RetryCount > 0 && !idempotent(r) {
+		return
+	}
+
+	region := f.region()
+	if region == aws.StringValue(r.Config.Region) {
+		return
+	}
+
+	resolved, err := f.resolver.EndpointFor(r.ClientInfo.ServiceName, region, f.resolverOptions)
+	if err != nil {
+		return
+	}
+	oldEndpoint, err := url.Parse(r.ClientInfo.Endpoint)
+	if err != nil {
+		return
+	}
+	newEndpoint, err := url.Parse(resolved.URL)
+	if err != nil {
+		return
+	}
+
+	// The host of the request may be prefixed with the resource, such as the bucket of S3
+	if !strings.HasSuffix(r.HTTPRequest.URL.Host, oldEndpoint.Host) {
+		return
+	}
+	r.HTTPRequest.URL.Host = strings.TrimSuffix(r.HTTPRequest.URL.Host, oldEndpoint.Host) + newEndpoint.Host
+	r.HTTPRequest.Host = ""
+
+	r.ClientInfo.Endpoint = resolved.URL
+	r.ClientInfo.SigningRegion = resolved.SigningRegion
+	r.Config.Region = aws.String(region)
+}
+
+// completeAttempt fails over to the next region if the attempt failed because its region is unavailable.
+func (f *regionFailover) completeAttempt(r *request.Request) {
+	if r.Error == nil || !regionUnavailable(r) {
+		return
+	}
+
+	f.failed(aws.StringValue(r.Config.Region))
+	if idempotent(r) {
+		r.Retryable = aws.Bool(true)
+	}
+}
+
+// regionUnavailable returns whether the attempt failed to connect to its region.
+// The server errors and the canceled requests don't tell whether the region is available.
+func regionUnavailable(r *request.Request) bool {
+	if r.Context().Err() != nil {
+		return false
+	}
+	if aerr, ok := r.Error.(awserr.Error); ok && aerr.Code() == request.CanceledErrorCode {
+		return false
+	}
+
+	// The status code of the requests which failed to connect is 0
+	return r.HTTPResponse == nil || r.HTTPResponse.StatusCode == 0
+}
+
+// idempotent returns whether the request only reads, so that it can be sent again to another region.
+func idempotent(r *request.Request) bool {
+	if r.HTTPRequest != nil && (r.HTTPRequest.Method == http.MethodGet || r.HTTPRequest.Method == http.MethodHead) {
+		return true
+	}
+	if r.Operation == nil {
+		return false
+	}
+	for _, prefix := range readOperationPrefixes {
+		if strings.HasPrefix(r.Operation.Name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
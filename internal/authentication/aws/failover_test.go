@@ -0,0 +1,154 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// roundTripper fails the requests to the unavailable hosts, and records the hosts of the requests.
+type roundTripper struct {
+	unavailable string
+	// Whether the unavailable hosts respond with a server error, or cancel the request, instead of refusing the connection.
+	serverError bool
+	cancel      context.CancelFunc
+	hosts       []string
+	lock        sync.Mutex
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.lock.Lock()
+	rt.hosts = append(rt.hosts, req.URL.Host)
+	rt.lock.Unlock()
+
+	if strings.Contains(req.URL.Host, rt.unavailable) {
+		switch {
+		case rt.serverError:
+			return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: io.NopCloser(strings.NewReader(""))}, nil
+		case rt.cancel != nil:
+			rt.cancel()
+			return nil, context.Canceled
+		default:
+			return nil, errors.New("connection refused")
+		}
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/x-amz-json-1.0"}},
+		Body:       io.NopCloser(strings.NewReader(`{"TableNames": ["table"]}`)),
+	}, nil
+}
+
+func TestFailoverRegions(t *testing.T) {
+	regions := failoverRegions("us-east-1", []string{"us-west-2", " ", "us-east-1", "eu-west-1 "})
+
+	assert.Equal(t, []string{"us-east-1", "us-west-2", "eu-west-1"}, regions)
+}
+
+func TestNewSessionWithFailover(t *testing.T) {
+	newClient := func(t *testing.T, opts Options, rt *roundTripper) *dynamodb.DynamoDB {
+		t.Helper()
+
+		opts.AccessKey = "key"
+		opts.SecretKey = "secret"
+		sess, err := NewSession(opts)
+		require.NoError(t, err)
+
+		// A single retry, in the next region
+		return dynamodb.New(sess.Copy(&aws.Config{HTTPClient: &http.Client{Transport: rt}, MaxRetries: aws.Int(1)}))
+	}
+
+	t.Run("fails over to the next region", func(t *testing.T) {
+		rt := &roundTripper{unavailable: "us-east-1"}
+		client := newClient(t, Options{Region: "us-east-1", FailoverRegions: []string{"us-west-2"}}, rt)
+
+		out, err := client.ListTables(&dynamodb.ListTablesInput{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"table"}, aws.StringValueSlice(out.TableNames))
+		assert.Equal(t, []string{"dynamodb.us-east-1.amazonaws.com", "dynamodb.us-west-2.amazonaws.com"}, rt.hosts)
+
+		// The next requests are sent to the available region
+		_, err = client.ListTables(&dynamodb.ListTablesInput{})
+		require.NoError(t, err)
+		assert.Equal(t, "dynamodb.us-west-2.amazonaws.com", rt.hosts[2])
+	})
+
+	t.Run("fips endpoints", func(t *testing.T) {
+		rt := &roundTripper{unavailable: "us-east-1"}
+		client := newClient(t, Options{Region: "us-east-1", FailoverRegions: []string{"us-west-2"}, UseFIPSEndpoint: true}, rt)
+
+		_, err := client.ListTables(&dynamodb.ListTablesInput{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"dynamodb-fips.us-east-1.amazonaws.com", "dynamodb-fips.us-west-2.amazonaws.com"}, rt.hosts)
+	})
+
+	t.Run("requests which aren't idempotent are not retried in the next region", func(t *testing.T) {
+		rt := &roundTripper{unavailable: "us-east-1"}
+		client := newClient(t, Options{Region: "us-east-1", FailoverRegions: []string{"us-west-2"}}, rt)
+
+		_, err := client.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String("table")})
+		assert.Error(t, err)
+		assert.Equal(t, []string{"dynamodb.us-east-1.amazonaws.com", "dynamodb.us-east-1.amazonaws.com"}, rt.hosts)
+
+		// The next requests are sent to the available region
+		_, err = client.ListTables(&dynamodb.ListTablesInput{})
+		require.NoError(t, err)
+		assert.Equal(t, "dynamodb.us-west-2.amazonaws.com", rt.hosts[2])
+	})
+
+	t.Run("no failover on server errors", func(t *testing.T) {
+		rt := &roundTripper{unavailable: "us-east-1", serverError: true}
+		client := newClient(t, Options{Region: "us-east-1", FailoverRegions: []string{"us-west-2"}}, rt)
+
+		_, err := client.ListTables(&dynamodb.ListTablesInput{})
+		assert.Error(t, err)
+		assert.Equal(t, []string{"dynamodb.us-east-1.amazonaws.com", "dynamodb.us-east-1.amazonaws.com"}, rt.hosts)
+	})
+
+	t.Run("no failover when the request is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		rt := &roundTripper{unavailable: "us-east-1", cancel: cancel}
+		client := newClient(t, Options{Region: "us-east-1", FailoverRegions: []string{"us-west-2"}}, rt)
+
+		_, err := client.ListTablesWithContext(ctx, &dynamodb.ListTablesInput{})
+		assert.Error(t, err)
+
+		rt.unavailable = "none"
+		_, err = client.ListTables(&dynamodb.ListTablesInput{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"dynamodb.us-east-1.amazonaws.com", "dynamodb.us-east-1.amazonaws.com"}, rt.hosts)
+	})
+
+	t.Run("no failover with a custom endpoint", func(t *testing.T) {
+		rt := &roundTripper{unavailable: "localhost"}
+		client := newClient(t, Options{Region: "us-east-1", FailoverRegions: []string{"us-west-2"}, Endpoint: "http://localhost:4566"}, rt)
+
+		_, err := client.ListTables(&dynamodb.ListTablesInput{})
+		assert.Error(t, err)
+		assert.Equal(t, []string{"localhost:4566", "localhost:4566"}, rt.hosts)
+	})
+}
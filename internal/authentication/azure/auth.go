@@ -14,15 +14,20 @@ limitations under the License.
 package azure
 
 import (
+	"bytes"
+	"context"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/keyvault/azsecrets"
 	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/adal"
 	"github.com/Azure/go-autorest/autorest/azure"
@@ -32,6 +37,32 @@ import (
 	"github.com/dapr/components-contrib/metadata"
 )
 
+// errMissingClientCert is returned by GetClientCert when no certificate credentials are available.
+var errMissingClientCert = errors.New("missing client certificate")
+
+// Timeout for fetching the client certificate from Azure Key Vault.
+const certificateFetchTimeout = 30 * time.Second
+
+// getCertificateSecret returns the value and the content type of the secret backing a certificate stored in Azure Key Vault.
+// It's a variable so it can be replaced in tests.
+var getCertificateSecret = func(ctx context.Context, vaultURL string, name string, cred azcore.TokenCredential) (string, string, error) {
+	client := azsecrets.NewClient(vaultURL, cred, nil)
+	res, err := client.GetSecret(ctx, name, "", nil)
+	if err != nil {
+		return "", "", err
+	}
+
+	var value, contentType string
+	if res.Value != nil {
+		value = *res.Value
+	}
+	if res.ContentType != nil {
+		contentType = *res.ContentType
+	}
+
+	return value, contentType, nil
+}
+
 // NewEnvironmentSettings returns a new EnvironmentSettings configured for a given Azure resource.
 func NewEnvironmentSettings(resourceName string, values map[string]string) (EnvironmentSettings, error) {
 	es := EnvironmentSettings{
@@ -57,6 +88,9 @@ func NewEnvironmentSettings(resourceName string, values map[string]string) (Envi
 		es.Resource = azureEnv.ResourceIdentifiers.CosmosDB
 	case "servicebus":
 		es.Resource = azureEnv.ResourceIdentifiers.ServiceBus
+	case "sql":
+		// Azure SQL Database (data plane)
+		es.Resource = azureEnv.ResourceIdentifiers.SQLDatabase
 	case "eventhubs":
 		// Azure EventHubs (data plane)
 		// For documentation https://docs.microsoft.com/en-us/azure/event-hubs/authorize-access-azure-active-directory#overview
@@ -66,6 +100,10 @@ func NewEnvironmentSettings(resourceName string, values map[string]string) (Envi
 	case "signalr":
 		// Azure SignalR (data plane)
 		es.Resource = "https://signalr.azure.com"
+	case "dataexplorer":
+		// Azure Data Explorer (data plane)
+		// The resource name to request a token is the URL of the cluster, or https://kusto.kusto.windows.net for all the clusters.
+		es.Resource = "https://kusto.kusto.windows.net"
 	case "appconfig":
 		// Azure App Configuration (data plane)
 		// For documentation https://docs.microsoft.com/en-us/azure/azure-app-configuration/rest-api-authentication-azure-ad#audience
@@ -120,6 +158,7 @@ func (s EnvironmentSettings) GetTokenCredential() (azcore.TokenCredential, error
 	}
 
 	// 2. Client certificate
+	// Failing to fetch a certificate from Azure Key Vault is not silently ignored
 	if c, e := s.GetClientCert(); e == nil {
 		cred, err := c.GetTokenCredential()
 		if err == nil {
@@ -127,6 +166,8 @@ func (s EnvironmentSettings) GetTokenCredential() (azcore.TokenCredential, error
 		} else {
 			errMsg += err.Error() + "\n"
 		}
+	} else if !errors.Is(e, errMissingClientCert) {
+		return nil, e
 	}
 
 	// 3. MSI
@@ -174,6 +215,8 @@ func (s EnvironmentSettings) GetServicePrincipalToken() (*adal.ServicePrincipalT
 	// 2. Client Certificate
 	if c, e := s.GetClientCert(); e == nil {
 		return c.ServicePrincipalToken()
+	} else if !errors.Is(e, errMissingClientCert) {
+		return nil, e
 	}
 
 	// 3. MSI
@@ -216,7 +259,19 @@ func (s EnvironmentSettings) GetClientCert() (CertConfig, error) {
 	tenantID, _ := s.GetEnvironment("TenantID")
 
 	if !certFilePathPresent && !certBytesPresent {
-		return CertConfig{}, fmt.Errorf("missing client certificate")
+		// The certificate may be stored in Azure Key Vault, in which case it's fetched using the managed identity
+		certVaultName, _ := s.GetEnvironment("CertificateVaultName")
+		certName, _ := s.GetEnvironment("CertificateName")
+		if certVaultName == "" || certName == "" {
+			return CertConfig{}, errMissingClientCert
+		}
+
+		data, err := s.fetchCertificate(certVaultName, certName)
+		if err != nil {
+			return CertConfig{}, err
+		}
+		// Certificates exported from Azure Key Vault are not protected with a password
+		return NewCertConfig(clientID, tenantID, "", data, "", s.Resource, azureEnv), nil
 	}
 
 	authorizer := NewCertConfig(clientID, tenantID, certFilePath, []byte(certBytes), certPassword, s.Resource, azureEnv)
@@ -224,11 +279,46 @@ func (s EnvironmentSettings) GetClientCert() (CertConfig, error) {
 	return authorizer, nil
 }
 
-// GetMSI creates a MSI config object from the available client ID.
+// fetchCertificate fetches the client certificate from Azure Key Vault, authenticating with the managed identity.
+// The certificate is returned as PKCS#12 or PEM data, depending on the content type it was created with.
+func (s EnvironmentSettings) fetchCertificate(vaultName string, certName string) ([]byte, error) {
+	// The client ID of the Service Principal is not the one of the managed identity here
+	msi := NewMSIConfig(s.Resource)
+	msi.ClientID, _ = s.GetEnvironment("MSIClientID")
+	msi.ResourceID, _ = s.GetEnvironment("MSIResourceID")
+	cred, err := msi.GetTokenCredential()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the managed identity credential to fetch the certificate: %w", err)
+	}
+
+	vaultURL := fmt.Sprintf("https://%s.%s", vaultName, s.AzureEnvironment.KeyVaultDNSSuffix)
+	ctx, cancel := context.WithTimeout(context.Background(), certificateFetchTimeout)
+	defer cancel()
+	value, contentType, err := getCertificateSecret(ctx, vaultURL, certName, cred)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the certificate %s from Azure Key Vault %s: %w", certName, vaultName, err)
+	}
+
+	if contentType == "application/x-pem-file" {
+		return []byte(value), nil
+	}
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the certificate %s from Azure Key Vault %s: %w", certName, vaultName, err)
+	}
+
+	return data, nil
+}
+
+// GetMSI creates a MSI config object from the available client ID or resource ID.
 func (s EnvironmentSettings) GetMSI() MSIConfig {
 	config := NewMSIConfig(s.Resource)
-	// This is optional and it's ok if value is empty
-	config.ClientID, _ = s.GetEnvironment("ClientID")
+	// These are optional and it's ok if values are empty
+	config.ClientID, _ = s.GetEnvironment("MSIClientID")
+	if config.ClientID == "" {
+		config.ClientID, _ = s.GetEnvironment("ClientID")
+	}
+	config.ResourceID, _ = s.GetEnvironment("MSIResourceID")
 
 	return config
 }
@@ -313,9 +403,9 @@ func (c CertConfig) ServicePrincipalTokenByCertBytes() (*adal.ServicePrincipalTo
 		return nil, err
 	}
 
-	certificate, rsaPrivateKey, err := c.decodePkcs12(c.CertificateData, c.CertificatePassword)
+	certificate, rsaPrivateKey, err := c.decodeCertificate(c.CertificateData, c.CertificatePassword)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode pkcs12 certificate while creating spt: %v", err)
+		return nil, fmt.Errorf("failed to decode certificate while creating spt: %v", err)
 	}
 
 	return adal.NewServicePrincipalTokenFromCertificate(*oauthConfig, c.ClientID, certificate, rsaPrivateKey, c.Resource)
@@ -340,10 +430,10 @@ func (c CertConfig) GetTokenCredential() (token azcore.TokenCredential, err erro
 		return nil, fmt.Errorf("certificate is not given")
 	}
 
-	// Decode the PKCS#12 certificate
-	cert, key, err := c.decodePkcs12(data, c.CertificatePassword)
+	// Decode the PKCS#12 or PEM certificate
+	cert, key, err := c.decodeCertificate(data, c.CertificatePassword)
 	if err != nil || cert == nil {
-		return nil, fmt.Errorf("failed to decode pkcs12 certificate while creating spt: %v", err)
+		return nil, fmt.Errorf("failed to decode certificate while creating spt: %v", err)
 	}
 
 	// Create the azcore.TokenCredential object
@@ -358,6 +448,25 @@ func (c CertConfig) GetTokenCredential() (token azcore.TokenCredential, err erro
 	return azidentity.NewClientCertificateCredential(c.TenantID, c.ClientID, certs, key, opts)
 }
 
+// decodeCertificate decodes a PEM certificate, with its private key in the same data, or a PKCS#12 one.
+func (c CertConfig) decodeCertificate(data []byte, password string) (*x509.Certificate, *rsa.PrivateKey, error) {
+	if !bytes.Contains(data, []byte("-----BEGIN")) {
+		return c.decodePkcs12(data, password)
+	}
+
+	certs, privateKey, err := azidentity.ParseCertificates(data, []byte(password))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rsaPrivateKey, isRsaKey := privateKey.(*rsa.PrivateKey)
+	if !isRsaKey {
+		return nil, nil, fmt.Errorf("PEM certificate must contain an RSA private key")
+	}
+
+	return certs[0], rsaPrivateKey, nil
+}
+
 func (c CertConfig) decodePkcs12(pkcs []byte, password string) (*x509.Certificate, *rsa.PrivateKey, error) {
 	privateKey, certificate, err := pkcs12.Decode(pkcs, password)
 	if err != nil {
@@ -374,8 +483,9 @@ func (c CertConfig) decodePkcs12(pkcs []byte, password string) (*x509.Certificat
 
 // MSIConfig provides the options to get a bearer authorizer through MSI.
 type MSIConfig struct {
-	Resource string
-	ClientID string
+	Resource   string
+	ClientID   string
+	ResourceID string
 }
 
 // NewMSIConfig creates an MSIConfig object configured to obtain an Authorizer through MSI.
@@ -393,7 +503,12 @@ func (c MSIConfig) ServicePrincipalToken() (*adal.ServicePrincipalToken, error)
 	}
 
 	var spToken *adal.ServicePrincipalToken
-	if c.ClientID == "" {
+	if c.ResourceID != "" {
+		spToken, err = adal.NewServicePrincipalTokenFromMSIWithIdentityResourceID(msiEndpoint, c.Resource, c.ResourceID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get oauth token from MSI for user assigned identity: %v", err)
+		}
+	} else if c.ClientID == "" {
 		spToken, err = adal.NewServicePrincipalTokenFromMSI(msiEndpoint, c.Resource)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get oauth token from MSI: %v", err)
@@ -411,7 +526,9 @@ func (c MSIConfig) ServicePrincipalToken() (*adal.ServicePrincipalToken, error)
 // GetTokenCredential returns the azcore.TokenCredential object from MSI.
 func (c MSIConfig) GetTokenCredential() (token azcore.TokenCredential, err error) {
 	opts := &azidentity.ManagedIdentityCredentialOptions{}
-	if c.ClientID != "" {
+	if c.ResourceID != "" {
+		opts.ID = azidentity.ResourceID(c.ResourceID)
+	} else if c.ClientID != "" {
 		opts.ID = azidentity.ClientID(c.ClientID)
 	}
 	return azidentity.NewManagedIdentityCredential(opts)
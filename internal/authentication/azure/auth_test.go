@@ -14,11 +14,22 @@ limitations under the License.
 package azure
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -210,6 +221,148 @@ func TestAuthorizorWithMSIAndUserAssignedID(t *testing.T) {
 	assert.NotNil(t, spt)
 }
 
+func TestGetClientCertFromKeyVault(t *testing.T) {
+	props := map[string]string{
+		"azureCertificateVaultName": "certvault",
+		"azureCertificateName":      "spn-cert",
+		"azureClientId":             fakeClientID,
+		"azureTenantId":             fakeTenantID,
+		"vaultName":                 "vaultName",
+	}
+	fakeSecret := func(value, contentType string, err error) func() {
+		orig := getCertificateSecret
+		getCertificateSecret = func(ctx context.Context, vaultURL string, name string, cred azcore.TokenCredential) (string, string, error) {
+			assert.Equal(t, "https://certvault.vault.azure.net", vaultURL)
+			assert.Equal(t, "spn-cert", name)
+			assert.NotNil(t, cred)
+
+			return value, contentType, err
+		}
+
+		return func() { getCertificateSecret = orig }
+	}
+
+	t.Run("PKCS#12 certificate", func(t *testing.T) {
+		defer fakeSecret(testCert, "application/x-pkcs12", nil)()
+
+		settings, err := NewEnvironmentSettings("keyvault", props)
+		require.NoError(t, err)
+
+		testCertConfig, err := settings.GetClientCert()
+		require.NoError(t, err)
+		assert.Equal(t, getTestCert(), testCertConfig.CertificateData)
+		assert.Equal(t, fakeClientID, testCertConfig.ClientID)
+
+		spt, err := testCertConfig.ServicePrincipalToken()
+		assert.NoError(t, err)
+		assert.NotNil(t, spt)
+
+		cred, err := settings.GetTokenCredential()
+		assert.NoError(t, err)
+		assert.NotNil(t, cred)
+	})
+
+	t.Run("PEM certificate", func(t *testing.T) {
+		defer fakeSecret(string(getTestPEMCert(t)), "application/x-pem-file", nil)()
+
+		settings, err := NewEnvironmentSettings("keyvault", props)
+		require.NoError(t, err)
+
+		testCertConfig, err := settings.GetClientCert()
+		require.NoError(t, err)
+
+		spt, err := testCertConfig.ServicePrincipalToken()
+		assert.NoError(t, err)
+		assert.NotNil(t, spt)
+
+		cred, err := testCertConfig.GetTokenCredential()
+		assert.NoError(t, err)
+		assert.NotNil(t, cred)
+	})
+
+	t.Run("Failing to fetch the certificate is an error", func(t *testing.T) {
+		defer fakeSecret("", "", errors.New("forbidden"))()
+
+		settings, err := NewEnvironmentSettings("keyvault", props)
+		require.NoError(t, err)
+
+		_, err = settings.GetClientCert()
+		assert.ErrorContains(t, err, "forbidden")
+
+		_, err = settings.GetTokenCredential()
+		assert.ErrorContains(t, err, "forbidden")
+	})
+
+	t.Run("Certificate name is required", func(t *testing.T) {
+		settings, err := NewEnvironmentSettings("keyvault", map[string]string{
+			"azureCertificateVaultName": "certvault",
+		})
+		require.NoError(t, err)
+
+		_, err = settings.GetClientCert()
+		assert.ErrorIs(t, err, errMissingClientCert)
+	})
+}
+
+func TestGetMSIWithUserAssignedIdentity(t *testing.T) {
+	t.Run("MSI client ID takes precedence", func(t *testing.T) {
+		settings, err := NewEnvironmentSettings(
+			"keyvault",
+			map[string]string{
+				"azureClientId":    fakeClientID,
+				"azureMsiClientId": fakeTenantID,
+			},
+		)
+		require.NoError(t, err)
+
+		assert.Equal(t, fakeTenantID, settings.GetMSI().ClientID)
+	})
+
+	t.Run("Resource ID", func(t *testing.T) {
+		os.Setenv("MSI_ENDPOINT", "test")
+		defer os.Unsetenv("MSI_ENDPOINT")
+		resourceID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/id"
+		settings, err := NewEnvironmentSettings(
+			"keyvault",
+			map[string]string{
+				"azureMsiResourceId": resourceID,
+			},
+		)
+		require.NoError(t, err)
+
+		msi := settings.GetMSI()
+		assert.Equal(t, resourceID, msi.ResourceID)
+
+		spt, err := msi.ServicePrincipalToken()
+		assert.NoError(t, err)
+		assert.NotNil(t, spt)
+
+		cred, err := msi.GetTokenCredential()
+		assert.NoError(t, err)
+		assert.NotNil(t, cred)
+	})
+}
+
+func getTestPEMCert(t *testing.T) []byte {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+
+	return data
+}
+
 func getTestCert() []byte {
 	certBytes, _ := base64.StdEncoding.DecodeString(testCert)
 
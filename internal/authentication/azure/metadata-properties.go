@@ -23,6 +23,11 @@ var MetadataKeys = map[string][]string{ //nolint:gochecknoglobals
 	"CertificateFile": {"azureCertificateFile", "spnCertificateFile"},
 	// Password for the certificate
 	"CertificatePassword": {"azureCertificatePassword", "spnCertificatePassword"},
+	// Name of the Azure Key Vault holding the certificate of the Service Principal, which is fetched at runtime
+	// authenticating with the managed identity
+	"CertificateVaultName": {"azureCertificateVaultName"},
+	// Name of the certificate in the Azure Key Vault
+	"CertificateName": {"azureCertificateName"},
 	// Client ID for the Service Principal
 	// The "clientId" alias is supported for backwards-compatibility as it's used by some components, but should be considered deprecated
 	"ClientID": {"azureClientId", "spnClientId", "clientId"},
@@ -32,6 +37,11 @@ var MetadataKeys = map[string][]string{ //nolint:gochecknoglobals
 	// Tenant ID for the Service Principal
 	// The "tenantId" alias is supported for backwards-compatibility as it's used by some components, but should be considered deprecated
 	"TenantID": {"azureTenantId", "spnTenantId", "tenantId"},
+	// Client ID of the user-assigned managed identity
+	// If not set, the client ID of the Service Principal is used, unless the certificate is fetched from Azure Key Vault
+	"MSIClientID": {"azureMsiClientId"},
+	// Resource ID of the user-assigned managed identity, as an alternative to its client ID
+	"MSIResourceID": {"azureMsiResourceId"},
 	// Identifier for the Azure environment
 	// Allowed values (case-insensitive): AZUREPUBLICCLOUD, AZURECHINACLOUD, AZUREGERMANCLOUD, AZUREUSGOVERNMENTCLOUD
 	"AzureEnvironment": {"azureEnvironment"},
@@ -14,7 +14,9 @@ limitations under the License.
 package azure
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Azure/azure-storage-blob-go/azblob"
@@ -32,8 +34,175 @@ var (
 	StorageQueueNameKeys     = []string{"queueName", "queue", "storageAccountQueue"}
 	StorageTableNameKeys     = []string{"tableName", "table", "storageAccountTable"}
 	StorageEndpointKeys      = []string{"endpoint", "storageEndpoint", "storageAccountEndpoint", "queueEndpointUrl"}
+	StorageConnectionStrKeys = []string{"connectionString", "storageConnectionString"}
+	StorageSASTokenKeys      = []string{"sasToken", "storageSasToken", "storageAccountSasToken"}
 )
 
+// StorageAuthMethod is the method used to authenticate with Azure Storage.
+type StorageAuthMethod string
+
+const (
+	StorageAuthAccountKey StorageAuthMethod = "accountKey"
+	StorageAuthSASToken   StorageAuthMethod = "sasToken"
+	StorageAuthAzureAD    StorageAuthMethod = "azureAD"
+)
+
+// Services of Azure Storage, as named in the endpoints.
+const (
+	StorageServiceBlob  = "blob"
+	StorageServiceQueue = "queue"
+	StorageServiceTable = "table"
+)
+
+// Well-known account of the Azure Storage emulator, used with "UseDevelopmentStorage=true".
+const (
+	devStorageAccountName = "devstoreaccount1"
+	devStorageAccountKey  = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+)
+
+// StorageAuth holds the credentials to authenticate with Azure Storage, which are selected by the metadata provided.
+// When several are provided, they are used in order of precedence:
+// 1. Connection string, containing either an account key or a SAS token
+// 2. Account key
+// 3. SAS token
+// 4. Azure AD (via a service principal or MSI)
+type StorageAuth struct {
+	Method      StorageAuthMethod
+	AccountName string
+	AccountKey  string
+	// SAS token, without the leading "?"
+	SASToken string
+
+	// Set when the credentials come from a connection string
+	fromConnectionString bool
+	protocol             string
+	endpointSuffix       string
+	endpoints            map[string]string
+}
+
+// GetStorageAuth returns the credentials to authenticate with Azure Storage from the metadata.
+func GetStorageAuth(metadata map[string]string) (*StorageAuth, error) {
+	auth := &StorageAuth{}
+	auth.AccountName, _ = mdutils.GetMetadataProperty(metadata, StorageAccountNameKeys...)
+
+	if connStr, ok := mdutils.GetMetadataProperty(metadata, StorageConnectionStrKeys...); ok && connStr != "" {
+		accountName := auth.AccountName
+		err := auth.parseConnectionString(connStr)
+		if err != nil {
+			return nil, err
+		}
+		if accountName != "" && auth.AccountName != "" && accountName != auth.AccountName {
+			return nil, fmt.Errorf("the %s field doesn't match the account in the connection string", StorageAccountNameKeys[0])
+		}
+		if auth.AccountName == "" {
+			auth.AccountName = accountName
+		}
+	} else if accountKey, ok := mdutils.GetMetadataProperty(metadata, StorageAccountKeyKeys...); ok && accountKey != "" {
+		auth.Method = StorageAuthAccountKey
+		auth.AccountKey = accountKey
+	} else if sasToken, ok := mdutils.GetMetadataProperty(metadata, StorageSASTokenKeys...); ok && sasToken != "" {
+		auth.Method = StorageAuthSASToken
+		auth.SASToken = strings.TrimPrefix(sasToken, "?")
+	} else {
+		auth.Method = StorageAuthAzureAD
+	}
+
+	if auth.AccountName == "" {
+		return nil, fmt.Errorf("missing or empty %s field from metadata", StorageAccountNameKeys[0])
+	}
+
+	return auth, nil
+}
+
+// parseConnectionString parses a connection string of Azure Storage, as shown in the Azure Portal.
+func (a *StorageAuth) parseConnectionString(connStr string) error {
+	a.fromConnectionString = true
+	a.protocol = "https"
+	a.endpoints = map[string]string{}
+	for _, part := range strings.Split(connStr, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return errors.New("invalid connection string: expected key=value pairs separated by semicolons")
+		}
+
+		switch strings.ToLower(key) {
+		case "accountname":
+			a.AccountName = value
+		case "accountkey":
+			a.AccountKey = value
+		case "sharedaccesssignature":
+			a.SASToken = strings.TrimPrefix(value, "?")
+		case "defaultendpointsprotocol":
+			a.protocol = value
+		case "endpointsuffix":
+			a.endpointSuffix = value
+		case "blobendpoint":
+			a.endpoints[StorageServiceBlob] = strings.TrimSuffix(value, "/")
+		case "queueendpoint":
+			a.endpoints[StorageServiceQueue] = strings.TrimSuffix(value, "/")
+		case "tableendpoint":
+			a.endpoints[StorageServiceTable] = strings.TrimSuffix(value, "/")
+		case "usedevelopmentstorage":
+			if strings.EqualFold(value, "true") {
+				a.AccountName = devStorageAccountName
+				a.AccountKey = devStorageAccountKey
+				a.endpoints[StorageServiceBlob] = "http://127.0.0.1:10000/" + devStorageAccountName
+				a.endpoints[StorageServiceQueue] = "http://127.0.0.1:10001/" + devStorageAccountName
+				a.endpoints[StorageServiceTable] = "http://127.0.0.1:10002/" + devStorageAccountName
+			}
+		}
+	}
+
+	switch {
+	case a.AccountKey != "":
+		a.Method = StorageAuthAccountKey
+	case a.SASToken != "":
+		a.Method = StorageAuthSASToken
+	default:
+		return errors.New("invalid connection string: either AccountKey or SharedAccessSignature must be present")
+	}
+
+	return nil
+}
+
+// ServiceURL returns the URL of a service of the storage account, without the SAS token.
+// The endpoint in the connection string is used if present; otherwise the URL is built from the endpoint suffix, in the
+// connection string or of the Azure environment.
+func (a *StorageAuth) ServiceURL(service string, env *azure.Environment) string {
+	if endpoint, ok := a.endpoints[service]; ok {
+		return endpoint
+	}
+
+	protocol, suffix := "https", ""
+	if env != nil {
+		suffix = env.StorageEndpointSuffix
+	}
+	if a.fromConnectionString {
+		protocol = a.protocol
+		if a.endpointSuffix != "" {
+			suffix = a.endpointSuffix
+		}
+	}
+
+	return fmt.Sprintf("%s://%s.%s.%s", protocol, a.AccountName, service, suffix)
+}
+
+// AddSASToken adds the SAS token to the query string of a URL, when authenticating with it.
+func (a *StorageAuth) AddSASToken(u string) string {
+	if a.Method != StorageAuthSASToken {
+		return u
+	}
+	if strings.Contains(u, "?") {
+		return u + "&" + a.SASToken
+	}
+
+	return u + "?" + a.SASToken
+}
+
 // GetAzureStorageBlobCredentials returns a azblob.Credential object that can be used to authenticate an Azure Blob Storage SDK pipeline ("track 1").
 // First it tries to authenticate using shared key credentials (using an account key) if present. It falls back to attempting to use Azure AD (via a service principal or MSI).
 func GetAzureStorageBlobCredentials(log logger.Logger, accountName string, metadata map[string]string) (azblob.Credential, *azure.Environment, error) {
@@ -79,22 +248,23 @@ func GetAzureStorageBlobCredentials(log logger.Logger, accountName string, metad
 }
 
 // GetAzureStorageQueueCredentials returns a azqueues.Credential object that can be used to authenticate an Azure Queue Storage SDK pipeline ("track 1").
-// First it tries to authenticate using shared key credentials (using an account key) if present. It falls back to attempting to use Azure AD (via a service principal or MSI).
-func GetAzureStorageQueueCredentials(log logger.Logger, accountName string, metadata map[string]string) (azqueue.Credential, *azure.Environment, error) {
+// The credentials are selected as described in StorageAuth. With SAS tokens the credential is anonymous, and the token must be added to the URLs with AddSASToken.
+func GetAzureStorageQueueCredentials(log logger.Logger, auth *StorageAuth, metadata map[string]string) (azqueue.Credential, *azure.Environment, error) {
 	settings, err := NewEnvironmentSettings("storage", metadata)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	// Try using shared key credentials first
-	accountKey, ok := mdutils.GetMetadataProperty(metadata, StorageAccountKeyKeys...)
-	if ok && accountKey != "" {
-		credential, newSharedKeyErr := azqueue.NewSharedKeyCredential(accountName, accountKey)
-		if err != nil {
+	switch auth.Method {
+	case StorageAuthAccountKey:
+		credential, newSharedKeyErr := azqueue.NewSharedKeyCredential(auth.AccountName, auth.AccountKey)
+		if newSharedKeyErr != nil {
 			return nil, nil, fmt.Errorf("invalid credentials with error: %s", newSharedKeyErr.Error())
 		}
 
 		return credential, settings.AzureEnvironment, nil
+	case StorageAuthSASToken:
+		return azqueue.NewAnonymousCredential(), settings.AzureEnvironment, nil
 	}
 
 	// Fallback to using Azure AD
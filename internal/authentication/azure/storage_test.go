@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStorageAuth(t *testing.T) {
+	t.Run("account key", func(t *testing.T) {
+		auth, err := GetStorageAuth(map[string]string{"accountName": "acc", "accountKey": "key", "sasToken": "sv=1"})
+		require.NoError(t, err)
+		assert.Equal(t, StorageAuthAccountKey, auth.Method)
+		assert.Equal(t, "acc", auth.AccountName)
+		assert.Equal(t, "key", auth.AccountKey)
+		assert.Equal(t, "https://acc.queue.core.windows.net", auth.ServiceURL(StorageServiceQueue, &azure.PublicCloud))
+		assert.Equal(t, "https://acc.queue.core.windows.net/q", auth.AddSASToken("https://acc.queue.core.windows.net/q"))
+	})
+
+	t.Run("SAS token", func(t *testing.T) {
+		auth, err := GetStorageAuth(map[string]string{"storageAccount": "acc", "sasToken": "?sv=1&sig=abc"})
+		require.NoError(t, err)
+		assert.Equal(t, StorageAuthSASToken, auth.Method)
+		assert.Equal(t, "sv=1&sig=abc", auth.SASToken)
+		assert.Equal(t, "https://acc.blob.core.chinacloudapi.cn/c?sv=1&sig=abc", auth.AddSASToken(auth.ServiceURL(StorageServiceBlob, &azure.ChinaCloud)+"/c"))
+		assert.Equal(t, "https://acc.blob.core.windows.net/c?comp=list&sv=1&sig=abc", auth.AddSASToken("https://acc.blob.core.windows.net/c?comp=list"))
+	})
+
+	t.Run("Azure AD", func(t *testing.T) {
+		auth, err := GetStorageAuth(map[string]string{"accountName": "acc"})
+		require.NoError(t, err)
+		assert.Equal(t, StorageAuthAzureAD, auth.Method)
+	})
+
+	t.Run("connection string with account key", func(t *testing.T) {
+		auth, err := GetStorageAuth(map[string]string{
+			"connectionString": "DefaultEndpointsProtocol=https;AccountName=acc;AccountKey=key;EndpointSuffix=core.usgovcloudapi.net",
+			"sasToken":         "sv=1",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, StorageAuthAccountKey, auth.Method)
+		assert.Equal(t, "acc", auth.AccountName)
+		assert.Equal(t, "key", auth.AccountKey)
+		assert.Equal(t, "https://acc.table.core.usgovcloudapi.net", auth.ServiceURL(StorageServiceTable, &azure.PublicCloud))
+	})
+
+	t.Run("connection string with SAS token and endpoints", func(t *testing.T) {
+		auth, err := GetStorageAuth(map[string]string{
+			"accountName":      "acc",
+			"connectionString": "BlobEndpoint=https://acc.blob.example.com/;SharedAccessSignature=sv=1&sig=abc",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, StorageAuthSASToken, auth.Method)
+		assert.Equal(t, "acc", auth.AccountName)
+		assert.Equal(t, "https://acc.blob.example.com", auth.ServiceURL(StorageServiceBlob, &azure.PublicCloud))
+		assert.Equal(t, "https://acc.queue.core.windows.net", auth.ServiceURL(StorageServiceQueue, &azure.PublicCloud))
+	})
+
+	t.Run("development storage", func(t *testing.T) {
+		auth, err := GetStorageAuth(map[string]string{"storageConnectionString": "UseDevelopmentStorage=true"})
+		require.NoError(t, err)
+		assert.Equal(t, StorageAuthAccountKey, auth.Method)
+		assert.Equal(t, "devstoreaccount1", auth.AccountName)
+		assert.Equal(t, "http://127.0.0.1:10001/devstoreaccount1", auth.ServiceURL(StorageServiceQueue, nil))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := GetStorageAuth(map[string]string{"accountKey": "key"})
+		assert.ErrorContains(t, err, "missing or empty accountName field from metadata")
+
+		_, err = GetStorageAuth(map[string]string{"connectionString": "AccountName=acc"})
+		assert.ErrorContains(t, err, "either AccountKey or SharedAccessSignature must be present")
+
+		_, err = GetStorageAuth(map[string]string{"connectionString": "AccountName=acc;AccountKey"})
+		assert.ErrorContains(t, err, "invalid connection string")
+
+		_, err = GetStorageAuth(map[string]string{"accountName": "other", "connectionString": "AccountName=acc;AccountKey=key"})
+		assert.ErrorContains(t, err, "doesn't match the account in the connection string")
+	})
+}
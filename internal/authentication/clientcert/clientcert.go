@@ -0,0 +1,294 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package clientcert provides the client certificates of the components authenticating with mTLS, obtained from
+// files or from a SPIFFE Workload API, and rotated when they are renewed.
+package clientcert
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	mdutils "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// SourceFiles reads the certificates from PEM files, which are reloaded when they change.
+	SourceFiles = "files"
+	// SourceSPIFFE fetches the X.509 SVIDs from a SPIFFE Workload API.
+	SourceSPIFFE = "spiffe"
+
+	// Environment variable of the address of the SPIFFE Workload API, such as unix:///run/spire/sockets/agent.sock.
+	spiffeEndpointSocketEnvVar = "SPIFFE_ENDPOINT_SOCKET"
+
+	defaultRefreshInterval = time.Minute
+	defaultFetchTimeout    = 30 * time.Second
+)
+
+// Metadata is the configuration of the client certificates in the metadata of the components.
+type Metadata struct {
+	// Source of the client certificates: files or spiffe. The components keep their own certificates when empty.
+	ClientCertSource string `mapstructure:"clientCertSource"`
+
+	// Paths of the PEM files of the files source.
+	ClientCertFile string `mapstructure:"clientCertFile"`
+	ClientKeyFile  string `mapstructure:"clientKeyFile"`
+	// Bundle of the CA certificates the server certificates are verified with, optional.
+	CACertFile string `mapstructure:"caCertFile"`
+	// Interval at which the files are checked for changes.
+	ClientCertRefreshInterval time.Duration `mapstructure:"clientCertRefreshInterval"`
+
+	// Address of the SPIFFE Workload API, the SPIFFE_ENDPOINT_SOCKET environment variable by default.
+	SPIFFEEndpointSocket string `mapstructure:"spiffeEndpointSocket"`
+	// SPIFFE ID of the SVID used, the first SVID of the workload by default.
+	SPIFFEID string `mapstructure:"spiffeID"`
+	// Maximum time waited for the first SVID.
+	SPIFFEFetchTimeout time.Duration `mapstructure:"spiffeFetchTimeout"`
+}
+
+// MetadataSchema returns the schema of the client certificate metadata, for the components using a Source.
+func MetadataSchema() []mdutils.MetadataField {
+	fields, _ := mdutils.GetMetadataSchemaFromStruct(Metadata{
+		ClientCertRefreshInterval: defaultRefreshInterval,
+		SPIFFEFetchTimeout:        defaultFetchTimeout,
+	})
+	return fields
+}
+
+// Source provides the current client certificate of a component, and the CA certificates of its source if any.
+type Source struct {
+	logger logger.Logger
+
+	cert  *tls.Certificate
+	roots *x509.CertPool
+	lock  sync.RWMutex
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New returns the source of the client certificates configured in the metadata properties.
+// It returns nil if no source is configured, to let the components keep their own certificates.
+func New(properties map[string]string, logger logger.Logger) (*Source, error) {
+	m := Metadata{
+		ClientCertRefreshInterval: defaultRefreshInterval,
+		SPIFFEFetchTimeout:        defaultFetchTimeout,
+	}
+	err := mdutils.DecodeMetadata(properties, &m)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client certificate metadata: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Source{logger: logger, cancel: cancel}
+
+	switch strings.ToLower(m.ClientCertSource) {
+	case "":
+		cancel()
+		return nil, nil
+	case SourceFiles:
+		err = s.watchFiles(ctx, m)
+	case SourceSPIFFE:
+		err = s.watchSPIFFE(ctx, m)
+	default:
+		err = fmt.Errorf("invalid clientCertSource %s, it must be %s or %s", m.ClientCertSource, SourceFiles, SourceSPIFFE)
+	}
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// GetClientCertificate returns the current client certificate, as the tls.Config.GetClientCertificate callback.
+func (s *Source) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.cert == nil {
+		return nil, errors.New("no client certificate available")
+	}
+	return s.cert, nil
+}
+
+// TLSConfig returns a copy of the config presenting the client certificates of the source.
+// If the source has CA certificates and the config doesn't have its own, the server certificates are verified with
+// the current CA certificates of the source.
+func (s *Source) TLSConfig(config *tls.Config) *tls.Config {
+	if config == nil {
+		config = &tls.Config{MinVersion: tls.VersionTLS12}
+	} else {
+		config = config.Clone()
+	}
+	config.Certificates = nil
+	config.GetClientCertificate = s.GetClientCertificate
+
+	if s.currentRoots() != nil && config.RootCAs == nil && !config.InsecureSkipVerify {
+		// The CA certificates are rotated with the client certificates, so they're read on each handshake
+		serverName := config.ServerName
+		config.InsecureSkipVerify = true //nolint:gosec
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			return s.verifyConnection(state, serverName)
+		}
+	}
+
+	return config
+}
+
+// Close stops the rotation of the certificates.
+func (s *Source) Close() error {
+	s.cancel()
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Source) set(cert *tls.Certificate, roots *x509.CertPool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.cert = cert
+	s.roots = roots
+}
+
+func (s *Source) currentRoots() *x509.CertPool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.roots
+}
+
+// verifyConnection verifies the server certificate with the current CA certificates, and its name with the server name
+// of the config, or the one derived from the address of the server. No name, as when connecting to an IP address,
+// is an error rather than accepting any name.
+func (s *Source) verifyConnection(state tls.ConnectionState, serverName string) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("no server certificate")
+	}
+	if serverName == "" {
+		serverName = state.ServerName
+	}
+	if serverName == "" {
+		return errors.New("the server name is required to verify the server certificate, it must be set in the TLS config")
+	}
+
+	opts := x509.VerifyOptions{
+		DNSName:       serverName,
+		Roots:         s.currentRoots(),
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range state.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := state.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// watchFiles loads the certificates from the files, and reloads them when the files change.
+func (s *Source) watchFiles(ctx context.Context, m Metadata) error {
+	if m.ClientCertFile == "" || m.ClientKeyFile == "" {
+		return errors.New("clientCertFile and clientKeyFile are required with the files client certificate source")
+	}
+	if m.ClientCertRefreshInterval <= 0 {
+		return errors.New("clientCertRefreshInterval must be positive")
+	}
+
+	files := []string{m.ClientCertFile, m.ClientKeyFile}
+	if m.CACertFile != "" {
+		files = append(files, m.CACertFile)
+	}
+	version, err := filesVersion(files)
+	if err == nil {
+		err = s.loadFiles(m)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(m.ClientCertRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current, err := filesVersion(files)
+			if err != nil {
+				s.logger.Warnf("failed to check the client certificate files: %v", err)
+				continue
+			}
+			if current == version {
+				continue
+			}
+			// The previous certificates are kept until the files are consistent, as they may be written one by one
+			err = s.loadFiles(m)
+			if err != nil {
+				s.logger.Warnf("failed to reload the client certificate files: %v", err)
+				continue
+			}
+			version = current
+			s.logger.Info("reloaded the client certificate files")
+		}
+	}()
+
+	return nil
+}
+
+func (s *Source) loadFiles(m Metadata) error {
+	cert, err := tls.LoadX509KeyPair(m.ClientCertFile, m.ClientKeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load the client certificate: %w", err)
+	}
+
+	var roots *x509.CertPool
+	if m.CACertFile != "" {
+		pem, err := os.ReadFile(m.CACertFile)
+		if err != nil {
+			return fmt.Errorf("failed to read the CA certificates: %w", err)
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no CA certificates found in %s", m.CACertFile)
+		}
+	}
+
+	s.set(&cert, roots)
+	return nil
+}
+
+// filesVersion returns a string changing with the modification times and the sizes of the files.
+func filesVersion(files []string) (string, error) {
+	var version strings.Builder
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&version, "%d:%d;", info.ModTime().UnixNano(), info.Size())
+	}
+	return version.String(), nil
+}
@@ -0,0 +1,377 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clientcert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/dapr/kit/logger"
+)
+
+var testLogger = logger.NewLogger("test")
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, name string, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		URIs:         []*url.URL{{Scheme: "spiffe", Host: "example.org", Path: "/" + name}},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		template.ExtKeyUsage = nil
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCert{cert: cert, key: key}
+}
+
+func (c *testCert) writeFiles(t *testing.T, certFile, keyFile string) {
+	t.Helper()
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(c.key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600))
+}
+
+func leafName(t *testing.T, s *Source) string {
+	t.Helper()
+
+	cert, err := s.GetClientCertificate(nil)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return leaf.Subject.CommonName
+}
+
+func TestNew(t *testing.T) {
+	t.Run("no source", func(t *testing.T) {
+		s, err := New(map[string]string{}, testLogger)
+
+		require.NoError(t, err)
+		assert.Nil(t, s)
+	})
+
+	t.Run("invalid source", func(t *testing.T) {
+		_, err := New(map[string]string{"clientCertSource": "vault"}, testLogger)
+
+		assert.ErrorContains(t, err, "invalid clientCertSource vault")
+	})
+
+	t.Run("missing files", func(t *testing.T) {
+		_, err := New(map[string]string{"clientCertSource": "files", "clientCertFile": "cert.pem"}, testLogger)
+
+		assert.ErrorContains(t, err, "clientCertFile and clientKeyFile are required")
+	})
+
+	t.Run("missing SPIFFE endpoint", func(t *testing.T) {
+		t.Setenv(spiffeEndpointSocketEnvVar, "")
+
+		_, err := New(map[string]string{"clientCertSource": "spiffe"}, testLogger)
+
+		assert.ErrorContains(t, err, "spiffeEndpointSocket or the SPIFFE_ENDPOINT_SOCKET environment variable is required")
+	})
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), filepath.Join(dir, "ca.pem")
+	ca := newTestCert(t, "ca", nil)
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0o600))
+	newTestCert(t, "first", ca).writeFiles(t, certFile, keyFile)
+
+	s, err := New(map[string]string{
+		"clientCertSource":          "files",
+		"clientCertFile":            certFile,
+		"clientKeyFile":             keyFile,
+		"caCertFile":                caFile,
+		"clientCertRefreshInterval": "10ms",
+	}, testLogger)
+	require.NoError(t, err)
+	defer s.Close()
+
+	assert.Equal(t, "first", leafName(t, s))
+	assert.NotNil(t, s.currentRoots())
+
+	newTestCert(t, "second", ca).writeFiles(t, certFile, keyFile)
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	assert.Eventually(t, func() bool {
+		return leafName(t, s) == "second"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	newTestCert(t, "client", newTestCert(t, "ca", nil)).writeFiles(t, certFile, keyFile)
+
+	s, err := New(map[string]string{"clientCertSource": "files", "clientCertFile": certFile, "clientKeyFile": keyFile}, testLogger)
+	require.NoError(t, err)
+	defer s.Close()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	base := server.Client().Transport.(*http.Transport).TLSClientConfig
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: s.TLSConfig(base)}}
+	res, err := client.Get(server.URL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	var body [16]byte
+	n, _ := res.Body.Read(body[:])
+	assert.Equal(t, "client", string(body[:n]))
+	assert.Nil(t, base.GetClientCertificate)
+}
+
+func encodeX509SVIDResponse(t *testing.T, ca *testCert, svids ...*testCert) []byte {
+	t.Helper()
+
+	var msg []byte
+	for _, svid := range svids {
+		key, err := x509.MarshalPKCS8PrivateKey(svid.key)
+		require.NoError(t, err)
+
+		var m []byte
+		m = protowire.AppendTag(m, 1, protowire.BytesType)
+		m = protowire.AppendString(m, svid.cert.URIs[0].String())
+		m = protowire.AppendTag(m, 2, protowire.BytesType)
+		m = protowire.AppendBytes(m, svid.cert.Raw)
+		m = protowire.AppendTag(m, 3, protowire.BytesType)
+		m = protowire.AppendBytes(m, key)
+		m = protowire.AppendTag(m, 4, protowire.BytesType)
+		m = protowire.AppendBytes(m, ca.cert.Raw)
+
+		msg = protowire.AppendTag(msg, 1, protowire.BytesType)
+		msg = protowire.AppendBytes(msg, m)
+	}
+	// Unknown fields are skipped
+	msg = protowire.AppendTag(msg, 5, protowire.VarintType)
+	msg = protowire.AppendVarint(msg, 1)
+
+	return msg
+}
+
+// startWorkloadAPI starts a SPIFFE Workload API sending the responses, and returns its address.
+func startWorkloadAPI(t *testing.T, responses chan []byte) string {
+	t.Helper()
+
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	lis, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	server := grpc.NewServer(grpc.ForceServerCodec(rawCodec{}), grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		method, _ := grpc.MethodFromServerStream(stream)
+		assert.Equal(t, fetchX509SVIDMethod, method)
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		assert.Equal(t, []string{"true"}, md.Get(workloadAPIHeader))
+
+		var req []byte
+		if err := stream.RecvMsg(&req); err != nil {
+			return err
+		}
+		for {
+			select {
+			case <-stream.Context().Done():
+				return nil
+			case res := <-responses:
+				if err := stream.SendMsg(res); err != nil {
+					return err
+				}
+			}
+		}
+	}))
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	return "unix://" + socket
+}
+
+func TestSPIFFE(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+
+	t.Run("first SVID", func(t *testing.T) {
+		responses := make(chan []byte, 1)
+		responses <- encodeX509SVIDResponse(t, ca, newTestCert(t, "first", ca), newTestCert(t, "db", ca))
+
+		s, err := New(map[string]string{"clientCertSource": "spiffe", "spiffeEndpointSocket": startWorkloadAPI(t, responses)}, testLogger)
+		require.NoError(t, err)
+		defer s.Close()
+
+		assert.Equal(t, "first", leafName(t, s))
+		assert.NotNil(t, s.currentRoots())
+	})
+
+	t.Run("SVID with the SPIFFE ID and renewal", func(t *testing.T) {
+		responses := make(chan []byte, 1)
+		responses <- encodeX509SVIDResponse(t, ca, newTestCert(t, "first", ca), newTestCert(t, "db", ca))
+		t.Setenv(spiffeEndpointSocketEnvVar, startWorkloadAPI(t, responses))
+
+		s, err := New(map[string]string{"clientCertSource": "spiffe", "spiffeID": "spiffe://example.org/db"}, testLogger)
+		require.NoError(t, err)
+		defer s.Close()
+
+		assert.Equal(t, "db", leafName(t, s))
+
+		renewed := newTestCert(t, "db", ca)
+		responses <- encodeX509SVIDResponse(t, ca, renewed)
+		assert.Eventually(t, func() bool {
+			cert, _ := s.GetClientCertificate(nil)
+			return cert.Leaf.Equal(renewed.cert)
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("invalid SPIFFE ID", func(t *testing.T) {
+		_, err := New(map[string]string{
+			"clientCertSource":     "spiffe",
+			"spiffeEndpointSocket": startWorkloadAPI(t, make(chan []byte)),
+			"spiffeID":             "https://example.org/db",
+		}, testLogger)
+
+		assert.ErrorContains(t, err, "invalid spiffeID")
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		_, err := New(map[string]string{
+			"clientCertSource":     "spiffe",
+			"spiffeEndpointSocket": startWorkloadAPI(t, make(chan []byte)),
+			"spiffeFetchTimeout":   "100ms",
+		}, testLogger)
+
+		assert.ErrorContains(t, err, "timed out waiting for the X.509 SVID")
+	})
+}
+
+func TestParseX509SVID(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	svid := newTestCert(t, "db", ca)
+	key, err := x509.MarshalPKCS8PrivateKey(svid.key)
+	require.NoError(t, err)
+	otherKey, err := x509.MarshalPKCS8PrivateKey(newTestCert(t, "db", ca).key)
+	require.NoError(t, err)
+
+	cert, roots, err := parseX509SVID("spiffe://example.org/db", svid.cert.Raw, key, ca.cert.Raw)
+	require.NoError(t, err)
+	assert.True(t, cert.Leaf.Equal(svid.cert))
+	assert.NotNil(t, roots)
+
+	tests := map[string]struct {
+		id     string
+		chain  []byte
+		key    []byte
+		bundle []byte
+		err    string
+	}{
+		"not a SPIFFE ID":     {id: "https://example.org/db", chain: svid.cert.Raw, key: key, bundle: ca.cert.Raw, err: "the scheme must be spiffe"},
+		"SPIFFE ID with port": {id: "spiffe://example.org:8080/db", chain: svid.cert.Raw, key: key, bundle: ca.cert.Raw, err: "the trust domain must be a host name"},
+		"other SPIFFE ID":     {id: "spiffe://example.org/other", chain: svid.cert.Raw, key: key, bundle: ca.cert.Raw, err: "as only URI SAN"},
+		"CA certificate":      {id: "spiffe://example.org/ca", chain: ca.cert.Raw, key: key, bundle: ca.cert.Raw, err: "must not be a CA"},
+		"other private key":   {id: "spiffe://example.org/db", chain: svid.cert.Raw, key: otherKey, bundle: ca.cert.Raw, err: "doesn't match the certificate"},
+		"no bundle":           {id: "spiffe://example.org/db", chain: svid.cert.Raw, key: key, err: "no bundle"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := parseX509SVID(tt.id, tt.chain, tt.key, tt.bundle)
+
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestVerifyConnection(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	s := &Source{}
+	s.set(nil, x509.NewCertPool())
+	s.roots.AddCert(ca.cert)
+
+	// Server certificate for localhost
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	server, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	config := s.TLSConfig(nil)
+	state := tls.ConnectionState{PeerCertificates: []*x509.Certificate{server}}
+
+	t.Run("server name of the connection", func(t *testing.T) {
+		state.ServerName = "localhost"
+		assert.NoError(t, config.VerifyConnection(state))
+		state.ServerName = "other"
+		assert.Error(t, config.VerifyConnection(state))
+	})
+
+	t.Run("server name of the config", func(t *testing.T) {
+		state.ServerName = ""
+		assert.NoError(t, s.TLSConfig(&tls.Config{ServerName: "localhost", MinVersion: tls.VersionTLS12}).VerifyConnection(state))
+	})
+
+	t.Run("no server name", func(t *testing.T) {
+		state.ServerName = ""
+		assert.ErrorContains(t, config.VerifyConnection(state), "the server name is required")
+	})
+}
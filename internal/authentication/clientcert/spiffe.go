@@ -0,0 +1,343 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clientcert
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	// Method of the SPIFFE Workload API streaming the X.509 SVIDs of the workload, and their updates.
+	fetchX509SVIDMethod = "/SpiffeWorkloadAPI/FetchX509SVID"
+	// Header required by the SPIFFE Workload API in all the requests.
+	workloadAPIHeader = "workload.spiffe.io"
+
+	spiffeRetryInterval = 5 * time.Second
+)
+
+// watchSPIFFE fetches the X.509 SVIDs from the SPIFFE Workload API, and keeps receiving their renewals.
+func (s *Source) watchSPIFFE(ctx context.Context, m Metadata) error {
+	address := m.SPIFFEEndpointSocket
+	if address == "" {
+		address = os.Getenv(spiffeEndpointSocketEnvVar)
+	}
+	if address == "" {
+		return fmt.Errorf("spiffeEndpointSocket or the %s environment variable is required with the spiffe client certificate source", spiffeEndpointSocketEnvVar)
+	}
+	if m.SPIFFEFetchTimeout <= 0 {
+		return errors.New("spiffeFetchTimeout must be positive")
+	}
+	if m.SPIFFEID != "" {
+		if _, err := parseSPIFFEID(m.SPIFFEID); err != nil {
+			return fmt.Errorf("invalid spiffeID: %w", err)
+		}
+	}
+
+	conn, err := grpc.DialContext(ctx, address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("failed to connect to the SPIFFE Workload API at %s: %w", address, err)
+	}
+
+	received := make(chan struct{})
+	var receivedOnce sync.Once
+	// Last error before the first SVID is received
+	errs := make(chan error, 1)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer conn.Close()
+
+		for {
+			err := s.receiveSVIDs(ctx, conn, m.SPIFFEID, func() {
+				receivedOnce.Do(func() { close(received) })
+			})
+			if ctx.Err() != nil {
+				return
+			}
+			s.logger.Warnf("failed to receive the X.509 SVIDs from the SPIFFE Workload API, retrying in %v: %v", spiffeRetryInterval, err)
+			select {
+			case <-errs:
+			default:
+			}
+			errs <- err
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(spiffeRetryInterval):
+			}
+		}
+	}()
+
+	select {
+	case <-received:
+		return nil
+	case <-time.After(m.SPIFFEFetchTimeout):
+		select {
+		case err = <-errs:
+			return fmt.Errorf("timed out waiting for the X.509 SVID from the SPIFFE Workload API: %w", err)
+		default:
+			return errors.New("timed out waiting for the X.509 SVID from the SPIFFE Workload API")
+		}
+	}
+}
+
+// receiveSVIDs receives the X.509 SVIDs of the workload until the stream fails.
+func (s *Source) receiveSVIDs(ctx context.Context, conn *grpc.ClientConn, spiffeID string, onReceived func()) error {
+	ctx, cancel := context.WithCancel(metadata.AppendToOutgoingContext(ctx, workloadAPIHeader, "true"))
+	defer cancel()
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, fetchX509SVIDMethod, grpc.ForceCodec(rawCodec{}))
+	if err != nil {
+		return err
+	}
+	// The X509SVIDRequest is an empty message
+	err = stream.SendMsg([]byte{})
+	if err != nil {
+		return err
+	}
+	err = stream.CloseSend()
+	if err != nil {
+		return err
+	}
+
+	for {
+		var msg []byte
+		err = stream.RecvMsg(&msg)
+		if err != nil {
+			return err
+		}
+
+		cert, roots, err := parseX509SVIDResponse(msg, spiffeID)
+		if err != nil {
+			s.logger.Warnf("invalid X.509 SVID received from the SPIFFE Workload API: %v", err)
+			continue
+		}
+		s.set(cert, roots)
+		s.logger.Debugf("received the X.509 SVID %s from the SPIFFE Workload API", cert.Leaf.URIs)
+		onReceived()
+	}
+}
+
+// parseX509SVIDResponse returns the certificate of the SVID with the SPIFFE ID, or of the first SVID if spiffeID is
+// empty, and the CA certificates of its trust domain. The X509SVIDResponse message is:
+//
+//	message X509SVIDResponse {
+//	  repeated X509SVID svids = 1;
+//	  ...
+//	}
+//
+//	message X509SVID {
+//	  string spiffe_id = 1;
+//	  bytes x509_svid = 2;     // ASN.1 DER certificates, the leaf first
+//	  bytes x509_svid_key = 3; // ASN.1 DER PKCS#8 private key
+//	  bytes bundle = 4;        // ASN.1 DER CA certificates
+//	  ...
+//	}
+func parseX509SVIDResponse(msg []byte, spiffeID string) (*tls.Certificate, *x509.CertPool, error) {
+	var found bool
+	var id string
+	var chain, key, bundle []byte
+	err := parseFields(msg, func(num protowire.Number, value []byte) error {
+		if num != 1 || found {
+			return nil
+		}
+
+		var svidID string
+		var svidChain, svidKey, svidBundle []byte
+		err := parseFields(value, func(num protowire.Number, value []byte) error {
+			switch num {
+			case 1:
+				svidID = string(value)
+			case 2:
+				svidChain = value
+			case 3:
+				svidKey = value
+			case 4:
+				svidBundle = value
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if spiffeID == "" || svidID == spiffeID {
+			found = true
+			id, chain, key, bundle = svidID, svidChain, svidKey, svidBundle
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if !found {
+		if spiffeID != "" {
+			return nil, nil, fmt.Errorf("no X.509 SVID with the SPIFFE ID %s", spiffeID)
+		}
+		return nil, nil, errors.New("no X.509 SVID")
+	}
+
+	return parseX509SVID(id, chain, key, bundle)
+}
+
+// parseX509SVID returns the certificate of an X.509 SVID and the CA certificates of its trust domain, verifying the
+// SVID as required by the X509-SVID specification: the leaf certificate has the SPIFFE ID of the SVID as only URI SAN,
+// is not a CA, and matches the private key.
+func parseX509SVID(id string, chain, key, bundle []byte) (*tls.Certificate, *x509.CertPool, error) {
+	spiffeID, err := parseSPIFFEID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certs, err := x509.ParseCertificates(chain)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid certificates: %w", err)
+	}
+	if len(certs) == 0 {
+		return nil, nil, errors.New("no certificates")
+	}
+	leaf := certs[0]
+	if len(leaf.URIs) != 1 || leaf.URIs[0].String() != spiffeID.String() {
+		return nil, nil, fmt.Errorf("the certificate must have the SPIFFE ID %s as only URI SAN", id)
+	}
+	if leaf.IsCA || leaf.KeyUsage&(x509.KeyUsageCertSign|x509.KeyUsageCRLSign) != 0 {
+		return nil, nil, errors.New("the certificate must not be a CA")
+	}
+	if leaf.KeyUsage&x509.KeyUsageDigitalSignature == 0 {
+		return nil, nil, errors.New("the certificate must have the digital signature key usage")
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported private key type %T", privateKey)
+	}
+	if publicKey, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool }); !ok || !publicKey.Equal(leaf.PublicKey) {
+		return nil, nil, errors.New("the private key doesn't match the certificate")
+	}
+
+	cert := &tls.Certificate{PrivateKey: privateKey, Leaf: leaf}
+	for _, c := range certs {
+		cert.Certificate = append(cert.Certificate, c.Raw)
+	}
+
+	if len(bundle) == 0 {
+		return nil, nil, errors.New("no bundle")
+	}
+	cas, err := x509.ParseCertificates(bundle)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	roots := x509.NewCertPool()
+	for _, ca := range cas {
+		roots.AddCert(ca)
+	}
+
+	return cert, roots, nil
+}
+
+// parseSPIFFEID parses a SPIFFE ID: spiffe://<trust domain>/<path>, without port, user info, query or fragment.
+func parseSPIFFEID(id string) (*url.URL, error) {
+	u, err := url.Parse(id)
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("invalid SPIFFE ID %s: %w", id, err)
+	case u.Scheme != "spiffe":
+		return nil, fmt.Errorf("invalid SPIFFE ID %s: the scheme must be spiffe", id)
+	case u.Host == "" || u.Port() != "" || u.User != nil:
+		return nil, fmt.Errorf("invalid SPIFFE ID %s: the trust domain must be a host name", id)
+	case u.RawQuery != "" || u.Fragment != "" || u.Opaque != "":
+		return nil, fmt.Errorf("invalid SPIFFE ID %s: no query or fragment allowed", id)
+	case u.Host != strings.ToLower(u.Host):
+		return nil, fmt.Errorf("invalid SPIFFE ID %s: the trust domain must be lowercase", id)
+	}
+
+	return u, nil
+}
+
+// parseFields calls fn with the length-delimited fields of a protobuf message, skipping the others.
+func parseFields(msg []byte, fn func(num protowire.Number, value []byte) error) error {
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		msg = msg[n:]
+
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, msg)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			msg = msg[n:]
+			continue
+		}
+
+		value, n := protowire.ConsumeBytes(msg)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		msg = msg[n:]
+		err := fn(num, value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rawCodec sends and receives the messages as the bytes of their protobuf encoding.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+	return b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+// Name is the name of the proto codec, for the content type of the messages.
+func (rawCodec) Name() string {
+	return "proto"
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcp
+
+import (
+	"encoding/json"
+	"errors"
+
+	"google.golang.org/api/option"
+)
+
+const serviceAccountType = "service_account"
+
+// ServiceAccountKey is the key of a service account, with the fields of the JSON key files created by GCP.
+type ServiceAccountKey struct {
+	Type                string `json:"type"`
+	ProjectID           string `json:"project_id"`
+	PrivateKeyID        string `json:"private_key_id"`
+	PrivateKey          string `json:"private_key"`
+	ClientEmail         string `json:"client_email"`
+	ClientID            string `json:"client_id"`
+	AuthURI             string `json:"auth_uri"`
+	TokenURI            string `json:"token_uri"`
+	AuthProviderCertURL string `json:"auth_provider_x509_cert_url"`
+	ClientCertURL       string `json:"client_x509_cert_url"`
+}
+
+// KeyFromJSON reads the service account key from a JSON object, such as the marshalled metadata properties of a
+// component, ignoring the other fields.
+func KeyFromJSON(b []byte) (ServiceAccountKey, error) {
+	var key ServiceAccountKey
+	err := json.Unmarshal(b, &key)
+
+	return key, err
+}
+
+// ClientOptions returns the options authenticating the clients of the GCP services.
+// The service account key is optional: without private key, the clients use the Application Default Credentials,
+// found from the GOOGLE_APPLICATION_CREDENTIALS environment variable, the gcloud configuration, or the metadata
+// server, which provides the service account bound to the pod with GKE Workload Identity.
+func ClientOptions(key ServiceAccountKey) ([]option.ClientOption, error) {
+	if key.PrivateKey == "" {
+		return nil, nil
+	}
+	if key.ClientEmail == "" {
+		return nil, errors.New("client_email is required with private_key")
+	}
+	if key.Type == "" {
+		key.Type = serviceAccountType
+	}
+
+	b, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return []option.ClientOption{option.WithCredentialsJSON(b)}, nil
+}
+
+// UsesDefaultCredentials returns true if the clients are authenticated with the Application Default Credentials.
+func UsesDefaultCredentials(key ServiceAccountKey) bool {
+	return key.PrivateKey == ""
+}
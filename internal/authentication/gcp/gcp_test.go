@@ -0,0 +1,54 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyFromJSON(t *testing.T) {
+	key, err := KeyFromJSON([]byte(`{"project_id": "p", "private_key": "k", "client_email": "me@p.iam.gserviceaccount.com", "topic": "t"}`))
+
+	require.NoError(t, err)
+	assert.Equal(t, ServiceAccountKey{ProjectID: "p", PrivateKey: "k", ClientEmail: "me@p.iam.gserviceaccount.com"}, key)
+}
+
+func TestClientOptions(t *testing.T) {
+	t.Run("default credentials without private key", func(t *testing.T) {
+		key := ServiceAccountKey{ProjectID: "p"}
+		opts, err := ClientOptions(key)
+
+		require.NoError(t, err)
+		assert.Empty(t, opts)
+		assert.True(t, UsesDefaultCredentials(key))
+	})
+
+	t.Run("service account key", func(t *testing.T) {
+		key := ServiceAccountKey{PrivateKey: "k", ClientEmail: "me@p.iam.gserviceaccount.com"}
+		opts, err := ClientOptions(key)
+
+		require.NoError(t, err)
+		assert.Len(t, opts, 1)
+		assert.False(t, UsesDefaultCredentials(key))
+	})
+
+	t.Run("private key without client email", func(t *testing.T) {
+		_, err := ClientOptions(ServiceAccountKey{PrivateKey: "k"})
+
+		assert.Error(t, err)
+	})
+}
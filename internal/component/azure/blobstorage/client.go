@@ -23,6 +23,7 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
 
 	azauth "github.com/dapr/components-contrib/internal/authentication/azure"
 	mdutils "github.com/dapr/components-contrib/metadata"
@@ -41,6 +42,7 @@ func CreateContainerStorageClient(log logger.Logger, meta map[string]string) (*c
 		return nil, nil, err
 	}
 
+	m.rewritePolicy = newRewritePolicy()
 	userAgent := "dapr-" + logger.DaprVersion
 	options := container.ClientOptions{
 		ClientOptions: azcore.ClientOptions{
@@ -50,6 +52,7 @@ func CreateContainerStorageClient(log logger.Logger, meta map[string]string) (*c
 			Telemetry: policy.TelemetryOptions{
 				ApplicationID: userAgent,
 			},
+			PerCallPolicies: []policy.Policy{m.rewritePolicy},
 		},
 	}
 
@@ -57,38 +60,26 @@ func CreateContainerStorageClient(log logger.Logger, meta map[string]string) (*c
 	if err != nil {
 		return nil, nil, err
 	}
-	var customEndpoint string
-	if val, ok := mdutils.GetMetadataProperty(meta, azauth.StorageEndpointKeys...); ok && val != "" {
-		customEndpoint = val
-	}
-	var URL *url.URL
-	if customEndpoint != "" {
-		var parseErr error
-		URL, parseErr = url.Parse(fmt.Sprintf("%s/%s/%s", customEndpoint, m.AccountName, m.ContainerName))
-		if parseErr != nil {
-			return nil, nil, parseErr
-		}
-	} else {
-		env := settings.AzureEnvironment
-		URL, _ = url.Parse(fmt.Sprintf("https://%s.blob.%s/%s", m.AccountName, env.StorageEndpointSuffix, m.ContainerName))
+	URL, err := url.Parse(blobServiceURL(meta, m, settings) + "/" + m.ContainerName)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	var clientErr error
 	var client *container.Client
-	// Try using shared key credentials first
-	if m.AccountKey != "" {
+	switch m.auth.Method {
+	case azauth.StorageAuthAccountKey:
 		credential, newSharedKeyErr := azblob.NewSharedKeyCredential(m.AccountName, m.AccountKey)
-		if err != nil {
+		if newSharedKeyErr != nil {
 			return nil, nil, fmt.Errorf("invalid shared key credentials with error: %w", newSharedKeyErr)
 		}
 		client, clientErr = container.NewClientWithSharedKeyCredential(URL.String(), credential, &options)
-		if clientErr != nil {
-			return nil, nil, fmt.Errorf("cannot init Blobstorage container client: %w", err)
-		}
-	} else {
+	case azauth.StorageAuthSASToken:
+		client, clientErr = container.NewClientWithNoCredential(m.auth.AddSASToken(URL.String()), &options)
+	default:
 		// fallback to AAD
 		credential, tokenErr := settings.GetTokenCredential()
-		if err != nil {
+		if tokenErr != nil {
 			return nil, nil, fmt.Errorf("invalid token credentials with error: %w", tokenErr)
 		}
 		client, clientErr = container.NewClient(URL.String(), credential, &options)
@@ -109,3 +100,46 @@ func CreateContainerStorageClient(log logger.Logger, meta map[string]string) (*c
 
 	return client, m, nil
 }
+
+// CreateServiceStorageClient returns a client of the Blob service of the storage account, when the component is
+// authenticated with Azure AD, to get the user delegation keys signing the SAS of the blobs. It returns nil when the
+// component is authenticated with the account key or a SAS token.
+func CreateServiceStorageClient(meta map[string]string) (*service.Client, error) {
+	m, err := parseMetadata(meta)
+	if err != nil {
+		return nil, err
+	}
+	if m.auth.Method != azauth.StorageAuthAzureAD {
+		return nil, nil
+	}
+
+	settings, err := azauth.NewEnvironmentSettings("storage", meta)
+	if err != nil {
+		return nil, err
+	}
+	credential, err := settings.GetTokenCredential()
+	if err != nil {
+		return nil, fmt.Errorf("invalid token credentials with error: %w", err)
+	}
+	options := service.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			Retry: policy.RetryOptions{
+				MaxRetries: m.RetryCount,
+			},
+			Telemetry: policy.TelemetryOptions{
+				ApplicationID: "dapr-" + logger.DaprVersion,
+			},
+		},
+	}
+
+	return service.NewClient(blobServiceURL(meta, m, settings), credential, &options)
+}
+
+// blobServiceURL returns the URL of the Blob service of the storage account, or of the custom endpoint.
+func blobServiceURL(meta map[string]string, m *BlobStorageMetadata, settings azauth.EnvironmentSettings) string {
+	if val, ok := mdutils.GetMetadataProperty(meta, azauth.StorageEndpointKeys...); ok && val != "" {
+		return val + "/" + m.AccountName
+	}
+
+	return m.auth.ServiceURL(azauth.StorageServiceBlob, settings.AzureEnvironment)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blobstorage
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+)
+
+// The operations on the legal hold and the immutability policy of blobs are not exposed by the SDK yet. They are sent as
+// requests to set the metadata of the blob, rewritten by the rewritePolicy of the client before being authorized.
+
+// rewritePolicy rewrites the requests whose context carries a rewrite function set by this policy, so that the
+// requests sent by the clients of other components are left untouched.
+type rewritePolicy struct {
+	// key of the rewrite function in the context of the requests, unique to the policy.
+	key *struct{ _ byte }
+}
+
+func newRewritePolicy() *rewritePolicy {
+	return &rewritePolicy{key: &struct{ _ byte }{}}
+}
+
+func (p *rewritePolicy) Do(req *policy.Request) (*http.Response, error) {
+	if rewrite, ok := req.Raw().Context().Value(p.key).(func(*http.Request)); ok {
+		rewrite(req.Raw())
+	}
+
+	return req.Next()
+}
+
+// send sends the request to set the metadata of a blob, rewritten by rewrite.
+func (p *rewritePolicy) send(ctx context.Context, client *blob.Client, rewrite func(*http.Request)) error {
+	ctx = context.WithValue(ctx, p.key, rewrite)
+	_, err := client.SetMetadata(ctx, nil, nil)
+
+	return err
+}
+
+// SetLegalHold sets or clears the legal hold of a blob of the client created with the metadata.
+// See: https://learn.microsoft.com/rest/api/storageservices/set-blob-legal-hold
+func (m *BlobStorageMetadata) SetLegalHold(ctx context.Context, client *blob.Client, legalHold bool) error {
+	return m.rewritePolicy.send(ctx, client, func(req *http.Request) {
+		setComp(req, "legalhold")
+		req.Header.Set("x-ms-legal-hold", strconv.FormatBool(legalHold))
+	})
+}
+
+// SetImmutabilityPolicy sets the immutability policy of a blob of the client created with the metadata, preventing its modification until the given time.
+// See: https://learn.microsoft.com/rest/api/storageservices/set-blob-immutability-policy
+func (m *BlobStorageMetadata) SetImmutabilityPolicy(ctx context.Context, client *blob.Client, until time.Time, mode blob.ImmutabilityPolicySetting) error {
+	return m.rewritePolicy.send(ctx, client, func(req *http.Request) {
+		setComp(req, "immutabilityPolicies")
+		req.Header.Set("x-ms-immutability-policy-until-date", until.UTC().Format(http.TimeFormat))
+		req.Header.Set("x-ms-immutability-policy-mode", string(mode))
+	})
+}
+
+// DeleteImmutabilityPolicy deletes the unlocked immutability policy of a blob of the client created with the metadata.
+// See: https://learn.microsoft.com/rest/api/storageservices/delete-blob-immutability-policy
+func (m *BlobStorageMetadata) DeleteImmutabilityPolicy(ctx context.Context, client *blob.Client) error {
+	return m.rewritePolicy.send(ctx, client, func(req *http.Request) {
+		req.Method = http.MethodDelete
+		setComp(req, "immutabilityPolicies")
+	})
+}
+
+func setComp(req *http.Request, comp string) {
+	q := req.URL.Query()
+	q.Set("comp", comp)
+	req.URL.RawQuery = q.Encode()
+}
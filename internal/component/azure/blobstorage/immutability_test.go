@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blobstorage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImmutabilityRequests(t *testing.T) {
+	var received *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	m := &BlobStorageMetadata{rewritePolicy: newRewritePolicy()}
+	client, err := blob.NewClientWithNoCredential(server.URL+"/container/blob", &blob.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			PerCallPolicies: []policy.Policy{m.rewritePolicy},
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("set legal hold", func(t *testing.T) {
+		err := m.SetLegalHold(context.Background(), client, true)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.MethodPut, received.Method)
+		assert.Equal(t, "/container/blob", received.URL.Path)
+		assert.Equal(t, "legalhold", received.URL.Query().Get("comp"))
+		assert.Equal(t, "true", received.Header.Get("x-ms-legal-hold"))
+	})
+
+	t.Run("set immutability policy", func(t *testing.T) {
+		until := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+		err := m.SetImmutabilityPolicy(context.Background(), client, until, blob.ImmutabilityPolicySettingLocked)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.MethodPut, received.Method)
+		assert.Equal(t, "immutabilityPolicies", received.URL.Query().Get("comp"))
+		assert.Equal(t, "Wed, 02 Jan 2030 03:04:05 GMT", received.Header.Get("x-ms-immutability-policy-until-date"))
+		assert.Equal(t, "Locked", received.Header.Get("x-ms-immutability-policy-mode"))
+	})
+
+	t.Run("delete immutability policy", func(t *testing.T) {
+		err := m.DeleteImmutabilityPolicy(context.Background(), client)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.MethodDelete, received.Method)
+		assert.Equal(t, "immutabilityPolicies", received.URL.Query().Get("comp"))
+	})
+
+	t.Run("other requests are not rewritten", func(t *testing.T) {
+		_, err := client.SetMetadata(context.Background(), map[string]string{"foo": "bar"}, nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, "metadata", received.URL.Query().Get("comp"))
+		assert.Equal(t, "bar", received.Header.Get("x-ms-meta-foo"))
+	})
+
+	t.Run("requests of other clients are not rewritten", func(t *testing.T) {
+		other := &BlobStorageMetadata{rewritePolicy: newRewritePolicy()}
+		err := other.rewritePolicy.send(context.Background(), client, func(req *http.Request) {
+			setComp(req, "legalhold")
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "metadata", received.URL.Query().Get("comp"))
+	})
+}
@@ -24,12 +24,27 @@ import (
 )
 
 type BlobStorageMetadata struct {
-	AccountName       string
-	AccountKey        string
-	ContainerName     string
-	RetryCount        int32 `json:"retryCount,string"`
-	DecodeBase64      bool  `json:"decodeBase64,string"`
+	AccountName       string `mdrequired:"true"`
+	AccountKey        string `mdsensitive:"true"`
+	SASToken          string `mapstructure:"sasToken" mdsensitive:"true"`
+	ConnectionString  string `mdsensitive:"true"`
+	ContainerName     string `mdrequired:"true"`
+	RetryCount        int32  `json:"retryCount,string"`
+	DecodeBase64      bool   `json:"decodeBase64,string"`
 	PublicAccessLevel azblob.PublicAccessType
+
+	// Credentials selected by the metadata provided
+	auth *azauth.StorageAuth
+	// Policy of the client rewriting the immutability requests
+	rewritePolicy *rewritePolicy
+}
+
+// MetadataSchema returns the schema of the metadata of the Azure Blob Storage components.
+func MetadataSchema() []mdutils.MetadataField {
+	fields, _ := mdutils.GetMetadataSchemaFromStruct(BlobStorageMetadata{
+		RetryCount: defaultBlobRetryCount,
+	})
+	return fields
 }
 
 func parseMetadata(meta map[string]string) (*BlobStorageMetadata, error) {
@@ -38,11 +53,14 @@ func parseMetadata(meta map[string]string) (*BlobStorageMetadata, error) {
 	}
 	mdutils.DecodeMetadata(meta, &m)
 
-	if val, ok := mdutils.GetMetadataProperty(meta, azauth.StorageAccountNameKeys...); ok && val != "" {
-		m.AccountName = val
-	} else {
-		return nil, fmt.Errorf("missing or empty %s field from metadata", azauth.StorageAccountNameKeys[0])
+	auth, err := azauth.GetStorageAuth(meta)
+	if err != nil {
+		return nil, err
 	}
+	m.auth = auth
+	m.AccountName = auth.AccountName
+	m.AccountKey = auth.AccountKey
+	m.SASToken = auth.SASToken
 
 	if val, ok := mdutils.GetMetadataProperty(meta, azauth.StorageContainerNameKeys...); ok && val != "" {
 		m.ContainerName = val
@@ -50,10 +68,6 @@ func parseMetadata(meta map[string]string) (*BlobStorageMetadata, error) {
 		return nil, fmt.Errorf("missing or empty %s field from metadata", azauth.StorageContainerNameKeys[0])
 	}
 
-	if val, ok := mdutils.GetMetadataProperty(meta, azauth.StorageAccountKeyKeys...); ok && val != "" {
-		m.AccountKey = val
-	}
-
 	// per the Dapr documentation "none" is a valid value
 	if m.PublicAccessLevel == "none" {
 		m.PublicAccessLevel = ""
@@ -19,6 +19,8 @@ import (
 
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/stretchr/testify/assert"
+
+	mdutils "github.com/dapr/components-contrib/metadata"
 )
 
 func TestParseMetadata(t *testing.T) {
@@ -66,6 +68,18 @@ func TestParseMetadata(t *testing.T) {
 		assert.Equal(t, azblob.PublicAccessTypeContainer, meta.PublicAccessLevel)
 	})
 
+	t.Run("parse metadata with connection string", func(t *testing.T) {
+		m = map[string]string{
+			"connectionString": "AccountName=account;SharedAccessSignature=sv=1&sig=abc",
+			"container":        "test",
+		}
+		meta, err := parseMetadata(m)
+		assert.Nil(t, err)
+		assert.Equal(t, "account", meta.AccountName)
+		assert.Equal(t, "", meta.AccountKey)
+		assert.Equal(t, "sv=1&sig=abc", meta.SASToken)
+	})
+
 	t.Run("parse metadata with invalid publicAccessLevel", func(t *testing.T) {
 		m = map[string]string{
 			"storageAccount":    "account",
@@ -77,3 +91,12 @@ func TestParseMetadata(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestMetadataSchema(t *testing.T) {
+	fields := MetadataSchema()
+
+	assert.Contains(t, fields, mdutils.MetadataField{Name: "accountName", Type: mdutils.FieldTypeString, Required: true})
+	assert.Contains(t, fields, mdutils.MetadataField{Name: "accountKey", Type: mdutils.FieldTypeString, Sensitive: true})
+	assert.Contains(t, fields, mdutils.MetadataField{Name: "retryCount", Type: mdutils.FieldTypeNumber, Default: "3"})
+	assert.Contains(t, fields, mdutils.MetadataField{Name: "decodeBase64", Type: mdutils.FieldTypeBool})
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package eventhubs contains the consumption logic shared by the Azure Event Hubs components: the position in the
+// partitions where the consumers start, and the store of the leases and the checkpoints of the partitions in Azure
+// Blob Storage, through which the replicas balance the partitions between them.
+package eventhubs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	eventhub "github.com/Azure/azure-event-hubs-go/v3"
+	"github.com/Azure/azure-event-hubs-go/v3/eph"
+	"github.com/Azure/azure-event-hubs-go/v3/persist"
+	"github.com/Azure/azure-event-hubs-go/v3/storage"
+	"github.com/Azure/go-autorest/autorest/azure"
+
+	mdutils "github.com/dapr/components-contrib/metadata"
+)
+
+const (
+	// StartingPositionKey is the metadata key for the position where the consumption of a partition starts when
+	// there's no checkpoint: earliest, latest, offset or timestamp.
+	StartingPositionKey = "startingPosition"
+	// StartingOffsetKey is the metadata key for the offset of the offset starting position.
+	StartingOffsetKey = "startingOffset"
+	// StartingTimestampKey is the metadata key for the enqueued time of the timestamp starting position, in RFC3339.
+	StartingTimestampKey = "startingTimestamp"
+
+	StartingPositionEarliest  = "earliest"
+	StartingPositionLatest    = "latest"
+	StartingPositionOffset    = "offset"
+	StartingPositionTimestamp = "timestamp"
+
+	// The blob store only keeps initial checkpoints with an offset, so the timestamps are kept in the offset
+	// with this prefix.
+	timestampOffsetPrefix = "@enqueuedTime:"
+)
+
+// StartingPosition is the position where the consumption of a partition without a checkpoint starts.
+type StartingPosition struct {
+	// Offset is the offset of the first event, or one of persist.StartOfStream and persist.EndOfStream.
+	// It's empty when starting from Timestamp.
+	Offset    string
+	Timestamp time.Time
+}
+
+// MetadataSchema returns the schema of the starting position metadata, with the default position of the component, if
+// it has a single one.
+func MetadataSchema(defaultPosition string) []mdutils.MetadataField {
+	return []mdutils.MetadataField{
+		{Name: StartingPositionKey, Type: mdutils.FieldTypeString, Default: defaultPosition},
+		{Name: StartingOffsetKey, Type: mdutils.FieldTypeString},
+		{Name: StartingTimestampKey, Type: mdutils.FieldTypeString},
+	}
+}
+
+// ParseStartingPosition returns the starting position configured in the metadata properties.
+// ok is false if no position is configured, to let the components keep their own default.
+func ParseStartingPosition(properties map[string]string) (position StartingPosition, ok bool, err error) {
+	switch strings.ToLower(properties[StartingPositionKey]) {
+	case "":
+		return StartingPosition{}, false, nil
+	case StartingPositionEarliest:
+		return StartingPosition{Offset: persist.StartOfStream}, true, nil
+	case StartingPositionLatest:
+		return StartingPosition{Offset: persist.EndOfStream}, true, nil
+	case StartingPositionOffset:
+		offset := properties[StartingOffsetKey]
+		if offset == "" {
+			return StartingPosition{}, false, fmt.Errorf("error: %s is required when %s is offset", StartingOffsetKey, StartingPositionKey)
+		}
+		return StartingPosition{Offset: offset}, true, nil
+	case StartingPositionTimestamp:
+		timestamp, err := time.Parse(time.RFC3339, properties[StartingTimestampKey])
+		if err != nil {
+			return StartingPosition{}, false, fmt.Errorf("error: invalid %s, it must be a RFC3339 time when %s is timestamp: %w", StartingTimestampKey, StartingPositionKey, err)
+		}
+		return StartingPosition{Timestamp: timestamp}, true, nil
+	default:
+		return StartingPosition{}, false, fmt.Errorf("error: invalid %s %s, it must be one of earliest, latest, offset or timestamp", StartingPositionKey, properties[StartingPositionKey])
+	}
+}
+
+// ReceiveOption returns the option of a receiver starting at the position.
+func (p StartingPosition) ReceiveOption() eventhub.ReceiveOption {
+	if p.Offset == "" {
+		return eventhub.ReceiveFromTimestamp(p.Timestamp)
+	}
+	return eventhub.ReceiveWithStartingOffset(p.Offset)
+}
+
+func (p StartingPosition) checkpoint() persist.Checkpoint {
+	if p.Offset == "" {
+		return persist.NewCheckpoint(timestampOffsetPrefix+p.Timestamp.UTC().Format(time.RFC3339Nano), 0, time.Time{})
+	}
+	return persist.NewCheckpoint(p.Offset, 0, time.Time{})
+}
+
+// LeaserCheckpointer leases the partitions of an event hub to the processors, and stores their checkpoints.
+type LeaserCheckpointer interface {
+	eph.Leaser
+	eph.Checkpointer
+}
+
+// NewLeaserCheckpointer returns a leaser checkpointer storing the leases and the checkpoints in blobs of the container,
+// under prefix. The consumption of the partitions without a checkpoint starts at position.
+func NewLeaserCheckpointer(credential storage.Credential, accountName, containerName string, env azure.Environment, prefix string, position StartingPosition) (LeaserCheckpointer, error) {
+	lc, err := storage.NewStorageLeaserCheckpointer(credential, accountName, containerName, env,
+		storage.WithPrefixInBlobPath(prefix),
+		storage.WithInitialCheckpoint(position.checkpoint),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &timestampLeaserCheckpointer{LeaserCheckpointer: lc}, nil
+}
+
+// timestampLeaserCheckpointer converts the initial checkpoints of the timestamp starting positions into checkpoints
+// which the receivers start from.
+type timestampLeaserCheckpointer struct {
+	LeaserCheckpointer
+}
+
+func (t *timestampLeaserCheckpointer) EnsureCheckpoint(ctx context.Context, partitionID string) (persist.Checkpoint, error) {
+	checkpoint, err := t.LeaserCheckpointer.EnsureCheckpoint(ctx, partitionID)
+	if err != nil || !strings.HasPrefix(checkpoint.Offset, timestampOffsetPrefix) {
+		return checkpoint, err
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, strings.TrimPrefix(checkpoint.Offset, timestampOffsetPrefix))
+	if err != nil {
+		return persist.Checkpoint{}, fmt.Errorf("error: invalid initial checkpoint %s of partition %s: %w", checkpoint.Offset, partitionID, err)
+	}
+	return persist.NewCheckpoint("", 0, timestamp), nil
+}
+
+// WithSeekPosition returns a leaser checkpointer replacing the checkpoint of each partition with position, the first
+// time the lease of the partition is acquired, so that the consumption restarts from there.
+// Only the partitions leased by the processor using it are moved: the processors of other replicas keep theirs.
+func WithSeekPosition(lc LeaserCheckpointer, position StartingPosition) LeaserCheckpointer {
+	return &seekLeaserCheckpointer{
+		LeaserCheckpointer: lc,
+		position:           position,
+		moved:              map[string]bool{},
+	}
+}
+
+type seekLeaserCheckpointer struct {
+	LeaserCheckpointer
+
+	position StartingPosition
+	lock     sync.Mutex
+	// Partitions whose checkpoint has been replaced.
+	moved map[string]bool
+}
+
+func (s *seekLeaserCheckpointer) EnsureCheckpoint(ctx context.Context, partitionID string) (persist.Checkpoint, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// The checkpoints are also ensured when the processor starts, before it owns the leases; the checkpoint can only be
+	// stored when the lease is owned, as the receiver of the partition starts
+	if _, owned := s.GetCheckpoint(ctx, partitionID); !owned || s.moved[partitionID] {
+		return s.LeaserCheckpointer.EnsureCheckpoint(ctx, partitionID)
+	}
+
+	checkpoint := persist.NewCheckpoint(s.position.Offset, 0, time.Time{})
+	if s.position.Offset == "" {
+		checkpoint = persist.NewCheckpoint("", 0, s.position.Timestamp)
+	}
+	if err := s.UpdateCheckpoint(ctx, partitionID, checkpoint); err != nil {
+		return persist.Checkpoint{}, fmt.Errorf("error: moving the checkpoint of partition %s: %w", partitionID, err)
+	}
+	s.moved[partitionID] = true
+
+	return checkpoint, nil
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventhubs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-event-hubs-go/v3/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStartingPosition(t *testing.T) {
+	t.Run("not configured", func(t *testing.T) {
+		_, ok, err := ParseStartingPosition(map[string]string{})
+
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("positions", func(t *testing.T) {
+		timestamp := time.Date(2022, 11, 1, 10, 0, 0, 0, time.UTC)
+		tests := map[string]struct {
+			properties map[string]string
+			expected   StartingPosition
+		}{
+			"earliest":  {map[string]string{"startingPosition": "earliest"}, StartingPosition{Offset: persist.StartOfStream}},
+			"latest":    {map[string]string{"startingPosition": "Latest"}, StartingPosition{Offset: persist.EndOfStream}},
+			"offset":    {map[string]string{"startingPosition": "offset", "startingOffset": "1024"}, StartingPosition{Offset: "1024"}},
+			"timestamp": {map[string]string{"startingPosition": "timestamp", "startingTimestamp": "2022-11-01T10:00:00Z"}, StartingPosition{Timestamp: timestamp}},
+		}
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				position, ok, err := ParseStartingPosition(tt.properties)
+
+				require.NoError(t, err)
+				assert.True(t, ok)
+				assert.Equal(t, tt.expected, position)
+			})
+		}
+	})
+
+	t.Run("invalid positions", func(t *testing.T) {
+		_, _, err := ParseStartingPosition(map[string]string{"startingPosition": "first"})
+		assert.ErrorContains(t, err, "invalid startingPosition first")
+
+		_, _, err = ParseStartingPosition(map[string]string{"startingPosition": "offset"})
+		assert.ErrorContains(t, err, "startingOffset is required")
+
+		_, _, err = ParseStartingPosition(map[string]string{"startingPosition": "timestamp", "startingTimestamp": "yesterday"})
+		assert.ErrorContains(t, err, "invalid startingTimestamp")
+	})
+}
+
+type fakeLeaserCheckpointer struct {
+	LeaserCheckpointer
+
+	checkpoint persist.Checkpoint
+}
+
+func (f *fakeLeaserCheckpointer) EnsureCheckpoint(_ context.Context, _ string) (persist.Checkpoint, error) {
+	return f.checkpoint, nil
+}
+
+func TestTimestampLeaserCheckpointer(t *testing.T) {
+	t.Run("initial checkpoint of a timestamp", func(t *testing.T) {
+		timestamp := time.Date(2022, 11, 1, 10, 0, 0, 0, time.UTC)
+		lc := &timestampLeaserCheckpointer{&fakeLeaserCheckpointer{checkpoint: StartingPosition{Timestamp: timestamp}.checkpoint()}}
+
+		checkpoint, err := lc.EnsureCheckpoint(context.Background(), "0")
+
+		require.NoError(t, err)
+		assert.Empty(t, checkpoint.Offset)
+		assert.Equal(t, timestamp, checkpoint.EnqueueTime)
+	})
+
+	t.Run("checkpoint of an offset", func(t *testing.T) {
+		lc := &timestampLeaserCheckpointer{&fakeLeaserCheckpointer{checkpoint: persist.NewCheckpoint("1024", 10, time.Time{})}}
+
+		checkpoint, err := lc.EnsureCheckpoint(context.Background(), "0")
+
+		require.NoError(t, err)
+		assert.Equal(t, "1024", checkpoint.Offset)
+	})
+}
+
+// ownedLeaserCheckpointer stores the checkpoints of the partitions it owns.
+type ownedLeaserCheckpointer struct {
+	LeaserCheckpointer
+
+	owned       map[string]bool
+	checkpoints map[string]persist.Checkpoint
+}
+
+func (o *ownedLeaserCheckpointer) GetCheckpoint(_ context.Context, partitionID string) (persist.Checkpoint, bool) {
+	return o.checkpoints[partitionID], o.owned[partitionID]
+}
+
+func (o *ownedLeaserCheckpointer) EnsureCheckpoint(_ context.Context, partitionID string) (persist.Checkpoint, error) {
+	return o.checkpoints[partitionID], nil
+}
+
+func (o *ownedLeaserCheckpointer) UpdateCheckpoint(_ context.Context, partitionID string, checkpoint persist.Checkpoint) error {
+	o.checkpoints[partitionID] = checkpoint
+	return nil
+}
+
+func TestSeekLeaserCheckpointer(t *testing.T) {
+	stored := persist.NewCheckpoint("4096", 40, time.Time{})
+	lc := &ownedLeaserCheckpointer{
+		owned:       map[string]bool{},
+		checkpoints: map[string]persist.Checkpoint{"0": stored, "1": stored},
+	}
+	timestamp := time.Date(2022, 11, 1, 10, 0, 0, 0, time.UTC)
+	seek := WithSeekPosition(lc, StartingPosition{Timestamp: timestamp})
+
+	t.Run("partitions not owned keep their checkpoint", func(t *testing.T) {
+		checkpoint, err := seek.EnsureCheckpoint(context.Background(), "0")
+
+		require.NoError(t, err)
+		assert.Equal(t, stored, checkpoint)
+	})
+
+	t.Run("checkpoint replaced when the lease is owned", func(t *testing.T) {
+		lc.owned["0"] = true
+
+		checkpoint, err := seek.EnsureCheckpoint(context.Background(), "0")
+
+		require.NoError(t, err)
+		assert.Empty(t, checkpoint.Offset)
+		assert.Equal(t, timestamp, checkpoint.EnqueueTime)
+		assert.Equal(t, checkpoint, lc.checkpoints["0"])
+		assert.Equal(t, stored, lc.checkpoints["1"])
+	})
+
+	t.Run("checkpoint replaced once", func(t *testing.T) {
+		progress := persist.NewCheckpoint("8192", 80, time.Time{})
+		lc.checkpoints["0"] = progress
+
+		checkpoint, err := seek.EnsureCheckpoint(context.Background(), "0")
+
+		require.NoError(t, err)
+		assert.Equal(t, progress, checkpoint)
+	})
+
+	t.Run("offset", func(t *testing.T) {
+		lc.owned["1"] = true
+
+		checkpoint, err := WithSeekPosition(lc, StartingPosition{Offset: "1024"}).EnsureCheckpoint(context.Background(), "1")
+
+		require.NoError(t, err)
+		assert.Equal(t, "1024", checkpoint.Offset)
+	})
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventhubs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-event-hubs-go/v3/persist"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+// PartitionCheckpoint is the checkpoint of a partition of an event hub, as exported and imported.
+type PartitionCheckpoint struct {
+	PartitionID    string    `json:"partitionId"`
+	Offset         string    `json:"offset"`
+	SequenceNumber int64     `json:"sequenceNumber"`
+	EnqueueTime    time.Time `json:"enqueueTime"`
+}
+
+// leaseBlob is the content of the blob of the lease of a partition, as stored by the leaser checkpointer.
+type leaseBlob struct {
+	PartitionID string              `json:"partitionID"`
+	Checkpoint  *persist.Checkpoint `json:"checkpoint"`
+}
+
+// CheckpointStore reads and writes the checkpoints stored in the blobs of the leases of the partitions, outside of the
+// event processors, to export and import them.
+type CheckpointStore struct {
+	container azblob.ContainerURL
+	prefix    string
+}
+
+// NewCheckpointStore returns a store of the checkpoints kept in the blobs of the container under prefix, as with
+// NewLeaserCheckpointer.
+func NewCheckpointStore(credential azblob.Credential, accountName, containerName string, env azure.Environment, prefix string) (*CheckpointStore, error) {
+	u, err := url.Parse(fmt.Sprintf("https://%s.blob.%s/%s", accountName, env.StorageEndpointSuffix, containerName))
+	if err != nil {
+		return nil, err
+	}
+
+	return &CheckpointStore{
+		container: azblob.NewContainerURL(*u, azblob.NewPipeline(credential, azblob.PipelineOptions{})),
+		prefix:    prefix,
+	}, nil
+}
+
+// ExportCheckpoints returns the checkpoints of the partitions, ordered by partition. The partitions whose consumption
+// hasn't started yet are omitted.
+func (s *CheckpointStore) ExportCheckpoints(ctx context.Context) ([]PartitionCheckpoint, error) {
+	checkpoints := []PartitionCheckpoint{}
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		res, err := s.container.ListBlobsFlatSegment(ctx, marker, azblob.ListBlobsSegmentOptions{Prefix: s.prefix})
+		if err != nil {
+			return nil, fmt.Errorf("error listing the checkpoints: %w", err)
+		}
+		marker = res.NextMarker
+
+		for _, item := range res.Segment.BlobItems {
+			lease, err := s.getLease(ctx, strings.TrimPrefix(item.Name, s.prefix))
+			if err != nil {
+				return nil, err
+			}
+			if lease == nil || lease.Checkpoint == nil {
+				continue
+			}
+			checkpoints = append(checkpoints, PartitionCheckpoint{
+				PartitionID:    lease.PartitionID,
+				Offset:         lease.Checkpoint.Offset,
+				SequenceNumber: lease.Checkpoint.SequenceNumber,
+				EnqueueTime:    lease.Checkpoint.EnqueueTime,
+			})
+		}
+	}
+
+	// The partition IDs are numbers
+	sort.Slice(checkpoints, func(i, j int) bool {
+		a, b := checkpoints[i].PartitionID, checkpoints[j].PartitionID
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return a < b
+	})
+
+	return checkpoints, nil
+}
+
+// ImportCheckpoints replaces the checkpoints of the partitions, from which the event processors resume.
+// The partitions must not be leased, so the consumers of the consumer group must be stopped first: the checkpoints of
+// the leased partitions would be overwritten by their processors.
+func (s *CheckpointStore) ImportCheckpoints(ctx context.Context, checkpoints []PartitionCheckpoint) error {
+	for _, c := range checkpoints {
+		if c.PartitionID == "" {
+			return errors.New("error: the partition ID of a checkpoint is empty")
+		}
+	}
+
+	for _, c := range checkpoints {
+		checkpoint := persist.NewCheckpoint(c.Offset, c.SequenceNumber, c.EnqueueTime)
+		if err := s.setCheckpoint(ctx, c.PartitionID, checkpoint); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// storedLease is a lease read from its blob, with the raw blob to update it without losing the other properties of the
+// lease.
+type storedLease struct {
+	leaseBlob
+
+	data  []byte
+	etag  azblob.ETag
+	state azblob.LeaseStateType
+}
+
+// getLease returns the lease of a partition, or nil if it doesn't exist.
+func (s *CheckpointStore) getLease(ctx context.Context, partitionID string) (*storedLease, error) {
+	res, err := s.container.NewBlobURL(s.prefix+partitionID).Download(ctx, 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false)
+	if err != nil {
+		var stgErr azblob.StorageError
+		if errors.As(err, &stgErr) && stgErr.Response() != nil && stgErr.Response().StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading the checkpoint of partition %s: %w", partitionID, err)
+	}
+
+	body := res.Body(azblob.RetryReaderOptions{})
+	defer body.Close()
+	lease := &storedLease{etag: res.ETag(), state: res.LeaseState()}
+	lease.data, err = io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the checkpoint of partition %s: %w", partitionID, err)
+	}
+	if err = json.Unmarshal(lease.data, &lease.leaseBlob); err != nil {
+		return nil, fmt.Errorf("error decoding the checkpoint of partition %s: %w", partitionID, err)
+	}
+	if lease.PartitionID == "" {
+		lease.PartitionID = partitionID
+	}
+
+	return lease, nil
+}
+
+// setCheckpoint replaces the checkpoint in the blob of the lease of a partition, which is created if it doesn't exist.
+func (s *CheckpointStore) setCheckpoint(ctx context.Context, partitionID string, checkpoint persist.Checkpoint) error {
+	lease, err := s.getLease(ctx, partitionID)
+	if err != nil {
+		return err
+	}
+
+	fields := map[string]json.RawMessage{}
+	conditions := azblob.ModifiedAccessConditions{IfNoneMatch: azblob.ETagAny}
+	if lease != nil {
+		if lease.state == azblob.LeaseStateLeased {
+			return fmt.Errorf("error: partition %s is leased by a consumer, the consumers must be stopped to import the checkpoints", partitionID)
+		}
+		if err = json.Unmarshal(lease.data, &fields); err != nil {
+			return fmt.Errorf("error decoding the checkpoint of partition %s: %w", partitionID, err)
+		}
+		conditions = azblob.ModifiedAccessConditions{IfMatch: lease.etag}
+	} else {
+		fields["partitionID"], _ = json.Marshal(partitionID)
+	}
+	fields["checkpoint"], err = json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	_, err = s.container.NewBlockBlobURL(s.prefix+partitionID).Upload(ctx, bytes.NewReader(data), azblob.BlobHTTPHeaders{}, azblob.Metadata{},
+		azblob.BlobAccessConditions{ModifiedAccessConditions: conditions})
+	if err != nil {
+		return fmt.Errorf("error writing the checkpoint of partition %s: %w", partitionID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventhubs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeContainer serves the blobs of a container of Azure Blob Storage, with their ETags and lease states.
+type fakeContainer struct {
+	lock   sync.Mutex
+	blobs  map[string]string
+	etags  map[string]int
+	leased map[string]bool
+}
+
+func (c *fakeContainer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	name := strings.TrimPrefix(r.URL.Path, "/container/")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Query().Get("comp") == "list":
+		names := []string{}
+		for n := range c.blobs {
+			if strings.HasPrefix(n, r.URL.Query().Get("prefix")) {
+				names = append(names, n)
+			}
+		}
+		sort.Strings(names)
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?><EnumerationResults><Blobs>`)
+		for _, n := range names {
+			fmt.Fprintf(w, `<Blob><Name>%s</Name><Properties><Last-Modified>Tue, 01 Nov 2022 10:00:00 GMT</Last-Modified><Etag>"%d"</Etag></Properties></Blob>`, n, c.etags[n])
+		}
+		fmt.Fprint(w, `</Blobs><NextMarker /></EnumerationResults>`)
+	case r.Method == http.MethodGet:
+		data, ok := c.blobs[name]
+		if !ok {
+			w.Header().Set("x-ms-error-code", "BlobNotFound")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", fmt.Sprintf(`"%d"`, c.etags[name]))
+		w.Header().Set("x-ms-lease-state", "available")
+		if c.leased[name] {
+			w.Header().Set("x-ms-lease-state", "leased")
+		}
+		w.Write([]byte(data))
+	case r.Method == http.MethodPut:
+		_, exists := c.blobs[name]
+		if (r.Header.Get("If-None-Match") == "*" && exists) ||
+			(r.Header.Get("If-Match") != "" && r.Header.Get("If-Match") != fmt.Sprintf(`"%d"`, c.etags[name])) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		c.blobs[name] = string(data)
+		c.etags[name]++
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func newTestCheckpointStore(t *testing.T, blobs map[string]string) (*CheckpointStore, *fakeContainer) {
+	t.Helper()
+
+	container := &fakeContainer{blobs: blobs, etags: map[string]int{}, leased: map[string]bool{}}
+	server := httptest.NewServer(container)
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL + "/container")
+	require.NoError(t, err)
+	pipeline := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{Retry: azblob.RetryOptions{MaxTries: 1}})
+
+	return &CheckpointStore{container: azblob.NewContainerURL(*u, pipeline), prefix: "dapr-hub-group-"}, container
+}
+
+func TestCheckpointStore(t *testing.T) {
+	enqueueTime := time.Date(2022, 11, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("export the checkpoints", func(t *testing.T) {
+		s, _ := newTestCheckpointStore(t, map[string]string{
+			"dapr-hub-group-10": `{"partitionID":"10","epoch":2,"owner":"a","checkpoint":{"offset":"20","sequenceNumber":2,"enqueueTime":"2022-11-01T10:00:00Z"}}`,
+			"dapr-hub-group-2":  `{"partitionID":"2","epoch":1,"owner":"b","checkpoint":{"offset":"10","sequenceNumber":1,"enqueueTime":"2022-11-01T10:00:00Z"}}`,
+			"dapr-hub-group-3":  `{"partitionID":"3"}`,
+			"dapr-hub-other-0":  `{"partitionID":"0","checkpoint":{"offset":"30"}}`,
+		})
+		checkpoints, err := s.ExportCheckpoints(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, []PartitionCheckpoint{
+			{PartitionID: "2", Offset: "10", SequenceNumber: 1, EnqueueTime: enqueueTime},
+			{PartitionID: "10", Offset: "20", SequenceNumber: 2, EnqueueTime: enqueueTime},
+		}, checkpoints)
+	})
+
+	t.Run("import the checkpoints", func(t *testing.T) {
+		s, container := newTestCheckpointStore(t, map[string]string{
+			"dapr-hub-group-0": `{"partitionID":"0","epoch":3,"owner":"a","checkpoint":{"offset":"20","sequenceNumber":2}}`,
+		})
+
+		err := s.ImportCheckpoints(context.Background(), []PartitionCheckpoint{
+			{PartitionID: "0", Offset: "10", SequenceNumber: 1, EnqueueTime: enqueueTime},
+			{PartitionID: "1", Offset: "5", SequenceNumber: 0, EnqueueTime: enqueueTime},
+		})
+		require.NoError(t, err)
+
+		// The other properties of the existing leases are kept
+		var lease map[string]any
+		require.NoError(t, json.Unmarshal([]byte(container.blobs["dapr-hub-group-0"]), &lease))
+		assert.Equal(t, float64(3), lease["epoch"])
+		assert.Equal(t, "a", lease["owner"])
+
+		checkpoints, err := s.ExportCheckpoints(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []PartitionCheckpoint{
+			{PartitionID: "0", Offset: "10", SequenceNumber: 1, EnqueueTime: enqueueTime},
+			{PartitionID: "1", Offset: "5", SequenceNumber: 0, EnqueueTime: enqueueTime},
+		}, checkpoints)
+	})
+
+	t.Run("leased partitions are not imported", func(t *testing.T) {
+		s, container := newTestCheckpointStore(t, map[string]string{
+			"dapr-hub-group-0": `{"partitionID":"0","checkpoint":{"offset":"20"}}`,
+		})
+		container.leased["dapr-hub-group-0"] = true
+
+		err := s.ImportCheckpoints(context.Background(), []PartitionCheckpoint{{PartitionID: "0", Offset: "10"}})
+
+		assert.ErrorContains(t, err, "partition 0 is leased by a consumer")
+		assert.Contains(t, container.blobs["dapr-hub-group-0"], `"offset":"20"`)
+	})
+
+	t.Run("missing partition ID", func(t *testing.T) {
+		s, _ := newTestCheckpointStore(t, map[string]string{})
+
+		err := s.ImportCheckpoints(context.Background(), []PartitionCheckpoint{{Offset: "10"}})
+
+		assert.Error(t, err)
+	})
+}
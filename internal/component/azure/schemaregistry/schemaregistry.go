@@ -0,0 +1,227 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package schemaregistry serializes payloads with the Avro schemas of an Azure Schema Registry.
+// See: https://learn.microsoft.com/azure/event-hubs/schema-registry-overview
+package schemaregistry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/linkedin/goavro/v2"
+)
+
+const (
+	apiVersion = "2021-10"
+	scope      = "https://eventhubs.azure.net/.default"
+
+	// ContentTypePrefix is the prefix of the content type of the serialized payloads, followed by the ID of their schema.
+	ContentTypePrefix = "avro/binary+"
+
+	// ContentTypeProperty is the application property of the events carrying their content type, for the clients which
+	// cannot set the content type of the AMQP messages.
+	ContentTypeProperty = "content-type"
+
+	// DefaultCacheTTL is the default duration for which the latest version of a schema is cached.
+	DefaultCacheTTL = 5 * time.Minute
+)
+
+// Schema is a version of an Avro schema of the registry.
+type Schema struct {
+	ID    string
+	codec *goavro.Codec
+}
+
+type cachedSchema struct {
+	schema    *Schema
+	expiresAt time.Time
+}
+
+// Client reads the schemas of an Azure Schema Registry, caching them.
+// The versions of the schemas are immutable and are cached by ID indefinitely, while the latest version of a schema
+// is cached for the TTL.
+type Client struct {
+	endpoint   string
+	cred       azcore.TokenCredential
+	httpClient *http.Client
+	cacheTTL   time.Duration
+
+	byID   map[string]*Schema
+	byName map[string]cachedSchema
+	lock   sync.Mutex
+}
+
+// NewClient returns a client of the schema registry of an Event Hubs namespace, such as
+// "mynamespace.servicebus.windows.net".
+func NewClient(namespace string, cred azcore.TokenCredential, cacheTTL time.Duration) *Client {
+	endpoint := namespace
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	if cacheTTL <= 0 {
+		cacheTTL = DefaultCacheTTL
+	}
+
+	return &Client{
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		cred:       cred,
+		httpClient: &http.Client{},
+		cacheTTL:   cacheTTL,
+		byID:       make(map[string]*Schema),
+		byName:     make(map[string]cachedSchema),
+	}
+}
+
+// GetSchemaByID returns a version of a schema.
+func (c *Client) GetSchemaByID(ctx context.Context, id string) (*Schema, error) {
+	c.lock.Lock()
+	schema, ok := c.byID[id]
+	c.lock.Unlock()
+	if ok {
+		return schema, nil
+	}
+
+	schema, err := c.getSchema(ctx, "/$schemaGroups/$schemas/"+url.PathEscape(id))
+	if err != nil {
+		return nil, fmt.Errorf("error getting schema %s: %w", id, err)
+	}
+
+	return schema, nil
+}
+
+// GetLatestSchema returns the latest version of a schema of a group.
+func (c *Client) GetLatestSchema(ctx context.Context, group string, name string) (*Schema, error) {
+	key := group + "/" + name
+	c.lock.Lock()
+	cached, ok := c.byName[key]
+	c.lock.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.schema, nil
+	}
+
+	schema, err := c.getSchema(ctx, "/$schemaGroups/"+url.PathEscape(group)+"/schemas/"+url.PathEscape(name))
+	if err != nil {
+		return nil, fmt.Errorf("error getting schema %s of group %s: %w", name, group, err)
+	}
+
+	c.lock.Lock()
+	c.byName[key] = cachedSchema{schema: schema, expiresAt: time.Now().Add(c.cacheTTL)}
+	c.lock.Unlock()
+
+	return schema, nil
+}
+
+// getSchema gets a schema from the registry, and caches it by ID.
+func (c *Client) getSchema(ctx context.Context, path string) (*Schema, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+path+"?api-version="+apiVersion, nil)
+	if err != nil {
+		return nil, err
+	}
+	token, err := c.cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d: %s", res.StatusCode, string(body))
+	}
+
+	id := res.Header.Get("Schema-Id")
+	if id == "" {
+		return nil, errors.New("missing schema ID in response")
+	}
+	codec, err := goavro.NewCodec(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Avro schema %s: %w", id, err)
+	}
+	schema := &Schema{ID: id, codec: codec}
+
+	c.lock.Lock()
+	c.byID[id] = schema
+	c.lock.Unlock()
+
+	return schema, nil
+}
+
+// Serializer converts JSON payloads to the Avro binary encoding of a schema, validating them, and back.
+type Serializer struct {
+	client *Client
+	group  string
+}
+
+// NewSerializer returns a serializer with the schemas of a group.
+func NewSerializer(client *Client, group string) *Serializer {
+	return &Serializer{
+		client: client,
+		group:  group,
+	}
+}
+
+// Serialize encodes a JSON payload with the latest version of a schema, and returns it with its content type.
+func (s *Serializer) Serialize(ctx context.Context, data []byte, schemaName string) ([]byte, string, error) {
+	schema, err := s.client.GetLatestSchema(ctx, s.group, schemaName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	native, _, err := schema.codec.NativeFromTextual(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("payload does not match schema %s: %w", schemaName, err)
+	}
+	b, err := schema.codec.BinaryFromNative(nil, native)
+	if err != nil {
+		return nil, "", fmt.Errorf("payload does not match schema %s: %w", schemaName, err)
+	}
+
+	return b, ContentTypePrefix + schema.ID, nil
+}
+
+// Deserialize decodes a payload serialized with a schema of the registry to JSON.
+// Payloads with another content type are returned as is.
+func (s *Serializer) Deserialize(ctx context.Context, data []byte, contentType string) ([]byte, error) {
+	if !strings.HasPrefix(contentType, ContentTypePrefix) {
+		return data, nil
+	}
+
+	schema, err := s.client.GetSchemaByID(ctx, strings.TrimPrefix(contentType, ContentTypePrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	native, _, err := schema.codec.NativeFromBinary(data)
+	if err != nil {
+		return nil, fmt.Errorf("payload does not match schema %s: %w", schema.ID, err)
+	}
+
+	return schema.codec.TextualFromNative(nil, native)
+}
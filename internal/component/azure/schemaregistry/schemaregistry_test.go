@@ -0,0 +1,123 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schemaregistry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `{"type": "record", "name": "Order", "fields": [{"name": "id", "type": "string"}, {"name": "amount", "type": "int"}]}`
+
+type fakeCredential struct{}
+
+func (fakeCredential) GetToken(_ context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func newTestServer(t *testing.T, requests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.Equal(t, apiVersion, r.URL.Query().Get("api-version"))
+
+		switch r.URL.Path {
+		case "/$schemaGroups/group/schemas/order", "/$schemaGroups/$schemas/abc123":
+			w.Header().Set("Schema-Id", "abc123")
+			w.Header().Set("Content-Type", "application/json;serialization=Avro")
+			w.Write([]byte(testSchema))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestClient(t *testing.T) {
+	requests := 0
+	server := newTestServer(t, &requests)
+	defer server.Close()
+
+	t.Run("caches the latest schema", func(t *testing.T) {
+		requests = 0
+		client := NewClient(server.URL, fakeCredential{}, time.Minute)
+
+		schema, err := client.GetLatestSchema(context.Background(), "group", "order")
+		require.NoError(t, err)
+		assert.Equal(t, "abc123", schema.ID)
+
+		_, err = client.GetLatestSchema(context.Background(), "group", "order")
+		require.NoError(t, err)
+		// The schema is also cached by ID
+		_, err = client.GetSchemaByID(context.Background(), "abc123")
+		require.NoError(t, err)
+		assert.Equal(t, 1, requests)
+	})
+
+	t.Run("latest schema expires", func(t *testing.T) {
+		requests = 0
+		client := NewClient(server.URL, fakeCredential{}, time.Nanosecond)
+
+		_, err := client.GetLatestSchema(context.Background(), "group", "order")
+		require.NoError(t, err)
+		time.Sleep(time.Millisecond)
+		_, err = client.GetLatestSchema(context.Background(), "group", "order")
+		require.NoError(t, err)
+		assert.Equal(t, 2, requests)
+	})
+
+	t.Run("schema not found", func(t *testing.T) {
+		client := NewClient(server.URL, fakeCredential{}, time.Minute)
+
+		_, err := client.GetSchemaByID(context.Background(), "unknown")
+		assert.ErrorContains(t, err, "unexpected status code 404")
+	})
+}
+
+func TestSerializer(t *testing.T) {
+	requests := 0
+	server := newTestServer(t, &requests)
+	defer server.Close()
+	serializer := NewSerializer(NewClient(server.URL, fakeCredential{}, time.Minute), "group")
+
+	t.Run("round trip", func(t *testing.T) {
+		data, contentType, err := serializer.Serialize(context.Background(), []byte(`{"id": "order1", "amount": 42}`), "order")
+		require.NoError(t, err)
+		assert.Equal(t, "avro/binary+abc123", contentType)
+
+		decoded, err := serializer.Deserialize(context.Background(), data, contentType)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id": "order1", "amount": 42}`, string(decoded))
+	})
+
+	t.Run("payload not matching the schema", func(t *testing.T) {
+		_, _, err := serializer.Serialize(context.Background(), []byte(`{"id": "order1"}`), "order")
+
+		assert.ErrorContains(t, err, "payload does not match schema order")
+	})
+
+	t.Run("other content types are not deserialized", func(t *testing.T) {
+		decoded, err := serializer.Deserialize(context.Background(), []byte("foo"), "text/plain")
+
+		require.NoError(t, err)
+		assert.Equal(t, "foo", string(decoded))
+	})
+}
@@ -15,6 +15,7 @@ package servicebus
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -97,6 +98,32 @@ func (c *Client) GetClient() *servicebus.Client {
 	return c.client
 }
 
+// AcceptNextSession returns a receiver for the next available session of a queue, or of a topic subscription if subscription is not empty.
+// It blocks until a session is available, or until the context is canceled.
+// The session is released when no message is received within idleTimeout, if greater than 0.
+func (c *Client) AcceptNextSession(ctx context.Context, queueOrTopic string, subscription string, idleTimeout time.Duration) (*SessionReceiver, error) {
+	for {
+		var (
+			receiver *servicebus.SessionReceiver
+			err      error
+		)
+		if subscription != "" {
+			receiver, err = c.client.AcceptNextSessionForSubscription(ctx, queueOrTopic, subscription, nil)
+		} else {
+			receiver, err = c.client.AcceptNextSessionForQueue(ctx, queueOrTopic, nil)
+		}
+		if err == nil {
+			return NewSessionReceiver(receiver, idleTimeout), nil
+		}
+
+		// The service returns a timeout error when there's no session available; keep waiting in that case
+		var sbErr *servicebus.Error
+		if !errors.As(err, &sbErr) || sbErr.Code != servicebus.CodeTimeout {
+			return nil, err
+		}
+	}
+}
+
 // GetSenderForTopic returns the sender for a topic, or creates a new one if it doesn't exist
 func (c *Client) GetSender(ctx context.Context, queueOrTopic string) (*servicebus.Sender, error) {
 	c.lock.RLock()
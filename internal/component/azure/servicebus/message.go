@@ -14,8 +14,10 @@ limitations under the License.
 package servicebus
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	azservicebus "github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
@@ -72,6 +74,21 @@ const (
 
 	// MessageKeyScheduledEnqueueTimeUtc defines the metadata key for the scheduled enqueue time utc value.
 	MessageKeyScheduledEnqueueTimeUtc = "ScheduledEnqueueTimeUtc" // read, write.
+	// MessageKeyScheduledEnqueueTimeUtcAlias is an alias for "ScheduledEnqueueTimeUtc" for write only.
+	MessageKeyScheduledEnqueueTimeUtcAlias = "scheduledEnqueueTimeUtc"
+
+	// MessageKeyDelaySeconds defines the metadata key for the number of seconds the delivery of the message is delayed by.
+	// It is an alternative to "ScheduledEnqueueTimeUtc".
+	MessageKeyDelaySeconds = "delaySeconds" // write.
+
+	// MessageKeyDeadLetterReason defines the metadata key for the reason a message was dead-lettered.
+	MessageKeyDeadLetterReason = "DeadLetterReason" // read.
+
+	// MessageKeyDeadLetterErrorDescription defines the metadata key for the description of the error a message was dead-lettered for.
+	MessageKeyDeadLetterErrorDescription = "DeadLetterErrorDescription" // read.
+
+	// MessageKeyDeadLetterSource defines the metadata key for the queue or subscription a message was dead-lettered from.
+	MessageKeyDeadLetterSource = "DeadLetterSource" // read.
 
 	// MessageKeyReplyToSessionID defines the metadata key for the reply to session id.
 	// Currently unused.
@@ -114,6 +131,7 @@ func NewASBMessageFromInvokeRequest(req *bindings.InvokeRequest) (*azservicebus.
 func addMetadataToMessage(asbMsg *azservicebus.Message, metadata map[string]string) error {
 	asbMsg.ApplicationProperties = make(map[string]interface{}, len(metadata))
 
+	var delaySeconds *int
 	for k, v := range metadata {
 		// Note: do not just do &v because we're in a loop
 		if v == "" {
@@ -155,11 +173,21 @@ func addMetadataToMessage(asbMsg *azservicebus.Message, metadata map[string]stri
 			asbMsg.ContentType = ptr.Of(v)
 
 		// Time
-		case MessageKeyScheduledEnqueueTimeUtc:
-			timeVal, err := time.Parse(http.TimeFormat, v)
-			if err == nil {
-				asbMsg.ScheduledEnqueueTime = &timeVal
+		case MessageKeyScheduledEnqueueTimeUtc, MessageKeyScheduledEnqueueTimeUtcAlias:
+			timeVal, err := parseScheduledEnqueueTime(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s %s: %w", k, v, err)
 			}
+			asbMsg.ScheduledEnqueueTime = &timeVal
+		case MessageKeyDelaySeconds:
+			delay, err := strconv.Atoi(v)
+			if err == nil && delay < 0 {
+				err = errors.New("must not be negative")
+			}
+			if err != nil {
+				return fmt.Errorf("invalid %s %s: %w", k, v, err)
+			}
+			delaySeconds = &delay
 
 		// Fallback: set as application property
 		default:
@@ -167,9 +195,26 @@ func addMetadataToMessage(asbMsg *azservicebus.Message, metadata map[string]stri
 		}
 	}
 
+	if delaySeconds != nil {
+		if asbMsg.ScheduledEnqueueTime != nil {
+			return fmt.Errorf("%s and %s cannot both be set", MessageKeyScheduledEnqueueTimeUtc, MessageKeyDelaySeconds)
+		}
+		asbMsg.ScheduledEnqueueTime = ptr.Of(time.Now().Add(time.Duration(*delaySeconds) * time.Second))
+	}
+
 	if asbMsg.PartitionKey != nil && asbMsg.SessionID != nil && *asbMsg.PartitionKey != *asbMsg.SessionID {
 		return fmt.Errorf("session id %s and partition key %s should be equal when both present", *asbMsg.SessionID, *asbMsg.PartitionKey)
 	}
 
 	return nil
 }
+
+// parseScheduledEnqueueTime parses a scheduled enqueue time in the RFC2616 format, or in the RFC3339 format.
+func parseScheduledEnqueueTime(val string) (time.Time, error) {
+	t, err := time.Parse(http.TimeFormat, val)
+	if err != nil {
+		t, err = time.Parse(time.RFC3339, val)
+	}
+
+	return t, err
+}
@@ -104,6 +104,15 @@ func addMessageAttributesToMetadata(metadata map[string]string, asbMsg *azservic
 		// Preserve RFC2616 time format.
 		metadata["metadata."+MessageKeyLockedUntilUtc] = asbMsg.LockedUntil.UTC().Format(http.TimeFormat)
 	}
+	if asbMsg.DeadLetterReason != nil {
+		metadata["metadata."+MessageKeyDeadLetterReason] = *asbMsg.DeadLetterReason
+	}
+	if asbMsg.DeadLetterErrorDescription != nil {
+		metadata["metadata."+MessageKeyDeadLetterErrorDescription] = *asbMsg.DeadLetterErrorDescription
+	}
+	if asbMsg.DeadLetterSource != nil {
+		metadata["metadata."+MessageKeyDeadLetterSource] = *asbMsg.DeadLetterSource
+	}
 
 	return metadata
 }
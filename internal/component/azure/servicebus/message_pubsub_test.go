@@ -19,6 +19,8 @@ import (
 
 	azservicebus "github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/dapr/kit/ptr"
 )
 
 func TestAddMessageAttributesToMetadata(t *testing.T) {
@@ -62,17 +64,34 @@ func TestAddMessageAttributesToMetadata(t *testing.T) {
 				"metadata." + MessageKeyLockedUntilUtc:          testSampleTimeHTTPFormat,
 			},
 		},
+		{
+			name: "Metadata must contain the dead-letter attributes",
+			ASBMessage: azservicebus.ReceivedMessage{
+				MessageID:                  testMessageID,
+				DeliveryCount:              testDeliveryCount,
+				DeadLetterReason:           ptr.Of("MaxDeliveryCountExceeded"),
+				DeadLetterErrorDescription: ptr.Of("Message could not be consumed after 10 delivery attempts."),
+				DeadLetterSource:           ptr.Of("orders"),
+			},
+			expectedMetadata: map[string]string{
+				"metadata." + MessageKeyMessageID:                  testMessageID,
+				"metadata." + MessageKeyDeliveryCount:              "1",
+				"metadata." + MessageKeyDeadLetterReason:           "MaxDeliveryCountExceeded",
+				"metadata." + MessageKeyDeadLetterErrorDescription: "Message could not be consumed after 10 delivery attempts.",
+				"metadata." + MessageKeyDeadLetterSource:           "orders",
+			},
+		},
 	}
 
-	metadataMap := map[string]map[string]string{
-		"Nil":   nil,
-		"Empty": {},
+	metadataMap := map[string]func() map[string]string{
+		"Nil":   func() map[string]string { return nil },
+		"Empty": func() map[string]string { return map[string]string{} },
 	}
 
 	for _, tc := range testCases {
 		for mType, mMap := range metadataMap {
 			t.Run(fmt.Sprintf("%s, metadata is %s", tc.name, mType), func(t *testing.T) {
-				actual := addMessageAttributesToMetadata(mMap, &tc.ASBMessage)
+				actual := addMessageAttributesToMetadata(mMap(), &tc.ASBMessage)
 				assert.Equal(t, tc.expectedMetadata, actual)
 			})
 		}
@@ -129,3 +129,41 @@ func TestAddMetadataToMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestAddScheduledEnqueueTimeToMessage(t *testing.T) {
+	t.Run("scheduled enqueue time in RFC3339 format", func(t *testing.T) {
+		msg := &azservicebus.Message{}
+		err := addMetadataToMessage(msg, map[string]string{
+			MessageKeyScheduledEnqueueTimeUtcAlias: "2022-11-01T10:00:00Z",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2022, 11, 1, 10, 0, 0, 0, time.UTC), msg.ScheduledEnqueueTime.UTC())
+	})
+
+	t.Run("delay in seconds", func(t *testing.T) {
+		msg := &azservicebus.Message{}
+		before := time.Now()
+		err := addMetadataToMessage(msg, map[string]string{
+			MessageKeyDelaySeconds: "30",
+		})
+
+		require.NoError(t, err)
+		require.NotNil(t, msg.ScheduledEnqueueTime)
+		assert.WithinDuration(t, before.Add(30*time.Second), *msg.ScheduledEnqueueTime, time.Second)
+		assert.NotContains(t, msg.ApplicationProperties, MessageKeyDelaySeconds)
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		for _, md := range []map[string]string{
+			{MessageKeyScheduledEnqueueTimeUtc: "tomorrow"},
+			{MessageKeyDelaySeconds: "-1"},
+			{MessageKeyDelaySeconds: "soon"},
+			{MessageKeyDelaySeconds: "30", MessageKeyScheduledEnqueueTimeUtc: testScheduledEnqueueTimeUtc},
+		} {
+			err := addMetadataToMessage(&azservicebus.Message{}, md)
+
+			assert.Error(t, err, md)
+		}
+	})
+}
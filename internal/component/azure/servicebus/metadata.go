@@ -31,7 +31,7 @@ import (
 // Note: AzureAD-related keys are handled separately.
 type Metadata struct {
 	/** For bindings and pubsubs **/
-	ConnectionString                string `json:"connectionString"`
+	ConnectionString                string `json:"connectionString" mdsensitive:"true"`
 	ConsumerID                      string `json:"consumerID"` // Only topics
 	TimeoutInSec                    int    `json:"timeoutInSec"`
 	HandlerTimeoutInSec             int    `json:"handlerTimeoutInSec"`
@@ -45,10 +45,20 @@ type Metadata struct {
 	LockDurationInSec               *int   `json:"lockDurationInSec"`             // Only used during subscription creation - default is set by the server (60s)
 	DefaultMessageTimeToLiveInSec   *int   `json:"defaultMessageTimeToLiveInSec"` // Only used during subscription creation - default is set by the server (depends on the tier)
 	AutoDeleteOnIdleInSec           *int   `json:"autoDeleteOnIdleInSec"`         // Only used during subscription creation - default is set by the server (disabled)
+	ForwardDeadLetteredMessagesTo   string `json:"forwardDeadLetteredMessagesTo"` // Only used during subscription creation - default is set by the server (not forwarded)
 	MaxConcurrentHandlers           int    `json:"maxConcurrentHandlers"`
 	PublishMaxRetries               int    `json:"publishMaxRetries"`
 	PublishInitialRetryIntervalInMs int    `json:"publishInitialRetryInternalInMs"`
 	NamespaceName                   string `json:"namespaceName"` // Only for Azure AD
+	PrefetchCount                   int    `json:"prefetchCount"`
+	MaxAutoRenewDurationInSec       int    `json:"maxAutoRenewDurationInSec"`
+	DeadLetterOnHandlerError        bool   `json:"deadLetterOnHandlerError"`
+	DrainTimeoutInSec               int    `json:"drainTimeoutInSec"`
+
+	/** For pubsubs only **/
+	RequireSessions         bool `json:"requireSessions"`
+	SessionIdleTimeoutInSec int  `json:"sessionIdleTimeoutInSec"`
+	MaxConcurrentSessions   int  `json:"maxConcurrentSessions"`
 
 	/** For bindings only **/
 	QueueName string `json:"queueName"` // Only queues
@@ -70,11 +80,19 @@ const (
 	keyLockDurationInSec               = "lockDurationInSec"
 	keyDefaultMessageTimeToLiveInSec   = "defaultMessageTimeToLiveInSec" // Alias: "ttlInSeconds" (mdutils.TTLMetadataKey)
 	keyAutoDeleteOnIdleInSec           = "autoDeleteOnIdleInSec"
+	keyForwardDeadLetteredMessagesTo   = "forwardDeadLetteredMessagesTo"
 	keyMaxConcurrentHandlers           = "maxConcurrentHandlers"
 	keyPublishMaxRetries               = "publishMaxRetries"
 	keyPublishInitialRetryInternalInMs = "publishInitialRetryInternalInMs"
 	keyNamespaceName                   = "namespaceName"
 	keyQueueName                       = "queueName"
+	keyRequireSessions                 = "requireSessions"
+	keySessionIdleTimeoutInSec         = "sessionIdleTimeoutInSec"
+	keyMaxConcurrentSessions           = "maxConcurrentSessions"
+	keyPrefetchCount                   = "prefetchCount"
+	keyMaxAutoRenewDurationInSec       = "maxAutoRenewDurationInSec"
+	keyDeadLetterOnHandlerError        = "deadLetterOnHandlerError"
+	keyDrainTimeoutInSec               = "drainTimeoutInSec"
 )
 
 // Defaults.
@@ -108,8 +126,69 @@ const (
 
 	defaultPublishMaxRetries               = 5
 	defaultPublishInitialRetryInternalInMs = 500
+
+	// Default time a session is held without receiving messages, before being released.
+	defaultSessionIdleTimeoutInSec = 60
+
+	// Default number of sessions processed in parallel.
+	defaultMaxConcurrentSessions = 8
+
+	// Default time to wait for the in-flight messages to be completed when closing a subscription.
+	defaultDrainTimeoutInSec = 5
 )
 
+// MetadataSchema returns the schema of the metadata of the Service Bus components of the mode, as in ParseMetadata.
+func MetadataSchema(mode byte) []mdutils.MetadataField {
+	binding := (mode & MetadataModeBinding) != 0
+	topics := (mode & MetadataModeTopics) != 0
+
+	m := Metadata{
+		TimeoutInSec:                    defaultTimeoutInSec,
+		LockRenewalInSec:                defaultLockRenewalInSec,
+		MaxRetriableErrorsPerSec:        defaultMaxRetriableErrorsPerSec,
+		MinConnectionRecoveryInSec:      defaultMinConnectionRecoveryInSec,
+		MaxConnectionRecoveryInSec:      defaultMaxConnectionRecoveryInSec,
+		PublishMaxRetries:               defaultPublishMaxRetries,
+		PublishInitialRetryIntervalInMs: defaultPublishInitialRetryInternalInMs,
+		DrainTimeoutInSec:               defaultDrainTimeoutInSec,
+	}
+	if binding {
+		m.HandlerTimeoutInSec = defaultHandlerTimeoutInSecBinding
+		m.MaxActiveMessages = defaultMaxActiveMessagesBinding
+		m.MaxConcurrentHandlers = defaultMaxConcurrentHandlersBinding
+	} else {
+		m.HandlerTimeoutInSec = defaultHandlerTimeoutInSecPubSub
+		m.MaxActiveMessages = defaultMaxActiveMessagesPubSub
+		m.MaxConcurrentHandlers = defaultMaxConcurrentHandlersPubSub
+		m.SessionIdleTimeoutInSec = defaultSessionIdleTimeoutInSec
+		m.MaxConcurrentSessions = defaultMaxConcurrentSessions
+	}
+
+	fields, _ := mdutils.GetMetadataSchemaFromStruct(m)
+	res := fields[:0]
+	for _, f := range fields {
+		switch f.Name {
+		case keyConsumerID:
+			if !topics {
+				continue
+			}
+			f.Required = true
+		case keyQueueName:
+			if !binding || topics {
+				continue
+			}
+			f.Required = true
+		case keyRequireSessions, keySessionIdleTimeoutInSec, keyMaxConcurrentSessions:
+			if binding {
+				continue
+			}
+		}
+		res = append(res, f)
+	}
+
+	return res
+}
+
 // Modes for ParseMetadata.
 const (
 	MetadataModeBinding byte = 1 << iota
@@ -256,6 +335,69 @@ func ParseMetadata(md map[string]string, logger logger.Logger, mode byte) (m *Me
 		}
 	}
 
+	if val, ok := md[keyPrefetchCount]; ok && val != "" {
+		m.PrefetchCount, err = strconv.Atoi(val)
+		if err == nil && m.PrefetchCount < 0 {
+			err = errors.New("must not be negative")
+		}
+		if err != nil {
+			return m, fmt.Errorf("invalid prefetchCount %s: %s", val, err)
+		}
+	}
+
+	if val, ok := md[keyMaxAutoRenewDurationInSec]; ok && val != "" {
+		m.MaxAutoRenewDurationInSec, err = strconv.Atoi(val)
+		if err == nil && m.MaxAutoRenewDurationInSec < 0 {
+			err = errors.New("must not be negative")
+		}
+		if err != nil {
+			return m, fmt.Errorf("invalid maxAutoRenewDurationInSec %s: %s", val, err)
+		}
+	}
+
+	if val, ok := md[keyDeadLetterOnHandlerError]; ok && val != "" {
+		m.DeadLetterOnHandlerError = utils.IsTruthy(val)
+	}
+
+	m.DrainTimeoutInSec = defaultDrainTimeoutInSec
+	if val, ok := md[keyDrainTimeoutInSec]; ok && val != "" {
+		m.DrainTimeoutInSec, err = strconv.Atoi(val)
+		if err == nil && m.DrainTimeoutInSec < 0 {
+			err = errors.New("must not be negative")
+		}
+		if err != nil {
+			return m, fmt.Errorf("invalid drainTimeoutInSec %s: %s", val, err)
+		}
+	}
+
+	if (mode & MetadataModeBinding) == 0 {
+		if val, ok := md[keyRequireSessions]; ok && val != "" {
+			m.RequireSessions = utils.IsTruthy(val)
+		}
+
+		m.SessionIdleTimeoutInSec = defaultSessionIdleTimeoutInSec
+		if val, ok := md[keySessionIdleTimeoutInSec]; ok && val != "" {
+			m.SessionIdleTimeoutInSec, err = strconv.Atoi(val)
+			if err == nil && m.SessionIdleTimeoutInSec < 1 {
+				err = errors.New("must be 1 or greater")
+			}
+			if err != nil {
+				return m, fmt.Errorf("invalid sessionIdleTimeoutInSec %s: %s", val, err)
+			}
+		}
+
+		m.MaxConcurrentSessions = defaultMaxConcurrentSessions
+		if val, ok := md[keyMaxConcurrentSessions]; ok && val != "" {
+			m.MaxConcurrentSessions, err = strconv.Atoi(val)
+			if err == nil && m.MaxConcurrentSessions < 1 {
+				err = errors.New("must be 1 or greater")
+			}
+			if err != nil {
+				return m, fmt.Errorf("invalid maxConcurrentSessions %s: %s", val, err)
+			}
+		}
+	}
+
 	/* Nullable configuration settings - defaults will be set by the server. */
 	if val, ok := md[keyMaxDeliveryCount]; ok && val != "" {
 		var valAsInt int64
@@ -306,9 +448,28 @@ func ParseMetadata(md map[string]string, logger logger.Logger, mode byte) (m *Me
 		m.AutoDeleteOnIdleInSec = &valAsInt
 	}
 
+	m.ForwardDeadLetteredMessagesTo = md[keyForwardDeadLetteredMessagesTo]
+
 	return m, nil
 }
 
+// SubscriptionOptions returns the options of a Subscription to the entity, which is only used for logging.
+// maxBulkSubCount is nil for subscriptions which are not bulk.
+func (a Metadata) SubscriptionOptions(entity string, maxBulkSubCount *int) SubscriptionOptions {
+	return SubscriptionOptions{
+		MaxActiveMessages:         a.MaxActiveMessages,
+		TimeoutInSec:              a.TimeoutInSec,
+		MaxBulkSubCount:           maxBulkSubCount,
+		MaxRetriableEPS:           a.MaxRetriableErrorsPerSec,
+		MaxConcurrentHandlers:     a.MaxConcurrentHandlers,
+		Entity:                    entity,
+		PrefetchCount:             a.PrefetchCount,
+		MaxAutoRenewDurationInSec: a.MaxAutoRenewDurationInSec,
+		DeadLetterOnHandlerError:  a.DeadLetterOnHandlerError,
+		DrainTimeoutInSec:         a.DrainTimeoutInSec,
+	}
+}
+
 // CreateSubscriptionProperties returns the SubscriptionProperties object to create new Subscriptions to Service Bus topics.
 func (a Metadata) CreateSubscriptionProperties() *sbadmin.SubscriptionProperties {
 	properties := &sbadmin.SubscriptionProperties{}
@@ -329,6 +490,14 @@ func (a Metadata) CreateSubscriptionProperties() *sbadmin.SubscriptionProperties
 		properties.AutoDeleteOnIdle = toDurationISOString(*a.AutoDeleteOnIdleInSec)
 	}
 
+	if a.RequireSessions {
+		properties.RequiresSession = ptr.Of(true)
+	}
+
+	if a.ForwardDeadLetteredMessagesTo != "" {
+		properties.ForwardDeadLetteredMessagesTo = ptr.Of(a.ForwardDeadLetteredMessagesTo)
+	}
+
 	return properties
 }
 
@@ -352,6 +521,14 @@ func (a Metadata) CreateQueueProperties() *sbadmin.QueueProperties {
 		properties.AutoDeleteOnIdle = toDurationISOString(*a.AutoDeleteOnIdleInSec)
 	}
 
+	if a.RequireSessions {
+		properties.RequiresSession = ptr.Of(true)
+	}
+
+	if a.ForwardDeadLetteredMessagesTo != "" {
+		properties.ForwardDeadLetteredMessagesTo = ptr.Of(a.ForwardDeadLetteredMessagesTo)
+	}
+
 	return properties
 }
 
@@ -14,9 +14,12 @@ limitations under the License.
 package servicebus
 
 import (
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	mdutils "github.com/dapr/components-contrib/metadata"
 )
 
 const invalidNumber = "invalid_number"
@@ -468,6 +471,156 @@ func TestParseServiceBusMetadata(t *testing.T) {
 		assert.Error(t, err)
 	})
 
+	t.Run("sessions in pubsub", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeProperties[keyRequireSessions] = "true"
+		fakeProperties[keySessionIdleTimeoutInSec] = "30"
+		fakeProperties[keyMaxConcurrentSessions] = "2"
+
+		// act.
+		m, err := ParseMetadata(fakeProperties, nil, MetadataModeTopics)
+
+		// assert.
+		assert.Nil(t, err)
+		assert.True(t, m.RequireSessions)
+		assert.Equal(t, 30, m.SessionIdleTimeoutInSec)
+		assert.Equal(t, 2, m.MaxConcurrentSessions)
+		assert.True(t, *m.CreateSubscriptionProperties().RequiresSession)
+		assert.True(t, *m.CreateQueueProperties().RequiresSession)
+	})
+
+	t.Run("missing optional sessions settings", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+
+		// act.
+		m, err := ParseMetadata(fakeProperties, nil, 0)
+
+		// assert.
+		assert.Nil(t, err)
+		assert.False(t, m.RequireSessions)
+		assert.Equal(t, defaultSessionIdleTimeoutInSec, m.SessionIdleTimeoutInSec)
+		assert.Equal(t, defaultMaxConcurrentSessions, m.MaxConcurrentSessions)
+		assert.Nil(t, m.CreateQueueProperties().RequiresSession)
+	})
+
+	t.Run("sessions ignored in binding", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeProperties[keyRequireSessions] = "true"
+
+		// act.
+		m, err := ParseMetadata(fakeProperties, nil, MetadataModeBinding)
+
+		// assert.
+		assert.Nil(t, err)
+		assert.False(t, m.RequireSessions)
+	})
+
+	t.Run("invalid optional sessionIdleTimeoutInSec", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeProperties[keySessionIdleTimeoutInSec] = "0"
+
+		// act.
+		_, err := ParseMetadata(fakeProperties, nil, 0)
+
+		// assert.
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid optional maxConcurrentSessions", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeProperties[keyMaxConcurrentSessions] = invalidNumber
+
+		// act.
+		_, err := ParseMetadata(fakeProperties, nil, 0)
+
+		// assert.
+		assert.Error(t, err)
+	})
+
+	t.Run("receive settings", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeProperties[keyPrefetchCount] = "20"
+		fakeProperties[keyMaxAutoRenewDurationInSec] = "300"
+		fakeProperties[keyDeadLetterOnHandlerError] = "true"
+		fakeProperties[keyDrainTimeoutInSec] = "30"
+
+		// act.
+		m, err := ParseMetadata(fakeProperties, nil, MetadataModeBinding)
+
+		// assert.
+		assert.Nil(t, err)
+		assert.Equal(t, 20, m.PrefetchCount)
+		assert.Equal(t, 300, m.MaxAutoRenewDurationInSec)
+		assert.True(t, m.DeadLetterOnHandlerError)
+		assert.Equal(t, 30, m.DrainTimeoutInSec)
+
+		opts := m.SubscriptionOptions("queue test", nil)
+		assert.Equal(t, 20, opts.PrefetchCount)
+		assert.Equal(t, 300, opts.MaxAutoRenewDurationInSec)
+		assert.True(t, opts.DeadLetterOnHandlerError)
+		assert.Equal(t, 30, opts.DrainTimeoutInSec)
+	})
+
+	t.Run("missing optional receive settings", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+
+		// act.
+		m, err := ParseMetadata(fakeProperties, nil, MetadataModeBinding)
+
+		// assert.
+		assert.Nil(t, err)
+		assert.Equal(t, 0, m.PrefetchCount)
+		assert.Equal(t, 0, m.MaxAutoRenewDurationInSec)
+		assert.False(t, m.DeadLetterOnHandlerError)
+		assert.Equal(t, defaultDrainTimeoutInSec, m.DrainTimeoutInSec)
+	})
+
+	t.Run("invalid optional prefetchCount", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeProperties[keyPrefetchCount] = "-1"
+
+		// act.
+		_, err := ParseMetadata(fakeProperties, nil, MetadataModeBinding)
+
+		// assert.
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid optional maxAutoRenewDurationInSec", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeProperties[keyMaxAutoRenewDurationInSec] = invalidNumber
+
+		// act.
+		_, err := ParseMetadata(fakeProperties, nil, MetadataModeBinding)
+
+		// assert.
+		assert.Error(t, err)
+	})
+
+	t.Run("forwardDeadLetteredMessagesTo", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeProperties[keyForwardDeadLetteredMessagesTo] = "dlq"
+
+		// act.
+		m, err := ParseMetadata(fakeProperties, nil, MetadataModeTopics)
+
+		// assert.
+		assert.Nil(t, err)
+		assert.Equal(t, "dlq", *m.CreateSubscriptionProperties().ForwardDeadLetteredMessagesTo)
+		assert.Equal(t, "dlq", *m.CreateQueueProperties().ForwardDeadLetteredMessagesTo)
+	})
+
+	t.Run("missing optional forwardDeadLetteredMessagesTo", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+
+		// act.
+		m, err := ParseMetadata(fakeProperties, nil, MetadataModeTopics)
+
+		// assert.
+		assert.Nil(t, err)
+		assert.Nil(t, m.CreateSubscriptionProperties().ForwardDeadLetteredMessagesTo)
+	})
+
 	t.Run("missing nullable maxDeliveryCount", func(t *testing.T) {
 		fakeProperties := getFakeProperties()
 		fakeProperties[keyMaxDeliveryCount] = ""
@@ -560,3 +713,47 @@ func TestParseServiceBusMetadata(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestMetadataSchema(t *testing.T) {
+	schema := func(mode byte) map[string]mdutils.MetadataField {
+		fields := map[string]mdutils.MetadataField{}
+		for _, f := range MetadataSchema(mode) {
+			fields[f.Name] = f
+		}
+		return fields
+	}
+
+	t.Run("topics", func(t *testing.T) {
+		fields := schema(MetadataModeTopics)
+
+		assert.True(t, fields[keyConsumerID].Required)
+		assert.True(t, fields[keyConnectionString].Sensitive)
+		assert.NotContains(t, fields, keyQueueName)
+		assert.Equal(t, "1000", fields[keyMaxActiveMessages].Default)
+		assert.Equal(t, "60", fields[keyHandlerTimeoutInSec].Default)
+		assert.Equal(t, "8", fields[keyMaxConcurrentSessions].Default)
+		assert.Equal(t, "", fields[keyMaxDeliveryCount].Default)
+	})
+
+	t.Run("queues binding", func(t *testing.T) {
+		fields := schema(MetadataModeBinding | MetadataModeQueues)
+
+		assert.True(t, fields[keyQueueName].Required)
+		assert.NotContains(t, fields, keyConsumerID)
+		assert.NotContains(t, fields, keyRequireSessions)
+		assert.Equal(t, "1", fields[keyMaxActiveMessages].Default)
+		assert.Equal(t, "", fields[keyHandlerTimeoutInSec].Default)
+		assert.Equal(t, "1", fields[keyMaxConcurrentHandlers].Default)
+	})
+
+	t.Run("defaults match the parsed metadata", func(t *testing.T) {
+		m, err := ParseMetadata(map[string]string{keyConnectionString: "fake"}, nil, MetadataModeQueues)
+		assert.NoError(t, err)
+
+		fields := schema(MetadataModeQueues)
+		assert.Equal(t, strconv.Itoa(m.TimeoutInSec), fields[keyTimeoutInSec].Default)
+		assert.Equal(t, strconv.Itoa(m.PublishInitialRetryIntervalInMs), fields[keyPublishInitialRetryInternalInMs].Default)
+		assert.Equal(t, strconv.Itoa(m.DrainTimeoutInSec), fields[keyDrainTimeoutInSec].Default)
+		assert.Equal(t, strconv.Itoa(m.SessionIdleTimeoutInSec), fields[keySessionIdleTimeoutInSec].Default)
+	})
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicebus
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	azservicebus "github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+
+	"github.com/dapr/components-contrib/internal/utils"
+)
+
+// SubscribeKeyDeadLetter is the key of the subscribe request metadata to receive the messages of the dead-letter
+// subqueue of the topic subscription or queue, instead of the messages of the topic subscription or queue.
+const SubscribeKeyDeadLetter = "deadLetter"
+
+// ErrSessionIdle is returned when no message is received from a session within its idle timeout.
+var ErrSessionIdle = errors.New("session idle")
+
+// Receiver is the interface for receivers of messages from a queue or a topic subscription, with or without sessions.
+type Receiver interface {
+	ReceiveMessages(ctx context.Context, maxMessages int, options *azservicebus.ReceiveMessagesOptions) ([]*azservicebus.ReceivedMessage, error)
+	CompleteMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.CompleteMessageOptions) error
+	AbandonMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.AbandonMessageOptions) error
+	DeadLetterMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.DeadLetterOptions) error
+	Close(ctx context.Context) error
+}
+
+// IsDeadLetterSubscription returns true if the subscribe request metadata selects the dead-letter subqueue.
+func IsDeadLetterSubscription(md map[string]string) bool {
+	return utils.IsTruthy(md[SubscribeKeyDeadLetter])
+}
+
+// NewReceiverOptions returns the options of the receivers for the subscribe request metadata.
+func NewReceiverOptions(md map[string]string) *azservicebus.ReceiverOptions {
+	if IsDeadLetterSubscription(md) {
+		return &azservicebus.ReceiverOptions{SubQueue: azservicebus.SubQueueDeadLetter}
+	}
+
+	return nil
+}
+
+// MessageReceiver is a Receiver for queues and topic subscriptions without sessions.
+type MessageReceiver struct {
+	*azservicebus.Receiver
+}
+
+// NewMessageReceiver returns a new MessageReceiver.
+func NewMessageReceiver(r *azservicebus.Receiver) *MessageReceiver {
+	return &MessageReceiver{Receiver: r}
+}
+
+// SessionReceiver is a Receiver for a session of a queue or topic subscription which requires sessions.
+// The messages of a session are received in order.
+type SessionReceiver struct {
+	*azservicebus.SessionReceiver
+
+	idleTimeout time.Duration
+}
+
+// NewSessionReceiver returns a new SessionReceiver.
+// The session is released when no message is received within idleTimeout, if greater than 0.
+func NewSessionReceiver(r *azservicebus.SessionReceiver, idleTimeout time.Duration) *SessionReceiver {
+	return &SessionReceiver{
+		SessionReceiver: r,
+		idleTimeout:     idleTimeout,
+	}
+}
+
+// ReceiveMessages receives messages from the session, returning ErrSessionIdle if none is received within the idle timeout.
+func (r *SessionReceiver) ReceiveMessages(ctx context.Context, maxMessages int, options *azservicebus.ReceiveMessagesOptions) ([]*azservicebus.ReceivedMessage, error) {
+	if r.idleTimeout <= 0 {
+		return r.SessionReceiver.ReceiveMessages(ctx, maxMessages, options)
+	}
+
+	idleCtx, idleCancel := context.WithTimeout(ctx, r.idleTimeout)
+	defer idleCancel()
+	msgs, err := r.SessionReceiver.ReceiveMessages(idleCtx, maxMessages, options)
+	if err != nil && idleCtx.Err() != nil && ctx.Err() == nil {
+		return nil, ErrSessionIdle
+	}
+
+	return msgs, err
+}
@@ -0,0 +1,34 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicebus
+
+import (
+	"testing"
+
+	azservicebus "github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewReceiverOptions(t *testing.T) {
+	t.Run("dead-letter subqueue", func(t *testing.T) {
+		opts := NewReceiverOptions(map[string]string{SubscribeKeyDeadLetter: "true"})
+
+		assert.Equal(t, azservicebus.SubQueueDeadLetter, opts.SubQueue)
+	})
+
+	t.Run("queue or subscription", func(t *testing.T) {
+		assert.Nil(t, NewReceiverOptions(map[string]string{}))
+		assert.Nil(t, NewReceiverOptions(map[string]string{SubscribeKeyDeadLetter: "false"}))
+	})
+}
@@ -23,6 +23,7 @@ import (
 	azservicebus "github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
 	"go.uber.org/ratelimit"
 
+	"github.com/dapr/components-contrib/internal/utils"
 	"github.com/dapr/kit/logger"
 	"github.com/dapr/kit/ptr"
 	"github.com/dapr/kit/retry"
@@ -39,34 +40,61 @@ type HandlerFunc func(ctx context.Context, msgs []*azservicebus.ReceivedMessage)
 
 // Subscription is an object that manages a subscription to an Azure Service Bus receiver, for a topic or queue.
 type Subscription struct {
-	entity               string
-	mu                   sync.RWMutex
-	activeMessages       map[int64]*azservicebus.ReceivedMessage
-	activeOperationsChan chan struct{}
-	receiver             *azservicebus.Receiver
-	timeout              time.Duration
-	maxBulkSubCount      int
-	retriableErrLimit    ratelimit.Limiter
-	handleChan           chan struct{}
-	logger               logger.Logger
-	ctx                  context.Context
-	cancel               context.CancelFunc
+	entity                   string
+	mu                       sync.RWMutex
+	activeMessages           map[int64]activeMessage
+	activeOperationsChan     chan struct{}
+	receiver                 Receiver
+	timeout                  time.Duration
+	maxBulkSubCount          int
+	prefetchCount            int
+	maxAutoRenewDuration     time.Duration
+	deadLetterOnHandlerError bool
+	drainTimeout             time.Duration
+	inFlight                 *utils.InFlight
+	inFlightLock             sync.Mutex
+	retriableErrLimit        ratelimit.Limiter
+	handleChan               chan struct{}
+	logger                   logger.Logger
+	ctx                      context.Context
+	cancel                   context.CancelFunc
 }
 
-// NewBulkSubscription returns a new Subscription object.
-// Parameter "entity" is usually in the format "topic <topicname>" or "queue <queuename>" and it's only used for logging.
-func NewSubscription(
-	parentCtx context.Context,
-	maxActiveMessages int,
-	timeoutInSec int,
-	maxBulkSubCount *int,
-	maxRetriableEPS int,
-	maxConcurrentHandlers int,
-	entity string,
-	logger logger.Logger,
-) *Subscription {
+// SubscriptionOptions are the options of a Subscription.
+type SubscriptionOptions struct {
+	MaxActiveMessages     int
+	TimeoutInSec          int
+	MaxBulkSubCount       *int
+	MaxRetriableEPS       int
+	MaxConcurrentHandlers int
+	// Entity is usually in the format "topic <topicname>" or "queue <queuename>" and it's only used for logging.
+	Entity string
+	// PrefetchCount is the number of messages received at once and buffered, when greater than 1.
+	// It's ignored for bulk subscriptions.
+	PrefetchCount int
+	// MaxAutoRenewDurationInSec is the maximum time the lock of a message is renewed for, when greater than 0.
+	MaxAutoRenewDurationInSec int
+	// DeadLetterOnHandlerError moves the messages whose handler returns an error to the dead-letter queue, instead of
+	// abandoning them.
+	DeadLetterOnHandlerError bool
+	// DrainTimeoutInSec is the time Close waits for the messages being handled to be completed, before canceling
+	// their handlers and abandoning them.
+	DrainTimeoutInSec int
+}
+
+// activeMessage is a message being processed or buffered.
+type activeMessage struct {
+	msg        *azservicebus.ReceivedMessage
+	receivedAt time.Time
+}
+
+// NewSubscription returns a new Subscription object.
+func NewSubscription(parentCtx context.Context, opts SubscriptionOptions, logger logger.Logger) *Subscription {
 	ctx, cancel := context.WithCancel(parentCtx)
 
+	maxActiveMessages := opts.MaxActiveMessages
+	maxBulkSubCount := opts.MaxBulkSubCount
+
 	if maxBulkSubCount != nil {
 		if *maxBulkSubCount < 1 {
 			logger.Warnf("maxBulkSubCount must be greater than 0, setting it to 1")
@@ -83,34 +111,39 @@ func NewSubscription(
 	}
 
 	s := &Subscription{
-		entity:          entity,
-		activeMessages:  make(map[int64]*azservicebus.ReceivedMessage),
-		timeout:         time.Duration(timeoutInSec) * time.Second,
-		maxBulkSubCount: *maxBulkSubCount,
-		logger:          logger,
-		ctx:             ctx,
-		cancel:          cancel,
+		entity:                   opts.Entity,
+		activeMessages:           make(map[int64]activeMessage),
+		timeout:                  time.Duration(opts.TimeoutInSec) * time.Second,
+		maxBulkSubCount:          *maxBulkSubCount,
+		prefetchCount:            opts.PrefetchCount,
+		maxAutoRenewDuration:     time.Duration(opts.MaxAutoRenewDurationInSec) * time.Second,
+		deadLetterOnHandlerError: opts.DeadLetterOnHandlerError,
+		drainTimeout:             time.Duration(opts.DrainTimeoutInSec) * time.Second,
+		inFlight:                 utils.NewInFlight(),
+		logger:                   logger,
+		ctx:                      ctx,
+		cancel:                   cancel,
 		// This is a pessimistic estimate of the number of total operations that can be active at any given time.
 		// In case of a non-bulk subscription, one operation is one message.
 		activeOperationsChan: make(chan struct{}, maxActiveMessages/(*maxBulkSubCount)),
 	}
 
-	if maxRetriableEPS > 0 {
-		s.retriableErrLimit = ratelimit.New(maxRetriableEPS)
+	if opts.MaxRetriableEPS > 0 {
+		s.retriableErrLimit = ratelimit.New(opts.MaxRetriableEPS)
 	} else {
 		s.retriableErrLimit = ratelimit.NewUnlimited()
 	}
 
-	if maxConcurrentHandlers > 0 {
-		s.logger.Debugf("Subscription to %s is limited to %d message handler(s)", entity, maxConcurrentHandlers)
-		s.handleChan = make(chan struct{}, maxConcurrentHandlers)
+	if opts.MaxConcurrentHandlers > 0 {
+		s.logger.Debugf("Subscription to %s is limited to %d message handler(s)", opts.Entity, opts.MaxConcurrentHandlers)
+		s.handleChan = make(chan struct{}, opts.MaxConcurrentHandlers)
 	}
 
 	return s
 }
 
 // Connect to a Service Bus topic or queue, blocking until it succeeds; it can retry forever (until the context is canceled).
-func (s *Subscription) Connect(newReceiverFunc func() (*azservicebus.Receiver, error)) error {
+func (s *Subscription) Connect(newReceiverFunc func() (Receiver, error)) error {
 	// Connections need to retry forever with a maximum backoff of 5 minutes and exponential scaling.
 	config := retry.DefaultConfig()
 	config.Policy = retry.PolicyExponential
@@ -164,6 +197,19 @@ func (s *Subscription) ReceiveAndBlock(handler HandlerFunc, lockRenewalInSec int
 		}
 	}()
 
+	// Number of messages requested to Service Bus at once
+	receiveCount := s.maxBulkSubCount
+	prefetch := !bulkEnabled && s.prefetchCount > 1
+	if prefetch {
+		receiveCount = s.prefetchCount
+	}
+
+	// Messages received in advance and not handled yet, which are abandoned if the loop ends
+	var prefetched []*azservicebus.ReceivedMessage
+	defer func() {
+		s.abandonPrefetched(prefetched)
+	}()
+
 	// Receiver loop
 	for {
 		select {
@@ -177,10 +223,21 @@ func (s *Subscription) ReceiveAndBlock(handler HandlerFunc, lockRenewalInSec int
 			return ctx.Err()
 		}
 
+		// Handle the next buffered message, if any
+		if len(prefetched) > 0 {
+			msgs := prefetched[:1]
+			prefetched = prefetched[1:]
+			s.logger.Debugf("Processing prefetched message: %s", msgs[0].MessageID)
+			s.dispatch(msgs, handler, bulkEnabled)
+			continue
+		}
+
 		// This method blocks until we get a message or the context is canceled
-		msgs, err := s.receiver.ReceiveMessages(s.ctx, s.maxBulkSubCount, nil)
+		msgs, err := s.receiver.ReceiveMessages(s.ctx, receiveCount, nil)
 		if err != nil {
-			if err != context.Canceled {
+			if errors.Is(err, ErrSessionIdle) {
+				s.logger.Debugf("Session %s on %s is idle", s.sessionID(), s.entity)
+			} else if err != context.Canceled {
 				s.logger.Errorf("Error reading from %s. %s", s.entity, err.Error())
 			}
 			<-s.activeOperationsChan
@@ -195,9 +252,9 @@ func (s *Subscription) ReceiveAndBlock(handler HandlerFunc, lockRenewalInSec int
 			<-s.activeOperationsChan
 			// Return an error to force the Service Bus component to try and reconnect.
 			return errors.New("received 0 messages from Service Bus")
-		} else if l > 1 {
-			// We are requesting one message only; this should never happen
-			s.logger.Errorf("Expected one message from Service Bus, but received %d", l)
+		} else if l > receiveCount {
+			// This should never happen
+			s.logger.Errorf("Expected up to %d messages from Service Bus, but received %d", receiveCount, l)
 		}
 
 		// Invoke only once
@@ -226,70 +283,116 @@ func (s *Subscription) ReceiveAndBlock(handler HandlerFunc, lockRenewalInSec int
 			continue
 		}
 
-		runHandlerFn := func(hctx context.Context) {
-			msg := msgs[0]
+		// When prefetching, the messages are handled one at a time and the others are buffered
+		if prefetch {
+			prefetched = msgs[1:]
+			msgs = msgs[:1]
+		}
 
-			// Invoke the handler to process the message
-			_, err = handler(hctx, msgs)
+		s.dispatch(msgs, handler, bulkEnabled)
+	}
+}
 
-			// This context is used for the calls to service bus to finalize (i.e. complete/abandon) the message.
-			// If we fail to finalize the message, this message will eventually be reprocessed (at-least once delivery).
-			// This uses a background context in case ctx has been canceled already.
-			finalizeCtx, finalizeCancel := context.WithTimeout(context.Background(), s.timeout)
-			defer finalizeCancel()
+// dispatch invokes the handler for the messages, and completes or abandons them.
+// The messages of a session are handled synchronously, as they must be processed in order.
+func (s *Subscription) dispatch(msgs []*azservicebus.ReceivedMessage, handler HandlerFunc, bulkEnabled bool) {
+	runHandlerFn := func(hctx context.Context) {
+		msg := msgs[0]
 
-			if err != nil {
-				// Log the error only, as we're running asynchronously
-				s.logger.Errorf("App handler returned an error for message %s on %s: %s", msg.MessageID, s.entity, err)
-				s.AbandonMessage(finalizeCtx, msg)
-				return
-			}
+		// Invoke the handler to process the message
+		_, err := handler(hctx, msgs)
 
-			s.CompleteMessage(finalizeCtx, msg)
+		// This context is used for the calls to service bus to finalize (i.e. complete/abandon) the message.
+		// If we fail to finalize the message, this message will eventually be reprocessed (at-least once delivery).
+		// This uses a background context in case ctx has been canceled already.
+		finalizeCtx, finalizeCancel := context.WithTimeout(context.Background(), s.timeout)
+		defer finalizeCancel()
+
+		if err != nil {
+			// Log the error only, as we're running asynchronously
+			s.logger.Errorf("App handler returned an error for message %s on %s: %s", msg.MessageID, s.entity, err)
+			s.settleFailedMessage(finalizeCtx, msg, err)
+			return
 		}
 
-		bulkRunHandlerFunc := func(hctx context.Context) {
-			resps, err := handler(hctx, msgs)
-
-			// This context is used for the calls to service bus to finalize (i.e. complete/abandon) the message.
-			// If we fail to finalize the message, this message will eventually be reprocessed (at-least once delivery).
-			// This uses a background context in case ctx has been canceled already.
-			finalizeCtx, finalizeCancel := context.WithTimeout(context.Background(), s.timeout)
-			defer finalizeCancel()
-
-			if err != nil {
-				// Handle the error and mark messages accordingly.
-				// Note, the order of the responses match the order of the messages.
-				for i, resp := range resps {
-					if resp.Error != nil {
-						// Log the error only, as we're running asynchronously.
-						s.logger.Errorf("App handler returned an error for message %s on %s: %s", msgs[i].MessageID, s.entity, resp.Error)
-						s.AbandonMessage(finalizeCtx, msgs[i])
-					} else {
-						s.CompleteMessage(finalizeCtx, msgs[i])
-					}
-				}
-				return
-			}
+		s.CompleteMessage(finalizeCtx, msg)
+	}
 
-			// No error, so we can complete all messages.
-			for _, msg := range msgs {
-				s.CompleteMessage(finalizeCtx, msg)
+	bulkRunHandlerFunc := func(hctx context.Context) {
+		resps, err := handler(hctx, msgs)
+
+		// This context is used for the calls to service bus to finalize (i.e. complete/abandon) the message.
+		// If we fail to finalize the message, this message will eventually be reprocessed (at-least once delivery).
+		// This uses a background context in case ctx has been canceled already.
+		finalizeCtx, finalizeCancel := context.WithTimeout(context.Background(), s.timeout)
+		defer finalizeCancel()
+
+		if err != nil {
+			// Handle the error and mark messages accordingly.
+			// Note, the order of the responses match the order of the messages.
+			for i, resp := range resps {
+				if resp.Error != nil {
+					// Log the error only, as we're running asynchronously.
+					s.logger.Errorf("App handler returned an error for message %s on %s: %s", msgs[i].MessageID, s.entity, resp.Error)
+					s.settleFailedMessage(finalizeCtx, msgs[i], resp.Error)
+				} else {
+					s.CompleteMessage(finalizeCtx, msgs[i])
+				}
 			}
+			return
 		}
 
-		if bulkEnabled {
-			s.handleAsync(s.ctx, msgs, bulkRunHandlerFunc)
-		} else {
-			s.handleAsync(s.ctx, msgs, runHandlerFn)
+		// No error, so we can complete all messages.
+		for _, msg := range msgs {
+			s.CompleteMessage(finalizeCtx, msg)
 		}
 	}
+
+	handlerFn := runHandlerFn
+	if bulkEnabled {
+		handlerFn = bulkRunHandlerFunc
+	}
+
+	// The messages received while closing are abandoned right away
+	s.inFlightLock.Lock()
+	inFlight := s.inFlight
+	s.inFlightLock.Unlock()
+	if !inFlight.Add() {
+		s.abandonPrefetched(msgs)
+		<-s.activeOperationsChan
+		return
+	}
+
+	// Handlers use the context of the in-flight messages, so they are not canceled until the subscription is drained
+	if _, ok := s.receiver.(*SessionReceiver); ok {
+		// The messages of a session are processed in order, so the next ones are received only once these are finalized
+		s.handle(inFlight.Context(), msgs, handlerFn)
+		inFlight.Done()
+	} else {
+		go func() {
+			s.handle(inFlight.Context(), msgs, handlerFn)
+			inFlight.Done()
+		}()
+	}
 }
 
 // Close the receiver and stops watching for new messages.
 func (s *Subscription) Close(closeCtx context.Context) {
 	s.logger.Debugf("Closing subscription to %s", s.entity)
 
+	// Wait for the messages being handled to be completed while the receiver is still open.
+	// When the drain timeout expires, their handlers are canceled and the messages are abandoned.
+	s.inFlightLock.Lock()
+	inFlight := s.inFlight
+	s.inFlightLock.Unlock()
+	if !inFlight.Drain(closeCtx, s.drainTimeout) {
+		s.logger.Warnf("Not all the in-flight messages on %s were completed within %s", s.entity, s.drainTimeout)
+	}
+	// Reset the in-flight messages, in case the subscription reconnects
+	s.inFlightLock.Lock()
+	s.inFlight = utils.NewInFlight()
+	s.inFlightLock.Unlock()
+
 	// Ensure subscription entity is closed.
 	if err := s.receiver.Close(closeCtx); err != nil {
 		s.logger.Warnf("Error closing subscription for %s: %+v", s.entity, err)
@@ -297,69 +400,86 @@ func (s *Subscription) Close(closeCtx context.Context) {
 	s.cancel()
 }
 
-// handleAsync handles messages from azure service bus asynchronously.
+// handle handles messages from azure service bus.
 // runHandlerFn is responsible for calling the message handler function
 // and marking messages as complete/abandon.
-func (s *Subscription) handleAsync(ctx context.Context, msgs []*azservicebus.ReceivedMessage, runHandlerFn func(ctx context.Context)) {
-	go func() {
-		var (
-			consumeToken           bool
-			takenConcurrentHandler bool
-		)
-
-		defer func() {
-			for _, msg := range msgs {
-				// Release a handler if needed
-				if takenConcurrentHandler {
-					<-s.handleChan
-					s.logger.Debugf("Released message handle for %s on %s", msg.MessageID, s.entity)
-				}
+func (s *Subscription) handle(ctx context.Context, msgs []*azservicebus.ReceivedMessage, runHandlerFn func(ctx context.Context)) {
+	var (
+		consumeToken           bool
+		takenConcurrentHandler bool
+	)
 
-				// If we got a retriable error (app handler returned a retriable error, or a network error while connecting to the app, etc) consume a retriable error token
-				// We do it here, after the handler has been released but before removing the active message (which would allow us to retrieve more messages)
-				if consumeToken {
-					s.logger.Debugf("Taking a retriable error token")
-					before := time.Now()
-					_ = s.retriableErrLimit.Take()
-					s.logger.Debugf("Resumed after pausing for %v", time.Since(before))
-				}
+	defer func() {
+		for _, msg := range msgs {
+			// Release a handler if needed
+			if takenConcurrentHandler {
+				<-s.handleChan
+				s.logger.Debugf("Released message handle for %s on %s", msg.MessageID, s.entity)
+			}
 
-				// Remove the message from the map of active ones
-				s.removeActiveMessage(msg.MessageID, *msg.SequenceNumber)
+			// If we got a retriable error (app handler returned a retriable error, or a network error while connecting to the app, etc) consume a retriable error token
+			// We do it here, after the handler has been released but before removing the active message (which would allow us to retrieve more messages)
+			if consumeToken {
+				s.logger.Debugf("Taking a retriable error token")
+				before := time.Now()
+				_ = s.retriableErrLimit.Take()
+				s.logger.Debugf("Resumed after pausing for %v", time.Since(before))
 			}
 
-			// Remove an entry from activeOperationsChan to allow processing more messages
-			<-s.activeOperationsChan
-		}()
+			// Remove the message from the map of active ones
+			s.removeActiveMessage(msg.MessageID, *msg.SequenceNumber)
+		}
 
-		for _, msg := range msgs {
-			// If handleChan is non-nil, we have a limit on how many handler we can process
-			if cap(s.handleChan) > 0 {
-				s.logger.Debugf("Taking message handle for %s on %s", msg.MessageID, s.entity)
-				select {
-				// Context is done, so we will stop waiting
-				case <-ctx.Done():
-					s.logger.Debugf("Message context done for %s on %s", msg.MessageID, s.entity)
-					return
-				// Blocks until we have a handler available
-				case s.handleChan <- struct{}{}:
-					takenConcurrentHandler = true
-					s.logger.Debugf("Taken message handle for %s on %s", msg.MessageID, s.entity)
-				}
+		// Remove an entry from activeOperationsChan to allow processing more messages
+		<-s.activeOperationsChan
+	}()
+
+	for _, msg := range msgs {
+		// If handleChan is non-nil, we have a limit on how many handler we can process
+		if cap(s.handleChan) > 0 {
+			s.logger.Debugf("Taking message handle for %s on %s", msg.MessageID, s.entity)
+			select {
+			// Context is done, so we will stop waiting
+			case <-ctx.Done():
+				s.logger.Debugf("Message context done for %s on %s", msg.MessageID, s.entity)
+				return
+			// Blocks until we have a handler available
+			case s.handleChan <- struct{}{}:
+				takenConcurrentHandler = true
+				s.logger.Debugf("Taken message handle for %s on %s", msg.MessageID, s.entity)
 			}
 		}
+	}
 
-		// Invoke the handler to process the message.
-		runHandlerFn(ctx)
-	}()
+	// Invoke the handler to process the message.
+	runHandlerFn(ctx)
 }
 
 func (s *Subscription) tryRenewLocks() {
+	// The lock of a session covers all its messages, and is renewed also while waiting for messages.
+	if sessionReceiver, ok := s.receiver.(*SessionReceiver); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		defer cancel()
+		err := sessionReceiver.RenewSessionLock(ctx, nil)
+		if err != nil {
+			s.logger.Debugf("Couldn't renew the lock of session %s for %s: %v", sessionReceiver.SessionID(), s.entity, err)
+		}
+		return
+	}
+	messageReceiver, ok := s.receiver.(*MessageReceiver)
+	if !ok {
+		return
+	}
+
 	// Snapshot the messages to try to renew locks for.
+	// The locks of the messages received for longer than maxAutoRenewDuration are not renewed anymore.
 	msgs := make([]*azservicebus.ReceivedMessage, 0)
 	s.mu.RLock()
 	for _, m := range s.activeMessages {
-		msgs = append(msgs, m)
+		if s.maxAutoRenewDuration > 0 && time.Since(m.receivedAt) > s.maxAutoRenewDuration {
+			continue
+		}
+		msgs = append(msgs, m.msg)
 	}
 	s.mu.RUnlock()
 	if len(msgs) == 0 {
@@ -374,7 +494,7 @@ func (s *Subscription) tryRenewLocks() {
 	var cancel context.CancelFunc
 	for _, msg := range msgs {
 		ctx, cancel = context.WithTimeout(context.Background(), s.timeout)
-		err = s.receiver.RenewMessageLock(ctx, msg, nil)
+		err = messageReceiver.RenewMessageLock(ctx, msg, nil)
 		if err != nil {
 			s.logger.Debugf("Couldn't renew all active message lock(s) for %s, ", s.entity, err)
 		}
@@ -401,6 +521,20 @@ func (s *Subscription) AbandonMessage(ctx context.Context, m *azservicebus.Recei
 	s.logger.Debugf("Resumed after pausing for %v", time.Now().Sub(before))
 }
 
+// DeadLetterMessage moves a message to the dead-letter queue.
+func (s *Subscription) DeadLetterMessage(ctx context.Context, m *azservicebus.ReceivedMessage, reason error) {
+	s.logger.Debugf("Dead-lettering message %s on %s", m.MessageID, s.entity)
+
+	err := s.receiver.DeadLetterMessage(ctx, m, &azservicebus.DeadLetterOptions{
+		Reason:           ptr.Of("HandlerError"),
+		ErrorDescription: ptr.Of(reason.Error()),
+	})
+	if err != nil {
+		// Log only
+		s.logger.Warnf("Error dead-lettering message %s on %s: %s", m.MessageID, s.entity, err.Error())
+	}
+}
+
 // CompleteMessage marks a message as complete.
 func (s *Subscription) CompleteMessage(ctx context.Context, m *azservicebus.ReceivedMessage) {
 	s.logger.Debugf("Completing message %s on %s", m.MessageID, s.entity)
@@ -413,13 +547,55 @@ func (s *Subscription) CompleteMessage(ctx context.Context, m *azservicebus.Rece
 	}
 }
 
+// settleFailedMessage abandons a message whose handler returned an error, or moves it to the dead-letter queue if
+// deadLetterOnHandlerError is set.
+func (s *Subscription) settleFailedMessage(ctx context.Context, m *azservicebus.ReceivedMessage, handlerErr error) {
+	if s.deadLetterOnHandlerError {
+		s.DeadLetterMessage(ctx, m, handlerErr)
+		return
+	}
+
+	s.AbandonMessage(ctx, m)
+}
+
+// abandonPrefetched abandons the messages received in advance which were not handled, so they can be redelivered
+// without waiting for their locks to expire.
+func (s *Subscription) abandonPrefetched(msgs []*azservicebus.ReceivedMessage) {
+	if len(msgs) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	for _, msg := range msgs {
+		s.logger.Debugf("Abandoning prefetched message %s on %s", msg.MessageID, s.entity)
+		err := s.receiver.AbandonMessage(ctx, msg, nil)
+		if err != nil {
+			s.logger.Debugf("Error abandoning prefetched message %s on %s: %s", msg.MessageID, s.entity, err.Error())
+		}
+		s.removeActiveMessage(msg.MessageID, *msg.SequenceNumber)
+	}
+}
+
+// sessionID returns the id of the session of the receiver, if any.
+func (s *Subscription) sessionID() string {
+	if sessionReceiver, ok := s.receiver.(*SessionReceiver); ok {
+		return sessionReceiver.SessionID()
+	}
+
+	return ""
+}
+
 func (s *Subscription) addActiveMessage(m *azservicebus.ReceivedMessage) error {
 	if m.SequenceNumber == nil {
 		return fmt.Errorf("message sequence number is nil")
 	}
 	s.logger.Debugf("Adding message %s with sequence number %d to active messages on %s", m.MessageID, *m.SequenceNumber, s.entity)
 	s.mu.Lock()
-	s.activeMessages[*m.SequenceNumber] = m
+	s.activeMessages[*m.SequenceNumber] = activeMessage{
+		msg:        m,
+		receivedAt: time.Now(),
+	}
 	s.mu.Unlock()
 	return nil
 }
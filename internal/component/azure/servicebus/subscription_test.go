@@ -15,7 +15,14 @@ package servicebus
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"testing"
+	"time"
+
+	azservicebus "github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/kit/logger"
 	"github.com/dapr/kit/ptr"
@@ -57,12 +64,14 @@ func TestNewSubscription(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			sub := NewSubscription(
 				context.Background(),
-				1000,
-				1,
-				tc.maxBulkSubCountParam,
-				10,
-				100,
-				"test",
+				SubscriptionOptions{
+					MaxActiveMessages:     1000,
+					TimeoutInSec:          1,
+					MaxBulkSubCount:       tc.maxBulkSubCountParam,
+					MaxRetriableEPS:       10,
+					MaxConcurrentHandlers: 100,
+					Entity:                "test",
+				},
 				logger.NewLogger("test"),
 			)
 			if sub.maxBulkSubCount != tc.maxBulkSubCountExpected {
@@ -74,3 +83,196 @@ func TestNewSubscription(t *testing.T) {
 		})
 	}
 }
+
+// fakeReceiver is a Receiver returning the messages it's created with, and recording how they are settled.
+type fakeReceiver struct {
+	lock         sync.Mutex
+	msgs         []*azservicebus.ReceivedMessage
+	maxMessages  []int
+	completed    []string
+	abandoned    []string
+	deadLettered []string
+}
+
+func newFakeReceiver(ids ...string) *fakeReceiver {
+	r := &fakeReceiver{}
+	for i, id := range ids {
+		r.msgs = append(r.msgs, &azservicebus.ReceivedMessage{
+			MessageID:      id,
+			SequenceNumber: ptr.Of(int64(i)),
+		})
+	}
+	return r
+}
+
+func (r *fakeReceiver) ReceiveMessages(ctx context.Context, maxMessages int, options *azservicebus.ReceiveMessagesOptions) ([]*azservicebus.ReceivedMessage, error) {
+	r.lock.Lock()
+	r.maxMessages = append(r.maxMessages, maxMessages)
+	n := len(r.msgs)
+	if n > maxMessages {
+		n = maxMessages
+	}
+	msgs := r.msgs[:n]
+	r.msgs = r.msgs[n:]
+	r.lock.Unlock()
+
+	if len(msgs) > 0 {
+		return msgs, nil
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (r *fakeReceiver) CompleteMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.CompleteMessageOptions) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.completed = append(r.completed, message.MessageID)
+	return nil
+}
+
+func (r *fakeReceiver) AbandonMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.AbandonMessageOptions) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.abandoned = append(r.abandoned, message.MessageID)
+	return nil
+}
+
+func (r *fakeReceiver) DeadLetterMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.DeadLetterOptions) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.deadLettered = append(r.deadLettered, message.MessageID)
+	return nil
+}
+
+func (r *fakeReceiver) Close(ctx context.Context) error {
+	return nil
+}
+
+func (r *fakeReceiver) settled() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return len(r.completed) + len(r.abandoned) + len(r.deadLettered)
+}
+
+func TestReceiveAndBlock(t *testing.T) {
+	// The handler fails for message "m2"
+	handler := func(ctx context.Context, msgs []*azservicebus.ReceivedMessage) ([]HandlerResponseItem, error) {
+		if msgs[0].MessageID == "m2" {
+			return nil, errors.New("handler error")
+		}
+		return nil, nil
+	}
+
+	receive := func(t *testing.T, opts SubscriptionOptions, receiver *fakeReceiver, expectSettled int) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		opts.MaxActiveMessages = 1
+		opts.TimeoutInSec = 1
+		opts.Entity = "queue test"
+		sub := NewSubscription(ctx, opts, logger.NewLogger("test"))
+		require.NoError(t, sub.Connect(func() (Receiver, error) {
+			return receiver, nil
+		}))
+
+		done := make(chan error)
+		go func() {
+			done <- sub.ReceiveAndBlock(handler, 0, false, nil)
+		}()
+		assert.Eventually(t, func() bool {
+			return receiver.settled() == expectSettled
+		}, 5*time.Second, 10*time.Millisecond)
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+	}
+
+	t.Run("failed messages are abandoned", func(t *testing.T) {
+		receiver := newFakeReceiver("m1", "m2", "m3")
+		receive(t, SubscriptionOptions{}, receiver, 3)
+
+		assert.ElementsMatch(t, []string{"m1", "m3"}, receiver.completed)
+		assert.Equal(t, []string{"m2"}, receiver.abandoned)
+		assert.Empty(t, receiver.deadLettered)
+		assert.Equal(t, 1, receiver.maxMessages[0])
+	})
+
+	t.Run("failed messages are dead-lettered", func(t *testing.T) {
+		receiver := newFakeReceiver("m1", "m2", "m3")
+		receive(t, SubscriptionOptions{DeadLetterOnHandlerError: true}, receiver, 3)
+
+		assert.ElementsMatch(t, []string{"m1", "m3"}, receiver.completed)
+		assert.Empty(t, receiver.abandoned)
+		assert.Equal(t, []string{"m2"}, receiver.deadLettered)
+	})
+
+	t.Run("messages are prefetched", func(t *testing.T) {
+		receiver := newFakeReceiver("m1", "m3", "m4")
+		receive(t, SubscriptionOptions{PrefetchCount: 10}, receiver, 3)
+
+		assert.ElementsMatch(t, []string{"m1", "m3", "m4"}, receiver.completed)
+		// All the messages are received at once
+		assert.Equal(t, 10, receiver.maxMessages[0])
+	})
+}
+
+func TestCloseDrainsInFlightMessages(t *testing.T) {
+	receive := func(t *testing.T, drainTimeoutInSec int, release <-chan struct{}) *fakeReceiver {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		receiver := newFakeReceiver("m1")
+		sub := NewSubscription(ctx, SubscriptionOptions{
+			MaxActiveMessages: 1,
+			TimeoutInSec:      1,
+			Entity:            "queue test",
+			DrainTimeoutInSec: drainTimeoutInSec,
+		}, logger.NewLogger("test"))
+		require.NoError(t, sub.Connect(func() (Receiver, error) {
+			return receiver, nil
+		}))
+
+		started := make(chan struct{})
+		handler := func(ctx context.Context, msgs []*azservicebus.ReceivedMessage) ([]HandlerResponseItem, error) {
+			close(started)
+			select {
+			case <-release:
+				return nil, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		done := make(chan error)
+		go func() {
+			done <- sub.ReceiveAndBlock(handler, 0, false, nil)
+		}()
+		<-started
+
+		// Stop receiving while the message is being handled
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+
+		closeCtx, closeCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer closeCancel()
+		sub.Close(closeCtx)
+		return receiver
+	}
+
+	t.Run("in-flight messages are completed", func(t *testing.T) {
+		release := make(chan struct{})
+		time.AfterFunc(100*time.Millisecond, func() {
+			close(release)
+		})
+		receiver := receive(t, 5, release)
+
+		assert.Equal(t, []string{"m1"}, receiver.completed)
+		assert.Empty(t, receiver.abandoned)
+	})
+
+	t.Run("messages not completed in time are abandoned", func(t *testing.T) {
+		receiver := receive(t, 0, make(chan struct{}))
+
+		assert.Empty(t, receiver.completed)
+		assert.Equal(t, []string{"m1"}, receiver.abandoned)
+	})
+}
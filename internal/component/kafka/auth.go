@@ -20,6 +20,8 @@ import (
 	"fmt"
 
 	"github.com/Shopify/sarama"
+
+	"github.com/dapr/components-contrib/internal/authentication/clientcert"
 )
 
 func updatePasswordAuthInfo(config *sarama.Config, metadata *kafkaMetadata, saslUsername, saslPassword string) {
@@ -37,10 +39,14 @@ func updatePasswordAuthInfo(config *sarama.Config, metadata *kafkaMetadata, sasl
 	}
 }
 
-func updateMTLSAuthInfo(config *sarama.Config, metadata *kafkaMetadata) error {
+func updateMTLSAuthInfo(config *sarama.Config, metadata *kafkaMetadata, source *clientcert.Source) error {
 	if metadata.TLSDisable {
 		return fmt.Errorf("kafka: cannot configure mTLS authentication when TLSDisable is 'true'")
 	}
+	if source != nil {
+		config.Net.TLS.Config = source.TLSConfig(config.Net.TLS.Config)
+		return nil
+	}
 	cert, err := tls.X509KeyPair([]byte(metadata.TLSClientCert), []byte(metadata.TLSClientKey))
 	if err != nil {
 		return fmt.Errorf("unable to load client certificate and key pair. Err: %w", err)
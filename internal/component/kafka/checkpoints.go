@@ -0,0 +1,254 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// ConsumerCheckpoint is the committed offset of the consumer group for a partition of a topic.
+type ConsumerCheckpoint struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	Offset    int64  `json:"offset"`
+	Metadata  string `json:"metadata,omitempty"`
+}
+
+// ExportCheckpoints returns the offsets committed by the consumer group.
+// If topics is empty, the checkpoints of all the topics consumed by the group are returned.
+// Partitions without a committed offset are omitted.
+func (k *Kafka) ExportCheckpoints(topics []string) ([]ConsumerCheckpoint, error) {
+	if k.consumerGroup == "" {
+		return nil, errors.New("kafka: consumerGroup must be set to export checkpoints")
+	}
+
+	client, err := sarama.NewClient(k.brokers, k.config)
+	if err != nil {
+		return nil, err
+	}
+	// Closing the admin closes the client too
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	defer admin.Close()
+
+	var topicPartitions map[string][]int32
+	if len(topics) > 0 {
+		topicPartitions = make(map[string][]int32, len(topics))
+		for _, topic := range topics {
+			topicPartitions[topic], err = client.Partitions(topic)
+			if err != nil {
+				return nil, fmt.Errorf("kafka: error getting partitions of topic %s: %w", topic, err)
+			}
+		}
+	}
+
+	res, err := admin.ListConsumerGroupOffsets(k.consumerGroup, topicPartitions)
+	if err != nil {
+		return nil, err
+	}
+	if res.Err != sarama.ErrNoError {
+		return nil, fmt.Errorf("kafka: error fetching offsets of consumer group %s: %w", k.consumerGroup, res.Err)
+	}
+
+	checkpoints := make([]ConsumerCheckpoint, 0)
+	for topic, blocks := range res.Blocks {
+		for partition, block := range blocks {
+			if block.Err != sarama.ErrNoError {
+				return nil, fmt.Errorf("kafka: error fetching offset of %s/%d: %w", topic, partition, block.Err)
+			}
+			if block.Offset < 0 {
+				// Nothing committed yet
+				continue
+			}
+			checkpoints = append(checkpoints, ConsumerCheckpoint{
+				Topic:     topic,
+				Partition: partition,
+				Offset:    block.Offset,
+				Metadata:  block.Metadata,
+			})
+		}
+	}
+	sort.Slice(checkpoints, func(i, j int) bool {
+		if checkpoints[i].Topic != checkpoints[j].Topic {
+			return checkpoints[i].Topic < checkpoints[j].Topic
+		}
+		return checkpoints[i].Partition < checkpoints[j].Partition
+	})
+
+	return checkpoints, nil
+}
+
+// ImportCheckpoints commits the given offsets for the consumer group, which resumes consuming from them.
+// Offsets can move backwards, to replay messages, or forwards, to skip them.
+// Kafka only accepts the commit when the group has no active member: the consumer of this component is
+// stopped for the duration of the import and restarted afterwards, but any other member must be stopped first.
+func (k *Kafka) ImportCheckpoints(checkpoints []ConsumerCheckpoint) error {
+	if k.consumerGroup == "" {
+		return errors.New("kafka: consumerGroup must be set to import checkpoints")
+	}
+	if err := validateCheckpoints(checkpoints); err != nil {
+		return err
+	}
+	if len(checkpoints) == 0 {
+		return nil
+	}
+
+	k.subscribeLock.Lock()
+	defer k.subscribeLock.Unlock()
+
+	running := k.cg != nil
+	if running {
+		k.closeSubscriptionResources()
+		// Wait for the consumer to leave the group
+		<-k.consumer.running
+		k.cg = nil
+	}
+
+	err := k.commitCheckpoints(checkpoints)
+
+	if running && k.subscribeCtx != nil && k.subscribeCtx.Err() == nil {
+		if subErr := k.subscribe(k.subscribeCtx); subErr != nil {
+			k.logger.Errorf("kafka: error re-subscribing after importing checkpoints: %v", subErr)
+		}
+	}
+
+	return err
+}
+
+// Seek moves the consumer group to the offset, or to the first messages published at or after the timestamp, on the
+// given partitions of the topic, or all of them if partitions is empty.
+// The checkpoints are imported like with ImportCheckpoints, so the other members of the group must be stopped first.
+func (k *Kafka) Seek(topic string, partitions []int32, offset int64, timestamp *time.Time) error {
+	if k.consumerGroup == "" {
+		return errors.New("kafka: consumerGroup must be set to seek")
+	}
+
+	client, err := sarama.NewClient(k.brokers, k.config)
+	if err != nil {
+		return err
+	}
+	checkpoints, err := seekCheckpoints(client, topic, partitions, offset, timestamp)
+	client.Close()
+	if err != nil {
+		return err
+	}
+
+	return k.ImportCheckpoints(checkpoints)
+}
+
+// offsetClient is the part of sarama.Client resolving the offsets of the partitions.
+type offsetClient interface {
+	Partitions(topic string) ([]int32, error)
+	GetOffset(topic string, partitionID int32, time int64) (int64, error)
+}
+
+// seekCheckpoints returns the checkpoints of the partitions at the offset, or at the offsets of the timestamp if not nil.
+func seekCheckpoints(client offsetClient, topic string, partitions []int32, offset int64, timestamp *time.Time) ([]ConsumerCheckpoint, error) {
+	if len(partitions) == 0 {
+		var err error
+		partitions, err = client.Partitions(topic)
+		if err != nil {
+			return nil, fmt.Errorf("kafka: error getting partitions of topic %s: %w", topic, err)
+		}
+	}
+
+	checkpoints := make([]ConsumerCheckpoint, len(partitions))
+	for i, partition := range partitions {
+		checkpoints[i] = ConsumerCheckpoint{Topic: topic, Partition: partition, Offset: offset}
+		if timestamp == nil {
+			continue
+		}
+
+		partitionOffset, err := client.GetOffset(topic, partition, timestamp.UnixMilli())
+		if err == nil && partitionOffset < 0 {
+			// No message at or after the timestamp: only the messages published from now are consumed
+			partitionOffset, err = client.GetOffset(topic, partition, sarama.OffsetNewest)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("kafka: error getting offset of %s/%d at %s: %w", topic, partition, timestamp.Format(time.RFC3339), err)
+		}
+		checkpoints[i].Offset = partitionOffset
+	}
+
+	return checkpoints, nil
+}
+
+func (k *Kafka) commitCheckpoints(checkpoints []ConsumerCheckpoint) error {
+	client, err := sarama.NewClient(k.brokers, k.config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	coordinator, err := client.Coordinator(k.consumerGroup)
+	if err != nil {
+		return err
+	}
+
+	// Commit outside of any generation, as a consumer that is not a member of the group
+	req := &sarama.OffsetCommitRequest{
+		Version:                 1,
+		ConsumerGroup:           k.consumerGroup,
+		ConsumerGroupGeneration: sarama.GroupGenerationUndefined,
+	}
+	for _, c := range checkpoints {
+		req.AddBlock(c.Topic, c.Partition, c.Offset, 0, sarama.ReceiveTime, c.Metadata)
+	}
+
+	res, err := coordinator.CommitOffset(req)
+	if err != nil {
+		return err
+	}
+	for topic, partitions := range res.Errors {
+		for partition, kerr := range partitions {
+			if kerr != sarama.ErrNoError {
+				return fmt.Errorf("kafka: error importing checkpoint of %s/%d: %w", topic, partition, kerr)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateCheckpoints(checkpoints []ConsumerCheckpoint) error {
+	seen := make(map[string]map[int32]bool)
+	for _, c := range checkpoints {
+		if c.Topic == "" {
+			return errors.New("kafka: invalid checkpoint: topic is empty")
+		}
+		if c.Partition < 0 {
+			return fmt.Errorf("kafka: invalid checkpoint for topic %s: partition must be greater than or equal to 0", c.Topic)
+		}
+		if c.Offset < 0 {
+			return fmt.Errorf("kafka: invalid checkpoint of %s/%d: offset must be greater than or equal to 0", c.Topic, c.Partition)
+		}
+		if seen[c.Topic][c.Partition] {
+			return fmt.Errorf("kafka: invalid checkpoints: %s/%d is listed more than once", c.Topic, c.Partition)
+		}
+		if seen[c.Topic] == nil {
+			seen[c.Topic] = make(map[int32]bool)
+		}
+		seen[c.Topic][c.Partition] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCheckpoints(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		err := validateCheckpoints([]ConsumerCheckpoint{
+			{Topic: "a", Partition: 0, Offset: 10},
+			{Topic: "a", Partition: 1, Offset: 0},
+			{Topic: "b", Partition: 0, Offset: 3, Metadata: "m"},
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("empty topic", func(t *testing.T) {
+		err := validateCheckpoints([]ConsumerCheckpoint{{Partition: 0, Offset: 10}})
+		require.Error(t, err)
+	})
+
+	t.Run("negative partition", func(t *testing.T) {
+		err := validateCheckpoints([]ConsumerCheckpoint{{Topic: "a", Partition: -1, Offset: 10}})
+		require.Error(t, err)
+	})
+
+	t.Run("negative offset", func(t *testing.T) {
+		err := validateCheckpoints([]ConsumerCheckpoint{{Topic: "a", Partition: 0, Offset: -2}})
+		require.Error(t, err)
+	})
+
+	t.Run("duplicate partition", func(t *testing.T) {
+		err := validateCheckpoints([]ConsumerCheckpoint{
+			{Topic: "a", Partition: 0, Offset: 10},
+			{Topic: "a", Partition: 0, Offset: 12},
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestCheckpointsRequireConsumerGroup(t *testing.T) {
+	k := getKafka()
+
+	_, err := k.ExportCheckpoints(nil)
+	require.Error(t, err)
+
+	err = k.ImportCheckpoints([]ConsumerCheckpoint{{Topic: "a", Partition: 0, Offset: 1}})
+	require.Error(t, err)
+
+	err = k.Seek("a", nil, 0, nil)
+	require.Error(t, err)
+}
+
+// fakeOffsetClient returns the offsets of the partitions by time.
+type fakeOffsetClient struct {
+	partitions []int32
+	offsets    map[int32]map[int64]int64
+}
+
+func (f *fakeOffsetClient) Partitions(topic string) ([]int32, error) {
+	return f.partitions, nil
+}
+
+func (f *fakeOffsetClient) GetOffset(topic string, partitionID int32, time int64) (int64, error) {
+	offset, ok := f.offsets[partitionID][time]
+	if !ok {
+		return 0, errors.New("unexpected time")
+	}
+	return offset, nil
+}
+
+func TestSeekCheckpoints(t *testing.T) {
+	timestamp := time.Date(2022, 11, 1, 10, 0, 0, 0, time.UTC)
+	client := &fakeOffsetClient{
+		partitions: []int32{0, 1},
+		offsets: map[int32]map[int64]int64{
+			0: {timestamp.UnixMilli(): 42},
+			1: {timestamp.UnixMilli(): -1, sarama.OffsetNewest: 7},
+		},
+	}
+
+	t.Run("offset on all partitions", func(t *testing.T) {
+		checkpoints, err := seekCheckpoints(client, "orders", nil, 10, nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, []ConsumerCheckpoint{
+			{Topic: "orders", Partition: 0, Offset: 10},
+			{Topic: "orders", Partition: 1, Offset: 10},
+		}, checkpoints)
+	})
+
+	t.Run("timestamp on a partition", func(t *testing.T) {
+		checkpoints, err := seekCheckpoints(client, "orders", []int32{0}, 0, &timestamp)
+
+		require.NoError(t, err)
+		assert.Equal(t, []ConsumerCheckpoint{{Topic: "orders", Partition: 0, Offset: 42}}, checkpoints)
+	})
+
+	t.Run("timestamp after the last message", func(t *testing.T) {
+		checkpoints, err := seekCheckpoints(client, "orders", []int32{1}, 0, &timestamp)
+
+		require.NoError(t, err)
+		assert.Equal(t, []ConsumerCheckpoint{{Topic: "orders", Partition: 1, Offset: 7}}, checkpoints)
+	})
+
+	t.Run("unknown partition", func(t *testing.T) {
+		_, err := seekCheckpoints(client, "orders", []int32{2}, 0, &timestamp)
+
+		assert.Error(t, err)
+	})
+}
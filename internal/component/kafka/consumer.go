@@ -24,17 +24,29 @@ import (
 	"github.com/Shopify/sarama"
 	"github.com/cenkalti/backoff/v4"
 
+	"github.com/dapr/components-contrib/internal/utils"
 	"github.com/dapr/kit/retry"
 )
 
 type consumer struct {
-	k       *Kafka
-	ready   chan bool
-	running chan struct{}
-	once    sync.Once
-	mutex   sync.Mutex
+	k        *Kafka
+	ready    chan bool
+	running  chan struct{}
+	once     sync.Once
+	inFlight *utils.InFlight
 }
 
+// updateConsumerConfig sets the consumer properties of the sarama config, which is only used by the consumer group.
+func updateConsumerConfig(config *sarama.Config, meta *kafkaMetadata) {
+	if meta.PartitionQueueDepth > 0 {
+		// Number of messages of each partition fetched in advance, while the handler processes the previous ones
+		config.ChannelBufferSize = meta.PartitionQueueDepth
+	}
+}
+
+// ConsumeClaim dispatches the messages of a partition to the handler.
+// Sarama invokes it in a goroutine for each claimed partition: messages of the same partition are handled
+// sequentially, in order, while the partitions are processed in parallel.
 func (consumer *consumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
 	b := consumer.k.backOffConfig.NewBackOffWithContext(session.Context())
 	isBulkSubscribe := consumer.k.checkBulkSubscribe(claim.Topic())
@@ -52,7 +64,6 @@ func (consumer *consumer) ConsumeClaim(session sarama.ConsumerGroupSession, clai
 			case <-session.Context().Done():
 				return consumer.flushBulkMessages(claim, messages, session, handlerConfig.BulkHandler, b)
 			case message := <-claim.Messages():
-				consumer.mutex.Lock()
 				if message != nil {
 					messages = append(messages, message)
 					if len(messages) >= handlerConfig.SubscribeConfig.MaxBulkSubCount {
@@ -60,12 +71,9 @@ func (consumer *consumer) ConsumeClaim(session sarama.ConsumerGroupSession, clai
 						messages = messages[:0]
 					}
 				}
-				consumer.mutex.Unlock()
 			case <-ticker.C:
-				consumer.mutex.Lock()
 				consumer.flushBulkMessages(claim, messages, session, handlerConfig.BulkHandler, b)
 				messages = messages[:0]
-				consumer.mutex.Unlock()
 			}
 		}
 	} else {
@@ -76,6 +84,11 @@ func (consumer *consumer) ConsumeClaim(session sarama.ConsumerGroupSession, clai
 					return nil
 				}
 
+				// When draining, the message is not marked, so it's consumed again after the rebalance
+				if !consumer.inFlight.Add() {
+					return nil
+				}
+
 				if consumer.k.consumeRetryEnabled {
 					if err := retry.NotifyRecover(func() error {
 						return consumer.doCallback(session, message)
@@ -92,6 +105,7 @@ func (consumer *consumer) ConsumeClaim(session sarama.ConsumerGroupSession, clai
 						consumer.k.logger.Errorf("Error processing Kafka message: %s/%d/%d [key=%s]. Error: %v.", message.Topic, message.Partition, message.Offset, asBase64String(message.Key), err)
 					}
 				}
+				consumer.inFlight.Done()
 			// Should return when `session.Context()` is done.
 			// If not, will raise `ErrRebalanceInProgress` or `read tcp <ip>:<port>: i/o timeout` when kafka rebalance. see:
 			// https://github.com/Shopify/sarama/issues/1192
@@ -107,6 +121,11 @@ func (consumer *consumer) flushBulkMessages(claim sarama.ConsumerGroupClaim,
 	handler BulkEventHandler, b backoff.BackOff,
 ) error {
 	if len(messages) > 0 {
+		if !consumer.inFlight.Add() {
+			return nil
+		}
+		defer consumer.inFlight.Done()
+
 		if consumer.k.consumeRetryEnabled {
 			if err := retry.NotifyRecover(func() error {
 				return consumer.doBulkCallback(session, messages, handler, claim.Topic())
@@ -154,7 +173,8 @@ func (consumer *consumer) doBulkCallback(session sarama.ConsumerGroupSession,
 		Topic:   topic,
 		Entries: messageValues,
 	}
-	responses, err := handler(session.Context(), &event)
+	// The handler is not canceled with the session, so in-flight messages can complete while draining
+	responses, err := handler(consumer.inFlight.Context(), &event)
 
 	if err != nil {
 		for i, resp := range responses {
@@ -189,7 +209,7 @@ func (consumer *consumer) doCallback(session sarama.ConsumerGroupSession, messag
 		Data:  message.Value,
 	}
 
-	err = handlerConfig.Handler(session.Context(), &event)
+	err = handlerConfig.Handler(consumer.inFlight.Context(), &event)
 	if err == nil {
 		session.MarkMessage(message, "")
 	}
@@ -253,6 +273,13 @@ func (k *Kafka) Subscribe(ctx context.Context) error {
 	k.subscribeLock.Lock()
 	defer k.subscribeLock.Unlock()
 
+	return k.subscribe(ctx)
+}
+
+// subscribe starts the consumer; the caller must hold subscribeLock.
+func (k *Kafka) subscribe(ctx context.Context) error {
+	k.subscribeCtx = ctx
+
 	// Close resources and reset synchronization primitives
 	k.closeSubscriptionResources()
 
@@ -262,7 +289,7 @@ func (k *Kafka) Subscribe(ctx context.Context) error {
 		return nil
 	}
 
-	cg, err := sarama.NewConsumerGroup(k.brokers, k.consumerGroup, k.config)
+	cg, err := sarama.NewConsumerGroup(k.brokers, k.consumerGroup, k.consumerConfig)
 	if err != nil {
 		return err
 	}
@@ -274,9 +301,10 @@ func (k *Kafka) Subscribe(ctx context.Context) error {
 
 	ready := make(chan bool)
 	k.consumer = consumer{
-		k:       k,
-		ready:   ready,
-		running: make(chan struct{}),
+		k:        k,
+		ready:    ready,
+		running:  make(chan struct{}),
+		inFlight: utils.NewInFlight(),
 	}
 
 	go func() {
@@ -326,6 +354,12 @@ func (k *Kafka) Subscribe(ctx context.Context) error {
 func (k *Kafka) closeSubscriptionResources() {
 	if k.cg != nil {
 		k.cancel()
+
+		// Stop fetching and wait for the in-flight messages to be marked, so their offsets are committed on close
+		if !k.consumer.inFlight.Drain(context.Background(), k.drainTimeout) {
+			k.logger.Warnf("Not all in-flight Kafka messages were processed within %s; they will be consumed again", k.drainTimeout)
+		}
+
 		err := k.cg.Close()
 		if err != nil {
 			k.logger.Errorf("Error closing consumer group: %v", err)
@@ -0,0 +1,161 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/internal/utils"
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/retry"
+)
+
+type fakeSession struct {
+	ctx    context.Context
+	lock   sync.Mutex
+	marked []*sarama.ConsumerMessage
+}
+
+func (s *fakeSession) Claims() map[string][]int32               { return nil }
+func (s *fakeSession) MemberID() string                         { return "member" }
+func (s *fakeSession) GenerationID() int32                      { return 1 }
+func (s *fakeSession) MarkOffset(string, int32, int64, string)  {}
+func (s *fakeSession) Commit()                                  {}
+func (s *fakeSession) ResetOffset(string, int32, int64, string) {}
+func (s *fakeSession) Context() context.Context                 { return s.ctx }
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.marked = append(s.marked, msg)
+}
+
+type fakeClaim struct {
+	partition int32
+	messages  chan *sarama.ConsumerMessage
+}
+
+func newFakeClaim(partition int32, count int) *fakeClaim {
+	c := &fakeClaim{
+		partition: partition,
+		messages:  make(chan *sarama.ConsumerMessage, count),
+	}
+	for i := 0; i < count; i++ {
+		c.messages <- &sarama.ConsumerMessage{
+			Topic:     "topic",
+			Partition: partition,
+			Offset:    int64(i),
+			Value:     []byte(fmt.Sprintf("%d-%d", partition, i)),
+		}
+	}
+	close(c.messages)
+	return c
+}
+
+func (c *fakeClaim) Topic() string                            { return "topic" }
+func (c *fakeClaim) Partition() int32                         { return c.partition }
+func (c *fakeClaim) InitialOffset() int64                     { return 0 }
+func (c *fakeClaim) HighWaterMarkOffset() int64               { return int64(cap(c.messages)) }
+func (c *fakeClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+func TestConsumeClaimPartitionOrdering(t *testing.T) {
+	const partitions, messagesPerPartition = 3, 5
+
+	var (
+		lock      sync.Mutex
+		received  = map[string][]string{}
+		active    = map[string]int{}
+		maxActive = map[string]int{}
+		started   sync.WaitGroup
+	)
+	// The first message of each partition waits for the others to start, so they must be handled in parallel
+	started.Add(partitions)
+	handler := func(ctx context.Context, event *NewEvent) error {
+		partition, offset, _ := strings.Cut(string(event.Data), "-")
+
+		lock.Lock()
+		active[partition]++
+		if active[partition] > maxActive[partition] {
+			maxActive[partition] = active[partition]
+		}
+		lock.Unlock()
+
+		if offset == "0" {
+			started.Done()
+			started.Wait()
+		}
+		time.Sleep(time.Millisecond)
+
+		lock.Lock()
+		active[partition]--
+		received[partition] = append(received[partition], offset)
+		lock.Unlock()
+		return nil
+	}
+
+	k := &Kafka{
+		logger:          logger.NewLogger("kafka_test"),
+		subscribeTopics: TopicHandlerConfig{"topic": {Handler: handler}},
+		backOffConfig:   retry.DefaultConfig(),
+	}
+	c := &consumer{k: k, inFlight: utils.NewInFlight()}
+	session := &fakeSession{ctx: context.Background()}
+
+	var wg sync.WaitGroup
+	for p := 0; p < partitions; p++ {
+		wg.Add(1)
+		go func(claim sarama.ConsumerGroupClaim) {
+			defer wg.Done()
+			assert.NoError(t, c.ConsumeClaim(session, claim))
+		}(newFakeClaim(int32(p), messagesPerPartition))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("partitions were not processed in parallel")
+	}
+
+	require.Len(t, received, partitions)
+	for p := 0; p < partitions; p++ {
+		partition := fmt.Sprint(p)
+		assert.Equal(t, []string{"0", "1", "2", "3", "4"}, received[partition])
+		assert.Equal(t, 1, maxActive[partition])
+	}
+	assert.Len(t, session.marked, partitions*messagesPerPartition)
+}
+
+func TestUpdateConsumerConfig(t *testing.T) {
+	config := sarama.NewConfig()
+	defaultDepth := config.ChannelBufferSize
+
+	updateConsumerConfig(config, &kafkaMetadata{})
+	assert.Equal(t, defaultDepth, config.ChannelBufferSize)
+
+	updateConsumerConfig(config, &kafkaMetadata{PartitionQueueDepth: 16})
+	assert.Equal(t, 16, config.ChannelBufferSize)
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/Shopify/sarama"
 
+	"github.com/dapr/components-contrib/internal/authentication/clientcert"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/kit/logger"
 	"github.com/dapr/kit/retry"
@@ -28,19 +29,25 @@ import (
 // Kafka allows reading/writing to a Kafka consumer group.
 type Kafka struct {
 	producer        sarama.SyncProducer
+	asyncProducer   sarama.AsyncProducer
+	producerLock    sync.RWMutex
+	producerErrors  sync.WaitGroup
 	consumerGroup   string
 	brokers         []string
 	logger          logger.Logger
 	authType        string
 	saslUsername    string
 	saslPassword    string
+	clientCert      *clientcert.Source
 	initialOffset   int64
 	cg              sarama.ConsumerGroup
 	cancel          context.CancelFunc
 	consumer        consumer
 	config          *sarama.Config
+	consumerConfig  *sarama.Config
 	subscribeTopics TopicHandlerConfig
 	subscribeLock   sync.Mutex
+	subscribeCtx    context.Context
 
 	backOffConfig retry.Config
 
@@ -49,6 +56,7 @@ type Kafka struct {
 	DefaultConsumeRetryEnabled bool
 	consumeRetryEnabled        bool
 	consumeRetryInterval       time.Duration
+	drainTimeout               time.Duration
 }
 
 func NewKafka(logger logger.Logger) *Kafka {
@@ -103,18 +111,35 @@ func (k *Kafka) Init(metadata map[string]string) error {
 		updatePasswordAuthInfo(config, meta, k.saslUsername, k.saslPassword)
 	case mtlsAuthType:
 		k.logger.Info("Configuring mTLS authentcation")
-		err = updateMTLSAuthInfo(config, meta)
+		k.clientCert, err = clientcert.New(metadata, k.logger)
+		if err != nil {
+			return err
+		}
+		err = updateMTLSAuthInfo(config, meta, k.clientCert)
 		if err != nil {
 			return err
 		}
 	}
 
 	k.config = config
+	// The consumer properties are set on a copy of the base config, so that they don't affect the producers
+	consumerConfig := *config
+	updateConsumerConfig(&consumerConfig, meta)
+	k.consumerConfig = &consumerConfig
 	sarama.Logger = SaramaLogBridge{daprLogger: k.logger}
 
-	k.producer, err = getSyncProducer(*k.config, k.brokers, meta.MaxMessageBytes)
-	if err != nil {
-		return err
+	if meta.DeliveryMode == deliveryModeFireAndForget {
+		k.asyncProducer, err = getAsyncProducer(*k.config, k.brokers, meta)
+		if err != nil {
+			return err
+		}
+		k.producerErrors.Add(1)
+		go k.logProducerErrors(k.asyncProducer)
+	} else {
+		k.producer, err = getSyncProducer(*k.config, k.brokers, meta)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Default retry configuration is used if no
@@ -127,6 +152,7 @@ func (k *Kafka) Init(metadata map[string]string) error {
 	}
 	k.consumeRetryEnabled = meta.ConsumeRetryEnabled
 	k.consumeRetryInterval = meta.ConsumeRetryInterval
+	k.drainTimeout = meta.DrainTimeout
 
 	k.logger.Debug("Kafka message bus initialization complete")
 
@@ -136,11 +162,29 @@ func (k *Kafka) Init(metadata map[string]string) error {
 func (k *Kafka) Close() (err error) {
 	k.closeSubscriptionResources()
 
+	// Wait for the messages being published
+	k.producerLock.Lock()
+	defer k.producerLock.Unlock()
+
 	if k.producer != nil {
 		err = k.producer.Close()
 		k.producer = nil
 	}
 
+	if k.asyncProducer != nil {
+		// Close flushes the messages which are still buffered, and the failures are logged before returning
+		if closeErr := k.asyncProducer.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		k.producerErrors.Wait()
+		k.asyncProducer = nil
+	}
+
+	if k.clientCert != nil {
+		k.clientCert.Close()
+		k.clientCert = nil
+	}
+
 	return err
 }
 
@@ -21,6 +21,9 @@ import (
 	"time"
 
 	"github.com/Shopify/sarama"
+
+	"github.com/dapr/components-contrib/internal/authentication/clientcert"
+	contribMetadata "github.com/dapr/components-contrib/metadata"
 )
 
 const (
@@ -29,6 +32,7 @@ const (
 	caCert               = "caCert"
 	clientCert           = "clientCert"
 	clientKey            = "clientKey"
+	clientCertSource     = "clientCertSource"
 	consumeRetryEnabled  = "consumeRetryEnabled"
 	consumeRetryInterval = "consumeRetryInterval"
 	authType             = "authType"
@@ -36,30 +40,99 @@ const (
 	oidcAuthType         = "oidc"
 	mtlsAuthType         = "mtls"
 	noAuthType           = "none"
+	deliveryMode         = "deliveryMode"
+	compression          = "compression"
+	acks                 = "acks"
+	maxInFlightRequests  = "maxInFlightRequests"
+	batchSize            = "batchSize"
+	batchTimeout         = "batchTimeout"
+	drainTimeout         = "drainTimeout"
+	partitionQueueDepth  = "partitionQueueDepth"
+
+	// deliveryModeConfirmed blocks the publishing of a message until it has been acknowledged by the brokers.
+	deliveryModeConfirmed = "confirmed"
+	// deliveryModeFireAndForget returns as soon as a message has been enqueued locally, while it is sent
+	// and retried in the background.
+	deliveryModeFireAndForget = "fireAndForget"
+
+	// Default time a batch of messages smaller than the batch size waits for more messages before being sent.
+	defaultBatchTimeout = 10 * time.Millisecond
+	// Default time the consumer waits for the in-flight messages to be processed on shutdown.
+	defaultDrainTimeout = 5 * time.Second
 )
 
 type kafkaMetadata struct {
-	Brokers              []string
-	ConsumerGroup        string
-	ClientID             string
-	AuthType             string
-	SaslUsername         string
-	SaslPassword         string
-	SaslMechanism        string
-	InitialOffset        int64
-	MaxMessageBytes      int
-	OidcTokenEndpoint    string
-	OidcClientID         string
-	OidcClientSecret     string
-	OidcScopes           []string
-	TLSDisable           bool
-	TLSSkipVerify        bool
-	TLSCaCert            string
-	TLSClientCert        string
-	TLSClientKey         string
-	ConsumeRetryEnabled  bool
-	ConsumeRetryInterval time.Duration
-	Version              sarama.KafkaVersion
+	Brokers              []string                `mapstructure:"brokers" mdrequired:"true"`
+	ConsumerGroup        string                  `mapstructure:"consumerGroup"`
+	ClientID             string                  `mapstructure:"clientID"`
+	AuthType             string                  `mapstructure:"authType" mdrequired:"true"`
+	SaslUsername         string                  `mapstructure:"saslUsername"`
+	SaslPassword         string                  `mapstructure:"saslPassword" mdsensitive:"true"`
+	SaslMechanism        string                  `mapstructure:"saslMechanism"`
+	InitialOffset        int64                   `mapstructure:"initialOffset" mdtype:"string"`
+	MaxMessageBytes      int                     `mapstructure:"maxMessageBytes"`
+	OidcTokenEndpoint    string                  `mapstructure:"oidcTokenEndpoint"`
+	OidcClientID         string                  `mapstructure:"oidcClientID"`
+	OidcClientSecret     string                  `mapstructure:"oidcClientSecret" mdsensitive:"true"`
+	OidcScopes           []string                `mapstructure:"oidcScopes"`
+	TLSDisable           bool                    `mapstructure:"disableTls"`
+	TLSSkipVerify        bool                    `mapstructure:"skipVerify"`
+	TLSCaCert            string                  `mapstructure:"caCert"`
+	TLSClientCert        string                  `mapstructure:"clientCert"`
+	TLSClientKey         string                  `mapstructure:"clientKey" mdsensitive:"true"`
+	ConsumeRetryEnabled  bool                    `mapstructure:"consumeRetryEnabled"`
+	ConsumeRetryInterval time.Duration           `mapstructure:"consumeRetryInterval"`
+	Version              sarama.KafkaVersion     `mapstructure:"version" mdtype:"string"`
+	DeliveryMode         string                  `mapstructure:"deliveryMode"`
+	Compression          sarama.CompressionCodec `mapstructure:"compression" mdtype:"string"`
+	RequiredAcks         sarama.RequiredAcks     `mapstructure:"acks" mdtype:"string"`
+	MaxInFlightRequests  int                     `mapstructure:"maxInFlightRequests"`
+	BatchSize            int                     `mapstructure:"batchSize"`
+	BatchTimeout         time.Duration           `mapstructure:"batchTimeout"`
+	DrainTimeout         time.Duration           `mapstructure:"drainTimeout"`
+	PartitionQueueDepth  int                     `mapstructure:"partitionQueueDepth"`
+}
+
+// defaultKafkaMetadata returns the metadata with the defaults of the properties which are not set.
+func defaultKafkaMetadata() kafkaMetadata {
+	return kafkaMetadata{
+		InitialOffset:        sarama.OffsetNewest,
+		ConsumeRetryInterval: 100 * time.Millisecond,
+		Version:              sarama.V2_0_0_0, //nolint:nosnakecase
+		DeliveryMode:         deliveryModeConfirmed,
+		Compression:          sarama.CompressionNone,
+		RequiredAcks:         sarama.WaitForAll,
+		DrainTimeout:         defaultDrainTimeout,
+	}
+}
+
+// MetadataSchema returns the schema of the metadata of the Kafka components, generated from kafkaMetadata.
+func (k *Kafka) MetadataSchema() []contribMetadata.MetadataField {
+	meta := defaultKafkaMetadata()
+	// Defaults which depend on the component or on other properties
+	meta.OidcScopes = []string{"openid"}
+	meta.ConsumeRetryEnabled = k.DefaultConsumeRetryEnabled
+	meta.BatchTimeout = defaultBatchTimeout
+	meta.PartitionQueueDepth = sarama.NewConfig().ChannelBufferSize
+
+	fields, _ := contribMetadata.GetMetadataSchemaFromStruct(meta)
+	for i := range fields {
+		// The defaults of the enums are the names of the sarama constants in the metadata, rather than their values
+		switch fields[i].Name {
+		case "initialOffset":
+			fields[i].Default = "newest"
+		case acks:
+			fields[i].Default = "all"
+		case compression:
+			fields[i].Default = meta.Compression.String()
+		}
+	}
+
+	fields = append(fields,
+		// Deprecated alias of consumerGroup
+		contribMetadata.MetadataField{Name: "consumerID", Type: contribMetadata.FieldTypeString},
+	)
+	return append(fields, clientcert.MetadataSchema()...)
 }
 
 // upgradeMetadata updates metadata properties based on deprecated usage.
@@ -100,9 +173,7 @@ func (k *Kafka) upgradeMetadata(metadata map[string]string) (map[string]string,
 
 // getKafkaMetadata returns new Kafka metadata.
 func (k *Kafka) getKafkaMetadata(metadata map[string]string) (*kafkaMetadata, error) {
-	meta := kafkaMetadata{
-		ConsumeRetryInterval: 100 * time.Millisecond,
-	}
+	meta := defaultKafkaMetadata()
 	// use the runtimeConfig.ID as the consumer group so that each dapr runtime creates its own consumergroup
 	if val, ok := metadata["consumerID"]; ok && val != "" {
 		meta.ConsumerGroup = val
@@ -204,6 +275,9 @@ func (k *Kafka) getKafkaMetadata(metadata map[string]string) (*kafkaMetadata, er
 		if (meta.TLSClientKey == "") != (meta.TLSClientCert == "") {
 			return nil, errors.New("kafka error: clientKey or clientCert is missing")
 		}
+		if meta.TLSClientCert != "" && metadata[clientCertSource] != "" {
+			return nil, errors.New("kafka error: clientCert and clientCertSource are mutually exclusive")
+		}
 		k.logger.Debug("Configuring mTLS authentication.")
 	case noAuthType:
 		meta.AuthType = val
@@ -270,14 +344,84 @@ func (k *Kafka) getKafkaMetadata(metadata map[string]string) (*kafkaMetadata, er
 		meta.ConsumeRetryInterval = durationVal
 	}
 
+	if val, ok := metadata[deliveryMode]; ok && val != "" {
+		switch {
+		case strings.EqualFold(val, deliveryModeConfirmed):
+			meta.DeliveryMode = deliveryModeConfirmed
+		case strings.EqualFold(val, deliveryModeFireAndForget):
+			meta.DeliveryMode = deliveryModeFireAndForget
+		default:
+			return nil, fmt.Errorf("kafka error: invalid value for '%s' attribute: %s", deliveryMode, val)
+		}
+	}
+
+	if val, ok := metadata[compression]; ok && val != "" {
+		err = meta.Compression.UnmarshalText([]byte(strings.ToLower(val)))
+		if err != nil {
+			return nil, fmt.Errorf("kafka error: invalid value for '%s' attribute: %w", compression, err)
+		}
+	}
+
+	if val, ok := metadata[acks]; ok && val != "" {
+		switch strings.ToLower(val) {
+		case "none", "0":
+			meta.RequiredAcks = sarama.NoResponse
+		case "leader", "1":
+			meta.RequiredAcks = sarama.WaitForLocal
+		case "all", "-1":
+			meta.RequiredAcks = sarama.WaitForAll
+		default:
+			return nil, fmt.Errorf("kafka error: invalid value for '%s' attribute: %s", acks, val)
+		}
+	}
+
+	if val, ok := metadata[maxInFlightRequests]; ok && val != "" {
+		intVal, err := strconv.Atoi(val)
+		if err != nil || intVal < 1 {
+			return nil, fmt.Errorf("kafka error: invalid value for '%s' attribute: %s", maxInFlightRequests, val)
+		}
+		meta.MaxInFlightRequests = intVal
+	}
+
+	if val, ok := metadata[batchSize]; ok && val != "" {
+		intVal, err := strconv.Atoi(val)
+		if err != nil || intVal < 0 {
+			return nil, fmt.Errorf("kafka error: invalid value for '%s' attribute: %s", batchSize, val)
+		}
+		meta.BatchSize = intVal
+		meta.BatchTimeout = defaultBatchTimeout
+	}
+
+	if val, ok := metadata[batchTimeout]; ok && val != "" {
+		durationVal, err := time.ParseDuration(val)
+		if err != nil || durationVal < 0 {
+			return nil, fmt.Errorf("kafka error: invalid value for '%s' attribute: %s", batchTimeout, val)
+		}
+		meta.BatchTimeout = durationVal
+	}
+
+	if val, ok := metadata[drainTimeout]; ok && val != "" {
+		durationVal, err := time.ParseDuration(val)
+		if err != nil || durationVal < 0 {
+			return nil, fmt.Errorf("kafka error: invalid value for '%s' attribute: %s", drainTimeout, val)
+		}
+		meta.DrainTimeout = durationVal
+	}
+
+	if val, ok := metadata[partitionQueueDepth]; ok && val != "" {
+		intVal, err := strconv.Atoi(val)
+		if err != nil || intVal < 1 {
+			return nil, fmt.Errorf("kafka error: invalid value for '%s' attribute: %s", partitionQueueDepth, val)
+		}
+		meta.PartitionQueueDepth = intVal
+	}
+
 	if val, ok := metadata["version"]; ok && val != "" {
 		version, err := sarama.ParseKafkaVersion(val)
 		if err != nil {
 			return nil, errors.New("kafka error: invalid kafka version")
 		}
 		meta.Version = version
-	} else {
-		meta.Version = sarama.V2_0_0_0 //nolint:nosnakecase
 	}
 
 	return &meta, nil
@@ -21,6 +21,7 @@ import (
 	"github.com/Shopify/sarama"
 	"github.com/stretchr/testify/require"
 
+	contribMetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/kit/logger"
 )
 
@@ -81,6 +82,99 @@ func TestParseMetadata(t *testing.T) {
 		require.Nil(t, meta)
 		require.Equal(t, "kafka error: invalid kafka version", err.Error())
 	})
+
+	t.Run("default delivery mode", func(t *testing.T) {
+		meta, err := k.getKafkaMetadata(getCompleteMetadata())
+		require.NoError(t, err)
+		require.Equal(t, deliveryModeConfirmed, meta.DeliveryMode)
+	})
+
+	t.Run("fire and forget delivery mode", func(t *testing.T) {
+		m := getCompleteMetadata()
+		m[deliveryMode] = "fireandforget"
+		meta, err := k.getKafkaMetadata(m)
+		require.NoError(t, err)
+		require.Equal(t, deliveryModeFireAndForget, meta.DeliveryMode)
+	})
+
+	t.Run("invalid delivery mode", func(t *testing.T) {
+		m := getCompleteMetadata()
+		m[deliveryMode] = "eventually"
+		meta, err := k.getKafkaMetadata(m)
+		require.Error(t, err)
+		require.Nil(t, meta)
+	})
+
+	t.Run("default producer tuning", func(t *testing.T) {
+		meta, err := k.getKafkaMetadata(getCompleteMetadata())
+		require.NoError(t, err)
+		require.Equal(t, sarama.CompressionNone, meta.Compression)
+		require.Equal(t, sarama.WaitForAll, meta.RequiredAcks)
+		require.Equal(t, 0, meta.MaxInFlightRequests)
+		require.Equal(t, 0, meta.BatchSize)
+		require.Equal(t, time.Duration(0), meta.BatchTimeout)
+	})
+
+	t.Run("producer tuning", func(t *testing.T) {
+		m := getCompleteMetadata()
+		m[compression] = "ZSTD"
+		m[acks] = "leader"
+		m[maxInFlightRequests] = "1"
+		m[batchSize] = "65536"
+		meta, err := k.getKafkaMetadata(m)
+		require.NoError(t, err)
+		require.Equal(t, sarama.CompressionZSTD, meta.Compression)
+		require.Equal(t, sarama.WaitForLocal, meta.RequiredAcks)
+		require.Equal(t, 1, meta.MaxInFlightRequests)
+		require.Equal(t, 65536, meta.BatchSize)
+		require.Equal(t, defaultBatchTimeout, meta.BatchTimeout)
+
+		config := sarama.NewConfig()
+		updateProducerConfig(config, meta)
+		require.Equal(t, sarama.CompressionZSTD, config.Producer.Compression)
+		require.Equal(t, sarama.WaitForLocal, config.Producer.RequiredAcks)
+		require.Equal(t, 1, config.Net.MaxOpenRequests)
+		require.Equal(t, 65536, config.Producer.Flush.Bytes)
+		require.Equal(t, defaultBatchTimeout, config.Producer.Flush.Frequency)
+	})
+
+	t.Run("drain timeout", func(t *testing.T) {
+		meta, err := k.getKafkaMetadata(getCompleteMetadata())
+		require.NoError(t, err)
+		require.Equal(t, defaultDrainTimeout, meta.DrainTimeout)
+
+		m := getCompleteMetadata()
+		m[drainTimeout] = "30s"
+		meta, err = k.getKafkaMetadata(m)
+		require.NoError(t, err)
+		require.Equal(t, 30*time.Second, meta.DrainTimeout)
+	})
+
+	t.Run("partition queue depth", func(t *testing.T) {
+		m := getCompleteMetadata()
+		m[partitionQueueDepth] = "32"
+		meta, err := k.getKafkaMetadata(m)
+		require.NoError(t, err)
+		require.Equal(t, 32, meta.PartitionQueueDepth)
+	})
+
+	t.Run("invalid producer tuning", func(t *testing.T) {
+		for name, val := range map[string]string{
+			compression:         "brotli",
+			acks:                "2",
+			maxInFlightRequests: "0",
+			batchSize:           "large",
+			batchTimeout:        "-1s",
+			drainTimeout:        "soon",
+			partitionQueueDepth: "0",
+		} {
+			m := getCompleteMetadata()
+			m[name] = val
+			meta, err := k.getKafkaMetadata(m)
+			require.Error(t, err, name)
+			require.Nil(t, meta)
+		}
+	})
 }
 
 func assertMetadata(t *testing.T, meta *kafkaMetadata) {
@@ -309,6 +403,16 @@ func TestTls(t *testing.T) {
 		require.Equal(t, "kafka error: clientKey or clientCert is missing", err.Error())
 	})
 
+	t.Run("client cert and client cert source", func(t *testing.T) {
+		m := getCompleteMetadata()
+		m[clientCertSource] = "files"
+		meta, err := k.getKafkaMetadata(m)
+		require.Error(t, err)
+		require.Nil(t, meta)
+
+		require.Equal(t, "kafka error: clientCert and clientCertSource are mutually exclusive", err.Error())
+	})
+
 	t.Run("wrong ca cert format", func(t *testing.T) {
 		m := getBaseMetadata()
 		m[caCert] = "caCert"
@@ -319,3 +423,31 @@ func TestTls(t *testing.T) {
 		require.Equal(t, "kafka error: invalid ca certificate", err.Error())
 	})
 }
+
+func TestMetadataSchema(t *testing.T) {
+	k := getKafka()
+	k.DefaultConsumeRetryEnabled = true
+
+	fields := map[string]contribMetadata.MetadataField{}
+	for _, f := range k.MetadataSchema() {
+		fields[f.Name] = f
+	}
+
+	require.True(t, fields["brokers"].Required)
+	require.True(t, fields["saslPassword"].Sensitive)
+	require.Equal(t, contribMetadata.FieldTypeDuration, fields[batchTimeout].Type)
+	require.Equal(t, "10ms", fields[batchTimeout].Default)
+	require.Equal(t, "true", fields[consumeRetryEnabled].Default)
+	require.Equal(t, "2.0.0", fields["version"].Default)
+	require.Equal(t, contribMetadata.FieldTypeString, fields["initialOffset"].Type)
+	require.Equal(t, "newest", fields["initialOffset"].Default)
+	require.Equal(t, "all", fields[acks].Default)
+	require.Equal(t, contribMetadata.FieldTypeString, fields[compression].Type)
+	require.Equal(t, "none", fields[compression].Default)
+	require.Equal(t, "256", fields[partitionQueueDepth].Default)
+	require.Equal(t, "openid", fields["oidcScopes"].Default)
+	require.Equal(t, "", fields["clientID"].Default)
+	require.True(t, fields[clientKey].Sensitive)
+	// Client certificate sources
+	require.Equal(t, "1m0s", fields["clientCertRefreshInterval"].Default)
+}
@@ -22,15 +22,30 @@ import (
 	"github.com/dapr/components-contrib/pubsub"
 )
 
-func getSyncProducer(config sarama.Config, brokers []string, maxMessageBytes int) (sarama.SyncProducer, error) {
-	// Add SyncProducer specific properties to copy of base config
-	config.Producer.RequiredAcks = sarama.WaitForAll
+// updateProducerConfig adds the producer properties of the metadata to a copy of the base config.
+func updateProducerConfig(config *sarama.Config, meta *kafkaMetadata) {
+	config.Producer.RequiredAcks = meta.RequiredAcks
 	config.Producer.Retry.Max = 5
-	config.Producer.Return.Successes = true
+	config.Producer.Compression = meta.Compression
 
-	if maxMessageBytes > 0 {
-		config.Producer.MaxMessageBytes = maxMessageBytes
+	if meta.MaxMessageBytes > 0 {
+		config.Producer.MaxMessageBytes = meta.MaxMessageBytes
+	}
+	if meta.MaxInFlightRequests > 0 {
+		config.Net.MaxOpenRequests = meta.MaxInFlightRequests
+	}
+	if meta.BatchSize > 0 {
+		config.Producer.Flush.Bytes = meta.BatchSize
 	}
+	if meta.BatchTimeout > 0 {
+		config.Producer.Flush.Frequency = meta.BatchTimeout
+	}
+}
+
+func getSyncProducer(config sarama.Config, brokers []string, meta *kafkaMetadata) (sarama.SyncProducer, error) {
+	// Add SyncProducer specific properties to copy of base config
+	updateProducerConfig(&config, meta)
+	config.Producer.Return.Successes = true
 
 	producer, err := sarama.NewSyncProducer(brokers, &config)
 	if err != nil {
@@ -40,9 +55,36 @@ func getSyncProducer(config sarama.Config, brokers []string, maxMessageBytes int
 	return producer, nil
 }
 
+func getAsyncProducer(config sarama.Config, brokers []string, meta *kafkaMetadata) (sarama.AsyncProducer, error) {
+	// Add AsyncProducer specific properties to copy of base config.
+	// The messages are retried in the background, and only the failures are returned.
+	updateProducerConfig(&config, meta)
+	config.Producer.Return.Successes = false
+	config.Producer.Return.Errors = true
+
+	producer, err := sarama.NewAsyncProducer(brokers, &config)
+	if err != nil {
+		return nil, err
+	}
+
+	return producer, nil
+}
+
+// logProducerErrors logs the messages which could not be delivered in the fire-and-forget mode, until the producer is closed.
+func (k *Kafka) logProducerErrors(producer sarama.AsyncProducer) {
+	defer k.producerErrors.Done()
+	for pErr := range producer.Errors() {
+		k.logger.Errorf("Failed to deliver message on topic %v: %v", pErr.Msg.Topic, pErr.Err)
+	}
+}
+
 // Publish message to Kafka cluster.
 func (k *Kafka) Publish(topic string, data []byte, metadata map[string]string) error {
-	if k.producer == nil {
+	// The producer is not closed while the message is sent or enqueued
+	k.producerLock.RLock()
+	defer k.producerLock.RUnlock()
+
+	if k.producer == nil && k.asyncProducer == nil {
 		return errors.New("component is closed")
 	}
 	// k.logger.Debugf("Publishing topic %v with data: %v", topic, string(data))
@@ -67,6 +109,11 @@ func (k *Kafka) Publish(topic string, data []byte, metadata map[string]string) e
 		}
 	}
 
+	if k.asyncProducer != nil {
+		k.asyncProducer.Input() <- msg
+		return nil
+	}
+
 	partition, offset, err := k.producer.SendMessage(msg)
 
 	k.logger.Debugf("Partition: %v, offset: %v", partition, offset)
@@ -79,7 +126,10 @@ func (k *Kafka) Publish(topic string, data []byte, metadata map[string]string) e
 }
 
 func (k *Kafka) BulkPublish(_ context.Context, topic string, entries []pubsub.BulkMessageEntry, metadata map[string]string) (pubsub.BulkPublishResponse, error) {
-	if k.producer == nil {
+	k.producerLock.RLock()
+	defer k.producerLock.RUnlock()
+
+	if k.producer == nil && k.asyncProducer == nil {
 		err := errors.New("component is closed")
 		return pubsub.NewBulkPublishResponse(entries, pubsub.PublishFailed, err), err
 	}
@@ -117,6 +167,13 @@ func (k *Kafka) BulkPublish(_ context.Context, topic string, entries []pubsub.Bu
 		msgs = append(msgs, msg)
 	}
 
+	if k.asyncProducer != nil {
+		for _, msg := range msgs {
+			k.asyncProducer.Input() <- msg
+		}
+		return pubsub.NewBulkPublishResponse(entries, pubsub.PublishSucceeded, nil), nil
+	}
+
 	if err := k.producer.SendMessages(msgs); err != nil {
 		// map the returned error to different entries
 		return k.mapKafkaProducerErrors(err, entries), err
@@ -0,0 +1,97 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/pubsub"
+)
+
+func TestFireAndForgetPublish(t *testing.T) {
+	config := sarama.NewConfig()
+	config.Producer.Return.Errors = true
+
+	t.Run("publish returns once the message is enqueued", func(t *testing.T) {
+		producer := mocks.NewAsyncProducer(t, config)
+		producer.ExpectInputAndSucceed()
+		k := getKafka()
+		k.asyncProducer = producer
+
+		err := k.Publish("topic", []byte("data"), map[string]string{key: "key"})
+
+		require.NoError(t, err)
+		require.NoError(t, k.Close())
+	})
+
+	t.Run("delivery failures are not returned", func(t *testing.T) {
+		producer := mocks.NewAsyncProducer(t, config)
+		producer.ExpectInputAndFail(errors.New("broker down"))
+		k := getKafka()
+		k.asyncProducer = producer
+		k.producerErrors.Add(1)
+		go k.logProducerErrors(producer)
+
+		err := k.Publish("topic", []byte("data"), nil)
+
+		require.NoError(t, err)
+		require.NoError(t, k.Close())
+	})
+
+	t.Run("bulk publish enqueues all the messages", func(t *testing.T) {
+		producer := mocks.NewAsyncProducer(t, config)
+		producer.ExpectInputAndSucceed()
+		producer.ExpectInputAndSucceed()
+		k := getKafka()
+		k.asyncProducer = producer
+
+		res, err := k.BulkPublish(context.Background(), "topic", []pubsub.BulkMessageEntry{
+			{EntryId: "1", Event: []byte("a")},
+			{EntryId: "2", Event: []byte("b")},
+		}, nil)
+
+		require.NoError(t, err)
+		require.Len(t, res.Statuses, 2)
+		require.NoError(t, k.Close())
+	})
+
+	t.Run("close waits for the messages being published", func(t *testing.T) {
+		producer := mocks.NewAsyncProducer(t, config)
+		k := getKafka()
+		k.asyncProducer = producer
+
+		// Publication in progress
+		k.producerLock.RLock()
+		closed := make(chan error)
+		go func() {
+			closed <- k.Close()
+		}()
+		select {
+		case <-closed:
+			t.Fatal("closed while publishing")
+		case <-time.After(100 * time.Millisecond):
+		}
+		k.producerLock.RUnlock()
+		require.NoError(t, <-closed)
+
+		require.ErrorContains(t, k.Publish("topic", []byte("data"), nil), "component is closed")
+	})
+}
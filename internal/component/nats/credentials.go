@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nats
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
+)
+
+// Credentials of the decentralized authentication of NATS: the JWT of a user and its seed key, e.g. the content of the
+// .creds file of a leaf node or of NGS, the path of a .creds file, or a NKey seed.
+type Credentials struct {
+	JWT       string
+	SeedKey   string
+	CredsFile string
+	NkeySeed  string
+	// Public key of the NKey seed.
+	Nkey string
+}
+
+// ParseCredentials returns the credentials of the "jwt" and "seedKey", "credsFile", "credsContent" or "nkeySeed"
+// metadata properties. At most one of them can be set.
+func ParseCredentials(props map[string]string) (Credentials, error) {
+	c := Credentials{
+		JWT:       props["jwt"],
+		SeedKey:   props["seedKey"],
+		CredsFile: props["credsFile"],
+		NkeySeed:  props["nkeySeed"],
+	}
+	credsContent := props["credsContent"]
+
+	if c.JWT != "" && c.SeedKey == "" {
+		return Credentials{}, errors.New("missing seed key")
+	}
+	if c.JWT == "" && c.SeedKey != "" {
+		return Credentials{}, errors.New("missing jwt")
+	}
+
+	credentials := 0
+	for _, v := range []string{c.JWT, c.CredsFile, credsContent, c.NkeySeed} {
+		if v != "" {
+			credentials++
+		}
+	}
+	if credentials > 1 {
+		return Credentials{}, errors.New("only one of jwt and seedKey, credsFile, credsContent and nkeySeed can be set")
+	}
+
+	// The content of a .creds file is decoded into the user JWT and seed key.
+	if credsContent != "" {
+		var err error
+		c.JWT, c.SeedKey, err = parseCredsContent(credsContent)
+		if err != nil {
+			return Credentials{}, err
+		}
+	}
+
+	if c.NkeySeed != "" {
+		kp, err := nkeys.FromSeed([]byte(c.NkeySeed))
+		if err != nil {
+			return Credentials{}, fmt.Errorf("invalid nkey seed: %w", err)
+		}
+		c.Nkey, err = kp.PublicKey()
+		kp.Wipe()
+		if err != nil {
+			return Credentials{}, fmt.Errorf("invalid nkey seed: %w", err)
+		}
+	}
+
+	return c, nil
+}
+
+// Option returns the option authenticating the connection with the credentials, or nil if none is set.
+func (c Credentials) Option() nats.Option {
+	switch {
+	case c.JWT != "":
+		return nats.UserJWT(func() (string, error) {
+			return c.JWT, nil
+		}, func(nonce []byte) ([]byte, error) {
+			return sign(c.SeedKey, nonce)
+		})
+	case c.CredsFile != "":
+		return nats.UserCredentials(c.CredsFile)
+	case c.NkeySeed != "":
+		return nats.Nkey(c.Nkey, func(nonce []byte) ([]byte, error) {
+			return sign(c.NkeySeed, nonce)
+		})
+	default:
+		return nil
+	}
+}
+
+// sign signs the nonce of the server with the seed key.
+func sign(seedKey string, nonce []byte) ([]byte, error) {
+	kp, err := nkeys.FromSeed([]byte(seedKey))
+	if err != nil {
+		return nil, err
+	}
+	// Wipe our key on exit.
+	defer kp.Wipe()
+
+	return kp.Sign(nonce)
+}
+
+// parseCredsContent returns the user JWT and seed key of the content of a .creds file.
+func parseCredsContent(content string) (string, string, error) {
+	jwt, err := nkeys.ParseDecoratedJWT([]byte(content))
+	if err != nil {
+		return "", "", fmt.Errorf("invalid creds content: %w", err)
+	}
+	kp, err := nkeys.ParseDecoratedUserNKey([]byte(content))
+	if err != nil {
+		return "", "", fmt.Errorf("invalid creds content: %w", err)
+	}
+	defer kp.Wipe()
+	seed, err := kp.Seed()
+	if err != nil {
+		return "", "", fmt.Errorf("invalid creds content: %w", err)
+	}
+
+	return jwt, string(seed), nil
+}
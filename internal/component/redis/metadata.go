@@ -24,10 +24,13 @@ const (
 	maxRetryBackoff        = "maxRetryBackoff"
 	ttlInSeconds           = "ttlInSeconds"
 	queryIndexes           = "queryIndexes"
+	clientSideCaching      = "clientSideCaching"
+	clientSideCacheMaxKeys = "clientSideCacheMaxKeys"
 	defaultBase            = 10
 	defaultBitSize         = 0
 	defaultMaxRetries      = 3
 	defaultMaxRetryBackoff = time.Second * 2
+	defaultCacheMaxKeys    = 10000
 )
 
 type Metadata struct {
@@ -35,6 +38,9 @@ type Metadata struct {
 	MaxRetryBackoff time.Duration
 	TTLInSeconds    *int
 	QueryIndexes    string
+	// Server-assisted client-side caching of the state entries, with at most ClientSideCacheMaxKeys keys.
+	ClientSideCaching      bool
+	ClientSideCacheMaxKeys int
 }
 
 func ParseRedisMetadata(properties map[string]string) (Metadata, error) {
@@ -72,5 +78,25 @@ func ParseRedisMetadata(properties map[string]string) (Metadata, error) {
 	if val, ok := properties[queryIndexes]; ok && val != "" {
 		m.QueryIndexes = val
 	}
+
+	if val, ok := properties[clientSideCaching]; ok && val != "" {
+		parsedVal, err := strconv.ParseBool(val)
+		if err != nil {
+			return m, fmt.Errorf("redis store error: can't parse clientSideCaching field: %s", err)
+		}
+		m.ClientSideCaching = parsedVal
+	}
+
+	m.ClientSideCacheMaxKeys = defaultCacheMaxKeys
+	if val, ok := properties[clientSideCacheMaxKeys]; ok && val != "" {
+		parsedVal, err := strconv.ParseInt(val, defaultBase, defaultBitSize)
+		if err != nil {
+			return m, fmt.Errorf("redis store error: can't parse clientSideCacheMaxKeys field: %s", err)
+		}
+		if parsedVal <= 0 {
+			return m, fmt.Errorf("redis store error: clientSideCacheMaxKeys must be greater than 0")
+		}
+		m.ClientSideCacheMaxKeys = int(parsedVal)
+	}
 	return m, nil
 }
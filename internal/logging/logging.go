@@ -0,0 +1,74 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package logging adds structured fields identifying components and operations to their logs.
+package logging
+
+import (
+	"context"
+
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// ComponentNameField is the log field holding the name of the component.
+	ComponentNameField = "component"
+	// ComponentTypeField is the log field holding the type of the component, e.g. "bindings.kubemq".
+	ComponentTypeField = "componentType"
+	// CorrelationIDField is the log field holding the correlation ID of an operation.
+	CorrelationIDField = "correlationId"
+
+	// Request metadata holding the W3C trace context, used as correlation ID when the context has none.
+	traceParentMetadata = "traceparent"
+)
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a copy of ctx carrying the correlation ID of an operation.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationID returns the correlation ID carried by ctx, or an empty string.
+func CorrelationID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+
+	return id
+}
+
+// ForComponent returns a logger whose lines carry the type and the name of a component.
+func ForComponent(log logger.Logger, componentType, name string) logger.Logger {
+	fields := map[string]any{ComponentTypeField: componentType}
+	if name != "" {
+		fields[ComponentNameField] = name
+	}
+
+	return log.WithFields(fields)
+}
+
+// ForRequest returns a logger whose lines carry the correlation ID of an operation, taken from ctx or else
+// from the trace context in the request metadata. log is returned as-is when there is none.
+func ForRequest(ctx context.Context, log logger.Logger, metadata map[string]string) logger.Logger {
+	id := CorrelationID(ctx)
+	if id == "" {
+		id = metadata[traceParentMetadata]
+	}
+	if id == "" {
+		return log
+	}
+
+	return log.WithFields(map[string]any{CorrelationIDField: id})
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/kit/logger"
+)
+
+func newTestLogger() (logger.Logger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	log := logger.NewLogger("test")
+	log.EnableJSONOutput(true)
+	log.SetOutput(buf)
+
+	return log, buf
+}
+
+func lastLine(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+
+	return line
+}
+
+func TestCorrelationID(t *testing.T) {
+	ctx := WithCorrelationID(context.Background(), "abc")
+
+	assert.Equal(t, "abc", CorrelationID(ctx))
+	assert.Empty(t, CorrelationID(context.Background()))
+}
+
+func TestForComponent(t *testing.T) {
+	log, buf := newTestLogger()
+
+	ForComponent(log, "bindings.kubemq", "queue").Error("boom")
+
+	line := lastLine(t, buf)
+	assert.Equal(t, "bindings.kubemq", line[ComponentTypeField])
+	assert.Equal(t, "queue", line[ComponentNameField])
+	assert.Equal(t, "boom", line["msg"])
+}
+
+func TestForRequest(t *testing.T) {
+	t.Run("correlation ID of the context", func(t *testing.T) {
+		log, buf := newTestLogger()
+		ctx := WithCorrelationID(context.Background(), "abc")
+
+		ForRequest(ctx, log, map[string]string{"traceparent": "00-trace"}).Error("boom")
+
+		assert.Equal(t, "abc", lastLine(t, buf)[CorrelationIDField])
+	})
+
+	t.Run("trace context of the request", func(t *testing.T) {
+		log, buf := newTestLogger()
+
+		ForRequest(context.Background(), log, map[string]string{"traceparent": "00-trace"}).Error("boom")
+
+		assert.Equal(t, "00-trace", lastLine(t, buf)[CorrelationIDField])
+	})
+
+	t.Run("none", func(t *testing.T) {
+		log, _ := newTestLogger()
+
+		assert.Equal(t, log, ForRequest(context.Background(), log, nil))
+	})
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package poller implements the polling loop shared by the input bindings which periodically look for new items in
+// their source.
+package poller
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// CursorStateStoreKey is the metadata key for the name of the state store persisting the cursors of a component.
+	CursorStateStoreKey = "cursorStateStore"
+	// CursorKeyKey is the metadata key for the key of the cursor in the state store, the name of the component by
+	// default.
+	CursorKeyKey = "cursorKey"
+)
+
+// Poll polls the source once, starting from cursor, and returns the cursor to start the next poll from.
+// more reports whether further items may be available right away, in which case the source is polled again without
+// waiting for the interval.
+type Poll func(ctx context.Context, cursor string) (next string, more bool, err error)
+
+// CursorStore persists the cursors of the pollers, so they resume from where they stopped.
+type CursorStore interface {
+	// GetCursor returns the cursor saved with the key, or the empty string if there is none.
+	GetCursor(ctx context.Context, key string) (string, error)
+	SetCursor(ctx context.Context, key string, cursor string) error
+}
+
+// Options are the options of a Poller.
+type Options struct {
+	// Interval between the polls which don't find more items.
+	Interval time.Duration
+	// Maximum random delay added to the interval, so that pollers started together don't poll together.
+	Jitter time.Duration
+	// Maximum delay before polling again after consecutive errors; the interval by default.
+	// The delay starts at half a second (or the interval if shorter) and doubles after each error.
+	MaxBackoff time.Duration
+	// Store of the cursor, which is not persisted if nil.
+	CursorStore CursorStore
+	// Key of the cursor in the store.
+	CursorKey string
+}
+
+// Poller polls a source until its context is canceled.
+type Poller struct {
+	poll    Poll
+	opts    Options
+	logger  logger.Logger
+	trigger chan struct{}
+}
+
+// New returns a new Poller.
+func New(poll Poll, opts Options, logger logger.Logger) *Poller {
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = opts.Interval
+	}
+
+	return &Poller{
+		poll:    poll,
+		opts:    opts,
+		logger:  logger,
+		trigger: make(chan struct{}, 1),
+	}
+}
+
+// Trigger makes the poller poll again as soon as the current poll, if any, completes, without waiting for the interval.
+func (p *Poller) Trigger() {
+	select {
+	case p.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run loads the cursor, then polls until ctx is canceled, starting right away.
+// Failed polls are retried from the same cursor with an exponential backoff; the cursor is saved after each successful
+// poll which changes it.
+func (p *Poller) Run(ctx context.Context) error {
+	cursor := ""
+	if p.opts.CursorStore != nil {
+		var err error
+		cursor, err = p.opts.CursorStore.GetCursor(ctx, p.opts.CursorKey)
+		if err != nil {
+			return fmt.Errorf("failed to load the cursor %s: %w", p.opts.CursorKey, err)
+		}
+	}
+
+	bo := backoff.NewExponentialBackOff()
+	bo.InitialInterval = 500 * time.Millisecond
+	if bo.InitialInterval > p.opts.MaxBackoff {
+		bo.InitialInterval = p.opts.MaxBackoff
+	}
+	bo.MaxInterval = p.opts.MaxBackoff
+	// Retry forever
+	bo.MaxElapsedTime = 0
+
+	for ctx.Err() == nil {
+		next, more, err := p.poll(ctx, cursor)
+
+		var wait time.Duration
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return nil
+			}
+			wait = bo.NextBackOff()
+			p.logger.Errorf("Error polling, retrying in %v: %v", wait, err)
+		case more:
+			bo.Reset()
+		default:
+			bo.Reset()
+			wait = p.opts.Interval
+			if p.opts.Jitter > 0 {
+				wait += time.Duration(rand.Int63n(int64(p.opts.Jitter))) //nolint:gosec
+			}
+		}
+
+		if err == nil && next != cursor {
+			cursor = next
+			p.saveCursor(ctx, cursor)
+		}
+
+		if wait > 0 {
+			p.wait(ctx, wait)
+		}
+	}
+
+	return nil
+}
+
+func (p *Poller) saveCursor(ctx context.Context, cursor string) {
+	if p.opts.CursorStore == nil {
+		return
+	}
+
+	// A failure only means that some items may be polled again after a restart
+	if err := p.opts.CursorStore.SetCursor(ctx, p.opts.CursorKey, cursor); err != nil {
+		p.logger.Warnf("Failed to save the cursor %s: %v", p.opts.CursorKey, err)
+	}
+}
+
+// wait waits for the delay, until ctx is canceled, or until the poller is triggered.
+func (p *Poller) wait(ctx context.Context, delay time.Duration) {
+	t := time.NewTimer(delay)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	case <-p.trigger:
+	}
+}
+
+// stateCursorStore is a CursorStore saving the cursors in a state store.
+type stateCursorStore struct {
+	store state.Store
+}
+
+// NewStateCursorStore returns a CursorStore saving the cursors in a state store, with the cursor keys as state keys.
+func NewStateCursorStore(store state.Store) CursorStore {
+	return &stateCursorStore{store: store}
+}
+
+func (s *stateCursorStore) GetCursor(ctx context.Context, key string) (string, error) {
+	res, err := s.store.Get(&state.GetRequest{Key: key})
+	if err != nil {
+		return "", err
+	}
+	if res == nil {
+		return "", nil
+	}
+
+	return string(res.Data), nil
+}
+
+func (s *stateCursorStore) SetCursor(ctx context.Context, key string, cursor string) error {
+	return s.store.Set(&state.SetRequest{Key: key, Value: []byte(cursor)})
+}
+
+// CursorStoreFromMetadata returns the CursorStore saving the cursors in the state store named by the cursorStateStore
+// metadata, resolved with resolve, and the key of the cursor. The CursorStore is nil if no state store is configured.
+func CursorStoreFromMetadata(props map[string]string, name string, resolve func(name string) (state.Store, error)) (CursorStore, string, error) {
+	storeName := props[CursorStateStoreKey]
+	if storeName == "" {
+		return nil, "", nil
+	}
+	if resolve == nil {
+		return nil, "", fmt.Errorf("%s is set but the state stores of the app are not available", CursorStateStoreKey)
+	}
+	store, err := resolve(storeName)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get the state store %s of the cursors: %w", storeName, err)
+	}
+
+	key := props[CursorKeyKey]
+	if key == "" {
+		key = name
+	}
+	if key == "" {
+		return nil, "", fmt.Errorf("%s is required with %s", CursorKeyKey, CursorStateStoreKey)
+	}
+
+	return NewStateCursorStore(store), key, nil
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poller
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/state"
+	stateInMemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/kit/logger"
+)
+
+// recorder records the cursors the source is polled from, and cancels the context after the given number of polls.
+type recorder struct {
+	lock    sync.Mutex
+	cursors []string
+	times   []time.Time
+	cancel  context.CancelFunc
+	polls   int
+}
+
+func (r *recorder) record(cursor string) int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.cursors = append(r.cursors, cursor)
+	r.times = append(r.times, time.Now())
+	if len(r.cursors) == r.polls {
+		r.cancel()
+	}
+
+	return len(r.cursors)
+}
+
+func runPoller(t *testing.T, polls int, poll func(r *recorder, ctx context.Context, cursor string) (string, bool, error), opts Options) *recorder {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := &recorder{cancel: cancel, polls: polls}
+	p := New(func(ctx context.Context, cursor string) (string, bool, error) {
+		return poll(r, ctx, cursor)
+	}, opts, logger.NewLogger("test"))
+
+	done := make(chan error)
+	go func() {
+		done <- p.Run(ctx)
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the poller")
+	}
+
+	return r
+}
+
+func TestPoller(t *testing.T) {
+	t.Run("polls again right away while there are more items", func(t *testing.T) {
+		r := runPoller(t, 4, func(r *recorder, ctx context.Context, cursor string) (string, bool, error) {
+			n := r.record(cursor)
+			return strconv.Itoa(n), n < 3, nil
+		}, Options{Interval: 200 * time.Millisecond})
+
+		assert.Equal(t, []string{"", "1", "2", "3"}, r.cursors)
+		assert.Less(t, r.times[2].Sub(r.times[0]), 100*time.Millisecond)
+		assert.GreaterOrEqual(t, r.times[3].Sub(r.times[2]), 200*time.Millisecond)
+	})
+
+	t.Run("adds the jitter to the interval", func(t *testing.T) {
+		r := runPoller(t, 2, func(r *recorder, ctx context.Context, cursor string) (string, bool, error) {
+			r.record(cursor)
+			return "", false, nil
+		}, Options{Interval: 100 * time.Millisecond, Jitter: 100 * time.Millisecond})
+
+		delay := r.times[1].Sub(r.times[0])
+		assert.GreaterOrEqual(t, delay, 100*time.Millisecond)
+		assert.Less(t, delay, time.Second)
+	})
+
+	t.Run("retries failed polls from the same cursor with backoff", func(t *testing.T) {
+		r := runPoller(t, 3, func(r *recorder, ctx context.Context, cursor string) (string, bool, error) {
+			n := r.record(cursor)
+			if n < 3 {
+				return "ignored", false, errors.New("failed")
+			}
+			return "next", false, nil
+		}, Options{Interval: time.Hour, MaxBackoff: 50 * time.Millisecond})
+
+		assert.Equal(t, []string{"", "", ""}, r.cursors)
+	})
+
+	t.Run("triggered polls don't wait for the interval", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		polls := make(chan struct{}, 10)
+		p := New(func(ctx context.Context, cursor string) (string, bool, error) {
+			polls <- struct{}{}
+			return "", false, nil
+		}, Options{Interval: time.Hour}, logger.NewLogger("test"))
+		go p.Run(ctx)
+
+		<-polls
+		p.Trigger()
+		select {
+		case <-polls:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the poller wasn't triggered")
+		}
+	})
+
+	t.Run("cursor is persisted", func(t *testing.T) {
+		store := stateInMemory.NewInMemoryStateStore(logger.NewLogger("test"))
+		require.NoError(t, store.Init(state.Metadata{}))
+		cursors := NewStateCursorStore(store)
+		require.NoError(t, cursors.SetCursor(context.Background(), "cursor", "5"))
+
+		opts := Options{Interval: time.Hour, CursorStore: cursors, CursorKey: "cursor"}
+		r := runPoller(t, 2, func(r *recorder, ctx context.Context, cursor string) (string, bool, error) {
+			r.record(cursor)
+			n, _ := strconv.Atoi(cursor)
+			return strconv.Itoa(n + 1), true, nil
+		}, opts)
+		assert.Equal(t, []string{"5", "6"}, r.cursors)
+
+		// A new poller resumes from the saved cursor
+		r = runPoller(t, 1, func(r *recorder, ctx context.Context, cursor string) (string, bool, error) {
+			r.record(cursor)
+			return cursor, false, nil
+		}, opts)
+		assert.Equal(t, []string{"7"}, r.cursors)
+	})
+}
+
+func TestCursorStoreFromMetadata(t *testing.T) {
+	store := stateInMemory.NewInMemoryStateStore(logger.NewLogger("test"))
+	require.NoError(t, store.Init(state.Metadata{}))
+	resolve := func(name string) (state.Store, error) {
+		if name != "statestore" {
+			return nil, errors.New("state store not found")
+		}
+		return store, nil
+	}
+
+	t.Run("no state store", func(t *testing.T) {
+		cursors, _, err := CursorStoreFromMetadata(map[string]string{}, "binding", resolve)
+
+		require.NoError(t, err)
+		assert.Nil(t, cursors)
+	})
+
+	t.Run("key of the cursor", func(t *testing.T) {
+		cursors, key, err := CursorStoreFromMetadata(map[string]string{CursorStateStoreKey: "statestore"}, "binding", resolve)
+		require.NoError(t, err)
+		assert.Equal(t, "binding", key)
+		cursor, err := cursors.GetCursor(context.Background(), key)
+		require.NoError(t, err)
+		assert.Equal(t, "", cursor)
+
+		_, key, err = CursorStoreFromMetadata(map[string]string{CursorStateStoreKey: "statestore", CursorKeyKey: "orders"}, "binding", resolve)
+		require.NoError(t, err)
+		assert.Equal(t, "orders", key)
+	})
+
+	t.Run("unknown state store", func(t *testing.T) {
+		_, _, err := CursorStoreFromMetadata(map[string]string{CursorStateStoreKey: "other"}, "binding", resolve)
+
+		assert.EqualError(t, err, "failed to get the state store other of the cursors: state store not found")
+	})
+
+	t.Run("state stores not available", func(t *testing.T) {
+		_, _, err := CursorStoreFromMetadata(map[string]string{CursorStateStoreKey: "statestore"}, "binding", nil)
+
+		assert.EqualError(t, err, "cursorStateStore is set but the state stores of the app are not available")
+	})
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultCancelGracePeriod is the time given to the handlers to return once their context is canceled.
+const defaultCancelGracePeriod = 5 * time.Second
+
+// InFlight tracks the messages being processed by the handlers of a consumer, so they can be drained on shutdown:
+// once draining starts no new message is accepted, and the in-flight ones are given some time to complete.
+type InFlight struct {
+	wg       sync.WaitGroup
+	lock     sync.RWMutex
+	draining bool
+	ctx      context.Context
+	cancel   context.CancelFunc
+	// Handlers ignoring the cancellation are abandoned after this period
+	cancelGracePeriod time.Duration
+}
+
+// NewInFlight returns a new InFlight object.
+func NewInFlight() *InFlight {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &InFlight{
+		ctx:               ctx,
+		cancel:            cancel,
+		cancelGracePeriod: defaultCancelGracePeriod,
+	}
+}
+
+// Context returns the context for the handlers of the messages.
+// It's not canceled when the consumer stops fetching messages, but only when draining times out.
+func (f *InFlight) Context() context.Context {
+	return f.ctx
+}
+
+// Add registers a message about to be processed.
+// It returns false if the consumer is draining, in which case the message must not be processed, and Done must not be
+// invoked.
+func (f *InFlight) Add() bool {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	if f.draining {
+		return false
+	}
+	f.wg.Add(1)
+	return true
+}
+
+// Done signals that the processing of a message, including its acknowledgement, completed.
+func (f *InFlight) Done() {
+	f.wg.Done()
+}
+
+// Draining returns true once draining started.
+func (f *InFlight) Draining() bool {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return f.draining
+}
+
+// Drain stops accepting new messages and waits for the in-flight ones to complete, for up to timeout.
+// If the timeout expires, the context of the handlers is canceled, so they can settle their messages as failed, and
+// Drain waits for them to return for a short grace period, or until ctx is done. Handlers which ignore the
+// cancellation are abandoned.
+// It returns true if all the messages completed before the timeout.
+func (f *InFlight) Drain(ctx context.Context, timeout time.Duration) bool {
+	f.lock.Lock()
+	f.draining = true
+	f.lock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		f.wg.Wait()
+		close(done)
+	}()
+
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-done:
+		f.cancel()
+		return true
+	case <-t.C:
+	case <-ctx.Done():
+	}
+
+	f.cancel()
+	grace := time.NewTimer(f.cancelGracePeriod)
+	defer grace.Stop()
+	select {
+	case <-done:
+	case <-grace.C:
+	case <-ctx.Done():
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInFlight(t *testing.T) {
+	t.Run("drain waits for in-flight messages", func(t *testing.T) {
+		f := NewInFlight()
+		assert.True(t, f.Add())
+
+		completed := make(chan struct{})
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			close(completed)
+			f.Done()
+		}()
+
+		assert.True(t, f.Drain(context.Background(), 5*time.Second))
+		assert.True(t, f.Draining())
+		select {
+		case <-completed:
+		default:
+			t.Fatal("drain returned before the message completed")
+		}
+		assert.Error(t, f.Context().Err())
+	})
+
+	t.Run("no new messages while draining", func(t *testing.T) {
+		f := NewInFlight()
+		assert.True(t, f.Drain(context.Background(), time.Second))
+		assert.False(t, f.Add())
+	})
+
+	t.Run("handlers are canceled when the timeout expires", func(t *testing.T) {
+		f := NewInFlight()
+		assert.True(t, f.Add())
+
+		canceled := make(chan struct{})
+		go func() {
+			<-f.Context().Done()
+			close(canceled)
+			f.Done()
+		}()
+
+		start := time.Now()
+		assert.False(t, f.Drain(context.Background(), 50*time.Millisecond))
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+		<-canceled
+	})
+
+	t.Run("handlers ignoring the cancellation are abandoned", func(t *testing.T) {
+		f := NewInFlight()
+		f.cancelGracePeriod = 50 * time.Millisecond
+		assert.True(t, f.Add())
+
+		start := time.Now()
+		assert.False(t, f.Drain(context.Background(), 50*time.Millisecond))
+		assert.Less(t, time.Since(start), 5*time.Second)
+		assert.Error(t, f.Context().Err())
+	})
+
+	t.Run("stops waiting when the context is done", func(t *testing.T) {
+		f := NewInFlight()
+		assert.True(t, f.Add())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		assert.False(t, f.Drain(ctx, time.Minute))
+		assert.Error(t, f.Context().Err())
+	})
+}
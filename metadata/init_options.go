@@ -0,0 +1,63 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// FailFastKey defines the metadata key for whether Init fails if the component cannot connect to its backend.
+	// When disabled, the component connects lazily and keeps retrying in the background.
+	FailFastKey = "failFast"
+
+	// InitTimeoutKey defines the metadata key for how long Init waits for the connection when failing fast.
+	InitTimeoutKey = "initTimeout"
+)
+
+// InitOptions controls whether components verify the connectivity to their backend in Init.
+type InitOptions struct {
+	FailFast bool
+	Timeout  time.Duration
+}
+
+// GetInitOptions returns the init options from the metadata, using the component's defaults for the ones not set.
+func GetInitOptions(props map[string]string, defaults InitOptions) (InitOptions, error) {
+	opts := defaults
+
+	if val, ok := props[FailFastKey]; ok && val != "" {
+		failFast, err := strconv.ParseBool(val)
+		if err != nil {
+			return opts, errors.Wrapf(err, "%s value must be a valid boolean: actual is '%s'", FailFastKey, val)
+		}
+		opts.FailFast = failFast
+	}
+
+	if val, ok := props[InitTimeoutKey]; ok && val != "" {
+		timeout, err := time.ParseDuration(val)
+		if err != nil {
+			return opts, errors.Wrapf(err, "%s value must be a valid duration: actual is '%s'", InitTimeoutKey, val)
+		}
+		if timeout <= 0 {
+			return opts, fmt.Errorf("%s value must be higher than zero: actual is %s", InitTimeoutKey, val)
+		}
+		opts.Timeout = timeout
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetInitOptions(t *testing.T) {
+	defaults := InitOptions{FailFast: true, Timeout: 30 * time.Second}
+
+	t.Run("Metadata without init options", func(t *testing.T) {
+		opts, err := GetInitOptions(map[string]string{}, defaults)
+
+		assert.Nil(t, err)
+		assert.Equal(t, defaults, opts)
+	})
+
+	t.Run("Metadata with init options", func(t *testing.T) {
+		opts, err := GetInitOptions(map[string]string{
+			"failFast":    "false",
+			"initTimeout": "5s",
+		}, defaults)
+
+		assert.Nil(t, err)
+		assert.Equal(t, InitOptions{FailFast: false, Timeout: 5 * time.Second}, opts)
+	})
+
+	t.Run("Metadata with invalid failFast", func(t *testing.T) {
+		_, err := GetInitOptions(map[string]string{"failFast": "maybe"}, defaults)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Metadata with invalid initTimeout", func(t *testing.T) {
+		_, err := GetInitOptions(map[string]string{"initTimeout": "5"}, defaults)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Metadata with negative initTimeout", func(t *testing.T) {
+		_, err := GetInitOptions(map[string]string{"initTimeout": "-5s"}, defaults)
+
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Types of the metadata fields, as in the metadata.yaml files of the components.
+const (
+	FieldTypeString   = "string"
+	FieldTypeNumber   = "number"
+	FieldTypeBool     = "bool"
+	FieldTypeDuration = "duration"
+)
+
+// MetadataField is the schema of a metadata field of a component.
+type MetadataField struct {
+	// Name of the metadata property.
+	Name string `json:"name"`
+	// Type of the property: string, number, bool or duration.
+	Type string `json:"type"`
+	// If true, the property is required.
+	Required bool `json:"required,omitempty"`
+	// Default value of the property, empty if it has none.
+	Default string `json:"default,omitempty"`
+	// If true, the property is a sensitive value such as a password.
+	Sensitive bool `json:"sensitive,omitempty"`
+}
+
+// ComponentWithMetadataSchema is implemented by the components exporting the schema of their metadata, so tooling can
+// generate validations and UIs.
+type ComponentWithMetadataSchema interface {
+	// GetComponentMetadataSchema returns the schema of the metadata fields of the component.
+	GetComponentMetadataSchema() []MetadataField
+}
+
+// GetMetadataSchemaFromStruct returns the schema of the metadata decoded into a struct.
+// The name of a field is its mapstructure or json tag, or its name starting with a lowercase letter. The fields with
+// the tags mdrequired:"true" or mdsensitive:"true" are required or sensitive, and the mdtype tag overrides the type
+// of a field, such as for the enums parsed from strings. The default value of a field is its value in s, when it's not
+// the zero value, so s is usually the struct with the defaults of the component.
+func GetMetadataSchemaFromStruct(s interface{}) ([]MetadataField, error) {
+	v := reflect.ValueOf(s)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("not a struct: %s", v.Kind().String())
+	}
+
+	fields := []MetadataField{}
+	appendMetadataSchema(v, &fields)
+	return fields, nil
+}
+
+func appendMetadataSchema(v reflect.Value, fields *[]MetadataField) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		currentField := t.Field(i)
+		if !currentField.IsExported() {
+			continue
+		}
+
+		name := metadataFieldName(currentField)
+		if name == "-" {
+			continue
+		}
+		if currentField.Anonymous && currentField.Type.Kind() == reflect.Struct {
+			// Traverse embedded structs
+			appendMetadataSchema(v.Field(i), fields)
+			continue
+		}
+
+		field := MetadataField{
+			Name:      name,
+			Type:      currentField.Tag.Get("mdtype"),
+			Required:  currentField.Tag.Get("mdrequired") == "true",
+			Sensitive: currentField.Tag.Get("mdsensitive") == "true",
+		}
+		if field.Type == "" {
+			field.Type = metadataFieldType(currentField.Type)
+		}
+		if value := v.Field(i); !value.IsZero() {
+			field.Default = metadataFieldDefault(value)
+		}
+		*fields = append(*fields, field)
+	}
+}
+
+func metadataFieldName(field reflect.StructField) string {
+	for _, tag := range []string{"mapstructure", "json"} {
+		if name, _, _ := strings.Cut(field.Tag.Get(tag), ","); name != "" {
+			return name
+		}
+	}
+
+	r, size := utf8.DecodeRuneInString(field.Name)
+	return string(unicode.ToLower(r)) + field.Name[size:]
+}
+
+var (
+	durationType         = reflect.TypeOf(time.Duration(0))
+	metadataDurationType = reflect.TypeOf(Duration{})
+)
+
+func metadataFieldType(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == durationType || t == metadataDurationType {
+		return FieldTypeDuration
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return FieldTypeBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return FieldTypeNumber
+	default:
+		// Strings, and lists as comma-separated strings
+		return FieldTypeString
+	}
+}
+
+func metadataFieldDefault(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	switch val := v.Interface().(type) {
+	case time.Duration:
+		return val.String()
+	case Duration:
+		return val.String()
+	case []string:
+		return strings.Join(val, ",")
+	default:
+		return fmt.Sprint(val)
+	}
+}
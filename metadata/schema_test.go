@@ -0,0 +1,76 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMetadataSchemaFromStruct(t *testing.T) {
+	type Embedded struct {
+		Endpoint string `mapstructure:"endpoint"`
+	}
+	type testMetadata struct {
+		Embedded   `mapstructure:",squash"`
+		Host       string `mapstructure:"host" mdrequired:"true"`
+		Password   string `json:"password,omitempty" mdsensitive:"true"`
+		Port       int
+		Enabled    bool
+		Timeout    time.Duration
+		Interval   Duration
+		Topics     []string
+		Level      int    `mdtype:"string"`
+		Ignored    string `mapstructure:"-"`
+		unexported string
+	}
+
+	t.Run("fields", func(t *testing.T) {
+		fields, err := GetMetadataSchemaFromStruct(testMetadata{
+			Port:     8080,
+			Timeout:  5 * time.Second,
+			Interval: Duration{time.Minute},
+			Topics:   []string{"a", "b"},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []MetadataField{
+			{Name: "endpoint", Type: FieldTypeString},
+			{Name: "host", Type: FieldTypeString, Required: true},
+			{Name: "password", Type: FieldTypeString, Sensitive: true},
+			{Name: "port", Type: FieldTypeNumber, Default: "8080"},
+			{Name: "enabled", Type: FieldTypeBool},
+			{Name: "timeout", Type: FieldTypeDuration, Default: "5s"},
+			{Name: "interval", Type: FieldTypeDuration, Default: "1m0s"},
+			{Name: "topics", Type: FieldTypeString, Default: "a,b"},
+			{Name: "level", Type: FieldTypeString},
+		}, fields)
+	})
+
+	t.Run("pointer to struct", func(t *testing.T) {
+		fields, err := GetMetadataSchemaFromStruct(&testMetadata{Host: "localhost"})
+
+		require.NoError(t, err)
+		assert.Equal(t, MetadataField{Name: "host", Type: FieldTypeString, Required: true, Default: "localhost"}, fields[1])
+	})
+
+	t.Run("not a struct", func(t *testing.T) {
+		_, err := GetMetadataSchemaFromStruct("host")
+
+		assert.ErrorContains(t, err, "not a struct: string")
+	})
+}
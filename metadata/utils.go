@@ -52,6 +52,12 @@ const (
 
 	// MaxBulkPubBytesKey defines the maximum bytes to publish in a bulk publish request metadata.
 	MaxBulkPubBytesKey string = "maxBulkPubBytes"
+
+	// AsOfMetadataKey defines the metadata key for reading the value that a key had at a point in time (RFC 3339), with stores that keep history.
+	AsOfMetadataKey = "asOf"
+
+	// VersionMetadataKey defines the metadata key for reading a given version of a value, with stores that keep history.
+	VersionMetadataKey = "version"
 )
 
 // TryGetTTL tries to get the ttl as a time.Duration value for pubsub, binding and any other building block.
@@ -99,6 +105,20 @@ func TryGetPriority(props map[string]string) (uint8, bool, error) {
 	return 0, false, nil
 }
 
+// TryGetAsOf tries to get the point in time to read historical values at, for state stores that support it.
+func TryGetAsOf(props map[string]string) (time.Time, bool, error) {
+	if val, ok := props[AsOfMetadataKey]; ok && val != "" {
+		asOf, err := time.Parse(time.RFC3339Nano, val)
+		if err != nil {
+			return time.Time{}, false, errors.Wrapf(err, "%s value must be a valid RFC 3339 timestamp: actual is '%s'", AsOfMetadataKey, val)
+		}
+
+		return asOf, true, nil
+	}
+
+	return time.Time{}, false, nil
+}
+
 // IsRawPayload determines if payload should be used as-is.
 func IsRawPayload(props map[string]string) (bool, error) {
 	if val, ok := props[RawPayloadKey]; ok && val != "" {
@@ -94,6 +94,34 @@ func TestTryGetContentType(t *testing.T) {
 	})
 }
 
+func TestTryGetAsOf(t *testing.T) {
+	t.Run("Metadata without asOf", func(t *testing.T) {
+		_, ok, err := TryGetAsOf(map[string]string{})
+
+		assert.Equal(t, false, ok)
+		assert.Nil(t, err)
+	})
+
+	t.Run("Metadata with valid asOf", func(t *testing.T) {
+		val, ok, err := TryGetAsOf(map[string]string{
+			"asOf": "2022-11-02T10:04:05.123Z",
+		})
+
+		assert.Equal(t, time.Date(2022, 11, 2, 10, 4, 5, 123000000, time.UTC), val)
+		assert.Equal(t, true, ok)
+		assert.Nil(t, err)
+	})
+
+	t.Run("Metadata with invalid asOf", func(t *testing.T) {
+		_, ok, err := TryGetAsOf(map[string]string{
+			"asOf": "yesterday",
+		})
+
+		assert.Equal(t, false, ok)
+		assert.NotNil(t, err)
+	})
+}
+
 func TestMetadataDecode(t *testing.T) {
 	t.Run("Test metadata decoding", func(t *testing.T) {
 		type testMetadata struct {
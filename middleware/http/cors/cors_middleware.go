@@ -0,0 +1,281 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	mdutils "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	headerOrigin           = "Origin"
+	headerVary             = "Vary"
+	headerRequestMethod    = "Access-Control-Request-Method"
+	headerRequestHeaders   = "Access-Control-Request-Headers"
+	headerAllowOrigin      = "Access-Control-Allow-Origin"
+	headerAllowMethods     = "Access-Control-Allow-Methods"
+	headerAllowHeaders     = "Access-Control-Allow-Headers"
+	headerAllowCredentials = "Access-Control-Allow-Credentials"
+	headerExposeHeaders    = "Access-Control-Expose-Headers"
+	headerMaxAge           = "Access-Control-Max-Age"
+)
+
+var defaultAllowedMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
+
+// Metadata is the CORS middleware config.
+type Metadata struct {
+	// Origins allowed to make cross-origin requests, such as https://*.example.com. "*" allows all the origins.
+	AllowedOrigins []string `mapstructure:"allowedOrigins"`
+	AllowedMethods []string `mapstructure:"allowedMethods"`
+	// Headers allowed in the requests. Without any or with "*", the headers of the preflight request are allowed.
+	AllowedHeaders   []string      `mapstructure:"allowedHeaders"`
+	ExposedHeaders   []string      `mapstructure:"exposedHeaders"`
+	AllowCredentials bool          `mapstructure:"allowCredentials"`
+	MaxAge           time.Duration `mapstructure:"maxAge"`
+	// Passes the preflight requests to the next handler instead of responding to them.
+	OptionsPassthrough bool `mapstructure:"optionsPassthrough"`
+}
+
+// NewMiddleware returns a new CORS middleware.
+func NewMiddleware(logger logger.Logger) middleware.Middleware {
+	return &Middleware{logger: logger}
+}
+
+// Middleware is a CORS middleware.
+type Middleware struct {
+	logger logger.Logger
+}
+
+type corsHandler struct {
+	meta           *Metadata
+	allowAll       bool
+	origins        []*regexp.Regexp
+	allowedMethods string
+	allowedHeaders map[string]struct{}
+	exposedHeaders string
+	maxAge         string
+}
+
+// GetHandler returns the HTTP handler provided by the middleware.
+func (m *Middleware) GetHandler(metadata middleware.Metadata) (func(next http.Handler) http.Handler, error) {
+	meta, err := m.getNativeMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := newCORSHandler(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isPreflight(r) {
+				h.handlePreflight(w, r)
+				if meta.OptionsPassthrough {
+					next.ServeHTTP(w, r)
+				} else {
+					w.WriteHeader(http.StatusNoContent)
+				}
+				return
+			}
+
+			h.handleRequest(w, r)
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+func (m *Middleware) getNativeMetadata(metadata middleware.Metadata) (*Metadata, error) {
+	var middlewareMetadata Metadata
+	err := mdutils.DecodeMetadata(metadata.Properties, &middlewareMetadata)
+	if err != nil {
+		return nil, err
+	}
+	if len(middlewareMetadata.AllowedOrigins) == 0 {
+		return nil, errors.New("allowedOrigins is required")
+	}
+	if middlewareMetadata.MaxAge < 0 {
+		return nil, errors.New("maxAge must not be negative")
+	}
+	if middlewareMetadata.AllowCredentials {
+		// Echoing any origin with credentials would let any website make authenticated requests to the app
+		for _, origin := range middlewareMetadata.AllowedOrigins {
+			if strings.TrimSpace(origin) == "*" {
+				return nil, errors.New("allowCredentials can't be used with the \"*\" allowed origin")
+			}
+		}
+	}
+	return &middlewareMetadata, nil
+}
+
+func newCORSHandler(meta *Metadata) (*corsHandler, error) {
+	h := &corsHandler{
+		meta:           meta,
+		allowedHeaders: map[string]struct{}{},
+		exposedHeaders: strings.Join(trim(meta.ExposedHeaders), ", "),
+	}
+
+	for _, origin := range trim(meta.AllowedOrigins) {
+		if origin == "*" {
+			h.allowAll = true
+			continue
+		}
+		// A wildcard matches any part of the host, such as the subdomains
+		pattern := strings.ReplaceAll(regexp.QuoteMeta(strings.ToLower(origin)), `\*`, `[a-z0-9.-]+`)
+		re, err := regexp.Compile("^" + pattern + "$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed origin %s: %w", origin, err)
+		}
+		h.origins = append(h.origins, re)
+	}
+
+	methods := trim(meta.AllowedMethods)
+	if len(methods) == 0 {
+		methods = append([]string{}, defaultAllowedMethods...)
+	}
+	for i := range methods {
+		methods[i] = strings.ToUpper(methods[i])
+	}
+	h.allowedMethods = strings.Join(methods, ", ")
+
+	for _, header := range trim(meta.AllowedHeaders) {
+		if header == "*" {
+			h.allowedHeaders = map[string]struct{}{}
+			break
+		}
+		h.allowedHeaders[http.CanonicalHeaderKey(header)] = struct{}{}
+	}
+
+	if meta.MaxAge > 0 {
+		h.maxAge = strconv.Itoa(int(meta.MaxAge.Seconds()))
+	}
+
+	return h, nil
+}
+
+// handlePreflight sets the headers of the response to a preflight request, if the origin, the method and the headers
+// of the request are allowed.
+func (h *corsHandler) handlePreflight(w http.ResponseWriter, r *http.Request) {
+	headers := w.Header()
+	headers.Add(headerVary, headerOrigin)
+	headers.Add(headerVary, headerRequestMethod)
+	headers.Add(headerVary, headerRequestHeaders)
+
+	origin := r.Header.Get(headerOrigin)
+	if !h.originAllowed(origin) {
+		return
+	}
+	method := strings.ToUpper(r.Header.Get(headerRequestMethod))
+	if !strings.Contains(", "+h.allowedMethods+", ", ", "+method+", ") {
+		return
+	}
+	requestHeaders := r.Header.Get(headerRequestHeaders)
+	if !h.headersAllowed(requestHeaders) {
+		return
+	}
+
+	h.setAllowOrigin(headers, origin)
+	headers.Set(headerAllowMethods, h.allowedMethods)
+	if requestHeaders != "" {
+		headers.Set(headerAllowHeaders, requestHeaders)
+	}
+	if h.maxAge != "" {
+		headers.Set(headerMaxAge, h.maxAge)
+	}
+}
+
+// handleRequest sets the headers of the response to a cross-origin request, if the origin is allowed.
+func (h *corsHandler) handleRequest(w http.ResponseWriter, r *http.Request) {
+	headers := w.Header()
+	headers.Add(headerVary, headerOrigin)
+
+	origin := r.Header.Get(headerOrigin)
+	if origin == "" || !h.originAllowed(origin) {
+		return
+	}
+
+	h.setAllowOrigin(headers, origin)
+	if h.exposedHeaders != "" {
+		headers.Set(headerExposeHeaders, h.exposedHeaders)
+	}
+}
+
+func (h *corsHandler) setAllowOrigin(headers http.Header, origin string) {
+	// allowAll is never set with credentials, which can't be used with the wildcard
+	if h.allowAll {
+		headers.Set(headerAllowOrigin, "*")
+	} else {
+		headers.Set(headerAllowOrigin, origin)
+	}
+	if h.meta.AllowCredentials {
+		headers.Set(headerAllowCredentials, "true")
+	}
+}
+
+func (h *corsHandler) originAllowed(origin string) bool {
+	if h.allowAll {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, re := range h.origins {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *corsHandler) headersAllowed(requestHeaders string) bool {
+	if len(h.allowedHeaders) == 0 || requestHeaders == "" {
+		return true
+	}
+	for _, header := range strings.Split(requestHeaders, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		if _, ok := h.allowedHeaders[http.CanonicalHeaderKey(header)]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get(headerOrigin) != "" && r.Header.Get(headerRequestMethod) != ""
+}
+
+// trim returns the values without spaces around them, skipping the empty ones.
+func trim(values []string) []string {
+	res := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
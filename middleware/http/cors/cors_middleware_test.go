@@ -0,0 +1,180 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/kit/logger"
+)
+
+// mockedRequestHandler acts like an upstream service returns success status code 200 and a fixed response body.
+func mockedRequestHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("from mock"))
+}
+
+func newHandler(t *testing.T, properties map[string]string) http.Handler {
+	t.Helper()
+
+	handler, err := NewMiddleware(logger.NewLogger("cors.test")).GetHandler(middleware.Metadata{Base: metadata.Base{Properties: properties}})
+	require.NoError(t, err)
+
+	return handler(http.HandlerFunc(mockedRequestHandler))
+}
+
+func preflight(origin, method, headers string) *http.Request {
+	r := httptest.NewRequest(http.MethodOptions, "http://localhost:3500/v1.0/invoke/app/method/orders", nil)
+	r.Header.Set("Origin", origin)
+	r.Header.Set("Access-Control-Request-Method", method)
+	if headers != "" {
+		r.Header.Set("Access-Control-Request-Headers", headers)
+	}
+	return r
+}
+
+func TestPreflight(t *testing.T) {
+	handler := newHandler(t, map[string]string{
+		"allowedOrigins":   "https://*.example.com, https://example.org",
+		"allowedMethods":   "get,post",
+		"allowedHeaders":   "Content-Type,X-Api-Key",
+		"allowCredentials": "true",
+		"maxAge":           "10m",
+	})
+
+	t.Run("allowed request", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, preflight("https://shop.eu.example.com", "POST", "content-type, x-api-key"))
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, w.Body.String())
+		assert.Equal(t, "https://shop.eu.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "content-type, x-api-key", w.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+		assert.Equal(t, []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}, w.Header().Values("Vary"))
+	})
+
+	t.Run("disallowed requests", func(t *testing.T) {
+		for _, r := range []*http.Request{
+			preflight("https://example.com", "GET", ""),
+			preflight("https://evil.com", "GET", ""),
+			preflight("https://example.org.evil.com", "GET", ""),
+			preflight("https://example.org", "DELETE", ""),
+			preflight("https://example.org", "GET", "Authorization"),
+		} {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+			assert.Empty(t, w.Header().Get("Access-Control-Allow-Methods"))
+		}
+	})
+
+	t.Run("passthrough", func(t *testing.T) {
+		handler := newHandler(t, map[string]string{
+			"allowedOrigins":     "*",
+			"optionsPassthrough": "true",
+		})
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, preflight("https://example.com", "DELETE", "Authorization"))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "from mock", w.Body.String())
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+		assert.Empty(t, w.Header().Get("Access-Control-Max-Age"))
+	})
+}
+
+func TestRequest(t *testing.T) {
+	request := func(origin string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "http://localhost:3500/v1.0/invoke/app/method/orders", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		return r
+	}
+
+	t.Run("allowed origin", func(t *testing.T) {
+		handler := newHandler(t, map[string]string{
+			"allowedOrigins": "https://example.com",
+			"exposedHeaders": "grpc-status,grpc-message",
+		})
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, request("https://example.com"))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "https://example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "grpc-status, grpc-message", w.Header().Get("Access-Control-Expose-Headers"))
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, "Origin", w.Header().Get("Vary"))
+	})
+
+	t.Run("credentials", func(t *testing.T) {
+		handler := newHandler(t, map[string]string{
+			"allowedOrigins":   "https://example.com",
+			"allowCredentials": "true",
+		})
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, request("https://example.com"))
+
+		assert.Equal(t, "https://example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	})
+
+	t.Run("disallowed and same-origin requests", func(t *testing.T) {
+		handler := newHandler(t, map[string]string{"allowedOrigins": "https://example.com"})
+
+		for _, origin := range []string{"https://evil.com", ""} {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, request(origin))
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "from mock", w.Body.String())
+			assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+		}
+	})
+}
+
+func TestGetNativeMetadata(t *testing.T) {
+	m := &Middleware{}
+
+	_, err := m.GetHandler(middleware.Metadata{})
+	assert.ErrorContains(t, err, "allowedOrigins is required")
+
+	_, err = m.GetHandler(middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"allowedOrigins": "*",
+		"maxAge":         "-1s",
+	}}})
+	assert.ErrorContains(t, err, "maxAge must not be negative")
+
+	_, err = m.GetHandler(middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"allowedOrigins":   "https://example.com,*",
+		"allowCredentials": "true",
+	}}})
+	assert.ErrorContains(t, err, "allowCredentials can't be used")
+}
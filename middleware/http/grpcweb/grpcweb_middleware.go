@@ -0,0 +1,288 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	mdutils "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	contentTypeGRPC    = "application/grpc"
+	contentTypeGRPCWeb = "application/grpc-web"
+	// Suffix of the content type of the requests and responses encoded in base64
+	textSuffix = "-text"
+
+	// Flag of the frame with the trailers at the end of a gRPC-Web response
+	trailersFrameFlag = 0x80
+)
+
+// Metadata is the gRPC-Web middleware config.
+type Metadata struct {
+	// Prefixes of the paths of the requests translated, all of them by default.
+	PathPrefixes []string `mapstructure:"pathPrefixes"`
+}
+
+// NewMiddleware returns a new gRPC-Web middleware.
+func NewMiddleware(logger logger.Logger) middleware.Middleware {
+	return &Middleware{logger: logger}
+}
+
+// Middleware is a middleware translating the gRPC-Web requests of browsers into gRPC requests for the backends,
+// and their responses back into gRPC-Web responses.
+// The protocol of the requests is kept: the next handler is expected to forward them to the backend over HTTP/2.
+type Middleware struct {
+	logger logger.Logger
+}
+
+// GetHandler returns the HTTP handler provided by the middleware.
+func (m *Middleware) GetHandler(metadata middleware.Metadata) (func(next http.Handler) http.Handler, error) {
+	meta, err := m.getNativeMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contentType := r.Header.Get("Content-Type")
+			if r.Method != http.MethodPost || !strings.HasPrefix(contentType, contentTypeGRPCWeb) || !meta.matches(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// application/grpc-web-text+proto is application/grpc+proto in base64
+			subtype := strings.TrimPrefix(contentType, contentTypeGRPCWeb)
+			text := strings.HasPrefix(subtype, textSuffix)
+			subtype = strings.TrimPrefix(subtype, textSuffix)
+
+			req := r.Clone(r.Context())
+			req.Header.Set("Content-Type", contentTypeGRPC+subtype)
+			req.Header.Set("Te", "trailers")
+			req.Header.Del("Content-Length")
+			req.ContentLength = -1
+			if text {
+				req.Body = io.NopCloser(&base64Reader{r: r.Body})
+			}
+
+			rw := newResponseWriter(w, contentType, text)
+			next.ServeHTTP(rw, req)
+			err := rw.finish()
+			if err != nil {
+				m.logger.Warnf("failed to write gRPC-Web response of %s: %v", r.URL.Path, err)
+			}
+		})
+	}, nil
+}
+
+func (m *Middleware) getNativeMetadata(metadata middleware.Metadata) (*Metadata, error) {
+	var middlewareMetadata Metadata
+	err := mdutils.DecodeMetadata(metadata.Properties, &middlewareMetadata)
+	if err != nil {
+		return nil, err
+	}
+	return &middlewareMetadata, nil
+}
+
+func (m *Metadata) matches(path string) bool {
+	if len(m.PathPrefixes) == 0 {
+		return true
+	}
+	for _, prefix := range m.PathPrefixes {
+		if strings.HasPrefix(path, strings.TrimSpace(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// responseWriter writes the gRPC response of the backend as a gRPC-Web response, with the trailers in the body.
+type responseWriter struct {
+	w           http.ResponseWriter
+	header      http.Header
+	contentType string
+	text        bool
+	// Headers sent before the body, the others are trailers
+	sentHeaders map[string]struct{}
+	wroteHeader bool
+}
+
+func newResponseWriter(w http.ResponseWriter, contentType string, text bool) *responseWriter {
+	return &responseWriter{
+		w:           w,
+		header:      http.Header{},
+		contentType: contentType,
+		text:        text,
+	}
+}
+
+func (rw *responseWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *responseWriter) WriteHeader(statusCode int) {
+	if rw.wroteHeader {
+		return
+	}
+	rw.wroteHeader = true
+
+	rw.sentHeaders = make(map[string]struct{}, len(rw.header))
+	announced := map[string]struct{}{}
+	for _, v := range rw.header.Values("Trailer") {
+		for _, k := range strings.Split(v, ",") {
+			announced[http.CanonicalHeaderKey(strings.TrimSpace(k))] = struct{}{}
+		}
+	}
+
+	dst := rw.w.Header()
+	for k, v := range rw.header {
+		if _, ok := announced[k]; ok || k == "Trailer" || strings.HasPrefix(k, http.TrailerPrefix) {
+			continue
+		}
+		rw.sentHeaders[k] = struct{}{}
+		dst[k] = v
+	}
+	dst.Set("Content-Type", rw.contentType)
+	dst.Del("Content-Length")
+	rw.w.WriteHeader(statusCode)
+}
+
+// Write writes the bytes to the body. In the text mode, they are written as a padded base64 chunk, so that the
+// messages of a stream flushed by the backend reach the client without waiting for the following bytes.
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.WriteHeader(http.StatusOK)
+	if !rw.text {
+		return rw.w.Write(b)
+	}
+	if len(b) == 0 {
+		return 0, nil
+	}
+	_, err := io.WriteString(rw.w, base64.StdEncoding.EncodeToString(b))
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// finish writes the trailers of the response in the last frame of the body.
+func (rw *responseWriter) finish() error {
+	rw.WriteHeader(http.StatusOK)
+
+	trailers := http.Header{}
+	for k, v := range rw.header {
+		switch {
+		case strings.HasPrefix(k, http.TrailerPrefix):
+			trailers[http.CanonicalHeaderKey(strings.TrimPrefix(k, http.TrailerPrefix))] = v
+		case k == "Trailer":
+		default:
+			if _, ok := rw.sentHeaders[k]; !ok {
+				trailers[k] = v
+			}
+		}
+	}
+
+	_, err := rw.Write(trailersFrame(trailers))
+	if err != nil {
+		return err
+	}
+	rw.Flush()
+	return nil
+}
+
+// trailersFrame returns the frame of the trailers of a gRPC-Web response: the frame flag, the length of the trailers
+// and the trailers in the format of HTTP/1 headers with lower-case keys.
+func trailersFrame(trailers http.Header) []byte {
+	keys := make([]string, 0, len(trailers))
+	for k := range trailers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, k := range keys {
+		for _, v := range trailers[k] {
+			buf.WriteString(strings.ToLower(k))
+			buf.WriteString(": ")
+			buf.WriteString(v)
+			buf.WriteString("\r\n")
+		}
+	}
+
+	frame := make([]byte, 5, 5+buf.Len())
+	frame[0] = trailersFrameFlag
+	binary.BigEndian.PutUint32(frame[1:], uint32(buf.Len()))
+	return append(frame, buf.Bytes()...)
+}
+
+// base64Reader decodes the body of a gRPC-Web text request, made of base64 chunks which may each be padded: the
+// quanta of 4 characters are decoded one by one, rather than the body as a single base64 string.
+type base64Reader struct {
+	r io.Reader
+	// Characters of the quantum being read, and bytes decoded not read yet
+	quantum [4]byte
+	n       int
+	decoded []byte
+	err     error
+}
+
+func (b *base64Reader) Read(p []byte) (int, error) {
+	for len(b.decoded) == 0 {
+		if b.err != nil {
+			return 0, b.err
+		}
+
+		var buf [512]byte
+		n, err := b.r.Read(buf[:])
+		for _, c := range buf[:n] {
+			b.quantum[b.n] = c
+			b.n++
+			if b.n < len(b.quantum) {
+				continue
+			}
+			var dst [3]byte
+			m, decodeErr := base64.StdEncoding.Decode(dst[:], b.quantum[:])
+			if decodeErr != nil {
+				b.err = decodeErr
+				break
+			}
+			b.decoded = append(b.decoded, dst[:m]...)
+			b.n = 0
+		}
+		if err != nil && b.err == nil {
+			if errors.Is(err, io.EOF) && b.n != 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			b.err = err
+		}
+	}
+
+	n := copy(p, b.decoded)
+	b.decoded = b.decoded[n:]
+	return n, nil
+}
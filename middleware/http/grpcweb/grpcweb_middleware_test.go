@@ -0,0 +1,145 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/middleware"
+	"github.com/dapr/kit/logger"
+)
+
+// message is a gRPC message frame.
+var message = []byte{0, 0, 0, 0, 3, 'a', 'b', 'c'}
+
+// mockedGRPCHandler acts like a gRPC backend echoing the message of the request.
+func mockedGRPCHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/grpc+proto", r.Header.Get("Content-Type"))
+		assert.Equal(t, "trailers", r.Header.Get("Te"))
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, message, body)
+
+		w.Header().Set("Content-Type", "application/grpc+proto")
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Header().Set("X-Request-Id", "1")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+		w.Header().Set("Grpc-Status", "0")
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", "ok")
+	}
+}
+
+func newHandler(t *testing.T, properties map[string]string) http.Handler {
+	t.Helper()
+
+	handler, err := NewMiddleware(logger.NewLogger("grpcweb.test")).GetHandler(middleware.Metadata{Base: metadata.Base{Properties: properties}})
+	require.NoError(t, err)
+
+	return handler(mockedGRPCHandler(t))
+}
+
+func TestGRPCWeb(t *testing.T) {
+	trailers := append([]byte{0x80, 0, 0, 0, 34}, []byte("grpc-message: ok\r\ngrpc-status: 0\r\n")...)
+
+	t.Run("binary requests", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "http://localhost:3500/echo.Echo/Echo", bytes.NewReader(message))
+		r.Header.Set("Content-Type", "application/grpc-web+proto")
+		w := httptest.NewRecorder()
+
+		newHandler(t, nil).ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/grpc-web+proto", w.Header().Get("Content-Type"))
+		assert.Equal(t, "1", w.Header().Get("X-Request-Id"))
+		assert.Empty(t, w.Header().Get("Grpc-Status"))
+		assert.Equal(t, append(append([]byte{}, message...), trailers...), w.Body.Bytes())
+	})
+
+	t.Run("text requests", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "http://localhost:3500/echo.Echo/Echo", strings.NewReader(base64.StdEncoding.EncodeToString(message)))
+		r.Header.Set("Content-Type", "application/grpc-web-text+proto")
+		w := httptest.NewRecorder()
+
+		newHandler(t, nil).ServeHTTP(w, r)
+
+		assert.Equal(t, "application/grpc-web-text+proto", w.Header().Get("Content-Type"))
+		assert.Equal(t, base64.StdEncoding.EncodeToString(message)+base64.StdEncoding.EncodeToString(trailers), w.Body.String())
+	})
+
+	t.Run("padded text chunks", func(t *testing.T) {
+		// The message is sent in two chunks, the first one padded
+		body := base64.StdEncoding.EncodeToString(message[:4]) + base64.StdEncoding.EncodeToString(message[4:])
+		r := httptest.NewRequest(http.MethodPost, "http://localhost:3500/echo.Echo/Echo", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/grpc-web-text+proto")
+		w := httptest.NewRecorder()
+
+		newHandler(t, nil).ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, base64.StdEncoding.EncodeToString(message)+base64.StdEncoding.EncodeToString(trailers), w.Body.String())
+	})
+
+	t.Run("other requests are passed as they are", func(t *testing.T) {
+		handler, err := NewMiddleware(logger.NewLogger("grpcweb.test")).GetHandler(middleware.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"pathPrefixes": "/echo.Echo/",
+		}}})
+		require.NoError(t, err)
+		next := handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Header.Get("Content-Type")))
+		}))
+
+		for _, contentType := range []string{"application/json", "application/grpc-web+proto"} {
+			r := httptest.NewRequest(http.MethodPost, "http://localhost:3500/other.Other/Call", nil)
+			r.Header.Set("Content-Type", contentType)
+			w := httptest.NewRecorder()
+
+			next.ServeHTTP(w, r)
+
+			assert.Equal(t, contentType, w.Body.String())
+		}
+	})
+}
+
+func TestBase64Reader(t *testing.T) {
+	t.Run("padded chunks", func(t *testing.T) {
+		decoded, err := io.ReadAll(&base64Reader{r: strings.NewReader("YQ==YmM=")})
+
+		require.NoError(t, err)
+		assert.Equal(t, "abc", string(decoded))
+	})
+
+	t.Run("truncated quantum", func(t *testing.T) {
+		_, err := io.ReadAll(&base64Reader{r: strings.NewReader("YWJj" + "YQ")})
+
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+
+	t.Run("invalid characters", func(t *testing.T) {
+		_, err := io.ReadAll(&base64Reader{r: strings.NewReader("YW!j")})
+
+		assert.ErrorContains(t, err, "illegal base64 data")
+	})
+}
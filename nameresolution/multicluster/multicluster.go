@@ -0,0 +1,435 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package multicluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/dapr/components-contrib/nameresolution"
+	"github.com/dapr/components-contrib/nameresolution/consul"
+	"github.com/dapr/components-contrib/nameresolution/dns"
+	"github.com/dapr/components-contrib/nameresolution/kubernetes"
+	"github.com/dapr/components-contrib/nameresolution/nomad"
+	"github.com/dapr/components-contrib/nameresolution/systemd"
+	"github.com/dapr/kit/config"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	defaultHealthCheckInterval = 10 * time.Second
+	defaultTimeout             = 2 * time.Second
+	defaultMaxHealthChecks     = 1000
+
+	// Health checks run concurrently in each round.
+	healthCheckConcurrency = 16
+	// Addresses which aren't resolved for this many health check intervals are no longer checked.
+	idleHealthCheckIntervals = 10
+)
+
+// Placeholders of the templates of the addresses and SRV records of the clusters.
+var placeholders = []string{"{appID}", "{namespace}", "{port}"}
+
+// clusterConfig is the configuration of a cluster. The apps of the cluster are resolved with the SRV records of the
+// srv template, with the address template, or with another name resolver.
+type clusterConfig struct {
+	Name string `json:"name"`
+	// Clusters with a lower priority are preferred, clusters with the same priority are tried in order.
+	Priority int `json:"priority"`
+	// Name of the SRV records of an app, such as _dapr._tcp.{appID}.{namespace}.east.example.com.
+	SRV string `json:"srv"`
+	// Address of an app, such as {appID}-dapr.{namespace}.svc.east.example.com:{port}.
+	Address string `json:"address"`
+	// Name resolver of the cluster, such as kubernetes for the local cluster, and its configuration.
+	Resolver      string      `json:"resolver"`
+	Configuration interface{} `json:"configuration"`
+}
+
+type resolverConfig struct {
+	Clusters []clusterConfig `json:"clusters"`
+	// Interval of the health checks of the addresses, as a Go duration.
+	HealthCheckInterval string `json:"healthCheckInterval"`
+	// Timeout of the SRV lookups and of the health checks, as a Go duration.
+	Timeout string `json:"timeout"`
+	// Maximum number of addresses whose health is checked; the least recently resolved addresses are dropped first.
+	MaxHealthChecks int `json:"maxHealthChecks"`
+	// Disables the health checks, the first cluster resolving an app is always used.
+	DisableHealthChecks bool `json:"disableHealthChecks"`
+}
+
+// resolverFactory creates a name resolver of the clusters.
+type resolverFactory func(logger.Logger) nameresolution.Resolver
+
+// cluster is a cluster of the configuration, with its name resolver if any.
+type cluster struct {
+	clusterConfig
+
+	resolver nameresolution.Resolver
+}
+
+type healthCheck struct {
+	healthy    bool
+	resolvedAt time.Time
+}
+
+type resolver struct {
+	logger logger.Logger
+
+	clusters            []cluster
+	healthCheckInterval time.Duration
+	timeout             time.Duration
+	maxHealthChecks     int
+	disableHealthChecks bool
+
+	// Results of the health checks of the addresses, updated in the background.
+	healthChecks     map[string]*healthCheck
+	healthChecksLock sync.Mutex
+	// Addresses resolved for the first time, checked right away.
+	newAddresses chan string
+	closeCh      chan struct{}
+	wg           sync.WaitGroup
+
+	resolverFactories map[string]resolverFactory
+	lookupSRV         func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	dial              func(network, address string, timeout time.Duration) (net.Conn, error)
+	clock             clock.Clock
+}
+
+// NewResolver creates a name resolver targeting the apps across multiple clusters, failing over from the preferred
+// clusters to the others when the apps are not found or not healthy there.
+func NewResolver(logger logger.Logger) nameresolution.Resolver {
+	return &resolver{
+		logger:       logger,
+		healthChecks: map[string]*healthCheck{},
+		newAddresses: make(chan string, 100),
+		closeCh:      make(chan struct{}),
+		resolverFactories: map[string]resolverFactory{
+			"consul":     consul.NewResolver,
+			"dns":        dns.NewResolver,
+			"kubernetes": kubernetes.NewResolver,
+			"nomad":      nomad.NewResolver,
+			"systemd":    systemd.NewResolver,
+		},
+		lookupSRV: net.DefaultResolver.LookupSRV,
+		dial:      net.DialTimeout,
+		clock:     clock.New(),
+	}
+}
+
+// Init initializes the multicluster name resolver, and the name resolvers of the clusters with the metadata.
+func (r *resolver) Init(metadata nameresolution.Metadata) error {
+	cfg, err := parseConfig(metadata.Configuration)
+	if err != nil {
+		return err
+	}
+
+	if len(cfg.Clusters) == 0 {
+		return errors.New("at least one cluster is required")
+	}
+	sort.SliceStable(cfg.Clusters, func(i, j int) bool {
+		return cfg.Clusters[i].Priority < cfg.Clusters[j].Priority
+	})
+	r.clusters = make([]cluster, len(cfg.Clusters))
+	for i, c := range cfg.Clusters {
+		if c.Name == "" {
+			c.Name = strconv.Itoa(i)
+		}
+		set := 0
+		for _, v := range []string{c.SRV, c.Address, c.Resolver} {
+			if v != "" {
+				set++
+			}
+		}
+		if set != 1 {
+			return fmt.Errorf("exactly one of srv, address and resolver is required for cluster %s", c.Name)
+		}
+		r.clusters[i] = cluster{clusterConfig: c}
+		if c.Resolver == "" {
+			continue
+		}
+
+		newResolver, ok := r.resolverFactories[c.Resolver]
+		if !ok {
+			return fmt.Errorf("unsupported resolver %s for cluster %s", c.Resolver, c.Name)
+		}
+		r.clusters[i].resolver = newResolver(r.logger)
+		err = r.clusters[i].resolver.Init(nameresolution.Metadata{Base: metadata.Base, Configuration: c.Configuration})
+		if err != nil {
+			return fmt.Errorf("error initializing the %s resolver of cluster %s: %w", c.Resolver, c.Name, err)
+		}
+	}
+
+	r.healthCheckInterval, err = parseDuration(cfg.HealthCheckInterval, defaultHealthCheckInterval)
+	if err != nil {
+		return fmt.Errorf("invalid healthCheckInterval: %w", err)
+	}
+	r.timeout, err = parseDuration(cfg.Timeout, defaultTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid timeout: %w", err)
+	}
+	r.maxHealthChecks = cfg.MaxHealthChecks
+	if r.maxHealthChecks == 0 {
+		r.maxHealthChecks = defaultMaxHealthChecks
+	} else if r.maxHealthChecks < 0 {
+		return fmt.Errorf("invalid maxHealthChecks: %d", r.maxHealthChecks)
+	}
+	r.disableHealthChecks = cfg.DisableHealthChecks
+
+	if !r.disableHealthChecks {
+		r.wg.Add(1)
+		go r.runHealthChecks()
+	}
+
+	return nil
+}
+
+// Close stops the health checks, and closes the name resolvers of the clusters.
+func (r *resolver) Close() error {
+	close(r.closeCh)
+	r.wg.Wait()
+
+	var errs []string
+	for _, c := range r.clusters {
+		if closer, ok := c.resolver.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Sprintf("cluster %s: %v", c.Name, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("error closing the resolvers: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+func parseConfig(rawConfig interface{}) (resolverConfig, error) {
+	var result resolverConfig
+	rawConfig, err := config.Normalize(rawConfig)
+	if err != nil {
+		return result, err
+	}
+
+	data, err := json.Marshal(rawConfig)
+	if err != nil {
+		return result, fmt.Errorf("error serializing to json: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&result); err != nil {
+		return result, fmt.Errorf("error deserializing to resolverConfig: %w", err)
+	}
+
+	return result, nil
+}
+
+func parseDuration(val string, defaultValue time.Duration) (time.Duration, error) {
+	if val == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s is not positive", val)
+	}
+	return d, nil
+}
+
+// ResolveID resolves the app to the first healthy address in the clusters, by priority.
+// If the app is found but not healthy in any cluster, the first address found is returned.
+func (r *resolver) ResolveID(req nameresolution.ResolveRequest) (string, error) {
+	var fallback string
+	errs := make([]string, 0, len(r.clusters))
+	for _, c := range r.clusters {
+		addresses, err := r.resolveInCluster(c, req)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("cluster %s: %v", c.Name, err))
+			continue
+		}
+
+		for _, address := range addresses {
+			if r.healthy(address) {
+				return address, nil
+			}
+			if fallback == "" {
+				fallback = address
+			}
+		}
+		r.logger.Debugf("no healthy address found for app %s in cluster %s", req.ID, c.Name)
+	}
+
+	if fallback != "" {
+		r.logger.Warnf("no healthy address found for app %s in any cluster, using %s", req.ID, fallback)
+		return fallback, nil
+	}
+
+	return "", fmt.Errorf("failed to resolve app %s in any cluster: %s", req.ID, strings.Join(errs, "; "))
+}
+
+// resolveInCluster returns the addresses of the app in the cluster, in order of preference.
+func (r *resolver) resolveInCluster(c cluster, req nameresolution.ResolveRequest) ([]string, error) {
+	if c.resolver != nil {
+		address, err := c.resolver.ResolveID(req)
+		if err != nil {
+			return nil, err
+		}
+		return []string{address}, nil
+	}
+
+	replacer := strings.NewReplacer(placeholders[0], req.ID, placeholders[1], req.Namespace, placeholders[2], strconv.Itoa(req.Port))
+	if c.Address != "" {
+		return []string{replacer.Replace(c.Address)}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	// The records are sorted by priority and randomized by weight
+	_, records, err := r.lookupSRV(ctx, "", "", replacer.Replace(c.SRV))
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("no SRV records found")
+	}
+
+	addresses := make([]string, len(records))
+	for i, srv := range records {
+		addresses[i] = net.JoinHostPort(strings.TrimSuffix(srv.Target, "."), strconv.Itoa(int(srv.Port)))
+	}
+	return addresses, nil
+}
+
+// healthy returns whether the last health check of the address succeeded. The health checks run in the background:
+// the addresses not checked yet are assumed healthy, and checked right away.
+func (r *resolver) healthy(address string) bool {
+	if r.disableHealthChecks {
+		return true
+	}
+
+	r.healthChecksLock.Lock()
+	defer r.healthChecksLock.Unlock()
+
+	check, ok := r.healthChecks[address]
+	if !ok {
+		if len(r.healthChecks) >= r.maxHealthChecks {
+			r.evictLeastRecentlyResolved()
+		}
+		check = &healthCheck{healthy: true}
+		r.healthChecks[address] = check
+		select {
+		case r.newAddresses <- address:
+		default:
+			// Checked in the next round
+		}
+	}
+	check.resolvedAt = r.clock.Now()
+
+	return check.healthy
+}
+
+// evictLeastRecentlyResolved drops the health check of the least recently resolved address.
+// It must be called with the lock held.
+func (r *resolver) evictLeastRecentlyResolved() {
+	var (
+		oldest     string
+		resolvedAt time.Time
+	)
+	for address, check := range r.healthChecks {
+		if oldest == "" || check.resolvedAt.Before(resolvedAt) {
+			oldest, resolvedAt = address, check.resolvedAt
+		}
+	}
+	delete(r.healthChecks, oldest)
+}
+
+// runHealthChecks checks the new addresses right away, and all the addresses at each health check interval, until
+// the resolver is closed.
+func (r *resolver) runHealthChecks() {
+	defer r.wg.Done()
+
+	ticker := r.clock.Ticker(r.healthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.closeCh:
+			return
+		case address := <-r.newAddresses:
+			r.check(address)
+		case <-ticker.C:
+			r.checkAll()
+		}
+	}
+}
+
+// checkAll checks the health of the addresses resolved recently, and drops the others.
+func (r *resolver) checkAll() {
+	idleSince := r.clock.Now().Add(-idleHealthCheckIntervals * r.healthCheckInterval)
+	r.healthChecksLock.Lock()
+	addresses := make([]string, 0, len(r.healthChecks))
+	for address, check := range r.healthChecks {
+		if check.resolvedAt.Before(idleSince) {
+			delete(r.healthChecks, address)
+			continue
+		}
+		addresses = append(addresses, address)
+	}
+	r.healthChecksLock.Unlock()
+
+	sem := make(chan struct{}, healthCheckConcurrency)
+	var wg sync.WaitGroup
+	for _, address := range addresses {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(address string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			r.check(address)
+		}(address)
+	}
+	wg.Wait()
+}
+
+// check opens a TCP connection to the address, and records whether it succeeded.
+func (r *resolver) check(address string) {
+	conn, err := r.dial("tcp", address, r.timeout)
+	if err == nil {
+		conn.Close()
+	} else {
+		r.logger.Debugf("health check of %s failed: %v", address, err)
+	}
+
+	r.healthChecksLock.Lock()
+	defer r.healthChecksLock.Unlock()
+	// The address may have been dropped meanwhile
+	if check, ok := r.healthChecks[address]; ok {
+		check.healthy = err == nil
+	}
+}
@@ -0,0 +1,333 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package multicluster
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/nameresolution"
+	"github.com/dapr/kit/logger"
+)
+
+// fakeNetwork serves the SRV records, and accepts the connections to the healthy addresses.
+type fakeNetwork struct {
+	records map[string][]*net.SRV
+
+	lock    sync.Mutex
+	healthy map[string]bool
+}
+
+func (n *fakeNetwork) setHealthy(address string, healthy bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.healthy[address] = healthy
+}
+
+func newTestResolver(t *testing.T, configuration map[string]interface{}, network *fakeNetwork) (*resolver, *clock.Mock) {
+	t.Helper()
+
+	if network.healthy == nil {
+		network.healthy = map[string]bool{}
+	}
+	mockClock := clock.NewMock()
+	r := NewResolver(logger.NewLogger("test")).(*resolver)
+	r.clock = mockClock
+	r.lookupSRV = func(_ context.Context, _, _, name string) (string, []*net.SRV, error) {
+		if srv, ok := network.records[name]; ok {
+			return name, srv, nil
+		}
+		return "", nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	r.dial = func(_, address string, _ time.Duration) (net.Conn, error) {
+		network.lock.Lock()
+		defer network.lock.Unlock()
+		if network.healthy[address] {
+			client, server := net.Pipe()
+			server.Close()
+			return client, nil
+		}
+		return nil, errors.New("connection refused")
+	}
+	require.NoError(t, r.Init(nameresolution.Metadata{Configuration: configuration}))
+	t.Cleanup(func() {
+		r.Close()
+	})
+
+	return r, mockClock
+}
+
+// settle resolves the app and checks the addresses resolved until all the addresses of the app are checked: the
+// addresses which aren't checked yet are assumed healthy.
+func settle(r *resolver, req nameresolution.ResolveRequest) {
+	for i := 0; i < 3; i++ {
+		r.ResolveID(req)
+		r.checkAll()
+	}
+}
+
+var clusters = map[string]interface{}{
+	"clusters": []interface{}{
+		map[string]interface{}{
+			"name":     "west",
+			"priority": 2,
+			"address":  "{appID}-dapr.{namespace}.svc.west.example.com:{port}",
+		},
+		map[string]interface{}{
+			"name":     "east",
+			"priority": 1,
+			"srv":      "_dapr._tcp.{appID}.{namespace}.east.example.com",
+		},
+	},
+}
+
+func TestInit(t *testing.T) {
+	t.Run("sorts the clusters by priority", func(t *testing.T) {
+		r, _ := newTestResolver(t, clusters, &fakeNetwork{})
+
+		require.Len(t, r.clusters, 2)
+		assert.Equal(t, "east", r.clusters[0].Name)
+		assert.Equal(t, "west", r.clusters[1].Name)
+		assert.Equal(t, defaultHealthCheckInterval, r.healthCheckInterval)
+		assert.Equal(t, defaultTimeout, r.timeout)
+		assert.Equal(t, defaultMaxHealthChecks, r.maxHealthChecks)
+	})
+
+	t.Run("invalid configurations", func(t *testing.T) {
+		tests := map[string]struct {
+			configuration map[string]interface{}
+			err           string
+		}{
+			"no clusters": {map[string]interface{}{}, "at least one cluster is required"},
+			"srv and address": {
+				map[string]interface{}{"clusters": []interface{}{map[string]interface{}{"srv": "a", "address": "b"}}},
+				"exactly one of srv, address and resolver is required for cluster 0",
+			},
+			"unsupported resolver": {
+				map[string]interface{}{"clusters": []interface{}{map[string]interface{}{"name": "local", "resolver": "mdns"}}},
+				"unsupported resolver mdns for cluster local",
+			},
+			"unknown field": {map[string]interface{}{"cluster": []interface{}{}}, "unknown field"},
+			"invalid interval": {
+				map[string]interface{}{"clusters": []interface{}{map[string]interface{}{"address": "b"}}, "healthCheckInterval": "-1s"},
+				"invalid healthCheckInterval",
+			},
+			"invalid maxHealthChecks": {
+				map[string]interface{}{"clusters": []interface{}{map[string]interface{}{"address": "b"}}, "maxHealthChecks": -1},
+				"invalid maxHealthChecks: -1",
+			},
+		}
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				err := NewResolver(logger.NewLogger("test")).Init(nameresolution.Metadata{Configuration: tt.configuration})
+
+				assert.ErrorContains(t, err, tt.err)
+			})
+		}
+	})
+}
+
+func TestResolveID(t *testing.T) {
+	request := nameresolution.ResolveRequest{ID: "orders", Namespace: "shop", Port: 50002}
+	records := map[string][]*net.SRV{
+		"_dapr._tcp.orders.shop.east.example.com": {
+			{Target: "10.0.0.1.", Port: 50002, Priority: 1},
+			{Target: "10.0.0.2.", Port: 50002, Priority: 2},
+		},
+	}
+
+	t.Run("addresses not checked yet are assumed healthy", func(t *testing.T) {
+		r, _ := newTestResolver(t, clusters, &fakeNetwork{records: records})
+
+		address, err := r.ResolveID(request)
+
+		require.NoError(t, err)
+		assert.Equal(t, "10.0.0.1:50002", address)
+	})
+
+	t.Run("first healthy SRV record of the preferred cluster", func(t *testing.T) {
+		r, _ := newTestResolver(t, clusters, &fakeNetwork{records: records, healthy: map[string]bool{"10.0.0.2:50002": true}})
+		settle(r, request)
+
+		address, err := r.ResolveID(request)
+
+		require.NoError(t, err)
+		assert.Equal(t, "10.0.0.2:50002", address)
+	})
+
+	t.Run("fails over to the next cluster", func(t *testing.T) {
+		r, _ := newTestResolver(t, clusters, &fakeNetwork{records: records, healthy: map[string]bool{"orders-dapr.shop.svc.west.example.com:50002": true}})
+		settle(r, request)
+
+		address, err := r.ResolveID(request)
+
+		require.NoError(t, err)
+		assert.Equal(t, "orders-dapr.shop.svc.west.example.com:50002", address)
+	})
+
+	t.Run("app not found in the preferred cluster", func(t *testing.T) {
+		r, _ := newTestResolver(t, clusters, &fakeNetwork{})
+
+		address, err := r.ResolveID(request)
+
+		require.NoError(t, err)
+		assert.Equal(t, "orders-dapr.shop.svc.west.example.com:50002", address)
+	})
+
+	t.Run("first address when none is healthy", func(t *testing.T) {
+		r, _ := newTestResolver(t, clusters, &fakeNetwork{records: records})
+		settle(r, request)
+
+		address, err := r.ResolveID(request)
+
+		require.NoError(t, err)
+		assert.Equal(t, "10.0.0.1:50002", address)
+	})
+
+	t.Run("app not found in any cluster", func(t *testing.T) {
+		r, _ := newTestResolver(t, map[string]interface{}{
+			"clusters": []interface{}{map[string]interface{}{"name": "east", "srv": "_dapr._tcp.{appID}.east.example.com"}},
+		}, &fakeNetwork{})
+
+		_, err := r.ResolveID(request)
+
+		assert.ErrorContains(t, err, "failed to resolve app orders in any cluster: cluster east:")
+	})
+
+	t.Run("health checks disabled", func(t *testing.T) {
+		r, _ := newTestResolver(t, map[string]interface{}{
+			"clusters":            clusters["clusters"],
+			"disableHealthChecks": true,
+		}, &fakeNetwork{records: records})
+
+		address, err := r.ResolveID(request)
+
+		require.NoError(t, err)
+		assert.Equal(t, "10.0.0.1:50002", address)
+		assert.Empty(t, r.healthChecks)
+	})
+}
+
+func TestHealthChecks(t *testing.T) {
+	request := nameresolution.ResolveRequest{ID: "orders", Namespace: "shop", Port: 50002}
+	single := map[string]interface{}{
+		"clusters":        []interface{}{map[string]interface{}{"address": "{appID}:{port}"}},
+		"maxHealthChecks": 2,
+	}
+	healthy := func(r *resolver, address string) func() bool {
+		return func() bool {
+			r.healthChecksLock.Lock()
+			defer r.healthChecksLock.Unlock()
+			check, ok := r.healthChecks[address]
+			return ok && check.healthy
+		}
+	}
+
+	t.Run("run in the background at each interval", func(t *testing.T) {
+		network := &fakeNetwork{healthy: map[string]bool{"orders:50002": true}}
+		r, mockClock := newTestResolver(t, single, network)
+
+		r.ResolveID(request)
+		require.Eventually(t, healthy(r, "orders:50002"), time.Second, 10*time.Millisecond)
+
+		network.setHealthy("orders:50002", false)
+		mockClock.Add(defaultHealthCheckInterval)
+		assert.Eventually(t, func() bool { return !healthy(r, "orders:50002")() }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("keep the most recently resolved addresses", func(t *testing.T) {
+		r, mockClock := newTestResolver(t, single, &fakeNetwork{})
+
+		for _, id := range []string{"orders", "payments", "orders", "shipping"} {
+			r.ResolveID(nameresolution.ResolveRequest{ID: id, Port: 50002})
+			mockClock.Add(time.Millisecond)
+		}
+
+		r.healthChecksLock.Lock()
+		defer r.healthChecksLock.Unlock()
+		assert.Len(t, r.healthChecks, 2)
+		assert.Contains(t, r.healthChecks, "orders:50002")
+		assert.Contains(t, r.healthChecks, "shipping:50002")
+	})
+
+	t.Run("drop the idle addresses", func(t *testing.T) {
+		r, mockClock := newTestResolver(t, single, &fakeNetwork{})
+		r.ResolveID(request)
+
+		mockClock.Add(idleHealthCheckIntervals*defaultHealthCheckInterval + time.Second)
+		r.checkAll()
+
+		r.healthChecksLock.Lock()
+		defer r.healthChecksLock.Unlock()
+		assert.Empty(t, r.healthChecks)
+	})
+}
+
+// fakeResolver resolves the apps to the address of its configuration.
+type fakeResolver struct {
+	address string
+	closed  bool
+}
+
+func (f *fakeResolver) Init(metadata nameresolution.Metadata) error {
+	f.address = metadata.Configuration.(map[string]interface{})["address"].(string)
+	return nil
+}
+
+func (f *fakeResolver) ResolveID(req nameresolution.ResolveRequest) (string, error) {
+	if req.ID != "orders" {
+		return "", errors.New("app not found")
+	}
+	return f.address, nil
+}
+
+func (f *fakeResolver) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestResolverChain(t *testing.T) {
+	local := &fakeResolver{}
+	r := NewResolver(logger.NewLogger("test")).(*resolver)
+	r.resolverFactories = map[string]resolverFactory{
+		"fake": func(logger.Logger) nameresolution.Resolver { return local },
+	}
+	require.NoError(t, r.Init(nameresolution.Metadata{Configuration: map[string]interface{}{
+		"clusters": []interface{}{
+			map[string]interface{}{"name": "local", "resolver": "fake", "configuration": map[string]interface{}{"address": "10.1.0.1:50002"}},
+			map[string]interface{}{"name": "west", "priority": 1, "address": "{appID}.west.example.com:{port}"},
+		},
+		"disableHealthChecks": true,
+	}}))
+
+	address, err := r.ResolveID(nameresolution.ResolveRequest{ID: "orders", Port: 50002})
+	require.NoError(t, err)
+	assert.Equal(t, "10.1.0.1:50002", address)
+
+	// Not resolved by the resolver of the local cluster
+	address, err = r.ResolveID(nameresolution.ResolveRequest{ID: "payments", Port: 50002})
+	require.NoError(t, err)
+	assert.Equal(t, "payments.west.example.com:50002", address)
+
+	require.NoError(t, r.Close())
+	assert.True(t, local.closed)
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nomad
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dapr/components-contrib/nameresolution"
+	"github.com/dapr/kit/config"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	defaultAddress     = "http://127.0.0.1:4646"
+	defaultServiceName = "{appID}-dapr"
+	defaultTimeout     = 5 * time.Second
+
+	// Environment variables of the Nomad CLI, used by default.
+	envAddress   = "NOMAD_ADDR"
+	envToken     = "NOMAD_TOKEN"
+	envNamespace = "NOMAD_NAMESPACE"
+)
+
+// resolverConfig is the configuration of the resolver. The Dapr sidecars are registered in the Nomad service catalog by
+// the jobs, with service blocks using the nomad provider and the internal gRPC port of the sidecars.
+type resolverConfig struct {
+	// Address of the Nomad HTTP API; NOMAD_ADDR or the local agent by default.
+	Address string `json:"address"`
+	// ACL token reading the services; NOMAD_TOKEN by default.
+	Token string `json:"token"`
+	// Nomad namespace of the services; NOMAD_NAMESPACE by default, or else the Dapr namespace of the request.
+	Namespace string `json:"namespace"`
+	// Name of the service of the sidecar of an app, with the {appID} placeholder.
+	ServiceName string `json:"serviceName"`
+	// Tags the services must all have.
+	Tags []string `json:"tags"`
+	// Timeout of the requests to the API, as a Go duration.
+	Timeout string `json:"timeout"`
+}
+
+// serviceRegistration is a service instance of the catalog.
+type serviceRegistration struct {
+	ServiceName string   `json:"ServiceName"`
+	Address     string   `json:"Address"`
+	Port        int      `json:"Port"`
+	Tags        []string `json:"Tags"`
+}
+
+type resolver struct {
+	logger logger.Logger
+
+	address     string
+	token       string
+	namespace   string
+	serviceName string
+	tags        []string
+	httpClient  *http.Client
+}
+
+// NewResolver creates a name resolver looking up the apps in the service catalog of Nomad.
+func NewResolver(logger logger.Logger) nameresolution.Resolver {
+	return &resolver{logger: logger}
+}
+
+// Init initializes the Nomad name resolver.
+func (r *resolver) Init(metadata nameresolution.Metadata) error {
+	cfg, err := parseConfig(metadata.Configuration)
+	if err != nil {
+		return err
+	}
+
+	r.address = strings.TrimSuffix(firstNonEmpty(cfg.Address, os.Getenv(envAddress), defaultAddress), "/")
+	if u, err := url.Parse(r.address); err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid address %s", r.address)
+	}
+	r.token = firstNonEmpty(cfg.Token, os.Getenv(envToken))
+	r.namespace = firstNonEmpty(cfg.Namespace, os.Getenv(envNamespace))
+	r.serviceName = firstNonEmpty(cfg.ServiceName, defaultServiceName)
+	r.tags = cfg.Tags
+
+	timeout := defaultTimeout
+	if cfg.Timeout != "" {
+		timeout, err = time.ParseDuration(cfg.Timeout)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("invalid timeout %s", cfg.Timeout)
+		}
+	}
+	r.httpClient = &http.Client{Timeout: timeout}
+
+	return nil
+}
+
+func parseConfig(rawConfig interface{}) (resolverConfig, error) {
+	var result resolverConfig
+	if rawConfig == nil {
+		return result, nil
+	}
+	rawConfig, err := config.Normalize(rawConfig)
+	if err != nil {
+		return result, err
+	}
+
+	data, err := json.Marshal(rawConfig)
+	if err != nil {
+		return result, fmt.Errorf("error serializing to json: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&result); err != nil {
+		return result, fmt.Errorf("error deserializing to resolverConfig: %w", err)
+	}
+
+	return result, nil
+}
+
+// ResolveID resolves the app to the address of a random instance of its service with the tags.
+func (r *resolver) ResolveID(req nameresolution.ResolveRequest) (string, error) {
+	service := strings.ReplaceAll(r.serviceName, "{appID}", req.ID)
+	namespace := firstNonEmpty(r.namespace, req.Namespace)
+
+	instances, err := r.services(service, namespace)
+	if err != nil {
+		return "", fmt.Errorf("failed to query the services of app %s: %w", req.ID, err)
+	}
+
+	candidates := make([]serviceRegistration, 0, len(instances))
+	for _, s := range instances {
+		if hasTags(s.Tags, r.tags) {
+			candidates = append(candidates, s)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no service %s found in namespace %s for app %s", service, namespace, req.ID)
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(candidates))))
+	if err != nil {
+		return "", err
+	}
+	s := candidates[n.Int64()]
+
+	return net.JoinHostPort(s.Address, strconv.Itoa(s.Port)), nil
+}
+
+// services returns the instances of the service in the namespace.
+func (r *resolver) services(service string, namespace string) ([]serviceRegistration, error) {
+	q := url.Values{}
+	if namespace != "" {
+		q.Set("namespace", namespace)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.httpClient.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.address+"/v1/service/"+url.PathEscape(service)+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if r.token != "" {
+		req.Header.Set("X-Nomad-Token", r.token)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var services []serviceRegistration
+	if err := json.Unmarshal(body, &services); err != nil {
+		return nil, errors.New("invalid response of the service catalog")
+	}
+
+	return services, nil
+}
+
+// hasTags returns whether all the tags required are in the tags.
+func hasTags(tags []string, required []string) bool {
+	for _, req := range required {
+		found := false
+		for _, t := range tags {
+			if t == req {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
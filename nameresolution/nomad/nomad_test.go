@@ -0,0 +1,154 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nomad
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/nameresolution"
+	"github.com/dapr/kit/logger"
+)
+
+func TestInit(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		t.Setenv(envAddress, "")
+		t.Setenv(envToken, "")
+		t.Setenv(envNamespace, "")
+		r := NewResolver(logger.NewLogger("test")).(*resolver)
+
+		require.NoError(t, r.Init(nameresolution.Metadata{}))
+		assert.Equal(t, defaultAddress, r.address)
+		assert.Equal(t, defaultServiceName, r.serviceName)
+		assert.Equal(t, defaultTimeout, r.httpClient.Timeout)
+	})
+
+	t.Run("environment of the Nomad CLI", func(t *testing.T) {
+		t.Setenv(envAddress, "https://nomad.example.com:4646/")
+		t.Setenv(envToken, "secret")
+		t.Setenv(envNamespace, "prod")
+		r := NewResolver(logger.NewLogger("test")).(*resolver)
+
+		require.NoError(t, r.Init(nameresolution.Metadata{}))
+		assert.Equal(t, "https://nomad.example.com:4646", r.address)
+		assert.Equal(t, "secret", r.token)
+		assert.Equal(t, "prod", r.namespace)
+	})
+
+	t.Run("invalid configurations", func(t *testing.T) {
+		tests := map[string]map[string]interface{}{
+			"invalid address": {"address": "nomad:4646"},
+			"invalid timeout": {"timeout": "soon"},
+			"unknown field":   {"datacenter": "dc1"},
+		}
+		for name, cfg := range tests {
+			t.Run(name, func(t *testing.T) {
+				r := NewResolver(logger.NewLogger("test"))
+				assert.Error(t, r.Init(nameresolution.Metadata{Configuration: cfg}))
+			})
+		}
+	})
+}
+
+func TestResolveID(t *testing.T) {
+	var lastRequest *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastRequest = r
+		switch r.URL.Path {
+		case "/v1/service/orders-dapr":
+			w.Write([]byte(`[
+				{"ServiceName": "orders-dapr", "Address": "10.0.0.1", "Port": 50001, "Tags": ["dapr"]},
+				{"ServiceName": "orders-dapr", "Address": "10.0.0.2", "Port": 50002, "Tags": ["dapr", "canary"]}
+			]`))
+		case "/v1/service/payments-dapr":
+			w.Write([]byte(`[{"ServiceName": "payments-dapr", "Address": "fd00::1", "Port": 50001, "Tags": ["dapr"]}]`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer server.Close()
+
+	newResolver := func(t *testing.T, cfg map[string]interface{}) nameresolution.Resolver {
+		t.Setenv(envNamespace, "")
+		cfg["address"] = server.URL
+		r := NewResolver(logger.NewLogger("test"))
+		require.NoError(t, r.Init(nameresolution.Metadata{Configuration: cfg}))
+		return r
+	}
+
+	t.Run("random instance", func(t *testing.T) {
+		r := newResolver(t, map[string]interface{}{"token": "secret", "timeout": "1s"})
+
+		seen := map[string]bool{}
+		for i := 0; i < 50; i++ {
+			address, err := r.ResolveID(nameresolution.ResolveRequest{ID: "orders", Namespace: "default", Port: 50001})
+			require.NoError(t, err)
+			seen[address] = true
+		}
+		assert.Equal(t, map[string]bool{"10.0.0.1:50001": true, "10.0.0.2:50002": true}, seen)
+		assert.Equal(t, "secret", lastRequest.Header.Get("X-Nomad-Token"))
+		assert.Equal(t, "default", lastRequest.URL.Query().Get("namespace"))
+	})
+
+	t.Run("instances with the tags", func(t *testing.T) {
+		r := newResolver(t, map[string]interface{}{"tags": []interface{}{"dapr", "canary"}, "namespace": "prod"})
+
+		address, err := r.ResolveID(nameresolution.ResolveRequest{ID: "orders", Namespace: "default"})
+
+		require.NoError(t, err)
+		assert.Equal(t, "10.0.0.2:50002", address)
+		assert.Equal(t, "prod", lastRequest.URL.Query().Get("namespace"))
+	})
+
+	t.Run("IPv6 address", func(t *testing.T) {
+		r := newResolver(t, map[string]interface{}{})
+
+		address, err := r.ResolveID(nameresolution.ResolveRequest{ID: "payments"})
+
+		require.NoError(t, err)
+		assert.Equal(t, "[fd00::1]:50001", address)
+	})
+
+	t.Run("custom service name", func(t *testing.T) {
+		r := newResolver(t, map[string]interface{}{"serviceName": "dapr-{appID}"})
+
+		_, err := r.ResolveID(nameresolution.ResolveRequest{ID: "orders"})
+
+		assert.ErrorContains(t, err, "no service dapr-orders found")
+		assert.Equal(t, "/v1/service/dapr-orders", lastRequest.URL.Path)
+	})
+}
+
+func TestResolveIDErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("Permission denied"))
+	}))
+	defer server.Close()
+
+	r := NewResolver(logger.NewLogger("test"))
+	require.NoError(t, r.Init(nameresolution.Metadata{Configuration: map[string]interface{}{
+		"address": server.URL,
+		"timeout": (100 * time.Millisecond).String(),
+	}}))
+
+	_, err := r.ResolveID(nameresolution.ResolveRequest{ID: "orders"})
+
+	assert.ErrorContains(t, err, "Permission denied")
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package systemd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dapr/components-contrib/nameresolution"
+	"github.com/dapr/kit/config"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// Address of the stub resolver of systemd-resolved.
+	defaultResolverAddress = "127.0.0.53:53"
+	defaultHost            = "{appID}"
+	defaultTimeout         = 2 * time.Second
+)
+
+// resolverConfig is the configuration of the resolver.
+type resolverConfig struct {
+	// Address of the DNS server receiving the lookups, by default the stub resolver of systemd-resolved.
+	ResolverAddress string `json:"resolverAddress"`
+	// Host name of an app, with the {appID} and {namespace} placeholders. The single-label names are resolved by
+	// systemd-resolved with LLMNR, and the names in the .local domain with mDNS.
+	Host string `json:"host"`
+	// Timeout of the lookups, as a Go duration.
+	Timeout string `json:"timeout"`
+	// Prefer the IPv6 addresses of the apps, if any.
+	PreferIPv6 bool `json:"preferIPv6"`
+}
+
+type resolver struct {
+	logger logger.Logger
+
+	host       string
+	timeout    time.Duration
+	preferIPv6 bool
+
+	lookupIPAddr func(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// NewResolver creates a name resolver looking up the host names of the apps with systemd-resolved, resolving the names
+// of the hosts of the local network with LLMNR or mDNS.
+func NewResolver(logger logger.Logger) nameresolution.Resolver {
+	return &resolver{logger: logger}
+}
+
+// Init initializes the systemd-resolved name resolver.
+func (r *resolver) Init(metadata nameresolution.Metadata) error {
+	cfg, err := parseConfig(metadata.Configuration)
+	if err != nil {
+		return err
+	}
+
+	address := defaultResolverAddress
+	if cfg.ResolverAddress != "" {
+		address = cfg.ResolverAddress
+	}
+	if _, _, err = net.SplitHostPort(address); err != nil {
+		return fmt.Errorf("invalid resolverAddress %s: %w", address, err)
+	}
+	r.host = defaultHost
+	if cfg.Host != "" {
+		r.host = cfg.Host
+	}
+	r.timeout = defaultTimeout
+	if cfg.Timeout != "" {
+		r.timeout, err = time.ParseDuration(cfg.Timeout)
+		if err != nil || r.timeout <= 0 {
+			return fmt.Errorf("invalid timeout %s", cfg.Timeout)
+		}
+	}
+	r.preferIPv6 = cfg.PreferIPv6
+
+	netResolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, address)
+		},
+	}
+	r.lookupIPAddr = netResolver.LookupIPAddr
+
+	return nil
+}
+
+func parseConfig(rawConfig interface{}) (resolverConfig, error) {
+	var result resolverConfig
+	if rawConfig == nil {
+		return result, nil
+	}
+	rawConfig, err := config.Normalize(rawConfig)
+	if err != nil {
+		return result, err
+	}
+
+	data, err := json.Marshal(rawConfig)
+	if err != nil {
+		return result, fmt.Errorf("error serializing to json: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&result); err != nil {
+		return result, fmt.Errorf("error deserializing to resolverConfig: %w", err)
+	}
+
+	return result, nil
+}
+
+// ResolveID resolves the host name of the app, returning its address with the port of the request.
+func (r *resolver) ResolveID(req nameresolution.ResolveRequest) (string, error) {
+	host := strings.NewReplacer("{appID}", req.ID, "{namespace}", req.Namespace).Replace(r.host)
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	addrs, err := r.lookupIPAddr(ctx, host)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s for app %s: %w", host, req.ID, err)
+	}
+	if len(addrs) == 0 {
+		return "", errors.New("no address found for " + host)
+	}
+
+	addr := addrs[0]
+	for _, a := range addrs {
+		if (a.IP.To4() == nil) == r.preferIPv6 {
+			addr = a
+			break
+		}
+	}
+	ip := addr.IP.String()
+	if addr.Zone != "" {
+		// Link-local addresses, as returned by LLMNR and mDNS, need their interface
+		ip += "%" + addr.Zone
+	}
+
+	return net.JoinHostPort(ip, strconv.Itoa(req.Port)), nil
+}
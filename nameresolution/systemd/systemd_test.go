@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package systemd
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/nameresolution"
+	"github.com/dapr/kit/logger"
+)
+
+func newTestResolver(t *testing.T, cfg map[string]interface{}, hosts map[string][]net.IPAddr) *resolver {
+	t.Helper()
+
+	r := NewResolver(logger.NewLogger("test")).(*resolver)
+	require.NoError(t, r.Init(nameresolution.Metadata{Configuration: cfg}))
+	r.lookupIPAddr = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		if addrs, ok := hosts[host]; ok {
+			return addrs, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	return r
+}
+
+func TestInit(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		r := newTestResolver(t, nil, nil)
+
+		assert.Equal(t, defaultHost, r.host)
+		assert.Equal(t, defaultTimeout, r.timeout)
+	})
+
+	t.Run("invalid configurations", func(t *testing.T) {
+		tests := map[string]map[string]interface{}{
+			"resolver address without port": {"resolverAddress": "127.0.0.53"},
+			"invalid timeout":               {"timeout": "-1s"},
+			"unknown field":                 {"domain": "local"},
+		}
+		for name, cfg := range tests {
+			t.Run(name, func(t *testing.T) {
+				r := NewResolver(logger.NewLogger("test"))
+				assert.Error(t, r.Init(nameresolution.Metadata{Configuration: cfg}))
+			})
+		}
+	})
+}
+
+func TestResolveID(t *testing.T) {
+	hosts := map[string][]net.IPAddr{
+		"orders": {
+			{IP: net.ParseIP("fe80::1"), Zone: "eth0"},
+			{IP: net.ParseIP("192.168.1.10")},
+		},
+		"payments.default.local": {{IP: net.ParseIP("192.168.1.11")}},
+	}
+
+	t.Run("single-label name", func(t *testing.T) {
+		r := newTestResolver(t, nil, hosts)
+
+		address, err := r.ResolveID(nameresolution.ResolveRequest{ID: "orders", Port: 50001})
+
+		require.NoError(t, err)
+		assert.Equal(t, "192.168.1.10:50001", address)
+	})
+
+	t.Run("prefer IPv6", func(t *testing.T) {
+		r := newTestResolver(t, map[string]interface{}{"preferIPv6": true}, hosts)
+
+		address, err := r.ResolveID(nameresolution.ResolveRequest{ID: "orders", Port: 50001})
+
+		require.NoError(t, err)
+		assert.Equal(t, "[fe80::1%eth0]:50001", address)
+	})
+
+	t.Run("host template", func(t *testing.T) {
+		r := newTestResolver(t, map[string]interface{}{"host": "{appID}.{namespace}.local"}, hosts)
+
+		address, err := r.ResolveID(nameresolution.ResolveRequest{ID: "payments", Namespace: "default", Port: 50002})
+
+		require.NoError(t, err)
+		assert.Equal(t, "192.168.1.11:50002", address)
+	})
+
+	t.Run("unknown host", func(t *testing.T) {
+		r := newTestResolver(t, nil, hosts)
+
+		_, err := r.ResolveID(nameresolution.ResolveRequest{ID: "inventory", Port: 50001})
+
+		assert.ErrorContains(t, err, "failed to resolve inventory")
+	})
+}
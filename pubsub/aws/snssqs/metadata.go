@@ -20,6 +20,12 @@ type snsSqsMetadata struct {
 	SecretKey string
 	// aws session token to use.
 	SessionToken string
+	// ARN of the IAM role to assume.
+	AssumeRoleArn string
+	// external ID required to assume the IAM role.
+	ExternalID string
+	// name of the session of the assumed IAM role.
+	AssumeRoleSessionName string
 	// aws region in which SNS/SQS should create resources.
 	Region string
 	// aws partition in which SNS/SQS should create resources.
@@ -36,6 +42,9 @@ type snsSqsMetadata struct {
 	fifoMessageGroupID string
 	// amount of time in seconds that a message is hidden from receive requests after it is sent to a subscriber. Default: 10.
 	messageVisibilityTimeout int64
+	// amount of time in seconds before a message whose processing failed is received again. Default: 0, meaning the
+	// message is received again once messageVisibilityTimeout expires.
+	messageRetryVisibilityTimeout int64
 	// number of times to resend a message after processing of that message fails before removing that message from the queue. Default: 10.
 	messageRetryLimit int64
 	// upon reaching the messageRetryLimit, disables the default deletion behaviour of the message from the SQS queue, and resetting the message visibilty on SQS
@@ -56,6 +65,50 @@ type snsSqsMetadata struct {
 	accountID string
 	// processing concurrency mode
 	concurrencyMode pubsub.ConcurrencyMode
+	// amount of time in seconds to wait for the messages being handled to be processed on shutdown. Default: 5.
+	drainTimeoutSeconds int64
+}
+
+// Defaults of the metadata properties.
+const (
+	defaultMessageVisibilityTimeout = 10
+	defaultMessageRetryLimit        = 10
+	defaultMessageWaitTimeSeconds   = 2
+	defaultMessageMaxNumber         = 10
+	defaultDrainTimeoutSeconds      = 5
+)
+
+// metadataSchema returns the schema of the metadata parsed by getSnsSqsMetatdata.
+func metadataSchema() []mdutils.MetadataField {
+	number := func(v int64) string {
+		return strconv.FormatInt(v, 10)
+	}
+
+	return []mdutils.MetadataField{
+		{Name: "endpoint", Type: mdutils.FieldTypeString},
+		{Name: "accessKey", Type: mdutils.FieldTypeString, Sensitive: true},
+		{Name: "secretKey", Type: mdutils.FieldTypeString, Sensitive: true},
+		{Name: "sessionToken", Type: mdutils.FieldTypeString, Sensitive: true},
+		{Name: "assumeRoleArn", Type: mdutils.FieldTypeString},
+		{Name: "externalId", Type: mdutils.FieldTypeString},
+		{Name: "assumeRoleSessionName", Type: mdutils.FieldTypeString},
+		{Name: "region", Type: mdutils.FieldTypeString},
+		{Name: "consumerID", Type: mdutils.FieldTypeString, Required: true},
+		{Name: "sqsDeadLettersQueueName", Type: mdutils.FieldTypeString},
+		{Name: "messageReceiveLimit", Type: mdutils.FieldTypeNumber},
+		{Name: "fifo", Type: mdutils.FieldTypeBool, Default: "false"},
+		{Name: "fifoMessageGroupID", Type: mdutils.FieldTypeString},
+		{Name: "messageVisibilityTimeout", Type: mdutils.FieldTypeNumber, Default: number(defaultMessageVisibilityTimeout)},
+		{Name: "messageRetryVisibilityTimeout", Type: mdutils.FieldTypeNumber},
+		{Name: "messageRetryLimit", Type: mdutils.FieldTypeNumber, Default: number(defaultMessageRetryLimit)},
+		{Name: "disableDeleteOnRetryLimit", Type: mdutils.FieldTypeBool, Default: "false"},
+		{Name: "messageWaitTimeSeconds", Type: mdutils.FieldTypeNumber, Default: number(defaultMessageWaitTimeSeconds)},
+		{Name: "messageMaxNumber", Type: mdutils.FieldTypeNumber, Default: number(defaultMessageMaxNumber)},
+		{Name: "disableEntityManagement", Type: mdutils.FieldTypeBool, Default: "false"},
+		{Name: "assetsManagementTimeoutSeconds", Type: mdutils.FieldTypeNumber, Default: strconv.FormatFloat(assetsManagementDefaultTimeoutSeconds, 'f', -1, 64)},
+		{Name: pubsub.ConcurrencyKey, Type: mdutils.FieldTypeString, Default: string(pubsub.Parallel)},
+		{Name: "drainTimeoutSeconds", Type: mdutils.FieldTypeNumber, Default: number(defaultDrainTimeoutSeconds)},
+	}
 }
 
 func parseInt64(input string, propertyName string) (int64, error) {
@@ -103,6 +156,10 @@ func (s *snsSqs) getSnsSqsMetatdata(metadata pubsub.Metadata) (*snsSqsMetadata,
 		return nil, err
 	}
 
+	if err := md.setMessageRetryVisibilityTimeout(props); err != nil {
+		return nil, err
+	}
+
 	if err := md.setMessageRetryLimit(props); err != nil {
 		return nil, err
 	}
@@ -139,6 +196,10 @@ func (s *snsSqs) getSnsSqsMetatdata(metadata pubsub.Metadata) (*snsSqsMetadata,
 		return nil, err
 	}
 
+	if err := md.setDrainTimeoutSeconds(props); err != nil {
+		return nil, err
+	}
+
 	s.logger.Debug(md.hideDebugPrintedCredentials())
 
 	return md, nil
@@ -180,6 +241,18 @@ func (md *snsSqsMetadata) setCredsAndQueueNameConfig(metadata pubsub.Metadata) e
 		md.SessionToken = val
 	}
 
+	if val, ok := metadata.Properties["assumeRoleArn"]; ok {
+		md.AssumeRoleArn = val
+	}
+
+	if val, ok := metadata.Properties["externalId"]; ok {
+		md.ExternalID = val
+	}
+
+	if val, ok := metadata.Properties["assumeRoleSessionName"]; ok {
+		md.AssumeRoleSessionName = val
+	}
+
 	if val, ok := mdutils.GetMetadataProperty(metadata.Properties, "awsRegion", "region"); ok {
 		md.Region = val
 
@@ -227,7 +300,7 @@ func (md *snsSqsMetadata) setDisableEntityManagement(props map[string]string) er
 
 func (md *snsSqsMetadata) setMessageMaxNumber(props map[string]string) error {
 	if val, ok := props["messageMaxNumber"]; !ok {
-		md.messageMaxNumber = 10
+		md.messageMaxNumber = defaultMessageMaxNumber
 	} else {
 		maxNumber, err := parseInt64(val, "messageMaxNumber")
 		if err != nil {
@@ -248,7 +321,7 @@ func (md *snsSqsMetadata) setMessageMaxNumber(props map[string]string) error {
 
 func (md *snsSqsMetadata) setMessageWaitTimeSeconds(props map[string]string) error {
 	if val, ok := props["messageWaitTimeSeconds"]; !ok {
-		md.messageWaitTimeSeconds = 2
+		md.messageWaitTimeSeconds = defaultMessageWaitTimeSeconds
 	} else {
 		waitTime, err := parseInt64(val, "messageWaitTimeSeconds")
 		if err != nil {
@@ -265,6 +338,25 @@ func (md *snsSqsMetadata) setMessageWaitTimeSeconds(props map[string]string) err
 	return nil
 }
 
+func (md *snsSqsMetadata) setDrainTimeoutSeconds(props map[string]string) error {
+	if val, ok := props["drainTimeoutSeconds"]; !ok {
+		md.drainTimeoutSeconds = defaultDrainTimeoutSeconds
+	} else {
+		timeout, err := parseInt64(val, "drainTimeoutSeconds")
+		if err != nil {
+			return err
+		}
+
+		if timeout < 0 {
+			return errors.New("drainTimeoutSeconds must not be negative")
+		}
+
+		md.drainTimeoutSeconds = timeout
+	}
+
+	return nil
+}
+
 func (md *snsSqsMetadata) setFifoConfig(props map[string]string) error {
 	// fifo settings: enable/disable SNS and SQS FIFO.
 	if val, ok := props["fifo"]; ok {
@@ -332,7 +424,7 @@ func (md *snsSqsMetadata) setDisableDeleteOnRetryLimit(props map[string]string)
 
 func (md *snsSqsMetadata) setMessageRetryLimit(props map[string]string) error {
 	if val, ok := props["messageRetryLimit"]; !ok {
-		md.messageRetryLimit = 10
+		md.messageRetryLimit = defaultMessageRetryLimit
 	} else {
 		retryLimit, err := parseInt64(val, "messageRetryLimit")
 		if err != nil {
@@ -351,7 +443,7 @@ func (md *snsSqsMetadata) setMessageRetryLimit(props map[string]string) error {
 
 func (md *snsSqsMetadata) setMessageVisibilityTimeout(props map[string]string) error {
 	if val, ok := props["messageVisibilityTimeout"]; !ok {
-		md.messageVisibilityTimeout = 10
+		md.messageVisibilityTimeout = defaultMessageVisibilityTimeout
 	} else {
 		timeout, err := parseInt64(val, "messageVisibilityTimeout")
 		if err != nil {
@@ -367,3 +459,20 @@ func (md *snsSqsMetadata) setMessageVisibilityTimeout(props map[string]string) e
 
 	return nil
 }
+
+func (md *snsSqsMetadata) setMessageRetryVisibilityTimeout(props map[string]string) error {
+	if val, ok := props["messageRetryVisibilityTimeout"]; ok {
+		timeout, err := parseInt64(val, "messageRetryVisibilityTimeout")
+		if err != nil {
+			return err
+		}
+
+		if timeout < 1 {
+			return errors.New("messageRetryVisibilityTimeout must be greater than 0")
+		}
+
+		md.messageRetryVisibilityTimeout = timeout
+	}
+
+	return nil
+}
@@ -33,6 +33,8 @@ import (
 	gonanoid "github.com/matoous/go-nanoid/v2"
 
 	awsAuth "github.com/dapr/components-contrib/internal/authentication/aws"
+	"github.com/dapr/components-contrib/internal/utils"
+	mdutils "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/kit/logger"
 )
@@ -67,6 +69,7 @@ type snsSqs struct {
 	pollerCancel  context.CancelFunc
 	backOffConfig retry.Config
 	pollerRunning chan struct{}
+	inFlight      *utils.InFlight
 }
 
 type sqsQueueInfo struct {
@@ -91,6 +94,10 @@ const (
 	maxAWSNameLength                      = 80
 	assetsManagementDefaultTimeoutSeconds = 5.0
 	awsAccountIDLength                    = 12
+
+	// Publish metadata for FIFO topics
+	messageGroupIDKey         = "messageGroupID"
+	messageDeduplicationIDKey = "messageDeduplicationID"
 )
 
 // NewSnsSqs - constructor for a new snssqs dapr component.
@@ -162,7 +169,18 @@ func (s *snsSqs) Init(metadata pubsub.Metadata) error {
 	s.queues = sync.Map{}
 	s.subscriptions = sync.Map{}
 
-	sess, err := awsAuth.GetClient(md.AccessKey, md.SecretKey, md.SessionToken, md.Region, md.Endpoint)
+	sess, err := awsAuth.NewSession(awsAuth.Options{
+		AccessKey:    md.AccessKey,
+		SecretKey:    md.SecretKey,
+		SessionToken: md.SessionToken,
+		Region:       md.Region,
+		Endpoint:     md.Endpoint,
+		AssumeRole: awsAuth.AssumeRole{
+			RoleARN:     md.AssumeRoleArn,
+			ExternalID:  md.ExternalID,
+			SessionName: md.AssumeRoleSessionName,
+		},
+	})
 	if err != nil {
 		return fmt.Errorf("error creating an AWS client: %w", err)
 	}
@@ -173,6 +191,7 @@ func (s *snsSqs) Init(metadata pubsub.Metadata) error {
 
 	s.opsTimeout = time.Duration(md.assetsManagementTimeoutSeconds * float64(time.Second))
 	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.inFlight = utils.NewInFlight()
 
 	err = s.setAwsAccountIDIfNotProvided(s.ctx)
 	if err != nil {
@@ -375,6 +394,9 @@ func (s *snsSqs) getOrCreateQueue(ctx context.Context, queueName string) (*sqsQu
 }
 
 func (s *snsSqs) getMessageGroupID(req *pubsub.PublishRequest) *string {
+	if val := req.Metadata[messageGroupIDKey]; val != "" {
+		return &val
+	}
 	if len(s.metadata.fifoMessageGroupID) > 0 {
 		return &s.metadata.fifoMessageGroupID
 	}
@@ -470,12 +492,16 @@ func (s *snsSqs) acknowledgeMessage(parentCtx context.Context, queueURL string,
 }
 
 func (s *snsSqs) resetMessageVisibilityTimeout(parentCtx context.Context, queueURL string, receiptHandle *string) error {
-	ctx, cancelFn := context.WithCancel(parentCtx)
 	// reset the timeout to its initial value so that the remaining timeout would be overridden by the initial value for other consumer to attempt processing.
+	return s.changeMessageVisibilityTimeout(parentCtx, queueURL, receiptHandle, 0)
+}
+
+func (s *snsSqs) changeMessageVisibilityTimeout(parentCtx context.Context, queueURL string, receiptHandle *string, timeout int64) error {
+	ctx, cancelFn := context.WithCancel(parentCtx)
 	_, err := s.sqsClient.ChangeMessageVisibilityWithContext(ctx, &sqs.ChangeMessageVisibilityInput{
 		QueueUrl:          aws.String(queueURL),
 		ReceiptHandle:     receiptHandle,
-		VisibilityTimeout: aws.Int64(0),
+		VisibilityTimeout: aws.Int64(timeout),
 	})
 	cancelFn()
 	if err != nil {
@@ -560,11 +586,19 @@ func (s *snsSqs) callHandler(ctx context.Context, message *sqs.Message, queueInf
 
 	s.logger.Debugf("Processing SNS message id: %s of topic: %s", *message.MessageId, sanitizedTopic)
 
-	err = handler.handler(handler.ctx, &pubsub.NewMessage{
+	// The handler is canceled only if it doesn't complete while draining on shutdown
+	err = handler.handler(s.inFlight.Context(), &pubsub.NewMessage{
 		Data:  []byte(snsMessagePayload.Message),
 		Topic: handler.topicName,
 	})
 	if err != nil {
+		// the message is received again once its visibility timeout expires, which can be shortened for retries.
+		if s.metadata.messageRetryVisibilityTimeout > 0 {
+			if innerErr := s.changeMessageVisibilityTimeout(ctx, queueInfo.url, message.ReceiptHandle, s.metadata.messageRetryVisibilityTimeout); innerErr != nil {
+				s.logger.Warnf("error changing visibility timeout of message id %s for redelivery: %v", *message.MessageId, innerErr)
+			}
+		}
+
 		return fmt.Errorf("error handling message: %w", err)
 	}
 	// otherwise, there was no error, acknowledge the message.
@@ -624,11 +658,19 @@ func (s *snsSqs) consumeSubscription(ctx context.Context, queueInfo, deadLetters
 				continue
 			}
 
+			// When draining, the message is made visible again so another consumer can receive it right away
+			if !s.inFlight.Add() {
+				s.releaseMessage(queueInfo, message)
+				continue
+			}
+
 			f := func(message *sqs.Message) {
-				if err := s.callHandler(ctx, message, queueInfo); err != nil {
+				// Messages are acknowledged with a background context, as ctx is canceled on shutdown while draining
+				if err := s.callHandler(context.Background(), message, queueInfo); err != nil {
 					s.logger.Errorf("error while handling received message. error is: %v", err)
 				}
 
+				s.inFlight.Done()
 				wg.Done()
 			}
 
@@ -647,6 +689,15 @@ func (s *snsSqs) consumeSubscription(ctx context.Context, queueInfo, deadLetters
 	<-s.pollerRunning
 }
 
+// releaseMessage makes a message which is not going to be handled visible again.
+func (s *snsSqs) releaseMessage(queueInfo *sqsQueueInfo, message *sqs.Message) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.opsTimeout)
+	defer cancel()
+	if err := s.resetMessageVisibilityTimeout(ctx, queueInfo.url, message.ReceiptHandle); err != nil {
+		s.logger.Warnf("error releasing message id %s: %v", *message.MessageId, err)
+	}
+}
+
 func (s *snsSqs) createDeadLettersQueueAttributes(queueInfo, deadLettersQueueInfo *sqsQueueInfo) (*sqs.SetQueueAttributesInput, error) {
 	policy := map[string]string{
 		"deadLetterTargetArn": deadLettersQueueInfo.arn,
@@ -851,7 +902,10 @@ func (s *snsSqs) Subscribe(subscribeCtx context.Context, req pubsub.SubscribeReq
 func (s *snsSqs) Publish(req *pubsub.PublishRequest) error {
 	topicArn, _, err := s.getOrCreateTopic(s.ctx, req.Topic)
 	if err != nil {
-		s.logger.Errorf("error getting topic ARN for %s: %v", req.Topic, err)
+		wrappedErr := fmt.Errorf("error getting topic ARN for %s: %w", req.Topic, err)
+		s.logger.Error(wrappedErr)
+
+		return wrappedErr
 	}
 
 	message := string(req.Data)
@@ -861,6 +915,10 @@ func (s *snsSqs) Publish(req *pubsub.PublishRequest) error {
 	}
 	if s.metadata.fifo {
 		snsPublishInput.MessageGroupId = s.getMessageGroupID(req)
+		// topics are created with content-based deduplication, which an explicit deduplication ID takes precedence over.
+		if val := req.Metadata[messageDeduplicationIDKey]; val != "" {
+			snsPublishInput.MessageDeduplicationId = aws.String(val)
+		}
 	}
 
 	// sns client has internal exponential backoffs.
@@ -876,7 +934,12 @@ func (s *snsSqs) Publish(req *pubsub.PublishRequest) error {
 }
 
 func (s *snsSqs) Close() error {
+	// Stop polling, then wait for the messages being handled to be acknowledged
 	s.cancel()
+	drainTimeout := time.Duration(s.metadata.drainTimeoutSeconds) * time.Second
+	if !s.inFlight.Drain(context.Background(), drainTimeout) {
+		s.logger.Warnf("not all the in-flight messages were processed within %s; they will be received again", drainTimeout)
+	}
 
 	return nil
 }
@@ -884,3 +947,8 @@ func (s *snsSqs) Close() error {
 func (s *snsSqs) Features() []pubsub.Feature {
 	return nil
 }
+
+// GetComponentMetadataSchema returns the schema of the metadata of the SNS/SQS pubsub.
+func (s *snsSqs) GetComponentMetadataSchema() []mdutils.MetadataField {
+	return metadataSchema()
+}
@@ -48,19 +48,21 @@ func Test_getSnsSqsMetatdata_AllConfiguration(t *testing.T) {
 	}
 
 	md, err := ps.getSnsSqsMetatdata(pubsub.Metadata{Base: metadata.Base{Properties: map[string]string{
-		"consumerID":               "consumer",
-		"Endpoint":                 "endpoint",
-		"concurrencyMode":          string(pubsub.Single),
-		"accessKey":                "a",
-		"secretKey":                "s",
-		"sessionToken":             "t",
-		"region":                   "r",
-		"sqsDeadLettersQueueName":  "q",
-		"messageVisibilityTimeout": "2",
-		"messageRetryLimit":        "3",
-		"messageWaitTimeSeconds":   "4",
-		"messageMaxNumber":         "5",
-		"messageReceiveLimit":      "6",
+		"consumerID":                    "consumer",
+		"Endpoint":                      "endpoint",
+		"concurrencyMode":               string(pubsub.Single),
+		"accessKey":                     "a",
+		"secretKey":                     "s",
+		"sessionToken":                  "t",
+		"region":                        "r",
+		"sqsDeadLettersQueueName":       "q",
+		"messageVisibilityTimeout":      "2",
+		"messageRetryVisibilityTimeout": "1",
+		"messageRetryLimit":             "3",
+		"messageWaitTimeSeconds":        "4",
+		"drainTimeoutSeconds":           "30",
+		"messageMaxNumber":              "5",
+		"messageReceiveLimit":           "6",
 	}}})
 
 	r.NoError(err)
@@ -74,8 +76,10 @@ func Test_getSnsSqsMetatdata_AllConfiguration(t *testing.T) {
 	r.Equal("r", md.Region)
 	r.Equal("q", md.sqsDeadLettersQueueName)
 	r.Equal(int64(2), md.messageVisibilityTimeout)
+	r.Equal(int64(1), md.messageRetryVisibilityTimeout)
 	r.Equal(int64(3), md.messageRetryLimit)
 	r.Equal(int64(4), md.messageWaitTimeSeconds)
+	r.Equal(int64(30), md.drainTimeoutSeconds)
 	r.Equal(int64(5), md.messageMaxNumber)
 	r.Equal(int64(6), md.messageReceiveLimit)
 }
@@ -106,12 +110,30 @@ func Test_getSnsSqsMetatdata_defaults(t *testing.T) {
 	r.Equal("r", md.Region)
 	r.Equal(pubsub.Parallel, md.concurrencyMode)
 	r.Equal(int64(10), md.messageVisibilityTimeout)
+	r.Equal(int64(0), md.messageRetryVisibilityTimeout)
 	r.Equal(int64(10), md.messageRetryLimit)
 	r.Equal(int64(2), md.messageWaitTimeSeconds)
 	r.Equal(int64(10), md.messageMaxNumber)
 	r.Equal(false, md.disableEntityManagement)
 	r.Equal(float64(5), md.assetsManagementTimeoutSeconds)
 	r.Equal(false, md.disableDeleteOnRetryLimit)
+	r.Equal(int64(5), md.drainTimeoutSeconds)
+}
+
+func Test_metadataSchema(t *testing.T) {
+	r := require.New(t)
+	fields := map[string]metadata.MetadataField{}
+	for _, f := range (&snsSqs{}).GetComponentMetadataSchema() {
+		fields[f.Name] = f
+	}
+
+	r.True(fields["consumerID"].Required)
+	r.True(fields["secretKey"].Sensitive)
+	r.Equal("10", fields["messageVisibilityTimeout"].Default)
+	r.Equal("2", fields["messageWaitTimeSeconds"].Default)
+	r.Equal("5", fields["assetsManagementTimeoutSeconds"].Default)
+	r.Equal("parallel", fields["concurrencyMode"].Default)
+	r.Equal(metadata.FieldTypeNumber, fields["drainTimeoutSeconds"].Type)
 }
 
 func Test_getSnsSqsMetatdata_legacyaliases(t *testing.T) {
@@ -259,6 +281,18 @@ func Test_getSnsSqsMetatdata_invalidMetadataSetup(t *testing.T) {
 			}}},
 			name: "invalid message visibility",
 		},
+		{
+			metadata: pubsub.Metadata{Base: metadata.Base{Properties: map[string]string{
+				"consumerID":                    "consumer",
+				"Endpoint":                      "endpoint",
+				"AccessKey":                     "acctId",
+				"SecretKey":                     "secret",
+				"awsToken":                      "token",
+				"Region":                        "region",
+				"messageRetryVisibilityTimeout": "0",
+			}}},
+			name: "invalid message retry visibility",
+		},
 		{
 			metadata: pubsub.Metadata{Base: metadata.Base{Properties: map[string]string{
 				"consumerID":        "consumer",
@@ -311,6 +345,30 @@ func Test_getSnsSqsMetatdata_invalidMetadataSetup(t *testing.T) {
 	}
 }
 
+func Test_getMessageGroupID(t *testing.T) {
+	t.Parallel()
+	req := &pubsub.PublishRequest{PubsubName: "pubsub", Topic: "topic"}
+
+	t.Run("generated", func(t *testing.T) {
+		ps := snsSqs{id: "id", metadata: &snsSqsMetadata{}}
+
+		require.Equal(t, "id:pubsub:topic", *ps.getMessageGroupID(req))
+	})
+
+	t.Run("from component metadata", func(t *testing.T) {
+		ps := snsSqs{id: "id", metadata: &snsSqsMetadata{fifoMessageGroupID: "group"}}
+
+		require.Equal(t, "group", *ps.getMessageGroupID(req))
+	})
+
+	t.Run("from request metadata", func(t *testing.T) {
+		ps := snsSqs{id: "id", metadata: &snsSqsMetadata{fifoMessageGroupID: "group"}}
+		req := &pubsub.PublishRequest{PubsubName: "pubsub", Topic: "topic", Metadata: map[string]string{messageGroupIDKey: "order-1"}}
+
+		require.Equal(t, "order-1", *ps.getMessageGroupID(req))
+	})
+}
+
 func Test_parseInt64(t *testing.T) {
 	t.Parallel()
 	r := require.New(t)
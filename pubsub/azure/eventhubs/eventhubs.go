@@ -20,18 +20,21 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Azure/azure-amqp-common-go/v3/aad"
 	"github.com/Azure/azure-amqp-common-go/v3/conn"
 	eventhub "github.com/Azure/azure-event-hubs-go/v3"
 	"github.com/Azure/azure-event-hubs-go/v3/eph"
-	"github.com/Azure/azure-event-hubs-go/v3/storage"
+	"github.com/Azure/azure-event-hubs-go/v3/persist"
 	mgmt "github.com/Azure/azure-sdk-for-go/services/eventhub/mgmt/2017-04-01/eventhub"
 	"github.com/Azure/azure-storage-blob-go/azblob"
 	"github.com/Azure/go-autorest/autorest/azure"
 
 	azauth "github.com/dapr/components-contrib/internal/authentication/azure"
+	ehcheckpoint "github.com/dapr/components-contrib/internal/component/azure/eventhubs"
+	"github.com/dapr/components-contrib/internal/component/azure/schemaregistry"
 	"github.com/dapr/components-contrib/internal/utils"
 	contribMetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
@@ -45,6 +48,8 @@ const (
 	entityPathKey = "EntityPath"
 	// metadata partitionKey key.
 	partitionKeyMetadataKey = "partitionKey"
+	// metadata schemaName key.
+	schemaNameMetadataKey = "schemaName"
 
 	// errors.
 	hubManagerCreationErrorMsg               = "error: creating eventHub manager client"
@@ -58,6 +63,7 @@ const (
 	missingResourceGroupNameMsg              = "error: missing resourceGroupName attribute required for entityManagement"
 	missingSubscriptionIDMsg                 = "error: missing subscriptionID attribute required for entityManagement"
 	entityManagementConnectionStrMsg         = "error: entity management support is not available with connectionString"
+	missingSchemaGroupMsg                    = "error: missing schemaGroup attribute required for schemaRegistryNamespace"
 	differentTopicConnectionStringErrorTmpl  = "error: specified topic %s does not match the event hub name in the provided connectionString"
 
 	// Event Hubs SystemProperties names for metadata passthrough.
@@ -137,6 +143,8 @@ type AzureEventHubs struct {
 	backOffConfig      retry.Config
 	hubClients         map[string]*eventhub.Hub
 	eventProcessors    map[string]*eph.EventProcessorHost
+	subscriptions      map[string]*subscription
+	subscriptionsLock  sync.Mutex
 	hubManager         *eventhub.HubManager
 	eventHubSettings   azauth.EnvironmentSettings
 	managementSettings azauth.EnvironmentSettings
@@ -144,20 +152,37 @@ type AzureEventHubs struct {
 	tokenProvider      *aad.TokenProvider
 	storageCredential  azblob.Credential
 	azureEnvironment   *azure.Environment
+	schemaSerializer   *schemaregistry.Serializer
 }
 
 type azureEventHubsMetadata struct {
-	ConnectionString       string `json:"connectionString,omitempty"`
-	EventHubNamespace      string `json:"eventHubNamespace,omitempty"`
-	ConsumerGroup          string `json:"consumerID"`
-	StorageAccountName     string `json:"storageAccountName,omitempty"`
-	StorageAccountKey      string `json:"storageAccountKey,omitempty"`
-	StorageContainerName   string `json:"storageContainerName,omitempty"`
-	EnableEntityManagement bool   `json:"enableEntityManagement,omitempty,string"`
-	MessageRetentionInDays int32  `json:"messageRetentionInDays,omitempty,string"`
-	PartitionCount         int32  `json:"partitionCount,omitempty,string"`
-	SubscriptionID         string `json:"subscriptionID,omitempty"`
-	ResourceGroupName      string `json:"resourceGroupName,omitempty"`
+	ConnectionString        string `json:"connectionString,omitempty" mdsensitive:"true"`
+	EventHubNamespace       string `json:"eventHubNamespace,omitempty"`
+	ConsumerGroup           string `json:"consumerID"`
+	StorageAccountName      string `json:"storageAccountName,omitempty"`
+	StorageAccountKey       string `json:"storageAccountKey,omitempty" mdsensitive:"true"`
+	StorageContainerName    string `json:"storageContainerName,omitempty"`
+	EnableEntityManagement  bool   `json:"enableEntityManagement,omitempty,string"`
+	MessageRetentionInDays  int32  `json:"messageRetentionInDays,omitempty,string"`
+	PartitionCount          int32  `json:"partitionCount,omitempty,string"`
+	SubscriptionID          string `json:"subscriptionID,omitempty"`
+	ResourceGroupName       string `json:"resourceGroupName,omitempty"`
+	SchemaRegistryNamespace string `json:"schemaRegistryNamespace,omitempty"`
+	SchemaGroup             string `json:"schemaGroup,omitempty"`
+	SchemaName              string `json:"schemaName,omitempty"`
+	SchemaCacheTTLInSec     int32  `json:"schemaCacheTTLInSec,omitempty,string"`
+
+	// Position where the consumption of the partitions without a checkpoint starts, the earliest event by default.
+	startingPosition ehcheckpoint.StartingPosition
+}
+
+// subscription is a subscription to a topic, whose processor is restarted to seek.
+type subscription struct {
+	ctx     context.Context
+	req     pubsub.SubscribeRequest
+	handler pubsub.Handler
+	// stop stops the processor of the subscription and waits for it to be closed.
+	stop func()
 }
 
 // NewAzureEventHubs returns a new Azure Event hubs instance.
@@ -185,6 +210,19 @@ func parseEventHubsMetadata(meta pubsub.Metadata) (*azureEventHubsMetadata, erro
 		return &m, errors.New(bothConnectionStringNamespaceErrorMsg)
 	}
 
+	if m.SchemaRegistryNamespace != "" && m.SchemaGroup == "" {
+		return &m, errors.New(missingSchemaGroupMsg)
+	}
+
+	position, ok, err := ehcheckpoint.ParseStartingPosition(meta.Properties)
+	if err != nil {
+		return &m, err
+	}
+	if !ok {
+		position.Offset = persist.StartOfStream
+	}
+	m.startingPosition = position
+
 	return &m, nil
 }
 
@@ -346,7 +384,7 @@ func (aeh *AzureEventHubs) ensurePublisherClient(ctx context.Context, hubName st
 	return nil
 }
 
-func (aeh *AzureEventHubs) ensureSubscriberClient(ctx context.Context, topic string, leaserCheckpointer *storage.LeaserCheckpointer) (*eph.EventProcessorHost, error) {
+func (aeh *AzureEventHubs) ensureSubscriberClient(ctx context.Context, topic string, leaserCheckpointer ehcheckpoint.LeaserCheckpointer) (*eph.EventProcessorHost, error) {
 	// connectionString given.
 	if aeh.metadata.ConnectionString != "" {
 		hubName, err := validateAndGetHubName(aeh.metadata.ConnectionString)
@@ -471,6 +509,7 @@ func (aeh *AzureEventHubs) Init(metadata pubsub.Metadata) error {
 
 	aeh.metadata = m
 	aeh.eventProcessors = map[string]*eph.EventProcessorHost{}
+	aeh.subscriptions = map[string]*subscription{}
 	aeh.hubClients = map[string]*eventhub.Hub{}
 
 	if aeh.metadata.ConnectionString != "" {
@@ -523,6 +562,20 @@ func (aeh *AzureEventHubs) Init(metadata pubsub.Metadata) error {
 		}
 	}
 
+	// The schema registry is accessed via AAD, even with a connectionString.
+	if m.SchemaRegistryNamespace != "" {
+		settings, err := azauth.NewEnvironmentSettings(azauth.AzureEventHubsResourceName, metadata.Properties)
+		if err != nil {
+			return err
+		}
+		cred, err := settings.GetTokenCredential()
+		if err != nil {
+			return err
+		}
+		client := schemaregistry.NewClient(m.SchemaRegistryNamespace, cred, time.Duration(m.SchemaCacheTTLInSec)*time.Second)
+		aeh.schemaSerializer = schemaregistry.NewSerializer(client, m.SchemaGroup)
+	}
+
 	// connect to the storage account.
 	if m.StorageAccountKey != "" {
 		metadata.Properties["accountKey"] = m.StorageAccountKey
@@ -558,7 +611,11 @@ func (aeh *AzureEventHubs) Publish(req *pubsub.PublishRequest) error {
 	if ok {
 		event.PartitionKey = &val
 	}
-	err := aeh.hubClients[req.Topic].Send(aeh.publishCtx, event)
+	err := aeh.serialize(aeh.publishCtx, event, req.Metadata)
+	if err != nil {
+		return fmt.Errorf("error from publish: %s", err)
+	}
+	err = aeh.hubClients[req.Topic].Send(aeh.publishCtx, event)
 	if err != nil {
 		return fmt.Errorf("error from publish: %s", err)
 	}
@@ -582,6 +639,9 @@ func (aeh *AzureEventHubs) BulkPublish(ctx context.Context, req *pubsub.BulkPubl
 		if val, ok := entry.Metadata[partitionKeyMetadataKey]; ok {
 			events[i].PartitionKey = &val
 		}
+		if err := aeh.serialize(ctx, events[i], entry.Metadata); err != nil {
+			return pubsub.NewBulkPublishResponse(req.Entries, pubsub.PublishFailed, err), err
+		}
 	}
 
 	// Configure options for sending events.
@@ -613,60 +673,178 @@ func (aeh *AzureEventHubs) Subscribe(subscribeCtx context.Context, req pubsub.Su
 		}
 	}
 
+	sub := &subscription{
+		ctx:     subscribeCtx,
+		req:     req,
+		handler: handler,
+	}
+
+	aeh.subscriptionsLock.Lock()
+	defer aeh.subscriptionsLock.Unlock()
+
+	err = aeh.startProcessor(sub, nil)
+	if err != nil {
+		return err
+	}
+	aeh.subscriptions[req.Topic] = sub
+
+	go func() {
+		<-subscribeCtx.Done()
+		aeh.subscriptionsLock.Lock()
+		if aeh.subscriptions[req.Topic] == sub {
+			delete(aeh.subscriptions, req.Topic)
+		}
+		aeh.subscriptionsLock.Unlock()
+	}()
+
+	return nil
+}
+
+// Seek restarts the processor of the subscription to the topic from the offset or the timestamp of the request, on the
+// partitions it leases: the processors of the other replicas must be restarted with the same request.
+// The offset is the one of the last event processed, as in the x-opt-offset metadata of the events, and the
+// consumption resumes after it; -1 restarts from the start of the stream.
+func (aeh *AzureEventHubs) Seek(ctx context.Context, req pubsub.SeekRequest) error {
+	position := ehcheckpoint.StartingPosition{Offset: req.Offset}
+	if req.Timestamp != nil {
+		position = ehcheckpoint.StartingPosition{Timestamp: *req.Timestamp}
+	}
+
+	aeh.subscriptionsLock.Lock()
+	defer aeh.subscriptionsLock.Unlock()
+
+	sub, ok := aeh.subscriptions[req.Topic]
+	if !ok || sub.ctx.Err() != nil {
+		return fmt.Errorf("error: no subscription to topic %s to seek", req.Topic)
+	}
+
+	aeh.logger.Infof("restarting the processor of topic %s to seek", req.Topic)
+	sub.stop()
+
+	return aeh.startProcessor(sub, &position)
+}
+
+// startProcessor starts a processor delivering the events of the topic of the subscription to its handler, from the
+// seek position if not nil. The caller must hold subscriptionsLock.
+func (aeh *AzureEventHubs) startProcessor(sub *subscription, seek *ehcheckpoint.StartingPosition) error {
+	topic := sub.req.Topic
+
 	// Set topic name, consumerID prefix for partition checkpoint lease blob path.
 	// This is needed to support multiple consumers for the topic using the same storage container.
-	leaserPrefixOpt := storage.WithPrefixInBlobPath(aeh.getStoragePrefixString(req.Topic))
-	leaserCheckpointer, err := storage.NewStorageLeaserCheckpointer(aeh.storageCredential, aeh.metadata.StorageAccountName, aeh.metadata.StorageContainerName, *aeh.azureEnvironment, leaserPrefixOpt)
+	leaserCheckpointer, err := ehcheckpoint.NewLeaserCheckpointer(aeh.storageCredential, aeh.metadata.StorageAccountName, aeh.metadata.StorageContainerName,
+		*aeh.azureEnvironment, aeh.getStoragePrefixString(topic), aeh.metadata.startingPosition)
 	if err != nil {
 		return err
 	}
+	if seek != nil {
+		leaserCheckpointer = ehcheckpoint.WithSeekPosition(leaserCheckpointer, *seek)
+	}
 
-	processor, err := aeh.ensureSubscriberClient(subscribeCtx, req.Topic, leaserCheckpointer)
+	// The processor is stopped with the subscription, or to seek
+	processorCtx, cancel := context.WithCancel(sub.ctx)
+	processor, err := aeh.ensureSubscriberClient(processorCtx, topic, leaserCheckpointer)
 	if err != nil {
+		cancel()
 		return err
 	}
 
-	aeh.logger.Debugf("registering handler for topic %s", req.Topic)
-	_, err = processor.RegisterHandler(subscribeCtx,
+	aeh.logger.Debugf("registering handler for topic %s", topic)
+	_, err = processor.RegisterHandler(processorCtx,
 		func(_ context.Context, e *eventhub.Event) error {
 			// This component has built-in retries because Event Hubs doesn't support N/ACK for messages
-			b := aeh.backOffConfig.NewBackOffWithContext(subscribeCtx)
+			b := aeh.backOffConfig.NewBackOffWithContext(processorCtx)
 
 			retryerr := retry.NotifyRecover(func() error {
-				aeh.logger.Debugf("Processing EventHubs event %s/%s", req.Topic, e.ID)
+				aeh.logger.Debugf("Processing EventHubs event %s/%s", topic, e.ID)
 
-				return subscribeHandler(subscribeCtx, req.Topic, e, handler)
+				if err := aeh.deserialize(processorCtx, e); err != nil {
+					return err
+				}
+				return subscribeHandler(processorCtx, topic, e, sub.handler)
 			}, b, func(_ error, _ time.Duration) {
-				aeh.logger.Warnf("Error processing EventHubs event: %s/%s. Retrying...", req.Topic, e.ID)
+				aeh.logger.Warnf("Error processing EventHubs event: %s/%s. Retrying...", topic, e.ID)
 			}, func() {
-				aeh.logger.Warnf("Successfully processed EventHubs event after it previously failed: %s/%s", req.Topic, e.ID)
+				aeh.logger.Warnf("Successfully processed EventHubs event after it previously failed: %s/%s", topic, e.ID)
 			})
 			if retryerr != nil {
-				aeh.logger.Errorf("Too many failed attempts at processing Eventhubs event: %s/%s. Error: %v.", req.Topic, e.ID, err)
+				aeh.logger.Errorf("Too many failed attempts at processing Eventhubs event: %s/%s. Error: %v.", topic, e.ID, err)
 			}
 			return retryerr
 		})
 	if err != nil {
+		cancel()
 		return err
 	}
 
-	err = processor.StartNonBlocking(subscribeCtx)
+	err = processor.StartNonBlocking(processorCtx)
 	if err != nil {
+		cancel()
 		return err
 	}
-	aeh.eventProcessors[req.Topic] = processor
+	aeh.eventProcessors[topic] = processor
 
 	// Listen for context cancelation and stop processing messages
 	// This seems to be necessary because otherwise the processor isn't automatically closed on context cancelation
+	closed := make(chan struct{})
 	go func() {
-		<-subscribeCtx.Done()
+		<-processorCtx.Done()
 		stopCtx, stopCancel := context.WithTimeout(context.Background(), resourceGetTimeout)
 		stopErr := processor.Close(stopCtx)
 		stopCancel()
 		if stopErr != nil {
 			aeh.logger.Warnf("Error closing subscribe processor: %v", stopErr)
 		}
+		close(closed)
 	}()
+	sub.stop = func() {
+		cancel()
+		<-closed
+	}
+
+	return nil
+}
+
+// serialize encodes the data of an event with its schema, if the schema registry is configured.
+func (aeh *AzureEventHubs) serialize(ctx context.Context, event *eventhub.Event, metadata map[string]string) error {
+	if aeh.schemaSerializer == nil {
+		return nil
+	}
+	schemaName := aeh.metadata.SchemaName
+	if val, ok := metadata[schemaNameMetadataKey]; ok && val != "" {
+		schemaName = val
+	}
+	if schemaName == "" {
+		return nil
+	}
+
+	data, contentType, err := aeh.schemaSerializer.Serialize(ctx, event.Data, schemaName)
+	if err != nil {
+		return err
+	}
+	event.Data = data
+	if event.Properties == nil {
+		event.Properties = map[string]interface{}{}
+	}
+	event.Properties[schemaregistry.ContentTypeProperty] = contentType
+
+	return nil
+}
+
+// deserialize decodes the data of an event encoded with a schema of the registry to JSON.
+func (aeh *AzureEventHubs) deserialize(ctx context.Context, event *eventhub.Event) error {
+	if aeh.schemaSerializer == nil {
+		return nil
+	}
+	contentType := event.RawAMQPMessage.Properties.ContentType
+	if val, ok := event.Properties[schemaregistry.ContentTypeProperty].(string); ok {
+		contentType = val
+	}
+
+	data, err := aeh.schemaSerializer.Deserialize(ctx, event.Data, contentType)
+	if err != nil {
+		return err
+	}
+	event.Data = data
 
 	return nil
 }
@@ -689,6 +867,8 @@ func (aeh *AzureEventHubs) Close() (err error) {
 		}
 	}
 	aeh.hubClients = map[string]*eventhub.Hub{}
+	aeh.subscriptionsLock.Lock()
+	defer aeh.subscriptionsLock.Unlock()
 	for topic, client := range aeh.eventProcessors {
 		ctx, cancel = context.WithTimeout(context.Background(), resourceGetTimeout)
 		err = client.Close(ctx)
@@ -708,3 +888,12 @@ func (aeh *AzureEventHubs) Close() (err error) {
 func (aeh *AzureEventHubs) Features() []pubsub.Feature {
 	return nil
 }
+
+// GetComponentMetadataSchema returns the schema of the metadata of the Event Hubs pubsub.
+func (aeh *AzureEventHubs) GetComponentMetadataSchema() []contribMetadata.MetadataField {
+	fields, _ := contribMetadata.GetMetadataSchemaFromStruct(azureEventHubsMetadata{
+		MessageRetentionInDays: defaultMessageRetentionInDays,
+		PartitionCount:         defaultPartitionCount,
+	})
+	return append(fields, ehcheckpoint.MetadataSchema(ehcheckpoint.StartingPositionEarliest)...)
+}
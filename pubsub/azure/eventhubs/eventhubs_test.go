@@ -14,12 +14,20 @@ limitations under the License.
 package eventhubs
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	eventhub "github.com/Azure/azure-event-hubs-go/v3"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/dapr/components-contrib/internal/component/azure/schemaregistry"
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/kit/logger"
@@ -67,6 +75,47 @@ func TestParseEventHubsMetadata(t *testing.T) {
 		assert.Error(t, err)
 		assert.Equal(t, missingConnectionStringNamespaceErrorMsg, err.Error())
 	})
+
+	t.Run("test schema registry given", func(t *testing.T) {
+		props := map[string]string{"connectionString": "fake", "schemaRegistryNamespace": "fake.servicebus.windows.net", "schemaGroup": "group", "schemaName": "order", "schemaCacheTTLInSec": "60"}
+
+		metadata := pubsub.Metadata{Base: metadata.Base{Properties: props}}
+		m, err := parseEventHubsMetadata(metadata)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "fake.servicebus.windows.net", m.SchemaRegistryNamespace)
+		assert.Equal(t, "group", m.SchemaGroup)
+		assert.Equal(t, "order", m.SchemaName)
+		assert.Equal(t, int32(60), m.SchemaCacheTTLInSec)
+	})
+
+	t.Run("test schema registry without schema group", func(t *testing.T) {
+		props := map[string]string{"connectionString": "fake", "schemaRegistryNamespace": "fake.servicebus.windows.net"}
+
+		metadata := pubsub.Metadata{Base: metadata.Base{Properties: props}}
+		_, err := parseEventHubsMetadata(metadata)
+
+		assert.Error(t, err)
+		assert.Equal(t, missingSchemaGroupMsg, err.Error())
+	})
+
+	t.Run("test starting position", func(t *testing.T) {
+		props := map[string]string{"connectionString": "fake"}
+
+		m, err := parseEventHubsMetadata(pubsub.Metadata{Base: metadata.Base{Properties: props}})
+		require.NoError(t, err)
+		assert.Equal(t, "-1", m.startingPosition.Offset)
+
+		props["startingPosition"] = "offset"
+		props["startingOffset"] = "1024"
+		m, err = parseEventHubsMetadata(pubsub.Metadata{Base: metadata.Base{Properties: props}})
+		require.NoError(t, err)
+		assert.Equal(t, "1024", m.startingPosition.Offset)
+
+		props["startingPosition"] = "timestamp"
+		_, err = parseEventHubsMetadata(pubsub.Metadata{Base: metadata.Base{Properties: props}})
+		assert.ErrorContains(t, err, "invalid startingTimestamp")
+	})
 }
 
 func TestValidateSubscriptionAttributes(t *testing.T) {
@@ -322,3 +371,48 @@ func TestConstructConnectionStringFromTopic(t *testing.T) {
 		assert.Equal(t, "", c)
 	})
 }
+
+func TestSeekRequiresSubscription(t *testing.T) {
+	aeh := &AzureEventHubs{logger: testLogger, metadata: &azureEventHubsMetadata{}, subscriptions: map[string]*subscription{}}
+
+	err := pubsub.Seek(context.Background(), aeh, pubsub.SeekRequest{Topic: "orders", Offset: "-1"})
+
+	assert.ErrorContains(t, err, "no subscription to topic orders")
+}
+
+func TestGetComponentMetadataSchema(t *testing.T) {
+	fields := map[string]metadata.MetadataField{}
+	for _, f := range (&AzureEventHubs{}).GetComponentMetadataSchema() {
+		fields[f.Name] = f
+	}
+
+	assert.Equal(t, "1", fields["messageRetentionInDays"].Default)
+	assert.Equal(t, "1", fields["partitionCount"].Default)
+	assert.Equal(t, "earliest", fields["startingPosition"].Default)
+}
+
+type fakeCredential struct{}
+
+func (fakeCredential) GetToken(_ context.Context, _ policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func TestSerialize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Schema-Id", "abc123")
+		w.Header().Set("Content-Type", "application/json;serialization=Avro")
+		w.Write([]byte(`{"type": "record", "name": "Order", "fields": [{"name": "id", "type": "string"}]}`))
+	}))
+	defer server.Close()
+	aeh := &AzureEventHubs{
+		metadata:         &azureEventHubsMetadata{SchemaName: "order"},
+		schemaSerializer: schemaregistry.NewSerializer(schemaregistry.NewClient(server.URL, fakeCredential{}, time.Minute), "group"),
+	}
+
+	event := &eventhub.Event{Data: []byte(`{"id": "1"}`), Properties: map[string]interface{}{"tenant": "a"}}
+	err := aeh.serialize(context.Background(), event, nil)
+
+	require.NoError(t, err)
+	// The properties of the event are kept
+	assert.Equal(t, map[string]interface{}{"tenant": "a", schemaregistry.ContentTypeProperty: "avro/binary+abc123"}, event.Properties)
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/cenkalti/backoff/v4"
 
 	impl "github.com/dapr/components-contrib/internal/component/azure/servicebus"
+	"github.com/dapr/components-contrib/internal/logging"
 	"github.com/dapr/components-contrib/internal/utils"
 	contribMetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
@@ -52,6 +53,7 @@ func NewAzureServiceBusQueues(logger logger.Logger) pubsub.PubSub {
 }
 
 func (a *azureServiceBus) Init(metadata pubsub.Metadata) (err error) {
+	a.logger = logging.ForComponent(a.logger, "pubsub.azure.servicebus.queues", metadata.Name)
 	a.metadata, err = impl.ParseMetadata(metadata.Properties, a.logger, impl.MetadataModeQueues)
 	if err != nil {
 		return err
@@ -178,18 +180,15 @@ func (a *azureServiceBus) BulkPublish(ctx context.Context, req *pubsub.BulkPubli
 }
 
 func (a *azureServiceBus) Subscribe(subscribeCtx context.Context, req pubsub.SubscribeRequest, handler pubsub.Handler) error {
-	sub := impl.NewSubscription(
-		subscribeCtx,
-		a.metadata.MaxActiveMessages,
-		a.metadata.TimeoutInSec,
-		nil,
-		a.metadata.MaxRetriableErrorsPerSec,
-		a.metadata.MaxConcurrentHandlers,
-		"queue "+req.Topic,
-		a.logger,
-	)
+	newSubscription := func() *impl.Subscription {
+		return impl.NewSubscription(
+			subscribeCtx,
+			a.metadata.SubscriptionOptions(subscriptionEntity(req), nil),
+			a.logger,
+		)
+	}
 
-	receiveAndBlockFn := func(onFirstSuccess func()) error {
+	receiveAndBlockFn := func(sub *impl.Subscription, onFirstSuccess func()) error {
 		return sub.ReceiveAndBlock(
 			impl.GetPubSubHandlerFunc(req.Topic, handler, a.logger, time.Duration(a.metadata.HandlerTimeoutInSec)*time.Second),
 			a.metadata.LockRenewalInSec,
@@ -198,23 +197,20 @@ func (a *azureServiceBus) Subscribe(subscribeCtx context.Context, req pubsub.Sub
 		)
 	}
 
-	return a.doSubscribe(subscribeCtx, req, sub, receiveAndBlockFn)
+	return a.doSubscribe(subscribeCtx, req, newSubscription, receiveAndBlockFn)
 }
 
 func (a *azureServiceBus) BulkSubscribe(subscribeCtx context.Context, req pubsub.SubscribeRequest, handler pubsub.BulkHandler) error {
 	maxBulkSubCount := utils.GetElemOrDefaultFromMap(req.Metadata, contribMetadata.MaxBulkSubCountKey, defaultMaxBulkSubCount)
-	sub := impl.NewSubscription(
-		subscribeCtx,
-		a.metadata.MaxActiveMessages,
-		a.metadata.TimeoutInSec,
-		&maxBulkSubCount,
-		a.metadata.MaxRetriableErrorsPerSec,
-		a.metadata.MaxConcurrentHandlers,
-		"queue "+req.Topic,
-		a.logger,
-	)
+	newSubscription := func() *impl.Subscription {
+		return impl.NewSubscription(
+			subscribeCtx,
+			a.metadata.SubscriptionOptions(subscriptionEntity(req), &maxBulkSubCount),
+			a.logger,
+		)
+	}
 
-	receiveAndBlockFn := func(onFirstSuccess func()) error {
+	receiveAndBlockFn := func(sub *impl.Subscription, onFirstSuccess func()) error {
 		return sub.ReceiveAndBlock(
 			impl.GetBulkPubSubHandlerFunc(req.Topic, handler, a.logger, time.Duration(a.metadata.HandlerTimeoutInSec)*time.Second),
 			a.metadata.LockRenewalInSec,
@@ -223,13 +219,13 @@ func (a *azureServiceBus) BulkSubscribe(subscribeCtx context.Context, req pubsub
 		)
 	}
 
-	return a.doSubscribe(subscribeCtx, req, sub, receiveAndBlockFn)
+	return a.doSubscribe(subscribeCtx, req, newSubscription, receiveAndBlockFn)
 }
 
 // doSubscribe is a helper function that handles the common logic for both Subscribe and BulkSubscribe.
 // The receiveAndBlockFn is a function should invoke a blocking call to receive messages from the topic.
 func (a *azureServiceBus) doSubscribe(subscribeCtx context.Context,
-	req pubsub.SubscribeRequest, sub *impl.Subscription, receiveAndBlockFn func(func()) error,
+	req pubsub.SubscribeRequest, newSubscription func() *impl.Subscription, receiveAndBlockFn func(*impl.Subscription, func()) error,
 ) error {
 	// Does nothing if DisableEntityManagement is true
 	err := a.client.EnsureQueue(subscribeCtx, req.Topic)
@@ -237,6 +233,14 @@ func (a *azureServiceBus) doSubscribe(subscribeCtx context.Context,
 		return err
 	}
 
+	// The messages of the dead-letter subqueue are not grouped in sessions
+	if a.metadata.RequireSessions && !impl.IsDeadLetterSubscription(req.Metadata) {
+		for i := 0; i < a.metadata.MaxConcurrentSessions; i++ {
+			go a.receiveSessions(subscribeCtx, req, newSubscription, receiveAndBlockFn)
+		}
+		return nil
+	}
+
 	// Reconnection backoff policy
 	bo := backoff.NewExponentialBackOff()
 	bo.MaxElapsedTime = 0
@@ -248,12 +252,17 @@ func (a *azureServiceBus) doSubscribe(subscribeCtx context.Context,
 		bo.Reset()
 	}
 
+	sub := newSubscription()
 	go func() {
 		// Reconnect loop.
 		for {
 			// Blocks until a successful connection (or until context is canceled)
-			err := sub.Connect(func() (*servicebus.Receiver, error) {
-				return a.client.GetClient().NewReceiverForQueue(req.Topic, nil)
+			err := sub.Connect(func() (impl.Receiver, error) {
+				receiver, err := a.client.GetClient().NewReceiverForQueue(req.Topic, impl.NewReceiverOptions(req.Metadata))
+				if err != nil {
+					return nil, err
+				}
+				return impl.NewMessageReceiver(receiver), nil
 			})
 			if err != nil {
 				// Realistically, the only time we should get to this point is if the context was canceled, but let's log any other error we may get.
@@ -265,9 +274,9 @@ func (a *azureServiceBus) doSubscribe(subscribeCtx context.Context,
 
 			// receiveAndBlockFn will only return with an error that it cannot handle internally. The subscription connection is closed when this method returns.
 			// If that occurs, we will log the error and attempt to re-establish the subscription connection until we exhaust the number of reconnect attempts.
-			err = receiveAndBlockFn(onFirstSuccess)
+			err = receiveAndBlockFn(sub, onFirstSuccess)
 			if err != nil && !errors.Is(err, context.Canceled) {
-				a.logger.Error(err)
+				a.logger.Errorf("Error receiving messages: %v", err)
 			}
 
 			// Gracefully close the connection (in case it's not closed already)
@@ -291,6 +300,53 @@ func (a *azureServiceBus) doSubscribe(subscribeCtx context.Context,
 	return nil
 }
 
+// receiveSessions processes the messages of the sessions of the queue, one session at a time, until the context is canceled.
+// The messages of each session are processed in order.
+func (a *azureServiceBus) receiveSessions(ctx context.Context,
+	req pubsub.SubscribeRequest, newSubscription func() *impl.Subscription, receiveAndBlockFn func(*impl.Subscription, func()) error,
+) {
+	for {
+		sub := newSubscription()
+
+		// Blocks until a session is accepted (or until context is canceled)
+		err := sub.Connect(func() (impl.Receiver, error) {
+			return a.client.AcceptNextSession(ctx, req.Topic, "", time.Duration(a.metadata.SessionIdleTimeoutInSec)*time.Second)
+		})
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				a.logger.Errorf("Could not accept a session of queue %s: %v", req.Topic, err)
+			}
+			return
+		}
+
+		// receiveAndBlockFn returns when the session is idle, or with an error that it cannot handle internally.
+		// In both cases, the session is released and the next available one is accepted.
+		err = receiveAndBlockFn(sub, nil)
+		if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, impl.ErrSessionIdle) {
+			a.logger.Errorf("Error receiving messages of the session: %v", err)
+		}
+
+		// Use a background context here (with timeout) because ctx may be closed already
+		closeCtx, closeCancel := context.WithTimeout(context.Background(), time.Second*time.Duration(a.metadata.TimeoutInSec))
+		sub.Close(closeCtx)
+		closeCancel()
+
+		if ctx.Err() != nil {
+			a.logger.Debug("Context canceled; will not accept new sessions")
+			return
+		}
+	}
+}
+
+// subscriptionEntity returns the entity of a subscription for the logs.
+func subscriptionEntity(req pubsub.SubscribeRequest) string {
+	if impl.IsDeadLetterSubscription(req.Metadata) {
+		return "dead-letter subqueue of queue " + req.Topic
+	}
+
+	return "queue " + req.Topic
+}
+
 func (a *azureServiceBus) Close() (err error) {
 	a.publishCancel()
 	a.client.CloseAllSenders(a.logger)
@@ -300,3 +356,8 @@ func (a *azureServiceBus) Close() (err error) {
 func (a *azureServiceBus) Features() []pubsub.Feature {
 	return a.features
 }
+
+// GetComponentMetadataSchema returns the schema of the metadata of the Service Bus queues pubsub.
+func (a *azureServiceBus) GetComponentMetadataSchema() []contribMetadata.MetadataField {
+	return impl.MetadataSchema(impl.MetadataModeQueues)
+}
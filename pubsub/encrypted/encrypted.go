@@ -0,0 +1,310 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package encrypted provides a decorator for pub/subs that encrypts the payloads of the messages before they reach the
+// broker and decrypts them for the subscribers, so that brokers operated by third parties never see the plaintext.
+//
+// Each message is encrypted with AES-GCM using a random data key, which is itself encrypted with the key configured in
+// the "encryptionKey" metadata property, usually a reference to a secret store. The envelope carries the ID of that key,
+// so that keys can be rotated while messages encrypted with the previous ones are still in flight.
+package encrypted
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/kit/ptr"
+)
+
+const (
+	// KeyKey is the metadata key for the base64-encoded AES key, of 16, 24 or 32 bytes, the messages are encrypted with.
+	// The messages are not encrypted if it is not set.
+	KeyKey = "encryptionKey"
+	// KeyIDKey is the metadata key for the ID of the key, written in the envelopes.
+	KeyIDKey = "encryptionKeyId"
+	// PreviousKeysKey is the metadata key for the keys which are only used for decrypting the messages, after a
+	// rotation, as comma-separated id=key pairs.
+	PreviousKeysKey = "encryptionPreviousKeys"
+	// AllowPlaintextKey is the metadata key for whether the messages which aren't encrypted are delivered to the
+	// subscribers, as when encryption is enabled on a topic with messages in flight.
+	AllowPlaintextKey = "encryptionAllowPlaintext"
+
+	// ContentType is the content type of the encrypted messages.
+	ContentType = "application/vnd.dapr.encrypted+json"
+
+	defaultKeyID    = "default"
+	envelopeVersion = 1
+	dataKeySize     = 32
+)
+
+// envelope is the payload of an encrypted message.
+type envelope struct {
+	Version int    `json:"v"`
+	KeyID   string `json:"kid"`
+	// Data key, encrypted with the key, prefixed by the nonce
+	Key []byte `json:"key"`
+	// Payload, encrypted with the data key, prefixed by the nonce
+	Data        []byte  `json:"data"`
+	ContentType *string `json:"ct,omitempty"`
+}
+
+// PubSub is a pub/sub that encrypts the messages published on another pub/sub, and decrypts the messages received.
+type PubSub struct {
+	pubsub.PubSub
+
+	keyID string
+	// Ciphers of the keys, by ID, including the current one
+	keys           map[string]cipher.AEAD
+	allowPlaintext bool
+}
+
+// New returns a pub/sub encrypting the messages of ps.
+func New(ps pubsub.PubSub) *PubSub {
+	return &PubSub{PubSub: ps}
+}
+
+// Init reads the encryption keys and initializes the wrapped pub/sub.
+func (p *PubSub) Init(metadata pubsub.Metadata) error {
+	p.keys = nil
+	if val := metadata.Properties[KeyKey]; val != "" {
+		p.keyID = defaultKeyID
+		if id := metadata.Properties[KeyIDKey]; id != "" {
+			p.keyID = id
+		}
+		aead, err := newCipher(val)
+		if err != nil {
+			return fmt.Errorf("encryption error: invalid %s: %w", KeyKey, err)
+		}
+		p.keys = map[string]cipher.AEAD{p.keyID: aead}
+
+		if val := metadata.Properties[PreviousKeysKey]; val != "" {
+			for _, pair := range strings.Split(val, ",") {
+				id, key, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok || id == "" {
+					return fmt.Errorf("encryption error: invalid %s, expected id=key pairs", PreviousKeysKey)
+				}
+				if _, exists := p.keys[id]; exists {
+					return fmt.Errorf("encryption error: duplicate key %s", id)
+				}
+				aead, err := newCipher(key)
+				if err != nil {
+					return fmt.Errorf("encryption error: invalid key %s in %s: %w", id, PreviousKeysKey, err)
+				}
+				p.keys[id] = aead
+			}
+		}
+	} else if metadata.Properties[PreviousKeysKey] != "" {
+		return fmt.Errorf("encryption error: %s requires %s", PreviousKeysKey, KeyKey)
+	}
+
+	p.allowPlaintext = false
+	if val := metadata.Properties[AllowPlaintextKey]; val != "" {
+		var err error
+		p.allowPlaintext, err = strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("encryption error: invalid %s %s: %w", AllowPlaintextKey, val, err)
+		}
+	}
+
+	return p.PubSub.Init(metadata)
+}
+
+// Publish encrypts the message and publishes it.
+func (p *PubSub) Publish(req *pubsub.PublishRequest) error {
+	if p.keys == nil {
+		return p.PubSub.Publish(req)
+	}
+
+	data, err := p.encrypt(req.Topic, req.Data, req.ContentType)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt message on topic %s: %w", req.Topic, err)
+	}
+
+	encrypted := *req
+	encrypted.Data = data
+	encrypted.ContentType = ptr.Of(ContentType)
+
+	return p.PubSub.Publish(&encrypted)
+}
+
+// BulkPublish encrypts the messages and publishes them, in bulk if the wrapped pub/sub supports it.
+func (p *PubSub) BulkPublish(ctx context.Context, req *pubsub.BulkPublishRequest) (pubsub.BulkPublishResponse, error) {
+	encrypted := *req
+	if p.keys != nil {
+		encrypted.Entries = make([]pubsub.BulkMessageEntry, len(req.Entries))
+		for i, entry := range req.Entries {
+			var contentType *string
+			if entry.ContentType != "" {
+				contentType = ptr.Of(entry.ContentType)
+			}
+			data, err := p.encrypt(req.Topic, entry.Event, contentType)
+			if err != nil {
+				err = fmt.Errorf("failed to encrypt message %s on topic %s: %w", entry.EntryId, req.Topic, err)
+				return pubsub.NewBulkPublishResponse(req.Entries, pubsub.PublishFailed, err), err
+			}
+			entry.Event = data
+			entry.ContentType = ContentType
+			encrypted.Entries[i] = entry
+		}
+	}
+
+	if bp, ok := p.PubSub.(pubsub.BulkPublisher); ok {
+		return bp.BulkPublish(ctx, &encrypted)
+	}
+
+	// Publish the messages one by one, these are already encrypted
+	for i, entry := range encrypted.Entries {
+		err := p.PubSub.Publish(&pubsub.PublishRequest{
+			Data:        entry.Event,
+			PubsubName:  req.PubsubName,
+			Topic:       req.Topic,
+			Metadata:    entry.Metadata,
+			ContentType: &encrypted.Entries[i].ContentType,
+		})
+		if err != nil {
+			return pubsub.NewBulkPublishResponse(req.Entries, pubsub.PublishFailed, err), err
+		}
+	}
+
+	return pubsub.NewBulkPublishResponse(req.Entries, pubsub.PublishSucceeded, nil), nil
+}
+
+// Seek forwards the request to the wrapped pubsub; the replayed messages are decrypted with the keys configured then.
+func (p *PubSub) Seek(ctx context.Context, req pubsub.SeekRequest) error {
+	return pubsub.Seek(ctx, p.PubSub, req)
+}
+
+// Subscribe subscribes to a topic on the wrapped pub/sub, decrypting the messages before passing them to handler.
+// The messages which can't be decrypted are returned to the broker with an error.
+func (p *PubSub) Subscribe(ctx context.Context, req pubsub.SubscribeRequest, handler pubsub.Handler) error {
+	if p.keys == nil {
+		return p.PubSub.Subscribe(ctx, req, handler)
+	}
+
+	return p.PubSub.Subscribe(ctx, req, func(ctx context.Context, msg *pubsub.NewMessage) error {
+		var env envelope
+		if err := json.Unmarshal(msg.Data, &env); err != nil || env.Version == 0 {
+			if p.allowPlaintext {
+				return handler(ctx, msg)
+			}
+			return fmt.Errorf("failed to decrypt message on topic %s: the message is not encrypted", msg.Topic)
+		}
+
+		data, err := p.decrypt(msg.Topic, &env)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt message on topic %s: %w", msg.Topic, err)
+		}
+		msg.Data = data
+		msg.ContentType = env.ContentType
+
+		return handler(ctx, msg)
+	})
+}
+
+// encrypt returns the envelope of data, encrypted with a new data key.
+// The topic is authenticated with the payload, so that messages can't be replayed on other topics.
+func (p *PubSub) encrypt(topic string, data []byte, contentType *string) ([]byte, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+	dataCipher, err := aeadFromKey(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	encryptedData, err := seal(dataCipher, data, []byte(topic))
+	if err != nil {
+		return nil, err
+	}
+	encryptedKey, err := seal(p.keys[p.keyID], dataKey, []byte(p.keyID))
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(envelope{
+		Version:     envelopeVersion,
+		KeyID:       p.keyID,
+		Key:         encryptedKey,
+		Data:        encryptedData,
+		ContentType: contentType,
+	})
+}
+
+func (p *PubSub) decrypt(topic string, env *envelope) ([]byte, error) {
+	if env.Version != envelopeVersion {
+		return nil, fmt.Errorf("unsupported envelope version %d", env.Version)
+	}
+	keyCipher, ok := p.keys[env.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %s", env.KeyID)
+	}
+	dataKey, err := open(keyCipher, env.Key, []byte(env.KeyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the data key: %w", err)
+	}
+	dataCipher, err := aeadFromKey(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return open(dataCipher, env.Data, []byte(topic))
+}
+
+// newCipher returns the AES-GCM cipher of a base64-encoded key.
+func newCipher(encodedKey string) (cipher.AEAD, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+	if err != nil {
+		return nil, err
+	}
+
+	return aeadFromKey(key)
+}
+
+func aeadFromKey(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext with a random nonce, which prefixes the result.
+func seal(aead cipher.AEAD, plaintext []byte, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts the result of seal.
+func open(aead cipher.AEAD, ciphertext []byte, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
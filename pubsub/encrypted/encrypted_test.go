@@ -0,0 +1,227 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encrypted
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/pubsub"
+	pubsubInMemory "github.com/dapr/components-contrib/pubsub/in-memory"
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/ptr"
+)
+
+const (
+	key1 = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+	key2 = "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
+)
+
+func newPubSub(t *testing.T, props map[string]string) *PubSub {
+	t.Helper()
+
+	p := New(pubsubInMemory.New(logger.NewLogger("test")))
+	require.NoError(t, p.Init(pubsub.Metadata{Base: metadata.Base{Properties: props}}))
+	t.Cleanup(func() {
+		p.Close()
+	})
+
+	return p
+}
+
+// subscribe returns the channels of the messages received on the topic, as seen by the broker and by the subscriber.
+func subscribe(t *testing.T, p *PubSub, topic string) (chan []byte, chan *pubsub.NewMessage) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	raw := make(chan []byte, 10)
+	received := make(chan *pubsub.NewMessage, 10)
+	require.NoError(t, p.PubSub.Subscribe(ctx, pubsub.SubscribeRequest{Topic: topic}, func(_ context.Context, msg *pubsub.NewMessage) error {
+		raw <- msg.Data
+		return nil
+	}))
+	require.NoError(t, p.Subscribe(ctx, pubsub.SubscribeRequest{Topic: topic}, func(_ context.Context, msg *pubsub.NewMessage) error {
+		received <- msg
+		return nil
+	}))
+
+	return raw, received
+}
+
+func receive[T any](t *testing.T, ch chan T) T {
+	t.Helper()
+
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not received")
+	}
+
+	var zero T
+	return zero
+}
+
+func TestEncryptedPubSub(t *testing.T) {
+	p := newPubSub(t, map[string]string{KeyKey: key1, KeyIDKey: "k1"})
+	raw, received := subscribe(t, p, "orders")
+
+	t.Run("messages are published encrypted", func(t *testing.T) {
+		require.NoError(t, p.Publish(&pubsub.PublishRequest{
+			Topic:       "orders",
+			Data:        []byte(`{"card": "4111"}`),
+			ContentType: ptr.Of("application/json"),
+		}))
+
+		data := receive(t, raw)
+		assert.NotContains(t, string(data), "4111")
+		var env envelope
+		require.NoError(t, json.Unmarshal(data, &env))
+		assert.Equal(t, "k1", env.KeyID)
+
+		msg := receive(t, received)
+		assert.Equal(t, `{"card": "4111"}`, string(msg.Data))
+		assert.Equal(t, "application/json", *msg.ContentType)
+	})
+
+	t.Run("bulk publish", func(t *testing.T) {
+		res, err := p.BulkPublish(context.Background(), &pubsub.BulkPublishRequest{
+			Topic:   "orders",
+			Entries: []pubsub.BulkMessageEntry{{EntryId: "1", Event: []byte("secret")}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, pubsub.PublishSucceeded, res.Statuses[0].Status)
+
+		assert.NotContains(t, string(receive(t, raw)), "secret")
+		assert.Equal(t, "secret", string(receive(t, received).Data))
+	})
+
+	t.Run("envelopes are bound to their topic", func(t *testing.T) {
+		data, err := p.encrypt("payments", []byte("secret"), nil)
+		require.NoError(t, err)
+		var env envelope
+		require.NoError(t, json.Unmarshal(data, &env))
+
+		_, err = p.decrypt("orders", &env)
+		assert.Error(t, err)
+		decrypted, err := p.decrypt("payments", &env)
+		require.NoError(t, err)
+		assert.Equal(t, "secret", string(decrypted))
+	})
+}
+
+func TestKeyRotation(t *testing.T) {
+	old := newPubSub(t, map[string]string{KeyKey: key1, KeyIDKey: "k1"})
+	data, err := old.encrypt("orders", []byte("secret"), nil)
+	require.NoError(t, err)
+	var env envelope
+	require.NoError(t, json.Unmarshal(data, &env))
+
+	rotated := newPubSub(t, map[string]string{KeyKey: key2, KeyIDKey: "k2", PreviousKeysKey: "k1=" + key1})
+	decrypted, err := rotated.decrypt("orders", &env)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(decrypted))
+
+	withoutOld := newPubSub(t, map[string]string{KeyKey: key2, KeyIDKey: "k2"})
+	_, err = withoutOld.decrypt("orders", &env)
+	assert.ErrorContains(t, err, "unknown key k1")
+}
+
+func TestPlaintextMessages(t *testing.T) {
+	publish := func(p *PubSub) {
+		// Published on the wrapped pub/sub, so not encrypted
+		require.NoError(t, p.PubSub.Publish(&pubsub.PublishRequest{Topic: "orders", Data: []byte("plain")}))
+	}
+
+	t.Run("rejected by default", func(t *testing.T) {
+		p := newPubSub(t, map[string]string{KeyKey: key1})
+		raw, received := subscribe(t, p, "orders")
+		publish(p)
+
+		receive(t, raw)
+		select {
+		case <-received:
+			t.Fatal("plaintext message delivered")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("delivered if allowed", func(t *testing.T) {
+		p := newPubSub(t, map[string]string{KeyKey: key1, AllowPlaintextKey: "true"})
+		_, received := subscribe(t, p, "orders")
+		publish(p)
+
+		assert.Equal(t, "plain", string(receive(t, received).Data))
+	})
+}
+
+func TestInit(t *testing.T) {
+	t.Run("no encryption without key", func(t *testing.T) {
+		p := newPubSub(t, map[string]string{})
+		_, received := subscribe(t, p, "orders")
+		require.NoError(t, p.Publish(&pubsub.PublishRequest{Topic: "orders", Data: []byte("plain")}))
+
+		assert.Equal(t, "plain", string(receive(t, received).Data))
+	})
+
+	t.Run("invalid metadata", func(t *testing.T) {
+		tests := map[string]struct {
+			props map[string]string
+			err   string
+		}{
+			"key not base64": {
+				props: map[string]string{KeyKey: "not base64!"},
+				err:   "encryption error: invalid encryptionKey: illegal base64 data at input byte 3",
+			},
+			"invalid key size": {
+				props: map[string]string{KeyKey: "MDEyMzQ1Njc="},
+				err:   "encryption error: invalid encryptionKey: crypto/aes: invalid key size 8",
+			},
+			"invalid previous keys": {
+				props: map[string]string{KeyKey: key1, PreviousKeysKey: "k2"},
+				err:   "encryption error: invalid encryptionPreviousKeys, expected id=key pairs",
+			},
+			"invalid previous key": {
+				props: map[string]string{KeyKey: key1, PreviousKeysKey: "k2=MDEyMzQ1Njc="},
+				err:   "encryption error: invalid key k2 in encryptionPreviousKeys: crypto/aes: invalid key size 8",
+			},
+			"duplicate previous key": {
+				props: map[string]string{KeyKey: key1, KeyIDKey: "k1", PreviousKeysKey: "k1=" + key2},
+				err:   "encryption error: duplicate key k1",
+			},
+			"previous keys only": {
+				props: map[string]string{PreviousKeysKey: "k1=" + key1},
+				err:   "encryption error: encryptionPreviousKeys requires encryptionKey",
+			},
+			"invalid allowPlaintext": {
+				props: map[string]string{KeyKey: key1, AllowPlaintextKey: "maybe"},
+				err:   `encryption error: invalid encryptionAllowPlaintext maybe: strconv.ParseBool: parsing "maybe": invalid syntax`,
+			},
+		}
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				p := New(pubsubInMemory.New(logger.NewLogger("test")))
+				err := p.Init(pubsub.Metadata{Base: metadata.Base{Properties: tt.props}})
+				assert.EqualError(t, err, tt.err)
+			})
+		}
+	})
+}
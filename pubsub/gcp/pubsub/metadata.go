@@ -15,20 +15,27 @@ package pubsub
 
 // GCPPubSubMetaData pubsub metadata.
 type metadata struct {
-	consumerID              string
-	Type                    string
-	IdentityProjectID       string
-	ProjectID               string
-	PrivateKeyID            string
-	PrivateKey              string
-	ClientEmail             string
-	ClientID                string
-	AuthURI                 string
-	TokenURI                string
-	AuthProviderCertURL     string
-	ClientCertURL           string
-	DisableEntityManagement bool
-	EnableMessageOrdering   bool
-	MaxReconnectionAttempts int
-	ConnectionRecoveryInSec int
+	consumerID                string
+	Type                      string
+	IdentityProjectID         string
+	ProjectID                 string
+	PrivateKeyID              string
+	PrivateKey                string
+	ClientEmail               string
+	ClientID                  string
+	AuthURI                   string
+	TokenURI                  string
+	AuthProviderCertURL       string
+	ClientCertURL             string
+	DisableEntityManagement   bool
+	EnableMessageOrdering     bool
+	EnableExactlyOnceDelivery bool
+	AckDeadlineInSec          int
+	DeadLetterTopic           string
+	MaxDeliveryAttempts       int
+	MinRetryBackoffInSec      int
+	MaxRetryBackoffInSec      int
+	MaxReconnectionAttempts   int
+	ConnectionRecoveryInSec   int
+	KMSKeyName                string
 }
@@ -15,16 +15,16 @@ package pubsub
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 
 	gcppubsub "cloud.google.com/go/pubsub"
-	"google.golang.org/api/option"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	gcpauth "github.com/dapr/components-contrib/internal/authentication/gcp"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/kit/logger"
 )
@@ -47,12 +47,34 @@ const (
 	metadataPrivateKeyKey              = "privateKey"
 	metadataDisableEntityManagementKey = "disableEntityManagement"
 	metadataEnableMessageOrderingKey   = "enableMessageOrdering"
+	metadataEnableExactlyOnceKey       = "enableExactlyOnceDelivery"
+	metadataAckDeadlineInSecKey        = "ackDeadlineInSec"
+	metadataDeadLetterTopicKey         = "deadLetterTopic"
+	metadataMaxDeliveryAttemptsKey     = "maxDeliveryAttempts"
+	metadataMinRetryBackoffInSecKey    = "minRetryBackoffInSec"
+	metadataMaxRetryBackoffInSecKey    = "maxRetryBackoffInSec"
 	metadataMaxReconnectionAttemptsKey = "maxReconnectionAttempts"
 	metadataConnectionRecoveryInSecKey = "connectionRecoveryInSec"
+	metadataKMSKeyNameKey              = "kmsKeyName"
+
+	// Request metadata keys.
+	metadataOrderingKey = "orderingKey"
 
 	// Defaults.
 	defaultMaxReconnectionAttempts = 30
 	defaultConnectionRecoveryInSec = 2
+
+	// Bounds of the ack deadline of the subscriptions, in seconds.
+	minAckDeadlineInSec = 10
+	maxAckDeadlineInSec = 600
+
+	// Bounds of the delivery attempts before messages are forwarded to the dead-letter topic.
+	minDeliveryAttempts     = 5
+	maxDeliveryAttempts     = 100
+	defaultDeliveryAttempts = 5
+
+	// Upper bound of the retry backoffs, in seconds.
+	maxRetryBackoffInSec = 600
 )
 
 // GCPPubSub type.
@@ -62,28 +84,25 @@ type GCPPubSub struct {
 	logger        logger.Logger
 	publishCtx    context.Context
 	publishCancel context.CancelFunc
-}
 
-type GCPAuthJSON struct {
-	ProjectID           string `json:"project_id"`
-	PrivateKeyID        string `json:"private_key_id"`
-	PrivateKey          string `json:"private_key"`
-	ClientEmail         string `json:"client_email"`
-	ClientID            string `json:"client_id"`
-	AuthURI             string `json:"auth_uri"`
-	TokenURI            string `json:"token_uri"`
-	AuthProviderCertURL string `json:"auth_provider_x509_cert_url"`
-	ClientCertURL       string `json:"client_x509_cert_url"`
-	Type                string `json:"type"`
+	// Topics to publish to, reused so their messages are batched, and ordered by ordering key
+	topics     map[string]*gcppubsub.Topic
+	topicsLock sync.Mutex
 }
 
+// GCPAuthJSON is the service account key of the explicit credentials, shared with the other GCP components.
+type GCPAuthJSON = gcpauth.ServiceAccountKey
+
 type WhatNow struct {
 	Type string `json:"type"`
 }
 
 // NewGCPPubSub returns a new GCPPubSub instance.
 func NewGCPPubSub(logger logger.Logger) pubsub.PubSub {
-	return &GCPPubSub{logger: logger}
+	return &GCPPubSub{
+		logger: logger,
+		topics: map[string]*gcppubsub.Topic{},
+	}
 }
 
 func createMetadata(pubSubMetadata pubsub.Metadata) (*metadata, error) {
@@ -155,6 +174,66 @@ func createMetadata(pubSubMetadata pubsub.Metadata) (*metadata, error) {
 		}
 	}
 
+	if val, found := pubSubMetadata.Properties[metadataEnableExactlyOnceKey]; found && val != "" {
+		if boolVal, err := strconv.ParseBool(val); err == nil {
+			result.EnableExactlyOnceDelivery = boolVal
+		}
+	}
+
+	if val, found := pubSubMetadata.Properties[metadataAckDeadlineInSecKey]; found && val != "" {
+		var err error
+		result.AckDeadlineInSec, err = strconv.Atoi(val)
+		if err != nil {
+			return &result, fmt.Errorf("%s invalid ackDeadlineInSec %s, %s", errorMessagePrefix, val, err)
+		}
+		if result.AckDeadlineInSec < minAckDeadlineInSec || result.AckDeadlineInSec > maxAckDeadlineInSec {
+			return &result, fmt.Errorf("%s invalid ackDeadlineInSec %s, must be between %d and %d", errorMessagePrefix, val, minAckDeadlineInSec, maxAckDeadlineInSec)
+		}
+	}
+
+	if val, found := pubSubMetadata.Properties[metadataDeadLetterTopicKey]; found && val != "" {
+		result.DeadLetterTopic = val
+	}
+
+	if val, found := pubSubMetadata.Properties[metadataMaxDeliveryAttemptsKey]; found && val != "" {
+		var err error
+		result.MaxDeliveryAttempts, err = strconv.Atoi(val)
+		if err != nil {
+			return &result, fmt.Errorf("%s invalid maxDeliveryAttempts %s, %s", errorMessagePrefix, val, err)
+		}
+		if result.MaxDeliveryAttempts < minDeliveryAttempts || result.MaxDeliveryAttempts > maxDeliveryAttempts {
+			return &result, fmt.Errorf("%s invalid maxDeliveryAttempts %s, must be between %d and %d", errorMessagePrefix, val, minDeliveryAttempts, maxDeliveryAttempts)
+		}
+		if result.DeadLetterTopic == "" {
+			return &result, fmt.Errorf("%s maxDeliveryAttempts requires deadLetterTopic", errorMessagePrefix)
+		}
+	} else if result.DeadLetterTopic != "" {
+		result.MaxDeliveryAttempts = defaultDeliveryAttempts
+	}
+
+	for key, field := range map[string]*int{
+		metadataMinRetryBackoffInSecKey: &result.MinRetryBackoffInSec,
+		metadataMaxRetryBackoffInSecKey: &result.MaxRetryBackoffInSec,
+	} {
+		if val, found := pubSubMetadata.Properties[key]; found && val != "" {
+			var err error
+			*field, err = strconv.Atoi(val)
+			if err != nil {
+				return &result, fmt.Errorf("%s invalid %s %s, %s", errorMessagePrefix, key, val, err)
+			}
+			if *field < 0 || *field > maxRetryBackoffInSec {
+				return &result, fmt.Errorf("%s invalid %s %s, must be between 0 and %d", errorMessagePrefix, key, val, maxRetryBackoffInSec)
+			}
+		}
+	}
+	if result.MaxRetryBackoffInSec > 0 && result.MinRetryBackoffInSec > result.MaxRetryBackoffInSec {
+		return &result, fmt.Errorf("%s minRetryBackoffInSec must not be greater than maxRetryBackoffInSec", errorMessagePrefix)
+	}
+
+	if val, found := pubSubMetadata.Properties[metadataKMSKeyNameKey]; found && val != "" {
+		result.KMSKeyName = val
+	}
+
 	result.MaxReconnectionAttempts = defaultMaxReconnectionAttempts
 	if val, ok := pubSubMetadata.Properties[metadataMaxReconnectionAttemptsKey]; ok && val != "" {
 		var err error
@@ -197,39 +276,29 @@ func (g *GCPPubSub) Init(meta pubsub.Metadata) error {
 }
 
 func (g *GCPPubSub) getPubSubClient(ctx context.Context, metadata *metadata) (*gcppubsub.Client, error) {
-	var pubsubClient *gcppubsub.Client
-	var err error
-
-	if metadata.PrivateKeyID != "" {
-		// TODO: validate that all auth json fields are filled
-		authJSON := &GCPAuthJSON{
-			ProjectID:           metadata.IdentityProjectID,
-			PrivateKeyID:        metadata.PrivateKeyID,
-			PrivateKey:          metadata.PrivateKey,
-			ClientEmail:         metadata.ClientEmail,
-			ClientID:            metadata.ClientID,
-			AuthURI:             metadata.AuthURI,
-			TokenURI:            metadata.TokenURI,
-			AuthProviderCertURL: metadata.AuthProviderCertURL,
-			ClientCertURL:       metadata.ClientCertURL,
-			Type:                metadata.Type,
-		}
-		gcpCompatibleJSON, _ := json.Marshal(authJSON)
-		g.logger.Debugf("Using explicit credentials for GCP")
-		clientOptions := option.WithCredentialsJSON(gcpCompatibleJSON)
-		pubsubClient, err = gcppubsub.NewClient(ctx, metadata.ProjectID, clientOptions)
-		if err != nil {
-			return pubsubClient, err
-		}
-	} else {
+	key := gcpauth.ServiceAccountKey{
+		Type:                metadata.Type,
+		ProjectID:           metadata.IdentityProjectID,
+		PrivateKeyID:        metadata.PrivateKeyID,
+		PrivateKey:          metadata.PrivateKey,
+		ClientEmail:         metadata.ClientEmail,
+		ClientID:            metadata.ClientID,
+		AuthURI:             metadata.AuthURI,
+		TokenURI:            metadata.TokenURI,
+		AuthProviderCertURL: metadata.AuthProviderCertURL,
+		ClientCertURL:       metadata.ClientCertURL,
+	}
+	if gcpauth.UsesDefaultCredentials(key) {
 		g.logger.Debugf("Using implicit credentials for GCP")
-		pubsubClient, err = gcppubsub.NewClient(ctx, metadata.ProjectID)
-		if err != nil {
-			return pubsubClient, err
-		}
+	} else {
+		g.logger.Debugf("Using explicit credentials for GCP")
+	}
+	clientOptions, err := gcpauth.ClientOptions(key)
+	if err != nil {
+		return nil, err
 	}
 
-	return pubsubClient, nil
+	return gcppubsub.NewClient(ctx, metadata.ProjectID, clientOptions...)
 }
 
 // Publish the topic to GCP Pubsub.
@@ -243,9 +312,19 @@ func (g *GCPPubSub) Publish(req *pubsub.PublishRequest) error {
 
 	topic := g.getTopic(req.Topic)
 
-	_, err := topic.Publish(g.publishCtx, &gcppubsub.Message{
+	msg := &gcppubsub.Message{
 		Data: req.Data,
-	}).Get(g.publishCtx)
+	}
+	// Messages with the same ordering key are delivered in order to the subscriptions with message ordering enabled
+	if val, found := req.Metadata[metadataOrderingKey]; found && val != "" {
+		msg.OrderingKey = val
+	}
+
+	_, err := topic.Publish(g.publishCtx, msg).Get(g.publishCtx)
+	if err != nil && msg.OrderingKey != "" {
+		// The client pauses the publishing of the messages with the ordering key after an error, until resumed
+		topic.ResumePublish(msg.OrderingKey)
+	}
 
 	return err
 }
@@ -317,10 +396,24 @@ func (g *GCPPubSub) handleSubscriptionMessages(parentCtx context.Context, topic
 
 			err := handler(ctx, msg)
 
+			if !g.metadata.EnableExactlyOnceDelivery {
+				if err == nil {
+					m.Ack()
+				} else {
+					m.Nack()
+				}
+				return
+			}
+
+			// With exactly-once delivery, the message is redelivered if the ack fails
+			var res *gcppubsub.AckResult
 			if err == nil {
-				m.Ack()
+				res = m.AckWithResult()
 			} else {
-				m.Nack()
+				res = m.NackWithResult()
+			}
+			if _, ackErr := res.Get(ctx); ackErr != nil {
+				g.logger.Warnf("Failed to acknowledge message %s on subscription %s: %s", m.ID, sub.ID(), ackErr)
 			}
 		})
 
@@ -355,7 +448,10 @@ func (g *GCPPubSub) ensureTopic(parentCtx context.Context, topic string) error {
 	}
 
 	if !exists {
-		_, err = g.client.CreateTopic(parentCtx, topic)
+		// The messages of the topics created are encrypted with the Cloud KMS key, if any
+		_, err = g.client.CreateTopicWithConfig(parentCtx, topic, &gcppubsub.TopicConfig{
+			KMSKeyName: g.metadata.KMSKeyName,
+		})
 		if status.Code(err) == codes.AlreadyExists {
 			return nil
 		}
@@ -366,8 +462,19 @@ func (g *GCPPubSub) ensureTopic(parentCtx context.Context, topic string) error {
 	return nil
 }
 
+// getTopic returns the topic, created once with message ordering enabled.
 func (g *GCPPubSub) getTopic(topic string) *gcppubsub.Topic {
-	return g.client.Topic(topic)
+	g.topicsLock.Lock()
+	defer g.topicsLock.Unlock()
+
+	t, ok := g.topics[topic]
+	if !ok {
+		t = g.client.Topic(topic)
+		t.EnableMessageOrdering = true
+		g.topics[topic] = t
+	}
+
+	return t
 }
 
 func (g *GCPPubSub) ensureSubscription(parentCtx context.Context, subscription string, topic string) error {
@@ -380,20 +487,57 @@ func (g *GCPPubSub) ensureSubscription(parentCtx context.Context, subscription s
 	entity := g.getSubscription(managedSubscription)
 	exists, subErr := entity.Exists(parentCtx)
 	if !exists {
-		_, subErr = g.client.CreateSubscription(parentCtx, managedSubscription, gcppubsub.SubscriptionConfig{
-			Topic:                 g.getTopic(topic),
-			EnableMessageOrdering: g.metadata.EnableMessageOrdering,
-		})
+		config := gcppubsub.SubscriptionConfig{
+			Topic:                     g.getTopic(topic),
+			EnableMessageOrdering:     g.metadata.EnableMessageOrdering,
+			EnableExactlyOnceDelivery: g.metadata.EnableExactlyOnceDelivery,
+			AckDeadline:               time.Duration(g.metadata.AckDeadlineInSec) * time.Second,
+			RetryPolicy:               g.retryPolicy(),
+		}
+		if g.metadata.DeadLetterTopic != "" {
+			// Messages are forwarded to the dead-letter topic once they fail to be processed maxDeliveryAttempts times
+			err = g.ensureTopic(parentCtx, g.metadata.DeadLetterTopic)
+			if err != nil {
+				return fmt.Errorf("could not get valid dead-letter topic %s, %w", g.metadata.DeadLetterTopic, err)
+			}
+			config.DeadLetterPolicy = &gcppubsub.DeadLetterPolicy{
+				DeadLetterTopic:     g.getTopic(g.metadata.DeadLetterTopic).String(),
+				MaxDeliveryAttempts: g.metadata.MaxDeliveryAttempts,
+			}
+		}
+		_, subErr = g.client.CreateSubscription(parentCtx, managedSubscription, config)
 	}
 
 	return subErr
 }
 
+// retryPolicy returns the policy of the redeliveries of the messages which aren't acknowledged, if configured.
+func (g *GCPPubSub) retryPolicy() *gcppubsub.RetryPolicy {
+	if g.metadata.MinRetryBackoffInSec == 0 && g.metadata.MaxRetryBackoffInSec == 0 {
+		return nil
+	}
+
+	policy := &gcppubsub.RetryPolicy{
+		MinimumBackoff: time.Duration(g.metadata.MinRetryBackoffInSec) * time.Second,
+	}
+	if g.metadata.MaxRetryBackoffInSec > 0 {
+		policy.MaximumBackoff = time.Duration(g.metadata.MaxRetryBackoffInSec) * time.Second
+	}
+
+	return policy
+}
+
 func (g *GCPPubSub) getSubscription(subscription string) *gcppubsub.Subscription {
 	return g.client.Subscription(subscription)
 }
 
 func (g *GCPPubSub) Close() error {
+	g.topicsLock.Lock()
+	for _, t := range g.topics {
+		t.Stop()
+	}
+	g.topicsLock.Unlock()
+
 	g.publishCancel()
 	return g.client.Close()
 }
@@ -29,18 +29,25 @@ func TestInit(t *testing.T) {
 	t.Run("metadata is correct with explicit creds", func(t *testing.T) {
 		m := pubsub.Metadata{}
 		m.Properties = map[string]string{
-			"projectId":               "superproject",
-			"authProviderX509CertUrl": "https://authcerturl",
-			"authUri":                 "https://auth",
-			"clientX509CertUrl":       "https://cert",
-			"clientEmail":             "test@test.com",
-			"clientId":                "id",
-			"privateKey":              "****",
-			"privateKeyId":            "key_id",
-			"identityProjectId":       "project1",
-			"tokenUri":                "https://token",
-			"type":                    "serviceaccount",
-			"enableMessageOrdering":   "true",
+			"projectId":                 "superproject",
+			"authProviderX509CertUrl":   "https://authcerturl",
+			"authUri":                   "https://auth",
+			"clientX509CertUrl":         "https://cert",
+			"clientEmail":               "test@test.com",
+			"clientId":                  "id",
+			"privateKey":                "****",
+			"privateKeyId":              "key_id",
+			"identityProjectId":         "project1",
+			"tokenUri":                  "https://token",
+			"type":                      "serviceaccount",
+			"enableMessageOrdering":     "true",
+			"enableExactlyOnceDelivery": "true",
+			"ackDeadlineInSec":          "60",
+			"deadLetterTopic":           "poison",
+			"maxDeliveryAttempts":       "10",
+			"minRetryBackoffInSec":      "5",
+			"maxRetryBackoffInSec":      "120",
+			"kmsKeyName":                "projects/p/locations/l/keyRings/r/cryptoKeys/k",
 		}
 		b, err := createMetadata(m)
 		assert.Nil(t, err)
@@ -56,6 +63,13 @@ func TestInit(t *testing.T) {
 		assert.Equal(t, "https://token", b.TokenURI)
 		assert.Equal(t, "serviceaccount", b.Type)
 		assert.Equal(t, true, b.EnableMessageOrdering)
+		assert.Equal(t, true, b.EnableExactlyOnceDelivery)
+		assert.Equal(t, 60, b.AckDeadlineInSec)
+		assert.Equal(t, "poison", b.DeadLetterTopic)
+		assert.Equal(t, 10, b.MaxDeliveryAttempts)
+		assert.Equal(t, 5, b.MinRetryBackoffInSec)
+		assert.Equal(t, 120, b.MaxRetryBackoffInSec)
+		assert.Equal(t, "projects/p/locations/l/keyRings/r/cryptoKeys/k", b.KMSKeyName)
 	})
 
 	t.Run("metadata is correct with implicit creds", func(t *testing.T) {
@@ -69,6 +83,22 @@ func TestInit(t *testing.T) {
 
 		assert.Equal(t, "superproject", b.ProjectID)
 		assert.Equal(t, "service_account", b.Type)
+		assert.Equal(t, false, b.EnableExactlyOnceDelivery)
+		assert.Equal(t, 0, b.AckDeadlineInSec)
+		assert.Equal(t, 0, b.MaxDeliveryAttempts)
+	})
+
+	t.Run("default maxDeliveryAttempts with deadLetterTopic", func(t *testing.T) {
+		m := pubsub.Metadata{}
+		m.Properties = map[string]string{
+			"projectId":       "superproject",
+			"deadLetterTopic": "poison",
+		}
+
+		b, err := createMetadata(m)
+		assert.Nil(t, err)
+
+		assert.Equal(t, 5, b.MaxDeliveryAttempts)
 	})
 
 	t.Run("missing project id", func(t *testing.T) {
@@ -129,6 +159,50 @@ func TestInit(t *testing.T) {
 		assert.Error(t, err)
 		assertValidErrorMessage(t, err)
 	})
+
+	t.Run("invalid optional ackDeadlineInSec", func(t *testing.T) {
+		for _, val := range []string{invalidNumber, "5", "601"} {
+			m := pubsub.Metadata{}
+			m.Properties = map[string]string{
+				"projectId":                 "superproject",
+				metadataAckDeadlineInSecKey: val,
+			}
+
+			_, err := createMetadata(m)
+
+			assert.Error(t, err)
+			assertValidErrorMessage(t, err)
+		}
+	})
+
+	t.Run("invalid optional dead-letter and retry settings", func(t *testing.T) {
+		tests := map[string]map[string]string{
+			"maxDeliveryAttempts not a number":   {"deadLetterTopic": "poison", "maxDeliveryAttempts": invalidNumber},
+			"maxDeliveryAttempts too low":        {"deadLetterTopic": "poison", "maxDeliveryAttempts": "4"},
+			"maxDeliveryAttempts too high":       {"deadLetterTopic": "poison", "maxDeliveryAttempts": "101"},
+			"maxDeliveryAttempts without topic":  {"maxDeliveryAttempts": "10"},
+			"minRetryBackoffInSec not a number":  {"minRetryBackoffInSec": invalidNumber},
+			"maxRetryBackoffInSec too high":      {"maxRetryBackoffInSec": "601"},
+			"minRetryBackoffInSec above maximum": {"minRetryBackoffInSec": "60", "maxRetryBackoffInSec": "30"},
+			"negative minRetryBackoffInSec":      {"minRetryBackoffInSec": "-1"},
+		}
+		for name, props := range tests {
+			t.Run(name, func(t *testing.T) {
+				m := pubsub.Metadata{}
+				m.Properties = map[string]string{
+					"projectId": "superproject",
+				}
+				for k, v := range props {
+					m.Properties[k] = v
+				}
+
+				_, err := createMetadata(m)
+
+				assert.Error(t, err)
+				assertValidErrorMessage(t, err)
+			})
+		}
+	})
 }
 
 func assertValidErrorMessage(t *testing.T, err error) {
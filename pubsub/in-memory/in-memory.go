@@ -15,16 +15,34 @@ package inmemory
 
 import (
 	"context"
+	"fmt"
+	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/dapr/components-contrib/internal/eventbus"
+	mdata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/kit/logger"
 )
 
 type bus struct {
-	bus eventbus.Bus
-	log logger.Logger
+	bus      eventbus.Bus
+	log      logger.Logger
+	metadata metadata
+
+	rand     *rand.Rand
+	randLock sync.Mutex
+}
+
+// metadata configures the faults injected in the delivery of messages, to test the resilience of applications.
+type metadata struct {
+	// Probability for a message to be dropped, between 0 and 1
+	DropRate float64 `mapstructure:"dropRate"`
+	// Probability for a message to be delivered twice, between 0 and 1
+	DuplicateRate float64 `mapstructure:"duplicateRate"`
+	// Delay before a message is delivered
+	Delay time.Duration `mapstructure:"delay"`
 }
 
 func New(logger logger.Logger) pubsub.PubSub {
@@ -42,17 +60,75 @@ func (a *bus) Features() []pubsub.Feature {
 }
 
 func (a *bus) Init(metadata pubsub.Metadata) error {
+	m, err := parseMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	a.metadata = m
+	a.rand = rand.New(rand.NewSource(time.Now().UnixNano())) //nolint:gosec
 	a.bus = eventbus.New(true)
 
 	return nil
 }
 
+func parseMetadata(meta pubsub.Metadata) (metadata, error) {
+	var m metadata
+	if err := mdata.DecodeMetadata(meta.Properties, &m); err != nil {
+		return m, fmt.Errorf("in-memory pubsub error: %w", err)
+	}
+	if m.DropRate < 0 || m.DropRate > 1 {
+		return m, fmt.Errorf("in-memory pubsub error: dropRate must be between 0 and 1")
+	}
+	if m.DuplicateRate < 0 || m.DuplicateRate > 1 {
+		return m, fmt.Errorf("in-memory pubsub error: duplicateRate must be between 0 and 1")
+	}
+	if m.Delay < 0 {
+		return m, fmt.Errorf("in-memory pubsub error: delay must not be negative")
+	}
+
+	return m, nil
+}
+
 func (a *bus) Publish(req *pubsub.PublishRequest) error {
-	a.bus.Publish(req.Topic, req.Data)
+	if a.inject(a.metadata.DropRate) {
+		a.log.Debugf("in-memory pubsub: dropping message published to topic %s", req.Topic)
+
+		return nil
+	}
+
+	a.deliver(req.Topic, req.Data)
+	if a.inject(a.metadata.DuplicateRate) {
+		a.log.Debugf("in-memory pubsub: duplicating message published to topic %s", req.Topic)
+		a.deliver(req.Topic, req.Data)
+	}
 
 	return nil
 }
 
+func (a *bus) deliver(topic string, data []byte) {
+	if a.metadata.Delay > 0 {
+		time.AfterFunc(a.metadata.Delay, func() {
+			a.bus.Publish(topic, data)
+		})
+
+		return
+	}
+
+	a.bus.Publish(topic, data)
+}
+
+// inject returns true if a fault with the given probability happens.
+func (a *bus) inject(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+
+	a.randLock.Lock()
+	defer a.randLock.Unlock()
+
+	return a.rand.Float64() < rate
+}
+
 func (a *bus) Subscribe(ctx context.Context, req pubsub.SubscribeRequest, handler pubsub.Handler) error {
 	// For this component we allow built-in retries because it is backed by memory
 	retryHandler := func(data []byte) {
@@ -17,6 +17,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -107,3 +108,98 @@ func publish(ch chan []byte, msg *pubsub.NewMessage) error {
 
 	return nil
 }
+
+func TestParseMetadata(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		m, err := parseMetadata(pubsub.Metadata{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, metadata{}, m)
+	})
+
+	t.Run("fault injection", func(t *testing.T) {
+		meta := pubsub.Metadata{}
+		meta.Properties = map[string]string{
+			"dropRate":      "0.1",
+			"duplicateRate": "0.2",
+			"delay":         "50ms",
+		}
+
+		m, err := parseMetadata(meta)
+
+		assert.NoError(t, err)
+		assert.Equal(t, metadata{DropRate: 0.1, DuplicateRate: 0.2, Delay: 50 * time.Millisecond}, m)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, props := range []map[string]string{
+			{"dropRate": "1.5"},
+			{"duplicateRate": "-1"},
+			{"delay": "-1s"},
+			{"delay": "soon"},
+		} {
+			meta := pubsub.Metadata{}
+			meta.Properties = props
+
+			_, err := parseMetadata(meta)
+
+			assert.Error(t, err)
+		}
+	})
+}
+
+func TestFaultInjection(t *testing.T) {
+	subscribe := func(bus pubsub.PubSub) chan []byte {
+		ch := make(chan []byte, 10)
+		bus.Subscribe(context.Background(), pubsub.SubscribeRequest{Topic: "demo"}, func(ctx context.Context, msg *pubsub.NewMessage) error {
+			ch <- msg.Data
+
+			return nil
+		})
+
+		return ch
+	}
+
+	t.Run("drop", func(t *testing.T) {
+		bus := New(logger.NewLogger("test"))
+		meta := pubsub.Metadata{}
+		meta.Properties = map[string]string{"dropRate": "1"}
+		bus.Init(meta)
+		ch := subscribe(bus)
+
+		bus.Publish(&pubsub.PublishRequest{Data: []byte("ABCD"), Topic: "demo"})
+
+		select {
+		case <-ch:
+			t.Fatal("message should have been dropped")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		bus := New(logger.NewLogger("test"))
+		meta := pubsub.Metadata{}
+		meta.Properties = map[string]string{"duplicateRate": "1"}
+		bus.Init(meta)
+		ch := subscribe(bus)
+
+		bus.Publish(&pubsub.PublishRequest{Data: []byte("ABCD"), Topic: "demo"})
+
+		assert.Equal(t, "ABCD", string(<-ch))
+		assert.Equal(t, "ABCD", string(<-ch))
+	})
+
+	t.Run("delay", func(t *testing.T) {
+		bus := New(logger.NewLogger("test"))
+		meta := pubsub.Metadata{}
+		meta.Properties = map[string]string{"delay": "100ms"}
+		bus.Init(meta)
+		ch := subscribe(bus)
+
+		start := time.Now()
+		bus.Publish(&pubsub.PublishRequest{Data: []byte("ABCD"), Topic: "demo"})
+
+		assert.Equal(t, "ABCD", string(<-ch))
+		assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	})
+}
@@ -15,15 +15,22 @@ package jetstream
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/nats-io/nats.go"
-	"github.com/nats-io/nkeys"
 
+	"github.com/dapr/components-contrib/internal/logging"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/kit/logger"
 	"github.com/dapr/kit/retry"
 )
 
+// streamNameReplacer replaces the characters that are not allowed in stream names.
+var streamNameReplacer = strings.NewReplacer(".", "_", "*", "_", ">", "_", " ", "_", "/", "_", "\\", "_")
+
 type jetstreamPubSub struct {
 	nc   *nats.Conn
 	jsc  nats.JetStreamContext
@@ -31,13 +38,21 @@ type jetstreamPubSub struct {
 	meta metadata
 
 	backOffConfig retry.Config
+
+	// Streams of the topics, when provisioned by the component
+	streams     map[string]string
+	streamsLock sync.Mutex
 }
 
 func NewJetStream(logger logger.Logger) pubsub.PubSub {
-	return &jetstreamPubSub{l: logger}
+	return &jetstreamPubSub{
+		l:       logger,
+		streams: make(map[string]string),
+	}
 }
 
 func (js *jetstreamPubSub) Init(metadata pubsub.Metadata) error {
+	js.l = logging.ForComponent(js.l, "pubsub.jetstream", metadata.Name)
 	var err error
 	js.meta, err = parseMetadata(metadata)
 	if err != nil {
@@ -47,13 +62,10 @@ func (js *jetstreamPubSub) Init(metadata pubsub.Metadata) error {
 	var opts []nats.Option
 	opts = append(opts, nats.Name(js.meta.name))
 
-	// Set nats.UserJWT options when jwt and seed key is provided.
-	if js.meta.jwt != "" && js.meta.seedKey != "" {
-		opts = append(opts, nats.UserJWT(func() (string, error) {
-			return js.meta.jwt, nil
-		}, func(nonce []byte) ([]byte, error) {
-			return sigHandler(js.meta.seedKey, nonce)
-		}))
+	// Set the options of the JWT, creds file or NKey authentication when provided.
+	if opt := js.meta.credentials.Option(); opt != nil {
+		js.l.Debug("Configure nats for decentralized authentication")
+		opts = append(opts, opt)
 	} else if js.meta.tlsClientCert != "" && js.meta.tlsClientKey != "" {
 		js.l.Debug("Configure nats for tls client authentication")
 		opts = append(opts, nats.ClientCert(js.meta.tlsClientCert, js.meta.tlsClientKey))
@@ -109,6 +121,12 @@ func (js *jetstreamPubSub) Publish(req *pubsub.PublishRequest) error {
 		js.l.Warn("empty message ID, Jetstream deduplication will not be possible")
 	}
 
+	if js.meta.autoProvisionStream {
+		if _, err = js.ensureStream(req.Topic); err != nil {
+			return err
+		}
+	}
+
 	js.l.Debugf("Publishing to topic %v id: %s", req.Topic, msgID)
 	_, err = js.jsc.Publish(req.Topic, req.Data, opts...)
 
@@ -118,8 +136,6 @@ func (js *jetstreamPubSub) Publish(req *pubsub.PublishRequest) error {
 func (js *jetstreamPubSub) Subscribe(ctx context.Context, req pubsub.SubscribeRequest, handler pubsub.Handler) error {
 	var consumerConfig nats.ConsumerConfig
 
-	consumerConfig.DeliverSubject = nats.NewInbox()
-
 	if v := js.meta.durableName; v != "" {
 		consumerConfig.Durable = v
 	}
@@ -134,10 +150,6 @@ func (js *jetstreamPubSub) Subscribe(ctx context.Context, req pubsub.SubscribeRe
 		consumerConfig.DeliverPolicy = nats.DeliverLastPolicy
 	}
 
-	if js.meta.flowControl {
-		consumerConfig.FlowControl = true
-	}
-
 	if js.meta.ackWait != 0 {
 		consumerConfig.AckWait = js.meta.ackWait
 	}
@@ -156,11 +168,18 @@ func (js *jetstreamPubSub) Subscribe(ctx context.Context, req pubsub.SubscribeRe
 	if js.meta.memoryStorage {
 		consumerConfig.MemoryStorage = true
 	}
-	if js.meta.rateLimit != 0 {
-		consumerConfig.RateLimit = js.meta.rateLimit
-	}
-	if js.meta.hearbeat != 0 {
-		consumerConfig.Heartbeat = js.meta.hearbeat
+	if js.meta.consumerMode == pushConsumer {
+		// Pull consumers don't have a delivery subject, and the options below only apply to push consumers
+		consumerConfig.DeliverSubject = nats.NewInbox()
+		if js.meta.flowControl {
+			consumerConfig.FlowControl = true
+		}
+		if js.meta.rateLimit != 0 {
+			consumerConfig.RateLimit = js.meta.rateLimit
+		}
+		if js.meta.hearbeat != 0 {
+			consumerConfig.Heartbeat = js.meta.hearbeat
+		}
 	}
 	consumerConfig.FilterSubject = req.Topic
 
@@ -169,7 +188,7 @@ func (js *jetstreamPubSub) Subscribe(ctx context.Context, req pubsub.SubscribeRe
 		if err != nil {
 			// If we get an error, then we don't have a valid JetStream
 			// message.
-			js.l.Error(err)
+			js.l.Errorf("Error reading the metadata of JetStream message %s: %v", m.Subject, err)
 
 			return
 		}
@@ -199,13 +218,9 @@ func (js *jetstreamPubSub) Subscribe(ctx context.Context, req pubsub.SubscribeRe
 		}
 	}
 
-	var err error
-	streamName := js.meta.streamName
-	if streamName == "" {
-		streamName, err = js.jsc.StreamNameBySubject(req.Topic)
-		if err != nil {
-			return err
-		}
+	streamName, err := js.ensureStream(req.Topic)
+	if err != nil {
+		return err
 	}
 	var subscription *nats.Subscription
 
@@ -214,7 +229,14 @@ func (js *jetstreamPubSub) Subscribe(ctx context.Context, req pubsub.SubscribeRe
 		return err
 	}
 
-	if queue := js.meta.queueGroupName; queue != "" {
+	if js.meta.consumerMode == pullConsumer {
+		// Messages of pull consumers are balanced among the subscribers, so there is no need for queue groups
+		js.l.Debugf("nats: pull subscribed to subject %s", req.Topic)
+		subscription, err = js.jsc.PullSubscribe(req.Topic, "", nats.Bind(streamName, consumerInfo.Name))
+		if err == nil {
+			go js.fetchMessages(ctx, subscription, natsHandler)
+		}
+	} else if queue := js.meta.queueGroupName; queue != "" {
 		js.l.Debugf("nats: subscribed to subject %s with queue group %s",
 			req.Topic, js.meta.queueGroupName)
 		subscription, err = js.jsc.QueueSubscribe(req.Topic, queue, natsHandler, nats.Bind(streamName, consumerInfo.Name))
@@ -237,19 +259,93 @@ func (js *jetstreamPubSub) Subscribe(ctx context.Context, req pubsub.SubscribeRe
 	return nil
 }
 
-func (js *jetstreamPubSub) Close() error {
-	return js.nc.Drain()
+// fetchMessages pulls batches of messages for a pull consumer, until ctx is canceled.
+func (js *jetstreamPubSub) fetchMessages(ctx context.Context, subscription *nats.Subscription, handler nats.MsgHandler) {
+	for {
+		fetchCtx, cancel := context.WithTimeout(ctx, js.meta.pullMaxWait)
+		msgs, err := subscription.Fetch(js.meta.pullBatchSize, nats.Context(fetchCtx))
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			if errors.Is(err, nats.ErrBadSubscription) || errors.Is(err, nats.ErrConnectionClosed) {
+				js.l.Warnf("nats: stopped fetching messages for subject %s: %v", subscription.Subject, err)
+				return
+			}
+			if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, nats.ErrTimeout) {
+				js.l.Errorf("nats: error fetching messages for subject %s: %v", subscription.Subject, err)
+			}
+			continue
+		}
+
+		for _, m := range msgs {
+			handler(m)
+		}
+	}
 }
 
-// Handle nats signature request for challenge response authentication.
-func sigHandler(seedKey string, nonce []byte) ([]byte, error) {
-	kp, err := nkeys.FromSeed([]byte(seedKey))
+// ensureStream returns the name of the stream that stores the messages of topic.
+// If autoProvisionStream is enabled and no stream matches the topic, it is created, or added to the
+// subjects of the stream set in the metadata.
+func (js *jetstreamPubSub) ensureStream(topic string) (string, error) {
+	if !js.meta.autoProvisionStream {
+		if js.meta.streamName != "" {
+			return js.meta.streamName, nil
+		}
+		return js.jsc.StreamNameBySubject(topic)
+	}
+
+	js.streamsLock.Lock()
+	defer js.streamsLock.Unlock()
+
+	if name, ok := js.streams[topic]; ok {
+		return name, nil
+	}
+
+	name, err := js.jsc.StreamNameBySubject(topic)
+	switch {
+	case err == nil && (js.meta.streamName == "" || name == js.meta.streamName):
+		// The topic is already stored in a stream
+	case err != nil && !errors.Is(err, nats.ErrNoMatchingStream):
+		return "", err
+	case js.meta.streamName == "":
+		name = streamNameReplacer.Replace(topic)
+		js.l.Infof("nats: creating stream %s for subject %s", name, topic)
+		_, err = js.jsc.AddStream(js.streamConfig(name, topic))
+	default:
+		name = js.meta.streamName
+		var info *nats.StreamInfo
+		info, err = js.jsc.StreamInfo(name)
+		if errors.Is(err, nats.ErrStreamNotFound) {
+			js.l.Infof("nats: creating stream %s for subject %s", name, topic)
+			_, err = js.jsc.AddStream(js.streamConfig(name, topic))
+		} else if err == nil {
+			js.l.Infof("nats: adding subject %s to stream %s", topic, name)
+			cfg := info.Config
+			cfg.Subjects = append(cfg.Subjects, topic)
+			_, err = js.jsc.UpdateStream(&cfg)
+		}
+	}
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("nats: error provisioning stream for subject %s: %w", topic, err)
+	}
+
+	js.streams[topic] = name
+
+	return name, nil
+}
+
+func (js *jetstreamPubSub) streamConfig(name string, topic string) *nats.StreamConfig {
+	return &nats.StreamConfig{
+		Name:     name,
+		Subjects: []string{topic},
+		Storage:  js.meta.streamStorage,
+		Replicas: js.meta.streamReplicas,
+		MaxAge:   js.meta.streamMaxAge,
 	}
-	// Wipe our key on exit.
-	defer kp.Wipe()
+}
 
-	sig, _ := kp.Sign(nonce)
-	return sig, nil
+func (js *jetstreamPubSub) Close() error {
+	return js.nc.Drain()
 }
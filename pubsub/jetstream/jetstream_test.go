@@ -0,0 +1,75 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jetstream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mdata "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/kit/logger"
+)
+
+func TestPullConsumerWithStreamProvisioning(t *testing.T) {
+	opts := natsserver.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	s := natsserver.RunServer(&opts)
+	defer s.Shutdown()
+
+	ps := NewJetStream(logger.NewLogger("test"))
+	err := ps.Init(pubsub.Metadata{Base: mdata.Base{
+		Properties: map[string]string{
+			"natsURL":             s.ClientURL(),
+			"durableName":         "myDurable",
+			"consumerMode":        "pull",
+			"pullMaxWait":         "200ms",
+			"autoProvisionStream": "true",
+			"streamStorage":       "memory",
+		},
+	}})
+	require.NoError(t, err)
+	defer ps.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := make(chan *pubsub.NewMessage, 1)
+	err = ps.Subscribe(ctx, pubsub.SubscribeRequest{Topic: "orders.created"}, func(_ context.Context, msg *pubsub.NewMessage) error {
+		received <- msg
+		return nil
+	})
+	require.NoError(t, err)
+
+	js := ps.(*jetstreamPubSub)
+	info, err := js.jsc.StreamInfo("orders_created")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"orders.created"}, info.Config.Subjects)
+
+	err = ps.Publish(&pubsub.PublishRequest{Topic: "orders.created", Data: []byte("hello")})
+	require.NoError(t, err)
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, []byte("hello"), msg.Data)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the message")
+	}
+}
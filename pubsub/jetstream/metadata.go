@@ -19,15 +19,17 @@ import (
 	"strings"
 	"time"
 
+	"github.com/nats-io/nats.go"
+
+	natscomponent "github.com/dapr/components-contrib/internal/component/nats"
 	"github.com/dapr/components-contrib/pubsub"
 )
 
 type metadata struct {
 	natsURL string
 
-	jwt     string
-	seedKey string
-	token   string
+	credentials natscomponent.Credentials
+	token       string
 
 	tlsClientCert string
 	tlsClientKey  string
@@ -48,8 +50,27 @@ type metadata struct {
 	memoryStorage  bool
 	rateLimit      uint64
 	hearbeat       time.Duration
+
+	consumerMode  consumerMode
+	pullBatchSize int
+	pullMaxWait   time.Duration
+
+	autoProvisionStream bool
+	streamStorage       nats.StorageType
+	streamReplicas      int
+	streamMaxAge        time.Duration
 }
 
+type consumerMode string
+
+const (
+	pushConsumer consumerMode = "push"
+	pullConsumer consumerMode = "pull"
+
+	defaultPullBatchSize = 10
+	defaultPullMaxWait   = 5 * time.Second
+)
+
 func parseMetadata(psm pubsub.Metadata) (metadata, error) {
 	var m metadata
 
@@ -60,15 +81,10 @@ func parseMetadata(psm pubsub.Metadata) (metadata, error) {
 	}
 
 	m.token = psm.Properties["token"]
-	m.jwt = psm.Properties["jwt"]
-	m.seedKey = psm.Properties["seedKey"]
-
-	if m.jwt != "" && m.seedKey == "" {
-		return metadata{}, fmt.Errorf("missing seed key")
-	}
-
-	if m.jwt == "" && m.seedKey != "" {
-		return metadata{}, fmt.Errorf("missing jwt")
+	var err error
+	m.credentials, err = natscomponent.ParseCredentials(psm.Properties)
+	if err != nil {
+		return metadata{}, err
 	}
 
 	m.tlsClientCert = psm.Properties["tls_client_cert"]
@@ -146,5 +162,41 @@ func parseMetadata(psm pubsub.Metadata) (metadata, error) {
 
 	m.streamName = psm.Properties["streamName"]
 
+	switch v := consumerMode(psm.Properties["consumerMode"]); v {
+	case pushConsumer, pullConsumer:
+		m.consumerMode = v
+	case "":
+		m.consumerMode = pushConsumer
+	default:
+		return metadata{}, fmt.Errorf("invalid consumer mode %s, supported values are push and pull", v)
+	}
+
+	m.pullBatchSize = defaultPullBatchSize
+	if v, err := strconv.Atoi(psm.Properties["pullBatchSize"]); err == nil && v > 0 {
+		m.pullBatchSize = v
+	}
+	m.pullMaxWait = defaultPullMaxWait
+	if v, err := time.ParseDuration(psm.Properties["pullMaxWait"]); err == nil && v > 0 {
+		m.pullMaxWait = v
+	}
+
+	if v, err := strconv.ParseBool(psm.Properties["autoProvisionStream"]); err == nil {
+		m.autoProvisionStream = v
+	}
+	switch v := psm.Properties["streamStorage"]; v {
+	case "", "file":
+		m.streamStorage = nats.FileStorage
+	case "memory":
+		m.streamStorage = nats.MemoryStorage
+	default:
+		return metadata{}, fmt.Errorf("invalid stream storage %s, supported values are file and memory", v)
+	}
+	if v, err := strconv.Atoi(psm.Properties["streamReplicas"]); err == nil {
+		m.streamReplicas = v
+	}
+	if v, err := time.ParseDuration(psm.Properties["streamMaxAge"]); err == nil {
+		m.streamMaxAge = v
+	}
+
 	return m, nil
 }
@@ -18,6 +18,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	natscomponent "github.com/dapr/components-contrib/internal/component/nats"
 	mdata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
 )
@@ -68,6 +73,10 @@ func TestParseMetadata(t *testing.T) {
 				memoryStorage:  true,
 				rateLimit:      20000,
 				hearbeat:       time.Second * 1,
+				consumerMode:   pushConsumer,
+				pullBatchSize:  defaultPullBatchSize,
+				pullMaxWait:    defaultPullMaxWait,
+				streamStorage:  nats.FileStorage,
 			},
 			expectErr: false,
 		},
@@ -111,10 +120,67 @@ func TestParseMetadata(t *testing.T) {
 				memoryStorage:  true,
 				rateLimit:      20000,
 				hearbeat:       time.Second * 1,
+				consumerMode:   pushConsumer,
+				pullBatchSize:  defaultPullBatchSize,
+				pullMaxWait:    defaultPullMaxWait,
+				streamStorage:  nats.FileStorage,
 				token:          "myToken",
 			},
 			expectErr: false,
 		},
+		{
+			desc: "Valid Metadata with pull consumer and stream provisioning",
+			input: pubsub.Metadata{Base: mdata.Base{
+				Properties: map[string]string{
+					"natsURL":             "nats://localhost:4222",
+					"durableName":         "myDurable",
+					"consumerMode":        "pull",
+					"pullBatchSize":       "50",
+					"pullMaxWait":         "10s",
+					"autoProvisionStream": "true",
+					"streamName":          "myStream",
+					"streamStorage":       "memory",
+					"streamReplicas":      "3",
+					"streamMaxAge":        "24h",
+				},
+			}},
+			want: metadata{
+				natsURL:             "nats://localhost:4222",
+				name:                "dapr.io - pubsub.jetstream",
+				durableName:         "myDurable",
+				streamName:          "myStream",
+				consumerMode:        pullConsumer,
+				pullBatchSize:       50,
+				pullMaxWait:         10 * time.Second,
+				autoProvisionStream: true,
+				streamStorage:       nats.MemoryStorage,
+				streamReplicas:      3,
+				streamMaxAge:        24 * time.Hour,
+			},
+			expectErr: false,
+		},
+		{
+			desc: "Invalid metadata with unknown consumer mode",
+			input: pubsub.Metadata{Base: mdata.Base{
+				Properties: map[string]string{
+					"natsURL":      "nats://localhost:4222",
+					"consumerMode": "poll",
+				},
+			}},
+			want:      metadata{},
+			expectErr: true,
+		},
+		{
+			desc: "Invalid metadata with unknown stream storage",
+			input: pubsub.Metadata{Base: mdata.Base{
+				Properties: map[string]string{
+					"natsURL":       "nats://localhost:4222",
+					"streamStorage": "disk",
+				},
+			}},
+			want:      metadata{},
+			expectErr: true,
+		},
 		{
 			desc: "Invalid metadata with missing seed key",
 			input: pubsub.Metadata{Base: mdata.Base{
@@ -203,3 +269,17 @@ func TestParseMetadata(t *testing.T) {
 		})
 	}
 }
+
+func TestParseCredentials(t *testing.T) {
+	parse := func(properties map[string]string) (metadata, error) {
+		properties["natsURL"] = "nats://localhost:4222"
+		return parseMetadata(pubsub.Metadata{Base: mdata.Base{Properties: properties}})
+	}
+
+	m, err := parse(map[string]string{"credsFile": "/path/to/user.creds"})
+	require.NoError(t, err)
+	assert.Equal(t, natscomponent.Credentials{CredsFile: "/path/to/user.creds"}, m.credentials)
+
+	_, err = parse(map[string]string{"nkeySeed": "SUINVALID"})
+	assert.ErrorContains(t, err, "invalid nkey seed")
+}
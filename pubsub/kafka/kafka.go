@@ -15,6 +15,9 @@ package kafka
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/dapr/kit/logger"
 
@@ -24,6 +27,9 @@ import (
 	"github.com/dapr/components-contrib/pubsub"
 )
 
+// Metadata key of the seek requests for the partitions moved.
+const seekPartitionsKey = "partitions"
+
 type PubSub struct {
 	kafka           *kafka.Kafka
 	logger          logger.Logger
@@ -110,11 +116,48 @@ func (p *PubSub) BulkPublish(ctx context.Context, req *pubsub.BulkPublishRequest
 	return p.kafka.BulkPublish(ctx, req.Topic, req.Entries, req.Metadata)
 }
 
+// Seek moves the consumer group to the offset of the request, the offset of the next message consumed, or to the
+// timestamp, on all the partitions of the topic or on those of the "partitions" metadata, a comma-separated list.
+func (p *PubSub) Seek(ctx context.Context, req pubsub.SeekRequest) error {
+	partitions, offset, err := parseSeekRequest(req)
+	if err != nil {
+		return err
+	}
+
+	return p.kafka.Seek(req.Topic, partitions, offset, req.Timestamp)
+}
+
+func parseSeekRequest(req pubsub.SeekRequest) (partitions []int32, offset int64, err error) {
+	if req.Offset != "" {
+		offset, err = strconv.ParseInt(req.Offset, 10, 64)
+		if err != nil || offset < 0 {
+			return nil, 0, fmt.Errorf("kafka: invalid offset %s", req.Offset)
+		}
+	}
+
+	if val := req.Metadata[seekPartitionsKey]; val != "" {
+		for _, s := range strings.Split(val, ",") {
+			partition, err := strconv.ParseInt(strings.TrimSpace(s), 10, 32)
+			if err != nil || partition < 0 {
+				return nil, 0, fmt.Errorf("kafka: invalid partition %s", s)
+			}
+			partitions = append(partitions, int32(partition))
+		}
+	}
+
+	return partitions, offset, nil
+}
+
 func (p *PubSub) Close() (err error) {
 	p.subscribeCancel()
 	return p.kafka.Close()
 }
 
+// GetComponentMetadataSchema returns the schema of the metadata of the Kafka pubsub.
+func (p *PubSub) GetComponentMetadataSchema() []metadata.MetadataField {
+	return p.kafka.MetadataSchema()
+}
+
 func (p *PubSub) Features() []pubsub.Feature {
 	return nil
 }
@@ -0,0 +1,52 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/pubsub"
+)
+
+func TestParseSeekRequest(t *testing.T) {
+	t.Run("offset on partitions", func(t *testing.T) {
+		partitions, offset, err := parseSeekRequest(pubsub.SeekRequest{
+			Topic:    "orders",
+			Offset:   "1024",
+			Metadata: map[string]string{"partitions": "0, 2"},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(1024), offset)
+		assert.Equal(t, []int32{0, 2}, partitions)
+	})
+
+	t.Run("all partitions", func(t *testing.T) {
+		partitions, _, err := parseSeekRequest(pubsub.SeekRequest{Topic: "orders", Offset: "0"})
+
+		require.NoError(t, err)
+		assert.Nil(t, partitions)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		_, _, err := parseSeekRequest(pubsub.SeekRequest{Topic: "orders", Offset: "-1"})
+		assert.ErrorContains(t, err, "invalid offset")
+
+		_, _, err = parseSeekRequest(pubsub.SeekRequest{Topic: "orders", Offset: "1", Metadata: map[string]string{"partitions": "a"}})
+		assert.ErrorContains(t, err, "invalid partition")
+	})
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt
+
+import (
+	"context"
+	"time"
+)
+
+// mqttClient is the connection to the broker used by the pub/sub.
+// It is implemented for both MQTT 3.1.1 and MQTT 5.
+type mqttClient interface {
+	// Publish sends a message and waits until it's delivered according to its QoS.
+	Publish(ctx context.Context, msg *mqttMessage) error
+	// Subscribe subscribes to the given topics, with their QoS.
+	Subscribe(ctx context.Context, topics map[string]byte) error
+	// IsConnectionOpen returns true if the client is connected.
+	IsConnectionOpen() bool
+	// Disconnect closes the connection with the broker.
+	Disconnect()
+}
+
+// mqttMessage is a message sent or received by a mqttClient.
+type mqttMessage struct {
+	topic   string
+	payload []byte
+	qos     byte
+	retain  bool
+	// Duplicate flag; only available with MQTT 3.
+	duplicate *bool
+	messageID uint16
+	// User properties; only supported with MQTT 5.
+	properties map[string]string
+	// Message expiry interval; only supported with MQTT 5.
+	expiry *time.Duration
+	// Acknowledges a received message; nil with MQTT 5, where the client acknowledges the message once handled.
+	ack func()
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/dapr/kit/ptr"
+)
+
+// mqtt3Client is a mqttClient that speaks MQTT 3.1.1.
+type mqtt3Client struct {
+	client  mqtt.Client
+	handler func(*mqttMessage)
+}
+
+func (m *mqttPubSub) connectV3(ctx context.Context, uri *url.URL, clientID string, handler func(*mqttMessage)) (mqttClient, error) {
+	opts := m.createClientOptions(uri, clientID)
+	// Turn off auto-ack
+	opts.SetAutoAckDisabled(true)
+	// The last will is registered by the producer, whose connection lives as long as the component
+	if w := m.metadata.lastWill; w != nil && handler == nil {
+		opts.SetBinaryWill(w.topic, w.payload, w.qos, w.retain)
+	}
+	// Unless failing fast, the producer connects in the background and keeps retrying until the broker is reachable
+	lazy := handler == nil && !m.metadata.initOptions.FailFast
+	if lazy {
+		opts.SetConnectRetry(true)
+		opts.SetConnectRetryInterval(defaultConnectRetryDelay)
+	}
+	c := &mqtt3Client{
+		client:  mqtt.NewClient(opts),
+		handler: handler,
+	}
+
+	// Add all routes before we connect to catch messages that may be delivered before client.Subscribe is invoked
+	// The routes will be overwritten later
+	if handler != nil {
+		for topic := range m.topics {
+			c.client.AddRoute(topic, c.onMessage)
+		}
+	}
+
+	if lazy {
+		c.client.Connect()
+
+		return c, nil
+	}
+
+	token := c.client.Connect()
+	select {
+	case <-token.Done():
+		// Connection went through
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if err := token.Error(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *mqtt3Client) Publish(ctx context.Context, msg *mqttMessage) error {
+	token := c.client.Publish(msg.topic, msg.qos, msg.retain, msg.payload)
+	select {
+	case <-token.Done():
+		// Operation completed
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("mqtt timeout while publishing")
+		}
+		// Context canceled
+		return ctx.Err()
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("mqtt error from publish: %v", err)
+	}
+
+	return nil
+}
+
+func (c *mqtt3Client) Subscribe(ctx context.Context, topics map[string]byte) error {
+	token := c.client.SubscribeMultiple(topics, c.onMessage)
+	select {
+	case <-token.Done():
+		// Subscription went through
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("mqtt error from subscribe: %v", err)
+	}
+
+	return nil
+}
+
+func (c *mqtt3Client) IsConnectionOpen() bool {
+	return c.client.IsConnectionOpen()
+}
+
+func (c *mqtt3Client) Disconnect() {
+	c.client.Disconnect(5)
+}
+
+func (c *mqtt3Client) onMessage(_ mqtt.Client, msg mqtt.Message) {
+	c.handler(&mqttMessage{
+		topic:     msg.Topic(),
+		payload:   msg.Payload(),
+		qos:       msg.Qos(),
+		retain:    msg.Retained(),
+		duplicate: ptr.Of(msg.Duplicate()),
+		messageID: msg.MessageID(),
+		ack:       msg.Ack,
+	})
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+
+	"github.com/dapr/kit/logger"
+)
+
+// mqtt5Client is a mqttClient that speaks MQTT 5.
+// The connection is re-established automatically, and subscriptions are restored on reconnection.
+type mqtt5Client struct {
+	cm            *autopaho.ConnectionManager
+	handler       func(*mqttMessage)
+	subscriptions map[string]byte
+	lock          sync.Mutex
+	logger        logger.Logger
+}
+
+func (m *mqttPubSub) connectV5(ctx context.Context, uri *url.URL, clientID string, handler func(*mqttMessage)) (mqttClient, error) {
+	c := &mqtt5Client{
+		handler:       handler,
+		subscriptions: make(map[string]byte),
+		logger:        m.logger,
+	}
+
+	brokerURL := *uri
+	brokerURL.User = nil
+	cfg := autopaho.ClientConfig{
+		BrokerUrls:        []*url.URL{&brokerURL},
+		TlsCfg:            m.newTLSConfig(),
+		KeepAlive:         defaultKeepAlive,
+		ConnectRetryDelay: defaultConnectRetryDelay,
+		OnConnectionUp:    c.onConnectionUp,
+		OnConnectError: func(err error) {
+			m.logger.Warnf("mqtt error connecting to the broker: %v", err)
+		},
+		ClientConfig: paho.ClientConfig{
+			ClientID: clientID,
+			Router:   paho.NewSingleHandlerRouter(c.onPublish),
+			OnClientError: func(err error) {
+				m.logger.Warnf("mqtt client error, reconnecting: %v", err)
+			},
+			OnServerDisconnect: func(d *paho.Disconnect) {
+				m.logger.Warnf("mqtt server requested disconnect with reason code %d, reconnecting", d.ReasonCode)
+			},
+		},
+	}
+	if username := uri.User.Username(); username != "" {
+		password, _ := uri.User.Password()
+		cfg.SetUsernamePassword(username, []byte(password))
+	}
+	cleanSession := m.metadata.cleanSession
+	var will *paho.WillMessage
+	// The last will is registered by the producer, whose connection lives as long as the component
+	if w := m.metadata.lastWill; w != nil && handler == nil {
+		will = &paho.WillMessage{
+			Topic:   w.topic,
+			Payload: w.payload,
+			QoS:     w.qos,
+			Retain:  w.retain,
+		}
+	}
+	cfg.SetConnectPacketConfigurator(func(cp *paho.Connect) *paho.Connect {
+		cp.CleanStart = cleanSession
+		if !cleanSession {
+			// Keep the session on the broker after a disconnection, which is the MQTT 3.1.1 behavior
+			sessionExpiry := uint32(math.MaxUint32)
+			cp.Properties = &paho.ConnectProperties{SessionExpiryInterval: &sessionExpiry}
+		}
+		cp.WillMessage = will
+
+		return cp
+	})
+
+	// The connection manager lives until the component is closed
+	cm, err := autopaho.NewConnection(m.ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	c.cm = cm
+
+	// Unless failing fast, the producer keeps connecting in the background and publishing waits for the connection
+	if handler == nil && !m.metadata.initOptions.FailFast {
+		return c, nil
+	}
+
+	if err = cm.AwaitConnection(ctx); err != nil {
+		c.Disconnect()
+
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *mqtt5Client) Publish(ctx context.Context, msg *mqttMessage) error {
+	p := &paho.Publish{
+		Topic:   msg.topic,
+		QoS:     msg.qos,
+		Retain:  msg.retain,
+		Payload: msg.payload,
+	}
+	if len(msg.properties) > 0 || msg.expiry != nil {
+		p.Properties = &paho.PublishProperties{}
+		for k, v := range msg.properties {
+			p.Properties.User.Add(k, v)
+		}
+		if msg.expiry != nil {
+			expiry := messageExpiry(*msg.expiry)
+			p.Properties.MessageExpiry = &expiry
+		}
+	}
+
+	if err := c.cm.AwaitConnection(ctx); err != nil {
+		return fmt.Errorf("mqtt timeout while publishing: %v", err)
+	}
+	if _, err := c.cm.Publish(ctx, p); err != nil {
+		return fmt.Errorf("mqtt error from publish: %v", err)
+	}
+
+	return nil
+}
+
+func (c *mqtt5Client) Subscribe(ctx context.Context, topics map[string]byte) error {
+	c.lock.Lock()
+	for topic, qos := range topics {
+		c.subscriptions[topic] = qos
+	}
+	c.lock.Unlock()
+
+	if err := c.cm.AwaitConnection(ctx); err != nil {
+		return err
+	}
+
+	return c.subscribe(ctx, topics)
+}
+
+func (c *mqtt5Client) subscribe(ctx context.Context, topics map[string]byte) error {
+	s := &paho.Subscribe{
+		Subscriptions: make(map[string]paho.SubscribeOptions, len(topics)),
+	}
+	for topic, qos := range topics {
+		s.Subscriptions[topic] = paho.SubscribeOptions{QoS: qos}
+	}
+	if _, err := c.cm.Subscribe(ctx, s); err != nil {
+		return fmt.Errorf("mqtt error from subscribe: %v", err)
+	}
+
+	return nil
+}
+
+func (c *mqtt5Client) IsConnectionOpen() bool {
+	select {
+	case <-c.cm.Done():
+		return false
+	default:
+		return true
+	}
+}
+
+func (c *mqtt5Client) Disconnect() {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultWait)
+	defer cancel()
+	if err := c.cm.Disconnect(ctx); err != nil {
+		c.logger.Warnf("mqtt error disconnecting: %v", err)
+	}
+}
+
+// onConnectionUp restores the subscriptions when the connection is (re-)established.
+func (c *mqtt5Client) onConnectionUp(_ *autopaho.ConnectionManager, _ *paho.Connack) {
+	c.lock.Lock()
+	topics := make(map[string]byte, len(c.subscriptions))
+	for topic, qos := range c.subscriptions {
+		topics[topic] = qos
+	}
+	c.lock.Unlock()
+
+	if len(topics) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultWait)
+	defer cancel()
+	if err := c.subscribe(ctx, topics); err != nil {
+		c.logger.Errorf("mqtt error restoring subscriptions: %v", err)
+	}
+}
+
+// onPublish is invoked for each received message.
+// The message is acknowledged by the client once this returns, as the manual acknowledgements aren't supported by the
+// connection manager: the messages which fail to be handled and to be re-published are delivered at most once.
+func (c *mqtt5Client) onPublish(p *paho.Publish) {
+	if c.handler == nil {
+		return
+	}
+
+	msg := &mqttMessage{
+		topic:     p.Topic,
+		payload:   p.Payload,
+		qos:       p.QoS,
+		retain:    p.Retain,
+		messageID: p.PacketID,
+	}
+	if p.Properties != nil && len(p.Properties.User) > 0 {
+		msg.properties = make(map[string]string, len(p.Properties.User))
+		for _, prop := range p.Properties.User {
+			msg.properties[prop.Key] = prop.Value
+		}
+	}
+	c.handler(msg)
+}
+
+// messageExpiry returns the message expiry interval of a TTL, in seconds. The TTL is rounded up, so that a TTL under a
+// second doesn't expire the message at once, and capped to the largest interval.
+func messageExpiry(ttl time.Duration) uint32 {
+	seconds := math.Ceil(ttl.Seconds())
+	if seconds >= math.MaxUint32 {
+		return math.MaxUint32
+	}
+
+	return uint32(seconds)
+}
@@ -14,10 +14,13 @@ limitations under the License.
 package mqtt
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
+	contribMetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/kit/logger"
 )
@@ -31,6 +34,18 @@ type metadata struct {
 	retain                   bool
 	cleanSession             bool
 	maxRetriableErrorsPerSec int
+	protocolVersion          byte
+	sharedSubscriptionGroup  string
+	lastWill                 *lastWill
+	initOptions              contribMetadata.InitOptions
+}
+
+// lastWill is the message published by the broker when the producer disconnects ungracefully.
+type lastWill struct {
+	topic   string
+	payload []byte
+	qos     byte
+	retain  bool
 }
 
 type tlsCfg struct {
@@ -44,6 +59,8 @@ const (
 	mqttURL                      = "url"
 	mqttQOS                      = "qos"
 	mqttRetain                   = "retain"
+	mqttRetained                 = "retained"
+	mqttDuplicate                = "duplicate"
 	mqttConsumerID               = "consumerID"
 	mqttProducerID               = "producerID"
 	mqttCleanSession             = "cleanSession"
@@ -51,6 +68,12 @@ const (
 	mqttClientCert               = "clientCert"
 	mqttClientKey                = "clientKey"
 	mqttMaxRetriableErrorsPerSec = "maxRetriableErrorsPerSec"
+	mqttProtocolVersion          = "protocolVersion"
+	mqttSharedSubscriptionGroup  = "sharedSubscriptionGroup"
+	mqttWillTopic                = "willTopic"
+	mqttWillPayload              = "willPayload"
+	mqttWillQOS                  = "willQos"
+	mqttWillRetain               = "willRetain"
 
 	// Defaults
 	defaultQOS                      = 1
@@ -58,6 +81,12 @@ const (
 	defaultWait                     = 30 * time.Second
 	defaultCleanSession             = false
 	defaultMaxRetriableErrorsPerSec = 10
+	defaultKeepAlive                = 30
+	defaultConnectRetryDelay        = 5 * time.Second
+
+	// Protocol versions
+	protocolVersion311 = 4
+	protocolVersion5   = 5
 )
 
 func parseMQTTMetaData(md pubsub.Metadata, log logger.Logger) (*metadata, error) {
@@ -73,11 +102,11 @@ func parseMQTTMetaData(md pubsub.Metadata, log logger.Logger) (*metadata, error)
 	// optional configuration settings
 	m.qos = defaultQOS
 	if val, ok := md.Properties[mqttQOS]; ok && val != "" {
-		qosInt, err := strconv.Atoi(val)
+		var err error
+		m.qos, err = parseQOS(val)
 		if err != nil {
 			return &m, fmt.Errorf("%s invalid qos %s, %s", errorMsgPrefix, val, err)
 		}
-		m.qos = byte(qosInt)
 	}
 
 	m.retain = defaultRetain
@@ -118,6 +147,47 @@ func parseMQTTMetaData(md pubsub.Metadata, log logger.Logger) (*metadata, error)
 		}
 	}
 
+	m.protocolVersion = protocolVersion311
+	if val, ok := md.Properties[mqttProtocolVersion]; ok && val != "" {
+		switch val {
+		case "3.1.1", "4":
+			m.protocolVersion = protocolVersion311
+		case "5", "5.0":
+			m.protocolVersion = protocolVersion5
+		default:
+			return &m, fmt.Errorf("%s invalid protocolVersion %s, supported values are 3.1.1 and 5", errorMsgPrefix, val)
+		}
+	}
+
+	if val, ok := md.Properties[mqttSharedSubscriptionGroup]; ok && val != "" {
+		if strings.ContainsAny(val, "/+#") {
+			return &m, fmt.Errorf("%s invalid sharedSubscriptionGroup %s, it must not contain '/', '+' or '#'", errorMsgPrefix, val)
+		}
+		m.sharedSubscriptionGroup = val
+	}
+
+	if val, ok := md.Properties[mqttWillTopic]; ok && val != "" {
+		m.lastWill = &lastWill{
+			topic:   val,
+			payload: []byte(md.Properties[mqttWillPayload]),
+			qos:     m.qos,
+		}
+		if val, ok = md.Properties[mqttWillQOS]; ok && val != "" {
+			var err error
+			m.lastWill.qos, err = parseQOS(val)
+			if err != nil {
+				return &m, fmt.Errorf("%s invalid willQos %s, %s", errorMsgPrefix, val, err)
+			}
+		}
+		if val, ok = md.Properties[mqttWillRetain]; ok && val != "" {
+			var err error
+			m.lastWill.retain, err = strconv.ParseBool(val)
+			if err != nil {
+				return &m, fmt.Errorf("%s invalid willRetain %s, %s", errorMsgPrefix, val, err)
+			}
+		}
+	}
+
 	if val, ok := md.Properties[mqttCACert]; ok && val != "" {
 		if !isValidPEM(val) {
 			return &m, fmt.Errorf("%s invalid caCert", errorMsgPrefix)
@@ -137,6 +207,16 @@ func parseMQTTMetaData(md pubsub.Metadata, log logger.Logger) (*metadata, error)
 		m.tlsCfg.clientKey = val
 	}
 
+	// By default, Init fails if the broker is not reachable
+	var err error
+	m.initOptions, err = contribMetadata.GetInitOptions(md.Properties, contribMetadata.InitOptions{
+		FailFast: true,
+		Timeout:  defaultWait,
+	})
+	if err != nil {
+		return &m, fmt.Errorf("%s %v", errorMsgPrefix, err)
+	}
+
 	// Deprecated config option
 	// TODO: Remove in the future
 	if _, ok := md.Properties["backOffMaxRetries"]; ok {
@@ -145,3 +225,16 @@ func parseMQTTMetaData(md pubsub.Metadata, log logger.Logger) (*metadata, error)
 
 	return &m, nil
 }
+
+// parseQOS parses a QoS level, which must be 0, 1 or 2.
+func parseQOS(val string) (byte, error) {
+	qos, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, err
+	}
+	if qos < 0 || qos > 2 {
+		return 0, errors.New("supported values are 0, 1 and 2")
+	}
+
+	return byte(qos), nil
+}
@@ -30,6 +30,7 @@ import (
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"go.uber.org/ratelimit"
 
+	contribMetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/kit/logger"
 )
@@ -41,9 +42,10 @@ const (
 
 // mqttPubSub type allows sending and receiving data to/from MQTT broker.
 type mqttPubSub struct {
-	producer          mqtt.Client
-	consumer          mqtt.Client
+	producer          mqttClient
+	consumer          mqttClient
 	metadata          *metadata
+	topicMetadata     *pubsub.TopicMetadata[*metadata]
 	logger            logger.Logger
 	topics            map[string]mqttPubSubSubscription
 	retriableErrLimit ratelimit.Limiter
@@ -54,6 +56,7 @@ type mqttPubSub struct {
 
 type mqttPubSubSubscription struct {
 	handler pubsub.Handler
+	qos     byte
 	alias   string
 	matcher func(topic string) bool
 }
@@ -80,6 +83,10 @@ func (m *mqttPubSub) Init(metadata pubsub.Metadata) error {
 		return err
 	}
 	m.metadata = mqttMeta
+	m.topicMetadata, err = pubsub.NewTopicMetadata(metadata, mqttMeta, m.parseMetadata)
+	if err != nil {
+		return fmt.Errorf("%s %v", errorMsgPrefix, err)
+	}
 
 	if m.metadata.maxRetriableErrorsPerSec > 0 {
 		m.retriableErrLimit = ratelimit.New(m.metadata.maxRetriableErrorsPerSec)
@@ -95,8 +102,8 @@ func (m *mqttPubSub) Init(metadata pubsub.Metadata) error {
 		// for backwards-compatibility; see: https://github.com/dapr/components-contrib/pull/2104
 		producerClientID = m.metadata.consumerID + "-producer"
 	}
-	connCtx, connCancel := context.WithTimeout(m.ctx, defaultWait)
-	p, err := m.connect(connCtx, producerClientID)
+	connCtx, connCancel := context.WithTimeout(m.ctx, m.metadata.initOptions.Timeout)
+	p, err := m.connect(connCtx, producerClientID, nil)
 	connCancel()
 	if err != nil {
 		return err
@@ -110,6 +117,11 @@ func (m *mqttPubSub) Init(metadata pubsub.Metadata) error {
 	return nil
 }
 
+// parseMetadata parses the metadata of the component, or of a topic with the overrides of its properties.
+func (m *mqttPubSub) parseMetadata(md pubsub.Metadata) (*metadata, error) {
+	return parseMQTTMetaData(md, m.logger)
+}
+
 // Publish the topic to mqtt pub sub.
 func (m *mqttPubSub) Publish(req *pubsub.PublishRequest) error {
 	if req.Topic == "" {
@@ -120,27 +132,56 @@ func (m *mqttPubSub) Publish(req *pubsub.PublishRequest) error {
 	// m.logger.Debugf("mqtt publishing topic %s with data: %v", req.Topic, req.Data)
 	m.logger.Debugf("mqtt publishing topic %s", req.Topic)
 
-	token := m.producer.Publish(req.Topic, m.metadata.qos, m.metadata.retain, req.Data)
-	t := time.NewTimer(defaultWait)
-	defer func() {
-		if !t.Stop() {
-			<-t.C
+	md, err := m.topicMetadata.ForTopic(req.Topic)
+	if err != nil {
+		return err
+	}
+
+	msg := &mqttMessage{
+		topic:   req.Topic,
+		payload: req.Data,
+		qos:     md.qos,
+		retain:  md.retain,
+	}
+	// The QoS and retain flag of the component can be overridden for each message
+	if val, ok := req.Metadata[mqttQOS]; ok && val != "" {
+		qos, err := parseQOS(val)
+		if err != nil {
+			return fmt.Errorf("%s invalid qos %s, %s", errorMsgPrefix, val, err)
 		}
-	}()
-	select {
-	case <-token.Done():
-		// Operation completed
-	case <-m.ctx.Done():
-		// Context canceled
-		return m.ctx.Err()
-	case <-t.C:
-		return fmt.Errorf("mqtt timeout while publishing")
+		msg.qos = qos
 	}
-	if !token.WaitTimeout(defaultWait) || token.Error() != nil {
-		return fmt.Errorf("mqtt error from publish: %v", token.Error())
+	if val, ok := req.Metadata[mqttRetain]; ok && val != "" {
+		retain, err := strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("%s invalid retain %s, %s", errorMsgPrefix, val, err)
+		}
+		msg.retain = retain
+	}
+	if m.metadata.protocolVersion == protocolVersion5 {
+		ttl, ok, err := contribMetadata.TryGetTTL(req.Metadata)
+		if err != nil {
+			return fmt.Errorf("%s invalid ttl: %v", errorMsgPrefix, err)
+		}
+		if ok {
+			msg.expiry = &ttl
+		}
+		// All other request metadata is sent as user properties
+		for k, v := range req.Metadata {
+			if k == contribMetadata.TTLMetadataKey || k == mqttQOS || k == mqttRetain {
+				continue
+			}
+			if msg.properties == nil {
+				msg.properties = make(map[string]string, len(req.Metadata))
+			}
+			msg.properties[k] = v
+		}
 	}
 
-	return nil
+	ctx, cancel := context.WithTimeout(m.ctx, defaultWait)
+	defer cancel()
+
+	return m.producer.Publish(ctx, msg)
 }
 
 // Subscribe to the mqtt pub sub topic.
@@ -154,6 +195,17 @@ func (m *mqttPubSub) Subscribe(ctx context.Context, req pubsub.SubscribeRequest,
 		return errors.New("topic name is empty")
 	}
 
+	md, err := m.topicMetadata.ForTopic(req.Topic)
+	if err != nil {
+		return err
+	}
+
+	topic := req.Topic
+	if md.sharedSubscriptionGroup != "" && !strings.HasPrefix(topic, "$share/") {
+		// Competing consumers: the broker delivers each message to only one member of the group
+		topic = "$share/" + md.sharedSubscriptionGroup + "/" + topic
+	}
+
 	m.subscribingLock.Lock()
 	defer m.subscribingLock.Unlock()
 
@@ -161,7 +213,7 @@ func (m *mqttPubSub) Subscribe(ctx context.Context, req pubsub.SubscribeRequest,
 	m.resetSubscription()
 
 	// Add the topic then start the subscription
-	m.addTopic(req.Topic, handler)
+	m.addTopic(topic, md.qos, handler)
 	// Use the global context here to maintain the connection
 	m.startSubscription(m.ctx)
 
@@ -177,13 +229,13 @@ func (m *mqttPubSub) Subscribe(ctx context.Context, req pubsub.SubscribeRequest,
 		// If this is the last subscription or if the global context is done, close the connection entirely
 		if len(m.topics) <= 1 || m.ctx.Err() != nil {
 			m.closeSubscription()
-			delete(m.topics, req.Topic)
+			delete(m.topics, topic)
 			return
 		}
 
 		// Reconnect with one less topic
 		m.resetSubscription()
-		delete(m.topics, req.Topic)
+		delete(m.topics, topic)
 		m.startSubscription(m.ctx)
 	}()
 
@@ -191,7 +243,7 @@ func (m *mqttPubSub) Subscribe(ctx context.Context, req pubsub.SubscribeRequest,
 }
 
 func (m *mqttPubSub) closeSubscription() {
-	m.consumer.Disconnect(5)
+	m.consumer.Disconnect()
 	m.consumer = nil
 }
 
@@ -214,7 +266,7 @@ func (m *mqttPubSub) startSubscription(ctx context.Context) error {
 		consumerClientID += "-consumer"
 	}
 	connCtx, connCancel := context.WithTimeout(ctx, defaultWait)
-	c, err := m.connect(connCtx, consumerClientID)
+	c, err := m.connect(connCtx, consumerClientID, m.onMessage(ctx))
 	connCancel()
 	if err != nil {
 		return err
@@ -222,50 +274,45 @@ func (m *mqttPubSub) startSubscription(ctx context.Context) error {
 	m.consumer = c
 
 	subscribeTopics := make(map[string]byte, len(m.topics))
-	for k := range m.topics {
-		subscribeTopics[k] = m.metadata.qos
+	for k, sub := range m.topics {
+		subscribeTopics[k] = sub.qos
 	}
 
-	token := m.consumer.SubscribeMultiple(
-		subscribeTopics,
-		m.onMessage(ctx),
-	)
 	subscribeCtx, subscribeCancel := context.WithTimeout(m.ctx, defaultWait)
 	defer subscribeCancel()
-	select {
-	case <-token.Done():
-		// Subscription went through
-	case <-subscribeCtx.Done():
-		return subscribeCtx.Err()
-	}
-	if err := token.Error(); err != nil {
-		return fmt.Errorf("mqtt error from subscribe: %v", err)
-	}
 
-	return nil
+	return m.consumer.Subscribe(subscribeCtx, subscribeTopics)
 }
 
 // onMessage returns the callback to be invoked when there's a new message from a topic
-func (m *mqttPubSub) onMessage(ctx context.Context) func(client mqtt.Client, mqttMsg mqtt.Message) {
-	return func(client mqtt.Client, mqttMsg mqtt.Message) {
+func (m *mqttPubSub) onMessage(ctx context.Context) func(mqttMsg *mqttMessage) {
+	return func(mqttMsg *mqttMessage) {
 		ack := false
 		defer func() {
 			// MQTT does not support NACK's, so in case of error we need to re-enqueue the message and then send a positive ACK for this message
 			// Note that if the connection drops before the message is explicitly ACK'd below, then it's automatically re-sent (assuming QoS is 1 or greater, which is the default). So we do not risk losing messages.
 			// Problem with this approach is that if the service crashes between the time the message is re-enqueued and when the ACK is sent, the message may be delivered twice
+			// With MQTT 5, the client acknowledges the message once this returns, so the message is lost if it can't be re-enqueued
 			if !ack {
-				m.logger.Debugf("Re-publishing message %s#%d", mqttMsg.Topic(), mqttMsg.MessageID())
+				m.logger.Debugf("Re-publishing message %s#%d", mqttMsg.topic, mqttMsg.messageID)
 				publishErr := m.Publish(&pubsub.PublishRequest{
-					Topic: mqttMsg.Topic(),
-					Data:  mqttMsg.Payload(),
+					Topic:    mqttMsg.topic,
+					Data:     mqttMsg.payload,
+					Metadata: mqttMsg.properties,
 				})
 				if publishErr != nil {
-					m.logger.Errorf("Failed to re-publish message %s#%d. Error: %v", mqttMsg.Topic(), mqttMsg.MessageID(), publishErr)
+					if mqttMsg.ack == nil {
+						m.logger.Errorf("Failed to re-publish message %s#%d, the message is lost. Error: %v", mqttMsg.topic, mqttMsg.messageID, publishErr)
+						return
+					}
+					m.logger.Errorf("Failed to re-publish message %s#%d. Error: %v", mqttMsg.topic, mqttMsg.messageID, publishErr)
 					// Return so Ack() isn't invoked
 					return
 				}
 			}
-			mqttMsg.Ack()
+			if mqttMsg.ack != nil {
+				mqttMsg.ack()
+			}
 
 			// If we re-published the message, consume a retriable error token
 			if !ack {
@@ -277,9 +324,19 @@ func (m *mqttPubSub) onMessage(ctx context.Context) func(client mqtt.Client, mqt
 		}()
 
 		msg := pubsub.NewMessage{
-			Topic:    mqttMsg.Topic(),
-			Data:     mqttMsg.Payload(),
-			Metadata: map[string]string{"retained": strconv.FormatBool(mqttMsg.Retained())},
+			Topic:    mqttMsg.topic,
+			Data:     mqttMsg.payload,
+			Metadata: make(map[string]string, len(mqttMsg.properties)+3),
+		}
+		// User properties (MQTT 5 only) are mapped to the message metadata
+		for k, v := range mqttMsg.properties {
+			msg.Metadata[k] = v
+		}
+		msg.Metadata[mqttQOS] = strconv.Itoa(int(mqttMsg.qos))
+		msg.Metadata[mqttRetained] = strconv.FormatBool(mqttMsg.retain)
+		// The duplicate flag is only available with MQTT 3
+		if mqttMsg.duplicate != nil {
+			msg.Metadata[mqttDuplicate] = strconv.FormatBool(*mqttMsg.duplicate)
 		}
 
 		topicHandler := m.handlerForTopic(msg.Topic)
@@ -288,14 +345,14 @@ func (m *mqttPubSub) onMessage(ctx context.Context) func(client mqtt.Client, mqt
 			return
 		}
 
-		m.logger.Debugf("Processing MQTT message %s#%d (retained=%v)", mqttMsg.Topic(), mqttMsg.MessageID(), mqttMsg.Retained())
+		m.logger.Debugf("Processing MQTT message %s#%d (retained=%v)", mqttMsg.topic, mqttMsg.messageID, mqttMsg.retain)
 		err := topicHandler(ctx, &msg)
 		if err != nil {
-			m.logger.Errorf("Failed processing MQTT message %s#%d: %v", mqttMsg.Topic(), mqttMsg.MessageID(), err)
+			m.logger.Errorf("Failed processing MQTT message %s#%d: %v", mqttMsg.topic, mqttMsg.messageID, err)
 			return
 		}
 
-		m.logger.Debugf("Done processing MQTT message %s#%d; sending ACK", mqttMsg.Topic(), mqttMsg.MessageID())
+		m.logger.Debugf("Done processing MQTT message %s#%d; sending ACK", mqttMsg.topic, mqttMsg.messageID)
 		ack = true
 	}
 }
@@ -324,34 +381,18 @@ func (m *mqttPubSub) handlerForTopic(topic string) pubsub.Handler {
 	return nil
 }
 
-func (m *mqttPubSub) connect(ctx context.Context, clientID string) (mqtt.Client, error) {
+// connect creates a new client connected to the broker. Received messages are passed to handler, which can be nil for producers.
+func (m *mqttPubSub) connect(ctx context.Context, clientID string, handler func(*mqttMessage)) (mqttClient, error) {
 	uri, err := url.Parse(m.metadata.url)
 	if err != nil {
 		return nil, err
 	}
-	opts := m.createClientOptions(uri, clientID)
-	// Turn off auto-ack
-	opts.SetAutoAckDisabled(true)
-	client := mqtt.NewClient(opts)
-
-	// Add all routes before we connect to catch messages that may be delivered before client.Subscribe is invoked
-	// The routes will be overwritten later
-	for topic := range m.topics {
-		client.AddRoute(topic, m.onMessage(ctx))
-	}
 
-	token := client.Connect()
-	select {
-	case <-token.Done():
-		// Connection went through
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	}
-	if err := token.Error(); err != nil {
-		return nil, err
+	if m.metadata.protocolVersion == protocolVersion5 {
+		return m.connectV5(ctx, uri, clientID, handler)
 	}
 
-	return client, nil
+	return m.connectV3(ctx, uri, clientID, handler)
 }
 
 func (m *mqttPubSub) newTLSConfig() *tls.Config {
@@ -406,23 +447,28 @@ func (m *mqttPubSub) Close() error {
 	m.cancel()
 
 	if m.consumer != nil {
-		m.consumer.Disconnect(5)
+		m.consumer.Disconnect()
 	}
-	m.producer.Disconnect(5)
+	m.producer.Disconnect()
 
 	return nil
 }
 
 func (m *mqttPubSub) Features() []pubsub.Feature {
+	if m.metadata != nil && m.metadata.protocolVersion == protocolVersion5 {
+		return []pubsub.Feature{pubsub.FeatureSubscribeWildcards, pubsub.FeatureMessageTTL}
+	}
+
 	return []pubsub.Feature{pubsub.FeatureSubscribeWildcards}
 }
 
 var sharedSubscriptionMatch = regexp.MustCompile(`^\$share\/(.*?)\/.`)
 
 // Adds a topic to the list of subscriptions.
-func (m *mqttPubSub) addTopic(origTopicName string, handler pubsub.Handler) {
+func (m *mqttPubSub) addTopic(origTopicName string, qos byte, handler pubsub.Handler) {
 	obj := mqttPubSubSubscription{
 		handler: handler,
+		qos:     qos,
 	}
 
 	// Shared subscriptions begin with "$share/GROUPID/" and we can remove that prefix
@@ -14,17 +14,23 @@ limitations under the License.
 package mqtt
 
 import (
+	"context"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
+	"math"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/ratelimit"
 
 	mdata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/ptr"
 )
 
 func getFakeProperties() map[string]string {
@@ -52,6 +58,86 @@ func TestParseMetadata(t *testing.T) {
 		assert.Equal(t, byte(1), m.qos)
 		assert.Equal(t, true, m.retain)
 		assert.Equal(t, false, m.cleanSession)
+		assert.Equal(t, byte(protocolVersion311), m.protocolVersion)
+		assert.Equal(t, "", m.sharedSubscriptionGroup)
+	})
+
+	t.Run("protocol version 5 with shared subscriptions", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeMetaData := pubsub.Metadata{Base: mdata.Base{Properties: fakeProperties}}
+		fakeMetaData.Properties[mqttProtocolVersion] = "5"
+		fakeMetaData.Properties[mqttSharedSubscriptionGroup] = "group1"
+
+		m, err := parseMQTTMetaData(fakeMetaData, log)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, byte(protocolVersion5), m.protocolVersion)
+		assert.Equal(t, "group1", m.sharedSubscriptionGroup)
+	})
+
+	t.Run("invalid protocol version", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeMetaData := pubsub.Metadata{Base: mdata.Base{Properties: fakeProperties}}
+		fakeMetaData.Properties[mqttProtocolVersion] = "3.1"
+
+		_, err := parseMQTTMetaData(fakeMetaData, log)
+
+		// assert
+		assert.Contains(t, err.Error(), "invalid protocolVersion")
+	})
+
+	t.Run("invalid shared subscription group", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeMetaData := pubsub.Metadata{Base: mdata.Base{Properties: fakeProperties}}
+		fakeMetaData.Properties[mqttSharedSubscriptionGroup] = "group/1"
+
+		_, err := parseMQTTMetaData(fakeMetaData, log)
+
+		// assert
+		assert.Contains(t, err.Error(), "invalid sharedSubscriptionGroup")
+	})
+
+	t.Run("invalid qos", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeMetaData := pubsub.Metadata{Base: mdata.Base{Properties: fakeProperties}}
+		fakeMetaData.Properties[mqttQOS] = "3"
+
+		_, err := parseMQTTMetaData(fakeMetaData, log)
+
+		// assert
+		assert.Contains(t, err.Error(), "invalid qos")
+	})
+
+	t.Run("last will", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeMetaData := pubsub.Metadata{Base: mdata.Base{Properties: fakeProperties}}
+		fakeMetaData.Properties[mqttWillTopic] = "devices/a/status"
+		fakeMetaData.Properties[mqttWillPayload] = "offline"
+		fakeMetaData.Properties[mqttWillRetain] = "true"
+
+		m, err := parseMQTTMetaData(fakeMetaData, log)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, &lastWill{
+			topic:   "devices/a/status",
+			payload: []byte("offline"),
+			qos:     m.qos,
+			retain:  true,
+		}, m.lastWill)
+	})
+
+	t.Run("invalid last will qos", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeMetaData := pubsub.Metadata{Base: mdata.Base{Properties: fakeProperties}}
+		fakeMetaData.Properties[mqttWillTopic] = "devices/a/status"
+		fakeMetaData.Properties[mqttWillQOS] = "5"
+
+		_, err := parseMQTTMetaData(fakeMetaData, log)
+
+		// assert
+		assert.Contains(t, err.Error(), "invalid willQos")
 	})
 
 	t.Run("missing consumerID", func(t *testing.T) {
@@ -184,6 +270,195 @@ func TestParseMetadata(t *testing.T) {
 		assert.NoError(t, err)
 		assert.NotNil(t, m.tlsCfg.clientKey, "failed to parse valid client certificate key")
 	})
+
+	t.Run("init options", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+		fakeMetaData := pubsub.Metadata{Base: mdata.Base{Properties: fakeProperties}}
+
+		m, err := parseMQTTMetaData(fakeMetaData, log)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, mdata.InitOptions{FailFast: true, Timeout: defaultWait}, m.initOptions)
+
+		fakeMetaData.Properties[mdata.FailFastKey] = "false"
+		fakeMetaData.Properties[mdata.InitTimeoutKey] = "5s"
+		m, err = parseMQTTMetaData(fakeMetaData, log)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, mdata.InitOptions{FailFast: false, Timeout: 5 * time.Second}, m.initOptions)
+
+		fakeMetaData.Properties[mdata.InitTimeoutKey] = "-1s"
+		_, err = parseMQTTMetaData(fakeMetaData, log)
+
+		// assert
+		assert.ErrorContains(t, err, mdata.InitTimeoutKey)
+	})
+}
+
+func TestInitFailFast(t *testing.T) {
+	log := logger.NewLogger("test")
+	// Nothing listens on this port
+	props := func(protocolVersion string, failFast string) pubsub.Metadata {
+		return pubsub.Metadata{Base: mdata.Base{Properties: map[string]string{
+			mqttURL:              "tcp://127.0.0.1:1",
+			mqttConsumerID:       "client",
+			mqttProtocolVersion:  protocolVersion,
+			mdata.FailFastKey:    failFast,
+			mdata.InitTimeoutKey: "1s",
+		}}}
+	}
+
+	for _, v := range []string{"3.1.1", "5"} {
+		t.Run("MQTT "+v+" fail fast", func(t *testing.T) {
+			m := NewMQTTPubSub(log)
+
+			err := m.Init(props(v, "true"))
+
+			// assert
+			assert.Error(t, err)
+		})
+
+		t.Run("MQTT "+v+" lazy connection", func(t *testing.T) {
+			m := NewMQTTPubSub(log)
+
+			err := m.Init(props(v, "false"))
+
+			// assert
+			require.NoError(t, err)
+			m.Close()
+		})
+	}
+}
+
+func TestOnMessageUserProperties(t *testing.T) {
+	m := NewMQTTPubSub(logger.NewLogger("test")).(*mqttPubSub)
+	m.topics = make(map[string]mqttPubSubSubscription)
+
+	var received *pubsub.NewMessage
+	m.addTopic("$share/group1/mytopic", 0, func(ctx context.Context, msg *pubsub.NewMessage) error {
+		received = msg
+		return nil
+	})
+
+	acked := false
+	m.onMessage(context.Background())(&mqttMessage{
+		topic:      "mytopic",
+		payload:    []byte("hello world"),
+		qos:        1,
+		duplicate:  ptr.Of(true),
+		properties: map[string]string{"traceparent": "00-abc-def-01"},
+		ack:        func() { acked = true },
+	})
+
+	assert.True(t, acked)
+	if assert.NotNil(t, received) {
+		assert.Equal(t, "hello world", string(received.Data))
+		assert.Equal(t, map[string]string{"traceparent": "00-abc-def-01", "qos": "1", "retained": "false", "duplicate": "true"}, received.Metadata)
+	}
+}
+
+func TestOnMessageMQTT5(t *testing.T) {
+	md := pubsub.Metadata{Base: mdata.Base{Properties: getFakeProperties()}}
+	m := NewMQTTPubSub(logger.NewLogger("test")).(*mqttPubSub)
+	meta, err := parseMQTTMetaData(md, m.logger)
+	require.NoError(t, err)
+	m.metadata = meta
+	m.topicMetadata, err = pubsub.NewTopicMetadata(md, meta, m.parseMetadata)
+	require.NoError(t, err)
+	m.topics = make(map[string]mqttPubSubSubscription)
+	m.retriableErrLimit = ratelimit.NewUnlimited()
+	m.ctx = context.Background()
+
+	var received []*pubsub.NewMessage
+	m.addTopic("mytopic", 1, func(ctx context.Context, msg *pubsub.NewMessage) error {
+		received = append(received, msg)
+		return errors.New("failed")
+	})
+
+	t.Run("no duplicate flag", func(t *testing.T) {
+		producer := &fakeProducer{}
+		m.producer = producer
+
+		m.onMessage(context.Background())(&mqttMessage{topic: "mytopic", payload: []byte("hello"), qos: 1})
+
+		require.NotEmpty(t, received)
+		assert.Equal(t, map[string]string{"qos": "1", "retained": "false"}, received[len(received)-1].Metadata)
+		// The failed message is re-published
+		require.Len(t, producer.published, 1)
+		assert.Equal(t, []byte("hello"), producer.published[0].payload)
+	})
+
+	t.Run("at most once delivery when the message can't be re-published", func(t *testing.T) {
+		producer := &fakeProducer{err: errors.New("disconnected")}
+		m.producer = producer
+		handled := len(received)
+
+		// The message has no ack function: it is acknowledged by the client once this returns, and not delivered again
+		assert.NotPanics(t, func() {
+			m.onMessage(context.Background())(&mqttMessage{topic: "mytopic", payload: []byte("hello"), qos: 1})
+		})
+		assert.Len(t, received, handled+1)
+		assert.Empty(t, producer.published)
+	})
+}
+
+// fakeProducer is a mqttClient recording the messages published, or failing with err.
+type fakeProducer struct {
+	mqttClient
+
+	published []*mqttMessage
+	err       error
+}
+
+func (c *fakeProducer) Publish(ctx context.Context, msg *mqttMessage) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.published = append(c.published, msg)
+	return nil
+}
+
+func TestMessageExpiry(t *testing.T) {
+	assert.Equal(t, uint32(1), messageExpiry(time.Millisecond))
+	assert.Equal(t, uint32(60), messageExpiry(time.Minute))
+	assert.Equal(t, uint32(61), messageExpiry(time.Minute+time.Millisecond))
+	assert.Equal(t, uint32(math.MaxUint32), messageExpiry(math.MaxInt64))
+}
+
+func TestTopicOverrides(t *testing.T) {
+	md := pubsub.Metadata{Base: mdata.Base{Properties: getFakeProperties()}}
+	md.Properties[pubsub.TopicOverridesKey] = `[{"topic": "sensors/*", "metadata": {"qos": "0", "retain": "false"}}]`
+
+	t.Run("publish with the properties of the topic", func(t *testing.T) {
+		m := NewMQTTPubSub(logger.NewLogger("test")).(*mqttPubSub)
+		meta, err := parseMQTTMetaData(md, m.logger)
+		require.NoError(t, err)
+		m.metadata = meta
+		m.topicMetadata, err = pubsub.NewTopicMetadata(md, meta, m.parseMetadata)
+		require.NoError(t, err)
+		producer := &fakeProducer{}
+		m.producer = producer
+		m.ctx = context.Background()
+
+		require.NoError(t, m.Publish(&pubsub.PublishRequest{Topic: "sensors/temperature", Data: []byte("20")}))
+		require.NoError(t, m.Publish(&pubsub.PublishRequest{Topic: "alerts", Data: []byte("fire")}))
+
+		require.Len(t, producer.published, 2)
+		assert.Equal(t, byte(0), producer.published[0].qos)
+		assert.False(t, producer.published[0].retain)
+		assert.Equal(t, byte(1), producer.published[1].qos)
+		assert.True(t, producer.published[1].retain)
+	})
+
+	t.Run("invalid override", func(t *testing.T) {
+		invalid := pubsub.Metadata{Base: mdata.Base{Properties: getFakeProperties()}}
+		invalid.Properties[pubsub.TopicOverridesKey] = `[{"topic": "sensors/*", "metadata": {"qos": "3"}}]`
+
+		err := NewMQTTPubSub(logger.NewLogger("test")).Init(invalid)
+		assert.ErrorContains(t, err, "invalid topicOverrides for topic sensors/*")
+	})
 }
 
 func Test_buildRegexForTopic(t *testing.T) {
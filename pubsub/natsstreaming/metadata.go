@@ -16,6 +16,7 @@ package natsstreaming
 import (
 	"time"
 
+	natscomponent "github.com/dapr/components-contrib/internal/component/nats"
 	"github.com/dapr/components-contrib/pubsub"
 )
 
@@ -35,4 +36,5 @@ type metadata struct {
 	ackWaitTime             time.Duration
 	maxInFlight             uint64
 	concurrencyMode         pubsub.ConcurrencyMode
+	credentials             natscomponent.Credentials
 }
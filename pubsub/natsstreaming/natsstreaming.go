@@ -28,6 +28,7 @@ import (
 	stan "github.com/nats-io/stan.go"
 	"github.com/nats-io/stan.go/pb"
 
+	natscomponent "github.com/dapr/components-contrib/internal/component/nats"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/kit/logger"
 	"github.com/dapr/kit/retry"
@@ -94,6 +95,12 @@ func parseNATSStreamingMetadata(meta pubsub.Metadata) (metadata, error) {
 		return m, errors.New("nats-streaming error: missing nats streaming cluster ID")
 	}
 
+	credentials, err := natscomponent.ParseCredentials(meta.Properties)
+	if err != nil {
+		return m, fmt.Errorf("nats-streaming error: %s", err)
+	}
+	m.credentials = credentials
+
 	if val, ok := meta.Properties[subscriptionType]; ok {
 		if val == subscriptionTypeTopic || val == subscriptionTypeQueueGroup {
 			m.subscriptionType = val
@@ -195,6 +202,10 @@ func (n *natsStreamingPubSub) Init(metadata pubsub.Metadata) error {
 	n.metadata = m
 	clientID := genRandomString(20)
 	opts := []nats.Option{nats.Name(clientID)}
+	// Set the options of the JWT, creds file or NKey authentication when provided.
+	if opt := m.credentials.Option(); opt != nil {
+		opts = append(opts, opt)
+	}
 	natsConn, err := nats.Connect(m.natsURL, opts...)
 	if err != nil {
 		return fmt.Errorf("nats-streaming: error connecting to nats server at %s: %s", m.natsURL, err)
@@ -20,6 +20,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	natscomponent "github.com/dapr/components-contrib/internal/component/nats"
 	mdata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
 )
@@ -332,6 +333,28 @@ func TestParseNATSStreamingMetadata(t *testing.T) {
 	})
 }
 
+func TestParseNATSStreamingMetadataForCredentials(t *testing.T) {
+	properties := func(credentials map[string]string) pubsub.Metadata {
+		props := map[string]string{
+			natsURL:                "nats://foo.bar:4222",
+			natsStreamingClusterID: "testcluster",
+			consumerID:             "consumer1",
+		}
+		for k, v := range credentials {
+			props[k] = v
+		}
+
+		return pubsub.Metadata{Base: mdata.Base{Properties: props}}
+	}
+
+	m, err := parseNATSStreamingMetadata(properties(map[string]string{"credsFile": "/path/to/user.creds"}))
+	assert.NoError(t, err)
+	assert.Equal(t, natscomponent.Credentials{CredsFile: "/path/to/user.creds"}, m.credentials)
+
+	_, err = parseNATSStreamingMetadata(properties(map[string]string{"jwt": "eyJ0eXAiOiJKV1QifQ.e30.c2ln"}))
+	assert.EqualError(t, err, "nats-streaming error: missing seed key")
+}
+
 func TestSubscriptionOptionsForValidOptions(t *testing.T) {
 	type test struct {
 		name                    string
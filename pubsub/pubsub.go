@@ -45,6 +45,13 @@ type BulkSubscriber interface {
 	BulkSubscribe(ctx context.Context, req SubscribeRequest, bulkHandler BulkHandler) error
 }
 
+// Seeker is the interface of the message buses able to move the subscriptions of a topic back, to replay the
+// messages, or forward, to skip them.
+type Seeker interface {
+	// Seek moves the subscription of this component to the topic to the position of the request.
+	Seek(ctx context.Context, req SeekRequest) error
+}
+
 // Handler is the handler used to invoke the app handler.
 type Handler func(ctx context.Context, msg *NewMessage) error
 
@@ -63,6 +70,19 @@ type Handler func(ctx context.Context, msg *NewMessage) error
 // orderly fashion.
 type BulkHandler func(ctx context.Context, msg *BulkMessage) ([]BulkSubscribeResponseEntry, error)
 
+// Seek moves the subscription to the topic if the pubsub supports seeking.
+func Seek(ctx context.Context, pubsub PubSub, req SeekRequest) error {
+	pubsubWithSeek, ok := pubsub.(Seeker)
+	if !ok {
+		return fmt.Errorf("seek is not implemented by this pubsub")
+	}
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	return pubsubWithSeek.Seek(ctx, req)
+}
+
 func Ping(pubsub PubSub) error {
 	// checks if this pubsub has the ping option then executes
 	if pubsubWithPing, ok := pubsub.(health.Pinger); ok {
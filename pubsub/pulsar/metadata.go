@@ -28,4 +28,11 @@ type pulsarMetadata struct {
 	Persistent              bool          `json:"persistent"`
 	Token                   string        `json:"token"`
 	RedeliveryDelay         time.Duration `json:"redeliveryDelay"`
+	SubscriptionType        string        `json:"subscribeType"`
+	TLSTrustCertsFilePath   string        `json:"tlsTrustCertsFilePath"`
+	TLSValidateHostname     bool          `json:"tlsValidateHostname"`
+	TLSClientCert           string        `json:"tlsClientCert"`
+	TLSClientKey            string        `json:"tlsClientKey"`
+	MaxRedeliveryCount      uint32        `json:"maxRedeliveryCount"`
+	DeadLetterTopic         string        `json:"deadLetterTopic"`
 }
@@ -15,10 +15,13 @@ package pulsar
 
 import (
 	"context"
+	"crypto/tls"
+	b64 "encoding/base64"
 	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/apache/pulsar-client-go/pulsar"
@@ -42,6 +45,18 @@ const (
 	namespace               = "namespace"
 	persistent              = "persistent"
 	redeliveryDelay         = "redeliveryDelay"
+	subscribeType           = "subscribeType"
+	tlsTrustCertsFilePath   = "tlsTrustCertsFilePath"
+	tlsValidateHostname     = "tlsValidateHostname"
+	tlsClientCert           = "tlsClientCert"
+	tlsClientKey            = "tlsClientKey"
+	maxRedeliveryCount      = "maxRedeliveryCount"
+	deadLetterTopic         = "deadLetterTopic"
+
+	subscribeTypeShared    = "shared"
+	subscribeTypeFailover  = "failover"
+	subscribeTypeExclusive = "exclusive"
+	subscribeTypeKeyShared = "key_shared"
 
 	defaultTenant     = "public"
 	defaultNamespace  = "default"
@@ -62,6 +77,14 @@ const (
 	defaultMaxBatchSize = 128 * 1024
 	// defaultRedeliveryDelay init default for redelivery delay.
 	defaultRedeliveryDelay = 30 * time.Second
+	// defaultDeadLetterTopicFormat is the format of the dead letter topic if not set, with the topic and the consumer ID.
+	defaultDeadLetterTopicFormat = "%s-%s-DLQ"
+
+	// messageIDMetadataKey is the metadata key of the messages delivered for their ID, serialized in base64, which is
+	// an offset of the seek requests.
+	messageIDMetadataKey = "pulsar-message-id"
+	seekOffsetEarliest   = "earliest"
+	seekOffsetLatest     = "latest"
 )
 
 type Pulsar struct {
@@ -72,10 +95,17 @@ type Pulsar struct {
 	publishCtx    context.Context
 	publishCancel context.CancelFunc
 	cache         *lru.Cache
+
+	// Consumers of the subscriptions by topic, to seek.
+	consumers     map[string]pulsar.Consumer
+	consumersLock sync.Mutex
 }
 
 func NewPulsar(l logger.Logger) pubsub.PubSub {
-	return &Pulsar{logger: l}
+	return &Pulsar{
+		logger:    l,
+		consumers: map[string]pulsar.Consumer{},
+	}
 }
 
 func parsePulsarMetadata(meta pubsub.Metadata) (*pulsarMetadata, error) {
@@ -151,6 +181,37 @@ func parsePulsarMetadata(meta pubsub.Metadata) (*pulsarMetadata, error) {
 	if val, ok := meta.Properties[pulsarToken]; ok && val != "" {
 		m.Token = val
 	}
+	m.SubscriptionType = subscribeTypeShared
+	if val, ok := meta.Properties[subscribeType]; ok && val != "" {
+		if _, err := parseSubscriptionType(val); err != nil {
+			return nil, err
+		}
+		m.SubscriptionType = val
+	}
+	m.TLSTrustCertsFilePath = meta.Properties[tlsTrustCertsFilePath]
+	if val, ok := meta.Properties[tlsValidateHostname]; ok && val != "" {
+		validate, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, errors.New("pulsar error: invalid value for tlsValidateHostname")
+		}
+		m.TLSValidateHostname = validate
+	}
+	m.TLSClientCert = meta.Properties[tlsClientCert]
+	m.TLSClientKey = meta.Properties[tlsClientKey]
+	if (m.TLSClientCert == "") != (m.TLSClientKey == "") {
+		return nil, errors.New("pulsar error: tlsClientCert and tlsClientKey must be set together")
+	}
+	if m.Token != "" && m.TLSClientCert != "" {
+		return nil, errors.New("pulsar error: token and TLS client authentication cannot be used together")
+	}
+	if val, ok := meta.Properties[maxRedeliveryCount]; ok && val != "" {
+		count, err := strconv.ParseUint(val, 10, 32)
+		if err != nil {
+			return nil, errors.New("pulsar error: invalid value for maxRedeliveryCount")
+		}
+		m.MaxRedeliveryCount = uint32(count)
+	}
+	m.DeadLetterTopic = meta.Properties[deadLetterTopic]
 
 	return &m, nil
 }
@@ -171,8 +232,20 @@ func (p *Pulsar) Init(metadata pubsub.Metadata) error {
 		ConnectionTimeout:          30 * time.Second,
 		TLSAllowInsecureConnection: !m.EnableTLS,
 	}
+	if m.EnableTLS {
+		options.TLSTrustCertsFilePath = m.TLSTrustCertsFilePath
+		options.TLSValidateHostname = m.TLSValidateHostname
+	}
 	if m.Token != "" {
 		options.Authentication = pulsar.NewAuthenticationToken(m.Token)
+	} else if m.TLSClientCert != "" {
+		cert, err := tls.X509KeyPair([]byte(m.TLSClientCert), []byte(m.TLSClientKey))
+		if err != nil {
+			return fmt.Errorf("pulsar error: invalid TLS client certificate: %v", err)
+		}
+		options.Authentication = pulsar.NewAuthenticationFromTLSCertSupplier(func() (*tls.Certificate, error) {
+			return &cert, nil
+		})
 	}
 	client, err := pulsar.NewClient(options)
 	if err != nil {
@@ -265,13 +338,26 @@ func (p *Pulsar) Subscribe(ctx context.Context, req pubsub.SubscribeRequest, han
 	channel := make(chan pulsar.ConsumerMessage, 100)
 
 	topic := p.formatTopic(req.Topic)
+	// The subscription type was validated in Init
+	subscriptionType, _ := parseSubscriptionType(p.metadata.SubscriptionType)
 	options := pulsar.ConsumerOptions{
 		Topic:               topic,
 		SubscriptionName:    p.metadata.ConsumerID,
-		Type:                pulsar.Shared,
+		Type:                subscriptionType,
 		MessageChannel:      channel,
 		NackRedeliveryDelay: p.metadata.RedeliveryDelay,
 	}
+	if p.metadata.MaxRedeliveryCount > 0 {
+		// Messages negatively acknowledged too many times are moved to the dead letter topic
+		dlqTopic := p.metadata.DeadLetterTopic
+		if dlqTopic == "" {
+			dlqTopic = fmt.Sprintf(defaultDeadLetterTopicFormat, req.Topic, p.metadata.ConsumerID)
+		}
+		options.DLQ = &pulsar.DLQPolicy{
+			MaxDeliveries:   p.metadata.MaxRedeliveryCount,
+			DeadLetterTopic: p.formatTopic(dlqTopic),
+		}
+	}
 
 	consumer, err := p.client.Subscribe(options)
 	if err != nil {
@@ -279,13 +365,67 @@ func (p *Pulsar) Subscribe(ctx context.Context, req pubsub.SubscribeRequest, han
 		return err
 	}
 
+	p.consumersLock.Lock()
+	p.consumers[req.Topic] = consumer
+	p.consumersLock.Unlock()
+
 	go p.listenMessage(ctx, req.Topic, consumer, handler)
 
 	return nil
 }
 
+// Seek resets the subscription to the topic to the message of the offset, which is earliest, latest or the ID of a
+// message from its pulsar-message-id metadata, or to the first message published at or after the timestamp.
+// Like with the Pulsar clients, the partitioned topics can't be reset.
+func (p *Pulsar) Seek(ctx context.Context, req pubsub.SeekRequest) error {
+	p.consumersLock.Lock()
+	consumer, ok := p.consumers[req.Topic]
+	p.consumersLock.Unlock()
+	if !ok {
+		return fmt.Errorf("no subscription to topic %s to seek", req.Topic)
+	}
+
+	if req.Timestamp != nil {
+		return consumer.SeekByTime(*req.Timestamp)
+	}
+
+	id, err := parseMessageID(req.Offset)
+	if err != nil {
+		return err
+	}
+
+	return consumer.Seek(id)
+}
+
+func parseMessageID(offset string) (pulsar.MessageID, error) {
+	switch strings.ToLower(offset) {
+	case seekOffsetEarliest:
+		return pulsar.EarliestMessageID(), nil
+	case seekOffsetLatest:
+		return pulsar.LatestMessageID(), nil
+	}
+
+	data, err := b64.StdEncoding.DecodeString(offset)
+	if err != nil {
+		return nil, fmt.Errorf("invalid offset %s: it must be earliest, latest or a message ID in base64", offset)
+	}
+	id, err := pulsar.DeserializeMessageID(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message ID %s: %w", offset, err)
+	}
+
+	return id, nil
+}
+
 func (p *Pulsar) listenMessage(ctx context.Context, originTopic string, consumer pulsar.Consumer, handler pubsub.Handler) {
-	defer consumer.Close()
+	defer func() {
+		p.consumersLock.Lock()
+		if p.consumers[originTopic] == consumer {
+			delete(p.consumers, originTopic)
+		}
+		p.consumersLock.Unlock()
+		consumer.Close()
+	}()
 
 	var err error
 	for {
@@ -304,10 +444,15 @@ func (p *Pulsar) listenMessage(ctx context.Context, originTopic string, consumer
 }
 
 func (p *Pulsar) handleMessage(ctx context.Context, originTopic string, msg pulsar.ConsumerMessage, handler pubsub.Handler) error {
+	metadata := make(map[string]string, len(msg.Properties())+1)
+	for k, v := range msg.Properties() {
+		metadata[k] = v
+	}
+	metadata[messageIDMetadataKey] = b64.StdEncoding.EncodeToString(msg.ID().Serialize())
 	pubsubMsg := pubsub.NewMessage{
 		Data:     msg.Payload(),
 		Topic:    originTopic,
-		Metadata: msg.Properties(),
+		Metadata: metadata,
 	}
 
 	p.logger.Debugf("Processing Pulsar message %s/%#v", msg.Topic(), msg.ID())
@@ -348,6 +493,21 @@ func (p *Pulsar) formatTopic(topic string) string {
 	return fmt.Sprintf(topicFormat, persist, p.metadata.Tenant, p.metadata.Namespace, topic)
 }
 
+func parseSubscriptionType(val string) (pulsar.SubscriptionType, error) {
+	switch strings.ToLower(val) {
+	case subscribeTypeShared:
+		return pulsar.Shared, nil
+	case subscribeTypeFailover:
+		return pulsar.Failover, nil
+	case subscribeTypeExclusive:
+		return pulsar.Exclusive, nil
+	case subscribeTypeKeyShared:
+		return pulsar.KeyShared, nil
+	default:
+		return pulsar.Shared, fmt.Errorf("pulsar error: invalid value for subscribeType: %s", val)
+	}
+}
+
 func formatDuration(durationString string) (time.Duration, error) {
 	if val, err := strconv.Atoi(durationString); err == nil {
 		return time.Duration(val) * time.Millisecond, nil
@@ -14,12 +14,17 @@ limitations under the License.
 package pulsar
 
 import (
+	"context"
+	b64 "encoding/base64"
 	"testing"
 	"time"
 
+	"github.com/apache/pulsar-client-go/pulsar"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/kit/logger"
 )
 
 func TestParsePulsarMetadata(t *testing.T) {
@@ -45,6 +50,70 @@ func TestParsePulsarMetadata(t *testing.T) {
 	assert.Equal(t, uint(200), meta.BatchingMaxMessages)
 }
 
+func TestParsePulsarMetadataSubscriptionAndTLS(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		m := pubsub.Metadata{}
+		m.Properties = map[string]string{"host": "a"}
+		meta, err := parsePulsarMetadata(m)
+
+		assert.Nil(t, err)
+		assert.Equal(t, subscribeTypeShared, meta.SubscriptionType)
+		assert.Equal(t, uint32(0), meta.MaxRedeliveryCount)
+	})
+
+	t.Run("all set", func(t *testing.T) {
+		m := pubsub.Metadata{}
+		m.Properties = map[string]string{
+			"host":                  "a",
+			"enableTLS":             "true",
+			"subscribeType":         "failover",
+			"tlsTrustCertsFilePath": "/certs/ca.pem",
+			"tlsValidateHostname":   "true",
+			"tlsClientCert":         "cert",
+			"tlsClientKey":          "key",
+			"maxRedeliveryCount":    "5",
+			"deadLetterTopic":       "dead",
+		}
+		meta, err := parsePulsarMetadata(m)
+
+		assert.Nil(t, err)
+		assert.Equal(t, subscribeTypeFailover, meta.SubscriptionType)
+		assert.Equal(t, "/certs/ca.pem", meta.TLSTrustCertsFilePath)
+		assert.Equal(t, true, meta.TLSValidateHostname)
+		assert.Equal(t, "cert", meta.TLSClientCert)
+		assert.Equal(t, "key", meta.TLSClientKey)
+		assert.Equal(t, uint32(5), meta.MaxRedeliveryCount)
+		assert.Equal(t, "dead", meta.DeadLetterTopic)
+	})
+
+	t.Run("invalid subscription type", func(t *testing.T) {
+		m := pubsub.Metadata{}
+		m.Properties = map[string]string{"host": "a", "subscribeType": "broadcast"}
+		meta, err := parsePulsarMetadata(m)
+
+		assert.Error(t, err)
+		assert.Nil(t, meta)
+	})
+
+	t.Run("client certificate without key", func(t *testing.T) {
+		m := pubsub.Metadata{}
+		m.Properties = map[string]string{"host": "a", "tlsClientCert": "cert"}
+		meta, err := parsePulsarMetadata(m)
+
+		assert.Error(t, err)
+		assert.Nil(t, meta)
+	})
+
+	t.Run("token and client certificate", func(t *testing.T) {
+		m := pubsub.Metadata{}
+		m.Properties = map[string]string{"host": "a", "token": "t", "tlsClientCert": "cert", "tlsClientKey": "key"}
+		meta, err := parsePulsarMetadata(m)
+
+		assert.Error(t, err)
+		assert.Nil(t, meta)
+	})
+}
+
 func TestParsePublishMetadata(t *testing.T) {
 	m := &pubsub.PublishRequest{}
 	m.Metadata = map[string]string{
@@ -120,3 +189,40 @@ func TestValidTenantAndNS(t *testing.T) {
 		assert.Equal(t, expectNonPersistentResult, res)
 	})
 }
+
+func TestParseMessageID(t *testing.T) {
+	t.Run("earliest and latest", func(t *testing.T) {
+		id, err := parseMessageID("earliest")
+		require.NoError(t, err)
+		assert.Equal(t, pulsar.EarliestMessageID(), id)
+
+		id, err = parseMessageID("Latest")
+		require.NoError(t, err)
+		assert.Equal(t, pulsar.LatestMessageID(), id)
+	})
+
+	t.Run("serialized message ID", func(t *testing.T) {
+		serialized := pulsar.EarliestMessageID().Serialize()
+
+		id, err := parseMessageID(b64.StdEncoding.EncodeToString(serialized))
+
+		require.NoError(t, err)
+		assert.Equal(t, serialized, id.Serialize())
+	})
+
+	t.Run("invalid offsets", func(t *testing.T) {
+		_, err := parseMessageID("first")
+		assert.ErrorContains(t, err, "invalid offset first")
+
+		_, err = parseMessageID("bm90IGFuIGlk")
+		assert.ErrorContains(t, err, "invalid message ID")
+	})
+}
+
+func TestSeekRequiresSubscription(t *testing.T) {
+	p := NewPulsar(logger.NewLogger("test"))
+
+	err := pubsub.Seek(context.Background(), p, pubsub.SeekRequest{Topic: "orders", Offset: "earliest"})
+
+	assert.ErrorContains(t, err, "no subscription to topic orders")
+}
@@ -0,0 +1,342 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package quarantine provides a decorator for pub/subs that moves the messages which the subscribers keep failing to
+// handle into a state store, with the details of the failure. The quarantined messages can be listed, inspected and
+// requeued on their topic without any tooling of the broker.
+package quarantine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/google/uuid"
+
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/retry"
+)
+
+const (
+	// KeyPrefixKey is the metadata key for the prefix of the keys of the quarantined messages in the state store.
+	KeyPrefixKey = "quarantineKeyPrefix"
+	// MaxMessagesKey is the metadata key for the maximum number of messages in quarantine. The messages failing when
+	// the quarantine is full are left to the broker.
+	MaxMessagesKey = "quarantineMaxMessages"
+	// BackOffPrefix is the prefix of the metadata keys of the retries of the handler before quarantining a message,
+	// such as quarantineBackOffMaxRetries and quarantineBackOffDuration.
+	BackOffPrefix = "quarantineBackOff"
+
+	defaultKeyPrefix   = "quarantine"
+	defaultMaxRetries  = 2
+	defaultDuration    = time.Second
+	defaultMaxMessages = 1000
+
+	// Maximum number of attempts of an update of the index when it is modified concurrently.
+	maxIndexUpdates = 5
+)
+
+// Message is a quarantined message.
+type Message struct {
+	ID          string            `json:"id"`
+	Topic       string            `json:"topic"`
+	Data        []byte            `json:"data"`
+	ContentType *string           `json:"contentType,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	// Error is the error returned by the last attempt to handle the message.
+	Error          string    `json:"error"`
+	Attempts       int       `json:"attempts"`
+	FirstAttemptAt time.Time `json:"firstAttemptAt"`
+	QuarantinedAt  time.Time `json:"quarantinedAt"`
+}
+
+// PubSub is a pub/sub that quarantines the messages its subscribers fail to handle after all the retries.
+type PubSub struct {
+	pubsub.PubSub
+
+	store         state.Store
+	keyPrefix     string
+	maxMessages   int
+	backOffConfig retry.Config
+	logger        logger.Logger
+
+	// Serializes the updates of the index by this replica
+	indexLock sync.Mutex
+}
+
+// New returns a pub/sub quarantining the failing messages of ps in store, which must be initialized.
+func New(ps pubsub.PubSub, store state.Store, logger logger.Logger) *PubSub {
+	return &PubSub{
+		PubSub: ps,
+		store:  store,
+		logger: logger,
+	}
+}
+
+// Init reads the quarantine options and initializes the wrapped pub/sub.
+func (p *PubSub) Init(metadata pubsub.Metadata) error {
+	p.keyPrefix = defaultKeyPrefix
+	if val := metadata.Properties[KeyPrefixKey]; val != "" {
+		p.keyPrefix = val
+	}
+
+	// The index of the messages is a single item of the state store, whose size is bounded
+	p.maxMessages = defaultMaxMessages
+	if val := metadata.Properties[MaxMessagesKey]; val != "" {
+		maxMessages, err := strconv.Atoi(val)
+		if err != nil || maxMessages <= 0 {
+			return fmt.Errorf("quarantine error: %s must be a positive integer: %s", MaxMessagesKey, val)
+		}
+		p.maxMessages = maxMessages
+	}
+
+	p.backOffConfig = retry.DefaultConfig()
+	p.backOffConfig.Duration = defaultDuration
+	p.backOffConfig.MaxRetries = defaultMaxRetries
+	err := retry.DecodeConfigWithPrefix(&p.backOffConfig, metadata.Properties, BackOffPrefix)
+	if err != nil {
+		return fmt.Errorf("quarantine error: invalid retry options: %w", err)
+	}
+	if p.backOffConfig.MaxRetries < 0 {
+		return fmt.Errorf("quarantine error: %sMaxRetries must not be negative", BackOffPrefix)
+	}
+
+	return p.PubSub.Init(metadata)
+}
+
+// Seek forwards the request to the wrapped pubsub. The messages already quarantined are kept.
+func (p *PubSub) Seek(ctx context.Context, req pubsub.SeekRequest) error {
+	return pubsub.Seek(ctx, p.PubSub, req)
+}
+
+// Subscribe subscribes to a topic on the wrapped pub/sub. The messages are retried as configured when handler fails,
+// then quarantined and acknowledged. If the message can't be quarantined, the error is returned to the broker.
+func (p *PubSub) Subscribe(ctx context.Context, req pubsub.SubscribeRequest, handler pubsub.Handler) error {
+	return p.PubSub.Subscribe(ctx, req, func(ctx context.Context, msg *pubsub.NewMessage) error {
+		firstAttemptAt := time.Now().UTC()
+		attempts := 0
+		err := backoff.Retry(func() error {
+			attempts++
+			return handler(ctx, msg)
+		}, p.backOffConfig.NewBackOffWithContext(ctx))
+		// Messages interrupted by the end of the subscription are left to the broker
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+
+		id, qErr := p.quarantine(msg, err, attempts, firstAttemptAt)
+		if qErr != nil {
+			p.logger.Errorf("quarantine error: failed to quarantine message on topic %s: %v", msg.Topic, qErr)
+			return err
+		}
+		p.logger.Warnf("quarantined message %s on topic %s after %d attempts: %v", id, msg.Topic, attempts, err)
+
+		return nil
+	})
+}
+
+func (p *PubSub) quarantine(msg *pubsub.NewMessage, handlerErr error, attempts int, firstAttemptAt time.Time) (string, error) {
+	m := Message{
+		ID:             uuid.NewString(),
+		Topic:          msg.Topic,
+		Data:           msg.Data,
+		ContentType:    msg.ContentType,
+		Metadata:       msg.Metadata,
+		Error:          handlerErr.Error(),
+		Attempts:       attempts,
+		FirstAttemptAt: firstAttemptAt,
+		QuarantinedAt:  time.Now().UTC(),
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+
+	err = p.store.Set(&state.SetRequest{Key: p.messageKey(m.ID), Value: data})
+	if err != nil {
+		return "", err
+	}
+
+	err = p.updateIndex(func(ids []string) ([]string, error) {
+		if len(ids) >= p.maxMessages {
+			return nil, fmt.Errorf("the quarantine is full with %d messages", len(ids))
+		}
+		return append(ids, m.ID), nil
+	})
+	if err != nil {
+		// The message can't be listed without the index, but it is handled again by the broker
+		p.deleteMessage(m.ID)
+		return "", err
+	}
+
+	return m.ID, nil
+}
+
+// List returns the quarantined messages, in the order in which they were quarantined.
+func (p *PubSub) List() ([]Message, error) {
+	ids, _, err := p.index()
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]Message, 0, len(ids))
+	for _, id := range ids {
+		m, err := p.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		// Skips the messages being requeued
+		if m != nil {
+			messages = append(messages, *m)
+		}
+	}
+
+	return messages, nil
+}
+
+// Get returns the quarantined message with the id, or nil if there's none.
+func (p *PubSub) Get(id string) (*Message, error) {
+	res, err := p.store.Get(&state.GetRequest{Key: p.messageKey(id)})
+	if err != nil {
+		return nil, fmt.Errorf("quarantine error: failed to get message %s: %w", id, err)
+	}
+	if res == nil || len(res.Data) == 0 {
+		return nil, nil
+	}
+
+	var m Message
+	err = json.Unmarshal(res.Data, &m)
+	if err != nil {
+		return nil, fmt.Errorf("quarantine error: invalid message %s: %w", id, err)
+	}
+
+	return &m, nil
+}
+
+// Requeue publishes the quarantined message with the id on its topic again, then removes it from the quarantine.
+func (p *PubSub) Requeue(id string) error {
+	m, err := p.Get(id)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		return fmt.Errorf("quarantine error: message %s not found", id)
+	}
+
+	err = p.PubSub.Publish(&pubsub.PublishRequest{
+		Data:        m.Data,
+		Topic:       m.Topic,
+		Metadata:    m.Metadata,
+		ContentType: m.ContentType,
+	})
+	if err != nil {
+		return fmt.Errorf("quarantine error: failed to requeue message %s on topic %s: %w", id, m.Topic, err)
+	}
+
+	return p.Remove(id)
+}
+
+// Remove discards the quarantined message with the id.
+func (p *PubSub) Remove(id string) error {
+	err := p.updateIndex(func(ids []string) ([]string, error) {
+		for i := range ids {
+			if ids[i] == id {
+				return append(ids[:i], ids[i+1:]...), nil
+			}
+		}
+		return ids, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return p.store.Delete(&state.DeleteRequest{Key: p.messageKey(id)})
+}
+
+func (p *PubSub) deleteMessage(id string) {
+	err := p.store.Delete(&state.DeleteRequest{Key: p.messageKey(id)})
+	if err != nil {
+		p.logger.Warnf("quarantine: failed to delete message %s: %v", id, err)
+	}
+}
+
+// index returns the ids of the quarantined messages with the etag of the index.
+func (p *PubSub) index() ([]string, *string, error) {
+	res, err := p.store.Get(&state.GetRequest{Key: p.indexKey()})
+	if err != nil {
+		return nil, nil, fmt.Errorf("quarantine error: failed to get the index: %w", err)
+	}
+	if res == nil || len(res.Data) == 0 {
+		return []string{}, nil, nil
+	}
+
+	var ids []string
+	err = json.Unmarshal(res.Data, &ids)
+	if err != nil {
+		return nil, nil, fmt.Errorf("quarantine error: invalid index: %w", err)
+	}
+
+	return ids, res.ETag, nil
+}
+
+// updateIndex saves the ids returned by update, retrying if the index is modified concurrently by another replica.
+// The first write of the index fails if another replica created it meanwhile.
+func (p *PubSub) updateIndex(update func(ids []string) ([]string, error)) error {
+	p.indexLock.Lock()
+	defer p.indexLock.Unlock()
+
+	for i := 1; ; i++ {
+		ids, etag, err := p.index()
+		if err != nil {
+			return err
+		}
+		ids, err = update(ids)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(ids)
+		if err != nil {
+			return err
+		}
+
+		req := &state.SetRequest{Key: p.indexKey(), Value: data, ETag: etag}
+		if etag == nil {
+			req.Options.Concurrency = state.FirstWrite
+		}
+		err = p.store.Set(req)
+		var etagErr *state.ETagError
+		if errors.As(err, &etagErr) && i < maxIndexUpdates {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("quarantine error: failed to save the index: %w", err)
+		}
+
+		return nil
+	}
+}
+
+func (p *PubSub) messageKey(id string) string {
+	return p.keyPrefix + "||" + id
+}
+
+func (p *PubSub) indexKey() string {
+	return p.keyPrefix + "||index"
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quarantine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/pubsub"
+	pubsubInMemory "github.com/dapr/components-contrib/pubsub/in-memory"
+	"github.com/dapr/components-contrib/state"
+	stateInMemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/kit/logger"
+)
+
+func newQuarantine(t *testing.T, properties map[string]string) *PubSub {
+	t.Helper()
+
+	log := logger.NewLogger("test")
+	store := stateInMemory.NewInMemoryStateStore(log)
+	require.NoError(t, store.Init(state.Metadata{}))
+	p := New(pubsubInMemory.New(log), store, log)
+	require.NoError(t, p.Init(pubsub.Metadata{Base: metadata.Base{Properties: properties}}))
+	t.Cleanup(func() { p.Close() })
+
+	return p
+}
+
+func TestInit(t *testing.T) {
+	t.Run("default options", func(t *testing.T) {
+		p := newQuarantine(t, nil)
+
+		assert.Equal(t, "quarantine||index", p.indexKey())
+		assert.Equal(t, int64(defaultMaxRetries), p.backOffConfig.MaxRetries)
+		assert.Equal(t, defaultDuration, p.backOffConfig.Duration)
+	})
+
+	t.Run("custom options", func(t *testing.T) {
+		p := newQuarantine(t, map[string]string{
+			KeyPrefixKey:                  "orders",
+			"quarantineBackOffMaxRetries": "5",
+			"quarantineBackOffDuration":   "10ms",
+		})
+
+		assert.Equal(t, "orders||1", p.messageKey("1"))
+		assert.Equal(t, int64(5), p.backOffConfig.MaxRetries)
+		assert.Equal(t, 10*time.Millisecond, p.backOffConfig.Duration)
+	})
+
+	t.Run("invalid maximum number of messages", func(t *testing.T) {
+		p := New(pubsubInMemory.New(logger.NewLogger("test")), nil, logger.NewLogger("test"))
+
+		err := p.Init(pubsub.Metadata{Base: metadata.Base{Properties: map[string]string{MaxMessagesKey: "0"}}})
+
+		assert.ErrorContains(t, err, "quarantineMaxMessages must be a positive integer")
+	})
+
+	t.Run("unlimited retries", func(t *testing.T) {
+		p := New(pubsubInMemory.New(logger.NewLogger("test")), nil, logger.NewLogger("test"))
+
+		err := p.Init(pubsub.Metadata{Base: metadata.Base{Properties: map[string]string{"quarantineBackOffMaxRetries": "-1"}}})
+
+		assert.ErrorContains(t, err, "quarantineBackOffMaxRetries must not be negative")
+	})
+}
+
+func TestQuarantine(t *testing.T) {
+	p := newQuarantine(t, map[string]string{
+		"quarantineBackOffMaxRetries": "1",
+		"quarantineBackOffDuration":   "1ms",
+	})
+
+	var failing atomic.Bool
+	failing.Store(true)
+	var attempts atomic.Int32
+	handled := make(chan []byte, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, p.Subscribe(ctx, pubsub.SubscribeRequest{Topic: "orders"}, func(_ context.Context, msg *pubsub.NewMessage) error {
+		attempts.Add(1)
+		if failing.Load() {
+			return errors.New("invalid order")
+		}
+		handled <- msg.Data
+		return nil
+	}))
+
+	list := func(t *testing.T, n int) []Message {
+		t.Helper()
+
+		var messages []Message
+		assert.Eventually(t, func() bool {
+			var err error
+			messages, err = p.List()
+			require.NoError(t, err)
+			return len(messages) == n
+		}, 5*time.Second, 10*time.Millisecond)
+
+		return messages
+	}
+
+	require.NoError(t, p.Publish(&pubsub.PublishRequest{Topic: "orders", Data: []byte(`{"id": 1}`)}))
+	require.NoError(t, p.Publish(&pubsub.PublishRequest{Topic: "orders", Data: []byte(`{"id": 2}`)}))
+
+	messages := list(t, 2)
+	// The in-memory pub/sub does not retry acknowledged messages
+	assert.Equal(t, int32(4), attempts.Load())
+	for _, m := range messages {
+		assert.Equal(t, "orders", m.Topic)
+		assert.Equal(t, "invalid order", m.Error)
+		assert.Equal(t, 2, m.Attempts)
+		assert.False(t, m.QuarantinedAt.Before(m.FirstAttemptAt))
+	}
+
+	t.Run("get", func(t *testing.T) {
+		m, err := p.Get(messages[0].ID)
+		require.NoError(t, err)
+		assert.Equal(t, messages[0], *m)
+
+		m, err = p.Get("unknown")
+		require.NoError(t, err)
+		assert.Nil(t, m)
+	})
+
+	t.Run("requeue", func(t *testing.T) {
+		failing.Store(false)
+
+		require.NoError(t, p.Requeue(messages[0].ID))
+
+		select {
+		case data := <-handled:
+			assert.Equal(t, messages[0].Data, data)
+		case <-time.After(5 * time.Second):
+			t.Fatal("requeued message not received")
+		}
+		assert.Equal(t, []Message{messages[1]}, list(t, 1))
+
+		assert.ErrorContains(t, p.Requeue(messages[0].ID), "not found")
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		require.NoError(t, p.Remove(messages[1].ID))
+
+		list(t, 0)
+		m, err := p.Get(messages[1].ID)
+		require.NoError(t, err)
+		assert.Nil(t, m)
+	})
+}
+
+func TestQuarantineFull(t *testing.T) {
+	p := newQuarantine(t, map[string]string{MaxMessagesKey: "1"})
+
+	_, err := p.quarantine(&pubsub.NewMessage{Topic: "orders", Data: []byte("1")}, errors.New("invalid order"), 1, time.Now())
+	require.NoError(t, err)
+	_, err = p.quarantine(&pubsub.NewMessage{Topic: "orders", Data: []byte("2")}, errors.New("invalid order"), 1, time.Now())
+	assert.ErrorContains(t, err, "the quarantine is full")
+
+	messages, err := p.List()
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, []byte("1"), messages[0].Data)
+}
+
+// interleavedStore runs a function after the first read of the state store.
+type interleavedStore struct {
+	state.Store
+	once  sync.Once
+	after func()
+}
+
+func (s *interleavedStore) Get(req *state.GetRequest) (*state.GetResponse, error) {
+	res, err := s.Store.Get(req)
+	s.once.Do(s.after)
+	return res, err
+}
+
+func TestIndexCreatedConcurrently(t *testing.T) {
+	log := logger.NewLogger("test")
+	store := stateInMemory.NewInMemoryStateStore(log)
+	require.NoError(t, store.Init(state.Metadata{}))
+	add := func(id string) func(ids []string) ([]string, error) {
+		return func(ids []string) ([]string, error) {
+			return append(ids, id), nil
+		}
+	}
+
+	// Another replica creates the index after it is read
+	other := New(nil, store, log)
+	other.keyPrefix = defaultKeyPrefix
+	p := New(nil, &interleavedStore{Store: store, after: func() {
+		require.NoError(t, other.updateIndex(add("1")))
+	}}, log)
+	p.keyPrefix = defaultKeyPrefix
+
+	require.NoError(t, p.updateIndex(add("2")))
+
+	ids, _, err := p.index()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, ids)
+}
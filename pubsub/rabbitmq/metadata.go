@@ -42,12 +42,18 @@ type metadata struct {
 	deliveryMode     uint8 // Transient (0 or 1) or Persistent (2)
 	prefetchCount    uint8 // Prefetch deactivated if 0
 	reconnectWait    time.Duration
+	initOptions      contribMetadata.InitOptions
 	maxLen           int64
 	maxLenBytes      int64
+	maxPriority      *uint8
 	exchangeKind     string
 	publisherConfirm bool
 	concurrency      pubsub.ConcurrencyMode
 	defaultQueueTTL  *time.Duration
+	maxRetryCount    int // Delayed retries deactivated if 0
+	retryInitialWait time.Duration
+	retryMaxWait     time.Duration
+	drainTimeout     time.Duration
 }
 
 const (
@@ -71,10 +77,19 @@ const (
 	metadataReconnectWaitSecondsKey = "reconnectWaitSeconds"
 	metadataMaxLenKey               = "maxLen"
 	metadataMaxLenBytesKey          = "maxLenBytes"
+	metadataMaxPriorityKey          = "maxPriority"
 	metadataExchangeKindKey         = "exchangeKind"
 	metadataPublisherConfirmKey     = "publisherConfirm"
-
-	defaultReconnectWaitSeconds = 3
+	metadataMaxRetryCountKey        = "maxRetryCount"
+	metadataRetryInitialWaitKey     = "retryInitialWaitSeconds"
+	metadataRetryMaxWaitKey         = "retryMaxWaitSeconds"
+	metadataDrainTimeoutKey         = "drainTimeoutSeconds"
+
+	defaultReconnectWaitSeconds    = 3
+	defaultRetryInitialWaitSeconds = 5
+	defaultRetryMaxWaitSeconds     = 300
+	defaultDrainTimeoutSeconds     = 5
+	defaultInitTimeout             = 30 * time.Second
 )
 
 // createMetadata creates a new instance from the pubsub metadata.
@@ -88,6 +103,9 @@ func createMetadata(pubSubMetadata pubsub.Metadata, log logger.Logger) (*metadat
 		reconnectWait:    time.Duration(defaultReconnectWaitSeconds) * time.Second,
 		exchangeKind:     fanoutExchangeKind,
 		publisherConfirm: false,
+		retryInitialWait: time.Duration(defaultRetryInitialWaitSeconds) * time.Second,
+		retryMaxWait:     time.Duration(defaultRetryMaxWaitSeconds) * time.Second,
+		drainTimeout:     time.Duration(defaultDrainTimeoutSeconds) * time.Second,
 	}
 
 	if val, found := pubSubMetadata.Properties[metadataConnectionStringKey]; found && val != "" {
@@ -180,6 +198,15 @@ func createMetadata(pubSubMetadata pubsub.Metadata, log logger.Logger) (*metadat
 		}
 	}
 
+	if val, found := pubSubMetadata.Properties[metadataMaxPriorityKey]; found && val != "" {
+		intVal, err := strconv.ParseUint(val, 10, 8)
+		if err != nil {
+			return &result, fmt.Errorf("%s invalid RabbitMQ max priority %s, accepted values are between 0 and 255", errorMessagePrefix, val)
+		}
+		maxPriority := uint8(intVal)
+		result.maxPriority = &maxPriority
+	}
+
 	if val, found := pubSubMetadata.Properties[metadataExchangeKindKey]; found && val != "" {
 		if exchangeKindValid(val) {
 			result.exchangeKind = val
@@ -194,6 +221,42 @@ func createMetadata(pubSubMetadata pubsub.Metadata, log logger.Logger) (*metadat
 		}
 	}
 
+	if val, found := pubSubMetadata.Properties[metadataMaxRetryCountKey]; found && val != "" {
+		intVal, err := strconv.Atoi(val)
+		if err != nil || intVal < 0 {
+			return &result, fmt.Errorf("%s invalid RabbitMQ max retry count %s", errorMessagePrefix, val)
+		}
+		result.maxRetryCount = intVal
+	}
+
+	if val, found := pubSubMetadata.Properties[metadataRetryInitialWaitKey]; found && val != "" {
+		intVal, err := strconv.Atoi(val)
+		if err != nil || intVal <= 0 {
+			return &result, fmt.Errorf("%s invalid RabbitMQ retry initial wait %s", errorMessagePrefix, val)
+		}
+		result.retryInitialWait = time.Duration(intVal) * time.Second
+	}
+
+	if val, found := pubSubMetadata.Properties[metadataRetryMaxWaitKey]; found && val != "" {
+		intVal, err := strconv.Atoi(val)
+		if err != nil || intVal <= 0 {
+			return &result, fmt.Errorf("%s invalid RabbitMQ retry max wait %s", errorMessagePrefix, val)
+		}
+		result.retryMaxWait = time.Duration(intVal) * time.Second
+	}
+
+	if val, found := pubSubMetadata.Properties[metadataDrainTimeoutKey]; found && val != "" {
+		intVal, err := strconv.Atoi(val)
+		if err != nil || intVal < 0 {
+			return &result, fmt.Errorf("%s invalid RabbitMQ drain timeout %s", errorMessagePrefix, val)
+		}
+		result.drainTimeout = time.Duration(intVal) * time.Second
+	}
+
+	if result.retryMaxWait < result.retryInitialWait {
+		return &result, fmt.Errorf("%s %s must not be lower than %s", errorMessagePrefix, metadataRetryMaxWaitKey, metadataRetryInitialWaitKey)
+	}
+
 	ttl, ok, err := contribMetadata.TryGetTTL(pubSubMetadata.Properties)
 	if err != nil {
 		return &result, fmt.Errorf("%s parse RabbitMQ ttl metadata with error: %s", errorMessagePrefix, err)
@@ -209,6 +272,15 @@ func createMetadata(pubSubMetadata pubsub.Metadata, log logger.Logger) (*metadat
 	}
 	result.concurrency = c
 
+	// By default, the connection is established lazily so the component loads even if the broker is not reachable yet
+	result.initOptions, err = contribMetadata.GetInitOptions(pubSubMetadata.Properties, contribMetadata.InitOptions{
+		FailFast: false,
+		Timeout:  defaultInitTimeout,
+	})
+	if err != nil {
+		return &result, fmt.Errorf("%s %v", errorMessagePrefix, err)
+	}
+
 	return &result, nil
 }
 
@@ -222,10 +294,28 @@ func (m *metadata) formatQueueDeclareArgs(origin amqp.Table) amqp.Table {
 	if m.maxLenBytes > 0 {
 		origin[argMaxLengthBytes] = m.maxLenBytes
 	}
+	if m.maxPriority != nil {
+		// Declared as an int: RabbitMQ rejects the argument when it's encoded as a byte
+		origin[argMaxPriority] = int(*m.maxPriority)
+	}
 
 	return origin
 }
 
+// retryDelay returns the backoff applied before the given retry attempt (starting at 1).
+// The delay doubles on each attempt and is capped at retryMaxWait.
+func (m *metadata) retryDelay(attempt int) time.Duration {
+	delay := m.retryInitialWait
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= m.retryMaxWait {
+			return m.retryMaxWait
+		}
+	}
+
+	return delay
+}
+
 func exchangeKindValid(kind string) bool {
 	return kind == amqp.ExchangeFanout || kind == amqp.ExchangeTopic || kind == amqp.ExchangeDirect || kind == amqp.ExchangeHeaders
 }
@@ -16,6 +16,7 @@ package rabbitmq
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/stretchr/testify/assert"
@@ -78,6 +79,10 @@ func TestCreateMetadata(t *testing.T) {
 		assert.Equal(t, int64(0), m.maxLen)
 		assert.Equal(t, int64(0), m.maxLenBytes)
 		assert.Equal(t, fanoutExchangeKind, m.exchangeKind)
+		assert.Equal(t, 0, m.maxRetryCount)
+		assert.Equal(t, 5*time.Second, m.retryInitialWait)
+		assert.Equal(t, 300*time.Second, m.retryMaxWait)
+		assert.Equal(t, 5*time.Second, m.drainTimeout)
 	})
 
 	invalidDeliveryModes := []string{"3", "10", "-1"}
@@ -173,6 +178,132 @@ func TestCreateMetadata(t *testing.T) {
 		assert.Equal(t, int64(2000000), m.maxLenBytes)
 	})
 
+	t.Run("maxPriority is set", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+
+		fakeMetaData := pubsub.Metadata{
+			Base: mdata.Base{Properties: fakeProperties},
+		}
+		fakeMetaData.Properties[metadataMaxPriorityKey] = "10"
+
+		// act
+		m, err := createMetadata(fakeMetaData, log)
+
+		// assert
+		assert.NoError(t, err)
+		if assert.NotNil(t, m.maxPriority) {
+			assert.Equal(t, uint8(10), *m.maxPriority)
+		}
+		assert.Equal(t, amqp.Table{argMaxPriority: 10}, m.formatQueueDeclareArgs(nil))
+	})
+
+	t.Run("maxPriority is invalid", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+
+		fakeMetaData := pubsub.Metadata{
+			Base: mdata.Base{Properties: fakeProperties},
+		}
+		fakeMetaData.Properties[metadataMaxPriorityKey] = "256"
+
+		// act
+		_, err := createMetadata(fakeMetaData, log)
+
+		// assert
+		assert.Error(t, err)
+	})
+
+	t.Run("delayed retries are set", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+
+		fakeMetaData := pubsub.Metadata{
+			Base: mdata.Base{Properties: fakeProperties},
+		}
+		fakeMetaData.Properties[metadataMaxRetryCountKey] = "4"
+		fakeMetaData.Properties[metadataRetryInitialWaitKey] = "10"
+		fakeMetaData.Properties[metadataRetryMaxWaitKey] = "60"
+
+		// act
+		m, err := createMetadata(fakeMetaData, log)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 4, m.maxRetryCount)
+		assert.Equal(t, 10*time.Second, m.retryDelay(1))
+		assert.Equal(t, 20*time.Second, m.retryDelay(2))
+		assert.Equal(t, 40*time.Second, m.retryDelay(3))
+		assert.Equal(t, 60*time.Second, m.retryDelay(4))
+	})
+
+	t.Run("maxRetryCount is invalid", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+
+		fakeMetaData := pubsub.Metadata{
+			Base: mdata.Base{Properties: fakeProperties},
+		}
+		fakeMetaData.Properties[metadataMaxRetryCountKey] = "-1"
+
+		// act
+		_, err := createMetadata(fakeMetaData, log)
+
+		// assert
+		assert.Error(t, err)
+	})
+
+	t.Run("retry waits are invalid", func(t *testing.T) {
+		for _, key := range []string{metadataRetryInitialWaitKey, metadataRetryMaxWaitKey} {
+			for _, val := range []string{"0", "-5", "ten"} {
+				fakeProperties := getFakeProperties()
+
+				fakeMetaData := pubsub.Metadata{
+					Base: mdata.Base{Properties: fakeProperties},
+				}
+				fakeMetaData.Properties[key] = val
+
+				// act
+				_, err := createMetadata(fakeMetaData, log)
+
+				// assert
+				assert.Error(t, err, key+"="+val)
+			}
+		}
+	})
+
+	t.Run("drainTimeoutSeconds", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+
+		fakeMetaData := pubsub.Metadata{
+			Base: mdata.Base{Properties: fakeProperties},
+		}
+		fakeMetaData.Properties[metadataDrainTimeoutKey] = "30"
+
+		// act
+		m, err := createMetadata(fakeMetaData, log)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 30*time.Second, m.drainTimeout)
+
+		fakeMetaData.Properties[metadataDrainTimeoutKey] = "-1"
+		_, err = createMetadata(fakeMetaData, log)
+		assert.Error(t, err)
+	})
+
+	t.Run("retryMaxWaitSeconds is lower than retryInitialWaitSeconds", func(t *testing.T) {
+		fakeProperties := getFakeProperties()
+
+		fakeMetaData := pubsub.Metadata{
+			Base: mdata.Base{Properties: fakeProperties},
+		}
+		fakeMetaData.Properties[metadataRetryInitialWaitKey] = "10"
+		fakeMetaData.Properties[metadataRetryMaxWaitKey] = "5"
+
+		// act
+		_, err := createMetadata(fakeMetaData, log)
+
+		// assert
+		assert.Error(t, err)
+	})
+
 	for _, tt := range booleanFlagTests {
 		t.Run(fmt.Sprintf("autoAck value=%s", tt.in), func(t *testing.T) {
 			fakeProperties := getFakeProperties()
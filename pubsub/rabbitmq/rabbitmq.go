@@ -26,6 +26,7 @@ import (
 
 	"github.com/dapr/kit/logger"
 
+	"github.com/dapr/components-contrib/internal/utils"
 	contribMetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
 )
@@ -38,6 +39,7 @@ const (
 	errorChannelConnection          = "channel/connection is not open"
 	defaultDeadLetterExchangeFormat = "dlx-%s"
 	defaultDeadLetterQueueFormat    = "dlq-%s"
+	defaultRetryQueueFormat         = "retry-%s-%d"
 
 	publishMaxRetries       = 3
 	publishRetryWaitSeconds = 2
@@ -45,9 +47,17 @@ const (
 	argQueueMode          = "x-queue-mode"
 	argMaxLength          = "x-max-length"
 	argMaxLengthBytes     = "x-max-length-bytes"
+	argMaxPriority        = "x-max-priority"
 	argDeadLetterExchange = "x-dead-letter-exchange"
+	argDeadLetterRouting  = "x-dead-letter-routing-key"
+	argMessageTTL         = "x-message-ttl"
+	headerRetryCount      = "x-retry-count"
 	queueModeLazy         = "lazy"
 	reqMetadataRoutingKey = "routingKey"
+
+	reqMetadataPriorityKey      = "priority"
+	reqMetadataExpirationKey    = "expiration"
+	reqMetadataCorrelationIDKey = "correlationId"
 )
 
 // RabbitMQ allows sending/receiving messages in pub/sub format.
@@ -57,9 +67,11 @@ type rabbitMQ struct {
 	channelMutex      sync.RWMutex
 	connectionCount   int
 	metadata          *metadata
+	topicMetadata     *pubsub.TopicMetadata[*metadata]
 	declaredExchanges map[string]bool
 	ctx               context.Context
 	cancel            context.CancelFunc
+	inFlight          *utils.InFlight
 
 	connectionDial func(uri string) (rabbitMQConnectionBroker, rabbitMQChannelBroker, error)
 
@@ -120,17 +132,52 @@ func (r *rabbitMQ) Init(metadata pubsub.Metadata) error {
 		return err
 	}
 
+	topicMetadata, err := pubsub.NewTopicMetadata(metadata, meta, r.createMetadata)
+	if err != nil {
+		return fmt.Errorf("%s %v", errorMessagePrefix, err)
+	}
+
 	r.ctx, r.cancel = context.WithCancel(context.Background())
+	r.inFlight = utils.NewInFlight()
 
 	r.metadata = meta
+	r.topicMetadata = topicMetadata
+
+	err = r.reconnect(0)
+	if err != nil && !meta.initOptions.FailFast {
+		// We do not return error on reconnect because it can cause problems if init() happens
+		// right at the restart window for service. So, we try it now but there is logic in the
+		// code to reconnect as many times as needed.
+		r.logger.Warnf("%s initial connection failed, reconnecting on first use: %v", logMessagePrefix, err)
+		return nil
+	}
+
+	// When failing fast, keep retrying until the init timeout
+	deadline := time.Now().Add(meta.initOptions.Timeout)
+	for err != nil && time.Now().Add(meta.reconnectWait).Before(deadline) {
+		r.logger.Warnf("%s initial connection failed, retrying in %s: %v", logMessagePrefix, meta.reconnectWait, err)
+		time.Sleep(meta.reconnectWait)
+		err = r.reconnect(0)
+	}
+	if err != nil {
+		r.cancel()
+		return fmt.Errorf("%s failed to connect within %s: %v", errorMessagePrefix, meta.initOptions.Timeout, err)
+	}
 
-	r.reconnect(0)
-	// We do not return error on reconnect because it can cause problems if init() happens
-	// right at the restart window for service. So, we try it now but there is logic in the
-	// code to reconnect as many times as needed.
 	return nil
 }
 
+// createMetadata parses the metadata of the component, or of a topic with the overrides of its properties.
+func (r *rabbitMQ) createMetadata(md pubsub.Metadata) (*metadata, error) {
+	return createMetadata(md, r.logger)
+}
+
+// metadataForTopic returns the metadata for a topic, with the per-topic overrides applied.
+// Connection-level properties are always read from the component metadata.
+func (r *rabbitMQ) metadataForTopic(topic string) (*metadata, error) {
+	return r.topicMetadata.ForTopic(topic)
+}
+
 func (r *rabbitMQ) reconnect(connectionCount int) error {
 	r.channelMutex.Lock()
 	defer r.channelMutex.Unlock()
@@ -176,7 +223,7 @@ func (r *rabbitMQ) reconnect(connectionCount int) error {
 	return nil
 }
 
-func (r *rabbitMQ) publishSync(req *pubsub.PublishRequest) (rabbitMQChannelBroker, int, error) {
+func (r *rabbitMQ) publishSync(md *metadata, req *pubsub.PublishRequest, msg amqp.Publishing) (rabbitMQChannelBroker, int, error) {
 	r.channelMutex.Lock()
 	defer r.channelMutex.Unlock()
 
@@ -184,7 +231,7 @@ func (r *rabbitMQ) publishSync(req *pubsub.PublishRequest) (rabbitMQChannelBroke
 		return r.channel, r.connectionCount, errors.New(errorChannelNotInitialized)
 	}
 
-	if err := r.ensureExchangeDeclared(r.channel, req.Topic, r.metadata.exchangeKind); err != nil {
+	if err := r.ensureExchangeDeclared(r.channel, req.Topic, md.exchangeKind); err != nil {
 		r.logger.Errorf("%s publishing to %s failed in ensureExchangeDeclared: %v", logMessagePrefix, req.Topic, err)
 
 		return r.channel, r.connectionCount, err
@@ -194,24 +241,7 @@ func (r *rabbitMQ) publishSync(req *pubsub.PublishRequest) (rabbitMQChannelBroke
 		routingKey = val
 	}
 
-	ttl, ok, err := contribMetadata.TryGetTTL(req.Metadata)
-	if err != nil {
-		r.logger.Warnf("%s publishing to %s failed parse TryGetTTL: %v, it is ignored.", logMessagePrefix, req.Topic, err)
-	}
-	var expiration string
-	if ok {
-		// RabbitMQ expects the duration in ms
-		expiration = strconv.FormatInt(ttl.Milliseconds(), 10)
-	} else if r.metadata.defaultQueueTTL != nil {
-		expiration = strconv.FormatInt(r.metadata.defaultQueueTTL.Milliseconds(), 10)
-	}
-
-	confirm, err := r.channel.PublishWithDeferredConfirmWithContext(r.ctx, req.Topic, routingKey, false, false, amqp.Publishing{
-		ContentType:  "text/plain",
-		Body:         req.Data,
-		DeliveryMode: r.metadata.deliveryMode,
-		Expiration:   expiration,
-	})
+	confirm, err := r.channel.PublishWithDeferredConfirmWithContext(r.ctx, req.Topic, routingKey, false, false, msg)
 	if err != nil {
 		r.logger.Errorf("%s publishing to %s failed in channel.Publish: %v", logMessagePrefix, req.Topic, err)
 
@@ -231,13 +261,61 @@ func (r *rabbitMQ) publishSync(req *pubsub.PublishRequest) (rabbitMQChannelBroke
 	return r.channel, r.connectionCount, nil
 }
 
+// newPublishing creates the message to publish, applying the properties set in the request metadata.
+func (r *rabbitMQ) newPublishing(md *metadata, req *pubsub.PublishRequest) (amqp.Publishing, error) {
+	msg := amqp.Publishing{
+		ContentType:   "text/plain",
+		Body:          req.Data,
+		DeliveryMode:  md.deliveryMode,
+		CorrelationId: req.Metadata[reqMetadataCorrelationIDKey],
+	}
+
+	ttl, ok, err := contribMetadata.TryGetTTL(req.Metadata)
+	if err != nil {
+		r.logger.Warnf("%s publishing to %s failed parse TryGetTTL: %v, it is ignored.", logMessagePrefix, req.Topic, err)
+	}
+	if val, found := req.Metadata[reqMetadataExpirationKey]; found && val != "" {
+		// expiration is passed through as-is, in ms
+		if _, err = strconv.ParseUint(val, 10, 64); err != nil {
+			return msg, fmt.Errorf("%s invalid expiration %s, a number of milliseconds is expected", errorMessagePrefix, val)
+		}
+		msg.Expiration = val
+	} else if ok {
+		// RabbitMQ expects the duration in ms
+		msg.Expiration = strconv.FormatInt(ttl.Milliseconds(), 10)
+	} else if md.defaultQueueTTL != nil {
+		msg.Expiration = strconv.FormatInt(md.defaultQueueTTL.Milliseconds(), 10)
+	}
+
+	if val, found := req.Metadata[reqMetadataPriorityKey]; found && val != "" {
+		intVal, err := strconv.ParseUint(val, 10, 8)
+		if err != nil {
+			return msg, fmt.Errorf("%s invalid priority %s, accepted values are between 0 and 255", errorMessagePrefix, val)
+		}
+		msg.Priority = uint8(intVal)
+	}
+
+	return msg, nil
+}
+
 func (r *rabbitMQ) Publish(req *pubsub.PublishRequest) error {
 	r.logger.Debugf("%s publishing message to %s", logMessagePrefix, req.Topic)
 
+	md, err := r.metadataForTopic(req.Topic)
+	if err != nil {
+		return err
+	}
+
+	msg, err := r.newPublishing(md, req)
+	if err != nil {
+		r.logger.Errorf("%s publishing failed: %v", logMessagePrefix, err)
+		return err
+	}
+
 	attempt := 0
 	for {
 		attempt++
-		channel, connectionCount, err := r.publishSync(req)
+		channel, connectionCount, err := r.publishSync(md, req, msg)
 		if err == nil {
 			return nil
 		}
@@ -257,17 +335,22 @@ func (r *rabbitMQ) Publish(req *pubsub.PublishRequest) error {
 }
 
 func (r *rabbitMQ) Subscribe(ctx context.Context, req pubsub.SubscribeRequest, handler pubsub.Handler) error {
-	if r.metadata.consumerID == "" {
+	md, err := r.metadataForTopic(req.Topic)
+	if err != nil {
+		return err
+	}
+
+	if md.consumerID == "" {
 		return errors.New("consumerID is required for subscriptions")
 	}
 
-	queueName := fmt.Sprintf("%s-%s", r.metadata.consumerID, req.Topic)
+	queueName := fmt.Sprintf("%s-%s", md.consumerID, req.Topic)
 	r.logger.Infof("%s subscribe to topic/queue '%s/%s'", logMessagePrefix, req.Topic, queueName)
 
 	// Do not set a timeout on the context, as we're just waiting for the first ack; we're using a semaphore instead
 	ackCh := make(chan struct{}, 1)
 	defer close(ackCh)
-	go r.subscribeForever(ctx, req, queueName, handler, ackCh)
+	go r.subscribeForever(ctx, md, req, queueName, handler, ackCh)
 
 	// Wait for the ack for 1 minute or return an error
 	select {
@@ -279,8 +362,8 @@ func (r *rabbitMQ) Subscribe(ctx context.Context, req pubsub.SubscribeRequest, h
 }
 
 // this function call should be wrapped by channelMutex.
-func (r *rabbitMQ) prepareSubscription(channel rabbitMQChannelBroker, req pubsub.SubscribeRequest, queueName string) (*amqp.Queue, error) {
-	err := r.ensureExchangeDeclared(channel, req.Topic, r.metadata.exchangeKind)
+func (r *rabbitMQ) prepareSubscription(md *metadata, channel rabbitMQChannelBroker, req pubsub.SubscribeRequest, queueName string) (*amqp.Queue, error) {
+	err := r.ensureExchangeDeclared(channel, req.Topic, md.exchangeKind)
 	if err != nil {
 		r.logger.Errorf("%s prepareSubscription for topic/queue '%s/%s' failed in ensureExchangeDeclared: %v", logMessagePrefix, req.Topic, queueName, err)
 
@@ -289,7 +372,7 @@ func (r *rabbitMQ) prepareSubscription(channel rabbitMQChannelBroker, req pubsub
 
 	r.logger.Infof("%s declaring queue '%s'", logMessagePrefix, queueName)
 	var args amqp.Table
-	if r.metadata.enableDeadLetter {
+	if md.enableDeadLetter {
 		// declare dead letter exchange
 		dlxName := fmt.Sprintf(defaultDeadLetterExchangeFormat, queueName)
 		dlqName := fmt.Sprintf(defaultDeadLetterQueueFormat, queueName)
@@ -300,10 +383,10 @@ func (r *rabbitMQ) prepareSubscription(channel rabbitMQChannelBroker, req pubsub
 			return nil, err
 		}
 		var q amqp.Queue
-		dlqArgs := r.metadata.formatQueueDeclareArgs(nil)
+		dlqArgs := md.formatQueueDeclareArgs(nil)
 		// dead letter queue use lazy mode, keeping as many messages as possible on disk to reduce RAM usage
 		dlqArgs[argQueueMode] = queueModeLazy
-		q, err = channel.QueueDeclare(dlqName, true, r.metadata.deleteWhenUnused, false, false, dlqArgs)
+		q, err = channel.QueueDeclare(dlqName, true, md.deleteWhenUnused, false, false, dlqArgs)
 		if err != nil {
 			r.logger.Errorf("%s prepareSubscription for topic/queue '%s/%s' failed in channel.QueueDeclare: %v", logMessagePrefix, req.Topic, dlqName, err)
 
@@ -318,17 +401,24 @@ func (r *rabbitMQ) prepareSubscription(channel rabbitMQChannelBroker, req pubsub
 		r.logger.Infof("%s declared dead letter exchange for queue '%s' bind dead letter queue '%s' to dead letter exchange '%s'", logMessagePrefix, queueName, dlqName, dlxName)
 		args = amqp.Table{argDeadLetterExchange: dlxName}
 	}
-	args = r.metadata.formatQueueDeclareArgs(args)
-	q, err := channel.QueueDeclare(queueName, r.metadata.durable, r.metadata.deleteWhenUnused, false, false, args)
+	args = md.formatQueueDeclareArgs(args)
+	q, err := channel.QueueDeclare(queueName, md.durable, md.deleteWhenUnused, false, false, args)
 	if err != nil {
 		r.logger.Errorf("%s prepareSubscription for topic/queue '%s/%s' failed in channel.QueueDeclare: %v", logMessagePrefix, req.Topic, queueName, err)
 
 		return nil, err
 	}
 
-	if r.metadata.prefetchCount > 0 {
-		r.logger.Infof("%s setting prefetch count to %s", logMessagePrefix, strconv.Itoa(int(r.metadata.prefetchCount)))
-		err = channel.Qos(int(r.metadata.prefetchCount), 0, false)
+	err = r.ensureRetryQueuesDeclared(md, channel, q.Name)
+	if err != nil {
+		r.logger.Errorf("%s prepareSubscription for topic/queue '%s/%s' failed in ensureRetryQueuesDeclared: %v", logMessagePrefix, req.Topic, queueName, err)
+
+		return nil, err
+	}
+
+	if md.prefetchCount > 0 {
+		r.logger.Infof("%s setting prefetch count to %s", logMessagePrefix, strconv.Itoa(int(md.prefetchCount)))
+		err = channel.Qos(int(md.prefetchCount), 0, false)
 		if err != nil {
 			r.logger.Errorf("%s prepareSubscription for topic/queue '%s/%s' failed in channel.Qos: %v", logMessagePrefix, req.Topic, queueName, err)
 
@@ -355,7 +445,30 @@ func (r *rabbitMQ) prepareSubscription(channel rabbitMQChannelBroker, req pubsub
 	return &q, nil
 }
 
-func (r *rabbitMQ) ensureSubscription(req pubsub.SubscribeRequest, queueName string) (rabbitMQChannelBroker, int, *amqp.Queue, error) {
+// ensureRetryQueuesDeclared declares one delay queue per retry attempt. Messages published to a delay
+// queue expire after the attempt's backoff and are dead-lettered back to the consumer queue through
+// the default exchange. Using a queue per attempt avoids expired messages being blocked behind
+// messages with a longer TTL.
+// this function call should be wrapped by channelMutex.
+func (r *rabbitMQ) ensureRetryQueuesDeclared(md *metadata, channel rabbitMQChannelBroker, queueName string) error {
+	for attempt := 1; attempt <= md.maxRetryCount; attempt++ {
+		retryQueueName := fmt.Sprintf(defaultRetryQueueFormat, queueName, attempt)
+		args := amqp.Table{
+			argDeadLetterExchange: "",
+			argDeadLetterRouting:  queueName,
+			argMessageTTL:         md.retryDelay(attempt).Milliseconds(),
+		}
+		_, err := channel.QueueDeclare(retryQueueName, md.durable, md.deleteWhenUnused, false, false, args)
+		if err != nil {
+			return err
+		}
+		r.logger.Debugf("%s declared retry queue '%s' for queue '%s' with delay %s", logMessagePrefix, retryQueueName, queueName, md.retryDelay(attempt))
+	}
+
+	return nil
+}
+
+func (r *rabbitMQ) ensureSubscription(md *metadata, req pubsub.SubscribeRequest, queueName string) (rabbitMQChannelBroker, int, *amqp.Queue, error) {
 	r.channelMutex.RLock()
 	defer r.channelMutex.RUnlock()
 
@@ -363,12 +476,12 @@ func (r *rabbitMQ) ensureSubscription(req pubsub.SubscribeRequest, queueName str
 		return nil, r.connectionCount, nil, errors.New(errorChannelNotInitialized)
 	}
 
-	q, err := r.prepareSubscription(r.channel, req, queueName)
+	q, err := r.prepareSubscription(md, r.channel, req, queueName)
 
 	return r.channel, r.connectionCount, q, err
 }
 
-func (r *rabbitMQ) subscribeForever(ctx context.Context, req pubsub.SubscribeRequest, queueName string, handler pubsub.Handler, ackCh chan struct{}) {
+func (r *rabbitMQ) subscribeForever(ctx context.Context, md *metadata, req pubsub.SubscribeRequest, queueName string, handler pubsub.Handler, ackCh chan struct{}) {
 	for {
 		var (
 			err             error
@@ -379,7 +492,7 @@ func (r *rabbitMQ) subscribeForever(ctx context.Context, req pubsub.SubscribeReq
 			msgs            <-chan amqp.Delivery
 		)
 		for {
-			channel, connectionCount, q, err = r.ensureSubscription(req, queueName)
+			channel, connectionCount, q, err = r.ensureSubscription(md, req, queueName)
 			if err != nil {
 				errFuncName = "ensureSubscription"
 				break
@@ -387,11 +500,11 @@ func (r *rabbitMQ) subscribeForever(ctx context.Context, req pubsub.SubscribeReq
 
 			msgs, err = channel.Consume(
 				q.Name,
-				queueName,          // consumerId
-				r.metadata.autoAck, // autoAck
-				false,              // exclusive
-				false,              // noLocal
-				false,              // noWait
+				queueName,  // consumerId
+				md.autoAck, // autoAck
+				false,      // exclusive
+				false,      // noLocal
+				false,      // noWait
 				nil,
 			)
 			if err != nil {
@@ -405,7 +518,7 @@ func (r *rabbitMQ) subscribeForever(ctx context.Context, req pubsub.SubscribeReq
 				ackCh = nil
 			}
 
-			err = r.listenMessages(ctx, channel, msgs, req.Topic, handler)
+			err = r.listenMessages(ctx, md, channel, msgs, queueName, req.Topic, handler)
 			if err != nil {
 				errFuncName = "listenMessages"
 				break
@@ -436,12 +549,15 @@ func (r *rabbitMQ) subscribeForever(ctx context.Context, req pubsub.SubscribeReq
 	}
 }
 
-func (r *rabbitMQ) listenMessages(ctx context.Context, channel rabbitMQChannelBroker, msgCh <-chan amqp.Delivery, topic string, handler pubsub.Handler) error {
+func (r *rabbitMQ) listenMessages(ctx context.Context, md *metadata, channel rabbitMQChannelBroker, msgCh <-chan amqp.Delivery, queueName, topic string, handler pubsub.Handler) error {
 	var err error
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
+		case <-r.ctx.Done():
+			// The component is closing: stop fetching messages, the in-flight ones are drained by Close
+			return r.ctx.Err()
 		case d, more := <-msgCh:
 			// Handle case of channel closed
 			if !more {
@@ -449,12 +565,25 @@ func (r *rabbitMQ) listenMessages(ctx context.Context, channel rabbitMQChannelBr
 				return nil
 			}
 
-			switch r.metadata.concurrency {
+			// Messages delivered while draining are requeued without being handled
+			if !r.inFlight.Add() {
+				if !md.autoAck {
+					if nackErr := d.Nack(false, true); nackErr != nil {
+						r.logger.Errorf("%s error nacking message '%s' from topic '%s', %s", logMessagePrefix, d.MessageId, topic, nackErr)
+					}
+				}
+				continue
+			}
+
+			// Handlers are not canceled with the subscription, so they can complete while draining
+			switch md.concurrency {
 			case pubsub.Single:
-				err = r.handleMessage(ctx, d, topic, handler)
+				err = r.handleMessage(r.inFlight.Context(), md, channel, d, queueName, topic, handler)
+				r.inFlight.Done()
 			case pubsub.Parallel:
 				go func(d amqp.Delivery) {
-					err = r.handleMessage(ctx, d, topic, handler)
+					err = r.handleMessage(r.inFlight.Context(), md, channel, d, queueName, topic, handler)
+					r.inFlight.Done()
 				}(d)
 			}
 			if err != nil && mustReconnect(channel, err) {
@@ -464,7 +593,7 @@ func (r *rabbitMQ) listenMessages(ctx context.Context, channel rabbitMQChannelBr
 	}
 }
 
-func (r *rabbitMQ) handleMessage(ctx context.Context, d amqp.Delivery, topic string, handler pubsub.Handler) error {
+func (r *rabbitMQ) handleMessage(ctx context.Context, md *metadata, channel rabbitMQChannelBroker, d amqp.Delivery, queueName, topic string, handler pubsub.Handler) error {
 	pubsubMsg := &pubsub.NewMessage{
 		Data:  d.Body,
 		Topic: topic,
@@ -475,14 +604,16 @@ func (r *rabbitMQ) handleMessage(ctx context.Context, d amqp.Delivery, topic str
 	if err != nil {
 		r.logger.Errorf("%s handling message from topic '%s', %s", errorMessagePrefix, topic, err)
 
-		if !r.metadata.autoAck {
+		if !md.autoAck && md.maxRetryCount > 0 {
+			return r.retryMessage(ctx, md, channel, d, queueName, topic)
+		} else if !md.autoAck {
 			// if message is not auto acked we need to ack/nack
-			r.logger.Debugf("%s nacking message '%s' from topic '%s', requeue=%t", logMessagePrefix, d.MessageId, topic, r.metadata.requeueInFailure)
-			if err = d.Nack(false, r.metadata.requeueInFailure); err != nil {
+			r.logger.Debugf("%s nacking message '%s' from topic '%s', requeue=%t", logMessagePrefix, d.MessageId, topic, md.requeueInFailure)
+			if err = d.Nack(false, md.requeueInFailure); err != nil {
 				r.logger.Errorf("%s error nacking message '%s' from topic '%s', %s", logMessagePrefix, d.MessageId, topic, err)
 			}
 		}
-	} else if !r.metadata.autoAck {
+	} else if !md.autoAck {
 		// if message is not auto acked we need to ack/nack
 		r.logger.Debugf("%s acking message '%s' from topic '%s'", logMessagePrefix, d.MessageId, topic)
 		if err = d.Ack(false); err != nil {
@@ -493,6 +624,69 @@ func (r *rabbitMQ) handleMessage(ctx context.Context, d amqp.Delivery, topic str
 	return err
 }
 
+// retryMessage schedules a failed message for redelivery through the delay queue of its next attempt.
+// Once maxRetryCount is reached the message is rejected without requeue, so it is routed to the
+// dead letter queue if enableDeadLetter is set, or dropped otherwise.
+func (r *rabbitMQ) retryMessage(ctx context.Context, md *metadata, channel rabbitMQChannelBroker, d amqp.Delivery, queueName, topic string) error {
+	attempt := retryCount(d.Headers) + 1
+	if attempt > md.maxRetryCount {
+		r.logger.Warnf("%s message '%s' from topic '%s' exhausted %d retries, rejecting it", logMessagePrefix, d.MessageId, topic, md.maxRetryCount)
+		if err := d.Nack(false, false); err != nil {
+			r.logger.Errorf("%s error nacking message '%s' from topic '%s', %s", logMessagePrefix, d.MessageId, topic, err)
+
+			return err
+		}
+
+		return nil
+	}
+
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[headerRetryCount] = int32(attempt)
+
+	retryQueueName := fmt.Sprintf(defaultRetryQueueFormat, queueName, attempt)
+	r.logger.Debugf("%s retrying message '%s' from topic '%s' in %s (attempt %d/%d)", logMessagePrefix, d.MessageId, topic, md.retryDelay(attempt), attempt, md.maxRetryCount)
+	err := channel.PublishWithContext(ctx, "", retryQueueName, false, false, amqp.Publishing{
+		Headers:       headers,
+		ContentType:   d.ContentType,
+		Body:          d.Body,
+		DeliveryMode:  d.DeliveryMode,
+		MessageId:     d.MessageId,
+		CorrelationId: d.CorrelationId,
+		Priority:      d.Priority,
+	})
+	if err != nil {
+		r.logger.Errorf("%s error publishing message '%s' from topic '%s' to retry queue '%s', %s", logMessagePrefix, d.MessageId, topic, retryQueueName, err)
+		if nackErr := d.Nack(false, true); nackErr != nil {
+			r.logger.Errorf("%s error nacking message '%s' from topic '%s', %s", logMessagePrefix, d.MessageId, topic, nackErr)
+		}
+
+		return err
+	}
+
+	if err = d.Ack(false); err != nil {
+		r.logger.Errorf("%s error acking message '%s' from topic '%s', %s", logMessagePrefix, d.MessageId, topic, err)
+	}
+
+	return err
+}
+
+// retryCount returns the number of delayed retries already performed for a message.
+func retryCount(headers amqp.Table) int {
+	switch v := headers[headerRetryCount].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 0
+	}
+}
+
 // this function call should be wrapped by channelMutex.
 func (r *rabbitMQ) ensureExchangeDeclared(channel rabbitMQChannelBroker, exchange, exchangeKind string) error {
 	if !r.containsExchange(exchange) {
@@ -552,10 +746,15 @@ func (r *rabbitMQ) isStopped() bool {
 }
 
 func (r *rabbitMQ) Close() error {
+	// Stop consuming and wait for the in-flight messages to be acked or nacked before closing the channel
+	r.cancel()
+	if !r.inFlight.Drain(context.Background(), r.metadata.drainTimeout) {
+		r.logger.Warnf("%s not all the in-flight messages were processed within %s", logMessagePrefix, r.metadata.drainTimeout)
+	}
+
 	r.channelMutex.Lock()
 	defer r.channelMutex.Unlock()
 
-	r.cancel()
 	err := r.reset()
 
 	return err
@@ -16,6 +16,7 @@ package rabbitmq
 import (
 	"context"
 	"errors"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -326,15 +327,179 @@ func TestSubscribeReconnect(t *testing.T) {
 	assert.Equal(t, 4, broker.closeCount)   // two counts for each connection closure - one for connection, one for channel
 }
 
-func createAMQPMessage(body []byte) amqp.Delivery {
-	return amqp.Delivery{Body: body}
+func TestPublishMessageProperties(t *testing.T) {
+	broker := newBroker()
+	pubsubRabbitMQ := newRabbitMQTest(broker)
+	metadata := pubsub.Metadata{Base: mdata.Base{
+		Properties: map[string]string{
+			metadataHostnameKey:   "anyhost",
+			metadataConsumerIDKey: "consumer",
+		},
+	}}
+	err := pubsubRabbitMQ.Init(metadata)
+	assert.Nil(t, err)
+	r := pubsubRabbitMQ.(*rabbitMQ)
+
+	t.Run("priority, expiration and correlationId are set", func(t *testing.T) {
+		msg, err := r.newPublishing(r.metadata, &pubsub.PublishRequest{
+			Topic: "mytopic",
+			Data:  []byte("hello world"),
+			Metadata: map[string]string{
+				reqMetadataPriorityKey:      "5",
+				reqMetadataExpirationKey:    "60000",
+				reqMetadataCorrelationIDKey: "abc",
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, uint8(5), msg.Priority)
+		assert.Equal(t, "60000", msg.Expiration)
+		assert.Equal(t, "abc", msg.CorrelationId)
+	})
+
+	t.Run("expiration takes priority over ttl", func(t *testing.T) {
+		msg, err := r.newPublishing(r.metadata, &pubsub.PublishRequest{
+			Topic: "mytopic",
+			Metadata: map[string]string{
+				reqMetadataExpirationKey: "1500",
+				mdata.TTLMetadataKey:     "10",
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "1500", msg.Expiration)
+	})
+
+	t.Run("invalid priority", func(t *testing.T) {
+		err := r.Publish(&pubsub.PublishRequest{
+			Topic:    "mytopic",
+			Metadata: map[string]string{reqMetadataPriorityKey: "300"},
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid expiration", func(t *testing.T) {
+		err := r.Publish(&pubsub.PublishRequest{
+			Topic:    "mytopic",
+			Metadata: map[string]string{reqMetadataExpirationKey: "1m"},
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestSubscribeRetryWithBackoff(t *testing.T) {
+	broker := newBroker()
+	pubsubRabbitMQ := newRabbitMQTest(broker)
+	metadata := pubsub.Metadata{Base: mdata.Base{
+		Properties: map[string]string{
+			metadataHostnameKey:      "anyhost",
+			metadataConsumerIDKey:    "consumer",
+			metadataMaxRetryCountKey: "2",
+			pubsub.ConcurrencyKey:    string(pubsub.Single),
+		},
+	}}
+	err := pubsubRabbitMQ.Init(metadata)
+	assert.Nil(t, err)
+
+	topic := "retrytopic"
+
+	processed := make(chan bool)
+	handler := func(ctx context.Context, msg *pubsub.NewMessage) error {
+		processed <- true
+
+		return errors.New("handler failed")
+	}
+
+	err = pubsubRabbitMQ.Subscribe(context.Background(), pubsub.SubscribeRequest{Topic: topic}, handler)
+	assert.Nil(t, err)
+
+	err = pubsubRabbitMQ.Publish(&pubsub.PublishRequest{Topic: topic, Data: []byte("hello world")})
+	assert.Nil(t, err)
+
+	// initial delivery plus 2 retries
+	for i := 0; i < 3; i++ {
+		select {
+		case <-processed:
+		case <-time.After(time.Second):
+			t.Fatalf("expected delivery %d", i+1)
+		}
+	}
+
+	select {
+	case <-processed:
+		t.Fatal("message must not be retried more than maxRetryCount times")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	assert.Equal(t, []string{"consumer-retrytopic", "retry-consumer-retrytopic-1", "retry-consumer-retrytopic-2"}, broker.declaredQueues)
+	assert.Equal(t, []string{"retry-consumer-retrytopic-1", "retry-consumer-retrytopic-2"}, broker.publishedKeys[1:])
+}
+
+func TestCloseDrainsInFlightMessages(t *testing.T) {
+	broker := newBroker()
+	pubsubRabbitMQ := newRabbitMQTest(broker)
+	metadata := pubsub.Metadata{Base: mdata.Base{
+		Properties: map[string]string{
+			metadataHostnameKey:     "anyhost",
+			metadataConsumerIDKey:   "consumer",
+			metadataDrainTimeoutKey: "5",
+		},
+	}}
+	err := pubsubRabbitMQ.Init(metadata)
+	assert.NoError(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var handlerErr atomic.Value
+	handler := func(ctx context.Context, msg *pubsub.NewMessage) error {
+		close(started)
+		select {
+		case <-release:
+		case <-ctx.Done():
+			handlerErr.Store(ctx.Err())
+		}
+		return nil
+	}
+
+	// The subscription is canceled while the message is being handled, as the runtime does on shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	err = pubsubRabbitMQ.Subscribe(ctx, pubsub.SubscribeRequest{Topic: "draintopic"}, handler)
+	assert.NoError(t, err)
+	err = pubsubRabbitMQ.Publish(&pubsub.PublishRequest{Topic: "draintopic", Data: []byte("hello world")})
+	assert.NoError(t, err)
+	<-started
+	cancel()
+
+	closed := make(chan struct{})
+	go func() {
+		assert.NoError(t, pubsubRabbitMQ.Close())
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+		t.Fatal("close must wait for the in-flight message")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("close must return once the in-flight message is processed")
+	}
+	assert.Nil(t, handlerErr.Load())
+}
+
+func createAMQPMessage(body []byte, headers amqp.Table) amqp.Delivery {
+	return amqp.Delivery{Body: body, Headers: headers}
 }
 
 type rabbitMQInMemoryBroker struct {
 	buffer chan amqp.Delivery
 
-	connectCount int
-	closeCount   int
+	connectCount   int
+	closeCount     int
+	declaredQueues []string
+	publishedKeys  []string
 }
 
 func (r *rabbitMQInMemoryBroker) Qos(prefetchCount, prefetchSize int, global bool) error {
@@ -352,12 +517,15 @@ func (r *rabbitMQInMemoryBroker) PublishWithDeferredConfirmWithContext(ctx conte
 		return nil, errors.New(errorChannelConnection)
 	}
 
-	r.buffer <- createAMQPMessage(msg.Body)
+	r.publishedKeys = append(r.publishedKeys, key)
+	r.buffer <- createAMQPMessage(msg.Body, msg.Headers)
 
 	return nil, nil
 }
 
 func (r *rabbitMQInMemoryBroker) QueueDeclare(name string, durable bool, autoDelete bool, exclusive bool, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	r.declaredQueues = append(r.declaredQueues, name)
+
 	return amqp.Queue{Name: name}, nil
 }
 
@@ -394,3 +562,125 @@ func (r *rabbitMQInMemoryBroker) Close() error {
 func (r *rabbitMQInMemoryBroker) IsClosed() bool {
 	return r.connectCount <= r.closeCount
 }
+
+func TestTopicOverrides(t *testing.T) {
+	t.Run("overrides apply to matching topics only", func(t *testing.T) {
+		broker := newBroker()
+		pubsubRabbitMQ := newRabbitMQTest(broker)
+		metadata := pubsub.Metadata{Base: mdata.Base{
+			Properties: map[string]string{
+				metadataHostnameKey:      "anyhost",
+				metadataConsumerIDKey:    "consumer",
+				pubsub.TopicOverridesKey: `[{"topic": "orders-*", "metadata": {"ttlInSeconds": "60", "deliveryMode": "2"}}]`,
+			},
+		}}
+		err := pubsubRabbitMQ.Init(metadata)
+		assert.NoError(t, err)
+		r := pubsubRabbitMQ.(*rabbitMQ)
+
+		// act
+		md, err := r.metadataForTopic("orders-eu")
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, uint8(2), md.deliveryMode)
+		msg, err := r.newPublishing(md, &pubsub.PublishRequest{Topic: "orders-eu"})
+		assert.NoError(t, err)
+		assert.Equal(t, "60000", msg.Expiration)
+
+		// act
+		md, err = r.metadataForTopic("audit")
+
+		// assert
+		assert.NoError(t, err)
+		assert.Same(t, r.metadata, md)
+	})
+
+	t.Run("invalid override", func(t *testing.T) {
+		broker := newBroker()
+		pubsubRabbitMQ := newRabbitMQTest(broker)
+		metadata := pubsub.Metadata{Base: mdata.Base{
+			Properties: map[string]string{
+				metadataHostnameKey:      "anyhost",
+				pubsub.TopicOverridesKey: `[{"topic": "orders-*", "metadata": {"deliveryMode": "3"}}]`,
+			},
+		}}
+
+		// act
+		err := pubsubRabbitMQ.Init(metadata)
+
+		// assert
+		assert.Error(t, err)
+	})
+}
+
+func TestInitFailFast(t *testing.T) {
+	newFailingRabbitMQTest := func(failures int) (pubsub.PubSub, *int) {
+		attempts := 0
+		r := newRabbitMQTest(newBroker()).(*rabbitMQ)
+		r.connectionDial = func(uri string) (rabbitMQConnectionBroker, rabbitMQChannelBroker, error) {
+			attempts++
+			if attempts <= failures {
+				return nil, nil, errors.New("connection refused")
+			}
+			broker := newBroker()
+
+			return broker, broker, nil
+		}
+
+		return r, &attempts
+	}
+	props := func(failFast string) pubsub.Metadata {
+		return pubsub.Metadata{Base: mdata.Base{Properties: map[string]string{
+			metadataHostnameKey:             "anyhost",
+			metadataReconnectWaitSecondsKey: "1",
+			mdata.FailFastKey:               failFast,
+			mdata.InitTimeoutKey:            "1500ms",
+		}}}
+	}
+
+	t.Run("lazy connection ignores errors", func(t *testing.T) {
+		pubsubRabbitMQ, attempts := newFailingRabbitMQTest(10)
+
+		// act
+		err := pubsubRabbitMQ.Init(props("false"))
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 1, *attempts)
+	})
+
+	t.Run("fail fast retries until connected", func(t *testing.T) {
+		pubsubRabbitMQ, attempts := newFailingRabbitMQTest(1)
+
+		// act
+		err := pubsubRabbitMQ.Init(props("true"))
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 2, *attempts)
+	})
+
+	t.Run("fail fast returns error after timeout", func(t *testing.T) {
+		pubsubRabbitMQ, attempts := newFailingRabbitMQTest(10)
+
+		// act
+		err := pubsubRabbitMQ.Init(props("true"))
+
+		// assert
+		assert.ErrorContains(t, err, "connection refused")
+		assert.Equal(t, 2, *attempts)
+	})
+
+	t.Run("invalid init timeout", func(t *testing.T) {
+		pubsubRabbitMQ, _ := newFailingRabbitMQTest(0)
+		md := props("true")
+		md.Properties[mdata.InitTimeoutKey] = "soon"
+
+		// act
+		err := pubsubRabbitMQ.Init(md)
+
+		// assert
+		assert.Error(t, err)
+	})
+}
@@ -15,6 +15,8 @@ package redis
 
 import (
 	"time"
+
+	contribMetadata "github.com/dapr/components-contrib/metadata"
 )
 
 type metadata struct {
@@ -31,4 +33,7 @@ type metadata struct {
 
 	// the max len of stream
 	maxLenApprox int64
+
+	// Whether Init fails if redis is not reachable
+	initOptions contribMetadata.InitOptions
 }
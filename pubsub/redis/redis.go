@@ -17,12 +17,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 
 	rediscomponent "github.com/dapr/components-contrib/internal/component/redis"
+	contribMetadata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/kit/logger"
 )
@@ -35,6 +38,8 @@ const (
 	queueDepth        = "queueDepth"
 	concurrency       = "concurrency"
 	maxLenApprox      = "maxLenApprox"
+
+	defaultInitTimeout = 30 * time.Second
 )
 
 // redisStreams handles consuming from a Redis stream using
@@ -45,6 +50,7 @@ const (
 // on the mechanics of Redis Streams.
 type redisStreams struct {
 	metadata       metadata
+	topicMetadata  *pubsub.TopicMetadata[metadata]
 	client         redis.UniversalClient
 	clientSettings *rediscomponent.Settings
 	logger         logger.Logger
@@ -128,6 +134,16 @@ func parseRedisMetadata(meta pubsub.Metadata) (metadata, error) {
 		m.maxLenApprox = maxLenApprox
 	}
 
+	// By default, Init fails if redis is not reachable
+	var err error
+	m.initOptions, err = contribMetadata.GetInitOptions(meta.Properties, contribMetadata.InitOptions{
+		FailFast: true,
+		Timeout:  defaultInitTimeout,
+	})
+	if err != nil {
+		return m, fmt.Errorf("redis streams error: %v", err)
+	}
+
 	return m, nil
 }
 
@@ -137,6 +153,10 @@ func (r *redisStreams) Init(metadata pubsub.Metadata) error {
 		return err
 	}
 	r.metadata = m
+	r.topicMetadata, err = pubsub.NewTopicMetadata(metadata, m, parseRedisMetadata)
+	if err != nil {
+		return fmt.Errorf("redis streams: %v", err)
+	}
 	r.client, r.clientSettings, err = rediscomponent.ParseClientFromProperties(metadata.Properties, nil)
 	if err != nil {
 		return err
@@ -144,8 +164,15 @@ func (r *redisStreams) Init(metadata pubsub.Metadata) error {
 
 	r.ctx, r.cancel = context.WithCancel(context.Background())
 
-	if _, err = r.client.Ping(r.ctx).Result(); err != nil {
-		return fmt.Errorf("redis streams: error connecting to redis at %s: %s", r.clientSettings.Host, err)
+	pingCtx, pingCancel := context.WithTimeout(r.ctx, m.initOptions.Timeout)
+	_, err = r.client.Ping(pingCtx).Result()
+	pingCancel()
+	if err != nil {
+		if m.initOptions.FailFast {
+			return fmt.Errorf("redis streams: error connecting to redis at %s: %s", r.clientSettings.Host, err)
+		}
+		// The client reconnects on the next command
+		r.logger.Warnf("redis streams: error connecting to redis at %s, reconnecting on first use: %s", r.clientSettings.Host, err)
 	}
 	r.queue = make(chan redisMessageWrapper, int(r.metadata.queueDepth))
 
@@ -157,9 +184,14 @@ func (r *redisStreams) Init(metadata pubsub.Metadata) error {
 }
 
 func (r *redisStreams) Publish(req *pubsub.PublishRequest) error {
-	_, err := r.client.XAdd(r.ctx, &redis.XAddArgs{
+	md, err := r.topicMetadata.ForTopic(req.Topic)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.XAdd(r.ctx, &redis.XAddArgs{
 		Stream:       req.Topic,
-		MaxLenApprox: r.metadata.maxLenApprox,
+		MaxLenApprox: md.maxLenApprox,
 		Values:       map[string]interface{}{"data": req.Data},
 	}).Result()
 	if err != nil {
@@ -183,6 +215,38 @@ func (r *redisStreams) Subscribe(ctx context.Context, req pubsub.SubscribeReques
 	return nil
 }
 
+// Seek sets the last delivered ID of the consumer group of the stream to the offset, so that the messages after it are
+// delivered, or to the ID preceding the messages added at or after the timestamp. The offset is a stream ID, 0 to
+// deliver the whole stream again, or $ to skip to its end.
+// The messages pending in the consumer group are kept and are redelivered independently of the new position.
+func (r *redisStreams) Seek(ctx context.Context, req pubsub.SeekRequest) error {
+	id := req.Offset
+	if req.Timestamp != nil {
+		id = lastIDBefore(*req.Timestamp)
+	}
+
+	err := r.client.XGroupSetID(ctx, req.Topic, r.metadata.consumerID, id).Err()
+	if err != nil && strings.HasPrefix(err.Error(), "NOGROUP") {
+		// Not subscribed yet: the consumer group starts at the position
+		err = r.client.XGroupCreateMkStream(ctx, req.Topic, r.metadata.consumerID, id).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("redis streams: error seeking stream %s to %s: %w", req.Topic, id, err)
+	}
+
+	return nil
+}
+
+// lastIDBefore returns the greatest stream ID before the IDs of the messages added at t.
+func lastIDBefore(t time.Time) string {
+	ms := t.UnixMilli()
+	if ms <= 0 {
+		return "0"
+	}
+
+	return strconv.FormatInt(ms-1, 10) + "-" + strconv.FormatUint(math.MaxUint64, 10)
+}
+
 // enqueueMessages is a shared function that funnels new messages (via polling)
 // and redelivered messages (via reclaiming) to a channel where workers can
 // pick them up for processing.
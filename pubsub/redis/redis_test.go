@@ -19,9 +19,12 @@ import (
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 
+	miniredis "github.com/alicebob/miniredis/v2"
 	"github.com/go-redis/redis/v8"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	mdata "github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/pubsub"
@@ -69,6 +72,39 @@ func TestParseRedisMetadata(t *testing.T) {
 	})
 }
 
+func TestPublishTopicOverrides(t *testing.T) {
+	server, err := miniredis.Run()
+	require.NoError(t, err)
+	defer server.Close()
+
+	md := pubsub.Metadata{Base: mdata.Base{Properties: map[string]string{
+		consumerID:               "fakeConsumer",
+		pubsub.TopicOverridesKey: `[{"topic": "small-*", "metadata": {"maxLenApprox": "1"}}]`,
+	}}}
+	m, err := parseRedisMetadata(md)
+	require.NoError(t, err)
+	topicMetadata, err := pubsub.NewTopicMetadata(md, m, parseRedisMetadata)
+	require.NoError(t, err)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	r := &redisStreams{
+		metadata:      m,
+		topicMetadata: topicMetadata,
+		client:        client,
+		logger:        logger.NewLogger("test"),
+		ctx:           context.Background(),
+	}
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, r.Publish(&pubsub.PublishRequest{Topic: "small-events", Data: []byte("data")}))
+		require.NoError(t, r.Publish(&pubsub.PublishRequest{Topic: "events", Data: []byte("data")}))
+	}
+
+	// Only the stream of the overridden topic is trimmed
+	assert.Equal(t, int64(1), client.XLen(context.Background(), "small-events").Val())
+	assert.Equal(t, int64(3), client.XLen(context.Background(), "events").Val())
+}
+
 func TestProcessStreams(t *testing.T) {
 	fakeConsumerID := "fakeConsumer"
 	topicCount := 0
@@ -125,3 +161,47 @@ func generateRedisStreamTestData(topicCount, messageCount int, data string) []re
 
 	return xmessageArray
 }
+
+func TestLastIDBefore(t *testing.T) {
+	assert.Equal(t, "1999-18446744073709551615", lastIDBefore(time.UnixMilli(2000)))
+	assert.Equal(t, "0", lastIDBefore(time.UnixMilli(0)))
+}
+
+func TestInitFailFast(t *testing.T) {
+	// Nothing listens on this port
+	props := func(failFast string) pubsub.Metadata {
+		return pubsub.Metadata{Base: mdata.Base{Properties: map[string]string{
+			consumerID:           "fakeConsumer",
+			"redisHost":          "127.0.0.1:1",
+			"redisMaxRetries":    "0",
+			mdata.FailFastKey:    failFast,
+			mdata.InitTimeoutKey: "1s",
+		}}}
+	}
+
+	t.Run("fail fast", func(t *testing.T) {
+		r := NewRedisStreams(logger.NewLogger("test"))
+
+		err := r.Init(props("true"))
+
+		assert.ErrorContains(t, err, "error connecting to redis at 127.0.0.1:1")
+	})
+
+	t.Run("lazy connection", func(t *testing.T) {
+		r := NewRedisStreams(logger.NewLogger("test"))
+
+		err := r.Init(props("false"))
+
+		require.NoError(t, err)
+		r.Close()
+	})
+
+	t.Run("invalid init timeout", func(t *testing.T) {
+		md := props("true")
+		md.Properties[mdata.InitTimeoutKey] = "soon"
+
+		_, err := parseRedisMetadata(md)
+
+		assert.ErrorContains(t, err, mdata.InitTimeoutKey)
+	})
+}
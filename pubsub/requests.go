@@ -13,6 +13,11 @@ limitations under the License.
 
 package pubsub
 
+import (
+	"errors"
+	"time"
+)
+
 // PublishRequest is the request to publish a message.
 type PublishRequest struct {
 	Data        []byte            `json:"data"`
@@ -36,6 +41,29 @@ type SubscribeRequest struct {
 	Metadata map[string]string `json:"metadata"`
 }
 
+// SeekRequest is the request to move the subscription of a topic to a position, so that the messages from there are
+// delivered again, or skipped if the position is ahead.
+// The position is either an offset, whose format and semantic depend on the broker, or a timestamp, from which the
+// messages published are delivered.
+type SeekRequest struct {
+	Topic     string            `json:"topic"`
+	Offset    string            `json:"offset,omitempty"`
+	Timestamp *time.Time        `json:"timestamp,omitempty"`
+	Metadata  map[string]string `json:"metadata"`
+}
+
+// Validate checks that the request has a topic and exactly one of an offset and a timestamp.
+func (r *SeekRequest) Validate() error {
+	if r.Topic == "" {
+		return errors.New("topic is required to seek")
+	}
+	if (r.Offset == "") == (r.Timestamp == nil) {
+		return errors.New("either an offset or a timestamp is required to seek")
+	}
+
+	return nil
+}
+
 // NewMessage is an event arriving from a message bus instance.
 type NewMessage struct {
 	Data        []byte            `json:"data"`
@@ -0,0 +1,128 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package serialized provides a decorator for pub/subs that publishes the messages in the format of the serializer
+// selected by the "serializer" metadata property, and delivers them to the subscribers as JSON.
+package serialized
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dapr/components-contrib/contenttype"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/serializer"
+	"github.com/dapr/kit/ptr"
+)
+
+// PubSub is a pub/sub that serializes the messages published on another pub/sub, and deserializes the messages received.
+// Without a serializer configured, the messages are passed as they are.
+type PubSub struct {
+	pubsub.PubSub
+
+	serializer serializer.Serializer
+}
+
+// New returns a pub/sub serializing the messages of ps.
+func New(ps pubsub.PubSub) *PubSub {
+	return &PubSub{PubSub: ps}
+}
+
+// Init creates the serializer and initializes the wrapped pub/sub.
+func (p *PubSub) Init(metadata pubsub.Metadata) error {
+	var err error
+	p.serializer, err = serializer.FromMetadata(metadata.Properties)
+	if err != nil {
+		return err
+	}
+
+	return p.PubSub.Init(metadata)
+}
+
+// Publish serializes the message and publishes it.
+func (p *PubSub) Publish(req *pubsub.PublishRequest) error {
+	if p.serializer == nil {
+		return p.PubSub.Publish(req)
+	}
+
+	data, err := p.serializer.Marshal(req.Data)
+	if err != nil {
+		return fmt.Errorf("failed to serialize message on topic %s: %w", req.Topic, err)
+	}
+	serialized := *req
+	serialized.Data = data
+	serialized.ContentType = ptr.Of(p.serializer.ContentType())
+
+	return p.PubSub.Publish(&serialized)
+}
+
+// BulkPublish serializes the messages and publishes them, in bulk if the wrapped pub/sub supports it.
+func (p *PubSub) BulkPublish(ctx context.Context, req *pubsub.BulkPublishRequest) (pubsub.BulkPublishResponse, error) {
+	serialized := *req
+	if p.serializer != nil {
+		serialized.Entries = make([]pubsub.BulkMessageEntry, len(req.Entries))
+		for i, entry := range req.Entries {
+			data, err := p.serializer.Marshal(entry.Event)
+			if err != nil {
+				err = fmt.Errorf("failed to serialize message %s on topic %s: %w", entry.EntryId, req.Topic, err)
+				return pubsub.NewBulkPublishResponse(req.Entries, pubsub.PublishFailed, err), err
+			}
+			entry.Event = data
+			entry.ContentType = p.serializer.ContentType()
+			serialized.Entries[i] = entry
+		}
+	}
+
+	if bp, ok := p.PubSub.(pubsub.BulkPublisher); ok {
+		return bp.BulkPublish(ctx, &serialized)
+	}
+
+	// Publish the messages one by one, these are already serialized
+	for i, entry := range serialized.Entries {
+		err := p.PubSub.Publish(&pubsub.PublishRequest{
+			Data:        entry.Event,
+			PubsubName:  req.PubsubName,
+			Topic:       req.Topic,
+			Metadata:    entry.Metadata,
+			ContentType: &serialized.Entries[i].ContentType,
+		})
+		if err != nil {
+			return pubsub.NewBulkPublishResponse(req.Entries, pubsub.PublishFailed, err), err
+		}
+	}
+
+	return pubsub.NewBulkPublishResponse(req.Entries, pubsub.PublishSucceeded, nil), nil
+}
+
+// Seek forwards the request to the wrapped pubsub: the messages are deserialized again when replayed.
+func (p *PubSub) Seek(ctx context.Context, req pubsub.SeekRequest) error {
+	return pubsub.Seek(ctx, p.PubSub, req)
+}
+
+// Subscribe subscribes to a topic on the wrapped pub/sub, deserializing the messages before passing them to handler.
+func (p *PubSub) Subscribe(ctx context.Context, req pubsub.SubscribeRequest, handler pubsub.Handler) error {
+	if p.serializer == nil {
+		return p.PubSub.Subscribe(ctx, req, handler)
+	}
+
+	return p.PubSub.Subscribe(ctx, req, func(ctx context.Context, msg *pubsub.NewMessage) error {
+		data, err := p.serializer.Unmarshal(msg.Data)
+		if err != nil {
+			return fmt.Errorf("failed to deserialize message on topic %s: %w", msg.Topic, err)
+		}
+		msg.Data = data
+		msg.ContentType = ptr.Of(contenttype.JSONContentType)
+
+		return handler(ctx, msg)
+	})
+}
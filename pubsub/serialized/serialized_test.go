@@ -0,0 +1,96 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serialized
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/pubsub"
+	pubsubInMemory "github.com/dapr/components-contrib/pubsub/in-memory"
+	"github.com/dapr/components-contrib/serializer"
+	"github.com/dapr/kit/logger"
+)
+
+func TestSerializedPubSub(t *testing.T) {
+	inner := pubsubInMemory.New(logger.NewLogger("test"))
+	p := New(inner)
+	require.NoError(t, p.Init(pubsub.Metadata{Base: metadata.Base{Properties: map[string]string{
+		serializer.MetadataKey: serializer.CBOR,
+	}}}))
+	defer p.Close()
+
+	raw := make(chan []byte, 10)
+	received := make(chan *pubsub.NewMessage, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, inner.Subscribe(ctx, pubsub.SubscribeRequest{Topic: "orders"}, func(_ context.Context, msg *pubsub.NewMessage) error {
+		raw <- msg.Data
+		return nil
+	}))
+	require.NoError(t, p.Subscribe(ctx, pubsub.SubscribeRequest{Topic: "orders"}, func(_ context.Context, msg *pubsub.NewMessage) error {
+		received <- msg
+		return nil
+	}))
+
+	receive := func(t *testing.T) ([]byte, *pubsub.NewMessage) {
+		t.Helper()
+
+		var data []byte
+		var msg *pubsub.NewMessage
+		for data == nil || msg == nil {
+			select {
+			case data = <-raw:
+			case msg = <-received:
+			case <-time.After(5 * time.Second):
+				t.Fatal("message not received")
+			}
+		}
+
+		return data, msg
+	}
+
+	t.Run("messages are published serialized", func(t *testing.T) {
+		require.NoError(t, p.Publish(&pubsub.PublishRequest{Topic: "orders", Data: []byte(`{"a": 1}`)}))
+
+		data, msg := receive(t)
+		assert.Equal(t, "a1616101", hex.EncodeToString(data))
+		assert.JSONEq(t, `{"a": 1}`, string(msg.Data))
+		assert.Equal(t, "application/json", *msg.ContentType)
+	})
+
+	t.Run("bulk publish", func(t *testing.T) {
+		res, err := p.BulkPublish(context.Background(), &pubsub.BulkPublishRequest{
+			Topic:   "orders",
+			Entries: []pubsub.BulkMessageEntry{{EntryId: "1", Event: []byte(`[1]`)}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, pubsub.PublishSucceeded, res.Statuses[0].Status)
+
+		_, msg := receive(t)
+		assert.JSONEq(t, `[1]`, string(msg.Data))
+	})
+
+	t.Run("messages which are not JSON are rejected", func(t *testing.T) {
+		err := p.Publish(&pubsub.PublishRequest{Topic: "orders", Data: []byte("not json")})
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pubsub
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"sync"
+)
+
+// TopicOverridesKey is the metadata key for per-topic overrides of the component metadata.
+// The value is a JSON array of overrides, each with a topic name pattern and the properties applied to matching topics:
+//
+//	[{"topic": "orders-*", "metadata": {"ttlInSeconds": "60"}}, {"topic": "audit", "metadata": {"enableDeadLetter": "true"}}]
+//
+// Patterns use the syntax of path.Match, so "*" does not match "/".
+// The overrides are implemented by RabbitMQ, MQTT and Redis Streams, which read the properties of the topics with
+// TopicMetadata.
+const TopicOverridesKey = "topicOverrides"
+
+// TopicOverride is a set of metadata properties that apply to the topics matching a pattern.
+type TopicOverride struct {
+	Topic    string            `json:"topic"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// TopicOverrides is the list of per-topic overrides of a component.
+type TopicOverrides []TopicOverride
+
+// ParseTopicOverrides parses the per-topic overrides from the component metadata.
+func ParseTopicOverrides(props map[string]string) (TopicOverrides, error) {
+	val, ok := props[TopicOverridesKey]
+	if !ok || val == "" {
+		return nil, nil
+	}
+
+	var overrides TopicOverrides
+	if err := json.Unmarshal([]byte(val), &overrides); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", TopicOverridesKey, err)
+	}
+	for _, o := range overrides {
+		if o.Topic == "" {
+			return nil, fmt.Errorf("invalid %s: topic pattern is empty", TopicOverridesKey)
+		}
+		if _, err := path.Match(o.Topic, ""); err != nil {
+			return nil, fmt.Errorf("invalid %s: topic pattern %s: %w", TopicOverridesKey, o.Topic, err)
+		}
+		if _, ok := o.Metadata[TopicOverridesKey]; ok {
+			return nil, fmt.Errorf("invalid %s: overrides cannot be nested", TopicOverridesKey)
+		}
+	}
+
+	return overrides, nil
+}
+
+// Apply returns the properties for a topic, with the overrides matching the topic applied on top of props.
+// When several overrides match, they are applied in order so the last one wins.
+// props is returned as-is if no override matches.
+func (o TopicOverrides) Apply(topic string, props map[string]string) map[string]string {
+	var res map[string]string
+	for _, override := range o {
+		// Patterns were validated when parsed
+		if ok, _ := path.Match(override.Topic, topic); !ok {
+			continue
+		}
+		if res == nil {
+			res = make(map[string]string, len(props)+len(override.Metadata))
+			for k, v := range props {
+				res[k] = v
+			}
+		}
+		for k, v := range override.Metadata {
+			res[k] = v
+		}
+	}
+
+	if res == nil {
+		return props
+	}
+
+	return res
+}
+
+// TopicMetadata is the metadata of a component parsed for each topic, with the per-topic overrides applied.
+type TopicMetadata[T any] struct {
+	base       T
+	properties map[string]string
+	metadata   Metadata
+	overrides  TopicOverrides
+	parse      func(Metadata) (T, error)
+
+	lock   sync.Mutex
+	topics map[string]T
+}
+
+// NewTopicMetadata returns the metadata of the topics of a component, where base is the metadata parsed from md by
+// parse. The properties of each override are validated upfront, regardless of the topics they match.
+func NewTopicMetadata[T any](md Metadata, base T, parse func(Metadata) (T, error)) (*TopicMetadata[T], error) {
+	overrides, err := ParseTopicOverrides(md.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &TopicMetadata[T]{
+		base:       base,
+		properties: md.Properties,
+		metadata:   md,
+		overrides:  overrides,
+		parse:      parse,
+		topics:     map[string]T{},
+	}
+	for _, o := range overrides {
+		props := TopicOverrides{{Topic: "*", Metadata: o.Metadata}}.Apply("", md.Properties)
+		if _, err = t.parseProperties(props); err != nil {
+			return nil, fmt.Errorf("invalid %s for topic %s: %w", TopicOverridesKey, o.Topic, err)
+		}
+	}
+
+	return t, nil
+}
+
+// ForTopic returns the metadata of a topic, which is the base metadata if no override matches the topic.
+func (t *TopicMetadata[T]) ForTopic(topic string) (T, error) {
+	if len(t.overrides) == 0 {
+		return t.base, nil
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if md, ok := t.topics[topic]; ok {
+		return md, nil
+	}
+
+	md := t.base
+	props := t.overrides.Apply(topic, t.properties)
+	if !reflect.DeepEqual(props, t.properties) {
+		var err error
+		md, err = t.parseProperties(props)
+		if err != nil {
+			return md, err
+		}
+	}
+	t.topics[topic] = md
+
+	return md, nil
+}
+
+func (t *TopicMetadata[T]) parseProperties(props map[string]string) (T, error) {
+	md := t.metadata
+	md.Properties = props
+
+	return t.parse(md)
+}
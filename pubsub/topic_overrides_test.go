@@ -0,0 +1,141 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pubsub
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+)
+
+func TestParseTopicOverrides(t *testing.T) {
+	t.Run("not set", func(t *testing.T) {
+		o, err := ParseTopicOverrides(map[string]string{})
+
+		assert.NoError(t, err)
+		assert.Nil(t, o)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		o, err := ParseTopicOverrides(map[string]string{
+			TopicOverridesKey: `[{"topic": "orders-*", "metadata": {"ttlInSeconds": "60"}}]`,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, TopicOverrides{{Topic: "orders-*", Metadata: map[string]string{"ttlInSeconds": "60"}}}, o)
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		_, err := ParseTopicOverrides(map[string]string{TopicOverridesKey: `{"topic": "a"}`})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("empty pattern", func(t *testing.T) {
+		_, err := ParseTopicOverrides(map[string]string{TopicOverridesKey: `[{"metadata": {"a": "b"}}]`})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		_, err := ParseTopicOverrides(map[string]string{TopicOverridesKey: `[{"topic": "orders-[", "metadata": {"a": "b"}}]`})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("nested overrides", func(t *testing.T) {
+		_, err := ParseTopicOverrides(map[string]string{
+			TopicOverridesKey: `[{"topic": "a", "metadata": {"topicOverrides": "[]"}}]`,
+		})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestTopicOverridesApply(t *testing.T) {
+	props := map[string]string{"ttlInSeconds": "10", "consumerID": "app"}
+	o := TopicOverrides{
+		{Topic: "orders-*", Metadata: map[string]string{"ttlInSeconds": "60"}},
+		{Topic: "orders-eu", Metadata: map[string]string{"ttlInSeconds": "120", "enableDeadLetter": "true"}},
+	}
+
+	t.Run("no match", func(t *testing.T) {
+		res := o.Apply("audit", props)
+
+		assert.Equal(t, props, res)
+	})
+
+	t.Run("single match", func(t *testing.T) {
+		res := o.Apply("orders-us", props)
+
+		assert.Equal(t, map[string]string{"ttlInSeconds": "60", "consumerID": "app"}, res)
+		assert.Equal(t, "10", props["ttlInSeconds"])
+	})
+
+	t.Run("last match wins", func(t *testing.T) {
+		res := o.Apply("orders-eu", props)
+
+		assert.Equal(t, map[string]string{"ttlInSeconds": "120", "consumerID": "app", "enableDeadLetter": "true"}, res)
+	})
+}
+
+func TestTopicMetadata(t *testing.T) {
+	parse := func(md Metadata) (string, error) {
+		if md.Properties["ttlInSeconds"] == "invalid" {
+			return "", errors.New("invalid ttl")
+		}
+		return md.Properties["ttlInSeconds"], nil
+	}
+
+	t.Run("overrides apply to matching topics only", func(t *testing.T) {
+		md := Metadata{Base: metadata.Base{Properties: map[string]string{
+			"ttlInSeconds":    "10",
+			TopicOverridesKey: `[{"topic": "orders-*", "metadata": {"ttlInSeconds": "60"}}]`,
+		}}}
+
+		topics, err := NewTopicMetadata(md, "base", parse)
+		require.NoError(t, err)
+
+		res, err := topics.ForTopic("orders-us")
+		require.NoError(t, err)
+		assert.Equal(t, "60", res)
+		assert.Equal(t, "10", md.Properties["ttlInSeconds"])
+
+		res, err = topics.ForTopic("audit")
+		require.NoError(t, err)
+		assert.Equal(t, "base", res)
+	})
+
+	t.Run("no overrides", func(t *testing.T) {
+		topics, err := NewTopicMetadata(Metadata{}, "base", parse)
+		require.NoError(t, err)
+
+		res, err := topics.ForTopic("orders-us")
+		require.NoError(t, err)
+		assert.Equal(t, "base", res)
+	})
+
+	t.Run("invalid override", func(t *testing.T) {
+		md := Metadata{Base: metadata.Base{Properties: map[string]string{
+			TopicOverridesKey: `[{"topic": "orders-*", "metadata": {"ttlInSeconds": "invalid"}}]`,
+		}}}
+
+		_, err := NewTopicMetadata(md, "base", parse)
+		assert.ErrorContains(t, err, "invalid topicOverrides for topic orders-*: invalid ttl")
+	})
+}
@@ -15,8 +15,10 @@ package parameterstore
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ssm"
@@ -41,16 +43,22 @@ func NewParameterStore(logger logger.Logger) secretstores.SecretStore {
 }
 
 type ParameterStoreMetaData struct {
-	Region       string `json:"region"`
-	AccessKey    string `json:"accessKey"`
-	SecretKey    string `json:"secretKey"`
-	SessionToken string `json:"sessionToken"`
-	Prefix       string `json:"prefix"`
+	Region                string `json:"region"`
+	AccessKey             string `json:"accessKey"`
+	SecretKey             string `json:"secretKey" mdsensitive:"true"`
+	SessionToken          string `json:"sessionToken" mdsensitive:"true"`
+	AssumeRoleArn         string `json:"assumeRoleArn"`
+	ExternalID            string `json:"externalId"`
+	AssumeRoleSessionName string `json:"assumeRoleSessionName"`
+	Prefix                string `json:"prefix"`
+	Path                  string `json:"path"`
 }
 
 type ssmSecretStore struct {
 	client ssmiface.SSMAPI
 	prefix string
+	// Hierarchy of the parameters, when the secrets are scoped by path.
+	path   string
 	logger logger.Logger
 }
 
@@ -68,6 +76,21 @@ func (s *ssmSecretStore) Init(metadata secretstores.Metadata) error {
 	s.client = client
 	s.prefix = meta.Prefix
 
+	// The names of the secrets are relative to the path of the hierarchy
+	if meta.Path != "" {
+		if meta.Prefix != "" {
+			return errors.New("only one of prefix and path can be set")
+		}
+		if !strings.HasPrefix(meta.Path, "/") {
+			return fmt.Errorf("invalid path %s: must begin with /", meta.Path)
+		}
+		s.path = strings.TrimSuffix(meta.Path, "/")
+		s.prefix = s.path + "/"
+		if s.path == "" {
+			s.path = "/"
+		}
+	}
+
 	return nil
 }
 
@@ -86,7 +109,7 @@ func (s *ssmSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecr
 		WithDecryption: aws.Bool(true),
 	})
 	if err != nil {
-		return secretstores.GetSecretResponse{Data: nil}, fmt.Errorf("couldn't get secret: %s", err)
+		return secretstores.GetSecretResponse{Data: nil}, fmt.Errorf("couldn't get secret: %w", err)
 	}
 
 	resp := secretstores.GetSecretResponse{
@@ -101,7 +124,12 @@ func (s *ssmSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecr
 }
 
 // BulkGetSecret retrieves all secrets in the store and returns a map of decrypted string/string values.
+// When the secrets are scoped by path, all the parameters of the hierarchy are retrieved.
 func (s *ssmSecretStore) BulkGetSecret(ctx context.Context, req secretstores.BulkGetSecretRequest) (secretstores.BulkGetSecretResponse, error) {
+	if s.path != "" {
+		return s.bulkGetSecretByPath(ctx)
+	}
+
 	resp := secretstores.BulkGetSecretResponse{
 		Data: map[string]map[string]string{},
 	}
@@ -152,8 +180,50 @@ func (s *ssmSecretStore) BulkGetSecret(ctx context.Context, req secretstores.Bul
 	return resp, nil
 }
 
+func (s *ssmSecretStore) bulkGetSecretByPath(ctx context.Context) (secretstores.BulkGetSecretResponse, error) {
+	resp := secretstores.BulkGetSecretResponse{
+		Data: map[string]map[string]string{},
+	}
+
+	search := true
+	var nextToken *string
+	for search {
+		output, err := s.client.GetParametersByPathWithContext(ctx, &ssm.GetParametersByPathInput{
+			Path:           aws.String(s.path),
+			Recursive:      aws.Bool(true),
+			WithDecryption: aws.Bool(true),
+			NextToken:      nextToken,
+		})
+		if err != nil {
+			return secretstores.BulkGetSecretResponse{Data: nil}, fmt.Errorf("couldn't get secrets of path %s: %s", s.path, err)
+		}
+
+		for _, param := range output.Parameters {
+			if param.Name != nil && param.Value != nil {
+				secretName := strings.TrimPrefix(*param.Name, s.prefix)
+				resp.Data[secretName] = map[string]string{secretName: *param.Value}
+			}
+		}
+
+		nextToken = output.NextToken
+		search = output.NextToken != nil
+	}
+
+	return resp, nil
+}
+
 func (s *ssmSecretStore) getClient(metadata *ParameterStoreMetaData) (*ssm.SSM, error) {
-	sess, err := awsAuth.GetClient(metadata.AccessKey, metadata.SecretKey, metadata.SessionToken, metadata.Region, "")
+	sess, err := awsAuth.NewSession(awsAuth.Options{
+		AccessKey:    metadata.AccessKey,
+		SecretKey:    metadata.SecretKey,
+		SessionToken: metadata.SessionToken,
+		Region:       metadata.Region,
+		AssumeRole: awsAuth.AssumeRole{
+			RoleARN:     metadata.AssumeRoleArn,
+			ExternalID:  metadata.ExternalID,
+			SessionName: metadata.AssumeRoleSessionName,
+		},
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -178,3 +248,9 @@ func (s *ssmSecretStore) GetComponentMetadata() map[string]string {
 	metadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo)
 	return metadataInfo
 }
+
+// GetComponentMetadataSchema returns the schema of the metadata of the AWS SSM Parameter Store secret store.
+func (s *ssmSecretStore) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(ParameterStoreMetaData{})
+	return fields
+}
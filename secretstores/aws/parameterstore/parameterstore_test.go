@@ -33,8 +33,9 @@ import (
 const secretValue = "secret"
 
 type mockedSSM struct {
-	GetParameterFn       func(context.Context, *ssm.GetParameterInput, ...request.Option) (*ssm.GetParameterOutput, error)
-	DescribeParametersFn func(context.Context, *ssm.DescribeParametersInput, ...request.Option) (*ssm.DescribeParametersOutput, error)
+	GetParameterFn        func(context.Context, *ssm.GetParameterInput, ...request.Option) (*ssm.GetParameterOutput, error)
+	DescribeParametersFn  func(context.Context, *ssm.DescribeParametersInput, ...request.Option) (*ssm.DescribeParametersOutput, error)
+	GetParametersByPathFn func(context.Context, *ssm.GetParametersByPathInput, ...request.Option) (*ssm.GetParametersByPathOutput, error)
 	ssmiface.SSMAPI
 }
 
@@ -60,6 +61,37 @@ func TestInit(t *testing.T) {
 		err := s.Init(m)
 		assert.Nil(t, err)
 	})
+
+	t.Run("Init with path", func(t *testing.T) {
+		s := NewParameterStore(logger.NewLogger("test")).(*ssmSecretStore)
+		m.Properties = map[string]string{
+			"Region": "a",
+			"Path":   "/myapp/prod/",
+		}
+		err := s.Init(m)
+		assert.Nil(t, err)
+		assert.Equal(t, "/myapp/prod", s.path)
+		assert.Equal(t, "/myapp/prod/", s.prefix)
+	})
+
+	t.Run("Init with invalid path", func(t *testing.T) {
+		m.Properties = map[string]string{
+			"Region": "a",
+			"Path":   "myapp/prod",
+		}
+		err := s.Init(m)
+		assert.Error(t, err)
+	})
+
+	t.Run("Init with both prefix and path", func(t *testing.T) {
+		m.Properties = map[string]string{
+			"Region": "a",
+			"Prefix": "/myapp",
+			"Path":   "/myapp/prod",
+		}
+		err := s.Init(m)
+		assert.Error(t, err)
+	})
 }
 
 func TestGetSecret(t *testing.T) {
@@ -165,6 +197,10 @@ func TestGetSecret(t *testing.T) {
 	})
 }
 
+func (m *mockedSSM) GetParametersByPathWithContext(ctx context.Context, input *ssm.GetParametersByPathInput, option ...request.Option) (*ssm.GetParametersByPathOutput, error) {
+	return m.GetParametersByPathFn(ctx, input, option...)
+}
+
 func TestGetBulkSecrets(t *testing.T) {
 	t.Run("successfully retrieve bulk secrets", func(t *testing.T) {
 		s := ssmSecretStore{
@@ -278,6 +314,62 @@ func TestGetBulkSecrets(t *testing.T) {
 	})
 }
 
+func TestGetBulkSecretsByPath(t *testing.T) {
+	t.Run("successfully retrieve the secrets of the hierarchy", func(t *testing.T) {
+		calls := 0
+		s := ssmSecretStore{
+			client: &mockedSSM{
+				GetParametersByPathFn: func(ctx context.Context, input *ssm.GetParametersByPathInput, option ...request.Option) (*ssm.GetParametersByPathOutput, error) {
+					assert.Equal(t, "/myapp/prod", *input.Path)
+					assert.True(t, *input.Recursive)
+					assert.True(t, *input.WithDecryption)
+
+					calls++
+					if input.NextToken == nil {
+						return &ssm.GetParametersByPathOutput{
+							NextToken: aws.String("next"),
+							Parameters: []*ssm.Parameter{
+								{Name: aws.String("/myapp/prod/secret1"), Value: aws.String("value1")},
+							},
+						}, nil
+					}
+
+					return &ssm.GetParametersByPathOutput{
+						Parameters: []*ssm.Parameter{
+							{Name: aws.String("/myapp/prod/db/password"), Value: aws.String("value2")},
+						},
+					}, nil
+				},
+			},
+			path:   "/myapp/prod",
+			prefix: "/myapp/prod/",
+		}
+
+		output, err := s.BulkGetSecret(context.Background(), secretstores.BulkGetSecretRequest{})
+		assert.Nil(t, err)
+		assert.Equal(t, 2, calls)
+		assert.Equal(t, map[string]map[string]string{
+			"secret1":     {"secret1": "value1"},
+			"db/password": {"db/password": "value2"},
+		}, output.Data)
+	})
+
+	t.Run("unsuccessfully retrieve the secrets of the hierarchy", func(t *testing.T) {
+		s := ssmSecretStore{
+			client: &mockedSSM{
+				GetParametersByPathFn: func(ctx context.Context, input *ssm.GetParametersByPathInput, option ...request.Option) (*ssm.GetParametersByPathOutput, error) {
+					return nil, fmt.Errorf("failed due to any reason")
+				},
+			},
+			path:   "/myapp/prod",
+			prefix: "/myapp/prod/",
+		}
+
+		_, err := s.BulkGetSecret(context.Background(), secretstores.BulkGetSecretRequest{})
+		assert.NotNil(t, err)
+	})
+}
+
 func TestGetFeatures(t *testing.T) {
 	s := ssmSecretStore{}
 	// Yes, we are skipping initialization as feature retrieval doesn't depend on it.
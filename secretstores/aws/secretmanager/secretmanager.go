@@ -41,15 +41,21 @@ func NewSecretManager(logger logger.Logger) secretstores.SecretStore {
 }
 
 type SecretManagerMetaData struct {
-	Region       string `json:"region"`
-	AccessKey    string `json:"accessKey"`
-	SecretKey    string `json:"secretKey"`
-	SessionToken string `json:"sessionToken"`
+	Region                string `json:"region"`
+	AccessKey             string `json:"accessKey"`
+	SecretKey             string `json:"secretKey" mdsensitive:"true"`
+	SessionToken          string `json:"sessionToken" mdsensitive:"true"`
+	AssumeRoleArn         string `json:"assumeRoleArn"`
+	ExternalID            string `json:"externalId"`
+	AssumeRoleSessionName string `json:"assumeRoleSessionName"`
+	// If true, the secrets whose value is a JSON object are expanded into a key/value pair per field.
+	MultiValued bool `json:"multiValued,string"`
 }
 
 type smSecretStore struct {
-	client secretsmanageriface.SecretsManagerAPI
-	logger logger.Logger
+	client      secretsmanageriface.SecretsManagerAPI
+	multiValued bool
+	logger      logger.Logger
 }
 
 // Init creates a AWS secret manager client.
@@ -64,6 +70,7 @@ func (s *smSecretStore) Init(metadata secretstores.Metadata) error {
 		return err
 	}
 	s.client = client
+	s.multiValued = meta.MultiValued
 
 	return nil
 }
@@ -85,14 +92,14 @@ func (s *smSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecre
 		VersionStage: versionStage,
 	})
 	if err != nil {
-		return secretstores.GetSecretResponse{Data: nil}, fmt.Errorf("couldn't get secret: %s", err)
+		return secretstores.GetSecretResponse{Data: nil}, fmt.Errorf("couldn't get secret: %w", err)
 	}
 
 	resp := secretstores.GetSecretResponse{
 		Data: map[string]string{},
 	}
 	if output.Name != nil && output.SecretString != nil {
-		resp.Data[*output.Name] = *output.SecretString
+		resp.Data = s.secretData(*output.Name, *output.SecretString)
 	}
 
 	return resp, nil
@@ -125,7 +132,7 @@ func (s *smSecretStore) BulkGetSecret(ctx context.Context, req secretstores.Bulk
 			}
 
 			if entry.Name != nil && secrets.SecretString != nil {
-				resp.Data[*entry.Name] = map[string]string{*entry.Name: *secrets.SecretString}
+				resp.Data[*entry.Name] = s.secretData(*entry.Name, *secrets.SecretString)
 			}
 		}
 
@@ -136,8 +143,42 @@ func (s *smSecretStore) BulkGetSecret(ctx context.Context, req secretstores.Bulk
 	return resp, nil
 }
 
+// secretData returns the key/value pairs of a secret: a pair per field of its JSON object value when multiValued is
+// set, or a single pair with the name of the secret otherwise.
+func (s *smSecretStore) secretData(name string, value string) map[string]string {
+	if s.multiValued {
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(value), &fields); err == nil {
+			data := make(map[string]string, len(fields))
+			for k, v := range fields {
+				if str, ok := v.(string); ok {
+					data[k] = str
+				} else {
+					// Values which are not strings are kept in their JSON representation
+					b, _ := json.Marshal(v)
+					data[k] = string(b)
+				}
+			}
+
+			return data
+		}
+	}
+
+	return map[string]string{name: value}
+}
+
 func (s *smSecretStore) getClient(metadata *SecretManagerMetaData) (*secretsmanager.SecretsManager, error) {
-	sess, err := awsAuth.GetClient(metadata.AccessKey, metadata.SecretKey, metadata.SessionToken, metadata.Region, "")
+	sess, err := awsAuth.NewSession(awsAuth.Options{
+		AccessKey:    metadata.AccessKey,
+		SecretKey:    metadata.SecretKey,
+		SessionToken: metadata.SessionToken,
+		Region:       metadata.Region,
+		AssumeRole: awsAuth.AssumeRole{
+			RoleARN:     metadata.AssumeRoleArn,
+			ExternalID:  metadata.ExternalID,
+			SessionName: metadata.AssumeRoleSessionName,
+		},
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -162,6 +203,10 @@ func (s *smSecretStore) getSecretManagerMetadata(spec secretstores.Metadata) (*S
 
 // Features returns the features available in this secret store.
 func (s *smSecretStore) Features() []secretstores.Feature {
+	if s.multiValued {
+		return []secretstores.Feature{secretstores.FeatureMultipleKeyValuesPerSecret}
+	}
+
 	return []secretstores.Feature{} // No Feature supported.
 }
 
@@ -171,3 +216,9 @@ func (s *smSecretStore) GetComponentMetadata() map[string]string {
 	metadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo)
 	return metadataInfo
 }
+
+// GetComponentMetadataSchema returns the schema of the metadata of the AWS Secrets Manager secret store.
+func (s *smSecretStore) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(SecretManagerMetaData{})
+	return fields
+}
@@ -135,6 +135,56 @@ func TestGetSecret(t *testing.T) {
 		})
 	})
 
+	t.Run("successfully retrieve multi-valued secret", func(t *testing.T) {
+		t.Run("with json value", func(t *testing.T) {
+			s := smSecretStore{
+				client: &mockedSM{
+					GetSecretValueFn: func(ctx context.Context, input *secretsmanager.GetSecretValueInput, option ...request.Option) (*secretsmanager.GetSecretValueOutput, error) {
+						secret := `{"username": "admin", "password": "secret", "port": 5432}`
+
+						return &secretsmanager.GetSecretValueOutput{
+							Name:         input.SecretId,
+							SecretString: &secret,
+						}, nil
+					},
+				},
+				multiValued: true,
+			}
+
+			req := secretstores.GetSecretRequest{
+				Name:     "/aws/secret/testing",
+				Metadata: map[string]string{},
+			}
+			output, e := s.GetSecret(context.Background(), req)
+			assert.Nil(t, e)
+			assert.Equal(t, map[string]string{"username": "admin", "password": "secret", "port": "5432"}, output.Data)
+		})
+
+		t.Run("with plain value", func(t *testing.T) {
+			s := smSecretStore{
+				client: &mockedSM{
+					GetSecretValueFn: func(ctx context.Context, input *secretsmanager.GetSecretValueInput, option ...request.Option) (*secretsmanager.GetSecretValueOutput, error) {
+						secret := secretValue
+
+						return &secretsmanager.GetSecretValueOutput{
+							Name:         input.SecretId,
+							SecretString: &secret,
+						}, nil
+					},
+				},
+				multiValued: true,
+			}
+
+			req := secretstores.GetSecretRequest{
+				Name:     "/aws/secret/testing",
+				Metadata: map[string]string{},
+			}
+			output, e := s.GetSecret(context.Background(), req)
+			assert.Nil(t, e)
+			assert.Equal(t, map[string]string{req.Name: secretValue}, output.Data)
+		})
+	})
+
 	t.Run("unsuccessfully retrieve secret", func(t *testing.T) {
 		s := smSecretStore{
 			client: &mockedSM{
@@ -158,4 +208,10 @@ func TestGetFeatures(t *testing.T) {
 		f := s.Features()
 		assert.Empty(t, f)
 	})
+
+	t.Run("multiple key values per secret", func(t *testing.T) {
+		s := smSecretStore{multiValued: true}
+		f := s.Features()
+		assert.True(t, secretstores.FeatureMultipleKeyValuesPerSecret.IsPresent(f))
+	})
 }
@@ -0,0 +1,382 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appconfig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig"
+	"github.com/Azure/azure-sdk-for-go/sdk/keyvault/azsecrets"
+
+	azauth "github.com/dapr/components-contrib/internal/authentication/azure"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/secretstores"
+	"github.com/dapr/kit/logger"
+)
+
+// Request metadata keys.
+const (
+	labelKey        = "label"
+	keyFilterKey    = "keyFilter"
+	snapshotTimeKey = "snapshotTime"
+)
+
+const (
+	// Content type of the settings referencing a secret in Azure Key Vault.
+	keyVaultRefContentType = "application/vnd.microsoft.appconfig.keyvaultref+json"
+	// Label filter selecting the settings without label.
+	noLabelFilter = "\x00"
+
+	defaultRequestTimeout = 15 * time.Second
+)
+
+var _ secretstores.SecretStore = (*appConfigSecretStore)(nil)
+
+type appConfigClient interface {
+	GetSetting(ctx context.Context, key string, options *azappconfig.GetSettingOptions) (azappconfig.GetSettingResponse, error)
+	NewListSettingsPager(selector azappconfig.SettingSelector, options *azappconfig.ListSettingsOptions) *runtime.Pager[azappconfig.ListSettingsPage]
+}
+
+type keyVaultClient interface {
+	GetSecret(ctx context.Context, name string, version string, options *azsecrets.GetSecretOptions) (azsecrets.GetSecretResponse, error)
+}
+
+type appConfigSecretStore struct {
+	client   appConfigClient
+	metadata AppConfigMetadata
+
+	// Clients of the Key Vaults referenced by the settings, by vault URL
+	vaultClients     map[string]keyVaultClient
+	vaultClientsLock sync.Mutex
+	newVaultClient   func(vaultURL string) (keyVaultClient, error)
+
+	logger logger.Logger
+}
+
+type AppConfigMetadata struct {
+	// Endpoint of the App Configuration store, when authenticating with Azure AD.
+	Host             string
+	ConnectionString string `mdsensitive:"true"`
+	// Label of the settings read, unless set in the requests; the settings without label by default.
+	Label string
+	// Filter of the keys of the settings read by BulkGetSecret, unless set in the requests; all the keys by default.
+	KeyFilter string
+	// Time, in RFC 3339 format, the settings are read as of, unless set in the requests; the latest values by default.
+	SnapshotTime string
+	// Whether the settings referencing a secret in Azure Key Vault are replaced by the value of the secret.
+	ResolveKeyVaultReferences bool
+	RequestTimeout            time.Duration
+}
+
+// keyVaultReference is the value of a setting referencing a secret in Azure Key Vault.
+type keyVaultReference struct {
+	URI string `json:"uri"`
+}
+
+// NewAzureAppConfigurationSecretStore returns a new Azure App Configuration secret store.
+func NewAzureAppConfigurationSecretStore(logger logger.Logger) secretstores.SecretStore {
+	return &appConfigSecretStore{
+		vaultClients: map[string]keyVaultClient{},
+		logger:       logger,
+	}
+}
+
+// Init creates the Azure App Configuration client, and the credential used for the Key Vault references.
+func (s *appConfigSecretStore) Init(meta secretstores.Metadata) error {
+	m := AppConfigMetadata{
+		ResolveKeyVaultReferences: true,
+		RequestTimeout:            defaultRequestTimeout,
+	}
+	if err := metadata.DecodeMetadata(meta.Properties, &m); err != nil {
+		return err
+	}
+	if m.Host == "" && m.ConnectionString == "" {
+		return errors.New("azure app configuration error: either host or connectionString must be set")
+	}
+	if m.Host != "" && m.ConnectionString != "" {
+		return errors.New("azure app configuration error: host and connectionString can't be both set")
+	}
+	if m.SnapshotTime != "" {
+		if _, err := time.Parse(time.RFC3339, m.SnapshotTime); err != nil {
+			return fmt.Errorf("azure app configuration error: invalid snapshotTime %s: %w", m.SnapshotTime, err)
+		}
+	}
+	if m.RequestTimeout <= 0 {
+		return errors.New("azure app configuration error: requestTimeout must be positive")
+	}
+	s.metadata = m
+
+	coreClientOpts := azcore.ClientOptions{
+		Telemetry: policy.TelemetryOptions{
+			ApplicationID: "dapr-" + logger.DaprVersion,
+		},
+	}
+
+	var err error
+	if m.ConnectionString != "" {
+		s.client, err = azappconfig.NewClientFromConnectionString(m.ConnectionString, &azappconfig.ClientOptions{
+			ClientOptions: coreClientOpts,
+		})
+		if err != nil {
+			return fmt.Errorf("azure app configuration error: %w", err)
+		}
+	} else {
+		settings, err := azauth.NewEnvironmentSettings("appconfig", meta.Properties)
+		if err != nil {
+			return err
+		}
+		cred, err := settings.GetTokenCredential()
+		if err != nil {
+			return err
+		}
+		s.client, err = azappconfig.NewClient(m.Host, cred, &azappconfig.ClientOptions{
+			ClientOptions: coreClientOpts,
+		})
+		if err != nil {
+			return fmt.Errorf("azure app configuration error: %w", err)
+		}
+	}
+
+	if m.ResolveKeyVaultReferences {
+		// Key Vault has its own audience, so the credential is separate even with Azure AD
+		settings, err := azauth.NewEnvironmentSettings("keyvault", meta.Properties)
+		if err != nil {
+			return err
+		}
+		cred, err := settings.GetTokenCredential()
+		if err != nil {
+			return err
+		}
+		s.newVaultClient = func(vaultURL string) (keyVaultClient, error) {
+			return azsecrets.NewClient(vaultURL, cred, &azsecrets.ClientOptions{
+				ClientOptions: coreClientOpts,
+			}), nil
+		}
+	}
+
+	return nil
+}
+
+// GetSecret retrieves the setting with the name of the secret as key.
+func (s *appConfigSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecretRequest) (secretstores.GetSecretResponse, error) {
+	acceptDateTime, err := s.snapshotTime(req.Metadata)
+	if err != nil {
+		return secretstores.GetSecretResponse{}, err
+	}
+
+	opts := &azappconfig.GetSettingOptions{
+		AcceptDateTime: acceptDateTime,
+	}
+	if label := s.label(req.Metadata); label != "" {
+		opts.Label = &label
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, s.metadata.RequestTimeout)
+	resp, err := s.client.GetSetting(timeoutCtx, req.Name, opts)
+	cancel()
+	if err != nil {
+		return secretstores.GetSecretResponse{}, fmt.Errorf("azure app configuration error: failed to get setting %s: %w", req.Name, err)
+	}
+
+	value, err := s.settingValue(ctx, resp.Setting)
+	if err != nil {
+		return secretstores.GetSecretResponse{}, err
+	}
+
+	return secretstores.GetSecretResponse{
+		Data: map[string]string{
+			req.Name: value,
+		},
+	}, nil
+}
+
+// BulkGetSecret retrieves the settings selected by the key and label filters.
+func (s *appConfigSecretStore) BulkGetSecret(ctx context.Context, req secretstores.BulkGetSecretRequest) (secretstores.BulkGetSecretResponse, error) {
+	acceptDateTime, err := s.snapshotTime(req.Metadata)
+	if err != nil {
+		return secretstores.BulkGetSecretResponse{}, err
+	}
+
+	keyFilter := req.Metadata[keyFilterKey]
+	if keyFilter == "" {
+		keyFilter = s.metadata.KeyFilter
+	}
+	if keyFilter == "" {
+		keyFilter = "*"
+	}
+	labelFilter := s.label(req.Metadata)
+	if labelFilter == "" {
+		labelFilter = noLabelFilter
+	}
+
+	pager := s.client.NewListSettingsPager(azappconfig.SettingSelector{
+		KeyFilter:      &keyFilter,
+		LabelFilter:    &labelFilter,
+		AcceptDateTime: acceptDateTime,
+		Fields:         azappconfig.AllSettingFields(),
+	}, nil)
+
+	resp := secretstores.BulkGetSecretResponse{
+		Data: map[string]map[string]string{},
+	}
+	for pager.More() {
+		timeoutCtx, cancel := context.WithTimeout(ctx, s.metadata.RequestTimeout)
+		page, err := pager.NextPage(timeoutCtx)
+		cancel()
+		if err != nil {
+			return secretstores.BulkGetSecretResponse{}, fmt.Errorf("azure app configuration error: failed to list settings: %w", err)
+		}
+
+		for _, setting := range page.Settings {
+			if setting.Key == nil {
+				continue
+			}
+			value, err := s.settingValue(ctx, setting)
+			if err != nil {
+				return secretstores.BulkGetSecretResponse{}, err
+			}
+			resp.Data[*setting.Key] = map[string]string{*setting.Key: value}
+		}
+	}
+
+	return resp, nil
+}
+
+func (s *appConfigSecretStore) label(reqMetadata map[string]string) string {
+	if label := reqMetadata[labelKey]; label != "" {
+		return label
+	}
+
+	return s.metadata.Label
+}
+
+// snapshotTime returns the time the settings are read as of, if any.
+func (s *appConfigSecretStore) snapshotTime(reqMetadata map[string]string) (*time.Time, error) {
+	val := reqMetadata[snapshotTimeKey]
+	if val == "" {
+		val = s.metadata.SnapshotTime
+	}
+	if val == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		return nil, fmt.Errorf("azure app configuration error: invalid %s %s: %w", snapshotTimeKey, val, err)
+	}
+
+	return &t, nil
+}
+
+// settingValue returns the value of a setting, or of the secret it references in Azure Key Vault.
+func (s *appConfigSecretStore) settingValue(ctx context.Context, setting azappconfig.Setting) (string, error) {
+	value := ""
+	if setting.Value != nil {
+		value = *setting.Value
+	}
+	if s.newVaultClient == nil || setting.ContentType == nil || !strings.HasPrefix(*setting.ContentType, keyVaultRefContentType) {
+		return value, nil
+	}
+
+	var ref keyVaultReference
+	if err := json.Unmarshal([]byte(value), &ref); err != nil {
+		return "", fmt.Errorf("azure app configuration error: invalid Key Vault reference: %w", err)
+	}
+	vaultURL, name, version, err := parseSecretURI(ref.URI)
+	if err != nil {
+		return "", err
+	}
+
+	client, err := s.vaultClient(vaultURL)
+	if err != nil {
+		return "", err
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, s.metadata.RequestTimeout)
+	defer cancel()
+	secret, err := client.GetSecret(timeoutCtx, name, version, nil)
+	if err != nil {
+		return "", fmt.Errorf("azure app configuration error: failed to resolve the Key Vault reference %s: %w", ref.URI, err)
+	}
+	if secret.Value == nil {
+		return "", nil
+	}
+
+	return *secret.Value, nil
+}
+
+func (s *appConfigSecretStore) vaultClient(vaultURL string) (keyVaultClient, error) {
+	s.vaultClientsLock.Lock()
+	defer s.vaultClientsLock.Unlock()
+
+	if client, ok := s.vaultClients[vaultURL]; ok {
+		return client, nil
+	}
+	client, err := s.newVaultClient(vaultURL)
+	if err != nil {
+		return nil, err
+	}
+	s.vaultClients[vaultURL] = client
+
+	return client, nil
+}
+
+// parseSecretURI parses the URI of a secret in Azure Key Vault, as https://<vault>/secrets/<name>[/<version>].
+func parseSecretURI(uri string) (vaultURL string, name string, version string, err error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return "", "", "", fmt.Errorf("azure app configuration error: invalid Key Vault secret URI %s", uri)
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] != "secrets" || parts[1] == "" {
+		return "", "", "", fmt.Errorf("azure app configuration error: invalid Key Vault secret URI %s", uri)
+	}
+	if len(parts) == 3 {
+		version = parts[2]
+	}
+
+	return "https://" + u.Host, parts[1], version, nil
+}
+
+// Features returns the features available in this secret store.
+func (s *appConfigSecretStore) Features() []secretstores.Feature {
+	return []secretstores.Feature{} // No Feature supported.
+}
+
+func (s *appConfigSecretStore) GetComponentMetadata() map[string]string {
+	metadataStruct := AppConfigMetadata{}
+	metadataInfo := map[string]string{}
+	metadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo)
+	return metadataInfo
+}
+
+// GetComponentMetadataSchema returns the schema of the metadata of the App Configuration secret store.
+func (s *appConfigSecretStore) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(AppConfigMetadata{
+		ResolveKeyVaultReferences: true,
+		RequestTimeout:            defaultRequestTimeout,
+	})
+	return fields
+}
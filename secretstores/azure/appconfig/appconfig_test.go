@@ -0,0 +1,268 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appconfig
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig"
+	"github.com/Azure/azure-sdk-for-go/sdk/keyvault/azsecrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/secretstores"
+	"github.com/dapr/kit/logger"
+)
+
+type fakeAppConfigClient struct {
+	settings []azappconfig.Setting
+
+	getOptions *azappconfig.GetSettingOptions
+	selector   azappconfig.SettingSelector
+}
+
+func (c *fakeAppConfigClient) GetSetting(ctx context.Context, key string, options *azappconfig.GetSettingOptions) (azappconfig.GetSettingResponse, error) {
+	c.getOptions = options
+	label := ""
+	if options != nil && options.Label != nil {
+		label = *options.Label
+	}
+	for _, s := range c.settings {
+		if *s.Key == key && labelOf(s) == label {
+			return azappconfig.GetSettingResponse{Setting: s}, nil
+		}
+	}
+
+	return azappconfig.GetSettingResponse{}, errors.New("setting not found")
+}
+
+func (c *fakeAppConfigClient) NewListSettingsPager(selector azappconfig.SettingSelector, options *azappconfig.ListSettingsOptions) *runtime.Pager[azappconfig.ListSettingsPage] {
+	c.selector = selector
+	label := *selector.LabelFilter
+	if label == noLabelFilter {
+		label = ""
+	}
+	var settings []azappconfig.Setting
+	for _, s := range c.settings {
+		if labelOf(s) == label {
+			settings = append(settings, s)
+		}
+	}
+
+	// One setting per page
+	next := 0
+	return runtime.NewPager(runtime.PagingHandler[azappconfig.ListSettingsPage]{
+		More: func(page azappconfig.ListSettingsPage) bool {
+			return next < len(settings)
+		},
+		Fetcher: func(ctx context.Context, page *azappconfig.ListSettingsPage) (azappconfig.ListSettingsPage, error) {
+			next++
+			return azappconfig.ListSettingsPage{Settings: settings[next-1 : next]}, nil
+		},
+	})
+}
+
+func labelOf(s azappconfig.Setting) string {
+	if s.Label == nil {
+		return ""
+	}
+	return *s.Label
+}
+
+type fakeKeyVaultClient struct {
+	secrets map[string]string
+}
+
+func (c *fakeKeyVaultClient) GetSecret(ctx context.Context, name string, version string, options *azsecrets.GetSecretOptions) (azsecrets.GetSecretResponse, error) {
+	value, ok := c.secrets[name+"/"+version]
+	if !ok {
+		return azsecrets.GetSecretResponse{}, errors.New("secret not found")
+	}
+
+	return azsecrets.GetSecretResponse{SecretBundle: azsecrets.SecretBundle{Value: &value}}, nil
+}
+
+func newFakeStore(t *testing.T) (*appConfigSecretStore, *fakeAppConfigClient) {
+	t.Helper()
+
+	keyVaultRef := keyVaultRefContentType + ";charset=utf-8"
+	client := &fakeAppConfigClient{
+		settings: []azappconfig.Setting{
+			{Key: to.Ptr("db"), Value: to.Ptr("plain")},
+			{Key: to.Ptr("db"), Label: to.Ptr("prod"), Value: to.Ptr("plain-prod")},
+			{Key: to.Ptr("password"), ContentType: &keyVaultRef, Value: to.Ptr(`{"uri":"https://vault1.vault.azure.net/secrets/password"}`)},
+			{Key: to.Ptr("password"), Label: to.Ptr("prod"), ContentType: &keyVaultRef, Value: to.Ptr(`{"uri":"https://vault1.vault.azure.net/secrets/password/v2"}`)},
+		},
+	}
+
+	s := NewAzureAppConfigurationSecretStore(logger.NewLogger("test")).(*appConfigSecretStore)
+	s.client = client
+	s.metadata = AppConfigMetadata{ResolveKeyVaultReferences: true, RequestTimeout: time.Second}
+	vaults := 0
+	s.newVaultClient = func(vaultURL string) (keyVaultClient, error) {
+		vaults++
+		assert.Equal(t, "https://vault1.vault.azure.net", vaultURL)
+		assert.Equal(t, 1, vaults, "the vault clients must be reused")
+		return &fakeKeyVaultClient{secrets: map[string]string{"password/": "s3cret", "password/v2": "s3cret-v2"}}, nil
+	}
+
+	return s, client
+}
+
+func TestInit(t *testing.T) {
+	s := NewAzureAppConfigurationSecretStore(logger.NewLogger("test"))
+
+	t.Run("connection string", func(t *testing.T) {
+		err := s.Init(secretstores.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"connectionString": "Endpoint=https://foo.azconfig.io;Id=id;Secret=c2VjcmV0",
+			"label":            "prod",
+			"requestTimeout":   "5s",
+		}}})
+		require.NoError(t, err)
+		store := s.(*appConfigSecretStore)
+		assert.NotNil(t, store.client)
+		assert.NotNil(t, store.newVaultClient)
+		assert.Equal(t, "prod", store.metadata.Label)
+		assert.Equal(t, 5*time.Second, store.metadata.RequestTimeout)
+	})
+
+	t.Run("host and Key Vault references not resolved", func(t *testing.T) {
+		s := NewAzureAppConfigurationSecretStore(logger.NewLogger("test"))
+		err := s.Init(secretstores.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"host":                      "https://foo.azconfig.io",
+			"azureTenantId":             "00000000-0000-0000-0000-000000000000",
+			"azureClientId":             "00000000-0000-0000-0000-000000000000",
+			"azureClientSecret":         "passw0rd",
+			"resolveKeyVaultReferences": "false",
+		}}})
+		require.NoError(t, err)
+		assert.Nil(t, s.(*appConfigSecretStore).newVaultClient)
+	})
+
+	t.Run("invalid metadata", func(t *testing.T) {
+		for _, props := range []map[string]string{
+			{},
+			{"host": "https://foo.azconfig.io", "connectionString": "Endpoint=https://foo.azconfig.io;Id=id;Secret=c2VjcmV0"},
+			{"host": "https://foo.azconfig.io", "snapshotTime": "yesterday"},
+			{"host": "https://foo.azconfig.io", "requestTimeout": "0s"},
+		} {
+			assert.Error(t, s.Init(secretstores.Metadata{Base: metadata.Base{Properties: props}}), "%v", props)
+		}
+	})
+}
+
+func TestGetSecret(t *testing.T) {
+	s, client := newFakeStore(t)
+
+	t.Run("plain setting", func(t *testing.T) {
+		resp, err := s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "db"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"db": "plain"}, resp.Data)
+		assert.Nil(t, client.getOptions.Label)
+		assert.Nil(t, client.getOptions.AcceptDateTime)
+	})
+
+	t.Run("label and snapshot time", func(t *testing.T) {
+		resp, err := s.GetSecret(context.Background(), secretstores.GetSecretRequest{
+			Name:     "db",
+			Metadata: map[string]string{"label": "prod", "snapshotTime": "2022-10-01T10:00:00Z"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"db": "plain-prod"}, resp.Data)
+		assert.Equal(t, time.Date(2022, 10, 1, 10, 0, 0, 0, time.UTC), *client.getOptions.AcceptDateTime)
+	})
+
+	t.Run("Key Vault references are resolved", func(t *testing.T) {
+		resp, err := s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "password"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"password": "s3cret"}, resp.Data)
+
+		resp, err = s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "password", Metadata: map[string]string{"label": "prod"}})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"password": "s3cret-v2"}, resp.Data)
+	})
+
+	t.Run("Key Vault references are not resolved if disabled", func(t *testing.T) {
+		s, _ := newFakeStore(t)
+		s.newVaultClient = nil
+
+		resp, err := s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "password"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"password": `{"uri":"https://vault1.vault.azure.net/secrets/password"}`}, resp.Data)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "missing"})
+		assert.ErrorContains(t, err, "failed to get setting missing")
+
+		_, err = s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "db", Metadata: map[string]string{"snapshotTime": "now"}})
+		assert.ErrorContains(t, err, "invalid snapshotTime")
+	})
+}
+
+func TestBulkGetSecret(t *testing.T) {
+	t.Run("settings without label", func(t *testing.T) {
+		s, client := newFakeStore(t)
+
+		resp, err := s.BulkGetSecret(context.Background(), secretstores.BulkGetSecretRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]map[string]string{
+			"db":       {"db": "plain"},
+			"password": {"password": "s3cret"},
+		}, resp.Data)
+		assert.Equal(t, "*", *client.selector.KeyFilter)
+		assert.Equal(t, noLabelFilter, *client.selector.LabelFilter)
+	})
+
+	t.Run("label and key filter", func(t *testing.T) {
+		s, client := newFakeStore(t)
+		s.metadata.Label = "prod"
+
+		resp, err := s.BulkGetSecret(context.Background(), secretstores.BulkGetSecretRequest{
+			Metadata: map[string]string{"keyFilter": "app1/*", "snapshotTime": "2022-10-01T10:00:00Z"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]map[string]string{
+			"db":       {"db": "plain-prod"},
+			"password": {"password": "s3cret-v2"},
+		}, resp.Data)
+		assert.Equal(t, "app1/*", *client.selector.KeyFilter)
+		assert.Equal(t, "prod", *client.selector.LabelFilter)
+		assert.NotNil(t, client.selector.AcceptDateTime)
+	})
+}
+
+func TestParseSecretURI(t *testing.T) {
+	vaultURL, name, version, err := parseSecretURI("https://vault1.vault.azure.net/secrets/password/abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://vault1.vault.azure.net", vaultURL)
+	assert.Equal(t, "password", name)
+	assert.Equal(t, "abc", version)
+
+	for _, uri := range []string{
+		"http://vault1.vault.azure.net/secrets/password",
+		"https://vault1.vault.azure.net/keys/password",
+		"https://vault1.vault.azure.net/secrets/",
+		"https://vault1.vault.azure.net/secrets/a/b/c",
+	} {
+		_, _, _, err = parseSecretURI(uri)
+		assert.Error(t, err, uri)
+	}
+}
@@ -19,9 +19,11 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
 	"github.com/Azure/azure-sdk-for-go/sdk/keyvault/azsecrets"
 
 	azauth "github.com/dapr/components-contrib/internal/authentication/azure"
@@ -34,21 +36,36 @@ import (
 // This is in addition to what's defined in authentication/azure.
 const (
 	VersionID          = "version_id"
+	Version            = "version"
 	secretItemIDPrefix = "/secrets/"
+
+	// Default number of secrets fetched concurrently by BulkGetSecret.
+	defaultBulkGetConcurrency = 10
+	// Maximum number of secrets in a page listed by Azure Key Vault.
+	maxListPageSize = 25
 )
 
 var _ secretstores.SecretStore = (*keyvaultSecretStore)(nil)
 
+// secretsClient is the subset of the methods of azsecrets.Client used by the secret store.
+type secretsClient interface {
+	GetSecret(ctx context.Context, name string, version string, options *azsecrets.GetSecretOptions) (azsecrets.GetSecretResponse, error)
+	NewListSecretsPager(options *azsecrets.ListSecretsOptions) *runtime.Pager[azsecrets.ListSecretsResponse]
+}
+
 type keyvaultSecretStore struct {
-	vaultName      string
-	vaultClient    *azsecrets.Client
-	vaultDNSSuffix string
+	vaultName          string
+	vaultClient        secretsClient
+	vaultDNSSuffix     string
+	bulkGetConcurrency int
 
 	logger logger.Logger
 }
 
 type KeyvaultMetadata struct {
-	VaultName string
+	VaultName string `mdrequired:"true"`
+	// Maximum number of secrets fetched concurrently by BulkGetSecret.
+	BulkGetConcurrency int
 }
 
 // NewAzureKeyvaultSecretStore returns a new Azure Key Vault secret store.
@@ -96,6 +113,10 @@ func (k *keyvaultSecretStore) Init(meta secretstores.Metadata) error {
 
 	k.vaultName = m.VaultName
 	k.vaultDNSSuffix = settings.AzureEnvironment.KeyVaultDNSSuffix
+	k.bulkGetConcurrency = m.BulkGetConcurrency
+	if k.bulkGetConcurrency <= 0 {
+		k.bulkGetConcurrency = defaultBulkGetConcurrency
+	}
 
 	cred, err := settings.GetTokenCredential()
 	if err != nil {
@@ -118,6 +139,8 @@ func (k *keyvaultSecretStore) GetSecret(ctx context.Context, req secretstores.Ge
 	version := "" // empty string means latest version
 	if val, ok := req.Metadata[VersionID]; ok {
 		version = val
+	} else if val, ok := req.Metadata[Version]; ok {
+		version = val
 	}
 
 	secretResp, err := k.vaultClient.GetSecret(ctx, req.Name, version, nil)
@@ -144,46 +167,105 @@ func (k *keyvaultSecretStore) BulkGetSecret(ctx context.Context, req secretstore
 		return secretstores.BulkGetSecretResponse{}, err
 	}
 
+	limit := 0
+	if maxResults != nil && *maxResults > 0 {
+		limit = int(*maxResults)
+	}
+
 	resp := secretstores.BulkGetSecretResponse{
 		Data: map[string]map[string]string{},
 	}
 
-	secretIDPrefix := k.getVaultURI() + secretItemIDPrefix
-
-	pager := k.vaultClient.NewListSecretsPager(nil)
+	opts := &azsecrets.ListSecretsOptions{}
+	if limit > 0 && limit < maxListPageSize {
+		pageSize := int32(limit) //nolint:gosec
+		opts.MaxResults = &pageSize
+	}
+	pager := k.vaultClient.NewListSecretsPager(opts)
 
-out:
-	for pager.More() {
+	secretIDPrefix := k.getVaultURI() + secretItemIDPrefix
+	for pager.More() && (limit == 0 || len(resp.Data) < limit) {
 		pr, err := pager.NextPage(ctx)
 		if err != nil {
 			return secretstores.BulkGetSecretResponse{}, err
 		}
 
+		names := make([]string, 0, len(pr.Value))
 		for _, secret := range pr.Value {
-			if secret.Attributes == nil || secret.Attributes.Enabled == nil || !*secret.Attributes.Enabled {
+			if limit > 0 && len(resp.Data)+len(names) >= limit {
+				break
+			}
+			if secret.ID == nil || secret.Attributes == nil || secret.Attributes.Enabled == nil || !*secret.Attributes.Enabled {
 				continue
 			}
 
-			secretName := strings.TrimPrefix(secret.ID.Name(), secretIDPrefix)
-			secretResp, err := k.vaultClient.GetSecret(ctx, secretName, "", nil) // empty string means latest version
+			names = append(names, strings.TrimPrefix(secret.ID.Name(), secretIDPrefix))
+		}
+
+		// The secrets of each page are fetched concurrently
+		err = k.getSecrets(ctx, names, resp.Data)
+		if err != nil {
+			return secretstores.BulkGetSecretResponse{}, err
+		}
+	}
+
+	return resp, nil
+}
+
+// getSecrets fetches the latest version of the secrets with the given names, at most bulkGetConcurrency at a time, and
+// adds them to data.
+func (k *keyvaultSecretStore) getSecrets(ctx context.Context, names []string, data map[string]map[string]string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		lock     sync.Mutex
+		firstErr error
+	)
+	sem := make(chan struct{}, k.bulkGetConcurrency)
+	for _, name := range names {
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func(name string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			secretResp, err := k.vaultClient.GetSecret(ctx, name, "", nil) // empty string means latest version
+
+			lock.Lock()
+			defer lock.Unlock()
 			if err != nil {
-				return secretstores.BulkGetSecretResponse{}, err
+				if firstErr == nil {
+					firstErr = err
+					// Stop fetching the other secrets
+					cancel()
+				}
+				return
 			}
 
 			secretValue := ""
 			if secretResp.Value != nil {
 				secretValue = *secretResp.Value
 			}
+			data[name] = map[string]string{name: secretValue}
+		}(name)
+	}
+	wg.Wait()
 
-			resp.Data[secretName] = map[string]string{secretName: secretValue}
-		}
-
-		if maxResults != nil && *maxResults > 0 && len(resp.Data) >= int(*maxResults) {
-			break out
-		}
+	if firstErr == nil {
+		// The context may have been canceled by the caller
+		firstErr = ctx.Err()
 	}
 
-	return resp, nil
+	return firstErr
 }
 
 // getVaultURI returns Azure Key Vault URI.
@@ -216,3 +298,11 @@ func (k *keyvaultSecretStore) GetComponentMetadata() map[string]string {
 	metadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo)
 	return metadataInfo
 }
+
+// GetComponentMetadataSchema returns the schema of the metadata of the Key Vault secret store.
+func (k *keyvaultSecretStore) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(KeyvaultMetadata{
+		BulkGetConcurrency: defaultBulkGetConcurrency,
+	})
+	return fields
+}
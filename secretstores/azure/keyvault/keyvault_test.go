@@ -15,9 +15,18 @@ limitations under the License.
 package keyvault
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/keyvault/azsecrets"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/components-contrib/secretstores"
 	"github.com/dapr/kit/logger"
@@ -57,6 +66,18 @@ func TestInit(t *testing.T) {
 		assert.Equal(t, kv.vaultDNSSuffix, "vault.azure.cn")
 		assert.NotNil(t, kv.vaultClient)
 	})
+	t.Run("Init with user-assigned managed identity", func(t *testing.T) {
+		m.Properties = map[string]string{
+			"vaultName":          "foo",
+			"azureMsiResourceId": "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/id",
+		}
+		err := s.Init(m)
+		assert.Nil(t, err)
+		kv, ok := s.(*keyvaultSecretStore)
+		assert.True(t, ok)
+		assert.Equal(t, kv.vaultName, "foo")
+		assert.NotNil(t, kv.vaultClient)
+	})
 	t.Run("Init with Azure environment as part of vaultName FQDN (1) - legacy", func(t *testing.T) {
 		m.Properties = map[string]string{
 			"vaultName":         "foo.vault.azure.cn",
@@ -97,3 +118,178 @@ func TestGetFeatures(t *testing.T) {
 		assert.Empty(t, f)
 	})
 }
+
+type fakeSecretsClient struct {
+	// Values of the secrets by name and version; the latest version is the empty string
+	secrets  map[string]map[string]string
+	disabled map[string]bool
+	order    []string
+
+	lock      sync.Mutex
+	inFlight  int
+	maxFlight int
+	pageSizes []int32
+}
+
+func (c *fakeSecretsClient) GetSecret(ctx context.Context, name string, version string, options *azsecrets.GetSecretOptions) (azsecrets.GetSecretResponse, error) {
+	c.lock.Lock()
+	c.inFlight++
+	if c.inFlight > c.maxFlight {
+		c.maxFlight = c.inFlight
+	}
+	c.lock.Unlock()
+	defer func() {
+		c.lock.Lock()
+		c.inFlight--
+		c.lock.Unlock()
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	value, ok := c.secrets[name][version]
+	if !ok {
+		return azsecrets.GetSecretResponse{}, errors.New("secret not found: " + name)
+	}
+
+	return azsecrets.GetSecretResponse{SecretBundle: azsecrets.SecretBundle{Value: &value}}, nil
+}
+
+func (c *fakeSecretsClient) NewListSecretsPager(options *azsecrets.ListSecretsOptions) *runtime.Pager[azsecrets.ListSecretsResponse] {
+	pageSize := 25
+	if options != nil && options.MaxResults != nil {
+		pageSize = int(*options.MaxResults)
+		c.pageSizes = append(c.pageSizes, *options.MaxResults)
+	}
+
+	return runtime.NewPager(runtime.PagingHandler[azsecrets.ListSecretsResponse]{
+		More: func(page azsecrets.ListSecretsResponse) bool {
+			return page.NextLink != nil
+		},
+		Fetcher: func(ctx context.Context, page *azsecrets.ListSecretsResponse) (azsecrets.ListSecretsResponse, error) {
+			start := 0
+			if page != nil {
+				start, _ = strconv.Atoi(*page.NextLink)
+			}
+			end := start + pageSize
+			if end > len(c.order) {
+				end = len(c.order)
+			}
+
+			resp := azsecrets.ListSecretsResponse{}
+			for _, name := range c.order[start:end] {
+				id := azsecrets.ID("https://foo.vault.azure.net/secrets/" + name)
+				enabled := !c.disabled[name]
+				resp.Value = append(resp.Value, &azsecrets.SecretItem{
+					ID:         &id,
+					Attributes: &azsecrets.SecretAttributes{Enabled: &enabled},
+				})
+			}
+			if end < len(c.order) {
+				next := strconv.Itoa(end)
+				resp.NextLink = &next
+			}
+
+			return resp, nil
+		},
+	})
+}
+
+func newFakeSecretStore(count int) (*keyvaultSecretStore, *fakeSecretsClient) {
+	client := &fakeSecretsClient{
+		secrets:  map[string]map[string]string{},
+		disabled: map[string]bool{},
+	}
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("secret%02d", i)
+		client.order = append(client.order, name)
+		client.secrets[name] = map[string]string{"": "value" + strconv.Itoa(i)}
+	}
+
+	return &keyvaultSecretStore{
+		vaultName:          "foo",
+		vaultDNSSuffix:     "vault.azure.net",
+		vaultClient:        client,
+		bulkGetConcurrency: 3,
+		logger:             logger.NewLogger("test"),
+	}, client
+}
+
+func TestGetSecret(t *testing.T) {
+	s, client := newFakeSecretStore(1)
+	client.secrets["secret00"]["v1"] = "old"
+
+	t.Run("latest version", func(t *testing.T) {
+		resp, err := s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "secret00"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"secret00": "value0"}, resp.Data)
+	})
+
+	t.Run("version selected with the version metadata", func(t *testing.T) {
+		resp, err := s.GetSecret(context.Background(), secretstores.GetSecretRequest{
+			Name:     "secret00",
+			Metadata: map[string]string{"version": "v1"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"secret00": "old"}, resp.Data)
+	})
+
+	t.Run("version selected with the version_id metadata", func(t *testing.T) {
+		resp, err := s.GetSecret(context.Background(), secretstores.GetSecretRequest{
+			Name:     "secret00",
+			Metadata: map[string]string{"version_id": "v1"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"secret00": "old"}, resp.Data)
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		_, err := s.GetSecret(context.Background(), secretstores.GetSecretRequest{
+			Name:     "secret00",
+			Metadata: map[string]string{"version": "v2"},
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestBulkGetSecret(t *testing.T) {
+	t.Run("all the enabled secrets across pages", func(t *testing.T) {
+		s, client := newFakeSecretStore(60)
+		client.disabled["secret07"] = true
+
+		resp, err := s.BulkGetSecret(context.Background(), secretstores.BulkGetSecretRequest{})
+		require.NoError(t, err)
+		assert.Len(t, resp.Data, 59)
+		assert.Equal(t, map[string]string{"secret42": "value42"}, resp.Data["secret42"])
+		assert.NotContains(t, resp.Data, "secret07")
+		assert.LessOrEqual(t, client.maxFlight, 3)
+		assert.Greater(t, client.maxFlight, 1)
+	})
+
+	t.Run("maxresults limits the secrets", func(t *testing.T) {
+		s, client := newFakeSecretStore(60)
+
+		resp, err := s.BulkGetSecret(context.Background(), secretstores.BulkGetSecretRequest{
+			Metadata: map[string]string{"maxresults": "10"},
+		})
+		require.NoError(t, err)
+		assert.Len(t, resp.Data, 10)
+		assert.Contains(t, resp.Data, "secret09")
+		assert.Equal(t, []int32{10}, client.pageSizes)
+	})
+
+	t.Run("error fetching a secret", func(t *testing.T) {
+		s, client := newFakeSecretStore(30)
+		delete(client.secrets, "secret12")
+
+		_, err := s.BulkGetSecret(context.Background(), secretstores.BulkGetSecretRequest{})
+		assert.ErrorContains(t, err, "secret12")
+	})
+
+	t.Run("invalid maxresults", func(t *testing.T) {
+		s, _ := newFakeSecretStore(1)
+
+		_, err := s.BulkGetSecret(context.Background(), secretstores.BulkGetSecretRequest{
+			Metadata: map[string]string{"maxresults": "ten"},
+		})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,336 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package composite implements a secret store chaining other secret stores, so that secrets can be moved between
+// backends without changes to the applications.
+package composite
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/secretstores"
+	"github.com/dapr/kit/logger"
+)
+
+const errorPrefix = "composite secret store error: "
+
+var _ secretstores.SecretStore = (*compositeSecretStore)(nil)
+
+// StoreFactory creates the chained stores, by component type, such as "secretstores.local.env", and version.
+// It is provided by the runtime, from its registry of secret stores.
+type StoreFactory func(storeType string, version string) (secretstores.SecretStore, error)
+
+type compositeSecretStore struct {
+	// Stores, in fallback order
+	stores  []namedStore
+	routes  []route
+	factory StoreFactory
+
+	logger logger.Logger
+}
+
+type namedStore struct {
+	name  string
+	store secretstores.SecretStore
+}
+
+type CompositeMetadata struct {
+	// JSON array of the chained stores, in fallback order, as [{"name": "...", "type": "...", "version": "...", "metadata": {...}}].
+	// The metadata of a store can also be given with properties named "<store name>.<key>", which override its inline
+	// metadata; credentials must be given this way, so that they can be read with secretKeyRef.
+	Stores string `mdrequired:"true"`
+	// JSON array of the routing rules, as [{"prefix": "...", "stores": ["..."]}].
+	// The secrets whose name starts with the prefix of a rule are read only from its stores, in order; the longest
+	// prefix wins.
+	Routes string
+}
+
+type storeConfig struct {
+	Name     string            `json:"name"`
+	Type     string            `json:"type"`
+	Version  string            `json:"version"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+type route struct {
+	Prefix string   `json:"prefix"`
+	Stores []string `json:"stores"`
+}
+
+// NewCompositeSecretStore returns a new composite secret store, creating the chained stores with the factory.
+func NewCompositeSecretStore(logger logger.Logger, factory StoreFactory) secretstores.SecretStore {
+	return &compositeSecretStore{
+		factory: factory,
+		logger:  logger,
+	}
+}
+
+// Init creates and initializes the chained stores.
+func (s *compositeSecretStore) Init(meta secretstores.Metadata) error {
+	m := CompositeMetadata{}
+	if err := metadata.DecodeMetadata(meta.Properties, &m); err != nil {
+		return err
+	}
+
+	var configs []storeConfig
+	if err := json.Unmarshal([]byte(m.Stores), &configs); err != nil {
+		return fmt.Errorf(errorPrefix+"invalid stores: %w", err)
+	}
+	if len(configs) == 0 {
+		return errors.New(errorPrefix + "at least one store must be configured")
+	}
+	if m.Routes != "" {
+		if err := json.Unmarshal([]byte(m.Routes), &s.routes); err != nil {
+			return fmt.Errorf(errorPrefix+"invalid routes: %w", err)
+		}
+	}
+
+	names := make(map[string]bool, len(configs))
+	for _, c := range configs {
+		if c.Name == "" {
+			return errors.New(errorPrefix + "the stores must have a name")
+		}
+		if names[c.Name] {
+			return fmt.Errorf(errorPrefix+"duplicate store %s", c.Name)
+		}
+		names[c.Name] = true
+	}
+	for _, r := range s.routes {
+		if len(r.Stores) == 0 {
+			return fmt.Errorf(errorPrefix+"no stores for the route of prefix %s", r.Prefix)
+		}
+		for _, name := range r.Stores {
+			if !names[name] {
+				return fmt.Errorf(errorPrefix+"unknown store %s in the route of prefix %s", name, r.Prefix)
+			}
+		}
+	}
+
+	s.stores = make([]namedStore, 0, len(configs))
+	for _, c := range configs {
+		storeType := c.Type
+		if !strings.HasPrefix(storeType, "secretstores.") {
+			storeType = "secretstores." + storeType
+		}
+		version := c.Version
+		if version == "" {
+			version = "v1"
+		}
+		store, err := s.factory(storeType, version)
+		if err != nil {
+			s.Close()
+			return fmt.Errorf(errorPrefix+"unsupported type %s for store %s: %w", c.Type, c.Name, err)
+		}
+		err = store.Init(secretstores.Metadata{Base: metadata.Base{
+			Name:       meta.Name + "-" + c.Name,
+			Properties: storeProperties(c, meta.Properties),
+		}})
+		if err != nil {
+			s.Close()
+			return fmt.Errorf(errorPrefix+"failed to init store %s: %w", c.Name, err)
+		}
+		s.stores = append(s.stores, namedStore{name: c.Name, store: store})
+	}
+
+	return nil
+}
+
+// storeProperties returns the metadata of a chained store: its inline metadata, overridden by the component properties
+// prefixed with its name.
+func storeProperties(c storeConfig, properties map[string]string) map[string]string {
+	res := make(map[string]string, len(c.Metadata))
+	for k, v := range c.Metadata {
+		res[k] = v
+	}
+	prefix := c.Name + "."
+	for k, v := range properties {
+		if strings.HasPrefix(k, prefix) && len(k) > len(prefix) {
+			res[k[len(prefix):]] = v
+		}
+	}
+
+	return res
+}
+
+// GetSecret retrieves the secret from the first store which has it.
+// A store is considered not to have the secret if it returns a not found error, or only empty values, as the env store
+// does for the variables which are not set. Any other error is returned, without falling back to the next stores, which
+// could hold a stale value of the secret.
+func (s *compositeSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecretRequest) (secretstores.GetSecretResponse, error) {
+	for _, ns := range s.storesFor(req.Name) {
+		resp, err := ns.store.GetSecret(ctx, req)
+		if err != nil {
+			if isNotFound(err) {
+				s.logger.Debugf("Secret %s not found in store %s: %v", req.Name, ns.name, err)
+				continue
+			}
+
+			return secretstores.GetSecretResponse{}, fmt.Errorf(errorPrefix+"failed to read secret %s from store %s: %w", req.Name, ns.name, err)
+		}
+		if hasValue(resp.Data) {
+			return resp, nil
+		}
+	}
+
+	return secretstores.GetSecretResponse{}, fmt.Errorf(errorPrefix+"secret %s %w", req.Name, secretstores.ErrSecretNotFound)
+}
+
+// BulkGetSecret retrieves the secrets of all the stores.
+// Each secret is taken from the first store which has it, as with GetSecret, among the stores of its route if any. If
+// any store fails, the error is returned.
+func (s *compositeSecretStore) BulkGetSecret(ctx context.Context, req secretstores.BulkGetSecretRequest) (secretstores.BulkGetSecretResponse, error) {
+	results := make(map[string]map[string]map[string]string, len(s.stores))
+	for _, ns := range s.stores {
+		resp, err := ns.store.BulkGetSecret(ctx, req)
+		if err != nil {
+			return secretstores.BulkGetSecretResponse{}, fmt.Errorf(errorPrefix+"failed to read the secrets of store %s: %w", ns.name, err)
+		}
+		results[ns.name] = resp.Data
+	}
+
+	data := map[string]map[string]string{}
+	for _, ns := range s.stores {
+		for key := range results[ns.name] {
+			if _, ok := data[key]; ok {
+				continue
+			}
+			for _, candidate := range s.storesFor(key) {
+				secret, ok := results[candidate.name][key]
+				if !ok {
+					continue
+				}
+				if _, found := data[key]; !found || hasValue(secret) {
+					data[key] = secret
+				}
+				if hasValue(secret) {
+					break
+				}
+			}
+		}
+	}
+
+	return secretstores.BulkGetSecretResponse{Data: data}, nil
+}
+
+// storesFor returns the stores the secret is read from, in order.
+func (s *compositeSecretStore) storesFor(name string) []namedStore {
+	var match *route
+	for i, r := range s.routes {
+		if strings.HasPrefix(name, r.Prefix) && (match == nil || len(r.Prefix) > len(match.Prefix)) {
+			match = &s.routes[i]
+		}
+	}
+	if match == nil {
+		return s.stores
+	}
+
+	stores := make([]namedStore, 0, len(match.Stores))
+	for _, name := range match.Stores {
+		for _, ns := range s.stores {
+			if ns.name == name {
+				stores = append(stores, ns)
+			}
+		}
+	}
+
+	return stores
+}
+
+// isNotFound returns true if the error of a store means that it does not have the secret, rather than a failure.
+// Besides secretstores.ErrSecretNotFound, the not found errors of the SDKs of the stores are recognized.
+func isNotFound(err error) bool {
+	if errors.Is(err, secretstores.ErrSecretNotFound) || apierrors.IsNotFound(err) {
+		return true
+	}
+
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &grpcErr) {
+		return grpcErr.GRPCStatus().Code() == codes.NotFound
+	}
+
+	var azErr *azcore.ResponseError
+	if errors.As(err, &azErr) {
+		return azErr.StatusCode == http.StatusNotFound
+	}
+
+	// AWS errors have a Code and Tencent Cloud errors a GetCode method
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		return strings.HasPrefix(awsErr.Code(), "ResourceNotFound") || awsErr.Code() == "ParameterNotFound"
+	}
+	var tencentErr interface{ GetCode() string }
+	if errors.As(err, &tencentErr) {
+		return strings.HasPrefix(tencentErr.GetCode(), "ResourceNotFound")
+	}
+
+	return false
+}
+
+func hasValue(data map[string]string) bool {
+	for _, v := range data {
+		if v != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Close closes the chained stores which need it.
+func (s *compositeSecretStore) Close() error {
+	var errs []string
+	for _, ns := range s.stores {
+		if closer, ok := ns.store.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, ns.name+": "+err.Error())
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(errorPrefix + "failed to close stores: " + strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// Features returns the features available in this secret store.
+func (s *compositeSecretStore) Features() []secretstores.Feature {
+	return []secretstores.Feature{} // No Feature supported.
+}
+
+func (s *compositeSecretStore) GetComponentMetadata() map[string]string {
+	metadataStruct := CompositeMetadata{}
+	metadataInfo := map[string]string{}
+	metadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo)
+	return metadataInfo
+}
+
+// GetComponentMetadataSchema returns the schema of the metadata of the composite secret store.
+func (s *compositeSecretStore) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(CompositeMetadata{})
+	return fields
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/secretstores"
+	"github.com/dapr/kit/logger"
+)
+
+// fakeStore serves its metadata properties as secrets.
+type fakeStore struct {
+	secrets map[string]string
+	fail    bool
+	closed  bool
+}
+
+func (f *fakeStore) Init(meta secretstores.Metadata) error {
+	if meta.Properties["initError"] != "" {
+		return errors.New(meta.Properties["initError"])
+	}
+	f.fail = meta.Properties["fail"] == "true"
+	f.secrets = map[string]string{}
+	for k, v := range meta.Properties {
+		if k != "fail" {
+			f.secrets[k] = v
+		}
+	}
+	return nil
+}
+
+func (f *fakeStore) GetSecret(ctx context.Context, req secretstores.GetSecretRequest) (secretstores.GetSecretResponse, error) {
+	if f.fail {
+		return secretstores.GetSecretResponse{}, errors.New("unavailable")
+	}
+	v, ok := f.secrets[req.Name]
+	if !ok {
+		return secretstores.GetSecretResponse{}, fmt.Errorf("secret %s %w", req.Name, secretstores.ErrSecretNotFound)
+	}
+	return secretstores.GetSecretResponse{Data: map[string]string{req.Name: v}}, nil
+}
+
+func (f *fakeStore) BulkGetSecret(ctx context.Context, req secretstores.BulkGetSecretRequest) (secretstores.BulkGetSecretResponse, error) {
+	if f.fail {
+		return secretstores.BulkGetSecretResponse{}, errors.New("unavailable")
+	}
+	data := map[string]map[string]string{}
+	for k, v := range f.secrets {
+		data[k] = map[string]string{k: v}
+	}
+	return secretstores.BulkGetSecretResponse{Data: data}, nil
+}
+
+func (f *fakeStore) Features() []secretstores.Feature {
+	return nil
+}
+
+func (f *fakeStore) GetComponentMetadata() map[string]string {
+	return nil
+}
+
+func (f *fakeStore) Close() error {
+	f.closed = true
+	return nil
+}
+
+func fakeFactory(storeType string, version string) (secretstores.SecretStore, error) {
+	if storeType != "secretstores.fake" || version != "v1" {
+		return nil, fmt.Errorf("couldn't find secret store %s/%s", storeType, version)
+	}
+
+	return &fakeStore{}, nil
+}
+
+func newStoreWithProperties(t *testing.T, properties map[string]string) (*compositeSecretStore, error) {
+	t.Helper()
+
+	s := NewCompositeSecretStore(logger.NewLogger("test"), fakeFactory).(*compositeSecretStore)
+	err := s.Init(secretstores.Metadata{Base: metadata.Base{
+		Name:       "composite",
+		Properties: properties,
+	}})
+
+	return s, err
+}
+
+func newStore(t *testing.T, stores string, routes string) (*compositeSecretStore, error) {
+	t.Helper()
+
+	return newStoreWithProperties(t, map[string]string{
+		"stores": stores,
+		"routes": routes,
+	})
+}
+
+const testStores = `[
+	{"name": "k8s", "type": "secretstores.fake", "metadata": {"db": "k8s-db", "empty": ""}},
+	{"name": "vault", "type": "fake", "metadata": {"db": "vault-db", "api": "vault-api", "legacy/token": "vault-token"}},
+	{"name": "env", "type": "fake", "metadata": {"api": "env-api", "legacy/token": "env-token", "empty": "env-empty"}}
+]`
+
+func TestInit(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		s, err := newStore(t, testStores, `[{"prefix": "legacy/", "stores": ["env"]}]`)
+		require.NoError(t, err)
+		require.Len(t, s.stores, 3)
+		assert.Equal(t, "k8s", s.stores[0].name)
+		assert.Equal(t, "env", s.stores[2].name)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		tests := map[string][2]string{
+			"no stores":         {``, ``},
+			"empty stores":      {`[]`, ``},
+			"unnamed store":     {`[{"type": "fake"}]`, ``},
+			"duplicate store":   {`[{"name": "a", "type": "fake"}, {"name": "a", "type": "fake"}]`, ``},
+			"unsupported type":  {`[{"name": "a", "type": "unknown"}]`, ``},
+			"unknown version":   {`[{"name": "a", "type": "fake", "version": "v2"}]`, ``},
+			"invalid routes":    {`[{"name": "a", "type": "fake"}]`, `{}`},
+			"unknown route":     {`[{"name": "a", "type": "fake"}]`, `[{"prefix": "p", "stores": ["b"]}]`},
+			"route sans stores": {`[{"name": "a", "type": "fake"}]`, `[{"prefix": "p"}]`},
+		}
+		for name, tc := range tests {
+			t.Run(name, func(t *testing.T) {
+				_, err := newStore(t, tc[0], tc[1])
+				assert.Error(t, err)
+			})
+		}
+	})
+
+	t.Run("metadata from the properties", func(t *testing.T) {
+		s, err := newStoreWithProperties(t, map[string]string{
+			"stores":      `[{"name": "vault", "type": "fake", "metadata": {"address": "inline", "token": "inline"}}]`,
+			"vault.token": "from-secret",
+			"vault.":      "ignored",
+			"other.token": "ignored",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"address": "inline", "token": "from-secret"}, s.stores[0].store.(*fakeStore).secrets)
+	})
+
+	t.Run("failed store init closes the others", func(t *testing.T) {
+		s, err := newStore(t, `[{"name": "a", "type": "fake"}, {"name": "b", "type": "fake", "metadata": {"initError": "boom"}}]`, ``)
+		assert.ErrorContains(t, err, "failed to init store b: boom")
+		assert.True(t, s.stores[0].store.(*fakeStore).closed)
+	})
+}
+
+func TestGetSecret(t *testing.T) {
+	s, err := newStore(t, testStores, `[{"prefix": "legacy/", "stores": ["env", "vault"]}, {"prefix": "legacy/x", "stores": ["k8s"]}]`)
+	require.NoError(t, err)
+
+	get := func(name string) (string, error) {
+		resp, err := s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: name})
+		return resp.Data[name], err
+	}
+
+	tests := map[string]string{
+		"db":           "k8s-db",
+		"api":          "vault-api",
+		"empty":        "env-empty",
+		"legacy/token": "env-token",
+	}
+	for name, expected := range tests {
+		v, err := get(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, expected, v, name)
+	}
+
+	_, err = get("missing")
+	assert.ErrorContains(t, err, "secret missing not found")
+	assert.ErrorIs(t, err, secretstores.ErrSecretNotFound)
+
+	// The longest prefix wins
+	s.stores[1].store.(*fakeStore).secrets["legacy/xyz"] = "vault-xyz"
+	_, err = get("legacy/xyz")
+	assert.ErrorIs(t, err, secretstores.ErrSecretNotFound)
+
+	t.Run("failed stores are not skipped", func(t *testing.T) {
+		s.stores[0].store.(*fakeStore).fail = true
+		defer func() {
+			s.stores[0].store.(*fakeStore).fail = false
+		}()
+
+		_, err := get("db")
+		assert.ErrorContains(t, err, "failed to read secret db from store k8s: unavailable")
+		assert.NotErrorIs(t, err, secretstores.ErrSecretNotFound)
+	})
+}
+
+func TestBulkGetSecret(t *testing.T) {
+	s, err := newStore(t, testStores, `[{"prefix": "legacy/", "stores": ["env"]}]`)
+	require.NoError(t, err)
+
+	resp, err := s.BulkGetSecret(context.Background(), secretstores.BulkGetSecretRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{
+		"db":           {"db": "k8s-db"},
+		"api":          {"api": "vault-api"},
+		"empty":        {"empty": "env-empty"},
+		"legacy/token": {"legacy/token": "env-token"},
+	}, resp.Data)
+
+	s.stores[1].store.(*fakeStore).fail = true
+	_, err = s.BulkGetSecret(context.Background(), secretstores.BulkGetSecretRequest{})
+	assert.ErrorContains(t, err, "failed to read the secrets of store vault: unavailable")
+
+	require.NoError(t, s.Close())
+	assert.True(t, s.stores[0].store.(*fakeStore).closed)
+}
+
+func TestIsNotFound(t *testing.T) {
+	notFound := []error{
+		fmt.Errorf("secret a %w", secretstores.ErrSecretNotFound),
+		apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "a"),
+		fmt.Errorf("failed to access secret version: %w", status.Error(codes.NotFound, "a")),
+		&azcore.ResponseError{StatusCode: http.StatusNotFound},
+		fmt.Errorf("couldn't get secret: %w", awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "a", nil)),
+		awserr.New("ParameterNotFound", "a", nil),
+	}
+	for i, err := range notFound {
+		assert.True(t, isNotFound(err), "error %d", i)
+	}
+
+	failures := []error{
+		errors.New("unavailable"),
+		apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "a", errors.New("denied")),
+		status.Error(codes.PermissionDenied, "a"),
+		&azcore.ResponseError{StatusCode: http.StatusForbidden},
+		awserr.New(secretsmanager.ErrCodeDecryptionFailure, "a", nil),
+		context.DeadlineExceeded,
+	}
+	for i, err := range failures {
+		assert.False(t, isNotFound(err), "error %d", i)
+	}
+}
@@ -15,15 +15,18 @@ package secretmanager
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
 	"google.golang.org/api/iterator"
-	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	gcpauth "github.com/dapr/components-contrib/internal/authentication/gcp"
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/secretstores"
 	"github.com/dapr/kit/logger"
@@ -31,7 +34,13 @@ import (
 	"github.com/googleapis/gax-go/v2"
 )
 
-const VersionID = "version_id"
+const (
+	VersionID = "version_id"
+	// Filter is the request metadata of the bulk requests filtering the secrets, overriding the filter of the component.
+	Filter = "filter"
+
+	latestVersion = "latest"
+)
 
 type GcpSecretManagerMetadata struct {
 	Type                string `mapstructure:"type" json:"type"`
@@ -44,20 +53,43 @@ type GcpSecretManagerMetadata struct {
 	TokenURI            string `mapstructure:"token_uri" json:"token_uri"`
 	AuthProviderCertURL string `mapstructure:"auth_provider_x509_cert_url" json:"auth_provider_x509_cert_url"`
 	ClientCertURL       string `mapstructure:"client_x509_cert_url" json:"client_x509_cert_url"`
+	// Versions pinned for some secrets, as a comma-separated list of name=version; the other secrets are read at their
+	// latest version, unless the requests set the version.
+	Versions string `mapstructure:"versions" json:"versions"`
+	// Filter of the secrets of the bulk requests, in the syntax of the list filters of Secret Manager, such as
+	// "labels.app=myapp" or "name:myapp-".
+	Filter string `mapstructure:"filter" json:"filter"`
+}
+
+// secretIterator iterates over the secrets listed by the client.
+type secretIterator interface {
+	Next() (*secretmanagerpb.Secret, error)
 }
 
 type gcpSecretemanagerClient interface {
 	AccessSecretVersion(ctx context.Context, req *secretmanagerpb.AccessSecretVersionRequest, opts ...gax.CallOption) (*secretmanagerpb.AccessSecretVersionResponse, error)
-	ListSecrets(ctx context.Context, req *secretmanagerpb.ListSecretsRequest, opts ...gax.CallOption) *secretmanager.SecretIterator
+	ListSecrets(ctx context.Context, req *secretmanagerpb.ListSecretsRequest, opts ...gax.CallOption) secretIterator
 	Close() error
 }
 
+// secretManagerClient adapts the Secret Manager client to gcpSecretemanagerClient.
+type secretManagerClient struct {
+	*secretmanager.Client
+}
+
+func (c secretManagerClient) ListSecrets(ctx context.Context, req *secretmanagerpb.ListSecretsRequest, opts ...gax.CallOption) secretIterator {
+	return c.Client.ListSecrets(ctx, req, opts...)
+}
+
 var _ secretstores.SecretStore = (*Store)(nil)
 
 // Store contains and GCP secret manager client and project id.
 type Store struct {
 	client    gcpSecretemanagerClient
 	ProjectID string
+	// Versions pinned by secret name.
+	versions map[string]string
+	filter   string
 
 	logger logger.Logger
 }
@@ -74,23 +106,43 @@ func (s *Store) Init(metadataRaw secretstores.Metadata) error {
 		return err
 	}
 
+	versions, err := parseVersions(metadata.Versions)
+	if err != nil {
+		return err
+	}
+
 	client, err := s.getClient(metadata)
 	if err != nil {
 		return fmt.Errorf("failed to setup secretmanager client: %s", err)
 	}
 
-	s.client = client
+	s.client = secretManagerClient{client}
 	s.ProjectID = metadata.ProjectID
+	s.versions = versions
+	s.filter = metadata.Filter
 
 	return nil
 }
 
 func (s *Store) getClient(metadata *GcpSecretManagerMetadata) (*secretmanager.Client, error) {
-	b, _ := json.Marshal(metadata)
-	clientOptions := option.WithCredentialsJSON(b)
+	clientOptions, err := gcpauth.ClientOptions(gcpauth.ServiceAccountKey{
+		Type:                metadata.Type,
+		ProjectID:           metadata.ProjectID,
+		PrivateKeyID:        metadata.PrivateKeyID,
+		PrivateKey:          metadata.PrivateKey,
+		ClientEmail:         metadata.ClientEmail,
+		ClientID:            metadata.ClientID,
+		AuthURI:             metadata.AuthURI,
+		TokenURI:            metadata.TokenURI,
+		AuthProviderCertURL: metadata.AuthProviderCertURL,
+		ClientCertURL:       metadata.ClientCertURL,
+	})
+	if err != nil {
+		return nil, err
+	}
 	ctx := context.Background()
 
-	client, err := secretmanager.NewClient(ctx, clientOptions)
+	client, err := secretmanager.NewClient(ctx, clientOptions...)
 	if err != nil {
 		return nil, err
 	}
@@ -111,23 +163,25 @@ func (s *Store) GetSecret(ctx context.Context, req secretstores.GetSecretRequest
 	}
 	secretName := fmt.Sprintf("projects/%s/secrets/%s", s.ProjectID, req.Name)
 
-	versionID := "latest"
-	if value, ok := req.Metadata[VersionID]; ok {
+	versionID := s.version(req.Name)
+	if value, ok := req.Metadata[VersionID]; ok && value != "" {
 		versionID = value
 	}
 
 	secret, err := s.getSecret(ctx, secretName, versionID)
 	if err != nil {
-		return res, fmt.Errorf("failed to access secret version: %v", err)
+		return res, fmt.Errorf("failed to access secret version: %w", err)
 	}
 
 	return secretstores.GetSecretResponse{Data: map[string]string{req.Name: *secret}}, nil
 }
 
-// BulkGetSecret retrieves all secrets in the store and returns a map of decrypted string/string values.
+// BulkGetSecret retrieves the secrets of the project matching the filter and returns a map of decrypted string/string
+// values, keyed by the names of the secrets. The secrets read at their latest version are skipped when they don't have
+// any enabled version, while the pinned versions must exist.
+// Breaking change: the secrets used to be keyed by their full resource names, projects/<project>/secrets/<name>,
+// rather than by the names used by GetSecret.
 func (s *Store) BulkGetSecret(ctx context.Context, req secretstores.BulkGetSecretRequest) (secretstores.BulkGetSecretResponse, error) {
-	versionID := "latest"
-
 	response := map[string]map[string]string{}
 
 	if s.client == nil {
@@ -136,6 +190,10 @@ func (s *Store) BulkGetSecret(ctx context.Context, req secretstores.BulkGetSecre
 
 	request := &secretmanagerpb.ListSecretsRequest{
 		Parent: fmt.Sprintf("projects/%s", s.ProjectID),
+		Filter: s.filter,
+	}
+	if value, ok := req.Metadata[Filter]; ok && value != "" {
+		request.Filter = value
 	}
 	it := s.client.ListSecrets(ctx, request)
 
@@ -150,17 +208,58 @@ func (s *Store) BulkGetSecret(ctx context.Context, req secretstores.BulkGetSecre
 			return secretstores.BulkGetSecretResponse{Data: nil}, fmt.Errorf("failed to list secrets: %v", err)
 		}
 
+		// The names of the secrets listed are their full resource names
 		name := resp.GetName()
-		secret, err := s.getSecret(ctx, name, versionID)
+		shortName := name[strings.LastIndex(name, "/")+1:]
+		version, pinned := s.versions[shortName]
+		if !pinned {
+			version = latestVersion
+		}
+		secret, err := s.getSecret(ctx, name, version)
 		if err != nil {
-			return secretstores.BulkGetSecretResponse{Data: nil}, fmt.Errorf("failed to access secret version: %v", err)
+			if code := status.Code(err); !pinned && (code == codes.NotFound || code == codes.FailedPrecondition) {
+				s.logger.Debugf("skipping secret %s without enabled version: %v", shortName, err)
+				continue
+			}
+			return secretstores.BulkGetSecretResponse{Data: nil}, fmt.Errorf("failed to access version %s of secret %s: %v", version, shortName, err)
 		}
-		response[name] = map[string]string{name: *secret}
+		response[shortName] = map[string]string{shortName: *secret}
 	}
 
 	return secretstores.BulkGetSecretResponse{Data: response}, nil
 }
 
+// version returns the version of the secret to read, pinned or latest.
+func (s *Store) version(name string) string {
+	if v, ok := s.versions[name]; ok {
+		return v
+	}
+
+	return latestVersion
+}
+
+// parseVersions parses the versions pinned, as name=version pairs.
+func parseVersions(val string) (map[string]string, error) {
+	versions := map[string]string{}
+	for _, pair := range strings.Split(val, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, version, ok := strings.Cut(pair, "=")
+		name, version = strings.TrimSpace(name), strings.TrimSpace(version)
+		if !ok || name == "" || version == "" {
+			return nil, fmt.Errorf("invalid property `versions` in metadata: %s is not name=version", pair)
+		}
+		if _, err := strconv.ParseUint(version, 10, 64); err != nil && version != latestVersion {
+			return nil, fmt.Errorf("invalid property `versions` in metadata: invalid version %s of secret %s", version, name)
+		}
+		versions[name] = version
+	}
+
+	return versions, nil
+}
+
 func (s *Store) getSecret(ctx context.Context, secretName string, versionID string) (*string, error) {
 	accessRequest := &secretmanagerpb.AccessSecretVersionRequest{
 		Name: fmt.Sprintf("%s/versions/%s", secretName, versionID),
@@ -179,16 +278,11 @@ func (s *Store) parseSecretManagerMetadata(metadataRaw secretstores.Metadata) (*
 	meta := GcpSecretManagerMetadata{}
 	metadata.DecodeMetadata(metadataRaw.Properties, &meta)
 
-	if meta.Type == "" {
-		return nil, fmt.Errorf("missing property `type` in metadata")
-	}
 	if meta.ProjectID == "" {
 		return nil, fmt.Errorf("missing property `project_id` in metadata")
 	}
-	if meta.PrivateKey == "" {
-		return nil, fmt.Errorf("missing property `private_key` in metadata")
-	}
-	if meta.ClientEmail == "" {
+	// Without the key of a service account, the client uses the Application Default Credentials
+	if meta.PrivateKey != "" && meta.ClientEmail == "" {
 		return nil, fmt.Errorf("missing property `client_email` in metadata")
 	}
 
@@ -18,10 +18,13 @@ import (
 	"fmt"
 	"testing"
 
-	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
 	"github.com/googleapis/gax-go/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/secretstores"
@@ -32,11 +35,54 @@ type MockStore struct {
 	gcpSecretemanagerClient
 }
 
-func (s *MockStore) ListSecrets(ctx context.Context, req *secretmanagerpb.ListSecretsRequest, opts ...gax.CallOption) *secretmanager.SecretIterator {
-	it := &secretmanager.SecretIterator{}
-	it.PageInfo().MaxSize = 1
-	it.Next()
-	return it
+func (s *MockStore) ListSecrets(ctx context.Context, req *secretmanagerpb.ListSecretsRequest, opts ...gax.CallOption) secretIterator {
+	return &mockIterator{}
+}
+
+type mockIterator struct {
+	secrets []*secretmanagerpb.Secret
+}
+
+func (it *mockIterator) Next() (*secretmanagerpb.Secret, error) {
+	if len(it.secrets) == 0 {
+		return nil, iterator.Done
+	}
+	secret := it.secrets[0]
+	it.secrets = it.secrets[1:]
+
+	return secret, nil
+}
+
+// mockVersionsStore stores versions of secrets by "name/versions/version", and records the requests.
+type mockVersionsStore struct {
+	gcpSecretemanagerClient
+
+	versions    map[string]string
+	listFilters []string
+	accessed    []string
+}
+
+func (s *mockVersionsStore) ListSecrets(ctx context.Context, req *secretmanagerpb.ListSecretsRequest, opts ...gax.CallOption) secretIterator {
+	s.listFilters = append(s.listFilters, req.Filter)
+
+	return &mockIterator{secrets: []*secretmanagerpb.Secret{
+		{Name: req.Parent + "/secrets/db-password"},
+		{Name: req.Parent + "/secrets/api-key"},
+		{Name: req.Parent + "/secrets/disabled"},
+	}}
+}
+
+func (s *mockVersionsStore) AccessSecretVersion(ctx context.Context, req *secretmanagerpb.AccessSecretVersionRequest, opts ...gax.CallOption) (*secretmanagerpb.AccessSecretVersionResponse, error) {
+	s.accessed = append(s.accessed, req.Name)
+	value, ok := s.versions[req.Name]
+	if !ok {
+		return nil, status.Error(codes.FailedPrecondition, "no enabled version")
+	}
+
+	return &secretmanagerpb.AccessSecretVersionResponse{
+		Name:    req.Name,
+		Payload: &secretmanagerpb.SecretPayload{Data: []byte(value)},
+	}, nil
 }
 
 func (s *MockStore) AccessSecretVersion(ctx context.Context, req *secretmanagerpb.AccessSecretVersionRequest, opts ...gax.CallOption) (*secretmanagerpb.AccessSecretVersionResponse, error) {
@@ -74,22 +120,31 @@ func TestInit(t *testing.T) {
 		assert.Equal(t, err, fmt.Errorf("failed to setup secretmanager client: google: could not parse key: private key should be a PEM or plain PKCS1 or PKCS8; parse error: asn1: syntax error: truncated tag or length"))
 	})
 
-	t.Run("Init with missing `type` metadata", func(t *testing.T) {
+	t.Run("Init with missing `project_id` metadata", func(t *testing.T) {
 		m.Properties = map[string]string{
-			"dummy": "a",
+			"type": "service_account",
 		}
 		err := sm.Init(m)
 		assert.NotNil(t, err)
-		assert.Equal(t, err, fmt.Errorf("missing property `type` in metadata"))
+		assert.Equal(t, err, fmt.Errorf("missing property `project_id` in metadata"))
 	})
 
-	t.Run("Init with missing `project_id` metadata", func(t *testing.T) {
+	t.Run("Init with `private_key` but no `client_email` metadata", func(t *testing.T) {
 		m.Properties = map[string]string{
-			"type": "service_account",
+			"project_id":  "a",
+			"private_key": "a",
 		}
 		err := sm.Init(m)
 		assert.NotNil(t, err)
-		assert.Equal(t, err, fmt.Errorf("missing property `project_id` in metadata"))
+		assert.Equal(t, err, fmt.Errorf("missing property `client_email` in metadata"))
+	})
+
+	t.Run("Parse metadata without service account key", func(t *testing.T) {
+		meta, err := sm.(*Store).parseSecretManagerMetadata(secretstores.Metadata{Base: metadata.Base{
+			Properties: map[string]string{"project_id": "a"},
+		}})
+		assert.Nil(t, err)
+		assert.Equal(t, "a", meta.ProjectID)
 	})
 }
 
@@ -180,6 +235,79 @@ func TestBulkGetSecret(t *testing.T) {
 	})
 }
 
+func TestVersionsAndFilter(t *testing.T) {
+	newStore := func() (*Store, *mockVersionsStore) {
+		versions, err := parseVersions("db-password=2")
+		require.NoError(t, err)
+		client := &mockVersionsStore{versions: map[string]string{
+			"projects/p/secrets/db-password/versions/1":      "v1",
+			"projects/p/secrets/db-password/versions/2":      "v2",
+			"projects/p/secrets/db-password/versions/latest": "v3",
+			"projects/p/secrets/api-key/versions/latest":     "key",
+		}}
+
+		return &Store{
+			client:    client,
+			ProjectID: "p",
+			versions:  versions,
+			filter:    "labels.app=myapp",
+			logger:    logger.NewLogger("test"),
+		}, client
+	}
+
+	t.Run("get pinned version", func(t *testing.T) {
+		s, _ := newStore()
+
+		resp, err := s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "db-password"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"db-password": "v2"}, resp.Data)
+
+		resp, err = s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "db-password", Metadata: map[string]string{VersionID: "1"}})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"db-password": "v1"}, resp.Data)
+
+		resp, err = s.GetSecret(context.Background(), secretstores.GetSecretRequest{Name: "api-key"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"api-key": "key"}, resp.Data)
+	})
+
+	t.Run("bulk get with filter", func(t *testing.T) {
+		s, client := newStore()
+
+		resp, err := s.BulkGetSecret(context.Background(), secretstores.BulkGetSecretRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]map[string]string{
+			"db-password": {"db-password": "v2"},
+			"api-key":     {"api-key": "key"},
+		}, resp.Data)
+		assert.Equal(t, []string{"labels.app=myapp"}, client.listFilters)
+
+		_, err = s.BulkGetSecret(context.Background(), secretstores.BulkGetSecretRequest{Metadata: map[string]string{Filter: "name:db-"}})
+		require.NoError(t, err)
+		assert.Equal(t, "name:db-", client.listFilters[1])
+	})
+
+	t.Run("bulk get fails on missing pinned version", func(t *testing.T) {
+		s, _ := newStore()
+		s.versions["db-password"] = "5"
+
+		_, err := s.BulkGetSecret(context.Background(), secretstores.BulkGetSecretRequest{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "version 5 of secret db-password")
+	})
+
+	t.Run("parse versions", func(t *testing.T) {
+		versions, err := parseVersions(" a=1, b=latest ,")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"a": "1", "b": "latest"}, versions)
+
+		for _, val := range []string{"a", "a=", "=1", "a=first"} {
+			_, err = parseVersions(val)
+			assert.Error(t, err, val)
+		}
+	})
+}
+
 func TestGetFeatures(t *testing.T) {
 	s := NewSecreteManager(logger.NewLogger("test"))
 	// Yes, we are skipping initialization as feature retrieval doesn't depend on it.
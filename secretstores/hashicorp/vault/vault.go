@@ -72,7 +72,7 @@ func (v valueType) isMapType() bool {
 	return v == valueTypeMap
 }
 
-var ErrNotFound = errors.New("secret key or version not exist")
+var ErrNotFound = fmt.Errorf("secret key or version not exist: %w", secretstores.ErrSecretNotFound)
 
 // vaultSecretStore is a secret store implementation for HashiCorp Vault.
 type vaultSecretStore struct {
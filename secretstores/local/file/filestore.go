@@ -110,7 +110,7 @@ func (j *localSecretStore) Init(metadata secretstores.Metadata) error {
 func (j *localSecretStore) GetSecret(ctx context.Context, req secretstores.GetSecretRequest) (secretstores.GetSecretResponse, error) {
 	secretValue, exists := j.secrets[req.Name]
 	if !exists {
-		return secretstores.GetSecretResponse{}, fmt.Errorf("secret %s not found", req.Name)
+		return secretstores.GetSecretResponse{}, fmt.Errorf("secret %s %w", req.Name, secretstores.ErrSecretNotFound)
 	}
 
 	var data map[string]string
@@ -137,7 +137,8 @@ func TestGetSecret(t *testing.T) {
 		}
 		_, err := s.GetSecret(context.Background(), req)
 		assert.NotNil(t, err)
-		assert.Equal(t, err, fmt.Errorf("secret %s not found", req.Name))
+		assert.EqualError(t, err, fmt.Sprintf("secret %s not found", req.Name))
+		assert.ErrorIs(t, err, secretstores.ErrSecretNotFound)
 	})
 
 	t.Run("Regular (non-MultiValued) secret store does not support MULTIPLE_KEY_VALUES_PER_SECRET", func(t *testing.T) {
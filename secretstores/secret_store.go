@@ -15,11 +15,15 @@ package secretstores
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/dapr/components-contrib/health"
 )
 
+// ErrSecretNotFound is wrapped by the errors of the secret stores which do not have the requested secret.
+var ErrSecretNotFound = errors.New("not found")
+
 // SecretStore is the interface for a component that handles secrets management.
 type SecretStore interface {
 	// Init authenticates with the actual secret store and performs other init operation
@@ -0,0 +1,63 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serializer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// AvroSchemaKey is the metadata property with the Avro schema of the payloads, in JSON.
+const AvroSchemaKey = "serializerAvroSchema"
+
+type avroSerializer struct {
+	codec *goavro.Codec
+}
+
+func newAvroSerializer(metadata map[string]string) (Serializer, error) {
+	schema := metadata[AvroSchemaKey]
+	if schema == "" {
+		return nil, errors.New("missing " + AvroSchemaKey)
+	}
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Avro schema: %w", err)
+	}
+
+	return &avroSerializer{codec: codec}, nil
+}
+
+func (s *avroSerializer) Marshal(data []byte) ([]byte, error) {
+	native, _, err := s.codec.NativeFromTextual(data)
+	if err != nil {
+		return nil, fmt.Errorf("payload does not match the Avro schema: %w", err)
+	}
+
+	return s.codec.BinaryFromNative(nil, native)
+}
+
+func (s *avroSerializer) Unmarshal(data []byte) ([]byte, error) {
+	native, _, err := s.codec.NativeFromBinary(data)
+	if err != nil {
+		return nil, fmt.Errorf("payload does not match the Avro schema: %w", err)
+	}
+
+	return s.codec.TextualFromNative(nil, native)
+}
+
+func (s *avroSerializer) ContentType() string {
+	return "avro/binary"
+}
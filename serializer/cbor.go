@@ -0,0 +1,118 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serializer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+
+	"github.com/hashicorp/go-msgpack/codec"
+)
+
+// cborHandle encodes the maps with their keys sorted, so that the encoding is deterministic, and decodes the maps
+// with their keys as strings, as in JSON.
+var cborHandle = func() *codec.CborHandle {
+	h := &codec.CborHandle{}
+	h.Canonical = true
+	h.MapType = reflect.TypeOf(map[string]any(nil))
+	return h
+}()
+
+// cborSerializer converts JSON payloads to CBOR, with the data model of JSON.
+// Byte strings are decoded as base64 strings.
+type cborSerializer struct{}
+
+func newCBORSerializer(map[string]string) (Serializer, error) {
+	return cborSerializer{}, nil
+}
+
+func (cborSerializer) Marshal(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("invalid JSON payload: %w", err)
+	}
+	v, err := cborNumbers(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []byte
+	if err = codec.NewEncoderBytes(&out, cborHandle).Encode(v); err != nil {
+		return nil, fmt.Errorf("error encoding CBOR payload: %w", err)
+	}
+
+	return out, nil
+}
+
+func (cborSerializer) Unmarshal(data []byte) ([]byte, error) {
+	dec := codec.NewDecoderBytes(data, cborHandle)
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("invalid CBOR payload: %w", err)
+	}
+	var trailing any
+	if err := dec.Decode(&trailing); !errors.Is(err, io.EOF) {
+		return nil, errors.New("trailing data after CBOR payload")
+	}
+
+	return json.Marshal(v)
+}
+
+func (cborSerializer) ContentType() string {
+	return "application/cbor"
+}
+
+// cborNumbers replaces the numbers of the decoded JSON value with the integers that fit in 64 bits, and with 64-bit
+// floats for the other numbers.
+func cborNumbers(v any) (any, error) {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			return u, nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s: %w", v, err)
+		}
+		return f, nil
+	case []any:
+		for i, item := range v {
+			n, err := cborNumbers(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = n
+		}
+	case map[string]any:
+		for k, item := range v {
+			n, err := cborNumbers(item)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = n
+		}
+	}
+
+	return v, nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serializer
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	// ProtobufDescriptorSetKey is the metadata property with the descriptor set of the messages, as generated by
+	// "protoc --include_imports --descriptor_set_out", encoded in base64.
+	ProtobufDescriptorSetKey = "serializerProtoDescriptorSet"
+	// ProtobufMessageKey is the metadata property with the full name of the message of the payloads, such as "shop.Order".
+	ProtobufMessageKey = "serializerProtoMessage"
+)
+
+type protobufSerializer struct {
+	message protoreflect.MessageDescriptor
+}
+
+func newProtobufSerializer(metadata map[string]string) (Serializer, error) {
+	if metadata[ProtobufDescriptorSetKey] == "" {
+		return nil, errors.New("missing " + ProtobufDescriptorSetKey)
+	}
+	if metadata[ProtobufMessageKey] == "" {
+		return nil, errors.New("missing " + ProtobufMessageKey)
+	}
+
+	b, err := base64.StdEncoding.DecodeString(metadata[ProtobufDescriptorSetKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", ProtobufDescriptorSetKey, err)
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err = proto.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", ProtobufDescriptorSetKey, err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", ProtobufDescriptorSetKey, err)
+	}
+
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(metadata[ProtobufMessageKey]))
+	if err != nil {
+		return nil, fmt.Errorf("message %s not found in the descriptor set: %w", metadata[ProtobufMessageKey], err)
+	}
+	message, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a message", metadata[ProtobufMessageKey])
+	}
+
+	return &protobufSerializer{message: message}, nil
+}
+
+func (s *protobufSerializer) Marshal(data []byte) ([]byte, error) {
+	msg := dynamicpb.NewMessage(s.message)
+	if err := protojson.Unmarshal(data, msg); err != nil {
+		return nil, fmt.Errorf("payload does not match message %s: %w", s.message.FullName(), err)
+	}
+
+	return proto.Marshal(msg)
+}
+
+func (s *protobufSerializer) Unmarshal(data []byte) ([]byte, error) {
+	msg := dynamicpb.NewMessage(s.message)
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return nil, fmt.Errorf("payload does not match message %s: %w", s.message.FullName(), err)
+	}
+
+	return protojson.Marshal(msg)
+}
+
+func (s *protobufSerializer) ContentType() string {
+	return "application/x-protobuf; messageType=" + string(s.message.FullName())
+}
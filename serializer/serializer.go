@@ -0,0 +1,128 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package serializer is a registry of the serializers converting the JSON payloads of the applications to the format
+// stored or transmitted by the components, and back.
+// The serializer of a component is selected by name with the "serializer" metadata property.
+package serializer
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const (
+	// MetadataKey is the metadata property selecting the serializer of a component.
+	MetadataKey = "serializer"
+
+	// JSON is the name of the serializer leaving the payloads as they are.
+	JSON = "json"
+	// Avro is the name of the serializer encoding the payloads with an Avro schema.
+	Avro = "avro"
+	// Protobuf is the name of the serializer encoding the payloads as a message of a Protocol Buffers descriptor set.
+	Protobuf = "protobuf"
+	// CBOR is the name of the serializer encoding the payloads in CBOR.
+	CBOR = "cbor"
+)
+
+// Serializer converts JSON payloads to another format, and back.
+type Serializer interface {
+	// Marshal converts a JSON payload to the format of the serializer.
+	Marshal(data []byte) ([]byte, error)
+	// Unmarshal converts a payload in the format of the serializer back to JSON.
+	Unmarshal(data []byte) ([]byte, error)
+	// ContentType returns the content type of the serialized payloads.
+	ContentType() string
+}
+
+// Factory creates a serializer with the metadata properties of a component.
+type Factory func(metadata map[string]string) (Serializer, error)
+
+var (
+	factories = map[string]Factory{}
+	lock      sync.RWMutex
+)
+
+func init() {
+	Register(JSON, newJSONSerializer)
+	Register(Avro, newAvroSerializer)
+	Register(Protobuf, newProtobufSerializer)
+	Register(CBOR, newCBORSerializer)
+}
+
+// Register adds a serializer to the registry, replacing any serializer with the same name.
+func Register(name string, factory Factory) {
+	lock.Lock()
+	defer lock.Unlock()
+	factories[name] = factory
+}
+
+// Names returns the names of the registered serializers, sorted.
+func Names() []string {
+	lock.RLock()
+	defer lock.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// New creates the serializer registered with a name.
+func New(name string, metadata map[string]string) (Serializer, error) {
+	lock.RLock()
+	factory, ok := factories[name]
+	lock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("serializer error: unknown serializer %s, expected one of %v", name, Names())
+	}
+
+	s, err := factory(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("serializer error: failed to create serializer %s: %w", name, err)
+	}
+
+	return s, nil
+}
+
+// FromMetadata creates the serializer selected by the metadata properties of a component.
+// It returns nil if no serializer is selected.
+func FromMetadata(metadata map[string]string) (Serializer, error) {
+	name := metadata[MetadataKey]
+	if name == "" {
+		return nil, nil
+	}
+
+	return New(name, metadata)
+}
+
+type jsonSerializer struct{}
+
+func newJSONSerializer(map[string]string) (Serializer, error) {
+	return jsonSerializer{}, nil
+}
+
+func (jsonSerializer) Marshal(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+func (jsonSerializer) Unmarshal(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+func (jsonSerializer) ContentType() string {
+	return "application/json"
+}
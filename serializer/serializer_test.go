@@ -0,0 +1,203 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serializer
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const testOrder = `{"id": "order1", "amount": 42}`
+
+type customSerializer struct{}
+
+func (customSerializer) Marshal(data []byte) ([]byte, error)   { return data, nil }
+func (customSerializer) Unmarshal(data []byte) ([]byte, error) { return data, nil }
+func (customSerializer) ContentType() string                   { return "text/custom" }
+
+func TestRegistry(t *testing.T) {
+	t.Run("no serializer selected", func(t *testing.T) {
+		s, err := FromMetadata(map[string]string{})
+
+		require.NoError(t, err)
+		assert.Nil(t, s)
+	})
+
+	t.Run("unknown serializer", func(t *testing.T) {
+		_, err := FromMetadata(map[string]string{MetadataKey: "xml"})
+
+		assert.ErrorContains(t, err, "unknown serializer xml")
+	})
+
+	t.Run("custom serializer", func(t *testing.T) {
+		Register("custom", func(map[string]string) (Serializer, error) {
+			return customSerializer{}, nil
+		})
+
+		s, err := FromMetadata(map[string]string{MetadataKey: "custom"})
+
+		require.NoError(t, err)
+		assert.Equal(t, "text/custom", s.ContentType())
+		assert.Contains(t, Names(), "custom")
+	})
+}
+
+func TestAvro(t *testing.T) {
+	md := map[string]string{
+		MetadataKey:   Avro,
+		AvroSchemaKey: `{"type": "record", "name": "Order", "fields": [{"name": "id", "type": "string"}, {"name": "amount", "type": "int"}]}`,
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		s, err := FromMetadata(md)
+		require.NoError(t, err)
+
+		data, err := s.Marshal([]byte(testOrder))
+		require.NoError(t, err)
+		decoded, err := s.Unmarshal(data)
+		require.NoError(t, err)
+		assert.JSONEq(t, testOrder, string(decoded))
+	})
+
+	t.Run("payload not matching the schema", func(t *testing.T) {
+		s, err := FromMetadata(md)
+		require.NoError(t, err)
+
+		_, err = s.Marshal([]byte(`{"id": "order1"}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("missing schema", func(t *testing.T) {
+		_, err := FromMetadata(map[string]string{MetadataKey: Avro})
+
+		assert.ErrorContains(t, err, AvroSchemaKey)
+	})
+}
+
+func testDescriptorSet(t *testing.T) string {
+	t.Helper()
+
+	set := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{{
+			Name:    proto.String("order.proto"),
+			Package: proto.String("shop"),
+			Syntax:  proto.String("proto3"),
+			MessageType: []*descriptorpb.DescriptorProto{{
+				Name: proto.String("Order"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("id"), JsonName: proto.String("id"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},        //nolint:nosnakecase
+					{Name: proto.String("amount"), JsonName: proto.String("amount"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()}, //nolint:nosnakecase
+				},
+			}},
+		}},
+	}
+	b, err := proto.Marshal(set)
+	require.NoError(t, err)
+
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func TestProtobuf(t *testing.T) {
+	descriptorSet := testDescriptorSet(t)
+
+	t.Run("round trip", func(t *testing.T) {
+		s, err := FromMetadata(map[string]string{MetadataKey: Protobuf, ProtobufDescriptorSetKey: descriptorSet, ProtobufMessageKey: "shop.Order"})
+		require.NoError(t, err)
+
+		data, err := s.Marshal([]byte(testOrder))
+		require.NoError(t, err)
+		decoded, err := s.Unmarshal(data)
+		require.NoError(t, err)
+		assert.JSONEq(t, testOrder, string(decoded))
+		assert.Equal(t, "application/x-protobuf; messageType=shop.Order", s.ContentType())
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		s, err := FromMetadata(map[string]string{MetadataKey: Protobuf, ProtobufDescriptorSetKey: descriptorSet, ProtobufMessageKey: "shop.Order"})
+		require.NoError(t, err)
+
+		_, err = s.Marshal([]byte(`{"customer": "c1"}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown message", func(t *testing.T) {
+		_, err := FromMetadata(map[string]string{MetadataKey: Protobuf, ProtobufDescriptorSetKey: descriptorSet, ProtobufMessageKey: "shop.Invoice"})
+
+		assert.ErrorContains(t, err, "shop.Invoice")
+	})
+}
+
+func TestCBOR(t *testing.T) {
+	s, err := FromMetadata(map[string]string{MetadataKey: CBOR})
+	require.NoError(t, err)
+
+	t.Run("round trip", func(t *testing.T) {
+		payload := `{"s": "text", "n": -17, "big": 18446744073709551615, "f": 1.5, "b": true, "z": null, "a": [1, "two", {"x": []}]}`
+
+		data, err := s.Marshal([]byte(payload))
+		require.NoError(t, err)
+		decoded, err := s.Unmarshal(data)
+		require.NoError(t, err)
+		assert.JSONEq(t, payload, string(decoded))
+	})
+
+	t.Run("encoding", func(t *testing.T) {
+		// Examples of RFC 8949, appendix A
+		data, err := s.Marshal([]byte(`{"a": 1, "b": [2, 3]}`))
+		require.NoError(t, err)
+		assert.Equal(t, "a26161016162820203", hex.EncodeToString(data))
+
+		data, err = s.Marshal([]byte(`-1000`))
+		require.NoError(t, err)
+		assert.Equal(t, "3903e7", hex.EncodeToString(data))
+	})
+
+	t.Run("decoding", func(t *testing.T) {
+		tests := map[string]string{
+			// Half-precision float
+			"f93e00": `1.5`,
+			// Indefinite length array and text
+			"9f0102ff":     `[1, 2]`,
+			"7f61616162ff": `"ab"`,
+			// Byte string
+			"43010203": `"AQID"`,
+			// Tagged date
+			"c074323031332d30332d32315432303a30343a30305a": `"2013-03-21T20:04:00Z"`,
+		}
+		for in, expected := range tests {
+			b, err := hex.DecodeString(in)
+			require.NoError(t, err)
+
+			decoded, err := s.Unmarshal(b)
+			require.NoError(t, err, in)
+			assert.JSONEq(t, expected, string(decoded), in)
+		}
+	})
+
+	t.Run("invalid payloads", func(t *testing.T) {
+		for _, in := range []string{"", "62", "9f01", "0101", "f97c00"} {
+			b, err := hex.DecodeString(in)
+			require.NoError(t, err)
+
+			_, err = s.Unmarshal(b)
+			assert.Error(t, err, in)
+		}
+	})
+}
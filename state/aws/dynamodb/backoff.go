@@ -0,0 +1,189 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dynamodb
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+const (
+	// Delays before retrying the requests throttled on tables with provisioned capacity, which is replenished every
+	// second.
+	provisionedMinThrottleDelay = 100 * time.Millisecond
+	provisionedMaxThrottleDelay = 5 * time.Second
+	// Delays before retrying the requests throttled on on-demand tables, which are throttled only on sudden peaks of
+	// traffic while the capacity adapts.
+	onDemandMinThrottleDelay = 25 * time.Millisecond
+	onDemandMaxThrottleDelay = time.Second
+
+	// Default number of retries of the requests, as in the AWS SDK.
+	defaultMaxRetries = 10
+)
+
+var (
+	throttledRequests = stats.Int64("dynamodb/throttled_requests", "Number of requests throttled because the capacity of the table was exceeded.", stats.UnitDimensionless)
+	throttleDelay     = stats.Float64("dynamodb/throttle_delay", "Delay before retrying a throttled request.", stats.UnitMilliseconds)
+
+	tableKey     = tag.MustNewKey("table")
+	operationKey = tag.MustNewKey("operation")
+
+	throttleViews = []*view.View{
+		{
+			Name:        throttledRequests.Name(),
+			Description: throttledRequests.Description(),
+			Measure:     throttledRequests,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{tableKey, operationKey},
+		},
+		{
+			Name:        throttleDelay.Name(),
+			Description: throttleDelay.Description(),
+			Measure:     throttleDelay,
+			Aggregation: view.Distribution(25, 50, 100, 250, 500, 1000, 2500, 5000),
+			TagKeys:     []tag.Key{tableKey, operationKey},
+		},
+	}
+)
+
+// capacityRetryer is the retryer of the requests to a table.
+// Requests throttled because the capacity of the table is exceeded are retried with a backoff shared by all the
+// requests: once a request is throttled the others wait at least as long, and the delay halves as requests succeed.
+// Other errors are retried as by the AWS SDK.
+type capacityRetryer struct {
+	client.DefaultRetryer
+
+	table string
+	// Returns whether the table has provisioned capacity; invoked in background on the first throttled request, unless
+	// the billing mode is known.
+	describe   func() (bool, error)
+	detectOnce sync.Once
+
+	lock        sync.Mutex
+	provisioned bool
+	delay       time.Duration
+}
+
+func newCapacityRetryer(table string, maxRetries int, describe func() (bool, error)) *capacityRetryer {
+	return &capacityRetryer{
+		DefaultRetryer: client.DefaultRetryer{
+			NumMaxRetries: maxRetries,
+			MinRetryDelay: 50 * time.Millisecond,
+		},
+		table:    table,
+		describe: describe,
+	}
+}
+
+// setProvisioned sets the billing mode of the table, which is then not detected.
+func (r *capacityRetryer) setProvisioned(provisioned bool) {
+	r.detectOnce.Do(func() {})
+
+	r.lock.Lock()
+	r.provisioned = provisioned
+	r.lock.Unlock()
+}
+
+// RetryRules returns the delay before retrying the request.
+func (r *capacityRetryer) RetryRules(req *request.Request) time.Duration {
+	if !req.IsErrorThrottle() {
+		return r.DefaultRetryer.RetryRules(req)
+	}
+
+	op := ""
+	if req.Operation != nil {
+		op = req.Operation.Name
+	}
+
+	return r.throttled(op, req.RetryCount)
+}
+
+// throttled returns the delay before retrying a request throttled for the given number of times, and records it.
+func (r *capacityRetryer) throttled(op string, retryCount int) time.Duration {
+	r.detectOnce.Do(func() {
+		if r.describe != nil {
+			go r.detect()
+		}
+	})
+
+	r.lock.Lock()
+	minDelay, maxDelay := onDemandMinThrottleDelay, onDemandMaxThrottleDelay
+	if r.provisioned {
+		minDelay, maxDelay = provisionedMinThrottleDelay, provisionedMaxThrottleDelay
+	}
+	delay := maxDelay
+	if retryCount < 16 {
+		delay = minDelay << retryCount
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+	// The table is under pressure, so don't retry before the other throttled requests
+	if r.delay > delay {
+		delay = r.delay
+	}
+	r.delay = delay
+	r.lock.Unlock()
+
+	// Jitter, between half and the whole delay
+	delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1)) //nolint:gosec
+
+	_ = stats.RecordWithTags(context.Background(),
+		[]tag.Mutator{tag.Upsert(tableKey, r.table), tag.Upsert(operationKey, op)},
+		throttledRequests.M(1), throttleDelay.M(float64(delay)/float64(time.Millisecond)),
+	)
+
+	return delay
+}
+
+// succeeded decreases the shared delay after a successful request.
+func (r *capacityRetryer) succeeded() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.delay == 0 {
+		return
+	}
+	r.delay /= 2
+	if r.delay < onDemandMinThrottleDelay {
+		r.delay = 0
+	}
+}
+
+func (r *capacityRetryer) detect() {
+	provisioned, err := r.describe()
+	if err != nil {
+		// Keep using the delays of on-demand tables
+		return
+	}
+
+	r.lock.Lock()
+	r.provisioned = provisioned
+	r.lock.Unlock()
+}
+
+// completeHandler is the handler of the completed requests, which decreases the shared delay of the successful ones.
+func (r *capacityRetryer) completeHandler(req *request.Request) {
+	if req.Error == nil {
+		r.succeeded()
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dynamodb
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/stats/view"
+)
+
+func throttledRequest(retryCount int) *request.Request {
+	return &request.Request{
+		Operation:  &request.Operation{Name: "PutItem"},
+		Error:      awserr.New(dynamodb.ErrCodeProvisionedThroughputExceededException, "throttled", nil),
+		RetryCount: retryCount,
+	}
+}
+
+func TestCapacityRetryer(t *testing.T) {
+	t.Run("on-demand tables", func(t *testing.T) {
+		r := newCapacityRetryer("table", 10, nil)
+
+		delay := r.RetryRules(throttledRequest(0))
+		assert.GreaterOrEqual(t, delay, onDemandMinThrottleDelay/2)
+		assert.LessOrEqual(t, delay, onDemandMinThrottleDelay)
+
+		delay = r.RetryRules(throttledRequest(10))
+		assert.GreaterOrEqual(t, delay, onDemandMaxThrottleDelay/2)
+		assert.LessOrEqual(t, delay, onDemandMaxThrottleDelay)
+	})
+
+	t.Run("provisioned tables", func(t *testing.T) {
+		r := newCapacityRetryer("table", 10, nil)
+		r.setProvisioned(true)
+
+		delay := r.RetryRules(throttledRequest(0))
+		assert.GreaterOrEqual(t, delay, provisionedMinThrottleDelay/2)
+		assert.LessOrEqual(t, delay, provisionedMinThrottleDelay)
+
+		delay = r.RetryRules(throttledRequest(20))
+		assert.GreaterOrEqual(t, delay, provisionedMaxThrottleDelay/2)
+		assert.LessOrEqual(t, delay, provisionedMaxThrottleDelay)
+	})
+
+	t.Run("the delay is shared and decreases as requests succeed", func(t *testing.T) {
+		r := newCapacityRetryer("table", 10, nil)
+
+		r.RetryRules(throttledRequest(3))
+		assert.Equal(t, 8*onDemandMinThrottleDelay, r.delay)
+
+		// Another request throttled for the first time waits as long
+		delay := r.RetryRules(throttledRequest(0))
+		assert.GreaterOrEqual(t, delay, 4*onDemandMinThrottleDelay)
+
+		r.completeHandler(&request.Request{})
+		assert.Equal(t, 4*onDemandMinThrottleDelay, r.delay)
+		r.completeHandler(&request.Request{Error: errors.New("failed")})
+		assert.Equal(t, 4*onDemandMinThrottleDelay, r.delay)
+		r.completeHandler(&request.Request{})
+		r.completeHandler(&request.Request{})
+		r.completeHandler(&request.Request{})
+		assert.Equal(t, time.Duration(0), r.delay)
+	})
+
+	t.Run("the billing mode is detected on the first throttled request", func(t *testing.T) {
+		described := make(chan struct{})
+		r := newCapacityRetryer("table", 10, func() (bool, error) {
+			defer close(described)
+			return true, nil
+		})
+
+		r.RetryRules(throttledRequest(0))
+		<-described
+
+		assert.Eventually(t, func() bool {
+			r.lock.Lock()
+			defer r.lock.Unlock()
+			return r.provisioned
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("other errors are retried as by the SDK", func(t *testing.T) {
+		r := newCapacityRetryer("table", 10, nil)
+
+		delay := r.RetryRules(&request.Request{
+			Error: awserr.New("InternalServerError", "failed", nil),
+		})
+
+		assert.Greater(t, delay, time.Duration(0))
+		assert.Equal(t, time.Duration(0), r.delay)
+	})
+
+	t.Run("throttled requests are recorded", func(t *testing.T) {
+		assert.NoError(t, view.Register(throttleViews...))
+		r := newCapacityRetryer("metrics_table", 10, nil)
+
+		r.RetryRules(throttledRequest(0))
+		r.RetryRules(throttledRequest(1))
+
+		rows, err := view.RetrieveData(throttledRequests.Name())
+		assert.NoError(t, err)
+		var count int64
+		for _, row := range rows {
+			for _, tag := range row.Tags {
+				if tag.Key == tableKey && tag.Value == "metrics_table" {
+					count += row.Data.(*view.CountData).Value
+				}
+			}
+		}
+		assert.Equal(t, int64(2), count)
+	})
+}
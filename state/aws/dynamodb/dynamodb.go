@@ -16,9 +16,11 @@ package dynamodb
 import (
 	"crypto/rand"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -26,6 +28,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	jsoniterator "github.com/json-iterator/go"
+	"go.opencensus.io/stats/view"
 
 	awsAuth "github.com/dapr/components-contrib/internal/authentication/aws"
 	"github.com/dapr/components-contrib/metadata"
@@ -33,26 +36,66 @@ import (
 	"github.com/dapr/kit/logger"
 )
 
+const (
+	// Maximum number of items written in a transaction.
+	maxTransactionItems = 100
+
+	// Metadata of the items read, with their expiration time.
+	ttlExpireTimeKey = "ttlExpireTime"
+
+	// Default capacity units of the tables created with provisioned capacity.
+	defaultCapacityUnits = 5
+	// Time to wait for a table created at initialization to be active.
+	tableCreationTimeout = 2 * time.Minute
+)
+
+// Interval at which the status of the tables created, or of their backups, is checked.
+var tableStatusPollInterval = 2 * time.Second
+
 // StateStore is a DynamoDB state store.
 type StateStore struct {
 	client           dynamodbiface.DynamoDBAPI
 	table            string
 	ttlAttributeName string
+	retryer          *capacityRetryer
+	logger           logger.Logger
 }
 
 type dynamoDBMetadata struct {
-	Region           string `json:"region"`
-	Endpoint         string `json:"endpoint"`
-	AccessKey        string `json:"accessKey"`
-	SecretKey        string `json:"secretKey"`
-	SessionToken     string `json:"sessionToken"`
-	Table            string `json:"table"`
-	TTLAttributeName string `json:"ttlAttributeName"`
+	Region                string `json:"region"`
+	Endpoint              string `json:"endpoint"`
+	AccessKey             string `json:"accessKey"`
+	SecretKey             string `json:"secretKey" mdsensitive:"true"`
+	SessionToken          string `json:"sessionToken" mdsensitive:"true"`
+	AssumeRoleArn         string `json:"assumeRoleArn"`
+	ExternalID            string `json:"externalId"`
+	AssumeRoleSessionName string `json:"assumeRoleSessionName"`
+	UseFIPSEndpoint       bool   `json:"useFipsEndpoint"`
+	UseDualStackEndpoint  bool   `json:"useDualStackEndpoint"`
+	FailoverRegions       string `json:"failoverRegions"`
+	Table                 string `json:"table" mdrequired:"true"`
+	TTLAttributeName      string `json:"ttlAttributeName"`
+	// If true, enables the expiration of the items by DynamoDB on the TTL attribute of the table.
+	EnableTableTTL bool `json:"enableTableTtl"`
+	// If true, creates the table at initialization if it does not exist, enabling the expiration of the items if
+	// ttlAttributeName is set.
+	CreateTable bool `json:"createTable"`
+	// Billing mode of the table: PAY_PER_REQUEST (on-demand, the default for the tables created) or PROVISIONED.
+	// If not set, it's read from the table when requests are first throttled.
+	BillingMode string `json:"billingMode"`
+	// Capacity units of the table created with the PROVISIONED billing mode.
+	ReadCapacityUnits  int64 `json:"readCapacityUnits"`
+	WriteCapacityUnits int64 `json:"writeCapacityUnits"`
+	// If true, enables the point-in-time recovery of the table.
+	EnablePointInTimeRecovery bool `json:"enablePointInTimeRecovery"`
+	// Maximum number of retries of the requests, including the ones throttled because the capacity of the table is
+	// exceeded.
+	MaxRetries int `json:"maxRetries"`
 }
 
 // NewDynamoDBStateStore returns a new dynamoDB state store.
-func NewDynamoDBStateStore(_ logger.Logger) state.Store {
-	return &StateStore{}
+func NewDynamoDBStateStore(logger logger.Logger) state.Store {
+	return &StateStore{logger: logger}
 }
 
 // Init does metadata and connection parsing.
@@ -62,21 +105,192 @@ func (d *StateStore) Init(metadata state.Metadata) error {
 		return err
 	}
 
+	// Views are registered once per process, so the metrics are exported with the others of the runtime
+	if err = view.Register(throttleViews...); err != nil {
+		return fmt.Errorf("dynamodb error: failed to register metrics views: %w", err)
+	}
+
+	d.table = meta.Table
+	d.ttlAttributeName = meta.TTLAttributeName
+	d.retryer = newCapacityRetryer(meta.Table, meta.MaxRetries, d.describeBillingMode)
+
 	client, err := d.getClient(meta)
 	if err != nil {
 		return err
 	}
-
 	d.client = client
-	d.table = meta.Table
-	d.ttlAttributeName = meta.TTLAttributeName
+
+	created := false
+	if meta.CreateTable {
+		var provisioned bool
+		created, provisioned, err = d.ensureTable(meta)
+		if err != nil {
+			return err
+		}
+		d.retryer.setProvisioned(provisioned)
+	} else if meta.BillingMode != "" {
+		d.retryer.setProvisioned(meta.BillingMode == dynamodb.BillingModeProvisioned)
+	}
+
+	if meta.EnableTableTTL || (created && d.ttlAttributeName != "") {
+		if d.ttlAttributeName == "" {
+			return fmt.Errorf("dynamodb error: enableTableTtl requires ttlAttributeName")
+		}
+		if err = d.enableTableTTL(); err != nil {
+			return err
+		}
+	}
+
+	if meta.EnablePointInTimeRecovery {
+		if err = d.enablePointInTimeRecovery(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensureTable creates the table if it does not exist, and waits for it to be active.
+// It returns whether the table was created, and whether it has provisioned capacity.
+func (d *StateStore) ensureTable(meta *dynamoDBMetadata) (bool, bool, error) {
+	out, err := d.client.DescribeTable(&dynamodb.DescribeTableInput{
+		TableName: aws.String(d.table),
+	})
+	if err == nil {
+		return false, isProvisioned(out.Table), nil
+	}
+	var notFound *dynamodb.ResourceNotFoundException
+	if !errors.As(err, &notFound) {
+		return false, false, fmt.Errorf("dynamodb error: failed to describe table %s: %w", d.table, err)
+	}
+
+	input := &dynamodb.CreateTableInput{
+		TableName: aws.String(d.table),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{{
+			AttributeName: aws.String("key"),
+			AttributeType: aws.String(dynamodb.ScalarAttributeTypeS),
+		}},
+		KeySchema: []*dynamodb.KeySchemaElement{{
+			AttributeName: aws.String("key"),
+			KeyType:       aws.String(dynamodb.KeyTypeHash),
+		}},
+		BillingMode: aws.String(meta.BillingMode),
+	}
+	if meta.BillingMode == dynamodb.BillingModeProvisioned {
+		input.ProvisionedThroughput = &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(meta.ReadCapacityUnits),
+			WriteCapacityUnits: aws.Int64(meta.WriteCapacityUnits),
+		}
+	}
+	_, err = d.client.CreateTable(input)
+	var inUse *dynamodb.ResourceInUseException
+	if err != nil && !errors.As(err, &inUse) {
+		return false, false, fmt.Errorf("dynamodb error: failed to create table %s: %w", d.table, err)
+	}
+	// The table may be created concurrently by another replica, in which case it's being created or already active
+
+	deadline := time.Now().Add(tableCreationTimeout)
+	for {
+		out, err = d.client.DescribeTable(&dynamodb.DescribeTableInput{
+			TableName: aws.String(d.table),
+		})
+		if err != nil {
+			return false, false, fmt.Errorf("dynamodb error: failed to describe table %s: %w", d.table, err)
+		}
+		if aws.StringValue(out.Table.TableStatus) == dynamodb.TableStatusActive {
+			break
+		}
+		if time.Now().After(deadline) {
+			return false, false, fmt.Errorf("dynamodb error: table %s is not active after %v", d.table, tableCreationTimeout)
+		}
+		time.Sleep(tableStatusPollInterval)
+	}
+	d.logger.Infof("Created table %s with billing mode %s", d.table, meta.BillingMode)
+
+	return true, isProvisioned(out.Table), nil
+}
+
+// enablePointInTimeRecovery enables the continuous backups of the table, which may not be available right after its
+// creation.
+func (d *StateStore) enablePointInTimeRecovery() error {
+	for attempt := 1; ; attempt++ {
+		_, err := d.client.UpdateContinuousBackups(&dynamodb.UpdateContinuousBackupsInput{
+			TableName: aws.String(d.table),
+			PointInTimeRecoverySpecification: &dynamodb.PointInTimeRecoverySpecification{
+				PointInTimeRecoveryEnabled: aws.Bool(true),
+			},
+		})
+		var unavailable *dynamodb.ContinuousBackupsUnavailableException
+		if errors.As(err, &unavailable) && attempt < 5 {
+			time.Sleep(tableStatusPollInterval)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("dynamodb error: failed to enable the point-in-time recovery of table %s: %w", d.table, err)
+		}
+
+		return nil
+	}
+}
+
+// describeBillingMode returns whether the table has provisioned capacity.
+func (d *StateStore) describeBillingMode() (bool, error) {
+	out, err := d.client.DescribeTable(&dynamodb.DescribeTableInput{
+		TableName: aws.String(d.table),
+	})
+	if err != nil {
+		d.logger.Warnf("dynamodb: failed to describe the billing mode of table %s: %v", d.table, err)
+		return false, err
+	}
+
+	return isProvisioned(out.Table), nil
+}
+
+// isProvisioned returns whether the table has provisioned capacity; tables created before on-demand capacity was
+// introduced have no billing mode.
+func isProvisioned(table *dynamodb.TableDescription) bool {
+	if table == nil {
+		return false
+	}
+
+	return table.BillingModeSummary == nil || aws.StringValue(table.BillingModeSummary.BillingMode) == dynamodb.BillingModeProvisioned
+}
+
+// enableTableTTL enables the expiration of the items on the TTL attribute, so that DynamoDB deletes the expired items.
+func (d *StateStore) enableTableTTL() error {
+	out, err := d.client.DescribeTimeToLive(&dynamodb.DescribeTimeToLiveInput{
+		TableName: aws.String(d.table),
+	})
+	if err != nil {
+		return fmt.Errorf("dynamodb error: failed to describe the time to live of table %s: %w", d.table, err)
+	}
+
+	if desc := out.TimeToLiveDescription; desc != nil && aws.StringValue(desc.TimeToLiveStatus) != dynamodb.TimeToLiveStatusDisabled {
+		if aws.StringValue(desc.AttributeName) != d.ttlAttributeName {
+			return fmt.Errorf("dynamodb error: time to live of table %s is enabled on attribute %s instead of %s", d.table, aws.StringValue(desc.AttributeName), d.ttlAttributeName)
+		}
+
+		return nil
+	}
+
+	_, err = d.client.UpdateTimeToLive(&dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(d.table),
+		TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+			AttributeName: aws.String(d.ttlAttributeName),
+			Enabled:       aws.Bool(true),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("dynamodb error: failed to enable the time to live of table %s: %w", d.table, err)
+	}
+	d.logger.Infof("Enabled the time to live of table %s on attribute %s", d.table, d.ttlAttributeName)
 
 	return nil
 }
 
 // Features returns the features available in this state store.
 func (d *StateStore) Features() []state.Feature {
-	return []state.Feature{state.FeatureETag}
+	return []state.Feature{state.FeatureETag, state.FeatureTransactional}
 }
 
 // Get retrieves a dynamoDB item.
@@ -105,6 +319,10 @@ func (d *StateStore) Get(req *state.GetRequest) (*state.GetResponse, error) {
 		return nil, err
 	}
 
+	resp := &state.GetResponse{
+		Data: []byte(output),
+	}
+
 	var ttl int64
 	if d.ttlAttributeName != "" {
 		if val, ok := result.Item[d.ttlAttributeName]; ok {
@@ -115,13 +333,12 @@ func (d *StateStore) Get(req *state.GetRequest) (*state.GetResponse, error) {
 				// Item has expired but DynamoDB didn't delete it yet.
 				return &state.GetResponse{}, nil
 			}
+			resp.Metadata = map[string]string{
+				ttlExpireTimeKey: time.Unix(ttl, 0).UTC().Format(time.RFC3339),
+			}
 		}
 	}
 
-	resp := &state.GetResponse{
-		Data: []byte(output),
-	}
-
 	var etag string
 	if etagVal, ok := result.Item["etag"]; ok {
 		if err = dynamodbattribute.Unmarshal(etagVal, &etag); err != nil {
@@ -151,20 +368,8 @@ func (d *StateStore) Set(req *state.SetRequest) error {
 		TableName: &d.table,
 	}
 
-	haveEtag := false
-	if req.ETag != nil && *req.ETag != "" {
-		haveEtag = true
-		condExpr := "etag = :etag"
-		input.ConditionExpression = &condExpr
-		exprAttrValues := make(map[string]*dynamodb.AttributeValue)
-		exprAttrValues[":etag"] = &dynamodb.AttributeValue{
-			S: req.ETag,
-		}
-		input.ExpressionAttributeValues = exprAttrValues
-	} else if req.Options.Concurrency == state.FirstWrite {
-		condExpr := "attribute_not_exists(etag)"
-		input.ConditionExpression = &condExpr
-	}
+	haveEtag := req.ETag != nil && *req.ETag != ""
+	input.ConditionExpression, input.ExpressionAttributeValues = etagCondition(req.ETag, req.Options.Concurrency == state.FirstWrite)
 
 	_, err = d.client.PutItem(input)
 	if err != nil && haveEtag {
@@ -178,6 +383,7 @@ func (d *StateStore) Set(req *state.SetRequest) error {
 }
 
 // BulkSet performs a bulk set operation.
+// When a request has an etag or first-write concurrency, the items are written in a transaction.
 func (d *StateStore) BulkSet(req []state.SetRequest) error {
 	writeRequests := []*dynamodb.WriteRequest{}
 
@@ -186,13 +392,13 @@ func (d *StateStore) BulkSet(req []state.SetRequest) error {
 	}
 
 	for _, r := range req {
-		r := r // avoid G601.
-
-		if r.ETag != nil && *r.ETag != "" {
-			return fmt.Errorf("dynamodb error: BulkSet() does not support etags; please use Set() instead")
-		} else if r.Options.Concurrency == state.FirstWrite {
-			return fmt.Errorf("dynamodb error: BulkSet() does not support FirstWrite concurrency; please use Set() instead")
+		if (r.ETag != nil && *r.ETag != "") || r.Options.Concurrency == state.FirstWrite {
+			return d.transactSet(req)
 		}
+	}
+
+	for _, r := range req {
+		r := r // avoid G601.
 
 		item, err := d.getItemFromReq(&r)
 		if err != nil {
@@ -208,14 +414,7 @@ func (d *StateStore) BulkSet(req []state.SetRequest) error {
 		writeRequests = append(writeRequests, writeRequest)
 	}
 
-	requestItems := map[string][]*dynamodb.WriteRequest{}
-	requestItems[d.table] = writeRequests
-
-	_, e := d.client.BatchWriteItem(&dynamodb.BatchWriteItemInput{
-		RequestItems: requestItems,
-	})
-
-	return e
+	return d.batchWrite(writeRequests)
 }
 
 // Delete performs a delete operation.
@@ -229,15 +428,7 @@ func (d *StateStore) Delete(req *state.DeleteRequest) error {
 		TableName: aws.String(d.table),
 	}
 
-	if req.ETag != nil && *req.ETag != "" {
-		condExpr := "etag = :etag"
-		input.ConditionExpression = &condExpr
-		exprAttrValues := make(map[string]*dynamodb.AttributeValue)
-		exprAttrValues[":etag"] = &dynamodb.AttributeValue{
-			S: req.ETag,
-		}
-		input.ExpressionAttributeValues = exprAttrValues
-	}
+	input.ConditionExpression, input.ExpressionAttributeValues = etagCondition(req.ETag, false)
 
 	_, err := d.client.DeleteItem(input)
 	if err != nil {
@@ -251,6 +442,7 @@ func (d *StateStore) Delete(req *state.DeleteRequest) error {
 }
 
 // BulkDelete performs a bulk delete operation.
+// When a request has an etag, the items are deleted in a transaction.
 func (d *StateStore) BulkDelete(req []state.DeleteRequest) error {
 	writeRequests := []*dynamodb.WriteRequest{}
 
@@ -260,9 +452,11 @@ func (d *StateStore) BulkDelete(req []state.DeleteRequest) error {
 
 	for _, r := range req {
 		if r.ETag != nil && *r.ETag != "" {
-			return fmt.Errorf("dynamodb error: BulkDelete() does not support etags; please use Delete() instead")
+			return d.transactDelete(req)
 		}
+	}
 
+	for _, r := range req {
 		writeRequest := &dynamodb.WriteRequest{
 			DeleteRequest: &dynamodb.DeleteRequest{
 				Key: map[string]*dynamodb.AttributeValue{
@@ -275,14 +469,163 @@ func (d *StateStore) BulkDelete(req []state.DeleteRequest) error {
 		writeRequests = append(writeRequests, writeRequest)
 	}
 
-	requestItems := map[string][]*dynamodb.WriteRequest{}
-	requestItems[d.table] = writeRequests
+	return d.batchWrite(writeRequests)
+}
+
+// batchWrite writes the requests in batch.
+// Requests not processed because the capacity of the table is exceeded are retried with the backoff of the throttled
+// requests.
+func (d *StateStore) batchWrite(writeRequests []*dynamodb.WriteRequest) error {
+	requestItems := map[string][]*dynamodb.WriteRequest{
+		d.table: writeRequests,
+	}
+	for attempt := 0; ; attempt++ {
+		out, err := d.client.BatchWriteItem(&dynamodb.BatchWriteItemInput{
+			RequestItems: requestItems,
+		})
+		if err != nil {
+			return err
+		}
+		if out == nil || len(out.UnprocessedItems[d.table]) == 0 {
+			return nil
+		}
+
+		requestItems = out.UnprocessedItems
+		if d.retryer == nil || attempt >= d.retryer.MaxRetries() {
+			return fmt.Errorf("dynamodb error: %d items were not written because the capacity of table %s is exceeded", len(requestItems[d.table]), d.table)
+		}
+		time.Sleep(d.retryer.throttled("BatchWriteItem", attempt))
+	}
+}
+
+// Multi performs the upserts and deletes of a request atomically, in a transaction.
+func (d *StateStore) Multi(request *state.TransactionalStateRequest) error {
+	if len(request.Operations) == 0 {
+		return nil
+	}
+	if len(request.Operations) > maxTransactionItems {
+		return fmt.Errorf("dynamodb error: a transaction supports up to %d operations, %d requested", maxTransactionItems, len(request.Operations))
+	}
+
+	items := make([]*dynamodb.TransactWriteItem, len(request.Operations))
+	haveEtag := make([]bool, len(request.Operations))
+	for i, o := range request.Operations {
+		switch o.Operation {
+		case state.Upsert:
+			req := o.Request.(state.SetRequest)
+			item, err := d.transactPutItem(&req)
+			if err != nil {
+				return err
+			}
+			items[i] = item
+			haveEtag[i] = req.ETag != nil && *req.ETag != ""
+		case state.Delete:
+			req := o.Request.(state.DeleteRequest)
+			items[i] = d.transactDeleteItem(&req)
+			haveEtag[i] = req.ETag != nil && *req.ETag != ""
+		default:
+			return fmt.Errorf("dynamodb error: unsupported operation %s", o.Operation)
+		}
+	}
+
+	return d.transactWrite(items, haveEtag)
+}
+
+// transactSet writes the items of the requests in a transaction, failing if the etag of any of them does not match.
+func (d *StateStore) transactSet(req []state.SetRequest) error {
+	if len(req) > maxTransactionItems {
+		return fmt.Errorf("dynamodb error: etags and FirstWrite concurrency are supported for up to %d items in a bulk operation", maxTransactionItems)
+	}
+
+	items := make([]*dynamodb.TransactWriteItem, len(req))
+	haveEtag := make([]bool, len(req))
+	for i := range req {
+		item, err := d.transactPutItem(&req[i])
+		if err != nil {
+			return err
+		}
+		items[i] = item
+		haveEtag[i] = req[i].ETag != nil && *req[i].ETag != ""
+	}
+
+	return d.transactWrite(items, haveEtag)
+}
+
+// transactDelete deletes the items of the requests in a transaction, failing if the etag of any of them does not match.
+func (d *StateStore) transactDelete(req []state.DeleteRequest) error {
+	if len(req) > maxTransactionItems {
+		return fmt.Errorf("dynamodb error: etags are supported for up to %d items in a bulk operation", maxTransactionItems)
+	}
+
+	items := make([]*dynamodb.TransactWriteItem, len(req))
+	haveEtag := make([]bool, len(req))
+	for i := range req {
+		items[i] = d.transactDeleteItem(&req[i])
+		haveEtag[i] = req[i].ETag != nil && *req[i].ETag != ""
+	}
+
+	return d.transactWrite(items, haveEtag)
+}
+
+func (d *StateStore) transactPutItem(req *state.SetRequest) (*dynamodb.TransactWriteItem, error) {
+	item, err := d.getItemFromReq(req)
+	if err != nil {
+		return nil, err
+	}
+	put := &dynamodb.Put{
+		Item:      item,
+		TableName: aws.String(d.table),
+	}
+	put.ConditionExpression, put.ExpressionAttributeValues = etagCondition(req.ETag, req.Options.Concurrency == state.FirstWrite)
+
+	return &dynamodb.TransactWriteItem{Put: put}, nil
+}
 
-	_, e := d.client.BatchWriteItem(&dynamodb.BatchWriteItemInput{
-		RequestItems: requestItems,
+func (d *StateStore) transactDeleteItem(req *state.DeleteRequest) *dynamodb.TransactWriteItem {
+	del := &dynamodb.Delete{
+		Key: map[string]*dynamodb.AttributeValue{
+			"key": {
+				S: aws.String(req.Key),
+			},
+		},
+		TableName: aws.String(d.table),
+	}
+	del.ConditionExpression, del.ExpressionAttributeValues = etagCondition(req.ETag, false)
+
+	return &dynamodb.TransactWriteItem{Delete: del}
+}
+
+func (d *StateStore) transactWrite(items []*dynamodb.TransactWriteItem, haveEtag []bool) error {
+	_, err := d.client.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: items,
 	})
+	if cErr, ok := err.(*dynamodb.TransactionCanceledException); ok {
+		// The transaction is canceled if the condition of an item fails, with a reason given for each item
+		for i, reason := range cErr.CancellationReasons {
+			if i < len(haveEtag) && haveEtag[i] && aws.StringValue(reason.Code) == "ConditionalCheckFailed" {
+				return state.NewETagError(state.ETagMismatch, cErr)
+			}
+		}
+	}
 
-	return e
+	return err
+}
+
+// etagCondition returns the condition expression, and its values, to write an item only if its etag matches.
+// Without etag, first-write concurrency allows the item to be written only if it does not exist.
+func etagCondition(etag *string, firstWrite bool) (*string, map[string]*dynamodb.AttributeValue) {
+	if etag != nil && *etag != "" {
+		return aws.String("etag = :etag"), map[string]*dynamodb.AttributeValue{
+			":etag": {
+				S: etag,
+			},
+		}
+	}
+	if firstWrite {
+		return aws.String("attribute_not_exists(etag)"), nil
+	}
+
+	return nil, nil
 }
 
 func (d *StateStore) GetComponentMetadata() map[string]string {
@@ -293,20 +636,67 @@ func (d *StateStore) GetComponentMetadata() map[string]string {
 }
 
 func (d *StateStore) getDynamoDBMetadata(meta state.Metadata) (*dynamoDBMetadata, error) {
-	var m dynamoDBMetadata
+	m := dynamoDBMetadata{
+		MaxRetries: defaultMaxRetries,
+	}
 	err := metadata.DecodeMetadata(meta.Properties, &m)
 	if m.Table == "" {
 		return nil, fmt.Errorf("missing dynamodb table name")
 	}
-	return &m, err
+	if err != nil {
+		return nil, err
+	}
+
+	m.BillingMode = strings.ToUpper(m.BillingMode)
+	switch m.BillingMode {
+	case "":
+		if m.CreateTable {
+			m.BillingMode = dynamodb.BillingModePayPerRequest
+		}
+	case dynamodb.BillingModePayPerRequest:
+	case dynamodb.BillingModeProvisioned:
+		if m.ReadCapacityUnits == 0 {
+			m.ReadCapacityUnits = defaultCapacityUnits
+		}
+		if m.WriteCapacityUnits == 0 {
+			m.WriteCapacityUnits = defaultCapacityUnits
+		}
+		if m.ReadCapacityUnits < 0 || m.WriteCapacityUnits < 0 {
+			return nil, fmt.Errorf("dynamodb error: invalid capacity units %d/%d", m.ReadCapacityUnits, m.WriteCapacityUnits)
+		}
+	default:
+		return nil, fmt.Errorf("dynamodb error: invalid billingMode %s, supported values are %s and %s", m.BillingMode, dynamodb.BillingModePayPerRequest, dynamodb.BillingModeProvisioned)
+	}
+	if m.MaxRetries < 0 {
+		return nil, fmt.Errorf("dynamodb error: invalid maxRetries %d", m.MaxRetries)
+	}
+
+	return &m, nil
 }
 
 func (d *StateStore) getClient(metadata *dynamoDBMetadata) (*dynamodb.DynamoDB, error) {
-	sess, err := awsAuth.GetClient(metadata.AccessKey, metadata.SecretKey, metadata.SessionToken, metadata.Region, metadata.Endpoint)
+	sess, err := awsAuth.NewSession(awsAuth.Options{
+		AccessKey:    metadata.AccessKey,
+		SecretKey:    metadata.SecretKey,
+		SessionToken: metadata.SessionToken,
+		Region:       metadata.Region,
+		Endpoint:     metadata.Endpoint,
+		AssumeRole: awsAuth.AssumeRole{
+			RoleARN:     metadata.AssumeRoleArn,
+			ExternalID:  metadata.ExternalID,
+			SessionName: metadata.AssumeRoleSessionName,
+		},
+		UseFIPSEndpoint:      metadata.UseFIPSEndpoint,
+		UseDualStackEndpoint: metadata.UseDualStackEndpoint,
+		FailoverRegions:      strings.Split(metadata.FailoverRegions, ","),
+	})
 	if err != nil {
 		return nil, err
 	}
-	c := dynamodb.New(sess)
+	c := dynamodb.New(sess, &aws.Config{
+		Retryer: d.retryer,
+	})
+	c.Handlers.Complete.PushBack(d.retryer.completeHandler)
 
 	return c, nil
 }
@@ -367,6 +757,7 @@ func (d *StateStore) marshalToString(v interface{}) (string, error) {
 }
 
 // Parse and process ttlInSeconds.
+// A value of -1 means that the item never expires.
 func (d *StateStore) parseTTL(req *state.SetRequest) (*int64, error) {
 	// Only attempt to parse the value when TTL has been specified in component metadata.
 	if d.ttlAttributeName != "" {
@@ -375,6 +766,12 @@ func (d *StateStore) parseTTL(req *state.SetRequest) (*int64, error) {
 			if err != nil {
 				return nil, err
 			}
+			if parsedVal == -1 {
+				return nil, nil
+			}
+			if parsedVal < -1 {
+				return nil, fmt.Errorf("invalid value %d, must be -1 or greater", parsedVal)
+			}
 			// DynamoDB expects an epoch timestamp in seconds.
 			expirationTime := time.Now().Unix() + parsedVal
 
@@ -384,3 +781,11 @@ func (d *StateStore) parseTTL(req *state.SetRequest) (*int64, error) {
 
 	return nil, nil
 }
+
+// GetComponentMetadataSchema returns the schema of the metadata of the DynamoDB state store.
+func (d *StateStore) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(dynamoDBMetadata{
+		MaxRetries: defaultMaxRetries,
+	})
+	return fields
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/kit/logger"
 )
@@ -35,6 +36,12 @@ type mockedDynamoDB struct {
 	PutItemFn        func(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error)
 	DeleteItemFn     func(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error)
 	BatchWriteItemFn func(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error)
+	TransactWriteFn  func(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error)
+	DescribeTTLFn    func(input *dynamodb.DescribeTimeToLiveInput) (*dynamodb.DescribeTimeToLiveOutput, error)
+	UpdateTTLFn      func(input *dynamodb.UpdateTimeToLiveInput) (*dynamodb.UpdateTimeToLiveOutput, error)
+	DescribeTableFn  func(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error)
+	CreateTableFn    func(input *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error)
+	UpdateBackupsFn  func(input *dynamodb.UpdateContinuousBackupsInput) (*dynamodb.UpdateContinuousBackupsOutput, error)
 	dynamodbiface.DynamoDBAPI
 }
 
@@ -60,6 +67,30 @@ func (m *mockedDynamoDB) BatchWriteItem(input *dynamodb.BatchWriteItemInput) (*d
 	return m.BatchWriteItemFn(input)
 }
 
+func (m *mockedDynamoDB) TransactWriteItems(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	return m.TransactWriteFn(input)
+}
+
+func (m *mockedDynamoDB) DescribeTimeToLive(input *dynamodb.DescribeTimeToLiveInput) (*dynamodb.DescribeTimeToLiveOutput, error) {
+	return m.DescribeTTLFn(input)
+}
+
+func (m *mockedDynamoDB) UpdateTimeToLive(input *dynamodb.UpdateTimeToLiveInput) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	return m.UpdateTTLFn(input)
+}
+
+func (m *mockedDynamoDB) DescribeTable(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+	return m.DescribeTableFn(input)
+}
+
+func (m *mockedDynamoDB) CreateTable(input *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+	return m.CreateTableFn(input)
+}
+
+func (m *mockedDynamoDB) UpdateContinuousBackups(input *dynamodb.UpdateContinuousBackupsInput) (*dynamodb.UpdateContinuousBackupsOutput, error) {
+	return m.UpdateBackupsFn(input)
+}
+
 func TestInit(t *testing.T) {
 	m := state.Metadata{}
 	s := NewDynamoDBStateStore(logger.NewLogger("test")).(*StateStore)
@@ -95,6 +126,248 @@ func TestInit(t *testing.T) {
 	})
 }
 
+func TestEnableTableTTL(t *testing.T) {
+	describe := func(status, attribute string) func(*dynamodb.DescribeTimeToLiveInput) (*dynamodb.DescribeTimeToLiveOutput, error) {
+		return func(input *dynamodb.DescribeTimeToLiveInput) (*dynamodb.DescribeTimeToLiveOutput, error) {
+			assert.Equal(t, "table", *input.TableName)
+
+			return &dynamodb.DescribeTimeToLiveOutput{
+				TimeToLiveDescription: &dynamodb.TimeToLiveDescription{
+					TimeToLiveStatus: aws.String(status),
+					AttributeName:    aws.String(attribute),
+				},
+			}, nil
+		}
+	}
+
+	t.Run("Enables time to live", func(t *testing.T) {
+		updated := false
+		ss := StateStore{
+			client: &mockedDynamoDB{
+				DescribeTTLFn: describe(dynamodb.TimeToLiveStatusDisabled, ""),
+				UpdateTTLFn: func(input *dynamodb.UpdateTimeToLiveInput) (*dynamodb.UpdateTimeToLiveOutput, error) {
+					updated = true
+					assert.Equal(t, "expiresAt", *input.TimeToLiveSpecification.AttributeName)
+					assert.True(t, *input.TimeToLiveSpecification.Enabled)
+
+					return &dynamodb.UpdateTimeToLiveOutput{}, nil
+				},
+			},
+			table:            "table",
+			ttlAttributeName: "expiresAt",
+			logger:           logger.NewLogger("test"),
+		}
+
+		err := ss.enableTableTTL()
+
+		assert.Nil(t, err)
+		assert.True(t, updated)
+	})
+
+	t.Run("Time to live already enabled", func(t *testing.T) {
+		ss := StateStore{
+			client: &mockedDynamoDB{
+				DescribeTTLFn: describe(dynamodb.TimeToLiveStatusEnabled, "expiresAt"),
+			},
+			table:            "table",
+			ttlAttributeName: "expiresAt",
+		}
+
+		err := ss.enableTableTTL()
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("Time to live enabled on another attribute", func(t *testing.T) {
+		ss := StateStore{
+			client: &mockedDynamoDB{
+				DescribeTTLFn: describe(dynamodb.TimeToLiveStatusEnabled, "other"),
+			},
+			table:            "table",
+			ttlAttributeName: "expiresAt",
+		}
+
+		err := ss.enableTableTTL()
+
+		assert.ErrorContains(t, err, "enabled on attribute other")
+	})
+}
+
+func TestGetDynamoDBMetadata(t *testing.T) {
+	ss := StateStore{}
+
+	t.Run("Defaults", func(t *testing.T) {
+		m, err := ss.getDynamoDBMetadata(state.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"table": "a",
+		}}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "", m.BillingMode)
+		assert.Equal(t, defaultMaxRetries, m.MaxRetries)
+	})
+
+	t.Run("Tables are created on-demand by default", func(t *testing.T) {
+		m, err := ss.getDynamoDBMetadata(state.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"table":       "a",
+			"createTable": "true",
+		}}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, dynamodb.BillingModePayPerRequest, m.BillingMode)
+	})
+
+	t.Run("Provisioned capacity", func(t *testing.T) {
+		m, err := ss.getDynamoDBMetadata(state.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"table":             "a",
+			"billingMode":       "provisioned",
+			"readCapacityUnits": "20",
+			"maxRetries":        "3",
+		}}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, dynamodb.BillingModeProvisioned, m.BillingMode)
+		assert.Equal(t, int64(20), m.ReadCapacityUnits)
+		assert.Equal(t, int64(defaultCapacityUnits), m.WriteCapacityUnits)
+		assert.Equal(t, 3, m.MaxRetries)
+	})
+
+	t.Run("Invalid billing mode", func(t *testing.T) {
+		_, err := ss.getDynamoDBMetadata(state.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"table":       "a",
+			"billingMode": "free",
+		}}})
+
+		assert.ErrorContains(t, err, "invalid billingMode FREE")
+	})
+}
+
+func TestEnsureTable(t *testing.T) {
+	tableDescription := func(status, billingMode string) *dynamodb.DescribeTableOutput {
+		return &dynamodb.DescribeTableOutput{
+			Table: &dynamodb.TableDescription{
+				TableStatus: aws.String(status),
+				BillingModeSummary: &dynamodb.BillingModeSummary{
+					BillingMode: aws.String(billingMode),
+				},
+			},
+		}
+	}
+
+	t.Run("Creates the table and waits for it to be active", func(t *testing.T) {
+		described := 0
+		var created *dynamodb.CreateTableInput
+		ss := StateStore{
+			client: &mockedDynamoDB{
+				DescribeTableFn: func(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+					described++
+					switch described {
+					case 1:
+						return nil, &dynamodb.ResourceNotFoundException{}
+					case 2:
+						return tableDescription(dynamodb.TableStatusCreating, dynamodb.BillingModeProvisioned), nil
+					default:
+						return tableDescription(dynamodb.TableStatusActive, dynamodb.BillingModeProvisioned), nil
+					}
+				},
+				CreateTableFn: func(input *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+					created = input
+					return &dynamodb.CreateTableOutput{}, nil
+				},
+			},
+			table:  "table",
+			logger: logger.NewLogger("test"),
+		}
+		interval := tableStatusPollInterval
+		tableStatusPollInterval = time.Millisecond
+		defer func() { tableStatusPollInterval = interval }()
+
+		wasCreated, provisioned, err := ss.ensureTable(&dynamoDBMetadata{
+			BillingMode:        dynamodb.BillingModeProvisioned,
+			ReadCapacityUnits:  10,
+			WriteCapacityUnits: 5,
+		})
+
+		assert.NoError(t, err)
+		assert.True(t, wasCreated)
+		assert.True(t, provisioned)
+		assert.Equal(t, 3, described)
+		if assert.NotNil(t, created) {
+			assert.Equal(t, "table", *created.TableName)
+			assert.Equal(t, "key", *created.KeySchema[0].AttributeName)
+			assert.Equal(t, dynamodb.KeyTypeHash, *created.KeySchema[0].KeyType)
+			assert.Equal(t, dynamodb.BillingModeProvisioned, *created.BillingMode)
+			assert.Equal(t, int64(10), *created.ProvisionedThroughput.ReadCapacityUnits)
+			assert.Equal(t, int64(5), *created.ProvisionedThroughput.WriteCapacityUnits)
+		}
+	})
+
+	t.Run("Existing table", func(t *testing.T) {
+		ss := StateStore{
+			client: &mockedDynamoDB{
+				DescribeTableFn: func(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+					return tableDescription(dynamodb.TableStatusActive, dynamodb.BillingModePayPerRequest), nil
+				},
+			},
+			table: "table",
+		}
+
+		wasCreated, provisioned, err := ss.ensureTable(&dynamoDBMetadata{BillingMode: dynamodb.BillingModePayPerRequest})
+
+		assert.NoError(t, err)
+		assert.False(t, wasCreated)
+		assert.False(t, provisioned)
+	})
+
+	t.Run("Table created concurrently", func(t *testing.T) {
+		described := 0
+		ss := StateStore{
+			client: &mockedDynamoDB{
+				DescribeTableFn: func(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+					described++
+					if described == 1 {
+						return nil, &dynamodb.ResourceNotFoundException{}
+					}
+					return tableDescription(dynamodb.TableStatusActive, dynamodb.BillingModePayPerRequest), nil
+				},
+				CreateTableFn: func(input *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+					return nil, &dynamodb.ResourceInUseException{}
+				},
+			},
+			table:  "table",
+			logger: logger.NewLogger("test"),
+		}
+
+		_, _, err := ss.ensureTable(&dynamoDBMetadata{BillingMode: dynamodb.BillingModePayPerRequest})
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestEnablePointInTimeRecovery(t *testing.T) {
+	attempts := 0
+	ss := StateStore{
+		client: &mockedDynamoDB{
+			UpdateBackupsFn: func(input *dynamodb.UpdateContinuousBackupsInput) (*dynamodb.UpdateContinuousBackupsOutput, error) {
+				attempts++
+				assert.True(t, *input.PointInTimeRecoverySpecification.PointInTimeRecoveryEnabled)
+				if attempts == 1 {
+					return nil, &dynamodb.ContinuousBackupsUnavailableException{}
+				}
+				return &dynamodb.UpdateContinuousBackupsOutput{}, nil
+			},
+		},
+		table: "table",
+	}
+	interval := tableStatusPollInterval
+	tableStatusPollInterval = time.Millisecond
+	defer func() { tableStatusPollInterval = interval }()
+
+	err := ss.enablePointInTimeRecovery()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+}
+
 func TestGet(t *testing.T) {
 	t.Run("Successfully retrieve item", func(t *testing.T) {
 		ss := StateStore{
@@ -163,6 +436,7 @@ func TestGet(t *testing.T) {
 		assert.Nil(t, err)
 		assert.Equal(t, []byte("some value"), out.Data)
 		assert.Equal(t, "1bdead4badc0ffee", *out.ETag)
+		assert.Equal(t, "2099-02-15T18:07:31Z", out.Metadata["ttlExpireTime"])
 	})
 	t.Run("Successfully retrieve item (with expired ttl)", func(t *testing.T) {
 		ss := StateStore{
@@ -459,13 +733,13 @@ func TestSet(t *testing.T) {
 		ss := StateStore{
 			client: &mockedDynamoDB{
 				PutItemFn: func(input *dynamodb.PutItemInput) (output *dynamodb.PutItemOutput, err error) {
-					assert.Equal(t, len(input.Item), 4)
+					// The item never expires
+					assert.Equal(t, len(input.Item), 3)
 					result := DynamoDBItem{}
 					dynamodbattribute.UnmarshalMap(input.Item, &result)
 					assert.Equal(t, result.Key, "someKey")
 					assert.Equal(t, result.Value, "{\"Value\":\"someValue\"}")
-					assert.Greater(t, result.TestAttributeName, time.Now().Unix()-2)
-					assert.Less(t, result.TestAttributeName, time.Now().Unix())
+					assert.NotContains(t, input.Item, "testAttributeName")
 
 					return &dynamodb.PutItemOutput{
 						Attributes: map[string]*dynamodb.AttributeValue{
@@ -617,6 +891,22 @@ func TestSet(t *testing.T) {
 		assert.NotNil(t, err)
 		assert.Equal(t, "dynamodb error: failed to parse ttlInSeconds: strconv.ParseInt: parsing \"invalidvalue\": invalid syntax", err.Error())
 	})
+	t.Run("Unsuccessfully set item with ttl (negative value)", func(t *testing.T) {
+		ss := StateStore{
+			ttlAttributeName: "testAttributeName",
+		}
+		req := &state.SetRequest{
+			Key: "somekey",
+			Value: value{
+				Value: "somevalue",
+			},
+			Metadata: map[string]string{
+				"ttlInSeconds": "-2",
+			},
+		}
+		err := ss.Set(req)
+		assert.ErrorContains(t, err, "must be -1 or greater")
+	})
 }
 
 func TestBulkSet(t *testing.T) {
@@ -707,9 +997,6 @@ func TestBulkSet(t *testing.T) {
 									"value": {
 										S: aws.String(`{"Value":"value1"}`),
 									},
-									"testAttributeName": {
-										N: aws.String(strconv.FormatInt(time.Now().Unix()-1, 10)),
-									},
 								},
 							},
 						},
@@ -733,6 +1020,7 @@ func TestBulkSet(t *testing.T) {
 
 							assert.Equal(t, expectedItem["key"], inputItem["key"])
 							assert.Equal(t, expectedItem["value"], inputItem["value"])
+							assert.NotContains(t, inputItem, "testAttributeName")
 						}
 					}
 
@@ -864,6 +1152,75 @@ func TestBulkSet(t *testing.T) {
 		err := ss.BulkSet(req)
 		assert.NotNil(t, err)
 	})
+	t.Run("Successfully set items with etags in a transaction", func(t *testing.T) {
+		tableName := "table_name"
+		ss := StateStore{
+			client: &mockedDynamoDB{
+				TransactWriteFn: func(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+					assert.Len(t, input.TransactItems, 2)
+
+					put := input.TransactItems[0].Put
+					assert.Equal(t, tableName, *put.TableName)
+					assert.Equal(t, "key1", *put.Item["key"].S)
+					assert.Equal(t, "etag = :etag", *put.ConditionExpression)
+					assert.Equal(t, "1bdead4badc0ffee", *put.ExpressionAttributeValues[":etag"].S)
+
+					put = input.TransactItems[1].Put
+					assert.Equal(t, "key2", *put.Item["key"].S)
+					assert.Equal(t, "attribute_not_exists(etag)", *put.ConditionExpression)
+
+					return &dynamodb.TransactWriteItemsOutput{}, nil
+				},
+			},
+			table: tableName,
+		}
+		etag := "1bdead4badc0ffee"
+		req := []state.SetRequest{
+			{
+				Key:   "key1",
+				Value: value{Value: "value1"},
+				ETag:  &etag,
+			},
+			{
+				Key:     "key2",
+				Value:   value{Value: "value2"},
+				Options: state.SetStateOption{Concurrency: state.FirstWrite},
+			},
+		}
+		err := ss.BulkSet(req)
+		assert.Nil(t, err)
+	})
+	t.Run("Unsuccessfully set items with mismatched etag", func(t *testing.T) {
+		ss := StateStore{
+			client: &mockedDynamoDB{
+				TransactWriteFn: func(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+					return nil, &dynamodb.TransactionCanceledException{
+						CancellationReasons: []*dynamodb.CancellationReason{
+							{Code: aws.String("None")},
+							{Code: aws.String("ConditionalCheckFailed")},
+						},
+					}
+				},
+			},
+		}
+		etag := "bogusetag"
+		req := []state.SetRequest{
+			{
+				Key:   "key1",
+				Value: value{Value: "value1"},
+			},
+			{
+				Key:   "key2",
+				Value: value{Value: "value2"},
+				ETag:  &etag,
+			},
+		}
+		err := ss.BulkSet(req)
+		var etagErr *state.ETagError
+		if assert.ErrorAs(t, err, &etagErr) {
+			assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+		}
+	})
 }
 
 func TestDelete(t *testing.T) {
@@ -1034,4 +1391,197 @@ func TestBulkDelete(t *testing.T) {
 		err := ss.BulkDelete(req)
 		assert.NotNil(t, err)
 	})
+	t.Run("Unsuccessfully delete items with mismatched etag", func(t *testing.T) {
+		ss := StateStore{
+			client: &mockedDynamoDB{
+				TransactWriteFn: func(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+					assert.Len(t, input.TransactItems, 2)
+					assert.Equal(t, "etag = :etag", *input.TransactItems[0].Delete.ConditionExpression)
+					assert.Nil(t, input.TransactItems[1].Delete.ConditionExpression)
+
+					return nil, &dynamodb.TransactionCanceledException{
+						CancellationReasons: []*dynamodb.CancellationReason{
+							{Code: aws.String("ConditionalCheckFailed")},
+							{Code: aws.String("None")},
+						},
+					}
+				},
+			},
+		}
+		etag := "bogusetag"
+		req := []state.DeleteRequest{
+			{
+				Key:  "key1",
+				ETag: &etag,
+			},
+			{
+				Key: "key2",
+			},
+		}
+		err := ss.BulkDelete(req)
+		var etagErr *state.ETagError
+		if assert.ErrorAs(t, err, &etagErr) {
+			assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+		}
+	})
+}
+
+func TestMulti(t *testing.T) {
+	tableName := "table_name"
+
+	t.Run("Successfully perform operations in a transaction", func(t *testing.T) {
+		ss := StateStore{
+			client: &mockedDynamoDB{
+				TransactWriteFn: func(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+					assert.Len(t, input.TransactItems, 2)
+
+					put := input.TransactItems[0].Put
+					assert.Equal(t, tableName, *put.TableName)
+					assert.Equal(t, "key1", *put.Item["key"].S)
+					assert.Equal(t, "etag = :etag", *put.ConditionExpression)
+					assert.Equal(t, "1bdead4badc0ffee", *put.ExpressionAttributeValues[":etag"].S)
+
+					del := input.TransactItems[1].Delete
+					assert.Equal(t, tableName, *del.TableName)
+					assert.Equal(t, "key2", *del.Key["key"].S)
+					assert.Nil(t, del.ConditionExpression)
+
+					return &dynamodb.TransactWriteItemsOutput{}, nil
+				},
+			},
+			table: tableName,
+		}
+		etag := "1bdead4badc0ffee"
+		req := &state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{
+				{
+					Operation: state.Upsert,
+					Request: state.SetRequest{
+						Key:   "key1",
+						Value: "value1",
+						ETag:  &etag,
+					},
+				},
+				{
+					Operation: state.Delete,
+					Request: state.DeleteRequest{
+						Key: "key2",
+					},
+				},
+			},
+		}
+		err := ss.Multi(req)
+		assert.Nil(t, err)
+	})
+
+	t.Run("Unsuccessfully perform operations with mismatched etag", func(t *testing.T) {
+		ss := StateStore{
+			client: &mockedDynamoDB{
+				TransactWriteFn: func(input *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+					return nil, &dynamodb.TransactionCanceledException{
+						CancellationReasons: []*dynamodb.CancellationReason{
+							{Code: aws.String("None")},
+							{Code: aws.String("ConditionalCheckFailed")},
+						},
+					}
+				},
+			},
+			table: tableName,
+		}
+		etag := "bogusetag"
+		req := &state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{
+				{
+					Operation: state.Upsert,
+					Request: state.SetRequest{
+						Key:   "key1",
+						Value: "value1",
+					},
+				},
+				{
+					Operation: state.Delete,
+					Request: state.DeleteRequest{
+						Key:  "key2",
+						ETag: &etag,
+					},
+				},
+			},
+		}
+		err := ss.Multi(req)
+		var etagErr *state.ETagError
+		if assert.ErrorAs(t, err, &etagErr) {
+			assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+		}
+	})
+
+	t.Run("Unsuccessfully perform too many operations", func(t *testing.T) {
+		ss := StateStore{
+			client: &mockedDynamoDB{},
+			table:  tableName,
+		}
+		req := &state.TransactionalStateRequest{}
+		for i := 0; i <= maxTransactionItems; i++ {
+			req.Operations = append(req.Operations, state.TransactionalStateOperation{
+				Operation: state.Delete,
+				Request: state.DeleteRequest{
+					Key: fmt.Sprintf("key%d", i),
+				},
+			})
+		}
+		err := ss.Multi(req)
+		assert.ErrorContains(t, err, "a transaction supports up to")
+	})
+}
+
+func TestBatchWriteUnprocessedItems(t *testing.T) {
+	unprocessed := []*dynamodb.WriteRequest{{
+		DeleteRequest: &dynamodb.DeleteRequest{
+			Key: map[string]*dynamodb.AttributeValue{"key": {S: aws.String("key2")}},
+		},
+	}}
+
+	t.Run("Unprocessed items are retried", func(t *testing.T) {
+		var calls []int
+		ss := StateStore{
+			client: &mockedDynamoDB{
+				BatchWriteItemFn: func(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+					calls = append(calls, len(input.RequestItems["table"]))
+					if len(calls) == 1 {
+						return &dynamodb.BatchWriteItemOutput{
+							UnprocessedItems: map[string][]*dynamodb.WriteRequest{"table": unprocessed},
+						}, nil
+					}
+					return &dynamodb.BatchWriteItemOutput{}, nil
+				},
+			},
+			table:   "table",
+			retryer: newCapacityRetryer("table", 3, nil),
+		}
+
+		err := ss.BulkDelete([]state.DeleteRequest{{Key: "key1"}, {Key: "key2"}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{2, 1}, calls)
+	})
+
+	t.Run("Fails when items are still unprocessed after the retries", func(t *testing.T) {
+		calls := 0
+		ss := StateStore{
+			client: &mockedDynamoDB{
+				BatchWriteItemFn: func(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+					calls++
+					return &dynamodb.BatchWriteItemOutput{
+						UnprocessedItems: map[string][]*dynamodb.WriteRequest{"table": unprocessed},
+					}, nil
+				},
+			},
+			table:   "table",
+			retryer: newCapacityRetryer("table", 2, nil),
+		}
+
+		err := ss.BulkDelete([]state.DeleteRequest{{Key: "key1"}, {Key: "key2"}})
+
+		assert.ErrorContains(t, err, "1 items were not written")
+		assert.Equal(t, 3, calls)
+	})
 }
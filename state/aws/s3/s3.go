@@ -0,0 +1,442 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Amazon S3 state store.
+
+Sample configuration in yaml:
+
+	apiVersion: dapr.io/v1alpha1
+	kind: Component
+	metadata:
+	  name: statestore
+	spec:
+	  type: state.aws.s3
+	  metadata:
+	  - name: bucket
+	    value: <bucket name>
+	  - name: region
+	    value: <region>
+	  - name: prefix
+	    value: <optional prefix of the objects>
+
+Each key is stored as an object named after the key as given, after the optional prefix. Keys which are not valid
+object keys are rejected.
+Concurrency is supported with the ETags of the objects, using conditional writes: https://docs.aws.amazon.com/AmazonS3/latest/userguide/conditional-requests.html
+
+When versioning is enabled on the bucket, the previous values of a key can be read with the "version" metadata, a
+version ID of its object, or the "asOf" metadata, an RFC 3339 timestamp. The version ID of the value read is returned in
+the "version" metadata of the response.
+*/
+
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	jsoniter "github.com/json-iterator/go"
+
+	awsAuth "github.com/dapr/components-contrib/internal/authentication/aws"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// Maximum length of the object keys, in bytes.
+	maxObjectKeyLength = 1024
+
+	// Maximum number of objects read concurrently in a bulk get.
+	defaultBulkGetParallelism = 10
+)
+
+// StateStore is an Amazon S3 state store.
+type StateStore struct {
+	state.DefaultBulkStore
+
+	client   s3iface.S3API
+	bucket   string
+	prefix   string
+	parallel int
+	logger   logger.Logger
+}
+
+type s3Metadata struct {
+	Region                string `json:"region"`
+	Endpoint              string `json:"endpoint"`
+	AccessKey             string `json:"accessKey"`
+	SecretKey             string `json:"secretKey" mdsensitive:"true"`
+	SessionToken          string `json:"sessionToken" mdsensitive:"true"`
+	AssumeRoleArn         string `json:"assumeRoleArn"`
+	ExternalID            string `json:"externalId"`
+	AssumeRoleSessionName string `json:"assumeRoleSessionName"`
+	UseFIPSEndpoint       bool   `json:"useFipsEndpoint,string"`
+	UseDualStackEndpoint  bool   `json:"useDualStackEndpoint,string"`
+	FailoverRegions       string `json:"failoverRegions"`
+	Bucket                string `json:"bucket" mdrequired:"true"`
+	// Prefix of the keys of the objects, such as "state/".
+	Prefix         string `json:"prefix"`
+	ForcePathStyle bool   `json:"forcePathStyle,string"`
+	DisableSSL     bool   `json:"disableSSL,string"`
+	// Maximum number of objects read concurrently in a bulk get.
+	BulkGetParallelism int `json:"bulkGetParallelism,string"`
+}
+
+// NewS3StateStore returns a new Amazon S3 state store.
+func NewS3StateStore(logger logger.Logger) state.Store {
+	s := &StateStore{logger: logger}
+	s.DefaultBulkStore = state.NewDefaultBulkStore(s)
+
+	return s
+}
+
+// Init does metadata and connection parsing.
+func (s *StateStore) Init(metadata state.Metadata) error {
+	meta, err := getS3Metadata(metadata)
+	if err != nil {
+		return err
+	}
+
+	sess, err := awsAuth.NewSession(awsAuth.Options{
+		AccessKey:    meta.AccessKey,
+		SecretKey:    meta.SecretKey,
+		SessionToken: meta.SessionToken,
+		Region:       meta.Region,
+		Endpoint:     meta.Endpoint,
+		AssumeRole: awsAuth.AssumeRole{
+			RoleARN:     meta.AssumeRoleArn,
+			ExternalID:  meta.ExternalID,
+			SessionName: meta.AssumeRoleSessionName,
+		},
+		UseFIPSEndpoint:      meta.UseFIPSEndpoint,
+		UseDualStackEndpoint: meta.UseDualStackEndpoint,
+		FailoverRegions:      strings.Split(meta.FailoverRegions, ","),
+	})
+	if err != nil {
+		return err
+	}
+
+	cfg := aws.NewConfig().
+		WithS3ForcePathStyle(meta.ForcePathStyle).
+		WithDisableSSL(meta.DisableSSL)
+
+	s.client = s3.New(sess, cfg)
+	s.bucket = meta.Bucket
+	s.prefix = meta.Prefix
+	s.parallel = meta.BulkGetParallelism
+
+	return nil
+}
+
+// Features returns the features available in this state store.
+func (s *StateStore) Features() []state.Feature {
+	return []state.Feature{state.FeatureETag}
+}
+
+// Ping checks that the bucket is reachable.
+func (s *StateStore) Ping() error {
+	_, err := s.client.HeadBucketWithContext(context.Background(), &s3.HeadBucketInput{
+		Bucket: aws.String(s.bucket),
+	})
+	if err != nil {
+		return fmt.Errorf("s3 error: failed to reach bucket %s: %w", s.bucket, err)
+	}
+
+	return nil
+}
+
+// Get reads the object of a key.
+func (s *StateStore) Get(req *state.GetRequest) (*state.GetResponse, error) {
+	return s.get(context.Background(), req.Key, req.Metadata)
+}
+
+// BulkGet reads the objects of the keys in parallel.
+func (s *StateStore) BulkGet(req []state.GetRequest) (bool, []state.BulkGetResponse, error) {
+	res := make([]state.BulkGetResponse, len(req))
+	sem := make(chan struct{}, s.parallel)
+	wg := sync.WaitGroup{}
+	wg.Add(len(req))
+	for i := range req {
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			res[i].Key = req[i].Key
+			item, err := s.get(context.Background(), req[i].Key, req[i].Metadata)
+			if err != nil {
+				res[i].Error = err.Error()
+				return
+			}
+			res[i].Data = item.Data
+			res[i].ETag = item.ETag
+			res[i].ContentType = item.ContentType
+			res[i].Metadata = item.Metadata
+		}(i)
+	}
+	wg.Wait()
+
+	return true, res, nil
+}
+
+// Set writes the object of a key.
+// With an etag, the object is written only if its etag matches; with first-write concurrency and no etag, only if it
+// does not exist.
+func (s *StateStore) Set(req *state.SetRequest) error {
+	key, err := s.objectKey(req.Key)
+	if err != nil {
+		return err
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(marshal(req.Value)),
+		ContentType: req.ContentType,
+	}
+
+	headers := map[string]string{}
+	haveEtag := req.ETag != nil && *req.ETag != ""
+	if haveEtag {
+		headers["If-Match"] = *req.ETag
+	} else if req.Options.Concurrency == state.FirstWrite {
+		headers["If-None-Match"] = "*"
+	}
+
+	_, err = s.client.PutObjectWithContext(context.Background(), input, request.WithSetRequestHeaders(headers))
+	if err != nil {
+		if isPreconditionFailed(err) {
+			return state.NewETagError(state.ETagMismatch, err)
+		}
+
+		return fmt.Errorf("s3 error: failed to set key %s: %w", req.Key, err)
+	}
+
+	return nil
+}
+
+// Delete deletes the object of a key.
+// With an etag, the object is deleted only if its etag matches.
+func (s *StateStore) Delete(req *state.DeleteRequest) error {
+	key, err := s.objectKey(req.Key)
+	if err != nil {
+		return err
+	}
+
+	input := &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+
+	headers := map[string]string{}
+	haveEtag := req.ETag != nil && *req.ETag != ""
+	if haveEtag {
+		headers["If-Match"] = *req.ETag
+	}
+
+	_, err = s.client.DeleteObjectWithContext(context.Background(), input, request.WithSetRequestHeaders(headers))
+	if err != nil {
+		if haveEtag && (isPreconditionFailed(err) || isNotFound(err)) {
+			return state.NewETagError(state.ETagMismatch, err)
+		}
+
+		return fmt.Errorf("s3 error: failed to delete key %s: %w", req.Key, err)
+	}
+
+	return nil
+}
+
+func (s *StateStore) GetComponentMetadata() map[string]string {
+	metadataStruct := s3Metadata{}
+	metadataInfo := map[string]string{}
+	metadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo)
+	return metadataInfo
+}
+
+// GetComponentMetadataSchema returns the schema of the metadata of the S3 state store.
+func (s *StateStore) GetComponentMetadataSchema() []metadata.MetadataField {
+	fields, _ := metadata.GetMetadataSchemaFromStruct(s3Metadata{
+		BulkGetParallelism: defaultBulkGetParallelism,
+	})
+	return fields
+}
+
+func (s *StateStore) get(ctx context.Context, key string, md map[string]string) (*state.GetResponse, error) {
+	objectKey, err := s.objectKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	versionID := md[metadata.VersionMetadataKey]
+	asOf, ok, err := metadata.TryGetAsOf(md)
+	if err != nil {
+		return nil, fmt.Errorf("s3 error: %w", err)
+	}
+	if ok {
+		if versionID != "" {
+			return nil, fmt.Errorf("s3 error: only one of %s and %s can be set", metadata.VersionMetadataKey, metadata.AsOfMetadataKey)
+		}
+		versionID, err = s.versionAsOf(ctx, objectKey, asOf)
+		if err != nil {
+			return nil, fmt.Errorf("s3 error: failed to list the versions of key %s: %w", key, err)
+		}
+		if versionID == "" {
+			// The key didn't exist, or was deleted, at that time
+			return &state.GetResponse{}, nil
+		}
+	}
+
+	in := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(objectKey),
+	}
+	if versionID != "" {
+		in.VersionId = aws.String(versionID)
+	}
+	out, err := s.client.GetObjectWithContext(ctx, in)
+	if err != nil {
+		if isNotFound(err) {
+			return &state.GetResponse{}, nil
+		}
+
+		return nil, fmt.Errorf("s3 error: failed to get key %s: %w", key, err)
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("s3 error: failed to read key %s: %w", key, err)
+	}
+
+	res := &state.GetResponse{
+		Data:        data,
+		ETag:        out.ETag,
+		ContentType: out.ContentType,
+	}
+	// Without versioning, the version ID is "null"
+	if v := aws.StringValue(out.VersionId); v != "" && v != "null" {
+		res.Metadata = map[string]string{metadata.VersionMetadataKey: v}
+	}
+
+	return res, nil
+}
+
+// versionAsOf returns the ID of the version of the object that was current at a point in time, or an empty string if
+// the object didn't exist or was deleted then.
+func (s *StateStore) versionAsOf(ctx context.Context, objectKey string, asOf time.Time) (string, error) {
+	var (
+		versionID    string
+		lastModified *time.Time
+	)
+	// The versions of the objects prefixed with the key are listed too, and skipped
+	candidate := func(key *string, id *string, modified *time.Time, deleted bool) {
+		if aws.StringValue(key) != objectKey || modified == nil || modified.After(asOf) {
+			return
+		}
+		// The versions are listed from the newest
+		if lastModified != nil && !modified.After(*lastModified) {
+			return
+		}
+		lastModified = modified
+		versionID = aws.StringValue(id)
+		if deleted {
+			versionID = ""
+		}
+	}
+	err := s.client.ListObjectVersionsPagesWithContext(ctx, &s3.ListObjectVersionsInput{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(objectKey),
+	}, func(page *s3.ListObjectVersionsOutput, _ bool) bool {
+		for _, v := range page.Versions {
+			candidate(v.Key, v.VersionId, v.LastModified, false)
+		}
+		for _, m := range page.DeleteMarkers {
+			candidate(m.Key, m.VersionId, m.LastModified, true)
+		}
+
+		return true
+	})
+
+	return versionID, err
+}
+
+// objectKey returns the key of the object of a state key.
+// The state key is kept as given, since cleaning it would map different keys to the same object; keys which cannot be
+// object keys are rejected.
+func (s *StateStore) objectKey(key string) (string, error) {
+	objectKey := s.prefix + key
+	if key == "" || !utf8.ValidString(objectKey) || len(objectKey) > maxObjectKeyLength {
+		return "", fmt.Errorf("s3 error: invalid key %q: the object keys must be non-empty UTF-8 strings of at most %d bytes", key, maxObjectKeyLength)
+	}
+
+	return objectKey, nil
+}
+
+func getS3Metadata(meta state.Metadata) (*s3Metadata, error) {
+	m := s3Metadata{
+		BulkGetParallelism: defaultBulkGetParallelism,
+	}
+	err := metadata.DecodeMetadata(meta.Properties, &m)
+	if err != nil {
+		return nil, err
+	}
+	if m.Bucket == "" {
+		return nil, errors.New("s3 error: missing bucket name")
+	}
+	if m.BulkGetParallelism <= 0 {
+		return nil, fmt.Errorf("s3 error: invalid bulkGetParallelism %d", m.BulkGetParallelism)
+	}
+
+	return &m, nil
+}
+
+func marshal(value any) []byte {
+	if b, ok := value.([]byte); ok {
+		return b
+	}
+	v, _ := jsoniter.MarshalToString(value)
+
+	return []byte(v)
+}
+
+func isNotFound(err error) bool {
+	var aErr awserr.Error
+	if errors.As(err, &aErr) && aErr.Code() == s3.ErrCodeNoSuchKey {
+		return true
+	}
+	var rErr awserr.RequestFailure
+	return errors.As(err, &rErr) && rErr.StatusCode() == http.StatusNotFound
+}
+
+// isPreconditionFailed returns true if a conditional write failed, or conflicted with a concurrent write.
+func isPreconditionFailed(err error) bool {
+	var rErr awserr.RequestFailure
+	return errors.As(err, &rErr) && (rErr.StatusCode() == http.StatusPreconditionFailed || rErr.StatusCode() == http.StatusConflict)
+}
@@ -0,0 +1,368 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+)
+
+type mockedS3 struct {
+	GetObjectFn    func(input *s3.GetObjectInput) (*s3.GetObjectOutput, error)
+	PutObjectFn    func(input *s3.PutObjectInput, headers http.Header) (*s3.PutObjectOutput, error)
+	DeleteObjectFn func(input *s3.DeleteObjectInput, headers http.Header) (*s3.DeleteObjectOutput, error)
+	// Pages of the versions of the objects.
+	Versions []*s3.ListObjectVersionsOutput
+	s3iface.S3API
+}
+
+func (m *mockedS3) ListObjectVersionsPagesWithContext(_ aws.Context, _ *s3.ListObjectVersionsInput, fn func(*s3.ListObjectVersionsOutput, bool) bool, _ ...request.Option) error {
+	for i, page := range m.Versions {
+		if !fn(page, i == len(m.Versions)-1) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (m *mockedS3) GetObjectWithContext(_ aws.Context, input *s3.GetObjectInput, _ ...request.Option) (*s3.GetObjectOutput, error) {
+	return m.GetObjectFn(input)
+}
+
+func (m *mockedS3) PutObjectWithContext(_ aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+	return m.PutObjectFn(input, requestHeaders(opts))
+}
+
+func (m *mockedS3) DeleteObjectWithContext(_ aws.Context, input *s3.DeleteObjectInput, opts ...request.Option) (*s3.DeleteObjectOutput, error) {
+	return m.DeleteObjectFn(input, requestHeaders(opts))
+}
+
+// requestHeaders returns the headers set on a request by its options.
+func requestHeaders(opts []request.Option) http.Header {
+	r := &request.Request{HTTPRequest: &http.Request{Header: http.Header{}}}
+	r.ApplyOptions(opts...)
+	r.Handlers.Build.Run(r)
+
+	return r.HTTPRequest.Header
+}
+
+func newTestStore(client s3iface.S3API) *StateStore {
+	return &StateStore{
+		client:   client,
+		bucket:   "bucket",
+		parallel: 2,
+		logger:   logger.NewLogger("test"),
+	}
+}
+
+func TestGetS3Metadata(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		m, err := getS3Metadata(state.Metadata{Base: metadata.Base{Properties: map[string]string{"bucket": "bucket"}}})
+
+		require.NoError(t, err)
+		assert.Equal(t, "bucket", m.Bucket)
+		assert.Equal(t, defaultBulkGetParallelism, m.BulkGetParallelism)
+	})
+
+	t.Run("missing bucket", func(t *testing.T) {
+		_, err := getS3Metadata(state.Metadata{Base: metadata.Base{Properties: map[string]string{}}})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid parallelism", func(t *testing.T) {
+		_, err := getS3Metadata(state.Metadata{Base: metadata.Base{Properties: map[string]string{"bucket": "bucket", "bulkGetParallelism": "0"}}})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestObjectKey(t *testing.T) {
+	s := newTestStore(nil)
+	for _, key := range []string{"app||key", "key", "app||../key", "app||a//b/", "app||./key"} {
+		objectKey, err := s.objectKey(key)
+		require.NoError(t, err)
+		assert.Equal(t, key, objectKey)
+	}
+
+	s.prefix = "state/"
+	objectKey, err := s.objectKey("app||key")
+	require.NoError(t, err)
+	assert.Equal(t, "state/app||key", objectKey)
+
+	t.Run("invalid keys are rejected", func(t *testing.T) {
+		for _, key := range []string{"", "app||\xff", "app||" + strings.Repeat("k", maxObjectKeyLength)} {
+			_, err := s.objectKey(key)
+			assert.Error(t, err, key)
+		}
+	})
+}
+
+func TestGet(t *testing.T) {
+	t.Run("existing key", func(t *testing.T) {
+		s := newTestStore(&mockedS3{
+			GetObjectFn: func(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				assert.Equal(t, "bucket", *input.Bucket)
+				assert.Equal(t, "app||key", *input.Key)
+
+				return &s3.GetObjectOutput{
+					Body: io.NopCloser(strings.NewReader("value")),
+					ETag: aws.String(`"etag"`),
+				}, nil
+			},
+		})
+
+		res, err := s.Get(&state.GetRequest{Key: "app||key"})
+
+		require.NoError(t, err)
+		assert.Equal(t, "value", string(res.Data))
+		assert.Equal(t, `"etag"`, *res.ETag)
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		s := newTestStore(&mockedS3{
+			GetObjectFn: func(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return nil, awserr.New(s3.ErrCodeNoSuchKey, "not found", nil)
+			},
+		})
+
+		res, err := s.Get(&state.GetRequest{Key: "app||key"})
+
+		require.NoError(t, err)
+		assert.Nil(t, res.Data)
+	})
+}
+
+func TestGetVersion(t *testing.T) {
+	at := func(minute int) *time.Time {
+		ts := time.Date(2022, 11, 1, 10, minute, 0, 0, time.UTC)
+		return &ts
+	}
+	client := &mockedS3{
+		GetObjectFn: func(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+			return &s3.GetObjectOutput{
+				Body:      io.NopCloser(strings.NewReader("value " + aws.StringValue(input.VersionId))),
+				VersionId: input.VersionId,
+			}, nil
+		},
+		// Newest first, with the versions of another key sharing the prefix
+		Versions: []*s3.ListObjectVersionsOutput{
+			{
+				Versions: []*s3.ObjectVersion{
+					{Key: aws.String("app||key"), VersionId: aws.String("v3"), LastModified: at(30)},
+					{Key: aws.String("app||key"), VersionId: aws.String("v2"), LastModified: at(20)},
+				},
+				DeleteMarkers: []*s3.DeleteMarkerEntry{
+					{Key: aws.String("app||key"), VersionId: aws.String("d1"), LastModified: at(15)},
+				},
+			},
+			{
+				Versions: []*s3.ObjectVersion{
+					{Key: aws.String("app||key"), VersionId: aws.String("v1"), LastModified: at(10)},
+					{Key: aws.String("app||key2"), VersionId: aws.String("w1"), LastModified: at(25)},
+				},
+			},
+		},
+	}
+	s := newTestStore(client)
+
+	tests := map[string]struct {
+		metadata map[string]string
+		value    string
+	}{
+		"version":              {map[string]string{"version": "v1"}, "value v1"},
+		"as of a version":      {map[string]string{"asOf": "2022-11-01T10:25:00Z"}, "value v2"},
+		"as of its creation":   {map[string]string{"asOf": "2022-11-01T10:30:00Z"}, "value v3"},
+		"as of a deletion":     {map[string]string{"asOf": "2022-11-01T10:16:00Z"}, ""},
+		"before the first one": {map[string]string{"asOf": "2022-11-01T10:05:00Z"}, ""},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := s.Get(&state.GetRequest{Key: "app||key", Metadata: tt.metadata})
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.value, string(res.Data))
+			if tt.value != "" {
+				assert.Equal(t, map[string]string{"version": strings.TrimPrefix(tt.value, "value ")}, res.Metadata)
+			}
+		})
+	}
+
+	t.Run("invalid metadata", func(t *testing.T) {
+		_, err := s.Get(&state.GetRequest{Key: "app||key", Metadata: map[string]string{"asOf": "yesterday"}})
+		assert.ErrorContains(t, err, "asOf value must be a valid RFC 3339 timestamp")
+
+		_, err = s.Get(&state.GetRequest{Key: "app||key", Metadata: map[string]string{"asOf": "2022-11-01T10:25:00Z", "version": "v1"}})
+		assert.ErrorContains(t, err, "only one of version and asOf can be set")
+	})
+}
+
+func TestBulkGet(t *testing.T) {
+	lock := sync.Mutex{}
+	requested := []string{}
+	s := newTestStore(&mockedS3{
+		GetObjectFn: func(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+			lock.Lock()
+			requested = append(requested, *input.Key)
+			lock.Unlock()
+			if *input.Key == "fail" {
+				return nil, errors.New("access denied")
+			}
+
+			return &s3.GetObjectOutput{
+				Body: io.NopCloser(strings.NewReader("value of " + *input.Key)),
+				ETag: aws.String(`"etag"`),
+			}, nil
+		},
+	})
+
+	supported, res, err := s.BulkGet([]state.GetRequest{{Key: "a"}, {Key: "b"}, {Key: "fail"}})
+
+	require.NoError(t, err)
+	assert.True(t, supported)
+	assert.ElementsMatch(t, []string{"a", "b", "fail"}, requested)
+	require.Len(t, res, 3)
+	assert.Equal(t, "a", res[0].Key)
+	assert.Equal(t, "value of a", string(res[0].Data))
+	assert.Equal(t, "value of b", string(res[1].Data))
+	assert.Equal(t, "fail", res[2].Key)
+	assert.Contains(t, res[2].Error, "access denied")
+}
+
+func TestSet(t *testing.T) {
+	t.Run("without etag", func(t *testing.T) {
+		s := newTestStore(&mockedS3{
+			PutObjectFn: func(input *s3.PutObjectInput, headers http.Header) (*s3.PutObjectOutput, error) {
+				body, _ := io.ReadAll(input.Body)
+				assert.Equal(t, `{"a":1}`, string(body))
+				assert.Empty(t, headers.Get("If-Match"))
+				assert.Empty(t, headers.Get("If-None-Match"))
+
+				return &s3.PutObjectOutput{}, nil
+			},
+		})
+
+		err := s.Set(&state.SetRequest{Key: "key", Value: map[string]int{"a": 1}})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("with etag", func(t *testing.T) {
+		s := newTestStore(&mockedS3{
+			PutObjectFn: func(input *s3.PutObjectInput, headers http.Header) (*s3.PutObjectOutput, error) {
+				assert.Equal(t, `"etag"`, headers.Get("If-Match"))
+
+				return &s3.PutObjectOutput{}, nil
+			},
+		})
+
+		err := s.Set(&state.SetRequest{Key: "key", Value: []byte("value"), ETag: aws.String(`"etag"`)})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("first write", func(t *testing.T) {
+		s := newTestStore(&mockedS3{
+			PutObjectFn: func(input *s3.PutObjectInput, headers http.Header) (*s3.PutObjectOutput, error) {
+				assert.Equal(t, "*", headers.Get("If-None-Match"))
+
+				return &s3.PutObjectOutput{}, nil
+			},
+		})
+
+		err := s.Set(&state.SetRequest{Key: "key", Value: []byte("value"), Options: state.SetStateOption{Concurrency: state.FirstWrite}})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("etag mismatch", func(t *testing.T) {
+		s := newTestStore(&mockedS3{
+			PutObjectFn: func(input *s3.PutObjectInput, headers http.Header) (*s3.PutObjectOutput, error) {
+				return nil, awserr.NewRequestFailure(awserr.New("PreconditionFailed", "precondition failed", nil), http.StatusPreconditionFailed, "")
+			},
+		})
+
+		err := s.Set(&state.SetRequest{Key: "key", Value: []byte("value"), ETag: aws.String(`"etag"`)})
+
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+		assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+	})
+}
+
+func TestDelete(t *testing.T) {
+	t.Run("with etag", func(t *testing.T) {
+		s := newTestStore(&mockedS3{
+			DeleteObjectFn: func(input *s3.DeleteObjectInput, headers http.Header) (*s3.DeleteObjectOutput, error) {
+				assert.Equal(t, "app||key", *input.Key)
+				assert.Equal(t, `"etag"`, headers.Get("If-Match"))
+
+				return &s3.DeleteObjectOutput{}, nil
+			},
+		})
+
+		err := s.Delete(&state.DeleteRequest{Key: "app||key", ETag: aws.String(`"etag"`)})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("etag mismatch", func(t *testing.T) {
+		s := newTestStore(&mockedS3{
+			DeleteObjectFn: func(input *s3.DeleteObjectInput, headers http.Header) (*s3.DeleteObjectOutput, error) {
+				return nil, awserr.NewRequestFailure(awserr.New("PreconditionFailed", "precondition failed", nil), http.StatusPreconditionFailed, "")
+			},
+		})
+
+		err := s.Delete(&state.DeleteRequest{Key: "key", ETag: aws.String(`"etag"`)})
+
+		var etagErr *state.ETagError
+		require.ErrorAs(t, err, &etagErr)
+		assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+	})
+}
+
+func TestBulkDelete(t *testing.T) {
+	// The bulk operations fall back to the single-key operations
+	deleted := []string{}
+	s := NewS3StateStore(logger.NewLogger("test")).(*StateStore)
+	s.client = &mockedS3{
+		DeleteObjectFn: func(input *s3.DeleteObjectInput, headers http.Header) (*s3.DeleteObjectOutput, error) {
+			deleted = append(deleted, *input.Key)
+
+			return &s3.DeleteObjectOutput{}, nil
+		},
+	}
+	s.bucket = "bucket"
+
+	require.NoError(t, s.BulkDelete([]state.DeleteRequest{{Key: "a"}, {Key: "b"}}))
+	assert.Equal(t, []string{"a", "b"}, deleted)
+}
@@ -30,6 +30,8 @@ Sample configuration in yaml:
 	  - name: containerName
 		value: <container Name>
 
+The accountKey may be replaced by a "sasToken" or a "connectionString"; when no credential is given, Azure AD is used.
+
 Concurrency is supported with ETags according to https://docs.microsoft.com/en-us/azure/storage/common/storage-concurrency#managing-concurrency-in-blob-storage
 */
 
@@ -252,3 +254,8 @@ func isNotFoundError(err error) bool {
 func isETagConflictError(err error) bool {
 	return bloberror.HasCode(err, bloberror.ConditionNotMet)
 }
+
+// GetComponentMetadataSchema returns the schema of the metadata of the Blob Storage state store.
+func (r *StateStore) GetComponentMetadataSchema() []mdutils.MetadataField {
+	return storageinternal.MetadataSchema()
+}
@@ -14,6 +14,7 @@ limitations under the License.
 package cosmosdb
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
@@ -50,11 +51,14 @@ type StateStore struct {
 }
 
 type metadata struct {
-	URL         string `json:"url"`
-	MasterKey   string `json:"masterKey"`
-	Database    string `json:"database"`
-	Collection  string `json:"collection"`
-	ContentType string `json:"contentType"`
+	URL         string `json:"url" mdrequired:"true"`
+	MasterKey   string `json:"masterKey" mdsensitive:"true"`
+	Database    string `json:"database" mdrequired:"true"`
+	Collection  string `json:"collection" mdrequired:"true"`
+	ContentType string `json:"contentType" mdrequired:"true"`
+	// Field of the values, as a dot-separated path, holding the partition key of the items, when the requests have no
+	// partitionKey metadata; by default, the partition key is the key of the item.
+	PartitionKeyField string `json:"partitionKeyField"`
 }
 
 type cosmosOperationType string
@@ -208,8 +212,31 @@ func (c *StateStore) Features() []state.Feature {
 }
 
 // Get retrieves a CosmosDB item.
+// When the partition key of the item is in its value, and not in the request metadata, the item is looked up with a
+// cross-partition query.
 func (c *StateStore) Get(req *state.GetRequest) (*state.GetResponse, error) {
-	partitionKey := populatePartitionMetadata(req.Key, req.Metadata)
+	// The change feed only keeps the latest version of the items, so the previous values can't be read
+	if req.Metadata[contribmeta.AsOfMetadataKey] != "" || req.Metadata[contribmeta.VersionMetadataKey] != "" {
+		return nil, fmt.Errorf("cosmosdb does not support reading previous values with the %s and %s metadata", contribmeta.AsOfMetadataKey, contribmeta.VersionMetadataKey)
+	}
+
+	partitionKey, ok := c.requestPartitionKey(req.Key, req.Metadata)
+	if !ok {
+		item, found, err := c.queryItemByID(req.Key)
+		if err != nil || !found {
+			return &state.GetResponse{}, err
+		}
+
+		data, err := c.itemData(item)
+		if err != nil {
+			return nil, err
+		}
+
+		return &state.GetResponse{
+			Data: data,
+			ETag: ptr.Of(item.Etag),
+		}, nil
+	}
 
 	options := azcosmos.ItemOptions{}
 	if req.Options.Consistency == state.Strong {
@@ -237,35 +264,34 @@ func (c *StateStore) Get(req *state.GetRequest) (*state.GetResponse, error) {
 
 	item.Etag = string(readItem.Response.ETag)
 
+	data, err := c.itemData(item)
+	if err != nil {
+		return nil, err
+	}
+
+	return &state.GetResponse{
+		Data: data,
+		ETag: ptr.Of(item.Etag),
+	}, nil
+}
+
+// itemData returns the state data of an item.
+func (c *StateStore) itemData(item CosmosItem) ([]byte, error) {
 	if item.IsBinary {
 		if item.Value == nil {
-			return &state.GetResponse{
-				Data: make([]byte, 0),
-				ETag: ptr.Of(item.Etag),
-			}, nil
+			return make([]byte, 0), nil
 		}
 
-		bytes, decodeErr := base64.StdEncoding.DecodeString(item.Value.(string))
+		data, decodeErr := base64.StdEncoding.DecodeString(item.Value.(string))
 		if decodeErr != nil {
 			c.logger.Warnf("CosmosDB state store Get request could not decode binary string: %v. Returning raw string instead.", decodeErr)
-			bytes = []byte(item.Value.(string))
+			data = []byte(item.Value.(string))
 		}
 
-		return &state.GetResponse{
-			Data: bytes,
-			ETag: ptr.Of(item.Etag),
-		}, nil
+		return data, nil
 	}
 
-	b, err := jsoniter.ConfigFastest.Marshal(&item.Value)
-	if err != nil {
-		return nil, err
-	}
-
-	return &state.GetResponse{
-		Data: b,
-		ETag: ptr.Of(item.Etag),
-	}, nil
+	return jsoniter.ConfigFastest.Marshal(&item.Value)
 }
 
 // Set saves a CosmosDB item.
@@ -275,20 +301,15 @@ func (c *StateStore) Set(req *state.SetRequest) error {
 		return err
 	}
 
-	partitionKey := populatePartitionMetadata(req.Key, req.Metadata)
+	partitionKey, err := c.setPartitionKey(req)
+	if err != nil {
+		return err
+	}
 	options := azcosmos.ItemOptions{}
 
-	if req.ETag != nil && *req.ETag != "" {
-		etag := azcore.ETag(*req.ETag)
-		options.IfMatchEtag = &etag
-	}
-	if req.Options.Concurrency == state.FirstWrite && (req.ETag == nil || *req.ETag == "") {
-		var u uuid.UUID
-		u, err = uuid.NewRandom()
-		if err != nil {
-			return err
-		}
-		options.IfMatchEtag = ptr.Of(azcore.ETag(u.String()))
+	options.IfMatchEtag, err = ifMatchETag(req.ETag, req.Options.Concurrency)
+	if err != nil {
+		return err
 	}
 	// Consistency levels can only be relaxed so the session level is used here
 	if req.Options.Consistency == state.Strong {
@@ -323,7 +344,15 @@ func (c *StateStore) Delete(req *state.DeleteRequest) error {
 	if err != nil {
 		return err
 	}
-	partitionKey := populatePartitionMetadata(req.Key, req.Metadata)
+	partitionKey, ok := c.requestPartitionKey(req.Key, req.Metadata)
+	if !ok {
+		// The partition key is in the value of the item
+		item, found, queryErr := c.queryItemByID(req.Key)
+		if queryErr != nil || !found {
+			return queryErr
+		}
+		partitionKey = item.PartitionKey
+	}
 	options := azcosmos.ItemOptions{}
 
 	if req.ETag != nil && *req.ETag != "" {
@@ -352,14 +381,21 @@ func (c *StateStore) Delete(req *state.DeleteRequest) error {
 }
 
 // Multi performs a transactional operation. succeeds only if all operations succeed, and fails if one or more operations fail.
+// The operations are executed in a transactional batch, so they must all be in the same partition: the partition key
+// is the "partitionKey" metadata of the request, or else the partition key of the operations.
 func (c *StateStore) Multi(request *state.TransactionalStateRequest) (err error) {
 	if len(request.Operations) == 0 {
 		c.logger.Debugf("No Operations Provided")
 		return nil
 	}
+	if len(request.Operations) > maxBatchOperations {
+		return fmt.Errorf("transaction has %d operations, the maximum is %d", len(request.Operations), maxBatchOperations)
+	}
 
-	var partitionKey string
-	partitionKey = populatePartitionMetadata(partitionKey, request.Metadata)
+	partitionKey, err := c.transactionPartitionKey(request)
+	if err != nil {
+		return err
+	}
 
 	batch := c.client.NewTransactionalBatch(azcosmos.NewPartitionKeyString(partitionKey))
 
@@ -368,25 +404,22 @@ func (c *StateStore) Multi(request *state.TransactionalStateRequest) (err error)
 	for _, o := range request.Operations {
 		options := &azcosmos.TransactionalBatchItemOptions{}
 
-		if o.Operation == state.Upsert {
+		switch o.Operation {
+		case state.Upsert:
 			req := o.Request.(state.SetRequest)
+			err = state.CheckRequestOptions(req.Options)
+			if err != nil {
+				return err
+			}
 			var doc CosmosItem
 			doc, err = createUpsertItem(c.contentType, req, partitionKey)
 			if err != nil {
 				return err
 			}
 
-			if req.ETag != nil && *req.ETag != "" {
-				etag := azcore.ETag(*req.ETag)
-				options.IfMatchETag = &etag
-			}
-			if req.Options.Concurrency == state.FirstWrite && (req.ETag == nil || *req.ETag == "") {
-				var u uuid.UUID
-				u, err = uuid.NewRandom()
-				if err != nil {
-					return err
-				}
-				options.IfMatchETag = ptr.Of(azcore.ETag(u.String()))
+			options.IfMatchETag, err = ifMatchETag(req.ETag, req.Options.Concurrency)
+			if err != nil {
+				return err
 			}
 
 			var marsh []byte
@@ -394,26 +427,24 @@ func (c *StateStore) Multi(request *state.TransactionalStateRequest) (err error)
 			if err != nil {
 				return err
 			}
-			batch.UpsertItem(marsh, nil)
+			batch.UpsertItem(marsh, options)
 			numOperations++
-		} else if o.Operation == state.Delete {
+		case state.Delete:
 			req := o.Request.(state.DeleteRequest)
-
-			if req.ETag != nil && *req.ETag != "" {
-				etag := azcore.ETag(*req.ETag)
-				options.IfMatchETag = &etag
+			err = state.CheckRequestOptions(req.Options)
+			if err != nil {
+				return err
 			}
-			if req.Options.Concurrency == state.FirstWrite && (req.ETag == nil || *req.ETag == "") {
-				var u uuid.UUID
-				u, err = uuid.NewRandom()
-				if err != nil {
-					return err
-				}
-				options.IfMatchETag = ptr.Of(azcore.ETag(u.String()))
+
+			options.IfMatchETag, err = ifMatchETag(req.ETag, req.Options.Concurrency)
+			if err != nil {
+				return err
 			}
 
 			batch.DeleteItem(req.Key, options)
 			numOperations++
+		default:
+			return fmt.Errorf("unsupported operation type %s", o.Operation)
 		}
 	}
 
@@ -428,13 +459,9 @@ func (c *StateStore) Multi(request *state.TransactionalStateRequest) (err error)
 
 	if !batchResponse.Success {
 		// Transaction failed, look for the offending operation
-		for index, operation := range batchResponse.OperationResults {
-			if operation.StatusCode != http.StatusFailedDependency {
-				c.logger.Errorf("Transaction failed due to operation %v which failed with status code %d", index, operation.StatusCode)
-				return fmt.Errorf("transaction failed due to operation %v which failed with status code %d", index, operation.StatusCode)
-			}
-		}
-		return errors.New("transaction failed")
+		err = batchError("transaction", batchResponse)
+		c.logger.Errorf("Transaction failed: %v", err)
+		return err
 	}
 
 	// Transaction succeeded
@@ -446,6 +473,58 @@ func (c *StateStore) Multi(request *state.TransactionalStateRequest) (err error)
 	return nil
 }
 
+// transactionPartitionKey returns the partition key of the operations of a transaction: the "partitionKey" metadata
+// of the request, or the partition key shared by all the operations whose partition key is known.
+func (c *StateStore) transactionPartitionKey(request *state.TransactionalStateRequest) (string, error) {
+	if val, found := request.Metadata[metadataPartitionKey]; found {
+		for _, o := range request.Operations {
+			req, ok := o.Request.(state.KeyInt)
+			if !ok {
+				continue
+			}
+			if opVal, ok := req.GetMetadata()[metadataPartitionKey]; ok && opVal != val {
+				return "", fmt.Errorf("operation on key %s has partition key %s, and the transaction %s: transactions cannot span multiple partitions", req.GetKey(), opVal, val)
+			}
+		}
+		return val, nil
+	}
+
+	var (
+		partitionKey string
+		found        bool
+	)
+	for _, o := range request.Operations {
+		var (
+			opPartitionKey string
+			ok             bool
+		)
+		switch req := o.Request.(type) {
+		case state.SetRequest:
+			var err error
+			opPartitionKey, err = c.setPartitionKey(&req)
+			if err != nil {
+				return "", fmt.Errorf("error getting the partition key of key %s: %w", req.Key, err)
+			}
+			ok = true
+		case state.DeleteRequest:
+			// The partition key of a delete is unknown if it is in the value of the item
+			opPartitionKey, ok = c.requestPartitionKey(req.Key, req.Metadata)
+		}
+		if !ok {
+			continue
+		}
+		if found && opPartitionKey != partitionKey {
+			return "", fmt.Errorf("operations on keys with partition keys %s and %s: transactions cannot span multiple partitions", partitionKey, opPartitionKey)
+		}
+		partitionKey, found = opPartitionKey, true
+	}
+	if !found {
+		return "", errors.New("the partition key of the transaction is unknown: the partitionKey metadata is required")
+	}
+
+	return partitionKey, nil
+}
+
 func (c *StateStore) Query(req *state.QueryRequest) (*state.QueryResponse, error) {
 	q := &Query{}
 
@@ -524,6 +603,89 @@ func createUpsertItem(contentType string, req state.SetRequest, partitionKey str
 	return item, nil
 }
 
+// requestPartitionKey returns the partition key of the "partitionKey" request metadata, or the key when the partition
+// keys are not in the values of the items. It returns false if the partition key is in the value of the item.
+func (c *StateStore) requestPartitionKey(key string, requestMetadata map[string]string) (string, bool) {
+	if val, found := requestMetadata[metadataPartitionKey]; found {
+		return val, true
+	}
+	if c.metadata.PartitionKeyField != "" {
+		return "", false
+	}
+
+	return key, true
+}
+
+// setPartitionKey returns the partition key of a set request: the "partitionKey" request metadata, or the field of
+// the value of the "partitionKeyField" component metadata, or the key.
+func (c *StateStore) setPartitionKey(req *state.SetRequest) (string, error) {
+	partitionKey, ok := c.requestPartitionKey(req.Key, req.Metadata)
+	if ok {
+		return partitionKey, nil
+	}
+
+	return partitionKeyFromValue(req.Value, c.metadata.PartitionKeyField)
+}
+
+// partitionKeyFromValue returns the value of the field, a dot-separated path, of a JSON object or of a value
+// marshalled as a JSON object.
+func partitionKeyFromValue(value interface{}, field string) (string, error) {
+	b, ok := value.([]byte)
+	if !ok {
+		var err error
+		b, err = json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var val interface{}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	err := decoder.Decode(&val)
+	if err != nil {
+		return "", fmt.Errorf("the value must be a JSON object with the partition key field %s: %w", field, err)
+	}
+	for _, name := range strings.Split(field, ".") {
+		obj, ok := val.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("partition key field %s not found in the value", field)
+		}
+		val, ok = obj[name]
+		if !ok {
+			return "", fmt.Errorf("partition key field %s not found in the value", field)
+		}
+	}
+
+	switch v := val.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		return "", fmt.Errorf("partition key field %s must be a string, a number or a boolean", field)
+	}
+}
+
+// ifMatchETag returns the etag condition of a write: the etag of the request, or a random etag, which never matches,
+// for first-write concurrency.
+func ifMatchETag(etag *string, concurrency string) (*azcore.ETag, error) {
+	if etag != nil && *etag != "" {
+		return ptr.Of(azcore.ETag(*etag)), nil
+	}
+	if concurrency == state.FirstWrite {
+		u, err := uuid.NewRandom()
+		if err != nil {
+			return nil, err
+		}
+		return ptr.Of(azcore.ETag(u.String())), nil
+	}
+
+	return nil, nil
+}
+
 // This is a helper to return the partition key to use.  If if metadata["partitionkey"] is present,
 // use that, otherwise use what's in "key".
 func populatePartitionMetadata(key string, requestMetadata map[string]string) string {
@@ -561,3 +723,11 @@ func isNotFoundError(err error) bool {
 
 	return false
 }
+
+// GetComponentMetadataSchema returns the schema of the metadata of the Cosmos DB state store.
+func (c *StateStore) GetComponentMetadataSchema() []contribmeta.MetadataField {
+	fields, _ := contribmeta.GetMetadataSchemaFromStruct(metadata{
+		ContentType: "application/json",
+	})
+	return fields
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cosmosdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/ptr"
+)
+
+const (
+	// Maximum number of operations of a transactional batch.
+	maxBatchOperations = 100
+	// Maximum number of ids of a query of the items of a bulk get.
+	maxBulkGetIDs = 100
+
+	queryItemsByID = "SELECT * FROM c WHERE ARRAY_CONTAINS(@ids, c.id)"
+)
+
+// queriedItem is an item returned by a query, with its etag.
+type queriedItem struct {
+	CosmosItem
+	ETag string `json:"_etag"`
+}
+
+// BulkGet retrieves the items with cross-partition queries of up to maxBulkGetIDs keys, instead of a read per key.
+func (c *StateStore) BulkGet(req []state.GetRequest) (bool, []state.BulkGetResponse, error) {
+	ids := make([]string, 0, len(req))
+	seen := make(map[string]struct{}, len(req))
+	for _, r := range req {
+		if _, ok := seen[r.Key]; !ok {
+			seen[r.Key] = struct{}{}
+			ids = append(ids, r.Key)
+		}
+	}
+
+	items := make(map[string][]CosmosItem, len(ids))
+	for start := 0; start < len(ids); start += maxBulkGetIDs {
+		end := start + maxBulkGetIDs
+		if end > len(ids) {
+			end = len(ids)
+		}
+		chunk, err := c.queryItemsByID(ids[start:end])
+		if err != nil {
+			return true, nil, err
+		}
+		for _, item := range chunk {
+			items[item.ID] = append(items[item.ID], item)
+		}
+	}
+
+	return true, c.bulkGetResponses(req, items), nil
+}
+
+// bulkGetResponses returns the responses of the get requests from the items found, by id.
+// The keys which are not found have no data.
+func (c *StateStore) bulkGetResponses(req []state.GetRequest, items map[string][]CosmosItem) []state.BulkGetResponse {
+	res := make([]state.BulkGetResponse, len(req))
+	for i, r := range req {
+		res[i].Key = r.Key
+
+		partitionKey, ok := c.requestPartitionKey(r.Key, r.Metadata)
+		var item *CosmosItem
+		for j := range items[r.Key] {
+			if !ok || items[r.Key][j].PartitionKey == partitionKey {
+				item = &items[r.Key][j]
+				break
+			}
+		}
+		if item == nil {
+			continue
+		}
+
+		data, err := c.itemData(*item)
+		if err != nil {
+			res[i].Error = err.Error()
+			continue
+		}
+		res[i].Data = data
+		res[i].ETag = ptr.Of(item.Etag)
+	}
+
+	return res
+}
+
+// queryItemByID returns the item with the id, in any partition.
+func (c *StateStore) queryItemByID(id string) (CosmosItem, bool, error) {
+	items, err := c.queryItemsByID([]string{id})
+	if err != nil || len(items) == 0 {
+		return CosmosItem{}, false, err
+	}
+	if len(items) > 1 {
+		return CosmosItem{}, false, fmt.Errorf("key %s exists in %d partitions: the partitionKey metadata is required", id, len(items))
+	}
+
+	return items[0], true, nil
+}
+
+// queryItemsByID returns the items with the ids, in all the partitions.
+func (c *StateStore) queryItemsByID(ids []string) ([]CosmosItem, error) {
+	opts := &azcosmos.QueryOptions{
+		QueryParameters: []azcosmos.QueryParameter{
+			{Name: "@ids", Value: ids},
+		},
+	}
+	// The query is sent to all the partitions by crossPartitionQueryPolicy
+	queryPager := c.client.NewQueryItemsPager(queryItemsByID, azcosmos.NewPartitionKeyBool(true), opts)
+
+	items := make([]CosmosItem, 0, len(ids))
+	for queryPager.More() {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		queryResponse, err := queryPager.NextPage(ctx)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, b := range queryResponse.Items {
+			var item queriedItem
+			err = json.Unmarshal(b, &item)
+			if err != nil {
+				return nil, err
+			}
+			item.CosmosItem.Etag = item.ETag
+			items = append(items, item.CosmosItem)
+		}
+	}
+
+	return items, nil
+}
+
+// BulkSet upserts the items with a transactional batch per partition key and per maxBatchOperations items, instead of
+// an upsert per key. The items of different batches are not written atomically.
+func (c *StateStore) BulkSet(req []state.SetRequest) error {
+	batches, err := c.bulkSetBatches(req)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range batches {
+		batch := c.client.NewTransactionalBatch(azcosmos.NewPartitionKeyString(b.partitionKey))
+		for _, op := range b.operations {
+			batch.UpsertItem(op.item, op.options)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		batchResponse, err := c.client.ExecuteTransactionalBatch(ctx, batch, nil)
+		cancel()
+		if err != nil {
+			return err
+		}
+		if !batchResponse.Success {
+			return batchError("batch", batchResponse)
+		}
+	}
+
+	return nil
+}
+
+type upsertBatch struct {
+	partitionKey string
+	operations   []upsertOperation
+}
+
+type upsertOperation struct {
+	item    []byte
+	options *azcosmos.TransactionalBatchItemOptions
+}
+
+// bulkSetBatches groups the items of the set requests in batches of at most maxBatchOperations items of the same
+// partition key, in the order of the requests.
+func (c *StateStore) bulkSetBatches(req []state.SetRequest) ([]upsertBatch, error) {
+	batches := []upsertBatch{}
+	// Index of the last batch of each partition key
+	last := map[string]int{}
+	for i := range req {
+		err := state.CheckRequestOptions(req[i].Options)
+		if err != nil {
+			return nil, err
+		}
+
+		partitionKey, err := c.setPartitionKey(&req[i])
+		if err != nil {
+			return nil, fmt.Errorf("error getting the partition key of key %s: %w", req[i].Key, err)
+		}
+		doc, err := createUpsertItem(c.contentType, req[i], partitionKey)
+		if err != nil {
+			return nil, err
+		}
+		item, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		options := &azcosmos.TransactionalBatchItemOptions{}
+		options.IfMatchETag, err = ifMatchETag(req[i].ETag, req[i].Options.Concurrency)
+		if err != nil {
+			return nil, err
+		}
+
+		idx, ok := last[partitionKey]
+		if !ok || len(batches[idx].operations) == maxBatchOperations {
+			batches = append(batches, upsertBatch{partitionKey: partitionKey})
+			idx = len(batches) - 1
+			last[partitionKey] = idx
+		}
+		batches[idx].operations = append(batches[idx].operations, upsertOperation{
+			item:    item,
+			options: options,
+		})
+	}
+
+	return batches, nil
+}
+
+// batchError returns the error of the operation which failed a transactional batch, executing a batch or a
+// transaction as named by kind.
+func batchError(kind string, batchResponse azcosmos.TransactionalBatchResponse) error {
+	for index, operation := range batchResponse.OperationResults {
+		switch operation.StatusCode {
+		case http.StatusFailedDependency:
+			continue
+		case http.StatusPreconditionFailed:
+			return state.NewETagError(state.ETagMismatch, fmt.Errorf("%s failed due to operation %v which failed with status code %d", kind, index, operation.StatusCode))
+		default:
+			return fmt.Errorf("%s failed due to operation %v which failed with status code %d", kind, index, operation.StatusCode)
+		}
+	}
+
+	return fmt.Errorf("%s failed", kind)
+}
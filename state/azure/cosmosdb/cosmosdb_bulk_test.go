@@ -0,0 +1,204 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cosmosdb
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/ptr"
+)
+
+func newTestStateStore(partitionKeyField string) *StateStore {
+	return &StateStore{
+		metadata: metadata{
+			ContentType:       "application/json",
+			PartitionKeyField: partitionKeyField,
+		},
+		contentType: "application/json",
+		logger:      logger.NewLogger("test"),
+	}
+}
+
+func TestPartitionKey(t *testing.T) {
+	t.Run("key by default", func(t *testing.T) {
+		c := newTestStateStore("")
+
+		partitionKey, err := c.setPartitionKey(&state.SetRequest{Key: "key1", Value: []byte(`{"tenant": "a"}`)})
+
+		require.NoError(t, err)
+		assert.Equal(t, "key1", partitionKey)
+	})
+
+	t.Run("request metadata", func(t *testing.T) {
+		c := newTestStateStore("tenant")
+
+		partitionKey, err := c.setPartitionKey(&state.SetRequest{
+			Key:      "key1",
+			Value:    []byte(`{"tenant": "a"}`),
+			Metadata: map[string]string{"partitionKey": "b"},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "b", partitionKey)
+	})
+
+	t.Run("field of the value", func(t *testing.T) {
+		c := newTestStateStore("customer.tenant")
+
+		partitionKey, err := c.setPartitionKey(&state.SetRequest{Key: "key1", Value: []byte(`{"customer": {"tenant": 12345678901}}`)})
+		require.NoError(t, err)
+		assert.Equal(t, "12345678901", partitionKey)
+
+		partitionKey, err = c.setPartitionKey(&state.SetRequest{Key: "key1", Value: map[string]interface{}{
+			"customer": map[string]interface{}{"tenant": "a"},
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, "a", partitionKey)
+	})
+
+	t.Run("missing field", func(t *testing.T) {
+		c := newTestStateStore("tenant")
+
+		_, err := c.setPartitionKey(&state.SetRequest{Key: "key1", Value: []byte(`{"name": "a"}`)})
+		assert.ErrorContains(t, err, "partition key field tenant not found")
+
+		_, err = c.setPartitionKey(&state.SetRequest{Key: "key1", Value: []byte(`"a"`)})
+		assert.ErrorContains(t, err, "partition key field tenant not found")
+
+		_, err = c.setPartitionKey(&state.SetRequest{Key: "key1", Value: []byte(`{"tenant": {}}`)})
+		assert.ErrorContains(t, err, "must be a string, a number or a boolean")
+	})
+
+	t.Run("unknown partition key of get requests", func(t *testing.T) {
+		c := newTestStateStore("tenant")
+
+		_, ok := c.requestPartitionKey("key1", nil)
+		assert.False(t, ok)
+
+		partitionKey, ok := c.requestPartitionKey("key1", map[string]string{"partitionKey": "a"})
+		assert.True(t, ok)
+		assert.Equal(t, "a", partitionKey)
+	})
+}
+
+func TestBulkGetResponses(t *testing.T) {
+	items := map[string][]CosmosItem{
+		"key1": {
+			{ID: "key1", PartitionKey: "a", Value: map[string]interface{}{"n": 1}, Etag: "etag1"},
+			{ID: "key1", PartitionKey: "b", Value: map[string]interface{}{"n": 2}, Etag: "etag2"},
+		},
+		"key2": {
+			{ID: "key2", PartitionKey: "key2", Value: "aGVsbG8=", IsBinary: true, Etag: "etag3"},
+		},
+	}
+
+	t.Run("matches the partition keys of the requests", func(t *testing.T) {
+		c := newTestStateStore("")
+
+		res := c.bulkGetResponses([]state.GetRequest{
+			{Key: "key1", Metadata: map[string]string{"partitionKey": "b"}},
+			{Key: "key2"},
+			{Key: "key1"},
+			{Key: "key3"},
+		}, items)
+
+		require.Len(t, res, 4)
+		assert.Equal(t, state.BulkGetResponse{Key: "key1", Data: []byte(`{"n":2}`), ETag: ptr.Of("etag2")}, res[0])
+		assert.Equal(t, state.BulkGetResponse{Key: "key2", Data: []byte("hello"), ETag: ptr.Of("etag3")}, res[1])
+		// The partition key of the request is its key
+		assert.Equal(t, state.BulkGetResponse{Key: "key1"}, res[2])
+		assert.Equal(t, state.BulkGetResponse{Key: "key3"}, res[3])
+	})
+
+	t.Run("any partition when the partition key is in the value", func(t *testing.T) {
+		c := newTestStateStore("tenant")
+
+		res := c.bulkGetResponses([]state.GetRequest{{Key: "key1"}}, items)
+
+		require.Len(t, res, 1)
+		assert.Equal(t, []byte(`{"n":1}`), res[0].Data)
+	})
+}
+
+func TestBulkSetBatches(t *testing.T) {
+	c := newTestStateStore("tenant")
+
+	req := make([]state.SetRequest, 0, maxBatchOperations+3)
+	for i := 0; i < maxBatchOperations+1; i++ {
+		req = append(req, state.SetRequest{Key: "a" + strconv.Itoa(i), Value: []byte(`{"tenant": "a"}`)})
+	}
+	req = append(req,
+		state.SetRequest{Key: "b1", Value: []byte(`{"tenant": "b"}`), ETag: ptr.Of("etag1")},
+		state.SetRequest{Key: "c1", Value: []byte(`{"tenant": "b"}`), Metadata: map[string]string{"partitionKey": "c"}},
+	)
+
+	batches, err := c.bulkSetBatches(req)
+
+	require.NoError(t, err)
+	require.Len(t, batches, 4)
+	assert.Equal(t, "a", batches[0].partitionKey)
+	assert.Len(t, batches[0].operations, maxBatchOperations)
+	assert.Equal(t, "a", batches[1].partitionKey)
+	assert.Len(t, batches[1].operations, 1)
+	assert.Equal(t, "b", batches[2].partitionKey)
+	require.Len(t, batches[2].operations, 1)
+	assert.Equal(t, "etag1", string(*batches[2].operations[0].options.IfMatchETag))
+	assert.Equal(t, "c", batches[3].partitionKey)
+
+	var item CosmosItem
+	require.NoError(t, json.Unmarshal(batches[2].operations[0].item, &item))
+	assert.Equal(t, "b1", item.ID)
+	assert.Equal(t, "b", item.PartitionKey)
+
+	t.Run("returns error for missing partition key", func(t *testing.T) {
+		_, err := c.bulkSetBatches([]state.SetRequest{{Key: "key1", Value: []byte(`{}`)}})
+
+		assert.ErrorContains(t, err, "error getting the partition key of key key1")
+	})
+}
+
+func TestBatchError(t *testing.T) {
+	t.Run("etag mismatch", func(t *testing.T) {
+		err := batchError("batch", azcosmos.TransactionalBatchResponse{
+			OperationResults: []azcosmos.TransactionalBatchResult{
+				{StatusCode: http.StatusFailedDependency},
+				{StatusCode: http.StatusPreconditionFailed},
+			},
+		})
+
+		var etagErr *state.ETagError
+		require.True(t, errors.As(err, &etagErr))
+		assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+	})
+
+	t.Run("other error", func(t *testing.T) {
+		err := batchError("batch", azcosmos.TransactionalBatchResponse{
+			OperationResults: []azcosmos.TransactionalBatchResult{
+				{StatusCode: http.StatusRequestEntityTooLarge},
+			},
+		})
+
+		assert.ErrorContains(t, err, "operation 0 which failed with status code 413")
+	})
+}
@@ -355,3 +355,88 @@ func TestCreateCosmosItemWithTTL(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestTransactionPartitionKey(t *testing.T) {
+	upsert := func(key string, value string, md map[string]string) state.TransactionalStateOperation {
+		return state.TransactionalStateOperation{
+			Operation: state.Upsert,
+			Request:   state.SetRequest{Key: key, Value: []byte(value), Metadata: md},
+		}
+	}
+	del := func(key string, md map[string]string) state.TransactionalStateOperation {
+		return state.TransactionalStateOperation{
+			Operation: state.Delete,
+			Request:   state.DeleteRequest{Key: key, Metadata: md},
+		}
+	}
+
+	t.Run("partition key of the request", func(t *testing.T) {
+		c := newTestStateStore("")
+
+		partitionKey, err := c.transactionPartitionKey(&state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{upsert("key1", `{}`, nil), del("key2", map[string]string{"partitionKey": "a"})},
+			Metadata:   map[string]string{"partitionKey": "a"},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "a", partitionKey)
+	})
+
+	t.Run("operation in another partition than the request", func(t *testing.T) {
+		c := newTestStateStore("")
+
+		_, err := c.transactionPartitionKey(&state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{del("key2", map[string]string{"partitionKey": "b"})},
+			Metadata:   map[string]string{"partitionKey": "a"},
+		})
+
+		assert.ErrorContains(t, err, "transactions cannot span multiple partitions")
+	})
+
+	t.Run("mixed upserts and deletes of the same partition", func(t *testing.T) {
+		c := newTestStateStore("tenant")
+
+		partitionKey, err := c.transactionPartitionKey(&state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{
+				upsert("key1", `{"tenant": "a"}`, nil),
+				// The partition key of the delete is in the value of the item
+				del("key2", nil),
+				upsert("key3", `{"tenant": "a"}`, nil),
+				del("key4", map[string]string{"partitionKey": "a"}),
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "a", partitionKey)
+	})
+
+	t.Run("operations in multiple partitions", func(t *testing.T) {
+		c := newTestStateStore("")
+
+		_, err := c.transactionPartitionKey(&state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{upsert("key1", `{}`, nil), del("key2", nil)},
+		})
+
+		assert.ErrorContains(t, err, "partition keys key1 and key2: transactions cannot span multiple partitions")
+	})
+
+	t.Run("unknown partition key", func(t *testing.T) {
+		c := newTestStateStore("tenant")
+
+		_, err := c.transactionPartitionKey(&state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{del("key1", nil)},
+		})
+
+		assert.ErrorContains(t, err, "the partitionKey metadata is required")
+	})
+}
+
+func TestGetPreviousValues(t *testing.T) {
+	c := newTestStateStore("")
+
+	for _, key := range []string{"asOf", "version"} {
+		_, err := c.Get(&state.GetRequest{Key: "key1", Metadata: map[string]string{key: "2022-11-01T10:00:00Z"}})
+
+		assert.ErrorContains(t, err, "cosmosdb does not support reading previous values", key)
+	}
+}
@@ -29,8 +29,18 @@ Sample configuration in yaml:
 		value: <key>
 	  - name: tableName
 		value: <table name>
+	  - name: partitionKeyStrategy
+		value: appID
 
-This store uses PartitionKey as service name, and RowKey as the rest of the composite key.
+Instead of accountKey, the store may be given a "sasToken" or a "connectionString" (which includes the account name);
+without any of them it authenticates with Azure AD.
+
+With the default "appID" partition key strategy, this store uses PartitionKey as service name, and RowKey as the rest
+of the composite key. With the "key" strategy, each key is stored in its own partition. With the "metadata" strategy,
+PartitionKey is read from the "partitionKey" metadata of the requests, and RowKey is the whole key.
+
+Transactions are supported as entity group transactions, so all the operations of a transaction must be in the same
+partition.
 
 Concurrency is supported with ETags according to https://docs.microsoft.com/en-us/azure/storage/common/storage-concurrency#managing-concurrency-in-table-storage
 */
@@ -40,6 +50,7 @@ package tablestorage
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"reflect"
 	"strings"
 	"time"
@@ -47,6 +58,7 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
+	"github.com/Azure/go-autorest/autorest/azure"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/pkg/errors"
 
@@ -62,25 +74,49 @@ const (
 
 	cosmosDBModeKey = "cosmosDbMode"
 	timeout         = 15 * time.Second
+
+	partitionKeyMetadataKey = "partitionKey"
+	emptyRowKeyCosmosDB     = "_dapr_empty_row_key_value_"
+
+	// Entity group transactions are limited to 100 operations.
+	maxTransactionOperations = 100
+)
+
+// Strategies to map the keys to the partition and row keys of the entities.
+const (
+	// Partition key is the app ID prefix of the key, and row key the rest of it.
+	partitionKeyStrategyAppID = "appID"
+	// Each key is stored in its own partition, with an empty row key.
+	partitionKeyStrategyKey = "key"
+	// Partition key is the partitionKey metadata of the request, and row key the whole key.
+	partitionKeyStrategyMetadata = "metadata"
 )
 
 type StateStore struct {
 	state.DefaultBulkStore
-	client       *aztables.Client
-	json         jsoniter.API
-	cosmosDBMode bool
+	client               *aztables.Client
+	json                 jsoniter.API
+	cosmosDBMode         bool
+	partitionKeyStrategy string
 
 	features []state.Feature
 	logger   logger.Logger
 }
 
 type tablesMetadata struct {
-	AccountName     string
-	AccountKey      string // optional, if not provided, will use Azure AD authentication
-	TableName       string
-	CosmosDBMode    bool   // if true, use CosmosDB Table API, otherwise use Azure Table Storage
-	ServiceURL      string // optional, if not provided, will use default Azure service URL
-	SkipCreateTable bool   // skip attempt to create table - useful for fine grained AAD roles
+	AccountName      string `mdrequired:"true"`
+	AccountKey       string `mdsensitive:"true"`                         // optional, if not provided, will use Azure AD authentication
+	SASToken         string `mapstructure:"sasToken" mdsensitive:"true"` // optional, used if the account key is not provided
+	ConnectionString string `mdsensitive:"true"`                         // optional, takes precedence over the account key and SAS token
+	TableName        string `mdrequired:"true"`
+	CosmosDBMode     bool   // if true, use CosmosDB Table API, otherwise use Azure Table Storage
+	ServiceURL       string // optional, if not provided, will use default Azure service URL
+	SkipCreateTable  bool   // skip attempt to create table - useful for fine grained AAD roles
+	// appID, key or metadata; how keys are mapped to partition and row keys
+	PartitionKeyStrategy string
+
+	// Credentials selected by the metadata provided
+	auth *azauth.StorageAuth
 }
 
 // Init Initialises connection to table storage, optionally creates a table if it doesn't exist.
@@ -93,13 +129,14 @@ func (r *StateStore) Init(metadata state.Metadata) error {
 	var client *aztables.ServiceClient
 
 	r.cosmosDBMode = meta.CosmosDBMode
+	r.partitionKeyStrategy = meta.PartitionKeyStrategy
 	serviceURL := meta.ServiceURL
 
 	if serviceURL == "" {
 		if r.cosmosDBMode {
 			serviceURL = fmt.Sprintf("https://%s.table.cosmos.azure.com", meta.AccountName)
 		} else {
-			serviceURL = fmt.Sprintf("https://%s.table.core.windows.net", meta.AccountName)
+			serviceURL = meta.auth.ServiceURL(azauth.StorageServiceTable, &azure.PublicCloud)
 		}
 	}
 
@@ -111,7 +148,8 @@ func (r *StateStore) Init(metadata state.Metadata) error {
 		},
 	}
 
-	if meta.AccountKey != "" {
+	switch meta.auth.Method {
+	case azauth.StorageAuthAccountKey:
 		// use shared key authentication
 		cred, innerErr := aztables.NewSharedKeyCredential(meta.AccountName, meta.AccountKey)
 		if innerErr != nil {
@@ -122,7 +160,13 @@ func (r *StateStore) Init(metadata state.Metadata) error {
 		if innerErr != nil {
 			return innerErr
 		}
-	} else {
+	case azauth.StorageAuthSASToken:
+		var innerErr error
+		client, innerErr = aztables.NewServiceClientWithNoCredential(meta.auth.AddSASToken(serviceURL), &opts)
+		if innerErr != nil {
+			return innerErr
+		}
+	default:
 		// fallback to azure AD authentication
 		var settings azauth.EnvironmentSettings
 		var innerErr error
@@ -140,7 +184,7 @@ func (r *StateStore) Init(metadata state.Metadata) error {
 			return innerErr
 		}
 		client, innerErr = aztables.NewServiceClient(serviceURL, token, &opts)
-		if err != nil {
+		if innerErr != nil {
 			return innerErr
 		}
 	}
@@ -188,7 +232,7 @@ func (r *StateStore) Delete(req *state.DeleteRequest) error {
 
 func (r *StateStore) Get(req *state.GetRequest) (*state.GetResponse, error) {
 	r.logger.Debugf("fetching %s", req.Key)
-	pk, rk := getPartitionAndRowKey(req.Key, r.cosmosDBMode)
+	pk, rk := r.partitionAndRowKey(req.Key, req.Metadata)
 	getContext, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 	resp, err := r.client.GetEntity(getContext, pk, rk, nil)
@@ -222,10 +266,117 @@ func (r *StateStore) GetComponentMetadata() map[string]string {
 	return metadataInfo
 }
 
+// Multi performs the operations of a transactional request as an entity group transaction.
+func (r *StateStore) Multi(request *state.TransactionalStateRequest) error {
+	if len(request.Operations) == 0 {
+		return nil
+	}
+
+	actions, err := r.transactionActions(request)
+	if err != nil {
+		return err
+	}
+
+	transactionContext, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	_, err = r.client.SubmitTransaction(transactionContext, actions, nil)
+	if err != nil {
+		if isETagMismatchError(err) {
+			return state.NewETagError(state.ETagMismatch, err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *StateStore) transactionActions(request *state.TransactionalStateRequest) ([]aztables.TransactionAction, error) {
+	if len(request.Operations) > maxTransactionOperations {
+		return nil, fmt.Errorf("transaction has %d operations, the maximum is %d", len(request.Operations), maxTransactionOperations)
+	}
+
+	actions := make([]aztables.TransactionAction, len(request.Operations))
+	partitionKey := ""
+	for i, o := range request.Operations {
+		var (
+			action aztables.TransactionAction
+			pk     string
+			err    error
+		)
+		switch o.Operation {
+		case state.Upsert:
+			req := o.Request.(state.SetRequest)
+			req.Metadata = transactionOperationMetadata(req.Metadata, request.Metadata)
+			action.Entity, err = r.marshal(&req)
+			if err != nil {
+				return nil, err
+			}
+			pk, _ = r.partitionAndRowKey(req.Key, req.Metadata)
+
+			switch {
+			case req.ETag != nil && *req.ETag != "":
+				etag := azcore.ETag(*req.ETag)
+				action.ActionType = aztables.TransactionTypeUpdateReplace
+				action.IfMatch = &etag
+			case req.Options.Concurrency == state.FirstWrite:
+				action.ActionType = aztables.TransactionTypeAdd
+			default:
+				action.ActionType = aztables.TransactionTypeInsertReplace
+			}
+		case state.Delete:
+			req := o.Request.(state.DeleteRequest)
+			req.Metadata = transactionOperationMetadata(req.Metadata, request.Metadata)
+			var rk string
+			pk, rk = r.partitionAndRowKey(req.Key, req.Metadata)
+			action.Entity, err = jsoniter.Marshal(aztables.Entity{
+				PartitionKey: pk,
+				RowKey:       rk,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			etag := azcore.ETagAny
+			if req.ETag != nil {
+				etag = azcore.ETag(*req.ETag)
+			}
+			action.ActionType = aztables.TransactionTypeDelete
+			action.IfMatch = &etag
+		default:
+			return nil, fmt.Errorf("unsupported operation: %s", o.Operation)
+		}
+
+		if i == 0 {
+			partitionKey = pk
+		} else if pk != partitionKey {
+			return nil, fmt.Errorf("all the operations of a transaction must have the same partition key, found '%s' and '%s'", partitionKey, pk)
+		}
+		actions[i] = action
+	}
+
+	return actions, nil
+}
+
+// transactionOperationMetadata returns the metadata of an operation, with the metadata of the transaction as fallback.
+func transactionOperationMetadata(operation map[string]string, transaction map[string]string) map[string]string {
+	if len(transaction) == 0 {
+		return operation
+	}
+
+	md := make(map[string]string, len(operation)+len(transaction))
+	for k, v := range transaction {
+		md[k] = v
+	}
+	for k, v := range operation {
+		md[k] = v
+	}
+	return md
+}
+
 func NewAzureTablesStateStore(logger logger.Logger) state.Store {
 	s := &StateStore{
 		json:     jsoniter.ConfigFastest,
-		features: []state.Feature{state.FeatureETag},
+		features: []state.Feature{state.FeatureETag, state.FeatureTransactional},
 		logger:   logger,
 	}
 	s.DefaultBulkStore = state.NewDefaultBulkStore(s)
@@ -237,14 +388,15 @@ func getTablesMetadata(meta map[string]string) (*tablesMetadata, error) {
 	m := tablesMetadata{}
 	err := mdutils.DecodeMetadata(meta, &m)
 
-	if val, ok := mdutils.GetMetadataProperty(meta, azauth.StorageAccountNameKeys...); ok && val != "" {
-		m.AccountName = val
-	} else {
-		return nil, errors.New(fmt.Sprintf("missing or empty %s field from metadata", azauth.StorageAccountNameKeys[0]))
+	auth, authErr := azauth.GetStorageAuth(meta)
+	if authErr != nil {
+		return nil, authErr
 	}
-
+	m.auth = auth
+	m.AccountName = auth.AccountName
 	// Can be empty (such as when using Azure AD for auth)
-	m.AccountKey, _ = mdutils.GetMetadataProperty(meta, azauth.StorageAccountKeyKeys...)
+	m.AccountKey = auth.AccountKey
+	m.SASToken = auth.SASToken
 
 	if val, ok := mdutils.GetMetadataProperty(meta, azauth.StorageTableNameKeys...); ok && val != "" {
 		m.TableName = val
@@ -252,6 +404,18 @@ func getTablesMetadata(meta map[string]string) (*tablesMetadata, error) {
 		return nil, errors.New(fmt.Sprintf("missing or empty %s field from metadata", azauth.StorageTableNameKeys[0]))
 	}
 
+	switch strings.ToLower(m.PartitionKeyStrategy) {
+	case "", strings.ToLower(partitionKeyStrategyAppID):
+		m.PartitionKeyStrategy = partitionKeyStrategyAppID
+	case partitionKeyStrategyKey:
+		m.PartitionKeyStrategy = partitionKeyStrategyKey
+	case partitionKeyStrategyMetadata:
+		m.PartitionKeyStrategy = partitionKeyStrategyMetadata
+	default:
+		return nil, fmt.Errorf("invalid partitionKeyStrategy '%s', must be one of: %s, %s, %s", m.PartitionKeyStrategy,
+			partitionKeyStrategyAppID, partitionKeyStrategyKey, partitionKeyStrategyMetadata)
+	}
+
 	return &m, err
 }
 
@@ -329,6 +493,16 @@ func isEntityAlreadyExistsError(err error) bool {
 	return false
 }
 
+func isETagMismatchError(err error) bool {
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.ErrorCode == string(aztables.UpdateConditionNotSatisfied) ||
+			respErr.ErrorCode == string(aztables.EntityAlreadyExists) ||
+			respErr.StatusCode == http.StatusPreconditionFailed
+	}
+	return false
+}
+
 func isTableAlreadyExistsError(err error) bool {
 	var respErr *azcore.ResponseError
 	if errors.As(err, &respErr) {
@@ -338,7 +512,7 @@ func isTableAlreadyExistsError(err error) bool {
 }
 
 func (r *StateStore) deleteRow(req *state.DeleteRequest) error {
-	pk, rk := getPartitionAndRowKey(req.Key, r.cosmosDBMode)
+	pk, rk := r.partitionAndRowKey(req.Key, req.Metadata)
 
 	deleteContext, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
@@ -353,19 +527,41 @@ func (r *StateStore) deleteRow(req *state.DeleteRequest) error {
 	return err
 }
 
+// partitionAndRowKey returns the partition and row keys of the entity storing a key, according to the partition key
+// strategy of the store.
+func (r *StateStore) partitionAndRowKey(key string, metadata map[string]string) (string, string) {
+	switch r.partitionKeyStrategy {
+	case partitionKeyStrategyKey:
+		return key, emptyRowKey(r.cosmosDBMode)
+	case partitionKeyStrategyMetadata:
+		if pk := metadata[partitionKeyMetadataKey]; pk != "" {
+			return pk, key
+		}
+		// Without partitionKey metadata, fall back to the app ID prefix of the key
+		pk, _ := getPartitionAndRowKey(key, r.cosmosDBMode)
+		return pk, key
+	default:
+		return getPartitionAndRowKey(key, r.cosmosDBMode)
+	}
+}
+
 func getPartitionAndRowKey(key string, cosmosDBmode bool) (string, string) {
 	pr := strings.Split(key, keyDelimiter)
 	if len(pr) != 2 {
-		if cosmosDBmode {
-			return pr[0], "_dapr_empty_row_key_value_"
-		} else {
-			return pr[0], ""
-		}
+		return pr[0], emptyRowKey(cosmosDBmode)
 	}
 
 	return pr[0], pr[1]
 }
 
+// emptyRowKey returns the row key of the entities without one: the Cosmos DB Table API does not allow empty row keys.
+func emptyRowKey(cosmosDBmode bool) string {
+	if cosmosDBmode {
+		return emptyRowKeyCosmosDB
+	}
+	return ""
+}
+
 func (r *StateStore) marshal(req *state.SetRequest) ([]byte, error) {
 	var value string
 	b, ok := req.Value.([]byte)
@@ -375,7 +571,7 @@ func (r *StateStore) marshal(req *state.SetRequest) ([]byte, error) {
 		value, _ = jsoniter.MarshalToString(req.Value)
 	}
 
-	pk, rk := getPartitionAndRowKey(req.Key, r.cosmosDBMode)
+	pk, rk := r.partitionAndRowKey(req.Key, req.Metadata)
 
 	entity := aztables.EDMEntity{
 		Entity: aztables.Entity{
@@ -418,3 +614,9 @@ func (r *StateStore) unmarshal(row *aztables.GetEntityResponse) ([]byte, *string
 	// use native ETag
 	return []byte(sv), &myEntity.ETag, nil
 }
+
+// GetComponentMetadataSchema returns the schema of the metadata of the Table Storage state store.
+func (r *StateStore) GetComponentMetadataSchema() []mdutils.MetadataField {
+	fields, _ := mdutils.GetMetadataSchemaFromStruct(tablesMetadata{})
+	return fields
+}
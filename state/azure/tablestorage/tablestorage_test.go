@@ -16,7 +16,14 @@ package tablestorage
 import (
 	"testing"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
+	"github.com/Azure/go-autorest/autorest/azure"
+	jsoniter "github.com/json-iterator/go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/state"
 )
 
 func TestGetTableStorageMetadata(t *testing.T) {
@@ -53,6 +60,50 @@ func TestGetTableStorageMetadata(t *testing.T) {
 		assert.Equal(t, "acc", meta.AccountName)
 		assert.Equal(t, "key", meta.AccountKey)
 		assert.Equal(t, "dapr", meta.TableName)
+		assert.Equal(t, partitionKeyStrategyAppID, meta.PartitionKeyStrategy)
+	})
+
+	t.Run("SAS token", func(t *testing.T) {
+		m := map[string]string{
+			"accountName": "acc",
+			"sasToken":    "?sv=1&sig=abc",
+			"tableName":   "dapr",
+		}
+		meta, err := getTablesMetadata(m)
+
+		require.NoError(t, err)
+		assert.Equal(t, "", meta.AccountKey)
+		assert.Equal(t, "sv=1&sig=abc", meta.SASToken)
+		assert.Equal(t, "https://acc.table.core.windows.net?sv=1&sig=abc", meta.auth.AddSASToken(meta.auth.ServiceURL("table", &azure.PublicCloud)))
+	})
+
+	t.Run("Connection string takes precedence over the account key", func(t *testing.T) {
+		m := map[string]string{
+			"accountKey":       "key",
+			"connectionString": "DefaultEndpointsProtocol=https;AccountName=acc;AccountKey=otherkey;EndpointSuffix=core.windows.net",
+			"tableName":        "dapr",
+		}
+		meta, err := getTablesMetadata(m)
+
+		require.NoError(t, err)
+		assert.Equal(t, "acc", meta.AccountName)
+		assert.Equal(t, "otherkey", meta.AccountKey)
+	})
+
+	t.Run("Partition key strategy", func(t *testing.T) {
+		m := map[string]string{
+			"accountName":          "acc",
+			"tableName":            "dapr",
+			"partitionKeyStrategy": "Metadata",
+		}
+		meta, err := getTablesMetadata(m)
+
+		require.NoError(t, err)
+		assert.Equal(t, partitionKeyStrategyMetadata, meta.PartitionKeyStrategy)
+
+		m["partitionKeyStrategy"] = "foo"
+		_, err = getTablesMetadata(m)
+		assert.Error(t, err)
 	})
 }
 
@@ -69,3 +120,107 @@ func TestPartitionAndRowKey(t *testing.T) {
 		assert.Equal(t, "", rk)
 	})
 }
+
+func TestPartitionKeyStrategies(t *testing.T) {
+	t.Run("appID", func(t *testing.T) {
+		s := &StateStore{partitionKeyStrategy: partitionKeyStrategyAppID}
+		pk, rk := s.partitionAndRowKey("app||key", map[string]string{partitionKeyMetadataKey: "ignored"})
+		assert.Equal(t, "app", pk)
+		assert.Equal(t, "key", rk)
+	})
+
+	t.Run("key", func(t *testing.T) {
+		s := &StateStore{partitionKeyStrategy: partitionKeyStrategyKey}
+		pk, rk := s.partitionAndRowKey("app||key", nil)
+		assert.Equal(t, "app||key", pk)
+		assert.Equal(t, "", rk)
+
+		s.cosmosDBMode = true
+		_, rk = s.partitionAndRowKey("app||key", nil)
+		assert.Equal(t, emptyRowKeyCosmosDB, rk)
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		s := &StateStore{partitionKeyStrategy: partitionKeyStrategyMetadata}
+		pk, rk := s.partitionAndRowKey("app||key", map[string]string{partitionKeyMetadataKey: "tenant1"})
+		assert.Equal(t, "tenant1", pk)
+		assert.Equal(t, "app||key", rk)
+
+		pk, rk = s.partitionAndRowKey("app||key", nil)
+		assert.Equal(t, "app", pk)
+		assert.Equal(t, "app||key", rk)
+	})
+}
+
+func TestTransactionActions(t *testing.T) {
+	s := &StateStore{partitionKeyStrategy: partitionKeyStrategyAppID}
+	etag := "W/\"etag\""
+
+	t.Run("Operations are mapped to transaction actions", func(t *testing.T) {
+		actions, err := s.transactionActions(&state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{
+				{Operation: state.Upsert, Request: state.SetRequest{Key: "app||k1", Value: "v1"}},
+				{Operation: state.Upsert, Request: state.SetRequest{Key: "app||k2", Value: "v2", ETag: &etag}},
+				{Operation: state.Upsert, Request: state.SetRequest{Key: "app||k3", Value: "v3", Options: state.SetStateOption{Concurrency: state.FirstWrite}}},
+				{Operation: state.Delete, Request: state.DeleteRequest{Key: "app||k4"}},
+				{Operation: state.Delete, Request: state.DeleteRequest{Key: "app||k5", ETag: &etag}},
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, actions, 5)
+
+		assert.Equal(t, aztables.TransactionTypeInsertReplace, actions[0].ActionType)
+		assert.Nil(t, actions[0].IfMatch)
+		assert.Equal(t, aztables.TransactionTypeUpdateReplace, actions[1].ActionType)
+		assert.Equal(t, azcore.ETag(etag), *actions[1].IfMatch)
+		assert.Equal(t, aztables.TransactionTypeAdd, actions[2].ActionType)
+		assert.Equal(t, aztables.TransactionTypeDelete, actions[3].ActionType)
+		assert.Equal(t, azcore.ETagAny, *actions[3].IfMatch)
+		assert.Equal(t, azcore.ETag(etag), *actions[4].IfMatch)
+
+		var entity aztables.EDMEntity
+		require.NoError(t, jsoniter.Unmarshal(actions[0].Entity, &entity))
+		assert.Equal(t, "app", entity.PartitionKey)
+		assert.Equal(t, "k1", entity.RowKey)
+		assert.Equal(t, "\"v1\"", entity.Properties[valueEntityProperty])
+
+		require.NoError(t, jsoniter.Unmarshal(actions[4].Entity, &entity))
+		assert.Equal(t, "k5", entity.RowKey)
+	})
+
+	t.Run("Operations in different partitions", func(t *testing.T) {
+		_, err := s.transactionActions(&state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{
+				{Operation: state.Upsert, Request: state.SetRequest{Key: "app1||k1", Value: "v1"}},
+				{Operation: state.Delete, Request: state.DeleteRequest{Key: "app2||k2"}},
+			},
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("Partition key from the transaction metadata", func(t *testing.T) {
+		s := &StateStore{partitionKeyStrategy: partitionKeyStrategyMetadata}
+		actions, err := s.transactionActions(&state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{
+				{Operation: state.Upsert, Request: state.SetRequest{Key: "app1||k1", Value: "v1"}},
+				{Operation: state.Delete, Request: state.DeleteRequest{Key: "app2||k2"}},
+			},
+			Metadata: map[string]string{partitionKeyMetadataKey: "tenant1"},
+		})
+		require.NoError(t, err)
+
+		var entity aztables.Entity
+		require.NoError(t, jsoniter.Unmarshal(actions[1].Entity, &entity))
+		assert.Equal(t, "tenant1", entity.PartitionKey)
+		assert.Equal(t, "app2||k2", entity.RowKey)
+	})
+
+	t.Run("Too many operations", func(t *testing.T) {
+		operations := make([]state.TransactionalStateOperation, maxTransactionOperations+1)
+		for i := range operations {
+			operations[i] = state.TransactionalStateOperation{Operation: state.Delete, Request: state.DeleteRequest{Key: "app||k"}}
+		}
+		_, err := s.transactionActions(&state.TransactionalStateRequest{Operations: operations})
+		assert.Error(t, err)
+	})
+}
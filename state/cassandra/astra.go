@@ -0,0 +1,239 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cassandra
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+const (
+	// Astra DB authenticates application tokens with this user name.
+	astraTokenUsername = "token"
+	// Placeholder contact point: Astra DB nodes are reached through the SNI proxy, by host ID.
+	astraContactPoint = "0.0.0.1"
+
+	astraDialTimeout = 10 * time.Second
+)
+
+// astraBundle is the content of an Astra DB secure connect bundle.
+type astraBundle struct {
+	// Host and port of the metadata service.
+	host string
+	port int
+	// Keyspace the bundle was downloaded for.
+	keyspace  string
+	tlsConfig *tls.Config
+}
+
+type astraBundleConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Keyspace string `json:"keyspace"`
+}
+
+// astraContactInfo is returned by the metadata service of Astra DB.
+type astraContactInfo struct {
+	SNIProxyAddress string   `json:"sni_proxy_address"`
+	ContactPoints   []string `json:"contact_points"`
+}
+
+// parseAstraBundle reads a secure connect bundle, which is a zip file.
+func parseAstraBundle(data []byte) (*astraBundle, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid secure connect bundle: %w", err)
+	}
+
+	files := make(map[string][]byte, len(r.File))
+	for _, f := range r.File {
+		switch f.Name {
+		case "config.json", "ca.crt", "cert", "key":
+		default:
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("invalid secure connect bundle: %w", err)
+		}
+		files[f.Name], err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid secure connect bundle: %w", err)
+		}
+	}
+	for _, name := range []string{"config.json", "ca.crt", "cert", "key"} {
+		if _, ok := files[name]; !ok {
+			return nil, fmt.Errorf("invalid secure connect bundle: missing %s", name)
+		}
+	}
+
+	var config astraBundleConfig
+	if err = json.Unmarshal(files["config.json"], &config); err != nil {
+		return nil, fmt.Errorf("invalid secure connect bundle config: %w", err)
+	}
+	if config.Host == "" || config.Port == 0 {
+		return nil, errors.New("invalid secure connect bundle config: missing host or port")
+	}
+
+	cert, err := tls.X509KeyPair(files["cert"], files["key"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid secure connect bundle certificate: %w", err)
+	}
+	ca := x509.NewCertPool()
+	if !ca.AppendCertsFromPEM(files["ca.crt"]) {
+		return nil, errors.New("invalid secure connect bundle CA certificate")
+	}
+
+	return &astraBundle{
+		host:     config.Host,
+		port:     config.Port,
+		keyspace: config.Keyspace,
+		tlsConfig: &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{cert},
+			RootCAs:      ca,
+			ServerName:   config.Host,
+		},
+	}, nil
+}
+
+// astraDialer connects to the nodes of Astra DB through its SNI proxy.
+type astraDialer struct {
+	bundle *astraBundle
+	dialer net.Dialer
+
+	contactInfo *astraContactInfo
+	lock        sync.Mutex
+}
+
+func newAstraDialer(bundle *astraBundle) *astraDialer {
+	return &astraDialer{
+		bundle: bundle,
+		dialer: net.Dialer{Timeout: astraDialTimeout},
+	}
+}
+
+// DialHost implements gocql.HostDialer.
+func (d *astraDialer) DialHost(ctx context.Context, host *gocql.HostInfo) (*gocql.DialedHost, error) {
+	contactInfo, err := d.getContactInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The proxy routes to the node whose host ID is the server name; the contact point has no host ID yet
+	hostID := host.HostID()
+	if hostID == "" {
+		hostID = contactInfo.ContactPoints[rand.Intn(len(contactInfo.ContactPoints))] //nolint:gosec
+	}
+
+	conn, err := d.dialer.DialContext(ctx, "tcp", contactInfo.SNIProxyAddress)
+	if err != nil {
+		return nil, err
+	}
+	tlsConn := tls.Client(conn, d.nodeTLSConfig(hostID))
+	if err = tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// TLS connections do not support writev
+	return &gocql.DialedHost{Conn: tlsConn, DisableCoalesce: true}, nil
+}
+
+// nodeTLSConfig returns the TLS configuration to connect to a node.
+// The server name selects the node, so the certificate of the proxy is verified against the host of the bundle instead.
+func (d *astraDialer) nodeTLSConfig(hostID string) *tls.Config {
+	tlsConfig := d.bundle.tlsConfig.Clone()
+	tlsConfig.ServerName = hostID
+	tlsConfig.InsecureSkipVerify = true //nolint:gosec
+	tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("astra: no certificate presented by the proxy")
+		}
+		opts := x509.VerifyOptions{
+			Roots:         d.bundle.tlsConfig.RootCAs,
+			DNSName:       d.bundle.host,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, cert := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		_, err := cs.PeerCertificates[0].Verify(opts)
+
+		return err
+	}
+
+	return tlsConfig
+}
+
+// getContactInfo fetches the address of the SNI proxy and the host IDs of the nodes from the metadata service.
+func (d *astraDialer) getContactInfo(ctx context.Context) (*astraContactInfo, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.contactInfo != nil {
+		return d.contactInfo, nil
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: d.bundle.tlsConfig,
+			DialContext:     d.dialer.DialContext,
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	url := "https://" + net.JoinHostPort(d.bundle.host, strconv.Itoa(d.bundle.port)) + "/metadata"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("astra: error fetching metadata: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("astra: error fetching metadata: status code %d", res.StatusCode)
+	}
+
+	var body struct {
+		ContactInfo astraContactInfo `json:"contact_info"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("astra: invalid metadata: %w", err)
+	}
+	if body.ContactInfo.SNIProxyAddress == "" || len(body.ContactInfo.ContactPoints) == 0 {
+		return nil, errors.New("astra: invalid metadata: missing proxy address or contact points")
+	}
+	d.contactInfo = &body.ContactInfo
+
+	return d.contactInfo, nil
+}
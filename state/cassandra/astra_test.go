@@ -0,0 +1,261 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cassandra
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+)
+
+func TestParseAstraBundle(t *testing.T) {
+	pki := newTestPKI(t)
+
+	t.Run("valid", func(t *testing.T) {
+		bundle, err := parseAstraBundle(pki.bundle(t, "db.example.com", 29080))
+
+		require.NoError(t, err)
+		assert.Equal(t, "db.example.com", bundle.host)
+		assert.Equal(t, 29080, bundle.port)
+		assert.Equal(t, "dapr_astra", bundle.keyspace)
+		assert.Len(t, bundle.tlsConfig.Certificates, 1)
+	})
+
+	t.Run("not a zip file", func(t *testing.T) {
+		_, err := parseAstraBundle([]byte("bundle"))
+
+		assert.Error(t, err)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		w := zip.NewWriter(buf)
+		f, _ := w.Create("config.json")
+		f.Write([]byte(`{"host": "db.example.com", "port": 29080}`))
+		w.Close()
+
+		_, err := parseAstraBundle(buf.Bytes())
+
+		assert.ErrorContains(t, err, "missing ca.crt")
+	})
+}
+
+func TestGetCassandraMetadataWithBundle(t *testing.T) {
+	pki := newTestPKI(t)
+	bundle := base64.StdEncoding.EncodeToString(pki.bundle(t, "db.example.com", 29080))
+
+	t.Run("keyspace of the bundle", func(t *testing.T) {
+		m, err := getCassandraMetadata(state.Metadata{Base: metadata.Base{Properties: map[string]string{
+			secureConnectBundle: bundle,
+			token:               "AstraCS:token",
+		}}})
+
+		require.NoError(t, err)
+		assert.Equal(t, "dapr_astra", m.Keyspace)
+
+		c := &Cassandra{}
+		cluster, err := c.createClusterConfig(m)
+		require.NoError(t, err)
+		assert.IsType(t, &astraDialer{}, cluster.HostDialer)
+		assert.Equal(t, gocql.PasswordAuthenticator{Username: astraTokenUsername, Password: "AstraCS:token"}, cluster.Authenticator)
+	})
+
+	t.Run("keyspace overridden", func(t *testing.T) {
+		m, err := getCassandraMetadata(state.Metadata{Base: metadata.Base{Properties: map[string]string{
+			secureConnectBundle: bundle,
+			keyspace:            "other",
+		}}})
+
+		require.NoError(t, err)
+		assert.Equal(t, "other", m.Keyspace)
+	})
+
+	t.Run("invalid bundle", func(t *testing.T) {
+		_, err := getCassandraMetadata(state.Metadata{Base: metadata.Base{Properties: map[string]string{
+			secureConnectBundle: "not base64",
+		}}})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestAstraDialer(t *testing.T) {
+	pki := newTestPKI(t)
+	serverCert := pki.serverCertificate(t)
+
+	// SNI proxy, which reports the server name requested by the client
+	proxy, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{serverCert},
+	})
+	require.NoError(t, err)
+	defer proxy.Close()
+	go func() {
+		for {
+			conn, err := proxy.Accept()
+			if err != nil {
+				return
+			}
+			tlsConn := conn.(*tls.Conn)
+			if tlsConn.Handshake() == nil {
+				tlsConn.Write([]byte(tlsConn.ConnectionState().ServerName))
+			}
+			tlsConn.Close()
+		}
+	}()
+
+	// Metadata service
+	metadataService := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/metadata", r.URL.Path)
+		fmt.Fprintf(w, `{"contact_info": {"sni_proxy_address": %q, "contact_points": ["host-1"]}}`, proxy.Addr().String())
+	}))
+	metadataService.TLS = &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{serverCert},
+	}
+	metadataService.StartTLS()
+	defer metadataService.Close()
+
+	_, port, _ := net.SplitHostPort(metadataService.Listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	bundle, err := parseAstraBundle(pki.bundle(t, "127.0.0.1", p))
+	require.NoError(t, err)
+	dialer := newAstraDialer(bundle)
+
+	// act
+	dialed, err := dialer.DialHost(context.Background(), &gocql.HostInfo{})
+
+	// assert
+	require.NoError(t, err)
+	defer dialed.Conn.Close()
+	assert.True(t, dialed.DisableCoalesce)
+	serverName := make([]byte, len("host-1"))
+	_, err = dialed.Conn.Read(serverName)
+	require.NoError(t, err)
+	assert.Equal(t, "host-1", string(serverName))
+}
+
+type testPKI struct {
+	caCert *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+	caPEM  []byte
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testPKI{
+		caCert: cert,
+		caKey:  key,
+		caPEM:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+func (p *testPKI) issue(t *testing.T, template *x509.Certificate) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Minute)
+	template.NotAfter = time.Now().Add(time.Hour)
+	der, err := x509.CreateCertificate(rand.Reader, template, p.caCert, &key.PublicKey, p.caKey)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func (p *testPKI) serverCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	certPEM, keyPEM := p.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "server"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	return cert
+}
+
+// bundle returns a secure connect bundle for the metadata service at host:port.
+func (p *testPKI) bundle(t *testing.T, host string, port int) []byte {
+	t.Helper()
+
+	certPEM, keyPEM := p.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "client"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	files := map[string][]byte{
+		"config.json": []byte(fmt.Sprintf(`{"host": %q, "port": %d, "keyspace": "dapr_astra"}`, host, port)),
+		"ca.crt":      p.caPEM,
+		"cert":        certPEM,
+		"key":         keyPEM,
+	}
+
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+	for name, data := range files {
+		f, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = f.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
@@ -14,6 +14,7 @@ limitations under the License.
 package cassandra
 
 import (
+	"encoding/base64"
 	"fmt"
 	"reflect"
 	"strconv"
@@ -36,6 +37,8 @@ const (
 	table                    = "table"
 	keyspace                 = "keyspace"
 	replicationFactor        = "replicationFactor"
+	secureConnectBundle      = "secureConnectBundle"
+	token                    = "token"
 	defaultProtoVersion      = 4
 	defaultReplicationFactor = 1
 	defaultConsistency       = gocql.All
@@ -65,6 +68,12 @@ type cassandraMetadata struct {
 	Consistency       string
 	Table             string
 	Keyspace          string
+	// Base64-encoded secure connect bundle of Astra DB
+	SecureConnectBundle string
+	// Application token of Astra DB
+	Token string
+
+	astraBundle *astraBundle
 }
 
 // NewCassandraStateStore returns a new cassandra state store.
@@ -94,9 +103,12 @@ func (c *Cassandra) Init(metadata state.Metadata) error {
 	}
 	c.session = session
 
-	err = c.tryCreateKeyspace(meta.Keyspace, meta.ReplicationFactor)
-	if err != nil {
-		return fmt.Errorf("error creating keyspace %s: %s", meta.Keyspace, err)
+	// Keyspaces of Astra DB cannot be created with CQL
+	if meta.astraBundle == nil {
+		err = c.tryCreateKeyspace(meta.Keyspace, meta.ReplicationFactor)
+		if err != nil {
+			return fmt.Errorf("error creating keyspace %s: %s", meta.Keyspace, err)
+		}
 	}
 
 	err = c.tryCreateTable(meta.Table, meta.Keyspace)
@@ -123,11 +135,19 @@ func (c *Cassandra) tryCreateTable(table, keyspace string) error {
 }
 
 func (c *Cassandra) createClusterConfig(metadata *cassandraMetadata) (*gocql.ClusterConfig, error) {
-	clusterConfig := gocql.NewCluster(metadata.Hosts...)
-	if metadata.Username != "" && metadata.Password != "" {
+	var clusterConfig *gocql.ClusterConfig
+	if metadata.astraBundle != nil {
+		clusterConfig = gocql.NewCluster(astraContactPoint)
+		clusterConfig.HostDialer = newAstraDialer(metadata.astraBundle)
+	} else {
+		clusterConfig = gocql.NewCluster(metadata.Hosts...)
+		clusterConfig.Port = metadata.Port
+	}
+	if metadata.Token != "" {
+		clusterConfig.Authenticator = gocql.PasswordAuthenticator{Username: astraTokenUsername, Password: metadata.Token}
+	} else if metadata.Username != "" && metadata.Password != "" {
 		clusterConfig.Authenticator = gocql.PasswordAuthenticator{Username: metadata.Username, Password: metadata.Password}
 	}
-	clusterConfig.Port = metadata.Port
 	clusterConfig.ProtoVersion = metadata.ProtoVersion
 	cons, err := c.getConsistency(metadata.Consistency)
 	if err != nil {
@@ -180,7 +200,20 @@ func getCassandraMetadata(meta state.Metadata) (*cassandraMetadata, error) {
 		return nil, err
 	}
 
-	if m.Hosts == nil || len(m.Hosts) == 0 {
+	if m.SecureConnectBundle != "" {
+		bundle, err := base64.StdEncoding.DecodeString(m.SecureConnectBundle)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding secureConnectBundle field: %s", err)
+		}
+		m.astraBundle, err = parseAstraBundle(bundle)
+		if err != nil {
+			return nil, err
+		}
+		// The bundle is downloaded for a keyspace, which is used unless another one is set
+		if _, ok := meta.Properties[keyspace]; !ok && m.astraBundle.keyspace != "" {
+			m.Keyspace = m.astraBundle.keyspace
+		}
+	} else if m.Hosts == nil || len(m.Hosts) == 0 {
 		return nil, fmt.Errorf("missing or empty hosts field from metadata")
 	}
 
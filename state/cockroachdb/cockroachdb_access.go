@@ -204,10 +204,20 @@ func (p *cockroachDBAccess) Get(req *state.GetRequest) (*state.GetResponse, erro
 		return nil, fmt.Errorf("missing key in get operation")
 	}
 
+	// Reading historical values relies on CockroachDB's time-travel queries, which are limited by the garbage collection window of the table
+	asOfClause := ""
+	asOf, ok, err := metadata.TryGetAsOf(req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		asOfClause = fmt.Sprintf(" AS OF SYSTEM TIME %d", asOf.UnixNano())
+	}
+
 	var value string
 	var isBinary bool
 	var etag int
-	err := p.db.QueryRow(fmt.Sprintf("SELECT value, isbinary, etag FROM %s WHERE key = $1", tableName), req.Key).Scan(&value, &isBinary, &etag)
+	err = p.db.QueryRow(fmt.Sprintf("SELECT value, isbinary, etag FROM %s%s WHERE key = $1", tableName, asOfClause), req.Key).Scan(&value, &isBinary, &etag)
 	if err != nil {
 		// If no rows exist, return an empty response, otherwise return the error.
 		if errors.Is(err, sql.ErrNoRows) {
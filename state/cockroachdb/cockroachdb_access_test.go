@@ -15,6 +15,7 @@ package cockroachdb
 
 import (
 	"database/sql"
+	"regexp"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -428,6 +429,44 @@ func TestValidBulkDelete(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestGetAsOf(t *testing.T) {
+	t.Run("reads the value at the given time", func(t *testing.T) {
+		// Arrange
+		m, _ := mockDatabase(t)
+		defer m.db.Close()
+
+		m.mock.ExpectQuery(regexp.QuoteMeta("SELECT value, isbinary, etag FROM state AS OF SYSTEM TIME 1667383445000000000 WHERE key = $1")).
+			WithArgs("key1").
+			WillReturnRows(sqlmock.NewRows([]string{"value", "isbinary", "etag"}).AddRow(`"old"`, false, 1))
+
+		// Act
+		res, err := m.roachDba.Get(&state.GetRequest{
+			Key:      "key1",
+			Metadata: map[string]string{"asOf": "2022-11-02T10:04:05Z"},
+		})
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, []byte(`"old"`), res.Data)
+		assert.Nil(t, m.mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid timestamp", func(t *testing.T) {
+		// Arrange
+		m, _ := mockDatabase(t)
+		defer m.db.Close()
+
+		// Act
+		_, err := m.roachDba.Get(&state.GetRequest{
+			Key:      "key1",
+			Metadata: map[string]string{"asOf": "yesterday"},
+		})
+
+		// Assert
+		assert.NotNil(t, err)
+	})
+}
+
 func createSetRequest() state.SetRequest {
 	return state.SetRequest{
 		Key:   randomKey(),
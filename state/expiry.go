@@ -0,0 +1,125 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/dapr/components-contrib/contenttype"
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/ptr"
+)
+
+const (
+	// ExpiryEventsPubsubKey is the metadata key of the name of the pub/sub component where the key expired events are published.
+	ExpiryEventsPubsubKey = "expiryEventsPubsubName"
+	// ExpiryEventsTopicKey is the metadata key of the topic where the key expired events are published.
+	ExpiryEventsTopicKey = "expiryEventsTopic"
+)
+
+// KeyExpiredEvent is the event published when a store removes an expired key.
+type KeyExpiredEvent struct {
+	Key       string    `json:"key"`
+	ExpiredAt time.Time `json:"expiredAt"`
+}
+
+// PublishFn publishes a message on a pub/sub component.
+type PublishFn func(req *pubsub.PublishRequest) error
+
+// ExpiryEventsNotifier is implemented by the stores which manage the TTL of the keys, and can publish an event when they
+// remove the expired keys: the in-memory and Oracle Database stores. The stores whose database expires the keys, such
+// as Redis or DynamoDB, or which don't support TTLs, such as PostgreSQL, MySQL or MongoDB, can't publish the events.
+// The runtime sets the function publishing the events on the pub/sub component configured.
+type ExpiryEventsNotifier interface {
+	SetExpiryEventsPublishFn(publish PublishFn)
+}
+
+// ExpiryEventsPublisher publishes the key expired events of a store, to the topic of the "expiryEventsTopic" metadata
+// property on the pub/sub component of the "expiryEventsPubsubName" metadata property.
+// Publishing is best effort: the events which cannot be published are only logged.
+type ExpiryEventsPublisher struct {
+	pubsubName string
+	topic      string
+	logger     logger.Logger
+
+	lock    sync.RWMutex
+	publish PublishFn
+}
+
+// NewExpiryEventsPublisher returns a new ExpiryEventsPublisher, which is configured by Init.
+func NewExpiryEventsPublisher(logger logger.Logger) *ExpiryEventsPublisher {
+	return &ExpiryEventsPublisher{logger: logger}
+}
+
+// Init reads the pub/sub component and the topic of the events from the metadata of the store.
+func (p *ExpiryEventsPublisher) Init(metadata map[string]string) {
+	p.lock.Lock()
+	p.pubsubName = metadata[ExpiryEventsPubsubKey]
+	p.topic = metadata[ExpiryEventsTopicKey]
+	p.lock.Unlock()
+}
+
+// SetExpiryEventsPublishFn sets the function publishing the events.
+func (p *ExpiryEventsPublisher) SetExpiryEventsPublishFn(publish PublishFn) {
+	p.lock.Lock()
+	p.publish = publish
+	p.lock.Unlock()
+}
+
+// Enabled returns true if the events are configured to be published.
+func (p *ExpiryEventsPublisher) Enabled() bool {
+	if p == nil {
+		return false
+	}
+
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.pubsubName != "" && p.topic != "" && p.publish != nil
+}
+
+// Publish publishes the events of the keys expired at the given time, if enabled.
+func (p *ExpiryEventsPublisher) Publish(keys []string, expiredAt time.Time) {
+	if !p.Enabled() {
+		return
+	}
+
+	p.lock.RLock()
+	pubsubName, topic, publish := p.pubsubName, p.topic, p.publish
+	p.lock.RUnlock()
+
+	for _, key := range keys {
+		data, err := json.Marshal(KeyExpiredEvent{
+			Key:       key,
+			ExpiredAt: expiredAt,
+		})
+		if err != nil {
+			p.logger.Warnf("Failed to encode the expired event of key %s: %v", key, err)
+			continue
+		}
+
+		err = publish(&pubsub.PublishRequest{
+			Data:        data,
+			PubsubName:  pubsubName,
+			Topic:       topic,
+			ContentType: ptr.Of(contenttype.JSONContentType),
+		})
+		if err != nil {
+			p.logger.Warnf("Failed to publish the expired event of key %s on topic %s: %v", key, topic, err)
+		}
+	}
+}
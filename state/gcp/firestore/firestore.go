@@ -15,15 +15,14 @@ package firestore
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
 
 	"cloud.google.com/go/datastore"
 	jsoniter "github.com/json-iterator/go"
-	"google.golang.org/api/option"
 
+	gcpauth "github.com/dapr/components-contrib/internal/authentication/gcp"
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/kit/logger"
@@ -71,14 +70,24 @@ func (f *Firestore) Init(metadata state.Metadata) error {
 	if err != nil {
 		return err
 	}
-	b, err := json.Marshal(meta)
+	opts, err := gcpauth.ClientOptions(gcpauth.ServiceAccountKey{
+		Type:                meta.Type,
+		ProjectID:           meta.ProjectID,
+		PrivateKeyID:        meta.PrivateKeyID,
+		PrivateKey:          meta.PrivateKey,
+		ClientEmail:         meta.ClientEmail,
+		ClientID:            meta.ClientID,
+		AuthURI:             meta.AuthURI,
+		TokenURI:            meta.TokenURI,
+		AuthProviderCertURL: meta.AuthProviderCertURL,
+		ClientCertURL:       meta.ClientCertURL,
+	})
 	if err != nil {
 		return err
 	}
 
-	opt := option.WithCredentialsJSON(b)
 	ctx := context.Background()
-	client, err := datastore.NewClient(ctx, meta.ProjectID, opt)
+	client, err := datastore.NewClient(ctx, meta.ProjectID, opts...)
 	if err != nil {
 		return err
 	}
@@ -166,25 +175,9 @@ func getFirestoreMetadata(meta state.Metadata) (*firestoreMetadata, error) {
 		return nil, err
 	}
 
-	requiredMetaProperties := []string{
-		"type", "project_id", "private_key_id", "private_key", "client_email", "client_id",
-		"auth_uri", "token_uri", "auth_provider_x509_cert_url", "client_x509_cert_url",
-	}
-
-	metadataMap := map[string]string{}
-	bytes, err := json.Marshal(m)
-	if err != nil {
-		return nil, err
-	}
-	err = json.Unmarshal(bytes, &metadataMap)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, k := range requiredMetaProperties {
-		if val, ok := metadataMap[k]; !ok || len(val) < 1 {
-			return nil, fmt.Errorf("error parsing required field: %s", k)
-		}
+	// The key of the service account is optional, the Application Default Credentials are used without it
+	if m.ProjectID == "" {
+		return nil, fmt.Errorf("error parsing required field: %s", "project_id")
 	}
 
 	return &m, nil
@@ -48,10 +48,22 @@ func TestGetFirestoreMetadata(t *testing.T) {
 		assert.Equal(t, defaultEntityKind, metadata.EntityKind)
 	})
 
+	t.Run("Without service account key", func(t *testing.T) {
+		properties := map[string]string{
+			"project_id": "myprojectid",
+		}
+		m := state.Metadata{
+			Base: metadata.Base{Properties: properties},
+		}
+		metadata, err := getFirestoreMetadata(m)
+		assert.Nil(t, err)
+		assert.Equal(t, "myprojectid", metadata.ProjectID)
+		assert.Empty(t, metadata.PrivateKey)
+	})
+
 	t.Run("With incorrect properties", func(t *testing.T) {
 		properties := map[string]string{
 			"type":           "service_account",
-			"project_id":     "myprojectid",
 			"private_key_id": "123",
 			"private_key":    "mykey",
 		}
@@ -0,0 +1,425 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package firestorenative
+
+import (
+	"context"
+	b64 "encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	firestore "google.golang.org/api/firestore/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+
+	gcpauth "github.com/dapr/components-contrib/internal/authentication/gcp"
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	defaultDatabaseID = "(default)"
+	defaultCollection = "DaprState"
+	datastoreScope    = "https://www.googleapis.com/auth/datastore"
+	requestTimeout    = 30 * time.Second
+	// Maximum number of writes of a commit.
+	maxWrites = 500
+	// Maximum number of attempts of a transaction aborted by the contention with other transactions.
+	maxTransactionAttempts = 5
+
+	valueField = "value"
+)
+
+// FirestoreNative is a state store using a database of Firestore in native mode.
+// The keys are the IDs of the documents of the collection, and the ETags are the update times of the documents.
+type FirestoreNative struct {
+	state.DefaultBulkStore
+	documents *firestore.ProjectsDatabasesDocumentsService
+	// Resource name of the database, projects/<project>/databases/<database>.
+	database   string
+	collection string
+
+	logger logger.Logger
+}
+
+type firestoreNativeMetadata struct {
+	Type                string `json:"type" mapstructure:"type"`
+	ProjectID           string `json:"project_id" mapstructure:"project_id"`
+	PrivateKeyID        string `json:"private_key_id" mapstructure:"private_key_id"`
+	PrivateKey          string `json:"private_key" mapstructure:"private_key"`
+	ClientEmail         string `json:"client_email" mapstructure:"client_email"`
+	ClientID            string `json:"client_id" mapstructure:"client_id"`
+	AuthURI             string `json:"auth_uri" mapstructure:"auth_uri"`
+	TokenURI            string `json:"token_uri" mapstructure:"token_uri"`
+	AuthProviderCertURL string `json:"auth_provider_x509_cert_url" mapstructure:"auth_provider_x509_cert_url"`
+	ClientCertURL       string `json:"client_x509_cert_url" mapstructure:"client_x509_cert_url"`
+	DatabaseID          string `json:"database_id" mapstructure:"database_id"`
+	Collection          string `json:"collection" mapstructure:"collection"`
+	// Host and port of the Firestore emulator, used without authentication.
+	EmulatorHost string `json:"emulator_host" mapstructure:"emulator_host"`
+}
+
+// apiError is the body of the errors returned by the API, with their canonical status.
+type apiError struct {
+	Error struct {
+		Status string `json:"status"`
+	} `json:"error"`
+}
+
+// NewFirestoreNativeStateStore returns a new state store for Firestore in native mode.
+func NewFirestoreNativeStateStore(logger logger.Logger) state.Store {
+	s := &FirestoreNative{logger: logger}
+	s.DefaultBulkStore = state.NewDefaultBulkStore(s)
+
+	return s
+}
+
+// Init parses the metadata and creates the authenticated HTTP client.
+func (f *FirestoreNative) Init(metadata state.Metadata) error {
+	meta, err := getFirestoreNativeMetadata(metadata)
+	if err != nil {
+		return err
+	}
+
+	var opts []option.ClientOption
+	if meta.EmulatorHost != "" {
+		opts = []option.ClientOption{
+			option.WithEndpoint("http://" + meta.EmulatorHost + "/"),
+			option.WithoutAuthentication(),
+		}
+	} else {
+		opts, err = gcpauth.ClientOptions(gcpauth.ServiceAccountKey{
+			Type:                meta.Type,
+			ProjectID:           meta.ProjectID,
+			PrivateKeyID:        meta.PrivateKeyID,
+			PrivateKey:          meta.PrivateKey,
+			ClientEmail:         meta.ClientEmail,
+			ClientID:            meta.ClientID,
+			AuthURI:             meta.AuthURI,
+			TokenURI:            meta.TokenURI,
+			AuthProviderCertURL: meta.AuthProviderCertURL,
+			ClientCertURL:       meta.ClientCertURL,
+		})
+		if err != nil {
+			return err
+		}
+		opts = append(opts, option.WithScopes(datastoreScope))
+	}
+
+	service, err := firestore.NewService(context.Background(), opts...)
+	if err != nil {
+		return fmt.Errorf("error creating the Firestore client: %w", err)
+	}
+	f.documents = service.Projects.Databases.Documents
+	f.database = fmt.Sprintf("projects/%s/databases/%s", meta.ProjectID, meta.DatabaseID)
+	f.collection = meta.Collection
+
+	return nil
+}
+
+func getFirestoreNativeMetadata(meta state.Metadata) (*firestoreNativeMetadata, error) {
+	m := firestoreNativeMetadata{
+		DatabaseID: defaultDatabaseID,
+		Collection: defaultCollection,
+	}
+
+	err := metadata.DecodeMetadata(meta.Properties, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	// The key of the service account is optional, the Application Default Credentials are used without it
+	if m.ProjectID == "" {
+		return nil, errors.New("error parsing required field: project_id")
+	}
+	if m.Collection == "" || strings.Contains(m.Collection, "/") {
+		return nil, fmt.Errorf("invalid collection %s", m.Collection)
+	}
+
+	return &m, nil
+}
+
+// Features returns the features available in this state store.
+func (f *FirestoreNative) Features() []state.Feature {
+	return []state.Feature{state.FeatureETag, state.FeatureTransactional}
+}
+
+// Get retrieves the state of the key, with its update time as ETag. The reads of Firestore are strongly consistent.
+func (f *FirestoreNative) Get(req *state.GetRequest) (*state.GetResponse, error) {
+	name, err := f.documentName(req.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	doc, err := f.documents.Get(name).Context(ctx).Do()
+	if err != nil {
+		if isHTTPError(err, http.StatusNotFound) {
+			return &state.GetResponse{}, nil
+		}
+		return nil, err
+	}
+
+	data, err := documentValue(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document %s: %w", req.Key, err)
+	}
+
+	return &state.GetResponse{
+		Data: data,
+		ETag: &doc.UpdateTime,
+	}, nil
+}
+
+// Set saves the state of the key, if the document wasn't updated since the ETag if any.
+func (f *FirestoreNative) Set(req *state.SetRequest) error {
+	w, err := f.setWrite(req)
+	if err != nil {
+		return err
+	}
+
+	return f.commit([]*firestore.Write{w})
+}
+
+// Delete deletes the state of the key, if the document wasn't updated since the ETag if any.
+func (f *FirestoreNative) Delete(req *state.DeleteRequest) error {
+	w, err := f.deleteWrite(req)
+	if err != nil {
+		return err
+	}
+
+	return f.commit([]*firestore.Write{w})
+}
+
+// Multi commits the operations in a transaction: none is applied if the precondition of the ETag of one fails.
+// The transactions aborted by the contention with other transactions are retried.
+func (f *FirestoreNative) Multi(request *state.TransactionalStateRequest) error {
+	if len(request.Operations) > maxWrites {
+		return fmt.Errorf("transactions are limited to %d operations", maxWrites)
+	}
+
+	writes := make([]*firestore.Write, 0, len(request.Operations))
+	for _, o := range request.Operations {
+		var (
+			w   *firestore.Write
+			err error
+		)
+		switch req := o.Request.(type) {
+		case state.SetRequest:
+			w, err = f.setWrite(&req)
+		case state.DeleteRequest:
+			w, err = f.deleteWrite(&req)
+		default:
+			err = fmt.Errorf("unsupported operation %s", o.Operation)
+		}
+		if err != nil {
+			return err
+		}
+		writes = append(writes, w)
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+
+	return f.runTransaction(writes)
+}
+
+func (f *FirestoreNative) setWrite(req *state.SetRequest) (*firestore.Write, error) {
+	err := state.CheckRequestOptions(req.Options)
+	if err != nil {
+		return nil, err
+	}
+	name, err := f.documentName(req.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	b, ok := req.Value.([]byte)
+	if !ok {
+		b, err = jsoniter.Marshal(req.Value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	w := &firestore.Write{
+		Update: &firestore.Document{
+			Name: name,
+			Fields: map[string]firestore.Value{valueField: {
+				BytesValue: b64.StdEncoding.EncodeToString(b),
+				// Empty values are sent too
+				ForceSendFields: []string{"BytesValue"},
+			}},
+		},
+	}
+	w.CurrentDocument, err = etagPrecondition(req.ETag)
+
+	return w, err
+}
+
+func (f *FirestoreNative) deleteWrite(req *state.DeleteRequest) (*firestore.Write, error) {
+	err := state.CheckRequestOptions(req.Options)
+	if err != nil {
+		return nil, err
+	}
+	name, err := f.documentName(req.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &firestore.Write{Delete: name}
+	w.CurrentDocument, err = etagPrecondition(req.ETag)
+
+	return w, err
+}
+
+// etagPrecondition returns the precondition of the writes with the ETag, an update time.
+func etagPrecondition(etag *string) (*firestore.Precondition, error) {
+	if etag == nil || *etag == "" {
+		return nil, nil
+	}
+	if _, err := time.Parse(time.RFC3339Nano, *etag); err != nil {
+		return nil, state.NewETagError(state.ETagInvalid, err)
+	}
+
+	return &firestore.Precondition{UpdateTime: *etag}, nil
+}
+
+// commit applies the writes atomically.
+func (f *FirestoreNative) commit(writes []*firestore.Write) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	_, err := f.documents.Commit(f.database, &firestore.CommitRequest{Writes: writes}).Context(ctx).Do()
+
+	return commitError(err, writes)
+}
+
+// runTransaction applies the writes in a transaction, retried while aborted by the contention with other transactions.
+// The transaction is rolled back if it can't be committed.
+func (f *FirestoreNative) runTransaction(writes []*firestore.Write) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	for attempt := 1; ; attempt++ {
+		tx, err := f.documents.BeginTransaction(f.database, &firestore.BeginTransactionRequest{
+			Options: &firestore.TransactionOptions{ReadWrite: &firestore.ReadWrite{}},
+		}).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("error beginning the transaction: %w", err)
+		}
+
+		_, err = f.documents.Commit(f.database, &firestore.CommitRequest{
+			Writes:      writes,
+			Transaction: tx.Transaction,
+		}).Context(ctx).Do()
+		if err == nil {
+			return nil
+		}
+
+		_, rollbackErr := f.documents.Rollback(f.database, &firestore.RollbackRequest{Transaction: tx.Transaction}).Context(ctx).Do()
+		if rollbackErr != nil {
+			f.logger.Debugf("error rolling back the transaction: %v", rollbackErr)
+		}
+		if apiStatus(err) != "ABORTED" || attempt == maxTransactionAttempts {
+			return commitError(err, writes)
+		}
+		f.logger.Debugf("transaction aborted, retrying: %v", err)
+	}
+}
+
+// commitError returns the error of the commit of the writes, an ETag error if the precondition of one failed.
+func commitError(err error, writes []*firestore.Write) error {
+	if err == nil {
+		return nil
+	}
+
+	// A document updated since its ETag fails the precondition, a document deleted is not found
+	if status := apiStatus(err); status == "FAILED_PRECONDITION" || status == "NOT_FOUND" {
+		for _, w := range writes {
+			if w.CurrentDocument != nil {
+				return state.NewETagError(state.ETagMismatch, err)
+			}
+		}
+	}
+
+	return err
+}
+
+// apiStatus returns the canonical status of an error of the API, such as NOT_FOUND.
+func apiStatus(err error) string {
+	var gErr *googleapi.Error
+	if !errors.As(err, &gErr) {
+		return ""
+	}
+	var apiErr apiError
+	if json.Unmarshal([]byte(gErr.Body), &apiErr) != nil {
+		return ""
+	}
+
+	return apiErr.Error.Status
+}
+
+// isHTTPError returns true if the error is a response of the API with the status code.
+func isHTTPError(err error, code int) bool {
+	var gErr *googleapi.Error
+
+	return errors.As(err, &gErr) && gErr.Code == code
+}
+
+// documentName returns the resource name of the document of the key. The keys are encoded in valid document IDs.
+func (f *FirestoreNative) documentName(key string) (string, error) {
+	if key == "" {
+		return "", errors.New("the key is required")
+	}
+
+	return f.database + "/documents/" + f.collection + "/" + documentID(key), nil
+}
+
+// documentID percent-encodes the characters of the key which are not valid in a document ID: slashes, and the dots and
+// underscores of the reserved IDs, ".", ".." and "__.*__". The percent signs are encoded too, so the IDs are unique.
+func documentID(key string) string {
+	id := strings.NewReplacer("%", "%25", "/", "%2F").Replace(key)
+	switch {
+	case id == "." || id == "..":
+		id = strings.ReplaceAll(id, ".", "%2E")
+	case len(id) >= 4 && strings.HasPrefix(id, "__") && strings.HasSuffix(id, "__"):
+		id = "%5F" + id[1:]
+	}
+
+	return id
+}
+
+func documentValue(doc *firestore.Document) ([]byte, error) {
+	v, ok := doc.Fields[valueField]
+	if !ok {
+		return nil, errors.New("no value field")
+	}
+
+	return b64.StdEncoding.DecodeString(v.BytesValue)
+}
+
+func (f *FirestoreNative) GetComponentMetadata() map[string]string {
+	metadataStruct := firestoreNativeMetadata{}
+	metadataInfo := map[string]string{}
+	metadata.GetMetadataInfoFromStructType(reflect.TypeOf(metadataStruct), &metadataInfo)
+	return metadataInfo
+}
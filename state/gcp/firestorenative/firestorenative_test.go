@@ -0,0 +1,305 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package firestorenative
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	firestore "google.golang.org/api/firestore/v1"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/ptr"
+)
+
+const testDatabase = "projects/myproject/databases/(default)"
+
+// fakeFirestore is an in-memory Firestore serving the reads, the commits and the transactions of documents.
+type fakeFirestore struct {
+	lock sync.Mutex
+	docs map[string]firestore.Document
+	now  time.Time
+	// Number of the next transactions aborted by a concurrent transaction.
+	aborts       int
+	transactions []string
+	rollbacks    []string
+}
+
+func (f *fakeFirestore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	name := strings.TrimPrefix(r.URL.Path, "/v1/")
+	switch {
+	case r.Method == http.MethodGet:
+		doc, ok := f.docs[name]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NOT_FOUND")
+			return
+		}
+		json.NewEncoder(w).Encode(doc)
+	case r.Method == http.MethodPost && name == testDatabase+"/documents:beginTransaction":
+		tx := base64.StdEncoding.EncodeToString([]byte(strconv.Itoa(len(f.transactions))))
+		f.transactions = append(f.transactions, tx)
+		json.NewEncoder(w).Encode(firestore.BeginTransactionResponse{Transaction: tx})
+	case r.Method == http.MethodPost && name == testDatabase+"/documents:rollback":
+		var req firestore.RollbackRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		f.rollbacks = append(f.rollbacks, req.Transaction)
+		w.Write([]byte(`{}`))
+	case r.Method == http.MethodPost && name == testDatabase+"/documents:commit":
+		var req firestore.CommitRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_ARGUMENT")
+			return
+		}
+		if req.Transaction != "" && f.aborts > 0 {
+			f.aborts--
+			writeError(w, http.StatusConflict, "ABORTED")
+			return
+		}
+		for _, wr := range req.Writes {
+			if wr.CurrentDocument == nil {
+				continue
+			}
+			target := wr.Delete
+			if wr.Update != nil {
+				target = wr.Update.Name
+			}
+			doc, ok := f.docs[target]
+			if !ok {
+				writeError(w, http.StatusNotFound, "NOT_FOUND")
+				return
+			}
+			if doc.UpdateTime != wr.CurrentDocument.UpdateTime {
+				writeError(w, http.StatusBadRequest, "FAILED_PRECONDITION")
+				return
+			}
+		}
+		f.now = f.now.Add(time.Millisecond)
+		for _, wr := range req.Writes {
+			if wr.Update != nil {
+				doc := *wr.Update
+				doc.UpdateTime = f.now.Format(time.RFC3339Nano)
+				f.docs[doc.Name] = doc
+			} else {
+				delete(f.docs, wr.Delete)
+			}
+		}
+		w.Write([]byte(`{}`))
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND")
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, status string) {
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{"code": code, "message": "failed", "status": status},
+	})
+}
+
+func newStore(t *testing.T) (*FirestoreNative, *fakeFirestore) {
+	t.Helper()
+
+	fake := &fakeFirestore{
+		docs: map[string]firestore.Document{},
+		now:  time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC),
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	s := NewFirestoreNativeStateStore(logger.NewLogger("test")).(*FirestoreNative)
+	require.NoError(t, s.Init(state.Metadata{Base: metadata.Base{Properties: map[string]string{
+		"project_id":    "myproject",
+		"emulator_host": strings.TrimPrefix(server.URL, "http://"),
+	}}}))
+
+	return s, fake
+}
+
+func TestGetFirestoreNativeMetadata(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		m, err := getFirestoreNativeMetadata(state.Metadata{Base: metadata.Base{Properties: map[string]string{
+			"project_id": "myproject",
+		}}})
+
+		require.NoError(t, err)
+		assert.Equal(t, "(default)", m.DatabaseID)
+		assert.Equal(t, "DaprState", m.Collection)
+	})
+
+	t.Run("invalid metadata", func(t *testing.T) {
+		tests := map[string]struct {
+			props map[string]string
+			err   string
+		}{
+			"missing project_id": {
+				props: map[string]string{"collection": "state"},
+				err:   "error parsing required field: project_id",
+			},
+			"nested collection": {
+				props: map[string]string{"project_id": "myproject", "collection": "a/b/c"},
+				err:   "invalid collection a/b/c",
+			},
+		}
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				_, err := getFirestoreNativeMetadata(state.Metadata{Base: metadata.Base{Properties: tt.props}})
+				assert.EqualError(t, err, tt.err)
+			})
+		}
+	})
+}
+
+func TestCRUD(t *testing.T) {
+	s, fake := newStore(t)
+
+	t.Run("missing key", func(t *testing.T) {
+		res, err := s.Get(&state.GetRequest{Key: "missing"})
+
+		require.NoError(t, err)
+		assert.Nil(t, res.Data)
+		assert.Nil(t, res.ETag)
+	})
+
+	t.Run("set and get", func(t *testing.T) {
+		require.NoError(t, s.Set(&state.SetRequest{Key: "order", Value: map[string]string{"id": "1"}}))
+		require.Contains(t, fake.docs, testDatabase+"/documents/DaprState/order")
+
+		res, err := s.Get(&state.GetRequest{Key: "order"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id": "1"}`, string(res.Data))
+		require.NotNil(t, res.ETag)
+
+		require.NoError(t, s.Set(&state.SetRequest{Key: "order", Value: []byte("raw"), ETag: res.ETag}))
+		res2, err := s.Get(&state.GetRequest{Key: "order"})
+		require.NoError(t, err)
+		assert.Equal(t, "raw", string(res2.Data))
+		assert.NotEqual(t, *res.ETag, *res2.ETag)
+
+		err = s.Set(&state.SetRequest{Key: "order", Value: []byte("stale"), ETag: res.ETag})
+		var etagErr *state.ETagError
+		require.True(t, errors.As(err, &etagErr))
+		assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		res, err := s.Get(&state.GetRequest{Key: "order"})
+		require.NoError(t, err)
+
+		err = s.Delete(&state.DeleteRequest{Key: "order", ETag: ptr.Of("2022-09-01T00:00:00Z")})
+		var etagErr *state.ETagError
+		require.True(t, errors.As(err, &etagErr))
+		assert.Equal(t, state.ETagMismatch, etagErr.Kind())
+
+		require.NoError(t, s.Delete(&state.DeleteRequest{Key: "order", ETag: res.ETag}))
+		assert.Empty(t, fake.docs)
+
+		err = s.Delete(&state.DeleteRequest{Key: "order", ETag: res.ETag})
+		require.True(t, errors.As(err, &etagErr))
+		require.NoError(t, s.Delete(&state.DeleteRequest{Key: "order"}))
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		err := s.Set(&state.SetRequest{Key: "order", Value: "v", ETag: ptr.Of("not a time")})
+		var etagErr *state.ETagError
+		require.True(t, errors.As(err, &etagErr))
+		assert.Equal(t, state.ETagInvalid, etagErr.Kind())
+
+		assert.EqualError(t, s.Set(&state.SetRequest{Key: "", Value: "v"}), "the key is required")
+	})
+
+	t.Run("keys which are not valid document IDs", func(t *testing.T) {
+		for _, key := range []string{"app||a/b", "a%2Fb", "..", "__id__", "empty"} {
+			value := []byte(key)
+			if key == "empty" {
+				value = []byte{}
+			}
+			require.NoError(t, s.Set(&state.SetRequest{Key: key, Value: value}), key)
+
+			res, err := s.Get(&state.GetRequest{Key: key})
+			require.NoError(t, err, key)
+			assert.Equal(t, value, res.Data, key)
+		}
+		assert.Contains(t, fake.docs, testDatabase+"/documents/DaprState/app||a%2Fb")
+		assert.Contains(t, fake.docs, testDatabase+"/documents/DaprState/a%252Fb")
+		assert.Contains(t, fake.docs, testDatabase+"/documents/DaprState/%2E%2E")
+		assert.Contains(t, fake.docs, testDatabase+"/documents/DaprState/%5F_id__")
+	})
+}
+
+func TestMulti(t *testing.T) {
+	s, fake := newStore(t)
+	require.NoError(t, s.Set(&state.SetRequest{Key: "a", Value: "1"}))
+	res, err := s.Get(&state.GetRequest{Key: "a"})
+	require.NoError(t, err)
+
+	t.Run("failed precondition aborts the transaction", func(t *testing.T) {
+		err := s.Multi(&state.TransactionalStateRequest{Operations: []state.TransactionalStateOperation{
+			{Operation: state.Upsert, Request: state.SetRequest{Key: "b", Value: "2"}},
+			{Operation: state.Delete, Request: state.DeleteRequest{Key: "a", ETag: ptr.Of("2022-09-01T00:00:00Z")}},
+		}})
+
+		var etagErr *state.ETagError
+		require.True(t, errors.As(err, &etagErr))
+		assert.Len(t, fake.docs, 1)
+		assert.Equal(t, fake.transactions, fake.rollbacks)
+	})
+
+	t.Run("aborted transactions are retried", func(t *testing.T) {
+		fake.aborts = 2
+		fake.transactions, fake.rollbacks = nil, nil
+
+		err := s.Multi(&state.TransactionalStateRequest{Operations: []state.TransactionalStateOperation{
+			{Operation: state.Upsert, Request: state.SetRequest{Key: "c", Value: "3"}},
+		}})
+
+		require.NoError(t, err)
+		assert.Len(t, fake.transactions, 3)
+		assert.Equal(t, fake.transactions[:2], fake.rollbacks)
+		require.NoError(t, s.Delete(&state.DeleteRequest{Key: "c"}))
+	})
+
+	t.Run("operations are committed together", func(t *testing.T) {
+		err := s.Multi(&state.TransactionalStateRequest{Operations: []state.TransactionalStateOperation{
+			{Operation: state.Upsert, Request: state.SetRequest{Key: "b", Value: "2"}},
+			{Operation: state.Delete, Request: state.DeleteRequest{Key: "a", ETag: res.ETag}},
+		}})
+
+		require.NoError(t, err)
+		assert.Len(t, fake.docs, 1)
+		assert.Contains(t, fake.docs, testDatabase+"/documents/DaprState/b")
+	})
+
+	t.Run("invalid operation", func(t *testing.T) {
+		err := s.Multi(&state.TransactionalStateRequest{Operations: []state.TransactionalStateOperation{
+			{Operation: "merge", Request: "b"},
+		}})
+
+		assert.Error(t, err)
+	})
+}
@@ -47,17 +47,21 @@ type inMemoryStore struct {
 
 	ctx    context.Context
 	cancel context.CancelFunc
+
+	expiryEvents *state.ExpiryEventsPublisher
 }
 
 func NewInMemoryStateStore(logger logger.Logger) state.Store {
 	return &inMemoryStore{
-		items: map[string]*inMemStateStoreItem{},
-		lock:  &sync.RWMutex{},
-		log:   logger,
+		items:        map[string]*inMemStateStoreItem{},
+		lock:         &sync.RWMutex{},
+		log:          logger,
+		expiryEvents: state.NewExpiryEventsPublisher(logger),
 	}
 }
 
 func (store *inMemoryStore) Init(metadata state.Metadata) error {
+	store.expiryEvents.Init(metadata.Properties)
 	store.ctx, store.cancel = context.WithCancel(context.Background())
 	// start a background go routine to clean expired item
 	go store.startCleanThread()
@@ -450,14 +454,23 @@ func (store *inMemoryStore) startCleanThread() {
 }
 
 func (store *inMemoryStore) doCleanExpiredItems() {
+	var expired []string
 	store.lock.Lock()
-	defer store.lock.Unlock()
-
 	for key, item := range store.items {
 		if item.expire != nil && isExpired(item) {
 			store.doDelete(key)
+			expired = append(expired, key)
 		}
 	}
+	store.lock.Unlock()
+
+	// Publish the events once the lock is released, as it can be slow
+	store.expiryEvents.Publish(expired, time.Now())
+}
+
+// SetExpiryEventsPublishFn sets the function publishing the events of the expired items removed.
+func (store *inMemoryStore) SetExpiryEventsPublishFn(publish state.PublishFn) {
+	store.expiryEvents.SetExpiryEventsPublishFn(publish)
 }
 
 func (store *inMemoryStore) GetComponentMetadata() map[string]string {
@@ -14,14 +14,18 @@ limitations under the License.
 package inmemory
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dapr/kit/logger"
 
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/pubsub"
 	"github.com/dapr/components-contrib/state"
 )
 
@@ -126,3 +130,41 @@ func TestReadAndWrite(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestExpiryEvents(t *testing.T) {
+	store := NewInMemoryStateStore(logger.NewLogger("test")).(*inMemoryStore)
+	store.Init(state.Metadata{Base: metadata.Base{Properties: map[string]string{
+		state.ExpiryEventsPubsubKey: "pubsub",
+		state.ExpiryEventsTopicKey:  "expired",
+	}}})
+	defer store.Close()
+
+	published := make(chan *pubsub.PublishRequest, 1)
+	store.SetExpiryEventsPublishFn(func(req *pubsub.PublishRequest) error {
+		published <- req
+		return nil
+	})
+
+	err := store.Set(&state.SetRequest{
+		Key:      "expiring",
+		Value:    "value",
+		Metadata: map[string]string{"ttlInSeconds": "1"},
+	})
+	require.NoError(t, err)
+	err = store.Set(&state.SetRequest{
+		Key:   "persistent",
+		Value: "value",
+	})
+	require.NoError(t, err)
+
+	select {
+	case req := <-published:
+		assert.Equal(t, "pubsub", req.PubsubName)
+		assert.Equal(t, "expired", req.Topic)
+		var event state.KeyExpiredEvent
+		require.NoError(t, json.Unmarshal(req.Data, &event))
+		assert.Equal(t, "expiring", event.Key)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expired event not published")
+	}
+}
@@ -0,0 +1,683 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package indexed provides a decorator for key-value state stores without secondary indexes, such as Redis or
+// DynamoDB, that maintains index entries for the fields of the JSON values configured with the "indexedFields"
+// metadata property, so the values can be queried by these fields.
+//
+// The index entries are saved in the store, next to the values, with the keys of the values having each value of a
+// field. Their keys have the same prefix as the keys of the values of the app, so apps sharing a store only query
+// their own values. They are updated with optimistic concurrency, so the store should support ETags. Since the index entries and
+// the values aren't written atomically, the values returned by a query are checked against its filter.
+package indexed
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+
+	mdutils "github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/components-contrib/state/query"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// FieldsKey is the metadata key for the comma-separated paths of the indexed fields, e.g. "person.org,state".
+	FieldsKey = "indexedFields"
+
+	// Metadata key of the strategy of the runtime to prefix the state keys, and environment variable of the app ID
+	// used as prefix by default.
+	keyPrefixKey = "keyPrefix"
+	appIDEnvVar  = "APP_ID"
+
+	// Prefix of the keys of the index entries after the key prefix of the app, followed by the path of the field and
+	// its JSON value.
+	indexKeyPrefix = "__index__||"
+	// Number of attempts to update an index entry modified concurrently.
+	maxIndexAttempts = 5
+)
+
+// Store is a state store maintaining secondary indexes for the values of another store.
+// Queries on indexed fields are served with the indexes, the others are run by the wrapped store, if supported.
+type Store struct {
+	state.Store
+
+	fields map[string]struct{}
+	// Prefix of the state keys of the app, such as "myapp||"
+	keyPrefix string
+	logger    logger.Logger
+}
+
+// New returns a store maintaining secondary indexes for the values of store.
+func New(store state.Store, logger logger.Logger) *Store {
+	return &Store{
+		Store:  store,
+		logger: logger,
+	}
+}
+
+// Factory wraps a factory of state stores, so that the stores it creates maintain secondary indexes. It is applied when
+// registering the key-value stores supporting the "indexedFields" metadata property, such as Redis and DynamoDB; without
+// indexed fields, the calls are forwarded to the wrapped store as they are.
+func Factory(factory func(logger.Logger) state.Store) func(logger.Logger) state.Store {
+	return func(logger logger.Logger) state.Store {
+		return New(factory(logger), logger)
+	}
+}
+
+// Init parses the indexed fields and initializes the wrapped store.
+func (s *Store) Init(metadata state.Metadata) error {
+	s.fields = map[string]struct{}{}
+	for _, f := range strings.Split(metadata.Properties[FieldsKey], ",") {
+		f = strings.TrimSpace(f)
+		if f != "" {
+			s.fields[f] = struct{}{}
+		}
+	}
+	if len(s.fields) > 0 {
+		var err error
+		s.keyPrefix, err = keyPrefix(metadata)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := s.Store.Init(metadata)
+	if err != nil {
+		return err
+	}
+
+	if len(s.fields) > 0 && !state.FeatureETag.IsPresent(s.Store.Features()) {
+		s.logger.Warn("indexed state store: the state store does not support ETags, concurrent updates may lose index entries")
+	}
+
+	return nil
+}
+
+// keyPrefix returns the prefix the runtime adds to the state keys, with the strategy of the component.
+func keyPrefix(metadata state.Metadata) (string, error) {
+	strategy := metadata.Properties[keyPrefixKey]
+	switch strings.ToLower(strategy) {
+	case "", "appid":
+		appID := os.Getenv(appIDEnvVar)
+		if appID == "" {
+			return "", fmt.Errorf("indexed state store error: the %s environment variable is required with the appid key prefix", appIDEnvVar)
+		}
+		return appID + "||", nil
+	case "none":
+		return "", nil
+	case "name":
+		return metadata.Name + "||", nil
+	default:
+		return strategy + "||", nil
+	}
+}
+
+// Ping pings the wrapped store, if supported.
+func (s *Store) Ping() error {
+	return state.Ping(s.Store)
+}
+
+// GetComponentMetadataSchema returns the metadata schema of the wrapped store, if any.
+func (s *Store) GetComponentMetadataSchema() []mdutils.MetadataField {
+	if schema, ok := s.Store.(interface {
+		GetComponentMetadataSchema() []mdutils.MetadataField
+	}); ok {
+		return schema.GetComponentMetadataSchema()
+	}
+
+	return nil
+}
+
+// Features returns the features of the wrapped store, with the query API if fields are indexed.
+func (s *Store) Features() []state.Feature {
+	features := s.Store.Features()
+	if len(s.fields) > 0 && !state.FeatureQueryAPI.IsPresent(features) {
+		features = append(features, state.FeatureQueryAPI)
+	}
+
+	return features
+}
+
+// Set saves the value in the store and updates the index entries of its fields.
+func (s *Store) Set(req *state.SetRequest) error {
+	if len(s.fields) == 0 {
+		return s.Store.Set(req)
+	}
+
+	previous, err := s.indexedValues(req.Key)
+	if err != nil {
+		return err
+	}
+	current := s.fieldValues(req.Value)
+
+	// The key is added to the new entries before the value is saved, and removed from the stale entries after, so the
+	// value can always be found
+	err = s.updateIndexes(req.Key, current, previous, true)
+	if err != nil {
+		return err
+	}
+	err = s.Store.Set(req)
+	if err != nil {
+		return err
+	}
+
+	return s.updateIndexes(req.Key, previous, current, false)
+}
+
+// Delete removes the value from the store and the key from the index entries of its fields.
+func (s *Store) Delete(req *state.DeleteRequest) error {
+	if len(s.fields) == 0 {
+		return s.Store.Delete(req)
+	}
+
+	previous, err := s.indexedValues(req.Key)
+	if err != nil {
+		return err
+	}
+
+	err = s.Store.Delete(req)
+	if err != nil {
+		return err
+	}
+
+	return s.updateIndexes(req.Key, previous, nil, false)
+}
+
+// BulkSet saves the values in the store one by one, updating the index entries of their fields.
+func (s *Store) BulkSet(req []state.SetRequest) error {
+	if len(s.fields) == 0 {
+		return s.Store.BulkSet(req)
+	}
+
+	for i := range req {
+		err := s.Set(&req[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BulkDelete removes the values from the store one by one, updating the index entries of their fields.
+func (s *Store) BulkDelete(req []state.DeleteRequest) error {
+	if len(s.fields) == 0 {
+		return s.Store.BulkDelete(req)
+	}
+
+	for i := range req {
+		err := s.Delete(&req[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Multi runs the transaction on the store, if supported, and updates the index entries of the values it modifies.
+func (s *Store) Multi(req *state.TransactionalStateRequest) error {
+	ts, ok := s.Store.(state.TransactionalStore)
+	if !ok {
+		return errors.New("indexed state store error: the state store does not support transactions")
+	}
+	if len(s.fields) == 0 {
+		return ts.Multi(req)
+	}
+
+	type change struct {
+		key               string
+		previous, current map[string]string
+	}
+	changes := make([]change, 0, len(req.Operations))
+	for _, o := range req.Operations {
+		var c change
+		switch r := o.Request.(type) {
+		case state.SetRequest:
+			c = change{key: r.Key, current: s.fieldValues(r.Value)}
+		case state.DeleteRequest:
+			c = change{key: r.Key}
+		default:
+			continue
+		}
+		var err error
+		c.previous, err = s.indexedValues(c.key)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, c)
+	}
+
+	for _, c := range changes {
+		err := s.updateIndexes(c.key, c.current, c.previous, true)
+		if err != nil {
+			return err
+		}
+	}
+	err := ts.Multi(req)
+	if err != nil {
+		return err
+	}
+	for _, c := range changes {
+		err = s.updateIndexes(c.key, c.previous, c.current, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Query returns the values matching a filter on indexed fields, using the index entries.
+// Other queries are run by the wrapped store, if it supports queries.
+func (s *Store) Query(req *state.QueryRequest) (*state.QueryResponse, error) {
+	if req.Query.Filter == nil || !s.indexed(req.Query.Filter) {
+		if querier, ok := s.Store.(state.Querier); ok {
+			return querier.Query(req)
+		}
+
+		return nil, errors.New("indexed state store error: the query filter must only contain indexed fields")
+	}
+	if len(req.Query.Sort) > 0 {
+		return nil, errors.New("indexed state store error: sorting is not supported")
+	}
+
+	keys, err := s.lookup(req.Query.Filter)
+	if err != nil {
+		return nil, err
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	// The token is the position of the first key of the page
+	start := 0
+	if req.Query.Page.Token != "" {
+		start, err = strconv.Atoi(req.Query.Page.Token)
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("indexed state store error: invalid page token %s", req.Query.Page.Token)
+		}
+	}
+
+	res := &state.QueryResponse{}
+	for i := start; i < len(sorted); i++ {
+		if req.Query.Page.Limit > 0 && len(res.Results) == req.Query.Page.Limit {
+			res.Token = strconv.Itoa(i)
+			break
+		}
+
+		item, err := s.Store.Get(&state.GetRequest{Key: sorted[i], Metadata: req.Metadata})
+		if err != nil {
+			return nil, err
+		}
+		// Index entries may be stale while the value is updated
+		if item == nil || item.Data == nil || !matches(item.Data, req.Query.Filter) {
+			continue
+		}
+		res.Results = append(res.Results, state.QueryItem{
+			Key:         sorted[i],
+			Data:        item.Data,
+			ETag:        item.ETag,
+			ContentType: item.ContentType,
+		})
+	}
+
+	return res, nil
+}
+
+// Close closes the wrapped store.
+func (s *Store) Close() error {
+	if closer, ok := s.Store.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// indexed returns true if all the conditions of the filter are on indexed fields.
+func (s *Store) indexed(filter query.Filter) bool {
+	switch f := filter.(type) {
+	case *query.EQ:
+		_, ok := s.fields[f.Key]
+		return ok
+	case *query.IN:
+		_, ok := s.fields[f.Key]
+		return ok
+	case *query.AND:
+		for _, sub := range f.Filters {
+			if !s.indexed(sub) {
+				return false
+			}
+		}
+		return true
+	case *query.OR:
+		for _, sub := range f.Filters {
+			if !s.indexed(sub) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// lookup returns the keys of the index entries matching the filter.
+func (s *Store) lookup(filter query.Filter) (map[string]struct{}, error) {
+	switch f := filter.(type) {
+	case *query.EQ:
+		return s.lookupValues(f.Key, f.Val)
+	case *query.IN:
+		return s.lookupValues(f.Key, f.Vals...)
+	case *query.AND:
+		var keys map[string]struct{}
+		for _, sub := range f.Filters {
+			subKeys, err := s.lookup(sub)
+			if err != nil {
+				return nil, err
+			}
+			if keys == nil {
+				keys = subKeys
+				continue
+			}
+			for k := range keys {
+				if _, ok := subKeys[k]; !ok {
+					delete(keys, k)
+				}
+			}
+		}
+		return keys, nil
+	case *query.OR:
+		keys := map[string]struct{}{}
+		for _, sub := range f.Filters {
+			subKeys, err := s.lookup(sub)
+			if err != nil {
+				return nil, err
+			}
+			for k := range subKeys {
+				keys[k] = struct{}{}
+			}
+		}
+		return keys, nil
+	default:
+		return nil, fmt.Errorf("indexed state store error: unsupported filter type %#v", filter)
+	}
+}
+
+func (s *Store) lookupValues(field string, vals ...interface{}) (map[string]struct{}, error) {
+	keys := map[string]struct{}{}
+	for _, val := range vals {
+		encoded, ok := encodeValue(val)
+		if !ok {
+			continue
+		}
+		entry, _, err := s.getIndexEntry(s.indexKey(field, encoded))
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range entry {
+			keys[k] = struct{}{}
+		}
+	}
+
+	return keys, nil
+}
+
+// indexedValues returns the values of the indexed fields of the value currently saved for the key.
+func (s *Store) indexedValues(key string) (map[string]string, error) {
+	res, err := s.Store.Get(&state.GetRequest{Key: key})
+	if err != nil {
+		return nil, fmt.Errorf("indexed state store error: failed to get the current value of key %s: %w", key, err)
+	}
+	if res == nil || res.Data == nil {
+		return nil, nil
+	}
+
+	return s.fieldValues(res.Data), nil
+}
+
+// fieldValues returns the JSON values of the indexed fields of a value, by path.
+// Fields which are missing, or which are objects or arrays, are not indexed.
+func (s *Store) fieldValues(value interface{}) map[string]string {
+	doc, ok := decodeDocument(value)
+	if !ok {
+		return nil
+	}
+
+	values := make(map[string]string, len(s.fields))
+	for field := range s.fields {
+		val, ok := fieldValue(doc, field)
+		if !ok {
+			continue
+		}
+		if encoded, ok := encodeValue(val); ok {
+			values[field] = encoded
+		}
+	}
+
+	return values
+}
+
+// updateIndexes adds the key to the index entries of the values, or removes it if add is false, skipping the values
+// which are the same in except.
+func (s *Store) updateIndexes(key string, values map[string]string, except map[string]string, add bool) error {
+	for field, val := range values {
+		if prev, ok := except[field]; ok && prev == val {
+			continue
+		}
+
+		err := s.updateIndexEntry(s.indexKey(field, val), key, add)
+		if err != nil {
+			return fmt.Errorf("indexed state store error: failed to update the index of field %s for key %s: %w", field, key, err)
+		}
+	}
+
+	return nil
+}
+
+// updateIndexEntry adds the key to, or removes it from, the index entry, retrying if the entry is modified
+// concurrently.
+func (s *Store) updateIndexEntry(indexKey string, key string, add bool) (err error) {
+	for attempt := 0; attempt < maxIndexAttempts; attempt++ {
+		var (
+			entry []string
+			etag  *string
+		)
+		entry, etag, err = s.getIndexEntry(indexKey)
+		if err != nil {
+			return err
+		}
+
+		i := sort.SearchStrings(entry, key)
+		found := i < len(entry) && entry[i] == key
+		switch {
+		case add && found, !add && !found:
+			return nil
+		case add:
+			entry = append(entry, "")
+			copy(entry[i+1:], entry[i:])
+			entry[i] = key
+		default:
+			entry = append(entry[:i], entry[i+1:]...)
+		}
+
+		if len(entry) == 0 {
+			err = s.Store.Delete(&state.DeleteRequest{Key: indexKey, ETag: etag})
+		} else {
+			var data []byte
+			data, err = jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			req := &state.SetRequest{Key: indexKey, Value: data, ETag: etag}
+			if etag == nil {
+				req.Options.Concurrency = state.FirstWrite
+			}
+			err = s.Store.Set(req)
+		}
+		if err == nil {
+			return nil
+		}
+		s.logger.Debugf("indexed state store: failed to update index entry %s, attempt %d: %v", indexKey, attempt+1, err)
+	}
+
+	return err
+}
+
+// getIndexEntry returns the sorted keys of the index entry and its ETag.
+func (s *Store) getIndexEntry(indexKey string) ([]string, *string, error) {
+	res, err := s.Store.Get(&state.GetRequest{Key: indexKey})
+	if err != nil {
+		return nil, nil, err
+	}
+	if res == nil || res.Data == nil {
+		return nil, nil, nil
+	}
+
+	var entry []string
+	err = jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(res.Data, &entry)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid index entry %s: %w", indexKey, err)
+	}
+	sort.Strings(entry)
+
+	return entry, res.ETag, nil
+}
+
+func (s *Store) indexKey(field string, encodedValue string) string {
+	return s.keyPrefix + indexKeyPrefix + field + "||" + encodedValue
+}
+
+// matches returns true if the JSON value matches the filter.
+func matches(data []byte, filter query.Filter) bool {
+	doc, ok := decodeDocument(data)
+	if !ok {
+		return false
+	}
+
+	return matchesDocument(doc, filter)
+}
+
+func matchesDocument(doc map[string]interface{}, filter query.Filter) bool {
+	switch f := filter.(type) {
+	case *query.EQ:
+		return fieldEquals(doc, f.Key, f.Val)
+	case *query.IN:
+		for _, val := range f.Vals {
+			if fieldEquals(doc, f.Key, val) {
+				return true
+			}
+		}
+		return false
+	case *query.AND:
+		for _, sub := range f.Filters {
+			if !matchesDocument(doc, sub) {
+				return false
+			}
+		}
+		return true
+	case *query.OR:
+		for _, sub := range f.Filters {
+			if matchesDocument(doc, sub) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+func fieldEquals(doc map[string]interface{}, field string, val interface{}) bool {
+	actual, ok := fieldValue(doc, field)
+	if !ok {
+		return false
+	}
+	a, ok := encodeValue(actual)
+	if !ok {
+		return false
+	}
+	b, ok := encodeValue(val)
+
+	return ok && a == b
+}
+
+// decodeDocument decodes a JSON object, from bytes or from any value encoded to JSON first.
+func decodeDocument(value interface{}) (map[string]interface{}, bool) {
+	data, ok := value.([]byte)
+	if !ok {
+		var err error
+		data, err = jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(value)
+		if err != nil {
+			return nil, false
+		}
+	}
+
+	var doc map[string]interface{}
+	err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, false
+	}
+
+	return doc, true
+}
+
+// fieldValue returns the value at the dot-separated path of the document.
+func fieldValue(doc map[string]interface{}, path string) (interface{}, bool) {
+	var cur interface{} = doc
+	for _, name := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		cur, ok = m[name]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return cur, true
+}
+
+// encodeValue returns the JSON encoding of a scalar value, which identifies it in the index.
+// Numbers are encoded as decoded from JSON, i.e. as float64.
+func encodeValue(val interface{}) (string, bool) {
+	switch v := val.(type) {
+	case string, float64, bool, nil:
+	case int:
+		val = float64(v)
+	case int64:
+		val = float64(v)
+	case float32:
+		val = float64(v)
+	default:
+		return "", false
+	}
+
+	b, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(val)
+	if err != nil {
+		return "", false
+	}
+
+	return string(b), true
+}
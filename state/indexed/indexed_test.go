@@ -0,0 +1,253 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package indexed
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/state"
+	stateInMemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/components-contrib/state/query"
+	"github.com/dapr/kit/logger"
+)
+
+func newIndexedStore(t *testing.T, fields string) (*Store, state.Store) {
+	t.Helper()
+
+	inner := stateInMemory.NewInMemoryStateStore(logger.NewLogger("test"))
+
+	return newAppStore(t, inner, "myapp", fields), inner
+}
+
+// newAppStore returns an indexed store of the app, wrapping inner.
+func newAppStore(t *testing.T, inner state.Store, appID string, fields string) *Store {
+	t.Helper()
+
+	t.Setenv("APP_ID", appID)
+	s := New(inner, logger.NewLogger("test"))
+	require.NoError(t, s.Init(state.Metadata{Base: metadata.Base{Properties: map[string]string{
+		FieldsKey: fields,
+	}}}))
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+func runQuery(t *testing.T, s *Store, q string) *state.QueryResponse {
+	t.Helper()
+
+	var req state.QueryRequest
+	require.NoError(t, json.Unmarshal([]byte(q), &req.Query))
+	res, err := s.Query(&req)
+	require.NoError(t, err)
+
+	return res
+}
+
+func resultKeys(res *state.QueryResponse) []string {
+	keys := make([]string, len(res.Results))
+	for i, r := range res.Results {
+		keys[i] = r.Key
+	}
+
+	return keys
+}
+
+func indexEntry(t *testing.T, inner state.Store, key string) []string {
+	t.Helper()
+
+	res, err := inner.Get(&state.GetRequest{Key: key})
+	require.NoError(t, err)
+	if res.Data == nil {
+		return nil
+	}
+	var entry []string
+	require.NoError(t, json.Unmarshal(res.Data, &entry))
+
+	return entry
+}
+
+func TestIndexedStore(t *testing.T) {
+	s, inner := newIndexedStore(t, "state, person.org")
+
+	require.NoError(t, s.Set(&state.SetRequest{Key: "1", Value: map[string]interface{}{"state": "CA", "person": map[string]interface{}{"org": "Dev"}}}))
+	require.NoError(t, s.Set(&state.SetRequest{Key: "2", Value: []byte(`{"state":"WA","person":{"org":"Dev"}}`)}))
+	require.NoError(t, s.Set(&state.SetRequest{Key: "3", Value: []byte(`{"state":"CA","person":{"org":"Finance"}}`)}))
+	require.NoError(t, s.Set(&state.SetRequest{Key: "4", Value: []byte(`"not an object"`)}))
+
+	t.Run("features include the query API", func(t *testing.T) {
+		assert.True(t, state.FeatureQueryAPI.IsPresent(s.Features()))
+	})
+
+	t.Run("index entries are saved in the store", func(t *testing.T) {
+		assert.Equal(t, []string{"1", "3"}, indexEntry(t, inner, `myapp||__index__||state||"CA"`))
+		assert.Equal(t, []string{"1", "2"}, indexEntry(t, inner, `myapp||__index__||person.org||"Dev"`))
+	})
+
+	t.Run("query by field", func(t *testing.T) {
+		res := runQuery(t, s, `{"filter":{"EQ":{"state":"CA"}}}`)
+		assert.Equal(t, []string{"1", "3"}, resultKeys(res))
+		assert.JSONEq(t, `{"state":"CA","person":{"org":"Finance"}}`, string(res.Results[1].Data))
+		assert.NotNil(t, res.Results[1].ETag)
+	})
+
+	t.Run("query with AND, OR and IN", func(t *testing.T) {
+		res := runQuery(t, s, `{"filter":{"AND":[{"EQ":{"state":"CA"}},{"EQ":{"person.org":"Dev"}}]}}`)
+		assert.Equal(t, []string{"1"}, resultKeys(res))
+
+		res = runQuery(t, s, `{"filter":{"OR":[{"EQ":{"state":"WA"}},{"EQ":{"person.org":"Finance"}}]}}`)
+		assert.Equal(t, []string{"2", "3"}, resultKeys(res))
+
+		res = runQuery(t, s, `{"filter":{"IN":{"state":["CA","WA"]}}}`)
+		assert.Equal(t, []string{"1", "2", "3"}, resultKeys(res))
+	})
+
+	t.Run("query with pagination", func(t *testing.T) {
+		res := runQuery(t, s, `{"filter":{"IN":{"state":["CA","WA"]}},"page":{"limit":2}}`)
+		assert.Equal(t, []string{"1", "2"}, resultKeys(res))
+		require.NotEmpty(t, res.Token)
+
+		res = runQuery(t, s, `{"filter":{"IN":{"state":["CA","WA"]}},"page":{"limit":2,"token":"`+res.Token+`"}}`)
+		assert.Equal(t, []string{"3"}, resultKeys(res))
+		assert.Empty(t, res.Token)
+	})
+
+	t.Run("updates move the key between index entries", func(t *testing.T) {
+		require.NoError(t, s.Set(&state.SetRequest{Key: "3", Value: []byte(`{"state":"WA","person":{"org":"Finance"}}`)}))
+
+		assert.Equal(t, []string{"1"}, indexEntry(t, inner, `myapp||__index__||state||"CA"`))
+		assert.Equal(t, []string{"2", "3"}, indexEntry(t, inner, `myapp||__index__||state||"WA"`))
+		assert.Equal(t, []string{"2", "3"}, resultKeys(runQuery(t, s, `{"filter":{"EQ":{"state":"WA"}}}`)))
+	})
+
+	t.Run("deletes remove the key from the index entries", func(t *testing.T) {
+		require.NoError(t, s.Delete(&state.DeleteRequest{Key: "1"}))
+
+		assert.Nil(t, indexEntry(t, inner, `myapp||__index__||state||"CA"`))
+		assert.Equal(t, []string{"2"}, indexEntry(t, inner, `myapp||__index__||person.org||"Dev"`))
+		assert.Empty(t, runQuery(t, s, `{"filter":{"EQ":{"state":"CA"}}}`).Results)
+	})
+
+	t.Run("transactions update the index entries", func(t *testing.T) {
+		require.NoError(t, s.Multi(&state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{
+				{Operation: state.Upsert, Request: state.SetRequest{Key: "5", Value: []byte(`{"state":"NY"}`)}},
+				{Operation: state.Delete, Request: state.DeleteRequest{Key: "2"}},
+			},
+		}))
+
+		assert.Equal(t, []string{"5"}, resultKeys(runQuery(t, s, `{"filter":{"EQ":{"state":"NY"}}}`)))
+		assert.Equal(t, []string{"3"}, indexEntry(t, inner, `myapp||__index__||state||"WA"`))
+		assert.Nil(t, indexEntry(t, inner, `myapp||__index__||person.org||"Dev"`))
+	})
+
+	t.Run("stale index entries are filtered out", func(t *testing.T) {
+		// Write to the wrapped store directly, as if the index update had failed
+		require.NoError(t, inner.Set(&state.SetRequest{Key: "5", Value: []byte(`{"state":"NJ"}`)}))
+
+		assert.Empty(t, runQuery(t, s, `{"filter":{"EQ":{"state":"NY"}}}`).Results)
+	})
+
+	t.Run("queries on fields which are not indexed are not supported", func(t *testing.T) {
+		var req state.QueryRequest
+		require.NoError(t, json.Unmarshal([]byte(`{"filter":{"EQ":{"city":"Seattle"}}}`), &req.Query))
+
+		_, err := s.Query(&req)
+		assert.ErrorContains(t, err, "must only contain indexed fields")
+	})
+
+	t.Run("sorting is not supported", func(t *testing.T) {
+		_, err := s.Query(&state.QueryRequest{Query: query.Query{
+			QueryFields: query.QueryFields{Sort: []query.Sorting{{Key: "state"}}},
+			Filter:      &query.EQ{Key: "state", Val: "WA"},
+		}})
+		assert.ErrorContains(t, err, "sorting is not supported")
+	})
+}
+
+func TestKeyPrefix(t *testing.T) {
+	inner := stateInMemory.NewInMemoryStateStore(logger.NewLogger("test"))
+	orders := newAppStore(t, inner, "orders", "state")
+	billing := newAppStore(t, inner, "billing", "state")
+
+	require.NoError(t, orders.Set(&state.SetRequest{Key: "orders||1", Value: []byte(`{"state":"CA"}`)}))
+	require.NoError(t, billing.Set(&state.SetRequest{Key: "billing||1", Value: []byte(`{"state":"CA"}`)}))
+
+	t.Run("apps only query their values", func(t *testing.T) {
+		assert.Equal(t, []string{"orders||1"}, resultKeys(runQuery(t, orders, `{"filter":{"EQ":{"state":"CA"}}}`)))
+		assert.Equal(t, []string{"billing||1"}, resultKeys(runQuery(t, billing, `{"filter":{"EQ":{"state":"CA"}}}`)))
+		assert.Equal(t, []string{"orders||1"}, indexEntry(t, inner, `orders||__index__||state||"CA"`))
+	})
+
+	t.Run("strategies", func(t *testing.T) {
+		t.Setenv("APP_ID", "orders")
+		for strategy, prefix := range map[string]string{"": "orders||", "AppID": "orders||", "none": "", "name": "statestore||", "shared": "shared||"} {
+			md := state.Metadata{Base: metadata.Base{Name: "statestore", Properties: map[string]string{"keyPrefix": strategy}}}
+			p, err := keyPrefix(md)
+			require.NoError(t, err)
+			assert.Equal(t, prefix, p, strategy)
+		}
+	})
+
+	t.Run("the app ID is required", func(t *testing.T) {
+		t.Setenv("APP_ID", "")
+		s := New(inner, logger.NewLogger("test"))
+		assert.Error(t, s.Init(state.Metadata{Base: metadata.Base{Properties: map[string]string{FieldsKey: "state"}}}))
+	})
+}
+
+func TestNoIndexedFields(t *testing.T) {
+	s, inner := newIndexedStore(t, "")
+
+	require.NoError(t, s.Set(&state.SetRequest{Key: "1", Value: []byte(`{"state":"CA"}`)}))
+
+	assert.False(t, state.FeatureQueryAPI.IsPresent(s.Features()))
+	assert.Nil(t, indexEntry(t, inner, `myapp||__index__||state||"CA"`))
+}
+
+func TestFactory(t *testing.T) {
+	inner := stateInMemory.NewInMemoryStateStore(logger.NewLogger("test"))
+	factory := Factory(func(logger.Logger) state.Store { return inner })
+
+	s := factory(logger.NewLogger("test"))
+
+	require.IsType(t, &Store{}, s)
+	assert.Same(t, inner, s.(*Store).Store)
+}
+
+func TestEncodeValue(t *testing.T) {
+	for _, tc := range []struct {
+		val     interface{}
+		encoded string
+		ok      bool
+	}{
+		{val: "CA", encoded: `"CA"`, ok: true},
+		{val: float64(5), encoded: `5`, ok: true},
+		{val: 5, encoded: `5`, ok: true},
+		{val: 1234567.891, encoded: `1234567.891`, ok: true},
+		{val: true, encoded: `true`, ok: true},
+		{val: nil, encoded: `null`, ok: true},
+		{val: map[string]interface{}{"a": 1}, ok: false},
+		{val: []interface{}{1}, ok: false},
+	} {
+		encoded, ok := encodeValue(tc.val)
+		assert.Equal(t, tc.ok, ok, "%v", tc.val)
+		assert.Equal(t, tc.encoded, encoded, "%v", tc.val)
+	}
+}
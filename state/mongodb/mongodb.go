@@ -17,9 +17,12 @@ package mongodb
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"reflect"
 	"strconv"
 	"time"
@@ -32,6 +35,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/readconcern"
 	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 
+	"github.com/dapr/components-contrib/internal/authentication/clientcert"
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/components-contrib/state/query"
@@ -52,12 +56,16 @@ const (
 	value            = "value"
 	etag             = "_etag"
 
+	// MONGODB-X509 authentication uses the subject of the client certificate as user name
+	authMechanismX509 = "MONGODB-X509"
+	authSourceX509    = "$external"
+
 	defaultTimeout        = 5 * time.Second
 	defaultDatabaseName   = "daprStore"
 	defaultCollectionName = "daprCollection"
 
-	// mongodb://<username>:<password@<host>/<database><params>
-	connectionURIFormatWithAuthentication = "mongodb://%s:%s@%s/%s%s"
+	// mongodb://<username>:<password>@<host>/<database><params>
+	connectionURIFormatWithAuthentication = "mongodb://%s@%s/%s%s"
 
 	// mongodb://<host>/<database><params>
 	connectionURIFormat = "mongodb://%s/%s%s"
@@ -65,8 +73,8 @@ const (
 	// mongodb+srv://<server>/<params>
 	connectionURIFormatWithSrv = "mongodb+srv://%s/%s"
 
-	// mongodb+srv://<username>:<password>@<server>/<params>
-	connectionURIFormatWithSrvAndCredentials = "mongodb+srv://%s:%s@%s/%s%s" //nolint:gosec
+	// mongodb+srv://<username>:<password>@<server>/<database><params>
+	connectionURIFormatWithSrvAndCredentials = "mongodb+srv://%s@%s/%s%s" //nolint:gosec
 )
 
 // MongoDB is a state store implementation for MongoDB.
@@ -76,6 +84,8 @@ type MongoDB struct {
 	collection       *mongo.Collection
 	operationTimeout time.Duration
 	metadata         mongoDBMetadata
+	// Source of the client certificates, if configured
+	clientCert *clientcert.Source
 
 	features []state.Feature
 	logger   logger.Logger
@@ -92,6 +102,16 @@ type mongoDBMetadata struct {
 	Readconcern      string
 	Params           string
 	OperationTimeout time.Duration
+	// PEM-encoded certificate and key of the client, for X.509 authentication
+	ClientCert string
+	ClientKey  string
+	// PEM-encoded certificate of the CA that signed the server certificate
+	CaCert string
+	// Source of the client certificates for X.509 authentication, instead of clientCert and clientKey
+	ClientCertSource string
+	// Stable API version to pin, such as "1"
+	ServerAPIVersion string
+	ServerAPIStrict  bool
 }
 
 // Item is Mongodb document wrapper.
@@ -121,7 +141,12 @@ func (m *MongoDB) Init(metadata state.Metadata) error {
 
 	m.operationTimeout = meta.OperationTimeout
 
-	client, err := getMongoDBClient(meta)
+	m.clientCert, err = clientcert.New(metadata.Properties, m.logger)
+	if err != nil {
+		return err
+	}
+
+	client, err := getMongoDBClient(meta, m.clientCert)
 	if err != nil {
 		return fmt.Errorf("error in creating mongodb client: %s", err)
 	}
@@ -171,6 +196,20 @@ func (m *MongoDB) Set(req *state.SetRequest) error {
 	return nil
 }
 
+// Close disconnects from MongoDB and stops the rotation of the client certificates.
+func (m *MongoDB) Close() error {
+	if m.clientCert != nil {
+		m.clientCert.Close()
+	}
+	if m.client == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.operationTimeout)
+	defer cancel()
+	return m.client.Disconnect(ctx)
+}
+
 func (m *MongoDB) Ping() error {
 	if err := m.client.Ping(context.Background(), nil); err != nil {
 		return fmt.Errorf("mongoDB store: error connecting to mongoDB at %s: %s", m.metadata.Host, err)
@@ -357,30 +396,57 @@ func (m *MongoDB) Query(req *state.QueryRequest) (*state.QueryResponse, error) {
 }
 
 func getMongoURI(metadata *mongoDBMetadata) string {
+	// Credentials are escaped as they can contain reserved characters such as "@" or ":"
+	var credentials string
+	if metadata.Username != "" && metadata.Password != "" {
+		credentials = url.UserPassword(metadata.Username, metadata.Password).String()
+	}
+
 	if len(metadata.Server) != 0 {
-		if metadata.Username != "" && metadata.Password != "" {
-			return fmt.Sprintf(connectionURIFormatWithSrvAndCredentials, metadata.Username, metadata.Password, metadata.Server, metadata.DatabaseName, metadata.Params)
+		if credentials != "" {
+			return fmt.Sprintf(connectionURIFormatWithSrvAndCredentials, credentials, metadata.Server, metadata.DatabaseName, metadata.Params)
 		}
 
 		return fmt.Sprintf(connectionURIFormatWithSrv, metadata.Server, metadata.Params)
 	}
 
-	if metadata.Username != "" && metadata.Password != "" {
-		return fmt.Sprintf(connectionURIFormatWithAuthentication, metadata.Username, metadata.Password, metadata.Host, metadata.DatabaseName, metadata.Params)
+	if credentials != "" {
+		return fmt.Sprintf(connectionURIFormatWithAuthentication, credentials, metadata.Host, metadata.DatabaseName, metadata.Params)
 	}
 
 	return fmt.Sprintf(connectionURIFormat, metadata.Host, metadata.DatabaseName, metadata.Params)
 }
 
-func getMongoDBClient(metadata *mongoDBMetadata) (*mongo.Client, error) {
+func getClientOptions(metadata *mongoDBMetadata, clientCert *clientcert.Source) (*options.ClientOptions, error) {
 	uri := getMongoURI(metadata)
 
 	// Set client options
 	clientOptions := options.Client().ApplyURI(uri)
+	if err := clientOptions.Validate(); err != nil {
+		return nil, err
+	}
 
-	// Connect to MongoDB
-	ctx, cancel := context.WithTimeout(context.Background(), metadata.OperationTimeout)
-	defer cancel()
+	if metadata.ClientCert != "" || metadata.CaCert != "" || clientCert != nil {
+		tlsConfig, err := getTLSConfig(metadata)
+		if err != nil {
+			return nil, err
+		}
+		if clientCert != nil {
+			tlsConfig = clientCert.TLSConfig(tlsConfig)
+		}
+		clientOptions.SetTLSConfig(tlsConfig)
+	}
+	if metadata.ClientCert != "" || clientCert != nil {
+		clientOptions.SetAuth(options.Credential{
+			AuthMechanism: authMechanismX509,
+			AuthSource:    authSourceX509,
+			Username:      metadata.Username,
+		})
+	}
+
+	if metadata.ServerAPIVersion != "" {
+		clientOptions.SetServerAPIOptions(options.ServerAPI(options.ServerAPIVersion(metadata.ServerAPIVersion)).SetStrict(metadata.ServerAPIStrict))
+	}
 
 	daprUserAgent := "dapr-" + logger.DaprVersion
 	if clientOptions.AppName != nil {
@@ -389,6 +455,43 @@ func getMongoDBClient(metadata *mongoDBMetadata) (*mongo.Client, error) {
 		clientOptions.SetAppName(daprUserAgent)
 	}
 
+	return clientOptions, nil
+}
+
+func getTLSConfig(metadata *mongoDBMetadata) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if metadata.ClientCert != "" {
+		cert, err := tls.X509KeyPair([]byte(metadata.ClientCert), []byte(metadata.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate or key: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if metadata.CaCert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(metadata.CaCert)) {
+			return nil, errors.New("invalid CA certificate")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+func getMongoDBClient(metadata *mongoDBMetadata, clientCert *clientcert.Source) (*mongo.Client, error) {
+	clientOptions, err := getClientOptions(metadata, clientCert)
+	if err != nil {
+		return nil, err
+	}
+
+	// Connect to MongoDB
+	ctx, cancel := context.WithTimeout(context.Background(), metadata.OperationTimeout)
+	defer cancel()
+
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
 		return nil, err
@@ -417,6 +520,22 @@ func getMongoDBMetaData(meta state.Metadata) (*mongoDBMetadata, error) {
 		return nil, errors.New("'host' or 'server' fields are mutually exclusive")
 	}
 
+	if (m.ClientCert == "") != (m.ClientKey == "") {
+		return nil, errors.New("'clientCert' and 'clientKey' fields must be set together")
+	}
+
+	if m.ClientCert != "" && m.Password != "" {
+		return nil, errors.New("'password' and 'clientCert' fields are mutually exclusive")
+	}
+
+	if m.ClientCertSource != "" && (m.ClientCert != "" || m.Password != "") {
+		return nil, errors.New("'clientCertSource' field is mutually exclusive with 'clientCert' and 'password'")
+	}
+
+	if m.ServerAPIVersion != "" && m.ServerAPIVersion != string(options.ServerAPIVersion1) {
+		return nil, fmt.Errorf("unsupported serverApiVersion '%s'", m.ServerAPIVersion)
+	}
+
 	var err error
 	if val, ok := meta.Properties[operationTimeout]; ok && val != "" {
 		m.OperationTimeout, err = time.ParseDuration(val)
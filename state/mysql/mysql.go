@@ -27,6 +27,7 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/dapr/components-contrib/internal/logging"
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/kit/logger"
@@ -122,6 +123,7 @@ func newMySQLStateStore(logger logger.Logger, factory iMySQLFactory) *MySQL {
 // Populate the rest of the MySQL object by reading the metadata and opening
 // a connection to the server.
 func (m *MySQL) Init(metadata state.Metadata) error {
+	m.logger = logging.ForComponent(m.logger, "state.mysql", metadata.Name)
 	m.logger.Debug("Initializing MySql state store")
 
 	err := m.parseMetadata(metadata.Properties)
@@ -131,7 +133,7 @@ func (m *MySQL) Init(metadata state.Metadata) error {
 
 	db, err := m.factory.Open(m.connectionString)
 	if err != nil {
-		m.logger.Error(err)
+		m.logger.Errorf("Error opening the database: %v", err)
 		return err
 	}
 
@@ -174,7 +176,7 @@ func (m *MySQL) parseMetadata(md map[string]string) error {
 	if meta.PemPath != "" {
 		err := m.factory.RegisterTLSConfig(meta.PemPath)
 		if err != nil {
-			m.logger.Error(err)
+			m.logger.Errorf("Error registering the TLS configuration: %v", err)
 			return err
 		}
 	}
@@ -215,13 +217,13 @@ func (m *MySQL) finishInit(db *sql.DB) error {
 
 	err := m.ensureStateSchema()
 	if err != nil {
-		m.logger.Error(err)
+		m.logger.Errorf("Error creating the schema: %v", err)
 		return err
 	}
 
 	err = m.Ping()
 	if err != nil {
-		m.logger.Error(err)
+		m.logger.Errorf("Error connecting to the database: %v", err)
 		return err
 	}
 
@@ -556,13 +558,13 @@ func (m *MySQL) setValue(querier querier, req *state.SetRequest) error {
 	if rows == 0 {
 		err = errors.New(`rows affected error: no rows match given key and eTag`)
 		err = state.NewETagError(state.ETagMismatch, err)
-		m.logger.Error(err)
+		m.logger.Errorf("Error setting the state value of key %s: %v", req.Key, err)
 		return err
 	}
 
 	if rows > maxRows {
 		err = fmt.Errorf(`rows affected error: more than %d row affected; actual %d`, maxRows, rows)
-		m.logger.Error(err)
+		m.logger.Errorf("Error setting the state value of key %s: %v", req.Key, err)
 		return err
 	}
 
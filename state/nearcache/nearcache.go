@@ -0,0 +1,296 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package nearcache provides a decorator for state stores that keeps the values read from the store in memory.
+// Replicas sharing the store publish the keys they modify on a pub/sub topic, so the others evict them from their cache.
+package nearcache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/patrickmn/go-cache"
+
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// TTLKey is the metadata key for how long values are cached, as a Go duration.
+	TTLKey = "nearCacheTTL"
+	// TopicKey is the metadata key for the pub/sub topic used to invalidate the caches of the other replicas.
+	TopicKey = "nearCacheTopic"
+
+	defaultTTL = 10 * time.Second
+)
+
+// invalidation is the message published when keys are modified.
+type invalidation struct {
+	// Source is the ID of the replica that modified the keys.
+	Source string   `json:"source"`
+	Keys   []string `json:"keys"`
+}
+
+// pendingRead tracks the reads of a key from the store in progress.
+type pendingRead struct {
+	readers int
+	// Incremented when the key is invalidated, so that the values read before are not cached.
+	version uint64
+}
+
+// Store is a state store that caches the values read from another store.
+// Reads with a strong consistency or with metadata always go to the store.
+type Store struct {
+	state.Store
+
+	ps        pubsub.PubSub
+	topic     string
+	cache     *cache.Cache
+	replicaID string
+
+	// Guards the pending reads, and orders the caching of the values read with the invalidations.
+	lock    sync.Mutex
+	pending map[string]*pendingRead
+	logger  logger.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// New returns a near cache for store.
+// ps is used to invalidate the caches of the other replicas and must be initialized; it can be nil if there's a single replica.
+func New(store state.Store, ps pubsub.PubSub, logger logger.Logger) *Store {
+	return &Store{
+		Store:     store,
+		ps:        ps,
+		replicaID: uuid.NewString(),
+		pending:   map[string]*pendingRead{},
+		logger:    logger,
+	}
+}
+
+// Init initializes the cache and the wrapped store.
+func (s *Store) Init(metadata state.Metadata) error {
+	ttl := defaultTTL
+	if val, ok := metadata.Properties[TTLKey]; ok && val != "" {
+		var err error
+		ttl, err = time.ParseDuration(val)
+		if err != nil || ttl <= 0 {
+			return fmt.Errorf("near cache error: invalid %s %s", TTLKey, val)
+		}
+	}
+	s.topic = metadata.Properties[TopicKey]
+	if s.topic != "" && s.ps == nil {
+		return fmt.Errorf("near cache error: %s is set but no pub/sub is configured", TopicKey)
+	}
+
+	if err := s.Store.Init(metadata); err != nil {
+		return err
+	}
+
+	s.cache = cache.New(ttl, 2*ttl)
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	if s.topic != "" {
+		err := s.ps.Subscribe(s.ctx, pubsub.SubscribeRequest{Topic: s.topic}, s.onInvalidation)
+		if err != nil {
+			return fmt.Errorf("near cache error: failed to subscribe to %s: %w", s.topic, err)
+		}
+	}
+
+	return nil
+}
+
+// Get returns the cached value for the key, or reads it from the store.
+func (s *Store) Get(req *state.GetRequest) (*state.GetResponse, error) {
+	if !cacheable(req) {
+		return s.Store.Get(req)
+	}
+
+	if val, ok := s.cache.Get(req.Key); ok {
+		return copyResponse(val.(*state.GetResponse)), nil
+	}
+
+	s.lock.Lock()
+	p, ok := s.pending[req.Key]
+	if !ok {
+		p = &pendingRead{}
+		s.pending[req.Key] = p
+	}
+	p.readers++
+	version := p.version
+	s.lock.Unlock()
+
+	res, err := s.Store.Get(req)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	p.readers--
+	if p.readers == 0 {
+		delete(s.pending, req.Key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Missing keys are not cached, nor the values which may have been read before an invalidation of the key
+	if res != nil && res.Data != nil && p.version == version {
+		s.cache.SetDefault(req.Key, copyResponse(res))
+	}
+
+	return res, nil
+}
+
+// Set saves the value in the store and invalidates the key.
+func (s *Store) Set(req *state.SetRequest) error {
+	err := s.Store.Set(req)
+	s.invalidate(req.Key)
+
+	return err
+}
+
+// Delete removes the key from the store and invalidates it.
+func (s *Store) Delete(req *state.DeleteRequest) error {
+	err := s.Store.Delete(req)
+	s.invalidate(req.Key)
+
+	return err
+}
+
+// BulkSet saves the values in the store and invalidates their keys.
+func (s *Store) BulkSet(req []state.SetRequest) error {
+	err := s.Store.BulkSet(req)
+	keys := make([]string, len(req))
+	for i := range req {
+		keys[i] = req[i].Key
+	}
+	s.invalidate(keys...)
+
+	return err
+}
+
+// BulkDelete removes the keys from the store and invalidates them.
+func (s *Store) BulkDelete(req []state.DeleteRequest) error {
+	err := s.Store.BulkDelete(req)
+	keys := make([]string, len(req))
+	for i := range req {
+		keys[i] = req[i].Key
+	}
+	s.invalidate(keys...)
+
+	return err
+}
+
+// Multi runs the transaction on the store, if supported, and invalidates the keys it modifies.
+func (s *Store) Multi(req *state.TransactionalStateRequest) error {
+	ts, ok := s.Store.(state.TransactionalStore)
+	if !ok {
+		return errors.New("near cache error: the state store does not support transactions")
+	}
+
+	err := ts.Multi(req)
+	keys := make([]string, 0, len(req.Operations))
+	for _, o := range req.Operations {
+		if r, ok := o.Request.(interface{ GetKey() string }); ok {
+			keys = append(keys, r.GetKey())
+		}
+	}
+	s.invalidate(keys...)
+
+	return err
+}
+
+// Close stops receiving invalidations and closes the wrapped store.
+func (s *Store) Close() error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	if closer, ok := s.Store.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// invalidate evicts the keys from the cache of all the replicas.
+// Keys are invalidated even if the operation failed, as it may have been partially applied.
+func (s *Store) invalidate(keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	s.evict(keys)
+
+	if s.topic == "" {
+		return
+	}
+	data, err := json.Marshal(invalidation{Source: s.replicaID, Keys: keys})
+	if err != nil {
+		s.logger.Errorf("near cache error: failed to encode invalidation: %v", err)
+		return
+	}
+	// The write succeeded, so a failure here only means the other replicas serve stale values until they expire
+	err = s.ps.Publish(&pubsub.PublishRequest{Topic: s.topic, Data: data})
+	if err != nil {
+		s.logger.Warnf("near cache: failed to publish invalidation of %d keys: %v", len(keys), err)
+	}
+}
+
+func (s *Store) onInvalidation(_ context.Context, msg *pubsub.NewMessage) error {
+	var inv invalidation
+	if err := json.Unmarshal(msg.Data, &inv); err != nil {
+		// Retrying would not help
+		s.logger.Warnf("near cache: ignoring invalid invalidation message: %v", err)
+		return nil
+	}
+	if inv.Source == s.replicaID {
+		return nil
+	}
+
+	s.evict(inv.Keys)
+
+	return nil
+}
+
+// evict removes the keys from the cache, and prevents the values being read from the store from being cached.
+func (s *Store) evict(keys []string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, k := range keys {
+		if p, ok := s.pending[k]; ok {
+			p.version++
+		}
+		s.cache.Delete(k)
+	}
+}
+
+func cacheable(req *state.GetRequest) bool {
+	return req.Options.Consistency != state.Strong && len(req.Metadata) == 0
+}
+
+func copyResponse(res *state.GetResponse) *state.GetResponse {
+	c := *res
+	if res.Data != nil {
+		c.Data = make([]byte, len(res.Data))
+		copy(c.Data, res.Data)
+	}
+
+	return &c
+}
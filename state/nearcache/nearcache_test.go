@@ -0,0 +1,144 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nearcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/pubsub"
+	pubsubInMemory "github.com/dapr/components-contrib/pubsub/in-memory"
+	"github.com/dapr/components-contrib/state"
+	stateInMemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/kit/logger"
+)
+
+func getValue(t *testing.T, s state.Store, key string, md map[string]string) string {
+	t.Helper()
+
+	res, err := s.Get(&state.GetRequest{Key: key, Metadata: md})
+	require.NoError(t, err)
+
+	return string(res.Data)
+}
+
+func TestNearCache(t *testing.T) {
+	log := logger.NewLogger("test")
+	inner := stateInMemory.NewInMemoryStateStore(log)
+	ps := pubsubInMemory.New(log)
+	require.NoError(t, ps.Init(pubsub.Metadata{}))
+
+	md := state.Metadata{Base: metadata.Base{Properties: map[string]string{
+		TTLKey:   "1m",
+		TopicKey: "invalidations",
+	}}}
+	replicaA := New(inner, ps, log)
+	require.NoError(t, replicaA.Init(md))
+	defer replicaA.Close()
+	replicaB := New(inner, ps, log)
+	require.NoError(t, replicaB.Init(md))
+
+	require.NoError(t, replicaA.Set(&state.SetRequest{Key: "k", Value: "v1"}))
+	assert.Equal(t, `"v1"`, getValue(t, replicaA, "k", nil))
+
+	t.Run("reads are served from the cache", func(t *testing.T) {
+		require.NoError(t, inner.Set(&state.SetRequest{Key: "k", Value: "v2"}))
+
+		assert.Equal(t, `"v1"`, getValue(t, replicaA, "k", nil))
+	})
+
+	t.Run("reads with metadata bypass the cache", func(t *testing.T) {
+		assert.Equal(t, `"v2"`, getValue(t, replicaA, "k", map[string]string{"partitionKey": "p"}))
+	})
+
+	t.Run("writes of other replicas invalidate the cache", func(t *testing.T) {
+		require.NoError(t, replicaB.Set(&state.SetRequest{Key: "k", Value: "v3"}))
+
+		assert.Eventually(t, func() bool {
+			return getValue(t, replicaA, "k", nil) == `"v3"`
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("deletes invalidate the cache", func(t *testing.T) {
+		require.NoError(t, replicaA.Delete(&state.DeleteRequest{Key: "k"}))
+
+		assert.Equal(t, "", getValue(t, replicaA, "k", nil))
+	})
+}
+
+// slowStore blocks the reads until released, after signaling they are in progress.
+type slowStore struct {
+	state.Store
+
+	reading chan struct{}
+	release chan struct{}
+}
+
+func (s *slowStore) Get(req *state.GetRequest) (*state.GetResponse, error) {
+	res, err := s.Store.Get(req)
+	s.reading <- struct{}{}
+	<-s.release
+
+	return res, err
+}
+
+func TestConcurrentInvalidation(t *testing.T) {
+	log := logger.NewLogger("test")
+	inner := stateInMemory.NewInMemoryStateStore(log)
+	slow := &slowStore{Store: inner, reading: make(chan struct{}), release: make(chan struct{})}
+	s := New(slow, nil, log)
+	require.NoError(t, s.Init(state.Metadata{}))
+	defer s.Close()
+	require.NoError(t, inner.Set(&state.SetRequest{Key: "k", Value: "v1"}))
+
+	// The value is read from the store before the write, and returned after its invalidation
+	read := make(chan string)
+	go func() {
+		read <- getValue(t, s, "k", nil)
+	}()
+	<-slow.reading
+	require.NoError(t, s.Set(&state.SetRequest{Key: "k", Value: "v2"}))
+	slow.release <- struct{}{}
+	assert.Equal(t, `"v1"`, <-read)
+
+	// The stale value was not cached
+	go func() {
+		<-slow.reading
+		slow.release <- struct{}{}
+	}()
+	assert.Equal(t, `"v2"`, getValue(t, s, "k", nil))
+	assert.Empty(t, s.pending)
+}
+
+func TestInitErrors(t *testing.T) {
+	log := logger.NewLogger("test")
+
+	t.Run("invalid ttl", func(t *testing.T) {
+		s := New(stateInMemory.NewInMemoryStateStore(log), nil, log)
+		err := s.Init(state.Metadata{Base: metadata.Base{Properties: map[string]string{TTLKey: "soon"}}})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("topic without pubsub", func(t *testing.T) {
+		s := New(stateInMemory.NewInMemoryStateStore(log), nil, log)
+		err := s.Init(state.Metadata{Base: metadata.Base{Properties: map[string]string{TopicKey: "invalidations"}}})
+
+		assert.Error(t, err)
+	})
+}
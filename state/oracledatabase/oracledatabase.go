@@ -51,6 +51,13 @@ func (o *OracleDatabase) Init(metadata state.Metadata) error {
 	return o.dbaccess.Init(metadata)
 }
 
+// SetExpiryEventsPublishFn sets the function publishing the events of the expired keys removed.
+func (o *OracleDatabase) SetExpiryEventsPublishFn(publish state.PublishFn) {
+	if notifier, ok := o.dbaccess.(state.ExpiryEventsNotifier); ok {
+		notifier.SetExpiryEventsPublishFn(publish)
+	}
+}
+
 func (o *OracleDatabase) Ping() error {
 	return o.dbaccess.Ping()
 }
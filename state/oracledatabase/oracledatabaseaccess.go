@@ -14,12 +14,14 @@ limitations under the License.
 package oracledatabase
 
 import (
+	"context"
 	"database/sql"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -38,6 +40,11 @@ const (
 	metadataTTLKey             = "ttlInSeconds"
 	errMissingConnectionString = "missing connection string"
 	tableName                  = "state"
+
+	// Interval and maximum number of keys of the removal of the expired keys, which only runs if the key expired
+	// events are published.
+	expiredCleanupInterval  = 10 * time.Second
+	expiredCleanupBatchSize = 1000
 )
 
 // oracleDatabaseAccess implements dbaccess.
@@ -47,6 +54,10 @@ type oracleDatabaseAccess struct {
 	db               *sql.DB
 	connectionString string
 	tx               *sql.Tx
+	expiryEvents     *state.ExpiryEventsPublisher
+
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 type oracleDatabaseMetadata struct {
@@ -59,8 +70,13 @@ type oracleDatabaseMetadata struct {
 func newOracleDatabaseAccess(logger logger.Logger) *oracleDatabaseAccess {
 	logger.Debug("Instantiating new Oracle Database state store")
 
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &oracleDatabaseAccess{
-		logger: logger,
+		logger:       logger,
+		expiryEvents: state.NewExpiryEventsPublisher(logger),
+		ctx:          ctx,
+		cancel:       cancel,
 	}
 }
 
@@ -111,6 +127,72 @@ func (o *oracleDatabaseAccess) Init(metadata state.Metadata) error {
 		return err
 	}
 
+	// The expired keys are otherwise only filtered out when read
+	o.expiryEvents.Init(metadata.Properties)
+	if metadata.Properties[state.ExpiryEventsTopicKey] != "" {
+		go o.startCleanup()
+	}
+
+	return nil
+}
+
+// SetExpiryEventsPublishFn sets the function publishing the events of the expired keys removed.
+func (o *oracleDatabaseAccess) SetExpiryEventsPublishFn(publish state.PublishFn) {
+	o.expiryEvents.SetExpiryEventsPublishFn(publish)
+}
+
+func (o *oracleDatabaseAccess) startCleanup() {
+	t := time.NewTicker(expiredCleanupInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := o.cleanupExpired(); err != nil {
+				o.logger.Warnf("Failed to remove the expired keys: %v", err)
+			}
+		case <-o.ctx.Done():
+			return
+		}
+	}
+}
+
+// cleanupExpired removes the expired keys, and publishes their events.
+// Each key is removed only if it is still expired, as it can be updated concurrently.
+func (o *oracleDatabaseAccess) cleanupExpired() error {
+	if !o.expiryEvents.Enabled() {
+		return nil
+	}
+
+	rows, err := o.db.QueryContext(o.ctx, fmt.Sprintf("SELECT key FROM %s WHERE expiration_time < SYSTIMESTAMP FETCH FIRST %d ROWS ONLY", tableName, expiredCleanupBatchSize))
+	if err != nil {
+		return err
+	}
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err = rows.Scan(&key); err != nil {
+			rows.Close()
+			return err
+		}
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	expired := make([]string, 0, len(keys))
+	for _, key := range keys {
+		res, err := o.db.ExecContext(o.ctx, fmt.Sprintf("DELETE FROM %s WHERE key = :key AND expiration_time < SYSTIMESTAMP", tableName), key)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 1 {
+			expired = append(expired, key)
+		}
+	}
+	o.expiryEvents.Publish(expired, time.Now())
+
 	return nil
 }
 
@@ -347,6 +429,7 @@ func (o *oracleDatabaseAccess) ExecuteMulti(sets []state.SetRequest, deletes []s
 
 // Close implements io.Closer.
 func (o *oracleDatabaseAccess) Close() error {
+	o.cancel()
 	if o.db != nil {
 		return o.db.Close()
 	}
@@ -0,0 +1,68 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oracledatabase
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/pubsub"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+)
+
+func TestCleanupExpired(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	o := newOracleDatabaseAccess(logger.NewLogger("test"))
+	o.db = db
+	defer o.Close()
+
+	t.Run("nothing is removed without expiry events", func(t *testing.T) {
+		require.NoError(t, o.cleanupExpired())
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	var published []state.KeyExpiredEvent
+	o.expiryEvents.Init(map[string]string{state.ExpiryEventsPubsubKey: "pubsub", state.ExpiryEventsTopicKey: "expired"})
+	o.SetExpiryEventsPublishFn(func(req *pubsub.PublishRequest) error {
+		var event state.KeyExpiredEvent
+		require.NoError(t, json.Unmarshal(req.Data, &event))
+		assert.Equal(t, "expired", req.Topic)
+		published = append(published, event)
+		return nil
+	})
+
+	t.Run("the keys still expired are removed and published", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT key FROM state WHERE expiration_time < SYSTIMESTAMP")).
+			WillReturnRows(sqlmock.NewRows([]string{"key"}).AddRow("app||order-1").AddRow("app||order-2"))
+		deleteQuery := regexp.QuoteMeta("DELETE FROM state WHERE key = :key AND expiration_time < SYSTIMESTAMP")
+		mock.ExpectExec(deleteQuery).WithArgs("app||order-1").WillReturnResult(sqlmock.NewResult(0, 1))
+		// Updated with a new TTL since it was selected
+		mock.ExpectExec(deleteQuery).WithArgs("app||order-2").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		require.NoError(t, o.cleanupExpired())
+
+		require.NoError(t, mock.ExpectationsWereMet())
+		require.Len(t, published, 1)
+		assert.Equal(t, "app||order-1", published[0].Key)
+	})
+}
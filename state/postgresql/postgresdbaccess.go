@@ -22,15 +22,17 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/dapr/components-contrib/internal/authentication/clientcert"
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/components-contrib/state/query"
 	"github.com/dapr/components-contrib/state/utils"
 	"github.com/dapr/kit/logger"
 	"github.com/dapr/kit/ptr"
-
-	// Blank import for the underlying PostgreSQL driver.
-	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
 const (
@@ -46,6 +48,8 @@ type postgresDBAccess struct {
 	db               *sql.DB
 	connectionString string
 	tableName        string
+	// Source of the client certificates, if configured
+	clientCert *clientcert.Source
 }
 
 // newPostgresDBAccess creates a new instance of postgresAccess.
@@ -82,7 +86,12 @@ func (p *postgresDBAccess) Init(meta state.Metadata) error {
 	}
 	p.connectionString = m.ConnectionString
 
-	db, err := sql.Open("pgx", p.connectionString)
+	p.clientCert, err = clientcert.New(meta.Properties, p.logger)
+	if err != nil {
+		return err
+	}
+
+	db, err := p.openDB()
 	if err != nil {
 		p.logger.Error(err)
 
@@ -110,6 +119,44 @@ func (p *postgresDBAccess) Init(meta state.Metadata) error {
 	return nil
 }
 
+// openDB opens the database, with the client certificates of the source if configured.
+func (p *postgresDBAccess) openDB() (*sql.DB, error) {
+	if p.clientCert == nil {
+		return sql.Open("pgx", p.connectionString)
+	}
+
+	config, err := p.clientCertConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return stdlib.OpenDB(*config), nil
+}
+
+// clientCertConfig returns the config of the connection string, presenting the client certificates of the source.
+func (p *postgresDBAccess) clientCertConfig() (*pgx.ConnConfig, error) {
+	config, err := pgx.ParseConfig(p.connectionString)
+	if err != nil {
+		return nil, err
+	}
+	if config.TLSConfig == nil {
+		return nil, errors.New("the client certificate source requires TLS, sslmode must not be disable")
+	}
+	config.TLSConfig = p.clientCert.TLSConfig(config.TLSConfig)
+	// The connection fails rather than falling back to a connection without TLS, as with sslmode=prefer
+	fallbacks := make([]*pgconn.FallbackConfig, 0, len(config.Fallbacks))
+	for _, fallback := range config.Fallbacks {
+		if fallback.TLSConfig == nil {
+			continue
+		}
+		fallback.TLSConfig = p.clientCert.TLSConfig(fallback.TLSConfig)
+		fallbacks = append(fallbacks, fallback)
+	}
+	config.Fallbacks = fallbacks
+
+	return config, nil
+}
+
 // Set makes an insert or update to the database.
 func (p *postgresDBAccess) Set(req *state.SetRequest) error {
 	p.logger.Debug("Setting state value in PostgreSQL")
@@ -396,6 +443,9 @@ func (p *postgresDBAccess) Query(req *state.QueryRequest) (*state.QueryResponse,
 
 // Close implements io.Close.
 func (p *postgresDBAccess) Close() error {
+	if p.clientCert != nil {
+		p.clientCert.Close()
+	}
 	if p.db != nil {
 		return p.db.Close()
 	}
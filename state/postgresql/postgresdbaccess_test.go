@@ -21,6 +21,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/dapr/components-contrib/internal/authentication/clientcert"
 	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/kit/logger"
 )
@@ -436,6 +437,28 @@ func createSetRequest() state.SetRequest {
 	}
 }
 
+func TestClientCertConfig(t *testing.T) {
+	newDBAccess := func(connectionString string) *postgresDBAccess {
+		return &postgresDBAccess{connectionString: connectionString, clientCert: &clientcert.Source{}}
+	}
+
+	t.Run("no fallback without TLS", func(t *testing.T) {
+		config, err := newDBAccess("host=localhost sslmode=prefer").clientCertConfig()
+
+		assert.NoError(t, err)
+		assert.NotNil(t, config.TLSConfig.GetClientCertificate)
+		assert.Empty(t, config.Fallbacks)
+	})
+
+	t.Run("TLS required", func(t *testing.T) {
+		for _, sslmode := range []string{"disable", "allow"} {
+			_, err := newDBAccess("host=localhost sslmode=" + sslmode).clientCertConfig()
+
+			assert.ErrorContains(t, err, "requires TLS", sslmode)
+		}
+	})
+}
+
 func createDeleteRequest() state.DeleteRequest {
 	return state.DeleteRequest{
 		Key: randomKey(),
@@ -98,6 +98,7 @@ type StateStore struct {
 	metadata       rediscomponent.Metadata
 	replicas       int
 	querySchemas   querySchemas
+	cache          *clientSideCache
 
 	features []state.Feature
 	logger   logger.Logger
@@ -159,6 +160,12 @@ func (r *StateStore) Init(metadata state.Metadata) error {
 		return fmt.Errorf("redis store: error registering query schemas: %v", err)
 	}
 
+	if m.ClientSideCaching {
+		if r.cache, err = newClientSideCache(r.ctx, r.client, m.ClientSideCacheMaxKeys, r.logger); err != nil {
+			return fmt.Errorf("redis store: error initializing client-side cache: %v", err)
+		}
+	}
+
 	return nil
 }
 
@@ -215,6 +222,7 @@ func (r *StateStore) Delete(req *state.DeleteRequest) error {
 		delQuery = delDefaultQuery
 	}
 	_, err = r.client.Do(r.ctx, "EVAL", delQuery, 1, req.Key, *req.ETag).Result()
+	r.invalidateCache(req.Key)
 	if err != nil {
 		return state.NewETagError(state.ETagMismatch, err)
 	}
@@ -222,8 +230,8 @@ func (r *StateStore) Delete(req *state.DeleteRequest) error {
 	return nil
 }
 
-func (r *StateStore) directGet(req *state.GetRequest) (*state.GetResponse, error) {
-	res, err := r.client.Do(r.ctx, "GET", req.Key).Result()
+func (r *StateStore) directGet(client redis.UniversalClient, req *state.GetRequest) (*state.GetResponse, error) {
+	res, err := client.Do(r.ctx, "GET", req.Key).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -239,10 +247,10 @@ func (r *StateStore) directGet(req *state.GetRequest) (*state.GetResponse, error
 	}, nil
 }
 
-func (r *StateStore) getDefault(req *state.GetRequest) (*state.GetResponse, error) {
-	res, err := r.client.Do(r.ctx, "HGETALL", req.Key).Result() // Prefer values with ETags
+func (r *StateStore) getDefault(client redis.UniversalClient, req *state.GetRequest) (*state.GetResponse, error) {
+	res, err := client.Do(r.ctx, "HGETALL", req.Key).Result() // Prefer values with ETags
 	if err != nil {
-		return r.directGet(req) // Falls back to original get for backward compats.
+		return r.directGet(client, req) // Falls back to original get for backward compats.
 	}
 	if res == nil {
 		return &state.GetResponse{}, nil
@@ -306,7 +314,13 @@ func (r *StateStore) Get(req *state.GetRequest) (*state.GetResponse, error) {
 		return r.getJSON(req)
 	}
 
-	return r.getDefault(req)
+	if r.cache != nil {
+		return r.cache.get(req.Key, func(client redis.UniversalClient) (*state.GetResponse, error) {
+			return r.getDefault(client, req)
+		})
+	}
+
+	return r.getDefault(r.client, req)
 }
 
 type jsonEntry struct {
@@ -349,6 +363,7 @@ func (r *StateStore) Set(req *state.SetRequest) error {
 	}
 
 	err = r.client.Do(r.ctx, "EVAL", setQuery, 1, req.Key, ver, bt, firstWrite).Err()
+	r.invalidateCache(req.Key)
 	if err != nil {
 		if req.ETag != nil {
 			return state.NewETagError(state.ETagMismatch, err)
@@ -395,9 +410,11 @@ func (r *StateStore) Multi(request *state.TransactionalStateRequest) error {
 	}
 
 	pipe := r.client.TxPipeline()
+	keys := make([]string, 0, len(request.Operations))
 	for _, o := range request.Operations {
 		if o.Operation == state.Upsert {
 			req := o.Request.(state.SetRequest)
+			keys = append(keys, req.Key)
 			ver, err := r.parseETag(&req)
 			if err != nil {
 				return err
@@ -430,14 +447,23 @@ func (r *StateStore) Multi(request *state.TransactionalStateRequest) error {
 				req.ETag = &etag
 			}
 			pipe.Do(r.ctx, "EVAL", delQuery, 1, req.Key, *req.ETag)
+			keys = append(keys, req.Key)
 		}
 	}
 
 	_, err := pipe.Exec(r.ctx)
+	r.invalidateCache(keys...)
 
 	return err
 }
 
+// invalidateCache removes keys from the client-side cache, if enabled, so that this store reads its own writes.
+func (r *StateStore) invalidateCache(keys ...string) {
+	if r.cache != nil {
+		r.cache.invalidate(keys...)
+	}
+}
+
 func (r *StateStore) registerSchemas() error {
 	for name, elem := range r.querySchemas {
 		r.logger.Infof("redis: create query index %s", name)
@@ -536,6 +562,10 @@ func (r *StateStore) Query(req *state.QueryRequest) (*state.QueryResponse, error
 func (r *StateStore) Close() error {
 	r.cancel()
 
+	if r.cache != nil {
+		r.cache.close()
+	}
+
 	return r.client.Close()
 }
 
@@ -0,0 +1,320 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+)
+
+const (
+	// Channel on which Redis publishes the invalidation of tracked keys to the redirect connection.
+	invalidationChannel = "__redis__:invalidate"
+	// Delay before checking the invalidation connection again after an error.
+	invalidationRetryDelay = time.Second
+)
+
+// clientSideCache serves the state entries read recently from memory, using the server-assisted client-side caching of Redis.
+// The entries are read through connections with CLIENT TRACKING enabled, which redirect the invalidation of the keys they read
+// to a connection subscribed to the invalidation channel. The entries are not cached while that subscription is interrupted.
+type clientSideCache struct {
+	entries *cacheEntries
+	client  *redis.Client
+	logger  logger.Logger
+
+	// Connection receiving the invalidations, and its client ID
+	subClient *redis.Client
+	sub       *redis.PubSub
+	subID     int64
+
+	// Connections tracking the keys they read, with invalidations redirected to readID
+	readClient *redis.Client
+	readID     int64
+	lock       sync.RWMutex
+}
+
+func newClientSideCache(ctx context.Context, client redis.UniversalClient, maxKeys int, logger logger.Logger) (*clientSideCache, error) {
+	c, ok := client.(*redis.Client)
+	if !ok {
+		return nil, fmt.Errorf("client-side caching is not supported by Redis Cluster")
+	}
+
+	cache := &clientSideCache{
+		entries: newCacheEntries(maxKeys),
+		client:  c,
+		logger:  logger,
+	}
+
+	subOpts := *c.Options()
+	subOpts.OnConnect = func(ctx context.Context, cn *redis.Conn) error {
+		id, err := cn.ClientID(ctx).Result()
+		if err != nil {
+			return err
+		}
+		atomic.StoreInt64(&cache.subID, id)
+
+		return nil
+	}
+	cache.subClient = redis.NewClient(&subOpts)
+	cache.sub = cache.subClient.Subscribe(ctx, invalidationChannel)
+	if _, err := cache.sub.Receive(ctx); err != nil {
+		cache.close()
+
+		return nil, fmt.Errorf("error subscribing to invalidations: %w", err)
+	}
+	if err := cache.enable(ctx); err != nil {
+		cache.close()
+
+		return nil, err
+	}
+
+	go cache.receiveInvalidations(ctx)
+
+	return cache, nil
+}
+
+// get returns the state of a key from the cache or, on a miss, from fetch, which reads it with the given client.
+func (c *clientSideCache) get(key string, fetch func(client redis.UniversalClient) (*state.GetResponse, error)) (*state.GetResponse, error) {
+	if res, ok := c.entries.get(key); ok {
+		return res, nil
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	epoch, ok := c.entries.begin(key)
+	if !ok {
+		return fetch(c.client)
+	}
+	res, err := fetch(c.readClient)
+	if err != nil {
+		c.entries.end(key, epoch, nil)
+
+		return nil, err
+	}
+	c.entries.end(key, epoch, res)
+
+	return res, nil
+}
+
+// invalidate removes keys modified by this store, without waiting for their invalidation by Redis.
+func (c *clientSideCache) invalidate(keys ...string) {
+	c.entries.invalidate(keys...)
+}
+
+// enable starts caching the entries, reading them with connections which redirect invalidations to the current subscription.
+func (c *clientSideCache) enable(ctx context.Context) error {
+	id := atomic.LoadInt64(&c.subID)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.readClient == nil || c.readID != id {
+		readOpts := *c.client.Options()
+		readOpts.OnConnect = func(ctx context.Context, cn *redis.Conn) error {
+			return cn.Process(ctx, redis.NewStatusCmd(ctx, "CLIENT", "TRACKING", "on", "REDIRECT", id))
+		}
+		readClient := redis.NewClient(&readOpts)
+		if err := readClient.Ping(ctx).Err(); err != nil {
+			readClient.Close()
+
+			return fmt.Errorf("error enabling client tracking: %w", err)
+		}
+		if c.readClient != nil {
+			c.readClient.Close()
+		}
+		c.readClient, c.readID = readClient, id
+	}
+	c.entries.reset(true)
+
+	return nil
+}
+
+// receiveInvalidations removes the invalidated keys from the cache until ctx is canceled.
+func (c *clientSideCache) receiveInvalidations(ctx context.Context) {
+	for {
+		msg, err := c.sub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			// Invalidations may have been lost: the cache is enabled again once the subscription is confirmed to be active,
+			// by the subscription of a new connection or by the reply to a ping on the current one
+			c.logger.Warnf("redis store: client-side cache disabled: error receiving invalidations: %s", err)
+			c.entries.reset(false)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(invalidationRetryDelay):
+			}
+			_ = c.sub.Ping(ctx)
+
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription, *redis.Pong:
+			if err = c.enable(ctx); err != nil {
+				c.logger.Warnf("redis store: client-side cache disabled: %s", err)
+			}
+		case *redis.Message:
+			if len(m.PayloadSlice) > 0 {
+				c.entries.invalidate(m.PayloadSlice...)
+			} else {
+				c.entries.invalidate(m.Payload)
+			}
+		}
+	}
+}
+
+func (c *clientSideCache) close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.entries.reset(false)
+	if c.readClient != nil {
+		c.readClient.Close()
+	}
+	c.sub.Close()
+
+	return c.subClient.Close()
+}
+
+// cacheEntries is a LRU cache of state entries.
+type cacheEntries struct {
+	maxKeys  int
+	enabled  bool
+	entries  map[string]*list.Element
+	lru      *list.List
+	inflight map[string]*cacheFill
+	lock     sync.Mutex
+}
+
+type cacheEntry struct {
+	key string
+	res *state.GetResponse
+}
+
+// cacheFill tracks the reads of a key in progress, so that a value invalidated while it is being read is not cached.
+type cacheFill struct {
+	readers int
+	epoch   uint64
+}
+
+func newCacheEntries(maxKeys int) *cacheEntries {
+	return &cacheEntries{
+		maxKeys:  maxKeys,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+		inflight: make(map[string]*cacheFill),
+	}
+}
+
+func (e *cacheEntries) get(key string) (*state.GetResponse, bool) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	el, ok := e.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e.lru.MoveToFront(el)
+	res := *el.Value.(*cacheEntry).res
+
+	return &res, true
+}
+
+// begin registers a read of key, and returns false if the cache is disabled.
+func (e *cacheEntries) begin(key string) (uint64, bool) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if !e.enabled {
+		return 0, false
+	}
+	fill, ok := e.inflight[key]
+	if !ok {
+		fill = &cacheFill{}
+		e.inflight[key] = fill
+	}
+	fill.readers++
+
+	return fill.epoch, true
+}
+
+// end completes a read of key started at epoch, and caches its result unless the key was invalidated in the meantime.
+func (e *cacheEntries) end(key string, epoch uint64, res *state.GetResponse) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	fill := e.inflight[key]
+	fill.readers--
+	if fill.readers == 0 {
+		delete(e.inflight, key)
+	}
+	if res == nil || !e.enabled || fill.epoch != epoch {
+		return
+	}
+
+	if el, ok := e.entries[key]; ok {
+		el.Value.(*cacheEntry).res = res
+		e.lru.MoveToFront(el)
+
+		return
+	}
+	e.entries[key] = e.lru.PushFront(&cacheEntry{key: key, res: res})
+	if e.lru.Len() > e.maxKeys {
+		oldest := e.lru.Back()
+		e.lru.Remove(oldest)
+		delete(e.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func (e *cacheEntries) invalidate(keys ...string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	for _, key := range keys {
+		if el, ok := e.entries[key]; ok {
+			e.lru.Remove(el)
+			delete(e.entries, key)
+		}
+		if fill, ok := e.inflight[key]; ok {
+			fill.epoch++
+		}
+	}
+}
+
+// reset removes all the entries, and enables or disables the cache.
+func (e *cacheEntries) reset(enabled bool) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.enabled = enabled
+	e.entries = make(map[string]*list.Element)
+	e.lru.Init()
+	for _, fill := range e.inflight {
+		fill.epoch++
+	}
+}
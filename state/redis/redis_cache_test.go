@@ -0,0 +1,170 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"context"
+	"testing"
+
+	redis "github.com/go-redis/redis/v8"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/logger"
+	"github.com/dapr/kit/ptr"
+)
+
+func TestCacheEntries(t *testing.T) {
+	res := &state.GetResponse{Data: []byte("value"), ETag: ptr.Of("1")}
+
+	t.Run("caches the value read", func(t *testing.T) {
+		e := newCacheEntries(10)
+		e.reset(true)
+
+		epoch, ok := e.begin("key")
+		assert.True(t, ok)
+		e.end("key", epoch, res)
+
+		cached, ok := e.get("key")
+		assert.True(t, ok)
+		assert.Equal(t, res, cached)
+		assert.Empty(t, e.inflight)
+	})
+
+	t.Run("does not cache a value invalidated while read", func(t *testing.T) {
+		e := newCacheEntries(10)
+		e.reset(true)
+
+		epoch, _ := e.begin("key")
+		e.invalidate("key")
+		e.end("key", epoch, res)
+
+		_, ok := e.get("key")
+		assert.False(t, ok)
+	})
+
+	t.Run("invalidation removes the value", func(t *testing.T) {
+		e := newCacheEntries(10)
+		e.reset(true)
+		epoch, _ := e.begin("key")
+		e.end("key", epoch, res)
+
+		e.invalidate("other", "key")
+
+		_, ok := e.get("key")
+		assert.False(t, ok)
+	})
+
+	t.Run("evicts the least recently used value", func(t *testing.T) {
+		e := newCacheEntries(2)
+		e.reset(true)
+		for _, key := range []string{"a", "b"} {
+			epoch, _ := e.begin(key)
+			e.end(key, epoch, res)
+		}
+		e.get("a")
+
+		epoch, _ := e.begin("c")
+		e.end("c", epoch, res)
+
+		_, ok := e.get("b")
+		assert.False(t, ok)
+		_, ok = e.get("a")
+		assert.True(t, ok)
+		_, ok = e.get("c")
+		assert.True(t, ok)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		e := newCacheEntries(10)
+		e.reset(true)
+		epoch, _ := e.begin("key")
+		e.reset(false)
+		e.end("key", epoch, res)
+
+		_, ok := e.begin("key")
+		assert.False(t, ok)
+		_, ok = e.get("key")
+		assert.False(t, ok)
+	})
+}
+
+func TestClientSideCache(t *testing.T) {
+	s, c := setupMiniredis()
+	defer s.Close()
+
+	ss := &StateStore{
+		client: c,
+		json:   jsoniter.ConfigFastest,
+		logger: logger.NewLogger("test"),
+		cache: &clientSideCache{
+			entries:    newCacheEntries(10),
+			client:     c,
+			readClient: c,
+		},
+	}
+	ss.ctx, ss.cancel = context.WithCancel(context.Background())
+	ss.cache.entries.reset(true)
+
+	err := ss.Set(&state.SetRequest{Key: "weapon", Value: "deathstar"})
+	assert.NoError(t, err)
+	res, err := ss.Get(&state.GetRequest{Key: "weapon"})
+	assert.NoError(t, err)
+	assert.Equal(t, `"deathstar"`, string(res.Data))
+
+	t.Run("reads from the cache", func(t *testing.T) {
+		s.HSet("weapon", "data", `"lightsaber"`)
+
+		res, err := ss.Get(&state.GetRequest{Key: "weapon"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, `"deathstar"`, string(res.Data))
+	})
+
+	t.Run("reads its own writes", func(t *testing.T) {
+		err := ss.Set(&state.SetRequest{Key: "weapon", Value: "blaster", ETag: res.ETag})
+		assert.NoError(t, err)
+
+		res, err := ss.Get(&state.GetRequest{Key: "weapon"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, `"blaster"`, string(res.Data))
+		assert.Equal(t, ptr.Of("2"), res.ETag)
+	})
+
+	t.Run("invalidated by transactions", func(t *testing.T) {
+		err := ss.Multi(&state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{{
+				Operation: state.Delete,
+				Request:   state.DeleteRequest{Key: "weapon"},
+			}},
+		})
+		assert.NoError(t, err)
+
+		res, err := ss.Get(&state.GetRequest{Key: "weapon"})
+
+		assert.NoError(t, err)
+		assert.Nil(t, res.Data)
+	})
+}
+
+func TestNewClientSideCacheCluster(t *testing.T) {
+	client := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{"127.0.0.1:6379"}})
+	defer client.Close()
+
+	_, err := newClientSideCache(context.Background(), client, 10, logger.NewLogger("test"))
+
+	assert.Error(t, err)
+}
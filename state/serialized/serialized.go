@@ -0,0 +1,185 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package serialized provides a decorator for state stores that stores the values in the format of the serializer
+// selected by the "serializer" metadata property, and returns them as JSON.
+package serialized
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/dapr/components-contrib/contenttype"
+	"github.com/dapr/components-contrib/serializer"
+	"github.com/dapr/components-contrib/state"
+	"github.com/dapr/kit/ptr"
+)
+
+// Store is a state store that serializes the values written to another store, and deserializes the values read.
+// Without a serializer configured, the values are passed as they are.
+type Store struct {
+	state.Store
+
+	serializer serializer.Serializer
+}
+
+// New returns a store serializing the values of store.
+func New(store state.Store) *Store {
+	return &Store{Store: store}
+}
+
+// Init creates the serializer and initializes the wrapped store.
+func (s *Store) Init(metadata state.Metadata) error {
+	var err error
+	s.serializer, err = serializer.FromMetadata(metadata.Properties)
+	if err != nil {
+		return err
+	}
+
+	return s.Store.Init(metadata)
+}
+
+// Get reads the value from the store and deserializes it.
+func (s *Store) Get(req *state.GetRequest) (*state.GetResponse, error) {
+	res, err := s.Store.Get(req)
+	if err != nil || res == nil || res.Data == nil || s.serializer == nil {
+		return res, err
+	}
+
+	res.Data, err = s.serializer.Unmarshal(res.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to deserialize key %s: %w", req.Key, err)
+	}
+	res.ContentType = ptr.Of(contenttype.JSONContentType)
+
+	return res, nil
+}
+
+// BulkGet reads the values from the store and deserializes them.
+// The values which cannot be deserialized are returned with an error.
+func (s *Store) BulkGet(req []state.GetRequest) (bool, []state.BulkGetResponse, error) {
+	supported, res, err := s.Store.BulkGet(req)
+	if err != nil || !supported || s.serializer == nil {
+		return supported, res, err
+	}
+
+	for i := range res {
+		if res[i].Data == nil || res[i].Error != "" {
+			continue
+		}
+		data, err := s.serializer.Unmarshal(res[i].Data)
+		if err != nil {
+			res[i].Data = nil
+			res[i].Error = fmt.Sprintf("failed to deserialize key %s: %v", res[i].Key, err)
+			continue
+		}
+		res[i].Data = data
+		res[i].ContentType = ptr.Of(contenttype.JSONContentType)
+	}
+
+	return true, res, nil
+}
+
+// Set serializes the value and saves it in the store.
+func (s *Store) Set(req *state.SetRequest) error {
+	serialized, err := s.serialize(req)
+	if err != nil {
+		return err
+	}
+
+	return s.Store.Set(serialized)
+}
+
+// BulkSet serializes the values and saves them in the store.
+func (s *Store) BulkSet(req []state.SetRequest) error {
+	serialized := make([]state.SetRequest, len(req))
+	for i := range req {
+		r, err := s.serialize(&req[i])
+		if err != nil {
+			return err
+		}
+		serialized[i] = *r
+	}
+
+	return s.Store.BulkSet(serialized)
+}
+
+// Multi serializes the values of the upserts and runs the transaction on the store, if supported.
+func (s *Store) Multi(req *state.TransactionalStateRequest) error {
+	ts, ok := s.Store.(state.TransactionalStore)
+	if !ok {
+		return errors.New("serializer error: the state store does not support transactions")
+	}
+
+	serialized := &state.TransactionalStateRequest{
+		Operations: make([]state.TransactionalStateOperation, len(req.Operations)),
+		Metadata:   req.Metadata,
+	}
+	for i, o := range req.Operations {
+		serialized.Operations[i] = o
+		if o.Operation != state.Upsert {
+			continue
+		}
+		set, ok := o.Request.(state.SetRequest)
+		if !ok {
+			continue
+		}
+		r, err := s.serialize(&set)
+		if err != nil {
+			return err
+		}
+		serialized.Operations[i].Request = *r
+	}
+
+	return ts.Multi(serialized)
+}
+
+// Close closes the wrapped store.
+func (s *Store) Close() error {
+	if closer, ok := s.Store.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// serialize returns a copy of the request with its value serialized.
+// Values which are not bytes are encoded to JSON first.
+func (s *Store) serialize(req *state.SetRequest) (*state.SetRequest, error) {
+	if s.serializer == nil {
+		return req, nil
+	}
+
+	data, ok := req.Value.([]byte)
+	if !ok {
+		var err error
+		data, err = jsoniter.ConfigFastest.Marshal(req.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode key %s: %w", req.Key, err)
+		}
+	}
+
+	data, err := s.serializer.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize key %s: %w", req.Key, err)
+	}
+
+	serialized := *req
+	serialized.Value = data
+	serialized.ContentType = ptr.Of(s.serializer.ContentType())
+
+	return &serialized, nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2022 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serialized
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/components-contrib/metadata"
+	"github.com/dapr/components-contrib/serializer"
+	"github.com/dapr/components-contrib/state"
+	stateInMemory "github.com/dapr/components-contrib/state/in-memory"
+	"github.com/dapr/kit/logger"
+)
+
+func TestSerializedStore(t *testing.T) {
+	log := logger.NewLogger("test")
+	inner := stateInMemory.NewInMemoryStateStore(log)
+	s := New(inner)
+	require.NoError(t, s.Init(state.Metadata{Base: metadata.Base{Properties: map[string]string{
+		serializer.MetadataKey: serializer.CBOR,
+	}}}))
+	defer s.Close()
+
+	t.Run("values are stored serialized", func(t *testing.T) {
+		require.NoError(t, s.Set(&state.SetRequest{Key: "k", Value: map[string]int{"a": 1}}))
+
+		raw, err := inner.Get(&state.GetRequest{Key: "k"})
+		require.NoError(t, err)
+		assert.Equal(t, "a1616101", hex.EncodeToString(raw.Data))
+
+		res, err := s.Get(&state.GetRequest{Key: "k"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"a": 1}`, string(res.Data))
+		assert.Equal(t, "application/json", *res.ContentType)
+	})
+
+	t.Run("bulk set", func(t *testing.T) {
+		require.NoError(t, s.BulkSet([]state.SetRequest{
+			{Key: "k1", Value: []byte(`"v1"`)},
+			{Key: "k2", Value: []byte(`"v2"`)},
+		}))
+
+		res, err := s.Get(&state.GetRequest{Key: "k2"})
+		require.NoError(t, err)
+		assert.Equal(t, `"v2"`, string(res.Data))
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		require.NoError(t, s.Multi(&state.TransactionalStateRequest{
+			Operations: []state.TransactionalStateOperation{
+				{Operation: state.Upsert, Request: state.SetRequest{Key: "k3", Value: []byte(`[1, 2]`)}},
+				{Operation: state.Delete, Request: state.DeleteRequest{Key: "k1"}},
+			},
+		}))
+
+		res, err := s.Get(&state.GetRequest{Key: "k3"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `[1, 2]`, string(res.Data))
+		res, err = s.Get(&state.GetRequest{Key: "k1"})
+		require.NoError(t, err)
+		assert.Nil(t, res.Data)
+	})
+
+	t.Run("values which are not JSON are rejected", func(t *testing.T) {
+		err := s.Set(&state.SetRequest{Key: "k", Value: []byte("not json")})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestWithoutSerializer(t *testing.T) {
+	inner := stateInMemory.NewInMemoryStateStore(logger.NewLogger("test"))
+	s := New(inner)
+	require.NoError(t, s.Init(state.Metadata{}))
+	defer s.Close()
+
+	require.NoError(t, s.Set(&state.SetRequest{Key: "k", Value: []byte("raw")}))
+
+	res, err := s.Get(&state.GetRequest{Key: "k"})
+	require.NoError(t, err)
+	assert.Equal(t, "raw", string(res.Data))
+}
+
+func TestUnknownSerializer(t *testing.T) {
+	s := New(stateInMemory.NewInMemoryStateStore(logger.NewLogger("test")))
+
+	err := s.Init(state.Metadata{Base: metadata.Base{Properties: map[string]string{
+		serializer.MetadataKey: "xml",
+	}}})
+
+	assert.Error(t, err)
+}
@@ -77,7 +77,7 @@ func (m *migration) newMigrationResult() migrationResult {
 func (m *migration) executeMigrations() (migrationResult, error) {
 	r := m.newMigrationResult()
 
-	db, err := sql.Open("sqlserver", m.store.connectionString)
+	db, err := m.store.openDB(m.store.connectionString)
 	if err != nil {
 		return r, err
 	}
@@ -99,7 +99,7 @@ func (m *migration) executeMigrations() (migrationResult, error) {
 
 		// Re connect with a database specific connection
 		m.store.connectionString = fmt.Sprintf("%s;database=%s;", m.store.connectionString, m.store.databaseName)
-		db, err = sql.Open("sqlserver", m.store.connectionString)
+		db, err = m.store.openDB(m.store.connectionString)
 		if err != nil {
 			return r, err
 		}
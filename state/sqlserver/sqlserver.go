@@ -14,15 +14,21 @@ limitations under the License.
 package sqlserver
 
 import (
+	"context"
 	"database/sql"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 	"unicode"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	mssql "github.com/denisenkom/go-mssqldb"
 
+	azauth "github.com/dapr/components-contrib/internal/authentication/azure"
 	"github.com/dapr/components-contrib/metadata"
 	"github.com/dapr/components-contrib/state"
 	"github.com/dapr/components-contrib/state/utils"
@@ -71,11 +77,14 @@ const (
 	keyColumnName        = "Key"
 	rowVersionColumnName = "RowVersion"
 	databaseNameKey      = "databaseName"
+	useAzureADKey        = "useAzureAD"
 
 	defaultKeyLength = 200
 	defaultSchema    = "dbo"
 	defaultDatabase  = "dapr"
 	defaultTable     = "state"
+
+	azureADTokenTimeout = 30 * time.Second
 )
 
 // NewSQLServerStateStore creates a new instance of a Sql Server transaction store.
@@ -107,6 +116,10 @@ type SQLServer struct {
 	indexedProperties []IndexedProperty
 	migratorFactory   func(*SQLServer) migrator
 
+	// Set when authenticating with Azure AD instead of a SQL login
+	tokenCredential azcore.TokenCredential
+	tokenScope      string
+
 	bulkDeleteCommand        string
 	itemRefTableTypeName     string
 	upsertCommand            string
@@ -127,6 +140,7 @@ type sqlServerMetadata struct {
 	KeyType           string
 	KeyLength         int
 	IndexedProperties string
+	UseAzureAD        bool
 }
 
 func isLetterOrNumber(c rune) bool {
@@ -183,7 +197,7 @@ func (s *SQLServer) Init(metadata state.Metadata) error {
 	s.deleteWithETagCommand = mr.deleteWithETagCommand
 	s.deleteWithoutETagCommand = mr.deleteWithoutETagCommand
 
-	s.db, err = sql.Open("sqlserver", s.connectionString)
+	s.db, err = s.openDB(s.connectionString)
 	if err != nil {
 		return err
 	}
@@ -191,6 +205,35 @@ func (s *SQLServer) Init(metadata state.Metadata) error {
 	return nil
 }
 
+// openDB opens a connection pool, authenticating with an Azure AD access token if configured.
+func (s *SQLServer) openDB(connectionString string) (*sql.DB, error) {
+	if s.tokenCredential == nil {
+		return sql.Open("sqlserver", connectionString)
+	}
+
+	connector, err := mssql.NewAccessTokenConnector(connectionString, s.getAccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return sql.OpenDB(connector), nil
+}
+
+// getAccessToken is invoked for each new connection; tokens are cached by the credential until they expire.
+func (s *SQLServer) getAccessToken() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), azureADTokenTimeout)
+	defer cancel()
+
+	token, err := s.tokenCredential.GetToken(ctx, policy.TokenRequestOptions{
+		Scopes: []string{s.tokenScope},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get Azure AD access token: %w", err)
+	}
+
+	return token.Token, nil
+}
+
 func (s *SQLServer) parseMetadata(meta map[string]string) error {
 	m := sqlServerMetadata{
 		TableName:    defaultTable,
@@ -205,8 +248,18 @@ func (s *SQLServer) parseMetadata(meta map[string]string) error {
 	if m.ConnectionString == "" {
 		return fmt.Errorf("missing connection string")
 	}
+	// The driver ignores the Always Encrypted settings, so encrypted columns would be read and written as ciphertext
+	if isColumnEncryptionEnabled(m.ConnectionString) {
+		return errors.New("always encrypted is not supported by the sql server driver")
+	}
 	s.connectionString = m.ConnectionString
 
+	if m.UseAzureAD {
+		if err := s.setAzureADCredential(meta); err != nil {
+			return err
+		}
+	}
+
 	if err := s.setTable(m.TableName); err != nil {
 		return err
 	}
@@ -230,6 +283,28 @@ func (s *SQLServer) parseMetadata(meta map[string]string) error {
 	return nil
 }
 
+// setAzureADCredential configures the credential used to get access tokens for Azure SQL.
+// The connection string must not contain a user and password.
+func (s *SQLServer) setAzureADCredential(meta map[string]string) error {
+	settings, err := azauth.NewEnvironmentSettings("sql", meta)
+	if err != nil {
+		return err
+	}
+	s.tokenCredential, err = settings.GetTokenCredential()
+	if err != nil {
+		return fmt.Errorf("failed to get Azure AD credential: %w", err)
+	}
+	s.tokenScope = strings.TrimSuffix(settings.Resource, "/") + "/.default"
+
+	return nil
+}
+
+func isColumnEncryptionEnabled(connectionString string) bool {
+	cs := strings.ToLower(connectionString)
+
+	return strings.Contains(cs, "columnencryption=true") || strings.Contains(cs, "column encryption setting=enabled")
+}
+
 // Returns validated index properties.
 func (s *SQLServer) setIndexedProperties(indexedPropertiesString string) error {
 	if indexedPropertiesString != "" {
@@ -314,6 +314,11 @@ func TestInvalidConfiguration(t *testing.T) {
 			props:       map[string]string{connectionStringKey: sampleConnectionString, tableNameKey: "test", databaseNameKey: "test GO DROP DATABASE dapr_test"},
 			expectedErr: "invalid database name",
 		},
+		{
+			name:        "Always Encrypted",
+			props:       map[string]string{connectionStringKey: sampleConnectionString + "columnencryption=true;", tableNameKey: "test"},
+			expectedErr: "always encrypted is not supported",
+		},
 		{
 			name:        "Invalid key type invalid",
 			props:       map[string]string{connectionStringKey: sampleConnectionString, tableNameKey: "test", keyTypeKey: "invalid"},
@@ -339,6 +344,25 @@ func TestInvalidConfiguration(t *testing.T) {
 	}
 }
 
+func TestAzureADConfiguration(t *testing.T) {
+	sqlStore := NewSQLServerStateStore(logger.NewLogger("test")).(*SQLServer)
+	sqlStore.migratorFactory = func(s *SQLServer) migrator {
+		return &mockMigrator{}
+	}
+
+	metadata := state.Metadata{
+		Base: metadata.Base{Properties: map[string]string{
+			connectionStringKey: "server=myserver.database.windows.net;port=1433;database=sample;",
+			useAzureADKey:       "true",
+		}},
+	}
+
+	err := sqlStore.Init(metadata)
+	assert.NoError(t, err)
+	assert.NotNil(t, sqlStore.tokenCredential)
+	assert.Equal(t, "https://database.windows.net/.default", sqlStore.tokenScope)
+}
+
 // Test that if the migration fails the error is reported.
 func TestExecuteMigrationFails(t *testing.T) {
 	sqlStore := NewSQLServerStateStore(logger.NewLogger("test")).(*SQLServer)